@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/anthropic/gap-map/internal/agent"
+	"github.com/anthropic/gap-map/internal/config"
+	"github.com/anthropic/gap-map/internal/store"
+)
+
+func agentCmd() *cobra.Command {
+	var (
+		dbPath       string
+		stdio        bool
+		afterFile    int64
+		afterSession int64
+		host         string
+		poll         time.Duration
+	)
+
+	cmd := &cobra.Command{
+		Use:   "agent",
+		Short: "Forward events between a remote host and this one",
+		Long: `Forward the events gap-map records on a remote host, where the AI tool
+and the repository live (a devcontainer, a Codespace, a machine reached
+over SSH), to the daemon on the machine you work from.
+
+On the remote host, run the daemon as usual (gapmap start). On this
+machine, run
+
+  gapmap agent connect user@devbox --map /workspaces/app=/home/me/app
+
+or list the host under remote_agents in the config so the daemon
+forwards it. connect runs "gapmap agent --stdio" on the remote host over
+ssh, which streams the remote store's file and session events; they are
+recorded here with the remote host's name, paths rewritten by --map,
+and attributed by the local daemon. Forwarding resumes where it stopped
+after a dropped connection. Only remote-to-local forwarding is supported.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !stdio {
+				return cmd.Help()
+			}
+			s, err := openConfiguredStore(dbPath)
+			if err != nil {
+				return err
+			}
+			defer s.Close()
+
+			if host == "" {
+				if host, err = os.Hostname(); err != nil {
+					return fmt.Errorf("get hostname: %w", err)
+				}
+			}
+			ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+			defer stop()
+			return agent.Serve(ctx, s, os.Stdout, host, agent.Cursor{File: afterFile, Session: afterSession}, poll)
+		},
+	}
+
+	cmd.PersistentFlags().StringVar(&dbPath, "db", "", "Override database path (default: from config)")
+	cmd.Flags().BoolVar(&stdio, "stdio", false, "Stream this host's events as JSON lines on stdout")
+	cmd.Flags().Int64Var(&afterFile, "after-file", 0, "Start after this file event id")
+	cmd.Flags().Int64Var(&afterSession, "after-session", 0, "Start after this session event id")
+	cmd.Flags().StringVar(&host, "host", "", "Host name to report (default: the system host name)")
+	cmd.Flags().DurationVar(&poll, "poll", time.Second, "How often to check for new events")
+
+	cmd.AddCommand(agentConnectCmd(&dbPath))
+
+	return cmd
+}
+
+func agentConnectCmd(dbPath *string) *cobra.Command {
+	var (
+		maps      []string
+		remoteCmd string
+		sshArgs   []string
+		once      bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "connect <ssh-target>",
+		Short: "Forward a remote host's events into the local store",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.Load(config.ConfigPath())
+			if err != nil {
+				return fmt.Errorf("load config: %w", err)
+			}
+			if *dbPath == "" {
+				*dbPath = cfg.DBPath
+			}
+			s, err := openStore(*dbPath)
+			if err != nil {
+				return fmt.Errorf("open store: %w", err)
+			}
+			defer s.Close()
+
+			f := &agent.Forwarder{
+				Store:   s,
+				Target:  args[0],
+				SSHArgs: sshArgs,
+				Command: remoteCmd,
+				Allow:   cfg.Trust().Allows,
+			}
+			for _, m := range maps {
+				pm, err := agent.ParsePathMap(m)
+				if err != nil {
+					return fmt.Errorf("--map: %w", err)
+				}
+				f.Maps = append(f.Maps, pm)
+			}
+
+			ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+			defer stop()
+			if once {
+				n, err := f.Connect(ctx)
+				fmt.Fprintf(os.Stderr, "Forwarded %d events from %s.\n", n, f.Target)
+				return err
+			}
+			f.Run(ctx)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringArrayVar(&maps, "map", nil, "Rewrite a remote path prefix to a local one (remote=local; repeatable)")
+	cmd.Flags().StringVar(&remoteCmd, "remote-cmd", "gapmap", "gapmap command on the remote host")
+	cmd.Flags().StringArrayVar(&sshArgs, "ssh-arg", nil, "Extra ssh option (repeatable)")
+	cmd.Flags().BoolVar(&once, "once", false, "Exit when the connection closes instead of reconnecting")
+
+	return cmd
+}
+
+// openConfiguredStore opens the store at dbPath, or the configured one.
+func openConfiguredStore(dbPath string) (*store.Store, error) {
+	if dbPath == "" {
+		cfg, err := config.Load(config.ConfigPath())
+		if err != nil {
+			return nil, fmt.Errorf("load config: %w", err)
+		}
+		dbPath = cfg.DBPath
+	}
+	s, err := openStore(dbPath)
+	if err != nil {
+		return nil, fmt.Errorf("open store: %w", err)
+	}
+	return s, nil
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/anthropic/gap-map/internal/config"
+	"github.com/anthropic/gap-map/internal/gitint"
+	"github.com/anthropic/gap-map/internal/metrics"
+	"github.com/anthropic/gap-map/internal/report"
+)
+
+func annotateCmd() *cobra.Command {
+	var (
+		dbPath string
+		commit string
+		lines  bool
+		dryRun bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "annotate",
+		Short: "Store attribution in git notes so it travels with the repository",
+		Long: `Write the project's attribution to a git note on a commit (HEAD by
+default) under refs/notes/gapmap: each file's work type, authorship level
+and AI lines, and with --lines the AI-written line numbers as well.
+
+Machines without the database read it back with analyze --from-notes,
+which uses the note on the nearest annotated commit. Notes are not pushed
+by default:
+
+  git push origin refs/notes/gapmap
+  git fetch origin refs/notes/gapmap:refs/notes/gapmap
+
+Line numbers follow the working tree, so annotate after committing.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.Load(config.ConfigPath())
+			if err != nil {
+				return fmt.Errorf("load config: %w", err)
+			}
+			if dbPath == "" {
+				dbPath = cfg.DBPath
+			}
+			scorer, err := metrics.NewScorer(cfg.Scorer, cfg.ScorerWeights)
+			if err != nil {
+				return fmt.Errorf("load scorer: %w", err)
+			}
+
+			s, err := openStore(dbPath)
+			if err != nil {
+				return fmt.Errorf("open store: %w", err)
+			}
+			defer s.Close()
+
+			pr, err := report.GenerateProject(cmd.Context(), s, scorer, report.Filter{}, reportOptions(cfg))
+			if err != nil {
+				return fmt.Errorf("generate project report: %w", err)
+			}
+			root, ok := gitRoot(pr.ProjectPath)
+			if !ok {
+				return fmt.Errorf("%s is not in a git repository", pr.ProjectPath)
+			}
+			rev, err := exec.Command("git", "-C", root, "rev-parse", "--verify", commit+"^{commit}").Output()
+			if err != nil {
+				return fmt.Errorf("resolve %s: %w", commit, err)
+			}
+			hash := strings.TrimSpace(string(rev))
+
+			var aiLines map[string][]int
+			if lines {
+				if status, _ := exec.Command("git", "-C", root, "status", "--porcelain", "--untracked-files=no").Output(); len(status) > 0 {
+					fmt.Fprintf(os.Stderr, "warning: uncommitted changes; line numbers follow the working tree, not %s\n", hash[:7])
+				}
+				files := make([]string, 0, len(pr.Files))
+				for _, fr := range pr.Files {
+					files = append(files, fr.FilePath)
+				}
+				aiLines, err = report.AILineNumbers(cmd.Context(), s, pr.ProjectPath, files, reportOptions(cfg))
+				if err != nil {
+					return fmt.Errorf("classify lines: %w", err)
+				}
+			}
+
+			note := report.BuildNote(pr, root, aiLines, time.Now())
+			data, err := json.MarshalIndent(note, "", "  ")
+			if err != nil {
+				return fmt.Errorf("encode note: %w", err)
+			}
+			out := cmd.OutOrStdout()
+			if dryRun {
+				fmt.Fprintln(out, string(data))
+				return nil
+			}
+			if err := gitint.WriteNote(root, hash, data); err != nil {
+				return err
+			}
+			fmt.Fprintf(out, "Annotated %s with %d files (%s)\n", hash[:7], len(note.Files), gitint.NotesRef)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&dbPath, "db", "", "Override database path (default: from config)")
+	cmd.Flags().StringVar(&commit, "commit", "HEAD", "Commit to attach the note to")
+	cmd.Flags().BoolVar(&lines, "lines", false, "Include the AI-written line numbers of each file")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Print the note instead of writing it")
+
+	return cmd
+}
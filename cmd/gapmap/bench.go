@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/anthropic/gap-map/internal/bench"
+	"github.com/anthropic/gap-map/internal/config"
+	"github.com/anthropic/gap-map/internal/report"
+)
+
+func benchCmd() *cobra.Command {
+	var (
+		dbPath  string
+		samples int
+		asJSON  bool
+		check   bool
+	)
+
+	cmd := &cobra.Command{
+		Use:    "bench",
+		Short:  "Time attribution's hot paths against your database",
+		Hidden: true,
+		Long: `Time session line parsing, line attribution, correlation and report
+generation against your gap-map database, and print the mean time of each
+with its budget and the slowest files and events. Attach the output to
+performance bug reports.
+
+Each stage times the --samples most recent session events, edited files
+or file events; the report is generated once over the whole database.
+Nothing is written to the database. With --check, gapmap bench exits
+non-zero when any stage's mean is over its budget.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			out := cmd.OutOrStdout()
+			cfg, err := config.Load(config.ConfigPath())
+			if err != nil {
+				return fmt.Errorf("load config: %w", err)
+			}
+			if dbPath == "" {
+				dbPath = cfg.DBPath
+			}
+
+			s, err := openStore(dbPath)
+			if err != nil {
+				return fmt.Errorf("open store: %w", err)
+			}
+			defer s.Close()
+
+			stages, err := bench.Run(cmd.Context(), s, bench.Options{
+				Samples: samples,
+				Match:   cfg.MatchOptions(""),
+				Report:  reportOptions(cfg),
+			})
+			if err != nil {
+				return fmt.Errorf("bench: %w", err)
+			}
+
+			var over int
+			for _, st := range stages {
+				if st.OverBudget() {
+					over++
+				}
+			}
+			if asJSON {
+				fmt.Fprintln(out, report.FormatJSON(stages))
+			} else {
+				fmt.Fprintf(out, "%-10s %8s %12s %12s\n", "STAGE", "OPS", "PER OP", "BUDGET")
+				for _, st := range stages {
+					mark := ""
+					if st.OverBudget() {
+						mark = "  over budget"
+					}
+					fmt.Fprintf(out, "%-10s %8d %12s %12s%s\n", st.Name, st.Ops, st.PerOp(), st.Budget, mark)
+				}
+				for _, st := range stages {
+					if len(st.Slowest) == 0 || st.Name == "report" {
+						continue
+					}
+					fmt.Fprintf(out, "\nSlowest %s:\n", st.Name)
+					for _, sm := range st.Slowest {
+						fmt.Fprintf(out, "  %12s  %s\n", sm.Took, sm.Label)
+					}
+				}
+			}
+			if check && over > 0 {
+				return fmt.Errorf("%d of %d stages over budget", over, len(stages))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&dbPath, "db", "", "Override database path (default: from config)")
+	cmd.Flags().IntVar(&samples, "samples", 1000, "Most recent events or files each stage times (0 for all)")
+	cmd.Flags().BoolVar(&asJSON, "json", false, "Output the stages as JSON")
+	cmd.Flags().BoolVar(&check, "check", false, "Exit non-zero when a stage is over its budget")
+	return cmd
+}
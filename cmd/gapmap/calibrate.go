@@ -0,0 +1,258 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/anthropic/gap-map/internal/authorship"
+	"github.com/anthropic/gap-map/internal/config"
+	"github.com/anthropic/gap-map/internal/sessionparser"
+	"github.com/anthropic/gap-map/internal/store"
+)
+
+// calibrateSnippetLines caps the session snippet and diff shown per sample.
+const calibrateSnippetLines = 20
+
+func calibrateCmd() *cobra.Command {
+	var (
+		dbPath     string
+		sample     int
+		days       int
+		reportOnly bool
+		jsonOut    bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "calibrate",
+		Short: "Label sampled attributions and measure how accurate they are",
+		Long: `Check gap-map's attributions against what you know.
+
+Samples recent unlabeled attributions and shows, for each, the AI edit it
+was matched to (if any) next to the change itself, from its commit or the
+working tree. Answer whether AI or a human made the change, or skip it.
+Labels are stored in the database and accumulate across runs.
+
+Then reports precision (of changes attributed to AI, how many AI made)
+and recall (of changes AI made, how many were attributed to it) for each
+match type: exact_file and fuzzy_file session matches, clipboard pastes,
+typing and none. It also shows both as they would be with narrower
+correlation windows, to tune correlation_window with evidence.
+
+Use --report to print the report without labeling.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if dbPath == "" {
+				cfg, err := config.Load(config.ConfigPath())
+				if err != nil {
+					return fmt.Errorf("load config: %w", err)
+				}
+				dbPath = cfg.DBPath
+			}
+
+			s, err := openStore(dbPath)
+			if err != nil {
+				return fmt.Errorf("open store: %w", err)
+			}
+			defer s.Close()
+
+			out := cmd.OutOrStdout()
+			if !reportOnly {
+				since := time.Now().AddDate(0, 0, -days)
+				samples, err := s.SampleAttributions(since, sample)
+				if err != nil {
+					return fmt.Errorf("sample attributions: %w", err)
+				}
+				if len(samples) == 0 {
+					fmt.Fprintf(out, "No unlabeled attributions in the last %d days.\n\n", days)
+				}
+				if err := labelSamples(s, samples, cmd.InOrStdin(), out); err != nil {
+					return err
+				}
+			}
+
+			labeled, err := s.QueryLabeledAttributions()
+			if err != nil {
+				return fmt.Errorf("query labels: %w", err)
+			}
+			c := authorship.Calibrate(labeled)
+			if jsonOut {
+				enc := json.NewEncoder(out)
+				enc.SetIndent("", "  ")
+				return enc.Encode(c)
+			}
+			printCalibration(out, c)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&dbPath, "db", "", "Override database path (default: from config)")
+	cmd.Flags().IntVarP(&sample, "sample", "n", 10, "Attributions to sample for labeling")
+	cmd.Flags().IntVar(&days, "days", 30, "Sample attributions from this many recent days")
+	cmd.Flags().BoolVar(&reportOnly, "report", false, "Print the accuracy report without labeling")
+	cmd.Flags().BoolVar(&jsonOut, "json", false, "Print the report as JSON")
+
+	return cmd
+}
+
+// labelSamples shows each sample and stores the label read from in, until
+// the samples run out or the user quits.
+func labelSamples(s *store.Store, samples []store.CalibrationSample, in io.Reader, out io.Writer) error {
+	sc := bufio.NewScanner(in)
+	for i, c := range samples {
+		said := "human"
+		if authorship.PredictsAI(c.AttributionRecord) {
+			said = "AI"
+		}
+		fmt.Fprintf(out, "[%d/%d] %s  %s\n", i+1, len(samples), c.FilePath, c.Timestamp.Local().Format("2006-01-02 15:04:05"))
+		fmt.Fprintf(out, "  attributed to %s: %s, match %s", said, c.AuthorshipLevel, orUnrecorded(c.MatchType))
+		if c.SessionEventID != nil {
+			fmt.Fprintf(out, " %.1fs apart", float64(c.CorrelationWindowMs)/1000)
+		}
+		fmt.Fprintf(out, ", confidence %.2f\n\n", c.Confidence)
+
+		if c.SessionEventID != nil {
+			fmt.Fprintln(out, "  AI edit:")
+			var snippet string
+			if content, err := s.QuerySessionEventContent(*c.SessionEventID, sessionparser.ExtractContent); err == nil {
+				snippet = content.Added
+			}
+			printSnippet(out, snippet)
+		}
+		fmt.Fprintln(out, "  Change:")
+		printSnippet(out, changeDiff(c))
+
+		for {
+			fmt.Fprint(out, "Who made this change? [a]i, [h]uman, [s]kip, [q]uit: ")
+			if !sc.Scan() {
+				fmt.Fprintln(out)
+				return sc.Err()
+			}
+			answer := strings.ToLower(strings.TrimSpace(sc.Text()))
+			var label string
+			switch answer {
+			case "a", "ai":
+				label = store.LabelAI
+			case "h", "human":
+				label = store.LabelHuman
+			case "s", "skip", "":
+			case "q", "quit":
+				fmt.Fprintln(out)
+				return nil
+			default:
+				continue
+			}
+			if label != "" {
+				if err := s.LabelAttribution(c.ID, label); err != nil {
+					return fmt.Errorf("store label: %w", err)
+				}
+			}
+			break
+		}
+		fmt.Fprintln(out)
+	}
+	return nil
+}
+
+// changeDiff returns the diff of the sample's file in the commit it landed
+// in, or in the working tree when it is not committed yet.
+func changeDiff(c store.CalibrationSample) string {
+	path := c.FilePath
+	if !filepath.IsAbs(path) && c.ProjectPath != "" {
+		path = filepath.Join(c.ProjectPath, path)
+	}
+	dir := c.ProjectPath
+	if dir == "" {
+		dir = filepath.Dir(path)
+	}
+
+	args := []string{"-C", dir, "diff", "-U1", "HEAD", "--", path}
+	if c.CommitHash != "" {
+		args = []string{"-C", dir, "show", "--format=", "-U1", c.CommitHash, "--", path}
+	}
+	out, err := exec.Command("git", args...).Output()
+	if err != nil || len(out) == 0 {
+		return ""
+	}
+	// Drop the diff header; the hunks are what matter.
+	lines := strings.Split(string(out), "\n")
+	for i, l := range lines {
+		if strings.HasPrefix(l, "@@") {
+			return strings.Join(lines[i:], "\n")
+		}
+	}
+	return string(out)
+}
+
+// printSnippet prints text indented, cut to calibrateSnippetLines lines.
+func printSnippet(out io.Writer, text string) {
+	text = strings.TrimRight(text, "\n")
+	if text == "" {
+		fmt.Fprintln(out, "    (not available)")
+		fmt.Fprintln(out)
+		return
+	}
+	lines := strings.Split(text, "\n")
+	for i, l := range lines {
+		if i == calibrateSnippetLines {
+			fmt.Fprintf(out, "    ... %d more lines\n", len(lines)-i)
+			break
+		}
+		fmt.Fprintf(out, "    %s\n", l)
+	}
+	fmt.Fprintln(out)
+}
+
+// printCalibration prints the accuracy report.
+func printCalibration(out io.Writer, c authorship.Calibration) {
+	if c.Overall.Labeled == 0 {
+		fmt.Fprintln(out, "No labeled attributions yet. Run gapmap calibrate to label some.")
+		return
+	}
+	fmt.Fprintf(out, "Attribution accuracy from %d labels (AI is positive)\n\n", c.Overall.Labeled)
+
+	row := func(r authorship.CalibrationRow) {
+		fmt.Fprintf(out, "  %-12s %7d %10s %8s %5d %5d %5d\n", r.Key, r.Labeled,
+			pct(r.Precision()), pct(r.Recall()), r.TruePos, r.FalsePos, r.FalseNeg)
+	}
+	header := func(first string) {
+		fmt.Fprintf(out, "  %-12s %7s %10s %8s %5s %5s %5s\n", first, "Labeled", "Precision", "Recall", "TP", "FP", "FN")
+	}
+
+	header("Match type")
+	for _, r := range c.ByMatchType {
+		row(r)
+	}
+	row(c.Overall)
+
+	fmt.Fprintln(out)
+	fmt.Fprintln(out, "With a narrower correlation window:")
+	header("Window")
+	for _, r := range c.ByWindow {
+		row(r)
+	}
+}
+
+// pct formats a percentage that may be undefined.
+func pct(v float64, ok bool) string {
+	if !ok {
+		return "-"
+	}
+	return fmt.Sprintf("%.1f%%", v)
+}
+
+// orUnrecorded names the match type of attributions from before match
+// types were recorded.
+func orUnrecorded(matchType string) string {
+	if matchType == "" {
+		return "unrecorded"
+	}
+	return matchType
+}
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/anthropic/gap-map/internal/config"
+	ghub "github.com/anthropic/gap-map/internal/github"
+	"github.com/anthropic/gap-map/internal/gitint"
+	"github.com/anthropic/gap-map/internal/metrics"
+	"github.com/anthropic/gap-map/internal/report"
+)
+
+func ciCmd() *cobra.Command {
+	var (
+		token        string
+		pr           int
+		dbPath       string
+		artifact     string
+		branch       string
+		baseBranch   string
+		skipComment  bool
+		skipCheckRun bool
+		verbose      bool
+		remote       string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "ci",
+		Short: "Run the full PR attribution flow in CI",
+		Long: `Run the full PR attribution flow as a single CI step:
+
+  1. Restore the database from the latest workflow artifact (--artifact),
+     unless a database already exists at --db.
+  2. Sync the PR's commits into it and run branch analysis against the
+     base branch. Without a database, attribution is derived from commit
+     metadata (Co-Authored-By tags and AI-Assisted: trailers).
+  3. Post the PR comment, or update the one posted by an earlier run.
+  4. Publish a "gap-map" check run on the PR head commit.
+
+The refreshed database is left at --db for the workflow to upload as the
+next artifact; under GitHub Actions its path is also written to the
+db-path step output. Requires GITHUB_TOKEN with pull-requests:write,
+checks:write, and actions:read.
+
+Without --pr, the PR number is detected as pr-comment does; --verbose
+prints which source was used. --skip-drafts, --min-ai-pct and
+--collapse-over gate and fold the comment as for pr-comment; the check
+run is published regardless. --annotate adds inline review comments as
+for pr-comment, alongside the comment.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			out := cmd.OutOrStdout()
+			cfg, err := config.Load(config.ConfigPath())
+			if err != nil {
+				return fmt.Errorf("load config: %w", err)
+			}
+			if dbPath == "" {
+				dbPath = cfg.DBPath
+			}
+			scorer, err := metrics.NewScorer(cfg.Scorer, cfg.ScorerWeights)
+			if err != nil {
+				return fmt.Errorf("load scorer: %w", err)
+			}
+			if token == "" {
+				token = os.Getenv("GITHUB_TOKEN")
+			}
+			if token == "" {
+				return fmt.Errorf("GitHub token required: set --token flag or GITHUB_TOKEN env var")
+			}
+			client := ghub.NewClient(token, ghub.ConfigClientOptions(cfg))
+
+			if remote == "" {
+				remote = cfg.GitHubRemote
+			}
+			owner, repo, err := ciRepository(remote)
+			if err != nil {
+				return err
+			}
+			if pr == 0 {
+				detected, err := client.DetectPRNumber(owner, repo)
+				if err != nil {
+					return fmt.Errorf("auto-detect PR number: %w", err)
+				}
+				pr = detected.Number
+				if verbose {
+					fmt.Fprintln(os.Stderr, detected)
+				}
+			}
+
+			detected := client.DetectPRBranches(owner, repo, pr)
+			if branch == "" {
+				branch = detected.Head
+			}
+			if branch == "" {
+				branch, _ = gitint.CurrentBranch(".")
+			}
+			if baseBranch == "" {
+				baseBranch = detected.Base
+			}
+			if baseBranch == "" {
+				if baseBranch, err = defaultBaseBranch(); err != nil {
+					return err
+				}
+			}
+
+			// Step 1: restore the database artifact, uploaded by a run on
+			// the PR's base or head branch.
+			if _, err := os.Stat(dbPath); os.IsNotExist(err) && artifact != "" {
+				err := client.DownloadArtifact(owner, repo, artifact, filepath.Base(dbPath), dbPath, []string{baseBranch, branch})
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "restore artifact %q: %v\n", artifact, err)
+				} else {
+					fmt.Fprintf(out, "restored database from artifact %q\n", artifact)
+				}
+			}
+
+			// Step 2: branch analysis.
+			projectReport, err := ciReport(dbPath, branch, baseBranch, scorer, reportOptions(cfg))
+			if err != nil {
+				return err
+			}
+
+			// Step 3: PR comment.
+			policy, err := commentPolicy(cmd, cfg)
+			if err != nil {
+				return err
+			}
+			body := policy.Comment(projectReport)
+			if reason := policy.SkipReason(projectReport, prIsDraft(policy, owner, repo, pr, client)); reason != "" && !skipComment {
+				fmt.Fprintf(out, "no comment posted: %s\n", reason)
+				skipComment = true
+			}
+			if !skipComment {
+				if err := client.UpsertComment(owner, repo, pr, policy.Apply(body)); err != nil {
+					return fmt.Errorf("post comment: %w", err)
+				}
+				fmt.Fprintf(out, "comment updated on PR #%d\n", pr)
+				if max := annotationLimit(cmd, cfg); max > 0 {
+					if err := annotatePR(out, dbPath, projectReport, branch, baseBranch, max, false, owner, repo, pr, client, reportOptions(cfg)); err != nil {
+						fmt.Fprintf(os.Stderr, "%v\n", err)
+					}
+				}
+			}
+
+			// Step 4: check run.
+			if !skipCheckRun {
+				headSHA := os.Getenv("GAPMAP_HEAD_SHA")
+				if headSHA == "" {
+					out, err := exec.Command("git", "rev-parse", "HEAD").Output()
+					if err != nil {
+						return fmt.Errorf("resolve HEAD: %w", err)
+					}
+					headSHA = strings.TrimSpace(string(out))
+				}
+				run := ghub.CheckRun{
+					Name:       "gap-map",
+					HeadSHA:    headSHA,
+					Conclusion: "neutral",
+					Title:      fmt.Sprintf("Meaningful AI: %.1f%% (%d files)", projectReport.MeaningfulAIPct, projectReport.TotalFiles),
+					Summary:    body,
+				}
+				if err := client.CreateCheckRun(owner, repo, run); err != nil {
+					return err
+				}
+				fmt.Fprintln(out, "check run published")
+			}
+
+			// The database (if any) is ready for upload.
+			if _, err := os.Stat(dbPath); err == nil {
+				fmt.Fprintf(out, "refreshed database at %s\n", dbPath)
+				if out := os.Getenv("GITHUB_OUTPUT"); out != "" {
+					if f, err := os.OpenFile(out, os.O_APPEND|os.O_WRONLY, 0644); err == nil {
+						fmt.Fprintf(f, "db-path=%s\n", dbPath)
+						f.Close()
+					}
+				}
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&token, "token", "", "GitHub token (default: GITHUB_TOKEN env var)")
+	cmd.Flags().IntVar(&pr, "pr", 0, "PR number (default: auto-detect)")
+	cmd.Flags().StringVar(&dbPath, "db", "", "Database path to restore to and refresh (default: from config)")
+	cmd.Flags().StringVar(&artifact, "artifact", "gapmap-db", "Workflow artifact holding the database (empty to skip restore)")
+	cmd.Flags().StringVar(&branch, "branch", "", "PR branch (default: auto-detect, then current branch)")
+	cmd.Flags().StringVar(&baseBranch, "base", "", "PR base branch (default: auto-detect, then base_branch in .gapmap.toml, then main)")
+	cmd.Flags().BoolVar(&skipComment, "skip-comment", false, "Do not post or update the PR comment")
+	cmd.Flags().BoolVar(&skipCheckRun, "skip-check-run", false, "Do not publish a check run")
+	cmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "Explain where the PR number came from")
+	cmd.Flags().StringVar(&remote, "remote", "", "Git remote of the GitHub repository when GITHUB_REPOSITORY is unset (default: github_remote, then upstream, then origin)")
+	addCommentPolicyFlags(cmd)
+	addAnnotationFlags(cmd)
+
+	return cmd
+}
+
+// ciRepository returns the GitHub owner and repo from GITHUB_REPOSITORY
+// (set by GitHub Actions), falling back to the git remote called remote,
+// or the one DetectRemote picks.
+func ciRepository(remote string) (owner, repo string, err error) {
+	if full := os.Getenv("GITHUB_REPOSITORY"); full != "" {
+		if owner, repo, ok := strings.Cut(full, "/"); ok {
+			return owner, repo, nil
+		}
+	}
+	r, err := ghub.DetectRemote(".", remote)
+	if err != nil {
+		return "", "", fmt.Errorf("auto-detect repository (set GITHUB_REPOSITORY): %w", err)
+	}
+	return r.Owner, r.Repo, nil
+}
+
+// ciReport syncs the checked-out commits into the database at dbPath and
+// generates the branch report. Without a usable database or branch data it
+// falls back to commit-metadata attribution.
+func ciReport(dbPath, branch, baseBranch string, scorer metrics.Scorer, opts report.Options) (*report.ProjectReport, error) {
+	if _, err := os.Stat(dbPath); err == nil {
+		s, err := openStore(dbPath)
+		if err != nil {
+			return nil, fmt.Errorf("open store: %w", err)
+		}
+		defer s.Close()
+
+		if repo, err := gitint.Open(".", s); err == nil {
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+			if err := repo.SyncCommits(ctx, time.Now().Add(-gitint.DefaultLookback())); err != nil {
+				fmt.Fprintf(os.Stderr, "git sync: %v\n", err)
+			}
+			cancel()
+		}
+
+		pr, err := report.GenerateProjectForBranch(s, branch, baseBranch, opts)
+		if err == nil {
+			return pr, nil
+		}
+		fmt.Fprintf(os.Stderr, "branch report for %s: %v; deriving attribution from git commit metadata\n", branch, err)
+	} else {
+		fmt.Fprintf(os.Stderr, "no database at %s; deriving attribution from git commit metadata\n", dbPath)
+	}
+
+	pr, err := report.GenerateProjectFromGit(".", baseBranch, scorer, opts)
+	if err != nil {
+		return nil, fmt.Errorf("generate git report: %w", err)
+	}
+	return pr, nil
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/anthropic/gap-map/internal/config"
+	"github.com/anthropic/gap-map/internal/gitint"
+	"github.com/anthropic/gap-map/internal/report"
+	"github.com/anthropic/gap-map/internal/store"
+)
+
+func coauthorsCmd() *cobra.Command {
+	var (
+		since      string
+		unverified bool
+		asJSON     bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "coauthors",
+		Short: "Check AI Co-Authored-By trailers against session data",
+		Long: `List synced commits with a Co-Authored-By trailer and whether session data
+backs each AI co-author claim.
+
+After each git sync, the daemon looks for AI edits to a co-authored
+commit's files in the 12 hours before it. A commit whose files AI edits
+touched is "corroborated", and its confidence grows with the share of
+files they cover. A commit that claims an AI co-author with no matching
+session activity is "unverified": the trailer may have been added by hand
+or by a commit template. Unverified commits are checked again at every
+sync, since their sessions may be ingested later.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			out := cmd.OutOrStdout()
+			cfg, err := config.Load(config.ConfigPath())
+			if err != nil {
+				return fmt.Errorf("load config: %w", err)
+			}
+			now := time.Now()
+			from := now.Add(-gitint.DefaultLookback())
+			if since != "" {
+				if from, err = report.ParseWindowBound(since, now, cfg.Location()); err != nil {
+					return fmt.Errorf("--since: %w", err)
+				}
+			}
+
+			s, err := openStore(cfg.DBPath)
+			if err != nil {
+				return fmt.Errorf("open store: %w", err)
+			}
+			defer s.Close()
+
+			commits, err := s.QueryCoauthorCommits(from)
+			if err != nil {
+				return err
+			}
+			var shown []store.CoauthorCommit
+			for _, c := range commits {
+				if c.Status == "" || (unverified && c.Status != store.CoauthorUnverified) {
+					continue // not an AI co-author, or not checked yet
+				}
+				shown = append(shown, c)
+			}
+
+			if asJSON {
+				fmt.Fprintln(out, report.FormatJSON(shown))
+				return nil
+			}
+			if len(shown) == 0 {
+				fmt.Fprintln(out, "No checked AI co-authored commits.")
+				return nil
+			}
+			for _, c := range shown {
+				fmt.Fprintf(out, "%s  %s  %-12s  confidence %.2f  %d AI edits  %s\n",
+					c.Hash[:7], c.Timestamp.In(cfg.Location()).Format("2006-01-02 15:04"),
+					c.Status, c.Confidence, c.SessionEvents, c.CoauthorName)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&since, "since", "", "Show commits from this time on (RFC 3339, YYYY-MM-DD or a duration like 14d; default: the sync lookback)")
+	cmd.Flags().BoolVar(&unverified, "unverified", false, "Show only commits no session activity backs")
+	cmd.Flags().BoolVar(&asJSON, "json", false, "Output as JSON")
+	return cmd
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/anthropic/gap-map/internal/config"
+	ghub "github.com/anthropic/gap-map/internal/github"
+)
+
+// addCommentPolicyFlags adds the flags that override the pr_comment_
+// config settings to a command that posts PR comments.
+func addCommentPolicyFlags(cmd *cobra.Command) {
+	cmd.Flags().Bool("skip-drafts", false, "Do not comment on draft PRs (default: pr_comment_skip_drafts)")
+	cmd.Flags().Float64("min-ai-pct", 0, "Only comment when meaningful AI% is at least this (default: pr_comment_min_ai_pct)")
+	cmd.Flags().Int("collapse-over", 0, "Fold the comment into a <details> block when longer than this many bytes (default: pr_comment_collapse_over)")
+	cmd.Flags().Int("notable-min-events", 0, "List as notable only files with at least this many events (default: pr_comment_notable_min_events)")
+	cmd.Flags().Int("notable-min-lines", 0, "List as notable only files with at least this many changed lines (default: pr_comment_notable_min_lines)")
+	cmd.Flags().Float64("notable-min-ai-pct", 0, "List as notable only files with at least this meaningful AI% (default: pr_comment_notable_min_ai_pct)")
+	cmd.Flags().Int("notable-max", 0, "Most notable files listed, per work type with --notable-by-work-type; 0 lists none (default: pr_comment_notable_max)")
+	cmd.Flags().String("notable-sort", "", "Order notable files by ai_pct, lines or events (default: pr_comment_notable_sort)")
+	cmd.Flags().Bool("notable-by-work-type", false, "List notable files in a table per work type (default: pr_comment_notable_by_work_type)")
+}
+
+// commentPolicy returns the PR comment policy from config, overridden by
+// the flags added by addCommentPolicyFlags that were set on cmd.
+func commentPolicy(cmd *cobra.Command, cfg *config.Config) (ghub.CommentPolicy, error) {
+	p := ghub.CommentPolicy{
+		SkipDrafts:   cfg.PRCommentSkipDrafts,
+		MinAIPct:     cfg.PRCommentMinAIPct,
+		CollapseOver: cfg.PRCommentCollapseOver,
+		Notable: ghub.NotableFiles{
+			MinEvents:  cfg.PRCommentNotableMinEvents,
+			MinLines:   cfg.PRCommentNotableMinLines,
+			MinAIPct:   cfg.PRCommentNotableMinAIPct,
+			Max:        cfg.PRCommentNotableMax,
+			Sort:       cfg.PRCommentNotableSort,
+			ByWorkType: cfg.PRCommentNotableByWorkType,
+		},
+	}
+	flags := cmd.Flags()
+	if flags.Changed("skip-drafts") {
+		p.SkipDrafts, _ = flags.GetBool("skip-drafts")
+	}
+	if flags.Changed("min-ai-pct") {
+		p.MinAIPct, _ = flags.GetFloat64("min-ai-pct")
+	}
+	if flags.Changed("collapse-over") {
+		p.CollapseOver, _ = flags.GetInt("collapse-over")
+	}
+	if flags.Changed("notable-min-events") {
+		p.Notable.MinEvents, _ = flags.GetInt("notable-min-events")
+	}
+	if flags.Changed("notable-min-lines") {
+		p.Notable.MinLines, _ = flags.GetInt("notable-min-lines")
+	}
+	if flags.Changed("notable-min-ai-pct") {
+		p.Notable.MinAIPct, _ = flags.GetFloat64("notable-min-ai-pct")
+	}
+	if flags.Changed("notable-max") {
+		p.Notable.Max, _ = flags.GetInt("notable-max")
+	}
+	if flags.Changed("notable-sort") {
+		p.Notable.Sort, _ = flags.GetString("notable-sort")
+	}
+	if flags.Changed("notable-by-work-type") {
+		p.Notable.ByWorkType, _ = flags.GetBool("notable-by-work-type")
+	}
+	return p, p.Notable.Validate()
+}
+
+// prIsDraft reports whether a PR is a draft when policy skips drafts and
+// the PR is known. A failed lookup is reported and treated as ready.
+func prIsDraft(policy ghub.CommentPolicy, owner, repo string, pr int, client *ghub.Client) bool {
+	if !policy.SkipDrafts || owner == "" || repo == "" || pr == 0 {
+		return false
+	}
+	draft, err := client.IsDraft(owner, repo, pr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "check whether PR #%d is a draft: %v\n", pr, err)
+		return false
+	}
+	return draft
+}
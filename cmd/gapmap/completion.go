@@ -0,0 +1,203 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/cobra/doc"
+
+	"github.com/anthropic/gap-map/internal/config"
+	"github.com/anthropic/gap-map/internal/worktype"
+)
+
+func completionCmd() *cobra.Command {
+	var install bool
+
+	cmd := &cobra.Command{
+		Use:   "completion bash|zsh|fish",
+		Short: "Generate or install shell completion",
+		Long: `Print the completion script for a shell, or with --install write it
+where the shell looks for completions:
+
+  bash  ~/.local/share/bash-completion/completions/gapmap
+        (needs the bash-completion package)
+  zsh   ~/.zfunc/_gapmap
+        (add "fpath=(~/.zfunc $fpath)" before compinit in ~/.zshrc)
+  fish  ~/.config/fish/completions/gapmap.fish
+
+Completion covers every subcommand and flag, including config keys, work
+types, sort orders, git refs and database files.
+
+Open a new shell after installing for the completion to take effect.`,
+		ValidArgs: []string{"bash", "zsh", "fish"},
+		Args:      cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			out := cmd.OutOrStdout()
+			shell := args[0]
+			if !install {
+				return writeCompletion(cmd.Root(), shell, cmd.OutOrStdout())
+			}
+
+			path, err := completionPath(shell)
+			if err != nil {
+				return err
+			}
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				return fmt.Errorf("create completion directory: %w", err)
+			}
+			f, err := os.Create(path)
+			if err != nil {
+				return fmt.Errorf("create %s: %w", path, err)
+			}
+			if err := writeCompletion(cmd.Root(), shell, f); err != nil {
+				f.Close()
+				return err
+			}
+			if err := f.Close(); err != nil {
+				return fmt.Errorf("write %s: %w", path, err)
+			}
+			fmt.Fprintf(out, "Installed %s completion to %s\n", shell, path)
+			if shell == "zsh" {
+				fmt.Fprintln(out, `Make sure ~/.zshrc has "fpath=(~/.zfunc $fpath)" before compinit.`)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&install, "install", false, "Write the script where the shell loads completions from")
+
+	return cmd
+}
+
+// writeCompletion writes root's completion script for shell to w.
+func writeCompletion(root *cobra.Command, shell string, w io.Writer) error {
+	switch shell {
+	case "bash":
+		return root.GenBashCompletionV2(w, true)
+	case "zsh":
+		return root.GenZshCompletion(w)
+	case "fish":
+		return root.GenFishCompletion(w, true)
+	default:
+		return fmt.Errorf("unsupported shell %q (want bash, zsh or fish)", shell)
+	}
+}
+
+// completionPath returns where shell loads user completion scripts from.
+func completionPath(shell string) (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("find home directory: %w", err)
+	}
+	switch shell {
+	case "bash":
+		data := os.Getenv("XDG_DATA_HOME")
+		if data == "" {
+			data = filepath.Join(home, ".local", "share")
+		}
+		return filepath.Join(data, "bash-completion", "completions", "gapmap"), nil
+	case "zsh":
+		return filepath.Join(home, ".zfunc", "_gapmap"), nil
+	case "fish":
+		conf := os.Getenv("XDG_CONFIG_HOME")
+		if conf == "" {
+			conf = filepath.Join(home, ".config")
+		}
+		return filepath.Join(conf, "fish", "completions", "gapmap.fish"), nil
+	default:
+		return "", fmt.Errorf("unsupported shell %q (want bash, zsh or fish)", shell)
+	}
+}
+
+func docsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "docs",
+		Short: "Generate documentation",
+	}
+
+	var dir string
+	man := &cobra.Command{
+		Use:   "man",
+		Short: "Generate man pages for every command",
+		Long: `Write a man page for gapmap and each of its subcommands to --dir
+(default ./man), e.g. gapmap.1 and gapmap-analyze.1. View one with
+"man ./man/gapmap-analyze.1" or install them under a man1 directory.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			out := cmd.OutOrStdout()
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				return fmt.Errorf("create %s: %w", dir, err)
+			}
+			root := cmd.Root()
+			root.DisableAutoGenTag = true
+			header := &doc.GenManHeader{Title: "GAPMAP", Section: "1", Source: "gap-map", Manual: "gap-map Manual"}
+			if err := doc.GenManTree(root, header, dir); err != nil {
+				return fmt.Errorf("generate man pages: %w", err)
+			}
+			fmt.Fprintf(out, "Wrote man pages to %s\n", dir)
+			return nil
+		},
+	}
+	man.Flags().StringVar(&dir, "dir", "man", "Directory to write the man pages to")
+	cmd.AddCommand(man)
+
+	return cmd
+}
+
+// registerFlagCompletions attaches value completion to the flags of every
+// command under root that share a name and meaning across commands.
+func registerFlagCompletions(root *cobra.Command) {
+	completions := map[string]cobra.CompletionFunc{
+		"db":           cobra.FixedCompletions([]string{"db"}, cobra.ShellCompDirectiveFilterFileExt),
+		"work-type":    completeWorkTypes,
+		"sort":         cobra.FixedCompletions([]string{"ai_pct", "lines", "events"}, cobra.ShellCompDirectiveNoFileComp),
+		"notable-sort": cobra.FixedCompletions([]string{"ai_pct", "lines", "events"}, cobra.ShellCompDirectiveNoFileComp),
+		"branch":       completeGitRefs,
+		"base":         completeGitRefs,
+		"commit":       completeGitRefs,
+	}
+
+	var walk func(cmd *cobra.Command)
+	walk = func(cmd *cobra.Command) {
+		for name, fn := range completions {
+			if cmd.Flags().Lookup(name) != nil {
+				_ = cmd.RegisterFlagCompletionFunc(name, fn)
+			}
+		}
+		for _, sub := range cmd.Commands() {
+			walk(sub)
+		}
+	}
+	walk(root)
+}
+
+func completeWorkTypes(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	var types []string
+	for _, wt := range worktype.AllWorkTypes() {
+		types = append(types, string(wt))
+	}
+	return types, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeGitRefs completes the branches and tags of the repository in the
+// current directory.
+func completeGitRefs(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	out, err := exec.Command("git", "for-each-ref", "--format=%(refname:short)", "refs/heads", "refs/remotes", "refs/tags").Output()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return strings.Fields(string(out)), cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeConfigKey completes the key argument of config get and set.
+func completeConfigKey(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveDefault
+	}
+	return config.Keys(), cobra.ShellCompDirectiveNoFileComp
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestDocsMan(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	dir := t.TempDir()
+	root := newRootCmd()
+	root.SetOut(&bytes.Buffer{})
+	root.SetArgs([]string{"docs", "man", "--dir", dir})
+	if err := root.Execute(); err != nil {
+		t.Fatalf("docs man: %v", err)
+	}
+
+	var check func(cmd *cobra.Command)
+	check = func(cmd *cobra.Command) {
+		if !cmd.IsAvailableCommand() || cmd.IsAdditionalHelpTopicCommand() {
+			return
+		}
+		page := strings.ReplaceAll(cmd.CommandPath(), " ", "-") + ".1"
+		if _, err := os.Stat(filepath.Join(dir, page)); err != nil {
+			t.Errorf("no man page for %q: %v", cmd.CommandPath(), err)
+		}
+		for _, sub := range cmd.Commands() {
+			check(sub)
+		}
+	}
+	check(root)
+	if t.Failed() {
+		return
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "gapmap-analyze.1"))
+	if err != nil || !bytes.Contains(data, []byte("gapmap-analyze")) {
+		t.Errorf("gapmap-analyze.1 = %q, %v", data, err)
+	}
+}
+
+func TestCompletionInstall(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_DATA_HOME", "")
+	t.Setenv("XDG_CONFIG_HOME", "")
+
+	for shell, path := range map[string]string{
+		"bash": ".local/share/bash-completion/completions/gapmap",
+		"zsh":  ".zfunc/_gapmap",
+		"fish": ".config/fish/completions/gapmap.fish",
+	} {
+		t.Run(shell, func(t *testing.T) {
+			var out bytes.Buffer
+			root := newRootCmd()
+			root.SetOut(&out)
+			root.SetArgs([]string{"completion", shell, "--install"})
+			if err := root.Execute(); err != nil {
+				t.Fatalf("completion %s --install: %v", shell, err)
+			}
+
+			path := filepath.Join(home, filepath.FromSlash(path))
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("read installed script: %v", err)
+			}
+			var want bytes.Buffer
+			if err := writeCompletion(root, shell, &want); err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(data, want.Bytes()) {
+				t.Errorf("%s does not hold the %s completion script", path, shell)
+			}
+			if !strings.Contains(out.String(), path) {
+				t.Errorf("output %q does not name %s", out.String(), path)
+			}
+		})
+	}
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/anthropic/gap-map/internal/config"
+)
+
+func configCmd() *cobra.Command {
+	var edit, showSecret bool
+
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "View and edit configuration",
+		Long: `View and edit the gap-map configuration file.
+
+Use the get/set/list subcommands instead of hand-editing the JSON file.
+Values are validated before they are written. Use --edit to open the
+config file in $EDITOR; it is validated after the editor exits.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !edit {
+				return cmd.Help()
+			}
+			return editConfig(cmd.OutOrStdout(), config.ConfigPath())
+		},
+	}
+
+	cmd.Flags().BoolVar(&edit, "edit", false, "Open the config file in $EDITOR")
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "path",
+		Short: "Print the config file path",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			out := cmd.OutOrStdout()
+			fmt.Fprintln(out, config.ConfigPath())
+		},
+	})
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "profiles",
+		Short: "List config profiles, marking the active one",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			out := cmd.OutOrStdout()
+			profiles, err := config.Profiles()
+			if err != nil {
+				return fmt.Errorf("list profiles: %w", err)
+			}
+			active := config.Profile()
+			for _, p := range profiles {
+				mark := " "
+				if p == active {
+					mark = "*"
+				}
+				fmt.Fprintf(out, "%s %-20s %s\n", mark, p, config.ProfileDataDir(p))
+			}
+			return nil
+		},
+	})
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "List all config keys and their values",
+		Long: `List all config keys and their values. Secrets (api_token,
+pr_webhook_secret) are shown as <redacted>; print one with
+"gapmap config get --show-secret <key>".`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			out := cmd.OutOrStdout()
+			cfg, err := config.Load(config.ConfigPath())
+			if err != nil {
+				return fmt.Errorf("load config: %w", err)
+			}
+			for _, key := range config.Keys() {
+				val, _ := cfg.GetRedacted(key)
+				fmt.Fprintf(out, "%-20s %s\n", key, val)
+			}
+			return nil
+		},
+	})
+
+	getCmd := &cobra.Command{
+		Use:               "get <key>",
+		Short:             "Print the value of a config key",
+		Long:              `Print the value of a config key. Secrets are shown as <redacted> unless --show-secret is given.`,
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeConfigKey,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			out := cmd.OutOrStdout()
+			cfg, err := config.Load(config.ConfigPath())
+			if err != nil {
+				return fmt.Errorf("load config: %w", err)
+			}
+			get := cfg.GetRedacted
+			if showSecret {
+				get = cfg.Get
+			}
+			val, err := get(args[0])
+			if err != nil {
+				return err
+			}
+			fmt.Fprintln(out, val)
+			return nil
+		},
+	}
+	getCmd.Flags().BoolVar(&showSecret, "show-secret", false, "Print the value of a secret key such as api_token")
+	cmd.AddCommand(getCmd)
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "set <key> <value>...",
+		Short: "Set a config key",
+		Long: `Set a config key and write it to the config file. The file's other
+keys are left as they are, and keys it does not set keep following the
+defaults.
+
+List keys such as watch_paths accept several values or a single
+comma-separated value:
+
+  gapmap config set watch_paths ~/src/api ~/src/web
+  gapmap config set ignore_patterns "*.log,tmp"`,
+		Args:              cobra.MinimumNArgs(2),
+		ValidArgsFunction: completeConfigKey,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			out := cmd.OutOrStdout()
+			path := config.ConfigPath()
+			cfg, err := config.Load(path)
+			if err != nil {
+				return fmt.Errorf("load config: %w", err)
+			}
+			if err := cfg.Set(args[0], args[1:]...); err != nil {
+				return err
+			}
+			if err := cfg.Validate(); err != nil {
+				return withCode(exitConfig, fmt.Errorf("invalid config:\n%w", err))
+			}
+			if err := cfg.SaveKeys(path, args[0]); err != nil {
+				return fmt.Errorf("save config: %w", err)
+			}
+			val, _ := cfg.GetRedacted(args[0])
+			fmt.Fprintf(out, "%s = %s\n", args[0], val)
+			return nil
+		},
+	})
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "validate",
+		Short: "Check the config file and the current repository's .gapmap.toml for errors",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			out := cmd.OutOrStdout()
+			cfg, err := config.Load(config.ConfigPath())
+			if err != nil {
+				return fmt.Errorf("load config: %w", err)
+			}
+			if err := cfg.Validate(); err != nil {
+				return withCode(exitConfig, fmt.Errorf("invalid config:\n%w", err))
+			}
+			repo, err := config.FindRepoSettings(".")
+			if err != nil {
+				return withCode(exitConfig, fmt.Errorf("invalid %s:\n%w", config.RepoSettingsFile, err))
+			}
+			fmt.Fprintln(out, "config is valid")
+			if repo != nil {
+				fmt.Fprintf(out, "%s is valid\n", filepath.Join(repo.Root, config.RepoSettingsFile))
+			}
+			return nil
+		},
+	})
+
+	return cmd
+}
+
+// editConfig opens the config file in $EDITOR (falling back to vi) and
+// validates the result once the editor exits. A missing config file is
+// created from defaults first so the user has something to edit.
+func editConfig(out io.Writer, path string) error {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		if err := config.Default().Save(path); err != nil {
+			return fmt.Errorf("create config: %w", err)
+		}
+	}
+
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+
+	// Run through the shell so EDITOR values with arguments ("code -w") work.
+	c := exec.Command("sh", "-c", editor+` "$1"`, "sh", path)
+	c.Stdin = os.Stdin
+	c.Stdout = os.Stdout
+	c.Stderr = os.Stderr
+	if err := c.Run(); err != nil {
+		return fmt.Errorf("run editor: %w", err)
+	}
+
+	cfg, err := config.Load(path)
+	if err != nil {
+		return fmt.Errorf("config no longer parses: %w", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return withCode(exitConfig, fmt.Errorf("invalid config:\n%w", err))
+	}
+	fmt.Fprintln(out, "config is valid")
+	return nil
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/anthropic/gap-map/internal/config"
+)
+
+func dedupeCmd() *cobra.Command {
+	var (
+		dbPath string
+		dryRun bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "dedupe",
+		Short: "Remove duplicate session events from the database",
+		Long: `Remove session events that were ingested more than once.
+
+Databases written before session events carried a dedupe key can hold
+duplicates from daemon restarts that re-read part of a session file. This
+gives every existing event its key (the JSONL message uuid, or a hash of
+the line) and deletes repeats, moving their attributions to the copy that
+is kept. New events are deduplicated on insert.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			out := cmd.OutOrStdout()
+			if dbPath == "" {
+				cfg, err := config.Load(config.ConfigPath())
+				if err != nil {
+					return fmt.Errorf("load config: %w", err)
+				}
+				dbPath = cfg.DBPath
+			}
+
+			s, err := openStore(dbPath)
+			if err != nil {
+				return fmt.Errorf("open store: %w", err)
+			}
+			defer s.Close()
+
+			res, err := s.DedupeSessionEvents(dryRun)
+			if err != nil {
+				return fmt.Errorf("dedupe session events: %w", err)
+			}
+
+			verb := "removed"
+			if dryRun {
+				verb = "would remove"
+			}
+			fmt.Fprintf(out, "Scanned %d session events without a dedupe key: %s %d duplicates, keyed %d.\n",
+				res.Scanned, verb, res.Duplicates, res.Backfilled)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&dbPath, "db", "", "Override database path (default: from config)")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Report duplicates without deleting them")
+
+	return cmd
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/anthropic/gap-map/internal/config"
+	"github.com/anthropic/gap-map/internal/daemon"
+	"github.com/anthropic/gap-map/internal/ipc"
+	"github.com/anthropic/gap-map/internal/logging"
+	"github.com/anthropic/gap-map/internal/sessionparser"
+	"github.com/anthropic/gap-map/internal/store"
+)
+
+// runDryRun runs the daemon pipeline in the foreground against a
+// throwaway data directory, printing each attribution it would record.
+// The real database, socket and PID file are left alone, so a dry run
+// can sit alongside a running daemon.
+func runDryRun(out io.Writer, cfg *config.Config) error {
+	tmp, err := os.MkdirTemp("", "gapmap-dry-run-")
+	if err != nil {
+		return fmt.Errorf("create dry-run directory: %w", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	dry := cfg.DryRun(tmp)
+	if err := logging.Setup(os.Stderr, dry.LogOptions()); err != nil {
+		return fmt.Errorf("set up logging: %w", err)
+	}
+
+	printDryRunPlan(out, dry)
+
+	ipcServer := ipc.NewServer(nil, nil, dry.WatchPaths)
+	d := daemon.New(dry, ipcServer)
+	ipcServer.SetDaemon(d)
+	loc := cfg.Location()
+	d.SetAttributionListener(func(rec store.AttributionRecord, workType string) {
+		printAttribution(out, loc, rec, workType)
+	})
+
+	return d.Start()
+}
+
+// printAttribution prints one line of the dry run's attribution table.
+func printAttribution(w io.Writer, loc *time.Location, rec store.AttributionRecord, workType string) {
+	kind := rec.Kind
+	if kind == "" {
+		kind = store.AttributionAddition
+	}
+	sign := "+"
+	if kind == store.AttributionDeletion {
+		sign = "-"
+	}
+	fmt.Fprintf(w, "%s  %-16s %-14s %s%-5d %s\n",
+		rec.Timestamp.In(loc).Format("15:04:05"), rec.AuthorshipLevel, workType,
+		sign, rec.LinesChanged, rec.FilePath)
+}
+
+// providerLabel names a session provider, with its session directory if
+// it reads one.
+func providerLabel(p sessionparser.SessionProvider) string {
+	if rp, ok := p.(sessionparser.RootedProvider); ok {
+		return p.Name() + " in " + rp.Root()
+	}
+	return p.Name()
+}
+
+// printDryRunPlan prints what the dry run watches, ignores and tails.
+func printDryRunPlan(out io.Writer, cfg *config.Config) {
+	fmt.Fprintln(out, "Dry run: nothing is written to your gap-map database.")
+	fmt.Fprintln(out)
+	if len(cfg.WatchPaths) == 0 {
+		fmt.Fprintln(out, "Watch paths:     (none; set watch_paths to attribute file edits)")
+	} else {
+		fmt.Fprintf(out, "Watch paths:     %s\n", strings.Join(cfg.WatchPaths, ", "))
+	}
+	fmt.Fprintf(out, "Ignore patterns: %s\n", strings.Join(cfg.IgnorePatterns, ", "))
+
+	var providers []sessionparser.SessionProvider
+	for _, dir := range cfg.SessionRoots() {
+		providers = append(providers, sessionparser.NewClaudeCodeParser(dir, 0, cfg.DiffAlgorithm))
+	}
+	for _, pc := range cfg.SessionProviders {
+		providers = append(providers, sessionparser.NewGenericProvider(pc.Name, pc.Command, 0, cfg.DiffAlgorithm))
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	for _, p := range providers {
+		files, err := p.Discover(ctx)
+		if err != nil {
+			fmt.Fprintf(out, "Sessions (%s): discovery failed: %v\n", providerLabel(p), err)
+			continue
+		}
+		fmt.Fprintf(out, "Sessions (%s): %d found\n", providerLabel(p), len(files))
+	}
+
+	fmt.Fprintln(out)
+	fmt.Fprintln(out, "Edit files under the watch paths to see attributions; Ctrl-C to stop.")
+	fmt.Fprintf(out, "%-8s  %-16s %-14s %-6s %s\n", "TIME", "AUTHORSHIP", "WORK TYPE", "LINES", "FILE")
+}
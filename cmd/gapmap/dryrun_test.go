@@ -0,0 +1,32 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/anthropic/gap-map/internal/store"
+)
+
+func TestPrintAttribution(t *testing.T) {
+	at := time.Date(2026, 3, 4, 15, 4, 5, 0, time.UTC)
+	for _, tc := range []struct {
+		rec  store.AttributionRecord
+		want string
+	}{
+		{
+			store.AttributionRecord{FilePath: "/work/api/main.go", AuthorshipLevel: "mostly_ai", Timestamp: at, LinesChanged: 12},
+			"15:04:05  mostly_ai        feature        +12    /work/api/main.go\n",
+		},
+		{
+			store.AttributionRecord{FilePath: "/work/api/old.go", AuthorshipLevel: "fully_human", Timestamp: at, LinesChanged: 3, Kind: store.AttributionDeletion},
+			"15:04:05  fully_human      feature        -3     /work/api/old.go\n",
+		},
+	} {
+		var buf bytes.Buffer
+		printAttribution(&buf, time.UTC, tc.rec, "feature")
+		if buf.String() != tc.want {
+			t.Errorf("printAttribution = %q, want %q", buf.String(), tc.want)
+		}
+	}
+}
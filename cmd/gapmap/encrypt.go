@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/anthropic/gap-map/internal/config"
+	"github.com/anthropic/gap-map/internal/ipc"
+	"github.com/anthropic/gap-map/internal/keychain"
+	"github.com/anthropic/gap-map/internal/store"
+)
+
+func encryptDBCmd() *cobra.Command {
+	var (
+		dbPath  string
+		decrypt bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "encrypt-db",
+		Short: "Encrypt the code and prompts stored in the database",
+		Long: `Encrypt the session content stored in the database (the raw JSONL line
+of every AI edit, which holds the code it wrote, and the prompt that asked
+for it) with AES-256-GCM.
+
+The key lives in the OS keychain (macOS Keychain via security, or the
+Secret Service via secret-tool on Linux) and is created on first use; set
+GAPMAP_DB_KEY to a base64-encoded 32-byte key to use your own instead.
+Once encrypted, the daemon encrypts new content as it records it and every
+command decrypts it transparently; without the key the database will not
+open.
+
+The database is vacuumed afterwards so no plaintext copy remains in it.
+Backups taken before encrypting still hold plaintext; delete them if that
+matters. --decrypt reverses the migration. Stop the daemon first.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			out := cmd.OutOrStdout()
+			cfg, err := config.Load(config.ConfigPath())
+			if err != nil {
+				return fmt.Errorf("load config: %w", err)
+			}
+			if dbPath == "" {
+				dbPath = cfg.DBPath
+			}
+			if ipc.NewClient(cfg.SocketPath).Ping() == nil {
+				return fmt.Errorf("the daemon is running; stop it with \"gapmap stop\" first")
+			}
+
+			s, err := openStore(dbPath)
+			if err != nil {
+				return fmt.Errorf("open store: %w", err)
+			}
+			defer s.Close()
+
+			if decrypt {
+				if !s.Encrypted() {
+					fmt.Fprintf(out, "%s is not encrypted.\n", dbPath)
+					return nil
+				}
+				res, err := s.DecryptContent()
+				if err != nil {
+					return fmt.Errorf("decrypt database: %w", err)
+				}
+				fmt.Fprintf(out, "Decrypted %d rows in %s.\n", res.Rows, dbPath)
+				return nil
+			}
+
+			key, err := keychain.KeyOrCreate()
+			if err != nil {
+				return fmt.Errorf("get encryption key: %w", err)
+			}
+			res, err := s.EncryptContent(key)
+			if err != nil {
+				return fmt.Errorf("encrypt database: %w", err)
+			}
+			fmt.Fprintf(out, "Encrypted %d rows in %s.\n", res.Rows, dbPath)
+
+			backups, err := store.Backups(dbPath)
+			if err == nil && len(backups) > 0 {
+				fmt.Fprintln(out, "These backups were taken before encrypting and still hold plaintext:")
+				for _, b := range backups {
+					fmt.Fprintf(out, "  %s\n", b)
+				}
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&dbPath, "db", "", "Override database path (default: from config)")
+	cmd.Flags().BoolVar(&decrypt, "decrypt", false, "Decrypt an encrypted database back to plaintext")
+
+	return cmd
+}
+
+// openStore opens the database at dbPath, reading encrypted content with
+// the key from $GAPMAP_DB_KEY or the keychain.
+func openStore(dbPath string) (*store.Store, error) {
+	return store.New(dbPath, store.WithKeySource(keychain.Key))
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/anthropic/gap-map/internal/api"
+	"github.com/anthropic/gap-map/internal/config"
+	"github.com/anthropic/gap-map/internal/ipc"
+	"github.com/anthropic/gap-map/internal/numfmt"
+	"github.com/anthropic/gap-map/internal/report"
+)
+
+// Exit codes, so scripts can tell failures apart. Any other failure exits
+// with exitFailure.
+const (
+	exitOK          = 0
+	exitFailure     = 1
+	exitConfig      = 2 // the config file cannot be read or is invalid
+	exitDaemonDown  = 3 // the daemon is not running, or a remote one not reachable
+	exitNoData      = 4 // there is no attribution data to report on
+	exitPartialData = 5 // a report was printed from partial data, e.g. commit metadata only
+)
+
+// codeError is an error that exits with a specific code.
+type codeError struct {
+	code int
+	err  error
+}
+
+func (e *codeError) Error() string { return e.err.Error() }
+func (e *codeError) Unwrap() error { return e.err }
+
+// withCode returns err exiting with code, or nil if err is nil.
+func withCode(code int, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &codeError{code: code, err: err}
+}
+
+// exitStatus is the code a successful command exits with; commands that
+// print a report from partial data set it to exitPartialData.
+var exitStatus = exitOK
+
+// exitCode returns the code a command failing with err exits with.
+func exitCode(err error) int {
+	var ce *codeError
+	switch {
+	case err == nil:
+		return exitStatus
+	case errors.As(err, &ce):
+		return ce.code
+	case errors.Is(err, config.ErrUnreadable):
+		return exitConfig
+	case errors.Is(err, ipc.ErrNotRunning), errors.Is(err, api.ErrUnreachable):
+		return exitDaemonDown
+	case errors.Is(err, report.ErrNoData):
+		return exitNoData
+	}
+	return exitFailure
+}
+
+// configureOutput applies --quiet: the output of every command is
+// discarded; errors still go to stderr.
+func configureOutput(cmd *cobra.Command, quiet bool) {
+	if quiet {
+		cmd.Root().SetOut(io.Discard)
+	}
+}
+
+// outputStyle is how cmd formats reports: color is off when --machine,
+// GAPMAP_NO_COLOR or NO_COLOR is set, or the output is not a terminal, and
+// numbers follow number_locale, or the C locale with --machine.
+func outputStyle(cmd *cobra.Command) report.Style {
+	machine, _ := cmd.Flags().GetBool("machine")
+	f, ok := cmd.OutOrStdout().(*os.File)
+	st := report.Style{
+		Color:   ok && isTerminal(f) && !machine && os.Getenv("GAPMAP_NO_COLOR") == "" && os.Getenv("NO_COLOR") == "",
+		Numbers: numfmt.C,
+	}
+	if !machine {
+		// An unreadable config is reported by the command that needs it.
+		if cfg, err := config.Load(config.ConfigPath()); err == nil {
+			if l, err := numfmt.Parse(cfg.NumberLocale); err == nil {
+				st.Numbers = l
+			}
+		}
+	}
+	return st
+}
+
+// isTerminal reports whether f is a character device such as a terminal.
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
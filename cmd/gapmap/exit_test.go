@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/spf13/cobra"
+
+	"github.com/anthropic/gap-map/internal/api"
+	"github.com/anthropic/gap-map/internal/config"
+	"github.com/anthropic/gap-map/internal/ipc"
+	"github.com/anthropic/gap-map/internal/numfmt"
+	"github.com/anthropic/gap-map/internal/report"
+)
+
+func TestExitCode(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"success", nil, exitOK},
+		{"failure", errors.New("boom"), exitFailure},
+		{"withCode", withCode(exitPartialData, errors.New("partial")), exitPartialData},
+		{"wrapped withCode", fmt.Errorf("analyze: %w", withCode(exitConfig, errors.New("bad"))), exitConfig},
+		{"withCode wins over its cause", withCode(exitFailure, report.ErrNoData), exitFailure},
+		{"config", fmt.Errorf("load: %w", config.ErrUnreadable), exitConfig},
+		{"daemon down", fmt.Errorf("status: %w", ipc.ErrNotRunning), exitDaemonDown},
+		{"remote down", api.ErrUnreachable, exitDaemonDown},
+		{"no data", fmt.Errorf("report: %w", report.ErrNoData), exitNoData},
+	} {
+		if got := exitCode(tc.err); got != tc.want {
+			t.Errorf("%s: exitCode = %d, want %d", tc.name, got, tc.want)
+		}
+	}
+	if withCode(exitConfig, nil) != nil {
+		t.Error("withCode(nil) is not nil")
+	}
+}
+
+func TestConfigureOutput(t *testing.T) {
+	t.Setenv("GAPMAP_DATA_DIR", t.TempDir())
+	cfg := config.Default()
+	cfg.NumberLocale = "de"
+	if err := cfg.Save(config.ConfigPath()); err != nil {
+		t.Fatal(err)
+	}
+	var st report.Style
+	root := &cobra.Command{Use: "gapmap"}
+	root.PersistentFlags().Bool("machine", false, "")
+	root.AddCommand(&cobra.Command{Use: "status", Run: func(cmd *cobra.Command, args []string) {
+		st = outputStyle(cmd)
+	}})
+	var buf bytes.Buffer
+	root.SetOut(&buf)
+	root.SetArgs([]string{"status", "--machine"})
+	if err := root.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	if st.Color || st.Numbers != numfmt.C {
+		t.Errorf("--machine style = %+v, want no color and the C locale", st)
+	}
+	root.SetArgs([]string{"status", "--machine=false"})
+	if err := root.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	if de, _ := numfmt.Parse("de"); st.Numbers != de {
+		t.Errorf("numbers = %+v, want number_locale de", st.Numbers)
+	}
+
+	sub, _, _ := root.Find([]string{"status"})
+	configureOutput(sub, true)
+	if out := sub.OutOrStdout(); out != io.Discard {
+		t.Errorf("--quiet output = %T, want io.Discard", out)
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/anthropic/gap-map/internal/config"
+	"github.com/anthropic/gap-map/internal/insights"
+	"github.com/anthropic/gap-map/internal/metrics"
+	"github.com/anthropic/gap-map/internal/report"
+)
+
+func gapsCmd() *cobra.Command {
+	var (
+		dbPath string
+		asJSON bool
+		top    int
+		since  string
+		until  string
+		opts   = insights.DefaultOptions
+	)
+
+	cmd := &cobra.Command{
+		Use:   "gaps",
+		Short: "Map your knowledge gaps: directories you left to AI",
+		Long: `List the directories where you rely on AI almost exclusively: AI wrote at
+least --min-ai-pct of their changed lines, and you edited their files by
+hand at most --max-human-edits times. Directories with fewer than
+--min-lines AI lines are left out.
+
+Gaps are ranked by their AI lines weighted by work type, so a directory
+of AI-written core logic ranks above one of AI-written boilerplate. Each
+lists the files to review first: those with the most weighted AI lines.
+
+--depth groups files by their first N path components instead of their
+own directory, e.g. --depth 2 for internal/api. --since and --until
+limit the report to a time window, as for analyze.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.Load(config.ConfigPath())
+			if err != nil {
+				return fmt.Errorf("load config: %w", err)
+			}
+			if dbPath == "" {
+				dbPath = cfg.DBPath
+			}
+			if opts.MinAIPct < 0 || opts.MinAIPct > 100 {
+				return fmt.Errorf("--min-ai-pct must be between 0 and 100")
+			}
+			if opts.MaxHumanEdits < 0 || opts.MinLines < 0 || opts.Depth < 0 || opts.Suggest < 0 || top < 0 {
+				return fmt.Errorf("--max-human-edits, --min-lines, --depth, --suggest and --top must not be negative")
+			}
+			scorer, err := metrics.NewScorer(cfg.Scorer, cfg.ScorerWeights)
+			if err != nil {
+				return fmt.Errorf("load scorer: %w", err)
+			}
+
+			var filter report.Filter
+			now := time.Now()
+			if since != "" {
+				if filter.Window.Since, err = report.ParseWindowBound(since, now, cfg.Location()); err != nil {
+					return fmt.Errorf("--since: %w", err)
+				}
+			}
+			if until != "" {
+				if filter.Window.Until, err = report.ParseWindowBound(until, now, cfg.Location()); err != nil {
+					return fmt.Errorf("--until: %w", err)
+				}
+			}
+			if err := filter.Window.Validate(); err != nil {
+				return err
+			}
+
+			s, err := openStore(dbPath)
+			if err != nil {
+				return fmt.Errorf("open store: %w", err)
+			}
+			defer s.Close()
+
+			pr, err := report.GenerateProject(cmd.Context(), s, scorer, filter, reportOptions(cfg))
+			if err != nil {
+				return fmt.Errorf("generate project report: %w", err)
+			}
+			gr := insights.FindGaps(pr, opts)
+			if top > 0 && len(gr.Gaps) > top {
+				gr.Gaps = gr.Gaps[:top]
+			}
+			out := cmd.OutOrStdout()
+			if asJSON {
+				fmt.Fprintln(out, report.FormatJSON(gr))
+			} else {
+				fmt.Fprint(out, insights.FormatGaps(gr, outputStyle(cmd)))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&dbPath, "db", "", "Override database path (default: from config)")
+	cmd.Flags().BoolVar(&asJSON, "json", false, "Output the gaps as JSON")
+	cmd.Flags().IntVar(&top, "top", 10, "List at most N gaps (0 lists all)")
+	cmd.Flags().Float64Var(&opts.MinAIPct, "min-ai-pct", opts.MinAIPct, "AI share of a directory's changed lines that makes it a gap")
+	cmd.Flags().IntVar(&opts.MaxHumanEdits, "max-human-edits", opts.MaxHumanEdits, "Human edits a gap may have at most")
+	cmd.Flags().IntVar(&opts.MinLines, "min-lines", opts.MinLines, "AI lines a gap needs")
+	cmd.Flags().IntVar(&opts.Depth, "depth", 0, "Group files by their first N path components (0: by their own directory)")
+	cmd.Flags().IntVar(&opts.Suggest, "suggest", opts.Suggest, "Files to suggest for review in each gap")
+	cmd.Flags().StringVar(&since, "since", "", "Count only AI edits and attributions from this time on (RFC 3339, YYYY-MM-DD or a duration like 14d)")
+	cmd.Flags().StringVar(&until, "until", "", "Count only AI edits and attributions before this time")
+	return cmd
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/anthropic/gap-map/internal/config"
+	"github.com/anthropic/gap-map/internal/metrics"
+	"github.com/anthropic/gap-map/internal/report"
+)
+
+func graphCmd() *cobra.Command {
+	var (
+		dbPath   string
+		format   string
+		level    string
+		depth    int
+		sessions int
+		output   string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "graph",
+		Short: "Export a collaboration map as Mermaid or Graphviz",
+		Long: `Export a graph of the project's directories (or, with --level file, files)
+and the AI sessions that wrote in them, for embedding in docs.
+
+Each node shows its changed lines and AI%, and is colored from blue
+(human) to red (AI) in steps of 20%. Edges run from each session to the
+nodes it wrote in, labeled with its AI lines there. In DOT output nodes
+grow with their lines and edges with theirs; Mermaid cannot size nodes.
+
+--depth cuts directories to their leading components (default 2), and
+--sessions keeps the sessions with the most AI lines (default 20; 0 keeps
+all).`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.Load(config.ConfigPath())
+			if err != nil {
+				return fmt.Errorf("load config: %w", err)
+			}
+			if dbPath == "" {
+				dbPath = cfg.DBPath
+			}
+			if format != "mermaid" && format != "dot" && format != "json" {
+				return fmt.Errorf("invalid --format %q: use mermaid, dot or json", format)
+			}
+			if level != report.GraphDirectories && level != report.GraphFiles {
+				return fmt.Errorf("invalid --level %q: use dir or file", level)
+			}
+			scorer, err := metrics.NewScorer(cfg.Scorer, cfg.ScorerWeights)
+			if err != nil {
+				return fmt.Errorf("load scorer: %w", err)
+			}
+
+			s, err := openStore(dbPath)
+			if err != nil {
+				return fmt.Errorf("open store: %w", err)
+			}
+			defer s.Close()
+
+			pr, err := report.GenerateProject(cmd.Context(), s, scorer, report.Filter{}, reportOptions(cfg))
+			if err != nil {
+				return fmt.Errorf("generate project report: %w", err)
+			}
+			g, err := report.BuildGraph(s, pr, report.GraphOptions{Level: level, Depth: depth, MaxSessions: sessions})
+			if err != nil {
+				return err
+			}
+
+			var out string
+			switch format {
+			case "mermaid":
+				out = report.FormatGraphMermaid(g, cfg.Location())
+			case "dot":
+				out = report.FormatGraphDOT(g, cfg.Location())
+			default:
+				out = report.FormatJSON(g) + "\n"
+			}
+			if output == "" || output == "-" {
+				fmt.Fprint(cmd.OutOrStdout(), out)
+			} else if err := os.WriteFile(output, []byte(out), 0644); err != nil {
+				return fmt.Errorf("write %s: %w", output, err)
+			}
+			if g.OmittedSessions > 0 {
+				fmt.Fprintf(os.Stderr, "%d smaller sessions left out (raise --sessions to include them)\n", g.OmittedSessions)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&dbPath, "db", "", "Override database path (default: from config)")
+	cmd.Flags().StringVar(&format, "format", "mermaid", "Output format: mermaid, dot or json")
+	cmd.Flags().StringVar(&level, "level", report.GraphDirectories, "Node level: dir or file")
+	cmd.Flags().IntVar(&depth, "depth", 2, "Leading directory components kept at the dir level (0 keeps all)")
+	cmd.Flags().IntVar(&sessions, "sessions", 20, "Sessions shown, most AI lines first (0 shows all)")
+	cmd.Flags().StringVarP(&output, "output", "o", "", "Write the graph to a file instead of stdout")
+	return cmd
+}
@@ -0,0 +1,368 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/anthropic/gap-map/internal/config"
+	"github.com/anthropic/gap-map/internal/gitint"
+)
+
+// gitHooks are the git hooks gap-map installs. post-commit and
+// post-rewrite ask the daemon to sync, after each commit and after an
+// amend or rebase, whose commits post-commit does not always see.
+// prepare-commit-msg adds the AI-Assisted trailer that attribution from
+// commit metadata alone reads on CI.
+var gitHooks = []string{"prepare-commit-msg", "post-commit", "post-rewrite"}
+
+// hookArgs are the gapmap arguments each hook runs, after the executable
+// and profile. They are inserted into the hook unquoted, so the hook's
+// own arguments can be passed on.
+var hookArgs = map[string]string{
+	"prepare-commit-msg": `commit-trailer "$1" "$2"`,
+	"post-commit":        "sync-git",
+	"post-rewrite":       "sync-git",
+}
+
+// Markers around the block gap-map manages in a git hook, so it can be
+// updated in place without touching the rest of an existing hook.
+const (
+	hookBegin = "# >>> gap-map >>>"
+	hookEnd   = "# <<< gap-map <<<"
+)
+
+func hooksCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "hooks",
+		Short: "Manage the git hooks that sync commits to the daemon",
+		Long: `Manage the post-commit and post-rewrite hooks that ask the daemon to sync
+commits right after a commit, amend or rebase. Without them, commits are
+picked up at the daemon's next poll; the poll keeps running either way,
+so a missed or failed hook only delays a sync.
+
+The prepare-commit-msg hook adds an "AI-Assisted:" trailer to the commit
+message when session data shows AI edits to the staged files, so
+attribution from commit metadata alone (on CI, without a database)
+counts them.
+
+The hooks are added between "# >>> gap-map >>>" markers, so existing
+hooks are kept, and core.hooksPath is honored. An existing hook written
+for another interpreter than sh, such as Python or Node, is left alone;
+the error shows the line to add to it.`,
+	}
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "install [path]",
+		Short: "Install the hooks in the repository at path (default: current directory)",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			root, err := hookRepo(args)
+			if err != nil {
+				return err
+			}
+			for _, name := range gitHooks {
+				hookPath, err := installHook(root, name)
+				if err != nil {
+					return fmt.Errorf("install %s hook: %w", name, err)
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "Installed %s\n", hookPath)
+			}
+			return nil
+		},
+	})
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "uninstall [path]",
+		Short: "Remove the hooks from the repository at path",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			root, err := hookRepo(args)
+			if err != nil {
+				return err
+			}
+			for _, name := range gitHooks {
+				hookPath, removed, err := removeHook(root, name)
+				if err != nil {
+					return fmt.Errorf("remove %s hook: %w", name, err)
+				}
+				if removed {
+					fmt.Fprintf(cmd.OutOrStdout(), "Removed the gap-map block from %s\n", hookPath)
+				}
+			}
+			return nil
+		},
+	})
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "status [path]",
+		Short: "Show which hooks are installed in the repository at path",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			root, err := hookRepo(args)
+			if err != nil {
+				return err
+			}
+			dir, err := hooksDir(root)
+			if err != nil {
+				return err
+			}
+			for _, name := range gitHooks {
+				hookPath := filepath.Join(dir, name)
+				state := "not installed"
+				if content, err := os.ReadFile(hookPath); err == nil && strings.Contains(string(content), hookBegin) {
+					state = "installed"
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "%-18s %-14s %s\n", name, state, hookPath)
+			}
+			return nil
+		},
+	})
+
+	return cmd
+}
+
+// hookRepo returns the root of the git repository named by args, or
+// containing the current directory.
+func hookRepo(args []string) (string, error) {
+	path := "."
+	if len(args) == 1 {
+		path = args[0]
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return "", fmt.Errorf("resolve %s: %w", path, err)
+	}
+	root, isGit := gitRoot(absPath)
+	if !isGit {
+		return "", fmt.Errorf("%s is not in a git repository", absPath)
+	}
+	return root, nil
+}
+
+// hooksDir returns the hooks directory of the repository at root, as git
+// resolves it, so core.hooksPath is honored.
+func hooksDir(root string) (string, error) {
+	out, err := exec.Command("git", "-C", root, "rev-parse", "--git-path", "hooks").Output()
+	if err != nil {
+		return "", fmt.Errorf("locate hooks directory: %w", err)
+	}
+	dir := strings.TrimSpace(string(out))
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(root, dir)
+	}
+	return dir, nil
+}
+
+// installHook adds the gap-map block to the named git hook in the
+// repository at root, creating the hook if needed, and returns its path.
+func installHook(root, name string) (string, error) {
+	dir, err := hooksDir(root)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		exe = "gapmap"
+	}
+	words := append([]string{exe}, profileArgs()...)
+	for i, w := range words {
+		words[i] = shellQuote(w)
+	}
+	words = append(words, hookArgs[name])
+	line := strings.Join(words, " ") + " >/dev/null 2>&1 || true"
+	block := fmt.Sprintf("%s\n%s\n%s\n", hookBegin, line, hookEnd)
+
+	hookPath := filepath.Join(dir, name)
+	existing, err := os.ReadFile(hookPath)
+	if err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
+	content := string(existing)
+	switch {
+	case content == "":
+		content = "#!/bin/sh\n" + block
+	case strings.Contains(content, hookBegin) && strings.Contains(content, hookEnd):
+		start := strings.Index(content, hookBegin)
+		end := strings.Index(content, hookEnd) + len(hookEnd)
+		content = content[:start] + strings.TrimSuffix(block, "\n") + content[end:]
+	default:
+		// The block goes right after the interpreter line, so it still runs
+		// when the hook exits early.
+		interp, ok := shellHook(content)
+		if !ok {
+			return "", fmt.Errorf("%s is not a shell script (%s); add this line to it yourself:\n  %s", hookPath, interp, line)
+		}
+		if interp == "" {
+			content = block + content
+			break
+		}
+		first, rest, _ := strings.Cut(content, "\n")
+		content = first + "\n" + block + rest
+		if !strings.HasSuffix(content, "\n") {
+			content += "\n"
+		}
+	}
+
+	if err := os.WriteFile(hookPath, []byte(content), 0755); err != nil {
+		return "", err
+	}
+	return hookPath, os.Chmod(hookPath, 0755)
+}
+
+// shellHook returns the interpreter line of an existing hook, or "" when
+// it has none and git runs it with sh, and reports whether the hook is
+// run by a POSIX-compatible shell the gap-map block can be added to.
+func shellHook(content string) (interp string, ok bool) {
+	first, _, _ := strings.Cut(content, "\n")
+	if !strings.HasPrefix(first, "#!") {
+		return "", true
+	}
+	interp = strings.TrimSpace(first)
+	fields := strings.Fields(strings.TrimPrefix(interp, "#!"))
+	if len(fields) == 0 {
+		return interp, false
+	}
+	prog := filepath.Base(fields[0])
+	if prog == "env" {
+		args := fields[1:]
+		for len(args) > 0 && strings.HasPrefix(args[0], "-") {
+			args = args[1:]
+		}
+		if len(args) == 0 {
+			return interp, false
+		}
+		prog = filepath.Base(args[0])
+	}
+	switch prog {
+	case "sh", "bash", "dash", "ash", "ksh", "zsh":
+		return interp, true
+	}
+	return interp, false
+}
+
+// shellQuote quotes s as a single POSIX shell word.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// removeHook removes the gap-map block from the named git hook in the
+// repository at root, and the hook itself if nothing else is left in it.
+// removed reports whether there was a block to remove.
+func removeHook(root, name string) (hookPath string, removed bool, err error) {
+	dir, err := hooksDir(root)
+	if err != nil {
+		return "", false, err
+	}
+	hookPath = filepath.Join(dir, name)
+	existing, err := os.ReadFile(hookPath)
+	if os.IsNotExist(err) {
+		return hookPath, false, nil
+	} else if err != nil {
+		return hookPath, false, err
+	}
+	content := string(existing)
+	start := strings.Index(content, hookBegin)
+	end := strings.Index(content, hookEnd)
+	if start < 0 || end < start {
+		return hookPath, false, nil
+	}
+	end += len(hookEnd)
+	if end < len(content) && content[end] == '\n' {
+		end++
+	}
+	content = content[:start] + content[end:]
+
+	if strings.TrimSpace(strings.TrimPrefix(content, "#!/bin/sh")) == "" {
+		return hookPath, true, os.Remove(hookPath)
+	}
+	return hookPath, true, os.WriteFile(hookPath, []byte(content), 0755)
+}
+
+// trailerSources are the prepare-commit-msg sources whose messages get no
+// trailer: git writes merge and squash messages itself.
+var trailerSources = map[string]bool{"merge": true, "squash": true}
+
+func commitTrailerCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "commit-trailer <message-file> [source]",
+		Short: "Add an AI-Assisted trailer from session data (run by the prepare-commit-msg hook)",
+		Long: `Add an "AI-Assisted:" trailer to the commit message in message-file, from
+the session events stored for the staged files since the last commit (at
+most 12 hours back): "yes" when AI edited every staged file, "partial"
+when it edited some. Nothing is added when it edited none, when the
+message already has the trailer, or for merge and squash messages.`,
+		Hidden: true,
+		Args:   cobra.RangeArgs(1, 3),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) > 1 && trailerSources[args[1]] {
+				return nil
+			}
+			root, ok := gitRoot(".")
+			if !ok {
+				return fmt.Errorf("not in a git repository")
+			}
+			out, err := exec.Command("git", "-C", root, "diff", "--cached", "--name-only", "-z").Output()
+			if err != nil {
+				return fmt.Errorf("list staged files: %w", err)
+			}
+			files := strings.Split(strings.TrimRight(string(out), "\x00"), "\x00")
+			if len(files) == 1 && files[0] == "" {
+				return nil
+			}
+
+			cfg, err := config.Load(config.ConfigPath())
+			if err != nil {
+				return fmt.Errorf("load config: %w", err)
+			}
+			s, err := openStore(cfg.DBPath)
+			if err != nil {
+				return fmt.Errorf("open store: %w", err)
+			}
+			defer s.Close()
+
+			now := time.Now()
+			start := now.Add(-gitint.CoauthorWindow)
+			if last, ok := lastCommitTime(root); ok && last.After(start) {
+				start = last
+			}
+			signal, err := gitint.SessionAISignal(s, root, files, start, now)
+			if err != nil {
+				return err
+			}
+			trailer := gitint.AITrailer(signal)
+			if trailer == "" {
+				return nil
+			}
+			if out, err := exec.Command("git", "-C", root, "interpret-trailers", "--in-place",
+				"--if-exists", "doNothing", "--trailer", trailer, args[0]).CombinedOutput(); err != nil {
+				return fmt.Errorf("add trailer: %w: %s", err, strings.TrimSpace(string(out)))
+			}
+			return nil
+		},
+	}
+}
+
+// lastCommitTime returns the commit time of HEAD in the repository at
+// root, or false before the first commit.
+func lastCommitTime(root string) (time.Time, bool) {
+	out, err := exec.Command("git", "-C", root, "log", "-1", "--format=%ct").Output()
+	if err != nil {
+		return time.Time{}, false
+	}
+	sec, err := strconv.ParseInt(strings.TrimSpace(string(out)), 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(sec, 0), true
+}
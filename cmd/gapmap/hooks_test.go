@@ -0,0 +1,72 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestInstallHook(t *testing.T) {
+	root := t.TempDir()
+	if out, err := exec.Command("git", "init", "-q", root).CombinedOutput(); err != nil {
+		t.Fatalf("git init: %v\n%s", err, out)
+	}
+	dir := filepath.Join(root, ".git", "hooks")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	// An existing sh hook keeps working, and the block runs before its exit.
+	original := "#!/usr/bin/env bash\nexit 0\n"
+	path := filepath.Join(dir, "post-commit")
+	if err := os.WriteFile(path, []byte(original), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := installHook(root, "post-commit"); err != nil {
+		t.Fatalf("installHook: %v", err)
+	}
+	data, _ := os.ReadFile(path)
+	got := string(data)
+	if !strings.HasPrefix(got, "#!/usr/bin/env bash\n"+hookBegin) || strings.Index(got, "sync-git") > strings.Index(got, "exit 0") {
+		t.Errorf("hook = %q, want the block after the interpreter line", got)
+	}
+	if _, removed, err := removeHook(root, "post-commit"); err != nil || !removed {
+		t.Fatalf("removeHook = %v, %v", removed, err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != original {
+		t.Errorf("hook after removal = %q, want %q", data, original)
+	}
+
+	// A hook in another language is left alone.
+	python := "#!/usr/bin/env python3\nimport sys\nsys.exit(0)\n"
+	path = filepath.Join(dir, "prepare-commit-msg")
+	if err := os.WriteFile(path, []byte(python), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := installHook(root, "prepare-commit-msg"); err == nil || !strings.Contains(err.Error(), "not a shell script") {
+		t.Errorf("installHook on a python hook: err = %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != python {
+		t.Errorf("python hook changed to %q", data)
+	}
+}
+
+func TestShellHook(t *testing.T) {
+	for content, want := range map[string]bool{
+		"echo hi\n":                 true,
+		"#!/bin/sh\n":               true,
+		"#!/bin/bash -e\n":          true,
+		"#!/usr/bin/env bash\n":     true,
+		"#!/usr/bin/env -S sh -e\n": true,
+		"#!/usr/bin/env node\n":     false,
+		"#!/usr/bin/python3\n":      false,
+		"#!\n":                      false,
+		"#!/usr/bin/env\n":          false,
+	} {
+		if _, got := shellHook(content); got != want {
+			t.Errorf("shellHook(%q) = %v, want %v", content, got, want)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/anthropic/gap-map/internal/config"
+	"github.com/anthropic/gap-map/internal/ingest"
+)
+
+func ingestDiffCmd() *cobra.Command {
+	var (
+		dbPath    string
+		author    string
+		timestamp string
+		sessionID string
+		model     string
+		project   string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "ingest-diff [file]",
+		Short: "Record a unified diff as AI or human edits",
+		Long: `Read a unified diff (git diff or diff -u) from a file or stdin and
+record each changed file as a file event, plus for --author ai a session
+event carrying the added and removed lines. The daemon attributes them
+like edits it watched itself, so tools gap-map cannot tail can still be
+counted, and diffs make reproducible test fixtures:
+
+  git diff HEAD~1 | gapmap ingest-diff --author ai --session cursor-42
+  gapmap ingest-diff --author human --timestamp 2025-03-01T12:00:00Z fix.patch
+
+Paths in the diff are relative to --project (default: the git repository
+of the current directory). Without --session, AI edits get a session id
+derived from the diff and timestamp, so ingesting the same diff twice adds
+no session events.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			out := cmd.OutOrStdout()
+			var in io.Reader = os.Stdin
+			if len(args) == 1 {
+				f, err := os.Open(args[0])
+				if err != nil {
+					return fmt.Errorf("open diff: %w", err)
+				}
+				defer f.Close()
+				in = f
+			}
+
+			ts := time.Now()
+			if timestamp != "" {
+				t, err := time.Parse(time.RFC3339, timestamp)
+				if err != nil {
+					return fmt.Errorf("--timestamp: %w", err)
+				}
+				ts = t
+			}
+
+			if project == "" {
+				wd, err := os.Getwd()
+				if err != nil {
+					return fmt.Errorf("get working directory: %w", err)
+				}
+				project = wd
+				if root, ok := gitRoot(wd); ok {
+					project = root
+				}
+			}
+			project, err := filepath.Abs(project)
+			if err != nil {
+				return fmt.Errorf("resolve %s: %w", project, err)
+			}
+
+			diffs, err := ingest.ParseUnifiedDiff(in)
+			if err != nil {
+				return fmt.Errorf("parse diff: %w", err)
+			}
+			if len(diffs) == 0 {
+				return fmt.Errorf("no file changes in diff")
+			}
+
+			cfg, err := config.Load(config.ConfigPath())
+			if err != nil {
+				return fmt.Errorf("load config: %w", err)
+			}
+			if !cfg.Trust().Allows(project) {
+				return fmt.Errorf("%s is not enabled (see gapmap enable)", project)
+			}
+			if dbPath == "" {
+				dbPath = cfg.DBPath
+			}
+			s, err := openStore(dbPath)
+			if err != nil {
+				return fmt.Errorf("open store: %w", err)
+			}
+			defer s.Close()
+
+			res, err := ingest.Diff(s, project, diffs, ingest.Meta{
+				Author:    author,
+				Timestamp: ts,
+				SessionID: sessionID,
+				Model:     model,
+			})
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(out, "Ingested %d files (+%d -%d lines) as %s edits", res.FileEvents, res.AddedLines, res.DeletedLines, author)
+			if res.SessionID != "" {
+				fmt.Fprintf(out, " in session %s", res.SessionID)
+			}
+			fmt.Fprintln(out)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&dbPath, "db", "", "Override database path (default: from config)")
+	cmd.Flags().StringVar(&author, "author", "", "Who made the changes: ai or human (required)")
+	cmd.Flags().StringVar(&timestamp, "timestamp", "", "When the changes were made, RFC 3339 (default: now)")
+	cmd.Flags().StringVar(&sessionID, "session", "", "AI session id to record the edits under (default: derived from the diff)")
+	cmd.Flags().StringVar(&model, "model", "", "Model that made the AI edits")
+	cmd.Flags().StringVar(&project, "project", "", "Project the diff's paths are relative to (default: git repository of the current directory)")
+	_ = cmd.MarkFlagRequired("author")
+	_ = cmd.RegisterFlagCompletionFunc("author", cobra.FixedCompletions([]string{ingest.AuthorAI, ingest.AuthorHuman}, cobra.ShellCompDirectiveNoFileComp))
+
+	return cmd
+}
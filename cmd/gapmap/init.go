@@ -0,0 +1,298 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/anthropic/gap-map/internal/config"
+	"github.com/anthropic/gap-map/internal/ipc"
+	"github.com/anthropic/gap-map/internal/sessionparser"
+)
+
+func initCmd() *cobra.Command {
+	var yes bool
+
+	cmd := &cobra.Command{
+		Use:   "init [path]",
+		Short: "Set up gap-map for a project",
+		Long: `Interactively set up gap-map for the project at path (default: the
+current directory):
+
+  1. Find the git repository root and add it to watch_paths.
+  2. Create the data directory and save the config.
+  3. Check that Claude Code sessions can be found, and how many belong to
+     this project.
+  4. Offer to install post-commit and post-rewrite hooks so each commit,
+     amend and rebase is synced to the daemon right away instead of at
+     the next poll.
+  5. Offer to install a service (systemd user unit on Linux, LaunchAgent
+     on macOS) that starts the daemon at login.
+
+Use --yes to accept the defaults without prompting: the project is added
+and the hooks installed, but no service is installed.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path := "."
+			if len(args) == 1 {
+				path = args[0]
+			}
+			absPath, err := filepath.Abs(path)
+			if err != nil {
+				return fmt.Errorf("resolve %s: %w", path, err)
+			}
+
+			in := bufio.NewReader(cmd.InOrStdin())
+			out := cmd.OutOrStdout()
+			ask := func(question string, def bool) bool {
+				if yes {
+					return def
+				}
+				return confirm(in, out, question, def)
+			}
+
+			// 1. Project root.
+			root, isGit := gitRoot(absPath)
+			if isGit {
+				fmt.Fprintf(out, "Project:   %s (git repository)\n", root)
+			} else {
+				fmt.Fprintf(out, "Project:   %s (not a git repository; commit sync and hooks are skipped)\n", root)
+			}
+
+			// 2. Config and data directory.
+			cfgPath := config.ConfigPath()
+			cfg, err := config.Load(cfgPath)
+			if err != nil {
+				return fmt.Errorf("load config: %w", err)
+			}
+			if slices.Contains(cfg.WatchPaths, root) {
+				fmt.Fprintf(out, "Watching:  already in watch_paths\n")
+			} else if ask(fmt.Sprintf("Add %s to watch_paths?", root), true) {
+				cfg.WatchPaths = append(cfg.WatchPaths, root)
+			}
+			if err := cfg.EnsureDataDir(); err != nil {
+				return fmt.Errorf("create data dir: %w", err)
+			}
+			fmt.Fprintf(out, "Data dir:  %s\n", cfg.DataDir)
+			if err := cfg.Validate(); err != nil {
+				return withCode(exitConfig, fmt.Errorf("invalid config: %w", err))
+			}
+			if err := cfg.SaveKeys(cfgPath, "watch_paths"); err != nil {
+				return fmt.Errorf("save config: %w", err)
+			}
+			fmt.Fprintf(out, "Config:    %s\n", cfgPath)
+
+			// 3. Claude Code session discovery.
+			total, project, err := countSessions(cmd.Context(), cfg.SessionRoots(), root)
+			if err != nil {
+				fmt.Fprintf(out, "Sessions:  discovery failed: %v\n", err)
+			} else {
+				fmt.Fprintf(out, "Sessions:  %d Claude Code session files found, %d for this project\n", total, project)
+				if project == 0 {
+					fmt.Fprintf(out, "           Run Claude Code in %s once; new sessions are picked up automatically.\n", root)
+				}
+			}
+
+			// 4. Git hooks.
+			if isGit && ask("Install git hooks to sync commits and add AI-Assisted trailers?", true) {
+				for _, name := range gitHooks {
+					hookPath, err := installHook(root, name)
+					if err != nil {
+						return fmt.Errorf("install %s hook: %w", name, err)
+					}
+					fmt.Fprintf(out, "Hook:      %s\n", hookPath)
+				}
+			}
+
+			// 5. Service.
+			if unit, ok := serviceUnit(); ok && ask(fmt.Sprintf("Install a %s so the daemon starts at login?", unit.kind), false) {
+				if err := unit.install(); err != nil {
+					return fmt.Errorf("install %s: %w", unit.kind, err)
+				}
+				fmt.Fprintf(out, "Service:   %s\n           Enable it with: %s\n", unit.path, unit.enable)
+			}
+
+			fmt.Fprintf(out, "\nDone. Start the daemon with: gapmap start\n")
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "Accept the defaults without prompting")
+
+	return cmd
+}
+
+// syncGitCmd is run by the git hooks that init installs. It never
+// fails the hook: an unreachable daemon syncs at its next poll anyway.
+func syncGitCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:    "sync-git",
+		Short:  "Ask the daemon to sync git commits now (run by git hooks)",
+		Hidden: true,
+		Args:   cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			cfg, err := config.Load(config.ConfigPath())
+			if err != nil {
+				return
+			}
+			root, ok := gitRoot(".")
+			if !ok {
+				return
+			}
+			_ = ipc.NewClient(cfg.SocketPath).SyncGit(root)
+		},
+	}
+}
+
+// confirm asks a yes/no question on out and reads the answer from in. An
+// empty answer (or end of input) takes def.
+func confirm(in *bufio.Reader, out io.Writer, question string, def bool) bool {
+	choices := "[y/N]"
+	if def {
+		choices = "[Y/n]"
+	}
+	fmt.Fprintf(out, "%s %s ", question, choices)
+	answer, _ := in.ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true
+	case "n", "no":
+		return false
+	default:
+		return def
+	}
+}
+
+// gitRoot returns the top level of the git repository containing path, or
+// path itself if it is not in one.
+func gitRoot(path string) (string, bool) {
+	out, err := exec.Command("git", "-C", path, "rev-parse", "--show-toplevel").Output()
+	if err != nil {
+		return path, false
+	}
+	return strings.TrimSpace(string(out)), true
+}
+
+// countSessions returns how many Claude Code session files exist under
+// sessionDirs, and how many are in the session directory Claude Code uses
+// for root (the path with separators and dots replaced by dashes).
+func countSessions(ctx context.Context, sessionDirs []string, root string) (total, project int, err error) {
+	dirName := strings.NewReplacer(string(filepath.Separator), "-", ".", "-").Replace(root)
+	for _, dir := range sessionDirs {
+		files, err := sessionparser.NewClaudeCodeParser(dir, 0, "").Discover(ctx)
+		if err != nil {
+			return 0, 0, err
+		}
+		for _, f := range files {
+			if filepath.Base(filepath.Dir(f.Path)) == dirName {
+				project++
+			}
+		}
+		total += len(files)
+	}
+	return total, project, nil
+}
+
+// service describes the per-user service definition for this platform.
+type service struct {
+	kind    string // description for prompts
+	path    string // file the definition is written to
+	content string
+	enable  string   // command the user runs to enable it
+	restart []string // command that restarts the daemon under the manager
+}
+
+// serviceUnit returns the service definition that runs the daemon in the
+// foreground under the platform's user service manager.
+func serviceUnit() (service, bool) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return service{}, false
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return service{}, false
+	}
+
+	// Each profile gets its own service running the daemon with that
+	// profile selected.
+	var args strings.Builder
+	for _, a := range profileArgs() {
+		args.WriteString(" " + a)
+	}
+
+	switch runtime.GOOS {
+	case "linux":
+		unit := "gapmap" + profileSuffix("-") + ".service"
+		return service{
+			kind: "systemd user service",
+			path: filepath.Join(home, ".config", "systemd", "user", unit),
+			content: fmt.Sprintf(`[Unit]
+Description=gap-map attribution daemon
+
+[Service]
+ExecStart=%s%s start --foreground
+Restart=on-failure
+
+[Install]
+WantedBy=default.target
+`, exe, args.String()),
+			enable:  "systemctl --user daemon-reload && systemctl --user enable --now " + unit,
+			restart: []string{"systemctl", "--user", "restart", unit},
+		}, true
+	case "darwin":
+		label := "com.anthropic.gapmap" + profileSuffix(".")
+		var plistArgs strings.Builder
+		for _, a := range profileArgs() {
+			plistArgs.WriteString("\t\t<string>" + a + "</string>\n")
+		}
+		path := filepath.Join(home, "Library", "LaunchAgents", label+".plist")
+		return service{
+			kind: "LaunchAgent",
+			path: path,
+			content: fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>Label</key>
+	<string>%s</string>
+	<key>ProgramArguments</key>
+	<array>
+		<string>%s</string>
+%s		<string>start</string>
+		<string>--foreground</string>
+	</array>
+	<key>RunAtLoad</key>
+	<true/>
+	<key>KeepAlive</key>
+	<dict>
+		<key>SuccessfulExit</key>
+		<false/>
+	</dict>
+</dict>
+</plist>
+`, label, exe, plistArgs.String()),
+			enable:  "launchctl load -w " + path,
+			restart: []string{"launchctl", "kickstart", "-k", fmt.Sprintf("gui/%d/%s", os.Getuid(), label)},
+		}, true
+	default:
+		return service{}, false
+	}
+}
+
+// install writes the service definition.
+func (s service) install() error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(s.path, []byte(s.content), 0644)
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/anthropic/gap-map/internal/config"
+	"github.com/anthropic/gap-map/internal/store"
+	"github.com/anthropic/gap-map/internal/watcher"
+)
+
+func labelCmd() *cobra.Command {
+	var dbPath string
+
+	cmd := &cobra.Command{
+		Use:   "label <file>:<line>[-<line>] human|ai",
+		Short: "Record who really wrote a range of lines",
+		Long: `Label a range of lines as written by AI or by a human.
+
+Labels are ground truth: gapmap analyze --accuracy compares them with the
+attribution gap-map computes for the same lines and reports how often they
+agree, so a team can check how trustworthy the numbers are before sharing
+them. Line numbers are those of the file in the working tree. Where labels
+overlap, the newest wins.
+
+Examples:
+  gapmap label internal/auth/token.go:10-42 ai
+  gapmap label main.go:7 human`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			file, start, end, err := parseLineRange(args[0])
+			if err != nil {
+				return err
+			}
+			label := strings.ToLower(args[1])
+			if label != store.LabelAI && label != store.LabelHuman {
+				return fmt.Errorf("label must be %s or %s, got %q", store.LabelHuman, store.LabelAI, args[1])
+			}
+
+			absPath, err := filepath.Abs(file)
+			if err != nil {
+				return fmt.Errorf("resolve path: %w", err)
+			}
+			if _, err := os.Stat(absPath); err != nil {
+				return fmt.Errorf("label %s: %w", file, err)
+			}
+
+			cfg, err := config.Load(config.ConfigPath())
+			if err != nil {
+				return fmt.Errorf("load config: %w", err)
+			}
+			if dbPath == "" {
+				dbPath = cfg.DBPath
+			}
+			// The project path must match the one attributions are recorded
+			// under: the watch root, or else the repository root.
+			projectPath := watcher.ProjectRoot(cfg.WatchPaths, absPath)
+			if projectPath == absPath {
+				projectPath, _ = gitRoot(filepath.Dir(absPath))
+			}
+
+			s, err := openStore(dbPath)
+			if err != nil {
+				return fmt.Errorf("open store: %w", err)
+			}
+			defer s.Close()
+
+			if _, err := s.InsertLineLabel(store.LineLabel{
+				ProjectPath: projectPath,
+				FilePath:    absPath,
+				StartLine:   start,
+				EndLine:     end,
+				Label:       label,
+			}); err != nil {
+				return fmt.Errorf("store label: %w", err)
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Labeled %s:%d-%d as %s\n", file, start, end, label)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&dbPath, "db", "", "Override database path (default: from config)")
+
+	return cmd
+}
+
+// parseLineRange splits "file:10-20" or "file:10" into the file and an
+// inclusive line range.
+func parseLineRange(arg string) (file string, start, end int, err error) {
+	i := strings.LastIndex(arg, ":")
+	if i <= 0 {
+		return "", 0, 0, fmt.Errorf("invalid %q: want <file>:<line>[-<line>]", arg)
+	}
+	file, lines := arg[:i], arg[i+1:]
+	from, to, isRange := strings.Cut(lines, "-")
+	start, err = strconv.Atoi(from)
+	if err != nil || start < 1 {
+		return "", 0, 0, fmt.Errorf("invalid start line in %q", arg)
+	}
+	end = start
+	if isRange {
+		end, err = strconv.Atoi(to)
+		if err != nil || end < start {
+			return "", 0, 0, fmt.Errorf("invalid end line in %q", arg)
+		}
+	}
+	return file, start, end, nil
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/anthropic/gap-map/internal/config"
+	"github.com/anthropic/gap-map/internal/report"
+	"github.com/anthropic/gap-map/internal/telemetry"
+)
+
+func leaderboardCmd() *cobra.Command {
+	var (
+		from   []string
+		week   string
+		by     string
+		asJSON bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "leaderboard",
+		Short: "Rank a team's developers by their weekly contribution",
+		Long: `Rank developers for one ISO week by surviving AI-assisted lines, human
+lines, or corrections to AI code (AI lines later rewritten).
+
+The leaderboard is opt-in per developer: with leaderboard_opt_in and
+telemetry enabled, the daemon adds the developer's weekly totals to the
+telemetry snapshots it posts to telemetry_endpoint. Entries carry
+leaderboard_name, or, with leaderboard_anonymize, an ID that changes
+every week and no name.
+
+--from reads the JSONL snapshots a team's endpoint collected (or a
+telemetry.jsonl file) and ranks every opted-in developer in them.
+Without it, this install's own entry is previewed from the local
+database.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			out := cmd.OutOrStdout()
+			cfg, err := config.Load(config.ConfigPath())
+			if err != nil {
+				return fmt.Errorf("load config: %w", err)
+			}
+			loc := cfg.Location()
+			start := telemetry.WeekStart(time.Now(), loc)
+			if week != "" {
+				if start, err = telemetry.ParseWeek(week, loc); err != nil {
+					return err
+				}
+			}
+			label := telemetry.WeekLabel(start)
+
+			var entries []telemetry.LeaderboardEntry
+			if len(from) > 0 {
+				for _, path := range from {
+					read, err := readLeaderboardFile(path)
+					if err != nil {
+						return err
+					}
+					entries = append(entries, read...)
+				}
+			} else {
+				s, err := openStore(cfg.DBPath)
+				if err != nil {
+					return fmt.Errorf("open store: %w", err)
+				}
+				defer s.Close()
+				opts := telemetry.LeaderboardOptions{
+					Name:      cfg.LeaderboardName,
+					Anonymize: cfg.LeaderboardAnonymize,
+					Location:  loc,
+				}
+				own, err := telemetry.CollectLeaderboardWeek(s, opts, start)
+				if err != nil {
+					return err
+				}
+				entries = append(entries, own)
+				if !cfg.LeaderboardOptIn {
+					fmt.Fprintln(os.Stderr, "Not opted in to the leaderboard (set leaderboard_opt_in); previewing what would be shared.")
+				}
+			}
+
+			ranked, err := telemetry.RankLeaderboard(entries, label, by)
+			if err != nil {
+				return err
+			}
+			if asJSON {
+				fmt.Fprintln(out, report.FormatJSON(ranked))
+				return nil
+			}
+			if len(ranked) == 0 {
+				fmt.Fprintf(out, "No leaderboard entries for %s.\n", label)
+				return nil
+			}
+
+			fmt.Fprintf(out, "Leaderboard %s\n\n", label)
+			fmt.Fprintf(out, "%3s  %-30s %13s %10s %15s\n", "#", "Developer", "Surviving AI", "Human", "AI corrections")
+			for i, e := range ranked {
+				name := e.Name
+				if name == "" {
+					name = e.DeveloperID
+				}
+				fmt.Fprintf(out, "%3d  %-30s %13d %10d %15d\n", i+1, name, e.SurvivingAILines, e.HumanLines, e.AICorrections)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringArrayVar(&from, "from", nil, "JSONL file of collected telemetry snapshots (repeatable)")
+	cmd.Flags().StringVar(&week, "week", "", "ISO week to rank, e.g. 2026-W42 (default: this week)")
+	cmd.Flags().StringVar(&by, "by", telemetry.RankSurviving, "Rank by surviving, human or corrections")
+	cmd.Flags().BoolVar(&asJSON, "json", false, "Output the ranked entries as JSON")
+	return cmd
+}
+
+// readLeaderboardFile reads the leaderboard entries of a snapshot file.
+func readLeaderboardFile(path string) ([]telemetry.LeaderboardEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	entries, err := telemetry.ReadLeaderboard(f)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", path, err)
+	}
+	return entries, nil
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"github.com/spf13/cobra"
+
+	"github.com/anthropic/gap-map/internal/config"
+	"github.com/anthropic/gap-map/internal/logging"
+)
+
+func logsCmd() *cobra.Command {
+	var (
+		follow bool
+		lines  int
+	)
+
+	cmd := &cobra.Command{
+		Use:   "logs",
+		Short: "Show the daemon log",
+		Long: `Print the last lines of the daemon's active log (daemon.log in the data
+directory). With --follow, keep printing new lines as the daemon writes
+them, across log rotations, until interrupted.
+
+The level, format (text or json) and rotation of the log are set with
+log_level, log_format, log_max_size_mb, log_rotate_interval and
+log_max_backups in the config. Rotated logs are kept next to the active
+one with a timestamp suffix.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.Load(config.ConfigPath())
+			if err != nil {
+				return fmt.Errorf("load config: %w", err)
+			}
+
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+			defer stop()
+
+			err = logging.Tail(ctx, cfg.LogPath(), lines, cmd.OutOrStdout(), follow)
+			if os.IsNotExist(err) {
+				return fmt.Errorf("no daemon log at %s (has the daemon been started?)", cfg.LogPath())
+			}
+			return err
+		},
+	}
+
+	cmd.Flags().BoolVarP(&follow, "follow", "f", false, "Keep printing new log lines")
+	cmd.Flags().IntVarP(&lines, "lines", "n", 50, "Number of lines to print first (0 for the whole log)")
+
+	return cmd
+}
@@ -1,33 +1,81 @@
 package main
 
 import (
+	"bufio"
+	"context"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
 	"os"
 	"os/exec"
+	"os/signal"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
 	"github.com/spf13/cobra"
 
+	"github.com/anthropic/gap-map/internal/api"
 	"github.com/anthropic/gap-map/internal/config"
+	"github.com/anthropic/gap-map/internal/coverage"
 	"github.com/anthropic/gap-map/internal/daemon"
+	"github.com/anthropic/gap-map/internal/gitexec"
+	"github.com/anthropic/gap-map/internal/gitint"
 	ghub "github.com/anthropic/gap-map/internal/github"
 	"github.com/anthropic/gap-map/internal/ipc"
+	"github.com/anthropic/gap-map/internal/logging"
+	"github.com/anthropic/gap-map/internal/metrics"
 	"github.com/anthropic/gap-map/internal/report"
 	"github.com/anthropic/gap-map/internal/store"
 	"github.com/anthropic/gap-map/internal/survival"
 )
 
 func main() {
+	err := newRootCmd().Execute()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+	}
+	closeGitExecutor()
+	os.Exit(exitCode(err))
+}
+
+// newRootCmd returns the gapmap command with all of its subcommands.
+func newRootCmd() *cobra.Command {
+	var quiet, machine bool
+	var profile string
 	rootCmd := &cobra.Command{
-		Use:   "gapmap",
-		Short: "Track human vs AI code authorship",
-		Long:  "gap-map is a daemon that monitors your development workflow to attribute code to human or AI authors.",
+		Use:     "gapmap",
+		Short:   "Track human vs AI code authorship",
+		Version: currentVersion(),
+		Long: `gap-map is a daemon that monitors your development workflow to attribute code to human or AI authors.
+
+Exit codes: 0 success, 1 failure, 2 config file unreadable or invalid,
+3 daemon not running, 4 no attribution data, 5 report printed from
+partial data (commit metadata or git notes, without the database).
+Color is off when GAPMAP_NO_COLOR or NO_COLOR is set or stdout is not a
+terminal. --machine turns color off and writes numbers in the C locale,
+whatever number_locale says, for output that is parsed.
+
+--profile (or GAPMAP_PROFILE) selects a named profile with its own data
+directory, database, socket and watch paths under ~/.gapmap/profiles, so
+attribution data for different clients never mixes. GAPMAP_DATA_DIR
+overrides the data directory outright.`,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if err := selectProfile(profile); err != nil {
+				return withCode(exitConfig, err)
+			}
+			configureOutput(cmd, quiet)
+			return nil
+		},
 	}
+	rootCmd.PersistentFlags().BoolVarP(&quiet, "quiet", "q", false, "Print nothing but errors; rely on the exit code")
+	rootCmd.PersistentFlags().BoolVar(&machine, "machine", false, "Plain output for scripts: no color, and numbers in the C locale")
+	rootCmd.PersistentFlags().StringVar(&profile, "profile", "", "Config profile to use (default: $GAPMAP_PROFILE or \"default\")")
 
 	rootCmd.AddCommand(startCmd())
 	rootCmd.AddCommand(stopCmd())
@@ -36,37 +84,80 @@ func main() {
 	rootCmd.AddCommand(analyzeCmd())
 	rootCmd.AddCommand(prCommentCmd())
 	rootCmd.AddCommand(survivalCmd())
-
-	if err := rootCmd.Execute(); err != nil {
-		os.Exit(1)
-	}
+	rootCmd.AddCommand(configCmd())
+	rootCmd.AddCommand(telemetryCmd())
+	rootCmd.AddCommand(migrateCmd())
+	rootCmd.AddCommand(ciCmd())
+	rootCmd.AddCommand(dedupeCmd())
+	rootCmd.AddCommand(provenanceCmd())
+	rootCmd.AddCommand(initCmd())
+	rootCmd.AddCommand(syncGitCmd())
+	rootCmd.AddCommand(hooksCmd())
+	rootCmd.AddCommand(commitTrailerCmd())
+	rootCmd.AddCommand(coauthorsCmd())
+	rootCmd.AddCommand(logsCmd())
+	rootCmd.AddCommand(repairCmd())
+	rootCmd.AddCommand(mcpCmd())
+	rootCmd.AddCommand(annotateCmd())
+	rootCmd.AddCommand(ingestDiffCmd())
+	rootCmd.AddCommand(markCmd())
+	rootCmd.AddCommand(replayCmd())
+	rootCmd.AddCommand(benchCmd())
+	rootCmd.AddCommand(enableCmd())
+	rootCmd.AddCommand(disableCmd())
+	rootCmd.AddCommand(encryptDBCmd())
+	rootCmd.AddCommand(calibrateCmd())
+	rootCmd.AddCommand(labelCmd())
+	rootCmd.AddCommand(pruneCmd())
+	rootCmd.AddCommand(agentCmd())
+	rootCmd.AddCommand(projectsCmd())
+	rootCmd.AddCommand(orphansCmd())
+	rootCmd.AddCommand(leaderboardCmd())
+	rootCmd.AddCommand(graphCmd())
+	rootCmd.AddCommand(gapsCmd())
+	rootCmd.AddCommand(watchCmd())
+	rootCmd.AddCommand(completionCmd())
+	rootCmd.AddCommand(docsCmd())
+	rootCmd.AddCommand(selfUpdateCmd())
+	registerFlagCompletions(rootCmd)
+	return rootCmd
 }
 
 func startCmd() *cobra.Command {
-	var foreground bool
+	var (
+		foreground bool
+		supervise  bool
+		logPath    string
+		dryRun     bool
+	)
 
 	cmd := &cobra.Command{
 		Use:   "start",
 		Short: "Start the gap-map daemon",
 		RunE: func(cmd *cobra.Command, args []string) error {
+			out := cmd.OutOrStdout()
 			cfg, err := config.Load(config.ConfigPath())
 			if err != nil {
 				return fmt.Errorf("load config: %w", err)
 			}
 
+			if dryRun {
+				return runDryRun(cmd.OutOrStdout(), cfg)
+			}
+
 			pidPath := filepath.Join(cfg.DataDir, "gapmap.pid")
 
 			// The already-running checks only apply to the user-facing
 			// entry point (non-foreground). The foreground child skips
 			// these because the parent already validated and wrote the
 			// PID file for the child's own PID.
-			if !foreground {
+			if !foreground && !supervise {
 				// Check if daemon is already running via PID file.
 				if data, err := os.ReadFile(pidPath); err == nil {
 					if pid, err := strconv.Atoi(strings.TrimSpace(string(data))); err == nil {
 						if process, err := os.FindProcess(pid); err == nil {
 							if err := process.Signal(syscall.Signal(0)); err == nil {
-								fmt.Printf("daemon is already running (pid %d)\n", pid)
+								fmt.Fprintf(out, "daemon is already running (pid %d)\n", pid)
 								return nil
 							}
 						}
@@ -78,36 +169,59 @@ func startCmd() *cobra.Command {
 				// Also check via IPC ping (covers case where PID file is missing).
 				client := ipc.NewClient(cfg.SocketPath)
 				if err := client.Ping(); err == nil {
-					fmt.Println("daemon is already running")
+					fmt.Fprintln(out, "daemon is already running")
 					return nil
 				}
 			}
 
 			// Remove stale socket file (from a prior crash).
 			if _, err := os.Stat(cfg.SocketPath); err == nil {
-				log.Println("removing stale socket file")
+				slog.Info("removing stale socket file", "path", cfg.SocketPath)
 				_ = os.Remove(cfg.SocketPath)
 			}
 
+			exe, err := os.Executable()
+			if err != nil {
+				return fmt.Errorf("resolve executable path: %w", err)
+			}
+			daemonCmd := func() *exec.Cmd {
+				return exec.Command(exe, "start", "--foreground", "--log-file", cfg.LogPath())
+			}
+
+			if supervise {
+				// Run the daemon as our child and restart it when it
+				// crashes. Our stderr is the daemon's.
+				ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+				defer stop()
+				return daemon.NewSupervisor(func() *exec.Cmd {
+					c := daemonCmd()
+					c.Stderr = os.Stderr
+					return c
+				}, cfg.DataDir).Run(ctx)
+			}
+
 			if !foreground {
-				// Re-exec ourselves with --foreground to daemonize.
-				exe, err := os.Executable()
-				if err != nil {
-					return fmt.Errorf("resolve executable path: %w", err)
-				}
+				// Re-exec ourselves with --foreground (or --supervise) to
+				// daemonize.
 
 				// Ensure data directory exists for log file.
 				_ = cfg.EnsureDataDir()
+				_ = os.Remove(daemon.CrashStatePath(cfg.DataDir))
 
-				logPath := filepath.Join(cfg.DataDir, "daemon.log")
-				logFile, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+				// The child writes its own rotated log; its stderr only
+				// catches what bypasses the logger, such as a panic.
+				stderrPath := filepath.Join(cfg.DataDir, "daemon-stderr.log")
+				logFile, err := os.OpenFile(stderrPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
 				if err != nil {
-					return fmt.Errorf("open daemon log: %w", err)
+					return fmt.Errorf("open daemon stderr log: %w", err)
 				}
 
-				child := exec.Command(exe, "start", "--foreground")
+				child := daemonCmd()
+				if cfg.Supervise {
+					child = exec.Command(exe, "start", "--supervise")
+				}
 				child.Stdin = nil
-				child.Stdout = logFile
+				child.Stdout = nil
 				child.Stderr = logFile
 				child.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
 
@@ -139,16 +253,34 @@ func startCmd() *cobra.Command {
 				}
 
 				if !healthy {
+					// Don't leave a supervisor restarting a daemon that
+					// cannot start.
+					_ = syscall.Kill(childPID, syscall.SIGTERM)
 					_ = os.Remove(pidPath)
-					return fmt.Errorf("daemon failed to start (check logs)")
+					return fmt.Errorf("daemon failed to start (see \"gapmap logs\" and %s)", stderrPath)
 				}
 
-				printBanner()
-				fmt.Printf("  daemon started (pid %d)\n\n", childPID)
+				printBanner(out, outputStyle(cmd))
+				fmt.Fprintf(out, "  daemon started (pid %d)\n\n", childPID)
 				return nil
 			}
 
-			// Foreground mode: run daemon directly.
+			// Foreground mode: log to stderr, or to the rotated log file
+			// the background parent asked for.
+			var logOut io.Writer = os.Stderr
+			if logPath != "" {
+				rf, err := logging.OpenRotating(logPath, cfg.LogOptions())
+				if err != nil {
+					return fmt.Errorf("open daemon log: %w", err)
+				}
+				defer rf.Close()
+				logOut = rf
+			}
+			if err := logging.Setup(logOut, cfg.LogOptions()); err != nil {
+				return fmt.Errorf("set up logging: %w", err)
+			}
+
+			// Run daemon directly.
 			// Create IPC server first (with nil store -- daemon will set it).
 			ipcServer := ipc.NewServer(nil, nil, cfg.WatchPaths)
 
@@ -157,6 +289,8 @@ func startCmd() *cobra.Command {
 
 			// Now wire the daemon back into the IPC server.
 			ipcServer.SetDaemon(d)
+			wireIPC(ipcServer, d, cfg)
+			d.SetAPIReporter(apiReporter(d, cfg))
 
 			// Start blocks until signal or error.
 			return d.Start()
@@ -164,15 +298,160 @@ func startCmd() *cobra.Command {
 	}
 
 	cmd.Flags().BoolVar(&foreground, "foreground", false, "Run in the foreground (don't daemonize)")
+	cmd.Flags().BoolVar(&supervise, "supervise", false, "Run the daemon as a child process and restart it after a crash (used when supervise is set in config)")
+	cmd.Flags().StringVar(&logPath, "log-file", "", "In the foreground, log to this file, rotated per config, instead of stderr")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Run in the foreground against a temporary database and print attributions instead of recording them")
 
 	return cmd
 }
 
+// wireIPC connects the IPC server's editor-plugin methods to the daemon:
+// fileReport, recordManualAttribution, typingHeartbeat, markLines, and
+// syncGit are served by the daemon, and each recorded attribution is
+// published to subscribers.
+func wireIPC(srv *ipc.Server, d *daemon.Daemon, cfg *config.Config) {
+	srv.SetFileReporter(func(filePath string) (interface{}, error) {
+		return report.GenerateFileWindow(d.Store(), filePath, report.Window{}, reportOptions(cfg))
+	})
+	srv.SetAttributionRecorder(func(m ipc.ManualAttribution) (int64, error) {
+		return d.RecordManualAttribution(m.FilePath, m.AuthorshipLevel, m.WorkType, m.Lines)
+	})
+	srv.SetTypingRecorder(func(h ipc.TypingHeartbeat) (int64, error) {
+		start, err := time.Parse(time.RFC3339Nano, h.StartedAt)
+		if err != nil {
+			return 0, fmt.Errorf("started_at: %w", err)
+		}
+		end, err := time.Parse(time.RFC3339Nano, h.EndedAt)
+		if err != nil {
+			return 0, fmt.Errorf("ended_at: %w", err)
+		}
+		return d.RecordTypingBurst(h.FilePath, start, end, h.CharsTyped, h.Source)
+	})
+	srv.SetLineMarker(func(m ipc.MarkLines) (ipc.MarkResult, error) {
+		res, err := d.MarkLines(m.FilePath, m.StartLine, m.EndLine, m.Author, m.Source)
+		return ipc.MarkResult{SessionID: res.SessionID, Lines: res.AddedLines}, err
+	})
+	srv.SetGitSyncer(d.SyncGit)
+	srv.SetTrustReloader(func() error {
+		latest, err := config.Load(config.ConfigPath())
+		if err != nil {
+			return fmt.Errorf("load config: %w", err)
+		}
+		d.SetTrust(latest.Trust())
+		return nil
+	})
+	srv.SetHealthReporter(func() ipc.HealthData {
+		return healthData(d.Health(), cfg.Location())
+	})
+	d.SetAttributionListener(func(rec store.AttributionRecord, workType string) {
+		kind := rec.Kind
+		if kind == "" {
+			kind = store.AttributionAddition
+		}
+		srv.PublishAttribution(ipc.AttributionEvent{
+			FilePath:        rec.FilePath,
+			ProjectPath:     rec.ProjectPath,
+			AuthorshipLevel: rec.AuthorshipLevel,
+			WorkType:        workType,
+			Kind:            kind,
+			LinesChanged:    rec.LinesChanged,
+			Timestamp:       formatTime(rec.Timestamp),
+			TimestampLocal:  formatLocal(rec.Timestamp, cfg.Location()),
+		})
+	})
+}
+
+// healthData converts the daemon's health report to its IPC form, with
+// local times in loc.
+func healthData(h daemon.Health, loc *time.Location) ipc.HealthData {
+	data := ipc.HealthData{
+		WatchedDirs:            h.Watcher.Dirs,
+		UnwatchedDirs:          h.Watcher.Failed,
+		EventOverflows:         h.Watcher.Overflows,
+		WatchLimitReached:      h.Watcher.LimitReached,
+		PolledDirs:             h.Watcher.Polled,
+		IdleSessions:           h.IdleSessions,
+		AttributionBacklog:     h.AttributionBacklog,
+		Crashes:                h.Crashes.Crashes,
+		LastCrashAt:            formatTime(h.Crashes.LastCrashAt),
+		LastCrashAtLocal:       formatLocal(h.Crashes.LastCrashAt, loc),
+		LastCrash:              h.Crashes.LastError,
+		LastMaintenanceAt:      formatTime(h.LastMaintenanceAt),
+		LastMaintenanceAtLocal: formatLocal(h.LastMaintenanceAt, loc),
+	}
+	if h.Watcher.Polled > 0 {
+		data.PollInterval = h.Watcher.PollInterval.String()
+	}
+	if h.Trust != nil {
+		data.ProjectTrust = config.ProjectTrustAll
+		if h.Trust.Allowlist() {
+			data.ProjectTrust = config.ProjectTrustAllowlist
+		}
+		data.EnabledProjects = h.Trust.Enabled()
+		data.DisabledProjects = h.Trust.Disabled()
+	}
+	for _, wp := range h.WatchPaths {
+		data.WatchPathStats = append(data.WatchPathStats, ipc.WatchPathStatus{
+			Path:             wp.Path,
+			Events:           wp.Events,
+			LastEventAt:      formatTime(wp.LastEventAt),
+			LastEventAtLocal: formatLocal(wp.LastEventAt, loc),
+			Disabled:         wp.Disabled,
+		})
+	}
+	for _, r := range h.SessionRoots {
+		data.SessionRoots = append(data.SessionRoots, ipc.SessionRootStatus{
+			Path:     r.Path,
+			Exists:   r.Exists,
+			Sessions: r.Sessions,
+		})
+	}
+	for _, sh := range h.Sessions {
+		data.Sessions = append(data.Sessions, ipc.SessionStatus{
+			Path:            sh.Path,
+			SessionID:       sh.SessionID,
+			LagBytes:        sh.LagBytes,
+			LinesParsed:     sh.LinesParsed,
+			LastLineAt:      formatTime(sh.LastLineAt),
+			LastLineAtLocal: formatLocal(sh.LastLineAt, loc),
+		})
+	}
+	for _, g := range h.GitRepos {
+		data.GitRepos = append(data.GitRepos, ipc.GitRepoStatus{
+			Path:            g.Path,
+			LastSyncAt:      formatTime(g.LastSyncAt),
+			LastSyncAtLocal: formatLocal(g.LastSyncAt, loc),
+			LastError:       g.LastError,
+		})
+	}
+	for _, r := range h.UntrackedRepos {
+		data.UntrackedRepos = append(data.UntrackedRepos, ipc.UntrackedRepoStatus{Path: r.Path, Edits: r.Edits})
+	}
+	return data
+}
+
+// formatTime formats t as RFC 3339, or "" for the zero time.
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// formatLocal formats t as RFC 3339 in loc, or "" for the zero time.
+func formatLocal(t time.Time, loc *time.Location) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.In(loc).Format(time.RFC3339)
+}
+
 func stopCmd() *cobra.Command {
 	return &cobra.Command{
 		Use:   "stop",
 		Short: "Stop the gap-map daemon",
 		RunE: func(cmd *cobra.Command, args []string) error {
+			out := cmd.OutOrStdout()
 			cfg, err := config.Load(config.ConfigPath())
 			if err != nil {
 				return fmt.Errorf("load config: %w", err)
@@ -186,7 +465,7 @@ func stopCmd() *cobra.Command {
 			// Remove PID file in case daemon crashes before its own cleanup.
 			_ = os.Remove(filepath.Join(cfg.DataDir, "gapmap.pid"))
 
-			fmt.Println("daemon stopping")
+			fmt.Fprintln(out, "daemon stopping")
 			return nil
 		},
 	}
@@ -196,19 +475,21 @@ func pingCmd() *cobra.Command {
 	return &cobra.Command{
 		Use:   "ping",
 		Short: "Check if daemon is alive",
+		Long: `Check if the daemon is alive. Exits 0 when it answers and 3 when it
+does not, whatever the reason, for health checks.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
+			out := cmd.OutOrStdout()
 			cfg, err := config.Load(config.ConfigPath())
 			if err != nil {
-				return fmt.Errorf("load config: %w", err)
+				return withCode(exitDaemonDown, fmt.Errorf("load config: %w", err))
 			}
 
 			client := ipc.NewClient(cfg.SocketPath)
 			if err := client.Ping(); err != nil {
-				fmt.Println("daemon is not running")
-				return err
+				return withCode(exitDaemonDown, err)
 			}
 
-			fmt.Println("daemon is alive")
+			fmt.Fprintln(out, "daemon is alive")
 			return nil
 		},
 	}
@@ -221,6 +502,7 @@ func statusCmd() *cobra.Command {
 		Use:   "status",
 		Short: "Show daemon status",
 		RunE: func(cmd *cobra.Command, args []string) error {
+			out := cmd.OutOrStdout()
 			cfg, err := config.Load(config.ConfigPath())
 			if err != nil {
 				return fmt.Errorf("load config: %w", err)
@@ -233,9 +515,9 @@ func statusCmd() *cobra.Command {
 			}
 
 			if jsonOutput {
-				fmt.Println(report.FormatJSON(status))
+				fmt.Fprintln(out, report.FormatJSON(status))
 			} else {
-				fmt.Print(report.FormatStatus(status))
+				fmt.Fprint(out, report.FormatStatus(status, outputStyle(cmd)))
 			}
 			return nil
 		},
@@ -253,6 +535,21 @@ func analyzeCmd() *cobra.Command {
 		dbPath     string
 		branch     string
 		baseBranch string
+		fromGit    bool
+		fromNotes  bool
+		coverPath  string
+		accuracy   bool
+		filter     report.Filter
+		explain    bool
+		review     bool
+		since      string
+		until      string
+		heatmap    bool
+		ndjson     bool
+		stack      bool
+		remote     string
+		token      string
+		project    string
 	)
 
 	cmd := &cobra.Command{
@@ -265,59 +562,390 @@ By default, shows a project-level summary. Use --file for single-file detail.
 
 Use --branch and --base to scope the report to a specific branch's changes
 relative to a base branch (e.g. main). This uses git merge-base to compute
-only the lines that changed on the branch.`,
+only the lines that changed on the branch.
+
+Use --stack for stacked PRs: the stack whose top is --branch (default:
+the current branch) is discovered from Graphite's branch metadata, else
+by chaining local branches by merge-base down to --base, and each layer
+is reported against the layer below it, followed by the full stack
+against the base.
+
+Use --from-git where the daemon never ran (e.g. CI): attribution is derived
+from commit metadata between --base and HEAD -- Co-Authored-By tags,
+"Generated with Claude Code" footers, and AI-Assisted: trailers. This is
+also the fallback when no database exists.
+
+Use --from-notes to read the attribution ` + "`gapmap annotate`" + ` stored in git notes
+(refs/notes/gapmap) on the nearest annotated commit. Where no database
+exists, notes are preferred over commit metadata when there are any.
+
+Use --coverage with a Go cover profile (go test -coverprofile) or an LCOV
+tracefile to add a Test Coverage section: the changed lines the profile
+instruments are split into covered and uncovered, with the AI share of
+each, answering whether AI-written code is exercised by tests. It applies
+to the full project report only.
+
+Use --accuracy to add an Attribution Accuracy section comparing the lines
+labeled with ` + "`gapmap label`" + ` against the attribution computed for them:
+agreement, precision and recall, with AI as the positive class. It applies
+to the full project report only.
+
+The full project report can be filtered where it is generated, so JSON
+consumers need not post-process the whole project: --work-type and
+--path-glob (relative to the project root, e.g. "internal/**/*.go") select
+the files the report covers, totals included; --min-ai-pct, --sort
+(ai_pct, lines or events) and --top N only trim and order the file list.
+
+Use --since and --until to report a time window, e.g. this sprint's AI%:
+only attributions and AI edits made in the window count, and each file's
+changed lines are diffed from the last commit before --since (to the last
+commit before --until, or the working tree). Both take an RFC 3339 time,
+a date (YYYY-MM-DD, in display_timezone) or a duration back from now
+such as 14d, 2w or 36h.
+
+Use --heatmap to see when AI usage peaks: a punch card of AI-authored
+lines by weekday and hour (in display_timezone), and a timeline of the
+--top files (default 10) by day, week or month, depending on the span.
+It honors --since and --until; --json gives the counts.
+
+Use --ndjson for very large repositories: the full project report is
+streamed as newline-delimited JSON, one {"type":"file",...} line per file
+in path order as soon as it is ready, then a {"type":"summary",...} line
+with the totals, so memory stays bounded however many files there are.
+It takes --work-type, --path-glob, --min-ai-pct, --since and --until, but
+not --sort or --top, which need the whole file list.
+
+Use --explain with --file to show why the AI wrote what it did: the user
+prompts behind the file's AI edits, redacted and truncated, with when
+each was made and how many lines it changed.
+
+Use --remote host:port to fetch the report from the API of a daemon with
+api_addr set, e.g. from CI or a teammate's machine, instead of opening
+the database. The token is --token or $GAPMAP_API_TOKEN. It takes --file
+(relative to the project root), --branch, --base, --review, --since,
+--until and the file filters; --since and --until are resolved locally.
+--project picks the daemon's project, by its path on the daemon's host;
+without it the daemon reports the first project it has. --base defaults
+to that project's base_branch.
+
+Use --review to add a checklist of the files reviewers should scrutinize
+first, ranked by risk: the file's AI share, weighted by its work type
+(architecture and core logic most), and raised when its AI edits have
+since been rewritten or, with --coverage, when its AI lines are untested.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
+			out := cmd.OutOrStdout()
+			cfg, err := config.Load(config.ConfigPath())
+			if err != nil {
+				return fmt.Errorf("load config: %w", err)
+			}
 			// Resolve DB path: flag > config default.
 			if dbPath == "" {
-				cfg, err := config.Load(config.ConfigPath())
+				dbPath = cfg.DBPath
+			}
+			scorer, err := metrics.NewScorer(cfg.Scorer, cfg.ScorerWeights)
+			if err != nil {
+				return fmt.Errorf("load scorer: %w", err)
+			}
+
+			if branch != "" && baseBranch == "" && !stack && remote == "" {
+				if baseBranch, err = defaultBaseBranch(); err != nil {
+					return err
+				}
+			}
+			if stack {
+				if fromGit || fromNotes || filePath != "" || heatmap || ndjson || coverPath != "" || accuracy || since != "" || until != "" || filter != (report.Filter{}) {
+					return fmt.Errorf("--stack reports the branches of a stack from the database; it cannot be combined with --file, --from-git, --from-notes, --heatmap, --ndjson, --coverage, --accuracy, --since, --until or the file filters")
+				}
+				if branch == "" {
+					branch, _ = gitint.CurrentBranch(".")
+				}
+			}
+
+			if remote != "" {
+				if fromGit || fromNotes || stack || heatmap || ndjson || coverPath != "" || accuracy || explain {
+					return fmt.Errorf("--remote fetches a project, branch or file report from a daemon; it cannot be combined with --from-git, --from-notes, --stack, --heatmap, --ndjson, --coverage, --accuracy or --explain")
+				}
+				if token == "" {
+					token = os.Getenv(api.EnvToken)
+				}
+				if token == "" {
+					return fmt.Errorf("--remote needs the daemon's api_token: pass --token or set $%s", api.EnvToken)
+				}
+			} else if project != "" {
+				return fmt.Errorf("--project picks the project of a daemon; it needs --remote")
+			}
+
+			if !fromGit && !fromNotes && filePath == "" && !heatmap && !ndjson && !stack && remote == "" {
+				if useNotesFallback(dbPath) {
+					fromNotes = true
+					exitStatus = exitPartialData
+				} else if useGitFallback(dbPath) {
+					fromGit = true
+					exitStatus = exitPartialData
+				}
+			}
+
+			if explain && filePath == "" {
+				return fmt.Errorf("--explain needs --file")
+			}
+			if review && filePath != "" {
+				return fmt.Errorf("--review ranks the files of a project report; it cannot be combined with --file")
+			}
+
+			var window report.Window
+			if since != "" || until != "" {
+				if fromGit || fromNotes || branch != "" {
+					return fmt.Errorf("--since and --until need the report from the database; they cannot be combined with --branch, --from-git or --from-notes")
+				}
+				now := time.Now()
+				if since != "" {
+					if window.Since, err = report.ParseWindowBound(since, now, cfg.Location()); err != nil {
+						return fmt.Errorf("--since: %w", err)
+					}
+				}
+				if until != "" {
+					if window.Until, err = report.ParseWindowBound(until, now, cfg.Location()); err != nil {
+						return fmt.Errorf("--until: %w", err)
+					}
+				}
+				if err := window.Validate(); err != nil {
+					return err
+				}
+			}
+
+			if filter != (report.Filter{}) {
+				if fromGit || fromNotes || filePath != "" || branch != "" {
+					return fmt.Errorf("--top, --min-ai-pct, --work-type, --path-glob and --sort apply to the full project report; they cannot be combined with --file, --branch, --from-git or --from-notes")
+				}
+				if err := filter.Validate(); err != nil {
+					return err
+				}
+			}
+
+			var profile *coverage.Profile
+			if coverPath != "" {
+				if fromGit || fromNotes || filePath != "" || branch != "" {
+					return fmt.Errorf("--coverage needs the full project report from the database; it cannot be combined with --file, --branch, --from-git or --from-notes")
+				}
+				profile, err = coverage.Load(coverPath)
 				if err != nil {
-					return fmt.Errorf("load config: %w", err)
+					return err
 				}
-				dbPath = cfg.DBPath
 			}
 
-			if branch != "" && baseBranch == "" {
-				baseBranch = "main"
+			if accuracy && (fromGit || fromNotes || filePath != "" || branch != "") {
+				return fmt.Errorf("--accuracy needs the full project report from the database; it cannot be combined with --file, --branch, --from-git or --from-notes")
 			}
 
-			if filePath != "" {
+			if ndjson {
+				if jsonOutput || fromGit || fromNotes || filePath != "" || branch != "" || heatmap || coverPath != "" || accuracy || review || explain {
+					return fmt.Errorf("--ndjson streams the full project report from the database; it cannot be combined with --json, --file, --branch, --from-git, --from-notes, --heatmap, --coverage, --accuracy, --review or --explain")
+				}
+				if filter.Sort != "" || filter.Top > 0 {
+					return fmt.Errorf("--ndjson lists files in path order as they are ready; it cannot be combined with --sort or --top")
+				}
+				s, err := openStore(dbPath)
+				if err != nil {
+					return fmt.Errorf("open store: %w", err)
+				}
+				defer s.Close()
+
+				buf := bufio.NewWriter(out)
+				w := report.NewNDJSONWriter(buf)
+				filter.Window = window
+				pr, err := report.StreamProject(cmd.Context(), s, scorer, filter, reportOptions(cfg), func(fr report.FileReport) error {
+					if err := w.File(fr); err != nil {
+						return err
+					}
+					return buf.Flush()
+				})
+				if err != nil {
+					return fmt.Errorf("stream project report: %w", err)
+				}
+				if err := w.Summary(pr); err != nil {
+					return err
+				}
+				return buf.Flush()
+			}
+
+			if heatmap {
+				if fromGit || fromNotes || filePath != "" || branch != "" || coverPath != "" || accuracy || review || explain {
+					return fmt.Errorf("--heatmap reads attributions from the database; it cannot be combined with --file, --branch, --from-git, --from-notes, --coverage, --accuracy, --review or --explain")
+				}
+				if filter != (report.Filter{Top: filter.Top}) {
+					return fmt.Errorf("--heatmap takes --top to limit the files listed; other filters do not apply to it")
+				}
+				s, err := openStore(dbPath)
+				if err != nil {
+					return fmt.Errorf("open store: %w", err)
+				}
+				defer s.Close()
+
+				top := filter.Top
+				if top == 0 {
+					top = 10
+				}
+				h, err := report.GenerateHeatmap(s, cfg.Location(), window, top)
+				if err != nil {
+					return fmt.Errorf("generate heatmap: %w", err)
+				}
+				if jsonOutput {
+					fmt.Fprintln(out, report.FormatJSON(h))
+				} else {
+					fmt.Fprint(out, report.FormatHeatmap(h, outputStyle(cmd)))
+				}
+				return nil
+			}
+
+			if remote != "" {
+				// Analysis by a remote daemon.
+				filter.Window = window
+				q := api.Query{File: filePath, Branch: branch, Base: baseBranch, Project: project, Review: review, Filter: filter}
+				if err := analyzeRemote(cmd, remote, token, q, jsonOutput); err != nil {
+					return err
+				}
+			} else if fromNotes {
+				pr, commit, err := report.GenerateProjectFromNotes(".", "HEAD", scorer, reportOptions(cfg))
+				if err != nil {
+					return fmt.Errorf("generate notes report: %w", err)
+				}
+				fmt.Fprintf(os.Stderr, "attribution from the git note on %s\n", commit[:7])
+				if review {
+					report.ApplyReview(pr, nil)
+				}
+				if jsonOutput {
+					fmt.Fprintln(out, report.FormatJSON(pr))
+				} else {
+					fmt.Fprint(out, report.FormatProjectReport(pr, outputStyle(cmd)))
+				}
+			} else if fromGit {
+				if baseBranch == "" {
+					if baseBranch, err = defaultBaseBranch(); err != nil {
+						return err
+					}
+				}
+				pr, err := report.GenerateProjectFromGit(".", baseBranch, scorer, reportOptions(cfg))
+				if err != nil {
+					return fmt.Errorf("generate git report: %w", err)
+				}
+				if review {
+					report.ApplyReview(pr, nil)
+				}
+				if jsonOutput {
+					fmt.Fprintln(out, report.FormatJSON(pr))
+				} else {
+					fmt.Fprint(out, report.FormatProjectReport(pr, outputStyle(cmd)))
+				}
+			} else if filePath != "" {
 				// Single file analysis.
-				fr, err := report.GenerateFile(dbPath, filePath)
+				s, err := openStore(dbPath)
+				if err != nil {
+					return fmt.Errorf("open store: %w", err)
+				}
+				defer s.Close()
+
+				fr, err := report.GenerateFileWindow(s, filePath, window, reportOptions(cfg))
 				if err != nil {
 					return fmt.Errorf("generate file report: %w", err)
 				}
+				if explain {
+					if err := report.Explain(s, fr, cfg.Location()); err != nil {
+						return fmt.Errorf("explain: %w", err)
+					}
+				}
 				if jsonOutput {
-					fmt.Println(report.FormatJSON(fr))
+					fmt.Fprintln(out, report.FormatJSON(fr))
 				} else {
-					fmt.Print(report.FormatFileReport(fr))
+					fmt.Fprint(out, report.FormatFileReport(fr, outputStyle(cmd)))
+					if explain {
+						fmt.Fprint(out, report.FormatExplanations(fr.Explanations, cfg.Location(), outputStyle(cmd)))
+					}
+				}
+			} else if stack {
+				// Per-layer and full-stack analysis.
+				s, err := openStore(dbPath)
+				if err != nil {
+					return fmt.Errorf("open store: %w", err)
+				}
+				defer s.Close()
+
+				st, err := gitint.DiscoverStack(".", branch, baseBranch)
+				if err != nil {
+					return fmt.Errorf("discover stack: %w", err)
+				}
+				sr, err := report.GenerateStack(s, st, reportOptions(cfg))
+				if err != nil {
+					return fmt.Errorf("generate stack report: %w", err)
+				}
+				if review {
+					for _, l := range sr.Layers {
+						if err := applyReview(s, l.Report, cfg); err != nil {
+							return err
+						}
+					}
+					if err := applyReview(s, sr.Rollup, cfg); err != nil {
+						return err
+					}
+				}
+				if jsonOutput {
+					fmt.Fprintln(out, report.FormatJSON(sr))
+				} else {
+					fmt.Fprint(out, report.FormatStack(sr, outputStyle(cmd)))
 				}
 			} else if branch != "" {
 				// Branch-scoped analysis.
-				s, err := store.New(dbPath)
+				s, err := openStore(dbPath)
 				if err != nil {
 					return fmt.Errorf("open store: %w", err)
 				}
 				defer s.Close()
 
-				pr, err := report.GenerateProjectForBranch(s, branch, baseBranch)
+				pr, err := report.GenerateProjectForBranch(s, branch, baseBranch, reportOptions(cfg))
 				if err != nil {
 					return fmt.Errorf("generate branch report: %w", err)
 				}
+				if review {
+					if err := applyReview(s, pr, cfg); err != nil {
+						return err
+					}
+				}
 				if jsonOutput {
-					fmt.Println(report.FormatJSON(pr))
+					fmt.Fprintln(out, report.FormatJSON(pr))
 				} else {
-					fmt.Print(report.FormatProjectReport(pr))
+					fmt.Fprint(out, report.FormatProjectReport(pr, outputStyle(cmd)))
 				}
 			} else {
 				// Full project analysis.
-				pr, err := report.GenerateProject(dbPath)
+				s, err := openStore(dbPath)
+				if err != nil {
+					return fmt.Errorf("open store: %w", err)
+				}
+				defer s.Close()
+
+				filter.Window = window
+				pr, err := report.GenerateProject(cmd.Context(), s, scorer, filter, reportOptions(cfg))
 				if err != nil {
 					return fmt.Errorf("generate project report: %w", err)
 				}
+				if profile != nil {
+					if err := report.ApplyCoverage(cmd.Context(), s, pr, profile, reportOptions(cfg)); err != nil {
+						return fmt.Errorf("apply coverage: %w", err)
+					}
+				}
+				if accuracy {
+					if err := report.ApplyAccuracy(cmd.Context(), s, pr, reportOptions(cfg)); err != nil {
+						return fmt.Errorf("apply accuracy: %w", err)
+					}
+				}
+				if review {
+					if err := applyReview(s, pr, cfg); err != nil {
+						return err
+					}
+				}
 				if jsonOutput {
-					fmt.Println(report.FormatJSON(pr))
+					fmt.Fprintln(out, report.FormatJSON(pr))
 				} else {
-					fmt.Print(report.FormatProjectReport(pr))
+					fmt.Fprint(out, report.FormatProjectReport(pr, outputStyle(cmd)))
 				}
 			}
 
@@ -329,19 +957,115 @@ only the lines that changed on the branch.`,
 	cmd.Flags().BoolVar(&jsonOutput, "json", false, "Output as JSON")
 	cmd.Flags().StringVar(&dbPath, "db", "", "Override database path (default: from config)")
 	cmd.Flags().StringVar(&branch, "branch", "", "Scope report to a specific branch")
-	cmd.Flags().StringVar(&baseBranch, "base", "", "Base branch for comparison (default: main)")
+	cmd.Flags().StringVar(&baseBranch, "base", "", "Base branch for comparison (default: base_branch in .gapmap.toml, else main)")
+	cmd.Flags().BoolVar(&stack, "stack", false, "Report each layer of the stack topped by --branch, and the full stack")
+	cmd.Flags().BoolVar(&fromGit, "from-git", false, "Derive attribution from commit metadata only (no database)")
+	cmd.Flags().BoolVar(&fromNotes, "from-notes", false, "Read attribution from git notes written by gapmap annotate (no database)")
+	cmd.Flags().BoolVar(&accuracy, "accuracy", false, "Compare attribution with lines labeled by gapmap label")
+	cmd.Flags().StringVar(&coverPath, "coverage", "", "Split attribution by test coverage from a Go cover profile or LCOV file")
+	cmd.Flags().IntVar(&filter.Top, "top", 0, "List only the first N files")
+	cmd.Flags().Float64Var(&filter.MinAIPct, "min-ai-pct", 0, "List only files with at least this meaningful AI%")
+	cmd.Flags().StringVar(&filter.WorkType, "work-type", "", "Report only files of this work type")
+	cmd.Flags().StringVar(&filter.PathGlob, "path-glob", "", "Report only files matching this glob, relative to the project root")
+	cmd.Flags().StringVar(&filter.Sort, "sort", "", "Order files by ai_pct (default), lines or events")
+	cmd.Flags().BoolVar(&explain, "explain", false, "With --file, show the user prompts behind the file's AI edits")
+	cmd.Flags().BoolVar(&review, "review", false, "Add a checklist of files to review, ranked by risk")
+	cmd.Flags().StringVar(&since, "since", "", "Report only AI edits and attributions from this time on (RFC 3339, YYYY-MM-DD or a duration like 14d)")
+	cmd.Flags().StringVar(&until, "until", "", "Report only AI edits and attributions before this time")
+	cmd.Flags().BoolVar(&heatmap, "heatmap", false, "Show AI-authored lines by weekday and hour, and per file over time")
+	cmd.Flags().BoolVar(&ndjson, "ndjson", false, "Stream the project report as newline-delimited JSON, one line per file then a summary")
+	cmd.Flags().StringVar(&remote, "remote", "", "Fetch the report from the API of the daemon at host:port instead of the database")
+	cmd.Flags().StringVar(&token, "token", "", "API token for --remote (default: $GAPMAP_API_TOKEN)")
+	cmd.Flags().StringVar(&project, "project", "", "With --remote, the daemon's project to report (default: its first)")
 
 	return cmd
 }
 
+// applyReview ranks pr's files for review, using the survival of their AI
+// edits recorded in s.
+func applyReview(s *store.Store, pr *report.ProjectReport, cfg *config.Config) error {
+	sr, err := survival.Analyze(s, pr.ProjectPath, cfg.CorrectionWindow())
+	if err != nil {
+		return fmt.Errorf("analyze survival: %w", err)
+	}
+	rates := make(map[string]float64, len(sr.ByFile))
+	for path, bd := range sr.ByFile {
+		rates[path] = bd.Rate
+	}
+	report.ApplyReview(pr, rates)
+	return nil
+}
+
+// useGitFallback reports whether dbPath does not exist, in which case
+// reports fall back to commit-metadata attribution (e.g. on CI runners).
+func useGitFallback(dbPath string) bool {
+	if _, err := os.Stat(dbPath); os.IsNotExist(err) {
+		fmt.Fprintf(os.Stderr, "no database at %s; deriving attribution from git commit metadata\n", dbPath)
+		return true
+	}
+	return false
+}
+
+// useNotesFallback reports whether dbPath does not exist and the current
+// repository has gap-map notes to read attribution from instead.
+func useNotesFallback(dbPath string) bool {
+	if _, err := os.Stat(dbPath); !os.IsNotExist(err) {
+		return false
+	}
+	commit, _, err := gitint.LatestNote(".", "HEAD")
+	if err != nil || commit == "" {
+		return false
+	}
+	fmt.Fprintf(os.Stderr, "no database at %s; reading attribution from git notes\n", dbPath)
+	return true
+}
+
+// reportOptions returns the report settings cfg configures.
+func reportOptions(cfg *config.Config) report.Options {
+	return report.Options{
+		LineMatch:        cfg.MatchOptions,
+		Packages:         cfg.Packages,
+		Guard:            cfg.FileGuard(),
+		DiffAlgorithm:    cfg.DiffAlgorithm,
+		CorrectionWindow: cfg.CorrectionWindow(),
+		Git:              gitExecutor(cfg),
+	}
+}
+
+// defaultBaseBranch returns the base_branch of the current repository's
+// .gapmap.toml, or main when it sets none.
+func defaultBaseBranch() (string, error) {
+	return projectBaseBranch(".")
+}
+
+// projectBaseBranch returns the base_branch of the repository at path, or
+// main.
+func projectBaseBranch(path string) (string, error) {
+	repo, err := config.FindRepoSettings(path)
+	if err != nil {
+		return "", err
+	}
+	if repo != nil && repo.BaseBranch != "" {
+		return repo.BaseBranch, nil
+	}
+	return "main", nil
+}
+
 func prCommentCmd() *cobra.Command {
 	var (
-		token  string
-		pr     int
-		owner  string
-		repo   string
-		dbPath string
-		dryRun bool
+		token      string
+		pr         int
+		owner      string
+		repo       string
+		dbPath     string
+		dryRun     bool
+		fromGit    bool
+		branch     string
+		baseBranch string
+		review     bool
+		verbose    bool
+		remote     string
+		stack      bool
 	)
 
 	cmd := &cobra.Command{
@@ -349,76 +1073,203 @@ func prCommentCmd() *cobra.Command {
 		Short: "Post a collaboration summary comment to a GitHub PR",
 		Long: `Generate and post a collaboration summary as a comment on a GitHub PR.
 
+The comment covers only the PR's changes: the report is scoped to the PR
+branch relative to its base branch. Both are auto-detected from
+GITHUB_HEAD_REF/GITHUB_BASE_REF or the GitHub API; use --branch and --base
+to override.
+
 The comment includes authorship breakdown by work type, insight callouts,
 and per-file collaboration patterns for notable files.
 
-Use --dry-run to preview the Markdown without posting.`,
+Use --dry-run to preview the Markdown without posting.
+
+Use --from-git on CI runners without a local database: attribution is
+derived from commit metadata between --base and HEAD. This is also the
+fallback when no database exists.
+
+Use --review to add a checklist of the files reviewers should scrutinize
+first (see analyze --review).
+
+Use --stack for stacked PRs: the stack topped by --branch (default: the
+current branch) is discovered as in analyze --stack, down to --base, and
+the open PR of each layer's branch gets its own comment, covering that
+layer against the one below it, with a table placing it in the stack
+next to the full-stack totals. Layers without an open PR are skipped.
+
+To spare small and human-only PRs, --skip-drafts posts nothing on draft
+PRs, --min-ai-pct posts only when meaningful AI% reaches a threshold, and
+--collapse-over folds long comments into a <details> block. They default
+to the pr_comment_ config settings.
+
+Use --annotate to also add inline review comments on the hunks that are at
+least 90% AI-written in core_logic files, largest first, up to
+--max-annotations (default 10). They need the database; --dry-run lists
+them. They default to the pr_annotate and pr_max_annotations settings.
+
+Without --pr, the PR number is detected from, in order: GITHUB_PR_NUMBER;
+the GitHub Actions event; CircleCI's CIRCLE_PR_NUMBER and
+CIRCLE_PULL_REQUEST; Buildkite's BUILDKITE_PULL_REQUEST; Jenkins' CHANGE_ID
+and ghprbPullId; the open PR for the CI's branch (CIRCLE_BRANCH,
+BUILDKITE_BRANCH, BRANCH_NAME or GIT_BRANCH) via the GitHub API; and
+"gh pr view". --verbose prints which source was used.
+
+Without --owner and --repo, the repository is taken from the git remote
+named by --remote (or the github_remote setting), else "upstream", else
+"origin", else the first other remote on GitHub, so that in a fork
+workflow the PR's repository is found rather than the fork. SSH remotes
+may use a host alias from ~/.ssh/config.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
+			out := cmd.OutOrStdout()
+			cfg, err := config.Load(config.ConfigPath())
+			if err != nil {
+				return fmt.Errorf("load config: %w", err)
+			}
 			// Resolve DB path.
 			if dbPath == "" {
-				cfg, err := config.Load(config.ConfigPath())
-				if err != nil {
-					return fmt.Errorf("load config: %w", err)
-				}
 				dbPath = cfg.DBPath
 			}
-
-			// Generate the project report.
-			projectReport, err := report.GenerateProject(dbPath)
+			scorer, err := metrics.NewScorer(cfg.Scorer, cfg.ScorerWeights)
 			if err != nil {
-				return fmt.Errorf("generate project report: %w", err)
+				return fmt.Errorf("load scorer: %w", err)
 			}
-
-			// Generate the comment body.
-			body := ghub.GenerateComment(projectReport)
-
-			// Dry run: print and exit.
-			if dryRun {
-				fmt.Println(body)
-				return nil
-			}
-
 			// Resolve token.
 			if token == "" {
 				token = os.Getenv("GITHUB_TOKEN")
 			}
-			if token == "" {
-				return fmt.Errorf("GitHub token required: set --token flag or GITHUB_TOKEN env var")
-			}
+			client := ghub.NewClient(token, ghub.ConfigClientOptions(cfg))
 
 			// Auto-detect owner/repo from git remote if not provided.
+			// Detection failures only matter when actually posting.
+			var detectErr error
 			if owner == "" || repo == "" {
-				remoteURL, err := detectRemoteURL()
-				if err != nil {
-					return fmt.Errorf("auto-detect remote (set --owner and --repo flags): %w", err)
+				if remote == "" {
+					remote = cfg.GitHubRemote
 				}
-				detectedOwner, detectedRepo, err := ghub.ParseGitHubRemote(remoteURL)
+				detected, err := ghub.DetectRemote(".", remote)
 				if err != nil {
-					return fmt.Errorf("parse remote URL %q: %w", remoteURL, err)
+					detectErr = fmt.Errorf("auto-detect remote (set --owner and --repo flags): %w", err)
+				} else {
+					if owner == "" {
+						owner = detected.Owner
+					}
+					if repo == "" {
+						repo = detected.Repo
+					}
+					if verbose {
+						fmt.Fprintf(os.Stderr, "repository %s/%s from remote %s\n", detected.Owner, detected.Repo, detected.Name)
+					}
 				}
-				if owner == "" {
-					owner = detectedOwner
+			}
+
+			if stack {
+				if fromGit || pr != 0 {
+					return fmt.Errorf("--stack finds each layer's PR from the database and its branch; it cannot be combined with --from-git or --pr")
 				}
-				if repo == "" {
-					repo = detectedRepo
+				if branch == "" {
+					branch, _ = gitint.CurrentBranch(".")
 				}
+				return postStackComments(cmd, cfg, dbPath, branch, baseBranch, review, dryRun, owner, repo, token, detectErr)
 			}
 
 			// Auto-detect PR number if not provided.
-			if pr == 0 {
-				detected, err := ghub.DetectPRNumber()
+			if pr == 0 && detectErr == nil {
+				detected, err := client.DetectPRNumber(owner, repo)
+				if err != nil {
+					detectErr = fmt.Errorf("auto-detect PR number: %w", err)
+				} else {
+					pr = detected.Number
+					if verbose {
+						fmt.Fprintln(os.Stderr, detected)
+					}
+				}
+			}
+
+			// Resolve the PR's head and base branches.
+			if branch == "" || baseBranch == "" {
+				detected := client.DetectPRBranches(owner, repo, pr)
+				if branch == "" {
+					branch = detected.Head
+				}
+				if baseBranch == "" {
+					baseBranch = detected.Base
+				}
+			}
+			if branch == "" {
+				branch, _ = gitint.CurrentBranch(".")
+			}
+			if baseBranch == "" {
+				if baseBranch, err = defaultBaseBranch(); err != nil {
+					return err
+				}
+			}
+
+			// Generate the branch-scoped report.
+			var projectReport *report.ProjectReport
+			if fromGit || useGitFallback(dbPath) {
+				projectReport, err = report.GenerateProjectFromGit(".", baseBranch, scorer, reportOptions(cfg))
+				if err != nil {
+					return fmt.Errorf("generate git report: %w", err)
+				}
+				if review {
+					report.ApplyReview(projectReport, nil)
+				}
+			} else {
+				s, err := openStore(dbPath)
+				if err != nil {
+					return fmt.Errorf("open store: %w", err)
+				}
+				defer s.Close()
+				projectReport, err = report.GenerateProjectForBranch(s, branch, baseBranch, reportOptions(cfg))
 				if err != nil {
-					return fmt.Errorf("auto-detect PR number: %w", err)
+					return fmt.Errorf("generate report for %s against %s: %w", branch, baseBranch, err)
 				}
-				pr = detected
+				if review {
+					if err := applyReview(s, projectReport, cfg); err != nil {
+						return err
+					}
+				}
+			}
+
+			// Generate the comment body.
+			policy, err := commentPolicy(cmd, cfg)
+			if err != nil {
+				return err
+			}
+			body := policy.Apply(policy.Comment(projectReport))
+			skip := policy.SkipReason(projectReport, prIsDraft(policy, owner, repo, pr, client))
+
+			// Dry run: print and exit.
+			if dryRun {
+				if skip != "" {
+					fmt.Fprintf(os.Stderr, "Would not comment: %s\n", skip)
+				}
+				fmt.Fprintln(out, body)
+				if max := annotationLimit(cmd, cfg); max > 0 {
+					return annotatePR(out, dbPath, projectReport, branch, baseBranch, max, true, owner, repo, pr, client, reportOptions(cfg))
+				}
+				return nil
+			}
+			if skip != "" {
+				fmt.Fprintf(out, "No comment posted: %s\n", skip)
+				return nil
+			}
+
+			if token == "" {
+				return fmt.Errorf("GitHub token required: set --token flag or GITHUB_TOKEN env var")
+			}
+			if detectErr != nil {
+				return detectErr
 			}
 
 			// Post the comment.
-			if err := ghub.PostComment(owner, repo, pr, body, token); err != nil {
+			if err := client.PostComment(owner, repo, pr, body); err != nil {
 				return fmt.Errorf("post comment: %w", err)
 			}
 
-			fmt.Printf("Comment posted to PR #%d\n", pr)
+			fmt.Fprintf(out, "Comment posted to PR #%d\n", pr)
+			if max := annotationLimit(cmd, cfg); max > 0 {
+				return annotatePR(out, dbPath, projectReport, branch, baseBranch, max, false, owner, repo, pr, client, reportOptions(cfg))
+			}
 			return nil
 		},
 	}
@@ -429,6 +1280,15 @@ Use --dry-run to preview the Markdown without posting.`,
 	cmd.Flags().StringVar(&repo, "repo", "", "Repository name (default: auto-detect from git remote)")
 	cmd.Flags().StringVar(&dbPath, "db", "", "Override database path (default: from config)")
 	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Print comment body without posting")
+	cmd.Flags().BoolVar(&fromGit, "from-git", false, "Derive attribution from commit metadata only (no database)")
+	cmd.Flags().StringVar(&branch, "branch", "", "PR branch (default: auto-detect, then current branch)")
+	cmd.Flags().StringVar(&baseBranch, "base", "", "PR base branch (default: auto-detect, then base_branch in .gapmap.toml, then main)")
+	cmd.Flags().BoolVar(&stack, "stack", false, "Comment on the PR of each layer of the stack topped by --branch")
+	cmd.Flags().BoolVar(&review, "review", false, "Add a checklist of files to review, ranked by risk")
+	cmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "Explain where the repository and PR number came from")
+	cmd.Flags().StringVar(&remote, "remote", "", "Git remote of the GitHub repository (default: github_remote, then upstream, then origin)")
+	addCommentPolicyFlags(cmd)
+	addAnnotationFlags(cmd)
 
 	return cmd
 }
@@ -437,6 +1297,9 @@ func survivalCmd() *cobra.Command {
 	var (
 		dbPath     string
 		jsonOutput bool
+		format     string
+		live       bool
+		history    bool
 	)
 
 	cmd := &cobra.Command{
@@ -445,19 +1308,41 @@ func survivalCmd() *cobra.Command {
 		Long: `Analyze how much AI-written code survives across subsequent commits.
 
 Compares AI attributions against current git blame data to measure
-code persistence by authorship level and work type.`,
+code persistence by authorship level and work type.
+
+When the AI client records token usage (Claude Code does), a Cost section
+relates the tokens the project's sessions spent to the AI lines that
+survived. Set token_prices in the config to see it in USD.
+
+The daemon checks survival every survival_interval and records the
+result. survival shows the latest recorded check, computing it now only
+when none was recorded or with --live. --history shows the survival rate
+at each recorded check instead, to see AI code decay over time.
+
+--format selects table (the default), json or markdown output; --json is
+short for --format json.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
+			out := cmd.OutOrStdout()
+			if jsonOutput {
+				format = "json"
+			}
+			switch format {
+			case "table", "json", "markdown":
+			default:
+				return fmt.Errorf("unknown --format %q (want table, json or markdown)", format)
+			}
+
+			cfg, err := config.Load(config.ConfigPath())
+			if err != nil {
+				return fmt.Errorf("load config: %w", err)
+			}
 			// Resolve DB path.
 			if dbPath == "" {
-				cfg, err := config.Load(config.ConfigPath())
-				if err != nil {
-					return fmt.Errorf("load config: %w", err)
-				}
 				dbPath = cfg.DBPath
 			}
 
 			// Open store and discover project path.
-			s, err := store.New(dbPath)
+			s, err := openStore(dbPath)
 			if err != nil {
 				return fmt.Errorf("open store: %w", err)
 			}
@@ -468,72 +1353,72 @@ code persistence by authorship level and work type.`,
 				return fmt.Errorf("discover project: %w", err)
 			}
 
-			// Run survival analysis.
-			sr, err := survival.Analyze(s, projectPath)
-			if err != nil {
-				return fmt.Errorf("survival analysis: %w", err)
+			if history {
+				points, err := s.QuerySurvivalHistory(projectPath)
+				if err != nil {
+					return fmt.Errorf("query survival history: %w", err)
+				}
+				switch format {
+				case "table":
+					fmt.Fprint(out, report.FormatSurvivalHistory(points, cfg.Location(), outputStyle(cmd)))
+				case "json":
+					fmt.Fprintln(out, report.FormatJSON(points))
+				case "markdown":
+					fmt.Fprint(out, report.FormatSurvivalHistoryMarkdown(points, cfg.Location()))
+				}
+				return nil
 			}
 
-			if jsonOutput {
-				fmt.Println(report.FormatJSON(sr))
-			} else {
-				// Convert to the github package's type for formatting.
-				formatted := &ghub.SurvivalReport{
-					TotalTracked:  sr.TotalTracked,
-					SurvivedCount: sr.SurvivedCount,
-					SurvivalRate:  sr.SurvivalRate,
-					ByAuthorship:  make(map[string]ghub.SurvivalBreakdown),
-					ByWorkType:    make(map[string]ghub.SurvivalBreakdown),
-				}
-				for k, v := range sr.ByAuthorship {
-					formatted.ByAuthorship[k] = ghub.SurvivalBreakdown{
-						Tracked: v.Tracked, Survived: v.Survived, Rate: v.Rate,
-					}
+			// Read the daemon's latest check, or run survival analysis.
+			var sr *survival.SurvivalReport
+			if !live {
+				if sr, err = survival.Latest(s, projectPath, cfg.CorrectionWindow()); err != nil {
+					return fmt.Errorf("read survival: %w", err)
 				}
-				for k, v := range sr.ByWorkType {
-					formatted.ByWorkType[k] = ghub.SurvivalBreakdown{
-						Tracked: v.Tracked, Survived: v.Survived, Rate: v.Rate,
-					}
+			}
+			if sr == nil {
+				if sr, err = survival.Analyze(s, projectPath, cfg.CorrectionWindow()); err != nil {
+					return fmt.Errorf("survival analysis: %w", err)
 				}
-				fmt.Print(ghub.FormatSurvivalReport(formatted))
+			}
+			sr.Cost, err = survival.AnalyzeCost(s, projectPath, sr, cfg.TokenPrices)
+			if err != nil {
+				return fmt.Errorf("cost analysis: %w", err)
+			}
+
+			switch format {
+			case "table":
+				fmt.Fprint(out, report.FormatSurvivalReport(sr, outputStyle(cmd)))
+			case "json":
+				fmt.Fprintln(out, report.FormatJSON(sr))
+			case "markdown":
+				fmt.Fprint(out, report.FormatSurvivalMarkdown(sr))
 			}
 			return nil
 		},
 	}
 
 	cmd.Flags().StringVar(&dbPath, "db", "", "Override database path (default: from config)")
-	cmd.Flags().BoolVar(&jsonOutput, "json", false, "Output as JSON")
+	cmd.Flags().BoolVar(&jsonOutput, "json", false, "Output as JSON (same as --format json)")
+	cmd.Flags().StringVar(&format, "format", "table", "Output format: table, json or markdown")
+	cmd.Flags().BoolVar(&live, "live", false, "Check survival now instead of reading the daemon's latest check")
+	cmd.Flags().BoolVar(&history, "history", false, "Show the survival rate at each recorded check")
 
 	return cmd
 }
 
 // discoverProjectPath finds the project path from the attributions table.
 func discoverProjectPath(s *store.Store) (string, error) {
-	rows, err := s.DB().Query("SELECT DISTINCT project_path FROM attributions ORDER BY project_path LIMIT 1")
-	if err != nil {
-		return "", fmt.Errorf("discover project path: %w", err)
-	}
-	defer rows.Close()
-
-	if !rows.Next() {
-		return "", fmt.Errorf("no attribution data found in database")
-	}
-
-	var path string
-	if err := rows.Scan(&path); err != nil {
-		return "", fmt.Errorf("scan project path: %w", err)
-	}
-
-	return path, rows.Err()
+	return report.DiscoverProjectPath(s)
 }
 
-func printBanner() {
-	const purple = "\033[38;5;135m"
-	const dim = "\033[38;5;99m"
-	const bold = "\033[1m"
-	const reset = "\033[0m"
+func printBanner(out io.Writer, st report.Style) {
+	purple, dim, bold, reset := "\033[38;5;135m", "\033[38;5;99m", "\033[1m", "\033[0m"
+	if !st.Color {
+		purple, dim, bold, reset = "", "", "", ""
+	}
 
-	fmt.Print(purple + bold + `
+	banner := purple + bold + `
 
    __ _   __ _  _ __   _ __ ___    __ _  _ __
   / _` + "`" + ` | / _` + "`" + ` || '_ \ | '_ ` + "`" + ` _ \  / _` + "`" + ` || '_ \
@@ -542,14 +1427,35 @@ func printBanner() {
   |___/        |_|                       |_|
 ` + reset + dim + `
     Map your knowledge gaps
-` + reset + "\n")
+` + reset + "\n"
+	fmt.Fprint(out, banner)
 }
 
-// detectRemoteURL runs `git remote get-url origin` to get the remote URL.
-func detectRemoteURL() (string, error) {
-	out, err := exec.Command("git", "remote", "get-url", "origin").Output()
-	if err != nil {
-		return "", fmt.Errorf("get git remote URL: %w", err)
+// gitExec is the executor the command's reports run git through, created
+// from config by the first reportOptions call.
+var gitExec struct {
+	sync.Mutex
+	e *gitexec.Executor
+}
+
+// gitExecutor returns the command's git executor, with the
+// git_max_processes and git_cache_mb limits of cfg.
+func gitExecutor(cfg *config.Config) *gitexec.Executor {
+	gitExec.Lock()
+	defer gitExec.Unlock()
+	if gitExec.e == nil {
+		gitExec.e = cfg.GitExecutor()
 	}
-	return strings.TrimSpace(string(out)), nil
+	return gitExec.e
 }
+
+// closeGitExecutor stops the git cat-file processes the command's reports
+// started, if any.
+func closeGitExecutor() {
+	gitExec.Lock()
+	defer gitExec.Unlock()
+	if gitExec.e != nil {
+		gitExec.e.Close()
+	}
+}
+
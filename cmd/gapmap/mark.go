@@ -0,0 +1,117 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/anthropic/gap-map/internal/config"
+	"github.com/anthropic/gap-map/internal/ingest"
+	"github.com/anthropic/gap-map/internal/ipc"
+	"github.com/anthropic/gap-map/internal/watcher"
+)
+
+func markCmd() *cobra.Command {
+	var (
+		file      string
+		lineRange string
+		author    string
+		source    string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "mark",
+		Short: "Record a range of lines as written by AI or a human",
+		Long: `Record lines of a file as written by AI or by a human, for work gap-map
+cannot see, such as code pasted from a web chat:
+
+  gapmap mark --file internal/auth/token.go --range 10-42 --author ai --source chatgpt-web
+
+The lines are taken from the file as it is on disk and recorded like an
+edit the daemon watched, so they count in every report. AI lines belong to
+the session mark/<source>, with the source standing in for the model, so
+by-model breakdowns show where they came from (default source: manual).
+
+The daemon records the mark through its "markLines" IPC method, which
+editors can bind to a hotkey. When it is not running, the mark is written
+to the database and attributed when it next starts.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			_, start, end, err := parseLineRange(file + ":" + lineRange)
+			if err != nil {
+				return fmt.Errorf("invalid --range %q: want <line>[-<line>]", lineRange)
+			}
+			if author != ingest.AuthorAI && author != ingest.AuthorHuman {
+				return fmt.Errorf("--author must be %s or %s, got %q", ingest.AuthorAI, ingest.AuthorHuman, author)
+			}
+			absPath, err := filepath.Abs(file)
+			if err != nil {
+				return fmt.Errorf("resolve path: %w", err)
+			}
+			if _, err := os.Stat(absPath); err != nil {
+				return fmt.Errorf("mark %s: %w", file, err)
+			}
+
+			cfg, err := config.Load(config.ConfigPath())
+			if err != nil {
+				return fmt.Errorf("load config: %w", err)
+			}
+			mark := ipc.MarkLines{FilePath: absPath, StartLine: start, EndLine: end, Author: author, Source: source}
+			res, err := ipc.NewClient(cfg.SocketPath).MarkLines(mark)
+			queued := false
+			if errors.Is(err, ipc.ErrNotRunning) {
+				res, err = markOffline(cfg, mark)
+				queued = true
+			}
+			if err != nil {
+				return err
+			}
+
+			out := cmd.OutOrStdout()
+			fmt.Fprintf(out, "Marked %s:%d-%d (%d lines) as %s", file, start, end, res.Lines, author)
+			if res.SessionID != "" {
+				fmt.Fprintf(out, " in session %s", res.SessionID)
+			}
+			fmt.Fprintln(out)
+			if queued {
+				fmt.Fprintln(out, "The daemon is not running; the lines are attributed when it next starts.")
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&file, "file", "", "File the lines are in (required)")
+	cmd.Flags().StringVar(&lineRange, "range", "", "Lines to mark, e.g. 10-42 or 7 (required)")
+	cmd.Flags().StringVar(&author, "author", ingest.AuthorAI, "Who wrote the lines: ai or human")
+	cmd.Flags().StringVar(&source, "source", "", "Where AI lines came from, e.g. chatgpt-web (default: manual)")
+	_ = cmd.MarkFlagRequired("file")
+	_ = cmd.MarkFlagRequired("range")
+	_ = cmd.RegisterFlagCompletionFunc("author", cobra.FixedCompletions([]string{ingest.AuthorAI, ingest.AuthorHuman}, cobra.ShellCompDirectiveNoFileComp))
+	return cmd
+}
+
+// markOffline records a mark in the database directly, as the daemon's
+// "markLines" does, for when the daemon is not running.
+func markOffline(cfg *config.Config, m ipc.MarkLines) (*ipc.MarkResult, error) {
+	if !cfg.Trust().Allows(m.FilePath) {
+		return nil, fmt.Errorf("%s is in a project gap-map is not enabled for", m.FilePath)
+	}
+	project := watcher.ProjectRoot(cfg.WatchPaths, m.FilePath)
+	if project == m.FilePath {
+		return nil, fmt.Errorf("%s is not under a watch path", m.FilePath)
+	}
+	s, err := openStore(cfg.DBPath)
+	if err != nil {
+		return nil, fmt.Errorf("open store: %w", err)
+	}
+	defer s.Close()
+	res, err := ingest.Mark(s, project, m.FilePath, m.StartLine, m.EndLine, m.Author, m.Source, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("mark %s: %w", m.FilePath, err)
+	}
+	return &ipc.MarkResult{SessionID: res.SessionID, Lines: res.AddedLines}, nil
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/spf13/cobra"
+
+	"github.com/anthropic/gap-map/internal/config"
+	"github.com/anthropic/gap-map/internal/mcp"
+	"github.com/anthropic/gap-map/pkg/gapmap"
+)
+
+func mcpCmd() *cobra.Command {
+	var (
+		dbPath string
+		sse    string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "mcp",
+		Short: "Serve attribution data to AI assistants over MCP",
+		Long: `Run a Model Context Protocol server, so an AI assistant can ask who wrote
+a file while it works.
+
+The server offers the project report (gapmap://project), code survival
+(gapmap://survival) and the report of any tracked file
+(gapmap://file/<absolute path>) as resources, and the tools analyze_file
+and survival. It reads the database the daemon writes, so the daemon
+should be running for the data to be current.
+
+By default the server speaks over stdin and stdout, which is how MCP
+clients start local servers. With --sse it listens on the given address
+instead: clients connect to /sse and post messages to the endpoint it
+names. The SSE server has no authentication, so it only answers requests
+addressed to that address (or another loopback name for its port) and
+rejects browser requests from pages not served from localhost. Listen
+on a loopback address such as localhost:8765.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.Load(config.ConfigPath())
+			if err != nil {
+				return fmt.Errorf("load config: %w", err)
+			}
+			if dbPath == "" {
+				dbPath = cfg.DBPath
+			}
+			maxFileLines := cfg.MaxFileLines
+			if maxFileLines <= 0 {
+				maxFileLines = -1 // no limit, which OpenProject spells as negative
+			}
+
+			p, err := gapmap.OpenProject(dbPath, &gapmap.Options{
+				Scorer:              cfg.Scorer,
+				ScorerWeights:       cfg.ScorerWeights,
+				LineMatch:           cfg.LineMatch,
+				SimilarityThreshold: cfg.SimilarityThreshold,
+				RevisionThreshold:   cfg.RevisionThreshold,
+				ExcludeComments:     cfg.ExcludeComments,
+				DiffAlgorithm:       cfg.DiffAlgorithm,
+				MaxFileLines:        maxFileLines,
+				CapLargeFiles:       cfg.LargeFiles == config.LargeFilesCap,
+				IncludeBinaryFiles:  cfg.BinaryFiles == config.BinaryFilesInclude,
+			})
+			if err != nil {
+				return err
+			}
+			defer p.Close()
+
+			ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+			defer stop()
+			server := mcp.NewServer(p)
+
+			if sse == "" {
+				return server.ServeStdio(ctx, os.Stdin, os.Stdout)
+			}
+
+			srv := &http.Server{Addr: sse, Handler: server.SSEHandler(sse)}
+			go func() {
+				<-ctx.Done()
+				_ = srv.Close()
+			}()
+			fmt.Fprintf(os.Stderr, "MCP server listening on http://%s/sse\n", sse)
+			if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				return fmt.Errorf("serve %s: %w", sse, err)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&dbPath, "db", "", "Override database path (default: from config)")
+	cmd.Flags().StringVar(&sse, "sse", "", "Serve over HTTP with server-sent events on this address (e.g. localhost:8765) instead of stdio")
+
+	return cmd
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/anthropic/gap-map/internal/config"
+	"github.com/anthropic/gap-map/internal/ipc"
+	"github.com/anthropic/gap-map/internal/store"
+)
+
+func migrateCmd() *cobra.Command {
+	var dbPath string
+
+	cmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Inspect and manage database schema migrations",
+		Long: `Inspect and manage database schema migrations.
+
+The daemon applies pending migrations automatically on start. These
+commands let you see where a database stands, apply migrations explicitly,
+or roll back before downgrading gapmap. A backup of the database file is
+written next to it before any migration runs.`,
+	}
+
+	cmd.PersistentFlags().StringVar(&dbPath, "db", "", "Override database path (default: from config)")
+
+	openMigrator := func() (*store.Migrator, string, error) {
+		path := dbPath
+		if path == "" {
+			cfg, err := config.Load(config.ConfigPath())
+			if err != nil {
+				return nil, "", fmt.Errorf("load config: %w", err)
+			}
+			path = cfg.DBPath
+		}
+		m, err := store.OpenMigrator(path)
+		if err != nil {
+			return nil, "", fmt.Errorf("open database: %w", err)
+		}
+		return m, path, nil
+	}
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "status",
+		Short: "Show applied and pending migrations",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			out := cmd.OutOrStdout()
+			m, path, err := openMigrator()
+			if err != nil {
+				return err
+			}
+			defer m.Close()
+
+			current, err := m.Version()
+			if err != nil {
+				return fmt.Errorf("read schema version: %w", err)
+			}
+			statuses, err := m.Status()
+			if err != nil {
+				return fmt.Errorf("read migrations: %w", err)
+			}
+
+			loc := time.Local
+			if cfg, err := config.Load(config.ConfigPath()); err == nil {
+				loc = cfg.Location()
+			}
+
+			fmt.Fprintf(out, "Database: %s\n", path)
+			fmt.Fprintf(out, "Schema:   v%d (latest v%d)\n\n", current, store.LatestVersion())
+
+			pending := 0
+			for _, st := range statuses {
+				switch {
+				case !st.Applied:
+					pending++
+					fmt.Fprintf(out, "  v%-3d pending\n", st.Version)
+				case st.AppliedAt.IsZero():
+					fmt.Fprintf(out, "  v%-3d applied\n", st.Version)
+				default:
+					fmt.Fprintf(out, "  v%-3d applied  %s\n", st.Version, st.AppliedAt.In(loc).Format("2006-01-02 15:04:05"))
+				}
+			}
+			if pending > 0 {
+				fmt.Fprintf(out, "\n%d pending migration(s). Run 'gapmap migrate up' to apply.\n", pending)
+			}
+			return nil
+		},
+	})
+
+	var upTo int
+	var upDryRun bool
+	upCmd := &cobra.Command{
+		Use:   "up",
+		Short: "Apply pending migrations",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			m, _, err := openMigrator()
+			if err != nil {
+				return err
+			}
+			defer m.Close()
+
+			target := store.LatestVersion()
+			if cmd.Flags().Changed("to") {
+				target = upTo
+			}
+			current, err := m.Version()
+			if err != nil {
+				return fmt.Errorf("read schema version: %w", err)
+			}
+			if target < current {
+				return fmt.Errorf("target v%d is below current v%d; use 'gapmap migrate down'", target, current)
+			}
+			return runMigration(cmd.OutOrStdout(), m, target, upDryRun)
+		},
+	}
+	upCmd.Flags().IntVar(&upTo, "to", 0, "Migrate up to this version (default: latest)")
+	upCmd.Flags().BoolVar(&upDryRun, "dry-run", false, "Print pending DDL without applying it")
+	cmd.AddCommand(upCmd)
+
+	var downDryRun bool
+	downCmd := &cobra.Command{
+		Use:   "down [version]",
+		Short: "Revert migrations down to a version (default: one step)",
+		Long: `Revert migrations down to the given version (default: one step back).
+
+Stop the daemon first: it re-applies pending migrations when it starts.
+Reverting drops the tables and columns those migrations added, so their
+data is lost; the automatic backup keeps a copy.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			m, _, err := openMigrator()
+			if err != nil {
+				return err
+			}
+			defer m.Close()
+
+			current, err := m.Version()
+			if err != nil {
+				return fmt.Errorf("read schema version: %w", err)
+			}
+			target := current - 1
+			if len(args) == 1 {
+				target, err = strconv.Atoi(strings.TrimPrefix(args[0], "v"))
+				if err != nil {
+					return fmt.Errorf("invalid version %q", args[0])
+				}
+			}
+			if target > current {
+				return fmt.Errorf("target v%d is above current v%d; use 'gapmap migrate up'", target, current)
+			}
+
+			if !downDryRun {
+				cfg, err := config.Load(config.ConfigPath())
+				if err == nil && ipc.NewClient(cfg.SocketPath).Ping() == nil {
+					return fmt.Errorf("daemon is running; stop it with 'gapmap stop' first")
+				}
+			}
+			return runMigration(cmd.OutOrStdout(), m, target, downDryRun)
+		},
+	}
+	downCmd.Flags().BoolVar(&downDryRun, "dry-run", false, "Print the DDL that would run without applying it")
+	cmd.AddCommand(downCmd)
+
+	return cmd
+}
+
+// runMigration prints the plan to reach target and, unless dryRun is set,
+// applies it.
+func runMigration(out io.Writer, m *store.Migrator, target int, dryRun bool) error {
+	steps, err := m.Plan(target)
+	if err != nil {
+		return err
+	}
+	if len(steps) == 0 {
+		fmt.Fprintf(out, "schema already at v%d\n", target)
+		return nil
+	}
+
+	if dryRun {
+		for _, step := range steps {
+			dir := "up"
+			if step.Down {
+				dir = "down"
+			}
+			fmt.Fprintf(out, "-- v%d (%s)\n%s\n", step.Version, dir, strings.TrimSpace(step.SQL))
+			fmt.Fprintln(out)
+		}
+		fmt.Fprintf(out, "dry run: %d migration(s) not applied\n", len(steps))
+		return nil
+	}
+
+	backup, err := m.Migrate(target)
+	if backup != "" {
+		fmt.Fprintf(out, "backup written to %s\n", backup)
+	}
+	if err != nil {
+		return fmt.Errorf("migrate: %w", err)
+	}
+	fmt.Fprintf(out, "migrated to v%d (%d step(s))\n", target, len(steps))
+	return nil
+}
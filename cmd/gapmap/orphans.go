@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/anthropic/gap-map/internal/config"
+	"github.com/anthropic/gap-map/internal/report"
+)
+
+func orphansCmd() *cobra.Command {
+	var (
+		dbPath string
+		asJSON bool
+		track  bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "orphans",
+		Short: "List AI edits to files outside the watch paths",
+		Long: `List the repositories, and directories outside any repository, where AI
+wrote or edited files that no watch path covers: scratch files, sibling
+repositories and the like. Their session events never match a file
+event, so that work is missing from every report.
+
+With --track, you are asked for each repository with at least
+untracked_repo_threshold edits whether to add it to watch_paths. Restart
+the daemon to start watching the added paths; edits made before then
+stay unattributed.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path := config.ConfigPath()
+			cfg, err := config.Load(path)
+			if err != nil {
+				return fmt.Errorf("load config: %w", err)
+			}
+			if dbPath == "" {
+				dbPath = cfg.DBPath
+			}
+			s, err := openStore(dbPath)
+			if err != nil {
+				return fmt.Errorf("open store: %w", err)
+			}
+			defer s.Close()
+
+			orphans, err := report.Orphans(s, cfg.WatchPaths)
+			if err != nil {
+				return err
+			}
+			out := cmd.OutOrStdout()
+			if asJSON {
+				fmt.Fprintln(out, report.FormatJSON(orphans))
+			} else {
+				fmt.Fprint(out, report.FormatOrphans(orphans, cfg.Location(), outputStyle(cmd)))
+			}
+			if !track {
+				return nil
+			}
+
+			in := bufio.NewReader(cmd.InOrStdin())
+			var asked int
+			var added []string
+			for _, o := range orphans {
+				if !o.Repo || o.Edits < max(cfg.UntrackedRepoThreshold, 1) {
+					continue
+				}
+				asked++
+				fmt.Fprintf(out, "\nTrack %s (%d AI edits in %d sessions)? [y/N] ", o.Root, o.Edits, o.Sessions)
+				answer, err := in.ReadString('\n')
+				if a := strings.ToLower(strings.TrimSpace(answer)); a == "y" || a == "yes" {
+					added = append(added, o.Root)
+				}
+				if err != nil {
+					break
+				}
+			}
+			if len(added) == 0 {
+				if asked > 0 {
+					fmt.Fprintln(out, "\nNo watch paths added.")
+				}
+				return nil
+			}
+			cfg.WatchPaths = append(cfg.WatchPaths, added...)
+			if err := cfg.Validate(); err != nil {
+				return withCode(exitConfig, fmt.Errorf("invalid config:\n%w", err))
+			}
+			if err := cfg.SaveKeys(path, "watch_paths"); err != nil {
+				return fmt.Errorf("save config: %w", err)
+			}
+			fmt.Fprintf(out, "\nAdded to watch_paths:\n")
+			for _, p := range added {
+				fmt.Fprintf(out, "  %s\n", p)
+			}
+			fmt.Fprintln(out, "Restart the daemon (gapmap stop && gapmap start) to watch them.")
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&dbPath, "db", "", "Override database path (default: from config)")
+	cmd.Flags().BoolVar(&asJSON, "json", false, "Output the list as JSON")
+	cmd.Flags().BoolVar(&track, "track", false, "Ask whether to add each repeatedly edited repository to watch_paths")
+	return cmd
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/anthropic/gap-map/internal/config"
+	ghub "github.com/anthropic/gap-map/internal/github"
+	"github.com/anthropic/gap-map/internal/report"
+)
+
+// addAnnotationFlags adds the flags that override the pr_annotate config
+// settings to a command that posts PR comments.
+func addAnnotationFlags(cmd *cobra.Command) {
+	cmd.Flags().Bool("annotate", false, "Add inline review comments on mostly-AI core logic hunks (default: pr_annotate)")
+	cmd.Flags().Int("max-annotations", ghub.DefaultAnnotations, "Most inline review comments to add (default: pr_max_annotations)")
+}
+
+// annotationLimit returns how many inline review comments to add, 0 for
+// none, from config overridden by the flags added by addAnnotationFlags.
+func annotationLimit(cmd *cobra.Command, cfg *config.Config) int {
+	enabled, max := cfg.PRAnnotate, cfg.PRMaxAnnotations
+	flags := cmd.Flags()
+	if flags.Changed("annotate") {
+		enabled, _ = flags.GetBool("annotate")
+	}
+	if flags.Changed("max-annotations") {
+		max, _ = flags.GetInt("max-annotations")
+	}
+	if !enabled || max < 0 {
+		return 0
+	}
+	return max
+}
+
+// annotatePR adds inline review comments on the hunks of branch that
+// SelectAnnotations picks, at most max of them. They need line-level
+// attribution, so a report derived from commit metadata gets none. With
+// dryRun they are printed instead.
+func annotatePR(out io.Writer, dbPath string, r *report.ProjectReport, branch, baseBranch string, max int, dryRun bool, owner, repo string, pr int, client *ghub.Client, opts report.Options) error {
+	if r.Source != "" {
+		fmt.Fprintln(os.Stderr, "inline annotations need the database; none added")
+		return nil
+	}
+	s, err := openStore(dbPath)
+	if err != nil {
+		return fmt.Errorf("open store: %w", err)
+	}
+	defer s.Close()
+
+	commit, hunks, err := report.BranchHunks(s, r, branch, baseBranch, opts)
+	if err != nil {
+		return fmt.Errorf("attribute hunks of %s: %w", branch, err)
+	}
+	anns := ghub.SelectAnnotations(hunks, max)
+
+	if dryRun {
+		for _, a := range anns {
+			fmt.Fprintf(os.Stderr, "Would annotate %s:%d-%d\n", a.Path, a.StartLine, a.EndLine)
+		}
+		return nil
+	}
+	if len(anns) == 0 {
+		return nil
+	}
+	n, err := client.PostAnnotations(owner, repo, pr, commit, anns)
+	if err != nil {
+		return fmt.Errorf("annotate PR: %w", err)
+	}
+	fmt.Fprintf(out, "%d inline annotation(s) added to PR #%d\n", n, pr)
+	return nil
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/anthropic/gap-map/internal/config"
+)
+
+// selectProfile makes the --profile flag, when given, the active profile.
+// It is exported to the environment so the daemon started by "gapmap
+// start" and the commands run by git hooks use the same profile.
+func selectProfile(flag string) error {
+	if flag != "" {
+		if err := config.ValidateProfile(flag); err != nil {
+			return err
+		}
+		return os.Setenv(config.EnvProfile, flag)
+	}
+	if err := config.ValidateProfile(config.Profile()); err != nil {
+		return fmt.Errorf("%s: %w", config.EnvProfile, err)
+	}
+	return nil
+}
+
+// profileArgs returns the --profile flag that selects the active profile
+// in a command line gap-map writes out for later, such as a service
+// definition or a git hook. It is empty for the default profile.
+func profileArgs() []string {
+	if p := config.Profile(); p != config.DefaultProfile {
+		return []string{"--profile", p}
+	}
+	return nil
+}
+
+// profileSuffix returns sep and the active profile's name, to keep the
+// names of per-profile files apart. It is empty for the default profile.
+func profileSuffix(sep string) string {
+	if p := config.Profile(); p != config.DefaultProfile {
+		return sep + p
+	}
+	return ""
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+)
+
+func projectsCmd() *cobra.Command {
+	var dbPath string
+
+	cmd := &cobra.Command{
+		Use:   "projects",
+		Short: "List recorded projects and merge duplicates",
+		Long: `List the project paths the database holds data under.
+
+Events are stored under their paths with symlinks resolved, so one
+checkout reached through a symlinked home, /var and /private/var or a
+mounted volume is one project. Data recorded before that, or under paths
+that do not resolve to each other, can still be split across projects;
+the list marks paths that now resolve elsewhere. Use
+"gapmap projects merge <from> <into>" to move one project's data into
+another.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			out := cmd.OutOrStdout()
+			s, err := openStore(dbPath)
+			if err != nil {
+				return err
+			}
+			defer s.Close()
+
+			projects, err := s.QueryProjects()
+			if err != nil {
+				return err
+			}
+			aliases, err := s.QueryProjectAliases()
+			if err != nil {
+				return err
+			}
+			if len(projects) == 0 {
+				fmt.Fprintln(out, "No projects recorded.")
+				return nil
+			}
+			for _, p := range projects {
+				last := "-"
+				if !p.LastEvent.IsZero() {
+					last = p.LastEvent.Local().Format("2006-01-02 15:04")
+				}
+				fmt.Fprintf(out, "%-50s %6d events  %6d attributions  last %s\n", p.ProjectPath, p.FileEvents, p.Attributions, last)
+				if resolved, err := s.ResolvePath(p.ProjectPath); err == nil && resolved != p.ProjectPath {
+					fmt.Fprintf(out, "  resolves to %s; merge with: gapmap projects merge %s %s\n", resolved, p.ProjectPath, resolved)
+				}
+			}
+			if len(aliases) > 0 {
+				fmt.Fprintln(out, "\nAliases:")
+				for _, a := range aliases {
+					fmt.Fprintf(out, "  %s -> %s\n", a.Alias, a.ProjectPath)
+				}
+			}
+			return nil
+		},
+	}
+
+	cmd.PersistentFlags().StringVar(&dbPath, "db", "", "Override database path (default: from config)")
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "merge <from> <into>",
+		Short: "Move a project's data under another project path",
+		Long: `Move everything recorded under the path <from>, and the files below
+it, to the same files under <into>, and record <from> as an alias of
+<into> so events still arriving under <from> are stored under <into>.
+Stop the daemon first, or it may record events under <from> while the
+merge runs.`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			out := cmd.OutOrStdout()
+			from, err := filepath.Abs(args[0])
+			if err != nil {
+				return fmt.Errorf("resolve %s: %w", args[0], err)
+			}
+			into, err := filepath.Abs(args[1])
+			if err != nil {
+				return fmt.Errorf("resolve %s: %w", args[1], err)
+			}
+			s, err := openStore(dbPath)
+			if err != nil {
+				return err
+			}
+			defer s.Close()
+
+			n, err := s.MergeProjects(from, into)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(out, "Merged %s into %s (%d rows updated).\n", from, into, n)
+			return nil
+		},
+	})
+
+	return cmd
+}
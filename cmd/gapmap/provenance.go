@@ -0,0 +1,160 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/anthropic/gap-map/internal/config"
+	"github.com/anthropic/gap-map/internal/metrics"
+	"github.com/anthropic/gap-map/internal/provenance"
+	"github.com/anthropic/gap-map/internal/report"
+)
+
+func provenanceCmd() *cobra.Command {
+	var (
+		dbPath  string
+		keyPath string
+		output  string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "provenance",
+		Short: "Write a signed attribution provenance document",
+		Long: `Write a machine-readable, signed record of how the tracked files were
+written, to accompany release artifacts.
+
+The document is an in-toto Statement (the format SLSA provenance uses)
+wrapped in a DSSE envelope signed with Ed25519. Its subjects are the
+attributed files with their SHA-256 digests; its predicate holds each
+file's AI and human percentages, work type, the AI tool calls behind its
+changes and the time range of the work, plus project totals.
+
+The signing key is read from --key, by default provenance.key in the data
+directory. If it does not exist a new key is created, with the public key
+written next to it as provenance.key.pub: publish that file so others can
+run "gapmap provenance verify".`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.Load(config.ConfigPath())
+			if err != nil {
+				return fmt.Errorf("load config: %w", err)
+			}
+			if dbPath == "" {
+				dbPath = cfg.DBPath
+			}
+			if keyPath == "" {
+				keyPath = filepath.Join(cfg.DataDir, "provenance.key")
+			}
+			scorer, err := metrics.NewScorer(cfg.Scorer, cfg.ScorerWeights)
+			if err != nil {
+				return fmt.Errorf("load scorer: %w", err)
+			}
+
+			s, err := openStore(dbPath)
+			if err != nil {
+				return fmt.Errorf("open store: %w", err)
+			}
+			defer s.Close()
+
+			pr, err := report.GenerateProject(cmd.Context(), s, scorer, report.Filter{}, reportOptions(cfg))
+			if err != nil {
+				return fmt.Errorf("generate project report: %w", err)
+			}
+			activity, err := s.QueryFileActivity(pr.ProjectPath)
+			if err != nil {
+				return err
+			}
+
+			key, err := provenance.LoadOrCreateKey(keyPath)
+			if err != nil {
+				return fmt.Errorf("load signing key: %w", err)
+			}
+
+			st := provenance.Build(pr, activity, time.Now().In(cfg.Location()))
+			env, err := provenance.Sign(st, key)
+			if err != nil {
+				return fmt.Errorf("sign provenance: %w", err)
+			}
+			data, err := json.MarshalIndent(env, "", "  ")
+			if err != nil {
+				return err
+			}
+			data = append(data, '\n')
+
+			if output == "" || output == "-" {
+				_, err = cmd.OutOrStdout().Write(data)
+				return err
+			}
+			if err := os.WriteFile(output, data, 0644); err != nil {
+				return fmt.Errorf("write %s: %w", output, err)
+			}
+			fmt.Fprintf(os.Stderr, "Wrote %s (%d files), signed with key %s\n", output, len(st.Subject), env.Signatures[0].KeyID[:16])
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&dbPath, "db", "", "Override database path (default: from config)")
+	cmd.Flags().StringVar(&keyPath, "key", "", "Ed25519 private key in PEM (default: provenance.key in the data directory)")
+	cmd.Flags().StringVarP(&output, "output", "o", "", "Write the document to this file instead of stdout")
+
+	cmd.AddCommand(provenanceVerifyCmd())
+
+	return cmd
+}
+
+func provenanceVerifyCmd() *cobra.Command {
+	var pubPath string
+
+	cmd := &cobra.Command{
+		Use:   "verify <document>",
+		Short: "Check the signature of a provenance document",
+		Long: `Check that a document written by "gapmap provenance" was signed by the
+key whose public half is given by --pubkey (default: provenance.key.pub in
+the data directory), and print its summary.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			out := cmd.OutOrStdout()
+			if pubPath == "" {
+				cfg, err := config.Load(config.ConfigPath())
+				if err != nil {
+					return fmt.Errorf("load config: %w", err)
+				}
+				pubPath = filepath.Join(cfg.DataDir, "provenance.key.pub")
+			}
+			pub, err := provenance.LoadPublicKey(pubPath)
+			if err != nil {
+				return fmt.Errorf("load public key: %w", err)
+			}
+
+			data, err := os.ReadFile(args[0])
+			if err != nil {
+				return err
+			}
+			var env provenance.Envelope
+			if err := json.Unmarshal(data, &env); err != nil {
+				return fmt.Errorf("parse %s: %w", args[0], err)
+			}
+			st, err := provenance.Verify(&env, pub)
+			if err != nil {
+				return fmt.Errorf("verify %s: %w", args[0], err)
+			}
+
+			p := st.Predicate
+			fmt.Fprintf(out, "Signature OK (key %s)\n", provenance.KeyID(pub)[:16])
+			fmt.Fprintf(out, "Project:   %s\n", p.Project)
+			fmt.Fprintf(out, "Generated: %s\n", p.GeneratedAt)
+			fmt.Fprintf(out, "Files:     %d\n", len(p.Files))
+			fmt.Fprintf(out, "AI:        %.1f%% (%d of %d lines), meaningful %.1f%%\n", p.AIPct, p.AILines, p.TotalLines, p.MeaningfulAIPct)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&pubPath, "pubkey", "", "Ed25519 public key in PEM (default: provenance.key.pub in the data directory)")
+
+	return cmd
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/anthropic/gap-map/internal/config"
+	ghub "github.com/anthropic/gap-map/internal/github"
+	"github.com/anthropic/gap-map/internal/gitint"
+	"github.com/anthropic/gap-map/internal/report"
+)
+
+// postStackComments comments on the open PR of each layer of the stack
+// topped by branch, with the layer's report against the layer below it and
+// the stack's rollup. Layers without an open PR are skipped. With dryRun
+// the comments are printed instead.
+func postStackComments(cmd *cobra.Command, cfg *config.Config, dbPath, branch, baseBranch string, review, dryRun bool, owner, repo, token string, detectErr error) error {
+	out := cmd.OutOrStdout()
+	s, err := openStore(dbPath)
+	if err != nil {
+		return fmt.Errorf("open store: %w", err)
+	}
+	defer s.Close()
+
+	st, err := gitint.DiscoverStack(".", branch, baseBranch)
+	if err != nil {
+		return fmt.Errorf("discover stack: %w", err)
+	}
+	sr, err := report.GenerateStack(s, st, reportOptions(cfg))
+	if err != nil {
+		return fmt.Errorf("generate stack report: %w", err)
+	}
+	if review {
+		for _, l := range sr.Layers {
+			if err := applyReview(s, l.Report, cfg); err != nil {
+				return err
+			}
+		}
+	}
+	if !dryRun {
+		if token == "" {
+			return fmt.Errorf("GitHub token required: set --token flag or GITHUB_TOKEN env var")
+		}
+		if detectErr != nil {
+			return detectErr
+		}
+	}
+
+	policy, err := commentPolicy(cmd, cfg)
+	if err != nil {
+		return err
+	}
+	max := annotationLimit(cmd, cfg)
+	client := ghub.NewClient(token, ghub.ConfigClientOptions(cfg))
+	for i, l := range sr.Layers {
+		var pr int
+		if detectErr == nil && token != "" {
+			if pr, err = client.FindPRForBranch(owner, repo, l.Branch); err != nil {
+				return fmt.Errorf("find PR for %s: %w", l.Branch, err)
+			}
+		}
+		body := policy.Apply(ghub.GenerateStackComment(sr, i, policy.Notable))
+		skip := policy.SkipReason(l.Report, prIsDraft(policy, owner, repo, pr, client))
+
+		if dryRun {
+			target := "no open PR"
+			if pr > 0 {
+				target = fmt.Sprintf("PR #%d", pr)
+			}
+			fmt.Fprintf(out, "--- Layer %d of %d: %s (%s) ---\n", i+1, len(sr.Layers), l.Branch, target)
+			if skip != "" {
+				fmt.Fprintf(os.Stderr, "Would not comment on %s: %s\n", l.Branch, skip)
+			}
+			fmt.Fprintln(out, body)
+			if max > 0 {
+				if err := annotatePR(out, dbPath, l.Report, l.Branch, l.Parent, max, true, owner, repo, pr, client, reportOptions(cfg)); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if pr == 0 {
+			fmt.Fprintf(out, "No open PR for %s; no comment posted\n", l.Branch)
+			continue
+		}
+		if skip != "" {
+			fmt.Fprintf(out, "No comment posted to PR #%d: %s\n", pr, skip)
+			continue
+		}
+		if err := client.PostComment(owner, repo, pr, body); err != nil {
+			return fmt.Errorf("post comment on PR #%d: %w", pr, err)
+		}
+		fmt.Fprintf(out, "Comment posted to PR #%d (%s)\n", pr, l.Branch)
+		if max > 0 {
+			if err := annotatePR(out, dbPath, l.Report, l.Branch, l.Parent, max, false, owner, repo, pr, client, reportOptions(cfg)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/anthropic/gap-map/internal/config"
+	"github.com/anthropic/gap-map/internal/gitint"
+)
+
+func pruneCmd() *cobra.Command {
+	var (
+		dbPath         string
+		mergedBranches bool
+		dryRun         bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "prune",
+		Short: "Delete attribution data of merged and deleted branches",
+		Long: `Delete attribution data that no longer describes live work.
+
+--merged-branches deletes the attributions of branches that were merged
+into the default branch or deleted, with their labels, snapshots and
+survival history. The daemon archives such branches after each git sync,
+which already keeps them out of branch reports; prune first checks the
+branches itself, so it works without the daemon, then deletes the
+archived attributions. Project reports still match the merged code
+against the AI session content, but lose the files only those
+attributions named.
+
+--dry-run archives gone branches and lists what would be deleted,
+deleting nothing.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			out := cmd.OutOrStdout()
+			if !mergedBranches {
+				return fmt.Errorf("nothing to prune: pass --merged-branches")
+			}
+			if dbPath == "" {
+				cfg, err := config.Load(config.ConfigPath())
+				if err != nil {
+					return fmt.Errorf("load config: %w", err)
+				}
+				dbPath = cfg.DBPath
+			}
+
+			s, err := openStore(dbPath)
+			if err != nil {
+				return fmt.Errorf("open store: %w", err)
+			}
+			defer s.Close()
+
+			projectPath, err := discoverProjectPath(s)
+			if err != nil {
+				return fmt.Errorf("discover project: %w", err)
+			}
+
+			repo, err := gitint.Open(projectPath, s)
+			if err != nil {
+				return err
+			}
+			if _, err := repo.CheckBranches(context.Background()); err != nil {
+				return fmt.Errorf("check branches: %w", err)
+			}
+
+			branches, err := s.QueryBranchAttributions(projectPath)
+			if err != nil {
+				return fmt.Errorf("query branches: %w", err)
+			}
+			total := 0
+			for _, b := range branches {
+				if b.Archived == "" {
+					continue
+				}
+				fmt.Fprintf(out, "  %-40s %-8s %d attributions\n", b.Branch, b.Archived, b.Attributions)
+				total += b.Attributions
+			}
+			if total == 0 {
+				fmt.Fprintln(out, "No merged or deleted branches to prune.")
+				return nil
+			}
+			if dryRun {
+				fmt.Fprintf(out, "Would delete %d attributions.\n", total)
+				return nil
+			}
+
+			n, err := s.DeleteArchivedAttributions(projectPath)
+			if err != nil {
+				return fmt.Errorf("delete archived attributions: %w", err)
+			}
+			fmt.Fprintf(out, "Deleted %d attributions.\n", n)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&dbPath, "db", "", "Override database path (default: from config)")
+	cmd.Flags().BoolVar(&mergedBranches, "merged-branches", false, "Delete attributions of merged and deleted branches")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "List what would be deleted without deleting it")
+
+	return cmd
+}
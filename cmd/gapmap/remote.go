@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/anthropic/gap-map/internal/api"
+	"github.com/anthropic/gap-map/internal/config"
+	"github.com/anthropic/gap-map/internal/daemon"
+	"github.com/anthropic/gap-map/internal/metrics"
+	"github.com/anthropic/gap-map/internal/report"
+	"github.com/anthropic/gap-map/internal/store"
+)
+
+// apiReporter generates the reports the daemon serves for analyze
+// --remote, from the daemon's store, as analyze would from the database.
+func apiReporter(d *daemon.Daemon, cfg *config.Config) api.Reporter {
+	return func(ctx context.Context, q api.Query) (any, error) {
+		s := d.Store()
+		project, file, err := apiTarget(s, d.Trust(), q)
+		if err != nil {
+			return nil, err
+		}
+		if file != "" {
+			return report.GenerateFileWindow(s, file, q.Filter.Window, reportOptions(cfg))
+		}
+
+		var pr *report.ProjectReport
+		if q.Branch != "" {
+			base := q.Base
+			if base == "" {
+				if base, err = projectBaseBranch(project); err != nil {
+					return nil, err
+				}
+			}
+			pr, err = report.GenerateProjectForBranchIn(s, project, q.Branch, base, reportOptions(cfg))
+		} else {
+			scorer, serr := metrics.NewScorer(cfg.Scorer, cfg.ScorerWeights)
+			if serr != nil {
+				return nil, fmt.Errorf("load scorer: %w", serr)
+			}
+			f := q.Filter
+			f.Project = project
+			pr, err = report.GenerateProject(ctx, s, scorer, f, reportOptions(cfg))
+		}
+		if err != nil {
+			return nil, err
+		}
+		if q.Review {
+			if err := applyReview(s, pr, cfg); err != nil {
+				return nil, err
+			}
+		}
+		return pr, nil
+	}
+}
+
+// apiTarget returns the project and file, if any, an API query reports
+// on. The project must be recorded in s and allowed by trust, and the
+// file must lie within it, so a client cannot make the daemon read other
+// files on the host. Without a project, the one containing an absolute
+// file is used, else the first project with attributions; relative files
+// are relative to the project.
+func apiTarget(s *store.Store, trust *config.Trust, q api.Query) (project, file string, err error) {
+	projects, err := s.QueryProjects()
+	if err != nil {
+		return "", "", err
+	}
+	switch {
+	case q.Project != "":
+		if project, err = s.ResolvePath(q.Project); err != nil {
+			return "", "", err
+		}
+		if !slices.ContainsFunc(projects, func(p store.ProjectSummary) bool { return p.ProjectPath == project }) {
+			return "", "", fmt.Errorf("%w: %s", api.ErrNotFound, q.Project)
+		}
+	case filepath.IsAbs(q.File):
+		if file, err = s.ResolvePath(q.File); err != nil {
+			return "", "", err
+		}
+		// The longest recorded project containing the file.
+		for _, p := range projects {
+			if within(p.ProjectPath, file) && len(p.ProjectPath) > len(project) {
+				project = p.ProjectPath
+			}
+		}
+		if project == "" {
+			return "", "", fmt.Errorf("%w: %s is in no recorded project", api.ErrNotFound, q.File)
+		}
+	default:
+		if project, err = report.DiscoverProjectPath(s); err != nil {
+			return "", "", err
+		}
+	}
+	if !trust.Allows(project) {
+		return "", "", fmt.Errorf("%w: %s", api.ErrNotFound, project)
+	}
+
+	if q.File == "" {
+		return project, "", nil
+	}
+	file = q.File
+	if !filepath.IsAbs(file) {
+		file = filepath.Join(project, file)
+	}
+	if file, err = s.ResolvePath(file); err != nil {
+		return "", "", err
+	}
+	if file == project || !within(project, file) {
+		return "", "", fmt.Errorf("%w: %s is outside project %s", api.ErrBadQuery, q.File, project)
+	}
+	return project, file, nil
+}
+
+// within reports whether path is dir or below it.
+func within(dir, path string) bool {
+	return path == dir || strings.HasPrefix(path, dir+string(filepath.Separator))
+}
+
+// analyzeRemote prints the report q selects, fetched from the daemon API
+// at addr.
+func analyzeRemote(cmd *cobra.Command, addr, token string, q api.Query, jsonOutput bool) error {
+	ctx, out := cmd.Context(), cmd.OutOrStdout()
+	c := api.NewClient(addr, token)
+	if q.File != "" {
+		fr, err := c.FileReport(ctx, q)
+		if err != nil {
+			return fmt.Errorf("fetch file report: %w", err)
+		}
+		if jsonOutput {
+			fmt.Fprintln(out, report.FormatJSON(fr))
+		} else {
+			fmt.Fprint(out, report.FormatFileReport(fr, outputStyle(cmd)))
+		}
+		return nil
+	}
+	pr, err := c.ProjectReport(ctx, q)
+	if err != nil {
+		return fmt.Errorf("fetch project report: %w", err)
+	}
+	if jsonOutput {
+		fmt.Fprintln(out, report.FormatJSON(pr))
+	} else {
+		fmt.Fprint(out, report.FormatProjectReport(pr, outputStyle(cmd)))
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/anthropic/gap-map/internal/api"
+	"github.com/anthropic/gap-map/internal/config"
+	"github.com/anthropic/gap-map/internal/store"
+)
+
+func TestAPITarget(t *testing.T) {
+	root := store.CanonicalPath(t.TempDir())
+	app, secret := filepath.Join(root, "app"), filepath.Join(root, "secret")
+	for _, dir := range []string{app, secret} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s, err := store.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	for _, project := range []string{app, secret} {
+		if _, err := s.InsertAttribution(store.AttributionRecord{
+			FilePath: filepath.Join(project, "main.go"), ProjectPath: project,
+			AuthorshipLevel: "mostly_ai", Timestamp: time.Now(), LinesChanged: 1,
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cfg := config.Default()
+	cfg.DisabledProjects = []string{secret}
+	trust := cfg.Trust()
+
+	project, file, err := apiTarget(s, trust, api.Query{Project: app, File: "main.go"})
+	if err != nil || project != app || file != filepath.Join(app, "main.go") {
+		t.Errorf("relative file = %q, %q, %v", project, file, err)
+	}
+	if project, _, err := apiTarget(s, trust, api.Query{File: filepath.Join(app, "main.go")}); err != nil || project != app {
+		t.Errorf("absolute file = %q, %v", project, err)
+	}
+
+	for name, tt := range map[string]struct {
+		q    api.Query
+		want error
+	}{
+		"unrecorded project": {api.Query{Project: root}, api.ErrNotFound},
+		"disabled project":   {api.Query{Project: secret}, api.ErrNotFound},
+		"file in disabled":   {api.Query{File: filepath.Join(secret, "main.go")}, api.ErrNotFound},
+		"file outside":       {api.Query{File: "/etc/passwd"}, api.ErrNotFound},
+		"dot-dot escape":     {api.Query{Project: app, File: "../secret/main.go"}, api.ErrBadQuery},
+		"absolute escape":    {api.Query{Project: app, File: "/etc/passwd"}, api.ErrBadQuery},
+		"project root":       {api.Query{Project: app, File: "."}, api.ErrBadQuery},
+	} {
+		if _, _, err := apiTarget(s, trust, tt.q); !errors.Is(err, tt.want) {
+			t.Errorf("%s: err = %v, want %v", name, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/anthropic/gap-map/internal/config"
+	"github.com/anthropic/gap-map/internal/ipc"
+	"github.com/anthropic/gap-map/internal/store"
+)
+
+func repairCmd() *cobra.Command {
+	var (
+		dbPath string
+		output string
+		force  bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "repair",
+		Short: "Salvage a corrupt database into a fresh one",
+		Long: `Check the database with SQLite's integrity check and, if it is corrupt,
+copy every readable row into a fresh database.
+
+Rows are copied table by table with their ids, so attributions keep
+pointing at their events; rows on damaged pages are skipped and counted.
+The corrupt file is kept next to the database as gapmap.db.corrupt.<time>
+and the repaired one takes its place. Use --output to write the repaired
+database elsewhere and leave the original untouched.
+
+The daemon checks the database when it starts and falls back to its
+newest backup by itself; repair is for when no usable backup exists.
+Stop the daemon first.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			out := cmd.OutOrStdout()
+			cfg, err := config.Load(config.ConfigPath())
+			if err != nil {
+				return fmt.Errorf("load config: %w", err)
+			}
+			if dbPath == "" {
+				dbPath = cfg.DBPath
+			}
+			if ipc.NewClient(cfg.SocketPath).Ping() == nil {
+				return fmt.Errorf("the daemon is running; stop it with \"gapmap stop\" first")
+			}
+
+			if _, err := os.Stat(dbPath); err != nil {
+				return fmt.Errorf("no database to repair: %w", err)
+			}
+			checkErr := store.CheckIntegrity(dbPath)
+			switch {
+			case checkErr == nil && !force:
+				fmt.Fprintf(out, "%s passed the integrity check; nothing to repair (use --force to rebuild anyway).\n", dbPath)
+				return nil
+			case checkErr != nil && !errors.Is(checkErr, store.ErrCorrupt):
+				return checkErr
+			case checkErr != nil:
+				fmt.Fprintf(out, "%v\n", checkErr)
+			}
+
+			dst := output
+			if dst == "" {
+				dst = dbPath + ".repaired"
+				_ = os.Remove(dst)
+			}
+			res, err := store.Repair(cmd.Context(), dbPath, dst)
+			if err != nil {
+				return fmt.Errorf("repair: %w", err)
+			}
+			for _, t := range res.Tables {
+				switch {
+				case t.Err != "":
+					fmt.Fprintf(out, "  %-22s unreadable: %s\n", t.Table, t.Err)
+				case t.Lost > 0:
+					fmt.Fprintf(out, "  %-22s %d rows copied, %d lost\n", t.Table, t.Copied, t.Lost)
+				default:
+					fmt.Fprintf(out, "  %-22s %d rows copied\n", t.Table, t.Copied)
+				}
+			}
+
+			if output != "" {
+				fmt.Fprintf(out, "Salvaged %d rows (%d lost) into %s.\n", res.Copied(), res.Lost(), dst)
+				return nil
+			}
+			aside, err := store.Quarantine(dbPath)
+			if err != nil {
+				return fmt.Errorf("move %s aside: %w (repaired database at %s)", dbPath, err, dst)
+			}
+			if err := os.Rename(dst, dbPath); err != nil {
+				return fmt.Errorf("replace %s: %w (repaired database at %s)", dbPath, err, dst)
+			}
+			fmt.Fprintf(out, "Salvaged %d rows (%d lost). The original is kept at %s.\n", res.Copied(), res.Lost(), aside)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&dbPath, "db", "", "Override database path (default: from config)")
+	cmd.Flags().StringVarP(&output, "output", "o", "", "Write the repaired database here instead of replacing the original")
+	cmd.Flags().BoolVar(&force, "force", false, "Rebuild the database even if it passes the integrity check")
+
+	return cmd
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/anthropic/gap-map/internal/config"
+	"github.com/anthropic/gap-map/internal/daemon"
+	"github.com/anthropic/gap-map/internal/report"
+	"github.com/anthropic/gap-map/internal/store"
+)
+
+func replayCmd() *cobra.Command {
+	var (
+		project   string
+		clock     string
+		saveDelay time.Duration
+		asJSON    bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "replay <session.jsonl>",
+		Short: "Replay a session file through attribution and print each decision",
+		Long: `Run a Claude Code session file through the daemon's session parser,
+correlation and classification against a throwaway database, and print
+each event the parser derives and the attribution decided for it. Attach
+the output to accuracy bug reports.
+
+Every Write or Edit of a file under --project is assumed to be seen by
+the file watcher --save-delay later. Replays are deterministic: lines
+without a timestamp get --clock, and times are printed in UTC. The
+correlation_window and clock_skew_window settings apply. Clipboard and
+editor typing evidence are not replayed, and your gap-map database is
+not touched.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			out := cmd.OutOrStdout()
+			cfg, err := config.Load(config.ConfigPath())
+			if err != nil {
+				return fmt.Errorf("load config: %w", err)
+			}
+			if project == "" {
+				wd, err := os.Getwd()
+				if err != nil {
+					return fmt.Errorf("get working directory: %w", err)
+				}
+				project = wd
+				if root, ok := gitRoot(wd); ok {
+					project = root
+				}
+			}
+			if project, err = filepath.Abs(project); err != nil {
+				return fmt.Errorf("resolve %s: %w", project, err)
+			}
+			at := time.Unix(0, 0).UTC()
+			if clock != "" {
+				if at, err = time.Parse(time.RFC3339, clock); err != nil {
+					return fmt.Errorf("--clock: %w", err)
+				}
+			}
+
+			f, err := os.Open(args[0])
+			if err != nil {
+				return fmt.Errorf("open session: %w", err)
+			}
+			defer f.Close()
+			steps, err := daemon.Replay(cfg, f, daemon.ReplayOptions{
+				ProjectPath: project,
+				SessionID:   strings.TrimSuffix(filepath.Base(args[0]), ".jsonl"),
+				Clock:       at,
+				SaveDelay:   saveDelay,
+			})
+			if err != nil {
+				return err
+			}
+
+			if asJSON {
+				fmt.Fprintln(out, report.FormatJSON(steps))
+				return nil
+			}
+			if len(steps) == 0 {
+				fmt.Fprintln(out, "No tool calls in the session.")
+				return nil
+			}
+			fmt.Fprintf(out, "%5s  %-12s  %-6s %6s  %s\n", "LINE", "TIME (UTC)", "TOOL", "LINES", "FILE")
+			for _, st := range steps {
+				file := st.FilePath
+				if len(file) > 80 {
+					file = file[:77] + "..."
+				}
+				fmt.Fprintf(out, "%5d  %-12s  %-6s %6d  %s\n", st.Line, st.Timestamp.UTC().Format("15:04:05.000"), st.ToolName, st.LinesChanged, file)
+				if st.FileEventAt.IsZero() {
+					continue
+				}
+				match := st.MatchType
+				if st.MatchedLine > 0 {
+					match = fmt.Sprintf("%s with line %d, %dms apart", st.MatchType, st.MatchedLine, st.TimeDeltaMs)
+				}
+				fmt.Fprintf(out, "       file event %s: %s\n", st.FileEventAt.UTC().Format("15:04:05.000"), match)
+				for _, a := range st.Attributions {
+					kind := "+"
+					if a.Kind == store.AttributionDeletion {
+						kind = "-"
+					}
+					uncertain := ""
+					if a.Uncertain {
+						uncertain = ", uncertain"
+					}
+					fmt.Fprintf(out, "       %s%d lines  %s (confidence %.2f%s, first author %s), %s\n",
+						kind, a.LinesChanged, a.AuthorshipLevel, a.Confidence, uncertain, a.FirstAuthor, st.WorkType)
+				}
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&project, "project", "", "Project whose files' edits are attributed (default: git repository of the current directory)")
+	cmd.Flags().StringVar(&clock, "clock", "", "Time given to lines without a timestamp, RFC 3339 (default: the Unix epoch)")
+	cmd.Flags().DurationVar(&saveDelay, "save-delay", daemon.DefaultSaveDelay, "How long after each edit the file watcher is assumed to see it")
+	cmd.Flags().BoolVar(&asJSON, "json", false, "Output the steps as JSON")
+	return cmd
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/anthropic/gap-map/internal/config"
+	ghub "github.com/anthropic/gap-map/internal/github"
+	"github.com/anthropic/gap-map/internal/ipc"
+	"github.com/anthropic/gap-map/internal/update"
+)
+
+func selfUpdateCmd() *cobra.Command {
+	var (
+		check         bool
+		tag           string
+		force         bool
+		noRestart     bool
+		skipSignature bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "self-update",
+		Short: "Update gapmap to the latest release",
+		Long: `Download the latest gap-map release for this platform from GitHub and
+replace the running binary with it.
+
+The binary must match its SHA-256 checksum in the release's checksums.txt,
+and checksums.txt must carry a valid ed25519 signature by the key this
+build was made with. A checksum from the same release only shows the
+download is intact, not that it came from the gap-map maintainers, so a
+build without a key (such as one made with go install) refuses to update
+unless --insecure-skip-signature accepts the checksum alone. The new
+binary is written next to the old one and renamed over it, so an
+interrupted update leaves the old binary in place. A running daemon is
+then restarted on the new binary: through systemd or launchd when gapmap
+init installed a service, else with stop and start.
+
+--check only reports how the release compares with the running version,
+whatever --tag and --force say. --tag installs a
+given release, also an older one. Development builds, which do not know
+their version, need --force. GITHUB_TOKEN or the gh CLI's login, when
+there is one, avoids GitHub's rate limit for anonymous requests.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			out := cmd.OutOrStdout()
+			current := currentVersion()
+			client := ghub.NewClient(ghub.DetectToken(), ghub.DefaultClientOptions)
+			rel, err := client.FetchRelease(update.Owner, update.Repo, tag)
+			if err != nil {
+				return err
+			}
+
+			cmp, known := update.Compare(rel.Tag, current)
+			if check {
+				fmt.Fprintln(out, updateStatus(current, rel.Tag, cmp, known))
+				return nil
+			}
+			switch {
+			case force || tag != "":
+			case !known:
+				return fmt.Errorf("gapmap %s is a development build; pass --force to replace it with %s", current, rel.Tag)
+			case cmp <= 0:
+				fmt.Fprintf(out, "gapmap %s is up to date\n", current)
+				return nil
+			}
+			if update.SigningKey == "" && !skipSignature {
+				return fmt.Errorf("this build has no release signing key, so %s cannot be verified as an official release; install it from the releases page, or pass --insecure-skip-signature to trust its checksum alone", rel.Tag)
+			}
+
+			binary, err := update.Download(client, rel, runtime.GOOS, runtime.GOARCH, update.SigningKey)
+			if err != nil {
+				return fmt.Errorf("download %s: %w", rel.Tag, err)
+			}
+			exe, err := os.Executable()
+			if err != nil {
+				return fmt.Errorf("resolve executable path: %w", err)
+			}
+			if err := update.Replace(exe, binary); err != nil {
+				return err
+			}
+			fmt.Fprintf(out, "updated gapmap %s -> %s\n", current, rel.Tag)
+
+			if noRestart {
+				return nil
+			}
+			return restartDaemon(out, exe)
+		},
+	}
+
+	cmd.Flags().BoolVar(&check, "check", false, "Only report whether an update is available")
+	cmd.Flags().StringVar(&tag, "tag", "", "Install this release tag instead of the latest (e.g. v1.4.0)")
+	cmd.Flags().BoolVar(&force, "force", false, "Install even when the release is not newer, or this is a development build")
+	cmd.Flags().BoolVar(&noRestart, "no-restart", false, "Leave a running daemon on the old binary")
+	cmd.Flags().BoolVar(&skipSignature, "insecure-skip-signature", false, "Install without a signature check when this build has no signing key")
+	return cmd
+}
+
+// updateStatus describes how release compares with the running version
+// current, as update.Compare(release, current) returned cmp and known.
+func updateStatus(current, release string, cmp int, known bool) string {
+	switch {
+	case !known:
+		return fmt.Sprintf("gapmap %s is a development build; %s is available (install it with --force)", current, release)
+	case cmp > 0:
+		return fmt.Sprintf("gapmap %s is available (running %s); run gapmap self-update to install it", release, current)
+	case cmp < 0:
+		return fmt.Sprintf("gapmap %s is older than the running %s", release, current)
+	default:
+		return fmt.Sprintf("gapmap %s is up to date", current)
+	}
+}
+
+// restartDaemon restarts a running daemon on the binary at exe: through
+// the service manager when a service definition is installed, else by
+// asking it to stop, waiting for it to go, and starting it again.
+func restartDaemon(out io.Writer, exe string) error {
+	cfg, err := config.Load(config.ConfigPath())
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+	client := ipc.NewClient(cfg.SocketPath)
+	if client.Ping() != nil {
+		return nil // not running
+	}
+
+	if svc, ok := serviceUnit(); ok {
+		if _, err := os.Stat(svc.path); err == nil {
+			if err := exec.Command(svc.restart[0], svc.restart[1:]...).Run(); err == nil {
+				fmt.Fprintf(out, "restarted the daemon through its %s\n", svc.kind)
+				return nil
+			}
+		}
+	}
+
+	if err := client.RequestStop(); err != nil {
+		return fmt.Errorf("stop daemon: %w", err)
+	}
+	stopped := false
+	for i := 0; i < 50; i++ {
+		time.Sleep(200 * time.Millisecond)
+		if client.Ping() != nil {
+			stopped = true
+			break
+		}
+	}
+	if !stopped {
+		return fmt.Errorf("daemon did not stop within 10s; run gapmap stop and gapmap start")
+	}
+	_ = os.Remove(filepath.Join(cfg.DataDir, "gapmap.pid"))
+
+	start := exec.Command(exe, "start")
+	start.Stdout = out
+	start.Stderr = os.Stderr
+	if err := start.Run(); err != nil {
+		return fmt.Errorf("restart daemon: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/anthropic/gap-map/internal/update"
+)
+
+func TestUpdateStatus(t *testing.T) {
+	tests := []struct {
+		current, release, want string
+	}{
+		{"v1.2.0", "v1.3.0", "gapmap v1.3.0 is available (running v1.2.0); run gapmap self-update to install it"},
+		{"v1.2.0", "v1.2.0", "gapmap v1.2.0 is up to date"},
+		{"v1.2.0", "v1.1.0", "gapmap v1.1.0 is older than the running v1.2.0"},
+		{"dev", "v1.2.0", "gapmap dev is a development build; v1.2.0 is available (install it with --force)"},
+	}
+	for _, tt := range tests {
+		cmp, known := update.Compare(tt.release, tt.current)
+		if got := updateStatus(tt.current, tt.release, cmp, known); got != tt.want {
+			t.Errorf("updateStatus(%q, %q) = %q, want %q", tt.current, tt.release, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/anthropic/gap-map/internal/config"
+	"github.com/anthropic/gap-map/internal/report"
+	"github.com/anthropic/gap-map/internal/store"
+	"github.com/anthropic/gap-map/internal/telemetry"
+)
+
+func telemetryCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "telemetry",
+		Short: "Manage opt-in anonymized telemetry",
+		Long: `Manage opt-in anonymized telemetry.
+
+When enabled, the daemon periodically aggregates coarse metrics -- AI%,
+lines changed per day, and tool usage counts -- and appends them to
+telemetry.jsonl in the data directory, or posts them to telemetry_endpoint
+if configured. File paths, project names, and code are never included.
+
+Telemetry is disabled by default. Restart the daemon after enabling.`,
+	}
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "status",
+		Short: "Show telemetry settings and a preview of the next snapshot",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			out := cmd.OutOrStdout()
+			cfg, err := config.Load(config.ConfigPath())
+			if err != nil {
+				return fmt.Errorf("load config: %w", err)
+			}
+
+			state := "disabled"
+			if cfg.TelemetryEnabled {
+				state = "enabled"
+			}
+			dest := cfg.TelemetryPath()
+			if cfg.TelemetryEndpoint != "" {
+				dest = cfg.TelemetryEndpoint
+			}
+			interval, err := time.ParseDuration(cfg.TelemetryInterval)
+			if err != nil || interval <= 0 {
+				interval = 24 * time.Hour
+			}
+
+			fmt.Fprintf(out, "%-14s %s\n", "Telemetry:", state)
+			fmt.Fprintf(out, "%-14s %s\n", "Destination:", dest)
+			fmt.Fprintf(out, "%-14s %s\n", "Interval:", interval)
+
+			// The preview must not change anything, so the database is
+			// opened read-only and no install ID is generated.
+			s, err := store.OpenReadOnly(cfg.DBPath)
+			if errors.Is(err, os.ErrNotExist) {
+				fmt.Fprintf(out, "%-14s %s\n", "Last sent:", "never")
+				fmt.Fprintf(out, "\nNo database at %s yet; nothing to preview.\n", cfg.DBPath)
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("open store: %w", err)
+			}
+			defer s.Close()
+
+			if last, err := telemetry.LastSent(s); err == nil && !last.IsZero() {
+				fmt.Fprintf(out, "%-14s %s\n", "Last sent:", last.In(cfg.Location()).Format(time.RFC3339))
+			} else {
+				fmt.Fprintf(out, "%-14s %s\n", "Last sent:", "never")
+			}
+
+			snap, err := telemetry.Preview(s, interval, time.Now())
+			if err != nil {
+				return fmt.Errorf("collect preview: %w", err)
+			}
+			fmt.Fprintln(out, "\nNext snapshot (preview):")
+			fmt.Fprintln(out, report.FormatJSON(snap))
+			if snap.InstallID == "" {
+				fmt.Fprintln(out, "(the install ID is generated when the first snapshot is sent)")
+			}
+			return nil
+		},
+	})
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "enable",
+		Short: "Opt in to anonymized telemetry",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return setTelemetry(cmd.OutOrStdout(), true)
+		},
+	})
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "disable",
+		Short: "Opt out of telemetry",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return setTelemetry(cmd.OutOrStdout(), false)
+		},
+	})
+
+	return cmd
+}
+
+// setTelemetry persists the telemetry_enabled flag in the config file.
+func setTelemetry(out io.Writer, enabled bool) error {
+	path := config.ConfigPath()
+	cfg, err := config.Load(path)
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+	cfg.TelemetryEnabled = enabled
+	if err := cfg.SaveKeys(path, "telemetry_enabled"); err != nil {
+		return fmt.Errorf("save config: %w", err)
+	}
+	if enabled {
+		fmt.Fprintln(out, "telemetry enabled (restart the daemon to apply)")
+	} else {
+		fmt.Fprintln(out, "telemetry disabled (restart the daemon to apply)")
+	}
+	return nil
+}
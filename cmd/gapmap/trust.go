@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/anthropic/gap-map/internal/config"
+	"github.com/anthropic/gap-map/internal/ipc"
+)
+
+func enableCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "enable [path]",
+		Short: "Record data for a project",
+		Long: `Let gap-map record file events, AI session events and commits for the
+project at path (default: the git repository of the current directory).
+
+By default every project is recorded except disabled ones. Set
+project_trust to allowlist to record only enabled projects:
+
+  gapmap config set project_trust allowlist
+  gapmap enable ~/src/api
+
+A running daemon picks the change up immediately.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return setProjectTrust(cmd.OutOrStdout(), args, true)
+		},
+	}
+}
+
+func disableCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "disable [path]",
+		Short: "Never record data for a project",
+		Long: `Stop gap-map recording anything for the project at path (default: the
+git repository of the current directory): its file events, the AI session
+events that touch it and its commits. Data recorded before is kept.
+
+A running daemon picks the change up immediately.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return setProjectTrust(cmd.OutOrStdout(), args, false)
+		},
+	}
+}
+
+// setProjectTrust enables or disables the project in args, or the current
+// one, in the config file and tells a running daemon.
+func setProjectTrust(out io.Writer, args []string, enable bool) error {
+	dir := ""
+	if len(args) == 1 {
+		dir = args[0]
+	} else {
+		wd, err := os.Getwd()
+		if err != nil {
+			return fmt.Errorf("get working directory: %w", err)
+		}
+		dir = wd
+		if root, ok := gitRoot(wd); ok {
+			dir = root
+		}
+	}
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return fmt.Errorf("resolve %s: %w", dir, err)
+	}
+
+	path := config.ConfigPath()
+	cfg, err := config.Load(path)
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+	if enable {
+		cfg.EnableProject(dir)
+	} else {
+		cfg.DisableProject(dir)
+	}
+	if err := cfg.Validate(); err != nil {
+		return withCode(exitConfig, fmt.Errorf("invalid config:\n%w", err))
+	}
+	if err := cfg.SaveKeys(path, "enabled_projects", "disabled_projects"); err != nil {
+		return fmt.Errorf("save config: %w", err)
+	}
+
+	state := "disabled"
+	if cfg.Trust().Allows(dir) {
+		state = "enabled"
+	}
+	fmt.Fprintf(out, "%s: %s\n", dir, state)
+
+	if err := ipc.NewClient(cfg.SocketPath).ReloadTrust(); err == nil {
+		fmt.Fprintln(out, "  the running daemon now uses the new setting")
+	}
+	return nil
+}
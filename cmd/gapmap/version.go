@@ -0,0 +1,19 @@
+package main
+
+import "runtime/debug"
+
+// version is the release gapmap was built as, set by release builds with
+// -ldflags "-X main.version=v1.2.3".
+var version string
+
+// currentVersion returns the version of this binary: the release it was
+// built as, else the module version go install recorded, else "(devel)".
+func currentVersion() string {
+	if version != "" {
+		return version
+	}
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" {
+		return info.Main.Version
+	}
+	return "(devel)"
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"path"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/anthropic/gap-map/internal/config"
+	"github.com/anthropic/gap-map/internal/ipc"
+	"github.com/anthropic/gap-map/internal/report"
+	"github.com/anthropic/gap-map/internal/store"
+)
+
+func watchCmd() *cobra.Command {
+	var (
+		filter     string
+		project    string
+		jsonOutput bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "watch",
+		Short: "Stream attributions as the daemon records them",
+		Long: `Print one line per attribution as the running daemon records it: the
+time, authorship, lines changed, work type and file. It is tail -f for
+attribution and runs until interrupted.
+
+--project limits the stream to one project. --filter is a path glob
+matched against each file's path relative to its project (or against the
+full path when the glob is absolute); "**" matches any number of
+directories and a glob without wildcards matches a directory:
+
+  gapmap watch --filter 'internal/**/*.go'
+  gapmap watch --json | jq -r 'select(.authorship_level == "mostly_ai") | .file_path'
+
+With --json each attribution is written as one JSON object per line.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			out := cmd.OutOrStdout()
+			cfg, err := config.Load(config.ConfigPath())
+			if err != nil {
+				return fmt.Errorf("load config: %w", err)
+			}
+			if _, err := path.Match(filter, ""); err != nil {
+				return fmt.Errorf("invalid --filter %q: %w", filter, err)
+			}
+			if project != "" {
+				abs, err := filepath.Abs(project)
+				if err != nil {
+					return fmt.Errorf("resolve project path: %w", err)
+				}
+				project = store.CanonicalPath(abs)
+			}
+
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+			defer stop()
+
+			loc, st := cfg.Location(), outputStyle(cmd)
+			enc := json.NewEncoder(out)
+			var writeErr error
+			client := ipc.NewClient(cfg.SocketPath)
+			return client.Subscribe(ctx, project, func(ev ipc.AttributionEvent) {
+				if writeErr != nil || !report.MatchEvent(filter, ev) {
+					return
+				}
+				if jsonOutput {
+					writeErr = enc.Encode(ev)
+				} else {
+					_, writeErr = fmt.Fprintln(out, report.FormatAttributionEvent(ev, loc, st))
+				}
+				if writeErr != nil {
+					stop() // stdout closed, e.g. the reader of a pipe exited
+				}
+			})
+		},
+	}
+
+	cmd.Flags().StringVar(&filter, "filter", "", "Only show files matching this path glob")
+	cmd.Flags().StringVar(&project, "project", "", "Only show attributions in this project")
+	cmd.Flags().BoolVar(&jsonOutput, "json", false, "Write one JSON object per attribution")
+
+	return cmd
+}
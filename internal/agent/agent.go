@@ -0,0 +1,107 @@
+// Package agent forwards the events a gap-map daemon records on a remote
+// host, where an AI tool and the repository live (a devcontainer, a
+// Codespace, a machine reached over SSH), to the daemon on the machine the
+// developer works from.
+//
+// The remote side runs "gapmap agent --stdio", which streams its store's
+// file and session events as JSON lines on stdout (Serve). The local side
+// runs it over ssh and records the events it reads with the remote host's
+// name (Forwarder). The stream starts with a hello line:
+//
+//	{"kind":"hello","protocol":1,"host":"devbox"}
+//
+// followed by one line per event, in the order they were recorded:
+//
+//	{"kind":"file","id":42,"project_path":"/workspaces/app","file_path":"/workspaces/app/main.go","event_type":"write","timestamp":"..."}
+//	{"kind":"session","id":7,"session_id":"...","event_type":"tool_use","tool_name":"Edit","file_path":"...","raw_json":"...",...}
+//
+// ids are the events' ids in the remote store; the forwarder keeps the
+// last one of each kind it recorded and asks for events after it when it
+// reconnects.
+package agent
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Protocol is the version of the event stream, sent in the hello line.
+const Protocol = 1
+
+// Kinds of stream lines.
+const (
+	KindHello   = "hello"
+	KindFile    = "file"
+	KindSession = "session"
+)
+
+// Event is one line of the stream.
+type Event struct {
+	Kind     string `json:"kind"`
+	Protocol int    `json:"protocol,omitempty"` // hello
+	Host     string `json:"host,omitempty"`     // hello: the remote host's name
+
+	ID          int64     `json:"id,omitempty"` // the event's id in the remote store
+	ProjectPath string    `json:"project_path,omitempty"`
+	FilePath    string    `json:"file_path,omitempty"`
+	EventType   string    `json:"event_type,omitempty"`
+	Timestamp   time.Time `json:"timestamp,omitempty"`
+
+	// Session events only.
+	SessionID     string `json:"session_id,omitempty"`
+	ToolName      string `json:"tool_name,omitempty"`
+	ContentHash   string `json:"content_hash,omitempty"`
+	LinesChanged  int    `json:"lines_changed,omitempty"`
+	Model         string `json:"model,omitempty"`
+	ClientVersion string `json:"client_version,omitempty"`
+	PromptID      string `json:"prompt_id,omitempty"`
+	Prompt        string `json:"prompt,omitempty"`
+	RawJSON       string `json:"raw_json,omitempty"`
+}
+
+// Cursor is the id of the last file and session event of a remote store
+// that has been forwarded.
+type Cursor struct {
+	File    int64
+	Session int64
+}
+
+// PathMap rewrites paths under Remote, as the remote host sees them, to
+// the same path under Local, for checkouts that live at a different path
+// on each machine.
+type PathMap struct {
+	Remote string
+	Local  string
+}
+
+// ParsePathMap parses a "remote=local" path mapping.
+func ParsePathMap(s string) (PathMap, error) {
+	remote, local, ok := strings.Cut(s, "=")
+	if !ok || remote == "" || local == "" {
+		return PathMap{}, fmt.Errorf("path map %q: want remote=local", s)
+	}
+	return PathMap{Remote: filepath.Clean(remote), Local: filepath.Clean(local)}, nil
+}
+
+// mapPath rewrites path with the longest of maps whose Remote contains it,
+// or returns it unchanged if none does.
+func mapPath(maps []PathMap, path string) string {
+	if path == "" {
+		return path
+	}
+	best := -1
+	for i, m := range maps {
+		if path != m.Remote && !strings.HasPrefix(path, m.Remote+string(filepath.Separator)) {
+			continue
+		}
+		if best < 0 || len(m.Remote) > len(maps[best].Remote) {
+			best = i
+		}
+	}
+	if best < 0 {
+		return path
+	}
+	return maps[best].Local + path[len(maps[best].Remote):]
+}
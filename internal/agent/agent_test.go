@@ -0,0 +1,148 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anthropic/gap-map/internal/store"
+)
+
+func newStore(t *testing.T, name string) *store.Store {
+	t.Helper()
+	s, err := store.New(filepath.Join(t.TempDir(), name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func TestServeAndForward(t *testing.T) {
+	remote := newStore(t, "remote.db")
+	base := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	file := "/workspaces/app/main.go"
+	if err := remote.InsertSessionEventWithContext("sess-1", "tool_use", "Write", file, "h1", base,
+		`{"uuid":"u1"}`, 3, store.SessionEventContext{Model: "claude-sonnet-4"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := remote.InsertWatchedFileEvent("/workspaces/app", file, "write", base.Add(time.Second), store.PriorityNormal, store.OriginUser); err != nil {
+		t.Fatal(err)
+	}
+	// Git-origin events are not forwarded.
+	if err := remote.InsertWatchedFileEvent("/workspaces/app", file, "write", base.Add(2*time.Second), store.PriorityNormal, store.OriginGit); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel() // send what is there, then stop
+	var stream bytes.Buffer
+	if err := Serve(ctx, remote, &stream, "devbox", Cursor{}, time.Second); err != nil {
+		t.Fatalf("Serve: %v", err)
+	}
+
+	local := newStore(t, "local.db")
+	f := &Forwarder{
+		Store:  local,
+		Target: "dev",
+		Maps:   []PathMap{{Remote: "/workspaces/app", Local: "/home/me/app"}},
+	}
+	n, err := f.Forward(bytes.NewReader(stream.Bytes()))
+	if err != nil {
+		t.Fatalf("Forward: %v", err)
+	}
+	if n != 2 {
+		t.Errorf("forwarded %d events, want 2", n)
+	}
+
+	events, err := local.QueryUnprocessedFileEvents(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].FilePath != "/home/me/app/main.go" || events[0].ProjectPath != "/home/me/app" {
+		t.Fatalf("local file events = %+v, want one for /home/me/app/main.go", events)
+	}
+	sessions, err := local.QueryWriteEditSessionEvents()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sessions) != 1 || sessions[0].FilePath != "/home/me/app/main.go" || sessions[0].Model != "claude-sonnet-4" {
+		t.Fatalf("local session events = %+v", sessions)
+	}
+
+	// Forwarded events are not forwarded again.
+	again, err := local.QuerySessionEventsAfter(0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(again) != 0 {
+		t.Errorf("forwarded session events are served again: %+v", again)
+	}
+
+	cur, err := f.Cursor()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cur.File != 1 || cur.Session != 1 {
+		t.Errorf("cursor = %+v, want file 1, session 1", cur)
+	}
+}
+
+func TestForwardRequiresHello(t *testing.T) {
+	f := &Forwarder{Store: newStore(t, "local.db"), Target: "dev"}
+	_, err := f.Forward(bytes.NewReader([]byte(`{"kind":"file","id":1,"file_path":"/a"}` + "\n")))
+	if err == nil {
+		t.Fatal("expected an error for a stream without hello")
+	}
+	_, err = f.Forward(bytes.NewReader([]byte(`{"kind":"hello","protocol":99}` + "\n")))
+	if err == nil {
+		t.Fatal("expected an error for an unknown protocol")
+	}
+}
+
+func TestSSHArgs(t *testing.T) {
+	f := &Forwarder{Target: "me@devbox", SSHArgs: []string{"-p", "2222"}}
+	got := strings.Join(f.sshArgs(Cursor{File: 7, Session: 3}), " ")
+	want := "-p 2222 -- me@devbox gapmap agent --stdio --after-file 7 --after-session 3"
+	if got != want {
+		t.Errorf("sshArgs = %q, want %q", got, want)
+	}
+
+	f = &Forwarder{Target: "-oProxyCommand=sh"}
+	if _, err := f.Connect(t.Context()); err == nil {
+		t.Error("Connect accepted a target that ssh would read as an option")
+	}
+}
+
+func TestMapPath(t *testing.T) {
+	maps := []PathMap{
+		{Remote: "/workspaces", Local: "/src"},
+		{Remote: "/workspaces/app", Local: "/home/me/app"},
+	}
+	tests := []struct {
+		path, want string
+	}{
+		{"/workspaces/app/main.go", "/home/me/app/main.go"},
+		{"/workspaces/app", "/home/me/app"},
+		{"/workspaces/lib/x.go", "/src/lib/x.go"},
+		{"/workspaces-old/x.go", "/workspaces-old/x.go"},
+		{"/etc/hosts", "/etc/hosts"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := mapPath(maps, tt.path); got != tt.want {
+			t.Errorf("mapPath(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+
+	if _, err := ParsePathMap("/only"); err == nil {
+		t.Error("ParsePathMap accepted a map without =")
+	}
+	m, err := ParsePathMap("/workspaces/app/=/home/me/app")
+	if err != nil || m.Remote != "/workspaces/app" || m.Local != "/home/me/app" {
+		t.Errorf("ParsePathMap = %+v, %v", m, err)
+	}
+}
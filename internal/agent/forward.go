@@ -0,0 +1,222 @@
+package agent
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/anthropic/gap-map/internal/store"
+)
+
+// Reconnect delays of Forwarder.Run, doubled after each failed connection.
+const (
+	minBackoff = time.Second
+	maxBackoff = time.Minute
+)
+
+// Forwarder records the events a remote gapmap agent streams in a local
+// store.
+type Forwarder struct {
+	Store *store.Store
+
+	// Target is the ssh destination of the remote host ("devbox",
+	// "user@host"). It keys the forwarder's cursor, so it should stay
+	// the same between runs.
+	Target  string
+	SSHArgs []string // extra ssh options, e.g. ["-p", "2222"]
+	Command string   // gapmap on the remote host; default "gapmap"
+
+	// Maps rewrite remote paths to local ones.
+	Maps []PathMap
+
+	// Allow, if set, decides which mapped paths are recorded, as the
+	// daemon's project trust settings do for local events.
+	Allow func(path string) bool
+}
+
+// cursorKey returns the daemon_state key of the forwarder's cursor for
+// events of kind.
+func (f *Forwarder) cursorKey(kind string) string {
+	return "agent_cursor:" + f.Target + ":" + kind
+}
+
+// Cursor returns the ids of the last remote events forwarded from Target.
+func (f *Forwarder) Cursor() (Cursor, error) {
+	var cur Cursor
+	for kind, id := range map[string]*int64{KindFile: &cur.File, KindSession: &cur.Session} {
+		v, err := f.Store.GetDaemonState(f.cursorKey(kind))
+		if err != nil {
+			return Cursor{}, fmt.Errorf("read %s cursor: %w", kind, err)
+		}
+		if v == "" {
+			continue
+		}
+		if *id, err = strconv.ParseInt(v, 10, 64); err != nil {
+			return Cursor{}, fmt.Errorf("parse %s cursor %q: %w", kind, v, err)
+		}
+	}
+	return cur, nil
+}
+
+// Forward records the events of the stream read from r until it ends, and
+// returns how many it recorded. Each event advances the cursor, so a
+// stream cut short can be resumed.
+func (f *Forwarder) Forward(r io.Reader) (int, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+
+	var host string
+	n := 0
+	for scanner.Scan() {
+		var ev Event
+		if err := json.Unmarshal(scanner.Bytes(), &ev); err != nil {
+			return n, fmt.Errorf("decode event: %w", err)
+		}
+		if host == "" && ev.Kind != KindHello {
+			return n, fmt.Errorf("stream from %s does not start with hello", f.Target)
+		}
+		switch ev.Kind {
+		case KindHello:
+			if ev.Protocol != Protocol {
+				return n, fmt.Errorf("%s speaks agent protocol %d, want %d", f.Target, ev.Protocol, Protocol)
+			}
+			host = ev.Host
+			if host == "" {
+				host = f.Target
+			}
+		case KindFile, KindSession:
+			recorded, err := f.record(host, ev)
+			if err != nil {
+				return n, err
+			}
+			if recorded {
+				n++
+			}
+		default:
+			slog.Debug("agent: skipping unknown event kind", "kind", ev.Kind)
+		}
+	}
+	return n, scanner.Err()
+}
+
+// record stores ev as an event of host, unless Allow rejects its path, and
+// advances the cursor past it either way. The event and the cursor are
+// saved in one transaction, so a crash between them neither drops the
+// event nor forwards it twice.
+func (f *Forwarder) record(host string, ev Event) (bool, error) {
+	key, cursor := f.cursorKey(ev.Kind), strconv.FormatInt(ev.ID, 10)
+	path := mapPath(f.Maps, ev.FilePath)
+	if f.Allow != nil && !f.Allow(path) {
+		if err := f.Store.SetDaemonState(key, cursor); err != nil {
+			return false, fmt.Errorf("save %s cursor: %w", ev.Kind, err)
+		}
+		return false, nil
+	}
+
+	var err error
+	switch ev.Kind {
+	case KindFile:
+		err = f.Store.InsertRemoteFileEventWithState(host, mapPath(f.Maps, ev.ProjectPath), path, ev.EventType, ev.Timestamp, key, cursor)
+	case KindSession:
+		err = f.Store.InsertSessionEventsWithState([]store.NewSessionEvent{{
+			SessionID: ev.SessionID, EventType: ev.EventType, ToolName: ev.ToolName,
+			FilePath: path, ContentHash: ev.ContentHash, Timestamp: ev.Timestamp,
+			RawJSON: ev.RawJSON, LinesChanged: ev.LinesChanged,
+			Context: store.SessionEventContext{
+				Model:         ev.Model,
+				ClientVersion: ev.ClientVersion,
+				PromptID:      ev.PromptID,
+				Prompt:        ev.Prompt,
+				Host:          host,
+			},
+		}}, key, cursor)
+	}
+	if err != nil {
+		return false, fmt.Errorf("record %s event %d from %s: %w", ev.Kind, ev.ID, host, err)
+	}
+	return true, nil
+}
+
+// Connect runs the agent on Target over ssh, from the saved cursor, and
+// forwards its events until the connection closes or ctx is done.
+func (f *Forwarder) Connect(ctx context.Context) (int, error) {
+	if strings.HasPrefix(f.Target, "-") {
+		return 0, fmt.Errorf("ssh target %q must not start with -", f.Target)
+	}
+	cur, err := f.Cursor()
+	if err != nil {
+		return 0, err
+	}
+	cmd := exec.CommandContext(ctx, "ssh", f.sshArgs(cur)...)
+	cmd.Stderr = os.Stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return 0, err
+	}
+	if err := cmd.Start(); err != nil {
+		return 0, fmt.Errorf("start ssh %s: %w", f.Target, err)
+	}
+	n, ferr := f.Forward(stdout)
+	if ferr != nil {
+		_ = cmd.Process.Kill()
+	}
+	werr := cmd.Wait()
+	if ferr != nil {
+		return n, ferr
+	}
+	if werr != nil && ctx.Err() == nil {
+		return n, fmt.Errorf("ssh %s: %w", f.Target, werr)
+	}
+	return n, nil
+}
+
+// sshArgs returns the ssh arguments running the agent on Target from cur.
+// "--" ends the options, so Target is never read as one.
+func (f *Forwarder) sshArgs(cur Cursor) []string {
+	command := f.Command
+	if command == "" {
+		command = "gapmap"
+	}
+	args := append([]string{}, f.SSHArgs...)
+	return append(args, "--", f.Target, command, "agent", "--stdio",
+		"--after-file", strconv.FormatInt(cur.File, 10),
+		"--after-session", strconv.FormatInt(cur.Session, 10))
+}
+
+// Run connects to Target and reconnects whenever the connection drops,
+// waiting longer after each connection that forwarded nothing, until ctx
+// is done.
+func (f *Forwarder) Run(ctx context.Context) {
+	backoff := minBackoff
+	for {
+		n, err := f.Connect(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if n > 0 {
+			backoff = minBackoff
+		}
+		if err != nil && !errors.Is(err, context.Canceled) {
+			slog.Warn("agent: connection failed", "target", f.Target, "err", err, "retry_in", backoff)
+		} else {
+			slog.Info("agent: connection closed", "target", f.Target, "forwarded", n, "retry_in", backoff)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		if n == 0 {
+			backoff = min(backoff*2, maxBackoff)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/anthropic/gap-map/internal/store"
+)
+
+// serveBatch is how many events of each kind Serve reads from the store at
+// a time.
+const serveBatch = 500
+
+// Serve writes the hello line for host to w, then the events of s after
+// from, then new events as they are recorded, checking every poll. It
+// returns when ctx is done or w cannot be written, as when the ssh
+// connection closes.
+func Serve(ctx context.Context, s *store.Store, w io.Writer, host string, from Cursor, poll time.Duration) error {
+	enc := json.NewEncoder(w)
+	if err := enc.Encode(Event{Kind: KindHello, Protocol: Protocol, Host: host}); err != nil {
+		return fmt.Errorf("write hello: %w", err)
+	}
+	cur := from
+	for {
+		next, n, err := sendBatch(s, enc, cur)
+		if err != nil {
+			return err
+		}
+		cur = next
+		if n == serveBatch {
+			continue // more are waiting
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(poll):
+		}
+	}
+}
+
+// sendBatch writes up to serveBatch file and session events after cur and
+// returns the cursor after them, with the larger of the two counts.
+func sendBatch(s *store.Store, enc *json.Encoder, cur Cursor) (Cursor, int, error) {
+	files, err := s.QueryFileEventsAfter(cur.File, serveBatch)
+	if err != nil {
+		return cur, 0, err
+	}
+	sessions, err := s.QuerySessionEventsAfter(cur.Session, serveBatch)
+	if err != nil {
+		return cur, 0, err
+	}
+
+	// Session events first: an edit's file event usually follows the tool
+	// call that made it, and the receiving daemon correlates a file event
+	// as soon as it is queued.
+	for _, se := range sessions {
+		ev := Event{
+			Kind:          KindSession,
+			ID:            se.ID,
+			FilePath:      se.FilePath,
+			EventType:     se.EventType,
+			Timestamp:     se.Timestamp,
+			SessionID:     se.SessionID,
+			ToolName:      se.ToolName,
+			ContentHash:   se.ContentHash,
+			LinesChanged:  se.LinesChanged,
+			Model:         se.Model,
+			ClientVersion: se.ClientVersion,
+			PromptID:      se.PromptID,
+			Prompt:        se.Prompt,
+			RawJSON:       se.RawJSON,
+		}
+		if err := enc.Encode(ev); err != nil {
+			return cur, 0, fmt.Errorf("write session event %d: %w", se.ID, err)
+		}
+		cur.Session = se.ID
+	}
+	for _, fe := range files {
+		ev := Event{
+			Kind:        KindFile,
+			ID:          fe.ID,
+			ProjectPath: fe.ProjectPath,
+			FilePath:    fe.FilePath,
+			EventType:   fe.EventType,
+			Timestamp:   fe.Timestamp,
+		}
+		if err := enc.Encode(ev); err != nil {
+			return cur, 0, fmt.Errorf("write file event %d: %w", fe.ID, err)
+		}
+		cur.File = fe.ID
+	}
+	return cur, max(len(files), len(sessions)), nil
+}
@@ -0,0 +1,109 @@
+// Package api serves report data over HTTP, so analyze can run against a
+// daemon on another machine (a CI runner, a teammate's workstation)
+// without copying its database. Requests carry a bearer token.
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/anthropic/gap-map/internal/report"
+)
+
+// Paths of the API.
+const (
+	PathReport     = "/v1/report"      // a project or branch report
+	PathFileReport = "/v1/report/file" // a single file's report
+)
+
+// ErrUnreachable is wrapped by the errors of requests that could not reach
+// the daemon.
+var ErrUnreachable = errors.New("remote daemon is not reachable")
+
+// Query selects a report.
+type Query struct {
+	File    string // a single file's report; Branch and Filter do not apply
+	Branch  string // a branch report against Base; Filter does not apply
+	Base    string // the branch Branch is compared with (default: the project's base_branch, else main)
+	Project string // the project reported, File is relative to (default: the daemon's first)
+	Review  bool   // add the review checklist
+
+	// Filter narrows the project report; its Window applies to file
+	// reports too. Its Project is not sent: Project is.
+	Filter report.Filter
+}
+
+// Values encodes q as URL query parameters.
+func (q Query) Values() url.Values {
+	v := url.Values{}
+	set := func(key, value string) {
+		if value != "" {
+			v.Set(key, value)
+		}
+	}
+	set("path", q.File)
+	set("branch", q.Branch)
+	set("base", q.Base)
+	set("project", q.Project)
+	if q.Review {
+		v.Set("review", "1")
+	}
+	f := q.Filter
+	set("work_type", f.WorkType)
+	set("path_glob", f.PathGlob)
+	set("sort", f.Sort)
+	if f.MinAIPct > 0 {
+		v.Set("min_ai_pct", strconv.FormatFloat(f.MinAIPct, 'f', -1, 64))
+	}
+	if f.Top > 0 {
+		v.Set("top", strconv.Itoa(f.Top))
+	}
+	if !f.Window.Since.IsZero() {
+		v.Set("since", f.Window.Since.UTC().Format(time.RFC3339Nano))
+	}
+	if !f.Window.Until.IsZero() {
+		v.Set("until", f.Window.Until.UTC().Format(time.RFC3339Nano))
+	}
+	return v
+}
+
+// ParseQuery decodes the query parameters Values encodes.
+func ParseQuery(v url.Values) (Query, error) {
+	q := Query{
+		File:    v.Get("path"),
+		Branch:  v.Get("branch"),
+		Base:    v.Get("base"),
+		Project: v.Get("project"),
+		Review:  v.Get("review") == "1",
+		Filter: report.Filter{
+			WorkType: v.Get("work_type"),
+			PathGlob: v.Get("path_glob"),
+			Sort:     v.Get("sort"),
+		},
+	}
+	var err error
+	if s := v.Get("min_ai_pct"); s != "" {
+		if q.Filter.MinAIPct, err = strconv.ParseFloat(s, 64); err != nil {
+			return q, fmt.Errorf("min_ai_pct: %w", err)
+		}
+	}
+	if s := v.Get("top"); s != "" {
+		if q.Filter.Top, err = strconv.Atoi(s); err != nil {
+			return q, fmt.Errorf("top: %w", err)
+		}
+	}
+	if s := v.Get("since"); s != "" {
+		if q.Filter.Window.Since, err = time.Parse(time.RFC3339Nano, s); err != nil {
+			return q, fmt.Errorf("since: %w", err)
+		}
+	}
+	if s := v.Get("until"); s != "" {
+		if q.Filter.Window.Until, err = time.Parse(time.RFC3339Nano, s); err != nil {
+			return q, fmt.Errorf("until: %w", err)
+		}
+	}
+	return q, q.Filter.Validate()
+}
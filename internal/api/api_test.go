@@ -0,0 +1,121 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anthropic/gap-map/internal/report"
+)
+
+func TestQueryValues(t *testing.T) {
+	q := Query{
+		Branch:  "feat",
+		Base:    "main",
+		Project: "/src/app",
+		Review:  true,
+		Filter: report.Filter{
+			WorkType: "core_logic",
+			PathGlob: "internal/**",
+			MinAIPct: 12.5,
+			Sort:     report.SortLines,
+			Top:      3,
+			Window:   report.Window{Since: time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)},
+		},
+	}
+	got, err := ParseQuery(q.Values())
+	if err != nil {
+		t.Fatalf("ParseQuery: %v", err)
+	}
+	if !reflect.DeepEqual(got, q) {
+		t.Errorf("round trip = %+v, want %+v", got, q)
+	}
+
+	bad := q.Values()
+	bad.Set("sort", "name")
+	if _, err := ParseQuery(bad); err == nil {
+		t.Error("ParseQuery with an unknown sort: want error")
+	}
+}
+
+func TestServerAndClient(t *testing.T) {
+	var gotQuery Query
+	srv := httptest.NewServer(NewServer("s3cret", func(ctx context.Context, q Query) (any, error) {
+		gotQuery = q
+		switch {
+		case q.File == "missing.go":
+			return nil, fmt.Errorf("%w for file %q", report.ErrNoData, q.File)
+		case q.File == "../escape.go":
+			return nil, fmt.Errorf("%w: outside the project", ErrBadQuery)
+		case q.File != "":
+			return &report.FileReport{FilePath: q.File, TotalLines: 10, AILines: 4}, nil
+		case q.Branch == "broken":
+			return nil, errors.New("cannot compute merge-base")
+		}
+		return &report.ProjectReport{ProjectPath: "/proj", TotalFiles: 2, TotalLines: 30, AILines: 12,
+			ByAuthorship: map[string]int{"mostly_ai": 1}}, nil
+	}))
+	defer srv.Close()
+	ctx := context.Background()
+
+	c := NewClient(srv.URL, "s3cret")
+	pr, err := c.ProjectReport(ctx, Query{Branch: "feat", Filter: report.Filter{Top: 5}})
+	if err != nil {
+		t.Fatalf("ProjectReport: %v", err)
+	}
+	if pr.ProjectPath != "/proj" || pr.TotalLines != 30 || pr.ByAuthorship["mostly_ai"] != 1 {
+		t.Errorf("ProjectReport = %+v", pr)
+	}
+	if gotQuery.Branch != "feat" || gotQuery.Filter.Top != 5 {
+		t.Errorf("server got query %+v", gotQuery)
+	}
+
+	fr, err := c.FileReport(ctx, Query{File: "main.go"})
+	if err != nil || fr.FilePath != "main.go" || fr.AILines != 4 {
+		t.Errorf("FileReport = %+v, %v", fr, err)
+	}
+	if _, err := c.FileReport(ctx, Query{File: "missing.go"}); !errors.Is(err, report.ErrNoData) {
+		t.Errorf("FileReport(missing) error = %v, want ErrNoData", err)
+	}
+	if _, err := c.FileReport(ctx, Query{File: "../escape.go"}); err == nil || !strings.Contains(err.Error(), "400") {
+		t.Errorf("FileReport(escape) error = %v, want status 400", err)
+	}
+	if _, err := c.ProjectReport(ctx, Query{Branch: "broken"}); err == nil || errors.Is(err, report.ErrNoData) {
+		t.Errorf("ProjectReport(broken) error = %v", err)
+	}
+	if _, err := c.FileReport(ctx, Query{}); err == nil {
+		t.Error("file report without a path: want error")
+	}
+
+	if _, err := NewClient(srv.URL, "wrong").ProjectReport(ctx, Query{}); err == nil {
+		t.Error("wrong token: want error")
+	}
+	if _, err := NewClient(srv.URL, "").ProjectReport(ctx, Query{}); err == nil {
+		t.Error("no token: want error")
+	}
+
+	unreachable := NewClient("127.0.0.1:1", "s3cret")
+	if _, err := unreachable.ProjectReport(ctx, Query{}); !errors.Is(err, ErrUnreachable) {
+		t.Errorf("unreachable daemon error = %v, want ErrUnreachable", err)
+	}
+}
+
+func TestLoopback(t *testing.T) {
+	for addr, want := range map[string]bool{
+		"127.0.0.1:8788": true,
+		"[::1]:8788":     true,
+		"localhost:8788": true,
+		"0.0.0.0:8788":   false,
+		":8788":          false,
+		"10.0.0.5:8788":  false,
+	} {
+		if got := loopback(addr); got != want {
+			t.Errorf("loopback(%q) = %v, want %v", addr, got, want)
+		}
+	}
+}
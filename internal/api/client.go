@@ -0,0 +1,83 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/anthropic/gap-map/internal/report"
+)
+
+// EnvToken holds the token analyze --remote sends when --token is not
+// given.
+const EnvToken = "GAPMAP_API_TOKEN"
+
+// Client fetches reports from a daemon's API.
+type Client struct {
+	base  string
+	token string
+	http  *http.Client
+}
+
+// NewClient returns a client for the daemon API at addr, "host:port" or
+// an http:// or https:// URL, sending token.
+func NewClient(addr, token string) *Client {
+	base := strings.TrimRight(addr, "/")
+	if !strings.HasPrefix(base, "http://") && !strings.HasPrefix(base, "https://") {
+		base = "http://" + base
+	}
+	return &Client{base: base, token: token, http: &http.Client{Timeout: 5 * time.Minute}}
+}
+
+// ProjectReport fetches the project or branch report q selects.
+func (c *Client) ProjectReport(ctx context.Context, q Query) (*report.ProjectReport, error) {
+	var pr report.ProjectReport
+	if err := c.get(ctx, PathReport, q, &pr); err != nil {
+		return nil, err
+	}
+	return &pr, nil
+}
+
+// FileReport fetches the report of q.File.
+func (c *Client) FileReport(ctx context.Context, q Query) (*report.FileReport, error) {
+	var fr report.FileReport
+	if err := c.get(ctx, PathFileReport, q, &fr); err != nil {
+		return nil, err
+	}
+	return &fr, nil
+}
+
+// get requests path with q and decodes the response into out. Errors the
+// daemon reports as missing data wrap report.ErrNoData.
+func (c *Client) get(ctx context.Context, path string, q Query, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.base+path+"?"+q.Values().Encode(), nil)
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w at %s (%w)", ErrUnreachable, c.base, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+		var e errorBody
+		if json.Unmarshal(body, &e) != nil || e.Error == "" {
+			return fmt.Errorf("remote daemon returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+		}
+		if e.NoData {
+			return fmt.Errorf("remote daemon: %w (%s)", report.ErrNoData, e.Error)
+		}
+		return fmt.Errorf("remote daemon returned status %d: %s", resp.StatusCode, e.Error)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decode report: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/anthropic/gap-map/internal/report"
+)
+
+// Reporter generates the report q selects: a *report.FileReport when
+// q.File is set, else a *report.ProjectReport.
+type Reporter func(ctx context.Context, q Query) (any, error)
+
+// Errors a Reporter wraps to answer with a client error rather than a
+// server one.
+var (
+	ErrBadQuery = errors.New("bad query")               // 400 Bad Request
+	ErrNotFound = errors.New("no such project or file") // 404 Not Found
+)
+
+// Server serves the reports a Reporter generates to requests bearing its
+// token.
+type Server struct {
+	token    string
+	reporter Reporter
+}
+
+// NewServer returns a Server for reporter. Requests must carry token as a
+// bearer token; it must not be empty.
+func NewServer(token string, reporter Reporter) *Server {
+	return &Server{token: token, reporter: reporter}
+}
+
+// errorBody is the JSON body of a failed request.
+type errorBody struct {
+	Error  string `json:"error"`
+	NoData bool   `json:"no_data,omitempty"` // the error wraps report.ErrNoData
+}
+
+// ServeHTTP answers GET requests for PathReport and PathFileReport.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !s.authorized(r) {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="gapmap"`)
+		writeJSON(w, http.StatusUnauthorized, errorBody{Error: "missing or invalid token"})
+		return
+	}
+	if r.URL.Path != PathReport && r.URL.Path != PathFileReport {
+		writeJSON(w, http.StatusNotFound, errorBody{Error: "unknown path " + r.URL.Path})
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		writeJSON(w, http.StatusMethodNotAllowed, errorBody{Error: "use GET"})
+		return
+	}
+
+	q, err := ParseQuery(r.URL.Query())
+	if err == nil && (r.URL.Path == PathFileReport) != (q.File != "") {
+		err = errors.New("path is required for file reports, and only for them")
+	}
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errorBody{Error: err.Error()})
+		return
+	}
+	rep, err := s.reporter(r.Context(), q)
+	if errors.Is(err, report.ErrNoData) {
+		writeJSON(w, http.StatusNotFound, errorBody{Error: err.Error(), NoData: true})
+		return
+	}
+	if errors.Is(err, ErrBadQuery) {
+		writeJSON(w, http.StatusBadRequest, errorBody{Error: err.Error()})
+		return
+	}
+	if errors.Is(err, ErrNotFound) {
+		writeJSON(w, http.StatusNotFound, errorBody{Error: err.Error()})
+		return
+	}
+	if err != nil {
+		slog.Warn("API report failed", "path", r.URL.Path, "err", err)
+		writeJSON(w, http.StatusInternalServerError, errorBody{Error: err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, rep)
+}
+
+// authorized reports whether r carries the server's token.
+func (s *Server) authorized(r *http.Request) bool {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && s.token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) == 1
+}
+
+// Run serves the API on addr until ctx is cancelled: over HTTPS with the
+// PEM certificate and key in certFile and keyFile when they are set, else
+// over plain HTTP, which sends the token in the clear and is only safe on
+// a loopback address or behind a tunnel.
+func (s *Server) Run(ctx context.Context, addr, certFile, keyFile string) {
+	srv := &http.Server{Addr: addr, Handler: s, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(shutdownCtx)
+	}()
+	var err error
+	if certFile != "" {
+		slog.Info("report API listening", "addr", addr, "tls", true)
+		err = srv.ListenAndServeTLS(certFile, keyFile)
+	} else {
+		if !loopback(addr) {
+			slog.Warn("report API serves plain HTTP on a non-loopback address; set api_tls_cert and api_tls_key, or bind it to 127.0.0.1 and use a tunnel", "addr", addr)
+		}
+		slog.Info("report API listening", "addr", addr)
+		err = srv.ListenAndServe()
+	}
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		slog.Error("report API stopped", "addr", addr, "err", err)
+	}
+}
+
+// loopback reports whether addr, a host:port, binds only to a loopback
+// interface.
+func loopback(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
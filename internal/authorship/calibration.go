@@ -0,0 +1,133 @@
+package authorship
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/anthropic/gap-map/internal/store"
+)
+
+// CalibrationWindowsMs are the correlation windows Calibrate evaluates
+// session matches at, so the window can be tuned from labels.
+var CalibrationWindowsMs = []int{500, 1000, 2000, 5000, 10000}
+
+// CalibrationRow counts how a group of labeled attributions fared, with
+// AI as the positive class.
+type CalibrationRow struct {
+	Key      string `json:"key"`
+	Labeled  int    `json:"labeled"`
+	TruePos  int    `json:"true_positives"`  // said AI, labeled AI
+	FalsePos int    `json:"false_positives"` // said AI, labeled human
+	FalseNeg int    `json:"false_negatives"` // said human, labeled AI
+}
+
+// Precision returns the share of the row's AI attributions labeled AI, and
+// false when it has none.
+func (r CalibrationRow) Precision() (float64, bool) {
+	if r.TruePos+r.FalsePos == 0 {
+		return 0, false
+	}
+	return float64(r.TruePos) / float64(r.TruePos+r.FalsePos) * 100, true
+}
+
+// Recall returns the share of the row's AI-labeled changes attributed to
+// AI, and false when it has none.
+func (r CalibrationRow) Recall() (float64, bool) {
+	if r.TruePos+r.FalseNeg == 0 {
+		return 0, false
+	}
+	return float64(r.TruePos) / float64(r.TruePos+r.FalseNeg) * 100, true
+}
+
+func (r *CalibrationRow) add(predictedAI, labeledAI bool) {
+	r.Labeled++
+	switch {
+	case predictedAI && labeledAI:
+		r.TruePos++
+	case predictedAI:
+		r.FalsePos++
+	case labeledAI:
+		r.FalseNeg++
+	}
+}
+
+// Calibration is how labeled attributions compare with what was computed.
+type Calibration struct {
+	Overall     CalibrationRow   `json:"overall"`
+	ByMatchType []CalibrationRow `json:"by_match_type"`
+
+	// ByWindow has a row per CalibrationWindowsMs entry (Key "<=2s"),
+	// counting session matches further apart than the window as if they
+	// had not matched: what precision and recall would be with that
+	// correlation window.
+	ByWindow []CalibrationRow `json:"by_window"`
+}
+
+// PredictsAI reports whether an attribution says AI made its change: it
+// matched an AI session event or a pasted AI clipboard block.
+func PredictsAI(a store.AttributionRecord) bool {
+	return a.SessionEventID != nil || a.AuthorshipLevel == string(AISuggestedHumanWritten)
+}
+
+// Calibrate compares labeled attributions with their computed authorship.
+// Attributions from before match types were recorded are grouped as
+// "unrecorded".
+func Calibrate(samples []store.CalibrationSample) Calibration {
+	var c Calibration
+	byType := make(map[string]*CalibrationRow)
+	windows := make([]CalibrationRow, len(CalibrationWindowsMs))
+	for i, ms := range CalibrationWindowsMs {
+		windows[i].Key = windowKey(ms)
+	}
+
+	for _, s := range samples {
+		if s.Label == "" {
+			continue
+		}
+		predicted := PredictsAI(s.AttributionRecord)
+		labeledAI := s.Label == store.LabelAI
+		c.Overall.add(predicted, labeledAI)
+
+		mt := s.MatchType
+		if mt == "" {
+			mt = "unrecorded"
+		}
+		row, ok := byType[mt]
+		if !ok {
+			row = &CalibrationRow{Key: mt}
+			byType[mt] = row
+		}
+		row.add(predicted, labeledAI)
+
+		for i, ms := range CalibrationWindowsMs {
+			within := predicted
+			if s.SessionEventID != nil && s.CorrelationWindowMs > ms {
+				within = false
+			}
+			windows[i].add(within, labeledAI)
+		}
+	}
+
+	c.Overall.Key = "all"
+	for _, row := range byType {
+		c.ByMatchType = append(c.ByMatchType, *row)
+	}
+	sort.Slice(c.ByMatchType, func(i, j int) bool {
+		if c.ByMatchType[i].Labeled != c.ByMatchType[j].Labeled {
+			return c.ByMatchType[i].Labeled > c.ByMatchType[j].Labeled
+		}
+		return c.ByMatchType[i].Key < c.ByMatchType[j].Key
+	})
+	if c.Overall.Labeled > 0 {
+		c.ByWindow = windows
+	}
+	return c
+}
+
+// windowKey names a window row, e.g. "<=2s" or "<=500ms".
+func windowKey(ms int) string {
+	if ms%1000 == 0 {
+		return fmt.Sprintf("<=%ds", ms/1000)
+	}
+	return fmt.Sprintf("<=%dms", ms)
+}
@@ -0,0 +1,77 @@
+package authorship
+
+import (
+	"testing"
+
+	"github.com/anthropic/gap-map/internal/store"
+)
+
+func TestCalibrate(t *testing.T) {
+	se := int64(1)
+	sample := func(matchType, level string, matched bool, windowMs int, label string) store.CalibrationSample {
+		c := store.CalibrationSample{Label: label}
+		c.MatchType = matchType
+		c.AuthorshipLevel = level
+		c.CorrelationWindowMs = windowMs
+		if matched {
+			c.SessionEventID = &se
+		}
+		return c
+	}
+
+	c := Calibrate([]store.CalibrationSample{
+		sample("exact_file", "mostly_ai", true, 300, store.LabelAI),
+		sample("exact_file", "mostly_ai", true, 1500, store.LabelAI),
+		sample("exact_file", "mostly_ai", true, 4000, store.LabelHuman),
+		sample("fuzzy_file", "mostly_ai", true, 800, store.LabelHuman),
+		sample("none", "mostly_human", false, 0, store.LabelAI),
+		sample("none", "mostly_human", false, 0, store.LabelHuman),
+		sample("clipboard", "ai_suggested_human_written", false, 0, store.LabelAI),
+		sample("", "mostly_ai", true, 100, store.LabelAI),
+		sample("exact_file", "mostly_ai", true, 100, ""), // unlabeled
+	})
+
+	want := CalibrationRow{Key: "all", Labeled: 8, TruePos: 4, FalsePos: 2, FalseNeg: 1}
+	if c.Overall != want {
+		t.Errorf("Overall = %+v, want %+v", c.Overall, want)
+	}
+	if p, _ := c.Overall.Precision(); p < 66.6 || p > 66.7 {
+		t.Errorf("precision = %.2f, want 66.67", p)
+	}
+	if r, _ := c.Overall.Recall(); r != 80 {
+		t.Errorf("recall = %.2f, want 80", r)
+	}
+
+	rows := make(map[string]CalibrationRow)
+	for _, r := range c.ByMatchType {
+		rows[r.Key] = r
+	}
+	if r := rows["exact_file"]; r.Labeled != 3 || r.TruePos != 2 || r.FalsePos != 1 {
+		t.Errorf("exact_file = %+v", r)
+	}
+	if r := rows["none"]; r.FalseNeg != 1 || r.TruePos != 0 {
+		t.Errorf("none = %+v", r)
+	}
+	if _, ok := rows["none"].Precision(); ok {
+		t.Error("precision defined for a match type that never says AI")
+	}
+	if r := rows["unrecorded"]; r.TruePos != 1 {
+		t.Errorf("unrecorded = %+v", r)
+	}
+	if c.ByMatchType[0].Key != "exact_file" {
+		t.Errorf("ByMatchType not ordered by labels: %+v", c.ByMatchType)
+	}
+
+	// A 1s window drops the 1.5s true match and the 4s false one.
+	for _, w := range c.ByWindow {
+		if w.Key == "<=1s" {
+			want := CalibrationRow{Key: "<=1s", Labeled: 8, TruePos: 3, FalsePos: 1, FalseNeg: 2}
+			if w != want {
+				t.Errorf("<=1s = %+v, want %+v", w, want)
+			}
+		}
+	}
+	if len(c.ByWindow) != len(CalibrationWindowsMs) || c.ByWindow[0].Key != "<=500ms" {
+		t.Errorf("ByWindow = %+v", c.ByWindow)
+	}
+}
@@ -5,6 +5,7 @@
 package authorship
 
 import (
+	"math"
 	"strings"
 	"time"
 
@@ -29,6 +30,11 @@ const (
 	// Per-event: assigned when no AI session activity is detected.
 	// Per-file: assigned when aggregated AI line ratio < 30%.
 	MostlyHuman AuthorshipLevel = "mostly_human"
+
+	// AISuggestedHumanWritten means a human pasted or retyped code that an
+	// AI tool produced outside of a Write/Edit tool call (e.g. copied from
+	// an assistant message). Assigned per-event on a clipboard match.
+	AISuggestedHumanWritten AuthorshipLevel = "ai_suggested_human_written"
 )
 
 // CorrelationResult is the output of the correlation engine. Defined here
@@ -38,7 +44,13 @@ type CorrelationResult struct {
 	FileEvent      store.FileEvent
 	MatchedSession *store.StoredSessionEvent // nil if no match found
 	TimeDeltaMs    int64                     // absolute ms between events; 0 if no match
-	MatchType      string                    // "exact_file", "fuzzy_file", "none"
+	MatchType      string                    // "exact_file", "fuzzy_file", "clipboard", "typing", "none"
+
+	// ContentChecked reports whether the matched edit was compared with
+	// the file on disk; ContentOverlap is then the share of the edit's
+	// lines found there, formatter differences aside (0 to 1).
+	ContentChecked bool
+	ContentOverlap float64
 }
 
 // Attribution is the final authorship classification for a file event.
@@ -68,9 +80,12 @@ func NewClassifier() *Classifier {
 //
 // Rules (per-event classification):
 //  1. No match (MatchType "none")                    -> MostlyHuman, confidence 1.0
-//  2. Exact file match (any delta)                   -> MostlyAI,    confidence 0.95
+//  2. Exact file match (any delta)                   -> MostlyAI,    confidence 0.95,
+//     or 0.35 + 0.6 x content overlap when the edit was checked against disk
 //  3. Fuzzy file match (same name, different prefix) -> MostlyAI,    confidence 0.85
-//  4. Confidence < 0.5                               -> Uncertain = true
+//  4. Clipboard match (pasted AI output)             -> AISuggestedHumanWritten, confidence 0.6
+//  5. Typing match (editor reported human typing)    -> MostlyHuman, confidence 1.0
+//  6. Confidence < 0.5                               -> Uncertain = true
 func (c *Classifier) Classify(result CorrelationResult) Attribution {
 	attr := Attribution{
 		FilePath:    result.FileEvent.FilePath,
@@ -88,7 +103,12 @@ func (c *Classifier) Classify(result CorrelationResult) Attribution {
 	}
 
 	switch {
-	case result.MatchType == "none" || result.MatchedSession == nil:
+	case result.MatchType == "clipboard":
+		attr.Level = AISuggestedHumanWritten
+		attr.Confidence = 0.6
+		attr.FirstAuthor = "ai"
+
+	case result.MatchType == "none" || result.MatchType == "typing" || result.MatchedSession == nil:
 		attr.Level = MostlyHuman
 		attr.Confidence = 1.0
 		attr.FirstAuthor = "human"
@@ -96,6 +116,9 @@ func (c *Classifier) Classify(result CorrelationResult) Attribution {
 	case result.MatchType == "exact_file":
 		attr.Level = MostlyAI
 		attr.Confidence = 0.95
+		if result.ContentChecked {
+			attr.Confidence = math.Round((0.35+0.6*result.ContentOverlap)*100) / 100
+		}
 		attr.FirstAuthor = "ai"
 
 	case result.MatchType == "fuzzy_file":
@@ -127,7 +150,8 @@ func (c *Classifier) ClassifyWithHistory(result CorrelationResult, priorAttribut
 	}
 
 	// Prior was AI-authored, current has no session match -> human is revising AI code
-	if priorAttribution.FirstAuthor == "ai" &&
+	// (A clipboard paste is still AI-suggested code, so it is not a revision.)
+	if priorAttribution.FirstAuthor == "ai" && result.MatchType != "clipboard" &&
 		(result.MatchType == "none" || result.MatchedSession == nil) {
 		attr := c.Classify(result)
 		attr.Level = Mixed
@@ -163,8 +187,7 @@ func (c *Classifier) ClassifyFromGit(hasCoauthorTag bool, coauthorName string) A
 		Timestamp: time.Now().UTC(),
 	}
 
-	lower := strings.ToLower(coauthorName)
-	if hasCoauthorTag && (strings.Contains(lower, "claude") || strings.Contains(lower, "anthropic")) {
+	if hasCoauthorTag && IsAICoauthor(coauthorName) {
 		attr.Level = MostlyAI
 		attr.Confidence = 0.6
 		attr.FirstAuthor = "ai"
@@ -180,3 +203,42 @@ func (c *Classifier) ClassifyFromGit(hasCoauthorTag bool, coauthorName string) A
 
 	return attr
 }
+
+// IsAICoauthor reports whether a Co-Authored-By name is an AI tool's.
+func IsAICoauthor(name string) bool {
+	lower := strings.ToLower(name)
+	return strings.Contains(lower, "claude") || strings.Contains(lower, "anthropic")
+}
+
+// CoauthorEvidence is the session activity found for a commit: how many of
+// the files it changed, and how many AI edits to them, fell in the window
+// before it.
+type CoauthorEvidence struct {
+	Files         int
+	MatchedFiles  int
+	SessionEvents int
+}
+
+// ClassifyFromGitVerified is ClassifyFromGit with an AI co-author trailer
+// checked against session data. A trailer backed by AI edits to the
+// commit's files gains confidence with the share of files they cover; one
+// with no matching session activity at all drops to low confidence and is
+// marked uncertain, since the trailer may have been added by hand or by a
+// template.
+func (c *Classifier) ClassifyFromGitVerified(hasCoauthorTag bool, coauthorName string, ev CoauthorEvidence) Attribution {
+	attr := c.ClassifyFromGit(hasCoauthorTag, coauthorName)
+	if attr.FirstAuthor != "ai" {
+		return attr
+	}
+	if ev.MatchedFiles == 0 {
+		attr.Confidence = 0.3
+		attr.Uncertain = true
+		return attr
+	}
+	coverage := 1.0
+	if ev.Files > 0 {
+		coverage = float64(ev.MatchedFiles) / float64(ev.Files)
+	}
+	attr.Confidence = 0.6 + 0.35*coverage
+	return attr
+}
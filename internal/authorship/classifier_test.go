@@ -77,6 +77,33 @@ func TestClassify_ExactMatch_MostlyAI(t *testing.T) {
 	}
 }
 
+func TestClassify_ExactMatchContentOverlap(t *testing.T) {
+	c := NewClassifier()
+	se := makeSessionEvent(10, "foo.go", 500*time.Millisecond)
+	for _, tt := range []struct {
+		overlap   float64
+		want      float64
+		uncertain bool
+	}{
+		{1, 0.95, false},
+		{0.5, 0.65, false},
+		{0, 0.35, true},
+	} {
+		attr := c.Classify(CorrelationResult{
+			FileEvent:      makeFileEvent(1, "foo.go"),
+			MatchedSession: ptrSession(se),
+			TimeDeltaMs:    500,
+			MatchType:      "exact_file",
+			ContentChecked: true,
+			ContentOverlap: tt.overlap,
+		})
+		if attr.Level != MostlyAI || attr.Confidence != tt.want || attr.Uncertain != tt.uncertain {
+			t.Errorf("overlap %v: level %s, confidence %v, uncertain %v; want mostly_ai, %v, %v",
+				tt.overlap, attr.Level, attr.Confidence, attr.Uncertain, tt.want, tt.uncertain)
+		}
+	}
+}
+
 func TestClassify_ExactMatchLargerDelta_StillMostlyAI(t *testing.T) {
 	c := NewClassifier()
 	se := makeSessionEvent(10, "foo.go", 3*time.Second)
@@ -316,3 +343,28 @@ func TestAllAuthorshipLevels(t *testing.T) {
 		}
 	}
 }
+
+func TestClassifyFromGitVerified(t *testing.T) {
+	c := NewClassifier()
+	const claude = "Claude <noreply@anthropic.com>"
+
+	full := c.ClassifyFromGitVerified(true, claude, CoauthorEvidence{Files: 2, MatchedFiles: 2, SessionEvents: 5})
+	if full.Level != MostlyAI || full.Confidence != 0.95 || full.Uncertain {
+		t.Errorf("corroborated = %+v, want mostly_ai at 0.95", full)
+	}
+	half := c.ClassifyFromGitVerified(true, claude, CoauthorEvidence{Files: 2, MatchedFiles: 1, SessionEvents: 1})
+	if half.Confidence <= 0.6 || half.Confidence >= full.Confidence {
+		t.Errorf("half corroborated confidence = %v, want between 0.6 and %v", half.Confidence, full.Confidence)
+	}
+
+	none := c.ClassifyFromGitVerified(true, claude, CoauthorEvidence{Files: 3})
+	if none.Level != MostlyAI || none.Confidence != 0.3 || !none.Uncertain {
+		t.Errorf("no session activity = %+v, want uncertain mostly_ai at 0.3", none)
+	}
+
+	// Human co-authors are not checked.
+	human := c.ClassifyFromGitVerified(true, "Ada <ada@example.com>", CoauthorEvidence{})
+	if human.Level != MostlyHuman || human.Confidence != 0.8 {
+		t.Errorf("human co-author = %+v", human)
+	}
+}
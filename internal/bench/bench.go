@@ -0,0 +1,213 @@
+// Package bench times the hot paths of attribution against a real
+// database: session line parsing, line attribution, correlation and
+// report generation. It backs the hidden gapmap bench command, which
+// finds where a user's data makes gap-map slow.
+package bench
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/anthropic/gap-map/internal/correlation"
+	"github.com/anthropic/gap-map/internal/metrics"
+	"github.com/anthropic/gap-map/internal/report"
+	"github.com/anthropic/gap-map/internal/sessionparser"
+	"github.com/anthropic/gap-map/internal/store"
+)
+
+// hotspots is how many of the slowest samples each stage keeps.
+const hotspots = 5
+
+// Budgets are the per-operation times each stage should stay within.
+var Budgets = map[string]time.Duration{
+	"parse":     time.Millisecond,
+	"line":      50 * time.Millisecond,
+	"correlate": 10 * time.Millisecond,
+	"report":    60 * time.Second,
+}
+
+// Sample is one timed operation: the session event, file or project it
+// ran on, and how long it took.
+type Sample struct {
+	Label string        `json:"label"`
+	Took  time.Duration `json:"took_ns"`
+}
+
+// Stage is the timing of one hot path over the sampled data.
+type Stage struct {
+	Name    string        `json:"name"`
+	Ops     int           `json:"ops"`
+	Total   time.Duration `json:"total_ns"`
+	Budget  time.Duration `json:"budget_ns"`
+	Slowest []Sample      `json:"slowest,omitempty"` // slowest first
+}
+
+// PerOp returns the mean time of an operation, or 0 if none ran.
+func (st Stage) PerOp() time.Duration {
+	if st.Ops == 0 {
+		return 0
+	}
+	return st.Total / time.Duration(st.Ops)
+}
+
+// OverBudget reports whether the mean operation took longer than the
+// stage's budget.
+func (st Stage) OverBudget() bool {
+	return st.Budget > 0 && st.PerOp() > st.Budget
+}
+
+// record adds a sample to the stage, keeping the slowest.
+func (st *Stage) record(label string, took time.Duration) {
+	st.Ops++
+	st.Total += took
+	st.Slowest = append(st.Slowest, Sample{Label: label, Took: took})
+	sort.SliceStable(st.Slowest, func(i, j int) bool { return st.Slowest[i].Took > st.Slowest[j].Took })
+	if len(st.Slowest) > hotspots {
+		st.Slowest = st.Slowest[:hotspots]
+	}
+}
+
+// Options configure Run.
+type Options struct {
+	// Samples caps the session events, files and file events each stage
+	// times, taking the most recent. 0 times them all.
+	Samples int
+	// Match is the line matching line attribution uses.
+	Match metrics.MatchOptions
+	// Report configures the project report the report stage times.
+	Report report.Options
+}
+
+// Run times each stage against s and returns them in order: parse, line,
+// correlate and report. It stops early if ctx is cancelled.
+func Run(ctx context.Context, s *store.Store, opts Options) ([]Stage, error) {
+	events, err := s.QueryWriteEditSessionEvents()
+	if err != nil {
+		return nil, fmt.Errorf("query session events: %w", err)
+	}
+	if opts.Samples > 0 && len(events) > opts.Samples {
+		events = events[len(events)-opts.Samples:]
+	}
+
+	var stages []Stage
+	for _, run := range []func(context.Context, *store.Store, []store.StoredSessionEvent, Options) (Stage, error){
+		parse, line, correlate, generate,
+	} {
+		if err := ctx.Err(); err != nil {
+			return stages, err
+		}
+		st, err := run(ctx, s, events, opts)
+		if err != nil {
+			return stages, err
+		}
+		st.Budget = Budgets[st.Name]
+		stages = append(stages, st)
+	}
+	return stages, nil
+}
+
+// parse times the session parser on the stored raw line of each event.
+func parse(ctx context.Context, s *store.Store, events []store.StoredSessionEvent, opts Options) (Stage, error) {
+	st := Stage{Name: "parse"}
+	p := sessionparser.NewClaudeCodeParser("", 0, opts.Report.DiffAlgorithm)
+	for _, se := range events {
+		if err := ctx.Err(); err != nil {
+			return st, err
+		}
+		raw, err := s.QuerySessionEventRawJSON(se.ID)
+		if err != nil || raw == "" {
+			continue
+		}
+		start := time.Now()
+		if _, err := p.ParseLine([]byte(raw)); err != nil {
+			continue
+		}
+		st.record(fmt.Sprintf("session event %d (%s)", se.ID, se.FilePath), time.Since(start))
+	}
+	return st, nil
+}
+
+// line times line attribution of each edited file that still exists
+// against the AI content written to it.
+func line(ctx context.Context, s *store.Store, events []store.StoredSessionEvent, opts Options) (Stage, error) {
+	st := Stage{Name: "line"}
+	contents, err := s.QueryWriteEditSessionContent(sessionparser.ExtractContent)
+	if err != nil {
+		return st, err
+	}
+	var files []string
+	byFile := make(map[string][]string)
+	for _, se := range events {
+		if _, ok := byFile[se.FilePath]; !ok {
+			files = append(files, se.FilePath)
+		}
+		byFile[se.FilePath] = append(byFile[se.FilePath], contents[se.ID].Added)
+	}
+	for _, file := range files {
+		if err := ctx.Err(); err != nil {
+			return st, err
+		}
+		current, err := os.ReadFile(file)
+		if err != nil {
+			continue
+		}
+		start := time.Now()
+		metrics.ComputeLineAttributionWithOptions(string(current), byFile[file], "", opts.Match)
+		st.record(file, time.Since(start))
+	}
+	return st, nil
+}
+
+// correlate times correlation of the most recent file events.
+func correlate(ctx context.Context, s *store.Store, _ []store.StoredSessionEvent, opts Options) (Stage, error) {
+	st := Stage{Name: "correlate"}
+	projects, err := s.QueryProjects()
+	if err != nil {
+		return st, err
+	}
+	var fileEvents []store.FileEvent
+	for _, p := range projects {
+		fes, err := s.QueryFileEventsByProject(p.ProjectPath, time.Time{})
+		if err != nil {
+			return st, fmt.Errorf("query file events: %w", err)
+		}
+		fileEvents = append(fileEvents, fes...)
+	}
+	sort.SliceStable(fileEvents, func(i, j int) bool { return fileEvents[i].Timestamp.Before(fileEvents[j].Timestamp) })
+	if opts.Samples > 0 && len(fileEvents) > opts.Samples {
+		fileEvents = fileEvents[len(fileEvents)-opts.Samples:]
+	}
+
+	c := correlation.New(s)
+	for _, fe := range fileEvents {
+		if err := ctx.Err(); err != nil {
+			return st, err
+		}
+		start := time.Now()
+		if _, err := c.CorrelateFileEvent(fe); err != nil {
+			return st, fmt.Errorf("correlate %s: %w", fe.FilePath, err)
+		}
+		st.record(fmt.Sprintf("file event %d (%s)", fe.ID, fe.FilePath), time.Since(start))
+	}
+	return st, nil
+}
+
+// generate times one project report over the whole database, if there
+// is anything to report.
+func generate(ctx context.Context, s *store.Store, _ []store.StoredSessionEvent, opts Options) (Stage, error) {
+	st := Stage{Name: "report"}
+	start := time.Now()
+	_, err := report.GenerateProject(ctx, s, metrics.DefaultScorer(), report.Filter{}, opts.Report)
+	if errors.Is(err, report.ErrNoData) {
+		return st, nil
+	}
+	if err != nil {
+		return st, fmt.Errorf("generate report: %w", err)
+	}
+	st.record("project report", time.Since(start))
+	return st, nil
+}
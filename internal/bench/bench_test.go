@@ -0,0 +1,82 @@
+package bench
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/anthropic/gap-map/internal/store"
+)
+
+func TestStageRecordKeepsSlowest(t *testing.T) {
+	st := Stage{Name: "parse", Budget: 3 * time.Millisecond}
+	for i := 1; i <= 8; i++ {
+		st.record(string(rune('a'+i-1)), time.Duration(i)*time.Millisecond)
+	}
+	if st.Ops != 8 || st.Total != 36*time.Millisecond {
+		t.Fatalf("ops/total = %d/%v, want 8/36ms", st.Ops, st.Total)
+	}
+	if len(st.Slowest) != hotspots || st.Slowest[0].Label != "h" || st.Slowest[4].Label != "d" {
+		t.Errorf("slowest = %+v, want h..d", st.Slowest)
+	}
+	if st.PerOp() != 4500*time.Microsecond || !st.OverBudget() {
+		t.Errorf("per op = %v, over budget = %v; want 4.5ms, true", st.PerOp(), st.OverBudget())
+	}
+	if (Stage{}).PerOp() != 0 || (Stage{}).OverBudget() {
+		t.Error("empty stage should have no time and be within budget")
+	}
+}
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	s, err := store.New(filepath.Join(dir, "gapmap.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	file := filepath.Join(dir, "main.go")
+	if err := os.WriteFile(file, []byte("package main\n\nfunc main() {}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ts := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	raw := `{"type":"assistant","timestamp":"2026-01-02T03:04:05Z","message":{"content":[{"type":"tool_use","name":"Write","input":{"file_path":"` + file + `","content":"package main\n\nfunc main() {}\n"}}]}}`
+	if err := s.InsertSessionEvents([]store.NewSessionEvent{{
+		SessionID: "s1", EventType: "tool_use", ToolName: "Write",
+		FilePath: file, Timestamp: ts, RawJSON: raw, LinesChanged: 3,
+	}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.InsertFileEvent(dir, file, "write", ts.Add(time.Second)); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.RecordAttributions([]store.AttributionRecord{{
+		FilePath: file, ProjectPath: dir, AuthorshipLevel: "fully_ai",
+		Confidence: 1, FirstAuthor: "ai", Timestamp: ts, LinesChanged: 3,
+	}}, "core_logic"); err != nil {
+		t.Fatal(err)
+	}
+
+	stages, err := Run(context.Background(), s, Options{Samples: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"parse", "line", "correlate", "report"}
+	if len(stages) != len(want) {
+		t.Fatalf("got %d stages, want %d", len(stages), len(want))
+	}
+	for i, st := range stages {
+		if st.Name != want[i] {
+			t.Errorf("stage %d = %s, want %s", i, st.Name, want[i])
+		}
+		if st.Ops != 1 {
+			t.Errorf("%s ran %d ops, want 1", st.Name, st.Ops)
+		}
+		if st.Budget != Budgets[st.Name] {
+			t.Errorf("%s budget = %v, want %v", st.Name, st.Budget, Budgets[st.Name])
+		}
+	}
+}
@@ -0,0 +1,296 @@
+// Package clipboard implements the pasted-AI-code heuristic. A Monitor polls
+// the system clipboard and records hashed snapshots of large text blocks that
+// were copied shortly after AI session activity. A Matcher later checks file
+// events that had no Write/Edit session match against those snapshots, so
+// code copied out of an assistant message and pasted by hand can be
+// attributed as ai_suggested_human_written.
+//
+// Only per-line hashes are stored; the clipboard text itself never reaches
+// the database.
+package clipboard
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/anthropic/gap-map/internal/metrics"
+	"github.com/anthropic/gap-map/internal/store"
+)
+
+// MinLines is the minimum number of non-blank lines a clipboard block needs
+// before it is considered code worth tracking.
+const MinLines = 5
+
+// DefaultOriginWindow is how soon after AI session activity a copy must
+// happen to count as AI-originated.
+const DefaultOriginWindow = 2 * time.Minute
+
+// DefaultPasteWindow is how long after a copy a file event may still be
+// matched against it.
+const DefaultPasteWindow = 10 * time.Minute
+
+// matchThreshold is the fraction of clipboard lines that must appear in the
+// file for a match.
+const matchThreshold = 0.8
+
+// ErrUnsupported is returned when no clipboard reader is available.
+var ErrUnsupported = errors.New("no clipboard reader available")
+
+// Store is the subset of the store the clipboard heuristic needs.
+type Store interface {
+	InsertClipboardSnapshot(snap store.ClipboardSnapshot) (int64, error)
+	QueryUnmatchedClipboardSnapshots(start, end time.Time) ([]store.ClipboardSnapshot, error)
+	MarkClipboardSnapshotMatched(snapshotID, fileEventID int64) error
+	QueryAnySessionEventsNearTimestamp(timestamp time.Time, windowMs int) ([]store.StoredSessionEvent, error)
+}
+
+// Reader returns the current clipboard text.
+type Reader func() (string, error)
+
+// SystemReader returns a Reader for the host platform: pbpaste on macOS;
+// wl-paste, xclip, or xsel on Linux (first one found on PATH).
+func SystemReader() (Reader, error) {
+	var candidates [][]string
+	switch runtime.GOOS {
+	case "darwin":
+		candidates = [][]string{{"pbpaste"}}
+	case "linux":
+		candidates = [][]string{
+			{"wl-paste", "--no-newline"},
+			{"xclip", "-selection", "clipboard", "-o"},
+			{"xsel", "--clipboard", "--output"},
+		}
+	}
+	for _, argv := range candidates {
+		if _, err := exec.LookPath(argv[0]); err != nil {
+			continue
+		}
+		argv := argv
+		return func() (string, error) {
+			out, err := exec.Command(argv[0], argv[1:]...).Output()
+			return string(out), err
+		}, nil
+	}
+	return nil, ErrUnsupported
+}
+
+// Monitor polls the clipboard and records AI-originated snapshots.
+type Monitor struct {
+	store        Store
+	read         Reader
+	interval     time.Duration
+	originWindow time.Duration
+	lastHash     string
+	lastReadErr  string // logged already; cleared by a successful read
+}
+
+// NewMonitor creates a Monitor polling read every interval (default 1s).
+func NewMonitor(s Store, read Reader, interval time.Duration) *Monitor {
+	if interval <= 0 {
+		interval = time.Second
+	}
+	return &Monitor{
+		store:        s,
+		read:         read,
+		interval:     interval,
+		originWindow: DefaultOriginWindow,
+	}
+}
+
+// Run polls until ctx is cancelled.
+func (m *Monitor) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			text, err := m.read()
+			if err != nil {
+				// An empty clipboard fails the same way every poll; log
+				// each failure once.
+				if err.Error() != m.lastReadErr {
+					m.lastReadErr = err.Error()
+					slog.Warn("clipboard: read failed", "err", err)
+				}
+				continue
+			}
+			m.lastReadErr = ""
+			if err := m.Observe(text, time.Now()); err != nil {
+				slog.Warn("clipboard: record failed", "err", err)
+			}
+		}
+	}
+}
+
+// Observe handles one clipboard reading. A snapshot is stored when the text
+// changed since the last reading, is at least MinLines long, and AI session
+// activity happened within the origin window before now.
+func (m *Monitor) Observe(text string, now time.Time) error {
+	hash := hashText(text)
+	if hash == m.lastHash {
+		return nil
+	}
+	m.lastHash = hash
+
+	lines := hashLines(text)
+	if len(lines) < MinLines {
+		return nil
+	}
+
+	recent, err := m.store.QueryAnySessionEventsNearTimestamp(now, int(m.originWindow.Milliseconds()))
+	if err != nil {
+		return err
+	}
+	aiOrigin := false
+	for _, se := range recent {
+		if !se.Timestamp.After(now) {
+			aiOrigin = true
+			break
+		}
+	}
+	if !aiOrigin {
+		return nil
+	}
+
+	_, err = m.store.InsertClipboardSnapshot(store.ClipboardSnapshot{
+		Timestamp:   now,
+		ContentHash: hash,
+		LineHashes:  lines,
+		LineCount:   len(lines),
+	})
+	return err
+}
+
+// Match is a clipboard snapshot that matched a file event.
+type Match struct {
+	SnapshotID int64
+	Lines      int
+}
+
+// maxTrackedFiles bounds how many files a Matcher keeps the content of to
+// diff their next event against. Past it, the contents are dropped and
+// files are diffed against git HEAD again.
+const maxTrackedFiles = 512
+
+// Matcher checks the lines file events add against recent clipboard
+// snapshots.
+type Matcher struct {
+	store         Store
+	pasteWindow   time.Duration
+	diffAlgorithm string
+
+	mu       sync.Mutex
+	previous map[string]string // file content at its last event, by path
+}
+
+// NewMatcher creates a Matcher using DefaultPasteWindow that diffs file
+// contents with diffAlgorithm, one of the metrics.Diff algorithms.
+func NewMatcher(s Store, diffAlgorithm string) *Matcher {
+	return &Matcher{store: s, pasteWindow: DefaultPasteWindow, diffAlgorithm: diffAlgorithm, previous: make(map[string]string)}
+}
+
+// MatchFileEvent returns the newest unmatched snapshot captured within the
+// paste window before fe whose lines appear (>= 80%) among the lines fe
+// added, marking it as matched. content is the file after fe; the added
+// lines are its diff against the content at the file's previous event or,
+// for the first event seen, at git HEAD. Lines the file already had, such
+// as a block copied from elsewhere in it, never match. Returns nil if
+// nothing matches.
+func (m *Matcher) MatchFileEvent(fe store.FileEvent, content string) (*Match, error) {
+	added := m.addedLines(fe.FilePath, content)
+	snaps, err := m.store.QueryUnmatchedClipboardSnapshots(fe.Timestamp.Add(-m.pasteWindow), fe.Timestamp)
+	if err != nil {
+		return nil, err
+	}
+	if len(snaps) == 0 || len(added) == 0 {
+		return nil, nil
+	}
+
+	fileLines := make(map[string]int)
+	for _, h := range hashLines(strings.Join(added, "\n")) {
+		fileLines[h]++
+	}
+
+	for _, snap := range snaps {
+		if snap.LineCount == 0 {
+			continue
+		}
+		avail := make(map[string]int, len(fileLines))
+		for h, n := range fileLines {
+			avail[h] = n
+		}
+		found := 0
+		for _, h := range snap.LineHashes {
+			if avail[h] > 0 {
+				avail[h]--
+				found++
+			}
+		}
+		if float64(found)/float64(snap.LineCount) >= matchThreshold {
+			if err := m.store.MarkClipboardSnapshotMatched(snap.ID, fe.ID); err != nil {
+				return nil, err
+			}
+			return &Match{SnapshotID: snap.ID, Lines: found}, nil
+		}
+	}
+	return nil, nil
+}
+
+// addedLines returns the lines content adds to path since its previous
+// event, or since git HEAD for the first, and records content as the
+// file's latest.
+func (m *Matcher) addedLines(path, content string) []string {
+	m.mu.Lock()
+	before, seen := m.previous[path]
+	if !seen && len(m.previous) >= maxTrackedFiles {
+		m.previous = make(map[string]string)
+	}
+	m.previous[path] = content
+	m.mu.Unlock()
+
+	if !seen {
+		before = headContent(path)
+	}
+	lines := strings.Split(content, "\n")
+	return metrics.AddedLines(lines, metrics.Diff(strings.Split(before, "\n"), lines, m.diffAlgorithm))
+}
+
+// headContent returns the content of path at git HEAD, or "" when it is
+// outside git or not committed.
+func headContent(path string) string {
+	out, err := exec.Command("git", "-C", filepath.Dir(path), "show", "HEAD:./"+filepath.Base(path)).Output()
+	if err != nil {
+		return ""
+	}
+	return string(out)
+}
+
+// hashLines returns the SHA-256 of each non-blank trimmed line, matching
+// the normalization used by metrics.ComputeLineAttribution.
+func hashLines(text string) []string {
+	var out []string
+	for _, line := range strings.Split(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" {
+			continue
+		}
+		out = append(out, hashText(trimmed))
+	}
+	return out
+}
+
+func hashText(s string) string {
+	h := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(h[:])
+}
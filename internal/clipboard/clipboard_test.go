@@ -0,0 +1,191 @@
+package clipboard
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anthropic/gap-map/internal/store"
+)
+
+const snippet = `func add(a, b int) int {
+	return a + b
+}
+
+func sub(a, b int) int {
+	return a - b
+}`
+
+func newTestStore(t *testing.T) *store.Store {
+	t.Helper()
+	s, err := store.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("store.New: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+// TestObserve_RequiresAIOrigin verifies that clipboard blocks are only
+// recorded when AI session activity happened shortly before the copy, and
+// that short blocks are ignored.
+func TestObserve_RequiresAIOrigin(t *testing.T) {
+	s := newTestStore(t)
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	m := NewMonitor(s, nil, 0)
+
+	if err := m.Observe(snippet, now); err != nil {
+		t.Fatalf("Observe: %v", err)
+	}
+	snaps, _ := s.QueryUnmatchedClipboardSnapshots(now.Add(-time.Hour), now)
+	if len(snaps) != 0 {
+		t.Fatalf("got %d snapshots without session activity, want 0", len(snaps))
+	}
+
+	if err := s.InsertSessionEvent("sess1", "assistant", "", "", "", now.Add(-30*time.Second), "{}", 0); err != nil {
+		t.Fatalf("InsertSessionEvent: %v", err)
+	}
+
+	if err := m.Observe("one\ntwo", now); err != nil {
+		t.Fatalf("Observe: %v", err)
+	}
+	if err := m.Observe(snippet, now); err != nil {
+		t.Fatalf("Observe: %v", err)
+	}
+	snaps, err := s.QueryUnmatchedClipboardSnapshots(now.Add(-time.Hour), now)
+	if err != nil {
+		t.Fatalf("QueryUnmatchedClipboardSnapshots: %v", err)
+	}
+	if len(snaps) != 1 {
+		t.Fatalf("got %d snapshots, want 1", len(snaps))
+	}
+	if snaps[0].LineCount != 6 {
+		t.Errorf("LineCount = %d, want 6", snaps[0].LineCount)
+	}
+	for _, h := range snaps[0].LineHashes {
+		if strings.Contains(h, "return") {
+			t.Errorf("line hash %q contains clipboard text", h)
+		}
+	}
+}
+
+// TestMatchFileEvent verifies that a file containing the clipboard block
+// (re-indented) matches once, and unrelated content does not.
+func TestMatchFileEvent(t *testing.T) {
+	s := newTestStore(t)
+	now := time.Now().UTC().Truncate(time.Millisecond)
+
+	_, err := s.InsertClipboardSnapshot(store.ClipboardSnapshot{
+		Timestamp:   now.Add(-time.Minute),
+		ContentHash: hashText(snippet),
+		LineHashes:  hashLines(snippet),
+		LineCount:   len(hashLines(snippet)),
+	})
+	if err != nil {
+		t.Fatalf("InsertClipboardSnapshot: %v", err)
+	}
+
+	if err := s.InsertFileEvent("/p", "/p/math.go", "write", now); err != nil {
+		t.Fatalf("InsertFileEvent: %v", err)
+	}
+	events, err := s.QueryUnprocessedFileEvents(1)
+	if err != nil || len(events) != 1 {
+		t.Fatalf("QueryUnprocessedFileEvents: %v (%d events)", err, len(events))
+	}
+	fe := events[0]
+	matcher := NewMatcher(s, "")
+
+	match, err := matcher.MatchFileEvent(fe, "package math\n\nimport \"fmt\"\n")
+	if err != nil {
+		t.Fatalf("MatchFileEvent: %v", err)
+	}
+	if match != nil {
+		t.Fatalf("unrelated content matched snapshot %d", match.SnapshotID)
+	}
+
+	pasted := "package math\n\n" + strings.ReplaceAll(snippet, "\t", "    ") + "\n"
+	match, err = matcher.MatchFileEvent(fe, pasted)
+	if err != nil {
+		t.Fatalf("MatchFileEvent: %v", err)
+	}
+	if match == nil {
+		t.Fatal("expected pasted content to match")
+	}
+	if match.Lines != 6 {
+		t.Errorf("Lines = %d, want 6", match.Lines)
+	}
+
+	// A matched snapshot is not reused.
+	match, err = matcher.MatchFileEvent(fe, pasted)
+	if err != nil {
+		t.Fatalf("MatchFileEvent: %v", err)
+	}
+	if match != nil {
+		t.Error("snapshot matched twice")
+	}
+}
+
+// TestMatchFileEventExistingLines verifies that an edit does not match a
+// snapshot whose lines the file already had, whether committed or seen at
+// the file's previous event, as when the block was copied from the file
+// itself.
+func TestMatchFileEventExistingLines(t *testing.T) {
+	s := newTestStore(t)
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	if _, err := s.InsertClipboardSnapshot(store.ClipboardSnapshot{
+		Timestamp:   now.Add(-time.Minute),
+		ContentHash: hashText(snippet),
+		LineHashes:  hashLines(snippet),
+		LineCount:   len(hashLines(snippet)),
+	}); err != nil {
+		t.Fatalf("InsertClipboardSnapshot: %v", err)
+	}
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "math.go")
+	before := "package math\n\n" + snippet + "\n"
+	if err := os.WriteFile(path, []byte(before), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"add", "math.go"},
+		{"-c", "user.name=t", "-c", "user.email=t@t", "commit", "-q", "-m", "math"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+
+	if err := s.InsertFileEvent(dir, path, "write", now); err != nil {
+		t.Fatalf("InsertFileEvent: %v", err)
+	}
+	events, err := s.QueryUnprocessedFileEvents(1)
+	if err != nil || len(events) != 1 {
+		t.Fatalf("QueryUnprocessedFileEvents: %v (%d events)", err, len(events))
+	}
+	fe := events[0]
+	matcher := NewMatcher(s, "")
+
+	// A human edit to a committed file that holds the block.
+	edited := before + "\nvar version = 2\n"
+	if match, err := matcher.MatchFileEvent(fe, edited); err != nil || match != nil {
+		t.Fatalf("edit beside committed lines: match %+v, err %v", match, err)
+	}
+
+	// The next edit is diffed against that event's content, not HEAD.
+	edited += "var name = \"math\"\n"
+	if match, err := matcher.MatchFileEvent(fe, edited); err != nil || match != nil {
+		t.Fatalf("edit beside previously seen lines: match %+v, err %v", match, err)
+	}
+
+	// Pasting the block a second time adds its lines.
+	if match, err := matcher.MatchFileEvent(fe, edited+"\n"+snippet+"\n"); err != nil || match == nil {
+		t.Fatalf("pasted block: match %+v, err %v", match, err)
+	}
+}
@@ -2,9 +2,16 @@ package config
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
+
+	"github.com/anthropic/gap-map/internal/gitexec"
+	"github.com/anthropic/gap-map/internal/logging"
+	"github.com/anthropic/gap-map/internal/metrics"
 )
 
 // Config holds all daemon configuration.
@@ -14,15 +21,370 @@ type Config struct {
 	DBPath         string   `json:"db_path"`
 	WatchPaths     []string `json:"watch_paths"`
 	IgnorePatterns []string `json:"ignore_patterns"`
+
+	// WatchExclude lists directory names (or globs) that are never watched,
+	// at any depth below a watch path, and whose events are dropped. The
+	// defaults keep dependency and build trees from exhausting the
+	// system's file watch limit; set it to [] to watch them.
+	WatchExclude []string `json:"watch_exclude"`
+
+	// SessionDirs are the directories Claude Code session files are read
+	// from, for setups with CLAUDE_CONFIG_DIR or several accounts. Empty
+	// reads ~/.claude/projects and, when CLAUDE_CONFIG_DIR is set in the
+	// daemon's environment, its projects directory (see SessionRoots).
+	SessionDirs []string `json:"session_dirs"`
+
+	// SessionProviders are plugins that feed gap-map the sessions of AI
+	// tools it does not know, through sessionparser.GenericProvider.
+	SessionProviders []SessionProvider `json:"session_providers"`
+
+	// TailerCheckpointLines and TailerCheckpointInterval bound how much
+	// session progress a killed daemon loses: each session tailer persists
+	// the read position of the lines it has stored once that many lines or
+	// that much time have gone by since it last did, at the end of a batch.
+	TailerCheckpointLines    int    `json:"tailer_checkpoint_lines"`
+	TailerCheckpointInterval string `json:"tailer_checkpoint_interval"`
+
+	// WatchMode is how the watcher notices changes: "notify" uses the
+	// system's file notifications only, "poll" scans the watched trees
+	// every WatchPollInterval instead, and "auto" (default) uses
+	// notifications and polls the directories it could not watch, e.g.
+	// once the system's watch limit is reached.
+	WatchMode         string `json:"watch_mode"`
+	WatchPollInterval string `json:"watch_poll_interval"`
+
+	// UntrackedRepoThreshold is how many AI edits to files of a repository
+	// outside every watch path the daemon sees before it suggests tracking
+	// the repository, in its log and gapmap status. 0 turns it off.
+	UntrackedRepoThreshold int `json:"untracked_repo_threshold"`
+
+	// Telemetry is opt-in. When enabled, coarse aggregate metrics (never
+	// file paths or code) are appended to telemetry.jsonl in DataDir, or
+	// posted to TelemetryEndpoint if set.
+	TelemetryEnabled  bool   `json:"telemetry_enabled"`
+	TelemetryEndpoint string `json:"telemetry_endpoint"`
+	TelemetryInterval string `json:"telemetry_interval"`
+
+	// LeaderboardOptIn adds this developer's weekly totals -- surviving AI
+	// lines, human lines, and AI lines since rewritten -- to telemetry
+	// snapshots, for a team leaderboard built from what TelemetryEndpoint
+	// collects. Entries carry LeaderboardName unless LeaderboardAnonymize
+	// is set or no name is given; anonymized entries use an ID that changes
+	// every week.
+	LeaderboardOptIn     bool   `json:"leaderboard_opt_in"`
+	LeaderboardName      string `json:"leaderboard_name"`
+	LeaderboardAnonymize bool   `json:"leaderboard_anonymize"`
+
+	// ClipboardMonitor enables the pasted-code heuristic: large clipboard
+	// blocks copied right after AI session activity are hashed (never
+	// stored as text) and matched against later file edits.
+	ClipboardMonitor  bool   `json:"clipboard_monitor"`
+	ClipboardInterval string `json:"clipboard_interval"`
+
+	// RecordPrompts stores, with each AI edit, the text of the user prompt
+	// that led to it (credentials redacted, cut to 200 characters), so
+	// "gapmap analyze --file --explain" can show what was asked for. When
+	// off, only the prompt's message id is kept.
+	RecordPrompts bool `json:"record_prompts"`
+
+	// HumanSnapshots records what a human changed: for each file event
+	// attributed to a human, the file's diff against git HEAD (or, outside
+	// git, its content) is stored if it is at most HumanSnapshotMaxBytes.
+	// Reports then count human lines from what was seen instead of
+	// inferring them as everything not matched to AI.
+	HumanSnapshots        bool `json:"human_snapshots"`
+	HumanSnapshotMaxBytes int  `json:"human_snapshot_max_bytes"`
+
+	// ProjectTrust decides which projects the daemon records anything
+	// for: "all" (default) records every project but those in
+	// DisabledProjects, "allowlist" only those in EnabledProjects. Entries
+	// cover the directories below them; the longest entry containing a
+	// path wins. "gapmap enable" and "gapmap disable" edit the lists.
+	ProjectTrust     string   `json:"project_trust"`
+	EnabledProjects  []string `json:"enabled_projects"`
+	DisabledProjects []string `json:"disabled_projects"`
+
+	// Scorer selects how the meaningful AI percentage weights work types:
+	// "weighted" (default tier weights), "raw" (all lines equal), or
+	// "custom" (ScorerWeights override the defaults per work type; a weight
+	// of 0 excludes that work type).
+	Scorer        string             `json:"scorer"`
+	ScorerWeights map[string]float64 `json:"scorer_weights"`
+
+	// LineMatch selects how changed lines are matched against AI output:
+	// "exact" (default; equal after trimming) or "normalized" (whitespace
+	// collapsed, quotes unified, trailing commas/semicolons dropped).
+	// SimilarityThreshold, when above 0, also attributes lines whose token
+	// overlap with an AI line reaches it. The project_ maps override both
+	// per project path.
+	LineMatch                  string             `json:"line_match"`
+	SimilarityThreshold        float64            `json:"similarity_threshold"`
+	ProjectLineMatch           map[string]string  `json:"project_line_match"`
+	ProjectSimilarityThreshold map[string]float64 `json:"project_similarity_threshold"`
+
+	// RevisionThreshold keeps a line Claude wrote and a human then edited
+	// attributed to AI, counted as revised, while its token edit distance
+	// to the AI line leaves at least this similarity (0-1; 0 turns it
+	// off).
+	RevisionThreshold float64 `json:"revision_threshold"`
+
+	// ExcludeComments leaves comment-only lines out of reports' line
+	// counts, as blank lines are, for the languages metrics.CommentLines
+	// knows.
+	ExcludeComments bool `json:"exclude_comments"`
+
+	// CorrelationWindow is how far apart a file change and an AI Write or
+	// Edit of the file can be and still be matched. gapmap calibrate shows
+	// how precision and recall would move with a narrower window.
+	CorrelationWindow string `json:"correlation_window"`
+
+	// ClockSkewWindow is the largest clock difference between the machine
+	// an AI session runs on and this one that correlation compensates
+	// for, as when Claude Code runs in a remote devcontainer and files
+	// sync back. Each session's offset is estimated from its Writes and
+	// the file changes that follow them. "0" turns compensation off.
+	ClockSkewWindow string `json:"clock_skew_window"`
+
+	// BulkEventThreshold marks file events as a bulk change (a branch
+	// switch, a repo-wide format) when more than this many distinct files
+	// change within BulkEventWindow; 0 disables detection. BulkEvents
+	// decides what happens to them: "defer" (default) attributes them after
+	// all other events, "skip" drops them.
+	BulkEventThreshold int    `json:"bulk_event_threshold"`
+	BulkEventWindow    string `json:"bulk_event_window"`
+	BulkEvents         string `json:"bulk_events"`
+
+	// GitOperationEvents decides what happens to file events caused by a
+	// git checkout, rebase, merge or reset: "tag" (default) keeps them but
+	// never attributes them, "drop" discards them, "off" treats them as
+	// edits.
+	GitOperationEvents string `json:"git_operation_events"`
+
+	// Packages maps path globs, relative to the project root, to package
+	// names for monorepos (e.g. "services/api/**" -> "api"). Reports and
+	// PR comments then break down by package; a file in several packages
+	// belongs to the longest matching glob.
+	Packages map[string]string `json:"packages"`
+
+	// MaxFileLines guards against large generated files (bundles,
+	// lockfiles, fixtures): a file with more lines is left out of reports
+	// and not watched, or, when LargeFiles is "cap", attributed on its
+	// first MaxFileLines changed lines only. 0 disables the limit.
+	// BinaryFiles is "exclude" (default) or "include". Reports list the
+	// files each guard kept out.
+	MaxFileLines int    `json:"max_file_lines"`
+	LargeFiles   string `json:"large_files"`
+	BinaryFiles  string `json:"binary_files"`
+
+	// LogLevel is the lowest level the daemon logs: "debug", "info"
+	// (default), "warn" or "error". LogFormat is "text" (default) or
+	// "json". The log rotates once it exceeds LogMaxSizeMB or is older
+	// than LogRotateInterval, keeping LogMaxBackups rotated files; 0
+	// disables each limit.
+	LogLevel          string `json:"log_level"`
+	LogFormat         string `json:"log_format"`
+	LogMaxSizeMB      int    `json:"log_max_size_mb"`
+	LogRotateInterval string `json:"log_rotate_interval"`
+	LogMaxBackups     int    `json:"log_max_backups"`
+
+	// PRRefresh has the daemon keep the gap-map comment on the open PRs
+	// of the first watch path's GitHub repository current: when a PR's
+	// branch is pushed, it regenerates the branch report and updates the
+	// comment. Pushes are noticed by polling the remote every
+	// PRRefreshInterval and, when PRWebhookAddr is set, at once from
+	// GitHub push and pull_request webhooks signed with PRWebhookSecret.
+	// The token comes from GITHUB_TOKEN or the gh CLI's login.
+	PRRefresh         bool   `json:"pr_refresh"`
+	PRRefreshInterval string `json:"pr_refresh_interval"`
+	PRWebhookAddr     string `json:"pr_webhook_addr"`
+	PRWebhookSecret   string `json:"pr_webhook_secret"`
+
+	// PRCommentSkipDrafts, PRCommentMinAIPct and PRCommentCollapseOver
+	// keep PR comments (from pr-comment, ci and the daemon's refresh) out
+	// of the way: no comment on draft PRs, none unless the meaningful AI%
+	// reaches the threshold (0 always comments), and everything below the
+	// headline folded into a <details> block once the comment is longer
+	// than that many bytes (0 never folds). The commands' flags override
+	// them.
+	PRCommentSkipDrafts   bool    `json:"pr_comment_skip_drafts"`
+	PRCommentMinAIPct     float64 `json:"pr_comment_min_ai_pct"`
+	PRCommentCollapseOver int     `json:"pr_comment_collapse_over"`
+
+	// The PRCommentNotable settings pick the files PR comments list as
+	// notable: those with at least the minimum events, changed lines and
+	// meaningful AI%, ordered by PRCommentNotableSort ("ai_pct", "lines"
+	// or "events"), at most PRCommentNotableMax of them (0 lists none).
+	// PRCommentNotableByWorkType lists them in a table per work type,
+	// each of up to PRCommentNotableMax files.
+	PRCommentNotableMinEvents  int     `json:"pr_comment_notable_min_events"`
+	PRCommentNotableMinLines   int     `json:"pr_comment_notable_min_lines"`
+	PRCommentNotableMinAIPct   float64 `json:"pr_comment_notable_min_ai_pct"`
+	PRCommentNotableMax        int     `json:"pr_comment_notable_max"`
+	PRCommentNotableSort       string  `json:"pr_comment_notable_sort"`
+	PRCommentNotableByWorkType bool    `json:"pr_comment_notable_by_work_type"`
+
+	// PRAnnotate adds inline review comments to PRs (from pr-comment and
+	// ci) on the hunks that are at least 90% AI-written in core_logic
+	// files, at most PRMaxAnnotations of them, largest first.
+	PRAnnotate       bool `json:"pr_annotate"`
+	PRMaxAnnotations int  `json:"pr_max_annotations"`
+
+	// GitHubRemote names the git remote of the GitHub repository that PRs
+	// are opened against, for pr-comment, ci and the daemon's PR refresh.
+	// Empty picks "upstream", then "origin", then the first other remote
+	// on GitHub, which suits fork workflows.
+	GitHubRemote string `json:"github_remote"`
+
+	// GitHubTimeout bounds each GitHub API request; GitHubMaxRetries is
+	// how often a request is retried after a network error, a 5xx
+	// response or a rate limit, waiting as GitHub asks or backing off
+	// exponentially.
+	GitHubTimeout    string `json:"github_timeout"`
+	GitHubMaxRetries int    `json:"github_max_retries"`
+
+	// GitMaxProcesses bounds how many git commands a report runs at once;
+	// file contents are read through one git cat-file process per
+	// repository besides. GitCacheMB bounds the memory kept for file
+	// contents at commits, which reports read again and again.
+	GitMaxProcesses int `json:"git_max_processes"`
+	GitCacheMB      int `json:"git_cache_mb"`
+
+	// DiffAlgorithm is the line diff that counts the lines a Write event
+	// changes and that reports ask git for: "myers", "patience" or
+	// "histogram". Patience and histogram anchor on rare lines, so moved
+	// blocks and shared braces pair up less arbitrarily.
+	DiffAlgorithm string `json:"diff_algorithm"`
+
+	// APIAddr, when set, has the daemon serve reports over HTTP on that
+	// address, for gapmap analyze --remote; requests must carry APIToken
+	// as a bearer token. With APITLSCert and APITLSKey (PEM files) it
+	// serves HTTPS; without them the token crosses the network in the
+	// clear, so bind it to localhost and reach it through an SSH tunnel
+	// or a TLS proxy.
+	APIAddr    string `json:"api_addr"`
+	APIToken   string `json:"api_token"`
+	APITLSCert string `json:"api_tls_cert"`
+	APITLSKey  string `json:"api_tls_key"`
+
+	// Supervise runs the background daemon under a supervisor process that
+	// restarts it after a crash, waiting longer after each crash in a row.
+	// gapmap status reports the crashes.
+	Supervise bool `json:"supervise"`
+
+	// DisplayTimezone is the time zone commands show times in and JSON
+	// output gives local times in, next to UTC: an IANA name such as
+	// "Europe/Berlin", "UTC", or empty for the system's zone. Times are
+	// always stored in UTC.
+	DisplayTimezone string `json:"display_timezone"`
+
+	// NumberLocale is the locale text reports write numbers in: a name
+	// such as "de" or "fr_FR.UTF-8", "auto" for the environment's locale,
+	// or empty for the C locale ("1234.5"), which scripts can parse.
+	// --machine overrides it. JSON output always uses the C locale.
+	NumberLocale string `json:"number_locale"`
+
+	// TokenPrices prices AI token usage for the cost section of the
+	// survival report, keyed by model name or a prefix of it (e.g.
+	// "claude-sonnet-4"). Models without a price are reported in tokens
+	// only.
+	TokenPrices map[string]TokenPrice `json:"token_prices"`
+
+	// SurvivalInterval is how often the daemon checks the survival of each
+	// project's AI code against git blame and records it, so gapmap
+	// survival reads the latest check and --history shows survival over
+	// time. "0" turns the job off.
+	SurvivalInterval string `json:"survival_interval"`
+
+	// CorrectionWindowDays is how many days after an AI edit a human
+	// change to its lines counts as a correction, for the correction rate
+	// of analyze and survival.
+	CorrectionWindowDays int `json:"correction_window_days"`
+
+	// MaintenanceTime is the local time of day, "15:04", from which the
+	// daemon runs its daily database maintenance: pruning blame data of
+	// deleted files, refreshing query statistics and vacuuming free
+	// pages. "off" turns it off.
+	MaintenanceTime string `json:"maintenance_time"`
+
+	// RemoteAgents are remote hosts whose events the daemon forwards over
+	// ssh from a "gapmap agent" running there, for AI sessions and
+	// checkouts that live in a devcontainer, a Codespace or on another
+	// machine. Their events are recorded with the remote host's name.
+	RemoteAgents []RemoteAgent `json:"remote_agents"`
+}
+
+// RemoteAgent declares a remote host to forward events from.
+type RemoteAgent struct {
+	Target  string   `json:"target"`   // ssh destination, e.g. "user@devbox"
+	Command string   `json:"command"`  // gapmap on the remote host; default "gapmap"
+	SSHArgs []string `json:"ssh_args"` // extra ssh options
+	PathMap []string `json:"path_map"` // "remote=local" path prefixes to rewrite
+}
+
+// TokenPrice is what a model charges, in USD per million tokens.
+type TokenPrice struct {
+	Input      float64 `json:"input"`
+	Output     float64 `json:"output"`
+	CacheWrite float64 `json:"cache_write"` // input written to the prompt cache
+	CacheRead  float64 `json:"cache_read"`  // input read from the prompt cache
+}
+
+// SessionProvider declares a session provider plugin: an external command
+// speaking the plugin protocol on stdin and stdout.
+type SessionProvider struct {
+	Name    string   `json:"name"`    // recorded as the sessions' provider
+	Command []string `json:"command"` // program and arguments
 }
 
-// DefaultDataDir returns the default data directory (~/.gapmap).
+// DefaultWatchExclude is the default WatchExclude.
+var DefaultWatchExclude = []string{"node_modules", ".git", "vendor", "target", "dist", "build"}
+
+// Values of WatchMode.
+const (
+	WatchModeAuto   = "auto"
+	WatchModeNotify = "notify"
+	WatchModePoll   = "poll"
+)
+
+// Values of BulkEvents.
+const (
+	BulkEventsDefer = "defer"
+	BulkEventsSkip  = "skip"
+)
+
+// Values of GitOperationEvents.
+const (
+	GitOperationEventsTag  = "tag"
+	GitOperationEventsDrop = "drop"
+	GitOperationEventsOff  = "off"
+)
+
+// Values of ProjectTrust.
+const (
+	ProjectTrustAll       = "all"
+	ProjectTrustAllowlist = "allowlist"
+)
+
+// Values of LargeFiles.
+const (
+	LargeFilesExclude = "exclude"
+	LargeFilesCap     = "cap"
+)
+
+// Values of BinaryFiles.
+const (
+	BinaryFilesExclude = "exclude"
+	BinaryFilesInclude = "include"
+)
+
+// DefaultDataDir returns the data directory of the active profile:
+// $GAPMAP_DATA_DIR when set, otherwise ~/.gapmap for the default profile
+// and ~/.gapmap/profiles/<name> for the others.
 func DefaultDataDir() string {
-	home, err := os.UserHomeDir()
-	if err != nil {
-		home = "."
+	if dir := os.Getenv(EnvDataDir); dir != "" {
+		return expandTilde(dir)
 	}
-	return filepath.Join(home, ".gapmap")
+	return ProfileDataDir(Profile())
 }
 
 // Default returns a Config with sensible defaults.
@@ -41,9 +403,68 @@ func Default() *Config {
 			"*.swp",
 			"*.swo",
 		},
+		WatchExclude:      append([]string(nil), DefaultWatchExclude...),
+		WatchMode:         WatchModeAuto,
+		WatchPollInterval: "5s",
+		TelemetryInterval: "24h",
+		ClipboardInterval: "1s",
+		RecordPrompts:     true,
+		ProjectTrust:      ProjectTrustAll,
+		EnabledProjects:   []string{},
+		DisabledProjects:  []string{},
+		Scorer:            "weighted",
+		LineMatch:         "exact",
+		RevisionThreshold: 0.8,
+
+		CorrelationWindow: "5s",
+		ClockSkewWindow:   "2m",
+
+		BulkEventThreshold: 50,
+		BulkEventWindow:    "2s",
+		BulkEvents:         BulkEventsDefer,
+		GitOperationEvents: GitOperationEventsTag,
+
+		MaxFileLines: 20000,
+		LargeFiles:   LargeFilesExclude,
+		BinaryFiles:  BinaryFilesExclude,
+
+		LogLevel:          "info",
+		LogFormat:         logging.FormatText,
+		LogMaxSizeMB:      10,
+		LogRotateInterval: "24h",
+		LogMaxBackups:     5,
+
+		PRRefreshInterval: "2m",
+		PRMaxAnnotations:  10,
+		GitHubTimeout:     "30s",
+		GitHubMaxRetries:  4,
+		GitMaxProcesses:   8,
+		GitCacheMB:        64,
+		DiffAlgorithm:     "myers",
+
+		PRCommentNotableMinEvents: 3,
+		PRCommentNotableMax:       5,
+		PRCommentNotableSort:      "ai_pct",
+
+		HumanSnapshotMaxBytes: 64 * 1024,
+
+		TailerCheckpointLines:    500,
+		TailerCheckpointInterval: "5s",
+
+		SurvivalInterval:     "6h",
+		CorrectionWindowDays: 14,
+		MaintenanceTime:      "03:00",
+
+		UntrackedRepoThreshold: 5,
+
+		Supervise: true,
 	}
 }
 
+// ErrUnreadable is wrapped by the errors Load returns for a config file
+// that exists but cannot be read or parsed.
+var ErrUnreadable = errors.New("config file unreadable")
+
 // Load reads configuration from a JSON file, falling back to defaults
 // for any unset fields.
 func Load(path string) (*Config, error) {
@@ -55,11 +476,11 @@ func Load(path string) (*Config, error) {
 			// No config file is fine, use defaults.
 			return cfg, nil
 		}
-		return nil, err
+		return nil, fmt.Errorf("%w: %w", ErrUnreadable, err)
 	}
 
 	if err := json.Unmarshal(data, cfg); err != nil {
-		return nil, err
+		return nil, fmt.Errorf("%w: %s: %w", ErrUnreadable, path, err)
 	}
 
 	// Expand ~ in all path fields.
@@ -69,6 +490,9 @@ func Load(path string) (*Config, error) {
 	for i, p := range cfg.WatchPaths {
 		cfg.WatchPaths[i] = expandTilde(p)
 	}
+	for i, p := range cfg.SessionDirs {
+		cfg.SessionDirs[i] = expandTilde(p)
+	}
 
 	// Re-derive paths if DataDir was overridden but socket/db paths were not.
 	if cfg.SocketPath == "" {
@@ -98,6 +522,178 @@ func (c *Config) EnsureDataDir() error {
 	return os.MkdirAll(c.DataDir, 0755)
 }
 
+// DryRun returns a copy of the config for a dry run with its data under
+// dir. Everything that reaches beyond that directory is switched off:
+// telemetry and the leaderboard, PR comment refresh and its webhook, the
+// report API and remote agent forwarding.
+func (c *Config) DryRun(dir string) *Config {
+	dry := *c
+	dry.DataDir = dir
+	dry.DBPath = filepath.Join(dir, "gapmap.db")
+	dry.SocketPath = filepath.Join(dir, "gapmap.sock")
+	dry.TelemetryEnabled = false
+	dry.LeaderboardOptIn = false
+	dry.PRRefresh = false
+	dry.PRWebhookAddr = ""
+	dry.APIAddr = ""
+	dry.RemoteAgents = nil
+	return &dry
+}
+
+// SessionRoots returns the directories to read Claude Code sessions from:
+// SessionDirs, or by default ~/.claude/projects and, if CLAUDE_CONFIG_DIR
+// is set, $CLAUDE_CONFIG_DIR/projects. Duplicates are dropped.
+func (c *Config) SessionRoots() []string {
+	dirs := c.SessionDirs
+	if len(dirs) == 0 {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			home = "."
+		}
+		dirs = []string{filepath.Join(home, ".claude", "projects")}
+		if dir := os.Getenv("CLAUDE_CONFIG_DIR"); dir != "" {
+			dirs = append(dirs, filepath.Join(expandTilde(dir), "projects"))
+		}
+	}
+
+	var roots []string
+	seen := make(map[string]bool, len(dirs))
+	for _, d := range dirs {
+		d = filepath.Clean(d)
+		if !seen[d] {
+			seen[d] = true
+			roots = append(roots, d)
+		}
+	}
+	return roots
+}
+
+// TelemetryPath returns the local JSONL file telemetry snapshots are
+// appended to when no endpoint is configured.
+func (c *Config) TelemetryPath() string {
+	return filepath.Join(c.DataDir, "telemetry.jsonl")
+}
+
+// MatchOptions returns the line matching options for a project: those set
+// in its repository's .gapmap.toml, then the project_line_match and
+// project_similarity_threshold entries for its path when present,
+// otherwise the global settings, and whether to exclude comments. Invalid
+// settings fall back to exact matching; Validate reports them.
+func (c *Config) MatchOptions(projectPath string) metrics.MatchOptions {
+	mode, threshold, revision := c.LineMatch, c.SimilarityThreshold, c.RevisionThreshold
+	for p, m := range c.ProjectLineMatch {
+		if sameProject(p, projectPath) {
+			mode = m
+		}
+	}
+	for p, t := range c.ProjectSimilarityThreshold {
+		if sameProject(p, projectPath) {
+			threshold = t
+		}
+	}
+	if repo, _ := FindRepoSettings(projectPath); repo != nil {
+		if repo.LineMatch != "" {
+			mode = repo.LineMatch
+		}
+		if repo.SimilarityThreshold != nil {
+			threshold = *repo.SimilarityThreshold
+		}
+		if repo.RevisionThreshold != nil {
+			revision = *repo.RevisionThreshold
+		}
+	}
+	opts, err := metrics.NewMatchOptions(mode, threshold)
+	if err != nil {
+		opts = metrics.MatchOptions{}
+	}
+	if revision > 0 && revision <= 1 {
+		opts.Revision = revision
+	}
+	opts.ExcludeComments = c.ExcludeComments
+	return opts
+}
+
+// LogPath returns the daemon's log file.
+func (c *Config) LogPath() string {
+	return filepath.Join(c.DataDir, "daemon.log")
+}
+
+// LogOptions returns the daemon log settings. Invalid settings fall back
+// to the defaults; Validate reports them.
+func (c *Config) LogOptions() logging.Options {
+	interval, _ := time.ParseDuration(c.LogRotateInterval)
+	return logging.Options{
+		Level:      c.LogLevel,
+		Format:     c.LogFormat,
+		MaxSize:    int64(c.LogMaxSizeMB) << 20,
+		Interval:   interval,
+		MaxBackups: c.LogMaxBackups,
+	}
+}
+
+// Location returns the DisplayTimezone location. An unknown zone falls
+// back to the system's; Validate reports it.
+func (c *Config) Location() *time.Location {
+	if c.DisplayTimezone == "" {
+		return time.Local
+	}
+	loc, err := time.LoadLocation(c.DisplayTimezone)
+	if err != nil {
+		return time.Local
+	}
+	return loc
+}
+
+// MaintenanceOff is the MaintenanceTime that turns maintenance off.
+const MaintenanceOff = "off"
+
+// MaintenanceClock returns the hour and minute of MaintenanceTime, and
+// false if maintenance is off or the time is invalid.
+func (c *Config) MaintenanceClock() (hour, minute int, ok bool) {
+	if c.MaintenanceTime == "" || c.MaintenanceTime == MaintenanceOff {
+		return 0, 0, false
+	}
+	t, err := time.Parse("15:04", c.MaintenanceTime)
+	if err != nil {
+		return 0, 0, false
+	}
+	return t.Hour(), t.Minute(), true
+}
+
+// FileGuard returns the large and binary file guards from MaxFileLines,
+// LargeFiles and BinaryFiles.
+func (c *Config) FileGuard() metrics.FileGuard {
+	return metrics.FileGuard{
+		MaxLines:    c.MaxFileLines,
+		Cap:         c.LargeFiles == LargeFilesCap,
+		AllowBinary: c.BinaryFiles == BinaryFilesInclude,
+	}
+}
+
+// GitExecutor returns an executor for report git commands with the
+// GitMaxProcesses and GitCacheMB limits. Invalid limits keep gitexec's
+// defaults; Validate reports them.
+func (c *Config) GitExecutor() *gitexec.Executor {
+	if c.GitMaxProcesses < 1 || c.GitCacheMB < 0 {
+		return gitexec.New(gitexec.DefaultMaxProcesses, gitexec.DefaultCacheBytes)
+	}
+	return gitexec.New(c.GitMaxProcesses, int64(c.GitCacheMB)<<20)
+}
+
+// CorrectionWindow returns CorrectionWindowDays as a duration, or
+// survival's default when it is invalid; Validate reports that.
+func (c *Config) CorrectionWindow() time.Duration {
+	if c.CorrectionWindowDays < 1 {
+		return 14 * 24 * time.Hour
+	}
+	return time.Duration(c.CorrectionWindowDays) * 24 * time.Hour
+}
+
+// sameProject reports whether a configured project path names projectPath.
+func sameProject(configured, projectPath string) bool {
+	return filepath.Clean(expandTilde(configured)) == filepath.Clean(projectPath)
+}
+
 // ConfigPath returns the default path to the config file.
 func ConfigPath() string {
 	return filepath.Join(DefaultDataDir(), "config.json")
@@ -0,0 +1,51 @@
+package config
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDryRun(t *testing.T) {
+	cfg := Default()
+	cfg.DBPath = "/home/me/.gapmap/gapmap.db"
+	cfg.TelemetryEnabled = true
+	cfg.LeaderboardOptIn = true
+	cfg.PRRefresh = true
+	cfg.PRWebhookAddr = "127.0.0.1:8790"
+	cfg.APIAddr = "127.0.0.1:8791"
+	cfg.RemoteAgents = []RemoteAgent{{Target: "me@devbox"}}
+	cfg.WatchPaths = []string{"/work/api"}
+
+	dir := t.TempDir()
+	dry := cfg.DryRun(dir)
+
+	if cfg.DBPath != "/home/me/.gapmap/gapmap.db" || !cfg.PRRefresh || len(cfg.RemoteAgents) != 1 {
+		t.Errorf("DryRun changed the original config: %+v", cfg)
+	}
+	for name, path := range map[string]string{
+		"DataDir":    dry.DataDir,
+		"DBPath":     dry.DBPath,
+		"SocketPath": dry.SocketPath,
+		"telemetry":  dry.TelemetryPath(),
+	} {
+		if path != dir && !strings.HasPrefix(path, dir+string(filepath.Separator)) {
+			t.Errorf("%s = %s, want under %s", name, path, dir)
+		}
+	}
+	if dry.TelemetryEnabled || dry.LeaderboardOptIn {
+		t.Error("dry run keeps telemetry on")
+	}
+	if dry.PRRefresh || dry.PRWebhookAddr != "" {
+		t.Error("dry run keeps PR comment refresh on")
+	}
+	if dry.APIAddr != "" {
+		t.Error("dry run keeps the report API on")
+	}
+	if len(dry.RemoteAgents) != 0 {
+		t.Error("dry run keeps remote agents")
+	}
+	if len(dry.WatchPaths) != 1 || dry.WatchPaths[0] != "/work/api" {
+		t.Errorf("WatchPaths = %v, want the configured paths", dry.WatchPaths)
+	}
+}
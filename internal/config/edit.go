@@ -0,0 +1,600 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/anthropic/gap-map/internal/logging"
+	"github.com/anthropic/gap-map/internal/metrics"
+	"github.com/anthropic/gap-map/internal/numfmt"
+)
+
+// Keys returns the JSON keys of all configurable fields, sorted.
+func Keys() []string {
+	t := reflect.TypeOf(Config{})
+	var keys []string
+	for i := 0; i < t.NumField(); i++ {
+		if key := jsonKey(t.Field(i)); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// secretKeys are the keys holding credentials.
+var secretKeys = map[string]bool{
+	"api_token":         true,
+	"pr_webhook_secret": true,
+}
+
+// IsSecret reports whether key holds a credential, whose value is not
+// shown unless asked for.
+func IsSecret(key string) bool {
+	return secretKeys[key]
+}
+
+// Redacted is what GetRedacted shows for a secret that is set.
+const Redacted = "<redacted>"
+
+// GetRedacted is Get with the value of a secret key that is set replaced
+// by Redacted.
+func (c *Config) GetRedacted(key string) (string, error) {
+	val, err := c.Get(key)
+	if err != nil || val == "" || !IsSecret(key) {
+		return val, err
+	}
+	return Redacted, nil
+}
+
+// Get returns the value of the field with the given JSON key, formatted
+// for display. List fields are joined with commas.
+func (c *Config) Get(key string) (string, error) {
+	v, err := c.field(key)
+	if err != nil {
+		return "", err
+	}
+	switch v.Kind() {
+	case reflect.Slice:
+		parts := make([]string, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			parts[i] = fmt.Sprint(v.Index(i).Interface())
+		}
+		return strings.Join(parts, ","), nil
+	case reflect.Map:
+		parts := make([]string, 0, v.Len())
+		for _, k := range v.MapKeys() {
+			parts = append(parts, fmt.Sprintf("%v=%v", k.Interface(), v.MapIndex(k).Interface()))
+		}
+		sort.Strings(parts)
+		return strings.Join(parts, ","), nil
+	default:
+		return fmt.Sprint(v.Interface()), nil
+	}
+}
+
+// Set assigns the field with the given JSON key from string values.
+// Scalar fields take exactly one value. List fields take one or more values;
+// a single comma-separated value is split into its elements. Map fields take
+// name=value pairs in the same way and replace the whole map.
+func (c *Config) Set(key string, values ...string) error {
+	v, err := c.field(key)
+	if err != nil {
+		return err
+	}
+
+	if v.Kind() == reflect.Map {
+		if len(values) == 1 {
+			values = splitList(values[0])
+		}
+		elem := v.Type().Elem().Kind()
+		if v.Type().Key().Kind() != reflect.String || (elem != reflect.Float64 && elem != reflect.String) {
+			return fmt.Errorf("unsupported map type for %q", key)
+		}
+		m := reflect.MakeMapWithSize(v.Type(), len(values))
+		for _, pair := range values {
+			name, raw, ok := strings.Cut(pair, "=")
+			if !ok {
+				return fmt.Errorf("%q expects name=value pairs, got %q", key, pair)
+			}
+			val := reflect.ValueOf(strings.TrimSpace(raw))
+			if elem == reflect.Float64 {
+				f, err := strconv.ParseFloat(strings.TrimSpace(raw), 64)
+				if err != nil {
+					return fmt.Errorf("%q: %s expects a number: %w", key, name, err)
+				}
+				val = reflect.ValueOf(f)
+			}
+			m.SetMapIndex(reflect.ValueOf(strings.TrimSpace(name)), val)
+		}
+		v.Set(m)
+		return nil
+	}
+
+	if v.Kind() == reflect.Slice {
+		if len(values) == 1 {
+			values = splitList(values[0])
+		}
+		if v.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported list type for %q", key)
+		}
+		list := reflect.MakeSlice(v.Type(), 0, len(values))
+		for _, s := range values {
+			list = reflect.Append(list, reflect.ValueOf(expandTilde(s)).Convert(v.Type().Elem()))
+		}
+		v.Set(list)
+		return nil
+	}
+
+	if len(values) != 1 {
+		return fmt.Errorf("%q takes exactly one value, got %d", key, len(values))
+	}
+	raw := values[0]
+
+	switch v.Kind() {
+	case reflect.String:
+		if strings.HasSuffix(key, "_dir") || strings.HasSuffix(key, "_path") {
+			raw = expandTilde(raw)
+		}
+		v.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("%q expects a boolean: %w", key, err)
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return fmt.Errorf("%q expects an integer: %w", key, err)
+		}
+		v.SetInt(n)
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return fmt.Errorf("%q expects a number: %w", key, err)
+		}
+		v.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported type for %q", key)
+	}
+	return nil
+}
+
+// Validate checks the config for values that would prevent the daemon from
+// working: missing watch paths, unparsable durations, empty data paths,
+// unknown scorers or scorer weights, invalid line match, bulk event or git
+// operation settings, malformed package globs, invalid file guards,
+// invalid log settings, and negative token prices.
+// All problems are reported together.
+func (c *Config) Validate() error {
+	var errs []error
+
+	if c.DataDir == "" {
+		errs = append(errs, fmt.Errorf("data_dir must not be empty"))
+	}
+	if c.DBPath == "" {
+		errs = append(errs, fmt.Errorf("db_path must not be empty"))
+	}
+	if c.SocketPath == "" {
+		errs = append(errs, fmt.Errorf("socket_path must not be empty"))
+	}
+
+	for _, p := range c.WatchPaths {
+		info, err := os.Stat(p)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("watch_paths: %s: %w", p, err))
+			continue
+		}
+		if !info.IsDir() {
+			errs = append(errs, fmt.Errorf("watch_paths: %s is not a directory", p))
+		}
+	}
+
+	for _, d := range c.SessionDirs {
+		if !filepath.IsAbs(d) {
+			errs = append(errs, fmt.Errorf("session_dirs: %s is not an absolute path", d))
+		} else if info, err := os.Stat(d); err == nil && !info.IsDir() {
+			errs = append(errs, fmt.Errorf("session_dirs: %s is not a directory", d))
+		}
+	}
+
+	for _, pattern := range c.IgnorePatterns {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			errs = append(errs, fmt.Errorf("ignore_patterns: %q: %w", pattern, err))
+		}
+	}
+	for _, pattern := range c.WatchExclude {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			errs = append(errs, fmt.Errorf("watch_exclude: %q: %w", pattern, err))
+		}
+	}
+
+	names := map[string]bool{"claude-code": true}
+	for i, sp := range c.SessionProviders {
+		switch {
+		case sp.Name == "":
+			errs = append(errs, fmt.Errorf("session_providers: entry %d has no name", i))
+		case names[sp.Name]:
+			errs = append(errs, fmt.Errorf("session_providers: duplicate name %q", sp.Name))
+		}
+		names[sp.Name] = true
+		if len(sp.Command) == 0 || sp.Command[0] == "" {
+			errs = append(errs, fmt.Errorf("session_providers: %s: command is required", sp.Name))
+		}
+	}
+
+	targets := map[string]bool{}
+	for i, ra := range c.RemoteAgents {
+		switch {
+		case ra.Target == "":
+			errs = append(errs, fmt.Errorf("remote_agents: entry %d has no target", i))
+		case strings.HasPrefix(ra.Target, "-"):
+			errs = append(errs, fmt.Errorf("remote_agents: target %q must not start with -", ra.Target))
+		case targets[ra.Target]:
+			errs = append(errs, fmt.Errorf("remote_agents: duplicate target %q", ra.Target))
+		}
+		targets[ra.Target] = true
+		for _, m := range ra.PathMap {
+			if remote, local, ok := strings.Cut(m, "="); !ok || remote == "" || local == "" {
+				errs = append(errs, fmt.Errorf("remote_agents: %s: path map %q: want remote=local", ra.Target, m))
+			}
+		}
+	}
+
+	switch c.WatchMode {
+	case "", WatchModeAuto, WatchModeNotify, WatchModePoll:
+	default:
+		errs = append(errs, fmt.Errorf("watch_mode: unknown value %q (want %q, %q or %q)", c.WatchMode, WatchModeAuto, WatchModeNotify, WatchModePoll))
+	}
+
+	if _, err := metrics.NewScorer(c.Scorer, c.ScorerWeights); err != nil {
+		errs = append(errs, fmt.Errorf("scorer: %w", err))
+	}
+
+	if _, err := metrics.NewMatchOptions(c.LineMatch, c.SimilarityThreshold); err != nil {
+		errs = append(errs, fmt.Errorf("line_match: %w", err))
+	}
+	for p, mode := range c.ProjectLineMatch {
+		if _, err := metrics.NewMatchOptions(mode, 0); err != nil {
+			errs = append(errs, fmt.Errorf("project_line_match: %s: %w", p, err))
+		}
+	}
+	for p, t := range c.ProjectSimilarityThreshold {
+		if _, err := metrics.NewMatchOptions("", t); err != nil {
+			errs = append(errs, fmt.Errorf("project_similarity_threshold: %s: %w", p, err))
+		}
+	}
+
+	if c.RevisionThreshold < 0 || c.RevisionThreshold > 1 {
+		errs = append(errs, fmt.Errorf("revision_threshold %v must be between 0 and 1", c.RevisionThreshold))
+	}
+
+	if c.UntrackedRepoThreshold < 0 {
+		errs = append(errs, fmt.Errorf("untracked_repo_threshold must not be negative"))
+	}
+	if c.BulkEventThreshold < 0 {
+		errs = append(errs, fmt.Errorf("bulk_event_threshold must not be negative"))
+	}
+	switch c.BulkEvents {
+	case "", BulkEventsDefer, BulkEventsSkip:
+	default:
+		errs = append(errs, fmt.Errorf("bulk_events: unknown value %q (want %q or %q)", c.BulkEvents, BulkEventsDefer, BulkEventsSkip))
+	}
+	switch c.ProjectTrust {
+	case "", ProjectTrustAll, ProjectTrustAllowlist:
+	default:
+		errs = append(errs, fmt.Errorf("project_trust: unknown value %q (want %q or %q)",
+			c.ProjectTrust, ProjectTrustAll, ProjectTrustAllowlist))
+	}
+	for _, p := range c.EnabledProjects {
+		if !filepath.IsAbs(expandTilde(p)) {
+			errs = append(errs, fmt.Errorf("enabled_projects: %s is not an absolute path", p))
+		}
+	}
+	for _, p := range c.DisabledProjects {
+		if !filepath.IsAbs(expandTilde(p)) {
+			errs = append(errs, fmt.Errorf("disabled_projects: %s is not an absolute path", p))
+		}
+	}
+
+	switch c.GitOperationEvents {
+	case "", GitOperationEventsTag, GitOperationEventsDrop, GitOperationEventsOff:
+	default:
+		errs = append(errs, fmt.Errorf("git_operation_events: unknown value %q (want %q, %q or %q)",
+			c.GitOperationEvents, GitOperationEventsTag, GitOperationEventsDrop, GitOperationEventsOff))
+	}
+
+	if c.MaxFileLines < 0 {
+		errs = append(errs, fmt.Errorf("max_file_lines must not be negative"))
+	}
+	switch c.LargeFiles {
+	case "", LargeFilesExclude, LargeFilesCap:
+	default:
+		errs = append(errs, fmt.Errorf("large_files: unknown value %q (want %q or %q)", c.LargeFiles, LargeFilesExclude, LargeFilesCap))
+	}
+	switch c.BinaryFiles {
+	case "", BinaryFilesExclude, BinaryFilesInclude:
+	default:
+		errs = append(errs, fmt.Errorf("binary_files: unknown value %q (want %q or %q)", c.BinaryFiles, BinaryFilesExclude, BinaryFilesInclude))
+	}
+
+	if _, err := logging.ParseLevel(c.LogLevel); err != nil {
+		errs = append(errs, fmt.Errorf("log_level: %w", err))
+	}
+	switch c.LogFormat {
+	case "", logging.FormatText, logging.FormatJSON:
+	default:
+		errs = append(errs, fmt.Errorf("log_format: unknown value %q (want %q or %q)", c.LogFormat, logging.FormatText, logging.FormatJSON))
+	}
+	if c.LogMaxSizeMB < 0 || c.LogMaxBackups < 0 {
+		errs = append(errs, fmt.Errorf("log_max_size_mb and log_max_backups must not be negative"))
+	}
+
+	if c.PRCommentMinAIPct < 0 || c.PRCommentMinAIPct > 100 {
+		errs = append(errs, fmt.Errorf("pr_comment_min_ai_pct must be between 0 and 100"))
+	}
+	if c.PRCommentCollapseOver < 0 {
+		errs = append(errs, fmt.Errorf("pr_comment_collapse_over must not be negative"))
+	}
+	if c.PRCommentNotableMinEvents < 0 || c.PRCommentNotableMinLines < 0 || c.PRCommentNotableMax < 0 {
+		errs = append(errs, fmt.Errorf("pr_comment_notable_min_events, pr_comment_notable_min_lines and pr_comment_notable_max must not be negative"))
+	}
+	if c.PRCommentNotableMinAIPct < 0 || c.PRCommentNotableMinAIPct > 100 {
+		errs = append(errs, fmt.Errorf("pr_comment_notable_min_ai_pct must be between 0 and 100"))
+	}
+	switch c.PRCommentNotableSort {
+	case "", "ai_pct", "lines", "events":
+	default:
+		errs = append(errs, fmt.Errorf("pr_comment_notable_sort: unknown value %q (want ai_pct, lines or events)", c.PRCommentNotableSort))
+	}
+	if c.HumanSnapshotMaxBytes < 1 {
+		errs = append(errs, fmt.Errorf("human_snapshot_max_bytes must be at least 1"))
+	}
+	if c.PRMaxAnnotations < 0 {
+		errs = append(errs, fmt.Errorf("pr_max_annotations must not be negative"))
+	}
+	if c.GitHubMaxRetries < 0 {
+		errs = append(errs, fmt.Errorf("github_max_retries must not be negative"))
+	}
+	if c.GitMaxProcesses < 1 {
+		errs = append(errs, fmt.Errorf("git_max_processes must be at least 1"))
+	}
+	if c.GitCacheMB < 0 {
+		errs = append(errs, fmt.Errorf("git_cache_mb must not be negative"))
+	}
+	if err := metrics.ValidDiffAlgorithm(c.DiffAlgorithm); err != nil {
+		errs = append(errs, fmt.Errorf("diff_algorithm: %w", err))
+	}
+	if c.TailerCheckpointLines < 1 {
+		errs = append(errs, fmt.Errorf("tailer_checkpoint_lines must be at least 1"))
+	}
+	if d, err := time.ParseDuration(c.TailerCheckpointInterval); err == nil && d < 0 {
+		errs = append(errs, fmt.Errorf("tailer_checkpoint_interval must not be negative"))
+	}
+	if c.CorrectionWindowDays < 1 {
+		errs = append(errs, fmt.Errorf("correction_window_days must be at least 1"))
+	}
+	if c.PRWebhookAddr != "" && c.PRWebhookSecret == "" {
+		errs = append(errs, fmt.Errorf("pr_webhook_secret is required with pr_webhook_addr"))
+	}
+	if c.APIAddr != "" && c.APIToken == "" {
+		errs = append(errs, fmt.Errorf("api_token is required with api_addr"))
+	}
+	if (c.APITLSCert == "") != (c.APITLSKey == "") {
+		errs = append(errs, fmt.Errorf("api_tls_cert and api_tls_key must be set together"))
+	}
+
+	if c.DisplayTimezone != "" {
+		if _, err := time.LoadLocation(c.DisplayTimezone); err != nil {
+			errs = append(errs, fmt.Errorf("display_timezone: unknown time zone %q", c.DisplayTimezone))
+		}
+	}
+	if _, err := numfmt.Parse(c.NumberLocale); err != nil {
+		errs = append(errs, fmt.Errorf("number_locale: %w", err))
+	}
+	if c.MaintenanceTime != "" && c.MaintenanceTime != MaintenanceOff {
+		if _, err := time.Parse("15:04", c.MaintenanceTime); err != nil {
+			errs = append(errs, fmt.Errorf("maintenance_time: %q is not a time of day like 03:00 or %q", c.MaintenanceTime, MaintenanceOff))
+		}
+	}
+
+	for glob, name := range c.Packages {
+		if name == "" {
+			errs = append(errs, fmt.Errorf("packages: %s: package name must not be empty", glob))
+		}
+		for _, seg := range strings.Split(glob, "/") {
+			if _, err := path.Match(seg, ""); err != nil {
+				errs = append(errs, fmt.Errorf("packages: %q: %w", glob, err))
+				break
+			}
+		}
+	}
+
+	for model, p := range c.TokenPrices {
+		if p.Input < 0 || p.Output < 0 || p.CacheWrite < 0 || p.CacheRead < 0 {
+			errs = append(errs, fmt.Errorf("token_prices: %s: prices must not be negative", model))
+		}
+	}
+
+	// Any string field named *_interval, *_timeout, or *_window holds a
+	// Go duration (e.g. "30s", "5m").
+	rv := reflect.ValueOf(c).Elem()
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		key := jsonKey(rt.Field(i))
+		if !isDurationKey(key) || rv.Field(i).Kind() != reflect.String {
+			continue
+		}
+		s := rv.Field(i).String()
+		if s == "" {
+			continue
+		}
+		if _, err := time.ParseDuration(s); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", key, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Save writes the config as indented JSON to path, creating the parent
+// directory if needed. The file is readable by its owner only, as it may
+// hold secrets such as api_token. Every key is written, defaults included;
+// use SaveKeys to change some keys of an existing file.
+func (c *Config) Save(path string) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeConfigFile(path, append(data, '\n'))
+}
+
+// SaveKeys writes the values c holds for keys into the config file at
+// path, creating it if needed. The file's other keys are kept as they are
+// and in their order, and keys it does not set are not added, so they keep
+// following the defaults.
+func (c *Config) SaveKeys(path string, keys ...string) error {
+	var names []string
+	values := make(map[string]json.RawMessage)
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if len(bytes.TrimSpace(data)) > 0 {
+		if names, err = objectKeys(data); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		if err := json.Unmarshal(data, &values); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	for _, key := range keys {
+		f, err := c.field(key)
+		if err != nil {
+			return err
+		}
+		v, err := json.Marshal(f.Interface())
+		if err != nil {
+			return err
+		}
+		if _, ok := values[key]; !ok {
+			names = append(names, key)
+		}
+		values[key] = v
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("{")
+	for i, name := range names {
+		if i > 0 {
+			buf.WriteString(",")
+		}
+		k, _ := json.Marshal(name)
+		fmt.Fprintf(&buf, "\n  %s: ", k)
+		if err := json.Indent(&buf, values[name], "  ", "  "); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	if len(names) > 0 {
+		buf.WriteString("\n")
+	}
+	buf.WriteString("}\n")
+	return writeConfigFile(path, buf.Bytes())
+}
+
+// objectKeys returns the keys of the JSON object data in file order.
+func objectKeys(data []byte) ([]string, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return nil, errors.New("config is not a JSON object")
+	}
+	var keys []string
+	seen := make(map[string]bool)
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		if key := tok.(string); !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+		var skip json.RawMessage
+		if err := dec.Decode(&skip); err != nil {
+			return nil, err
+		}
+	}
+	return keys, nil
+}
+
+// writeConfigFile writes data to path, readable by its owner only,
+// creating the parent directory if needed.
+func writeConfigFile(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	// Write to a temp file and rename so a crash never leaves a
+	// half-written config behind.
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	// WriteFile keeps the mode of a stale temp file.
+	if err := os.Chmod(tmp, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// field returns the settable struct field for a JSON key.
+func (c *Config) field(key string) (reflect.Value, error) {
+	rv := reflect.ValueOf(c).Elem()
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		if jsonKey(rt.Field(i)) == key {
+			return rv.Field(i), nil
+		}
+	}
+	return reflect.Value{}, fmt.Errorf("unknown config key %q (valid keys: %s)", key, strings.Join(Keys(), ", "))
+}
+
+// jsonKey returns the JSON name for a struct field, or "" if the field is
+// not serialized.
+func jsonKey(f reflect.StructField) string {
+	tag := f.Tag.Get("json")
+	if tag == "" || tag == "-" {
+		return ""
+	}
+	name, _, _ := strings.Cut(tag, ",")
+	return name
+}
+
+// isDurationKey reports whether a config key holds a duration string.
+func isDurationKey(key string) bool {
+	return strings.HasSuffix(key, "_interval") ||
+		strings.HasSuffix(key, "_timeout") ||
+		strings.HasSuffix(key, "_window")
+}
+
+// splitList splits a comma-separated value, trimming whitespace and
+// dropping empty elements.
+func splitList(s string) []string {
+	var out []string
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
@@ -0,0 +1,306 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSetAndGet(t *testing.T) {
+	cfg := Default()
+
+	if err := cfg.Set("watch_paths", "/a, /b"); err != nil {
+		t.Fatalf("Set watch_paths: %v", err)
+	}
+	got, err := cfg.Get("watch_paths")
+	if err != nil {
+		t.Fatalf("Get watch_paths: %v", err)
+	}
+	if got != "/a,/b" {
+		t.Errorf("watch_paths = %q, want %q", got, "/a,/b")
+	}
+
+	if err := cfg.Set("watch_paths", "/x", "/y", "/z"); err != nil {
+		t.Fatalf("Set watch_paths (multi): %v", err)
+	}
+	if len(cfg.WatchPaths) != 3 {
+		t.Errorf("expected 3 watch paths, got %v", cfg.WatchPaths)
+	}
+
+	if err := cfg.Set("db_path", "/tmp/x.db"); err != nil {
+		t.Fatalf("Set db_path: %v", err)
+	}
+	if cfg.DBPath != "/tmp/x.db" {
+		t.Errorf("DBPath = %q, want /tmp/x.db", cfg.DBPath)
+	}
+}
+
+func TestSetUnknownKey(t *testing.T) {
+	cfg := Default()
+	err := cfg.Set("no_such_key", "x")
+	if err == nil || !strings.Contains(err.Error(), "unknown config key") {
+		t.Errorf("expected unknown key error, got %v", err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	cfg := Default()
+	cfg.WatchPaths = []string{t.TempDir()}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("expected valid config, got %v", err)
+	}
+
+	cfg.WatchPaths = []string{filepath.Join(t.TempDir(), "missing")}
+	cfg.IgnorePatterns = []string{"[bad"}
+	cfg.WatchExclude = []string{"dist", "[bad"}
+	cfg.WatchMode = "inotify"
+	cfg.SessionProviders = []SessionProvider{{Name: "claude-code", Command: []string{"x"}}}
+	cfg.WatchPollInterval = "often"
+	cfg.BulkEvents = "drop"
+	cfg.BulkEventWindow = "soon"
+	cfg.GitOperationEvents = "ignore"
+	cfg.Packages = map[string]string{"services/[api/**": "api"}
+	cfg.LargeFiles = "truncate"
+	cfg.BinaryFiles = "skip"
+	cfg.LogLevel = "loud"
+	cfg.LogFormat = "xml"
+	cfg.LogRotateInterval = "daily"
+	cfg.DisplayTimezone = "Mars/Olympus_Mons"
+	cfg.NumberLocale = "tlh"
+	cfg.MaintenanceTime = "3am"
+	cfg.UntrackedRepoThreshold = -1
+	cfg.TailerCheckpointInterval = "-5s"
+	cfg.TokenPrices = map[string]TokenPrice{"claude-opus-4": {Input: -15}}
+	cfg.RemoteAgents = []RemoteAgent{{Target: "devbox", PathMap: []string{"/workspaces"}}, {Target: "-oProxyCommand=sh"}}
+	cfg.APITLSCert = "cert.pem"
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("expected validation errors")
+	}
+	for _, key := range []string{"watch_paths", "ignore_patterns", "watch_exclude", "session_providers", "watch_mode", "watch_poll_interval", "bulk_events", "bulk_event_window", "git_operation_events", "packages", "large_files", "binary_files", "log_level", "log_format", "log_rotate_interval", "display_timezone", "number_locale", "maintenance_time", "untracked_repo_threshold", "tailer_checkpoint_interval", "token_prices", "remote_agents", "api_tls_cert"} {
+		if !strings.Contains(err.Error(), key) {
+			t.Errorf("expected a %s error, got %v", key, err)
+		}
+	}
+	if !strings.Contains(err.Error(), `target "-oProxyCommand=sh" must not start with -`) {
+		t.Errorf("expected an option-like target to be rejected, got %v", err)
+	}
+}
+
+func TestSaveRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sub", "config.json")
+	cfg := Default()
+	cfg.WatchPaths = []string{"/project"}
+	if err := cfg.Save(path); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(loaded.WatchPaths) != 1 || loaded.WatchPaths[0] != "/project" {
+		t.Errorf("WatchPaths = %v, want [/project]", loaded.WatchPaths)
+	}
+	// The config may hold secrets, so only its owner may read it.
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("config mode = %v, want 0600", perm)
+	}
+}
+
+func TestSaveKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sub", "config.json")
+	cfg := Default()
+	if err := cfg.Set("log_level", "debug"); err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.SaveKeys(path, "log_level"); err != nil {
+		t.Fatalf("SaveKeys to a new file: %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "{\n  \"log_level\": \"debug\"\n}\n" {
+		t.Errorf("new file = %q, want only log_level", data)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"watch_paths": ["/b", "/a"], "log_level": "debug"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.Set("telemetry_enabled", "true"); err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.Set("log_level", "warn"); err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.SaveKeys(path, "telemetry_enabled", "log_level"); err != nil {
+		t.Fatalf("SaveKeys: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{
+  "watch_paths": [
+    "/b",
+    "/a"
+  ],
+  "log_level": "warn",
+  "telemetry_enabled": true
+}
+`
+	if string(data) != want {
+		t.Errorf("config file =\n%s\nwant only the keys set, in file order:\n%s", data, want)
+	}
+
+	if err := cfg.SaveKeys(path, "no_such_key"); err == nil {
+		t.Error("SaveKeys with an unknown key: want error")
+	}
+	if err := os.WriteFile(path, []byte(`["not", "an", "object"]`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.SaveKeys(path, "log_level"); err == nil {
+		t.Error("SaveKeys over a non-object file: want error")
+	}
+}
+
+func TestGetRedacted(t *testing.T) {
+	cfg := Default()
+	if got, _ := cfg.GetRedacted("api_token"); got != "" {
+		t.Errorf("unset api_token = %q, want empty", got)
+	}
+	cfg.APIToken = "s3cret"
+	if got, _ := cfg.GetRedacted("api_token"); got != Redacted {
+		t.Errorf("api_token = %q, want %q", got, Redacted)
+	}
+	if got, _ := cfg.Get("api_token"); got != "s3cret" {
+		t.Errorf("Get(api_token) = %q, want the token", got)
+	}
+	if got, _ := cfg.GetRedacted("scorer"); got != "weighted" {
+		t.Errorf("scorer = %q, want weighted", got)
+	}
+}
+
+func TestSetMap(t *testing.T) {
+	cfg := Default()
+	if err := cfg.Set("scorer", "custom"); err != nil {
+		t.Fatalf("Set scorer: %v", err)
+	}
+	if err := cfg.Set("scorer_weights", "boilerplate=0,test_scaffolding=0.5"); err != nil {
+		t.Fatalf("Set scorer_weights: %v", err)
+	}
+	if cfg.ScorerWeights["test_scaffolding"] != 0.5 || cfg.ScorerWeights["boilerplate"] != 0 {
+		t.Errorf("ScorerWeights = %v", cfg.ScorerWeights)
+	}
+	got, _ := cfg.Get("scorer_weights")
+	if got != "boilerplate=0,test_scaffolding=0.5" {
+		t.Errorf("Get = %q", got)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate: %v", err)
+	}
+
+	if err := cfg.Set("scorer_weights", "docs=1"); err != nil {
+		t.Fatalf("Set scorer_weights: %v", err)
+	}
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected Validate to reject unknown work type")
+	}
+	if err := cfg.Set("scorer_weights", "core_logic"); err == nil {
+		t.Error("expected error for missing =value")
+	}
+}
+
+func TestMatchOptions_ProjectOverride(t *testing.T) {
+	cfg := Default()
+	if err := cfg.Set("similarity_threshold", "0.8"); err != nil {
+		t.Fatalf("Set similarity_threshold: %v", err)
+	}
+	if err := cfg.Set("project_line_match", "/work/web=normalized"); err != nil {
+		t.Fatalf("Set project_line_match: %v", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+
+	if got := cfg.MatchOptions("/work/web/"); got.Mode != "normalized" || got.Similarity != 0.8 {
+		t.Errorf("MatchOptions(/work/web) = %+v, want normalized with 0.8", got)
+	}
+	if got := cfg.MatchOptions("/work/api"); got.Mode != "exact" || got.Similarity != 0.8 {
+		t.Errorf("MatchOptions(/work/api) = %+v, want exact with 0.8", got)
+	}
+
+	if err := cfg.Set("project_line_match", "/work/web=fuzzy"); err != nil {
+		t.Fatalf("Set project_line_match: %v", err)
+	}
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected Validate to reject unknown line match mode")
+	}
+	if err := cfg.Set("similarity_threshold", "1.5"); err != nil {
+		t.Fatalf("Set similarity_threshold: %v", err)
+	}
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected Validate to reject similarity threshold above 1")
+	}
+	if got := cfg.MatchOptions("/work/api"); got.ExcludeComments {
+		t.Error("MatchOptions excludes comments by default")
+	}
+	if err := cfg.Set("exclude_comments", "true"); err != nil {
+		t.Fatalf("Set exclude_comments: %v", err)
+	}
+	// Still set when the invalid threshold falls back to exact matching.
+	if got := cfg.MatchOptions("/work/api"); !got.ExcludeComments {
+		t.Error("MatchOptions ignores exclude_comments")
+	}
+}
+
+func TestLocation(t *testing.T) {
+	cfg := Default()
+	if got := cfg.Location(); got != time.Local {
+		t.Errorf("Location() = %v, want the system zone", got)
+	}
+	if err := cfg.Set("display_timezone", "America/New_York"); err != nil {
+		t.Fatalf("Set display_timezone: %v", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	if got := cfg.Location().String(); got != "America/New_York" {
+		t.Errorf("Location() = %s, want America/New_York", got)
+	}
+}
+
+func TestSessionRoots(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("CLAUDE_CONFIG_DIR", "")
+
+	cfg := Default()
+	if got := cfg.SessionRoots(); len(got) != 1 || got[0] != filepath.Join(home, ".claude", "projects") {
+		t.Errorf("default SessionRoots() = %v", got)
+	}
+
+	t.Setenv("CLAUDE_CONFIG_DIR", filepath.Join(home, ".claude-work"))
+	want := []string{filepath.Join(home, ".claude", "projects"), filepath.Join(home, ".claude-work", "projects")}
+	if got := cfg.SessionRoots(); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("SessionRoots() with CLAUDE_CONFIG_DIR = %v, want %v", got, want)
+	}
+
+	if err := cfg.Set("session_dirs", "/a/projects,/b/projects/,/a/projects"); err != nil {
+		t.Fatalf("Set session_dirs: %v", err)
+	}
+	if got := cfg.SessionRoots(); strings.Join(got, ",") != "/a/projects,/b/projects" {
+		t.Errorf("configured SessionRoots() = %v", got)
+	}
+
+	cfg.SessionDirs = []string{"relative/projects"}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "session_dirs") {
+		t.Errorf("Validate with a relative session dir = %v, want a session_dirs error", err)
+	}
+}
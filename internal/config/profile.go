@@ -0,0 +1,77 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+)
+
+// Environment variables that select where gap-map keeps its data.
+const (
+	// EnvProfile names the active profile; the gapmap --profile flag
+	// sets it for the command and every process it starts.
+	EnvProfile = "GAPMAP_PROFILE"
+	// EnvDataDir overrides the data directory, and with it the config
+	// file, of whichever profile is active.
+	EnvDataDir = "GAPMAP_DATA_DIR"
+)
+
+// DefaultProfile is the profile whose data lives in ~/.gapmap itself.
+const DefaultProfile = "default"
+
+var profileName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]*$`)
+
+// Profile returns the active profile, DefaultProfile unless EnvProfile
+// names another.
+func Profile() string {
+	if p := os.Getenv(EnvProfile); p != "" {
+		return p
+	}
+	return DefaultProfile
+}
+
+// ValidateProfile reports whether name can name a profile: letters,
+// digits, '-' and '_', not starting with '-' or '_'.
+func ValidateProfile(name string) error {
+	if !profileName.MatchString(name) {
+		return fmt.Errorf("invalid profile name %q: use letters, digits, '-' and '_'", name)
+	}
+	return nil
+}
+
+// ProfileDataDir returns the data directory of a profile: ~/.gapmap for
+// DefaultProfile, ~/.gapmap/profiles/<name> for the others.
+func ProfileDataDir(name string) string {
+	if name == "" || name == DefaultProfile {
+		return baseDataDir()
+	}
+	return filepath.Join(baseDataDir(), "profiles", name)
+}
+
+// Profiles returns DefaultProfile and every other profile with a data
+// directory, sorted.
+func Profiles() ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(baseDataDir(), "profiles"))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	profiles := []string{DefaultProfile}
+	for _, e := range entries {
+		if e.IsDir() && ValidateProfile(e.Name()) == nil && e.Name() != DefaultProfile {
+			profiles = append(profiles, e.Name())
+		}
+	}
+	sort.Strings(profiles[1:])
+	return profiles, nil
+}
+
+// baseDataDir returns ~/.gapmap.
+func baseDataDir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		home = "."
+	}
+	return filepath.Join(home, ".gapmap")
+}
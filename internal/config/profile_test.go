@@ -0,0 +1,62 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestProfileDataDir(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv(EnvProfile, "")
+	t.Setenv(EnvDataDir, "")
+
+	base := filepath.Join(home, ".gapmap")
+	if got := DefaultDataDir(); got != base {
+		t.Errorf("DefaultDataDir() = %s, want %s", got, base)
+	}
+
+	t.Setenv(EnvProfile, "client-a")
+	dir := filepath.Join(base, "profiles", "client-a")
+	if got := DefaultDataDir(); got != dir {
+		t.Errorf("DefaultDataDir() with profile = %s, want %s", got, dir)
+	}
+	if got := ConfigPath(); got != filepath.Join(dir, "config.json") {
+		t.Errorf("ConfigPath() with profile = %s", got)
+	}
+	cfg := Default()
+	if !strings.HasPrefix(cfg.DBPath, dir) || !strings.HasPrefix(cfg.SocketPath, dir) {
+		t.Errorf("profile DBPath = %s, SocketPath = %s, want under %s", cfg.DBPath, cfg.SocketPath, dir)
+	}
+
+	t.Setenv(EnvDataDir, "~/elsewhere")
+	if got := DefaultDataDir(); got != filepath.Join(home, "elsewhere") {
+		t.Errorf("DefaultDataDir() with %s = %s", EnvDataDir, got)
+	}
+}
+
+func TestProfiles(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	for _, name := range []string{"zeta", "client-a", ".hidden"} {
+		if err := os.MkdirAll(filepath.Join(home, ".gapmap", "profiles", name), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got, err := Profiles()
+	if err != nil {
+		t.Fatalf("Profiles: %v", err)
+	}
+	if strings.Join(got, ",") != "default,client-a,zeta" {
+		t.Errorf("Profiles() = %v", got)
+	}
+
+	for name, ok := range map[string]bool{"work": true, "client_2": true, "": false, "-x": false, "a/b": false, "..": false} {
+		if err := ValidateProfile(name); (err == nil) != ok {
+			t.Errorf("ValidateProfile(%q) = %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,319 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/go-git/go-git/v5/plumbing/format/gitignore"
+
+	"github.com/anthropic/gap-map/internal/metrics"
+	"github.com/anthropic/gap-map/internal/worktype"
+)
+
+// RepoSettingsFile is the file at the root of a repository that holds the
+// attribution settings its team shares through version control.
+const RepoSettingsFile = ".gapmap.toml"
+
+// RepoSettings are the attribution settings a repository commits in its
+// .gapmap.toml. They apply to that repository over the user config.
+type RepoSettings struct {
+	// Root is the top level of the repository.
+	Root string
+	// BaseBranch is the branch analyze compares branches against when no
+	// --base is given, and pull request commands fall back to when they
+	// cannot detect the PR's base. Empty means main.
+	BaseBranch string
+	// Ignore lists .gitignore-style patterns, relative to Root, of paths
+	// left out of attribution even when git tracks them.
+	Ignore []string
+	// WorkTypes maps .gitignore-style patterns, relative to Root, to the
+	// work type of the paths they match. The longest matching pattern
+	// wins. They take precedence over the built-in rules, but not over
+	// work type overrides.
+	WorkTypes map[string]string
+	// LineMatch, SimilarityThreshold and RevisionThreshold replace the
+	// user config's settings of the same names, including its per-project
+	// ones, when set.
+	LineMatch           string
+	SimilarityThreshold *float64
+	RevisionThreshold   *float64
+
+	workTypes []workTypeRule // longest pattern first
+}
+
+type workTypeRule struct {
+	glob     string
+	pattern  gitignore.Pattern
+	workType worktype.WorkType
+}
+
+// LoadRepoSettings reads root/.gapmap.toml. Returns nil and no error when
+// the repository has none.
+func LoadRepoSettings(root string) (*RepoSettings, error) {
+	path := filepath.Join(root, RepoSettingsFile)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", path, err)
+	}
+	rs, err := parseRepoSettings(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	rs.Root = root
+	return rs, nil
+}
+
+// FindRepoSettings returns the settings of the git repository containing
+// path, a file or directory. Returns nil and no error for an empty path,
+// a path outside git, or a repository without a .gapmap.toml.
+func FindRepoSettings(path string) (*RepoSettings, error) {
+	root := repoRoot(path)
+	if root == "" {
+		return nil, nil
+	}
+	return LoadRepoSettings(root)
+}
+
+// repoRoot returns the nearest directory at or above path that holds a
+// .git directory or file, or "" when there is none.
+func repoRoot(path string) string {
+	if path == "" {
+		return ""
+	}
+	dir, err := filepath.Abs(path)
+	if err != nil {
+		return ""
+	}
+	for {
+		if _, err := os.Lstat(filepath.Join(dir, ".git")); err == nil {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// parseRepoSettings decodes and validates a .gapmap.toml. Unknown keys are
+// errors, so typos do not go unnoticed. All problems are reported together.
+func parseRepoSettings(data []byte) (*RepoSettings, error) {
+	var doc map[string]any
+	if err := toml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+
+	rs := &RepoSettings{}
+	var errs []error
+	keys := make([]string, 0, len(doc))
+	for k := range doc {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		v := doc[key]
+		var err error
+		switch key {
+		case "base_branch":
+			rs.BaseBranch, err = tomlString(key, v)
+		case "ignore":
+			rs.Ignore, err = tomlStrings(key, v)
+		case "line_match":
+			rs.LineMatch, err = tomlString(key, v)
+		case "similarity_threshold":
+			rs.SimilarityThreshold, err = tomlFloat(key, v)
+		case "revision_threshold":
+			rs.RevisionThreshold, err = tomlFloat(key, v)
+		case "work_types":
+			table, ok := v.(map[string]any)
+			if !ok {
+				err = fmt.Errorf("work_types must be a table of pattern = \"work type\"")
+				break
+			}
+			rs.WorkTypes = make(map[string]string, len(table))
+			for glob, wt := range table {
+				s, ok := wt.(string)
+				if !ok {
+					errs = append(errs, fmt.Errorf("work_types: %s: work type must be a string", glob))
+					continue
+				}
+				rs.WorkTypes[glob] = s
+			}
+		default:
+			err = fmt.Errorf("unknown key %q", key)
+		}
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if _, err := metrics.NewMatchOptions(rs.LineMatch, 0); err != nil {
+		errs = append(errs, fmt.Errorf("line_match: %w", err))
+	}
+	if t := rs.SimilarityThreshold; t != nil {
+		if _, err := metrics.NewMatchOptions("", *t); err != nil {
+			errs = append(errs, fmt.Errorf("similarity_threshold: %w", err))
+		}
+	}
+	if t := rs.RevisionThreshold; t != nil && (*t < 0 || *t > 1) {
+		errs = append(errs, fmt.Errorf("revision_threshold %v must be between 0 and 1", *t))
+	}
+
+	for _, p := range rs.Ignore {
+		if strings.TrimSpace(p) == "" {
+			errs = append(errs, fmt.Errorf("ignore: patterns must not be empty"))
+			break
+		}
+	}
+
+	for glob, wt := range rs.WorkTypes {
+		if _, ok := worktype.WorkTypeWeights[worktype.WorkType(wt)]; !ok {
+			errs = append(errs, fmt.Errorf("work_types: %s: unknown work type %q", glob, wt))
+			continue
+		}
+		if strings.TrimSpace(glob) == "" || strings.HasPrefix(glob, "!") {
+			errs = append(errs, fmt.Errorf("work_types: %q: invalid pattern", glob))
+			continue
+		}
+		rs.workTypes = append(rs.workTypes, workTypeRule{glob, gitignore.ParsePattern(glob, nil), worktype.WorkType(wt)})
+	}
+	sort.Slice(rs.workTypes, func(i, j int) bool {
+		a, b := rs.workTypes[i].glob, rs.workTypes[j].glob
+		if len(a) != len(b) {
+			return len(a) > len(b)
+		}
+		return a < b
+	})
+
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return rs, nil
+}
+
+// RepoIgnore returns the ignore patterns of the .gapmap.toml of the git
+// repository containing path, relative to its root. An invalid file is
+// treated as absent; the commands that read its other settings report it.
+func RepoIgnore(path string) []string {
+	rs, err := FindRepoSettings(path)
+	if err != nil || rs == nil {
+		return nil
+	}
+	return rs.Ignore
+}
+
+// WorkType returns the work type the work_types patterns give path, which
+// is absolute or relative to Root. A nil *RepoSettings has none.
+func (rs *RepoSettings) WorkType(path string) (worktype.WorkType, bool) {
+	if rs == nil || len(rs.workTypes) == 0 {
+		return "", false
+	}
+	rel := path
+	if filepath.IsAbs(path) {
+		var err error
+		if rel, err = filepath.Rel(rs.Root, path); err != nil {
+			return "", false
+		}
+	}
+	rel = filepath.ToSlash(filepath.Clean(rel))
+	if rel == "." || rel == ".." || strings.HasPrefix(rel, "../") {
+		return "", false
+	}
+	segments := strings.Split(rel, "/")
+	for _, r := range rs.workTypes {
+		if r.pattern.Match(segments, false) == gitignore.Exclude {
+			return r.workType, true
+		}
+	}
+	return "", false
+}
+
+// RepoSettingsCache hands out the settings of the repositories files are
+// in, for long-running processes like the daemon. A .gapmap.toml is read
+// again once it changes; an invalid one is logged and treated as absent.
+// The zero value is ready to use.
+type RepoSettingsCache struct {
+	mu      sync.Mutex
+	entries map[string]repoSettingsEntry // by repository root
+}
+
+type repoSettingsEntry struct {
+	modTime  time.Time
+	size     int64
+	settings *RepoSettings
+}
+
+// For returns the settings of the repository containing path, or nil.
+func (c *RepoSettingsCache) For(path string) *RepoSettings {
+	root := repoRoot(path)
+	if root == "" {
+		return nil
+	}
+	file := filepath.Join(root, RepoSettingsFile)
+	info, err := os.Stat(file)
+	if err != nil {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[root]; ok && e.modTime.Equal(info.ModTime()) && e.size == info.Size() {
+		return e.settings
+	}
+	rs, err := LoadRepoSettings(root)
+	if err != nil {
+		slog.Warn("ignoring invalid repository settings", "file", file, "err", err)
+	}
+	if c.entries == nil {
+		c.entries = make(map[string]repoSettingsEntry)
+	}
+	c.entries[root] = repoSettingsEntry{modTime: info.ModTime(), size: info.Size(), settings: rs}
+	return rs
+}
+
+func tomlString(key string, v any) (string, error) {
+	s, ok := v.(string)
+	if !ok {
+		return "", fmt.Errorf("%s must be a string", key)
+	}
+	return s, nil
+}
+
+func tomlStrings(key string, v any) ([]string, error) {
+	list, ok := v.([]any)
+	if !ok {
+		return nil, fmt.Errorf("%s must be an array of strings", key)
+	}
+	out := make([]string, len(list))
+	for i, e := range list {
+		s, ok := e.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s must be an array of strings", key)
+		}
+		out[i] = s
+	}
+	return out, nil
+}
+
+func tomlFloat(key string, v any) (*float64, error) {
+	switch n := v.(type) {
+	case float64:
+		return &n, nil
+	case int64:
+		f := float64(n)
+		return &f, nil
+	}
+	return nil, fmt.Errorf("%s must be a number", key)
+}
@@ -0,0 +1,171 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/anthropic/gap-map/internal/worktype"
+)
+
+func TestParseRepoSettingsSyntax(t *testing.T) {
+	rs, err := parseRepoSettings([]byte(`# settings
+base_branch = "a \"quoted\" value" # trailing comment
+line_match = 'exact'
+similarity_threshold = 1
+ignore = [
+  "one",
+  'C:\raw', # comment
+]
+
+[work_types]
+"docs with spaces/" = "documentation"
+`))
+	if err != nil {
+		t.Fatalf("parseRepoSettings: %v", err)
+	}
+	if rs.BaseBranch != `a "quoted" value` || rs.LineMatch != "exact" || *rs.SimilarityThreshold != 1 {
+		t.Errorf("scalars = %+v", rs)
+	}
+	if len(rs.Ignore) != 2 || rs.Ignore[1] != `C:\raw` {
+		t.Errorf("ignore = %#v", rs.Ignore)
+	}
+	if rs.WorkTypes["docs with spaces/"] != "documentation" {
+		t.Errorf("work_types = %#v", rs.WorkTypes)
+	}
+
+	for _, bad := range []string{
+		"base_branch",
+		"base_branch = ",
+		`base_branch = "open`,
+		`base_branch = "\x41"`, // a Go escape, not a TOML one
+		`ignore = ["a", "b"`,
+		"base_branch = \"a\" \"b\"",
+		"base_branch = \"a\"\nbase_branch = \"b\"",
+		"[work_types]\n[work_types]",
+	} {
+		if _, err := parseRepoSettings([]byte(bad)); err == nil {
+			t.Errorf("parseRepoSettings(%q): want error", bad)
+		}
+	}
+	if _, err := parseRepoSettings([]byte("line_match = \"exact\"\n\nbase_branch = ?")); err == nil || !strings.Contains(err.Error(), "line 3") {
+		t.Errorf("error does not name the line: %v", err)
+	}
+}
+
+func TestLoadRepoSettings(t *testing.T) {
+	root := t.TempDir()
+	if rs, err := LoadRepoSettings(root); rs != nil || err != nil {
+		t.Fatalf("without a file: %v, %v", rs, err)
+	}
+
+	writeRepoSettings(t, root, `base_branch = "develop"
+ignore = ["vendor/", "*.pb.go", "!keep.pb.go"]
+line_match = "normalized"
+similarity_threshold = 0.8
+revision_threshold = 1
+
+[work_types]
+"e2e/" = "test_scaffolding"
+"e2e/fixtures/" = "boilerplate"
+"*.sql" = "database_migration"
+`)
+	rs, err := LoadRepoSettings(root)
+	if err != nil {
+		t.Fatalf("LoadRepoSettings: %v", err)
+	}
+	if rs.BaseBranch != "develop" || rs.LineMatch != "normalized" || *rs.SimilarityThreshold != 0.8 || *rs.RevisionThreshold != 1 {
+		t.Errorf("settings = %+v", rs)
+	}
+
+	if len(rs.Ignore) != 3 || rs.Ignore[2] != "!keep.pb.go" {
+		t.Errorf("ignore = %#v", rs.Ignore)
+	}
+
+	for path, want := range map[string]worktype.WorkType{
+		"e2e/login_test.ts":                   worktype.TestScaffolding,
+		filepath.Join(root, "e2e/fixtures/a"): worktype.Boilerplate, // longest pattern wins
+		"db/001_init.sql":                     worktype.DatabaseMigration,
+		"src/main.go":                         "",
+		"/elsewhere/e2e/x.ts":                 "",
+	} {
+		if got, _ := rs.WorkType(path); got != want {
+			t.Errorf("WorkType(%q) = %q, want %q", path, got, want)
+		}
+	}
+
+	var none *RepoSettings
+	if _, ok := none.WorkType("e2e/x"); ok {
+		t.Error("nil RepoSettings gave a work type")
+	}
+}
+
+func TestLoadRepoSettingsInvalid(t *testing.T) {
+	root := t.TempDir()
+	writeRepoSettings(t, root, `base_brnach = "develop"
+line_match = "fuzzy"
+revision_threshold = 2
+ignore = "vendor/"
+
+[work_types]
+"docs/" = "prose"
+`)
+	_, err := LoadRepoSettings(root)
+	if err == nil {
+		t.Fatal("want error")
+	}
+	for _, want := range []string{`unknown key "base_brnach"`, "line_match", "revision_threshold", "ignore must be an array", `unknown work type "prose"`} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %q", err, want)
+		}
+	}
+}
+
+func TestRepoSettingsInRepository(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, ".git"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	sub := filepath.Join(root, "services", "api")
+	if err := os.MkdirAll(sub, 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeRepoSettings(t, root, "line_match = \"normalized\"\nsimilarity_threshold = 0.7\nrevision_threshold = 0.9\n")
+
+	rs, err := FindRepoSettings(sub)
+	if err != nil || rs == nil || rs.Root != root {
+		t.Fatalf("FindRepoSettings = %+v, %v", rs, err)
+	}
+	if rs, err := FindRepoSettings(""); rs != nil || err != nil {
+		t.Errorf("FindRepoSettings(\"\") = %v, %v", rs, err)
+	}
+
+	// The repository's settings win over the user's, per-project ones too.
+	cfg := Default()
+	cfg.ProjectLineMatch = map[string]string{sub: "exact"}
+	opts := cfg.MatchOptions(sub)
+	if opts.Mode != "normalized" || opts.Similarity != 0.7 || opts.Revision != 0.9 {
+		t.Errorf("MatchOptions = %+v", opts)
+	}
+
+	var cache RepoSettingsCache
+	if got := cache.For(filepath.Join(sub, "main.go")); got == nil || got.LineMatch != "normalized" {
+		t.Fatalf("cache.For = %+v", got)
+	}
+	writeRepoSettings(t, root, "line_match = \"exact\"\n")
+	if got := cache.For(filepath.Join(sub, "main.go")); got == nil || got.LineMatch != "exact" {
+		t.Errorf("cache.For after a change = %+v", got)
+	}
+	writeRepoSettings(t, root, "line_match = 1\n")
+	if got := cache.For(sub); got != nil {
+		t.Errorf("cache.For with an invalid file = %+v, want nil", got)
+	}
+}
+
+func writeRepoSettings(t *testing.T, root, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(root, RepoSettingsFile), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,118 @@
+package config
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// Trust decides which projects gap-map records data for. It is built from
+// the project_trust, enabled_projects and disabled_projects settings and
+// is safe for concurrent use.
+type Trust struct {
+	allowlist bool
+	enabled   []string
+	disabled  []string
+}
+
+// Trust returns the project trust settings of c.
+func (c *Config) Trust() *Trust {
+	t := &Trust{allowlist: c.ProjectTrust == ProjectTrustAllowlist}
+	for _, p := range c.EnabledProjects {
+		t.enabled = append(t.enabled, cleanProjectPath(p))
+	}
+	for _, p := range c.DisabledProjects {
+		t.disabled = append(t.disabled, cleanProjectPath(p))
+	}
+	return t
+}
+
+// Allowlist reports whether only enabled projects are recorded.
+func (t *Trust) Allowlist() bool {
+	return t.allowlist
+}
+
+// Enabled returns the enabled project directories.
+func (t *Trust) Enabled() []string {
+	return append([]string(nil), t.enabled...)
+}
+
+// Disabled returns the disabled project directories.
+func (t *Trust) Disabled() []string {
+	return append([]string(nil), t.disabled...)
+}
+
+// Allows reports whether data about path may be recorded. The longest
+// enabled or disabled directory containing path decides; with none, path
+// is allowed unless only enabled projects are recorded. An empty path, for
+// data that names no file, is allowed unless only enabled projects are.
+func (t *Trust) Allows(path string) bool {
+	if path == "" {
+		return !t.allowlist
+	}
+	path = cleanProjectPath(path)
+	best, allowed := -1, !t.allowlist
+	for _, dir := range t.enabled {
+		if len(dir) > best && within(dir, path) {
+			best, allowed = len(dir), true
+		}
+	}
+	for _, dir := range t.disabled {
+		if len(dir) >= best && within(dir, path) {
+			best, allowed = len(dir), false
+		}
+	}
+	return allowed
+}
+
+// EnableProject records data for the project at dir: it is added to
+// enabled_projects, where it is needed, and removed from
+// disabled_projects.
+func (c *Config) EnableProject(dir string) {
+	dir = cleanProjectPath(dir)
+	c.DisabledProjects = removeProject(c.DisabledProjects, dir)
+	if c.ProjectTrust == ProjectTrustAllowlist || !c.Trust().Allows(dir) {
+		c.EnabledProjects = appendProject(c.EnabledProjects, dir)
+	}
+}
+
+// DisableProject stops recording data for the project at dir: it is added
+// to disabled_projects and removed from enabled_projects.
+func (c *Config) DisableProject(dir string) {
+	dir = cleanProjectPath(dir)
+	c.EnabledProjects = removeProject(c.EnabledProjects, dir)
+	c.DisabledProjects = appendProject(c.DisabledProjects, dir)
+}
+
+// within reports whether path is dir or below it.
+func within(dir, path string) bool {
+	if path == dir {
+		return true
+	}
+	if !strings.HasSuffix(dir, string(filepath.Separator)) {
+		dir += string(filepath.Separator)
+	}
+	return strings.HasPrefix(path, dir)
+}
+
+func cleanProjectPath(p string) string {
+	return filepath.Clean(expandTilde(p))
+}
+
+func removeProject(list []string, dir string) []string {
+	kept := make([]string, 0, len(list))
+	for _, p := range list {
+		if cleanProjectPath(p) != dir {
+			kept = append(kept, p)
+		}
+	}
+	return kept
+}
+
+func appendProject(list []string, dir string) []string {
+	for _, p := range list {
+		if cleanProjectPath(p) == dir {
+			return list
+		}
+	}
+	return append(list, dir)
+}
@@ -0,0 +1,58 @@
+package config
+
+import "testing"
+
+func TestTrust(t *testing.T) {
+	cfg := Default()
+	if !cfg.Trust().Allows("/work/api/main.go") || !cfg.Trust().Allows("") {
+		t.Fatal("default trust should record every project")
+	}
+
+	cfg.DisableProject("/work/client")
+	cfg.DisableProject("/work/client/") // no duplicate
+	trust := cfg.Trust()
+	for path, want := range map[string]bool{
+		"/work/client":         false,
+		"/work/client/main.go": false,
+		"/work/clients/x.go":   true,
+		"/work/api/main.go":    true,
+	} {
+		if got := trust.Allows(path); got != want {
+			t.Errorf("Allows(%s) = %v, want %v", path, got, want)
+		}
+	}
+	if len(cfg.DisabledProjects) != 1 {
+		t.Errorf("DisabledProjects = %v", cfg.DisabledProjects)
+	}
+
+	// Allowlist mode records enabled projects only; the longest entry
+	// containing a path decides.
+	cfg.ProjectTrust = ProjectTrustAllowlist
+	cfg.EnableProject("/work")
+	trust = cfg.Trust()
+	for path, want := range map[string]bool{
+		"/work/api/main.go":    true,
+		"/work/client/main.go": false,
+		"/home/me/notes.go":    false,
+		"":                     false,
+	} {
+		if got := trust.Allows(path); got != want {
+			t.Errorf("allowlist: Allows(%q) = %v, want %v", path, got, want)
+		}
+	}
+
+	cfg.EnableProject("/work/client")
+	if !cfg.Trust().Allows("/work/client/main.go") || len(cfg.DisabledProjects) != 0 {
+		t.Errorf("after enable: DisabledProjects = %v", cfg.DisabledProjects)
+	}
+	cfg.DisableProject("/work")
+	if len(cfg.EnabledProjects) != 1 || cfg.Trust().Allows("/work/api/x.go") || !cfg.Trust().Allows("/work/client/x.go") {
+		t.Errorf("after disable: enabled %v, disabled %v", cfg.EnabledProjects, cfg.DisabledProjects)
+	}
+
+	cfg.ProjectTrust = "trusting"
+	cfg.EnabledProjects = append(cfg.EnabledProjects, "relative/dir")
+	if err := cfg.Validate(); err == nil {
+		t.Error("Validate accepted an unknown project_trust and a relative project")
+	}
+}
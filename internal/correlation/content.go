@@ -0,0 +1,64 @@
+package correlation
+
+import (
+	"os"
+	"strings"
+
+	"github.com/anthropic/gap-map/internal/authorship"
+	"github.com/anthropic/gap-map/internal/metrics"
+	"github.com/anthropic/gap-map/internal/store"
+)
+
+// ContentReader is implemented by stores that return the content of a
+// session event. When the correlator's store implements it and Extract is
+// set, exact_file matches are checked against the file on disk.
+type ContentReader interface {
+	QuerySessionEventContent(id int64, extract store.ContentExtractor) (store.SessionContent, error)
+}
+
+// verifyContent records on an exact_file result how much of the matched
+// edit's content is in the file on disk. Lines are compared normalized,
+// so an editor that formats on save between the AI write and the file
+// event still matches. Nothing is recorded when the edit added no lines
+// or the file cannot be read.
+func (c *Correlator) verifyContent(result *authorship.CorrelationResult) {
+	cr, ok := c.store.(ContentReader)
+	if !ok || c.Extract == nil || result.MatchType != "exact_file" || result.MatchedSession == nil {
+		return
+	}
+	content, err := cr.QuerySessionEventContent(result.MatchedSession.ID, c.Extract)
+	if err != nil {
+		return
+	}
+	current, err := os.ReadFile(result.FileEvent.FilePath)
+	if err != nil {
+		return
+	}
+	if overlap, ok := ContentOverlap(content.Added, string(current)); ok {
+		result.ContentChecked = true
+		result.ContentOverlap = overlap
+	}
+}
+
+// ContentOverlap returns the share of the non-blank lines of written that
+// current contains, each line of current matching once, after formatter
+// differences (whitespace, quote style, trailing commas and semicolons)
+// are normalized away. ok is false when written has no non-blank lines.
+func ContentOverlap(written, current string) (overlap float64, ok bool) {
+	lines := strings.Split(written, "\n")
+	found := metrics.ClassifyLines(lines, []string{current}, "", metrics.MatchOptions{Mode: metrics.MatchNormalized})
+	var total, matched int
+	for i, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		total++
+		if found[i] {
+			matched++
+		}
+	}
+	if total == 0 {
+		return 0, false
+	}
+	return float64(matched) / float64(total), true
+}
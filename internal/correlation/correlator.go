@@ -27,10 +27,32 @@ type StoreReader interface {
 	QuerySessionEventsNearTimestamp(timestamp time.Time, windowMs int) ([]store.StoredSessionEvent, error)
 }
 
+// TypingReader is implemented by stores that record editor typing bursts.
+// When the correlator's store implements it, typing reported on a file just
+// before a file event is treated as evidence that a human made the edit.
+type TypingReader interface {
+	QueryTypingBurstsInWindow(filePath string, start, end time.Time) ([]store.TypingBurst, error)
+}
+
+// TypingSlackMs is how long after a typing burst ends a file event can still
+// be the save of what was typed.
+const TypingSlackMs = 2000
+
 // Correlator matches file events to session events by time proximity.
 type Correlator struct {
 	store    StoreReader
 	WindowMs int // Configurable window; defaults to DefaultWindowMs.
+
+	// MaxSkewMs is the largest clock skew between a session's machine and
+	// this one that is estimated and compensated for; 0 (the default)
+	// turns skew compensation off.
+	MaxSkewMs int
+	skew      skewEstimator
+
+	// Extract reads the content of a session event's raw JSON, for
+	// checking exact_file matches against the file on disk (see
+	// ContentReader); nil skips the check.
+	Extract store.ContentExtractor
 }
 
 // New creates a new Correlator with the given store reader and default window.
@@ -51,20 +73,41 @@ func New(reader StoreReader) *Correlator {
 //     the same name but different path prefix (handles path normalization,
 //     relative vs absolute paths, etc.). Pick closest in time.
 //  3. No match: no AI Write/Edit detected near this file event.
+//
+// A typing burst reported on the same file overrides a session match that
+// is further from the file event than the end of the burst: the human was
+// typing right up to the save, so the match type becomes "typing".
+//
+// An exact file match records how much of the matched edit is in the file
+// on disk, when the store can supply the edit's content.
 func (c *Correlator) CorrelateFileEvent(fe store.FileEvent) (*authorship.CorrelationResult, error) {
+	result, err := c.correlateSession(fe)
+	if err != nil {
+		return nil, err
+	}
+	c.verifyContent(result)
+	return c.applyTyping(result)
+}
+
+// correlateSession matches a file event to the closest session event,
+// shifting each session's events by its estimated clock offset.
+func (c *Correlator) correlateSession(fe store.FileEvent) (*authorship.CorrelationResult, error) {
 	windowDur := time.Duration(c.WindowMs) * time.Millisecond
-	start := fe.Timestamp.Add(-windowDur)
-	end := fe.Timestamp.Add(windowDur)
+	reach := windowDur + c.skew.maxOffset()
+	if c.MaxSkewMs > 0 {
+		reach = windowDur + time.Duration(c.MaxSkewMs)*time.Millisecond
+	}
+	start := fe.Timestamp.Add(-reach)
+	end := fe.Timestamp.Add(reach)
 
 	// Step 1: exact file match (Write/Edit on same file)
 	sessions, err := c.store.QuerySessionEventsInWindow(fe.FilePath, start, end)
 	if err != nil {
 		return nil, err
 	}
+	c.sampleSkew(fe, sessions)
 
-	if len(sessions) > 0 {
-		closest := pickClosest(fe.Timestamp, sessions)
-		delta := absDurationMs(fe.Timestamp, closest.Timestamp)
+	if closest, delta, ok := c.pickClosestAdjusted(fe.Timestamp, sessions); ok {
 		return &authorship.CorrelationResult{
 			FileEvent:      fe,
 			MatchedSession: &closest,
@@ -75,7 +118,7 @@ func (c *Correlator) CorrelateFileEvent(fe store.FileEvent) (*authorship.Correla
 
 	// Step 2: Fuzzy file match — Claude wrote a file with the same name but
 	// different path prefix (handles path normalization, relative vs absolute, etc.)
-	allSessions, err := c.store.QuerySessionEventsNearTimestamp(fe.Timestamp, c.WindowMs)
+	allSessions, err := c.store.QuerySessionEventsNearTimestamp(fe.Timestamp, int((windowDur + c.skew.maxOffset()).Milliseconds()))
 	if err != nil {
 		return nil, err
 	}
@@ -88,9 +131,7 @@ func (c *Correlator) CorrelateFileEvent(fe store.FileEvent) (*authorship.Correla
 				fuzzyMatches = append(fuzzyMatches, se)
 			}
 		}
-		if len(fuzzyMatches) > 0 {
-			closest := pickClosest(fe.Timestamp, fuzzyMatches)
-			delta := absDurationMs(fe.Timestamp, closest.Timestamp)
+		if closest, delta, ok := c.pickClosestAdjusted(fe.Timestamp, fuzzyMatches); ok {
 			return &authorship.CorrelationResult{
 				FileEvent:      fe,
 				MatchedSession: &closest,
@@ -118,45 +159,56 @@ func (c *Correlator) CorrelateAll(projectPath string, since time.Time) ([]author
 
 	results := make([]authorship.CorrelationResult, 0, len(events))
 	for _, fe := range events {
-		cr, err := c.store.QuerySessionEventsInWindow(fe.FilePath, fe.Timestamp.Add(-time.Duration(c.WindowMs)*time.Millisecond), fe.Timestamp.Add(time.Duration(c.WindowMs)*time.Millisecond))
+		result, err := c.CorrelateFileEvent(fe)
 		if err != nil {
 			return nil, err
 		}
+		results = append(results, *result)
+	}
 
-		var result authorship.CorrelationResult
-		result.FileEvent = fe
-
-		if len(cr) > 0 {
-			closest := pickClosest(fe.Timestamp, cr)
-			result.MatchedSession = &closest
-			result.TimeDeltaMs = absDurationMs(fe.Timestamp, closest.Timestamp)
-			result.MatchType = "exact_file"
-		} else {
-			// Try fuzzy file match (same name, different prefix)
-			allSessions, err := c.store.QuerySessionEventsNearTimestamp(fe.Timestamp, c.WindowMs)
-			if err != nil {
-				return nil, err
-			}
-			var fuzzyMatches []store.StoredSessionEvent
-			for _, se := range allSessions {
-				if pathSuffixMatch(fe.FilePath, se.FilePath) {
-					fuzzyMatches = append(fuzzyMatches, se)
-				}
-			}
-			if len(fuzzyMatches) > 0 {
-				closest := pickClosest(fe.Timestamp, fuzzyMatches)
-				result.MatchedSession = &closest
-				result.TimeDeltaMs = absDurationMs(fe.Timestamp, closest.Timestamp)
-				result.MatchType = "fuzzy_file"
-			} else {
-				result.MatchType = "none"
-			}
-		}
+	return results, nil
+}
 
-		results = append(results, result)
+// applyTyping replaces a correlation result with a "typing" result when a
+// typing burst on the file is at least as close to the file event as the
+// matched session event.
+func (c *Correlator) applyTyping(result *authorship.CorrelationResult) (*authorship.CorrelationResult, error) {
+	tr, ok := c.store.(TypingReader)
+	if !ok {
+		return result, nil
+	}
+	fe := result.FileEvent
+	bursts, err := tr.QueryTypingBurstsInWindow(fe.FilePath, fe.Timestamp.Add(-TypingSlackMs*time.Millisecond), fe.Timestamp)
+	if err != nil {
+		return nil, err
 	}
 
-	return results, nil
+	gap := int64(-1)
+	for _, b := range bursts {
+		if b.CharsTyped <= 0 {
+			continue
+		}
+		// A burst still running at the file event has no gap.
+		var d int64
+		if b.EndedAt.Before(fe.Timestamp) {
+			d = absDurationMs(fe.Timestamp, b.EndedAt)
+		}
+		if gap < 0 || d < gap {
+			gap = d
+		}
+	}
+	if gap < 0 {
+		return result, nil
+	}
+	if result.MatchedSession != nil && result.TimeDeltaMs < gap {
+		return result, nil
+	}
+
+	return &authorship.CorrelationResult{
+		FileEvent:   fe,
+		TimeDeltaMs: gap,
+		MatchType:   "typing",
+	}, nil
 }
 
 // pickClosest returns the session event closest in time to the reference timestamp.
@@ -1,6 +1,9 @@
 package correlation
 
 import (
+	"fmt"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -391,3 +394,274 @@ func TestAbsDurationMs(t *testing.T) {
 		t.Errorf("absDurationMs (reversed) = %d, want 2500", got)
 	}
 }
+
+// typingStoreReader adds editor typing bursts to mockStoreReader.
+type typingStoreReader struct {
+	mockStoreReader
+	bursts []store.TypingBurst
+}
+
+func (m *typingStoreReader) QueryTypingBurstsInWindow(filePath string, start, end time.Time) ([]store.TypingBurst, error) {
+	var out []store.TypingBurst
+	for _, b := range m.bursts {
+		if b.FilePath == filePath && !b.EndedAt.Before(start) && !b.StartedAt.After(end) {
+			out = append(out, b)
+		}
+	}
+	return out, nil
+}
+
+type contentStoreReader struct {
+	mockStoreReader
+	content map[int64]store.SessionContent
+}
+
+func (m *contentStoreReader) QuerySessionEventContent(id int64, extract store.ContentExtractor) (store.SessionContent, error) {
+	return m.content[id], nil
+}
+
+func TestContentOverlap(t *testing.T) {
+	written := "func add(a, b int) int {\n\treturn a+b;\n}\n\nconst name = 'gap';\n"
+	tests := []struct {
+		name    string
+		current string
+		want    float64
+	}{
+		{"identical", written, 1},
+		{"formatted on save", "package x\n\nfunc add(a, b int) int {\n    return a + b\n}\n\nconst name = \"gap\"\n", 1},
+		{"half rewritten", "func add(a, b int) int {\n\treturn b - a\n}\n\nconst other = 1\n", 0.5},
+		{"different file", "package y\n", 0},
+	}
+	for _, tt := range tests {
+		got, ok := ContentOverlap(written, tt.current)
+		if !ok || got != tt.want {
+			t.Errorf("%s: ContentOverlap = %v, %v; want %v", tt.name, got, ok, tt.want)
+		}
+	}
+	if _, ok := ContentOverlap("\n  \n", "x"); ok {
+		t.Error("blank written content should not be checked")
+	}
+}
+
+func TestCorrelateFileEvent_VerifiesContent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "foo.go")
+	if err := os.WriteFile(path, []byte("func f() {\n    return\n}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	fe := store.FileEvent{ID: 1, ProjectPath: "/proj", FilePath: path, EventType: "write", Timestamp: baseTime}
+	mock := &contentStoreReader{
+		mockStoreReader: mockStoreReader{sessionEvents: []store.StoredSessionEvent{{
+			ID: 10, SessionID: "s1", EventType: "tool_use", ToolName: "Write",
+			FilePath: path, Timestamp: baseTime.Add(-100 * time.Millisecond),
+		}}},
+		content: map[int64]store.SessionContent{10: {Added: "func f() {\n\treturn;\n}\n"}},
+	}
+
+	// Without an extractor, nothing is checked.
+	c := New(mock)
+	result, err := c.CorrelateFileEvent(fe)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.ContentChecked {
+		t.Error("content checked without an extractor")
+	}
+
+	c.Extract = func(string) (string, string) { return "", "" }
+	result, err = c.CorrelateFileEvent(fe)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.MatchType != "exact_file" || !result.ContentChecked || result.ContentOverlap != 1 {
+		t.Errorf("got %s, checked %v, overlap %v; want exact_file, checked, 1", result.MatchType, result.ContentChecked, result.ContentOverlap)
+	}
+}
+
+func TestCorrelateFileEvent_TypingOverridesFartherSession(t *testing.T) {
+	// Claude wrote the file 3s before the save, but the human was typing
+	// in it until 200ms before the save.
+	fe := store.FileEvent{
+		ID: 1, ProjectPath: "/proj", FilePath: "foo.go",
+		EventType: "write", Timestamp: baseTime,
+	}
+	mock := &typingStoreReader{
+		mockStoreReader: mockStoreReader{sessionEvents: []store.StoredSessionEvent{{
+			ID: 10, SessionID: "s1", EventType: "tool_use", ToolName: "Edit",
+			FilePath: "foo.go", Timestamp: baseTime.Add(-3 * time.Second),
+		}}},
+		bursts: []store.TypingBurst{{
+			FilePath: "foo.go", CharsTyped: 40,
+			StartedAt: baseTime.Add(-10 * time.Second), EndedAt: baseTime.Add(-200 * time.Millisecond),
+		}},
+	}
+
+	result, err := New(mock).CorrelateFileEvent(fe)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.MatchType != "typing" || result.MatchedSession != nil {
+		t.Errorf("MatchType = %q (session %v), want typing with no session", result.MatchType, result.MatchedSession)
+	}
+	if result.TimeDeltaMs != 200 {
+		t.Errorf("TimeDeltaMs = %d, want 200", result.TimeDeltaMs)
+	}
+}
+
+func TestCorrelateFileEvent_CloserSessionBeatsTyping(t *testing.T) {
+	// The human stopped typing 1.5s before the save; Claude's Edit landed
+	// 100ms before it, so the save is Claude's.
+	fe := store.FileEvent{
+		ID: 1, ProjectPath: "/proj", FilePath: "foo.go",
+		EventType: "write", Timestamp: baseTime,
+	}
+	mock := &typingStoreReader{
+		mockStoreReader: mockStoreReader{sessionEvents: []store.StoredSessionEvent{{
+			ID: 10, SessionID: "s1", EventType: "tool_use", ToolName: "Edit",
+			FilePath: "foo.go", Timestamp: baseTime.Add(-100 * time.Millisecond),
+		}}},
+		bursts: []store.TypingBurst{{
+			FilePath: "foo.go", CharsTyped: 12,
+			StartedAt: baseTime.Add(-5 * time.Second), EndedAt: baseTime.Add(-1500 * time.Millisecond),
+		}},
+	}
+
+	result, err := New(mock).CorrelateFileEvent(fe)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.MatchType != "exact_file" {
+		t.Errorf("MatchType = %q, want exact_file", result.MatchType)
+	}
+}
+
+func TestCorrelateFileEvent_StaleTypingIgnored(t *testing.T) {
+	// Typing that ended well before the save (beyond TypingSlackMs) says
+	// nothing about who made it.
+	fe := store.FileEvent{
+		ID: 1, ProjectPath: "/proj", FilePath: "foo.go",
+		EventType: "write", Timestamp: baseTime,
+	}
+	mock := &typingStoreReader{
+		mockStoreReader: mockStoreReader{sessionEvents: []store.StoredSessionEvent{{
+			ID: 10, SessionID: "s1", EventType: "tool_use", ToolName: "Write",
+			FilePath: "foo.go", Timestamp: baseTime.Add(-4 * time.Second),
+		}}},
+		bursts: []store.TypingBurst{{
+			FilePath: "foo.go", CharsTyped: 30,
+			StartedAt: baseTime.Add(-20 * time.Second), EndedAt: baseTime.Add(-10 * time.Second),
+		}},
+	}
+
+	result, err := New(mock).CorrelateFileEvent(fe)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.MatchType != "exact_file" {
+		t.Errorf("MatchType = %q, want exact_file", result.MatchType)
+	}
+}
+
+func TestCorrelateFileEvent_ClockSkew(t *testing.T) {
+	// The session's machine runs 60s behind: each Write lands on disk 60s
+	// after its session timestamp, far outside the 5s window.
+	const skew = 60 * time.Second
+	mock := &mockStoreReader{}
+	var fileEvents []store.FileEvent
+	// Irregular gaps, as real edits have, so only the true offset recurs.
+	for i, sec := range []int{0, 7, 31, 52, 90} {
+		at := baseTime.Add(time.Duration(sec) * time.Second)
+		mock.sessionEvents = append(mock.sessionEvents, store.StoredSessionEvent{
+			ID: int64(10 + i), SessionID: "remote", EventType: "tool_use", ToolName: "Write",
+			FilePath: "foo.go", Timestamp: at,
+		})
+		fileEvents = append(fileEvents, store.FileEvent{
+			ID: int64(i + 1), ProjectPath: "/proj", FilePath: "foo.go",
+			EventType: "write", Timestamp: at.Add(skew),
+		})
+	}
+
+	plain := New(mock)
+	if result, _ := plain.CorrelateFileEvent(fileEvents[4]); result.MatchType != "none" {
+		t.Errorf("without skew compensation MatchType = %q, want none", result.MatchType)
+	}
+
+	c := New(mock)
+	c.MaxSkewMs = 120000
+	for i, fe := range fileEvents {
+		result, err := c.CorrelateFileEvent(fe)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		// The offset applies once MinSkewSamples samples agree.
+		if i < MinSkewSamples-1 {
+			if result.MatchType != "none" {
+				t.Errorf("event %d: MatchType = %q before the skew is estimated, want none", i, result.MatchType)
+			}
+			continue
+		}
+		if result.MatchType != "exact_file" || result.MatchedSession == nil || result.MatchedSession.ID != int64(10+i) {
+			t.Errorf("event %d: result = %+v, want exact_file match of session event %d", i, result, 10+i)
+			continue
+		}
+		if result.TimeDeltaMs != 0 {
+			t.Errorf("event %d: TimeDeltaMs = %d, want 0 after compensation", i, result.TimeDeltaMs)
+		}
+	}
+	if got := c.SessionOffset("remote"); got != skew {
+		t.Errorf("SessionOffset = %v, want %v", got, skew)
+	}
+}
+
+// BenchmarkCorrelateFileEvent measures correlating a file event against a
+// store holding 10k, 100k and 1M session events spread over 500 files and
+// a month; the largest is skipped with -short.
+func BenchmarkCorrelateFileEvent(b *testing.B) {
+	const files = 500
+	start := time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)
+	for _, n := range []int{10_000, 100_000, 1_000_000} {
+		b.Run(fmt.Sprintf("events=%d", n), func(b *testing.B) {
+			if testing.Short() && n > 100_000 {
+				b.Skip("large dataset skipped with -short")
+			}
+			s, err := store.New(filepath.Join(b.TempDir(), "bench.db"))
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer s.Close()
+			step := 30 * 24 * time.Hour / time.Duration(n)
+			batch := make([]store.NewSessionEvent, 0, 10_000)
+			for i := 0; i < n; i++ {
+				batch = append(batch, store.NewSessionEvent{
+					SessionID: fmt.Sprintf("s%d", i/1000),
+					EventType: "tool_use",
+					ToolName:  "Edit",
+					FilePath:  fmt.Sprintf("/work/api/pkg%d/file%d.go", i%files%20, i%files),
+					Timestamp: start.Add(time.Duration(i) * step),
+					RawJSON:   fmt.Sprintf(`{"uuid":"u%d"}`, i),
+				})
+				if len(batch) == cap(batch) || i == n-1 {
+					if err := s.InsertSessionEvents(batch); err != nil {
+						b.Fatal(err)
+					}
+					batch = batch[:0]
+				}
+			}
+
+			c := New(s)
+			i := 0
+			for b.Loop() {
+				j := (i * 7919) % n // spread lookups over the month
+				fe := store.FileEvent{
+					ID:          int64(i),
+					ProjectPath: "/work/api",
+					FilePath:    fmt.Sprintf("/work/api/pkg%d/file%d.go", j%files%20, j%files),
+					Timestamp:   start.Add(time.Duration(j)*step + time.Second),
+				}
+				if _, err := c.CorrelateFileEvent(fe); err != nil {
+					b.Fatal(err)
+				}
+				i++
+			}
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package correlation
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/anthropic/gap-map/internal/store"
+)
+
+// Clock skew estimation. When the AI client runs on another machine (a
+// remote devcontainer, say) and files sync back, session timestamps follow
+// that machine's clock and file events this one's, and the two can differ
+// by more than the correlation window. The correlator samples the offsets
+// between each file event and the session events on the same file within
+// MaxSkewMs. Pairs of an AI write and the file event it caused share the
+// session's offset, unrelated pairs scatter, so the estimate is the median
+// of the largest cluster of offsets, applied once the cluster has
+// MinSkewSamples samples.
+const (
+	// MinSkewSamples is how many matching samples a session needs before
+	// its estimated offset is applied.
+	MinSkewSamples = 3
+
+	// maxSkewSamples is how many of a session's most recent samples the
+	// estimate uses.
+	maxSkewSamples = 200
+)
+
+// skewEstimator keeps the clock offset samples and estimate of each
+// session.
+type skewEstimator struct {
+	mu        sync.Mutex
+	samples   map[string][]time.Duration // by session ID, oldest first
+	estimates map[string]time.Duration   // by session ID
+}
+
+// add records that file events happened offsets after session events of
+// sessionID, as the two clocks tell it, and updates the session's
+// estimate. Offsets within tolerance of each other count as one cluster.
+func (e *skewEstimator) add(sessionID string, offsets []time.Duration, tolerance time.Duration) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.samples == nil {
+		e.samples = make(map[string][]time.Duration)
+		e.estimates = make(map[string]time.Duration)
+	}
+	s := append(e.samples[sessionID], offsets...)
+	if len(s) > maxSkewSamples {
+		s = s[len(s)-maxSkewSamples:]
+	}
+	e.samples[sessionID] = s
+	e.estimates[sessionID] = estimateSkew(s, tolerance)
+}
+
+// offset returns the estimated offset of sessionID, or 0 if none.
+func (e *skewEstimator) offset(sessionID string) time.Duration {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.estimates[sessionID]
+}
+
+// maxOffset returns the largest absolute offset applied to any session.
+func (e *skewEstimator) maxOffset() time.Duration {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	var max time.Duration
+	for _, d := range e.estimates {
+		if d < 0 {
+			d = -d
+		}
+		if d > max {
+			max = d
+		}
+	}
+	return max
+}
+
+// estimateSkew returns the median of the largest cluster of samples: the
+// samples within tolerance of the sample with the most such neighbours.
+// Returns 0 when no cluster has MinSkewSamples samples.
+func estimateSkew(samples []time.Duration, tolerance time.Duration) time.Duration {
+	sorted := append([]time.Duration(nil), samples...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	// Sliding window over the sorted samples: the widest run spanning at
+	// most 2*tolerance.
+	bestLo, bestHi := 0, 0
+	lo := 0
+	for hi := range sorted {
+		for sorted[hi]-sorted[lo] > 2*tolerance {
+			lo++
+		}
+		if hi+1-lo > bestHi-bestLo {
+			bestLo, bestHi = lo, hi+1
+		}
+	}
+	cluster := sorted[bestLo:bestHi]
+	if len(cluster) < MinSkewSamples {
+		return 0
+	}
+	mid := len(cluster) / 2
+	if len(cluster)%2 == 0 {
+		return (cluster[mid-1] + cluster[mid]) / 2
+	}
+	return cluster[mid]
+}
+
+// SessionOffset returns the clock offset the correlator applies to the
+// events of a session: how far its file events trail the session's clock.
+func (c *Correlator) SessionOffset(sessionID string) time.Duration {
+	return c.skew.offset(sessionID)
+}
+
+// sampleSkew records the offsets between a file event and the session
+// events on the same file within MaxSkewMs of it.
+func (c *Correlator) sampleSkew(fe store.FileEvent, sessions []store.StoredSessionEvent) {
+	if c.MaxSkewMs <= 0 {
+		return
+	}
+	maxSkew := time.Duration(c.MaxSkewMs) * time.Millisecond
+	bySession := make(map[string][]time.Duration)
+	for _, se := range sessions {
+		if absDuration(fe.Timestamp, se.Timestamp) <= maxSkew {
+			bySession[se.SessionID] = append(bySession[se.SessionID], fe.Timestamp.Sub(se.Timestamp))
+		}
+	}
+	tolerance := time.Duration(c.WindowMs) * time.Millisecond / 2
+	for id, offsets := range bySession {
+		c.skew.add(id, offsets, tolerance)
+	}
+}
+
+// pickClosestAdjusted returns the session event closest to ref once its
+// session's clock offset is applied, and the distance between them in
+// milliseconds. ok is false when none is within the correlation window.
+func (c *Correlator) pickClosestAdjusted(ref time.Time, sessions []store.StoredSessionEvent) (best store.StoredSessionEvent, deltaMs int64, ok bool) {
+	window := time.Duration(c.WindowMs) * time.Millisecond
+	bestDelta := time.Duration(-1)
+	for _, se := range sessions {
+		d := absDuration(ref, se.Timestamp.Add(c.skew.offset(se.SessionID)))
+		if d > window {
+			continue
+		}
+		if bestDelta < 0 || d < bestDelta {
+			best, bestDelta = se, d
+		}
+	}
+	return best, bestDelta.Milliseconds(), bestDelta >= 0
+}
@@ -0,0 +1,190 @@
+// Package coverage reads test coverage profiles, so attribution can be
+// split by whether lines are exercised by tests. It understands Go cover
+// profiles (go test -coverprofile) and LCOV tracefiles (genhtml, c8, jest,
+// pytest-cov and most other tools can emit LCOV).
+package coverage
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Profile is the line coverage of a set of source files.
+type Profile struct {
+	Path string // file the profile was loaded from, if any
+
+	files map[string]map[int]bool // path as written in the profile -> line -> covered
+	paths []string                // sorted keys of files, for stable lookups
+}
+
+// Load reads the coverage profile at path.
+func Load(path string) (*Profile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open coverage profile: %w", err)
+	}
+	defer f.Close()
+	p, err := Parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	p.Path = path
+	return p, nil
+}
+
+// Parse reads a Go cover profile or an LCOV tracefile, telling them apart
+// by the "mode:" header Go profiles start with.
+func Parse(r io.Reader) (*Profile, error) {
+	p := &Profile{files: make(map[string]map[int]bool)}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	first := true
+	goProfile := false
+	current := "" // LCOV source file
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if first {
+			first = false
+			if strings.HasPrefix(line, "mode:") {
+				goProfile = true
+				continue
+			}
+		}
+
+		var err error
+		if goProfile {
+			err = p.addGoBlock(line)
+		} else {
+			current, err = p.addLCOVRecord(current, line)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(p.files) == 0 {
+		return nil, fmt.Errorf("no coverage data")
+	}
+
+	for path := range p.files {
+		p.paths = append(p.paths, path)
+	}
+	sort.Strings(p.paths)
+	return p, nil
+}
+
+// addGoBlock records one block of a Go cover profile:
+//
+//	name.go:startLine.startCol,endLine.endCol numStatements count
+//
+// A line is covered if any block spanning it ran, since a line can hold
+// the end of one block and the start of the next.
+func (p *Profile) addGoBlock(line string) error {
+	if strings.HasPrefix(line, "mode:") {
+		// Profiles concatenated from several runs repeat the header.
+		return nil
+	}
+	colon := strings.LastIndex(line, ":")
+	if colon < 0 {
+		return fmt.Errorf("malformed block %q", line)
+	}
+	fields := strings.Fields(line[colon+1:])
+	if len(fields) != 3 {
+		return fmt.Errorf("malformed block %q", line)
+	}
+	start, end, ok := strings.Cut(fields[0], ",")
+	if !ok {
+		return fmt.Errorf("malformed block %q", line)
+	}
+	startLine, err1 := strconv.Atoi(strings.SplitN(start, ".", 2)[0])
+	endLine, err2 := strconv.Atoi(strings.SplitN(end, ".", 2)[0])
+	count, err3 := strconv.Atoi(fields[2])
+	if err1 != nil || err2 != nil || err3 != nil || endLine < startLine {
+		return fmt.Errorf("malformed block %q", line)
+	}
+
+	lines := p.file(line[:colon])
+	for l := startLine; l <= endLine; l++ {
+		lines[l] = lines[l] || count > 0
+	}
+	return nil
+}
+
+// addLCOVRecord handles one LCOV line, given the source file of the
+// current record, and returns the source file for the next line. Only SF,
+// DA and end_of_record matter for line coverage; the rest is skipped.
+func (p *Profile) addLCOVRecord(current, line string) (string, error) {
+	switch {
+	case strings.HasPrefix(line, "SF:"):
+		return strings.TrimPrefix(line, "SF:"), nil
+	case line == "end_of_record":
+		return "", nil
+	case strings.HasPrefix(line, "DA:"):
+		if current == "" {
+			return "", fmt.Errorf("DA outside a record")
+		}
+		// DA:<line>,<count>[,<checksum>]
+		fields := strings.Split(strings.TrimPrefix(line, "DA:"), ",")
+		if len(fields) < 2 {
+			return "", fmt.Errorf("malformed %q", line)
+		}
+		l, err1 := strconv.Atoi(fields[0])
+		count, err2 := strconv.ParseFloat(fields[1], 64) // some tools write counts as floats
+		if err1 != nil || err2 != nil {
+			return "", fmt.Errorf("malformed %q", line)
+		}
+		lines := p.file(current)
+		lines[l] = lines[l] || count > 0
+	}
+	return current, nil
+}
+
+func (p *Profile) file(path string) map[int]bool {
+	lines, ok := p.files[path]
+	if !ok {
+		lines = make(map[int]bool)
+		p.files[path] = lines
+	}
+	return lines
+}
+
+// Files returns how many source files the profile covers.
+func (p *Profile) Files() int {
+	return len(p.files)
+}
+
+// Lines returns the instrumented lines of a file, mapped to whether they
+// were covered, or nil if the profile does not include the file. Lines
+// that are not instrumented (comments, declarations) are absent.
+//
+// Profiles name files differently: Go profiles use the import path
+// ("example.com/mod/pkg/file.go") and LCOV usually an absolute path. So
+// filePath, best given relative to the project root, matches a profile
+// entry when either is a suffix of the other at a path boundary.
+func (p *Profile) Lines(filePath string) map[int]bool {
+	filePath = filepath.ToSlash(filepath.Clean(filePath))
+	if lines, ok := p.files[filePath]; ok {
+		return lines
+	}
+	for _, path := range p.paths {
+		clean := filepath.ToSlash(filepath.Clean(path))
+		if clean == filePath || strings.HasSuffix(clean, "/"+filePath) || strings.HasSuffix(filePath, "/"+clean) {
+			return p.files[path]
+		}
+	}
+	return nil
+}
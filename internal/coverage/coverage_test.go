@@ -0,0 +1,102 @@
+package coverage
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const goProfile = `mode: set
+example.com/mod/pkg/a.go:3.20,5.2 2 1
+example.com/mod/pkg/a.go:5.2,8.3 1 0
+example.com/mod/pkg/a.go:10.1,11.2 1 0
+example.com/mod/cmd/main.go:4.13,6.2 1 1
+`
+
+func TestParse_GoProfile(t *testing.T) {
+	p, err := Parse(strings.NewReader(goProfile))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if p.Files() != 2 {
+		t.Errorf("Files() = %d, want 2", p.Files())
+	}
+
+	lines := p.Lines("pkg/a.go")
+	want := map[int]bool{3: true, 4: true, 5: true, 6: false, 7: false, 8: false, 10: false, 11: false}
+	if len(lines) != len(want) {
+		t.Fatalf("Lines(pkg/a.go) = %v, want %v", lines, want)
+	}
+	for l, covered := range want {
+		if got, ok := lines[l]; !ok || got != covered {
+			t.Errorf("line %d = %v (present %v), want %v", l, got, ok, covered)
+		}
+	}
+
+	if p.Lines("cmd/main.go") == nil {
+		t.Error("relative path should match the import path by suffix")
+	}
+	if p.Lines("a.go") == nil {
+		t.Error("base name should match at a path boundary")
+	}
+	if p.Lines("ain.go") != nil {
+		t.Error("partial name must not match")
+	}
+	if p.Lines("other/a.go") != nil {
+		t.Error("a file in another directory must not match")
+	}
+}
+
+func TestParse_LCOV(t *testing.T) {
+	lcov := `TN:
+SF:/repo/src/app.js
+FN:1,start
+DA:1,4
+DA:2,0
+DA:3,1.5
+LF:3
+LH:2
+end_of_record
+SF:/repo/src/util.js
+DA:7,0
+end_of_record
+`
+	p, err := Parse(strings.NewReader(lcov))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	lines := p.Lines("src/app.js")
+	if len(lines) != 3 || !lines[1] || lines[2] || !lines[3] {
+		t.Errorf("Lines(src/app.js) = %v", lines)
+	}
+	if lines := p.Lines("src/util.js"); len(lines) != 1 || lines[7] {
+		t.Errorf("Lines(src/util.js) = %v", lines)
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	for name, input := range map[string]string{
+		"empty":         "",
+		"header only":   "mode: count\n",
+		"bad block":     "mode: set\npkg/a.go:3.20 2 1\n",
+		"DA outside SF": "DA:1,1\n",
+	} {
+		if _, err := Parse(strings.NewReader(input)); err == nil {
+			t.Errorf("%s: Parse succeeded, want error", name)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cover.out")
+	if err := os.WriteFile(path, []byte(goProfile), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.out")); err == nil {
+		t.Error("Load of a missing file succeeded")
+	}
+}
@@ -0,0 +1,38 @@
+package daemon
+
+import (
+	"log/slog"
+
+	"github.com/anthropic/gap-map/internal/agent"
+)
+
+// startRemoteAgents starts a forwarder for each configured remote agent.
+// Entries with an invalid path map are logged and skipped.
+func (d *Daemon) startRemoteAgents() {
+	for _, ra := range d.cfg.RemoteAgents {
+		var maps []agent.PathMap
+		valid := true
+		for _, s := range ra.PathMap {
+			m, err := agent.ParsePathMap(s)
+			if err != nil {
+				slog.Warn("remote agent disabled", "target", ra.Target, "err", err)
+				valid = false
+				break
+			}
+			maps = append(maps, m)
+		}
+		if !valid {
+			continue
+		}
+		f := &agent.Forwarder{
+			Store:   d.store,
+			Target:  ra.Target,
+			SSHArgs: ra.SSHArgs,
+			Command: ra.Command,
+			Maps:    maps,
+			Allow:   func(path string) bool { return d.trust.Load().Allows(path) },
+		}
+		go f.Run(d.ctx)
+		slog.Info("remote agent forwarding", "target", ra.Target)
+	}
+}
@@ -0,0 +1,147 @@
+package daemon
+
+import (
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/anthropic/gap-map/internal/authorship"
+	"github.com/anthropic/gap-map/internal/clipboard"
+	"github.com/anthropic/gap-map/internal/config"
+	"github.com/anthropic/gap-map/internal/correlation"
+	"github.com/anthropic/gap-map/internal/sessionparser"
+	"github.com/anthropic/gap-map/internal/store"
+	"github.com/anthropic/gap-map/internal/worktype"
+)
+
+// attributor decides the attributions of file events: correlation ->
+// authorship classification -> work-type classification. The daemon
+// records what it decides; gapmap replay prints it.
+type attributor struct {
+	store        *store.Store
+	correlator   *correlation.Correlator
+	classifier   *authorship.Classifier
+	wtClassifier *worktype.Classifier
+	clipMatcher  *clipboard.Matcher // nil unless clipboard_monitor is on
+}
+
+func newAttributor(s *store.Store, cfg *config.Config) *attributor {
+	a := &attributor{
+		store:        s,
+		correlator:   correlation.New(s),
+		classifier:   authorship.NewClassifier(),
+		wtClassifier: worktype.NewClassifier(s),
+	}
+	repos := &config.RepoSettingsCache{}
+	a.wtClassifier.SetPathRules(func(filePath string) (worktype.WorkType, bool) {
+		return repos.For(filePath).WorkType(filePath)
+	})
+	a.correlator.Extract = sessionparser.ExtractContent
+	if window, err := time.ParseDuration(cfg.CorrelationWindow); err == nil && window > 0 {
+		a.correlator.WindowMs = int(window.Milliseconds())
+	}
+	if skew, err := time.ParseDuration(cfg.ClockSkewWindow); err == nil && skew > 0 {
+		a.correlator.MaxSkewMs = int(skew.Milliseconds())
+	}
+	if cfg.ClipboardMonitor {
+		a.clipMatcher = clipboard.NewMatcher(s, cfg.DiffAlgorithm)
+	}
+	return a
+}
+
+// decide returns the correlation of fe and the attribution records to
+// store for it on branch, with their work type: an addition, and a
+// deletion when the matched edit removed lines. Nothing is stored.
+func (a *attributor) decide(fe store.FileEvent, branch string) (*authorship.CorrelationResult, []store.AttributionRecord, worktype.WorkType, error) {
+	// Step 1: Correlate file event with session events.
+	result, err := a.correlator.CorrelateFileEvent(fe)
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	// Step 1b: With no session match, check whether the edit
+	// pasted a recently copied AI clipboard block. Pasting is
+	// not typing, so this also applies to a typing match.
+	var clipLines int
+	if a.clipMatcher != nil && (result.MatchType == "none" || result.MatchType == "typing") {
+		if content, err := os.ReadFile(fe.FilePath); err == nil {
+			match, err := a.clipMatcher.MatchFileEvent(fe, string(content))
+			if err != nil {
+				slog.Warn("attribution: clipboard match failed", "file", fe.FilePath, "err", err)
+			} else if match != nil {
+				result.MatchType = "clipboard"
+				clipLines = match.Lines
+			}
+		}
+	}
+
+	// Step 2: Classify authorship level (with history for mixed attributions).
+	var prior *authorship.Attribution
+	if priorRecord, err := a.store.QueryLatestAttributionByFile(fe.FilePath); err == nil && priorRecord != nil {
+		prior = &authorship.Attribution{
+			FirstAuthor: priorRecord.FirstAuthor,
+			Level:       authorship.AuthorshipLevel(priorRecord.AuthorshipLevel),
+		}
+	}
+	attr := a.classifier.ClassifyWithHistory(*result, prior)
+
+	// Step 3: Extract diff content and lines_changed from matched session event.
+	var diffContent, deletedContent string
+	var linesChanged int
+	if result.MatchedSession != nil {
+		// Get lines_changed from the matched session event.
+		if seDetails, err := a.store.QuerySessionEventByID(result.MatchedSession.ID); err == nil {
+			linesChanged = seDetails.LinesChanged
+		}
+		// Diff content for work type classification.
+		if content, err := a.store.QuerySessionEventContent(result.MatchedSession.ID, sessionparser.ExtractContent); err == nil {
+			diffContent = content.Added
+			deletedContent = content.Deleted
+		}
+	}
+	if linesChanged == 0 && clipLines > 0 {
+		linesChanged = clipLines
+	}
+	if linesChanged == 0 && diffContent != "" {
+		// Compute from content (handles pre-v5 session events).
+		linesChanged = strings.Count(diffContent, "\n")
+		if !strings.HasSuffix(diffContent, "\n") {
+			linesChanged++
+		}
+	}
+	if linesChanged == 0 {
+		linesChanged = 1 // conservative default when no content available
+	}
+
+	// Step 4: Classify work type with actual content.
+	wt := a.wtClassifier.ClassifyFile(attr.FilePath, diffContent, "")
+
+	// Step 5: Build the store records.
+	record := store.AttributionRecord{
+		FilePath:            attr.FilePath,
+		ProjectPath:         attr.ProjectPath,
+		FileEventID:         attr.FileEventID,
+		SessionEventID:      attr.SessionEventID,
+		AuthorshipLevel:     string(attr.Level),
+		Confidence:          attr.Confidence,
+		Uncertain:           attr.Uncertain,
+		FirstAuthor:         attr.FirstAuthor,
+		CorrelationWindowMs: attr.CorrelationWindowMs,
+		Timestamp:           attr.Timestamp,
+		LinesChanged:        linesChanged,
+		Branch:              branch,
+		MatchType:           result.MatchType,
+	}
+
+	// Lines the matched edit removed are recorded as a separate
+	// deletion attribution.
+	records := []store.AttributionRecord{record}
+	if deletedContent != "" {
+		deletion := record
+		deletion.Kind = store.AttributionDeletion
+		deletion.LinesChanged = strings.Count(strings.TrimSuffix(deletedContent, "\n"), "\n") + 1
+		records = append(records, deletion)
+	}
+	return result, records, wt, nil
+}
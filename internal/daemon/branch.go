@@ -0,0 +1,48 @@
+package daemon
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/anthropic/gap-map/internal/gitint"
+)
+
+// branchCache remembers the current git branch of each project, so the
+// attribution processor need not run git for every event. An entry is
+// reread when the project's .git/HEAD changes.
+type branchCache struct {
+	mu      sync.Mutex
+	entries map[string]branchEntry // by project path
+}
+
+type branchEntry struct {
+	headMod time.Time
+	branch  string
+}
+
+func newBranchCache() *branchCache {
+	return &branchCache{entries: make(map[string]branchEntry)}
+}
+
+// Branch returns the branch checked out in project, the commit hash on a
+// detached HEAD, or "" outside git.
+func (c *branchCache) Branch(project string) string {
+	info, err := os.Stat(filepath.Join(project, ".git", "HEAD"))
+	if err != nil {
+		// Not a repository root, or a worktree whose .git is a file:
+		// ask git each time.
+		branch, _ := gitint.CurrentBranch(project)
+		return branch
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[project]; ok && e.headMod.Equal(info.ModTime()) {
+		return e.branch
+	}
+	branch, _ := gitint.CurrentBranch(project)
+	c.entries[project] = branchEntry{headMod: info.ModTime(), branch: branch}
+	return branch
+}
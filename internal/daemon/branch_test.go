@@ -0,0 +1,34 @@
+package daemon
+
+import (
+	"os"
+	"os/exec"
+	"testing"
+)
+
+func TestBranchCache(t *testing.T) {
+	dir := t.TempDir()
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+		cmd.Env = append(os.Environ(), "GIT_AUTHOR_NAME=t", "GIT_AUTHOR_EMAIL=t@t", "GIT_COMMITTER_NAME=t", "GIT_COMMITTER_EMAIL=t@t")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	git("init", "-q")
+	git("symbolic-ref", "HEAD", "refs/heads/main")
+	git("commit", "-q", "--allow-empty", "-m", "init")
+
+	c := newBranchCache()
+	if got := c.Branch(dir); got != "main" {
+		t.Errorf("Branch = %q, want main", got)
+	}
+	git("checkout", "-q", "-b", "feature")
+	if got := c.Branch(dir); got != "feature" {
+		t.Errorf("Branch after checkout = %q, want feature", got)
+	}
+	if got := c.Branch(t.TempDir()); got != "" {
+		t.Errorf("Branch outside git = %q, want empty", got)
+	}
+}
@@ -3,22 +3,27 @@ package daemon
 import (
 	"context"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
 	"os"
 	"path/filepath"
-	"strconv"
-	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/anthropic/gap-map/internal/api"
 	"github.com/anthropic/gap-map/internal/authorship"
+	"github.com/anthropic/gap-map/internal/clipboard"
 	"github.com/anthropic/gap-map/internal/config"
-	"github.com/anthropic/gap-map/internal/correlation"
+	"github.com/anthropic/gap-map/internal/gitexec"
+	"github.com/anthropic/gap-map/internal/github"
 	"github.com/anthropic/gap-map/internal/gitint"
+	"github.com/anthropic/gap-map/internal/keychain"
+	"github.com/anthropic/gap-map/internal/report"
 	"github.com/anthropic/gap-map/internal/sessionparser"
 	"github.com/anthropic/gap-map/internal/store"
+	"github.com/anthropic/gap-map/internal/telemetry"
 	"github.com/anthropic/gap-map/internal/watcher"
-	"github.com/anthropic/gap-map/internal/worktype"
 )
 
 // IPCServer is the interface the daemon uses to start/stop the IPC listener.
@@ -33,6 +38,10 @@ type StoreAware interface {
 	SetStore(store interface{})
 }
 
+// AttributionListener is called with each attribution the daemon records
+// and its work type.
+type AttributionListener func(rec store.AttributionRecord, workType string)
+
 // Daemon manages the lifecycle of the gap-map background process.
 type Daemon struct {
 	cfg       *config.Config
@@ -41,11 +50,22 @@ type Daemon struct {
 	watcher   *watcher.Watcher
 	startTime time.Time
 
-	sessionParser *sessionparser.ClaudeCodeParser
+	providers     map[string]sessionparser.SessionProvider // by providerKey
+	sessionRoots  []string                                 // Claude Code session directories read
+	tailNotifier  *sessionparser.TailNotifier              // wakes session tailers; nil to poll only
 	gitRepo       *gitint.Repository
 	sessionCancel context.CancelFunc
+	sessionWG     sync.WaitGroup // session tailers and their discovery
 	gitCancel     context.CancelFunc
 	attrCancel    context.CancelFunc
+	gitSyncNow    chan struct{} // requests an immediate git sync
+	onAttribution AttributionListener
+	apiReporter   api.Reporter              // serves the report API; nil disables it
+	sessions      map[string]*tailedSession // by session file path
+	gitHealth     *GitHealth                // outcome of the latest git sync
+	untracked     map[string]int            // AI edits since start by untracked repository
+	trust         atomic.Pointer[config.Trust]
+	git           *gitexec.Executor // runs the git commands of PR refresh reports
 
 	ctx     context.Context
 	cancel  context.CancelFunc
@@ -53,13 +73,28 @@ type Daemon struct {
 	running bool
 }
 
+// The daemon backs up the database on start and daily after, whenever the
+// newest backup is older than backupInterval, keeping backupsKept backups.
+const (
+	backupInterval = 24 * time.Hour
+	backupsKept    = 3
+)
+
+// contentBackfillBatch is how many session events the startup content
+// backfill fills per transaction.
+const contentBackfillBatch = 500
+
 // New creates a new Daemon with the given config.
 // The IPC server is injected to avoid circular imports.
 func New(cfg *config.Config, ipcServer IPCServer) *Daemon {
-	return &Daemon{
-		cfg: cfg,
-		ipc: ipcServer,
+	d := &Daemon{
+		cfg:        cfg,
+		ipc:        ipcServer,
+		gitSyncNow: make(chan struct{}, 1),
+		git:        cfg.GitExecutor(),
 	}
+	d.trust.Store(cfg.Trust())
+	return d
 }
 
 // Start initialises the store, runs migrations, starts the IPC server,
@@ -77,13 +112,26 @@ func (d *Daemon) Start() error {
 		return fmt.Errorf("create data dir: %w", err)
 	}
 
+	// Check the database first; if it is corrupt, fall back to the
+	// newest backup that is not.
+	restored, err := store.RecoverCorrupt(d.cfg.DBPath)
+	if err != nil {
+		return fmt.Errorf("check database: %w", err)
+	}
+	if restored != "" {
+		slog.Warn("database was corrupt, restored from backup", "backup", restored)
+	}
+
 	// Open store (runs migrations).
-	s, err := store.New(d.cfg.DBPath)
+	s, err := store.New(d.cfg.DBPath, store.WithKeySource(keychain.Key))
 	if err != nil {
 		return fmt.Errorf("open store: %w", err)
 	}
 	d.store = s
 
+	// Keep a recent backup to fall back to.
+	d.backup()
+
 	// If the IPC server is StoreAware, give it the store reference.
 	if sa, ok := d.ipc.(StoreAware); ok {
 		sa.SetStore(s)
@@ -107,43 +155,80 @@ func (d *Daemon) Start() error {
 
 	// Start file system watcher if watch paths are configured.
 	if len(d.cfg.WatchPaths) > 0 {
-		d.watcher = watcher.New(s, d.cfg)
+		w := watcher.New(s, d.cfg)
+		w.SetTrust(d.trust.Load())
+		d.mu.Lock()
+		d.watcher = w
+		d.mu.Unlock()
 		go func() {
-			if err := d.watcher.Start(d.ctx); err != nil {
-				log.Printf("watcher error: %v", err)
+			if err := w.Start(d.ctx); err != nil {
+				slog.Error("watcher stopped", "err", err)
 			}
 		}()
 	}
 
 	// --- Session parser integration ---
-	// Discover existing Claude Code session files and start tailing them.
-	d.sessionParser = sessionparser.NewClaudeCodeParser("", 0)
-	sessionFiles, err := d.sessionParser.Discover(d.ctx)
-	if err != nil {
-		log.Printf("session discover error: %v", err)
+	// Discover existing session files of Claude Code, with a parser per
+	// session directory, and of any plugin providers, and start tailing
+	// them.
+	if n, err := sessionparser.NewTailNotifier(); err != nil {
+		slog.Warn("session tailers will poll: file notifications unavailable", "err", err)
+	} else {
+		d.tailNotifier = n
+	}
+	roots := d.cfg.SessionRoots()
+	d.mu.Lock()
+	d.sessionRoots = roots
+	d.mu.Unlock()
+	var providers []sessionparser.SessionProvider
+	for _, root := range roots {
+		providers = append(providers, sessionparser.NewClaudeCodeParser(root, 0, d.cfg.DiffAlgorithm))
+	}
+	for _, pc := range d.cfg.SessionProviders {
+		providers = append(providers, sessionparser.NewGenericProvider(pc.Name, pc.Command, 0, d.cfg.DiffAlgorithm))
+	}
+	d.providers = make(map[string]sessionparser.SessionProvider, len(providers))
+	for _, p := range providers {
+		var root string
+		if rp, ok := p.(sessionparser.RootedProvider); ok {
+			root = rp.Root()
+		}
+		d.providers[providerKey(p.Name(), root)] = p
 	}
-	// discovered session files silently
 
 	sessionCtx, sessionCancel := context.WithCancel(d.ctx)
 	d.sessionCancel = sessionCancel
 
-	for _, sf := range sessionFiles {
-		d.startSessionTailer(sessionCtx, sf)
-	}
-
-	// Watch for new session files (e.g. session rotation).
 	newSessions := make(chan sessionparser.SessionFile, 10)
-	go func() {
-		if err := d.sessionParser.WatchForNew(sessionCtx, newSessions); err != nil {
-			log.Printf("session watcher error: %v", err)
+	for _, p := range providers {
+		sessionFiles, err := p.Discover(d.ctx)
+		if err != nil {
+			slog.Error("session discovery failed", "provider", p.Name(), "err", err)
 		}
-	}()
+		for _, sf := range sessionFiles {
+			d.startSessionTailer(sessionCtx, sf)
+		}
+
+		// Watch for new session files (e.g. session rotation).
+		go func() {
+			if err := p.WatchForNew(sessionCtx, newSessions); err != nil {
+				slog.Error("session watcher stopped", "provider", p.Name(), "err", err)
+			}
+		}()
+	}
+	// Counted in sessionWG so the tailers it starts are added before
+	// shutdown waits for them.
+	d.sessionWG.Add(1)
 	go func() {
+		defer d.sessionWG.Done()
 		for {
 			select {
 			case <-sessionCtx.Done():
 				return
 			case sf := <-newSessions:
+				if sessionCtx.Err() != nil {
+					return
+				}
 				d.startSessionTailer(sessionCtx, sf)
 			}
 		}
@@ -154,19 +239,31 @@ func (d *Daemon) Start() error {
 	if len(d.cfg.WatchPaths) > 0 {
 		repo, err := gitint.Open(d.cfg.WatchPaths[0], d.store)
 		if err != nil {
-			log.Printf("git open warning (not a git repo?): %v", err)
+			slog.Warn("git integration disabled (not a git repo?)", "path", d.cfg.WatchPaths[0], "err", err)
 		} else {
+			d.mu.Lock()
 			d.gitRepo = repo
+			d.mu.Unlock()
 
 			gitCtx, gitCancel := context.WithCancel(d.ctx)
 			d.gitCancel = gitCancel
 
 			// Initial sync: look back 30 days.
-			if err := repo.SyncCommits(gitCtx, time.Now().Add(-gitint.DefaultLookback())); err != nil {
-				log.Printf("git initial sync error: %v", err)
+			if d.trust.Load().Allows(d.cfg.WatchPaths[0]) {
+				since := time.Now().Add(-gitint.DefaultLookback())
+				err := repo.SyncCommits(gitCtx, since)
+				if err != nil {
+					slog.Error("git initial sync failed", "err", err)
+				} else {
+					checkRewrites(gitCtx, repo)
+					checkBranches(gitCtx, repo)
+					checkCoauthors(gitCtx, repo, since)
+				}
+				d.recordGitSync(d.cfg.WatchPaths[0], err)
 			}
 
-			// Periodic sync goroutine.
+			// Periodic sync goroutine. Git hooks can also request a sync
+			// right after a commit via SyncGit.
 			go func() {
 				ticker := time.NewTicker(gitint.SyncInterval())
 				defer ticker.Stop()
@@ -175,11 +272,21 @@ func (d *Daemon) Start() error {
 					case <-gitCtx.Done():
 						return
 					case <-ticker.C:
-						since := time.Now().Add(-gitint.DefaultLookback())
-						if err := repo.SyncCommits(gitCtx, since); err != nil {
-							log.Printf("git sync error: %v", err)
-						}
+					case <-d.gitSyncNow:
 					}
+					if !d.trust.Load().Allows(d.cfg.WatchPaths[0]) {
+						continue
+					}
+					since := time.Now().Add(-gitint.DefaultLookback())
+					err := repo.SyncCommits(gitCtx, since)
+					if err != nil {
+						slog.Error("git sync failed", "err", err)
+					} else {
+						checkRewrites(gitCtx, repo)
+						checkBranches(gitCtx, repo)
+						checkCoauthors(gitCtx, repo, since)
+					}
+					d.recordGitSync(d.cfg.WatchPaths[0], err)
 				}
 			}()
 		}
@@ -193,15 +300,89 @@ func (d *Daemon) Start() error {
 	d.attrCancel = attrCancel
 	d.startAttributionProcessor(attrCtx)
 
-	log.Printf("daemon started (pid %d, db %s, socket %s)", os.Getpid(), d.cfg.DBPath, d.cfg.SocketPath)
+	// --- Clipboard monitor (opt-in) ---
+	if d.cfg.ClipboardMonitor {
+		if read, err := clipboard.SystemReader(); err != nil {
+			slog.Warn("clipboard monitor disabled", "err", err)
+		} else {
+			interval, _ := time.ParseDuration(d.cfg.ClipboardInterval)
+			go clipboard.NewMonitor(s, read, interval).Run(d.ctx)
+		}
+	}
+
+	// --- Telemetry (opt-in) ---
+	if d.cfg.TelemetryEnabled {
+		interval, _ := time.ParseDuration(d.cfg.TelemetryInterval)
+		reporter := telemetry.NewReporter(s, d.cfg.TelemetryPath(), d.cfg.TelemetryEndpoint, interval)
+		if d.cfg.LeaderboardOptIn {
+			reporter.SetLeaderboard(s, telemetry.LeaderboardOptions{
+				Name:      d.cfg.LeaderboardName,
+				Anonymize: d.cfg.LeaderboardAnonymize,
+				Location:  d.cfg.Location(),
+			})
+		}
+		go reporter.Run(d.ctx)
+	}
+
+	// --- PR comment refresh (opt-in) ---
+	d.startPRRefresh()
+
+	// --- Report API (opt-in) ---
+	d.mu.Lock()
+	reporter := d.apiReporter
+	d.mu.Unlock()
+	if d.cfg.APIAddr != "" && reporter != nil {
+		go api.NewServer(d.cfg.APIToken, reporter).Run(d.ctx, d.cfg.APIAddr, d.cfg.APITLSCert, d.cfg.APITLSKey)
+	}
+
+	// --- Survival history ---
+	if interval, _ := time.ParseDuration(d.cfg.SurvivalInterval); interval > 0 {
+		go d.runSurvivalJob(d.ctx, interval)
+	}
+
+	// --- Database maintenance ---
+	if hour, minute, ok := d.cfg.MaintenanceClock(); ok {
+		go d.runMaintenanceJob(d.ctx, hour, minute)
+	}
+
+	// --- Remote agents ---
+	d.startRemoteAgents()
+
+	// --- Session content backfill ---
+	// Events stored before diff content was recorded have it extracted
+	// once, so reports stop re-parsing their raw JSON.
+	go func() {
+		n, err := d.store.BackfillSessionContent(sessionparser.ExtractContent, contentBackfillBatch)
+		if err != nil {
+			slog.Warn("session content backfill failed", "err", err)
+		} else if n > 0 {
+			slog.Info("session content backfilled", "events", n)
+		}
+	}()
+
+	// --- Daily database backup ---
+	go func() {
+		ticker := time.NewTicker(backupInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-d.ctx.Done():
+				return
+			case <-ticker.C:
+				d.backup()
+			}
+		}
+	}()
+
+	slog.Info("daemon started", "pid", os.Getpid(), "db", d.cfg.DBPath, "socket", d.cfg.SocketPath)
 
 	// Block until context is cancelled or IPC server fails.
 	select {
 	case <-d.ctx.Done():
-		log.Println("shutdown signal received")
+		slog.Info("shutdown signal received")
 	case err := <-ipcErrCh:
 		if err != nil {
-			log.Printf("IPC server error: %v", err)
+			slog.Error("IPC server stopped", "err", err)
 		}
 	}
 
@@ -209,6 +390,103 @@ func (d *Daemon) Start() error {
 	return d.shutdown()
 }
 
+// startPRRefresh starts refreshing the PR comments of the first watch path
+// when pr_refresh is set, reporting whether it did.
+func (d *Daemon) startPRRefresh() bool {
+	if !d.cfg.PRRefresh || len(d.cfg.WatchPaths) == 0 {
+		return false
+	}
+
+	interval, _ := time.ParseDuration(d.cfg.PRRefreshInterval)
+	dir := d.cfg.WatchPaths[0]
+	refresher, err := github.NewRefresher(d.store, dir, github.RefreshOptions{
+		Token:         github.DetectToken(),
+		Interval:      interval,
+		Remote:        d.cfg.GitHubRemote,
+		WebhookAddr:   d.cfg.PRWebhookAddr,
+		WebhookSecret: d.cfg.PRWebhookSecret,
+		Allow:         func() bool { return d.trust.Load().Allows(dir) },
+		HTTP:          github.ConfigClientOptions(d.cfg),
+		Policy: github.CommentPolicy{
+			SkipDrafts:   d.cfg.PRCommentSkipDrafts,
+			MinAIPct:     d.cfg.PRCommentMinAIPct,
+			CollapseOver: d.cfg.PRCommentCollapseOver,
+			Notable: github.NotableFiles{
+				MinEvents:  d.cfg.PRCommentNotableMinEvents,
+				MinLines:   d.cfg.PRCommentNotableMinLines,
+				MinAIPct:   d.cfg.PRCommentNotableMinAIPct,
+				Max:        d.cfg.PRCommentNotableMax,
+				Sort:       d.cfg.PRCommentNotableSort,
+				ByWorkType: d.cfg.PRCommentNotableByWorkType,
+			},
+		},
+		Report: report.Options{
+			LineMatch:        d.cfg.MatchOptions,
+			Packages:         d.cfg.Packages,
+			Guard:            d.cfg.FileGuard(),
+			DiffAlgorithm:    d.cfg.DiffAlgorithm,
+			CorrectionWindow: d.cfg.CorrectionWindow(),
+			Git:              d.git,
+		},
+	})
+	if err != nil {
+		slog.Warn("PR comment refresh disabled", "err", err)
+		return false
+	}
+	go refresher.Run(d.ctx)
+	return true
+}
+
+// backup writes a database backup unless a recent one exists.
+func (d *Daemon) backup() {
+	if path, err := d.store.BackupIfStale(backupInterval, backupsKept); err != nil {
+		slog.Warn("database backup failed", "err", err)
+	} else if path != "" {
+		slog.Info("database backed up", "backup", path)
+	}
+}
+
+// checkRewrites re-attributes synced commits after an amend or rebase shows
+// up in the reflog, so attributions and blame data follow the rewritten
+// commits.
+func checkRewrites(ctx context.Context, repo *gitint.Repository) {
+	res, rewritten, err := repo.CheckRewrites(ctx)
+	if err != nil {
+		slog.Error("git rewrite check failed", "err", err)
+		return
+	}
+	if rewritten {
+		slog.Info("git history rewritten",
+			"commits_remapped", res.Rewritten, "attributions_moved", res.Attributions, "files_reblamed", res.Reblamed)
+	}
+}
+
+// checkCoauthors verifies the AI co-author trailers of commits made since
+// since against session data, logging commits newly found to have no
+// session activity behind them.
+func checkCoauthors(ctx context.Context, repo *gitint.Repository, since time.Time) {
+	checked, flagged, err := repo.VerifyCoauthors(ctx, since)
+	if err != nil {
+		slog.Error("git co-author check failed", "err", err)
+		return
+	}
+	if flagged > 0 {
+		slog.Warn("AI co-author trailers without session activity", "commits", flagged, "checked", checked)
+	}
+}
+
+// checkBranches archives the attributions of branches that were merged or
+// deleted since the last sync, so branch reports only show live branches.
+func checkBranches(ctx context.Context, repo *gitint.Repository) {
+	archived, err := repo.CheckBranches(ctx)
+	if err != nil {
+		slog.Error("git branch check failed", "err", err)
+	}
+	for _, b := range archived {
+		slog.Info("branch attributions archived", "branch", b.Branch, "reason", b.Reason, "attributions", b.Attributions)
+	}
+}
+
 // Stop triggers a graceful shutdown from outside (e.g. via IPC stop command).
 func (d *Daemon) Stop() {
 	d.mu.Lock()
@@ -220,12 +498,22 @@ func (d *Daemon) Stop() {
 
 // shutdown performs ordered teardown: IPC server, then store, then socket cleanup.
 func (d *Daemon) shutdown() error {
-	log.Println("shutting down...")
+	slog.Info("shutting down")
 
-	// Cancel session tailers first (allows offset persistence before store closes).
+	// Cancel session tailers first, and wait for them to persist their
+	// checkpoints before the store closes.
 	if d.sessionCancel != nil {
 		d.sessionCancel()
 	}
+	d.sessionWG.Wait()
+	for _, p := range d.providers {
+		if c, ok := p.(io.Closer); ok {
+			_ = c.Close()
+		}
+	}
+	if d.tailNotifier != nil {
+		_ = d.tailNotifier.Close()
+	}
 
 	// Cancel git sync goroutine.
 	if d.gitCancel != nil {
@@ -245,14 +533,17 @@ func (d *Daemon) shutdown() error {
 	// Stop IPC server (stops accepting, drains connections).
 	if d.ipc != nil {
 		if err := d.ipc.Stop(); err != nil {
-			log.Printf("ipc stop: %v", err)
+			slog.Error("ipc stop failed", "err", err)
 		}
 	}
 
+	// Stop the git cat-file processes of PR refresh reports.
+	_ = d.git.Close()
+
 	// Close the store.
 	if d.store != nil {
 		if err := d.store.Close(); err != nil {
-			log.Printf("store close: %v", err)
+			slog.Error("store close failed", "err", err)
 		}
 	}
 
@@ -266,7 +557,7 @@ func (d *Daemon) shutdown() error {
 	d.running = false
 	d.mu.Unlock()
 
-	log.Println("daemon stopped")
+	slog.Info("daemon stopped")
 	return nil
 }
 
@@ -282,6 +573,71 @@ func (d *Daemon) Store() *store.Store {
 	return d.store
 }
 
+// SetTrust replaces the project trust settings deciding which projects
+// the daemon records file events, session events and commits for, e.g.
+// after "gapmap enable" or "gapmap disable" changed them.
+func (d *Daemon) SetTrust(t *config.Trust) {
+	d.trust.Store(t)
+	d.mu.Lock()
+	w := d.watcher
+	d.mu.Unlock()
+	if w != nil {
+		w.SetTrust(t)
+	}
+}
+
+// Trust returns the project trust settings in effect.
+func (d *Daemon) Trust() *config.Trust {
+	return d.trust.Load()
+}
+
+// SetAttributionListener registers fn to be called for every attribution
+// the daemon records, e.g. to notify IPC subscribers.
+func (d *Daemon) SetAttributionListener(fn AttributionListener) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.onAttribution = fn
+}
+
+// SetAPIReporter registers fn to generate the reports the daemon serves on
+// api_addr. It must be called before Start.
+func (d *Daemon) SetAPIReporter(fn api.Reporter) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.apiReporter = fn
+}
+
+// notifyAttribution passes a recorded attribution to the listener, if any.
+func (d *Daemon) notifyAttribution(rec store.AttributionRecord, workType string) {
+	d.mu.Lock()
+	fn := d.onAttribution
+	d.mu.Unlock()
+	if fn != nil {
+		fn(rec, workType)
+	}
+}
+
+// SyncGit requests an immediate git commit sync of the repository at root
+// instead of waiting for the next poll; "" means the synced repository.
+// Only the repository of the first watch path is synced, so any other is
+// rejected. Requests made while a sync is pending are coalesced.
+func (d *Daemon) SyncGit(root string) error {
+	d.mu.Lock()
+	repo := d.gitRepo
+	d.mu.Unlock()
+	if repo == nil {
+		return fmt.Errorf("no git repository is being synced")
+	}
+	if root != "" && store.CanonicalPath(root) != store.CanonicalPath(repo.Path()) {
+		return fmt.Errorf("git repository %s is not synced (the daemon syncs %s)", root, repo.Path())
+	}
+	select {
+	case d.gitSyncNow <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
 // Uptime returns how long the daemon has been running.
 func (d *Daemon) Uptime() time.Duration {
 	if d.startTime.IsZero() {
@@ -295,57 +651,215 @@ func (d *Daemon) Config() *config.Config {
 	return d.cfg
 }
 
+// providerKey is the key of a provider in d.providers: its name, and for a
+// provider reading one of several session directories, the directory.
+func providerKey(name, root string) string {
+	if root == "" {
+		return name
+	}
+	return name + ":" + root
+}
+
 // startSessionTailer starts a goroutine that tails a single session file,
 // parsing each line and storing events. It resumes from the last persisted
 // offset for the file.
 func (d *Daemon) startSessionTailer(ctx context.Context, sf sessionparser.SessionFile) {
-	// Restore offset from daemon_state for resume across daemon restarts.
+	// Restore the checkpoint from daemon_state for resume across daemon
+	// restarts. The checkpoint carries a fingerprint of the file it was
+	// taken from, so a session file rewritten in place is re-read in full.
 	offsetKey := "tailer_offset:" + sf.Path
-	offsetStr, _ := d.store.GetDaemonState(offsetKey)
-	var offset int64
-	if offsetStr != "" {
-		offset, _ = strconv.ParseInt(offsetStr, 10, 64)
+	checkpoint, _ := d.store.GetDaemonState(offsetKey)
+
+	provider, ok := d.providers[providerKey(sf.Provider, sf.Root)]
+	if !ok {
+		slog.Error("session has unknown provider", "session", sf.Path, "provider", sf.Provider)
+		return
+	}
+	tailer := sessionparser.NewTailerFromCheckpoint(sf.Path, sessionparser.ParseCheckpoint(checkpoint), 0)
+	if d.tailNotifier != nil {
+		tailer.SetNotifier(d.tailNotifier)
 	}
+	lines := make(chan sessionparser.Line, sessionLineBatch)
 
-	tailer := sessionparser.NewTailer(sf.Path, offset, 0)
-	lines := make(chan []byte, 100)
+	tracked := &tailedSession{file: sf, tailer: tailer}
+	d.mu.Lock()
+	if d.sessions == nil {
+		d.sessions = make(map[string]*tailedSession)
+	}
+	d.sessions[sf.Path] = tracked
+	d.mu.Unlock()
 
+	d.sessionWG.Add(2)
 	go func() {
-		finalOffset, err := tailer.Tail(ctx, lines)
-		if err != nil {
-			log.Printf("session tailer %s error: %v", sf.Path, err)
+		defer d.sessionWG.Done()
+		if _, err := tailer.TailLines(ctx, lines); err != nil {
+			slog.Error("session tailer stopped", "session", sf.Path, "err", err)
 		}
-		// Persist final offset for resume.
-		_ = d.store.SetDaemonState(offsetKey, strconv.FormatInt(finalOffset, 10))
 	}()
 
+	// The checkpoint persisted is that of the last line stored, never of
+	// lines still waiting in the channel, so a daemon killed at any point
+	// resumes with the first line it had not stored. It is written once
+	// checkpointLines lines or checkpointInterval have gone by since the
+	// last write, after the batch that crosses the mark, and on exit.
+	checkpointLines := d.cfg.TailerCheckpointLines
+	checkpointInterval, err := time.ParseDuration(d.cfg.TailerCheckpointInterval)
+	if err != nil || checkpointInterval < 0 {
+		checkpointInterval = defaultCheckpointInterval
+	}
+	usageParser, _ := provider.(sessionparser.UsageParser)
 	go func() {
+		defer d.sessionWG.Done()
+		var (
+			stored    sessionparser.Checkpoint // of the last line stored
+			pending   int                      // lines stored since the last write
+			lastWrite = time.Now()
+		)
+		persist := func() {
+			if pending == 0 {
+				return
+			}
+			if err := d.store.SetDaemonState(offsetKey, stored.String()); err != nil {
+				slog.Warn("session checkpoint failed", "session", sf.Path, "err", err)
+				return
+			}
+			pending = 0
+			lastWrite = time.Now()
+		}
+		defer persist()
+
+		batch := make([][]byte, 0, sessionLineBatch)
 		for {
 			select {
 			case <-ctx.Done():
 				return
 			case line := <-lines:
-				event, err := d.sessionParser.ParseLine(line)
-				if err != nil {
-					log.Printf("session parse error: %v", err)
-					continue
+				// Take the lines already waiting too, so a burst is
+				// stored in one transaction.
+				batch = append(batch[:0], line.Data)
+				next := line.Checkpoint
+			drain:
+				for len(batch) < sessionLineBatch {
+					select {
+					case line := <-lines:
+						batch = append(batch, line.Data)
+						next = line.Checkpoint
+					default:
+						break drain
+					}
 				}
-				if event == nil {
-					continue
+				if err := d.storeSessionLines(ctx, provider, usageParser, tracked, batch); err != nil {
+					// Stopped before the batch was stored: the checkpoint
+					// stays before it, so the next run reads it again.
+					slog.Warn("session lines left unstored", "session", sf.Path, "lines", len(batch), "err", err)
+					return
 				}
-				event.SessionID = sf.SessionID
-				if err := d.store.InsertSessionEvent(
-					event.SessionID, event.EventType, event.ToolName,
-					event.FilePath, event.ContentHash, event.Timestamp, event.RawJSON,
-					event.LinesChanged,
-				); err != nil {
-					log.Printf("session store error: %v", err)
+				stored = next
+				pending += len(batch)
+				if pending >= checkpointLines || time.Since(lastWrite) >= checkpointInterval {
+					persist()
 				}
 			}
 		}
 	}()
+}
+
+// defaultCheckpointInterval is the tailer_checkpoint_interval of a config
+// whose own does not parse.
+const defaultCheckpointInterval = 5 * time.Second
+
+// sessionLineBatch bounds how many session lines are stored per
+// transaction.
+const sessionLineBatch = 200
+
+// storeSessionLines parses a tailed session's lines and stores the events
+// and token usage they record. A failed store is retried with backoff
+// until it succeeds or ctx is done; the error is returned only then.
+func (d *Daemon) storeSessionLines(ctx context.Context, provider sessionparser.SessionProvider, usageParser sessionparser.UsageParser, tracked *tailedSession, lines [][]byte) error {
+	sf := tracked.file
+	var events []store.NewSessionEvent
+	for _, line := range lines {
+		event, err := provider.ParseLine(line)
+		if err != nil {
+			slog.Warn("session parse failed", "err", err)
+			continue
+		}
+		tracked.parsed(time.Now())
+		var filePath string
+		if event != nil {
+			filePath = event.FilePath
+		}
+		if !d.trust.Load().Allows(filePath) {
+			continue
+		}
+		if usageParser != nil {
+			d.storeTokenUsage(usageParser, sf.SessionID, line)
+		}
+		if event == nil {
+			continue
+		}
+		ec := store.SessionEventContext{
+			Model:         event.Model,
+			ClientVersion: event.ClientVersion,
+			PromptID:      event.PromptID,
+			Content: &store.SessionContent{
+				Added:   event.DiffContent,
+				Deleted: event.DeletedContent,
+			},
+		}
+		if d.cfg.RecordPrompts {
+			ec.Prompt = event.Prompt
+		}
+		if event.ToolName == "Write" || event.ToolName == "Edit" {
+			d.noteUntracked(event.FilePath)
+		}
+		events = append(events, store.NewSessionEvent{
+			SessionID:    sf.SessionID,
+			EventType:    event.EventType,
+			ToolName:     event.ToolName,
+			FilePath:     event.FilePath,
+			ContentHash:  event.ContentHash,
+			Timestamp:    event.Timestamp,
+			RawJSON:      event.RawJSON,
+			LinesChanged: event.LinesChanged,
+			Context:      ec,
+		})
+	}
+	if len(events) == 0 {
+		return nil
+	}
+	for backoff := time.Second; ; backoff = min(2*backoff, time.Minute) {
+		err := d.store.InsertSessionEvents(events)
+		if err == nil {
+			return nil
+		}
+		slog.Warn("session store failed", "session", sf.Path, "events", len(events), "err", err, "retry_in", backoff)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+	}
+}
 
-	// tailing session silently
+// storeTokenUsage records the token usage a session line reports, if any.
+func (d *Daemon) storeTokenUsage(up sessionparser.UsageParser, sessionID string, line []byte) {
+	u, err := up.ParseUsage(line)
+	if err != nil || u == nil {
+		return
+	}
+	if err := d.store.InsertTokenUsage(store.TokenUsage{
+		MessageID:           u.MessageID,
+		SessionID:           sessionID,
+		Model:               u.Model,
+		InputTokens:         u.InputTokens,
+		OutputTokens:        u.OutputTokens,
+		CacheCreationTokens: u.CacheCreationTokens,
+		CacheReadTokens:     u.CacheReadTokens,
+		Timestamp:           u.Timestamp,
+	}); err != nil {
+		slog.Error("token usage store failed", "err", err)
+	}
 }
 
 // startAttributionProcessor runs a background goroutine that periodically
@@ -353,103 +867,79 @@ func (d *Daemon) startSessionTailer(ctx context.Context, sf sessionparser.Sessio
 // attribution pipeline: correlation -> authorship classification ->
 // work-type classification -> store.
 func (d *Daemon) startAttributionProcessor(ctx context.Context) {
-	correlator := correlation.New(d.store)
-	classifier := authorship.NewClassifier()
-	wtClassifier := worktype.NewClassifier(d.store)
+	pipeline := newAttributor(d.store, d.cfg)
+	branches := newBranchCache()
+
+	// attribute runs one file event through the pipeline and reports
+	// whether an attribution was recorded for it.
+	attribute := func(fe store.FileEvent) bool {
+		_, records, wt, err := pipeline.decide(fe, branches.Branch(fe.ProjectPath))
+		if err != nil {
+			slog.Error("attribution: correlate failed", "file", fe.FilePath, "err", err)
+			return false
+		}
+
+		// Step 6: Persist the attributions, with their work type, and
+		// mark the file event processed, all in one transaction.
+		ids, err := d.store.RecordAttributions(records, string(wt))
+		if err != nil {
+			slog.Error("attribution: record failed", "file", fe.FilePath, "err", err)
+			return false
+		}
+		if len(ids) == 0 {
+			return false // attributed by an earlier run
+		}
+
+		// Step 7: Tell subscribers, and keep what a human changed, when
+		// asked to.
+		for i, id := range ids {
+			records[i].ID = id
+			d.notifyAttribution(records[i], string(wt))
+		}
+		if d.cfg.HumanSnapshots && !authorship.PredictsAI(records[0]) {
+			d.snapshotHumanEdit(ids[0], fe)
+		}
+
+		return true
+	}
 
 	go func() {
 		ticker := time.NewTicker(2 * time.Second)
 		defer ticker.Stop()
+		sizer := newBatchSizer()
 
 		for {
 			select {
 			case <-ctx.Done():
 				return
 			case <-ticker.C:
-				events, err := d.store.QueryUnprocessedFileEvents(100)
+			}
+
+			// Keep taking batches while they come back full, so a burst
+			// drains without waiting for the next tick.
+			for ctx.Err() == nil {
+				limit := sizer.size
+				started := time.Now()
+				events, err := d.store.QueryUnprocessedFileEvents(limit)
 				if err != nil {
-					log.Printf("attribution: query error: %v", err)
-					continue
-				}
-				if len(events) == 0 {
-					continue
+					slog.Error("attribution: query failed", "err", err)
+					break
 				}
 
-				for _, fe := range events {
-					// Step 1: Correlate file event with session events.
-					result, err := correlator.CorrelateFileEvent(fe)
-					if err != nil {
-						log.Printf("attribution: correlate error for %s: %v", fe.FilePath, err)
-						continue
-					}
-
-					// Step 2: Classify authorship level (with history for mixed attributions).
-					var prior *authorship.Attribution
-					if priorRecord, err := d.store.QueryLatestAttributionByFile(fe.FilePath); err == nil && priorRecord != nil {
-						prior = &authorship.Attribution{
-							FirstAuthor: priorRecord.FirstAuthor,
-							Level:       authorship.AuthorshipLevel(priorRecord.AuthorshipLevel),
-						}
-					}
-					attr := classifier.ClassifyWithHistory(*result, prior)
-
-					// Step 3: Extract diff content and lines_changed from matched session event.
-					var diffContent string
-					var linesChanged int
-					if result.MatchedSession != nil {
-						// Get lines_changed from the matched session event.
-						if seDetails, err := d.store.QuerySessionEventByID(result.MatchedSession.ID); err == nil {
-							linesChanged = seDetails.LinesChanged
-						}
-						// Extract diff content from raw JSON for work type classification.
-						if rawJSON, err := d.store.QuerySessionEventRawJSON(result.MatchedSession.ID); err == nil {
-							diffContent = sessionparser.ExtractDiffContent(rawJSON)
-						}
-					}
-					if linesChanged == 0 && diffContent != "" {
-						// Compute from content (handles pre-v5 session events).
-						linesChanged = strings.Count(diffContent, "\n")
-						if !strings.HasSuffix(diffContent, "\n") {
-							linesChanged++
-						}
-					}
-					if linesChanged == 0 {
-						linesChanged = 1 // conservative default when no content available
-					}
-
-					// Step 4: Classify work type with actual content.
-					wt := wtClassifier.ClassifyFile(attr.FilePath, diffContent, "")
-
-					// Step 5: Build store record and persist.
-					record := store.AttributionRecord{
-						FilePath:            attr.FilePath,
-						ProjectPath:         attr.ProjectPath,
-						FileEventID:         attr.FileEventID,
-						SessionEventID:      attr.SessionEventID,
-						AuthorshipLevel:     string(attr.Level),
-						Confidence:          attr.Confidence,
-						Uncertain:           attr.Uncertain,
-						FirstAuthor:         attr.FirstAuthor,
-						CorrelationWindowMs: attr.CorrelationWindowMs,
-						Timestamp:           attr.Timestamp,
-						LinesChanged:        linesChanged,
-					}
-
-					id, err := d.store.InsertAttribution(record)
-					if err != nil {
-						log.Printf("attribution: insert error for %s: %v", fe.FilePath, err)
-						continue
-					}
-
-					// Step 6: Set work type on the attribution record.
-					if id > 0 {
-						if err := d.store.UpdateAttributionWorkType(id, string(wt)); err != nil {
-							log.Printf("attribution: update work type error for %s: %v", fe.FilePath, err)
-						}
+				// The query returns the newest events first; attribute a
+				// batch oldest first so each event sees the attributions
+				// that precede it as history.
+				attributed := 0
+				for i := len(events) - 1; i >= 0 && ctx.Err() == nil; i-- {
+					if attribute(events[i]) {
+						attributed++
 					}
 				}
+				sizer.observe(len(events), time.Since(started))
 
-				// processed silently
+				if len(events) < limit || attributed == 0 {
+					break
+				}
 			}
 		}
 	}()
@@ -1,12 +1,19 @@
 package daemon
 
 import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
 	"path/filepath"
 	"testing"
 	"time"
 
 	"github.com/anthropic/gap-map/internal/authorship"
+	"github.com/anthropic/gap-map/internal/config"
 	"github.com/anthropic/gap-map/internal/correlation"
+	"github.com/anthropic/gap-map/internal/gitint"
+	"github.com/anthropic/gap-map/internal/sessionparser"
 	"github.com/anthropic/gap-map/internal/store"
 	"github.com/anthropic/gap-map/internal/worktype"
 )
@@ -256,3 +263,306 @@ func TestAttributionPipelineNoSessionMatch(t *testing.T) {
 		t.Errorf("stored Confidence = %f, want 1.0", a.Confidence)
 	}
 }
+
+// TestHealth checks the per-watch-path, per-session and git sync state
+// reported for "gapmap status".
+func TestHealth(t *testing.T) {
+	dir := t.TempDir()
+	s, err := store.New(filepath.Join(dir, "test.db"))
+	if err != nil {
+		t.Fatalf("store.New: %v", err)
+	}
+	defer s.Close()
+
+	project := filepath.Join(dir, "api")
+	now := time.Now().UTC()
+	for i := 0; i < 2; i++ {
+		if err := s.InsertFileEvent(project, filepath.Join(project, "main.go"), "write", now.Add(time.Duration(i)*time.Second)); err != nil {
+			t.Fatalf("InsertFileEvent: %v", err)
+		}
+	}
+
+	d := New(&config.Config{WatchPaths: []string{project}}, nil)
+	d.store = s
+
+	// One session with unread data, one fully read and quiet.
+	behind := filepath.Join(dir, "behind.jsonl")
+	idle := filepath.Join(dir, "idle.jsonl")
+	for _, p := range []string{behind, idle} {
+		if err := os.WriteFile(p, []byte("{}\n{}\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	d.sessionRoots = []string{dir, filepath.Join(dir, "missing")}
+	d.sessions = map[string]*tailedSession{
+		behind: {file: sessionparser.SessionFile{Path: behind, SessionID: "behind", Root: dir}, tailer: sessionparser.NewTailer(behind, 3, 0)},
+		idle:   {file: sessionparser.SessionFile{Path: idle, SessionID: "idle", Root: dir}, tailer: sessionparser.NewTailer(idle, 6, 0)},
+	}
+	d.sessions[behind].parsed(now)
+	d.recordGitSync(project, errors.New("exit status 128"))
+
+	h := d.Health()
+	if len(h.WatchPaths) != 1 || h.WatchPaths[0].Events != 2 || !h.WatchPaths[0].LastEventAt.Equal(now.Add(time.Second)) {
+		t.Errorf("WatchPaths = %+v", h.WatchPaths)
+	}
+	if h.AttributionBacklog != 2 {
+		t.Errorf("AttributionBacklog = %d, want 2", h.AttributionBacklog)
+	}
+	if len(h.Sessions) != 1 || h.Sessions[0].SessionID != "behind" || h.Sessions[0].LagBytes != 3 || h.Sessions[0].LinesParsed != 1 {
+		t.Errorf("Sessions = %+v", h.Sessions)
+	}
+	if h.IdleSessions != 1 {
+		t.Errorf("IdleSessions = %d, want 1", h.IdleSessions)
+	}
+	if len(h.SessionRoots) != 2 || !h.SessionRoots[0].Exists || h.SessionRoots[0].Sessions != 2 || h.SessionRoots[1].Exists {
+		t.Errorf("SessionRoots = %+v", h.SessionRoots)
+	}
+	if len(h.GitRepos) != 1 || h.GitRepos[0].LastError != "exit status 128" || h.GitRepos[0].LastSyncAt.IsZero() {
+		t.Errorf("GitRepos = %+v", h.GitRepos)
+	}
+	if h.WatchPaths[0].Disabled || h.Trust == nil || h.Trust.Allowlist() {
+		t.Errorf("watch path disabled = %v, trust = %+v; want everything recorded", h.WatchPaths[0].Disabled, h.Trust)
+	}
+
+	d.SetTrust((&config.Config{DisabledProjects: []string{project}}).Trust())
+	if h := d.Health(); !h.WatchPaths[0].Disabled {
+		t.Error("watch path not reported disabled after SetTrust")
+	}
+	if _, err := d.RecordManualAttribution(filepath.Join(project, "main.go"), "mostly_ai", "core_logic", 3); err == nil {
+		t.Error("RecordManualAttribution in a disabled project: want error")
+	}
+	if _, err := d.MarkLines(filepath.Join(project, "main.go"), 1, 2, "ai", "chatgpt-web"); err == nil {
+		t.Error("MarkLines in a disabled project: want error")
+	}
+}
+
+// TestSyncGit checks that a hook's sync request is only accepted for the
+// repository the daemon syncs.
+func TestSyncGit(t *testing.T) {
+	dir := t.TempDir()
+	repoDir := filepath.Join(dir, "api")
+	if out, err := exec.Command("git", "init", "-q", repoDir).CombinedOutput(); err != nil {
+		t.Fatalf("git init: %v: %s", err, out)
+	}
+	d := New(&config.Config{WatchPaths: []string{repoDir}}, nil)
+	if err := d.SyncGit(repoDir); err == nil {
+		t.Error("SyncGit with no synced repository: want error")
+	}
+
+	repo, err := gitint.Open(repoDir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.gitRepo = repo
+	if err := d.SyncGit(repoDir); err != nil {
+		t.Errorf("SyncGit(%s) = %v", repoDir, err)
+	}
+	if len(d.gitSyncNow) != 1 {
+		t.Error("sync of the synced repository was not requested")
+	}
+	if err := d.SyncGit(filepath.Join(dir, "web")); err == nil {
+		t.Error("SyncGit of another repository: want error")
+	}
+}
+
+// TestBatchSizer checks that the attribution batch grows while full batches
+// finish quickly and shrinks when a batch runs long.
+func TestBatchSizer(t *testing.T) {
+	b := newBatchSizer()
+	if b.size != defaultAttributionBatch {
+		t.Fatalf("initial size = %d, want %d", b.size, defaultAttributionBatch)
+	}
+
+	b.observe(b.size, 10*time.Millisecond)
+	if b.size != 2*defaultAttributionBatch {
+		t.Errorf("size after a fast full batch = %d, want %d", b.size, 2*defaultAttributionBatch)
+	}
+	b.observe(5, 10*time.Millisecond)
+	if b.size != 2*defaultAttributionBatch {
+		t.Errorf("size after a partial batch = %d, want unchanged", b.size)
+	}
+	for i := 0; i < 10; i++ {
+		b.observe(b.size, time.Millisecond)
+	}
+	if b.size != maxAttributionBatch {
+		t.Errorf("size after many fast batches = %d, want %d", b.size, maxAttributionBatch)
+	}
+	for i := 0; i < 10; i++ {
+		b.observe(b.size, 3*time.Second)
+	}
+	if b.size != minAttributionBatch {
+		t.Errorf("size after many slow batches = %d, want %d", b.size, minAttributionBatch)
+	}
+}
+
+// TestSessionCheckpoints checks that a session tailer persists the read
+// position of the lines it has stored while it runs, not only on exit, and
+// flushes what is left when it stops.
+func TestSessionCheckpoints(t *testing.T) {
+	dir := t.TempDir()
+	s, err := store.New(filepath.Join(dir, "test.db"))
+	if err != nil {
+		t.Fatalf("store.New: %v", err)
+	}
+	defer s.Close()
+
+	d := New(&config.Config{TailerCheckpointLines: 2, TailerCheckpointInterval: "1h"}, nil)
+	d.store = s
+	p := sessionparser.NewClaudeCodeParser(dir, 24*time.Hour, "")
+	d.providers = map[string]sessionparser.SessionProvider{providerKey(p.Name(), dir): p}
+
+	path := filepath.Join(dir, "sess.jsonl")
+	content := "{\"type\":\"user\"}\n{\"type\":\"user\"}\n{\"type\":\"user\"}\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	key := "tailer_offset:" + path
+	offset := func() int64 {
+		state, _ := s.GetDaemonState(key)
+		return sessionparser.ParseCheckpoint(state).Offset
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	d.startSessionTailer(ctx, sessionparser.SessionFile{Path: path, SessionID: "sess", Provider: p.Name(), Root: dir})
+	deadline := time.Now().Add(2 * time.Second)
+	for offset() != int64(len(content)) {
+		if time.Now().After(deadline) {
+			t.Fatalf("checkpoint offset = %d while running, want %d", offset(), len(content))
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	// One more line is below tailer_checkpoint_lines: it is persisted
+	// only when the tailer stops.
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString("{\"type\":\"user\"}\n")
+	f.Close()
+	tracked := d.sessions[path]
+	for tracked.linesParsed.Load() < 4 {
+		if time.Now().After(deadline.Add(5 * time.Second)) {
+			t.Fatal("appended line not read")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if got := offset(); got != int64(len(content)) {
+		t.Errorf("checkpoint offset = %d after one more line, want %d until the next write", got, len(content))
+	}
+	cancel()
+	d.sessionWG.Wait()
+	if got, want := offset(), int64(len(content))+16; got != want {
+		t.Errorf("checkpoint offset after stop = %d, want %d", got, want)
+	}
+}
+
+// TestSessionCheckpointAfterStoreFailure checks that lines whose events
+// could not be stored do not advance the checkpoint, so the next run reads
+// them again.
+func TestSessionCheckpointAfterStoreFailure(t *testing.T) {
+	dir := t.TempDir()
+	s, err := store.New(filepath.Join(dir, "test.db"))
+	if err != nil {
+		t.Fatalf("store.New: %v", err)
+	}
+	defer s.Close()
+	if _, err := s.DB().Exec(`CREATE TRIGGER fail_insert BEFORE INSERT ON session_events BEGIN SELECT RAISE(FAIL, 'disk I/O error'); END`); err != nil {
+		t.Fatal(err)
+	}
+
+	d := New(&config.Config{TailerCheckpointLines: 1, TailerCheckpointInterval: "1h"}, nil)
+	d.store = s
+	p := sessionparser.NewClaudeCodeParser(dir, 24*time.Hour, "")
+	d.providers = map[string]sessionparser.SessionProvider{providerKey(p.Name(), dir): p}
+
+	path := filepath.Join(dir, "sess.jsonl")
+	content := `{"type":"assistant","message":{"content":[{"type":"tool_use","name":"Write","input":{"file_path":"/proj/main.go","content":"package main\n"}}]}}` + "\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	sf := sessionparser.SessionFile{Path: path, SessionID: "sess", Provider: p.Name(), Root: dir}
+	events := func() int {
+		var n int
+		s.DB().QueryRow(`SELECT COUNT(*) FROM session_events`).Scan(&n)
+		return n
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	d.startSessionTailer(ctx, sf)
+	tracked := d.sessions[path]
+	deadline := time.Now().Add(2 * time.Second)
+	for tracked.linesParsed.Load() < 1 {
+		if time.Now().After(deadline) {
+			t.Fatal("line not read")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	cancel()
+	d.sessionWG.Wait()
+	if state, _ := s.GetDaemonState("tailer_offset:" + path); state != "" {
+		t.Fatalf("checkpoint %q written for a line that was not stored", state)
+	}
+
+	// Once the store recovers, the next run stores the line.
+	if _, err := s.DB().Exec(`DROP TRIGGER fail_insert`); err != nil {
+		t.Fatal(err)
+	}
+	delete(d.sessions, path)
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	d.startSessionTailer(ctx, sf)
+	for events() != 1 {
+		if time.Now().After(deadline.Add(3 * time.Second)) {
+			t.Fatalf("session events = %d after recovery, want 1", events())
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+// TestDryRunSkipsPRRefresh checks that a daemon on a dry-run config does
+// not start PR comment refresh, which would overwrite the real comments.
+func TestDryRunSkipsPRRefresh(t *testing.T) {
+	dir := t.TempDir()
+	repoDir := filepath.Join(dir, "api")
+	for _, args := range [][]string{
+		{"init", "-q", repoDir},
+		{"-C", repoDir, "remote", "add", "origin", "https://github.com/acme/api.git"},
+	} {
+		if out, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v: %s", args, err, out)
+		}
+	}
+	t.Setenv("GITHUB_TOKEN", "test-token")
+
+	s, err := store.New(filepath.Join(dir, "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	cfg := config.Default()
+	cfg.WatchPaths = []string{repoDir}
+	cfg.PRRefresh = true
+
+	// A canceled context stops the refresher before it reaches GitHub.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	start := func(cfg *config.Config) bool {
+		d := New(cfg, nil)
+		d.store = s
+		d.ctx = ctx
+		return d.startPRRefresh()
+	}
+
+	if !start(cfg) {
+		t.Fatal("PR refresh not started with pr_refresh set")
+	}
+	cfg.PRWebhookAddr = "127.0.0.1:0"
+	cfg.PRWebhookSecret = "secret"
+	if start(cfg.DryRun(filepath.Join(dir, "dry"))) {
+		t.Error("dry-run daemon started PR refresh")
+	}
+}
@@ -0,0 +1,170 @@
+package daemon
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/anthropic/gap-map/internal/config"
+	"github.com/anthropic/gap-map/internal/sessionparser"
+	"github.com/anthropic/gap-map/internal/watcher"
+)
+
+// activeSessionWindow is how recently a session must have produced a line,
+// if it is not behind, to be listed as active in Health.
+const activeSessionWindow = 30 * time.Minute
+
+// Health describes what the daemon is doing, for "gapmap status".
+type Health struct {
+	WatchPaths         []WatchPathHealth
+	Watcher            watcher.Stats // zero when no paths are watched
+	SessionRoots       []SessionRootHealth
+	Sessions           []SessionHealth // active sessions, most recent first
+	IdleSessions       int             // tailed sessions not listed in Sessions
+	GitRepos           []GitHealth
+	AttributionBacklog int64           // file events not yet attributed
+	Crashes            CrashState      // restarts by the supervisor, if any
+	LastMaintenanceAt  time.Time       // zero if the database was never maintained
+	UntrackedRepos     []UntrackedRepo // repositories AI keeps editing outside the watch paths
+	Trust              *config.Trust   // which projects are recorded
+}
+
+// WatchPathHealth summarizes the file events recorded under a watch path.
+type WatchPathHealth struct {
+	Path        string
+	Events      int64
+	LastEventAt time.Time // zero if no events
+	Disabled    bool      // gap-map is not enabled for the path
+}
+
+// SessionRootHealth describes a directory Claude Code sessions are read
+// from.
+type SessionRootHealth struct {
+	Path     string
+	Exists   bool
+	Sessions int // session files tailed from it
+}
+
+// SessionHealth describes one tailed session file.
+type SessionHealth struct {
+	Path        string
+	SessionID   string
+	LagBytes    int64 // bytes written to the file but not read yet
+	LinesParsed int64 // since the daemon started
+	LastLineAt  time.Time
+}
+
+// GitHealth describes the commit sync of one repository.
+type GitHealth struct {
+	Path       string
+	LastSyncAt time.Time // zero before the first sync finishes
+	LastError  string    // from the most recent sync, empty if it succeeded
+}
+
+// tailedSession tracks a session tailer's progress for Health.
+type tailedSession struct {
+	file        sessionparser.SessionFile
+	tailer      *sessionparser.Tailer
+	linesParsed atomic.Int64
+	lastLine    atomic.Int64 // unix nanoseconds
+}
+
+func (t *tailedSession) parsed(now time.Time) {
+	t.linesParsed.Add(1)
+	t.lastLine.Store(now.UnixNano())
+}
+
+// recordGitSync stores the outcome of a git sync of path for Health.
+func (d *Daemon) recordGitSync(path string, err error) {
+	state := GitHealth{Path: path, LastSyncAt: time.Now()}
+	if err != nil {
+		state.LastError = err.Error()
+	}
+	d.mu.Lock()
+	d.gitHealth = &state
+	d.mu.Unlock()
+}
+
+// Health reports per-watch-path event counts, the watcher's directories
+// and dropped events, the session directories read, the state of each
+// active session tailer and of git sync, the attribution backlog, the crashes
+// the supervisor recovered from, the last database maintenance, which
+// projects are recorded, and the untracked repositories AI keeps editing.
+func (d *Daemon) Health() Health {
+	var h Health
+
+	d.mu.Lock()
+	sessions := make([]*tailedSession, 0, len(d.sessions))
+	for _, ts := range d.sessions {
+		sessions = append(sessions, ts)
+	}
+	roots := d.sessionRoots
+	if d.gitHealth != nil {
+		h.GitRepos = append(h.GitRepos, *d.gitHealth)
+	} else if d.gitRepo != nil {
+		h.GitRepos = append(h.GitRepos, GitHealth{Path: d.cfg.WatchPaths[0]})
+	}
+	d.mu.Unlock()
+
+	if d.store != nil {
+		stats, _ := d.store.QueryFileEventStatsByProject()
+		for _, p := range d.cfg.WatchPaths {
+			wp := WatchPathHealth{Path: p, Disabled: !d.trust.Load().Allows(p)}
+			if abs, err := filepath.Abs(p); err == nil {
+				wp.Events = stats[abs].Events
+				wp.LastEventAt = stats[abs].LastEventAt
+			}
+			h.WatchPaths = append(h.WatchPaths, wp)
+		}
+		h.AttributionBacklog, _ = d.store.UnprocessedFileEventsCount()
+		h.LastMaintenanceAt, _ = d.store.LastMaintenance()
+	}
+
+	if d.watcher != nil {
+		h.Watcher = d.watcher.Stats()
+	}
+	h.Trust = d.trust.Load()
+	h.UntrackedRepos = d.untrackedRepos()
+
+	h.Crashes, _ = LoadCrashState(CrashStatePath(d.cfg.DataDir))
+
+	for _, root := range roots {
+		rh := SessionRootHealth{Path: root}
+		if info, err := os.Stat(root); err == nil && info.IsDir() {
+			rh.Exists = true
+		}
+		for _, ts := range sessions {
+			if ts.file.Root == root {
+				rh.Sessions++
+			}
+		}
+		h.SessionRoots = append(h.SessionRoots, rh)
+	}
+
+	now := time.Now()
+	for _, ts := range sessions {
+		sh := SessionHealth{
+			Path:        ts.file.Path,
+			SessionID:   ts.file.SessionID,
+			LinesParsed: ts.linesParsed.Load(),
+		}
+		if last := ts.lastLine.Load(); last > 0 {
+			sh.LastLineAt = time.Unix(0, last)
+		}
+		if info, err := os.Stat(ts.file.Path); err == nil {
+			sh.LagBytes = max(info.Size()-ts.tailer.Offset(), 0)
+		}
+		if sh.LagBytes == 0 && now.Sub(sh.LastLineAt) > activeSessionWindow {
+			h.IdleSessions++
+			continue
+		}
+		h.Sessions = append(h.Sessions, sh)
+	}
+	sort.Slice(h.Sessions, func(i, j int) bool {
+		return h.Sessions[i].LastLineAt.After(h.Sessions[j].LastLineAt)
+	})
+
+	return h
+}
@@ -0,0 +1,86 @@
+package daemon
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	// maintenanceCheck is how often the daemon checks whether database
+	// maintenance is due.
+	maintenanceCheck = 5 * time.Minute
+	// maintenanceWindow is how long after maintenance_time a run may
+	// still start that day.
+	maintenanceWindow = 2 * time.Hour
+	// maintenanceOverdue is how long a database may go unmaintained
+	// before it is maintained at any time of day, for machines that are
+	// asleep at maintenance_time.
+	maintenanceOverdue = 7 * 24 * time.Hour
+)
+
+// runMaintenanceJob maintains the database once a day from hour:minute
+// local time until ctx is done.
+func (d *Daemon) runMaintenanceJob(ctx context.Context, hour, minute int) {
+	ticker := time.NewTicker(maintenanceCheck)
+	defer ticker.Stop()
+	for {
+		last, err := d.store.LastMaintenance()
+		if err != nil {
+			slog.Warn("maintenance: read last run failed", "err", err)
+		}
+		if last.IsZero() {
+			last = d.startTime
+		}
+		if now := time.Now(); maintenanceDue(now, last, hour, minute) {
+			d.maintain(ctx)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// maintenanceDue reports whether maintenance last run at last is due
+// again at now: it has not run since the latest hour:minute and either
+// that was less than maintenanceWindow ago or the last run is overdue.
+func maintenanceDue(now, last time.Time, hour, minute int) bool {
+	slot := time.Date(now.Year(), now.Month(), now.Day(), hour, minute, 0, 0, now.Location())
+	if slot.After(now) {
+		slot = slot.AddDate(0, 0, -1)
+	}
+	if !last.Before(slot) {
+		return false
+	}
+	return now.Sub(slot) < maintenanceWindow || now.Sub(last) > maintenanceOverdue
+}
+
+// maintain runs database maintenance, pruning the blame data of files no
+// longer under any watch path.
+func (d *Daemon) maintain(ctx context.Context) {
+	res, err := d.store.Maintain(ctx, d.fileExists)
+	if err != nil {
+		slog.Error("maintenance failed", "err", err)
+		return
+	}
+	slog.Info("database maintained", "blame_files_pruned", res.BlameFilesPruned, "pages_freed", res.PagesFreed, "took", res.Took)
+}
+
+// fileExists reports whether a stored file path exists: as is if it is
+// absolute, under some watch path if it is relative to a repository.
+func (d *Daemon) fileExists(path string) bool {
+	if filepath.IsAbs(path) {
+		_, err := os.Stat(path)
+		return err == nil || !os.IsNotExist(err)
+	}
+	for _, root := range d.cfg.WatchPaths {
+		if _, err := os.Stat(filepath.Join(root, path)); err == nil || !os.IsNotExist(err) {
+			return true
+		}
+	}
+	return false
+}
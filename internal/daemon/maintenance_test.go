@@ -0,0 +1,29 @@
+package daemon
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMaintenanceDue(t *testing.T) {
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2026, 3, day, hour, minute, 0, 0, time.UTC)
+	}
+	tests := []struct {
+		name      string
+		now, last time.Time
+		want      bool
+	}{
+		{"in window, last run yesterday", at(10, 3, 5), at(9, 3, 5), true},
+		{"in window, already ran", at(10, 3, 30), at(10, 3, 5), false},
+		{"before the time", at(10, 2, 55), at(9, 3, 5), false},
+		{"window passed", at(10, 9, 0), at(9, 3, 5), false},
+		{"window passed, overdue", at(10, 9, 0), at(2, 3, 5), true},
+		{"just after midnight, window passed", at(10, 0, 30), at(8, 3, 5), false},
+	}
+	for _, tt := range tests {
+		if got := maintenanceDue(tt.now, tt.last, 3, 0); got != tt.want {
+			t.Errorf("%s: maintenanceDue(%v, %v) = %v, want %v", tt.name, tt.now, tt.last, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package daemon
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/anthropic/gap-map/internal/authorship"
+	"github.com/anthropic/gap-map/internal/config"
+	"github.com/anthropic/gap-map/internal/ingest"
+	"github.com/anthropic/gap-map/internal/store"
+	"github.com/anthropic/gap-map/internal/watcher"
+	"github.com/anthropic/gap-map/internal/worktype"
+)
+
+// RecordManualAttribution stores an authorship level the user asserts for
+// filePath, e.g. from an editor plugin. It is recorded like a correlated
+// attribution with full confidence and no file or session event. lines
+// defaults to 1; an empty workType is classified from the path.
+func (d *Daemon) RecordManualAttribution(filePath, level, workType string, lines int) (int64, error) {
+	if d.store == nil {
+		return 0, fmt.Errorf("store not open")
+	}
+
+	switch authorship.AuthorshipLevel(level) {
+	case authorship.MostlyAI, authorship.Mixed, authorship.MostlyHuman, authorship.AISuggestedHumanWritten:
+	default:
+		return 0, fmt.Errorf("unknown authorship level %q", level)
+	}
+
+	absPath, err := filepath.Abs(filePath)
+	if err != nil {
+		return 0, fmt.Errorf("resolve %s: %w", filePath, err)
+	}
+
+	wt := worktype.WorkType(workType)
+	if wt == "" {
+		c := worktype.NewClassifier(d.store)
+		if repo, err := config.FindRepoSettings(absPath); err == nil && repo != nil {
+			c.SetPathRules(func(string) (worktype.WorkType, bool) { return repo.WorkType(absPath) })
+		}
+		wt = c.ClassifyFile(filePath, "", "")
+	} else if _, ok := worktype.WorkTypeWeights[wt]; !ok {
+		return 0, fmt.Errorf("unknown work type %q", workType)
+	}
+	if !d.trust.Load().Allows(absPath) {
+		return 0, fmt.Errorf("%s is in a project gap-map is not enabled for", absPath)
+	}
+
+	firstAuthor := "ai"
+	if level == string(authorship.MostlyHuman) {
+		firstAuthor = "human"
+	}
+
+	record := store.AttributionRecord{
+		FilePath:        absPath,
+		ProjectPath:     watcher.ProjectRoot(d.cfg.WatchPaths, absPath),
+		AuthorshipLevel: level,
+		Confidence:      1.0,
+		FirstAuthor:     firstAuthor,
+		Timestamp:       time.Now(),
+		LinesChanged:    max(lines, 1),
+		Kind:            store.AttributionAddition,
+		MatchType:       store.MatchManual,
+	}
+	ids, err := d.store.RecordAttributions([]store.AttributionRecord{record}, string(wt))
+	if err != nil {
+		return 0, fmt.Errorf("insert manual attribution for %s: %w", absPath, err)
+	}
+	id := ids[0]
+
+	record.ID = id
+	d.notifyAttribution(record, string(wt))
+	return id, nil
+}
+
+// MarkLines records lines start to end (1-based, inclusive) of filePath,
+// as they are on disk, as written by author ("ai" or "human") from source,
+// e.g. code pasted from a web chat. The file and session events are
+// attributed like a watched edit; see ingest.Mark.
+func (d *Daemon) MarkLines(filePath string, start, end int, author, source string) (ingest.Result, error) {
+	if d.store == nil {
+		return ingest.Result{}, fmt.Errorf("store not open")
+	}
+	absPath, err := filepath.Abs(filePath)
+	if err != nil {
+		return ingest.Result{}, fmt.Errorf("resolve %s: %w", filePath, err)
+	}
+	if !d.trust.Load().Allows(absPath) {
+		return ingest.Result{}, fmt.Errorf("%s is in a project gap-map is not enabled for", absPath)
+	}
+	project := watcher.ProjectRoot(d.cfg.WatchPaths, absPath)
+	if project == absPath {
+		return ingest.Result{}, fmt.Errorf("%s is not under a watch path", absPath)
+	}
+	res, err := ingest.Mark(d.store, project, absPath, start, end, author, source, time.Now())
+	if err != nil {
+		return res, fmt.Errorf("mark %s: %w", absPath, err)
+	}
+	return res, nil
+}
+
+// maxTypingBurst bounds a single reported typing burst. Plugins report
+// bursts as they happen; a longer span would let one heartbeat mark every
+// AI edit in it as human.
+const maxTypingBurst = 10 * time.Minute
+
+// RecordTypingBurst stores a burst of human typing in filePath reported by
+// an editor plugin, for correlation to weigh against nearby AI edits.
+func (d *Daemon) RecordTypingBurst(filePath string, start, end time.Time, charsTyped int, source string) (int64, error) {
+	if d.store == nil {
+		return 0, fmt.Errorf("store not open")
+	}
+	if end.Before(start) {
+		return 0, fmt.Errorf("typing burst ends before it starts")
+	}
+	if end.Sub(start) > maxTypingBurst {
+		return 0, fmt.Errorf("typing burst longer than %s", maxTypingBurst)
+	}
+	if charsTyped < 0 {
+		return 0, fmt.Errorf("chars typed must not be negative")
+	}
+
+	absPath, err := filepath.Abs(filePath)
+	if err != nil {
+		return 0, fmt.Errorf("resolve %s: %w", filePath, err)
+	}
+	if !d.trust.Load().Allows(absPath) {
+		return 0, fmt.Errorf("%s is in a project gap-map is not enabled for", absPath)
+	}
+	id, err := d.store.InsertTypingBurst(store.TypingBurst{
+		FilePath:   absPath,
+		StartedAt:  start,
+		EndedAt:    end,
+		CharsTyped: charsTyped,
+		Source:     source,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("insert typing burst for %s: %w", absPath, err)
+	}
+	return id, nil
+}
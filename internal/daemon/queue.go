@@ -0,0 +1,32 @@
+package daemon
+
+import "time"
+
+// Attribution batch size bounds. The processor grows its batch while
+// batches come back full and finish quickly, and shrinks it when a batch
+// runs long, so a flood of events drains fast without stalling the store.
+const (
+	minAttributionBatch     = 25
+	maxAttributionBatch     = 1000
+	defaultAttributionBatch = 100
+	attributionBatchTarget  = time.Second // time a batch should take at most
+)
+
+// batchSizer picks the size of the next attribution batch.
+type batchSizer struct {
+	size int
+}
+
+func newBatchSizer() *batchSizer {
+	return &batchSizer{size: defaultAttributionBatch}
+}
+
+// observe adjusts the size after a batch of n events took took.
+func (b *batchSizer) observe(n int, took time.Duration) {
+	switch {
+	case took > attributionBatchTarget:
+		b.size = max(b.size/2, minAttributionBatch)
+	case n >= b.size && took < attributionBatchTarget/2:
+		b.size = min(b.size*2, maxAttributionBatch)
+	}
+}
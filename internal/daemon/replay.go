@@ -0,0 +1,188 @@
+package daemon
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/anthropic/gap-map/internal/config"
+	"github.com/anthropic/gap-map/internal/sessionparser"
+	"github.com/anthropic/gap-map/internal/store"
+)
+
+// DefaultSaveDelay is how long after an AI edit Replay assumes the
+// watcher saw the file change.
+const DefaultSaveDelay = 500 * time.Millisecond
+
+// ReplayOptions configure Replay.
+type ReplayOptions struct {
+	ProjectPath string        // only edits to files under it are attributed
+	SessionID   string        // session the events are stored under
+	Clock       time.Time     // the time of lines that carry none
+	SaveDelay   time.Duration // file event delay after each edit; DefaultSaveDelay if 0
+}
+
+// ReplayStep is one session line that produced an event, with the file
+// event a Write or Edit under the project implies and what the
+// attribution pipeline decided for it.
+type ReplayStep struct {
+	Line         int                       `json:"line"`
+	ToolName     string                    `json:"tool_name"`
+	FilePath     string                    `json:"file_path,omitempty"`
+	Timestamp    time.Time                 `json:"timestamp"`
+	LinesChanged int                       `json:"lines_changed"`
+	Model        string                    `json:"model,omitempty"`
+	FileEventAt  time.Time                 `json:"file_event_at,omitzero"`
+	MatchType    string                    `json:"match_type,omitempty"`
+	MatchedLine  int                       `json:"matched_line,omitempty"` // session line of the matched event
+	TimeDeltaMs  int64                     `json:"time_delta_ms,omitempty"`
+	WorkType     string                    `json:"work_type,omitempty"`
+	Attributions []store.AttributionRecord `json:"attributions,omitempty"`
+
+	contentHash string
+}
+
+// Replay runs the session file read from r through the daemon's parser
+// and attribution pipeline against a throwaway database, and returns each
+// step. Every Write or Edit under opts.ProjectPath is assumed to have
+// been seen by the watcher opts.SaveDelay later; file events are
+// attributed in time order once the whole session is stored, as the
+// daemon does once its sessions have caught up. Clipboard and typing
+// evidence are not replayed, and the real database is left alone.
+func Replay(cfg *config.Config, r io.Reader, opts ReplayOptions) ([]ReplayStep, error) {
+	tmp, err := os.MkdirTemp("", "gapmap-replay-")
+	if err != nil {
+		return nil, fmt.Errorf("create replay directory: %w", err)
+	}
+	defer os.RemoveAll(tmp)
+	s, err := store.New(filepath.Join(tmp, "gapmap.db"))
+	if err != nil {
+		return nil, fmt.Errorf("open replay store: %w", err)
+	}
+	defer s.Close()
+
+	if opts.SaveDelay == 0 {
+		opts.SaveDelay = DefaultSaveDelay
+	}
+	project := filepath.Clean(opts.ProjectPath)
+	parser := sessionparser.NewClaudeCodeParser(tmp, 0, cfg.DiffAlgorithm)
+	parser.SetClock(func() time.Time { return opts.Clock })
+
+	// Parse and store the whole session first.
+	var steps []ReplayStep
+	var events []store.NewSessionEvent
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for line := 1; sc.Scan(); line++ {
+		event, err := parser.ParseLine(sc.Bytes())
+		if err != nil || event == nil {
+			continue
+		}
+		steps = append(steps, ReplayStep{
+			Line:         line,
+			ToolName:     event.ToolName,
+			FilePath:     event.FilePath,
+			Timestamp:    event.Timestamp,
+			LinesChanged: event.LinesChanged,
+			Model:        event.Model,
+			contentHash:  event.ContentHash,
+		})
+		events = append(events, store.NewSessionEvent{
+			SessionID:    opts.SessionID,
+			EventType:    event.EventType,
+			ToolName:     event.ToolName,
+			FilePath:     event.FilePath,
+			ContentHash:  event.ContentHash,
+			Timestamp:    event.Timestamp,
+			RawJSON:      event.RawJSON,
+			LinesChanged: event.LinesChanged,
+			Context: store.SessionEventContext{
+				Model:         event.Model,
+				ClientVersion: event.ClientVersion,
+				PromptID:      event.PromptID,
+				Content: &store.SessionContent{
+					Added:   event.DiffContent,
+					Deleted: event.DeletedContent,
+				},
+			},
+		})
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("read session: %w", err)
+	}
+	if err := s.InsertSessionEvents(events); err != nil {
+		return nil, fmt.Errorf("store session events: %w", err)
+	}
+
+	// Then the file events the project's edits imply.
+	stepOf := make(map[string][]int) // file event key -> steps
+	for i, st := range steps {
+		if st.ToolName != "Write" && st.ToolName != "Edit" || !underProject(project, st.FilePath) {
+			continue
+		}
+		steps[i].FileEventAt = st.Timestamp.Add(opts.SaveDelay)
+		if err := s.InsertFileEvent(project, st.FilePath, "write", steps[i].FileEventAt); err != nil {
+			return nil, fmt.Errorf("store file event: %w", err)
+		}
+		key := fileEventKey(st.FilePath, steps[i].FileEventAt)
+		stepOf[key] = append(stepOf[key], i)
+	}
+
+	resolved, err := s.ResolvePath(project)
+	if err != nil {
+		return nil, err
+	}
+	fileEvents, err := s.QueryFileEventsByProject(resolved, time.Time{})
+	if err != nil {
+		return nil, fmt.Errorf("query file events: %w", err)
+	}
+	pipeline := newAttributor(s, cfg)
+	for _, fe := range fileEvents {
+		result, records, wt, err := pipeline.decide(fe, "")
+		if err != nil {
+			return nil, fmt.Errorf("correlate %s: %w", fe.FilePath, err)
+		}
+		if _, err := s.RecordAttributions(records, string(wt)); err != nil {
+			return nil, fmt.Errorf("record attribution: %w", err)
+		}
+		key := fileEventKey(fe.FilePath, fe.Timestamp)
+		if len(stepOf[key]) == 0 {
+			continue
+		}
+		st := &steps[stepOf[key][0]]
+		stepOf[key] = stepOf[key][1:]
+		st.MatchType = result.MatchType
+		st.TimeDeltaMs = result.TimeDeltaMs
+		if m := result.MatchedSession; m != nil {
+			st.MatchedLine = replayLine(steps, *m)
+		}
+		st.WorkType = string(wt)
+		st.Attributions = records
+	}
+	return steps, nil
+}
+
+// replayLine returns the session line that produced a stored session
+// event, or 0 if none did.
+func replayLine(steps []ReplayStep, se store.StoredSessionEvent) int {
+	for _, st := range steps {
+		if st.Timestamp.Equal(se.Timestamp) && st.ToolName == se.ToolName && st.contentHash == se.ContentHash && st.FilePath == se.FilePath {
+			return st.Line
+		}
+	}
+	return 0
+}
+
+// underProject reports whether path is project or inside it.
+func underProject(project, path string) bool {
+	path = filepath.Clean(path)
+	return path == project || strings.HasPrefix(path, project+string(filepath.Separator))
+}
+
+func fileEventKey(path string, ts time.Time) string {
+	return path + "\x00" + ts.UTC().Format(time.RFC3339Nano)
+}
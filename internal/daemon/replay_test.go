@@ -0,0 +1,76 @@
+package daemon
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anthropic/gap-map/internal/config"
+)
+
+func TestReplay(t *testing.T) {
+	session := strings.Join([]string{
+		`{"type":"user","uuid":"u1","timestamp":"2026-10-14T10:00:00Z","message":{"content":"add a handler"}}`,
+		`{"type":"assistant","uuid":"a1","parentUuid":"u1","timestamp":"2026-10-14T10:00:05Z","message":{"model":"claude-opus-4-1","content":[{"type":"tool_use","name":"Write","input":{"file_path":"/proj/handler.go","content":"package proj\n\nfunc Handle() {}\n"}}]}}`,
+		`{"type":"assistant","uuid":"a2","parentUuid":"a1","message":{"content":[{"type":"tool_use","name":"Edit","input":{"file_path":"/elsewhere/notes.md","old_string":"a","new_string":"b"}}]}}`,
+		`{"type":"assistant","uuid":"a3","timestamp":"2026-10-14T10:00:09Z","message":{"content":[{"type":"tool_use","name":"Bash","input":{"command":"go test ./..."}}]}}`,
+	}, "\n")
+
+	replay := func() []ReplayStep {
+		steps, err := Replay(config.Default(), strings.NewReader(session), ReplayOptions{ProjectPath: "/proj", SessionID: "s1"})
+		if err != nil {
+			t.Fatalf("Replay: %v", err)
+		}
+		return steps
+	}
+	steps := replay()
+	if len(steps) != 3 {
+		t.Fatalf("steps = %+v, want Write, Edit and Bash", steps)
+	}
+
+	w := steps[0]
+	if w.Line != 2 || w.ToolName != "Write" || w.Model != "claude-opus-4-1" {
+		t.Errorf("write step = %+v", w)
+	}
+	if !w.FileEventAt.Equal(w.Timestamp.Add(DefaultSaveDelay)) {
+		t.Errorf("file event at %v, want %v after %v", w.FileEventAt, DefaultSaveDelay, w.Timestamp)
+	}
+	if w.MatchType != "exact_file" || w.MatchedLine != 2 || w.TimeDeltaMs != DefaultSaveDelay.Milliseconds() {
+		t.Errorf("write matched %s line %d, %dms apart; want exact_file line 2, 500ms", w.MatchType, w.MatchedLine, w.TimeDeltaMs)
+	}
+	if len(w.Attributions) != 1 || w.Attributions[0].AuthorshipLevel != "mostly_ai" {
+		t.Errorf("write attributions = %+v, want one mostly_ai", w.Attributions)
+	}
+
+	// The edit outside the project gets no file event; its line has no
+	// timestamp of its own, so it takes its parent's.
+	e := steps[1]
+	if !e.FileEventAt.IsZero() || e.Attributions != nil {
+		t.Errorf("edit outside the project was attributed: %+v", e)
+	}
+	if !e.Timestamp.Equal(w.Timestamp) {
+		t.Errorf("edit timestamp = %v, want its parent's %v", e.Timestamp, w.Timestamp)
+	}
+	if b := steps[2]; b.ToolName != "Bash" || !b.FileEventAt.IsZero() {
+		t.Errorf("bash step = %+v", b)
+	}
+
+	// A second replay decides the same.
+	again := replay()
+	for i := range steps {
+		if again[i].MatchType != steps[i].MatchType || again[i].MatchedLine != steps[i].MatchedLine || !again[i].Timestamp.Equal(steps[i].Timestamp) {
+			t.Errorf("replays differ at step %d: %+v vs %+v", i, steps[i], again[i])
+		}
+	}
+
+	// Lines without any time take the clock.
+	clock := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	orphan := `{"type":"assistant","message":{"content":[{"type":"tool_use","name":"Write","input":{"file_path":"/proj/x.go","content":"package proj\n"}}]}}`
+	steps, err := Replay(config.Default(), strings.NewReader(orphan), ReplayOptions{ProjectPath: "/proj", Clock: clock})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(steps) != 1 || !steps[0].Timestamp.Equal(clock) {
+		t.Errorf("steps = %+v, want one at the clock", steps)
+	}
+}
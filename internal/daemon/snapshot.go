@@ -0,0 +1,62 @@
+package daemon
+
+import (
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	"github.com/anthropic/gap-map/internal/store"
+)
+
+// snapshotHumanEdit stores what a human-attributed file event changed, so
+// reports can count the human's lines instead of inferring them. Files
+// whose snapshot would exceed human_snapshot_max_bytes are skipped.
+func (d *Daemon) snapshotHumanEdit(attributionID int64, fe store.FileEvent) {
+	kind, content, ok := snapshotFile(fe.FilePath, d.cfg.HumanSnapshotMaxBytes)
+	if !ok {
+		return
+	}
+	if _, err := d.store.InsertFileSnapshot(store.FileSnapshot{
+		AttributionID: attributionID,
+		FilePath:      fe.FilePath,
+		Kind:          kind,
+		Content:       content,
+		Timestamp:     time.Now(),
+	}); err != nil {
+		slog.Error("attribution: insert snapshot failed", "file", fe.FilePath, "err", err)
+	}
+}
+
+// snapshotFile returns the diff of path against git HEAD or, for a file
+// outside git or not tracked by it, its content. ok is false when the file
+// is unreadable, unchanged from HEAD, or the snapshot exceeds maxBytes.
+func snapshotFile(path string, maxBytes int) (kind, content string, ok bool) {
+	info, err := os.Stat(path)
+	if err != nil || info.IsDir() {
+		return "", "", false
+	}
+
+	dir := filepath.Dir(path)
+	tracked := exec.Command("git", "-C", dir, "ls-files", "--error-unmatch", "--", filepath.Base(path)).Run() == nil
+	if tracked {
+		out, err := exec.Command("git", "-C", dir, "diff", "--no-color", "--no-ext-diff", "HEAD", "--", filepath.Base(path)).Output()
+		if err == nil {
+			if len(out) == 0 || len(out) > maxBytes {
+				return "", "", false
+			}
+			return store.SnapshotDiff, string(out), true
+		}
+		// No HEAD commit yet: fall back to the content.
+	}
+
+	if info.Size() > int64(maxBytes) {
+		return "", "", false
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || len(data) == 0 {
+		return "", "", false
+	}
+	return store.SnapshotContent, string(data), true
+}
@@ -0,0 +1,59 @@
+package daemon
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/anthropic/gap-map/internal/store"
+)
+
+func TestSnapshotFile(t *testing.T) {
+	dir := t.TempDir()
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+		cmd.Env = append(os.Environ(), "GIT_AUTHOR_NAME=t", "GIT_AUTHOR_EMAIL=t@t", "GIT_COMMITTER_NAME=t", "GIT_COMMITTER_EMAIL=t@t")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	write := func(name, content string) string {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	git("init", "-q")
+	tracked := write("a.go", "package a\n")
+	git("add", "a.go")
+	git("commit", "-qm", "init")
+
+	// Unchanged from HEAD: nothing to keep.
+	if _, _, ok := snapshotFile(tracked, 1024); ok {
+		t.Error("snapshotFile took a snapshot of an unchanged file")
+	}
+
+	write("a.go", "package a\n\nvar human = 1\n")
+	kind, content, ok := snapshotFile(tracked, 1024)
+	if !ok || kind != store.SnapshotDiff || !strings.Contains(content, "+var human = 1") {
+		t.Errorf("snapshotFile(tracked) = %q, %q, %v; want a diff adding the line", kind, content, ok)
+	}
+	if _, _, ok := snapshotFile(tracked, 10); ok {
+		t.Error("snapshotFile kept a diff larger than the limit")
+	}
+
+	untracked := write("b.go", "package a\n\nvar b = 2\n")
+	kind, content, ok = snapshotFile(untracked, 1024)
+	if !ok || kind != store.SnapshotContent || content != "package a\n\nvar b = 2\n" {
+		t.Errorf("snapshotFile(untracked) = %q, %q, %v; want the content", kind, content, ok)
+	}
+
+	if _, _, ok := snapshotFile(filepath.Join(dir, "missing.go"), 1024); ok {
+		t.Error("snapshotFile took a snapshot of a missing file")
+	}
+}
@@ -0,0 +1,134 @@
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+	"time"
+)
+
+// CrashState records the crashes of the supervised daemon since the
+// supervisor started. The supervisor writes it; Health reads it.
+type CrashState struct {
+	Crashes     int       `json:"crashes"`
+	LastCrashAt time.Time `json:"last_crash_at,omitempty"`
+	LastError   string    `json:"last_error,omitempty"` // how the last crashed daemon exited
+}
+
+// CrashStatePath returns where the supervisor keeps its CrashState.
+func CrashStatePath(dataDir string) string {
+	return filepath.Join(dataDir, "crashes.json")
+}
+
+// LoadCrashState reads the CrashState at path; a missing file is no
+// crashes.
+func LoadCrashState(path string) (CrashState, error) {
+	var state CrashState
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return state, err
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return state, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return state, nil
+}
+
+func saveCrashState(path string, state CrashState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Supervisor runs the daemon as a child process and restarts it when it
+// crashes, waiting longer after each crash in a row. A child that exits
+// cleanly (e.g. after "gapmap stop") ends supervision.
+type Supervisor struct {
+	// Command returns the command that runs the daemon; it is called for
+	// every start.
+	Command func() *exec.Cmd
+	// StatePath is where crashes are recorded (see CrashStatePath).
+	StatePath string
+
+	MinBackoff  time.Duration // wait after the first crash in a row
+	MaxBackoff  time.Duration // longest wait between restarts
+	StableAfter time.Duration // a child that ran this long resets the wait
+}
+
+// NewSupervisor returns a Supervisor with the default backoff: 1s after a
+// crash, doubling up to 5m, reset by a child that ran for 10m.
+func NewSupervisor(command func() *exec.Cmd, dataDir string) *Supervisor {
+	return &Supervisor{
+		Command:     command,
+		StatePath:   CrashStatePath(dataDir),
+		MinBackoff:  time.Second,
+		MaxBackoff:  5 * time.Minute,
+		StableAfter: 10 * time.Minute,
+	}
+}
+
+// Run starts the daemon and restarts it after each crash until it exits
+// cleanly or ctx is done. When ctx is done the child is sent SIGTERM and
+// waited for.
+func (s *Supervisor) Run(ctx context.Context) error {
+	var state CrashState
+	if err := saveCrashState(s.StatePath, state); err != nil {
+		return fmt.Errorf("reset crash state: %w", err)
+	}
+
+	backoff := s.MinBackoff
+	for {
+		cmd := s.Command()
+		started := time.Now()
+		if err := cmd.Start(); err != nil {
+			return fmt.Errorf("start daemon: %w", err)
+		}
+		done := make(chan error, 1)
+		go func() { done <- cmd.Wait() }()
+
+		var err error
+		select {
+		case err = <-done:
+		case <-ctx.Done():
+			_ = cmd.Process.Signal(syscall.SIGTERM)
+			<-done
+			return nil
+		}
+		if err == nil {
+			return nil
+		}
+
+		state.Crashes++
+		state.LastCrashAt = time.Now()
+		state.LastError = err.Error()
+		if err := saveCrashState(s.StatePath, state); err != nil {
+			slog.Error("supervisor: record crash failed", "err", err)
+		}
+
+		if time.Since(started) >= s.StableAfter {
+			backoff = s.MinBackoff
+		}
+		slog.Warn("supervisor: daemon crashed, restarting", "err", err, "crashes", state.Crashes, "backoff", backoff)
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return nil
+		}
+		backoff = min(backoff*2, s.MaxBackoff)
+	}
+}
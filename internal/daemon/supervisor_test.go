@@ -0,0 +1,58 @@
+package daemon
+
+import (
+	"context"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSupervisorRestartsAfterCrash(t *testing.T) {
+	dir := t.TempDir()
+	starts := 0
+	sup := NewSupervisor(func() *exec.Cmd {
+		starts++
+		if starts < 3 {
+			return exec.Command("sh", "-c", "exit 2")
+		}
+		return exec.Command("sh", "-c", "exit 0")
+	}, dir)
+	sup.MinBackoff = time.Millisecond
+	sup.MaxBackoff = 2 * time.Millisecond
+
+	if err := sup.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if starts != 3 {
+		t.Errorf("starts = %d, want 3 (two crashes, then a clean exit)", starts)
+	}
+	state, err := LoadCrashState(filepath.Join(dir, "crashes.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.Crashes != 2 || state.LastError != "exit status 2" || state.LastCrashAt.IsZero() {
+		t.Errorf("crash state = %+v, want 2 crashes with exit status 2", state)
+	}
+}
+
+func TestSupervisorStopsChild(t *testing.T) {
+	sup := NewSupervisor(func() *exec.Cmd {
+		return exec.Command("sleep", "30")
+	}, t.TempDir())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- sup.Run(ctx) }()
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("supervisor did not stop its child")
+	}
+}
@@ -0,0 +1,46 @@
+package daemon
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/anthropic/gap-map/internal/survival"
+)
+
+// runSurvivalJob records a survival snapshot of every attributed project
+// now and then every interval until ctx is done.
+func (d *Daemon) runSurvivalJob(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		d.recordSurvival(time.Now())
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// recordSurvival records the survival of the AI code of each attributed
+// project the trust settings allow.
+func (d *Daemon) recordSurvival(now time.Time) {
+	projects, err := d.store.QueryAttributedProjects()
+	if err != nil {
+		slog.Error("survival: query projects failed", "err", err)
+		return
+	}
+	trust := d.trust.Load()
+	for _, project := range projects {
+		if !trust.Allows(project) {
+			continue
+		}
+		sr, err := survival.Record(d.store, project, now, d.cfg.CorrectionWindow())
+		if err != nil {
+			slog.Error("survival: record failed", "project", project, "err", err)
+			continue
+		}
+		slog.Debug("survival recorded", "project", project, "tracked", sr.TotalTracked, "rate", sr.SurvivalRate)
+	}
+}
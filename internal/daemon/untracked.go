@@ -0,0 +1,59 @@
+package daemon
+
+import (
+	"log/slog"
+	"sort"
+
+	"github.com/anthropic/gap-map/internal/watcher"
+)
+
+// UntrackedRepo is a repository outside every watch path that AI edited
+// at least untracked_repo_threshold times since the daemon started.
+type UntrackedRepo struct {
+	Path  string
+	Edits int
+}
+
+// noteUntracked counts an AI edit of path if it lies in a repository no
+// watch path covers, and suggests tracking the repository once the count
+// reaches untracked_repo_threshold.
+func (d *Daemon) noteUntracked(path string) {
+	threshold := d.cfg.UntrackedRepoThreshold
+	if threshold <= 0 || path == "" {
+		return
+	}
+	root, repo := watcher.UntrackedRoot(d.cfg.WatchPaths, path)
+	if !repo {
+		return
+	}
+	d.mu.Lock()
+	if d.untracked == nil {
+		d.untracked = make(map[string]int)
+	}
+	d.untracked[root]++
+	edits := d.untracked[root]
+	d.mu.Unlock()
+	if edits == threshold {
+		slog.Warn("AI is editing a repository gap-map does not watch; run \"gapmap orphans --track\" to track it", "repo", root, "edits", edits)
+	}
+}
+
+// untrackedRepos returns the repositories noteUntracked suggested
+// tracking, most edited first.
+func (d *Daemon) untrackedRepos() []UntrackedRepo {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var repos []UntrackedRepo
+	for path, edits := range d.untracked {
+		if edits >= d.cfg.UntrackedRepoThreshold {
+			repos = append(repos, UntrackedRepo{Path: path, Edits: edits})
+		}
+	}
+	sort.Slice(repos, func(i, j int) bool {
+		if repos[i].Edits != repos[j].Edits {
+			return repos[i].Edits > repos[j].Edits
+		}
+		return repos[i].Path < repos[j].Path
+	})
+	return repos
+}
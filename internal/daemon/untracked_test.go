@@ -0,0 +1,38 @@
+package daemon
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/anthropic/gap-map/internal/config"
+)
+
+func TestNoteUntracked(t *testing.T) {
+	dir := t.TempDir()
+	watched := filepath.Join(dir, "api")
+	sibling := filepath.Join(dir, "web")
+	for _, d := range []string{watched, filepath.Join(sibling, ".git")} {
+		if err := os.MkdirAll(d, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cfg := config.Default()
+	cfg.WatchPaths = []string{watched}
+	cfg.UntrackedRepoThreshold = 3
+	d := &Daemon{cfg: cfg}
+
+	for i := 0; i < 2; i++ {
+		d.noteUntracked(filepath.Join(sibling, "app.ts"))
+		d.noteUntracked(filepath.Join(watched, "main.go"))
+		d.noteUntracked(filepath.Join(dir, "scratch.txt"))
+	}
+	if repos := d.untrackedRepos(); len(repos) != 0 {
+		t.Fatalf("below the threshold: got %+v, want none", repos)
+	}
+	d.noteUntracked(filepath.Join(sibling, "lib", "util.ts"))
+	repos := d.untrackedRepos()
+	if len(repos) != 1 || repos[0].Path != sibling || repos[0].Edits != 3 {
+		t.Errorf("untrackedRepos = %+v, want %s with 3 edits", repos, sibling)
+	}
+}
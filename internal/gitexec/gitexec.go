@@ -0,0 +1,302 @@
+// Package gitexec runs the git commands behind report generation. A report
+// can need hundreds of them, so at most a fixed number run at once, file
+// contents are read through one long-lived git cat-file --batch process
+// per repository instead of a git show each, and contents at a commit are
+// cached by commit and path.
+package gitexec
+
+import (
+	"bufio"
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Defaults of New's limits.
+const (
+	DefaultMaxProcesses = 8
+	DefaultCacheBytes   = 64 << 20
+)
+
+// ErrNotFound is returned by Show when the path does not exist at the
+// revision, or is not a file there.
+var ErrNotFound = errors.New("not found")
+
+// Executor runs git commands, at most maxProcesses at once, and reads file
+// contents through git cat-file --batch. The cat-file processes, one per
+// repository, are not counted against the limit.
+type Executor struct {
+	sem chan struct{}
+
+	mu      sync.Mutex
+	batches map[string]*batch // by repository directory
+
+	cache *cache
+}
+
+// New returns an executor that runs at most maxProcesses git commands at
+// once (DefaultMaxProcesses when less than 1) and caches up to cacheBytes
+// of file contents (none when cacheBytes is 0).
+func New(maxProcesses int, cacheBytes int64) *Executor {
+	if maxProcesses < 1 {
+		maxProcesses = DefaultMaxProcesses
+	}
+	return &Executor{
+		sem:     make(chan struct{}, maxProcesses),
+		batches: make(map[string]*batch),
+		cache:   newCache(cacheBytes),
+	}
+}
+
+// Output runs git with args in dir, waiting for a free slot first, and
+// returns its standard output.
+func (e *Executor) Output(ctx context.Context, dir string, args ...string) ([]byte, error) {
+	select {
+	case e.sem <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	defer func() { <-e.sem }()
+
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	return cmd.Output()
+}
+
+// Show returns the content of path, relative to the repository root, at
+// rev, like git show rev:path. Contents at a full commit hash are cached;
+// other revisions, such as branch names, can move and are read each time.
+func (e *Executor) Show(ctx context.Context, dir, rev, path string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	key := cacheKey{dir: dir, commit: rev, path: path}
+	cacheable := IsCommitHash(rev)
+	if cacheable {
+		if content, found, ok := e.cache.get(key); ok {
+			if !found {
+				return "", ErrNotFound
+			}
+			return content, nil
+		}
+	}
+
+	var content string
+	var err error
+	if strings.ContainsAny(path, "\n\r") {
+		// cat-file --batch reads one object name per line.
+		var out []byte
+		out, err = e.Output(ctx, dir, "show", rev+":"+path)
+		content = string(out)
+		if err != nil {
+			err = ErrNotFound
+		}
+	} else {
+		content, err = e.catFile(dir, rev+":"+path)
+	}
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return "", err
+	}
+	if cacheable {
+		e.cache.put(key, content, err == nil)
+	}
+	return content, err
+}
+
+// Close stops the executor's cat-file processes. The executor can still be
+// used; they are started again as needed.
+func (e *Executor) Close() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	var errs []error
+	for dir, b := range e.batches {
+		errs = append(errs, b.close())
+		delete(e.batches, dir)
+	}
+	return errors.Join(errs...)
+}
+
+// catFile reads object from the cat-file process of dir, starting it if
+// need be. A process that fails is dropped, to be restarted next time.
+func (e *Executor) catFile(dir, object string) (string, error) {
+	e.mu.Lock()
+	b := e.batches[dir]
+	if b == nil {
+		var err error
+		if b, err = startBatch(dir); err != nil {
+			e.mu.Unlock()
+			return "", err
+		}
+		e.batches[dir] = b
+	}
+	e.mu.Unlock()
+
+	content, err := b.read(object)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		e.mu.Lock()
+		if e.batches[dir] == b {
+			delete(e.batches, dir)
+		}
+		e.mu.Unlock()
+		b.close()
+	}
+	return content, err
+}
+
+// IsCommitHash reports whether rev is a full SHA-1 or SHA-256 object name,
+// which always names the same commit.
+func IsCommitHash(rev string) bool {
+	if len(rev) != 40 && len(rev) != 64 {
+		return false
+	}
+	for _, c := range rev {
+		if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// batch is a git cat-file --batch process. Requests are answered in
+// order, so one is sent at a time.
+type batch struct {
+	mu  sync.Mutex
+	cmd *exec.Cmd
+	in  io.WriteCloser
+	out *bufio.Reader
+}
+
+// startBatch starts git cat-file --batch in dir.
+func startBatch(dir string) (*batch, error) {
+	cmd := exec.Command("git", "cat-file", "--batch")
+	cmd.Dir = dir
+	in, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("git cat-file: %w", err)
+	}
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("git cat-file: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("start git cat-file: %w", err)
+	}
+	return &batch{cmd: cmd, in: in, out: bufio.NewReader(out)}, nil
+}
+
+// read returns the content of a blob, named as cat-file takes it.
+func (b *batch) read(object string) (string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, err := io.WriteString(b.in, object+"\n"); err != nil {
+		return "", fmt.Errorf("git cat-file: %w", err)
+	}
+	header, err := b.out.ReadString('\n')
+	if err != nil {
+		return "", fmt.Errorf("git cat-file: %w", err)
+	}
+	// "<name> missing", "<name> ambiguous" or "<oid> <type> <size>".
+	fields := strings.Fields(header)
+	if len(fields) == 3 {
+		size, err := strconv.ParseInt(fields[2], 10, 64)
+		if err != nil {
+			return "", fmt.Errorf("git cat-file: bad header %q", strings.TrimSpace(header))
+		}
+		body := make([]byte, size+1) // the content and a newline
+		if _, err := io.ReadFull(b.out, body); err != nil {
+			return "", fmt.Errorf("git cat-file: %w", err)
+		}
+		if fields[1] != "blob" {
+			return "", ErrNotFound
+		}
+		return string(body[:size]), nil
+	}
+	if strings.HasSuffix(header, " missing\n") || strings.HasSuffix(header, " ambiguous\n") {
+		return "", ErrNotFound
+	}
+	return "", fmt.Errorf("git cat-file: bad header %q", strings.TrimSpace(header))
+}
+
+// close ends the process by closing its input.
+func (b *batch) close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.in.Close()
+	return b.cmd.Wait()
+}
+
+// cacheKey names the content of a file at a commit.
+type cacheKey struct {
+	dir, commit, path string
+}
+
+// cache is a least recently used cache of file contents, bounded by their
+// total size. Paths missing at a commit are cached too.
+type cache struct {
+	mu      sync.Mutex
+	max     int64
+	size    int64
+	order   *list.List // of *cacheEntry, most recently used first
+	entries map[cacheKey]*list.Element
+}
+
+type cacheEntry struct {
+	key     cacheKey
+	content string
+	found   bool
+}
+
+func newCache(max int64) *cache {
+	return &cache{max: max, order: list.New(), entries: make(map[cacheKey]*list.Element)}
+}
+
+// get returns the cached content of key, whether the path was found, and
+// whether key was cached.
+func (c *cache) get(key cacheKey) (content string, found, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.entries[key]
+	if !ok {
+		return "", false, false
+	}
+	c.order.MoveToFront(el)
+	ent := el.Value.(*cacheEntry)
+	return ent.content, ent.found, true
+}
+
+// put caches the content of key, evicting the least recently used
+// contents past the size limit. Contents larger than the limit are not
+// cached.
+func (c *cache) put(key cacheKey, content string, found bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	size := entrySize(key, content)
+	if size > c.max {
+		return
+	}
+	if el, ok := c.entries[key]; ok {
+		c.order.MoveToFront(el)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&cacheEntry{key: key, content: content, found: found})
+	c.size += size
+	for c.size > c.max {
+		el := c.order.Back()
+		ent := el.Value.(*cacheEntry)
+		c.order.Remove(el)
+		delete(c.entries, ent.key)
+		c.size -= entrySize(ent.key, ent.content)
+	}
+}
+
+// entrySize is what an entry counts against the cache's size limit.
+func entrySize(key cacheKey, content string) int64 {
+	return int64(len(key.dir) + len(key.commit) + len(key.path) + len(content))
+}
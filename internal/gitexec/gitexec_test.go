@@ -0,0 +1,127 @@
+package gitexec
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func git(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v: %v\n%s", args, err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func TestExecutorShow(t *testing.T) {
+	dir := t.TempDir()
+	git(t, dir, "init", "-q")
+	git(t, dir, "config", "user.email", "test@test.com")
+	git(t, dir, "config", "user.name", "Test")
+	if err := os.MkdirAll(filepath.Join(dir, "pkg"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "pkg", "a.go"), []byte("package pkg\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	git(t, dir, "add", ".")
+	git(t, dir, "commit", "-q", "-m", "add a")
+	commit := git(t, dir, "rev-parse", "HEAD")
+
+	e := New(2, 1<<20)
+	defer e.Close()
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		got, err := e.Show(ctx, dir, commit, "pkg/a.go")
+		if err != nil || got != "package pkg\n" {
+			t.Fatalf("Show = %q, %v", got, err)
+		}
+	}
+	if _, found, ok := e.cache.get(cacheKey{dir, commit, "pkg/a.go"}); !ok || !found {
+		t.Error("content at a commit hash not cached")
+	}
+	if _, err := e.Show(ctx, dir, commit, "missing.go"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Show(missing) error = %v, want ErrNotFound", err)
+	}
+	if _, err := e.Show(ctx, dir, commit, "pkg"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Show(directory) error = %v, want ErrNotFound", err)
+	}
+
+	// Branch names are read each time, not cached.
+	if got, err := e.Show(ctx, dir, "HEAD", "pkg/a.go"); err != nil || got != "package pkg\n" {
+		t.Errorf("Show(HEAD) = %q, %v", got, err)
+	}
+	if _, _, ok := e.cache.get(cacheKey{dir, "HEAD", "pkg/a.go"}); ok {
+		t.Error("content at HEAD cached")
+	}
+
+	// Concurrent reads share the cat-file process, which restarts after Close.
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if got, err := e.Show(ctx, dir, "HEAD", "pkg/a.go"); err != nil || got != "package pkg\n" {
+				t.Errorf("concurrent Show = %q, %v", got, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if err := e.Close(); err != nil {
+		t.Errorf("Close: %v", err)
+	}
+	if got, err := e.Show(ctx, dir, "HEAD", "pkg/a.go"); err != nil || got != "package pkg\n" {
+		t.Errorf("Show after Close = %q, %v", got, err)
+	}
+
+	out, err := e.Output(ctx, dir, "rev-parse", "HEAD")
+	if err != nil || strings.TrimSpace(string(out)) != commit {
+		t.Errorf("Output = %q, %v", out, err)
+	}
+}
+
+func TestCacheEviction(t *testing.T) {
+	c := newCache(entrySize(cacheKey{"d", "c", "a"}, "12345") * 2)
+	c.put(cacheKey{"d", "c", "a"}, "12345", true)
+	c.put(cacheKey{"d", "c", "b"}, "12345", true)
+	c.get(cacheKey{"d", "c", "a"}) // b is now the least recently used
+	c.put(cacheKey{"d", "c", "c"}, "12345", true)
+
+	if _, _, ok := c.get(cacheKey{"d", "c", "b"}); ok {
+		t.Error("least recently used entry not evicted")
+	}
+	for _, p := range []string{"a", "c"} {
+		if _, _, ok := c.get(cacheKey{"d", "c", p}); !ok {
+			t.Errorf("entry %s evicted", p)
+		}
+	}
+	c.put(cacheKey{"d", "c", "big"}, strings.Repeat("x", 100), true)
+	if _, _, ok := c.get(cacheKey{"d", "c", "big"}); ok {
+		t.Error("entry larger than the cache was cached")
+	}
+}
+
+func TestIsCommitHash(t *testing.T) {
+	for rev, want := range map[string]bool{
+		strings.Repeat("a1", 20): true,
+		strings.Repeat("0f", 32): true,
+		"HEAD":                   false,
+		"main":                   false,
+		strings.Repeat("A1", 20): false,
+		strings.Repeat("a", 39):  false,
+	} {
+		if got := IsCommitHash(rev); got != want {
+			t.Errorf("IsCommitHash(%q) = %v, want %v", rev, got, want)
+		}
+	}
+}
@@ -0,0 +1,275 @@
+package github
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// CommentMarker is embedded in PR comments posted by UpsertComment so a
+// later run can find and update its own comment instead of adding another.
+const CommentMarker = "<!-- gap-map:pr-comment -->"
+
+// CheckRun is the summary published as a GitHub check run.
+type CheckRun struct {
+	Name       string // check name shown in the PR checks list
+	HeadSHA    string // commit the check is attached to
+	Conclusion string // success, neutral, failure, ...
+	Title      string
+	Summary    string // Markdown
+}
+
+// UpsertComment updates the PR comment previously posted by gap-map, or
+// posts a new one if none exists. Comments are searched page by page, so
+// the marker is found on PRs with any number of comments.
+func (c *Client) UpsertComment(owner, repo string, prNumber int, body string) error {
+	if !strings.Contains(body, CommentMarker) {
+		body = CommentMarker + "\n" + body
+	}
+
+	url := fmt.Sprintf("%s/repos/%s/%s/issues/%d/comments?per_page=100", apiBaseURL, owner, repo, prNumber)
+	for url != "" {
+		var comments []struct {
+			ID   int64  `json:"id"`
+			Body string `json:"body"`
+		}
+		next, err := c.getPage(url, &comments)
+		if err != nil {
+			return fmt.Errorf("list comments: %w", err)
+		}
+
+		for _, comment := range comments {
+			if strings.Contains(comment.Body, CommentMarker) {
+				url := fmt.Sprintf("%s/repos/%s/%s/issues/comments/%d", apiBaseURL, owner, repo, comment.ID)
+				payload := map[string]string{"body": body}
+				if err := c.doJSON("PATCH", url, payload, http.StatusOK, nil); err != nil {
+					return fmt.Errorf("update comment: %w", err)
+				}
+				return nil
+			}
+		}
+		url = next
+	}
+
+	return c.PostComment(owner, repo, prNumber, body)
+}
+
+// CreateCheckRun publishes a completed check run on a commit.
+func (c *Client) CreateCheckRun(owner, repo string, run CheckRun) error {
+	url := fmt.Sprintf("%s/repos/%s/%s/check-runs", apiBaseURL, owner, repo)
+	payload := map[string]any{
+		"name":       run.Name,
+		"head_sha":   run.HeadSHA,
+		"status":     "completed",
+		"conclusion": run.Conclusion,
+		"output": map[string]string{
+			"title":   run.Title,
+			"summary": run.Summary,
+		},
+	}
+	if err := c.doJSON("POST", url, payload, http.StatusCreated, nil); err != nil {
+		return fmt.Errorf("create check run: %w", err)
+	}
+	return nil
+}
+
+// DownloadArtifact fetches the most recent unexpired workflow artifact with
+// the given name and extracts the file named fileName from it to destPath.
+// Only artifacts of workflow runs on one of branches, in the repository
+// itself rather than a fork, are considered, so a PR cannot restore a
+// database another branch or a fork uploaded.
+func (c *Client) DownloadArtifact(owner, repo, name, fileName, destPath string, branches []string) error {
+	downloadURL, err := c.findArtifact(owner, repo, name, branches)
+	if err != nil {
+		return err
+	}
+
+	// The download URL redirects to blob storage; the zip is read into
+	// memory because archive/zip needs random access.
+	req, err := c.newRequest("GET", downloadURL, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.send(req)
+	if err != nil {
+		return fmt.Errorf("download artifact: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("github API returned status %d: %s", resp.StatusCode, string(respBody))
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("read artifact: %w", err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return fmt.Errorf("open artifact zip: %w", err)
+	}
+	for _, f := range zr.File {
+		if filepath.Base(f.Name) != fileName {
+			continue
+		}
+		return extractZipFile(f, destPath)
+	}
+	return fmt.Errorf("artifact %q has no file %q", name, fileName)
+}
+
+// findArtifact returns the download URL of the newest unexpired artifact
+// named name from a run on one of branches in owner/repo itself.
+func (c *Client) findArtifact(owner, repo, name string, branches []string) (string, error) {
+	endpoint := fmt.Sprintf("%s/repos/%s/%s/actions/artifacts?name=%s&per_page=100", apiBaseURL, owner, repo, url.QueryEscape(name))
+	for endpoint != "" {
+		var list struct {
+			Artifacts []struct {
+				Expired            bool   `json:"expired"`
+				ArchiveDownloadURL string `json:"archive_download_url"`
+				WorkflowRun        struct {
+					RepositoryID     int64  `json:"repository_id"`
+					HeadRepositoryID int64  `json:"head_repository_id"`
+					HeadBranch       string `json:"head_branch"`
+				} `json:"workflow_run"`
+			} `json:"artifacts"`
+		}
+		next, err := c.getPage(endpoint, &list)
+		if err != nil {
+			return "", fmt.Errorf("list artifacts: %w", err)
+		}
+		for _, a := range list.Artifacts {
+			run := a.WorkflowRun
+			if a.Expired || run.RepositoryID == 0 || run.HeadRepositoryID != run.RepositoryID {
+				continue
+			}
+			if slices.Contains(branches, run.HeadBranch) {
+				return a.ArchiveDownloadURL, nil
+			}
+		}
+		endpoint = next
+	}
+	return "", fmt.Errorf("no artifact named %q from %s", name, strings.Join(branches, " or "))
+}
+
+// extractZipFile writes a single zip entry to destPath. The entry is
+// written next to destPath and renamed into place once complete, so a
+// failed extraction leaves nothing at destPath.
+func extractZipFile(f *zip.File, destPath string) error {
+	rc, err := f.Open()
+	if err != nil {
+		return fmt.Errorf("open %s in artifact: %w", f.Name, err)
+	}
+	defer rc.Close()
+
+	if err := os.MkdirAll(filepath.Dir(destPath), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(destPath), "."+filepath.Base(destPath)+".download-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // fails harmlessly once renamed
+	if _, err := io.Copy(tmp, rc); err != nil {
+		tmp.Close()
+		return fmt.Errorf("extract %s: %w", f.Name, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("extract %s: %w", f.Name, err)
+	}
+	if err := os.Rename(tmp.Name(), destPath); err != nil {
+		return fmt.Errorf("extract %s: %w", f.Name, err)
+	}
+	return nil
+}
+
+// newRequest builds a GitHub REST API request with the standard headers.
+func (c *Client) newRequest(method, url string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return req, nil
+}
+
+// doJSON sends payload (if non-nil) as JSON, checks for wantStatus, and
+// decodes the response into out (if non-nil).
+func (c *Client) doJSON(method, url string, payload any, wantStatus int, out any) error {
+	_, err := c.doJSONHeader(method, url, payload, wantStatus, out)
+	return err
+}
+
+// getPage GETs one page of a list into out and returns the URL of the
+// next page from the Link header, or "" on the last page.
+func (c *Client) getPage(url string, out any) (string, error) {
+	header, err := c.doJSONHeader("GET", url, nil, http.StatusOK, out)
+	if err != nil {
+		return "", err
+	}
+	return nextPage(header.Get("Link")), nil
+}
+
+// nextPage returns the rel="next" URL of a Link header, e.g.
+// <https://api.github.com/...&page=2>; rel="next", <...>; rel="last".
+func nextPage(link string) string {
+	for _, part := range strings.Split(link, ",") {
+		target, params, ok := strings.Cut(part, ";")
+		if !ok {
+			continue
+		}
+		for _, p := range strings.Split(params, ";") {
+			if strings.TrimSpace(p) == `rel="next"` {
+				return strings.Trim(strings.TrimSpace(target), "<>")
+			}
+		}
+	}
+	return ""
+}
+
+// doJSONHeader is doJSON that also returns the response headers.
+func (c *Client) doJSONHeader(method, url string, payload any, wantStatus int, out any) (http.Header, error) {
+	var body io.Reader
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return nil, fmt.Errorf("marshal request: %w", err)
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := c.newRequest(method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.send(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != wantStatus {
+		respBody, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("github API returned status %d: %s", resp.StatusCode, string(respBody))
+	}
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return nil, fmt.Errorf("decode response: %w", err)
+		}
+	}
+	return resp.Header, nil
+}
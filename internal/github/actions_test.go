@@ -0,0 +1,210 @@
+package github
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// withAPI points apiBaseURL at a test server for the duration of the test.
+func withAPI(t *testing.T, handler http.HandlerFunc) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	orig := apiBaseURL
+	apiBaseURL = srv.URL
+	t.Cleanup(func() {
+		apiBaseURL = orig
+		srv.Close()
+	})
+	return srv
+}
+
+// testClient returns a client with token and the default options.
+func testClient(token string) *Client {
+	return NewClient(token, DefaultClientOptions)
+}
+
+func TestUpsertComment_CreatesWhenMissing(t *testing.T) {
+	var posted string
+	withAPI(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "GET" && r.URL.Path == "/repos/acme/widgets/issues/3/comments":
+			fmt.Fprint(w, `[{"id":1,"body":"unrelated review note"}]`)
+		case r.Method == "POST" && r.URL.Path == "/repos/acme/widgets/issues/3/comments":
+			var payload struct {
+				Body string `json:"body"`
+			}
+			json.NewDecoder(r.Body).Decode(&payload)
+			posted = payload.Body
+			w.WriteHeader(http.StatusCreated)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			http.NotFound(w, r)
+		}
+	})
+
+	if err := testClient("tok").UpsertComment("acme", "widgets", 3, "report"); err != nil {
+		t.Fatalf("UpsertComment: %v", err)
+	}
+	if !strings.Contains(posted, CommentMarker) || !strings.Contains(posted, "report") {
+		t.Errorf("posted body = %q, want marker and report", posted)
+	}
+}
+
+func TestUpsertComment_UpdatesExisting(t *testing.T) {
+	var patched bool
+	withAPI(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "GET" && r.URL.Path == "/repos/acme/widgets/issues/3/comments":
+			fmt.Fprintf(w, `[{"id":1,"body":"hi"},{"id":42,"body":%q}]`, CommentMarker+"\nold")
+		case r.Method == "PATCH" && r.URL.Path == "/repos/acme/widgets/issues/comments/42":
+			patched = true
+			fmt.Fprint(w, `{}`)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			http.NotFound(w, r)
+		}
+	})
+
+	if err := testClient("tok").UpsertComment("acme", "widgets", 3, "new"); err != nil {
+		t.Fatalf("UpsertComment: %v", err)
+	}
+	if !patched {
+		t.Error("expected existing comment 42 to be updated")
+	}
+}
+
+func TestUpsertComment_FindsOnLaterPage(t *testing.T) {
+	var srvURL string
+	var patched, posted bool
+	srv := withAPI(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "GET" && r.URL.Path == "/repos/acme/widgets/issues/3/comments":
+			if r.URL.Query().Get("page") == "" {
+				w.Header().Set("Link", fmt.Sprintf(`<%s/repos/acme/widgets/issues/3/comments?per_page=100&page=2>; rel="next", <%s/repos/acme/widgets/issues/3/comments?per_page=100&page=2>; rel="last"`, srvURL, srvURL))
+				fmt.Fprint(w, `[{"id":1,"body":"hi"}]`)
+				return
+			}
+			fmt.Fprintf(w, `[{"id":77,"body":%q}]`, CommentMarker+"\nold")
+		case r.Method == "PATCH" && r.URL.Path == "/repos/acme/widgets/issues/comments/77":
+			patched = true
+			fmt.Fprint(w, `{}`)
+		case r.Method == "POST":
+			posted = true
+			w.WriteHeader(http.StatusCreated)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			http.NotFound(w, r)
+		}
+	})
+	srvURL = srv.URL
+
+	if err := testClient("tok").UpsertComment("acme", "widgets", 3, "new"); err != nil {
+		t.Fatalf("UpsertComment: %v", err)
+	}
+	if !patched || posted {
+		t.Errorf("patched = %v, posted = %v; want the comment on page 2 updated", patched, posted)
+	}
+}
+
+func TestCreateCheckRun(t *testing.T) {
+	var payload map[string]any
+	withAPI(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.URL.Path != "/repos/acme/widgets/check-runs" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		json.NewDecoder(r.Body).Decode(&payload)
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, `{"id":1}`)
+	})
+
+	run := CheckRun{Name: "gap-map", HeadSHA: "abc123", Conclusion: "neutral", Title: "t", Summary: "s"}
+	if err := testClient("tok").CreateCheckRun("acme", "widgets", run); err != nil {
+		t.Fatalf("CreateCheckRun: %v", err)
+	}
+	if payload["head_sha"] != "abc123" || payload["status"] != "completed" || payload["conclusion"] != "neutral" {
+		t.Errorf("payload = %v", payload)
+	}
+}
+
+func TestDownloadArtifact(t *testing.T) {
+	var srv *httptest.Server
+	srv = withAPI(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/acme/widgets/actions/artifacts":
+			if got := r.URL.Query().Get("name"); got != "gapmap-db" {
+				t.Errorf("artifact name = %q", got)
+			}
+			// Newest first: a fork's run and another branch's run are
+			// skipped for the base branch's.
+			fmt.Fprintf(w, `{"artifacts":[
+				{"expired":false,"archive_download_url":%q,"workflow_run":{"repository_id":1,"head_repository_id":2,"head_branch":"feature"}},
+				{"expired":false,"archive_download_url":%q,"workflow_run":{"repository_id":1,"head_repository_id":1,"head_branch":"other"}},
+				{"expired":false,"archive_download_url":%q,"workflow_run":{"repository_id":1,"head_repository_id":1,"head_branch":"main"}}
+			]}`, srv.URL+"/fork", srv.URL+"/other", srv.URL+"/zip")
+		case "/zip":
+			zw := zip.NewWriter(w)
+			f, _ := zw.Create("gapmap.db")
+			f.Write([]byte("sqlite bytes"))
+			zw.Close()
+		default:
+			http.NotFound(w, r)
+		}
+	})
+
+	dest := filepath.Join(t.TempDir(), "restored", "gapmap.db")
+	if err := testClient("tok").DownloadArtifact("acme", "widgets", "gapmap-db", "gapmap.db", dest, []string{"main", "feature"}); err != nil {
+		t.Fatalf("DownloadArtifact: %v", err)
+	}
+	data, err := os.ReadFile(dest)
+	if err != nil {
+		t.Fatalf("read restored file: %v", err)
+	}
+	if string(data) != "sqlite bytes" {
+		t.Errorf("restored content = %q", data)
+	}
+
+	if err := testClient("tok").DownloadArtifact("acme", "widgets", "gapmap-db", "other.db", dest, []string{"main", "feature"}); err == nil {
+		t.Error("expected error for missing file in artifact")
+	}
+	if err := testClient("tok").DownloadArtifact("acme", "widgets", "gapmap-db", "gapmap.db", dest, []string{"release", "hotfix"}); err == nil {
+		t.Error("expected error when no artifact is from the PR's branches")
+	}
+}
+
+func TestExtractZipFile_Truncated(t *testing.T) {
+	// A stored entry whose checksum does not match its content fails
+	// partway through the copy, like a truncated download.
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, err := zw.CreateRaw(&zip.FileHeader{Name: "gapmap.db", Method: zip.Store, CRC32: 1, CompressedSize64: 12, UncompressedSize64: 12})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write([]byte("sqlite bytes"))
+	zw.Close()
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	dest := filepath.Join(dir, "gapmap.db")
+	if err := extractZipFile(zr.File[0], dest); err == nil {
+		t.Fatal("extractZipFile of a corrupt entry: want error")
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("failed extraction left %v behind", entries)
+	}
+}
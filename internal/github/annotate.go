@@ -0,0 +1,125 @@
+package github
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/anthropic/gap-map/internal/report"
+)
+
+// AnnotationMarker is embedded in the inline review comments posted by
+// PostAnnotations so a later run does not repeat them.
+const AnnotationMarker = "<!-- gap-map:annotation -->"
+
+// Hunks worth an inline pointer: nearly all AI, in the work type where a
+// wrong line costs most.
+const (
+	AnnotateMinAIPct   = 90.0
+	AnnotateWorkType   = "core_logic"
+	DefaultAnnotations = 10
+)
+
+// Annotation is an inline review comment on a range of a PR's lines.
+type Annotation struct {
+	Path      string // relative to the repository root
+	StartLine int
+	EndLine   int
+	Body      string
+}
+
+// SelectAnnotations picks the hunks reviewers should look at first: those
+// in core_logic files that are at least 90% AI-written, largest first,
+// at most max of them.
+func SelectAnnotations(hunks []report.Hunk, max int) []Annotation {
+	var picked []report.Hunk
+	for _, h := range hunks {
+		if h.WorkType == AnnotateWorkType && h.AILines > 0 && h.AIPct() >= AnnotateMinAIPct {
+			picked = append(picked, h)
+		}
+	}
+	sort.SliceStable(picked, func(i, j int) bool {
+		if picked[i].AILines != picked[j].AILines {
+			return picked[i].AILines > picked[j].AILines
+		}
+		if picked[i].RepoPath != picked[j].RepoPath {
+			return picked[i].RepoPath < picked[j].RepoPath
+		}
+		return picked[i].StartLine < picked[j].StartLine
+	})
+	if max > 0 && len(picked) > max {
+		picked = picked[:max]
+	}
+
+	anns := make([]Annotation, 0, len(picked))
+	for _, h := range picked {
+		lines := fmt.Sprintf("Line %d is", h.StartLine)
+		if h.EndLine > h.StartLine {
+			lines = fmt.Sprintf("Lines %d-%d are", h.StartLine, h.EndLine)
+		}
+		anns = append(anns, Annotation{
+			Path:      h.RepoPath,
+			StartLine: h.StartLine,
+			EndLine:   h.EndLine,
+			Body: fmt.Sprintf("%s\n**gap-map:** %s %.0f%% AI-written (%d of %d lines) core logic. Worth a careful review.",
+				AnnotationMarker, lines, h.AIPct(), h.AILines, h.Lines),
+		})
+	}
+	return anns
+}
+
+// PostAnnotations posts anns as one review on a PR at commit, leaving out
+// any a previous run already posted on the same lines. It returns how many
+// it posted.
+func (c *Client) PostAnnotations(owner, repo string, prNumber int, commit string, anns []Annotation) (int, error) {
+	url := fmt.Sprintf("%s/repos/%s/%s/pulls/%d/comments?per_page=100", apiBaseURL, owner, repo, prNumber)
+	var existing []struct {
+		Path string `json:"path"`
+		Line int    `json:"line"`
+		Body string `json:"body"`
+	}
+	if err := c.doJSON("GET", url, nil, http.StatusOK, &existing); err != nil {
+		return 0, fmt.Errorf("list review comments: %w", err)
+	}
+	posted := make(map[string]bool)
+	for _, c := range existing {
+		if strings.Contains(c.Body, AnnotationMarker) {
+			posted[fmt.Sprintf("%s:%d", c.Path, c.Line)] = true
+		}
+	}
+
+	type reviewComment struct {
+		Path      string `json:"path"`
+		Line      int    `json:"line"`
+		Side      string `json:"side"`
+		StartLine int    `json:"start_line,omitempty"`
+		StartSide string `json:"start_side,omitempty"`
+		Body      string `json:"body"`
+	}
+	var comments []reviewComment
+	for _, a := range anns {
+		if posted[fmt.Sprintf("%s:%d", a.Path, a.EndLine)] {
+			continue
+		}
+		c := reviewComment{Path: a.Path, Line: a.EndLine, Side: "RIGHT", Body: a.Body}
+		if a.StartLine < a.EndLine {
+			c.StartLine, c.StartSide = a.StartLine, "RIGHT"
+		}
+		comments = append(comments, c)
+	}
+	if len(comments) == 0 {
+		return 0, nil
+	}
+
+	url = fmt.Sprintf("%s/repos/%s/%s/pulls/%d/reviews", apiBaseURL, owner, repo, prNumber)
+	payload := map[string]any{
+		"commit_id": commit,
+		"event":     "COMMENT",
+		"comments":  comments,
+	}
+	if err := c.doJSON("POST", url, payload, http.StatusOK, nil); err != nil {
+		return 0, fmt.Errorf("post review: %w", err)
+	}
+	return len(comments), nil
+}
@@ -0,0 +1,105 @@
+package github
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/anthropic/gap-map/internal/report"
+)
+
+func TestSelectAnnotations(t *testing.T) {
+	hunks := []report.Hunk{
+		{RepoPath: "a.go", WorkType: "core_logic", StartLine: 1, EndLine: 10, Lines: 10, AILines: 10},
+		{RepoPath: "a.go", WorkType: "core_logic", StartLine: 20, EndLine: 39, Lines: 20, AILines: 18}, // 90%
+		{RepoPath: "b.go", WorkType: "core_logic", StartLine: 5, EndLine: 9, Lines: 5, AILines: 4},     // 80%
+		{RepoPath: "b_test.go", WorkType: "test", StartLine: 1, EndLine: 50, Lines: 50, AILines: 50},
+		{RepoPath: "c.go", WorkType: "core_logic", StartLine: 7, EndLine: 7, Lines: 1, AILines: 1},
+	}
+
+	anns := SelectAnnotations(hunks, 0)
+	var got []string
+	for _, a := range anns {
+		got = append(got, fmt.Sprintf("%s:%d-%d", a.Path, a.StartLine, a.EndLine))
+	}
+	want := "a.go:20-39 a.go:1-10 c.go:7-7"
+	if strings.Join(got, " ") != want {
+		t.Errorf("SelectAnnotations = %v, want %s", got, want)
+	}
+	if !strings.Contains(anns[0].Body, AnnotationMarker) || !strings.Contains(anns[0].Body, "Lines 20-39 are 90% AI-written (18 of 20 lines)") {
+		t.Errorf("body = %q", anns[0].Body)
+	}
+	if !strings.Contains(anns[2].Body, "Line 7 is 100% AI-written") {
+		t.Errorf("single-line body = %q", anns[2].Body)
+	}
+
+	if anns := SelectAnnotations(hunks, 1); len(anns) != 1 || anns[0].StartLine != 20 {
+		t.Errorf("capped SelectAnnotations = %+v, want the largest hunk only", anns)
+	}
+}
+
+func TestPostAnnotations_SkipsPosted(t *testing.T) {
+	var review struct {
+		CommitID string `json:"commit_id"`
+		Event    string `json:"event"`
+		Comments []struct {
+			Path      string `json:"path"`
+			Line      int    `json:"line"`
+			StartLine int    `json:"start_line"`
+			Side      string `json:"side"`
+		} `json:"comments"`
+	}
+	withAPI(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "GET" && r.URL.Path == "/repos/o/r/pulls/7/comments":
+			fmt.Fprintf(w, `[{"path":"a.go","line":10,"body":%q},{"path":"b.go","line":3,"body":"a human comment"}]`,
+				AnnotationMarker+"\nold")
+		case r.Method == "POST" && r.URL.Path == "/repos/o/r/pulls/7/reviews":
+			if err := json.NewDecoder(r.Body).Decode(&review); err != nil {
+				t.Errorf("decode review: %v", err)
+			}
+			w.WriteHeader(http.StatusOK)
+			fmt.Fprint(w, `{}`)
+		default:
+			t.Errorf("unexpected %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	anns := []Annotation{
+		{Path: "a.go", StartLine: 1, EndLine: 10, Body: "x"},
+		{Path: "b.go", StartLine: 1, EndLine: 3, Body: "y"},
+		{Path: "c.go", StartLine: 4, EndLine: 4, Body: "z"},
+	}
+	n, err := testClient("tok").PostAnnotations("o", "r", 7, "abc123", anns)
+	if err != nil {
+		t.Fatalf("PostAnnotations: %v", err)
+	}
+	if n != 2 {
+		t.Errorf("posted %d, want 2", n)
+	}
+	if review.CommitID != "abc123" || review.Event != "COMMENT" || len(review.Comments) != 2 {
+		t.Fatalf("review = %+v", review)
+	}
+	if c := review.Comments[0]; c.Path != "b.go" || c.StartLine != 1 || c.Line != 3 || c.Side != "RIGHT" {
+		t.Errorf("range comment = %+v", c)
+	}
+	if c := review.Comments[1]; c.Path != "c.go" || c.StartLine != 0 || c.Line != 4 {
+		t.Errorf("single-line comment = %+v", c)
+	}
+}
+
+func TestPostAnnotations_AllPosted(t *testing.T) {
+	withAPI(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" {
+			t.Errorf("unexpected %s %s", r.Method, r.URL.Path)
+		}
+		fmt.Fprintf(w, `[{"path":"a.go","line":10,"body":%q}]`, AnnotationMarker)
+	})
+	n, err := testClient("tok").PostAnnotations("o", "r", 7, "abc", []Annotation{{Path: "a.go", StartLine: 1, EndLine: 10}})
+	if err != nil || n != 0 {
+		t.Errorf("PostAnnotations = %d, %v; want 0, nil", n, err)
+	}
+}
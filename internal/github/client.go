@@ -0,0 +1,210 @@
+package github
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/anthropic/gap-map/internal/config"
+)
+
+// ClientOptions controls how a Client's GitHub API calls time out and retry.
+type ClientOptions struct {
+	// Timeout bounds each attempt, including reading the response; 0
+	// means no limit.
+	Timeout time.Duration
+	// MaxRetries is how many times a call is retried after a network
+	// error, a 5xx response or a rate limit; 0 disables retries. POSTs
+	// are only retried when they cannot have taken effect (see send).
+	MaxRetries int
+	// MaxWait is the longest a call waits before one retry. A rate limit
+	// that resets later than that fails the call instead.
+	MaxWait time.Duration
+}
+
+// DefaultClientOptions are the ClientOptions of github_timeout and
+// github_max_retries left at their defaults.
+var DefaultClientOptions = ClientOptions{
+	Timeout:    30 * time.Second,
+	MaxRetries: 4,
+	MaxWait:    5 * time.Minute,
+}
+
+// ConfigClientOptions returns the ClientOptions cfg's github_timeout and
+// github_max_retries set. Invalid settings keep the defaults; Validate
+// reports them.
+func ConfigClientOptions(cfg *config.Config) ClientOptions {
+	opts := DefaultClientOptions
+	if d, err := time.ParseDuration(cfg.GitHubTimeout); err == nil {
+		opts.Timeout = d
+	}
+	if cfg.GitHubMaxRetries >= 0 {
+		opts.MaxRetries = cfg.GitHubMaxRetries
+	}
+	return opts
+}
+
+// Client makes GitHub API calls with a token, timing out and retrying
+// them as its ClientOptions say.
+type Client struct {
+	token string
+	opts  ClientOptions
+}
+
+// NewClient returns a Client authenticating with token; an empty token
+// makes unauthenticated calls.
+func NewClient(token string, opts ClientOptions) *Client {
+	return &Client{token: token, opts: opts}
+}
+
+// retryBase is the first backoff delay, doubled after each retry.
+// Overridden in tests.
+var retryBase = time.Second
+
+// sleep waits for d or until ctx is done. Overridden in tests.
+var sleep = func(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// send performs req, retrying transient failures: network errors, 500,
+// 502, 503 and 504 responses with exponential backoff, and primary (429,
+// or 403 with no requests remaining) and secondary rate limits after the
+// wait GitHub asks for in Retry-After or X-RateLimit-Reset. The last
+// response is returned as it is, for the caller to report.
+//
+// GitHub often creates a comment, check run or review before a POST
+// fails with a 5xx or times out, and sending it again would create a
+// duplicate. POSTs are therefore retried only after rate limits and
+// after errors raised before the request was sent, such as a refused
+// connection.
+func (c *Client) send(req *http.Request) (*http.Response, error) {
+	client := &http.Client{Timeout: c.opts.Timeout}
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = body
+		}
+
+		resp, err := client.Do(req)
+		if attempt >= c.opts.MaxRetries {
+			return resp, err
+		}
+		wait, retry := retryWait(req.Method, resp, err, attempt)
+		if !retry || (c.opts.MaxWait > 0 && wait > c.opts.MaxWait) {
+			return resp, err
+		}
+		if resp != nil {
+			_, _ = io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		reason := "network error"
+		if resp != nil {
+			reason = resp.Status
+		}
+		slog.Debug("retrying GitHub API call", "method", req.Method, "url", req.URL.Path, "reason", reason, "wait", wait)
+		if err := sleep(req.Context(), wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// retryWait reports whether the outcome of an attempt with method is
+// worth retrying and how long to wait first.
+func retryWait(method string, resp *http.Response, err error, attempt int) (time.Duration, bool) {
+	if err != nil {
+		if errors.Is(err, context.Canceled) || (method == http.MethodPost && !notSent(err)) {
+			return 0, false
+		}
+		return backoff(attempt), true
+	}
+
+	switch resp.StatusCode {
+	case http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		if method == http.MethodPost {
+			return 0, false
+		}
+		if wait, ok := retryAfter(resp); ok {
+			return wait, true
+		}
+		return backoff(attempt), true
+	case http.StatusTooManyRequests, http.StatusForbidden:
+		if !rateLimited(resp) {
+			return 0, false
+		}
+		if wait, ok := retryAfter(resp); ok {
+			return wait, true
+		}
+		if resp.Header.Get("X-RateLimit-Remaining") == "0" {
+			if reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+				return max(time.Until(time.Unix(reset, 0))+time.Second, 0), true
+			}
+		}
+		// A secondary rate limit without a hint: GitHub asks for at
+		// least a minute.
+		return max(backoff(attempt), time.Minute), true
+	}
+	return 0, false
+}
+
+// notSent reports whether err was raised before the request reached the
+// server: the host did not resolve or the connection was not made.
+func notSent(err error) bool {
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return true
+	}
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// rateLimited reports whether a 403 or 429 response is a rate limit
+// rather than a permission error. It peeks at the body and leaves it
+// readable.
+func rateLimited(resp *http.Response) bool {
+	if resp.StatusCode == http.StatusTooManyRequests ||
+		resp.Header.Get("Retry-After") != "" ||
+		resp.Header.Get("X-RateLimit-Remaining") == "0" {
+		return true
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	return strings.Contains(strings.ToLower(string(body)), "rate limit")
+}
+
+// retryAfter parses a Retry-After header given in seconds.
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	secs, err := strconv.Atoi(strings.TrimSpace(resp.Header.Get("Retry-After")))
+	if err != nil || secs < 0 {
+		return 0, false
+	}
+	return time.Duration(secs) * time.Second, true
+}
+
+// backoff returns the delay before retry attempt+1: retryBase doubled per
+// attempt with up to 50% jitter, capped at 30s.
+func backoff(attempt int) time.Duration {
+	d := retryBase << attempt
+	if d > 30*time.Second || d <= 0 {
+		d = 30 * time.Second
+	}
+	return d + rand.N(d/2+1)
+}
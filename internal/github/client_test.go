@@ -0,0 +1,165 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// recordSleeps replaces sleep with one that records the waits and returns
+// at once.
+func recordSleeps(t *testing.T) *[]time.Duration {
+	t.Helper()
+	var waits []time.Duration
+	orig, origBase := sleep, retryBase
+	sleep = func(ctx context.Context, d time.Duration) error {
+		waits = append(waits, d)
+		return nil
+	}
+	retryBase = time.Millisecond
+	t.Cleanup(func() { sleep, retryBase = orig, origBase })
+	return &waits
+}
+
+func TestSend_Retries(t *testing.T) {
+	waits := recordSleeps(t)
+	calls := 0
+	var bodies []string
+	withAPI(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		b, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(b))
+		switch calls {
+		case 1:
+			w.Header().Set("Retry-After", "7")
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `{"message": "You have exceeded a secondary rate limit."}`)
+		case 2:
+			w.Header().Set("X-RateLimit-Remaining", "0")
+			w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(30*time.Second).Unix(), 10))
+			w.WriteHeader(http.StatusForbidden)
+		default:
+			w.WriteHeader(http.StatusCreated)
+		}
+	})
+
+	if err := testClient("tok").PostComment("acme", "widgets", 1, "hello"); err != nil {
+		t.Fatalf("PostComment: %v", err)
+	}
+	if calls != 3 {
+		t.Fatalf("calls = %d, want 3", calls)
+	}
+	// Every retry resends the body.
+	for i, b := range bodies {
+		if !strings.Contains(b, "hello") {
+			t.Errorf("attempt %d body = %q", i+1, b)
+		}
+	}
+	w := *waits
+	if len(w) != 2 {
+		t.Fatalf("waits = %v, want 2", w)
+	}
+	if w[0] != 7*time.Second {
+		t.Errorf("Retry-After wait = %v, want 7s", w[0])
+	}
+	if w[1] < 25*time.Second || w[1] > 32*time.Second {
+		t.Errorf("rate limit reset wait = %v, want about 31s", w[1])
+	}
+
+	// A GET is retried after a 5xx.
+	*waits = nil
+	calls = 0
+	withAPI(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		fmt.Fprint(w, `{"tag_name": "v1.0.0"}`)
+	})
+	if _, err := testClient("tok").FetchRelease("acme", "widgets", ""); err != nil || calls != 2 {
+		t.Fatalf("FetchRelease: err = %v after %d calls, want success after 2", err, calls)
+	}
+	if w := *waits; len(w) != 1 || w[0] > 10*time.Millisecond {
+		t.Errorf("502 backoff = %v, want about retryBase", w)
+	}
+}
+
+func TestSend_PostNotResent(t *testing.T) {
+	recordSleeps(t)
+
+	// GitHub may have created the comment before answering 502.
+	calls := 0
+	withAPI(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusBadGateway)
+	})
+	if err := testClient("tok").PostComment("acme", "widgets", 1, "hello"); err == nil || calls != 1 {
+		t.Errorf("PostComment: err = %v after %d calls, want the 502 after 1", err, calls)
+	}
+
+	// A connection that was never made is safe to retry.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+	req, _ := http.NewRequest(http.MethodPost, "http://"+addr+"/repos/acme/widgets/issues/1/comments", strings.NewReader("{}"))
+	_, err = http.DefaultClient.Do(req)
+	if err == nil {
+		t.Skip("nothing refused the connection")
+	}
+	if _, retry := retryWait(http.MethodPost, nil, err, 0); !retry {
+		t.Errorf("refused dial %v: not retried", err)
+	}
+}
+
+func TestSend_GivesUp(t *testing.T) {
+	recordSleeps(t)
+
+	t.Run("permission errors are not retried", func(t *testing.T) {
+		calls := 0
+		withAPI(t, func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `{"message": "Resource not accessible by integration"}`)
+		})
+		err := testClient("tok").PostComment("acme", "widgets", 1, "hello")
+		if err == nil || !strings.Contains(err.Error(), "not accessible") || calls != 1 {
+			t.Errorf("err = %v after %d calls, want the 403 after 1", err, calls)
+		}
+	})
+
+	t.Run("retries are bounded", func(t *testing.T) {
+		client := NewClient("tok", ClientOptions{MaxRetries: 2})
+		calls := 0
+		withAPI(t, func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			w.WriteHeader(http.StatusServiceUnavailable)
+		})
+		if _, err := client.FetchRelease("acme", "widgets", ""); err == nil || calls != 3 {
+			t.Errorf("err = %v after %d calls, want an error after 3", err, calls)
+		}
+	})
+
+	t.Run("a far-off reset fails at once", func(t *testing.T) {
+		client := NewClient("tok", ClientOptions{MaxRetries: 4, MaxWait: time.Minute})
+		calls := 0
+		withAPI(t, func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			w.Header().Set("X-RateLimit-Remaining", "0")
+			w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10))
+			w.WriteHeader(http.StatusForbidden)
+		})
+		if err := client.PostComment("acme", "widgets", 1, "hello"); err == nil || calls != 1 {
+			t.Errorf("err = %v after %d calls, want an error after 1", err, calls)
+		}
+	})
+}
@@ -0,0 +1,154 @@
+package github
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/anthropic/gap-map/internal/report"
+)
+
+// NotableFiles selects the files a PR comment lists as notable, so the
+// list can follow a team's review culture: only large changes, only
+// mostly-AI files, or a short list per work type.
+type NotableFiles struct {
+	MinEvents int     // attributed events a file needs
+	MinLines  int     // changed lines a file needs
+	MinAIPct  float64 // meaningful AI% a file needs
+	Max       int     // files listed, per work type with ByWorkType; 0 lists none
+
+	// Sort orders the files: report.SortAIPct (the default),
+	// report.SortLines or report.SortEvents, largest first.
+	Sort string
+
+	// ByWorkType lists the files in a table per work type instead of
+	// one table.
+	ByWorkType bool
+}
+
+// DefaultNotableFiles lists the five files with the highest AI% among
+// those with at least three attributed events.
+var DefaultNotableFiles = NotableFiles{MinEvents: 3, Max: 5, Sort: report.SortAIPct}
+
+// workTypeOrder is the order PR comments list work types in.
+var workTypeOrder = []string{"architecture", "core_logic", "bug_fix", "edge_case", "database_migration", "boilerplate", "test_scaffolding", "documentation"}
+
+// Validate reports whether n's thresholds and sort are usable.
+func (n NotableFiles) Validate() error {
+	if n.MinEvents < 0 || n.MinLines < 0 || n.Max < 0 {
+		return fmt.Errorf("notable file minimums and maximum must not be negative")
+	}
+	if n.MinAIPct < 0 || n.MinAIPct > 100 {
+		return fmt.Errorf("notable file minimum AI%% must be between 0 and 100")
+	}
+	return report.Filter{Sort: n.Sort}.Validate()
+}
+
+// selects reports whether f passes n's thresholds.
+func (n NotableFiles) selects(f report.FileReport) bool {
+	return f.TotalEvents >= n.MinEvents && f.TotalLines >= n.MinLines && f.MeaningfulAIPct >= n.MinAIPct
+}
+
+// files returns the files of pr that pass n's thresholds, in n's order.
+func (n NotableFiles) files(pr *report.ProjectReport) []report.FileReport {
+	var files []report.FileReport
+	for _, f := range pr.Files {
+		if n.selects(f) {
+			files = append(files, f)
+		}
+	}
+	key := func(f report.FileReport) float64 { return f.MeaningfulAIPct }
+	switch n.Sort {
+	case report.SortLines:
+		key = func(f report.FileReport) float64 { return float64(f.TotalLines) }
+	case report.SortEvents:
+		key = func(f report.FileReport) float64 { return float64(f.TotalEvents) }
+	}
+	// Stable, so files tied on the key keep the report's order.
+	sort.SliceStable(files, func(i, j int) bool { return key(files[i]) > key(files[j]) })
+	return files
+}
+
+// writeNotableFiles writes the Notable Files section of pr's comment: one
+// table of at most n.Max files, or with n.ByWorkType a table of at most
+// n.Max files for each work type that has any. Nothing is written when no
+// file qualifies.
+func writeNotableFiles(b *strings.Builder, pr *report.ProjectReport, n NotableFiles) {
+	files := n.files(pr)
+	if n.Max <= 0 || len(files) == 0 {
+		return
+	}
+	b.WriteString("### Notable Files\n\n")
+
+	if !n.ByWorkType {
+		b.WriteString("| File | Work Type | AI% | Collaboration Pattern |\n")
+		b.WriteString("|------|-----------|----:|----------------------|\n")
+		for _, f := range files[:min(n.Max, len(files))] {
+			b.WriteString(fmt.Sprintf("| `%s` | %s | %.1f%% | %s |\n",
+				notableName(f.FilePath), f.WorkType, f.MeaningfulAIPct, collaborationPattern(f)))
+		}
+		b.WriteString("\n")
+		return
+	}
+
+	byType := make(map[string][]report.FileReport)
+	for _, f := range files {
+		byType[f.WorkType] = append(byType[f.WorkType], f)
+	}
+	order := append([]string(nil), workTypeOrder...)
+	var others []string
+	for wt := range byType {
+		if !containsString(workTypeOrder, wt) {
+			others = append(others, wt)
+		}
+	}
+	sort.Strings(others)
+	for _, wt := range append(order, others...) {
+		list := byType[wt]
+		if len(list) == 0 {
+			continue
+		}
+		b.WriteString(fmt.Sprintf("#### %s\n\n", wt))
+		b.WriteString("| File | AI% | Lines | Collaboration Pattern |\n")
+		b.WriteString("|------|----:|------:|----------------------|\n")
+		for _, f := range list[:min(n.Max, len(list))] {
+			b.WriteString(fmt.Sprintf("| `%s` | %.1f%% | %d | %s |\n",
+				notableName(f.FilePath), f.MeaningfulAIPct, f.TotalLines, collaborationPattern(f)))
+		}
+		if more := len(list) - n.Max; more > 0 {
+			b.WriteString(fmt.Sprintf("\n_%d more %s files not listed._\n", more, wt))
+		}
+		b.WriteString("\n")
+	}
+}
+
+// collaborationPattern returns the authorship level most of f's events
+// have.
+func collaborationPattern(f report.FileReport) string {
+	topLevel := ""
+	topCount := 0
+	for level, count := range f.AuthorshipCounts {
+		if count > topCount {
+			topLevel = level
+			topCount = count
+		}
+	}
+	return topLevel
+}
+
+// notableName shortens long file paths from the left.
+func notableName(path string) string {
+	if len(path) > 50 {
+		return "..." + path[len(path)-47:]
+	}
+	return path
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,64 @@
+package github
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/anthropic/gap-map/internal/report"
+)
+
+func TestNotableFiles(t *testing.T) {
+	file := func(path, wt string, pct float64, lines, events int) report.FileReport {
+		return report.FileReport{FilePath: path, WorkType: wt, MeaningfulAIPct: pct, TotalLines: lines,
+			TotalEvents: events, AuthorshipCounts: map[string]int{"mostly_ai": events}}
+	}
+	pr := &report.ProjectReport{
+		ByAuthorship: map[string]int{"mostly_ai": 4},
+		ByWorkType:   map[string]report.WorkTypeSummary{},
+		Files: []report.FileReport{
+			file("api.go", "core_logic", 95, 40, 4),
+			file("big.go", "core_logic", 60, 400, 5),
+			file("small.go", "core_logic", 90, 3, 3),
+			file("gen.go", "boilerplate", 100, 200, 6),
+		},
+	}
+
+	p := CommentPolicy{Notable: NotableFiles{MinEvents: 3, MinLines: 10, MinAIPct: 50, Max: 2, Sort: report.SortLines}}
+	body := p.Comment(pr)
+	notable := body[strings.Index(body, "### Notable Files"):]
+	if strings.Contains(notable, "small.go") {
+		t.Errorf("file under the line minimum listed:\n%s", notable)
+	}
+	if strings.Contains(notable, "api.go") || !strings.Contains(notable, "big.go") {
+		t.Errorf("want the two largest files only:\n%s", notable)
+	}
+	if strings.Index(notable, "big.go") > strings.Index(notable, "gen.go") {
+		t.Errorf("files not ordered by lines:\n%s", notable)
+	}
+
+	p.Notable.ByWorkType = true
+	p.Notable.Max = 1
+	body = p.Comment(pr)
+	for _, want := range []string{"#### core_logic", "#### boilerplate", "_1 more core_logic files not listed._"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("per-work-type comment missing %q:\n%s", want, body)
+		}
+	}
+	if strings.Index(body, "#### core_logic") > strings.Index(body, "#### boilerplate") {
+		t.Errorf("work types out of order:\n%s", body)
+	}
+
+	p.Notable.Max = 0
+	if body := p.Comment(pr); strings.Contains(body, "### Notable Files") {
+		t.Errorf("Max 0 listed notable files:\n%s", body)
+	}
+
+	for _, bad := range []NotableFiles{{Max: -1}, {MinAIPct: 101}, {Sort: "name"}} {
+		if bad.Validate() == nil {
+			t.Errorf("Validate(%+v) = nil, want error", bad)
+		}
+	}
+	if err := DefaultNotableFiles.Validate(); err != nil {
+		t.Errorf("Validate(DefaultNotableFiles) = %v", err)
+	}
+}
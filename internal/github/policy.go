@@ -0,0 +1,92 @@
+package github
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/anthropic/gap-map/internal/report"
+)
+
+// CommentPolicy decides whether a PR gets a gap-map comment and how much
+// of it is shown, so small or human-only PRs are not cluttered.
+type CommentPolicy struct {
+	SkipDrafts bool // post nothing on draft PRs
+
+	// MinAIPct posts only when the report's meaningful AI% is at least
+	// this; 0 always posts.
+	MinAIPct float64
+
+	// CollapseOver folds everything below the headline into a <details>
+	// block when the comment is longer than this many bytes; 0 never
+	// folds.
+	CollapseOver int
+
+	// Notable selects the files the comment lists as notable.
+	Notable NotableFiles
+}
+
+// Comment produces the comment body of r, listing the files Notable
+// selects.
+func (p CommentPolicy) Comment(r *report.ProjectReport) string {
+	return generateComment(r, p.Notable)
+}
+
+// SkipReason returns why no comment should be posted for r on a PR that
+// is a draft or not, or "" to post it.
+func (p CommentPolicy) SkipReason(r *report.ProjectReport, draft bool) string {
+	if p.SkipDrafts && draft {
+		return "the PR is a draft"
+	}
+	if p.MinAIPct > 0 && r.MeaningfulAIPct < p.MinAIPct {
+		return fmt.Sprintf("meaningful AI is %.1f%%, below the %.1f%% threshold", r.MeaningfulAIPct, p.MinAIPct)
+	}
+	return ""
+}
+
+// Apply returns body, collapsed if it is longer than CollapseOver: the
+// header and headline stay visible, the tables and file lists fold into
+// a <details> block, and the footer follows it.
+func (p CommentPolicy) Apply(body string) string {
+	if p.CollapseOver <= 0 || len(body) <= p.CollapseOver {
+		return body
+	}
+
+	// The header and headline are the first two paragraphs.
+	cut := 0
+	for i := 0; i < 2; i++ {
+		j := strings.Index(body[cut:], "\n\n")
+		if j < 0 {
+			return body
+		}
+		cut += j + 2
+	}
+	head, rest := body[:cut], body[cut:]
+	footer := ""
+	if j := strings.LastIndex(rest, "---\n"); j >= 0 {
+		rest, footer = rest[:j], rest[j:]
+	}
+	if strings.TrimSpace(rest) == "" {
+		return body
+	}
+
+	var b strings.Builder
+	b.WriteString(head)
+	b.WriteString("<details>\n<summary>Full breakdown</summary>\n\n")
+	b.WriteString(strings.TrimRight(rest, "\n"))
+	b.WriteString("\n\n</details>\n\n")
+	b.WriteString(footer)
+	return b.String()
+}
+
+// IsDraft reports whether a PR is a draft.
+func (c *Client) IsDraft(owner, repo string, prNumber int) (bool, error) {
+	url := fmt.Sprintf("%s/repos/%s/%s/pulls/%d", apiBaseURL, owner, repo, prNumber)
+	var result struct {
+		Draft bool `json:"draft"`
+	}
+	if err := c.doJSON("GET", url, nil, http.StatusOK, &result); err != nil {
+		return false, fmt.Errorf("get pull request: %w", err)
+	}
+	return result.Draft, nil
+}
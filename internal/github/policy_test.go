@@ -0,0 +1,53 @@
+package github
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/anthropic/gap-map/internal/report"
+)
+
+func TestCommentPolicy(t *testing.T) {
+	r := &report.ProjectReport{MeaningfulAIPct: 12.5, TotalFiles: 3}
+
+	for _, tc := range []struct {
+		name   string
+		policy CommentPolicy
+		draft  bool
+		want   string
+	}{
+		{"default posts", CommentPolicy{}, true, ""},
+		{"draft skipped", CommentPolicy{SkipDrafts: true}, true, "the PR is a draft"},
+		{"ready posted", CommentPolicy{SkipDrafts: true}, false, ""},
+		{"below threshold", CommentPolicy{MinAIPct: 20}, false, "meaningful AI is 12.5%, below the 20.0% threshold"},
+		{"at threshold", CommentPolicy{MinAIPct: 12.5}, false, ""},
+	} {
+		if got := tc.policy.SkipReason(r, tc.draft); got != tc.want {
+			t.Errorf("%s: SkipReason = %q, want %q", tc.name, got, tc.want)
+		}
+	}
+
+	body := GenerateComment(r)
+	if got := (CommentPolicy{}).Apply(body); got != body {
+		t.Error("Apply without CollapseOver changed the body")
+	}
+	if got := (CommentPolicy{CollapseOver: len(body)}).Apply(body); got != body {
+		t.Error("Apply folded a body within the limit")
+	}
+
+	folded := CommentPolicy{CollapseOver: 10}.Apply(body)
+	details := strings.Index(folded, "<details>")
+	if details < 0 {
+		t.Fatalf("long body not folded:\n%s", folded)
+	}
+	// The headline stays visible; the tables fold; the footer follows.
+	if h := strings.Index(folded, "Meaningful AI: 12.5%"); h < 0 || h > details {
+		t.Errorf("headline not above the fold:\n%s", folded)
+	}
+	if w := strings.Index(folded, "### Work Type Breakdown"); w < details {
+		t.Errorf("work type table not folded:\n%s", folded)
+	}
+	if f := strings.Index(folded, "_Generated by"); f < strings.Index(folded, "</details>") {
+		t.Errorf("footer folded:\n%s", folded)
+	}
+}
@@ -10,19 +10,25 @@ import (
 	"io"
 	"net/http"
 	"os"
-	"os/exec"
-	"regexp"
 	"sort"
-	"strconv"
 	"strings"
 
 	"github.com/anthropic/gap-map/internal/report"
 )
 
+// maxReviewItems caps the review checklist in a PR comment.
+const maxReviewItems = 10
+
 // GenerateComment produces a Markdown PR comment body from a ProjectReport.
 // The comment is compact and insight-driven: headline metric, work-type
 // breakdown table with callouts, and top notable files.
 func GenerateComment(pr *report.ProjectReport) string {
+	return generateComment(pr, DefaultNotableFiles)
+}
+
+// generateComment produces the comment body of pr, listing the files
+// notable selects.
+func generateComment(pr *report.ProjectReport, notable NotableFiles) string {
 	var b strings.Builder
 
 	// 1. Header.
@@ -37,18 +43,35 @@ func GenerateComment(pr *report.ProjectReport) string {
 	mixed := pr.ByAuthorship["mixed"]
 	mostlyHuman := pr.ByAuthorship["mostly_human"]
 
-	b.WriteString(fmt.Sprintf("**Meaningful AI: %.1f%%** &mdash; ", pr.MeaningfulAIPct))
+	if len(pr.ByPackage) > 0 {
+		// In a monorepo one project-wide number hides which packages the
+		// AI work landed in; lead with the affected packages instead.
+		b.WriteString(fmt.Sprintf("**%d packages affected** &mdash; ", len(pr.ByPackage)))
+	} else {
+		b.WriteString(fmt.Sprintf("**Meaningful AI: %.1f%%** &mdash; ", pr.MeaningfulAIPct))
+	}
 	b.WriteString(fmt.Sprintf("%d mostly AI, %d mixed, %d mostly human",
 		mostlyAI, mixed, mostlyHuman))
 	b.WriteString(fmt.Sprintf(" (%d events across %d files)\n\n", totalEvents, pr.TotalFiles))
 
+	if len(pr.ByPackage) > 0 {
+		b.WriteString("### Packages\n\n")
+		b.WriteString("| Package | Files | Lines | AI% |\n")
+		b.WriteString("|---------|------:|------:|----:|\n")
+		for _, name := range report.SortedPackages(pr.ByPackage) {
+			summary := pr.ByPackage[name]
+			b.WriteString(fmt.Sprintf("| %s | %d | %d | %.1f%% |\n",
+				name, summary.Files, summary.TotalLines, summary.AIPct))
+		}
+		b.WriteString("\n")
+	}
+
 	// 3. Work-type breakdown table.
 	b.WriteString("### Work Type Breakdown\n\n")
 	b.WriteString("| Work Type | Tier | Files | AI% |\n")
 	b.WriteString("|-----------|------|------:|----:|\n")
 
-	wtOrder := []string{"architecture", "core_logic", "bug_fix", "edge_case", "boilerplate", "test_scaffolding"}
-	for _, wt := range wtOrder {
+	for _, wt := range workTypeOrder {
 		summary, ok := pr.ByWorkType[wt]
 		if !ok {
 			continue
@@ -83,45 +106,36 @@ func GenerateComment(pr *report.ProjectReport) string {
 		b.WriteString("\n")
 	}
 
-	// 4. Top 3-5 notable files (by AI% desc, skip files with < 3 events).
-	var notable []report.FileReport
-	for _, f := range pr.Files {
-		if f.TotalEvents >= 3 {
-			notable = append(notable, f)
+	// 4. Notable files, in one table or one per work type.
+	writeNotableFiles(&b, pr, notable)
+
+	// 5. Review checklist, when ApplyReview ran.
+	if len(pr.Review) > 0 {
+		b.WriteString("### Review Checklist\n\n")
+		items := pr.Review
+		if len(items) > maxReviewItems {
+			items = items[:maxReviewItems]
 		}
-	}
-	// Already sorted by AI% desc from report generation.
-	maxNotable := 5
-	if len(notable) < maxNotable {
-		maxNotable = len(notable)
+		for _, item := range items {
+			b.WriteString(fmt.Sprintf("- [ ] `%s` (risk %.0f) &mdash; %s\n",
+				item.FilePath, item.Risk, strings.Join(item.Reasons, ", ")))
+		}
+		if n := len(pr.Review) - len(items); n > 0 {
+			b.WriteString(fmt.Sprintf("\n_%d more files with AI lines not listed._\n", n))
+		}
+		b.WriteString("\n")
 	}
 
-	if maxNotable > 0 {
-		b.WriteString("### Notable Files\n\n")
-		b.WriteString("| File | Work Type | AI% | Collaboration Pattern |\n")
-		b.WriteString("|------|-----------|----:|----------------------|\n")
-
-		for _, f := range notable[:maxNotable] {
-			// Find the authorship level with the highest count.
-			topLevel := ""
-			topCount := 0
-			for level, count := range f.AuthorshipCounts {
-				if count > topCount {
-					topLevel = level
-					topCount = count
-				}
-			}
-			fileName := f.FilePath
-			if len(fileName) > 50 {
-				fileName = "..." + fileName[len(fileName)-47:]
-			}
-			b.WriteString(fmt.Sprintf("| `%s` | %s | %.1f%% | %s |\n",
-				fileName, f.WorkType, f.MeaningfulAIPct, topLevel))
+	// 6. Files the size and binary guards kept out or capped.
+	if len(pr.Excluded) > 0 {
+		b.WriteString(fmt.Sprintf("<details><summary>%d files excluded or capped</summary>\n\n", len(pr.Excluded)))
+		for _, ex := range pr.Excluded {
+			b.WriteString(fmt.Sprintf("- `%s` (%s)\n", ex.FilePath, ex.Describe()))
 		}
-		b.WriteString("\n")
+		b.WriteString("\n</details>\n\n")
 	}
 
-	// 5. Footer.
+	// 7. Footer.
 	b.WriteString("---\n_Generated by [gap-map](https://github.com/anthropic/gap-map)_\n")
 
 	return b.String()
@@ -129,9 +143,9 @@ func GenerateComment(pr *report.ProjectReport) string {
 
 // PostComment posts a comment body to a GitHub PR using the REST API.
 // It sends a POST to https://api.github.com/repos/{owner}/{repo}/issues/{prNumber}/comments
-// with Bearer token authentication. Returns an error with status code on failure.
-func PostComment(owner, repo string, prNumber int, body string, token string) error {
-	url := fmt.Sprintf("https://api.github.com/repos/%s/%s/issues/%d/comments", owner, repo, prNumber)
+// with the client's Bearer token. Returns an error with status code on failure.
+func (c *Client) PostComment(owner, repo string, prNumber int, body string) error {
+	url := fmt.Sprintf("%s/repos/%s/%s/issues/%d/comments", apiBaseURL, owner, repo, prNumber)
 
 	payload := struct {
 		Body string `json:"body"`
@@ -147,12 +161,12 @@ func PostComment(owner, repo string, prNumber int, body string, token string) er
 		return fmt.Errorf("create request: %w", err)
 	}
 
-	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Authorization", "Bearer "+c.token)
 	req.Header.Set("Accept", "application/vnd.github+json")
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := c.send(req)
 	if err != nil {
 		return fmt.Errorf("post comment: %w", err)
 	}
@@ -166,165 +180,67 @@ func PostComment(owner, repo string, prNumber int, body string, token string) er
 	return nil
 }
 
-// ParseGitHubRemote extracts the owner and repo from a GitHub remote URL.
-// Supports both HTTPS and SSH formats:
-//   - https://github.com/{owner}/{repo}.git (or without .git)
-//   - git@github.com:{owner}/{repo}.git (or without .git)
-func ParseGitHubRemote(remoteURL string) (owner, repo string, err error) {
-	remoteURL = strings.TrimSpace(remoteURL)
-
-	// Try HTTPS format: https://github.com/{owner}/{repo}[.git]
-	httpsRe := regexp.MustCompile(`^https?://github\.com/([^/]+)/([^/]+?)(?:\.git)?$`)
-	if matches := httpsRe.FindStringSubmatch(remoteURL); matches != nil {
-		return matches[1], matches[2], nil
-	}
+// apiBaseURL is the GitHub REST API root. Overridden in tests.
+var apiBaseURL = "https://api.github.com"
 
-	// Try SSH format: git@github.com:{owner}/{repo}[.git]
-	sshRe := regexp.MustCompile(`^git@github\.com:([^/]+)/([^/]+?)(?:\.git)?$`)
-	if matches := sshRe.FindStringSubmatch(remoteURL); matches != nil {
-		return matches[1], matches[2], nil
-	}
-
-	return "", "", fmt.Errorf("unable to parse GitHub remote URL: %q", remoteURL)
+// PRBranches holds the head and base branch names of a pull request.
+type PRBranches struct {
+	Head string
+	Base string
 }
 
-// DetectPRNumber attempts to detect the current PR number.
-// It checks, in order:
-//  1. GITHUB_PR_NUMBER environment variable (for CI environments)
-//  2. `gh pr view --json number` via exec.Command (for local development)
-//
-// Returns an error if neither method works.
-func DetectPRNumber() (int, error) {
-	// Check environment variable first (CI).
-	if envPR := os.Getenv("GITHUB_PR_NUMBER"); envPR != "" {
-		n, err := strconv.Atoi(envPR)
-		if err != nil {
-			return 0, fmt.Errorf("invalid GITHUB_PR_NUMBER %q: %w", envPR, err)
-		}
-		return n, nil
-	}
-
-	// Try gh CLI.
-	out, err := exec.Command("gh", "pr", "view", "--json", "number").Output()
-	if err != nil {
-		return 0, fmt.Errorf("auto-detect PR number failed (set --pr flag or GITHUB_PR_NUMBER env var): %w", err)
-	}
+// FetchPRBranches looks up a PR's head and base branch names via the REST API.
+func (c *Client) FetchPRBranches(owner, repo string, prNumber int) (PRBranches, error) {
+	url := fmt.Sprintf("%s/repos/%s/%s/pulls/%d", apiBaseURL, owner, repo, prNumber)
 
 	var result struct {
-		Number int `json:"number"`
+		Head struct {
+			Ref string `json:"ref"`
+		} `json:"head"`
+		Base struct {
+			Ref string `json:"ref"`
+		} `json:"base"`
 	}
-	if err := json.Unmarshal(out, &result); err != nil {
-		return 0, fmt.Errorf("parse gh pr view output: %w", err)
+	if err := c.doJSON("GET", url, nil, http.StatusOK, &result); err != nil {
+		return PRBranches{}, fmt.Errorf("get pull request: %w", err)
 	}
-
-	if result.Number == 0 {
-		return 0, fmt.Errorf("gh pr view returned PR number 0")
-	}
-
-	return result.Number, nil
+	return PRBranches{Head: result.Head.Ref, Base: result.Base.Ref}, nil
 }
 
-// DetectRemoteURL runs `git remote get-url origin` to get the remote URL.
-func DetectRemoteURL() (string, error) {
-	out, err := exec.Command("git", "remote", "get-url", "origin").Output()
-	if err != nil {
-		return "", fmt.Errorf("get git remote URL: %w", err)
+// DetectPRBranches returns the PR's head and base branch names.
+// It checks, in order:
+//  1. GITHUB_HEAD_REF / GITHUB_BASE_REF environment variables (GitHub Actions
+//     pull_request events)
+//  2. The GitHub API, when owner, repo, and prNumber are known
+//
+// Either field may be empty if it could not be detected.
+func (c *Client) DetectPRBranches(owner, repo string, prNumber int) PRBranches {
+	b := PRBranches{
+		Head: os.Getenv("GITHUB_HEAD_REF"),
+		Base: os.Getenv("GITHUB_BASE_REF"),
 	}
-	return strings.TrimSpace(string(out)), nil
-}
-
-// FormatSurvivalReport formats a SurvivalReport as a terminal-friendly string
-// with ANSI colors. Placed here to avoid circular imports (survival imports store,
-// not report).
-func FormatSurvivalReport(sr *SurvivalReport) string {
-	var b strings.Builder
-
-	bold := "\033[1m"
-	green := "\033[32m"
-	yellow := "\033[33m"
-	red := "\033[31m"
-	reset := "\033[0m"
-
-	colorRate := func(rate float64) string {
-		switch {
-		case rate >= 80:
-			return green
-		case rate >= 50:
-			return yellow
-		default:
-			return red
-		}
+	if b.Head != "" && b.Base != "" {
+		return b
 	}
-
-	b.WriteString(bold + "Gap Map - Code Survival Report" + reset + "\n")
-	b.WriteString(strings.Repeat("=", 40) + "\n\n")
-
-	b.WriteString(fmt.Sprintf("Tracked AI lines: %d\n", sr.TotalTracked))
-	b.WriteString(fmt.Sprintf("Survived:         %d\n", sr.SurvivedCount))
-	b.WriteString(fmt.Sprintf("Survival rate:    %s%s%.1f%%%s\n\n",
-		bold, colorRate(sr.SurvivalRate), sr.SurvivalRate, reset))
-
-	// By authorship level.
-	if len(sr.ByAuthorship) > 0 {
-		b.WriteString(bold + "By Authorship Level" + reset + "\n")
-		b.WriteString(strings.Repeat("-", 50) + "\n")
-		b.WriteString(fmt.Sprintf("%-28s %8s %8s %7s\n", "Level", "Tracked", "Survived", "Rate"))
-		b.WriteString(strings.Repeat("-", 50) + "\n")
-
-		levels := []string{"mostly_ai", "mixed", "mostly_human"}
-		for _, level := range levels {
-			bd, ok := sr.ByAuthorship[level]
-			if !ok {
-				continue
-			}
-			b.WriteString(fmt.Sprintf("%-28s %8d %8d %s%6.1f%%%s\n",
-				level, bd.Tracked, bd.Survived, colorRate(bd.Rate), bd.Rate, reset))
-		}
-		b.WriteString("\n")
+	if owner == "" || repo == "" || prNumber == 0 {
+		return b
 	}
-
-	// By work type.
-	if len(sr.ByWorkType) > 0 {
-		b.WriteString(bold + "By Work Type" + reset + "\n")
-		b.WriteString(strings.Repeat("-", 50) + "\n")
-		b.WriteString(fmt.Sprintf("%-18s %8s %8s %7s\n", "Work Type", "Tracked", "Survived", "Rate"))
-		b.WriteString(strings.Repeat("-", 50) + "\n")
-
-		wtOrder := []string{"architecture", "core_logic", "bug_fix", "edge_case", "boilerplate", "test_scaffolding"}
-		for _, wt := range wtOrder {
-			bd, ok := sr.ByWorkType[wt]
-			if !ok {
-				continue
-			}
-			b.WriteString(fmt.Sprintf("%-18s %8d %8d %s%6.1f%%%s\n",
-				wt, bd.Tracked, bd.Survived, colorRate(bd.Rate), bd.Rate, reset))
-		}
+	fetched, err := c.FetchPRBranches(owner, repo, prNumber)
+	if err != nil {
+		return b
 	}
-
-	return b.String()
-}
-
-// SurvivalReport is defined in the survival package but duplicated here
-// for formatting purposes to avoid circular imports. The survival package
-// is the canonical definition.
-type SurvivalReport struct {
-	TotalTracked  int                          `json:"total_tracked"`
-	SurvivedCount int                          `json:"survived_count"`
-	SurvivalRate  float64                      `json:"survival_rate"`
-	ByAuthorship  map[string]SurvivalBreakdown `json:"by_authorship"`
-	ByWorkType    map[string]SurvivalBreakdown `json:"by_work_type"`
-}
-
-// SurvivalBreakdown holds survival statistics for a category.
-type SurvivalBreakdown struct {
-	Tracked  int     `json:"tracked"`
-	Survived int     `json:"survived"`
-	Rate     float64 `json:"rate"`
+	if b.Head == "" {
+		b.Head = fetched.Head
+	}
+	if b.Base == "" {
+		b.Base = fetched.Base
+	}
+	return b
 }
 
 // sortedWorkTypes returns the canonical work type order for table output.
 func sortedWorkTypes(m map[string]report.WorkTypeSummary) []string {
-	order := []string{"architecture", "core_logic", "bug_fix", "edge_case", "boilerplate", "test_scaffolding"}
+	order := []string{"architecture", "core_logic", "bug_fix", "edge_case", "database_migration", "boilerplate", "test_scaffolding", "documentation"}
 	var result []string
 	for _, wt := range order {
 		if _, ok := m[wt]; ok {
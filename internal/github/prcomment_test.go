@@ -1,9 +1,13 @@
 package github
 
 import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
 
+	"github.com/anthropic/gap-map/internal/metrics"
 	"github.com/anthropic/gap-map/internal/report"
 )
 
@@ -90,6 +94,75 @@ func TestGenerateComment_BasicOutput(t *testing.T) {
 	}
 }
 
+func TestGenerateComment_Packages(t *testing.T) {
+	pr := &report.ProjectReport{
+		MeaningfulAIPct: 40.0,
+		TotalFiles:      3,
+		ByAuthorship:    map[string]int{"mostly_ai": 2, "mostly_human": 1},
+		ByPackage: map[string]report.PackageSummary{
+			"api":              {Files: 2, TotalLines: 120, AILines: 90, AIPct: 75.0},
+			report.OtherPackage: {Files: 1, TotalLines: 300, AILines: 0},
+		},
+	}
+
+	body := GenerateComment(pr)
+	if strings.Contains(body, "Meaningful AI: 40.0%") {
+		t.Error("monorepo comment should lead with packages, not the project-wide number")
+	}
+	for _, check := range []string{"**2 packages affected**", "### Packages", "| api | 2 | 120 | 75.0% |"} {
+		if !strings.Contains(body, check) {
+			t.Errorf("GenerateComment output missing %q\n\nFull output:\n%s", check, body)
+		}
+	}
+	if strings.Index(body, "| api |") > strings.Index(body, "| (other) |") {
+		t.Error("(other) should be listed after named packages")
+	}
+}
+
+func TestGenerateComment_Excluded(t *testing.T) {
+	pr := &report.ProjectReport{
+		MeaningfulAIPct: 40.0,
+		TotalFiles:      1,
+		ByAuthorship:    map[string]int{"mostly_ai": 1},
+		Excluded: []report.Exclusion{
+			{FilePath: "package-lock.json", Reason: metrics.GuardTooLarge, Lines: 48000},
+			{FilePath: "logo.png", Reason: metrics.GuardBinary},
+		},
+	}
+
+	body := GenerateComment(pr)
+	for _, check := range []string{"2 files excluded or capped", "`package-lock.json` (48000 lines, over the size limit)", "`logo.png` (binary)"} {
+		if !strings.Contains(body, check) {
+			t.Errorf("GenerateComment output missing %q\n\nFull output:\n%s", check, body)
+		}
+	}
+}
+
+func TestGenerateComment_Review(t *testing.T) {
+	pr := &report.ProjectReport{
+		MeaningfulAIPct: 80.0,
+		TotalFiles:      12,
+		ByAuthorship:    map[string]int{"mostly_ai": 12},
+	}
+	for i := 0; i < 12; i++ {
+		pr.Review = append(pr.Review, report.ReviewItem{
+			FilePath: fmt.Sprintf("f%02d.go", i),
+			Risk:     float64(90 - i),
+			Reasons:  []string{"90% AI", "core_logic"},
+		})
+	}
+
+	body := GenerateComment(pr)
+	for _, check := range []string{"### Review Checklist", "- [ ] `f00.go` (risk 90) &mdash; 90% AI, core_logic", "_2 more files with AI lines not listed._"} {
+		if !strings.Contains(body, check) {
+			t.Errorf("GenerateComment output missing %q\n\nFull output:\n%s", check, body)
+		}
+	}
+	if strings.Contains(body, "f10.go") {
+		t.Errorf("GenerateComment lists more than %d review items:\n%s", maxReviewItems, body)
+	}
+}
+
 func TestGenerateComment_InsightCallouts(t *testing.T) {
 	pr := &report.ProjectReport{
 		ProjectPath:    "/proj",
@@ -199,37 +272,44 @@ func TestParseGitHubRemote_Invalid(t *testing.T) {
 	}
 }
 
-func TestFormatSurvivalReport_BasicOutput(t *testing.T) {
-	sr := &SurvivalReport{
-		TotalTracked:  100,
-		SurvivedCount: 85,
-		SurvivalRate:  85.0,
-		ByAuthorship: map[string]SurvivalBreakdown{
-			"mostly_ai": {Tracked: 60, Survived: 50, Rate: 83.3},
-			"mixed":     {Tracked: 40, Survived: 35, Rate: 87.5},
-		},
-		ByWorkType: map[string]SurvivalBreakdown{
-			"core_logic":  {Tracked: 70, Survived: 60, Rate: 85.7},
-			"boilerplate": {Tracked: 30, Survived: 25, Rate: 83.3},
-		},
+func TestDetectPRBranches_Env(t *testing.T) {
+	t.Setenv("GITHUB_HEAD_REF", "feature-x")
+	t.Setenv("GITHUB_BASE_REF", "develop")
+
+	b := testClient("").DetectPRBranches("", "", 0)
+	if b.Head != "feature-x" || b.Base != "develop" {
+		t.Errorf("DetectPRBranches = %+v, want feature-x/develop", b)
 	}
+}
 
-	output := FormatSurvivalReport(sr)
+func TestDetectPRBranches_API(t *testing.T) {
+	t.Setenv("GITHUB_HEAD_REF", "")
+	t.Setenv("GITHUB_BASE_REF", "")
 
-	checks := []string{
-		"Code Survival Report",
-		"Tracked AI lines: 100",
-		"Survived:         85",
-		"85.0%",
-		"mostly_ai",
-		"mixed",
-		"core_logic",
-		"boilerplate",
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/acme/widgets/pulls/7" {
+			http.NotFound(w, r)
+			return
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer tok" {
+			t.Errorf("Authorization = %q", got)
+		}
+		fmt.Fprint(w, `{"head":{"ref":"feature-y"},"base":{"ref":"release"}}`)
+	}))
+	defer srv.Close()
+
+	orig := apiBaseURL
+	apiBaseURL = srv.URL
+	defer func() { apiBaseURL = orig }()
+
+	b := testClient("tok").DetectPRBranches("acme", "widgets", 7)
+	if b.Head != "feature-y" || b.Base != "release" {
+		t.Errorf("DetectPRBranches = %+v, want feature-y/release", b)
 	}
 
-	for _, check := range checks {
-		if !strings.Contains(output, check) {
-			t.Errorf("FormatSurvivalReport output missing %q", check)
-		}
+	// Unknown PR: nothing detected, no error surfaced.
+	b = testClient("tok").DetectPRBranches("acme", "widgets", 8)
+	if b.Head != "" || b.Base != "" {
+		t.Errorf("DetectPRBranches for missing PR = %+v, want empty", b)
 	}
 }
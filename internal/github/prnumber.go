@@ -0,0 +1,222 @@
+package github
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// PRDetection is the PR number DetectPRNumber found and how.
+type PRDetection struct {
+	Number int
+	Source string   // the source that supplied Number
+	Tried  []string // the sources before it and why each did not apply
+}
+
+// String explains the detection, e.g. for a --verbose flag.
+func (d PRDetection) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "PR #%d from %s", d.Number, d.Source)
+	if len(d.Tried) > 0 {
+		fmt.Fprintf(&b, " (skipped: %s)", strings.Join(d.Tried, "; "))
+	}
+	return b.String()
+}
+
+// prSource is one place a PR number can come from. detect returns 0 and
+// why it did not apply when it has no number, and an error when it has
+// an invalid one.
+type prSource struct {
+	name   string
+	detect func(c *Client, owner, repo string) (n int, skip string, err error)
+}
+
+// prSources are the PR number sources in the order DetectPRNumber tries
+// them: explicit configuration first, then each CI system's own
+// variables, then lookups by branch.
+var prSources = []prSource{
+	{"GITHUB_PR_NUMBER", envNumber("GITHUB_PR_NUMBER")},
+	{"GitHub Actions event", githubActionsPR},
+	{"CIRCLE_PR_NUMBER", envNumber("CIRCLE_PR_NUMBER")},
+	{"CIRCLE_PULL_REQUEST", circlePullRequest},
+	{"BUILDKITE_PULL_REQUEST", buildkitePullRequest},
+	{"CHANGE_ID (Jenkins)", envNumber("CHANGE_ID")},
+	{"ghprbPullId (Jenkins)", envNumber("ghprbPullId")},
+	{"CI branch via GitHub API", ciBranchPR},
+	{"gh pr view", ghPRView},
+}
+
+// DetectPRNumber finds the number of the PR being built or checked out.
+// It tries, in order:
+//  1. GITHUB_PR_NUMBER, which any CI can set explicitly
+//  2. GitHub Actions: the pull_request event payload, then a GITHUB_REF
+//     of refs/pull/<n>/merge
+//  3. CircleCI: CIRCLE_PR_NUMBER (fork PRs), then the CIRCLE_PULL_REQUEST URL
+//  4. Buildkite: BUILDKITE_PULL_REQUEST
+//  5. Jenkins: CHANGE_ID (multibranch pipelines), then ghprbPullId (the
+//     GitHub pull request builder plugin)
+//  6. The open PR whose head is the CI's branch (CIRCLE_BRANCH,
+//     BUILDKITE_BRANCH, BRANCH_NAME or GIT_BRANCH), looked up through the
+//     GitHub API; needs owner and repo
+//  7. `gh pr view` for the checked-out branch (local development)
+//
+// The first source with a number wins. A source holding an invalid value
+// is an error rather than skipped.
+func (c *Client) DetectPRNumber(owner, repo string) (PRDetection, error) {
+	var d PRDetection
+	for _, src := range prSources {
+		n, skip, err := src.detect(c, owner, repo)
+		if err != nil {
+			return d, fmt.Errorf("%s: %w", src.name, err)
+		}
+		if n > 0 {
+			d.Number, d.Source = n, src.name
+			return d, nil
+		}
+		d.Tried = append(d.Tried, src.name+": "+skip)
+	}
+	return d, fmt.Errorf("no PR number found (set --pr or GITHUB_PR_NUMBER); tried %s", strings.Join(d.Tried, "; "))
+}
+
+// envNumber returns a source reading a PR number from an env variable.
+func envNumber(name string) func(c *Client, owner, repo string) (int, string, error) {
+	return func(c *Client, owner, repo string) (int, string, error) {
+		v := os.Getenv(name)
+		if v == "" {
+			return 0, "not set", nil
+		}
+		return parsePRNumber(v)
+	}
+}
+
+func parsePRNumber(v string) (int, string, error) {
+	n, err := strconv.Atoi(strings.TrimSpace(v))
+	if err != nil || n <= 0 {
+		return 0, "", fmt.Errorf("invalid PR number %q", v)
+	}
+	return n, "", nil
+}
+
+// pullRefRe matches the ref GitHub Actions checks out for a PR.
+var pullRefRe = regexp.MustCompile(`^refs/pull/(\d+)/(?:merge|head)$`)
+
+// githubActionsPR reads the PR number from a GitHub Actions run's event
+// payload or ref.
+func githubActionsPR(c *Client, owner, repo string) (int, string, error) {
+	if os.Getenv("GITHUB_ACTIONS") != "true" {
+		return 0, "not GitHub Actions", nil
+	}
+	if path := os.Getenv("GITHUB_EVENT_PATH"); path != "" && strings.HasPrefix(os.Getenv("GITHUB_EVENT_NAME"), "pull_request") {
+		if data, err := os.ReadFile(path); err == nil {
+			var event struct {
+				PullRequest struct {
+					Number int `json:"number"`
+				} `json:"pull_request"`
+			}
+			if json.Unmarshal(data, &event) == nil && event.PullRequest.Number > 0 {
+				return event.PullRequest.Number, "", nil
+			}
+		}
+	}
+	if m := pullRefRe.FindStringSubmatch(os.Getenv("GITHUB_REF")); m != nil {
+		return parsePRNumber(m[1])
+	}
+	return 0, "not a pull_request run", nil
+}
+
+// circlePullRequest reads the number from CircleCI's PR URL, e.g.
+// https://github.com/acme/widgets/pull/42.
+func circlePullRequest(c *Client, owner, repo string) (int, string, error) {
+	v := os.Getenv("CIRCLE_PULL_REQUEST")
+	if v == "" {
+		return 0, "not set", nil
+	}
+	i := strings.LastIndex(v, "/pull/")
+	if i < 0 {
+		return 0, "", fmt.Errorf("unexpected PR URL %q", v)
+	}
+	return parsePRNumber(v[i+len("/pull/"):])
+}
+
+// buildkitePullRequest reads BUILDKITE_PULL_REQUEST, which is "false"
+// for branch builds.
+func buildkitePullRequest(c *Client, owner, repo string) (int, string, error) {
+	v := os.Getenv("BUILDKITE_PULL_REQUEST")
+	switch v {
+	case "":
+		return 0, "not set", nil
+	case "false":
+		return 0, "not a PR build", nil
+	}
+	return parsePRNumber(v)
+}
+
+// ciBranchEnv are the variables CI systems name the built branch in.
+var ciBranchEnv = []string{"CIRCLE_BRANCH", "BUILDKITE_BRANCH", "BRANCH_NAME", "GIT_BRANCH"}
+
+// ciBranchPR looks up the open PR whose head is the branch being built.
+func ciBranchPR(c *Client, owner, repo string) (int, string, error) {
+	var branch string
+	for _, name := range ciBranchEnv {
+		if v := os.Getenv(name); v != "" {
+			// Jenkins' GIT_BRANCH is the remote-tracking name.
+			branch = strings.TrimPrefix(v, "origin/")
+			break
+		}
+	}
+	if branch == "" {
+		return 0, "no CI branch variable set", nil
+	}
+	if owner == "" || repo == "" {
+		return 0, "repository unknown", nil
+	}
+	n, err := c.FindPRForBranch(owner, repo, branch)
+	if err != nil {
+		return 0, fmt.Sprintf("lookup of %s failed: %v", branch, err), nil
+	}
+	if n == 0 {
+		return 0, "no open PR for " + branch, nil
+	}
+	return n, "", nil
+}
+
+// FindPRForBranch returns the number of the open PR whose head is branch
+// in owner/repo, or 0 if there is none.
+func (c *Client) FindPRForBranch(owner, repo, branch string) (int, error) {
+	u := fmt.Sprintf("%s/repos/%s/%s/pulls?state=open&head=%s", apiBaseURL, owner, repo,
+		url.QueryEscape(owner+":"+branch))
+	var prs []struct {
+		Number int `json:"number"`
+	}
+	if err := c.doJSON("GET", u, nil, http.StatusOK, &prs); err != nil {
+		return 0, err
+	}
+	if len(prs) == 0 {
+		return 0, nil
+	}
+	return prs[0].Number, nil
+}
+
+// ghPRView asks the gh CLI for the PR of the checked-out branch.
+func ghPRView(c *Client, owner, repo string) (int, string, error) {
+	out, err := exec.Command("gh", "pr", "view", "--json", "number").Output()
+	if err != nil {
+		return 0, "no PR for the current branch or gh unavailable", nil
+	}
+	var result struct {
+		Number int `json:"number"`
+	}
+	if err := json.Unmarshal(out, &result); err != nil {
+		return 0, "", fmt.Errorf("parse gh pr view output: %w", err)
+	}
+	if result.Number == 0 {
+		return 0, "gh returned no PR", nil
+	}
+	return result.Number, "", nil
+}
@@ -0,0 +1,97 @@
+package github
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// clearCIEnv unsets every variable DetectPRNumber reads and keeps gh off
+// PATH, so only what a test sets is seen.
+func clearCIEnv(t *testing.T) {
+	t.Helper()
+	for _, name := range []string{
+		"GITHUB_PR_NUMBER", "GITHUB_ACTIONS", "GITHUB_EVENT_NAME", "GITHUB_EVENT_PATH", "GITHUB_REF",
+		"CIRCLE_PR_NUMBER", "CIRCLE_PULL_REQUEST", "BUILDKITE_PULL_REQUEST", "CHANGE_ID", "ghprbPullId",
+		"CIRCLE_BRANCH", "BUILDKITE_BRANCH", "BRANCH_NAME", "GIT_BRANCH",
+	} {
+		t.Setenv(name, "")
+	}
+	t.Setenv("PATH", t.TempDir())
+}
+
+func TestDetectPRNumber(t *testing.T) {
+	eventPath := filepath.Join(t.TempDir(), "event.json")
+	if err := os.WriteFile(eventPath, []byte(`{"pull_request": {"number": 11}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		env    map[string]string
+		want   int
+		source string
+	}{
+		{"explicit wins", map[string]string{"GITHUB_PR_NUMBER": "5", "CIRCLE_PR_NUMBER": "6"}, 5, "GITHUB_PR_NUMBER"},
+		{"actions event", map[string]string{"GITHUB_ACTIONS": "true", "GITHUB_EVENT_NAME": "pull_request_target", "GITHUB_EVENT_PATH": eventPath}, 11, "GitHub Actions event"},
+		{"actions ref", map[string]string{"GITHUB_ACTIONS": "true", "GITHUB_REF": "refs/pull/12/merge"}, 12, "GitHub Actions event"},
+		{"circle url", map[string]string{"CIRCLE_PULL_REQUEST": "https://github.com/acme/widgets/pull/13"}, 13, "CIRCLE_PULL_REQUEST"},
+		{"buildkite", map[string]string{"BUILDKITE_PULL_REQUEST": "14"}, 14, "BUILDKITE_PULL_REQUEST"},
+		{"buildkite branch build falls through to jenkins", map[string]string{"BUILDKITE_PULL_REQUEST": "false", "CHANGE_ID": "15"}, 15, "CHANGE_ID (Jenkins)"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			clearCIEnv(t)
+			for k, v := range tc.env {
+				t.Setenv(k, v)
+			}
+			d, err := testClient("").DetectPRNumber("", "")
+			if err != nil {
+				t.Fatalf("DetectPRNumber: %v", err)
+			}
+			if d.Number != tc.want || d.Source != tc.source {
+				t.Errorf("got %s, want #%d from %s", d, tc.want, tc.source)
+			}
+		})
+	}
+
+	t.Run("invalid value is an error", func(t *testing.T) {
+		clearCIEnv(t)
+		t.Setenv("BUILDKITE_PULL_REQUEST", "abc")
+		if _, err := testClient("").DetectPRNumber("", ""); err == nil || !strings.Contains(err.Error(), "BUILDKITE_PULL_REQUEST") {
+			t.Errorf("err = %v, want one naming BUILDKITE_PULL_REQUEST", err)
+		}
+	})
+
+	t.Run("jenkins branch via API", func(t *testing.T) {
+		clearCIEnv(t)
+		t.Setenv("GIT_BRANCH", "origin/feature")
+		withAPI(t, func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/repos/acme/widgets/pulls" || r.URL.Query().Get("head") != "acme:feature" {
+				t.Errorf("unexpected request %s", r.URL)
+			}
+			fmt.Fprint(w, `[{"number": 16}]`)
+		})
+		d, err := testClient("tok").DetectPRNumber("acme", "widgets")
+		if err != nil {
+			t.Fatalf("DetectPRNumber: %v", err)
+		}
+		if d.Number != 16 || d.Source != "CI branch via GitHub API" {
+			t.Errorf("got %s", d)
+		}
+		if !strings.Contains(d.String(), "CHANGE_ID (Jenkins): not set") {
+			t.Errorf("explanation lacks skipped sources: %s", d)
+		}
+	})
+
+	t.Run("nothing found", func(t *testing.T) {
+		clearCIEnv(t)
+		_, err := testClient("").DetectPRNumber("", "")
+		if err == nil || !strings.Contains(err.Error(), "gh pr view:") {
+			t.Errorf("err = %v, want the list of tried sources", err)
+		}
+	})
+}
@@ -0,0 +1,399 @@
+package github
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/anthropic/gap-map/internal/report"
+	"github.com/anthropic/gap-map/internal/store"
+)
+
+// DefaultRefreshInterval is how often a Refresher polls the remote for
+// pushed branches when no interval is configured.
+const DefaultRefreshInterval = 2 * time.Minute
+
+// refreshStatePrefix prefixes the daemon_state keys holding the head a
+// branch's PR comment was last refreshed for, so a restarted daemon does
+// not repost comments that are already current.
+const refreshStatePrefix = "pr_refresh_head:"
+
+// RefreshOptions configures a Refresher.
+type RefreshOptions struct {
+	Token    string        // GitHub token allowed to comment on the repository's PRs
+	Interval time.Duration // remote polling interval; 0 means DefaultRefreshInterval
+	Remote   string        // git remote of the repository; "" picks one as DetectRemote does
+	HTTP     ClientOptions // timeouts and retries of its GitHub API calls
+
+	// WebhookAddr, when set, is the address to receive GitHub push and
+	// pull_request webhooks on; a delivery refreshes its branch at once.
+	// Deliveries must be signed with WebhookSecret.
+	WebhookAddr   string
+	WebhookSecret string
+
+	// Allow, when set, is checked before each refresh; returning false
+	// skips it (e.g. for a disabled project).
+	Allow func() bool
+
+	// Policy decides which PRs get a comment and whether it is collapsed.
+	Policy CommentPolicy
+
+	// Report configures the branch reports, as for "gapmap pr-comment".
+	Report report.Options
+}
+
+// Refresher keeps the sticky gap-map comment on a repository's open PRs
+// current: when a PR's branch is pushed, it regenerates the branch report
+// and updates the comment, as "gapmap pr-comment" would.
+type Refresher struct {
+	store       *store.Store
+	dir         string
+	remote      string
+	owner, repo string
+	opts        RefreshOptions
+	client      *Client
+
+	// generate builds the report for a PR; report.GenerateProjectForBranchIn
+	// for dir outside tests.
+	generate func(head, base string) (*report.ProjectReport, error)
+	pushed   chan string // branches named by webhook deliveries
+}
+
+// openPR is the part of a pull request a Refresher needs.
+type openPR struct {
+	Number int
+	Head   string
+	Base   string
+	SHA    string
+	Draft  bool
+}
+
+// NewRefresher returns a Refresher for the GitHub repository that dir's
+// remote points at.
+func NewRefresher(s *store.Store, dir string, opts RefreshOptions) (*Refresher, error) {
+	if opts.Token == "" {
+		return nil, errors.New("no GitHub token (set GITHUB_TOKEN or log in with gh)")
+	}
+	if opts.WebhookAddr != "" && opts.WebhookSecret == "" {
+		return nil, errors.New("a webhook address needs a webhook secret")
+	}
+	remote, err := DetectRemote(dir, opts.Remote)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = DefaultRefreshInterval
+	}
+	project, err := s.ResolvePath(dir)
+	if err != nil {
+		return nil, err
+	}
+	return &Refresher{
+		store:  s,
+		dir:    dir,
+		remote: remote.Name,
+		owner:  remote.Owner,
+		repo:   remote.Repo,
+		opts:   opts,
+		client: NewClient(opts.Token, opts.HTTP),
+		generate: func(head, base string) (*report.ProjectReport, error) {
+			return report.GenerateProjectForBranchIn(s, project, head, base, opts.Report)
+		},
+		pushed: make(chan string, 16),
+	}, nil
+}
+
+// DetectToken returns a GitHub token from GITHUB_TOKEN, else from the gh
+// CLI's login, else "".
+func DetectToken() string {
+	if t := os.Getenv("GITHUB_TOKEN"); t != "" {
+		return t
+	}
+	out, err := exec.Command("gh", "auth", "token").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// Run polls the remote, and serves webhooks if configured, until ctx is
+// cancelled.
+func (r *Refresher) Run(ctx context.Context) {
+	if r.opts.WebhookAddr != "" {
+		srv := &http.Server{Addr: r.opts.WebhookAddr, Handler: http.HandlerFunc(r.handleWebhook)}
+		go func() {
+			if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				slog.Error("PR refresh webhook receiver stopped", "addr", r.opts.WebhookAddr, "err", err)
+			}
+		}()
+		go func() {
+			<-ctx.Done()
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			_ = srv.Shutdown(shutdownCtx)
+		}()
+		slog.Info("PR refresh webhook receiver listening", "addr", r.opts.WebhookAddr)
+	}
+
+	r.poll(ctx)
+	ticker := time.NewTicker(r.opts.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.poll(ctx)
+		case branch := <-r.pushed:
+			r.refreshBranches(ctx, map[string]string{branch: ""}, true)
+		}
+	}
+}
+
+// poll refreshes the PRs of the branches whose head on the remote moved
+// since it was last seen. A branch with no open PR is not looked at again
+// until it is pushed; a PR opened for it before then is refreshed by its
+// pull_request webhook, if configured.
+func (r *Refresher) poll(ctx context.Context) {
+	heads, err := r.remoteHeads(ctx)
+	if err != nil {
+		slog.Warn("PR refresh: list remote branches failed", "err", err)
+		return
+	}
+	changed := make(map[string]string)
+	for branch, sha := range heads {
+		last, err := r.store.GetDaemonState(refreshStatePrefix + branch)
+		if err != nil {
+			slog.Warn("PR refresh: read state failed", "err", err)
+			return
+		}
+		if last != sha {
+			changed[branch] = sha
+		}
+	}
+	if len(changed) > 0 {
+		r.refreshBranches(ctx, changed, false)
+	}
+}
+
+// remoteHeads returns the head commit of each branch on the remote.
+func (r *Refresher) remoteHeads(ctx context.Context) (map[string]string, error) {
+	out, err := exec.CommandContext(ctx, "git", "-C", r.dir, "ls-remote", "--heads", r.remote).Output()
+	if err != nil {
+		return nil, fmt.Errorf("git ls-remote: %w", err)
+	}
+	heads := make(map[string]string)
+	sc := bufio.NewScanner(bytes.NewReader(out))
+	for sc.Scan() {
+		sha, ref, ok := strings.Cut(sc.Text(), "\t")
+		if !ok {
+			continue
+		}
+		heads[strings.TrimPrefix(ref, "refs/heads/")] = sha
+	}
+	return heads, sc.Err()
+}
+
+// refreshBranches updates the comment of every open PR whose head is one
+// of branches, which maps each branch to its head on the remote, or "" if
+// unknown. Unless force is set, a PR whose head was already refreshed is
+// skipped. The head of each branch is recorded whether or not its refresh
+// succeeded, so a failing report is not regenerated on every poll, and so
+// is the head of a branch with no open PR.
+func (r *Refresher) refreshBranches(ctx context.Context, branches map[string]string, force bool) {
+	if r.opts.Allow != nil && !r.opts.Allow() {
+		return
+	}
+	prs, err := r.openPRs()
+	if err != nil {
+		slog.Warn("PR refresh: list open PRs failed", "err", err)
+		return
+	}
+	withPR := make(map[string]bool)
+	for _, pr := range prs {
+		if ctx.Err() != nil {
+			return
+		}
+		if _, ok := branches[pr.Head]; !ok {
+			continue
+		}
+		withPR[pr.Head] = true
+		key := refreshStatePrefix + pr.Head
+		// A draft skipped by the policy is remembered as such, so marking
+		// it ready refreshes it without a push.
+		state := pr.SHA
+		if pr.Draft && r.opts.Policy.SkipDrafts {
+			state += " draft"
+		}
+		if !force {
+			if last, _ := r.store.GetDaemonState(key); last == state {
+				continue
+			}
+		}
+		skipped, err := r.refresh(pr)
+		if err := r.store.SetDaemonState(key, state); err != nil {
+			slog.Warn("PR refresh: save state failed", "err", err)
+		}
+		if err != nil {
+			slog.Warn("PR refresh failed", "pr", pr.Number, "branch", pr.Head, "err", err)
+			continue
+		}
+		if skipped != "" {
+			slog.Info("PR comment skipped", "pr", pr.Number, "branch", pr.Head, "reason", skipped)
+			continue
+		}
+		slog.Info("PR comment refreshed", "pr", pr.Number, "branch", pr.Head, "head", pr.SHA)
+	}
+	for branch, sha := range branches {
+		if withPR[branch] || sha == "" {
+			continue
+		}
+		if err := r.store.SetDaemonState(refreshStatePrefix+branch, sha); err != nil {
+			slog.Warn("PR refresh: save state failed", "err", err)
+		}
+	}
+}
+
+// refresh regenerates pr's report and updates its comment, unless the
+// comment policy skips the PR; it then returns why.
+func (r *Refresher) refresh(pr openPR) (string, error) {
+	rep, err := r.generate(pr.Head, pr.Base)
+	if err != nil {
+		return "", fmt.Errorf("generate report for %s against %s: %w", pr.Head, pr.Base, err)
+	}
+	if reason := r.opts.Policy.SkipReason(rep, pr.Draft); reason != "" {
+		return reason, nil
+	}
+	body := r.opts.Policy.Apply(r.opts.Policy.Comment(rep))
+	return "", r.client.UpsertComment(r.owner, r.repo, pr.Number, body)
+}
+
+// openPRs lists the repository's open pull requests whose head branch is
+// in the repository itself (not a fork).
+func (r *Refresher) openPRs() ([]openPR, error) {
+	var prs []openPR
+	url := fmt.Sprintf("%s/repos/%s/%s/pulls?state=open&per_page=100", apiBaseURL, r.owner, r.repo)
+	for url != "" {
+		var result []struct {
+			Number int  `json:"number"`
+			Draft  bool `json:"draft"`
+			Head   struct {
+				Ref  string `json:"ref"`
+				SHA  string `json:"sha"`
+				Repo *struct {
+					FullName string `json:"full_name"`
+				} `json:"repo"`
+			} `json:"head"`
+			Base struct {
+				Ref string `json:"ref"`
+			} `json:"base"`
+		}
+		next, err := r.client.getPage(url, &result)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range result {
+			if p.Head.Repo == nil || !strings.EqualFold(p.Head.Repo.FullName, r.owner+"/"+r.repo) {
+				continue
+			}
+			prs = append(prs, openPR{Number: p.Number, Head: p.Head.Ref, Base: p.Base.Ref, SHA: p.Head.SHA, Draft: p.Draft})
+		}
+		url = next
+	}
+	return prs, nil
+}
+
+// handleWebhook accepts GitHub push and pull_request deliveries signed
+// with the webhook secret and queues their branch for a refresh.
+func (r *Refresher) handleWebhook(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(req.Body, 25<<20))
+	if err != nil {
+		http.Error(w, "read body", http.StatusBadRequest)
+		return
+	}
+	if !validSignature(r.opts.WebhookSecret, body, req.Header.Get("X-Hub-Signature-256")) {
+		http.Error(w, "bad signature", http.StatusUnauthorized)
+		return
+	}
+
+	branch := webhookBranch(req.Header.Get("X-GitHub-Event"), body)
+	if branch == "" {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	select {
+	case r.pushed <- branch:
+	default:
+		// A refresh backlog; the next poll catches up.
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// validSignature checks a GitHub X-Hub-Signature-256 header.
+func validSignature(secret string, body []byte, header string) bool {
+	sig, ok := strings.CutPrefix(header, "sha256=")
+	if !ok {
+		return false
+	}
+	got, err := hex.DecodeString(sig)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(got, mac.Sum(nil))
+}
+
+// webhookBranch returns the branch a push or pull_request delivery moved,
+// or "" for other events and actions.
+func webhookBranch(event string, body []byte) string {
+	switch event {
+	case "push":
+		var p struct {
+			Ref     string `json:"ref"`
+			Deleted bool   `json:"deleted"`
+		}
+		if json.Unmarshal(body, &p) != nil || p.Deleted {
+			return ""
+		}
+		branch, ok := strings.CutPrefix(p.Ref, "refs/heads/")
+		if !ok {
+			return ""
+		}
+		return branch
+	case "pull_request":
+		var p struct {
+			Action      string `json:"action"`
+			PullRequest struct {
+				Head struct {
+					Ref string `json:"ref"`
+				} `json:"head"`
+			} `json:"pull_request"`
+		}
+		if json.Unmarshal(body, &p) != nil {
+			return ""
+		}
+		switch p.Action {
+		case "opened", "reopened", "synchronize", "ready_for_review":
+			return p.PullRequest.Head.Ref
+		}
+	}
+	return ""
+}
@@ -0,0 +1,154 @@
+package github
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/anthropic/gap-map/internal/report"
+	"github.com/anthropic/gap-map/internal/store"
+)
+
+func TestRefresher_RefreshBranches(t *testing.T) {
+	var updated []string
+	var srvURL string
+	srv := withAPI(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "GET" && r.URL.Path == "/repos/acme/widgets/pulls" && r.URL.Query().Get("page") == "":
+			w.Header().Set("Link", fmt.Sprintf(`<%s/repos/acme/widgets/pulls?state=open&per_page=100&page=2>; rel="next"`, srvURL))
+			fmt.Fprint(w, `[
+				{"number": 7, "head": {"ref": "feature", "sha": "aaa", "repo": {"full_name": "acme/widgets"}}, "base": {"ref": "main"}},
+				{"number": 8, "head": {"ref": "feature", "sha": "fff", "repo": {"full_name": "someone/widgets"}}, "base": {"ref": "main"}}
+			]`)
+		case r.Method == "GET" && r.URL.Path == "/repos/acme/widgets/pulls":
+			fmt.Fprint(w, `[
+				{"number": 9, "head": {"ref": "other", "sha": "bbb", "repo": {"full_name": "acme/widgets"}}, "base": {"ref": "main"}},
+				{"number": 10, "head": {"ref": "broken", "sha": "ddd", "repo": {"full_name": "acme/widgets"}}, "base": {"ref": "main"}}
+			]`)
+		case r.Method == "GET" && strings.HasSuffix(r.URL.Path, "/comments"):
+			fmt.Fprint(w, `[]`)
+		case r.Method == "POST" && strings.HasSuffix(r.URL.Path, "/comments"):
+			var payload struct {
+				Body string `json:"body"`
+			}
+			json.NewDecoder(r.Body).Decode(&payload)
+			updated = append(updated, r.URL.Path)
+			w.WriteHeader(http.StatusCreated)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+	})
+
+	srvURL = srv.URL
+
+	s, err := store.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("store.New: %v", err)
+	}
+	defer s.Close()
+
+	var generated []string
+	r := &Refresher{
+		store: s, owner: "acme", repo: "widgets",
+		opts:   RefreshOptions{Token: "t"},
+		client: testClient("t"),
+		generate: func(head, base string) (*report.ProjectReport, error) {
+			generated = append(generated, head+".."+base)
+			if head == "broken" {
+				return nil, errors.New("no attributions")
+			}
+			return &report.ProjectReport{}, nil
+		},
+	}
+
+	ctx := context.Background()
+	r.refreshBranches(ctx, map[string]string{"feature": "aaa"}, false)
+	// The fork's PR with the same branch name is left alone.
+	if strings.Join(updated, ",") != "/repos/acme/widgets/issues/7/comments" {
+		t.Errorf("updated = %v, want PR 7 only", updated)
+	}
+	if strings.Join(generated, ",") != "feature..main" {
+		t.Errorf("generated = %v", generated)
+	}
+	if head, _ := s.GetDaemonState(refreshStatePrefix + "feature"); head != "aaa" {
+		t.Errorf("recorded head = %q, want aaa", head)
+	}
+
+	// The same head is not refreshed again unless forced, as a webhook does.
+	r.refreshBranches(ctx, map[string]string{"feature": "aaa"}, false)
+	if len(updated) != 1 {
+		t.Errorf("refreshed an unchanged head: %v", updated)
+	}
+	r.refreshBranches(ctx, map[string]string{"feature": "aaa"}, true)
+	if len(updated) != 2 {
+		t.Errorf("forced refresh did not update: %v", updated)
+	}
+
+	// A failed refresh and a branch with no open PR are both recorded, so
+	// the next poll does not retry them until they are pushed again.
+	r.refreshBranches(ctx, map[string]string{"broken": "ddd", "nopr": "ccc"}, false)
+	for branch, want := range map[string]string{"broken": "ddd", "nopr": "ccc"} {
+		if head, _ := s.GetDaemonState(refreshStatePrefix + branch); head != want {
+			t.Errorf("recorded head of %s = %q, want %q", branch, head, want)
+		}
+	}
+	if len(updated) != 2 {
+		t.Errorf("updated a failed or missing PR: %v", updated)
+	}
+
+	// A disallowed project is never refreshed.
+	r.opts.Allow = func() bool { return false }
+	r.refreshBranches(ctx, map[string]string{"other": "bbb"}, true)
+	if len(updated) != 2 {
+		t.Errorf("refreshed a disallowed project: %v", updated)
+	}
+}
+
+func TestRefresher_Webhook(t *testing.T) {
+	r := &Refresher{opts: RefreshOptions{WebhookSecret: "s3cret"}, pushed: make(chan string, 1)}
+	sign := func(body string) string {
+		mac := hmac.New(sha256.New, []byte("s3cret"))
+		mac.Write([]byte(body))
+		return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	}
+	deliver := func(event, body, sig string) int {
+		req := httptest.NewRequest("POST", "/", strings.NewReader(body))
+		req.Header.Set("X-GitHub-Event", event)
+		req.Header.Set("X-Hub-Signature-256", sig)
+		rec := httptest.NewRecorder()
+		r.handleWebhook(rec, req)
+		return rec.Code
+	}
+
+	push := `{"ref": "refs/heads/feature", "after": "abc"}`
+	if code := deliver("push", push, "sha256=00"); code != http.StatusUnauthorized {
+		t.Errorf("unsigned delivery: status %d, want 401", code)
+	}
+	if code := deliver("push", push, sign(push)); code != http.StatusAccepted {
+		t.Errorf("push: status %d, want 202", code)
+	}
+	if got := <-r.pushed; got != "feature" {
+		t.Errorf("queued branch %q, want feature", got)
+	}
+
+	for _, tc := range []struct{ event, body, want string }{
+		{"pull_request", `{"action": "synchronize", "pull_request": {"head": {"ref": "fix"}}}`, "fix"},
+		{"pull_request", `{"action": "closed", "pull_request": {"head": {"ref": "fix"}}}`, ""},
+		{"push", `{"ref": "refs/tags/v1"}`, ""},
+		{"push", `{"ref": "refs/heads/gone", "deleted": true}`, ""},
+		{"issues", `{"action": "opened"}`, ""},
+	} {
+		if got := webhookBranch(tc.event, []byte(tc.body)); got != tc.want {
+			t.Errorf("webhookBranch(%s, %s) = %q, want %q", tc.event, tc.body, got, tc.want)
+		}
+	}
+}
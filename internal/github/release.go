@@ -0,0 +1,73 @@
+package github
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+// Release is a published GitHub release.
+type Release struct {
+	Tag    string  `json:"tag_name"`
+	Assets []Asset `json:"assets"`
+}
+
+// Asset is a file attached to a release.
+type Asset struct {
+	Name string `json:"name"`
+	URL  string `json:"url"` // API URL; downloads with Accept: application/octet-stream
+	Size int64  `json:"size"`
+}
+
+// Asset returns the asset of r named name.
+func (r Release) Asset(name string) (Asset, bool) {
+	for _, a := range r.Assets {
+		if a.Name == name {
+			return a, true
+		}
+	}
+	return Asset{}, false
+}
+
+// FetchRelease returns the release of owner/repo tagged tag, or the latest
+// release when tag is empty. Drafts and pre-releases are never latest.
+// The client's token may be empty for a public repository.
+func (c *Client) FetchRelease(owner, repo, tag string) (Release, error) {
+	endpoint := fmt.Sprintf("%s/repos/%s/%s/releases/latest", apiBaseURL, owner, repo)
+	if tag != "" {
+		endpoint = fmt.Sprintf("%s/repos/%s/%s/releases/tags/%s", apiBaseURL, owner, repo, url.PathEscape(tag))
+	}
+	var rel Release
+	if err := c.doJSON("GET", endpoint, nil, http.StatusOK, &rel); err != nil {
+		return Release{}, fmt.Errorf("fetch release: %w", err)
+	}
+	return rel, nil
+}
+
+// DownloadAsset writes the contents of a to w, failing unless they are
+// a.Size bytes long.
+func (c *Client) DownloadAsset(a Asset, w io.Writer) error {
+	req, err := c.newRequest("GET", a.URL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/octet-stream")
+	resp, err := c.send(req)
+	if err != nil {
+		return fmt.Errorf("download %s: %w", a.Name, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("github API returned status %d: %s", resp.StatusCode, string(respBody))
+	}
+	n, err := io.Copy(w, io.LimitReader(resp.Body, a.Size+1))
+	if err != nil {
+		return fmt.Errorf("download %s: %w", a.Name, err)
+	}
+	if n != a.Size {
+		return fmt.Errorf("download %s: got %d bytes, want %d", a.Name, n, a.Size)
+	}
+	return nil
+}
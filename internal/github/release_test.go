@@ -0,0 +1,62 @@
+package github
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestFetchReleaseAndDownload(t *testing.T) {
+	var srvURL string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/acme/tool/releases/latest", "/repos/acme/tool/releases/tags/v1.2.0":
+			fmt.Fprintf(w, `{"tag_name":"v1.2.0","assets":[{"name":"tool_linux_amd64","url":"%s/assets/1","size":6}]}`, srvURL)
+		case "/assets/1":
+			if got := r.Header.Get("Accept"); got != "application/octet-stream" {
+				t.Errorf("Accept = %q", got)
+			}
+			fmt.Fprint(w, "binary")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	srvURL = srv.URL
+
+	orig := apiBaseURL
+	apiBaseURL = srv.URL
+	defer func() { apiBaseURL = orig }()
+
+	for _, tag := range []string{"", "v1.2.0"} {
+		rel, err := testClient("").FetchRelease("acme", "tool", tag)
+		if err != nil {
+			t.Fatalf("FetchRelease(%q): %v", tag, err)
+		}
+		if rel.Tag != "v1.2.0" || len(rel.Assets) != 1 {
+			t.Errorf("FetchRelease(%q) = %+v", tag, rel)
+		}
+	}
+	if _, err := testClient("").FetchRelease("acme", "tool", "v9"); err == nil {
+		t.Error("FetchRelease of a missing tag: want error")
+	}
+
+	rel, _ := testClient("").FetchRelease("acme", "tool", "")
+	a, ok := rel.Asset("tool_linux_amd64")
+	if !ok {
+		t.Fatal("asset tool_linux_amd64 not found")
+	}
+	var buf bytes.Buffer
+	if err := testClient("").DownloadAsset(a, &buf); err != nil || buf.String() != "binary" {
+		t.Errorf("DownloadAsset = %q, %v", buf.String(), err)
+	}
+
+	// A truncated download is an error.
+	a.Size = 100
+	if err := testClient("").DownloadAsset(a, &bytes.Buffer{}); err == nil || !strings.Contains(err.Error(), "want 100") {
+		t.Errorf("DownloadAsset of a short body: err = %v", err)
+	}
+}
@@ -0,0 +1,200 @@
+package github
+
+import (
+	"bufio"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// remotePreference is the order remotes are tried in when none is named:
+// in a fork workflow "upstream" holds the PRs and "origin" the fork.
+var remotePreference = []string{"upstream", "origin"}
+
+// sshConfigPath is the ssh client configuration read to resolve host
+// aliases in SSH remote URLs.
+var sshConfigPath = func() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".ssh", "config")
+}()
+
+// Remote is a git remote that points at a GitHub repository.
+type Remote struct {
+	Name  string
+	URL   string
+	Owner string
+	Repo  string
+}
+
+// DetectRemote returns the GitHub remote of the repository at dir: the one
+// called name if set, otherwise "upstream", then "origin", then the first
+// other remote whose URL is a GitHub repository.
+func DetectRemote(dir, name string) (Remote, error) {
+	if name != "" {
+		return readRemote(dir, name)
+	}
+	out, err := exec.Command("git", "-C", dir, "remote").Output()
+	if err != nil {
+		return Remote{}, fmt.Errorf("list git remotes: %w", err)
+	}
+	names := strings.Fields(string(out))
+	if len(names) == 0 {
+		return Remote{}, fmt.Errorf("no git remotes")
+	}
+
+	ordered := make([]string, 0, len(names))
+	for _, pref := range remotePreference {
+		for _, n := range names {
+			if n == pref {
+				ordered = append(ordered, n)
+			}
+		}
+	}
+	for _, n := range names {
+		if n != "upstream" && n != "origin" {
+			ordered = append(ordered, n)
+		}
+	}
+	for _, n := range ordered {
+		if r, err := readRemote(dir, n); err == nil {
+			return r, nil
+		}
+	}
+	return Remote{}, fmt.Errorf("no GitHub remote among %s", strings.Join(names, ", "))
+}
+
+// readRemote returns the remote called name, which must be on GitHub.
+func readRemote(dir, name string) (Remote, error) {
+	out, err := exec.Command("git", "-C", dir, "remote", "get-url", name).Output()
+	if err != nil {
+		return Remote{}, fmt.Errorf("get URL of git remote %q: %w", name, err)
+	}
+	r := Remote{Name: name, URL: strings.TrimSpace(string(out))}
+	r.Owner, r.Repo, err = ParseGitHubRemote(r.URL)
+	if err != nil {
+		return Remote{}, err
+	}
+	return r, nil
+}
+
+// ParseGitHubRemote extracts the owner and repo from a GitHub remote URL.
+// Supports HTTPS and SSH formats:
+//   - https://github.com/{owner}/{repo}.git (or without .git)
+//   - git@github.com:{owner}/{repo}.git (or without .git)
+//   - ssh://git@github.com/{owner}/{repo}.git
+//
+// The host of an SSH URL may be an alias that ~/.ssh/config maps to
+// github.com with HostName.
+func ParseGitHubRemote(remoteURL string) (owner, repo string, err error) {
+	remoteURL = strings.TrimSpace(remoteURL)
+	invalid := fmt.Errorf("unable to parse GitHub remote URL: %q", remoteURL)
+
+	host, repoPath, ssh, ok := splitRemoteURL(remoteURL)
+	if !ok {
+		return "", "", invalid
+	}
+	if ssh {
+		host = sshHostName(host)
+	}
+	switch strings.ToLower(host) {
+	case "github.com", "www.github.com", "ssh.github.com":
+	default:
+		return "", "", invalid
+	}
+
+	repoPath = strings.TrimSuffix(strings.Trim(repoPath, "/"), ".git")
+	owner, repo, ok = strings.Cut(repoPath, "/")
+	if !ok || owner == "" || repo == "" || strings.Contains(repo, "/") {
+		return "", "", invalid
+	}
+	return owner, repo, nil
+}
+
+// splitRemoteURL splits a git remote URL into its host and path, and
+// reports whether it is reached over SSH.
+func splitRemoteURL(remoteURL string) (host, repoPath string, ssh, ok bool) {
+	if strings.Contains(remoteURL, "://") {
+		u, err := url.Parse(remoteURL)
+		if err != nil {
+			return "", "", false, false
+		}
+		switch u.Scheme {
+		case "https", "http":
+		case "ssh", "git+ssh":
+			ssh = true
+		default:
+			return "", "", false, false
+		}
+		return u.Hostname(), u.Path, ssh, u.Hostname() != ""
+	}
+
+	// scp-like syntax: [user@]host:path
+	hostPart, repoPath, found := strings.Cut(remoteURL, ":")
+	if !found || hostPart == "" || strings.Contains(hostPart, "/") {
+		return "", "", false, false
+	}
+	if _, h, hasUser := strings.Cut(hostPart, "@"); hasUser {
+		hostPart = h
+	}
+	return hostPart, repoPath, true, hostPart != ""
+}
+
+// sshHostName resolves an ssh host alias through the HostName setting of
+// the first matching Host block in the ssh client configuration, as ssh
+// does. Hosts without one resolve to themselves.
+func sshHostName(alias string) string {
+	if sshConfigPath == "" {
+		return alias
+	}
+	f, err := os.Open(sshConfigPath)
+	if err != nil {
+		return alias
+	}
+	defer f.Close()
+
+	matching := true // settings before the first Host block apply to all
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, _ := strings.Cut(strings.Replace(line, "=", " ", 1), " ")
+		value = strings.Trim(strings.TrimSpace(value), `"`)
+		switch strings.ToLower(key) {
+		case "host":
+			matching = sshHostMatches(alias, strings.Fields(value))
+		case "match":
+			// Match conditions are not evaluated; skip the block.
+			matching = false
+		case "hostname":
+			if matching {
+				return strings.ReplaceAll(value, "%h", alias)
+			}
+		}
+	}
+	return alias
+}
+
+// sshHostMatches reports whether host matches a Host line's patterns: any
+// pattern matches and no negated one does.
+func sshHostMatches(host string, patterns []string) bool {
+	matched := false
+	for _, p := range patterns {
+		negated := strings.HasPrefix(p, "!")
+		if ok, _ := path.Match(strings.TrimPrefix(p, "!"), host); ok {
+			if negated {
+				return false
+			}
+			matched = true
+		}
+	}
+	return matched
+}
@@ -0,0 +1,98 @@
+package github
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseGitHubRemote_SSHAliases(t *testing.T) {
+	cfg := filepath.Join(t.TempDir(), "config")
+	if err := os.WriteFile(cfg, []byte(`# personal and work accounts
+Host gh-work github-*
+    HostName github.com
+    User git
+
+Host !gh-mirror gh-*
+    HostName=ssh.github.com
+
+Host gitlab
+    HostName gitlab.com
+`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	orig := sshConfigPath
+	sshConfigPath = cfg
+	t.Cleanup(func() { sshConfigPath = orig })
+
+	for _, url := range []string{
+		"gh-work:acme/widgets.git",
+		"git@github-personal:acme/widgets",
+		"ssh://git@gh-work/acme/widgets.git",
+		"ssh://git@github.com:22/acme/widgets",
+		"git@gh-other:acme/widgets.git",
+		"https://user@github.com/acme/widgets.git",
+	} {
+		owner, repo, err := ParseGitHubRemote(url)
+		if err != nil {
+			t.Errorf("ParseGitHubRemote(%q) error: %v", url, err)
+			continue
+		}
+		if owner != "acme" || repo != "widgets" {
+			t.Errorf("ParseGitHubRemote(%q) = (%q, %q), want (acme, widgets)", url, owner, repo)
+		}
+	}
+
+	for _, url := range []string{
+		"git@gitlab:acme/widgets.git",
+		"git@gh-mirror:acme/widgets.git",
+		"git@unknown:acme/widgets.git",
+		"https://github.com/acme",
+		"https://github.com/acme/widgets/extra",
+	} {
+		if _, _, err := ParseGitHubRemote(url); err == nil {
+			t.Errorf("ParseGitHubRemote(%q) expected error, got nil", url)
+		}
+	}
+}
+
+func TestDetectRemote(t *testing.T) {
+	dir := t.TempDir()
+	git := func(args ...string) {
+		t.Helper()
+		if out, err := exec.Command("git", append([]string{"-C", dir}, args...)...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	git("init", "-q")
+
+	if _, err := DetectRemote(dir, ""); err == nil {
+		t.Error("DetectRemote without remotes: expected error")
+	}
+
+	git("remote", "add", "mirror", "https://gitlab.com/me/widgets.git")
+	git("remote", "add", "fork", "git@github.com:me/widgets.git")
+	r, err := DetectRemote(dir, "")
+	if err != nil || r.Name != "fork" {
+		t.Errorf("DetectRemote = %+v, %v; want the only GitHub remote", r, err)
+	}
+
+	git("remote", "add", "origin", "git@github.com:me/widgets.git")
+	git("remote", "add", "upstream", "https://github.com/acme/widgets.git")
+	r, err = DetectRemote(dir, "")
+	if err != nil || r.Name != "upstream" || r.Owner != "acme" || r.Repo != "widgets" {
+		t.Errorf("DetectRemote = %+v, %v; want upstream acme/widgets", r, err)
+	}
+
+	r, err = DetectRemote(dir, "origin")
+	if err != nil || r.Owner != "me" {
+		t.Errorf("DetectRemote(origin) = %+v, %v; want me/widgets", r, err)
+	}
+	if _, err := DetectRemote(dir, "mirror"); err == nil {
+		t.Error("DetectRemote(mirror): expected error for a non-GitHub remote")
+	}
+	if _, err := DetectRemote(dir, "missing"); err == nil {
+		t.Error("DetectRemote(missing): expected error")
+	}
+}
@@ -0,0 +1,36 @@
+package github
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/anthropic/gap-map/internal/report"
+)
+
+// GenerateStackComment produces the PR comment for layer i of a stack: the
+// layer's own summary, listing the files notable selects, followed by a
+// table placing it in the stack next to the full-stack rollup.
+func GenerateStackComment(sr *report.StackReport, i int, notable NotableFiles) string {
+	body := generateComment(sr.Layers[i].Report, notable)
+
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("### Stack (layer %d of %d on `%s`)\n\n", i+1, len(sr.Layers), sr.Base))
+	b.WriteString("| # | Branch | Files | Lines | AI% |\n")
+	b.WriteString("|--:|--------|------:|------:|----:|\n")
+	for j, l := range sr.Layers {
+		branch := fmt.Sprintf("`%s`", l.Branch)
+		if j == i {
+			branch = "**" + branch + "** (this PR)"
+		}
+		b.WriteString(fmt.Sprintf("| %d | %s | %d | %d | %.1f%% |\n",
+			j+1, branch, l.Report.TotalFiles, l.Report.TotalLines, l.Report.MeaningfulAIPct))
+	}
+	b.WriteString(fmt.Sprintf("| | Full stack | %d | %d | %.1f%% |\n\n",
+		sr.Rollup.TotalFiles, sr.Rollup.TotalLines, sr.Rollup.MeaningfulAIPct))
+
+	// The table goes above the footer.
+	if at := strings.LastIndex(body, "---\n"); at >= 0 {
+		return body[:at] + b.String() + body[at:]
+	}
+	return body + "\n" + b.String()
+}
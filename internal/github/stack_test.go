@@ -0,0 +1,38 @@
+package github
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/anthropic/gap-map/internal/report"
+)
+
+func TestGenerateStackComment(t *testing.T) {
+	layer := func(files, lines int, pct float64) *report.ProjectReport {
+		return &report.ProjectReport{TotalFiles: files, TotalLines: lines, MeaningfulAIPct: pct,
+			ByAuthorship: map[string]int{}, ByWorkType: map[string]report.WorkTypeSummary{}}
+	}
+	sr := &report.StackReport{
+		Base: "main",
+		Layers: []report.StackLayer{
+			{Branch: "feat-a", Parent: "main", Report: layer(1, 10, 80)},
+			{Branch: "feat-b", Parent: "feat-a", Report: layer(2, 30, 20)},
+		},
+		Rollup: layer(3, 40, 35),
+	}
+
+	body := GenerateStackComment(sr, 1, DefaultNotableFiles)
+	for _, want := range []string{
+		"### Stack (layer 2 of 2 on `main`)",
+		"| 1 | `feat-a` | 1 | 10 | 80.0% |",
+		"| 2 | **`feat-b`** (this PR) | 2 | 30 | 20.0% |",
+		"| | Full stack | 3 | 40 | 35.0% |",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("comment missing %q:\n%s", want, body)
+		}
+	}
+	if strings.Index(body, "### Stack") > strings.Index(body, "_Generated by") {
+		t.Errorf("stack table after the footer:\n%s", body)
+	}
+}
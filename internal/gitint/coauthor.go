@@ -0,0 +1,91 @@
+package gitint
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/anthropic/gap-map/internal/authorship"
+	"github.com/anthropic/gap-map/internal/store"
+)
+
+// CoauthorWindow is how long before a commit an AI edit to one of its
+// files counts as evidence for the commit's AI Co-Authored-By trailer.
+// Edits up to coauthorSkew after the commit also count, for clocks that
+// disagree.
+const (
+	CoauthorWindow = 12 * time.Hour
+	coauthorSkew   = 2 * time.Minute
+)
+
+// VerifyCoauthors checks the AI Co-Authored-By trailers of commits made
+// since since against the session events stored for their files, and
+// records each commit's verification. Commits already corroborated are
+// skipped; unverified ones are checked again, since their session data may
+// be stored after the commit is synced. It returns how many commits were
+// checked and how many were newly found to have no session activity
+// backing them.
+func (r *Repository) VerifyCoauthors(ctx context.Context, since time.Time) (checked, flagged int, err error) {
+	commits, err := r.store.QueryCoauthorCommits(since)
+	if err != nil {
+		return 0, 0, err
+	}
+	classifier := authorship.NewClassifier()
+	for _, c := range commits {
+		if err := ctx.Err(); err != nil {
+			return checked, flagged, err
+		}
+		name := aiCoauthor(c.Message)
+		if name == "" || c.Status == store.CoauthorCorroborated {
+			continue
+		}
+
+		ev, err := r.coauthorEvidence(c)
+		if err != nil {
+			return checked, flagged, fmt.Errorf("commit %s: %w", c.Hash[:7], err)
+		}
+		attr := classifier.ClassifyFromGitVerified(true, name, ev)
+		status := store.CoauthorCorroborated
+		if ev.MatchedFiles == 0 {
+			if c.Status == "" {
+				flagged++
+			}
+			status = store.CoauthorUnverified
+		}
+		if err := r.store.SetCoauthorVerification(c.Hash, status, attr.Confidence, ev.SessionEvents); err != nil {
+			return checked, flagged, fmt.Errorf("commit %s: %w", c.Hash[:7], err)
+		}
+		checked++
+	}
+	return checked, flagged, nil
+}
+
+// coauthorEvidence counts the AI edits to c's files in the window before
+// it.
+func (r *Repository) coauthorEvidence(c store.CoauthorCommit) (authorship.CoauthorEvidence, error) {
+	ev := authorship.CoauthorEvidence{Files: len(c.Files)}
+	start, end := c.Timestamp.Add(-CoauthorWindow), c.Timestamp.Add(coauthorSkew)
+	for _, f := range c.Files {
+		events, err := r.store.QuerySessionEventsInWindow(filepath.Join(r.path, f), start, end)
+		if err != nil {
+			return ev, err
+		}
+		if len(events) > 0 {
+			ev.MatchedFiles++
+			ev.SessionEvents += len(events)
+		}
+	}
+	return ev, nil
+}
+
+// aiCoauthor returns the first AI co-author named in a commit message, or
+// "" if none is.
+func aiCoauthor(message string) string {
+	for _, name := range AllCoAuthors(message) {
+		if authorship.IsAICoauthor(name) {
+			return name
+		}
+	}
+	return ""
+}
@@ -0,0 +1,102 @@
+package gitint
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	gogit "github.com/go-git/go-git/v5"
+
+	"github.com/anthropic/gap-map/internal/store"
+)
+
+func TestVerifyCoauthors(t *testing.T) {
+	tmpDir := t.TempDir()
+	repo := initTestRepo(t, tmpDir)
+	wt, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := store.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	commit := func(file, message string) string {
+		t.Helper()
+		writeFile(t, tmpDir, file, "package main\n")
+		if _, err := wt.Add(file); err != nil {
+			t.Fatal(err)
+		}
+		h, err := wt.Commit(message, &gogit.CommitOptions{Author: testAuthor()})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return h.String()
+	}
+	const trailer = "\n\nCo-Authored-By: Claude <noreply@anthropic.com>\n"
+
+	// Claude edited a.go before its commit; b.go's commit claims Claude
+	// with no session activity; c.go's co-author is a person.
+	if err := s.InsertSessionEvent("sess", "tool_use", "Edit", filepath.Join(tmpDir, "a.go"), "h",
+		time.Now().Add(-time.Hour), `{"uuid":"u-1"}`, 1); err != nil {
+		t.Fatal(err)
+	}
+	backed := commit("a.go", "add a"+trailer)
+	claimed := commit("b.go", "add b"+trailer)
+	commit("c.go", "add c\n\nCo-Authored-By: Ada <ada@example.com>\n")
+
+	r, err := Open(tmpDir, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	since := time.Now().Add(-24 * time.Hour)
+	if err := r.SyncCommits(context.Background(), since); err != nil {
+		t.Fatal(err)
+	}
+
+	checked, flagged, err := r.VerifyCoauthors(context.Background(), since)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if checked != 2 || flagged != 1 {
+		t.Errorf("VerifyCoauthors = %d checked, %d flagged; want 2, 1", checked, flagged)
+	}
+	status := func() map[string]store.CoauthorCommit {
+		commits, err := s.QueryCoauthorCommits(since)
+		if err != nil {
+			t.Fatal(err)
+		}
+		byHash := make(map[string]store.CoauthorCommit)
+		for _, c := range commits {
+			byHash[c.Hash] = c
+		}
+		return byHash
+	}
+	got := status()
+	if c := got[backed]; c.Status != store.CoauthorCorroborated || c.SessionEvents != 1 || c.Confidence != 0.95 {
+		t.Errorf("backed commit = %+v, want corroborated by 1 edit", c)
+	}
+	if c := got[claimed]; c.Status != store.CoauthorUnverified || c.Confidence != 0.3 {
+		t.Errorf("claimed commit = %+v, want unverified", c)
+	}
+	if len(got) != 3 {
+		t.Errorf("co-authored commits = %d, want 3", len(got))
+	}
+
+	// A session ingested late corroborates the flagged commit; only it is
+	// checked again.
+	if err := s.InsertSessionEvent("sess", "tool_use", "Write", filepath.Join(tmpDir, "b.go"), "h",
+		time.Now().Add(-time.Hour), `{"uuid":"u-2"}`, 1); err != nil {
+		t.Fatal(err)
+	}
+	checked, flagged, err = r.VerifyCoauthors(context.Background(), since)
+	if err != nil || checked != 1 || flagged != 0 {
+		t.Errorf("second VerifyCoauthors = %d, %d, %v; want 1 checked, 0 flagged", checked, flagged, err)
+	}
+	if c := status()[claimed]; c.Status != store.CoauthorCorroborated {
+		t.Errorf("claimed commit after late session = %+v, want corroborated", c)
+	}
+}
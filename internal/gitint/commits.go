@@ -1,6 +1,8 @@
 package gitint
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"regexp"
 	"strings"
 
@@ -51,32 +53,91 @@ func AllCoAuthors(message string) []string {
 // coAuthorRe matches "Co-Authored-By: Name <email>" (case insensitive, multi-line).
 var coAuthorRe = regexp.MustCompile(`(?im)co-authored-by:\s*(.+?)(?:\s*<[^>]*>)?\s*$`)
 
-// commitDiffs computes per-file diff stats for a commit.
+// AISignal is the AI involvement a commit message declares.
+type AISignal string
+
+const (
+	// AISignalNone means the message carries no AI signal either way.
+	AISignalNone AISignal = ""
+	// AISignalFull means the commit's changes were written by an AI tool.
+	AISignalFull AISignal = "full"
+	// AISignalPartial means AI and human both contributed to the changes.
+	AISignalPartial AISignal = "partial"
+	// AISignalHuman means the message explicitly declares no AI involvement.
+	AISignalHuman AISignal = "human"
+)
+
+// DetectAISignal derives AI involvement from commit metadata alone, for
+// environments (such as CI) where the daemon never ran.
+//
+// Signals, strongest first:
+//
+//	AI-Assisted: yes|true|full    -> AISignalFull
+//	AI-Assisted: partial|mixed    -> AISignalPartial
+//	AI-Assisted: no|false|none    -> AISignalHuman
+//	Co-Authored-By: Claude ...    -> AISignalFull
+//	"Generated with Claude Code"  -> AISignalFull
+func DetectAISignal(message string) AISignal {
+	if m := aiAssistedRe.FindStringSubmatch(message); m != nil {
+		switch strings.ToLower(strings.TrimSpace(m[1])) {
+		case "yes", "true", "full":
+			return AISignalFull
+		case "partial", "mixed":
+			return AISignalPartial
+		case "no", "false", "none":
+			return AISignalHuman
+		}
+	}
+
+	for _, name := range AllCoAuthors(message) {
+		lower := strings.ToLower(name)
+		if strings.Contains(lower, "claude") || strings.Contains(lower, "anthropic") {
+			return AISignalFull
+		}
+	}
+
+	if aiMarkerRe.MatchString(message) {
+		return AISignalFull
+	}
+	return AISignalNone
+}
+
+// aiAssistedRe matches an "AI-Assisted: <value>" trailer (case insensitive).
+var aiAssistedRe = regexp.MustCompile(`(?im)^ai-assisted:\s*(\S+)\s*$`)
+
+// aiMarkerRe matches the footer AI tools append to generated commit messages.
+var aiMarkerRe = regexp.MustCompile(`(?i)generated (with|by) \[?claude`)
+
+// commitDiffs computes per-file diff stats for a commit, and its patch id:
+// a hash of the lines each file gained and lost, without line numbers or
+// context, so a commit keeps its patch id when it is rebased or amended
+// without changing its content.
 // For merge commits (multiple parents), diffs against first parent only.
 // For initial commits (no parents), all files are additions.
-func commitDiffs(c *object.Commit) ([]diffStat, error) {
+func commitDiffs(c *object.Commit) ([]diffStat, string, error) {
 	var parentTree *object.Tree
 	if c.NumParents() > 0 {
 		parent, err := c.Parent(0)
 		if err != nil {
-			return nil, err
+			return nil, "", err
 		}
 		parentTree, err = parent.Tree()
 		if err != nil {
-			return nil, err
+			return nil, "", err
 		}
 	}
 
 	commitTree, err := c.Tree()
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
 	changes, err := parentTree.Diff(commitTree)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
+	h := sha256.New()
 	var stats []diffStat
 	for _, change := range changes {
 		ds := diffStat{}
@@ -100,11 +161,13 @@ func commitDiffs(c *object.Commit) ([]diffStat, error) {
 			ds.ChangeType = "modify"
 			ds.FilePath = toName
 		}
+		h.Write([]byte(ds.ChangeType + " " + ds.FilePath + "\n"))
 
 		// Compute line-level stats using patch.
 		patch, err := change.Patch()
 		if err != nil {
 			// If patch fails (binary file, etc.), record without line counts.
+			h.Write([]byte(change.To.TreeEntry.Hash.String() + "\n"))
 			stats = append(stats, ds)
 			continue
 		}
@@ -122,8 +185,10 @@ func commitDiffs(c *object.Commit) ([]diffStat, error) {
 					// No additions or deletions.
 				case 1: // Add
 					ds.Additions += lineCount
+					h.Write([]byte("+" + content))
 				case 2: // Delete
 					ds.Deletions += lineCount
+					h.Write([]byte("-" + content))
 				}
 			}
 		}
@@ -131,5 +196,5 @@ func commitDiffs(c *object.Commit) ([]diffStat, error) {
 		stats = append(stats, ds)
 	}
 
-	return stats, nil
+	return stats, hex.EncodeToString(h.Sum(nil)), nil
 }
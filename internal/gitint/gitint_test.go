@@ -93,6 +93,30 @@ func TestAllCoAuthors(t *testing.T) {
 	}
 }
 
+func TestDetectAISignal(t *testing.T) {
+	cases := []struct {
+		name    string
+		message string
+		want    AISignal
+	}{
+		{"claude coauthor", "feat: x\n\nCo-Authored-By: Claude <noreply@anthropic.com>", AISignalFull},
+		{"other coauthor", "feat: x\n\nCo-Authored-By: Alice <alice@example.com>", AISignalNone},
+		{"generated footer", "feat: x\n\nGenerated with [Claude Code](https://claude.ai)", AISignalFull},
+		{"trailer full", "feat: x\n\nAI-Assisted: yes", AISignalFull},
+		{"trailer partial", "feat: x\n\nai-assisted: partial", AISignalPartial},
+		{"trailer overrides coauthor", "feat: x\n\nAI-Assisted: no\nCo-Authored-By: Claude", AISignalHuman},
+		{"plain message", "fix: typo in README", AISignalNone},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := DetectAISignal(tc.message); got != tc.want {
+				t.Errorf("DetectAISignal = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
 // --- Integration Tests (require temp git repo) ---
 
 func TestSyncCommitsAndDiffs(t *testing.T) {
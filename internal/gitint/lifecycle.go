@@ -0,0 +1,137 @@
+package gitint
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/anthropic/gap-map/internal/store"
+)
+
+// ArchivedBranch is a branch whose attributions CheckBranches archived.
+type ArchivedBranch struct {
+	Branch       string
+	Reason       string // store.ArchivedMerged or store.ArchivedDeleted
+	Attributions int64
+}
+
+// CheckBranches archives the attributions of the repository's branches
+// that are gone: deleted, locally and from origin, or merged into the
+// default branch. A branch counts as merged once its tip is in the default
+// branch and was committed after its newest attribution, so a new branch
+// with uncommitted work is left alone; the current and default branches
+// never are archived.
+func (r *Repository) CheckBranches(ctx context.Context) ([]ArchivedBranch, error) {
+	branches, err := r.store.QueryBranchAttributions(r.path)
+	if err != nil {
+		return nil, fmt.Errorf("query branches: %w", err)
+	}
+
+	current, _ := CurrentBranch(r.path)
+	def := DefaultBranch(r.path)
+	defTip := branchTip(ctx, r.path, def)
+
+	var archived []ArchivedBranch
+	for _, b := range branches {
+		if b.Archived != "" || b.Branch == current || b.Branch == def || isCommitHash(b.Branch) {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return archived, err
+		}
+
+		reason := ""
+		tip := branchTip(ctx, r.path, b.Branch)
+		switch {
+		case tip == "":
+			reason = store.ArchivedDeleted
+		case defTip != "":
+			if merged(ctx, r.path, tip, defTip) && !commitTime(ctx, r.path, tip).Before(b.Latest.Truncate(time.Second)) {
+				reason = store.ArchivedMerged
+			}
+		}
+		if reason == "" {
+			continue
+		}
+
+		n, err := r.store.ArchiveBranch(r.path, b.Branch, reason)
+		if err != nil {
+			return archived, fmt.Errorf("archive branch %s: %w", b.Branch, err)
+		}
+		archived = append(archived, ArchivedBranch{Branch: b.Branch, Reason: reason, Attributions: n})
+	}
+	return archived, nil
+}
+
+// DefaultBranch returns the repository's default branch: the branch
+// origin's HEAD points to, else main or master if one exists. Returns ""
+// when none is found.
+func DefaultBranch(repoPath string) string {
+	cmd := exec.Command("git", "symbolic-ref", "--short", "refs/remotes/origin/HEAD")
+	cmd.Dir = repoPath
+	if out, err := cmd.Output(); err == nil {
+		return strings.TrimPrefix(strings.TrimSpace(string(out)), "origin/")
+	}
+	for _, b := range []string{"main", "master"} {
+		if branchTip(context.Background(), repoPath, b) != "" {
+			return b
+		}
+	}
+	return ""
+}
+
+// branchTip returns the commit a branch points to, locally or on origin,
+// or "" if neither has it.
+func branchTip(ctx context.Context, repoPath, branch string) string {
+	if branch == "" {
+		return ""
+	}
+	for _, ref := range []string{"refs/heads/" + branch, "refs/remotes/origin/" + branch} {
+		cmd := exec.CommandContext(ctx, "git", "rev-parse", "--verify", "--quiet", ref+"^{commit}")
+		cmd.Dir = repoPath
+		if out, err := cmd.Output(); err == nil {
+			return strings.TrimSpace(string(out))
+		}
+	}
+	return ""
+}
+
+// merged reports whether commit is reachable from into.
+func merged(ctx context.Context, repoPath, commit, into string) bool {
+	cmd := exec.CommandContext(ctx, "git", "merge-base", "--is-ancestor", commit, into)
+	cmd.Dir = repoPath
+	return cmd.Run() == nil
+}
+
+// commitTime returns the committer time of commit, or the zero time if it
+// cannot be read.
+func commitTime(ctx context.Context, repoPath, commit string) time.Time {
+	cmd := exec.CommandContext(ctx, "git", "log", "-1", "--format=%ct", commit)
+	cmd.Dir = repoPath
+	out, err := cmd.Output()
+	if err != nil {
+		return time.Time{}
+	}
+	sec, err := strconv.ParseInt(strings.TrimSpace(string(out)), 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	return time.Unix(sec, 0)
+}
+
+// isCommitHash reports whether name is a full commit hash, the branch
+// CurrentBranch records on a detached HEAD.
+func isCommitHash(name string) bool {
+	if len(name) != 40 {
+		return false
+	}
+	for _, c := range name {
+		if !strings.ContainsRune("0123456789abcdef", c) {
+			return false
+		}
+	}
+	return true
+}
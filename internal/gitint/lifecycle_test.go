@@ -0,0 +1,95 @@
+package gitint
+
+import (
+	"context"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/anthropic/gap-map/internal/store"
+)
+
+func TestCheckBranches(t *testing.T) {
+	dir := t.TempDir()
+	gitInitShell(t, dir)
+
+	// merged: committed and merged into main.
+	gitCheckoutNewBranch(t, dir, "merged")
+	gitCommitFile(t, dir, "a.go", "package a\n", "add a")
+	gitCheckoutBranch(t, dir, "main")
+	run := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, out)
+		}
+	}
+	run("merge", "--no-ff", "-m", "merge", "merged")
+	// deleted: its branch is gone.
+	run("branch", "gone")
+	run("branch", "-D", "gone")
+	// fresh: just created from main, with work not committed yet.
+	run("branch", "fresh")
+	// open: has commits main lacks.
+	gitCheckoutNewBranch(t, dir, "open")
+	gitCommitFile(t, dir, "b.go", "package b\n", "add b")
+	gitCheckoutBranch(t, dir, "main")
+
+	s, err := store.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	past := time.Now().Add(-time.Hour)
+	for branch, ts := range map[string]time.Time{
+		"merged": past, "gone": past, "fresh": time.Now().Add(time.Minute), "open": past, "main": past,
+	} {
+		if _, err := s.InsertAttribution(store.AttributionRecord{
+			FilePath: filepath.Join(dir, branch+".go"), ProjectPath: dir, AuthorshipLevel: "mostly_ai",
+			Confidence: 1, FirstAuthor: "ai", Timestamp: ts, LinesChanged: 1, Branch: branch,
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	repo, err := Open(dir, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	archived, err := repo.CheckBranches(context.Background())
+	if err != nil {
+		t.Fatalf("CheckBranches: %v", err)
+	}
+	got := make(map[string]string)
+	for _, b := range archived {
+		got[b.Branch] = b.Reason
+	}
+	want := map[string]string{"merged": store.ArchivedMerged, "gone": store.ArchivedDeleted}
+	if len(got) != len(want) || got["merged"] != want["merged"] || got["gone"] != want["gone"] {
+		t.Errorf("CheckBranches archived %v, want %v", got, want)
+	}
+
+	// Archived branches drop out of branch queries, and a second check
+	// archives nothing new.
+	if attrs, err := s.QueryAttributionsByBranch(dir, "merged"); err != nil || len(attrs) != 0 {
+		t.Errorf("QueryAttributionsByBranch(merged) = %d, %v, want none", len(attrs), err)
+	}
+	if again, err := repo.CheckBranches(context.Background()); err != nil || len(again) != 0 {
+		t.Errorf("second CheckBranches = %v, %v, want nothing", again, err)
+	}
+
+	n, err := s.DeleteArchivedAttributions(dir)
+	if err != nil || n != 2 {
+		t.Errorf("DeleteArchivedAttributions = %d, %v, want 2", n, err)
+	}
+	branches, err := s.QueryBranchAttributions(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(branches) != 3 {
+		t.Errorf("branches after prune = %+v, want fresh, main and open", branches)
+	}
+}
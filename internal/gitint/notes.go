@@ -0,0 +1,88 @@
+package gitint
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// NotesRef is the git notes ref attribution is written to. Notes are not
+// pushed or fetched by default; share them with
+// `git push origin refs/notes/gapmap` and
+// `git fetch origin refs/notes/gapmap:refs/notes/gapmap`.
+const NotesRef = "refs/notes/gapmap"
+
+// WriteNote attaches data to commit under NotesRef, replacing any note the
+// commit already has.
+func WriteNote(repoPath, commit string, data []byte) error {
+	cmd := exec.Command("git", "notes", "--ref="+NotesRef, "add", "-f", "-F", "-", commit)
+	cmd.Dir = repoPath
+	cmd.Stdin = bytes.NewReader(data)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("git notes add %s: %w: %s", commit, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// ReadNote returns the note on commit under NotesRef, or nil if it has none.
+func ReadNote(repoPath, commit string) ([]byte, error) {
+	cmd := exec.Command("git", "notes", "--ref="+NotesRef, "show", commit)
+	cmd.Dir = repoPath
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if strings.Contains(stderr.String(), "no note found") {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("git notes show %s: %w: %s", commit, err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
+
+// LatestNote returns the note under NotesRef on the nearest commit reachable
+// from rev that has one, and that commit. It returns an empty commit and
+// nil data if no reachable commit has a note.
+func LatestNote(repoPath, rev string) (commit string, data []byte, err error) {
+	cmd := exec.Command("git", "notes", "--ref="+NotesRef, "list")
+	cmd.Dir = repoPath
+	out, err := cmd.Output()
+	if err != nil {
+		// A repository that never had notes has no ref to list.
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return "", nil, nil
+		}
+		return "", nil, fmt.Errorf("git notes list: %w", err)
+	}
+	annotated := make(map[string]bool)
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		// Each line is "<note blob> <annotated commit>".
+		if fields := strings.Fields(line); len(fields) == 2 {
+			annotated[fields[1]] = true
+		}
+	}
+	if len(annotated) == 0 {
+		return "", nil, nil
+	}
+
+	cmd = exec.Command("git", "rev-list", rev)
+	cmd.Dir = repoPath
+	out, err = cmd.Output()
+	if err != nil {
+		return "", nil, fmt.Errorf("git rev-list %s: %w", rev, err)
+	}
+	for _, hash := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		if !annotated[hash] {
+			continue
+		}
+		data, err := ReadNote(repoPath, hash)
+		if err != nil {
+			return "", nil, err
+		}
+		return hash, data, nil
+	}
+	return "", nil, nil
+}
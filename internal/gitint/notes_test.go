@@ -0,0 +1,48 @@
+package gitint
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNotes(t *testing.T) {
+	dir := t.TempDir()
+	gitInitShell(t, dir)
+
+	// No notes ref yet.
+	commit, data, err := LatestNote(dir, "HEAD")
+	if err != nil || commit != "" || data != nil {
+		t.Fatalf("LatestNote with no notes = %q, %q, %v", commit, data, err)
+	}
+
+	gitCommitFile(t, dir, "a.go", "package a\n", "add a")
+	annotated := gitRevParseHelper(t, dir, "HEAD")
+	if data, err := ReadNote(dir, annotated); err != nil || data != nil {
+		t.Fatalf("ReadNote before writing = %q, %v", data, err)
+	}
+	if err := WriteNote(dir, annotated, []byte(`{"v":1}`)); err != nil {
+		t.Fatal(err)
+	}
+	// Rewriting replaces the note.
+	if err := WriteNote(dir, annotated, []byte(`{"v":2}`)); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := ReadNote(dir, annotated); err != nil || strings.TrimSpace(string(data)) != `{"v":2}` {
+		t.Fatalf("ReadNote = %q, %v", data, err)
+	}
+
+	// Commits after the annotated one find its note.
+	gitCommitFile(t, dir, "b.go", "package a\n", "add b")
+	commit, data, err = LatestNote(dir, "HEAD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if commit != annotated || strings.TrimSpace(string(data)) != `{"v":2}` {
+		t.Errorf("LatestNote = %q, %q, want %q", commit, data, annotated)
+	}
+
+	// The init commit precedes every note.
+	if commit, _, err := LatestNote(dir, "HEAD~2"); err != nil || commit != "" {
+		t.Errorf("LatestNote(HEAD~2) = %q, %v, want none", commit, err)
+	}
+}
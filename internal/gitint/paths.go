@@ -0,0 +1,170 @@
+package gitint
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-billy/v5/osfs"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/format/gitignore"
+)
+
+// PathFilter tells which paths of a repository git leaves out of its
+// working tree: untracked paths ignored by .gitignore, .git/info/exclude
+// or core.excludesFile, and paths outside a sparse checkout, along with
+// the paths extra ignore rules, such as a .gapmap.toml's, match. It is
+// read once; build a new one after ignore files, the extra rules or the
+// sparse checkout change.
+//
+// A nil *PathFilter excludes nothing, so callers need not special-case
+// directories outside git.
+type PathFilter struct {
+	root    string
+	ignore  gitignore.Matcher
+	tracked map[string]bool   // repository-relative, slash-separated
+	sparse  []string          // skip-worktree entries; directories end in "/"
+	rules   gitignore.Matcher // extra rules; nil without any
+}
+
+// NewPathFilter reads the ignore rules and index of the repository
+// containing dir. rules are extra .gitignore-style patterns, relative to
+// the repository root, that exclude paths even when git tracks them.
+// Returns nil and an error if dir is not in a repository.
+func NewPathFilter(dir string, rules []string) (*PathFilter, error) {
+	repo, err := git.PlainOpenWithOptions(dir, &git.PlainOpenOptions{DetectDotGit: true})
+	if err != nil {
+		return nil, fmt.Errorf("open git repo at %s: %w", dir, err)
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		return nil, fmt.Errorf("open worktree: %w", err)
+	}
+
+	f := &PathFilter{
+		root:    wt.Filesystem.Root(),
+		tracked: make(map[string]bool),
+	}
+
+	// Lowest priority first: system, global, then the repository's own.
+	var patterns []gitignore.Pattern
+	rootFS := osfs.New("/")
+	if ps, err := gitignore.LoadSystemPatterns(rootFS); err == nil {
+		patterns = append(patterns, ps...)
+	}
+	if ps, err := gitignore.LoadGlobalPatterns(rootFS); err == nil {
+		patterns = append(patterns, ps...)
+	}
+	ps, err := gitignore.ReadPatterns(wt.Filesystem, nil)
+	if err != nil {
+		return nil, fmt.Errorf("read ignore files: %w", err)
+	}
+	f.ignore = gitignore.NewMatcher(append(patterns, ps...))
+	if len(rules) > 0 {
+		extra := make([]gitignore.Pattern, len(rules))
+		for i, r := range rules {
+			extra[i] = gitignore.ParsePattern(r, nil)
+		}
+		f.rules = gitignore.NewMatcher(extra)
+	}
+
+	// A repository without commits may have no index yet. go-git fails on
+	// the extension a sparse index adds after the entries, but the entries
+	// it read are complete.
+	if idx, _ := repo.Storer.Index(); idx != nil {
+		for _, e := range idx.Entries {
+			if e.SkipWorktree {
+				f.sparse = append(f.sparse, e.Name)
+				continue
+			}
+			f.tracked[e.Name] = true
+		}
+	}
+	return f, nil
+}
+
+// Root returns the top level of the repository's working tree.
+func (f *PathFilter) Root() string {
+	if f == nil {
+		return ""
+	}
+	return f.root
+}
+
+// Excluded reports whether path is left out of attribution: git leaves it
+// out of the working tree, being outside the sparse checkout or untracked
+// and ignored, or the extra rules match it, tracked or not. path is
+// absolute or relative to the repository root; paths outside the
+// repository are never excluded.
+func (f *PathFilter) Excluded(path string) bool {
+	rel, ok := f.rel(path)
+	if !ok {
+		return false
+	}
+	if f.outsideSparse(rel) {
+		return true
+	}
+	if f.matchesRules(rel) {
+		return true
+	}
+	if f.tracked[rel] {
+		return false
+	}
+	return f.ignore.Match(strings.Split(rel, "/"), f.isDir(rel))
+}
+
+// IgnoredByRules reports whether the extra rules match path.
+func (f *PathFilter) IgnoredByRules(path string) bool {
+	rel, ok := f.rel(path)
+	return ok && f.matchesRules(rel)
+}
+
+// matchesRules reports whether the extra rules match the relative path rel.
+func (f *PathFilter) matchesRules(rel string) bool {
+	return f.rules != nil && f.rules.Match(strings.Split(rel, "/"), f.isDir(rel))
+}
+
+// isDir reports whether the relative path rel is a directory.
+func (f *PathFilter) isDir(rel string) bool {
+	info, err := os.Stat(filepath.Join(f.root, filepath.FromSlash(rel)))
+	return err == nil && info.IsDir()
+}
+
+// Sparse reports whether path is outside the sparse checkout: in the
+// repository but not in the working tree.
+func (f *PathFilter) Sparse(path string) bool {
+	rel, ok := f.rel(path)
+	return ok && f.outsideSparse(rel)
+}
+
+// rel returns path relative to the repository root with forward slashes,
+// and false for paths outside the repository or its root itself.
+func (f *PathFilter) rel(path string) (string, bool) {
+	if f == nil {
+		return "", false
+	}
+	rel := path
+	if filepath.IsAbs(path) {
+		var err error
+		if rel, err = filepath.Rel(f.root, path); err != nil {
+			return "", false
+		}
+	}
+	rel = filepath.ToSlash(filepath.Clean(rel))
+	if rel == "." || rel == ".." || strings.HasPrefix(rel, "../") {
+		return "", false
+	}
+	return rel, true
+}
+
+// outsideSparse reports whether the relative path rel is a skip-worktree
+// entry or lies in a skip-worktree directory of a sparse index.
+func (f *PathFilter) outsideSparse(rel string) bool {
+	for _, s := range f.sparse {
+		if rel == strings.TrimSuffix(s, "/") || (strings.HasSuffix(s, "/") && strings.HasPrefix(rel, s)) {
+			return true
+		}
+	}
+	return false
+}
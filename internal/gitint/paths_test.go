@@ -0,0 +1,90 @@
+package gitint
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestPathFilter(t *testing.T) {
+	dir := t.TempDir()
+	gitInitShell(t, dir)
+	// Tracked files stay in even when a pattern matches them.
+	gitCommitFile(t, dir, "keep.log", "kept\n", "tracked log")
+	gitCommitFile(t, dir, ".gitignore", "*.log\nbuild/\n", "ignore")
+	gitCommitFile(t, dir, "src/main.go", "package main\n", "main")
+	// Extra rules ignore paths even when they are tracked.
+	gitCommitFile(t, dir, "gen/api.go", "package gen\n", "generated")
+	gitCommitFile(t, dir, "gen/keep.go", "package gen\n", "kept")
+	if err := os.WriteFile(filepath.Join(dir, ".git", "info", "exclude"), []byte("scratch/\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, d := range []string{"build", "scratch"} {
+		if err := os.MkdirAll(filepath.Join(dir, d), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	f, err := NewPathFilter(dir, []string{"gen/", "!gen/keep.go"})
+	if err != nil {
+		t.Fatalf("NewPathFilter: %v", err)
+	}
+	for path, want := range map[string]bool{
+		"src/main.go":                         false,
+		filepath.Join(dir, "src", "new.go"):   false,
+		"debug.log":                           true,
+		"keep.log":                            false,
+		"build":                               true,
+		"build/out.js":                        true,
+		filepath.Join(dir, "scratch", "x.go"): true,
+		"/elsewhere/debug.log":                false,
+		"gen/api.go":                          true,
+		"gen/keep.go":                         false,
+	} {
+		if got := f.Excluded(path); got != want {
+			t.Errorf("Excluded(%q) = %v, want %v", path, got, want)
+		}
+	}
+	if !f.IgnoredByRules("gen/api.go") || f.IgnoredByRules("debug.log") {
+		t.Error("IgnoredByRules does not tell the extra rules from git's ignore rules")
+	}
+
+	var none *PathFilter
+	if none.Excluded(filepath.Join(dir, "debug.log")) {
+		t.Error("nil PathFilter excluded a path")
+	}
+	if _, err := NewPathFilter(t.TempDir(), nil); err == nil {
+		t.Error("NewPathFilter outside a repository: expected error")
+	}
+}
+
+func TestPathFilterSparse(t *testing.T) {
+	for _, sparseIndex := range []bool{false, true} {
+		dir := t.TempDir()
+		gitInitShell(t, dir)
+		gitCommitFile(t, dir, "src/main.go", "package main\n", "main")
+		gitCommitFile(t, dir, "docs/guide.md", "# Guide\n", "docs")
+
+		args := []string{"sparse-checkout", "set", "src"}
+		if sparseIndex {
+			args = append(args, "--sparse-index")
+		}
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git sparse-checkout: %v\n%s", err, out)
+		}
+
+		f, err := NewPathFilter(dir, nil)
+		if err != nil {
+			t.Fatalf("NewPathFilter (sparse index %v): %v", sparseIndex, err)
+		}
+		if !f.Excluded(filepath.Join(dir, "docs", "guide.md")) || !f.Sparse("docs/guide.md") {
+			t.Errorf("sparse index %v: docs/guide.md not excluded", sparseIndex)
+		}
+		if f.Excluded("src/main.go") || f.Sparse("src/main.go") {
+			t.Errorf("sparse index %v: src/main.go excluded", sparseIndex)
+		}
+	}
+}
@@ -9,7 +9,7 @@ package gitint
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"strconv"
 	"time"
 
@@ -40,6 +40,11 @@ func Open(repoPath string, s *store.Store) (*Repository, error) {
 	}, nil
 }
 
+// Path returns the path the repository was opened at.
+func (r *Repository) Path() string {
+	return r.path
+}
+
 // SyncCommits scans commits since the given time and stores any that are new.
 // It uses daemon_state to track the last synced commit hash and avoid reprocessing.
 func (r *Repository) SyncCommits(ctx context.Context, since time.Time) error {
@@ -69,7 +74,7 @@ func (r *Repository) SyncCommits(ctx context.Context, since time.Time) error {
 	}
 	defer iter.Close()
 
-	var synced int
+	var synced []*object.Commit
 	err = iter.ForEach(func(c *object.Commit) error {
 		select {
 		case <-ctx.Done():
@@ -83,10 +88,10 @@ func (r *Repository) SyncCommits(ctx context.Context, since time.Time) error {
 		}
 
 		if err := r.processCommit(c); err != nil {
-			log.Printf("gitint: process commit %s: %v", c.Hash.String()[:7], err)
+			slog.Warn("gitint: process commit failed", "commit", c.Hash.String()[:7], "err", err)
 			// Continue processing other commits.
 		}
-		synced++
+		synced = append(synced, c)
 		return nil
 	})
 
@@ -94,8 +99,17 @@ func (r *Repository) SyncCommits(ctx context.Context, since time.Time) error {
 		return fmt.Errorf("iterate commits: %w", err)
 	}
 
+	// Link attributions to the commits their changes landed in, oldest
+	// commit first so each attribution gets the first commit after it.
+	for i := len(synced) - 1; i >= 0; i-- {
+		c := synced[i]
+		if _, err := r.store.LinkAttributionsToCommit(r.path, c.Hash.String(), c.Committer.When); err != nil {
+			slog.Warn("gitint: link attributions failed", "commit", c.Hash.String()[:7], "err", err)
+		}
+	}
+
 	// Update last synced commit.
-	if synced > 0 {
+	if len(synced) > 0 {
 		if err := r.store.SetDaemonState("git_last_synced_commit", head.Hash().String()); err != nil {
 			return fmt.Errorf("set last synced commit: %w", err)
 		}
@@ -117,15 +131,18 @@ func (r *Repository) processCommit(c *object.Commit) error {
 	}
 
 	// Compute diffs.
-	diffs, err := commitDiffs(c)
+	diffs, patchID, err := commitDiffs(c)
 	if err != nil {
-		log.Printf("gitint: diff for %s: %v", hash[:7], err)
+		slog.Warn("gitint: diff failed", "commit", hash[:7], "err", err)
 		return nil // Non-fatal: store the commit even if diffs fail.
 	}
+	if err := r.store.SetCommitPatchID(hash, patchID); err != nil {
+		slog.Warn("gitint: patch id failed", "commit", hash[:7], "err", err)
+	}
 
 	for _, d := range diffs {
 		if err := r.store.InsertGitDiff(commitID, d.FilePath, d.OldPath, d.ChangeType, d.Additions, d.Deletions); err != nil {
-			log.Printf("gitint: insert diff for %s %s: %v", hash[:7], d.FilePath, err)
+			slog.Warn("gitint: insert diff failed", "commit", hash[:7], "file", d.FilePath, "err", err)
 		}
 	}
 
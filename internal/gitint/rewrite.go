@@ -0,0 +1,250 @@
+package gitint
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+
+	"github.com/anthropic/gap-map/internal/store"
+)
+
+// reflogOffsetKey is the daemon_state key holding how far the HEAD reflog
+// has been read.
+const reflogOffsetKey = "git_reflog_offset"
+
+// ReflogEntry is one line of a reflog: HEAD moved from Old to New.
+type ReflogEntry struct {
+	Old     string
+	New     string
+	Message string
+}
+
+// IsRewrite reports whether the entry records a history rewrite: an amended
+// commit, or the end of a rebase (including pull --rebase).
+func (e ReflogEntry) IsRewrite() bool {
+	return strings.HasPrefix(e.Message, "commit (amend)") || strings.Contains(e.Message, "(finish)")
+}
+
+// ReadReflog returns the complete entries of the reflog at path after byte
+// offset, and the offset after them. A reflog shorter than offset, e.g.
+// after "git reflog expire", is read from the start.
+func ReadReflog(path string, offset int64) ([]ReflogEntry, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, offset, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, offset, err
+	}
+	if info.Size() < offset {
+		offset = 0
+	}
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return nil, offset, err
+	}
+
+	var entries []ReflogEntry
+	r := bufio.NewReader(f)
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			// A partial last line is read again once it is complete.
+			break
+		}
+		offset += int64(len(line))
+
+		// "<old> <new> <name> <email> <time> <tz>\t<message>"
+		head, message, _ := strings.Cut(strings.TrimSuffix(line, "\n"), "\t")
+		fields := strings.Fields(head)
+		if len(fields) < 2 {
+			continue
+		}
+		entries = append(entries, ReflogEntry{Old: fields[0], New: fields[1], Message: message})
+	}
+	return entries, offset, nil
+}
+
+// RewriteResult summarizes a re-attribution after a history rewrite.
+type RewriteResult struct {
+	Rewritten    int   // rewritten commits mapped to the commits replacing them
+	Attributions int64 // attributions moved to the new commits
+	Reblamed     int   // files whose blame data was refreshed
+}
+
+// CheckRewrites reads the HEAD reflog entries written since the last check
+// and, if one records an amend or a finished rebase, runs Reattribute. The
+// first check only notes where the reflog ends. It reports whether a
+// rewrite was found.
+func (r *Repository) CheckRewrites(ctx context.Context) (RewriteResult, bool, error) {
+	path := filepath.Join(r.path, ".git", "logs", "HEAD")
+	stored, err := r.store.GetDaemonState(reflogOffsetKey)
+	if err != nil {
+		return RewriteResult{}, false, fmt.Errorf("get reflog offset: %w", err)
+	}
+	if stored == "" {
+		info, err := os.Stat(path)
+		if err != nil {
+			return RewriteResult{}, false, nil // no reflog yet
+		}
+		return RewriteResult{}, false, r.store.SetDaemonState(reflogOffsetKey, strconv.FormatInt(info.Size(), 10))
+	}
+	offset, _ := strconv.ParseInt(stored, 10, 64)
+
+	entries, next, err := ReadReflog(path, offset)
+	if err != nil {
+		return RewriteResult{}, false, fmt.Errorf("read reflog: %w", err)
+	}
+
+	rewritten := false
+	amended := make(map[string]string)
+	for _, e := range entries {
+		if !e.IsRewrite() {
+			continue
+		}
+		rewritten = true
+		if strings.HasPrefix(e.Message, "commit (amend)") {
+			amended[e.Old] = e.New
+		}
+	}
+
+	var result RewriteResult
+	if rewritten {
+		if result, err = r.Reattribute(ctx, amended); err != nil {
+			return result, true, err
+		}
+	}
+	if next != offset {
+		if err := r.store.SetDaemonState(reflogOffsetKey, strconv.FormatInt(next, 10)); err != nil {
+			return result, rewritten, fmt.Errorf("set reflog offset: %w", err)
+		}
+	}
+	return result, rewritten, nil
+}
+
+// Reattribute follows synced commits that a rewrite took out of history to
+// the commits that replaced them, and moves their attributions, blame lines,
+// survival records and work type overrides there. A commit is matched by
+// its patch id, so a rebased commit or an amend that kept the content is
+// found by content; amended maps old to new hashes from the reflog for
+// amends that changed it. Blame data of the files the new commits changed
+// is refreshed so survival checks see the rewritten history. Commits must
+// have been synced with SyncCommits first.
+func (r *Repository) Reattribute(ctx context.Context, amended map[string]string) (RewriteResult, error) {
+	var result RewriteResult
+	since := time.Now().Add(-DefaultLookback())
+
+	// Commits reachable from any ref are still in history.
+	reachable := make(map[string]bool)
+	iter, err := r.repo.Log(&git.LogOptions{All: true, Since: &since})
+	if err != nil {
+		return result, fmt.Errorf("git log: %w", err)
+	}
+	err = iter.ForEach(func(c *object.Commit) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		reachable[c.Hash.String()] = true
+		return nil
+	})
+	iter.Close()
+	if err != nil {
+		return result, fmt.Errorf("iterate commits: %w", err)
+	}
+
+	commits, err := r.store.QueryLiveCommitsSince(since)
+	if err != nil {
+		return result, fmt.Errorf("query commits: %w", err)
+	}
+	var orphans []store.StoredCommit
+	byPatch := make(map[string]string) // patch id -> newest live commit
+	for _, c := range commits {
+		if !reachable[c.Hash] {
+			orphans = append(orphans, c)
+			continue
+		}
+		if patchID := r.patchID(c); patchID != "" && byPatch[patchID] == "" {
+			byPatch[patchID] = c.Hash
+		}
+	}
+
+	rewrites := make(map[string]string)
+	for _, old := range orphans {
+		if next, ok := amended[old.Hash]; ok && reachable[next] {
+			rewrites[old.Hash] = next
+			continue
+		}
+		if next, ok := byPatch[r.patchID(old)]; ok {
+			rewrites[old.Hash] = next
+		}
+	}
+	if len(rewrites) == 0 {
+		return result, nil
+	}
+
+	result.Rewritten = len(rewrites)
+	if result.Attributions, err = r.store.RemapCommits(rewrites); err != nil {
+		return result, fmt.Errorf("remap commits: %w", err)
+	}
+
+	// Refresh blame for the files the new commits changed, where blame
+	// data is kept.
+	files := make(map[string]bool)
+	for _, next := range rewrites {
+		c, err := r.repo.CommitObject(plumbing.NewHash(next))
+		if err != nil {
+			continue
+		}
+		diffs, _, err := commitDiffs(c)
+		if err != nil {
+			continue
+		}
+		for _, d := range diffs {
+			files[d.FilePath] = true
+		}
+	}
+	for file := range files {
+		lines, err := r.store.QueryBlameLinesByFile(file)
+		if err != nil || len(lines) == 0 {
+			continue
+		}
+		if err := BlameAndStore(r.repo, r.store, file); err != nil {
+			slog.Warn("gitint: blame failed", "file", file, "err", err)
+			continue
+		}
+		result.Reblamed++
+	}
+	return result, nil
+}
+
+// patchID returns the patch id of a synced commit. Commits synced before
+// patch ids were recorded get theirs computed and stored; it is "" if the
+// commit cannot be read.
+func (r *Repository) patchID(sc store.StoredCommit) string {
+	if sc.PatchID != "" {
+		return sc.PatchID
+	}
+	c, err := r.repo.CommitObject(plumbing.NewHash(sc.Hash))
+	if err != nil {
+		return ""
+	}
+	_, patchID, err := commitDiffs(c)
+	if err != nil {
+		return ""
+	}
+	_ = r.store.SetCommitPatchID(sc.Hash, patchID)
+	return patchID
+}
@@ -0,0 +1,132 @@
+package gitint
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	gogit "github.com/go-git/go-git/v5"
+
+	"github.com/anthropic/gap-map/internal/store"
+)
+
+func TestReadReflog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "HEAD")
+	log := "0000 aaaa Dev <dev@example.com> 1700000000 +0000\tcommit (initial): first\n" +
+		"aaaa bbbb Dev <dev@example.com> 1700000100 +0000\tcommit (amend): first, fixed\n" +
+		"bbbb cccc Dev <dev@example.com> 1700000200 +0000\trebase (finish): returning to refs/heads/main\n" +
+		"cccc dddd Dev <dev@example.com> 1700000300 +0000\tcommit: partial"
+	if err := os.WriteFile(path, []byte(log), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, next, err := ReadReflog(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The last line has no newline yet, so it is not read.
+	if len(entries) != 3 {
+		t.Fatalf("entries = %+v, want 3", entries)
+	}
+	if e := entries[1]; e.Old != "aaaa" || e.New != "bbbb" || !e.IsRewrite() {
+		t.Errorf("amend entry = %+v, want a rewrite from aaaa to bbbb", e)
+	}
+	if entries[0].IsRewrite() || !entries[2].IsRewrite() {
+		t.Errorf("IsRewrite = %v, %v; want false, true", entries[0].IsRewrite(), entries[2].IsRewrite())
+	}
+
+	if more, _, err := ReadReflog(path, next); err != nil || len(more) != 0 {
+		t.Errorf("ReadReflog from %d = %v, %v; want nothing new", next, more, err)
+	}
+	// A reflog shorter than the offset was expired and is read again.
+	if again, _, _ := ReadReflog(path, next+1000); len(again) != 3 {
+		t.Errorf("ReadReflog past the end = %d entries, want 3", len(again))
+	}
+}
+
+func TestReattributeAfterAmend(t *testing.T) {
+	tmpDir := t.TempDir()
+	repo := initTestRepo(t, tmpDir)
+	wt, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writeFile(t, tmpDir, "base.go", "package main\n")
+	if _, err := wt.Add("base.go"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wt.Commit("base", &gogit.CommitOptions{Author: testAuthor()}); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := store.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	// Claude writes a file, which is then committed.
+	writeFile(t, tmpDir, "feature.go", "package main\n\nfunc Feature() {}\n")
+	if _, err := s.InsertAttribution(store.AttributionRecord{
+		FilePath:        filepath.Join(tmpDir, "feature.go"),
+		ProjectPath:     tmpDir,
+		AuthorshipLevel: "mostly_ai",
+		Timestamp:       time.Now().Add(-time.Minute),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wt.Add("feature.go"); err != nil {
+		t.Fatal(err)
+	}
+	original, err := wt.Commit("add feature", &gogit.CommitOptions{Author: testAuthor()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := Open(tmpDir, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	since := time.Now().Add(-time.Hour)
+	if err := r.SyncCommits(context.Background(), since); err != nil {
+		t.Fatal(err)
+	}
+	if hash, _ := s.QueryCommitForFile(filepath.Join(tmpDir, "feature.go")); hash != original.String() {
+		t.Fatalf("attribution commit = %q, want %s", hash, original)
+	}
+
+	// Reword the commit: same content, new hash.
+	amended, err := wt.Commit("add feature (reworded)", &gogit.CommitOptions{Author: testAuthor(), Amend: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.SyncCommits(context.Background(), since); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := r.Reattribute(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Rewritten != 1 || res.Attributions != 1 {
+		t.Errorf("Reattribute = %+v, want 1 commit and 1 attribution remapped", res)
+	}
+	if hash, _ := s.QueryCommitForFile(filepath.Join(tmpDir, "feature.go")); hash != amended.String() {
+		t.Errorf("attribution commit = %q, want amended %s", hash, amended)
+	}
+	var rewrittenTo string
+	if err := s.DB().QueryRow(`SELECT rewritten_to FROM git_commits WHERE hash = ?`, original.String()).Scan(&rewrittenTo); err != nil {
+		t.Fatal(err)
+	}
+	if rewrittenTo != amended.String() {
+		t.Errorf("rewritten_to = %q, want %s", rewrittenTo, amended)
+	}
+
+	// Nothing left to remap.
+	if res, _ := r.Reattribute(context.Background(), nil); res.Rewritten != 0 {
+		t.Errorf("second Reattribute = %+v, want no rewrites", res)
+	}
+}
@@ -0,0 +1,172 @@
+package gitint
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// Stack sources: how the layers of a Stack were found.
+const (
+	StackGraphite  = "graphite"   // Graphite's refs/branch-metadata
+	StackMergeBase = "merge-base" // local branches chained by ancestry
+)
+
+// Stack is a chain of stacked branches, each based on the one before it.
+type Stack struct {
+	Base     string   // the branch the bottom layer is based on
+	Branches []string // bottom layer first; the last is the top
+	Source   string   // StackGraphite or StackMergeBase
+}
+
+// Parent returns the branch layer i of the stack is based on.
+func (st Stack) Parent(i int) string {
+	if i == 0 {
+		return st.Base
+	}
+	return st.Branches[i-1]
+}
+
+// DiscoverStack returns the stack whose top is branch. Graphite's branch
+// metadata is used when branch has any, following parents down to base, or
+// to trunk when base is empty. Otherwise the stack is chained by merge-base:
+// each layer's parent is the local branch whose tip is the nearest ancestor
+// of the layer's tip with commits of its own over base (by default the
+// repository's default branch, else main).
+func DiscoverStack(repoPath, branch, base string) (Stack, error) {
+	if branch == "" {
+		return Stack{}, fmt.Errorf("no branch to discover a stack for")
+	}
+	if parent, ok := graphiteParent(repoPath, branch); ok {
+		st := Stack{Source: StackGraphite}
+		seen := map[string]bool{}
+		for b := branch; ; {
+			if seen[b] {
+				return Stack{}, fmt.Errorf("graphite metadata for %s has a cycle", b)
+			}
+			seen[b] = true
+			st.Branches = append([]string{b}, st.Branches...)
+			if parent == base {
+				break
+			}
+			next, ok := graphiteParent(repoPath, parent)
+			if !ok {
+				if base != "" {
+					return Stack{}, fmt.Errorf("graphite stack of %s ends at %s, not %s", branch, parent, base)
+				}
+				break
+			}
+			b, parent = parent, next
+		}
+		st.Base = parent
+		return st, nil
+	}
+
+	if base == "" {
+		if base = DefaultBranch(repoPath); base == "" {
+			base = "main"
+		}
+	}
+	baseTip := branchTip(context.Background(), repoPath, base)
+	if baseTip == "" {
+		return Stack{}, fmt.Errorf("base branch %s not found", base)
+	}
+	tips, err := localBranchTips(repoPath)
+	if err != nil {
+		return Stack{}, err
+	}
+	tip, ok := tips[branch]
+	if !ok {
+		return Stack{}, fmt.Errorf("branch %s not found", branch)
+	}
+
+	// Candidate parents: branches with commits of their own over base.
+	ahead := make(map[string]int)
+	for b, t := range tips {
+		if b == base || b == branch {
+			continue
+		}
+		if n := countCommits(repoPath, baseTip, t); n > 0 {
+			ahead[b] = n
+		}
+	}
+
+	st := Stack{Base: base, Branches: []string{branch}, Source: StackMergeBase}
+	for {
+		var parent string
+		for b, n := range ahead {
+			t := tips[b]
+			if t == tip || !isAncestor(repoPath, t, tip) {
+				continue
+			}
+			if parent == "" || n > ahead[parent] || (n == ahead[parent] && b < parent) {
+				parent = b
+			}
+		}
+		if parent == "" {
+			return st, nil
+		}
+		st.Branches = append([]string{parent}, st.Branches...)
+		tip = tips[parent]
+		delete(ahead, parent)
+	}
+}
+
+// graphiteParent returns the parent Graphite recorded for branch in
+// refs/branch-metadata, and whether it recorded one.
+func graphiteParent(repoPath, branch string) (string, bool) {
+	cmd := exec.Command("git", "cat-file", "blob", "refs/branch-metadata/"+branch)
+	cmd.Dir = repoPath
+	out, err := cmd.Output()
+	if err != nil {
+		return "", false
+	}
+	var meta struct {
+		ParentBranchName string `json:"parentBranchName"`
+	}
+	if err := json.Unmarshal(out, &meta); err != nil || meta.ParentBranchName == "" {
+		return "", false
+	}
+	return meta.ParentBranchName, true
+}
+
+// localBranchTips returns the commit each local branch points to.
+func localBranchTips(repoPath string) (map[string]string, error) {
+	cmd := exec.Command("git", "for-each-ref", "--format=%(refname:short) %(objectname)", "refs/heads")
+	cmd.Dir = repoPath
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("list branches: %w", err)
+	}
+	tips := make(map[string]string)
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		if name, commit, ok := strings.Cut(line, " "); ok {
+			tips[name] = commit
+		}
+	}
+	return tips, nil
+}
+
+// isAncestor reports whether commit ancestor is an ancestor of (or is)
+// commit descendant.
+func isAncestor(repoPath, ancestor, descendant string) bool {
+	cmd := exec.Command("git", "merge-base", "--is-ancestor", ancestor, descendant)
+	cmd.Dir = repoPath
+	return cmd.Run() == nil
+}
+
+// countCommits returns the number of commits reachable from to but not
+// from from.
+func countCommits(repoPath, from, to string) int {
+	cmd := exec.Command("git", "rev-list", "--count", from+".."+to)
+	cmd.Dir = repoPath
+	out, err := cmd.Output()
+	if err != nil {
+		return 0
+	}
+	n, _ := strconv.Atoi(strings.TrimSpace(string(out)))
+	return n
+}
@@ -0,0 +1,73 @@
+package gitint
+
+import (
+	"os/exec"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDiscoverStack(t *testing.T) {
+	dir := t.TempDir()
+	gitInitShell(t, dir)
+
+	// main → feat-a → feat-b → feat-c, with an unrelated branch off
+	// main and a branch that has no commits of its own.
+	gitCheckoutNewBranch(t, dir, "feat-a")
+	gitCommitFile(t, dir, "a.go", "package a\n", "add a")
+	gitCheckoutNewBranch(t, dir, "feat-b")
+	gitCommitFile(t, dir, "b.go", "package b\n", "add b")
+	gitCheckoutNewBranch(t, dir, "feat-c")
+	gitCommitFile(t, dir, "c.go", "package c\n", "add c")
+	gitCheckoutBranch(t, dir, "main")
+	gitCheckoutNewBranch(t, dir, "other")
+	gitCommitFile(t, dir, "o.go", "package o\n", "add o")
+	gitCheckoutNewBranch(t, dir, "empty")
+
+	st, err := DiscoverStack(dir, "feat-c", "main")
+	if err != nil {
+		t.Fatalf("DiscoverStack: %v", err)
+	}
+	want := Stack{Base: "main", Branches: []string{"feat-a", "feat-b", "feat-c"}, Source: StackMergeBase}
+	if !reflect.DeepEqual(st, want) {
+		t.Errorf("merge-base stack = %+v, want %+v", st, want)
+	}
+	if st.Parent(0) != "main" || st.Parent(2) != "feat-b" {
+		t.Errorf("parents = %s, %s", st.Parent(0), st.Parent(2))
+	}
+
+	// Graphite metadata wins over ancestry: here feat-c is recorded
+	// directly on feat-a.
+	for branch, parent := range map[string]string{"feat-a": "main", "feat-c": "feat-a"} {
+		setGraphiteParent(t, dir, branch, parent)
+	}
+	st, err = DiscoverStack(dir, "feat-c", "")
+	if err != nil {
+		t.Fatalf("DiscoverStack (graphite): %v", err)
+	}
+	want = Stack{Base: "main", Branches: []string{"feat-a", "feat-c"}, Source: StackGraphite}
+	if !reflect.DeepEqual(st, want) {
+		t.Errorf("graphite stack = %+v, want %+v", st, want)
+	}
+	if _, err := DiscoverStack(dir, "feat-c", "develop"); err == nil {
+		t.Error("graphite stack that never reaches the base: want error")
+	}
+}
+
+// setGraphiteParent records parent as branch's parent the way Graphite
+// does, in a blob under refs/branch-metadata.
+func setGraphiteParent(t *testing.T, dir, branch, parent string) {
+	t.Helper()
+	cmd := exec.Command("git", "hash-object", "-w", "--stdin")
+	cmd.Dir = dir
+	cmd.Stdin = strings.NewReader(`{"parentBranchName":"` + parent + `","parentBranchRevision":"0"}`)
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("git hash-object: %v", err)
+	}
+	cmd = exec.Command("git", "update-ref", "refs/branch-metadata/"+branch, strings.TrimSpace(string(out)))
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git update-ref: %v\n%s", err, out)
+	}
+}
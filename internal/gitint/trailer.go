@@ -0,0 +1,48 @@
+package gitint
+
+import (
+	"path/filepath"
+	"time"
+
+	"github.com/anthropic/gap-map/internal/store"
+)
+
+// SessionAISignal derives the AI involvement of a commit of files, given
+// relative to root, from the session events stored for them between start
+// and end: AISignalFull when AI edited every file, AISignalPartial when it
+// edited some, and AISignalNone otherwise. None rather than
+// AISignalHuman, since edits from tools gap-map does not watch leave no
+// session events.
+func SessionAISignal(s *store.Store, root string, files []string, start, end time.Time) (AISignal, error) {
+	edited := 0
+	for _, f := range files {
+		events, err := s.QuerySessionEventsInWindow(filepath.Join(root, f), start, end)
+		if err != nil {
+			return AISignalNone, err
+		}
+		if len(events) > 0 {
+			edited++
+		}
+	}
+	switch {
+	case edited == 0:
+		return AISignalNone, nil
+	case edited == len(files):
+		return AISignalFull, nil
+	}
+	return AISignalPartial, nil
+}
+
+// AITrailer returns the "AI-Assisted:" trailer that DetectAISignal reads
+// back as signal, or "" for AISignalNone.
+func AITrailer(signal AISignal) string {
+	switch signal {
+	case AISignalFull:
+		return "AI-Assisted: yes"
+	case AISignalPartial:
+		return "AI-Assisted: partial"
+	case AISignalHuman:
+		return "AI-Assisted: no"
+	}
+	return ""
+}
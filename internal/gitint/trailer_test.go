@@ -0,0 +1,51 @@
+package gitint
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/anthropic/gap-map/internal/store"
+)
+
+func TestSessionAISignal(t *testing.T) {
+	root := t.TempDir()
+	s, err := store.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	now := time.Now()
+	if err := s.InsertSessionEvent("sess", "tool_use", "Edit", filepath.Join(root, "a.go"), "h",
+		now.Add(-time.Hour), `{"uuid":"u-1"}`, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.InsertSessionEvent("sess", "tool_use", "Edit", filepath.Join(root, "old.go"), "h",
+		now.Add(-48*time.Hour), `{"uuid":"u-2"}`, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	start := now.Add(-CoauthorWindow)
+	tests := []struct {
+		files []string
+		want  AISignal
+	}{
+		{[]string{"a.go"}, AISignalFull},
+		{[]string{"a.go", "b.go"}, AISignalPartial},
+		{[]string{"b.go"}, AISignalNone},
+		{[]string{"old.go"}, AISignalNone},
+	}
+	for _, tt := range tests {
+		got, err := SessionAISignal(s, root, tt.files, start, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("SessionAISignal(%v) = %q, want %q", tt.files, got, tt.want)
+		}
+		if trailer := AITrailer(got); got != AISignalNone && DetectAISignal("msg\n\n"+trailer) != got {
+			t.Errorf("trailer %q does not read back as %q", trailer, got)
+		}
+	}
+}
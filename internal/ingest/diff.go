@@ -0,0 +1,133 @@
+// Package ingest records edits made outside the tools gap-map can tail,
+// given as unified diffs, as synthetic file and session events.
+package ingest
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// FileDiff is one file's changes in a unified diff.
+type FileDiff struct {
+	OldPath string   // "" for a created file
+	NewPath string   // "" for a deleted file
+	Added   []string // added lines, without the leading "+"
+	Deleted []string // removed lines, without the leading "-"
+}
+
+// Path returns the file's path after the change, or before it for a
+// deleted file.
+func (d FileDiff) Path() string {
+	if d.NewPath != "" {
+		return d.NewPath
+	}
+	return d.OldPath
+}
+
+// ParseUnifiedDiff parses the output of git diff or diff -u into its files.
+// The a/ and b/ prefixes git adds are removed; other paths are kept as
+// given. Files without line changes, such as pure renames, are skipped.
+func ParseUnifiedDiff(r io.Reader) ([]FileDiff, error) {
+	var (
+		diffs            []FileDiff
+		cur              *FileDiff
+		oldLeft, newLeft int // lines of the current hunk still to read
+	)
+	flush := func() {
+		if cur != nil && (len(cur.Added) > 0 || len(cur.Deleted) > 0) {
+			diffs = append(diffs, *cur)
+		}
+		cur = nil
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if oldLeft > 0 || newLeft > 0 {
+			switch {
+			case strings.HasPrefix(line, "+"):
+				cur.Added = append(cur.Added, line[1:])
+				newLeft--
+			case strings.HasPrefix(line, "-"):
+				cur.Deleted = append(cur.Deleted, line[1:])
+				oldLeft--
+			case strings.HasPrefix(line, `\`):
+				// "\ No newline at end of file"
+			default:
+				// Context; some tools strip the space of empty lines.
+				oldLeft--
+				newLeft--
+			}
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(line, "diff "):
+			flush()
+		case strings.HasPrefix(line, "--- "):
+			flush()
+			cur = &FileDiff{OldPath: diffPath(line[4:], "a/")}
+		case strings.HasPrefix(line, "+++ "):
+			if cur == nil {
+				return nil, fmt.Errorf("%q without a preceding --- line", line)
+			}
+			cur.NewPath = diffPath(line[4:], "b/")
+		case strings.HasPrefix(line, "@@"):
+			if cur == nil || cur.Path() == "" {
+				return nil, fmt.Errorf("hunk %q before a file header", line)
+			}
+			var err error
+			if oldLeft, newLeft, err = hunkLengths(line); err != nil {
+				return nil, err
+			}
+		}
+		// Anything else is a header such as "index ..." or
+		// "new file mode ...".
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read diff: %w", err)
+	}
+	if oldLeft > 0 || newLeft > 0 {
+		return nil, fmt.Errorf("diff of %s ends inside a hunk", cur.Path())
+	}
+	flush()
+	return diffs, nil
+}
+
+// hunkLengths returns the old and new line counts of a hunk header,
+// "@@ -a,b +c,d @@"; an omitted count is 1.
+func hunkLengths(header string) (oldLen, newLen int, err error) {
+	fields := strings.Fields(header)
+	if len(fields) < 3 || !strings.HasPrefix(fields[1], "-") || !strings.HasPrefix(fields[2], "+") {
+		return 0, 0, fmt.Errorf("malformed hunk header %q", header)
+	}
+	length := func(r string) (int, error) {
+		_, n, ok := strings.Cut(r[1:], ",")
+		if !ok {
+			return 1, nil
+		}
+		return strconv.Atoi(n)
+	}
+	if oldLen, err = length(fields[1]); err != nil {
+		return 0, 0, fmt.Errorf("malformed hunk header %q", header)
+	}
+	if newLen, err = length(fields[2]); err != nil {
+		return 0, 0, fmt.Errorf("malformed hunk header %q", header)
+	}
+	return oldLen, newLen, nil
+}
+
+// diffPath returns the path of a ---/+++ header, "" for /dev/null.
+func diffPath(header, prefix string) string {
+	// diff -u appends a tab and the file's modification time.
+	path, _, _ := strings.Cut(header, "\t")
+	path = strings.TrimSpace(path)
+	if path == "/dev/null" {
+		return ""
+	}
+	return strings.TrimPrefix(path, prefix)
+}
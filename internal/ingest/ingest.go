@@ -0,0 +1,171 @@
+package ingest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/anthropic/gap-map/internal/sessionparser"
+	"github.com/anthropic/gap-map/internal/store"
+)
+
+// Authors a diff can be ingested as.
+const (
+	AuthorAI    = "ai"
+	AuthorHuman = "human"
+)
+
+// Meta describes who made the changes of an ingested diff, and when.
+type Meta struct {
+	Author    string    // AuthorAI or AuthorHuman
+	Timestamp time.Time // when the edits were made
+	SessionID string    // AI session the edits belong to; derived from the diff if empty
+	Model     string    // model that made AI edits, if known
+}
+
+// Result counts what Diff recorded.
+type Result struct {
+	SessionID     string // "" for human edits
+	FileEvents    int
+	SessionEvents int
+	AddedLines    int
+	DeletedLines  int
+}
+
+// Diff records each file of diffs as a file event in projectPath, and for
+// AI edits a matching session event carrying the added and removed lines,
+// so the daemon's attribution pipeline treats them like a watched edit
+// and a tailed tool call. Relative paths are resolved against projectPath.
+//
+// Session events are keyed by their content, so ingesting the same diff
+// with the same metadata again adds file events but no session events.
+func Diff(s *store.Store, projectPath string, diffs []FileDiff, meta Meta) (Result, error) {
+	var res Result
+	switch meta.Author {
+	case AuthorAI, AuthorHuman:
+	default:
+		return res, fmt.Errorf("unknown author %q (want %s or %s)", meta.Author, AuthorAI, AuthorHuman)
+	}
+	if meta.Timestamp.IsZero() {
+		meta.Timestamp = time.Now()
+	}
+	if meta.Author == AuthorAI {
+		res.SessionID = meta.SessionID
+		if res.SessionID == "" {
+			res.SessionID = "ingest/" + diffKey(diffs, meta.Timestamp)[:12]
+		}
+	}
+
+	for _, d := range diffs {
+		path := d.Path()
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(projectPath, path)
+		}
+		eventType := "modify"
+		switch {
+		case d.OldPath == "":
+			eventType = "create"
+		case d.NewPath == "":
+			eventType = "delete"
+		}
+
+		if meta.Author == AuthorAI {
+			rawJSON, toolName, err := sessionLine(res.SessionID, path, d, meta)
+			if err != nil {
+				return res, fmt.Errorf("encode session event for %s: %w", path, err)
+			}
+			added, deleted := sessionparser.ExtractContent(rawJSON)
+			if err := s.InsertSessionEventWithContext(
+				res.SessionID, "tool_use", toolName, path,
+				hashContent(strings.Join(d.Added, "\n")), meta.Timestamp, rawJSON,
+				len(d.Added), store.SessionEventContext{
+					Model:   meta.Model,
+					Content: &store.SessionContent{Added: added, Deleted: deleted},
+				},
+			); err != nil {
+				return res, fmt.Errorf("insert session event for %s: %w", path, err)
+			}
+			res.SessionEvents++
+		}
+		if err := s.InsertFileEvent(projectPath, path, eventType, meta.Timestamp); err != nil {
+			return res, fmt.Errorf("insert file event for %s: %w", path, err)
+		}
+		res.FileEvents++
+		res.AddedLines += len(d.Added)
+		res.DeletedLines += len(d.Deleted)
+	}
+	return res, nil
+}
+
+// sessionLine builds a session line in the Claude Code format for an AI
+// edit, so diff and deleted content extraction read it like a tailed one:
+// a Write for a created file and an Edit otherwise.
+func sessionLine(sessionID, path string, d FileDiff, meta Meta) (string, string, error) {
+	type block struct {
+		Type  string         `json:"type"`
+		Name  string         `json:"name"`
+		Input map[string]any `json:"input"`
+	}
+	b := block{Type: "tool_use", Name: "Edit", Input: map[string]any{
+		"file_path":  path,
+		"old_string": joinLines(d.Deleted),
+		"new_string": joinLines(d.Added),
+	}}
+	if d.OldPath == "" {
+		b.Name = "Write"
+		b.Input = map[string]any{"file_path": path, "content": joinLines(d.Added)}
+	}
+
+	ts := meta.Timestamp.UTC().Format(time.RFC3339Nano)
+	line := map[string]any{
+		"type":      "assistant",
+		"uuid":      "ingest-" + diffKey([]FileDiff{d}, meta.Timestamp, sessionID)[:32],
+		"sessionId": sessionID,
+		"timestamp": ts,
+		"message": map[string]any{
+			"role":    "assistant",
+			"model":   meta.Model,
+			"content": []block{b},
+		},
+	}
+	data, err := json.Marshal(line)
+	return string(data), b.Name, err
+}
+
+// diffKey is a stable digest of diffs, ts and the strings in extra.
+func diffKey(diffs []FileDiff, ts time.Time, extra ...string) string {
+	h := sha256.New()
+	fmt.Fprintln(h, ts.UTC().Format(time.RFC3339Nano))
+	for _, e := range extra {
+		fmt.Fprintln(h, e)
+	}
+	for _, d := range diffs {
+		fmt.Fprintln(h, d.OldPath, d.NewPath)
+		for _, l := range d.Deleted {
+			fmt.Fprintln(h, "-"+l)
+		}
+		for _, l := range d.Added {
+			fmt.Fprintln(h, "+"+l)
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// joinLines joins lines into file content with a trailing newline.
+func joinLines(lines []string) string {
+	if len(lines) == 0 {
+		return ""
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// hashContent returns the hex SHA-256 of content, as session parsers hash
+// written content.
+func hashContent(content string) string {
+	h := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(h[:])
+}
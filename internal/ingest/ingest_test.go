@@ -0,0 +1,179 @@
+package ingest
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anthropic/gap-map/internal/correlation"
+	"github.com/anthropic/gap-map/internal/sessionparser"
+	"github.com/anthropic/gap-map/internal/store"
+)
+
+const testDiff = `diff --git a/main.go b/main.go
+index 1111111..2222222 100644
+--- a/main.go
++++ b/main.go
+@@ -1,4 +1,5 @@
+ package main
+-func old() {}
++func handler() {}
++-- not a header
+ 
+ func main() {}
+diff --git a/new.go b/new.go
+new file mode 100644
+--- /dev/null
++++ b/new.go
+@@ -0,0 +1,2 @@
++package main
++var x = 1
+\ No newline at end of file
+--- a/gone.go	2025-01-01 10:00:00
++++ /dev/null	2025-01-01 10:00:00
+@@ -1 +0,0 @@
+-package main
+`
+
+func TestParseUnifiedDiff(t *testing.T) {
+	diffs, err := ParseUnifiedDiff(strings.NewReader(testDiff))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []FileDiff{
+		{OldPath: "main.go", NewPath: "main.go", Added: []string{"func handler() {}", "-- not a header"}, Deleted: []string{"func old() {}"}},
+		{NewPath: "new.go", Added: []string{"package main", "var x = 1"}},
+		{OldPath: "gone.go", Deleted: []string{"package main"}},
+	}
+	if !reflect.DeepEqual(diffs, want) {
+		t.Fatalf("ParseUnifiedDiff =\n%+v\nwant\n%+v", diffs, want)
+	}
+
+	if _, err := ParseUnifiedDiff(strings.NewReader("--- a/x\n+++ b/x\n@@ -1,3 +1,3 @@\n a\n")); err == nil {
+		t.Error("truncated hunk: want error")
+	}
+}
+
+func TestDiff(t *testing.T) {
+	s, err := store.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	diffs, err := ParseUnifiedDiff(strings.NewReader(testDiff))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	meta := Meta{Author: AuthorAI, Timestamp: ts, Model: "test-model"}
+	res, err := Diff(s, "/proj", diffs, meta)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.FileEvents != 3 || res.SessionEvents != 3 || res.AddedLines != 4 || res.DeletedLines != 2 {
+		t.Errorf("Diff = %+v", res)
+	}
+	if !strings.HasPrefix(res.SessionID, "ingest/") {
+		t.Errorf("session id = %q, want one derived from the diff", res.SessionID)
+	}
+
+	// The session event reads like a tailed Edit.
+	sessions, err := s.QuerySessionEventsInWindow("/proj/main.go", ts.Add(-time.Second), ts.Add(time.Second))
+	if err != nil || len(sessions) != 1 {
+		t.Fatalf("session events for main.go = %v, %v", sessions, err)
+	}
+	raw, err := s.QuerySessionEventRawJSON(sessions[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := sessionparser.ExtractDiffContent(raw); got != "func handler() {}\n-- not a header" {
+		t.Errorf("diff content = %q", got)
+	}
+	if got := sessionparser.ExtractDeletedContent(raw); got != "func old() {}" {
+		t.Errorf("deleted content = %q", got)
+	}
+
+	// The file event correlates to it.
+	events, err := s.QueryUnprocessedFileEvents(10)
+	if err != nil || len(events) != 3 {
+		t.Fatalf("unprocessed file events = %d, %v", len(events), err)
+	}
+	for _, fe := range events {
+		result, err := correlation.New(s).CorrelateFileEvent(fe)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.MatchType != "exact_file" {
+			t.Errorf("%s matched %q, want exact_file", fe.FilePath, result.MatchType)
+		}
+	}
+
+	// Re-ingesting the same diff adds no session events.
+	again, err := Diff(s, "/proj", diffs, meta)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.SessionID != res.SessionID {
+		t.Errorf("session id changed on re-ingest: %q != %q", again.SessionID, res.SessionID)
+	}
+	sessions, _ = s.QuerySessionEventsInWindow("/proj/main.go", ts.Add(-time.Second), ts.Add(time.Second))
+	if len(sessions) != 1 {
+		t.Errorf("session events after re-ingest = %d, want 1", len(sessions))
+	}
+
+	// Human edits record file events only.
+	human, err := Diff(s, "/proj", diffs[:1], Meta{Author: AuthorHuman, Timestamp: ts.Add(time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if human.SessionEvents != 0 || human.FileEvents != 1 || human.SessionID != "" {
+		t.Errorf("human Diff = %+v", human)
+	}
+
+	if _, err := Diff(s, "/proj", diffs, Meta{Author: "robot"}); err == nil {
+		t.Error("unknown author: want error")
+	}
+}
+
+func TestMark(t *testing.T) {
+	dir := t.TempDir()
+	s, err := store.New(filepath.Join(dir, "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	path := filepath.Join(dir, "handler.go")
+	if err := os.WriteFile(path, []byte("package main\n\nfunc a() {}\nfunc b() {}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ts := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	res, err := Mark(s, dir, path, 3, 4, AuthorAI, "chatgpt-web", ts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.SessionID != "mark/chatgpt-web" || res.SessionEvents != 1 || res.FileEvents != 1 || res.AddedLines != 2 {
+		t.Errorf("Mark = %+v", res)
+	}
+	sessions, err := s.QuerySessionEventsInWindow(path, ts.Add(-time.Second), ts.Add(time.Second))
+	if err != nil || len(sessions) != 1 || sessions[0].Model != "chatgpt-web" {
+		t.Fatalf("session events = %+v, %v", sessions, err)
+	}
+	raw, err := s.QuerySessionEventRawJSON(sessions[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := sessionparser.ExtractDiffContent(raw); got != "func a() {}\nfunc b() {}\n" {
+		t.Errorf("marked content = %q", got)
+	}
+
+	for _, r := range [][2]int{{0, 1}, {3, 2}, {4, 5}} {
+		if _, err := Mark(s, dir, path, r[0], r[1], AuthorAI, "", ts); err == nil {
+			t.Errorf("Mark %d-%d: want error", r[0], r[1])
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package ingest
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/anthropic/gap-map/internal/store"
+)
+
+// DefaultMarkSource is the source of marked lines that name none.
+const DefaultMarkSource = "manual"
+
+// MarkSession returns the AI session marked lines from source are
+// recorded under.
+func MarkSession(source string) string {
+	return "mark/" + source
+}
+
+// Mark records lines start to end (1-based, inclusive) of the file at
+// path, as it is on disk now, as written by author at now: for AI, code
+// pasted from a tool gap-map cannot tail, such as a web chat. It is Diff
+// of one edit adding those lines, in the AI session MarkSession(source)
+// and with source as its model, so reports count the lines and show where
+// they came from. path must be absolute.
+func Mark(s *store.Store, projectPath, path string, start, end int, author, source string, now time.Time) (Result, error) {
+	if start < 1 || end < start {
+		return Result{}, fmt.Errorf("invalid line range %d-%d", start, end)
+	}
+	if source == "" {
+		source = DefaultMarkSource
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Result{}, err
+	}
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	if end > len(lines) {
+		return Result{}, fmt.Errorf("%s has %d lines, not %d", path, len(lines), end)
+	}
+	meta := Meta{Author: author, Timestamp: now}
+	if author == AuthorAI {
+		meta.SessionID = MarkSession(source)
+		meta.Model = source
+	}
+	return Diff(s, projectPath, []FileDiff{{OldPath: path, NewPath: path, Added: lines[start-1 : end]}}, meta)
+}
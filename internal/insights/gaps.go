@@ -0,0 +1,192 @@
+// Package insights reads a project report for where a developer leans on
+// AI: the knowledge gaps gap-map is named for.
+package insights
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/anthropic/gap-map/internal/report"
+	"github.com/anthropic/gap-map/internal/worktype"
+)
+
+// Options decide which directories count as knowledge gaps.
+type Options struct {
+	MinAIPct      float64 // AI share of a directory's changed lines it needs
+	MaxHumanEdits int     // human-authored edits it may have at most
+	MinLines      int     // AI lines it needs, so a stray generated file is no gap
+	Depth         int     // path components directories are grouped by; 0 uses each file's own directory
+	Suggest       int     // files suggested for review per gap
+}
+
+// DefaultOptions count directories where AI wrote at least 90% of at least
+// 20 lines and a human edited files at most twice, suggesting three files
+// to review in each.
+var DefaultOptions = Options{MinAIPct: 90, MaxHumanEdits: 2, MinLines: 20, Suggest: 3}
+
+// Gap is a directory whose code the developer left almost entirely to AI.
+type Gap struct {
+	Dir        string   `json:"dir"` // relative to the project root; "." for the root
+	Files      int      `json:"files"`
+	TotalLines int      `json:"total_lines"`
+	AILines    int      `json:"ai_lines"`
+	AIPct      float64  `json:"ai_pct"`      // AI share of the changed lines
+	HumanEdits int      `json:"human_edits"` // attributed events not authored by AI
+	WorkTypes  []string `json:"work_types"`  // by AI lines, most first
+	Score      float64  `json:"score"`       // AI lines weighted by work type; highest ranks first
+	Review     []string `json:"review"`      // files to read first, relative to the project root
+}
+
+// GapReport is the knowledge gaps of a project, largest first.
+type GapReport struct {
+	ProjectPath string  `json:"project_path"`
+	Options     Options `json:"-"`
+	Dirs        int     `json:"dirs"` // directories with changed lines
+	Gaps        []Gap   `json:"gaps"`
+}
+
+// dirStats accumulates the files of one directory.
+type dirStats struct {
+	gap     Gap
+	byType  map[string]int
+	files   []report.FileReport
+	weights map[string]float64 // by file path
+}
+
+// FindGaps returns the directories of pr where AI wrote at least
+// opts.MinAIPct of the changed lines and a human made at most
+// opts.MaxHumanEdits edits. A gap's score is its AI lines weighted by work
+// type, architecture and core logic counting three times boilerplate and
+// tests, as the review checklist weighs them; the files it suggests are
+// those with the most weighted AI lines.
+func FindGaps(pr *report.ProjectReport, opts Options) *GapReport {
+	dirs := make(map[string]*dirStats)
+	for _, fr := range pr.Files {
+		if fr.TotalLines == 0 {
+			continue
+		}
+		dir := dirOf(pr.ProjectPath, fr.FilePath, opts.Depth)
+		ds := dirs[dir]
+		if ds == nil {
+			ds = &dirStats{gap: Gap{Dir: dir}, byType: make(map[string]int), weights: make(map[string]float64)}
+			dirs[dir] = ds
+		}
+		weight := float64(fr.AILines) * workTypeWeight(fr.WorkType)
+		ds.gap.Files++
+		ds.gap.TotalLines += fr.TotalLines
+		ds.gap.AILines += fr.AILines
+		ds.gap.HumanEdits += fr.TotalEvents - fr.AIEventCount
+		ds.gap.Score += weight
+		ds.byType[fr.WorkType] += fr.AILines
+		ds.files = append(ds.files, fr)
+		ds.weights[fr.FilePath] = weight
+	}
+
+	gr := &GapReport{ProjectPath: pr.ProjectPath, Options: opts, Dirs: len(dirs), Gaps: make([]Gap, 0)}
+	for _, ds := range dirs {
+		g := ds.gap
+		g.AIPct = float64(g.AILines) / float64(g.TotalLines) * 100
+		if g.AIPct < opts.MinAIPct || g.HumanEdits > opts.MaxHumanEdits || g.AILines < opts.MinLines || g.AILines == 0 {
+			continue
+		}
+		for wt, lines := range ds.byType {
+			if lines > 0 {
+				g.WorkTypes = append(g.WorkTypes, wt)
+			}
+		}
+		sort.Slice(g.WorkTypes, func(i, j int) bool {
+			a, b := g.WorkTypes[i], g.WorkTypes[j]
+			if ds.byType[a] != ds.byType[b] {
+				return ds.byType[a] > ds.byType[b]
+			}
+			return a < b
+		})
+		sort.Slice(ds.files, func(i, j int) bool {
+			a, b := ds.files[i], ds.files[j]
+			if ds.weights[a.FilePath] != ds.weights[b.FilePath] {
+				return ds.weights[a.FilePath] > ds.weights[b.FilePath]
+			}
+			return a.FilePath < b.FilePath
+		})
+		g.Review = make([]string, 0, opts.Suggest)
+		for _, fr := range ds.files {
+			if len(g.Review) == opts.Suggest || fr.AILines == 0 {
+				break
+			}
+			g.Review = append(g.Review, relPath(pr.ProjectPath, fr.FilePath))
+		}
+		gr.Gaps = append(gr.Gaps, g)
+	}
+	sort.Slice(gr.Gaps, func(i, j int) bool {
+		a, b := gr.Gaps[i], gr.Gaps[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		return a.Dir < b.Dir
+	})
+	return gr
+}
+
+// FormatGaps formats gr as text, largest gap first, in style st.
+func FormatGaps(gr *GapReport, st report.Style) string {
+	bold, reset := "", ""
+	if st.Color {
+		bold, reset = "\033[1m", "\033[0m"
+	}
+	var b strings.Builder
+	b.WriteString(bold + "Gap Map - Knowledge Gaps" + reset + "\n")
+	b.WriteString(strings.Repeat("=", 40) + "\n\n")
+
+	o := gr.Options
+	if len(gr.Gaps) == 0 {
+		b.WriteString(fmt.Sprintf("No knowledge gaps: none of the %d directories has at least %d AI lines making up %.0f%% or more of its changes with at most %d human edits.\n",
+			gr.Dirs, o.MinLines, o.MinAIPct, o.MaxHumanEdits))
+		return b.String()
+	}
+	b.WriteString(fmt.Sprintf("%d of %d directories are at least %.0f%% AI with at most %d human edits.\n",
+		len(gr.Gaps), gr.Dirs, o.MinAIPct, o.MaxHumanEdits))
+	b.WriteString("Read the suggested files until you could have written them yourself.\n\n")
+	for i, g := range gr.Gaps {
+		b.WriteString(fmt.Sprintf("%2d. %s%s%s\n", i+1, bold, g.Dir, reset))
+		b.WriteString(fmt.Sprintf("    %.1f%% AI of %d lines in %d files, %d human edits, %s\n",
+			g.AIPct, g.TotalLines, g.Files, g.HumanEdits, strings.Join(g.WorkTypes, ", ")))
+		for _, f := range g.Review {
+			b.WriteString(fmt.Sprintf("    review: %s\n", f))
+		}
+	}
+	return b.String()
+}
+
+// workTypeWeight returns the weight of wt relative to core logic, 1 for
+// work types without a weight.
+func workTypeWeight(wt string) float64 {
+	w := worktype.WorkTypeWeights[worktype.WorkType(wt)] / worktype.WorkTypeWeights[worktype.CoreLogic]
+	if w == 0 {
+		return 1
+	}
+	return w
+}
+
+// relPath returns path relative to projectPath when it lies inside it.
+func relPath(projectPath, path string) string {
+	if filepath.IsAbs(path) && projectPath != "" {
+		if rel, err := filepath.Rel(projectPath, path); err == nil && !strings.HasPrefix(rel, "..") {
+			path = rel
+		}
+	}
+	return filepath.ToSlash(path)
+}
+
+// dirOf returns the directory path groups files under: its directory
+// relative to projectPath, cut to depth components when depth is positive.
+func dirOf(projectPath, path string, depth int) string {
+	dir := filepath.ToSlash(filepath.Dir(relPath(projectPath, path)))
+	if depth > 0 && dir != "." {
+		if parts := strings.Split(dir, "/"); len(parts) > depth {
+			dir = strings.Join(parts[:depth], "/")
+		}
+	}
+	return dir
+}
@@ -0,0 +1,83 @@
+package insights
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/anthropic/gap-map/internal/report"
+)
+
+func fileReport(path, wt string, total, ai, events, aiEvents int) report.FileReport {
+	return report.FileReport{FilePath: path, WorkType: wt, TotalLines: total, AILines: ai, TotalEvents: events, AIEventCount: aiEvents}
+}
+
+func TestFindGaps(t *testing.T) {
+	pr := &report.ProjectReport{
+		ProjectPath: "/proj",
+		Files: []report.FileReport{
+			// internal/api: all AI, core logic — the largest gap.
+			fileReport("/proj/internal/api/server.go", "core_logic", 100, 100, 4, 4),
+			fileReport("/proj/internal/api/client.go", "core_logic", 60, 58, 3, 2),
+			fileReport("/proj/internal/api/doc.go", "documentation", 5, 5, 1, 1),
+			// web/styles: all AI boilerplate, ranked below api.
+			fileReport("/proj/web/styles/main.css", "boilerplate", 150, 150, 2, 2),
+			// internal/store: AI-heavy but edited by hand often.
+			fileReport("/proj/internal/store/store.go", "core_logic", 100, 95, 9, 3),
+			// cmd: mostly human.
+			fileReport("/proj/cmd/main.go", "core_logic", 80, 20, 5, 1),
+			// tools: all AI but too small to count.
+			fileReport("/proj/tools/gen.go", "boilerplate", 10, 10, 1, 1),
+		},
+	}
+
+	gr := FindGaps(pr, DefaultOptions)
+	if gr.Dirs != 5 {
+		t.Errorf("Dirs = %d, want 5", gr.Dirs)
+	}
+	var dirs []string
+	for _, g := range gr.Gaps {
+		dirs = append(dirs, g.Dir)
+	}
+	if want := []string{"internal/api", "web/styles"}; !reflect.DeepEqual(dirs, want) {
+		t.Fatalf("gaps = %v, want %v", dirs, want)
+	}
+
+	api := gr.Gaps[0]
+	if api.Files != 3 || api.TotalLines != 165 || api.AILines != 163 || api.HumanEdits != 1 {
+		t.Errorf("api gap = %+v", api)
+	}
+	if want := []string{"core_logic", "documentation"}; !reflect.DeepEqual(api.WorkTypes, want) {
+		t.Errorf("WorkTypes = %v, want %v", api.WorkTypes, want)
+	}
+	if want := []string{"internal/api/server.go", "internal/api/client.go", "internal/api/doc.go"}; !reflect.DeepEqual(api.Review, want) {
+		t.Errorf("Review = %v, want %v", api.Review, want)
+	}
+
+	// Grouping by the first path component merges internal/api and
+	// internal/store, whose human edits then rule the group out.
+	opts := DefaultOptions
+	opts.Depth = 1
+	opts.Suggest = 1
+	gr = FindGaps(pr, opts)
+	if len(gr.Gaps) != 1 || gr.Gaps[0].Dir != "web" || len(gr.Gaps[0].Review) != 1 {
+		t.Errorf("depth 1 gaps = %+v, want only web", gr.Gaps)
+	}
+}
+
+func TestFormatGaps(t *testing.T) {
+	pr := &report.ProjectReport{ProjectPath: "/proj", Files: []report.FileReport{
+		fileReport("/proj/pkg/a.go", "core_logic", 40, 40, 2, 2),
+	}}
+	out := FormatGaps(FindGaps(pr, DefaultOptions), report.Style{})
+	for _, want := range []string{"1 of 1 directories", " 1. pkg\n", "100.0% AI of 40 lines in 1 files, 0 human edits, core_logic", "review: pkg/a.go"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output lacks %q:\n%s", want, out)
+		}
+	}
+
+	out = FormatGaps(FindGaps(&report.ProjectReport{}, DefaultOptions), report.Style{})
+	if !strings.Contains(out, "No knowledge gaps") {
+		t.Errorf("empty report output:\n%s", out)
+	}
+}
@@ -2,12 +2,18 @@ package ipc
 
 import (
 	"bufio"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net"
 	"time"
 )
 
+// ErrNotRunning is wrapped by the errors of calls that could not reach the
+// daemon's socket.
+var ErrNotRunning = errors.New("daemon is not running")
+
 // Client communicates with the daemon over a Unix domain socket.
 type Client struct {
 	socketPath string
@@ -24,75 +30,160 @@ func NewClient(socketPath string) *Client {
 
 // Ping tests if the daemon is alive.
 func (c *Client) Ping() error {
-	_, err := c.send(Request{Command: "ping"})
-	return err
+	return c.Call(MethodPing, nil, nil)
 }
 
 // Status returns the daemon's status data.
 func (c *Client) Status() (*StatusData, error) {
-	resp, err := c.send(Request{Command: "status"})
-	if err != nil {
+	var status StatusData
+	if err := c.Call(MethodStatus, nil, &status); err != nil {
 		return nil, err
 	}
+	return &status, nil
+}
 
-	// resp.Data is a map[string]interface{} from JSON unmarshal.
-	// Re-marshal and unmarshal into StatusData.
-	raw, err := json.Marshal(resp.Data)
-	if err != nil {
-		return nil, fmt.Errorf("marshal status data: %w", err)
-	}
+// RequestStop asks the daemon to shut down gracefully.
+func (c *Client) RequestStop() error {
+	return c.Call(MethodStop, nil, nil)
+}
 
-	var status StatusData
-	if err := json.Unmarshal(raw, &status); err != nil {
-		return nil, fmt.Errorf("unmarshal status data: %w", err)
+// SyncGit asks the daemon to sync the git commits of the repository at
+// repo, its top level, now rather than at its next poll. The daemon
+// rejects a repository it does not sync.
+func (c *Client) SyncGit(repo string) error {
+	return c.Call(MethodSyncGit, SyncGitParams{Repo: repo}, nil)
+}
+
+// MarkLines asks the daemon to record a range of lines as written by AI
+// or a human.
+func (c *Client) MarkLines(m MarkLines) (*MarkResult, error) {
+	var res MarkResult
+	if err := c.Call(MethodMarkLines, m, &res); err != nil {
+		return nil, err
 	}
+	return &res, nil
+}
 
-	return &status, nil
+// ReloadTrust asks the daemon to reread which projects it records from
+// the config file.
+func (c *Client) ReloadTrust() error {
+	return c.Call(MethodReloadTrust, nil, nil)
 }
 
-// RequestStop asks the daemon to shut down gracefully.
-func (c *Client) RequestStop() error {
-	_, err := c.send(Request{Command: "stop"})
-	return err
+// Subscribe streams the daemon's "attribution" notifications to fn, only
+// those in projectPath when it is not empty, until ctx is done. It returns
+// nil when ctx ends the stream and an error when the daemon closes it.
+func (c *Client) Subscribe(ctx context.Context, projectPath string, fn func(AttributionEvent)) error {
+	conn, err := net.DialTimeout("unix", c.socketPath, c.timeout)
+	if err != nil {
+		return fmt.Errorf("connect to daemon: %w (%w)", ErrNotRunning, err)
+	}
+	defer conn.Close()
+
+	// Closing the connection unblocks the read loop when ctx is done.
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	params, err := json.Marshal(SubscribeParams{ProjectPath: projectPath})
+	if err != nil {
+		return fmt.Errorf("marshal params: %w", err)
+	}
+	req := RPCRequest{JSONRPC: JSONRPCVersion, ID: json.RawMessage("1"), Method: MethodSubscribe, Params: params}
+	data, err := json.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("marshal request: %w", err)
+	}
+	_ = conn.SetWriteDeadline(time.Now().Add(c.timeout))
+	if _, err := conn.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("send request: %w", err)
+	}
+
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxMessageBytes)
+	for scanner.Scan() {
+		var msg struct {
+			Method string          `json:"method"`
+			Params json.RawMessage `json:"params"`
+			Error  *RPCError       `json:"error"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+			return fmt.Errorf("unmarshal message: %w", err)
+		}
+		if msg.Error != nil {
+			return fmt.Errorf("daemon error: %w", msg.Error)
+		}
+		if msg.Method != NotifyAttribution {
+			continue // the subscribe response
+		}
+		var ev AttributionEvent
+		if err := json.Unmarshal(msg.Params, &ev); err != nil {
+			return fmt.Errorf("unmarshal %s notification: %w", NotifyAttribution, err)
+		}
+		fn(ev)
+	}
+	if ctx.Err() != nil {
+		return nil
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("read notification: %w", err)
+	}
+	return fmt.Errorf("daemon closed the connection")
 }
 
-// send dials the socket, sends a JSON request, reads the JSON response.
-func (c *Client) send(req Request) (*Response, error) {
+// Call dials the socket, sends a JSON-RPC request for method with params,
+// and decodes the result into result (which may be nil). A JSON-RPC error
+// from the daemon is returned as *RPCError.
+func (c *Client) Call(method string, params, result interface{}) error {
 	conn, err := net.DialTimeout("unix", c.socketPath, c.timeout)
 	if err != nil {
-		return nil, fmt.Errorf("connect to daemon: %w", err)
+		return fmt.Errorf("connect to daemon: %w (%w)", ErrNotRunning, err)
 	}
 	defer conn.Close()
 
 	_ = conn.SetDeadline(time.Now().Add(c.timeout))
 
+	req := RPCRequest{JSONRPC: JSONRPCVersion, ID: json.RawMessage("1"), Method: method}
+	if params != nil {
+		if req.Params, err = json.Marshal(params); err != nil {
+			return fmt.Errorf("marshal params: %w", err)
+		}
+	}
+
 	// Send request.
 	data, err := json.Marshal(req)
 	if err != nil {
-		return nil, fmt.Errorf("marshal request: %w", err)
+		return fmt.Errorf("marshal request: %w", err)
 	}
 	data = append(data, '\n')
 	if _, err := conn.Write(data); err != nil {
-		return nil, fmt.Errorf("send request: %w", err)
+		return fmt.Errorf("send request: %w", err)
 	}
 
 	// Read response.
 	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxMessageBytes)
 	if !scanner.Scan() {
 		if err := scanner.Err(); err != nil {
-			return nil, fmt.Errorf("read response: %w", err)
+			return fmt.Errorf("read response: %w", err)
 		}
-		return nil, fmt.Errorf("empty response from daemon")
+		return fmt.Errorf("empty response from daemon")
 	}
 
-	var resp Response
+	var resp struct {
+		Result json.RawMessage `json:"result"`
+		Error  *RPCError       `json:"error"`
+	}
 	if err := json.Unmarshal(scanner.Bytes(), &resp); err != nil {
-		return nil, fmt.Errorf("unmarshal response: %w", err)
+		return fmt.Errorf("unmarshal response: %w", err)
 	}
 
-	if !resp.OK {
-		return nil, fmt.Errorf("daemon error: %s", resp.Error)
+	if resp.Error != nil {
+		return fmt.Errorf("daemon error: %w", resp.Error)
 	}
-
-	return &resp, nil
+	if result != nil {
+		if err := json.Unmarshal(resp.Result, result); err != nil {
+			return fmt.Errorf("unmarshal %s result: %w", method, err)
+		}
+	}
+	return nil
 }
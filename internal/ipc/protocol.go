@@ -1,12 +1,176 @@
+// Package ipc implements the daemon's Unix domain socket protocol.
+//
+// Clients speak JSON-RPC 2.0, one message per line. A connection stays open
+// for as many requests as the client sends, and a client that calls
+// "subscribe" receives "attribution" notifications on the same connection
+// as the daemon records them. See the README's "Editor integration" section
+// for the method reference.
+//
+// The older {"command": ...} request format is still answered, one request
+// per connection, so CLIs from before JSON-RPC keep working.
 package ipc
 
-// Request is a JSON message sent from client to server.
+import "encoding/json"
+
+// ProtocolVersion is the IPC protocol version reported by the "version"
+// method. It is bumped whenever a method's params or result change
+// incompatibly.
+const ProtocolVersion = 1
+
+// JSONRPCVersion is the value of the jsonrpc member of every message.
+const JSONRPCVersion = "2.0"
+
+// JSON-RPC methods served by the daemon.
+const (
+	MethodVersion                 = "version"
+	MethodPing                    = "ping"
+	MethodStatus                  = "status"
+	MethodStop                    = "stop"
+	MethodFileReport              = "fileReport"
+	MethodSubscribe               = "subscribe"
+	MethodUnsubscribe             = "unsubscribe"
+	MethodRecordManualAttribution = "recordManualAttribution"
+	MethodTypingHeartbeat         = "typingHeartbeat"
+	MethodSyncGit                 = "syncGit"
+	MethodReloadTrust             = "reloadTrust"
+	MethodMarkLines               = "markLines"
+)
+
+// NotifyAttribution is the method of the notification sent to subscribers
+// for each attribution the daemon records.
+const NotifyAttribution = "attribution"
+
+// JSON-RPC error codes. The -32700..-32600 range is defined by the spec;
+// CodeServerError reports a method that was called correctly but failed.
+const (
+	CodeParseError     = -32700
+	CodeInvalidRequest = -32600
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	CodeInternalError  = -32603
+	CodeServerError    = -32000
+)
+
+// RPCRequest is a JSON-RPC request, or a notification when ID is absent.
+type RPCRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// RPCResponse is a JSON-RPC response. Exactly one of Result and Error is set.
+type RPCResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *RPCError       `json:"error,omitempty"`
+}
+
+// RPCNotification is a server-to-client message that expects no reply.
+type RPCNotification struct {
+	JSONRPC string      `json:"jsonrpc"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params,omitempty"`
+}
+
+// RPCError is the error member of a failed JSON-RPC response.
+type RPCError struct {
+	Code    int         `json:"code"`
+	Message string      `json:"message"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+func (e *RPCError) Error() string {
+	return e.Message
+}
+
+// VersionResult is returned by "version".
+type VersionResult struct {
+	Protocol int      `json:"protocol"`
+	Methods  []string `json:"methods"`
+}
+
+// FileReportParams are the params of "fileReport".
+type FileReportParams struct {
+	FilePath string `json:"file_path"`
+}
+
+// SyncGitParams are the params of "syncGit": the top level of the
+// repository whose commits to sync. An empty Repo syncs whichever
+// repository the daemon syncs.
+type SyncGitParams struct {
+	Repo string `json:"repo,omitempty"`
+}
+
+// SubscribeParams are the params of "subscribe". With ProjectPath set, only
+// attributions in that project are sent.
+type SubscribeParams struct {
+	ProjectPath string `json:"project_path,omitempty"`
+}
+
+// ManualAttribution is the params of "recordManualAttribution": an
+// authorship label the user asserts for a file, e.g. from an editor command
+// marking a selection as AI-written.
+type ManualAttribution struct {
+	FilePath        string `json:"file_path"`
+	AuthorshipLevel string `json:"authorship_level"`    // mostly_ai, mixed, mostly_human, ai_suggested_human_written
+	Lines           int    `json:"lines,omitempty"`     // lines covered; defaults to 1
+	WorkType        string `json:"work_type,omitempty"` // classified from the path when empty
+}
+
+// MarkLines is the params of "markLines": a range of lines the user says
+// were written by AI or a human, e.g. code pasted from a web chat, bound to
+// an editor hotkey.
+type MarkLines struct {
+	FilePath  string `json:"file_path"`
+	StartLine int    `json:"start_line"`       // 1-based
+	EndLine   int    `json:"end_line"`         // inclusive
+	Author    string `json:"author"`           // ai or human
+	Source    string `json:"source,omitempty"` // where AI lines came from, e.g. "chatgpt-web"; default "manual"
+}
+
+// MarkResult is returned by "markLines".
+type MarkResult struct {
+	SessionID string `json:"session_id,omitempty"` // session AI lines were recorded under
+	Lines     int    `json:"lines"`
+}
+
+// TypingHeartbeat is the params of "typingHeartbeat": a burst of human
+// typing in one file, reported by an editor plugin. Times are RFC 3339.
+type TypingHeartbeat struct {
+	FilePath   string `json:"file_path"`
+	StartedAt  string `json:"started_at"`
+	EndedAt    string `json:"ended_at"`
+	CharsTyped int    `json:"chars_typed"`
+	Source     string `json:"source,omitempty"` // editor name, e.g. "neovim"
+}
+
+// RecordResult is returned by "recordManualAttribution" and
+// "typingHeartbeat".
+type RecordResult struct {
+	ID int64 `json:"id"`
+}
+
+// AttributionEvent is the params of an "attribution" notification.
+type AttributionEvent struct {
+	FilePath        string `json:"file_path"`
+	ProjectPath     string `json:"project_path"`
+	AuthorshipLevel string `json:"authorship_level"`
+	WorkType        string `json:"work_type"`
+	Kind            string `json:"kind"`
+	LinesChanged    int    `json:"lines_changed"`
+	Timestamp       string `json:"timestamp"`       // UTC
+	TimestampLocal  string `json:"timestamp_local"` // in the display time zone
+}
+
+// Request is a legacy (pre-JSON-RPC) message sent from client to server.
 type Request struct {
 	Command string            `json:"command"` // "status", "stop", "ping"
 	Args    map[string]string `json:"args,omitempty"`
 }
 
-// Response is a JSON message sent from server to client.
+// Response is a legacy (pre-JSON-RPC) message sent from server to client.
 type Response struct {
 	OK    bool        `json:"ok"`
 	Data  interface{} `json:"data,omitempty"`
@@ -21,4 +185,75 @@ type StatusData struct {
 	SessionEventsCount int64    `json:"session_events_count"`
 	GitCommitsCount    int64    `json:"git_commits_count"`
 	WatchedPaths       []string `json:"watched_paths"`
+	HealthData
+}
+
+// HealthData is the part of StatusData that describes what the daemon is
+// doing. Times are RFC 3339 and empty when unknown; each *_at field is UTC
+// and its *_at_local twin is the same instant in the display time zone.
+type HealthData struct {
+	WatchPathStats         []WatchPathStatus     `json:"watch_path_stats,omitempty"`
+	WatchedDirs            int                   `json:"watched_dirs"`
+	UnwatchedDirs          int64                 `json:"unwatched_dirs"`  // directories that could not be watched
+	EventOverflows         int64                 `json:"event_overflows"` // times the kernel dropped file events
+	WatchLimitReached      bool                  `json:"watch_limit_reached"`
+	PolledDirs             int                   `json:"polled_dirs"`             // directories scanned instead of watched
+	PollInterval           string                `json:"poll_interval,omitempty"` // how often polled directories are scanned
+	SessionRoots           []SessionRootStatus   `json:"session_roots,omitempty"` // Claude Code session directories read
+	Sessions               []SessionStatus       `json:"sessions,omitempty"`      // actively tailed sessions
+	IdleSessions           int                   `json:"idle_sessions"`
+	GitRepos               []GitRepoStatus       `json:"git_repos,omitempty"`
+	AttributionBacklog     int64                 `json:"attribution_backlog"` // file events not yet attributed
+	Crashes                int                   `json:"crashes"`             // daemon crashes the supervisor restarted
+	LastCrashAt            string                `json:"last_crash_at,omitempty"`
+	LastCrashAtLocal       string                `json:"last_crash_at_local,omitempty"`
+	LastCrash              string                `json:"last_crash,omitempty"` // how the last crashed daemon exited
+	LastMaintenanceAt      string                `json:"last_maintenance_at,omitempty"`
+	LastMaintenanceAtLocal string                `json:"last_maintenance_at_local,omitempty"`
+	ProjectTrust           string                `json:"project_trust,omitempty"` // "all" or "allowlist"
+	EnabledProjects        []string              `json:"enabled_projects,omitempty"`
+	DisabledProjects       []string              `json:"disabled_projects,omitempty"`
+	UntrackedRepos         []UntrackedRepoStatus `json:"untracked_repos,omitempty"`
+}
+
+// WatchPathStatus reports the file events recorded under one watch path.
+type WatchPathStatus struct {
+	Path             string `json:"path"`
+	Events           int64  `json:"events"`
+	LastEventAt      string `json:"last_event_at,omitempty"`
+	LastEventAtLocal string `json:"last_event_at_local,omitempty"`
+	Disabled         bool   `json:"disabled,omitempty"` // gap-map is not enabled for the path
+}
+
+// SessionRootStatus reports a directory the daemon reads Claude Code
+// sessions from.
+type SessionRootStatus struct {
+	Path     string `json:"path"`
+	Exists   bool   `json:"exists"`
+	Sessions int    `json:"sessions"` // session files tailed from it
+}
+
+// SessionStatus reports the progress of one tailed session file.
+type SessionStatus struct {
+	Path            string `json:"path"`
+	SessionID       string `json:"session_id"`
+	LagBytes        int64  `json:"lag_bytes"`    // written but not read yet
+	LinesParsed     int64  `json:"lines_parsed"` // since the daemon started
+	LastLineAt      string `json:"last_line_at,omitempty"`
+	LastLineAtLocal string `json:"last_line_at_local,omitempty"`
+}
+
+// UntrackedRepoStatus reports a repository outside every watch path that
+// AI has edited repeatedly since the daemon started.
+type UntrackedRepoStatus struct {
+	Path  string `json:"path"`
+	Edits int    `json:"edits"`
+}
+
+// GitRepoStatus reports the commit sync state of one repository.
+type GitRepoStatus struct {
+	Path            string `json:"path"`
+	LastSyncAt      string `json:"last_sync_at,omitempty"`
+	LastSyncAtLocal string `json:"last_sync_at_local,omitempty"`
+	LastError       string `json:"last_error,omitempty"`
 }
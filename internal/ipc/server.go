@@ -2,6 +2,7 @@ package ipc
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -26,16 +27,58 @@ type StoreQuerier interface {
 	DBSizeBytes() (int64, error)
 }
 
+// FileReporter produces the single-file report returned by "fileReport".
+type FileReporter func(filePath string) (interface{}, error)
+
+// AttributionRecorder stores a manual attribution for
+// "recordManualAttribution" and returns its ID.
+type AttributionRecorder func(m ManualAttribution) (int64, error)
+
+// TypingRecorder stores a typing burst for "typingHeartbeat" and returns
+// its ID.
+type TypingRecorder func(h TypingHeartbeat) (int64, error)
+
+// LineMarker records the lines of "markLines".
+type LineMarker func(m MarkLines) (MarkResult, error)
+
+// GitSyncer requests an immediate git commit sync of repo, or of the
+// daemon's repository when repo is "", for "syncGit".
+type GitSyncer func(repo string) error
+
+// HealthReporter supplies the HealthData part of "status".
+type HealthReporter func() HealthData
+
+// TrustReloader rereads the project trust settings for "reloadTrust".
+type TrustReloader func() error
+
 // Server is a Unix domain socket server for CLI-to-daemon communication.
 type Server struct {
-	daemon   DaemonQuerier
-	store    StoreQuerier
+	daemon     DaemonQuerier
+	store      StoreQuerier
 	watchPaths []string
+	reporter   FileReporter
+	recorder   AttributionRecorder
+	typing     TypingRecorder
+	marker     LineMarker
+	gitSync    GitSyncer
+	health     HealthReporter
+	trust      TrustReloader
 
 	listener net.Listener
 	mu       sync.Mutex
 	wg       sync.WaitGroup
 	stopped  bool
+	conns    map[*rpcConn]struct{} // open JSON-RPC connections
+}
+
+// rpcConn is a JSON-RPC connection. Responses and notifications may be
+// written from different goroutines, so writes hold mu.
+type rpcConn struct {
+	conn net.Conn
+
+	mu         sync.Mutex
+	subscribed bool
+	project    string // subscription filter; "" for all projects
 }
 
 // NewServer creates a new IPC server.
@@ -105,11 +148,15 @@ func (s *Server) Listen(socketPath string, ctx context.Context) error {
 	}
 }
 
-// Stop stops accepting connections and waits for in-flight connections to drain.
+// Stop stops accepting connections, closes open JSON-RPC connections, and
+// waits for in-flight connections to drain.
 func (s *Server) Stop() error {
 	s.mu.Lock()
 	s.stopped = true
 	ln := s.listener
+	for c := range s.conns {
+		_ = c.conn.Close()
+	}
 	s.mu.Unlock()
 
 	if ln != nil {
@@ -150,21 +197,124 @@ func (s *Server) SetDaemon(d DaemonQuerier) {
 	s.daemon = d
 }
 
-// handleConn reads a single JSON request, dispatches it, and writes the response.
+// SetFileReporter sets the function that serves "fileReport".
+func (s *Server) SetFileReporter(fn FileReporter) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reporter = fn
+}
+
+// SetAttributionRecorder sets the function that serves
+// "recordManualAttribution".
+func (s *Server) SetAttributionRecorder(fn AttributionRecorder) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.recorder = fn
+}
+
+// SetTypingRecorder sets the function that serves "typingHeartbeat".
+func (s *Server) SetTypingRecorder(fn TypingRecorder) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.typing = fn
+}
+
+// SetLineMarker sets the function that serves "markLines".
+func (s *Server) SetLineMarker(fn LineMarker) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.marker = fn
+}
+
+// SetGitSyncer sets the function that serves "syncGit".
+func (s *Server) SetGitSyncer(fn GitSyncer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.gitSync = fn
+}
+
+// SetHealthReporter sets the function that fills in the health part of
+// "status".
+func (s *Server) SetHealthReporter(fn HealthReporter) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.health = fn
+}
+
+// SetTrustReloader sets the function that serves "reloadTrust".
+func (s *Server) SetTrustReloader(fn TrustReloader) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.trust = fn
+}
+
+// maxMessageBytes bounds a single request line.
+const maxMessageBytes = 1 << 20
+
+// handleConn reads the first message to tell the protocols apart. A legacy
+// request gets one response and the connection is closed; a JSON-RPC
+// connection is served until the client closes it or the server stops.
 func (s *Server) handleConn(conn net.Conn) {
 	defer conn.Close()
 
-	// Set a read/write deadline.
+	// The first message must arrive promptly.
 	_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
 
 	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxMessageBytes)
 	if !scanner.Scan() {
 		writeError(conn, "empty request")
 		return
 	}
 
+	if isLegacy(scanner.Bytes()) {
+		s.handleLegacy(conn, scanner.Bytes())
+		return
+	}
+
+	// JSON-RPC connections are long-lived (subscribers wait for
+	// notifications), so only writes keep a deadline.
+	_ = conn.SetDeadline(time.Time{})
+	c := &rpcConn{conn: conn}
+	s.mu.Lock()
+	if s.conns == nil {
+		s.conns = make(map[*rpcConn]struct{})
+	}
+	s.conns[c] = struct{}{}
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, c)
+		s.mu.Unlock()
+	}()
+
+	for {
+		if !s.serveRPC(c, scanner.Bytes()) {
+			return
+		}
+		if !scanner.Scan() {
+			return
+		}
+	}
+}
+
+// isLegacy reports whether a message is a pre-JSON-RPC {"command": ...}
+// request.
+func isLegacy(msg []byte) bool {
+	var probe struct {
+		JSONRPC *string `json:"jsonrpc"`
+		Command *string `json:"command"`
+	}
+	if err := json.Unmarshal(msg, &probe); err != nil {
+		return false
+	}
+	return probe.JSONRPC == nil && probe.Command != nil
+}
+
+// handleLegacy answers a single pre-JSON-RPC request.
+func (s *Server) handleLegacy(conn net.Conn, msg []byte) {
 	var req Request
-	if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+	if err := json.Unmarshal(msg, &req); err != nil {
 		writeError(conn, fmt.Sprintf("invalid JSON: %v", err))
 		return
 	}
@@ -174,13 +324,13 @@ func (s *Server) handleConn(conn net.Conn) {
 		writeResponse(conn, Response{OK: true, Data: "pong"})
 
 	case "status":
-		s.handleStatus(conn)
+		writeResponse(conn, Response{OK: true, Data: s.status()})
 
 	case "stop":
 		writeResponse(conn, Response{OK: true, Data: "shutting down"})
 		// Trigger daemon shutdown after sending response.
-		if s.daemon != nil {
-			s.daemon.Stop()
+		if d := s.getDaemon(); d != nil {
+			d.Stop()
 		}
 
 	default:
@@ -188,31 +338,324 @@ func (s *Server) handleConn(conn net.Conn) {
 	}
 }
 
-func (s *Server) handleStatus(conn net.Conn) {
+// serveRPC answers one JSON-RPC message, which may be a batch. It returns
+// false when the connection should be closed.
+func (s *Server) serveRPC(c *rpcConn, msg []byte) bool {
+	msg = bytes.TrimSpace(msg)
+	if len(msg) == 0 {
+		return true
+	}
+	if !json.Valid(msg) {
+		return c.write(rpcError(nil, CodeParseError, "parse error"))
+	}
+
+	var stop bool
+	if msg[0] == '[' {
+		var batch []json.RawMessage
+		if err := json.Unmarshal(msg, &batch); err != nil || len(batch) == 0 {
+			return c.write(rpcError(nil, CodeInvalidRequest, "invalid batch"))
+		}
+		var responses []*RPCResponse
+		for _, raw := range batch {
+			resp, isStop := s.handleRPC(c, raw)
+			stop = stop || isStop
+			if resp != nil {
+				responses = append(responses, resp)
+			}
+		}
+		if len(responses) > 0 && !c.write(responses) {
+			return false
+		}
+	} else {
+		resp, isStop := s.handleRPC(c, msg)
+		stop = isStop
+		if resp != nil && !c.write(resp) {
+			return false
+		}
+	}
+
+	// "stop" is acted on only once its response is written.
+	if stop {
+		if d := s.getDaemon(); d != nil {
+			d.Stop()
+		}
+	}
+	return true
+}
+
+// handleRPC dispatches a single JSON-RPC request. The response is nil for
+// notifications. stop reports a "stop" call.
+func (s *Server) handleRPC(c *rpcConn, raw json.RawMessage) (resp *RPCResponse, stop bool) {
+	var req RPCRequest
+	if err := json.Unmarshal(raw, &req); err != nil || req.JSONRPC != JSONRPCVersion || req.Method == "" {
+		return rpcError(nil, CodeInvalidRequest, "invalid request"), false
+	}
+
+	result, rpcErr := s.dispatch(c, req)
+	if req.ID == nil {
+		return nil, req.Method == MethodStop
+	}
+	if rpcErr != nil {
+		return &RPCResponse{JSONRPC: JSONRPCVersion, ID: req.ID, Error: rpcErr}, false
+	}
+	return &RPCResponse{JSONRPC: JSONRPCVersion, ID: req.ID, Result: result}, req.Method == MethodStop
+}
+
+// dispatch runs a JSON-RPC method. Every successful method returns a
+// non-nil result, since a response must carry one.
+func (s *Server) dispatch(c *rpcConn, req RPCRequest) (interface{}, *RPCError) {
+	switch req.Method {
+	case MethodVersion:
+		return VersionResult{
+			Protocol: ProtocolVersion,
+			Methods: []string{
+				MethodVersion, MethodPing, MethodStatus, MethodStop, MethodFileReport,
+				MethodSubscribe, MethodUnsubscribe, MethodRecordManualAttribution,
+				MethodTypingHeartbeat, MethodSyncGit, MethodReloadTrust, MethodMarkLines,
+			},
+		}, nil
+
+	case MethodPing:
+		return "pong", nil
+
+	case MethodStatus:
+		return s.status(), nil
+
+	case MethodStop:
+		return "shutting down", nil
+
+	case MethodFileReport:
+		var p FileReportParams
+		if err := decodeParams(req.Params, &p); err != nil {
+			return nil, err
+		}
+		if p.FilePath == "" {
+			return nil, &RPCError{Code: CodeInvalidParams, Message: "file_path is required"}
+		}
+		s.mu.Lock()
+		reporter := s.reporter
+		s.mu.Unlock()
+		if reporter == nil {
+			return nil, &RPCError{Code: CodeServerError, Message: "file reports are not available"}
+		}
+		fr, err := reporter(p.FilePath)
+		if err != nil {
+			return nil, &RPCError{Code: CodeServerError, Message: err.Error()}
+		}
+		return fr, nil
+
+	case MethodSubscribe:
+		var p SubscribeParams
+		if err := decodeParams(req.Params, &p); err != nil {
+			return nil, err
+		}
+		c.mu.Lock()
+		c.subscribed = true
+		c.project = p.ProjectPath
+		c.mu.Unlock()
+		return true, nil
+
+	case MethodUnsubscribe:
+		c.mu.Lock()
+		c.subscribed = false
+		c.mu.Unlock()
+		return true, nil
+
+	case MethodRecordManualAttribution:
+		var p ManualAttribution
+		if err := decodeParams(req.Params, &p); err != nil {
+			return nil, err
+		}
+		if p.FilePath == "" || p.AuthorshipLevel == "" {
+			return nil, &RPCError{Code: CodeInvalidParams, Message: "file_path and authorship_level are required"}
+		}
+		if p.Lines < 0 {
+			return nil, &RPCError{Code: CodeInvalidParams, Message: "lines must not be negative"}
+		}
+		s.mu.Lock()
+		recorder := s.recorder
+		s.mu.Unlock()
+		if recorder == nil {
+			return nil, &RPCError{Code: CodeServerError, Message: "manual attribution is not available"}
+		}
+		id, err := recorder(p)
+		if err != nil {
+			return nil, &RPCError{Code: CodeServerError, Message: err.Error()}
+		}
+		return RecordResult{ID: id}, nil
+
+	case MethodTypingHeartbeat:
+		var p TypingHeartbeat
+		if err := decodeParams(req.Params, &p); err != nil {
+			return nil, err
+		}
+		if p.FilePath == "" || p.StartedAt == "" || p.EndedAt == "" {
+			return nil, &RPCError{Code: CodeInvalidParams, Message: "file_path, started_at, and ended_at are required"}
+		}
+		s.mu.Lock()
+		typing := s.typing
+		s.mu.Unlock()
+		if typing == nil {
+			return nil, &RPCError{Code: CodeServerError, Message: "typing heartbeats are not available"}
+		}
+		id, err := typing(p)
+		if err != nil {
+			return nil, &RPCError{Code: CodeServerError, Message: err.Error()}
+		}
+		return RecordResult{ID: id}, nil
+
+	case MethodMarkLines:
+		var p MarkLines
+		if err := decodeParams(req.Params, &p); err != nil {
+			return nil, err
+		}
+		if p.FilePath == "" || p.Author == "" {
+			return nil, &RPCError{Code: CodeInvalidParams, Message: "file_path and author are required"}
+		}
+		if p.StartLine < 1 || p.EndLine < p.StartLine {
+			return nil, &RPCError{Code: CodeInvalidParams, Message: "start_line and end_line must be a range of lines from 1"}
+		}
+		s.mu.Lock()
+		marker := s.marker
+		s.mu.Unlock()
+		if marker == nil {
+			return nil, &RPCError{Code: CodeServerError, Message: "marking lines is not available"}
+		}
+		res, err := marker(p)
+		if err != nil {
+			return nil, &RPCError{Code: CodeServerError, Message: err.Error()}
+		}
+		return res, nil
+
+	case MethodSyncGit:
+		var p SyncGitParams
+		if err := decodeParams(req.Params, &p); err != nil {
+			return nil, err
+		}
+		s.mu.Lock()
+		gitSync := s.gitSync
+		s.mu.Unlock()
+		if gitSync == nil {
+			return nil, &RPCError{Code: CodeServerError, Message: "git sync is not available"}
+		}
+		if err := gitSync(p.Repo); err != nil {
+			return nil, &RPCError{Code: CodeServerError, Message: err.Error()}
+		}
+		return true, nil
+
+	case MethodReloadTrust:
+		s.mu.Lock()
+		reload := s.trust
+		s.mu.Unlock()
+		if reload == nil {
+			return nil, &RPCError{Code: CodeServerError, Message: "project trust reload is not available"}
+		}
+		if err := reload(); err != nil {
+			return nil, &RPCError{Code: CodeServerError, Message: err.Error()}
+		}
+		return true, nil
+
+	default:
+		return nil, &RPCError{Code: CodeMethodNotFound, Message: fmt.Sprintf("method not found: %q", req.Method)}
+	}
+}
+
+// decodeParams unmarshals params into v. Absent params leave v zero.
+func decodeParams(params json.RawMessage, v interface{}) *RPCError {
+	if len(params) == 0 || string(params) == "null" {
+		return nil
+	}
+	if err := json.Unmarshal(params, v); err != nil {
+		return &RPCError{Code: CodeInvalidParams, Message: fmt.Sprintf("invalid params: %v", err)}
+	}
+	return nil
+}
+
+// rpcError builds an error response.
+func rpcError(id json.RawMessage, code int, msg string) *RPCResponse {
+	if id == nil {
+		id = json.RawMessage("null")
+	}
+	return &RPCResponse{JSONRPC: JSONRPCVersion, ID: id, Error: &RPCError{Code: code, Message: msg}}
+}
+
+// PublishAttribution sends an "attribution" notification to every
+// subscribed connection whose project filter matches. A subscriber that
+// cannot keep up is disconnected rather than allowed to stall the daemon.
+func (s *Server) PublishAttribution(ev AttributionEvent) {
+	s.mu.Lock()
+	conns := make([]*rpcConn, 0, len(s.conns))
+	for c := range s.conns {
+		conns = append(conns, c)
+	}
+	s.mu.Unlock()
+
+	note := RPCNotification{JSONRPC: JSONRPCVersion, Method: NotifyAttribution, Params: ev}
+	for _, c := range conns {
+		c.mu.Lock()
+		want := c.subscribed && (c.project == "" || c.project == ev.ProjectPath)
+		c.mu.Unlock()
+		if want && !c.write(note) {
+			_ = c.conn.Close()
+		}
+	}
+}
+
+// write sends one message, reporting whether it was written.
+func (c *rpcConn) write(v interface{}) bool {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return false
+	}
+	data = append(data, '\n')
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_ = c.conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+	_, err = c.conn.Write(data)
+	return err == nil
+}
+
+func (s *Server) getDaemon() DaemonQuerier {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.daemon
+}
+
+// status collects the data returned by "status".
+func (s *Server) status() StatusData {
+	s.mu.Lock()
+	daemon, st, health := s.daemon, s.store, s.health
+	s.mu.Unlock()
+
 	data := StatusData{
 		WatchedPaths: s.watchPaths,
 	}
 
-	if s.daemon != nil {
-		data.Uptime = s.daemon.Uptime().Truncate(time.Second).String()
+	if daemon != nil {
+		data.Uptime = daemon.Uptime().Truncate(time.Second).String()
 	}
 
-	if s.store != nil {
-		if v, err := s.store.DBSizeBytes(); err == nil {
+	if st != nil {
+		if v, err := st.DBSizeBytes(); err == nil {
 			data.DBSizeBytes = v
 		}
-		if v, err := s.store.FileEventsCount(); err == nil {
+		if v, err := st.FileEventsCount(); err == nil {
 			data.FileEventsCount = v
 		}
-		if v, err := s.store.SessionEventsCount(); err == nil {
+		if v, err := st.SessionEventsCount(); err == nil {
 			data.SessionEventsCount = v
 		}
-		if v, err := s.store.GitCommitsCount(); err == nil {
+		if v, err := st.GitCommitsCount(); err == nil {
 			data.GitCommitsCount = v
 		}
 	}
 
-	writeResponse(conn, Response{OK: true, Data: data})
+	if health != nil {
+		data.HealthData = health()
+	}
+
+	return data
 }
 
 func writeResponse(conn net.Conn, resp Response) {
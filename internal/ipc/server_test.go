@@ -0,0 +1,360 @@
+package ipc
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type fakeStore struct{}
+
+func (fakeStore) FileEventsCount() (int64, error)    { return 3, nil }
+func (fakeStore) SessionEventsCount() (int64, error) { return 2, nil }
+func (fakeStore) GitCommitsCount() (int64, error)    { return 1, nil }
+func (fakeStore) DBSizeBytes() (int64, error)        { return 4096, nil }
+
+// startServer runs a server on a fresh socket until the test ends.
+func startServer(t *testing.T) (*Server, string) {
+	t.Helper()
+	// Unix socket paths are length-limited, so avoid the long t.TempDir.
+	dir, err := os.MkdirTemp("", "ipc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	socketPath := filepath.Join(dir, "s.sock")
+
+	srv := NewServer(nil, fakeStore{}, []string{"/work/api"})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- srv.Listen(socketPath, ctx) }()
+	t.Cleanup(func() {
+		cancel()
+		_ = srv.Stop()
+		<-done
+	})
+
+	for i := 0; i < 100; i++ {
+		if _, err := os.Stat(socketPath); err == nil {
+			return srv, socketPath
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("server did not start")
+	return nil, ""
+}
+
+// rpcSession is a raw JSON-RPC connection for tests.
+type rpcSession struct {
+	t       *testing.T
+	conn    net.Conn
+	scanner *bufio.Scanner
+}
+
+func dial(t *testing.T, socketPath string) *rpcSession {
+	t.Helper()
+	conn, err := net.Dial("unix", socketPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return &rpcSession{t: t, conn: conn, scanner: bufio.NewScanner(conn)}
+}
+
+func (r *rpcSession) send(msg string) {
+	r.t.Helper()
+	if _, err := r.conn.Write([]byte(msg + "\n")); err != nil {
+		r.t.Fatal(err)
+	}
+}
+
+func (r *rpcSession) recv(v interface{}) {
+	r.t.Helper()
+	_ = r.conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if !r.scanner.Scan() {
+		r.t.Fatalf("no message: %v", r.scanner.Err())
+	}
+	if err := json.Unmarshal(r.scanner.Bytes(), v); err != nil {
+		r.t.Fatalf("unmarshal %s: %v", r.scanner.Bytes(), err)
+	}
+}
+
+type testResponse struct {
+	ID     json.RawMessage `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  *RPCError       `json:"error"`
+}
+
+func TestClient_StatusOverJSONRPC(t *testing.T) {
+	_, socketPath := startServer(t)
+	client := NewClient(socketPath)
+
+	if err := client.Ping(); err != nil {
+		t.Fatalf("Ping: %v", err)
+	}
+	status, err := client.Status()
+	if err != nil {
+		t.Fatalf("Status: %v", err)
+	}
+	if status.FileEventsCount != 3 || status.DBSizeBytes != 4096 || len(status.WatchedPaths) != 1 {
+		t.Errorf("Status = %+v", status)
+	}
+
+	var rpcErr *RPCError
+	if err := client.Call("nope", nil, nil); !errors.As(err, &rpcErr) || rpcErr.Code != CodeMethodNotFound {
+		t.Errorf("Call(nope) = %v, want method not found", err)
+	}
+}
+
+func TestServer_LegacyRequest(t *testing.T) {
+	_, socketPath := startServer(t)
+	s := dial(t, socketPath)
+	s.send(`{"command":"ping"}`)
+
+	var resp Response
+	s.recv(&resp)
+	if !resp.OK || resp.Data != "pong" {
+		t.Errorf("legacy ping = %+v", resp)
+	}
+}
+
+func TestServer_PersistentConnectionAndBatch(t *testing.T) {
+	_, socketPath := startServer(t)
+	s := dial(t, socketPath)
+
+	s.send(`{"jsonrpc":"2.0","id":1,"method":"version"}`)
+	var resp testResponse
+	s.recv(&resp)
+	var version VersionResult
+	if err := json.Unmarshal(resp.Result, &version); err != nil || version.Protocol != ProtocolVersion {
+		t.Fatalf("version = %s (%v)", resp.Result, err)
+	}
+
+	// A notification gets no reply, so the next message answers id 2.
+	s.send(`{"jsonrpc":"2.0","method":"ping"}`)
+	s.send(`{"jsonrpc":"2.0","id":2,"method":"ping"}`)
+	s.recv(&resp)
+	if string(resp.ID) != "2" || string(resp.Result) != `"pong"` {
+		t.Errorf("ping = id %s result %s", resp.ID, resp.Result)
+	}
+
+	s.send(`[{"jsonrpc":"2.0","id":"a","method":"ping"},{"jsonrpc":"2.0","id":"b","method":"missing"},{"jsonrpc":"1.0","id":"c"}]`)
+	var batch []testResponse
+	s.recv(&batch)
+	if len(batch) != 3 {
+		t.Fatalf("batch returned %d responses, want 3", len(batch))
+	}
+	if batch[1].Error == nil || batch[1].Error.Code != CodeMethodNotFound {
+		t.Errorf("batch[1] = %+v, want method not found", batch[1])
+	}
+	if batch[2].Error == nil || batch[2].Error.Code != CodeInvalidRequest {
+		t.Errorf("batch[2] = %+v, want invalid request", batch[2])
+	}
+
+	s.send(`{not json`)
+	s.recv(&resp)
+	if resp.Error == nil || resp.Error.Code != CodeParseError || string(resp.ID) != "null" {
+		t.Errorf("malformed message = %+v, want parse error with null id", resp)
+	}
+}
+
+func TestServer_FileReportAndManualAttribution(t *testing.T) {
+	srv, socketPath := startServer(t)
+	client := NewClient(socketPath)
+
+	var rpcErr *RPCError
+	if err := client.Call(MethodFileReport, FileReportParams{FilePath: "/work/api/a.go"}, nil); !errors.As(err, &rpcErr) || rpcErr.Code != CodeServerError {
+		t.Errorf("fileReport without a reporter = %v, want server error", err)
+	}
+
+	srv.SetFileReporter(func(filePath string) (interface{}, error) {
+		return map[string]string{"file_path": filePath}, nil
+	})
+	var fr map[string]string
+	if err := client.Call(MethodFileReport, FileReportParams{FilePath: "/work/api/a.go"}, &fr); err != nil {
+		t.Fatalf("fileReport: %v", err)
+	}
+	if fr["file_path"] != "/work/api/a.go" {
+		t.Errorf("fileReport = %v", fr)
+	}
+	if err := client.Call(MethodFileReport, FileReportParams{}, nil); !errors.As(err, &rpcErr) || rpcErr.Code != CodeInvalidParams {
+		t.Errorf("fileReport without file_path = %v, want invalid params", err)
+	}
+
+	var got ManualAttribution
+	srv.SetAttributionRecorder(func(m ManualAttribution) (int64, error) {
+		got = m
+		return 42, nil
+	})
+	var res RecordResult
+	params := ManualAttribution{FilePath: "/work/api/a.go", AuthorshipLevel: "mostly_ai", Lines: 5}
+	if err := client.Call(MethodRecordManualAttribution, params, &res); err != nil {
+		t.Fatalf("recordManualAttribution: %v", err)
+	}
+	if res.ID != 42 || got != params {
+		t.Errorf("recorded %+v with id %d", got, res.ID)
+	}
+
+	var marked MarkLines
+	srv.SetLineMarker(func(m MarkLines) (MarkResult, error) {
+		marked = m
+		return MarkResult{SessionID: "mark/" + m.Source, Lines: m.EndLine - m.StartLine + 1}, nil
+	})
+	mark := MarkLines{FilePath: "/work/api/a.go", StartLine: 10, EndLine: 42, Author: "ai", Source: "chatgpt-web"}
+	mres, err := client.MarkLines(mark)
+	if err != nil {
+		t.Fatalf("markLines: %v", err)
+	}
+	if marked != mark || mres.SessionID != "mark/chatgpt-web" || mres.Lines != 33 {
+		t.Errorf("marked %+v, result %+v", marked, mres)
+	}
+	mark.StartLine = 0
+	if _, err := client.MarkLines(mark); !errors.As(err, &rpcErr) || rpcErr.Code != CodeInvalidParams {
+		t.Errorf("markLines from line 0 = %v, want invalid params", err)
+	}
+}
+
+func TestServer_SubscribeReceivesAttributions(t *testing.T) {
+	srv, socketPath := startServer(t)
+
+	all := dial(t, socketPath)
+	all.send(`{"jsonrpc":"2.0","id":1,"method":"subscribe"}`)
+	web := dial(t, socketPath)
+	web.send(`{"jsonrpc":"2.0","id":1,"method":"subscribe","params":{"project_path":"/work/web"}}`)
+	var resp testResponse
+	all.recv(&resp)
+	web.recv(&resp)
+
+	srv.PublishAttribution(AttributionEvent{FilePath: "/work/api/a.go", ProjectPath: "/work/api", AuthorshipLevel: "mostly_ai"})
+	srv.PublishAttribution(AttributionEvent{FilePath: "/work/web/b.ts", ProjectPath: "/work/web", AuthorshipLevel: "mostly_human"})
+
+	var note struct {
+		Method string           `json:"method"`
+		Params AttributionEvent `json:"params"`
+	}
+	all.recv(&note)
+	if note.Method != NotifyAttribution || note.Params.FilePath != "/work/api/a.go" {
+		t.Errorf("first notification = %+v", note)
+	}
+	all.recv(&note)
+	if note.Params.FilePath != "/work/web/b.ts" {
+		t.Errorf("second notification = %+v", note)
+	}
+
+	// The filtered subscriber only sees its project.
+	web.recv(&note)
+	if note.Params.FilePath != "/work/web/b.ts" {
+		t.Errorf("filtered notification = %+v", note)
+	}
+}
+
+func TestClient_Subscribe(t *testing.T) {
+	srv, socketPath := startServer(t)
+	client := NewClient(socketPath)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	events := make(chan AttributionEvent, 1)
+	done := make(chan error, 1)
+	go func() {
+		done <- client.Subscribe(ctx, "/work/api", func(ev AttributionEvent) {
+			select {
+			case events <- ev:
+			default:
+			}
+		})
+	}()
+
+	// Publish until the subscription is registered; the first matching
+	// notification to arrive ends the loop.
+	var got AttributionEvent
+	deadline := time.After(2 * time.Second)
+wait:
+	for {
+		srv.PublishAttribution(AttributionEvent{FilePath: "/work/web/b.ts", ProjectPath: "/work/web"})
+		srv.PublishAttribution(AttributionEvent{FilePath: "/work/api/a.go", ProjectPath: "/work/api", LinesChanged: 4})
+		select {
+		case got = <-events:
+			break wait
+		case <-deadline:
+			t.Fatal("no notification received")
+		case <-time.After(20 * time.Millisecond):
+		}
+	}
+	if got.FilePath != "/work/api/a.go" || got.LinesChanged != 4 {
+		t.Errorf("notification = %+v", got)
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Subscribe after cancel = %v, want nil", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Subscribe did not return after cancel")
+	}
+}
+
+func TestServer_TypingHeartbeat(t *testing.T) {
+	srv, socketPath := startServer(t)
+	client := NewClient(socketPath)
+
+	var got TypingHeartbeat
+	srv.SetTypingRecorder(func(h TypingHeartbeat) (int64, error) {
+		got = h
+		return 7, nil
+	})
+	params := TypingHeartbeat{
+		FilePath:   "/work/api/a.go",
+		StartedAt:  "2026-03-01T09:00:00Z",
+		EndedAt:    "2026-03-01T09:00:12Z",
+		CharsTyped: 80,
+		Source:     "neovim",
+	}
+	var res RecordResult
+	if err := client.Call(MethodTypingHeartbeat, params, &res); err != nil {
+		t.Fatalf("typingHeartbeat: %v", err)
+	}
+	if res.ID != 7 || got != params {
+		t.Errorf("recorded %+v with id %d", got, res.ID)
+	}
+
+	var rpcErr *RPCError
+	if err := client.Call(MethodTypingHeartbeat, TypingHeartbeat{FilePath: "/work/api/a.go"}, nil); !errors.As(err, &rpcErr) || rpcErr.Code != CodeInvalidParams {
+		t.Errorf("typingHeartbeat without times = %v, want invalid params", err)
+	}
+}
+
+func TestClient_SyncGit(t *testing.T) {
+	srv, socketPath := startServer(t)
+	client := NewClient(socketPath)
+
+	var rpcErr *RPCError
+	if err := client.SyncGit(""); !errors.As(err, &rpcErr) || rpcErr.Code != CodeServerError {
+		t.Errorf("SyncGit without a syncer = %v, want server error", err)
+	}
+
+	var repos []string
+	srv.SetGitSyncer(func(repo string) error {
+		if repo == "/other" {
+			return errors.New("not synced")
+		}
+		repos = append(repos, repo)
+		return nil
+	})
+	if err := client.SyncGit("/repo"); err != nil {
+		t.Fatalf("SyncGit: %v", err)
+	}
+	if len(repos) != 1 || repos[0] != "/repo" {
+		t.Errorf("synced %v, want [/repo]", repos)
+	}
+	if err := client.SyncGit("/other"); !errors.As(err, &rpcErr) || rpcErr.Code != CodeServerError {
+		t.Errorf("SyncGit of an unsynced repository = %v, want server error", err)
+	}
+}
@@ -0,0 +1,152 @@
+// Package keychain keeps the database encryption key in the OS keychain:
+// the login keychain via security(1) on macOS, and the Secret Service via
+// secret-tool(1) (GNOME Keyring, KWallet) on Linux, one key per config
+// profile. The GAPMAP_DB_KEY
+// environment variable overrides the keychain, for headless machines and
+// CI.
+package keychain
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/anthropic/gap-map/internal/config"
+	"github.com/anthropic/gap-map/internal/store"
+)
+
+// EnvKey is the environment variable holding a base64-encoded key that
+// takes precedence over the keychain.
+const EnvKey = "GAPMAP_DB_KEY"
+
+// The keychain item the key is stored under.
+const (
+	service = "gap-map"
+	label   = "gap-map database key"
+)
+
+// account returns the account of the active profile's keychain item, so
+// each profile's database has a key of its own. The default profile's is
+// "db-key", as before profiles existed.
+func account() string {
+	if p := config.Profile(); p != config.DefaultProfile {
+		return "db-key-" + p
+	}
+	return "db-key"
+}
+
+// ErrNotFound is returned when the keychain holds no key.
+var ErrNotFound = errors.New("no gap-map key in the keychain")
+
+// ErrUnsupported is returned when no keychain tool is available.
+var ErrUnsupported = errors.New("no keychain available (set " + EnvKey + " instead)")
+
+// Key returns the database key from $GAPMAP_DB_KEY or the keychain.
+func Key() ([]byte, error) {
+	if v := os.Getenv(EnvKey); v != "" {
+		return decode(v, EnvKey)
+	}
+	secret, err := lookup()
+	if err != nil {
+		return nil, err
+	}
+	return decode(secret, "keychain item")
+}
+
+// KeyOrCreate returns the database key, generating one and storing it in
+// the keychain if there is none yet.
+func KeyOrCreate() ([]byte, error) {
+	key, err := Key()
+	if !errors.Is(err, ErrNotFound) {
+		return key, err
+	}
+	key = make([]byte, store.KeySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("generate key: %w", err)
+	}
+	if err := save(base64.StdEncoding.EncodeToString(key)); err != nil {
+		return nil, fmt.Errorf("store key in keychain: %w", err)
+	}
+	return key, nil
+}
+
+func decode(v, from string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(v))
+	if err != nil {
+		return nil, fmt.Errorf("decode %s: %w", from, err)
+	}
+	if len(key) != store.KeySize {
+		return nil, fmt.Errorf("%s holds a %d-byte key, want %d", from, len(key), store.KeySize)
+	}
+	return key, nil
+}
+
+// lookup reads the key item from the platform keychain.
+func lookup() (string, error) {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("security", "find-generic-password", "-s", service, "-a", account(), "-w")
+	case "linux":
+		cmd = exec.Command("secret-tool", "lookup", "service", service, "account", account())
+	default:
+		return "", ErrUnsupported
+	}
+	if _, err := exec.LookPath(cmd.Path); err != nil {
+		return "", ErrUnsupported
+	}
+	out, err := cmd.Output()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) || (err == nil && strings.TrimSpace(string(out)) == "") {
+		// Both tools exit non-zero (secret-tool sometimes zero with no
+		// output) when the item does not exist.
+		return "", ErrNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("read keychain: %w", err)
+	}
+	return string(out), nil
+}
+
+// save writes the key item to the platform keychain, replacing any
+// existing one. The secret goes to the tool on stdin, never on its command
+// line, where other users could read it.
+func save(secret string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		// security(1) only takes the password as an argument, so it is
+		// run in interactive mode and sent the whole command.
+		cmd = exec.Command("security", "-i")
+		cmd.Stdin = strings.NewReader(addPasswordCommand(secret))
+	case "linux":
+		cmd = exec.Command("secret-tool", "store", "--label", label, "service", service, "account", account())
+		cmd.Stdin = strings.NewReader(secret)
+	default:
+		return ErrUnsupported
+	}
+	if _, err := exec.LookPath(cmd.Path); err != nil {
+		return ErrUnsupported
+	}
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(string(out)))
+	}
+	// security -i does not fail when the command it runs does, so read
+	// the item back to be sure it was written.
+	if got, err := lookup(); err != nil || strings.TrimSpace(got) != secret {
+		return fmt.Errorf("key not saved: %s", strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// addPasswordCommand is the security(1) interactive command that stores
+// secret as the key item.
+func addPasswordCommand(secret string) string {
+	return fmt.Sprintf("add-generic-password -U -s %q -a %q -l %q -w %q\n", service, account(), label, secret)
+}
@@ -0,0 +1,53 @@
+package keychain
+
+import (
+	"bytes"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/anthropic/gap-map/internal/config"
+	"github.com/anthropic/gap-map/internal/store"
+)
+
+func TestKey_Env(t *testing.T) {
+	want := bytes.Repeat([]byte{7}, store.KeySize)
+	t.Setenv(EnvKey, base64.StdEncoding.EncodeToString(want)+"\n")
+	key, err := Key()
+	if err != nil {
+		t.Fatalf("Key: %v", err)
+	}
+	if !bytes.Equal(key, want) {
+		t.Errorf("Key = %x, want %x", key, want)
+	}
+	// The environment key is used as it is, never replaced by a new one.
+	if key, err := KeyOrCreate(); err != nil || !bytes.Equal(key, want) {
+		t.Errorf("KeyOrCreate = %x, %v", key, err)
+	}
+
+	for _, bad := range []string{"not base64!", base64.StdEncoding.EncodeToString([]byte("short"))} {
+		t.Setenv(EnvKey, bad)
+		if _, err := Key(); err == nil {
+			t.Errorf("Key with %s=%q: want error", EnvKey, bad)
+		}
+	}
+}
+
+func TestAddPasswordCommand(t *testing.T) {
+	t.Setenv(config.EnvProfile, "")
+	secret := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{9}, store.KeySize))
+	got := addPasswordCommand(secret)
+	want := `add-generic-password -U -s "gap-map" -a "db-key" -l "gap-map database key" -w "` + secret + "\"\n"
+	if got != want {
+		t.Errorf("addPasswordCommand = %q, want %q", got, want)
+	}
+	if strings.Count(got, "\n") != 1 {
+		t.Error("command spans more than one line")
+	}
+
+	// Another profile keeps its key in an item of its own.
+	t.Setenv(config.EnvProfile, "client-a")
+	if got := addPasswordCommand(secret); !strings.Contains(got, `-a "db-key-client-a"`) {
+		t.Errorf("addPasswordCommand in profile client-a = %q", got)
+	}
+}
@@ -0,0 +1,69 @@
+// Package logging sets up the daemon's structured log: a log/slog handler
+// in text or JSON format at a configured level, written to a file that
+// rotates by size and age and keeps a bounded number of old files.
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// Log formats.
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// Options configure the daemon log.
+type Options struct {
+	Level      string        // "debug", "info" (default), "warn" or "error"
+	Format     string        // FormatText (default) or FormatJSON
+	MaxSize    int64         // rotate once the file reaches this many bytes; 0 means no limit
+	Interval   time.Duration // rotate once the file is this old; 0 means never
+	MaxBackups int           // rotated files to keep; 0 keeps them all
+}
+
+// ParseLevel parses a level name, case-insensitively. An empty name is
+// slog.LevelInfo.
+func ParseLevel(name string) (slog.Level, error) {
+	var level slog.Level
+	if name == "" {
+		return slog.LevelInfo, nil
+	}
+	if err := level.UnmarshalText([]byte(name)); err != nil {
+		return level, fmt.Errorf("unknown log level %q (want debug, info, warn or error)", name)
+	}
+	return level, nil
+}
+
+// NewHandler returns a handler writing records at opts.Level and above to
+// w in opts.Format.
+func NewHandler(w io.Writer, opts Options) (slog.Handler, error) {
+	level, err := ParseLevel(opts.Level)
+	if err != nil {
+		return nil, err
+	}
+	ho := &slog.HandlerOptions{Level: level}
+	switch strings.ToLower(opts.Format) {
+	case "", FormatText:
+		return slog.NewTextHandler(w, ho), nil
+	case FormatJSON:
+		return slog.NewJSONHandler(w, ho), nil
+	default:
+		return nil, fmt.Errorf("unknown log format %q (want %s or %s)", opts.Format, FormatText, FormatJSON)
+	}
+}
+
+// Setup makes a handler for w the default slog logger. Output of the
+// standard log package goes through it too, at info level.
+func Setup(w io.Writer, opts Options) error {
+	h, err := NewHandler(w, opts)
+	if err != nil {
+		return err
+	}
+	slog.SetDefault(slog.New(h))
+	return nil
+}
@@ -0,0 +1,34 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"testing"
+)
+
+func TestNewHandler(t *testing.T) {
+	var buf bytes.Buffer
+	h, err := NewHandler(&buf, Options{Level: "WARN", Format: FormatJSON})
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := slog.New(h)
+	logger.Info("dropped")
+	logger.Warn("git sync failed", "err", "boom")
+
+	var rec map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &rec); err != nil {
+		t.Fatalf("want one JSON record, got %q: %v", buf.String(), err)
+	}
+	if rec["msg"] != "git sync failed" || rec["level"] != "WARN" || rec["err"] != "boom" {
+		t.Errorf("record = %v", rec)
+	}
+
+	if _, err := NewHandler(&buf, Options{Level: "loud"}); err == nil {
+		t.Error("expected an error for an unknown level")
+	}
+	if _, err := NewHandler(&buf, Options{Format: "xml"}); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
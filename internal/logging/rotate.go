@@ -0,0 +1,152 @@
+package logging
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// backupTimeFormat stamps rotated files; it sorts in time order.
+const backupTimeFormat = "20060102T150405.000"
+
+// RotatingFile is an append-only log file that is renamed aside, with a
+// timestamp suffix, once it reaches a size or age limit. Only the newest
+// MaxBackups renamed files are kept. It is safe for concurrent use.
+type RotatingFile struct {
+	path string
+	opts Options
+	now  func() time.Time
+
+	mu     sync.Mutex
+	f      *os.File
+	size   int64
+	opened time.Time
+}
+
+// OpenRotating opens (or creates) the log file at path for appending,
+// rotating it per opts.MaxSize, opts.Interval and opts.MaxBackups. An
+// existing file's age counts from its last modification.
+func OpenRotating(path string, opts Options) (*RotatingFile, error) {
+	r := &RotatingFile{path: path, opts: opts, now: time.Now}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Path returns the path of the active log file.
+func (r *RotatingFile) Path() string {
+	return r.path
+}
+
+// Write appends p to the file, rotating first if p would take it past
+// MaxSize or the file is older than Interval.
+func (r *RotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.f == nil {
+		return 0, os.ErrClosed
+	}
+	if r.due(int64(len(p))) {
+		if err := r.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := r.f.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+// Close closes the file.
+func (r *RotatingFile) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.f == nil {
+		return nil
+	}
+	err := r.f.Close()
+	r.f = nil
+	return err
+}
+
+// due reports whether the file must rotate before writing n more bytes. An
+// empty file never rotates, so one oversized record still gets written.
+func (r *RotatingFile) due(n int64) bool {
+	if r.size == 0 {
+		return false
+	}
+	if r.opts.MaxSize > 0 && r.size+n > r.opts.MaxSize {
+		return true
+	}
+	return r.opts.Interval > 0 && r.now().Sub(r.opened) >= r.opts.Interval
+}
+
+func (r *RotatingFile) open() error {
+	if err := os.MkdirAll(filepath.Dir(r.path), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	r.f, r.size, r.opened = f, info.Size(), r.now()
+	if r.size > 0 {
+		r.opened = info.ModTime()
+	}
+	return nil
+}
+
+// rotate renames the active file aside, opens a new one and removes
+// backups beyond MaxBackups.
+func (r *RotatingFile) rotate() error {
+	if err := r.f.Close(); err != nil {
+		return err
+	}
+	r.f = nil
+	backup := r.path + "." + r.now().Format(backupTimeFormat)
+	if err := os.Rename(r.path, backup); err != nil {
+		return fmt.Errorf("rotate log: %w", err)
+	}
+	if err := r.open(); err != nil {
+		return fmt.Errorf("rotate log: %w", err)
+	}
+	if r.opts.MaxBackups <= 0 {
+		return nil
+	}
+	backups, err := Backups(r.path)
+	if err != nil {
+		return nil
+	}
+	for len(backups) > r.opts.MaxBackups {
+		_ = os.Remove(backups[0])
+		backups = backups[1:]
+	}
+	return nil
+}
+
+// Backups returns the rotated files of the log at path, oldest first.
+func Backups(path string) ([]string, error) {
+	matches, err := filepath.Glob(path + ".*")
+	if err != nil {
+		return nil, err
+	}
+	var backups []string
+	for _, m := range matches {
+		stamp := strings.TrimPrefix(m, path+".")
+		if _, err := time.Parse(backupTimeFormat, stamp); err == nil {
+			backups = append(backups, m)
+		}
+	}
+	sort.Strings(backups)
+	return backups, nil
+}
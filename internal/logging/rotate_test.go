@@ -0,0 +1,74 @@
+package logging
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRotatingFileSize(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "daemon.log")
+	r, err := OpenRotating(path, Options{MaxSize: 10, MaxBackups: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	clock := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	r.now = func() time.Time { clock = clock.Add(time.Second); return clock }
+
+	for _, line := range []string{"one\n", "two\n", "three\n", "four\n", "five\n", "six\n"} {
+		if _, err := r.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Every file holds what fits in 10 bytes; only two backups are kept.
+	backups, err := Backups(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backups) != 2 {
+		t.Fatalf("backups = %v, want 2", backups)
+	}
+	var got []string
+	for _, p := range append(backups, path) {
+		data, err := os.ReadFile(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, string(data))
+	}
+	if want := []string{"three\n", "four\nfive\n", "six\n"}; strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("files = %q, want %q", got, want)
+	}
+}
+
+func TestRotatingFileInterval(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "daemon.log")
+	r, err := OpenRotating(path, Options{Interval: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	clock := time.Now()
+	r.now = func() time.Time { return clock }
+	r.opened = clock
+
+	r.Write([]byte("first\n"))
+	clock = clock.Add(30 * time.Minute)
+	r.Write([]byte("second\n"))
+	if backups, _ := Backups(path); len(backups) != 0 {
+		t.Fatalf("rotated early: %v", backups)
+	}
+	clock = clock.Add(time.Hour)
+	r.Write([]byte("third\n"))
+	backups, _ := Backups(path)
+	if len(backups) != 1 {
+		t.Fatalf("backups = %v, want 1", backups)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "third\n" {
+		t.Errorf("active log = %q, want third", data)
+	}
+}
@@ -0,0 +1,90 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"time"
+)
+
+// followInterval is how often Tail checks a followed log for new output.
+const followInterval = 250 * time.Millisecond
+
+// Tail writes the last n lines of the log at path to w; n <= 0 writes the
+// whole file. With follow it then writes output as it is appended until ctx
+// is done, switching to the new file when the log rotates.
+func Tail(ctx context.Context, path string, n int, w io.Writer, follow bool) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() { f.Close() }()
+
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data[lastLinesStart(data, n):]); err != nil {
+		return err
+	}
+	if !follow {
+		return nil
+	}
+
+	offset := int64(len(data))
+	ticker := time.NewTicker(followInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		// Drain the open file first: after a rotation it holds the last
+		// lines written before the rename.
+		written, err := io.Copy(w, f)
+		if err != nil {
+			return err
+		}
+		offset += written
+
+		opened, err := f.Stat()
+		if err != nil {
+			return err
+		}
+		current, err := os.Stat(path)
+		if err != nil {
+			continue // between the rename and the new file
+		}
+		if !os.SameFile(opened, current) || current.Size() < offset {
+			next, err := os.Open(path)
+			if err != nil {
+				continue
+			}
+			f.Close()
+			f, offset = next, 0
+		}
+	}
+}
+
+// lastLinesStart returns the offset in data where its last n lines begin,
+// ignoring a final newline.
+func lastLinesStart(data []byte, n int) int {
+	if n <= 0 {
+		return 0
+	}
+	end := len(data)
+	if end > 0 && data[end-1] == '\n' {
+		end--
+	}
+	for ; n > 0; n-- {
+		i := bytes.LastIndexByte(data[:end], '\n')
+		if i < 0 {
+			return 0
+		}
+		end = i
+	}
+	return end + 1
+}
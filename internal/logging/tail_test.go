@@ -0,0 +1,81 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLastLinesStart(t *testing.T) {
+	cases := []struct {
+		data string
+		n    int
+		want string
+	}{
+		{"a\nb\nc\n", 2, "b\nc\n"},
+		{"a\nb\nc", 1, "c"},
+		{"a\nb\n", 5, "a\nb\n"},
+		{"a\nb\n", 0, "a\nb\n"},
+		{"", 3, ""},
+	}
+	for _, c := range cases {
+		if got := c.data[lastLinesStart([]byte(c.data), c.n):]; got != c.want {
+			t.Errorf("last %d lines of %q = %q, want %q", c.n, c.data, got, c.want)
+		}
+	}
+}
+
+// syncBuffer is a bytes.Buffer safe to read while Tail writes to it.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestTailFollowAcrossRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "daemon.log")
+	r, err := OpenRotating(path, Options{MaxSize: 12})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	r.Write([]byte("old\nlast\n"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var out syncBuffer
+	done := make(chan error, 1)
+	go func() { done <- Tail(ctx, path, 1, &out, true) }()
+
+	waitFor := func(s string) {
+		deadline := time.Now().Add(5 * time.Second)
+		for !strings.Contains(out.String(), s) && time.Now().Before(deadline) {
+			time.Sleep(20 * time.Millisecond)
+		}
+	}
+	waitFor("last\n")
+	r.Write([]byte("next\n"))  // rotates the log
+	r.Write([]byte("after\n")) // into the new file
+	waitFor("after\n")
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if got := out.String(); got != "last\nnext\nafter\n" {
+		t.Errorf("tail = %q, want last, next and after", got)
+	}
+}
@@ -0,0 +1,222 @@
+// Package mcp serves gap-map's attribution data over the Model Context
+// Protocol, so an AI assistant can ask during a session who wrote a file.
+//
+// The server speaks JSON-RPC 2.0 (the message types of package ipc) over
+// stdio, one message per line, or over HTTP with server-sent events. It
+// offers the project report and per-file reports as resources, and the
+// analyze_file and survival tools.
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/anthropic/gap-map/internal/ipc"
+	"github.com/anthropic/gap-map/pkg/gapmap"
+)
+
+// ProtocolVersion is the MCP revision the server implements.
+const ProtocolVersion = "2024-11-05"
+
+// Resource URIs. A file report is read at FileURIPrefix followed by the
+// file's path as tracked, e.g. gapmap://file/home/me/proj/main.go.
+const (
+	ProjectURI    = "gapmap://project"
+	SurvivalURI   = "gapmap://survival"
+	FileURIPrefix = "gapmap://file"
+)
+
+// Backend answers the queries the server exposes. *gapmap.Project is one.
+type Backend interface {
+	ProjectReport(ctx context.Context) (*gapmap.ProjectReport, error)
+	FileReport(ctx context.Context, filePath string) (*gapmap.FileReport, error)
+	Survival(ctx context.Context) (*gapmap.SurvivalReport, error)
+}
+
+// Server handles MCP messages for a Backend.
+type Server struct {
+	backend Backend
+}
+
+// NewServer returns a Server answering from backend.
+func NewServer(backend Backend) *Server {
+	return &Server{backend: backend}
+}
+
+// tool describes one MCP tool.
+type tool struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	InputSchema json.RawMessage `json:"inputSchema"`
+}
+
+var tools = []tool{
+	{
+		Name:        "analyze_file",
+		Description: "Report who wrote a file: how much of it an AI wrote, its work type and authorship level.",
+		InputSchema: json.RawMessage(`{"type":"object","properties":{"file_path":{"type":"string","description":"Absolute path of the file"}},"required":["file_path"]}`),
+	},
+	{
+		Name:        "survival",
+		Description: "Report how much AI-written code in the project is still present, by authorship level and work type.",
+		InputSchema: json.RawMessage(`{"type":"object","properties":{}}`),
+	},
+}
+
+// resource describes one MCP resource or resource template.
+type resource struct {
+	URI         string `json:"uri,omitempty"`
+	URITemplate string `json:"uriTemplate,omitempty"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	MimeType    string `json:"mimeType"`
+}
+
+var resources = []resource{
+	{URI: ProjectURI, Name: "Project attribution report", Description: "AI and human authorship of every tracked file of the project.", MimeType: "application/json"},
+	{URI: SurvivalURI, Name: "AI code survival", Description: "How much AI-written code is still present.", MimeType: "application/json"},
+}
+
+var resourceTemplates = []resource{
+	{URITemplate: FileURIPrefix + "{+path}", Name: "File attribution report", Description: "AI and human authorship of one file, by its absolute path.", MimeType: "application/json"},
+}
+
+// Handle answers one JSON-RPC message and returns the response to send, or
+// nil for a notification.
+func (s *Server) Handle(ctx context.Context, msg []byte) *ipc.RPCResponse {
+	var req ipc.RPCRequest
+	if err := json.Unmarshal(msg, &req); err != nil {
+		return errorResponse(nil, ipc.CodeParseError, "parse error: "+err.Error())
+	}
+	if req.JSONRPC != ipc.JSONRPCVersion || req.Method == "" {
+		return errorResponse(req.ID, ipc.CodeInvalidRequest, "invalid request")
+	}
+
+	result, rpcErr := s.dispatch(ctx, req.Method, req.Params)
+	if len(req.ID) == 0 {
+		return nil // notification
+	}
+	if rpcErr != nil {
+		return &ipc.RPCResponse{JSONRPC: ipc.JSONRPCVersion, ID: req.ID, Error: rpcErr}
+	}
+	return &ipc.RPCResponse{JSONRPC: ipc.JSONRPCVersion, ID: req.ID, Result: result}
+}
+
+func (s *Server) dispatch(ctx context.Context, method string, params json.RawMessage) (interface{}, *ipc.RPCError) {
+	switch method {
+	case "initialize":
+		return map[string]interface{}{
+			"protocolVersion": ProtocolVersion,
+			"capabilities": map[string]interface{}{
+				"resources": map[string]interface{}{},
+				"tools":     map[string]interface{}{},
+			},
+			"serverInfo": map[string]string{"name": "gap-map", "version": gapmap.APIVersion},
+		}, nil
+	case "notifications/initialized", "notifications/cancelled", "ping":
+		return map[string]interface{}{}, nil
+	case "tools/list":
+		return map[string]interface{}{"tools": tools}, nil
+	case "tools/call":
+		var p struct {
+			Name      string          `json:"name"`
+			Arguments json.RawMessage `json:"arguments"`
+		}
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, &ipc.RPCError{Code: ipc.CodeInvalidParams, Message: "invalid params: " + err.Error()}
+		}
+		return s.callTool(ctx, p.Name, p.Arguments)
+	case "resources/list":
+		return map[string]interface{}{"resources": resources}, nil
+	case "resources/templates/list":
+		return map[string]interface{}{"resourceTemplates": resourceTemplates}, nil
+	case "resources/read":
+		var p struct {
+			URI string `json:"uri"`
+		}
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, &ipc.RPCError{Code: ipc.CodeInvalidParams, Message: "invalid params: " + err.Error()}
+		}
+		data, err := s.readResource(ctx, p.URI)
+		if err != nil {
+			return nil, &ipc.RPCError{Code: ipc.CodeServerError, Message: err.Error()}
+		}
+		return map[string]interface{}{
+			"contents": []map[string]string{{"uri": p.URI, "mimeType": "application/json", "text": string(data)}},
+		}, nil
+	default:
+		return nil, &ipc.RPCError{Code: ipc.CodeMethodNotFound, Message: "method not found: " + method}
+	}
+}
+
+// callTool runs a tool. A tool that fails reports the failure in its
+// result, as MCP asks, so the model can see it.
+func (s *Server) callTool(ctx context.Context, name string, args json.RawMessage) (interface{}, *ipc.RPCError) {
+	var (
+		v   interface{}
+		err error
+	)
+	switch name {
+	case "analyze_file":
+		var a struct {
+			FilePath string `json:"file_path"`
+		}
+		if len(args) > 0 {
+			if err := json.Unmarshal(args, &a); err != nil {
+				return nil, &ipc.RPCError{Code: ipc.CodeInvalidParams, Message: "invalid arguments: " + err.Error()}
+			}
+		}
+		if a.FilePath == "" {
+			return nil, &ipc.RPCError{Code: ipc.CodeInvalidParams, Message: "file_path is required"}
+		}
+		v, err = s.backend.FileReport(ctx, a.FilePath)
+	case "survival":
+		v, err = s.backend.Survival(ctx)
+	default:
+		return nil, &ipc.RPCError{Code: ipc.CodeInvalidParams, Message: "unknown tool: " + name}
+	}
+
+	if err != nil {
+		return toolResult(err.Error(), true), nil
+	}
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, &ipc.RPCError{Code: ipc.CodeInternalError, Message: err.Error()}
+	}
+	return toolResult(string(data), false), nil
+}
+
+func toolResult(text string, isError bool) map[string]interface{} {
+	return map[string]interface{}{
+		"content": []map[string]string{{"type": "text", "text": text}},
+		"isError": isError,
+	}
+}
+
+// readResource returns the JSON of the resource at uri.
+func (s *Server) readResource(ctx context.Context, uri string) ([]byte, error) {
+	var (
+		v   interface{}
+		err error
+	)
+	switch {
+	case uri == ProjectURI:
+		v, err = s.backend.ProjectReport(ctx)
+	case uri == SurvivalURI:
+		v, err = s.backend.Survival(ctx)
+	case strings.HasPrefix(uri, FileURIPrefix+"/"):
+		v, err = s.backend.FileReport(ctx, strings.TrimPrefix(uri, FileURIPrefix))
+	default:
+		return nil, fmt.Errorf("unknown resource %q", uri)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(v, "", "  ")
+}
+
+func errorResponse(id json.RawMessage, code int, msg string) *ipc.RPCResponse {
+	return &ipc.RPCResponse{JSONRPC: ipc.JSONRPCVersion, ID: id, Error: &ipc.RPCError{Code: code, Message: msg}}
+}
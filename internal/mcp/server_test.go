@@ -0,0 +1,294 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/anthropic/gap-map/internal/ipc"
+	"github.com/anthropic/gap-map/pkg/gapmap"
+)
+
+type fakeBackend struct{}
+
+func (fakeBackend) ProjectReport(ctx context.Context) (*gapmap.ProjectReport, error) {
+	return &gapmap.ProjectReport{ProjectPath: "/proj", TotalFiles: 1, MeaningfulAIPct: 50}, nil
+}
+
+func (fakeBackend) FileReport(ctx context.Context, filePath string) (*gapmap.FileReport, error) {
+	if filePath != "/proj/main.go" {
+		return nil, errors.New("file not tracked: " + filePath)
+	}
+	return &gapmap.FileReport{Path: filePath, AuthorshipLevel: "mostly_ai", RawAIPct: 80}, nil
+}
+
+func (fakeBackend) Survival(ctx context.Context) (*gapmap.SurvivalReport, error) {
+	return &gapmap.SurvivalReport{Tracked: 10, Survived: 7, Rate: 70}, nil
+}
+
+// call sends one request to s and decodes the result into out.
+func call(t *testing.T, s *Server, method string, params interface{}, out interface{}) *ipc.RPCError {
+	t.Helper()
+	req := map[string]interface{}{"jsonrpc": "2.0", "id": 1, "method": method}
+	if params != nil {
+		req["params"] = params
+	}
+	msg, _ := json.Marshal(req)
+	resp := s.Handle(context.Background(), msg)
+	if resp == nil {
+		t.Fatalf("%s: no response", method)
+	}
+	if resp.Error != nil {
+		return resp.Error
+	}
+	data, _ := json.Marshal(resp.Result)
+	if err := json.Unmarshal(data, out); err != nil {
+		t.Fatalf("%s: decode result: %v", method, err)
+	}
+	return nil
+}
+
+type toolCallResult struct {
+	Content []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"content"`
+	IsError bool `json:"isError"`
+}
+
+func TestServer(t *testing.T) {
+	s := NewServer(fakeBackend{})
+
+	var init struct {
+		ProtocolVersion string                     `json:"protocolVersion"`
+		Capabilities    map[string]json.RawMessage `json:"capabilities"`
+	}
+	if err := call(t, s, "initialize", map[string]interface{}{"protocolVersion": ProtocolVersion}, &init); err != nil {
+		t.Fatalf("initialize: %v", err)
+	}
+	if init.ProtocolVersion != ProtocolVersion || init.Capabilities["tools"] == nil || init.Capabilities["resources"] == nil {
+		t.Errorf("initialize = %+v", init)
+	}
+
+	if resp := s.Handle(context.Background(), []byte(`{"jsonrpc":"2.0","method":"notifications/initialized"}`)); resp != nil {
+		t.Errorf("notification answered: %+v", resp)
+	}
+
+	var list struct {
+		Tools []tool `json:"tools"`
+	}
+	if err := call(t, s, "tools/list", nil, &list); err != nil {
+		t.Fatalf("tools/list: %v", err)
+	}
+	if len(list.Tools) != 2 || list.Tools[0].Name != "analyze_file" || list.Tools[1].Name != "survival" {
+		t.Errorf("tools = %+v", list.Tools)
+	}
+
+	var res toolCallResult
+	if err := call(t, s, "tools/call", map[string]interface{}{"name": "analyze_file", "arguments": map[string]string{"file_path": "/proj/main.go"}}, &res); err != nil {
+		t.Fatalf("analyze_file: %v", err)
+	}
+	if res.IsError || len(res.Content) != 1 || !strings.Contains(res.Content[0].Text, `"AuthorshipLevel": "mostly_ai"`) {
+		t.Errorf("analyze_file = %+v", res)
+	}
+
+	res = toolCallResult{}
+	if err := call(t, s, "tools/call", map[string]interface{}{"name": "analyze_file", "arguments": map[string]string{"file_path": "/other.go"}}, &res); err != nil {
+		t.Fatalf("analyze_file untracked: %v", err)
+	}
+	if !res.IsError || !strings.Contains(res.Content[0].Text, "not tracked") {
+		t.Errorf("analyze_file untracked = %+v, want a tool error", res)
+	}
+
+	if err := call(t, s, "tools/call", map[string]interface{}{"name": "analyze_file"}, &res); err == nil || err.Code != ipc.CodeInvalidParams {
+		t.Errorf("analyze_file without file_path: err = %v, want invalid params", err)
+	}
+
+	res = toolCallResult{}
+	if err := call(t, s, "tools/call", map[string]interface{}{"name": "survival"}, &res); err != nil {
+		t.Fatalf("survival: %v", err)
+	}
+	if res.IsError || !strings.Contains(res.Content[0].Text, `"Rate": 70`) {
+		t.Errorf("survival = %+v", res)
+	}
+
+	if err := call(t, s, "no/such", nil, &res); err == nil || err.Code != ipc.CodeMethodNotFound {
+		t.Errorf("unknown method: err = %v, want method not found", err)
+	}
+}
+
+func TestServerResources(t *testing.T) {
+	s := NewServer(fakeBackend{})
+
+	var list struct {
+		Resources []resource `json:"resources"`
+	}
+	if err := call(t, s, "resources/list", nil, &list); err != nil {
+		t.Fatalf("resources/list: %v", err)
+	}
+	if len(list.Resources) == 0 || list.Resources[0].URI != ProjectURI {
+		t.Errorf("resources = %+v", list.Resources)
+	}
+
+	tests := []struct {
+		uri  string
+		want string
+	}{
+		{ProjectURI, `"ProjectPath": "/proj"`},
+		{SurvivalURI, `"Survived": 7`},
+		{FileURIPrefix + "/proj/main.go", `"Path": "/proj/main.go"`},
+	}
+	for _, tt := range tests {
+		var read struct {
+			Contents []struct {
+				URI      string `json:"uri"`
+				MimeType string `json:"mimeType"`
+				Text     string `json:"text"`
+			} `json:"contents"`
+		}
+		if err := call(t, s, "resources/read", map[string]string{"uri": tt.uri}, &read); err != nil {
+			t.Errorf("read %s: %v", tt.uri, err)
+			continue
+		}
+		if len(read.Contents) != 1 || read.Contents[0].URI != tt.uri || !strings.Contains(read.Contents[0].Text, tt.want) {
+			t.Errorf("read %s = %+v, want text containing %s", tt.uri, read, tt.want)
+		}
+	}
+
+	var read json.RawMessage
+	if err := call(t, s, "resources/read", map[string]string{"uri": "gapmap://nothing"}, &read); err == nil {
+		t.Error("read of unknown resource succeeded")
+	}
+}
+
+func TestServeStdio(t *testing.T) {
+	s := NewServer(fakeBackend{})
+	in := strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"ping"}` + "\n" +
+		`{"jsonrpc":"2.0","method":"notifications/initialized"}` + "\n" +
+		`not json` + "\n" +
+		`{"jsonrpc":"2.0","id":"two","method":"tools/list"}` + "\n")
+	var out bytes.Buffer
+	if err := s.ServeStdio(context.Background(), in, &out); err != nil {
+		t.Fatalf("ServeStdio: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("got %d responses, want 3:\n%s", len(lines), out.String())
+	}
+	var ids []string
+	for _, line := range lines {
+		var resp ipc.RPCResponse
+		if err := json.Unmarshal([]byte(line), &resp); err != nil {
+			t.Fatalf("response %q: %v", line, err)
+		}
+		ids = append(ids, string(resp.ID))
+	}
+	if ids[0] != "1" || ids[1] != "null" || ids[2] != `"two"` {
+		t.Errorf("response ids = %v, want [1 null \"two\"]", ids)
+	}
+}
+
+func TestSSE(t *testing.T) {
+	srv := httptest.NewUnstartedServer(nil)
+	srv.Config.Handler = NewServer(fakeBackend{}).SSEHandler(srv.Listener.Addr().String())
+	srv.Start()
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/sse", nil)
+	stream, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET /sse: %v", err)
+	}
+	defer stream.Body.Close()
+	events := bufio.NewReader(stream.Body)
+
+	// next returns the event name and data of the next event.
+	next := func() (string, string) {
+		var event, data string
+		for {
+			line, err := events.ReadString('\n')
+			if err != nil {
+				t.Fatalf("read event: %v", err)
+			}
+			line = strings.TrimRight(line, "\n")
+			switch {
+			case strings.HasPrefix(line, "event: "):
+				event = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: "):
+				data = strings.TrimPrefix(line, "data: ")
+			case line == "":
+				return event, data
+			}
+		}
+	}
+
+	event, endpoint := next()
+	if event != "endpoint" || !strings.HasPrefix(endpoint, "/message?session_id=") {
+		t.Fatalf("first event = %s %q, want endpoint", event, endpoint)
+	}
+
+	resp, err := http.Post(srv.URL+endpoint, "application/json", strings.NewReader(`{"jsonrpc":"2.0","id":7,"method":"ping"}`))
+	if err != nil {
+		t.Fatalf("POST: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		t.Errorf("POST status = %d, want 202", resp.StatusCode)
+	}
+
+	event, data := next()
+	var rpc ipc.RPCResponse
+	if err := json.Unmarshal([]byte(data), &rpc); err != nil || event != "message" || string(rpc.ID) != "7" {
+		t.Errorf("response event = %s %q (%v)", event, data, err)
+	}
+
+	resp, err = http.Post(srv.URL+"/message?session_id=nope", "application/json", strings.NewReader(`{}`))
+	if err != nil {
+		t.Fatalf("POST: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("POST to unknown session: status = %d, want 404", resp.StatusCode)
+	}
+}
+
+// TestSSE_RejectsForeignRequests checks that the SSE transport refuses
+// requests for another host, as a DNS rebinding sends, and requests from
+// web pages on other origins.
+func TestSSE_RejectsForeignRequests(t *testing.T) {
+	h := NewServer(fakeBackend{}).SSEHandler("127.0.0.1:8765")
+
+	tests := []struct {
+		name, host, origin string
+		allowed            bool
+	}{
+		{"bind address", "127.0.0.1:8765", "", true},
+		{"localhost alias", "localhost:8765", "http://localhost:3000", true},
+		{"rebound host", "evil.example:8765", "", false},
+		{"other port", "localhost:9999", "", false},
+		{"foreign origin", "127.0.0.1:8765", "https://evil.example", false},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/message?session_id=nope", strings.NewReader(`{}`))
+		req.Host = tt.host
+		if tt.origin != "" {
+			req.Header.Set("Origin", tt.origin)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		// An allowed request reaches the transport, which has no such
+		// session and answers 404.
+		if allowed := rec.Code != http.StatusForbidden; allowed != tt.allowed {
+			t.Errorf("%s: status = %d, allowed = %v, want %v", tt.name, rec.Code, allowed, tt.allowed)
+		}
+	}
+}
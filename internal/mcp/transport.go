@@ -0,0 +1,196 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// maxMessageSize bounds one JSON-RPC message read from a client.
+const maxMessageSize = 4 << 20
+
+// ServeStdio reads newline-delimited JSON-RPC messages from r and writes
+// the responses to w, one per line, until r ends or ctx is cancelled.
+func (s *Server) ServeStdio(ctx context.Context, r io.Reader, w io.Writer) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxMessageSize)
+	enc := json.NewEncoder(w)
+	for scanner.Scan() {
+		if ctx.Err() != nil {
+			return nil
+		}
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		if resp := s.Handle(ctx, line); resp != nil {
+			if err := enc.Encode(resp); err != nil {
+				return fmt.Errorf("write response: %w", err)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("read request: %w", err)
+	}
+	return nil
+}
+
+// SSEHandler returns an http.Handler serving the MCP HTTP+SSE transport.
+// A client opens an event stream with GET /sse, whose first "endpoint"
+// event names the URL to POST its messages to; responses arrive on the
+// stream as "message" events.
+//
+// The transport has no authentication, so the handler only serves requests
+// addressed to addr, the address it listens on, and rejects browser
+// requests from any origin but localhost. A web page can then neither call
+// it directly nor reach it through a DNS rebinding.
+func (s *Server) SSEHandler(addr string) http.Handler {
+	h := &sseHandler{server: s, sessions: make(map[string]chan []byte)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/sse", h.stream)
+	mux.HandleFunc("/message", h.message)
+	hosts := allowedHosts(addr)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !hosts[strings.ToLower(r.Host)] {
+			http.Error(w, "host not allowed", http.StatusForbidden)
+			return
+		}
+		if origin := r.Header.Get("Origin"); origin != "" && !localOrigin(origin) {
+			http.Error(w, "origin not allowed", http.StatusForbidden)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// allowedHosts returns the Host headers that address a server listening on
+// addr: addr itself and, for a loopback address, the other loopback names
+// for its port.
+func allowedHosts(addr string) map[string]bool {
+	hosts := map[string]bool{strings.ToLower(addr): true}
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil || !loopback(host) {
+		return hosts
+	}
+	for _, h := range []string{"localhost", "127.0.0.1", "::1"} {
+		hosts[net.JoinHostPort(h, port)] = true
+	}
+	return hosts
+}
+
+// localOrigin reports whether origin, an Origin header, names a page
+// served from this machine.
+func localOrigin(origin string) bool {
+	u, err := url.Parse(origin)
+	return err == nil && loopback(u.Hostname())
+}
+
+// loopback reports whether host is localhost or a loopback IP address.
+func loopback(host string) bool {
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+type sseHandler struct {
+	server *Server
+
+	mu       sync.Mutex
+	sessions map[string]chan []byte // session id -> responses to send
+}
+
+func (h *sseHandler) stream(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	id, err := newSessionID()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	out := make(chan []byte, 16)
+	h.mu.Lock()
+	h.sessions[id] = out
+	h.mu.Unlock()
+	defer func() {
+		h.mu.Lock()
+		delete(h.sessions, id)
+		h.mu.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	fmt.Fprintf(w, "event: endpoint\ndata: /message?session_id=%s\n\n", id)
+	flusher.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case msg := <-out:
+			fmt.Fprintf(w, "event: message\ndata: %s\n\n", msg)
+			flusher.Flush()
+		}
+	}
+}
+
+func (h *sseHandler) message(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	h.mu.Lock()
+	out, ok := h.sessions[r.URL.Query().Get("session_id")]
+	h.mu.Unlock()
+	if !ok {
+		http.Error(w, "unknown session", http.StatusNotFound)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxMessageSize))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+
+	resp := h.server.Handle(r.Context(), body)
+	if resp == nil {
+		return
+	}
+	data, err := json.Marshal(resp)
+	if err != nil {
+		return
+	}
+	select {
+	case out <- data:
+	case <-r.Context().Done():
+	}
+}
+
+func newSessionID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("session id: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -26,6 +26,7 @@ type FileMetrics struct {
 // ProjectMetrics holds aggregate metrics for an entire project.
 type ProjectMetrics struct {
 	ProjectPath     string
+	Scorer          string                     // name of the scorer behind MeaningfulAIPct
 	TotalFiles      int
 	FileMetrics     []FileMetrics
 	MeaningfulAIPct float64                    // weighted aggregate
@@ -52,17 +53,26 @@ type WorkTypeBreakdown struct {
 // aiAuthorshipLevels are the authorship levels that count as AI-authored.
 // Includes both new 3-level names and legacy 5-level names for backward compat.
 var aiAuthorshipLevels = map[string]bool{
-	"mostly_ai":              true,
-	"fully_ai":               true,
-	"ai_first_human_revised": true,
+	"mostly_ai":                  true,
+	"fully_ai":                   true,
+	"ai_first_human_revised":     true,
+	"ai_suggested_human_written": true,
 }
 
 // Calculator computes meaningful AI percentage metrics.
-type Calculator struct{}
+type Calculator struct {
+	scorer Scorer
+}
 
-// NewCalculator creates a new Calculator.
+// NewCalculator creates a new Calculator using the default scorer.
 func NewCalculator() *Calculator {
-	return &Calculator{}
+	return &Calculator{scorer: DefaultScorer()}
+}
+
+// NewCalculatorWithScorer creates a Calculator that computes the meaningful
+// AI percentage with the given scorer.
+func NewCalculatorWithScorer(scorer Scorer) *Calculator {
+	return &Calculator{scorer: scorer}
 }
 
 // effectiveLines returns the lines_changed for an attribution, treating 0 as 1
@@ -125,11 +135,12 @@ func (c *Calculator) ComputeFileMetrics(filePath string, attributions []store.At
 }
 
 // ComputeProjectMetrics computes aggregate metrics for a project from all
-// attributions. The meaningful AI percentage weights each file's contribution
-// by its work type weight and uses line counts for accuracy.
+// attributions. The meaningful AI percentage is computed by the calculator's
+// scorer from per-file line counts.
 func (c *Calculator) ComputeProjectMetrics(projectPath string, allAttributions []store.AttributionWithWorkType) ProjectMetrics {
 	pm := ProjectMetrics{
 		ProjectPath:  projectPath,
+		Scorer:       c.scorer.Name(),
 		ByWorkType:   make(map[string]WorkTypeBreakdown),
 		ByAuthorship: make(map[string]int),
 	}
@@ -146,8 +157,7 @@ func (c *Calculator) ComputeProjectMetrics(projectPath string, allAttributions [
 
 	pm.TotalFiles = len(byFile)
 
-	var totalWeightedAI float64
-	var totalWeightedAll float64
+	var scores []FileScore
 
 	for filePath, fileAttrs := range byFile {
 		fm := c.ComputeFileMetrics(filePath, fileAttrs)
@@ -155,14 +165,13 @@ func (c *Calculator) ComputeProjectMetrics(projectPath string, allAttributions [
 
 		// Look up the weight for this file's work type.
 		wt := worktype.WorkType(fm.WorkType)
-		weight, ok := worktype.WorkTypeWeights[wt]
-		if !ok {
-			weight = worktype.WorkTypeWeights[worktype.CoreLogic]
-		}
+		weight := c.scorer.Weight(fm.WorkType)
 
-		// Weighted contribution using line counts.
-		totalWeightedAI += float64(fm.AILines) * weight
-		totalWeightedAll += float64(fm.TotalLines) * weight
+		scores = append(scores, FileScore{
+			WorkType:   fm.WorkType,
+			AILines:    fm.AILines,
+			TotalLines: fm.TotalLines,
+		})
 
 		// Raw (unweighted) totals using line counts.
 		pm.AILines += fm.AILines
@@ -192,9 +201,7 @@ func (c *Calculator) ComputeProjectMetrics(projectPath string, allAttributions [
 	}
 
 	// Compute project-level percentages.
-	if totalWeightedAll > 0 {
-		pm.MeaningfulAIPct = totalWeightedAI / totalWeightedAll * 100.0
-	}
+	pm.MeaningfulAIPct = c.scorer.Score(scores)
 	if pm.TotalLines > 0 {
 		pm.RawAIPct = float64(pm.AILines) / float64(pm.TotalLines) * 100.0
 	}
@@ -0,0 +1,141 @@
+package metrics
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// commentSyntax is how a language marks comments.
+type commentSyntax struct {
+	line       []string // line comment prefixes
+	blockStart string   // "" if the language has no block comments
+	blockEnd   string
+}
+
+// languageComments lists the comment syntax of each language by its file
+// extensions, or the names of files without one.
+var languageComments = []struct {
+	names  []string
+	syntax commentSyntax
+}{
+	{[]string{".go", ".c", ".h", ".cc", ".cpp", ".cxx", ".hpp", ".java", ".js", ".jsx", ".mjs", ".cjs",
+		".ts", ".tsx", ".cs", ".rs", ".swift", ".kt", ".kts", ".scala", ".dart", ".scss", ".less", ".proto"},
+		commentSyntax{line: []string{"//"}, blockStart: "/*", blockEnd: "*/"}},
+	{[]string{".php"}, commentSyntax{line: []string{"//", "#"}, blockStart: "/*", blockEnd: "*/"}},
+	{[]string{".tf"}, commentSyntax{line: []string{"#", "//"}, blockStart: "/*", blockEnd: "*/"}},
+	{[]string{".css"}, commentSyntax{blockStart: "/*", blockEnd: "*/"}},
+	{[]string{".py"}, commentSyntax{line: []string{"#"}, blockStart: `"""`, blockEnd: `"""`}},
+	{[]string{".rb"}, commentSyntax{line: []string{"#"}, blockStart: "=begin", blockEnd: "=end"}},
+	{[]string{".sh", ".bash", ".zsh", ".pl", ".r", ".yaml", ".yml", ".toml", "Dockerfile", "Makefile"},
+		commentSyntax{line: []string{"#"}}},
+	{[]string{".sql"}, commentSyntax{line: []string{"--"}, blockStart: "/*", blockEnd: "*/"}},
+	{[]string{".lua"}, commentSyntax{line: []string{"--"}, blockStart: "--[[", blockEnd: "]]"}},
+	{[]string{".hs"}, commentSyntax{line: []string{"--"}, blockStart: "{-", blockEnd: "-}"}},
+	{[]string{".html", ".htm", ".xml", ".vue"}, commentSyntax{blockStart: "<!--", blockEnd: "-->"}},
+}
+
+// CommentLines reports for each line of content (index 0 is line 1)
+// whether it holds only a comment, judged by the comment syntax of
+// filePath's language. Blank lines are not comments. Files in languages it
+// does not know have no comment lines. Python docstrings count as
+// comments; a block comment opened after code on the same line does not
+// make the lines below it comments.
+func CommentLines(filePath, content string) []bool {
+	lines := splitLines(content)
+	comments := make([]bool, len(lines))
+	syntax, ok := commentSyntaxFor(filePath)
+	if !ok {
+		return comments
+	}
+	inBlock := false
+	for i, line := range lines {
+		comments[i] = syntax.isComment(line, &inBlock)
+	}
+	return comments
+}
+
+// DropCommentLines removes from lines those CommentLines marks as comments
+// in content, where numbers holds each line's 1-based number in content.
+// Lines outside content are kept.
+func DropCommentLines(filePath, content string, numbers []int, lines []string) ([]int, []string) {
+	comments := CommentLines(filePath, content)
+	var keptNumbers []int
+	var kept []string
+	for i, line := range lines {
+		if n := numbers[i]; n >= 1 && n <= len(comments) && comments[n-1] {
+			continue
+		}
+		keptNumbers = append(keptNumbers, numbers[i])
+		kept = append(kept, line)
+	}
+	return keptNumbers, kept
+}
+
+// StripCommentLines returns text without the lines CommentLines marks as
+// comments in it, e.g. for deleted lines that no longer sit in a file.
+func StripCommentLines(filePath, text string) string {
+	if _, ok := commentSyntaxFor(filePath); !ok || text == "" {
+		return text
+	}
+	comments := CommentLines(filePath, text)
+	var kept []string
+	for i, line := range splitLines(text) {
+		if !comments[i] {
+			kept = append(kept, line)
+		}
+	}
+	if len(kept) == 0 {
+		return ""
+	}
+	return strings.Join(kept, "\n") + "\n"
+}
+
+// commentSyntaxFor returns the comment syntax of filePath's language.
+func commentSyntaxFor(filePath string) (commentSyntax, bool) {
+	base := filepath.Base(filePath)
+	ext := strings.ToLower(filepath.Ext(base))
+	for _, lang := range languageComments {
+		for _, name := range lang.names {
+			if name == base || name == ext {
+				return lang.syntax, true
+			}
+		}
+	}
+	return commentSyntax{}, false
+}
+
+// isComment reports whether line holds only comments. inBlock carries
+// whether a block comment is open from line to line.
+func (c commentSyntax) isComment(line string, inBlock *bool) bool {
+	s := strings.TrimSpace(line)
+	if s == "" {
+		return false
+	}
+	for {
+		if *inBlock {
+			end := strings.Index(s, c.blockEnd)
+			if end < 0 {
+				return true
+			}
+			*inBlock = false
+			s = strings.TrimSpace(s[end+len(c.blockEnd):])
+			if s == "" {
+				return true
+			}
+			continue
+		}
+		// Block openers first: Lua's "--[[" starts with its line
+		// comment prefix.
+		if c.blockStart != "" && strings.HasPrefix(s, c.blockStart) {
+			*inBlock = true
+			s = s[len(c.blockStart):]
+			continue
+		}
+		for _, prefix := range c.line {
+			if strings.HasPrefix(s, prefix) {
+				return true
+			}
+		}
+		return false
+	}
+}
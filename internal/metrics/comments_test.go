@@ -0,0 +1,53 @@
+package metrics
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCommentLines(t *testing.T) {
+	tests := []struct {
+		name    string
+		path    string
+		content string
+		want    []bool
+	}{
+		{"go", "main.go", "// Package main.\npackage main\n\n/*\n block\n*/ var x = 1\nvar y = 2 // trailing\n/* one line */\n",
+			[]bool{true, false, false, true, true, false, false, true}},
+		{"python", "app.py", "#!/usr/bin/env python\ndef f():\n    \"\"\"Docstring\n    more.\"\"\"\n    return 1  # trailing\n",
+			[]bool{true, false, true, true, false}},
+		{"lua", "init.lua", "--[[\nblock\n]]\n-- line\nlocal x = 1\n",
+			[]bool{true, true, true, true, false}},
+		{"sql", "q.SQL", "-- pick\nSELECT 1;\n", []bool{true, false}},
+		{"html", "index.html", "<!-- nav -->\n<nav></nav>\n", []bool{true, false}},
+		{"dockerfile", "build/Dockerfile", "# base\nFROM alpine\n", []bool{true, false}},
+		{"unknown", "notes.txt", "# heading\n// text\n", []bool{false, false}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CommentLines(tt.path, tt.content); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CommentLines = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDropCommentLines(t *testing.T) {
+	content := "package main\n\n// Add adds.\nfunc Add(a, b int) int {\n\t/* sum */\n\treturn a + b\n}\n"
+	numbers, lines := DropCommentLines("add.go", content,
+		[]int{3, 4, 5, 6, 7, 9},
+		[]string{"// Add adds.", "func Add(a, b int) int {", "\t/* sum */", "\treturn a + b", "}", "// past the end"})
+	if want := []int{4, 6, 7, 9}; !reflect.DeepEqual(numbers, want) {
+		t.Errorf("numbers = %v, want %v", numbers, want)
+	}
+	if len(lines) != 4 || lines[0] != "func Add(a, b int) int {" {
+		t.Errorf("lines = %q", lines)
+	}
+
+	if got := StripCommentLines("add.go", "// gone\nx := 1\n"); got != "x := 1\n" {
+		t.Errorf("StripCommentLines = %q", got)
+	}
+	if got := StripCommentLines("add.go", "// only\n"); got != "" {
+		t.Errorf("StripCommentLines of comments only = %q", got)
+	}
+}
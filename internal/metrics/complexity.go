@@ -0,0 +1,196 @@
+package metrics
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"regexp"
+	"strings"
+)
+
+// ComplexityAttribution is line attribution with each line weighted by the
+// complexity of the code around it, so a branchy function Claude wrote
+// counts for more than the same number of lines of declarations.
+type ComplexityAttribution struct {
+	Total float64 // summed weight of all non-blank lines
+	AI    float64 // summed weight of AI lines
+}
+
+// AIPct returns the complexity-weighted AI percentage.
+func (c ComplexityAttribution) AIPct() float64 {
+	if c.Total == 0 {
+		return 0
+	}
+	return c.AI / c.Total * 100.0
+}
+
+// CountLines tallies lines as classified by ClassifyLines. Empty or
+// whitespace-only lines are not counted.
+func CountLines(lines []string, ai []bool) LineAttribution {
+	var result LineAttribution
+	for i, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		result.TotalLines++
+		if ai[i] {
+			result.AILines++
+		}
+	}
+	result.HumanLines = result.TotalLines - result.AILines
+	return result
+}
+
+// WeighLines sums the complexity weights of lines, where numbers holds each
+// line's 1-based number in the file weights was computed for. Lines are
+// classified by ai as from ClassifyLines; blank lines weigh nothing, and a
+// line outside weights weighs 1.
+func WeighLines(numbers []int, lines []string, ai []bool, weights []float64) ComplexityAttribution {
+	var result ComplexityAttribution
+	for i, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		w := 1.0
+		if n := numbers[i]; n >= 1 && n <= len(weights) {
+			w = weights[n-1]
+		}
+		result.Total += w
+		if ai[i] {
+			result.AI += w
+		}
+	}
+	return result
+}
+
+// LineComplexity returns a weight of at least 1 for each line of a file's
+// content (index 0 is line 1).
+//
+// Go files are parsed: a line in a function weighs the function's
+// cyclomatic complexity (1 plus its if, for, case, && and || decision
+// points; a closure counts on its own), and lines outside functions weigh 1.
+// Other files, and Go that does not parse, use a heuristic: 1 plus the
+// line's nesting depth, judged by indentation, plus the decision keywords
+// on the line.
+func LineComplexity(filePath, content string) []float64 {
+	if strings.HasSuffix(filePath, ".go") {
+		if weights, ok := goLineComplexity(content); ok {
+			return weights
+		}
+	}
+	return heuristicLineComplexity(content)
+}
+
+// goLineComplexity weights lines by the cyclomatic complexity of their
+// enclosing function. It reports false if content does not parse.
+func goLineComplexity(content string) ([]float64, bool) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "", content, parser.SkipObjectResolution)
+	if err != nil {
+		return nil, false
+	}
+
+	weights := make([]float64, len(splitLines(content)))
+	for i := range weights {
+		weights[i] = 1
+	}
+	// ast.Inspect visits outer functions first, so a closure's lines are
+	// overwritten with its own complexity.
+	ast.Inspect(f, func(n ast.Node) bool {
+		var body *ast.BlockStmt
+		switch fn := n.(type) {
+		case *ast.FuncDecl:
+			body = fn.Body
+		case *ast.FuncLit:
+			body = fn.Body
+		}
+		if body == nil {
+			return true
+		}
+		complexity := float64(cyclomatic(body))
+		start, end := fset.Position(n.Pos()).Line, fset.Position(n.End()).Line
+		for line := start; line <= end && line <= len(weights); line++ {
+			weights[line-1] = complexity
+		}
+		return true
+	})
+	return weights, true
+}
+
+// cyclomatic returns 1 plus the decision points in body, not counting those
+// in nested function literals.
+func cyclomatic(body *ast.BlockStmt) int {
+	complexity := 1
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.IfStmt, *ast.ForStmt, *ast.RangeStmt:
+			complexity++
+		case *ast.CaseClause:
+			if n.List != nil { // default is not a decision
+				complexity++
+			}
+		case *ast.CommClause:
+			if n.Comm != nil {
+				complexity++
+			}
+		case *ast.BinaryExpr:
+			if n.Op == token.LAND || n.Op == token.LOR {
+				complexity++
+			}
+		}
+		return true
+	})
+	return complexity
+}
+
+// decisionPattern matches the branch keywords and operators the heuristic
+// counts, across C-like languages, Python and Ruby.
+var decisionPattern = regexp.MustCompile(`\b(if|elif|else if|for|foreach|while|case|catch|except|when|unless|until)\b|&&|\|\||\?\s`)
+
+// heuristicLineComplexity weights each line by its indentation depth and
+// decision keywords. The indent unit is the smallest indentation in the
+// file, so two- and four-space styles agree.
+func heuristicLineComplexity(content string) []float64 {
+	lines := splitLines(content)
+	unit := 0
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if w := indentWidth(line); w > 0 && (unit == 0 || w < unit) {
+			unit = w
+		}
+	}
+
+	weights := make([]float64, len(lines))
+	for i, line := range lines {
+		weights[i] = 1
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if unit > 0 {
+			weights[i] += float64(indentWidth(line) / unit)
+		}
+		weights[i] += float64(len(decisionPattern.FindAllString(line, -1)))
+	}
+	return weights
+}
+
+// indentWidth returns the width of a line's leading whitespace, counting a
+// tab as four columns.
+func indentWidth(line string) int {
+	width := 0
+	for _, r := range line {
+		switch r {
+		case ' ':
+			width++
+		case '\t':
+			width += 4
+		default:
+			return width
+		}
+	}
+	return width
+}
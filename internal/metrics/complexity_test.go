@@ -0,0 +1,82 @@
+package metrics
+
+import "testing"
+
+const complexGo = `package p
+
+type T struct{}
+
+func Simple() int {
+	return 1
+}
+
+func Branchy(a, b int) int {
+	if a > 0 && b > 0 {
+		return 1
+	}
+	for i := 0; i < a; i++ {
+		switch i {
+		case 1:
+			return i
+		default:
+		}
+	}
+	f := func() {
+		if b > 1 {
+		}
+	}
+	f()
+	return 0
+}
+`
+
+func TestLineComplexity_Go(t *testing.T) {
+	weights := LineComplexity("p.go", complexGo)
+	cases := map[int]float64{
+		3:  1, // type declaration
+		6:  1, // Simple
+		10: 5, // Branchy: if, &&, for, case
+		21: 2, // closure: if
+		25: 5, // back in Branchy after the closure
+	}
+	for line, want := range cases {
+		if got := weights[line-1]; got != want {
+			t.Errorf("line %d weight = %v, want %v", line, got, want)
+		}
+	}
+}
+
+func TestLineComplexity_Heuristic(t *testing.T) {
+	content := "def f(x):\n  if x and y:\n    return 1\n  return 0\n"
+	weights := LineComplexity("f.py", content)
+	want := []float64{1, 3, 3, 2} // indent depth + "if"
+	for i := range want {
+		if weights[i] != want[i] {
+			t.Errorf("weights = %v, want %v", weights, want)
+			break
+		}
+	}
+
+	// Go that does not parse falls back to the heuristic.
+	if w := LineComplexity("bad.go", "func {\n\tif x {\n"); len(w) != 2 || w[1] != 3 {
+		t.Errorf("unparsable Go weights = %v", w)
+	}
+}
+
+func TestWeighLines(t *testing.T) {
+	lines := []string{"a", "", "b", "c"}
+	ai := []bool{true, false, false, true}
+	got := WeighLines([]int{1, 2, 3, 9}, lines, ai, []float64{4, 1, 1})
+	// "c" is line 9, outside weights, so it weighs 1.
+	if got.Total != 6 || got.AI != 5 {
+		t.Errorf("WeighLines = %+v, want total 6, AI 5", got)
+	}
+	if pct := got.AIPct(); pct < 83.3 || pct > 83.4 {
+		t.Errorf("AIPct = %v, want 83.3", pct)
+	}
+
+	la := CountLines(lines, ai)
+	if la.TotalLines != 3 || la.AILines != 2 || la.HumanLines != 1 {
+		t.Errorf("CountLines = %+v", la)
+	}
+}
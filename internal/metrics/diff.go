@@ -0,0 +1,382 @@
+package metrics
+
+import "fmt"
+
+// Line diff algorithms accepted by Diff, named as git names them so the
+// same setting applies to the diffs reports read from git.
+const (
+	DiffMyers     = "myers"     // default: a shortest edit script
+	DiffPatience  = "patience"  // anchored on lines unique to both sides
+	DiffHistogram = "histogram" // anchored on the rarest lines, patience for repeated ones
+)
+
+// maxHistogramChain bounds how often a line may occur in the old side for
+// histogram diff to anchor on it; regions with only more common lines fall
+// back to Myers, as in git.
+const maxHistogramChain = 64
+
+// maxHistogramDepth bounds the recursion of histogram diff; deeper regions
+// fall back to Myers.
+const maxHistogramDepth = 64
+
+// Hunk is a run of changed lines: OldLines lines of the old side from
+// OldStart are replaced by NewLines lines of the new side from NewStart.
+// Starts are 0-based line indexes.
+type Hunk struct {
+	OldStart, OldLines int
+	NewStart, NewLines int
+}
+
+// ValidDiffAlgorithm returns an error unless name is a diff algorithm Diff
+// accepts. An empty name is DiffMyers.
+func ValidDiffAlgorithm(name string) error {
+	switch name {
+	case "", DiffMyers, DiffPatience, DiffHistogram:
+		return nil
+	}
+	return fmt.Errorf("unknown diff algorithm %q (want myers, patience or histogram)", name)
+}
+
+// Diff returns the hunks that turn the lines of old into those of new, in
+// order, using algorithm; unknown or empty names use DiffMyers. Unlike a
+// positional comparison, a line inserted near the top of a file is one
+// changed line, not a change of every line below it.
+func Diff(old, new []string, algorithm string) []Hunk {
+	d := newDiffer(old, new)
+	switch algorithm {
+	case DiffPatience:
+		d.patience(0, len(d.a), 0, len(d.b))
+	case DiffHistogram:
+		d.histogram(0, len(d.a), 0, len(d.b), 0)
+	default:
+		d.myers(0, len(d.a), 0, len(d.b))
+	}
+	return d.hunks()
+}
+
+// ChangedLines returns the lines hunks change: for each, the larger of the
+// lines it removes and the lines it adds, so a rewritten line counts once.
+func ChangedLines(hunks []Hunk) int {
+	n := 0
+	for _, h := range hunks {
+		if h.OldLines > h.NewLines {
+			n += h.OldLines
+		} else {
+			n += h.NewLines
+		}
+	}
+	return n
+}
+
+// AddedLines returns the lines of new that hunks add, in order.
+func AddedLines(new []string, hunks []Hunk) []string {
+	var lines []string
+	for _, h := range hunks {
+		lines = append(lines, new[h.NewStart:h.NewStart+h.NewLines]...)
+	}
+	return lines
+}
+
+// differ holds the two sides of a diff as line ids, equal lines sharing
+// an id, and marks the lines each algorithm matches.
+type differ struct {
+	a, b   []int
+	ma, mb []bool // whether each line of a and b is matched
+}
+
+func newDiffer(old, new []string) *differ {
+	ids := make(map[string]int)
+	intern := func(lines []string) []int {
+		out := make([]int, len(lines))
+		for i, line := range lines {
+			id, ok := ids[line]
+			if !ok {
+				id = len(ids)
+				ids[line] = id
+			}
+			out[i] = id
+		}
+		return out
+	}
+	a := intern(old)
+	b := intern(new)
+	return &differ{a: a, b: b, ma: make([]bool, len(a)), mb: make([]bool, len(b))}
+}
+
+// match marks a[i] and b[j] as the same line.
+func (d *differ) match(i, j int) {
+	d.ma[i] = true
+	d.mb[j] = true
+}
+
+// trim matches the common prefix and suffix of a[aLo:aHi] and b[bLo:bHi]
+// and returns what is left between them.
+func (d *differ) trim(aLo, aHi, bLo, bHi int) (int, int, int, int) {
+	for aLo < aHi && bLo < bHi && d.a[aLo] == d.b[bLo] {
+		d.match(aLo, bLo)
+		aLo++
+		bLo++
+	}
+	for aLo < aHi && bLo < bHi && d.a[aHi-1] == d.b[bHi-1] {
+		d.match(aHi-1, bHi-1)
+		aHi--
+		bHi--
+	}
+	return aLo, aHi, bLo, bHi
+}
+
+// myers matches a[aLo:aHi] and b[bLo:bHi] along a shortest edit script,
+// splitting the ranges at the middle snake to stay in linear space.
+func (d *differ) myers(aLo, aHi, bLo, bHi int) {
+	aLo, aHi, bLo, bHi = d.trim(aLo, aHi, bLo, bHi)
+	if aLo == aHi || bLo == bHi {
+		return
+	}
+	x, y, ok := d.bisect(aLo, aHi, bLo, bHi)
+	if !ok {
+		return // nothing in common
+	}
+	d.myers(aLo, x, bLo, y)
+	d.myers(x, aHi, y, bHi)
+}
+
+// bisect finds a point on a shortest edit script of a[aLo:aHi] and
+// b[bLo:bHi] by searching from both ends until the paths overlap. Both
+// ranges must be non-empty and differ in their first and last lines.
+func (d *differ) bisect(aLo, aHi, bLo, bHi int) (int, int, bool) {
+	a, b := d.a[aLo:aHi], d.b[bLo:bHi]
+	n, m := len(a), len(b)
+	maxD := (n + m + 1) / 2
+	off := maxD
+	v1 := make([]int, 2*maxD+2)
+	v2 := make([]int, 2*maxD+2)
+	for i := range v1 {
+		v1[i], v2[i] = -1, -1
+	}
+	v1[off+1], v2[off+1] = 0, 0
+	delta := n - m
+	front := delta%2 != 0 // the forward path is the one to detect overlap
+	var k1start, k1end, k2start, k2end int
+	for step := 0; step < maxD; step++ {
+		for k1 := -step + k1start; k1 <= step-k1end; k1 += 2 {
+			var x1 int
+			if k1 == -step || (k1 != step && v1[off+k1-1] < v1[off+k1+1]) {
+				x1 = v1[off+k1+1]
+			} else {
+				x1 = v1[off+k1-1] + 1
+			}
+			y1 := x1 - k1
+			for x1 < n && y1 < m && a[x1] == b[y1] {
+				x1++
+				y1++
+			}
+			v1[off+k1] = x1
+			switch {
+			case x1 > n:
+				k1end += 2
+			case y1 > m:
+				k1start += 2
+			case front:
+				if k2 := off + delta - k1; k2 >= 0 && k2 < len(v2) && v2[k2] != -1 && x1 >= n-v2[k2] {
+					return aLo + x1, bLo + y1, true
+				}
+			}
+		}
+		for k2 := -step + k2start; k2 <= step-k2end; k2 += 2 {
+			var x2 int
+			if k2 == -step || (k2 != step && v2[off+k2-1] < v2[off+k2+1]) {
+				x2 = v2[off+k2+1]
+			} else {
+				x2 = v2[off+k2-1] + 1
+			}
+			y2 := x2 - k2
+			for x2 < n && y2 < m && a[n-x2-1] == b[m-y2-1] {
+				x2++
+				y2++
+			}
+			v2[off+k2] = x2
+			switch {
+			case x2 > n:
+				k2end += 2
+			case y2 > m:
+				k2start += 2
+			case !front:
+				if k1 := off + delta - k2; k1 >= 0 && k1 < len(v1) && v1[k1] != -1 {
+					x1 := v1[k1]
+					y1 := off + x1 - k1
+					if x1 >= n-x2 {
+						return aLo + x1, bLo + y1, true
+					}
+				}
+			}
+		}
+	}
+	return 0, 0, false
+}
+
+// patience matches a[aLo:aHi] and b[bLo:bHi] on the longest increasing
+// run of lines that occur exactly once on each side, then diffs between
+// them. Ranges without such lines fall back to Myers.
+func (d *differ) patience(aLo, aHi, bLo, bHi int) {
+	aLo, aHi, bLo, bHi = d.trim(aLo, aHi, bLo, bHi)
+	if aLo == aHi || bLo == bHi {
+		return
+	}
+
+	type count struct{ a, b, bPos int }
+	counts := make(map[int]*count)
+	for i := aLo; i < aHi; i++ {
+		c := counts[d.a[i]]
+		if c == nil {
+			c = &count{}
+			counts[d.a[i]] = c
+		}
+		c.a++
+	}
+	for j := bLo; j < bHi; j++ {
+		if c := counts[d.b[j]]; c != nil {
+			c.b++
+			c.bPos = j
+		}
+	}
+	var unique [][2]int // positions in a and b, in order of a
+	for i := aLo; i < aHi; i++ {
+		if c := counts[d.a[i]]; c.a == 1 && c.b == 1 {
+			unique = append(unique, [2]int{i, c.bPos})
+		}
+	}
+	if len(unique) == 0 {
+		d.myers(aLo, aHi, bLo, bHi)
+		return
+	}
+
+	prevA, prevB := aLo, bLo
+	for _, anchor := range longestIncreasing(unique) {
+		d.match(anchor[0], anchor[1])
+		d.patience(prevA, anchor[0], prevB, anchor[1])
+		prevA, prevB = anchor[0]+1, anchor[1]+1
+	}
+	d.patience(prevA, aHi, prevB, bHi)
+}
+
+// longestIncreasing returns the longest run of pairs, ordered by their
+// first element, whose second elements increase, by patience sorting.
+func longestIncreasing(pairs [][2]int) [][2]int {
+	var tops []int // index in pairs of the top of each pile
+	prev := make([]int, len(pairs))
+	for i, p := range pairs {
+		lo, hi := 0, len(tops)
+		for lo < hi {
+			mid := (lo + hi) / 2
+			if pairs[tops[mid]][1] < p[1] {
+				lo = mid + 1
+			} else {
+				hi = mid
+			}
+		}
+		prev[i] = -1
+		if lo > 0 {
+			prev[i] = tops[lo-1]
+		}
+		if lo == len(tops) {
+			tops = append(tops, i)
+		} else {
+			tops[lo] = i
+		}
+	}
+	run := make([][2]int, len(tops))
+	for i, k := len(tops)-1, tops[len(tops)-1]; i >= 0; i, k = i-1, prev[k] {
+		run[i] = pairs[k]
+	}
+	return run
+}
+
+// histogram matches a[aLo:aHi] and b[bLo:bHi] on the longest common run
+// of lines around the line rarest in a, then diffs either side of it.
+// Ranges whose common lines are all too common fall back to Myers.
+func (d *differ) histogram(aLo, aHi, bLo, bHi, depth int) {
+	aLo, aHi, bLo, bHi = d.trim(aLo, aHi, bLo, bHi)
+	if aLo == aHi || bLo == bHi {
+		return
+	}
+	if depth > maxHistogramDepth {
+		d.myers(aLo, aHi, bLo, bHi)
+		return
+	}
+
+	occurs := make(map[int][]int) // positions in a by line
+	for i := aLo; i < aHi; i++ {
+		occurs[d.a[i]] = append(occurs[d.a[i]], i)
+	}
+	bestCount := maxHistogramChain + 1
+	var bestA, bestB, bestLen int
+	for j := bLo; j < bHi; {
+		next := j + 1
+		positions := occurs[d.b[j]]
+		if len(positions) == 0 || len(positions) > bestCount {
+			j = next
+			continue
+		}
+		for _, i := range positions {
+			// Grow the common run through a[i] and b[j] both ways,
+			// tracking the rarest line in it.
+			si, sj, ei, ej := i, j, i+1, j+1
+			count := len(positions)
+			for si > aLo && sj > bLo && d.a[si-1] == d.b[sj-1] {
+				si--
+				sj--
+				if c := len(occurs[d.a[si]]); c < count {
+					count = c
+				}
+			}
+			for ei < aHi && ej < bHi && d.a[ei] == d.b[ej] {
+				if c := len(occurs[d.a[ei]]); c < count {
+					count = c
+				}
+				ei++
+				ej++
+			}
+			if count < bestCount || (count == bestCount && ei-si > bestLen) {
+				bestA, bestB, bestLen, bestCount = si, sj, ei-si, count
+			}
+			if ej > next {
+				next = ej
+			}
+		}
+		j = next
+	}
+	if bestLen == 0 {
+		d.myers(aLo, aHi, bLo, bHi)
+		return
+	}
+
+	for k := 0; k < bestLen; k++ {
+		d.match(bestA+k, bestB+k)
+	}
+	d.histogram(aLo, bestA, bLo, bestB, depth+1)
+	d.histogram(bestA+bestLen, aHi, bestB+bestLen, bHi, depth+1)
+}
+
+// hunks returns the runs of unmatched lines between matched ones. Matches
+// never cross, so the k-th matched line of a pairs with the k-th of b.
+func (d *differ) hunks() []Hunk {
+	var hunks []Hunk
+	i, j := 0, 0
+	for i < len(d.a) || j < len(d.b) {
+		if i < len(d.a) && j < len(d.b) && d.ma[i] && d.mb[j] {
+			i++
+			j++
+			continue
+		}
+		h := Hunk{OldStart: i, NewStart: j}
+		for i < len(d.a) && !d.ma[i] {
+			i++
+		}
+		for j < len(d.b) && !d.mb[j] {
+			j++
+		}
+		h.OldLines, h.NewLines = i-h.OldStart, j-h.NewStart
+		hunks = append(hunks, h)
+	}
+	return hunks
+}
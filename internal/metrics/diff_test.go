@@ -0,0 +1,122 @@
+package metrics
+
+import (
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var diffAlgorithms = []string{DiffMyers, DiffPatience, DiffHistogram}
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name     string
+		old, new string
+		want     []Hunk
+	}{
+		{"identical", "a\nb\nc", "a\nb\nc", nil},
+		{"one line changed", "a\nb\nc", "a\nB\nc", []Hunk{{1, 1, 1, 1}}},
+		{"line inserted at top", "a\nb\nc", "x\na\nb\nc", []Hunk{{0, 0, 0, 1}}},
+		{"line removed", "a\nb\nc", "a\nc", []Hunk{{1, 1, 1, 0}}},
+		{"all changed", "a\nb", "x\ny\nz", []Hunk{{0, 2, 0, 3}}},
+		{"empty old", "", "a\nb", []Hunk{{0, 1, 0, 2}}},
+	}
+	for _, tt := range tests {
+		for _, alg := range diffAlgorithms {
+			t.Run(tt.name+"/"+alg, func(t *testing.T) {
+				got := Diff(strings.Split(tt.old, "\n"), strings.Split(tt.new, "\n"), alg)
+				if !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("Diff = %v, want %v", got, tt.want)
+				}
+			})
+		}
+	}
+}
+
+// TestDiffRandom checks on random inputs that every algorithm's hunks turn
+// old into new and that Myers finds a shortest edit script.
+func TestDiffRandom(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	randomLines := func() []string {
+		lines := make([]string, rng.Intn(30))
+		for i := range lines {
+			lines[i] = string(rune('a' + rng.Intn(5)))
+		}
+		return lines
+	}
+	for n := 0; n < 500; n++ {
+		old, new := randomLines(), randomLines()
+		for _, alg := range diffAlgorithms {
+			hunks := Diff(old, new, alg)
+			if got := applyHunks(old, new, hunks); !reflect.DeepEqual(got, new) && len(new) > 0 {
+				t.Fatalf("%s: %q -> %q: hunks %v give %q", alg, old, new, hunks, got)
+			}
+			if alg != DiffMyers {
+				continue
+			}
+			edits := 0
+			for _, h := range hunks {
+				edits += h.OldLines + h.NewLines
+			}
+			if want := len(old) + len(new) - 2*lcsLength(old, new); edits != want {
+				t.Fatalf("myers: %q -> %q: %d edits, want %d", old, new, edits, want)
+			}
+		}
+	}
+}
+
+func TestChangedAndAddedLines(t *testing.T) {
+	new := []string{"x", "a", "B", "C", "c"}
+	hunks := []Hunk{{0, 0, 0, 1}, {1, 1, 2, 2}}
+	if got := ChangedLines(hunks); got != 3 {
+		t.Errorf("ChangedLines = %d, want 3", got)
+	}
+	if got, want := AddedLines(new, hunks), []string{"x", "B", "C"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("AddedLines = %q, want %q", got, want)
+	}
+}
+
+func TestValidDiffAlgorithm(t *testing.T) {
+	for _, alg := range append(diffAlgorithms, "") {
+		if err := ValidDiffAlgorithm(alg); err != nil {
+			t.Errorf("ValidDiffAlgorithm(%q) = %v", alg, err)
+		}
+	}
+	if err := ValidDiffAlgorithm("minimal"); err == nil {
+		t.Error("ValidDiffAlgorithm(minimal) = nil, want error")
+	}
+}
+
+// applyHunks rebuilds new from old, copying the lines between hunks from
+// old and the lines of each hunk from new.
+func applyHunks(old, new []string, hunks []Hunk) []string {
+	var out []string
+	i := 0
+	for _, h := range hunks {
+		out = append(out, old[i:h.OldStart]...)
+		out = append(out, new[h.NewStart:h.NewStart+h.NewLines]...)
+		i = h.OldStart + h.OldLines
+	}
+	return append(out, old[i:]...)
+}
+
+// lcsLength returns the length of the longest common subsequence of a and b.
+func lcsLength(a, b []string) int {
+	prev := make([]int, len(b)+1)
+	for i := range a {
+		cur := make([]int, len(b)+1)
+		for j := range b {
+			switch {
+			case a[i] == b[j]:
+				cur[j+1] = prev[j] + 1
+			case prev[j+1] > cur[j]:
+				cur[j+1] = prev[j+1]
+			default:
+				cur[j+1] = cur[j]
+			}
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,68 @@
+package metrics
+
+import "strings"
+
+// Reasons a FileGuard keeps a file out of attribution.
+const (
+	GuardBinary   = "binary"    // the file is not text
+	GuardTooLarge = "too_large" // the file has more than MaxLines lines
+	GuardCapped   = "capped"    // only the first MaxLines changed lines count
+)
+
+// binarySniffLen is how much of a file is checked for NUL bytes, the same
+// amount git looks at to decide a file is binary.
+const binarySniffLen = 8000
+
+// FileGuard keeps generated and binary files (bundles, lockfiles,
+// fixtures, images) from dominating line counts. The zero value excludes
+// binary files and puts no limit on size.
+type FileGuard struct {
+	MaxLines    int  // files with more lines are guarded; 0 means no limit
+	Cap         bool // attribute the first MaxLines changed lines of a large file instead of excluding it
+	AllowBinary bool // attribute binary files like text
+}
+
+// Check returns why a file with content must be left out of attribution,
+// GuardBinary or GuardTooLarge, or "" if it may be attributed. A large file
+// is not excluded when g.Cap is set; use CapLines on its changed lines.
+func (g FileGuard) Check(content string) string {
+	if !g.AllowBinary && IsBinary(content) {
+		return GuardBinary
+	}
+	if g.MaxLines > 0 && !g.Cap && CountFileLines(content) > g.MaxLines {
+		return GuardTooLarge
+	}
+	return ""
+}
+
+// CapLines trims a large file's changed lines, with their line numbers, to
+// the first g.MaxLines when g.Cap is set. It reports whether any were
+// dropped.
+func (g FileGuard) CapLines(numbers []int, lines []string) ([]int, []string, bool) {
+	if !g.Cap || g.MaxLines <= 0 || len(lines) <= g.MaxLines {
+		return numbers, lines, false
+	}
+	if len(numbers) > g.MaxLines {
+		numbers = numbers[:g.MaxLines]
+	}
+	return numbers, lines[:g.MaxLines], true
+}
+
+// IsBinary reports whether content looks binary: it has a NUL byte near
+// the start.
+func IsBinary(content string) bool {
+	if len(content) > binarySniffLen {
+		content = content[:binarySniffLen]
+	}
+	return strings.IndexByte(content, 0) >= 0
+}
+
+// CountFileLines returns the number of lines in content, counting a final
+// line without a newline.
+func CountFileLines(content string) int {
+	n := strings.Count(content, "\n")
+	if content != "" && !strings.HasSuffix(content, "\n") {
+		n++
+	}
+	return n
+}
@@ -0,0 +1,47 @@
+package metrics
+
+import "testing"
+
+func TestFileGuard_Check(t *testing.T) {
+	large := "a\nb\nc\nd"
+	binary := "GIF89a\x00\x01"
+	cases := []struct {
+		name    string
+		guard   FileGuard
+		content string
+		want    string
+	}{
+		{"text", FileGuard{MaxLines: 4}, large, ""},
+		{"too large", FileGuard{MaxLines: 3}, large, GuardTooLarge},
+		{"capped", FileGuard{MaxLines: 3, Cap: true}, large, ""},
+		{"no limit", FileGuard{}, large, ""},
+		{"binary", FileGuard{}, binary, GuardBinary},
+		{"binary allowed", FileGuard{AllowBinary: true}, binary, ""},
+	}
+	for _, c := range cases {
+		if got := c.guard.Check(c.content); got != c.want {
+			t.Errorf("%s: Check = %q, want %q", c.name, got, c.want)
+		}
+	}
+}
+
+func TestFileGuard_CapLines(t *testing.T) {
+	numbers := []int{1, 2, 5, 6}
+	lines := []string{"a", "b", "c", "d"}
+
+	n, l, capped := FileGuard{MaxLines: 2, Cap: true}.CapLines(numbers, lines)
+	if !capped || len(n) != 2 || len(l) != 2 || l[1] != "b" {
+		t.Errorf("CapLines = %v, %v, %v; want first two lines capped", n, l, capped)
+	}
+	if _, l, capped := (FileGuard{MaxLines: 2}).CapLines(numbers, lines); capped || len(l) != 4 {
+		t.Errorf("CapLines without Cap = %v, %v; want lines unchanged", l, capped)
+	}
+}
+
+func TestCountFileLines(t *testing.T) {
+	for content, want := range map[string]int{"": 0, "a": 1, "a\n": 1, "a\nb": 2, "\n\n": 2} {
+		if got := CountFileLines(content); got != want {
+			t.Errorf("CountFileLines(%q) = %d, want %d", content, got, want)
+		}
+	}
+}
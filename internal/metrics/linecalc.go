@@ -3,14 +3,16 @@ package metrics
 import (
 	"crypto/sha256"
 	"encoding/hex"
+	"sort"
 	"strings"
 )
 
 // LineAttribution holds the result of line-level attribution for a file.
 type LineAttribution struct {
-	TotalLines int
-	AILines    int
-	HumanLines int
+	TotalLines   int
+	AILines      int
+	HumanLines   int
+	RevisedLines int // AI lines kept AI though changed since Claude wrote them
 }
 
 // ComputeLineAttribution compares the current file content against all content
@@ -26,25 +28,57 @@ type LineAttribution struct {
 // pre-existing patterns (like common annotations or boilerplate) are not
 // falsely attributed to AI.
 func ComputeLineAttribution(currentContent string, claudeContents []string, baseContent string) LineAttribution {
+	return ComputeLineAttributionWithOptions(currentContent, claudeContents, baseContent, MatchOptions{})
+}
+
+// ComputeLineAttributionWithOptions is ComputeLineAttribution with the line
+// comparison controlled by opts. In normalized mode, lines are compared after
+// normalizeLine, so a formatter run after an AI write does not flip lines to
+// human. With a similarity threshold, lines left unmatched are then paired
+// with the closest unconsumed AI line by token overlap.
+func ComputeLineAttributionWithOptions(currentContent string, claudeContents []string, baseContent string, opts MatchOptions) LineAttribution {
 	if currentContent == "" {
 		return LineAttribution{}
 	}
 
 	// Skip empty/whitespace-only lines — they carry no authorship signal.
 	currentLines := splitNonEmpty(currentContent)
-	result := LineAttribution{TotalLines: len(currentLines)}
+	ai, revised := ClassifyLinesRevised(currentLines, claudeContents, baseContent, opts)
+	la := CountLines(currentLines, ai)
+	la.RevisedLines = CountRevised(revised)
+	return la
+}
 
+// ClassifyLines reports for each of lines whether it is AI-authored, by the
+// same matching as ComputeLineAttributionWithOptions, so callers that know
+// where each line sits in the file can attribute individual lines. Empty or
+// whitespace-only lines are never AI and consume no Claude line.
+func ClassifyLines(lines []string, claudeContents []string, baseContent string, opts MatchOptions) []bool {
+	ai, _ := ClassifyLinesRevised(lines, claudeContents, baseContent, opts)
+	return ai
+}
+
+// ClassifyLinesRevised is ClassifyLines that also reports which AI lines
+// match no Claude line as is and were attributed by the revision or
+// similarity pass: lines Claude wrote that have since been revised.
+func ClassifyLinesRevised(lines []string, claudeContents []string, baseContent string, opts MatchOptions) (ai, revised []bool) {
+	ai = make([]bool, len(lines))
+	revised = make([]bool, len(lines))
 	if len(claudeContents) == 0 {
-		result.HumanLines = result.TotalLines
-		return result
+		return ai, revised
 	}
 
-	// Build a frequency map of line hashes from Claude's output.
+	// Build a frequency map of line hashes from Claude's output, keeping
+	// one example line per hash for the similarity pass.
 	claudeHashes := make(map[string]int)
+	claudeLines := make(map[string]string)
 	for _, content := range claudeContents {
 		for _, line := range splitNonEmpty(content) {
-			h := hashLine(line)
+			h := opts.key(line)
 			claudeHashes[h]++
+			if _, ok := claudeLines[h]; !ok {
+				claudeLines[h] = line
+			}
 		}
 	}
 
@@ -52,25 +86,93 @@ func ComputeLineAttribution(currentContent string, claudeContents []string, base
 	// existed before Claude was involved, it shouldn't count as AI.
 	if baseContent != "" {
 		for _, line := range splitNonEmpty(baseContent) {
-			h := hashLine(line)
+			h := opts.key(line)
 			if claudeHashes[h] > 0 {
 				claudeHashes[h]--
 			}
 		}
 	}
 
-	// For each line in the current file, check if Claude wrote it.
-	for _, line := range currentLines {
-		h := hashLine(line)
+	// For each line, check if Claude wrote it.
+	var unmatched []int
+	for i, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		h := opts.key(line)
 		if claudeHashes[h] > 0 {
-			result.AILines++
+			ai[i] = true
 			claudeHashes[h]-- // consume one occurrence
 		} else {
-			result.HumanLines++
+			unmatched = append(unmatched, i)
 		}
 	}
 
-	return result
+	if opts.Revision > 0 && len(unmatched) > 0 {
+		matchFuzzy(lines, unmatched, ai, revised, claudeHashes, claudeLines, opts.Revision, tokenize,
+			func(a, b []string) float64 { return editSimilarity(a, b, opts.Revision) })
+	}
+	if opts.Similarity > 0 && len(unmatched) > 0 {
+		matchFuzzy(lines, unmatched, ai, revised, claudeHashes, claudeLines, opts.Similarity, tokenBag, similarity)
+	}
+
+	return ai, revised
+}
+
+// CountRevised returns how many lines revised marks.
+func CountRevised(revised []bool) int {
+	n := 0
+	for _, r := range revised {
+		if r {
+			n++
+		}
+	}
+	return n
+}
+
+// matchFuzzy pairs each unmatched line (an index into lines) that is not
+// yet AI with the unconsumed Claude line it scores highest against, if
+// that score reaches threshold, and marks paired lines in ai and revised.
+// prepare turns a line into what score compares. Each Claude occurrence
+// pairs once.
+func matchFuzzy[T any](lines []string, unmatched []int, ai, revised []bool, claudeHashes map[string]int, claudeLines map[string]string, threshold float64, prepare func(string) T, score func(a, b T) float64) {
+	type candidate struct {
+		hash string
+		val  T
+	}
+	var pool []candidate
+	for h, n := range claudeHashes {
+		if n > 0 {
+			pool = append(pool, candidate{hash: h, val: prepare(claudeLines[h])})
+		}
+	}
+	if len(pool) == 0 || len(pool)*len(unmatched) > maxSimilarityComparisons {
+		return
+	}
+	// Map iteration order is random; sort so ties resolve the same way
+	// on every run.
+	sort.Slice(pool, func(i, j int) bool { return pool[i].hash < pool[j].hash })
+
+	for _, i := range unmatched {
+		if ai[i] {
+			continue
+		}
+		val := prepare(lines[i])
+		best, bestScore := -1, 0.0
+		for j, c := range pool {
+			if claudeHashes[c.hash] == 0 {
+				continue
+			}
+			if sc := score(val, c.val); sc >= threshold && sc > bestScore {
+				best, bestScore = j, sc
+			}
+		}
+		if best >= 0 {
+			claudeHashes[pool[best].hash]--
+			ai[i] = true
+			revised[i] = true
+		}
+	}
 }
 
 // splitNonEmpty splits content into lines, excluding empty/whitespace-only
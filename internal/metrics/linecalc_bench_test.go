@@ -0,0 +1,41 @@
+package metrics
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// benchLines are the synthetic file sizes line attribution is measured
+// at; the largest is skipped with -short.
+var benchLines = []int{10_000, 100_000, 1_000_000}
+
+// BenchmarkComputeLineAttribution measures attributing a file's changed
+// lines when Claude wrote every third of them, with exact and normalized line
+// matching.
+func BenchmarkComputeLineAttribution(b *testing.B) {
+	for _, n := range benchLines {
+		var current, claude strings.Builder
+		for i := 0; i < n; i++ {
+			line := fmt.Sprintf("\tresult%d := process(items[%d], opts)\n", i, i%97)
+			current.WriteString(line)
+			if i%3 == 0 {
+				claude.WriteString(line)
+			}
+		}
+		for _, mode := range []string{MatchExact, MatchNormalized} {
+			b.Run(fmt.Sprintf("lines=%d/%s", n, mode), func(b *testing.B) {
+				if testing.Short() && n > 100_000 {
+					b.Skip("large dataset skipped with -short")
+				}
+				opts, err := NewMatchOptions(mode, 0)
+				if err != nil {
+					b.Fatal(err)
+				}
+				for b.Loop() {
+					ComputeLineAttributionWithOptions(current.String(), []string{claude.String()}, "", opts)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package metrics
+
+import "testing"
+
+// aiWroteJS is code as Claude wrote it; prettierJS is the same code after a
+// formatter added spacing, semicolons, trailing commas, and double quotes.
+const aiWroteJS = `const config = {name:'app',port:8080}
+export function start(opts){
+  return listen(opts.port,opts.host)
+}
+const items = [
+  'a',
+  'b'
+]`
+
+const prettierJS = `const config = { name: "app", port: 8080 };
+export function start(opts) {
+  return listen(opts.port, opts.host);
+}
+const items = [
+  "a",
+  "b",
+];`
+
+func TestComputeLineAttribution_FormatterFlipsLines(t *testing.T) {
+	result := ComputeLineAttribution(prettierJS, []string{aiWroteJS}, "")
+	if result.AILines == result.TotalLines {
+		t.Fatalf("exact matching: want some lines changed by the formatter attributed to human, got %+v", result)
+	}
+}
+
+func TestComputeLineAttributionWithOptions_Normalized(t *testing.T) {
+	opts := MatchOptions{Mode: MatchNormalized}
+	result := ComputeLineAttributionWithOptions(prettierJS, []string{aiWroteJS}, "", opts)
+	if result.TotalLines != 8 || result.AILines != 8 {
+		t.Errorf("normalized matching: want 8/8 AI lines, got %+v", result)
+	}
+}
+
+func TestComputeLineAttributionWithOptions_NormalizedBaseSubtraction(t *testing.T) {
+	// The line existed before tracking began, so a reformatted copy of it
+	// is not Claude's even in normalized mode.
+	base := "import React from 'react'\n"
+	current := "import React from \"react\";\n"
+	opts := MatchOptions{Mode: MatchNormalized}
+	result := ComputeLineAttributionWithOptions(current, []string{base}, base, opts)
+	if result.AILines != 0 || result.HumanLines != 1 {
+		t.Errorf("want the pre-existing line attributed to human, got %+v", result)
+	}
+}
+
+func TestComputeLineAttributionWithOptions_Similarity(t *testing.T) {
+	aiWrote := "total := computeTotal(items, taxRate, discount)\n"
+	// A human renamed one argument.
+	current := "total := computeTotal(items, taxRate, coupon)\n"
+
+	exact := ComputeLineAttributionWithOptions(current, []string{aiWrote}, "", MatchOptions{Mode: MatchNormalized})
+	if exact.AILines != 0 {
+		t.Fatalf("without a threshold: want 0 AI lines, got %+v", exact)
+	}
+
+	loose := ComputeLineAttributionWithOptions(current, []string{aiWrote}, "", MatchOptions{Mode: MatchNormalized, Similarity: 0.7})
+	if loose.AILines != 1 || loose.HumanLines != 0 {
+		t.Errorf("threshold 0.7: want the line attributed to AI, got %+v", loose)
+	}
+
+	strict := ComputeLineAttributionWithOptions(current, []string{aiWrote}, "", MatchOptions{Mode: MatchNormalized, Similarity: 0.95})
+	if strict.AILines != 0 {
+		t.Errorf("threshold 0.95: want 0 AI lines, got %+v", strict)
+	}
+}
+
+func TestComputeLineAttributionWithOptions_SimilarityConsumesOnce(t *testing.T) {
+	aiWrote := "log.Printf(\"started %s\", name)\n"
+	current := "log.Printf(\"started %s\", id)\nlog.Printf(\"started %s\", key)\n"
+	result := ComputeLineAttributionWithOptions(current, []string{aiWrote}, "", MatchOptions{Similarity: 0.5})
+	if result.AILines != 1 || result.HumanLines != 1 {
+		t.Errorf("want one AI line (a single AI line pairs once), got %+v", result)
+	}
+}
+
+func TestNewMatchOptions(t *testing.T) {
+	if opts, err := NewMatchOptions("", 0); err != nil || opts.Mode != MatchExact {
+		t.Errorf("NewMatchOptions(\"\", 0) = %+v, %v; want exact", opts, err)
+	}
+	if _, err := NewMatchOptions("fuzzy", 0); err == nil {
+		t.Error("expected error for unknown mode")
+	}
+	if _, err := NewMatchOptions(MatchNormalized, -0.1); err == nil {
+		t.Error("expected error for negative threshold")
+	}
+}
+
+func TestNormalizeLine(t *testing.T) {
+	tests := []struct{ a, b string }{
+		{"x:=1", "x := 1"},
+		{"foo(a,b);", "foo(a, b)"},
+		{"  'a',", `"a"`},
+		{"return  a  +  b", "return a+b"},
+	}
+	for _, tt := range tests {
+		if normalizeLine(tt.a) != normalizeLine(tt.b) {
+			t.Errorf("normalizeLine(%q) = %q, normalizeLine(%q) = %q; want equal",
+				tt.a, normalizeLine(tt.a), tt.b, normalizeLine(tt.b))
+		}
+	}
+}
+
+func TestClassifyLines(t *testing.T) {
+	lines := []string{"func a() {", "", "\treturn 1", "}", "}"}
+	got := ClassifyLines(lines, []string{"func a() {\n\treturn 1\n}\n"}, "", MatchOptions{})
+	want := []bool{true, false, true, true, false}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("ClassifyLines = %v, want %v", got, want)
+			break
+		}
+	}
+}
+
+func TestComputeLineAttributionWithOptions_Revision(t *testing.T) {
+	aiWrote := "total := computeTotal(items, taxRate, discount)\n"
+	// A human renamed one argument: 10 of 11 tokens are unchanged.
+	current := "total := computeTotal(items, taxRate, coupon)\n"
+
+	kept := ComputeLineAttributionWithOptions(current, []string{aiWrote}, "", MatchOptions{Revision: 0.8})
+	if kept.AILines != 1 || kept.RevisedLines != 1 {
+		t.Errorf("threshold 0.8: want one revised AI line, got %+v", kept)
+	}
+
+	strict := ComputeLineAttributionWithOptions(current, []string{aiWrote}, "", MatchOptions{Revision: 0.95})
+	if strict.AILines != 0 || strict.RevisedLines != 0 {
+		t.Errorf("threshold 0.95: want 0 AI lines, got %+v", strict)
+	}
+
+	// Unchanged lines are AI but not revised.
+	exact := ComputeLineAttributionWithOptions(aiWrote, []string{aiWrote}, "", MatchOptions{Revision: 0.8})
+	if exact.AILines != 1 || exact.RevisedLines != 0 {
+		t.Errorf("unchanged line: want AI, not revised, got %+v", exact)
+	}
+
+	// The same tokens in another order are a rewrite, not a revision.
+	reordered := "computeTotal(discount, taxRate, items) := total\n"
+	if got := ComputeLineAttributionWithOptions(reordered, []string{aiWrote}, "", MatchOptions{Revision: 0.8}); got.AILines != 0 {
+		t.Errorf("reordered line: want human, got %+v", got)
+	}
+}
+
+func TestEditSimilarity(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want float64
+	}{
+		{"a b c d", "a b c d", 1},
+		{"a b c d", "a b x d", 0.75},
+		{"a b c d", "a b c", 0.75},
+		{"a b", "c d", 0},
+		{"", "", 0},
+	}
+	for _, tt := range tests {
+		if got := editSimilarity(tokenize(tt.a), tokenize(tt.b), 0); got != tt.want {
+			t.Errorf("editSimilarity(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+	// Below the threshold the distance is not computed.
+	if got := editSimilarity(tokenize("a b c d"), tokenize("a x y z"), 0.5); got != 0 {
+		t.Errorf("editSimilarity below threshold = %v, want 0", got)
+	}
+}
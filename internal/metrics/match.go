@@ -0,0 +1,183 @@
+package metrics
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Line match modes accepted by NewMatchOptions.
+const (
+	MatchExact      = "exact"      // default: lines equal after trimming
+	MatchNormalized = "normalized" // lines equal after normalizeLine
+)
+
+// maxSimilarityComparisons bounds the token-similarity pass, which compares
+// every unmatched line against every unconsumed AI line. Beyond this the
+// pass is skipped and unmatched lines stay human.
+const maxSimilarityComparisons = 1 << 20
+
+// MatchOptions controls how ComputeLineAttributionWithOptions decides that a
+// line in the file is one Claude wrote. The zero value is exact matching.
+type MatchOptions struct {
+	// Mode is MatchExact or MatchNormalized.
+	Mode string
+	// Similarity, when above 0, lets a line that matched nothing be
+	// attributed to the unconsumed AI line it shares the most tokens with,
+	// if their token overlap (Jaccard) is at least this value.
+	Similarity float64
+	// Revision, when above 0, keeps a line that matched nothing AI when
+	// the token edit distance to an unconsumed AI line leaves a similarity
+	// (1 - distance / tokens in the longer line) of at least this value,
+	// as when a human renames one identifier in a line Claude wrote. It is
+	// tried before Similarity. Lines kept by either are marked revised.
+	Revision float64
+	// ExcludeComments leaves lines that hold only comments (see
+	// CommentLines) out of the lines reports count, so heavily commented
+	// code does not inflate either side.
+	ExcludeComments bool
+}
+
+// NewMatchOptions validates a mode and similarity threshold. An empty mode
+// selects exact matching; a threshold of 0 disables similarity matching.
+func NewMatchOptions(mode string, similarity float64) (MatchOptions, error) {
+	switch mode {
+	case "":
+		mode = MatchExact
+	case MatchExact, MatchNormalized:
+	default:
+		return MatchOptions{}, fmt.Errorf("unknown line match mode %q (want %q or %q)", mode, MatchExact, MatchNormalized)
+	}
+	if similarity < 0 || similarity > 1 {
+		return MatchOptions{}, fmt.Errorf("similarity threshold %v must be between 0 and 1", similarity)
+	}
+	return MatchOptions{Mode: mode, Similarity: similarity}, nil
+}
+
+// key returns the value two lines must share to match under these options.
+func (o MatchOptions) key(line string) string {
+	if o.Mode == MatchNormalized {
+		return hashLine(normalizeLine(line))
+	}
+	return hashLine(line)
+}
+
+// normalizeLine rewrites a line so that formatter-only differences vanish:
+// whitespace between tokens is collapsed (so "a+b" and "a + b" agree),
+// single quotes and backticks become double quotes, and trailing commas and
+// semicolons are dropped.
+func normalizeLine(line string) string {
+	tokens := tokenize(line)
+	for len(tokens) > 0 {
+		last := tokens[len(tokens)-1]
+		if last != "," && last != ";" {
+			break
+		}
+		tokens = tokens[:len(tokens)-1]
+	}
+	return strings.Join(tokens, " ")
+}
+
+// tokenize splits a line into identifier/number runs and single punctuation
+// characters, dropping whitespace. Quote characters are unified.
+func tokenize(line string) []string {
+	var tokens []string
+	word := -1
+	for i, r := range line {
+		if r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if word < 0 {
+				word = i
+			}
+			continue
+		}
+		if word >= 0 {
+			tokens = append(tokens, line[word:i])
+			word = -1
+		}
+		switch {
+		case unicode.IsSpace(r):
+		case r == '\'' || r == '`':
+			tokens = append(tokens, `"`)
+		default:
+			tokens = append(tokens, string(r))
+		}
+	}
+	if word >= 0 {
+		tokens = append(tokens, line[word:])
+	}
+	return tokens
+}
+
+// tokenBag counts the tokens of a normalized line.
+func tokenBag(line string) map[string]int {
+	bag := make(map[string]int)
+	for _, tok := range strings.Fields(normalizeLine(line)) {
+		bag[tok]++
+	}
+	return bag
+}
+
+// editSimilarity returns 1 - d/n for the token sequences a and b, where d
+// is their Levenshtein distance in tokens and n the length of the longer.
+// It returns 0 without computing the distance when the tokens the two
+// share show it cannot reach threshold.
+func editSimilarity(a, b []string, threshold float64) float64 {
+	n := max(len(a), len(b))
+	if n == 0 {
+		return 0
+	}
+	// Every token of the longer sequence that has no counterpart in the
+	// shorter costs at least one edit.
+	shared := make(map[string]int, len(a))
+	for _, tok := range a {
+		shared[tok]++
+	}
+	common := 0
+	for _, tok := range b {
+		if shared[tok] > 0 {
+			shared[tok]--
+			common++
+		}
+	}
+	if float64(common)/float64(n) < threshold {
+		return 0
+	}
+
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return 1 - float64(prev[len(b)])/float64(n)
+}
+
+// similarity returns the Jaccard index of two token bags, counting repeated
+// tokens: the size of their intersection over the size of their union.
+func similarity(a, b map[string]int) float64 {
+	inter, union := 0, 0
+	for tok, n := range a {
+		m := b[tok]
+		inter += min(n, m)
+		union += max(n, m)
+	}
+	for tok, m := range b {
+		if _, ok := a[tok]; !ok {
+			union += m
+		}
+	}
+	if union == 0 {
+		return 0
+	}
+	return float64(inter) / float64(union)
+}
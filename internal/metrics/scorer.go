@@ -0,0 +1,124 @@
+package metrics
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/anthropic/gap-map/internal/worktype"
+)
+
+// Scorer names accepted by NewScorer.
+const (
+	ScorerWeighted = "weighted" // default: work-type tier weights
+	ScorerRaw      = "raw"      // every line counts the same
+	ScorerCustom   = "custom"   // tier weights overridden per work type
+)
+
+// FileScore is the per-file input to a Scorer.
+type FileScore struct {
+	WorkType   string
+	AILines    int
+	TotalLines int
+}
+
+// Scorer computes the meaningful AI percentage from per-file line counts.
+// Implementations decide how much each work type contributes.
+type Scorer interface {
+	// Name identifies the scorer in reports.
+	Name() string
+	// Weight returns the multiplier applied to lines of the given work type.
+	// A weight of 0 excludes the work type from the score.
+	Weight(workType string) float64
+	// Score returns the meaningful AI percentage (0-100) across files.
+	Score(files []FileScore) float64
+}
+
+// weightedScorer weights each file's lines by a per-work-type multiplier.
+type weightedScorer struct {
+	name    string
+	weights map[string]float64
+}
+
+// DefaultScorer returns the built-in scorer, which weights lines by
+// worktype.WorkTypeWeights (architecture/core logic 3x, boilerplate/tests 1x).
+func DefaultScorer() Scorer {
+	return &weightedScorer{name: ScorerWeighted, weights: defaultWeights()}
+}
+
+// RawScorer returns a scorer that weights every work type equally, so the
+// meaningful AI percentage equals the raw line ratio.
+func RawScorer() Scorer {
+	weights := defaultWeights()
+	for wt := range weights {
+		weights[wt] = 1.0
+	}
+	return &weightedScorer{name: ScorerRaw, weights: weights}
+}
+
+// NewScorer returns the scorer with the given name. Custom weights override
+// the default weight for the listed work types and are only accepted by the
+// "custom" scorer. An empty name selects the default scorer.
+func NewScorer(name string, weights map[string]float64) (Scorer, error) {
+	switch name {
+	case "", ScorerWeighted:
+		return DefaultScorer(), nil
+	case ScorerRaw:
+		return RawScorer(), nil
+	case ScorerCustom:
+		merged := defaultWeights()
+		for wt, w := range weights {
+			if _, ok := merged[wt]; !ok {
+				return nil, fmt.Errorf("unknown work type %q in scorer weights", wt)
+			}
+			if w < 0 {
+				return nil, fmt.Errorf("scorer weight for %q must not be negative", wt)
+			}
+			merged[wt] = w
+		}
+		return &weightedScorer{name: ScorerCustom, weights: merged}, nil
+	default:
+		return nil, fmt.Errorf("unknown scorer %q (valid: %s)", name, strings.Join(ScorerNames(), ", "))
+	}
+}
+
+// ScorerNames returns the names accepted by NewScorer, sorted.
+func ScorerNames() []string {
+	names := []string{ScorerWeighted, ScorerRaw, ScorerCustom}
+	sort.Strings(names)
+	return names
+}
+
+func (s *weightedScorer) Name() string {
+	return s.name
+}
+
+// Weight falls back to the core_logic weight for unknown work types.
+func (s *weightedScorer) Weight(workType string) float64 {
+	if w, ok := s.weights[workType]; ok {
+		return w
+	}
+	return s.weights[string(worktype.CoreLogic)]
+}
+
+func (s *weightedScorer) Score(files []FileScore) float64 {
+	var weightedAI, weightedAll float64
+	for _, f := range files {
+		w := s.Weight(f.WorkType)
+		weightedAI += float64(f.AILines) * w
+		weightedAll += float64(f.TotalLines) * w
+	}
+	if weightedAll == 0 {
+		return 0
+	}
+	return weightedAI / weightedAll * 100.0
+}
+
+// defaultWeights returns a copy of worktype.WorkTypeWeights keyed by string.
+func defaultWeights() map[string]float64 {
+	weights := make(map[string]float64, len(worktype.WorkTypeWeights))
+	for wt, w := range worktype.WorkTypeWeights {
+		weights[string(wt)] = w
+	}
+	return weights
+}
@@ -0,0 +1,78 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/anthropic/gap-map/internal/store"
+)
+
+var scorerFiles = []FileScore{
+	{WorkType: "core_logic", AILines: 10, TotalLines: 100},
+	{WorkType: "test_scaffolding", AILines: 100, TotalLines: 100},
+	{WorkType: "boilerplate", AILines: 50, TotalLines: 50},
+}
+
+func TestDefaultScorer_MatchesTierWeights(t *testing.T) {
+	s := DefaultScorer()
+	if s.Name() != ScorerWeighted {
+		t.Errorf("Name = %q, want %q", s.Name(), ScorerWeighted)
+	}
+	// (10*3 + 100*1 + 50*1) / (100*3 + 100*1 + 50*1) = 180/450 = 40%
+	if got := s.Score(scorerFiles); !almostEqual(got, 40.0, 0.01) {
+		t.Errorf("Score = %.2f, want 40.00", got)
+	}
+	if got := s.Weight("unknown"); got != 3.0 {
+		t.Errorf("Weight(unknown) = %.1f, want core_logic weight 3.0", got)
+	}
+}
+
+func TestRawScorer_EqualsLineRatio(t *testing.T) {
+	// 160 / 250 = 64%
+	if got := RawScorer().Score(scorerFiles); !almostEqual(got, 64.0, 0.01) {
+		t.Errorf("Score = %.2f, want 64.00", got)
+	}
+}
+
+func TestCustomScorer_ExcludesZeroWeight(t *testing.T) {
+	s, err := NewScorer(ScorerCustom, map[string]float64{"boilerplate": 0, "test_scaffolding": 0.5})
+	if err != nil {
+		t.Fatalf("NewScorer: %v", err)
+	}
+	// (10*3 + 100*0.5) / (100*3 + 100*0.5) = 80/350
+	if got := s.Score(scorerFiles); !almostEqual(got, 80.0/350.0*100.0, 0.01) {
+		t.Errorf("Score = %.2f, want %.2f", got, 80.0/350.0*100.0)
+	}
+	if got := s.Score([]FileScore{{WorkType: "boilerplate", AILines: 5, TotalLines: 5}}); got != 0 {
+		t.Errorf("Score with only excluded work = %.2f, want 0", got)
+	}
+}
+
+func TestNewScorer_Errors(t *testing.T) {
+	if _, err := NewScorer("fancy", nil); err == nil {
+		t.Error("expected error for unknown scorer")
+	}
+	if _, err := NewScorer(ScorerCustom, map[string]float64{"docs": 1}); err == nil {
+		t.Error("expected error for unknown work type")
+	}
+	if _, err := NewScorer(ScorerCustom, map[string]float64{"core_logic": -1}); err == nil {
+		t.Error("expected error for negative weight")
+	}
+}
+
+func TestComputeProjectMetrics_UsesScorer(t *testing.T) {
+	attrs := []store.AttributionWithWorkType{
+		makeAttr(1, "main.go", "/proj", "mostly_human", "core_logic", 100),
+		makeAttr(2, "main_test.go", "/proj", "mostly_ai", "test_scaffolding", 100),
+	}
+
+	pm := NewCalculatorWithScorer(RawScorer()).ComputeProjectMetrics("/proj", attrs)
+	if pm.Scorer != ScorerRaw {
+		t.Errorf("Scorer = %q, want %q", pm.Scorer, ScorerRaw)
+	}
+	if !almostEqual(pm.MeaningfulAIPct, pm.RawAIPct, 0.01) {
+		t.Errorf("raw scorer MeaningfulAIPct = %.2f, want RawAIPct %.2f", pm.MeaningfulAIPct, pm.RawAIPct)
+	}
+	if w := pm.ByWorkType["core_logic"].Weight; w != 1.0 {
+		t.Errorf("core_logic weight = %.1f, want 1.0", w)
+	}
+}
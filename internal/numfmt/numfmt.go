@@ -0,0 +1,147 @@
+// Package numfmt formats numbers for people in a chosen locale, with
+// its decimal and thousands separators.
+package numfmt
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Locale is how numbers are written in a locale: the decimal
+// separator, and the separator between groups of three digits, if any.
+type Locale struct {
+	Name      string
+	Decimal   string
+	Thousands string // "" for no grouping
+}
+
+// C writes numbers as Go and the C locale do: "1234.5", so they parse the
+// same everywhere.
+var C = Locale{Name: "C", Decimal: "."}
+
+// locales by language, or language and region, as in "de" or "de-ch".
+// Separators are those of CLDR's standard number pattern.
+var locales = map[string]Locale{
+	"en":    {Decimal: ".", Thousands: ","},
+	"ja":    {Decimal: ".", Thousands: ","},
+	"ko":    {Decimal: ".", Thousands: ","},
+	"zh":    {Decimal: ".", Thousands: ","},
+	"de":    {Decimal: ",", Thousands: "."},
+	"de-ch": {Decimal: ".", Thousands: "\u2019"},
+	"es":    {Decimal: ",", Thousands: "."},
+	"id":    {Decimal: ",", Thousands: "."},
+	"it":    {Decimal: ",", Thousands: "."},
+	"nl":    {Decimal: ",", Thousands: "."},
+	"pt":    {Decimal: ",", Thousands: "."},
+	"tr":    {Decimal: ",", Thousands: "."},
+	"da":    {Decimal: ",", Thousands: "."},
+	"fr":    {Decimal: ",", Thousands: "\u202f"},
+	"cs":    {Decimal: ",", Thousands: "\u00a0"},
+	"fi":    {Decimal: ",", Thousands: "\u00a0"},
+	"nb":    {Decimal: ",", Thousands: "\u00a0"},
+	"pl":    {Decimal: ",", Thousands: "\u00a0"},
+	"ru":    {Decimal: ",", Thousands: "\u00a0"},
+	"sv":    {Decimal: ",", Thousands: "\u00a0"},
+	"uk":    {Decimal: ",", Thousands: "\u00a0"},
+}
+
+// Parse returns the number format of a locale name such as "de", "de-CH"
+// or "de_DE.UTF-8". "", "C" and "POSIX" are C; "auto" is the locale of
+// the environment, as FromEnv finds it.
+func Parse(name string) (Locale, error) {
+	switch name {
+	case "", "C", "POSIX":
+		return C, nil
+	case "auto":
+		return FromEnv(), nil
+	}
+	tag := strings.ToLower(name)
+	if i := strings.IndexAny(tag, ".@"); i >= 0 {
+		tag = tag[:i] // drop the encoding and modifier
+	}
+	tag = strings.ReplaceAll(tag, "_", "-")
+	l, ok := locales[tag]
+	if !ok {
+		lang, _, _ := strings.Cut(tag, "-")
+		if l, ok = locales[lang]; !ok {
+			return Locale{}, fmt.Errorf("unknown locale %q", name)
+		}
+	}
+	l.Name = name
+	return l, nil
+}
+
+// FromEnv returns the number format of the locale named by LC_ALL,
+// LC_NUMERIC or LANG, the first one set, or C when none is set or the
+// locale is unknown.
+func FromEnv() Locale {
+	for _, key := range []string{"LC_ALL", "LC_NUMERIC", "LANG"} {
+		v := os.Getenv(key)
+		if v == "" {
+			continue
+		}
+		if l, err := Parse(v); err == nil && v != "auto" {
+			return l
+		}
+		return C
+	}
+	return C
+}
+
+// Int formats n with the locale's thousands separator.
+func (l Locale) Int(n int) string {
+	s := strconv.Itoa(n)
+	if l.Thousands == "" {
+		return s
+	}
+	sign := ""
+	if n < 0 {
+		sign, s = "-", s[1:]
+	}
+	return sign + l.group(s)
+}
+
+// Float formats f with prec decimals and the locale's separators.
+func (l Locale) Float(f float64, prec int) string {
+	s := strconv.FormatFloat(f, 'f', prec, 64)
+	if l.Decimal == "." && l.Thousands == "" {
+		return s
+	}
+	sign := ""
+	if strings.HasPrefix(s, "-") {
+		sign, s = "-", s[1:]
+	}
+	whole, frac, hasFrac := strings.Cut(s, ".")
+	if l.Thousands != "" {
+		whole = l.group(whole)
+	}
+	if hasFrac {
+		return sign + whole + l.Decimal + frac
+	}
+	return sign + whole
+}
+
+// Pct formats a percentage with one decimal, as in "12.5%".
+func (l Locale) Pct(f float64) string {
+	return l.Float(f, 1) + "%"
+}
+
+// group inserts the thousands separator into a string of digits.
+func (l Locale) group(digits string) string {
+	if len(digits) <= 3 {
+		return digits
+	}
+	var b strings.Builder
+	first := len(digits) % 3
+	if first == 0 {
+		first = 3
+	}
+	b.WriteString(digits[:first])
+	for i := first; i < len(digits); i += 3 {
+		b.WriteString(l.Thousands)
+		b.WriteString(digits[i : i+3])
+	}
+	return b.String()
+}
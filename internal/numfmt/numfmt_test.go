@@ -0,0 +1,60 @@
+package numfmt
+
+import "testing"
+
+func TestLocaleFormat(t *testing.T) {
+	de, _ := Parse("de_DE.UTF-8")
+	fr, _ := Parse("fr-FR")
+	ch, _ := Parse("de-CH")
+	en, _ := Parse("en_US")
+	tests := []struct {
+		l    Locale
+		f    float64
+		prec int
+		want string
+	}{
+		{C, 1234567.25, 2, "1234567.25"},
+		{en, 1234567.25, 2, "1,234,567.25"},
+		{de, 1234567.25, 2, "1.234.567,25"},
+		{de, -1234, 0, "-1.234"},
+		{fr, 12345.5, 1, "12\u202f345,5"},
+		{ch, 1234.5, 1, "1\u2019234.5"},
+		{de, 999, 1, "999,0"},
+	}
+	for _, tt := range tests {
+		if got := tt.l.Float(tt.f, tt.prec); got != tt.want {
+			t.Errorf("%s.Float(%v, %d) = %q, want %q", tt.l.Name, tt.f, tt.prec, got, tt.want)
+		}
+	}
+	if got := de.Int(-1234567); got != "-1.234.567" {
+		t.Errorf("de.Int = %q", got)
+	}
+	if got := C.Int(1234567); got != "1234567" {
+		t.Errorf("C.Int = %q", got)
+	}
+	if got := de.Pct(12.46); got != "12,5%" {
+		t.Errorf("de.Pct = %q", got)
+	}
+}
+
+func TestParse(t *testing.T) {
+	for _, name := range []string{"", "C", "POSIX"} {
+		if l, err := Parse(name); err != nil || l != C {
+			t.Errorf("Parse(%q) = %+v, %v; want C", name, l, err)
+		}
+	}
+	if _, err := Parse("tlh"); err == nil {
+		t.Error("unknown locale accepted")
+	}
+
+	t.Setenv("LC_ALL", "")
+	t.Setenv("LC_NUMERIC", "de_DE.UTF-8")
+	t.Setenv("LANG", "en_US.UTF-8")
+	if l, err := Parse("auto"); err != nil || l.Decimal != "," {
+		t.Errorf("Parse(auto) = %+v, %v; want LC_NUMERIC's de", l, err)
+	}
+	t.Setenv("LC_ALL", "C.UTF-8")
+	if l := FromEnv(); l.Decimal != "." || l.Thousands != "" {
+		t.Errorf("FromEnv with LC_ALL=C.UTF-8 = %+v, want C", l)
+	}
+}
@@ -0,0 +1,321 @@
+// Package provenance builds signed, machine-readable attribution documents
+// that can accompany release artifacts.
+//
+// A document is an in-toto Statement (the format SLSA provenance uses)
+// whose subjects are the attributed files with their SHA-256 digests, and
+// whose predicate carries the per-file AI/human split, the AI tools
+// involved and when the work happened. The statement is signed with
+// Ed25519 inside a DSSE envelope, so standard in-toto tooling can verify it
+// given the public key.
+package provenance
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/anthropic/gap-map/internal/report"
+	"github.com/anthropic/gap-map/internal/store"
+)
+
+// Type URIs of the document.
+const (
+	StatementType = "https://in-toto.io/Statement/v1"
+	PredicateType = "https://github.com/anthropic/gap-map/provenance/v1"
+	PayloadType   = "application/vnd.in-toto+json"
+)
+
+// Statement is an in-toto Statement about the attributed files.
+type Statement struct {
+	Type          string    `json:"_type"`
+	Subject       []Subject `json:"subject"`
+	PredicateType string    `json:"predicateType"`
+	Predicate     Predicate `json:"predicate"`
+}
+
+// Subject is one attributed file, identified by its content digest.
+type Subject struct {
+	Name   string            `json:"name"`
+	Digest map[string]string `json:"digest"`
+}
+
+// Predicate is the attribution of the subjects.
+type Predicate struct {
+	Generator        string     `json:"generator"`
+	GeneratedAt      string     `json:"generated_at"`       // UTC
+	GeneratedAtLocal string     `json:"generated_at_local"` // in the display time zone
+	Project          string     `json:"project"`
+	AITools          []string   `json:"ai_tools"`
+	Scorer           string     `json:"scorer"`
+	AIPct            float64    `json:"ai_pct"`
+	MeaningfulAIPct  float64    `json:"meaningful_ai_pct"`
+	TotalLines       int        `json:"total_lines"`
+	AILines          int        `json:"ai_lines"`
+	TimeRange        *TimeRange `json:"time_range,omitempty"`
+	Files            []File     `json:"files"`
+}
+
+// TimeRange is the span of attributed work, in RFC 3339: Start and End in
+// UTC, StartLocal and EndLocal in the display time zone.
+type TimeRange struct {
+	Start      string `json:"start"`
+	End        string `json:"end"`
+	StartLocal string `json:"start_local"`
+	EndLocal   string `json:"end_local"`
+}
+
+// File is the attribution of one file. Its name matches a subject.
+type File struct {
+	Path            string     `json:"path"`
+	SHA256          string     `json:"sha256"`
+	WorkType        string     `json:"work_type"`
+	AuthorshipLevel string     `json:"authorship_level"`
+	AIPct           float64    `json:"ai_pct"`
+	HumanPct        float64    `json:"human_pct"`
+	TotalLines      int        `json:"total_lines"`
+	AILines         int        `json:"ai_lines"`
+	Tools           []string   `json:"tools,omitempty"` // AI tool calls behind its changes
+	TimeRange       *TimeRange `json:"time_range,omitempty"`
+}
+
+// aiTool is the session provider whose events gap-map correlates.
+const aiTool = "claude-code"
+
+// Build creates the statement for a project report. activity, from
+// store.QueryFileActivity, supplies per-file tools and time ranges. Files
+// that can no longer be read are left out, since they cannot be digested.
+// Local times are given in now's location.
+func Build(r *report.ProjectReport, activity map[string]store.FileActivity, now time.Time) *Statement {
+	st := &Statement{
+		Type:          StatementType,
+		PredicateType: PredicateType,
+		Predicate: Predicate{
+			Generator:        "gap-map",
+			GeneratedAt:      now.UTC().Format(time.RFC3339),
+			GeneratedAtLocal: now.Format(time.RFC3339),
+			Project:          r.ProjectPath,
+			AITools:          []string{aiTool},
+			Scorer:           r.Scorer,
+			AIPct:            r.RawAIPct,
+			MeaningfulAIPct:  r.MeaningfulAIPct,
+			TotalLines:       r.TotalLines,
+			AILines:          r.AILines,
+		},
+	}
+
+	var start, end time.Time
+	files := append([]report.FileReport(nil), r.Files...)
+	sort.Slice(files, func(i, j int) bool { return files[i].FilePath < files[j].FilePath })
+	for _, fr := range files {
+		path := fr.FilePath
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(r.ProjectPath, path)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		sum := sha256.Sum256(data)
+		digest := hex.EncodeToString(sum[:])
+		name := fr.FilePath
+		if rel, err := filepath.Rel(r.ProjectPath, path); err == nil {
+			name = filepath.ToSlash(rel)
+		}
+
+		f := File{
+			Path:            name,
+			SHA256:          digest,
+			WorkType:        fr.WorkType,
+			AuthorshipLevel: fr.AuthorshipLevel,
+			AIPct:           fr.RawAIPct,
+			HumanPct:        100 - fr.RawAIPct,
+			TotalLines:      fr.TotalLines,
+			AILines:         fr.AILines,
+		}
+		if fr.TotalLines == 0 {
+			f.HumanPct = 0
+		}
+		if fa, ok := activity[fr.FilePath]; ok {
+			f.Tools = fa.Tools
+			f.TimeRange = timeRange(fa.FirstAt, fa.LastAt, now.Location())
+			if start.IsZero() || fa.FirstAt.Before(start) {
+				start = fa.FirstAt
+			}
+			if fa.LastAt.After(end) {
+				end = fa.LastAt
+			}
+		}
+		st.Subject = append(st.Subject, Subject{Name: name, Digest: map[string]string{"sha256": digest}})
+		st.Predicate.Files = append(st.Predicate.Files, f)
+	}
+	if !start.IsZero() {
+		st.Predicate.TimeRange = timeRange(start, end, now.Location())
+	}
+	return st
+}
+
+func timeRange(start, end time.Time, loc *time.Location) *TimeRange {
+	return &TimeRange{
+		Start:      start.UTC().Format(time.RFC3339),
+		End:        end.UTC().Format(time.RFC3339),
+		StartLocal: start.In(loc).Format(time.RFC3339),
+		EndLocal:   end.In(loc).Format(time.RFC3339),
+	}
+}
+
+// Envelope is a DSSE envelope: the base64 statement and its signatures.
+type Envelope struct {
+	PayloadType string      `json:"payloadType"`
+	Payload     string      `json:"payload"`
+	Signatures  []Signature `json:"signatures"`
+}
+
+// Signature is one DSSE signature. KeyID is KeyID of the public key.
+type Signature struct {
+	KeyID string `json:"keyid"`
+	Sig   string `json:"sig"`
+}
+
+// Sign serializes st and signs it with key.
+func Sign(st *Statement, key ed25519.PrivateKey) (*Envelope, error) {
+	payload, err := json.Marshal(st)
+	if err != nil {
+		return nil, fmt.Errorf("marshal statement: %w", err)
+	}
+	sig := ed25519.Sign(key, pae(PayloadType, payload))
+	return &Envelope{
+		PayloadType: PayloadType,
+		Payload:     base64.StdEncoding.EncodeToString(payload),
+		Signatures: []Signature{{
+			KeyID: KeyID(key.Public().(ed25519.PublicKey)),
+			Sig:   base64.StdEncoding.EncodeToString(sig),
+		}},
+	}, nil
+}
+
+// ErrBadSignature is returned by Verify when no signature on the envelope
+// is valid for the key.
+var ErrBadSignature = errors.New("no valid signature for this key")
+
+// Verify checks that env was signed by pub and returns its statement.
+func Verify(env *Envelope, pub ed25519.PublicKey) (*Statement, error) {
+	if env.PayloadType != PayloadType {
+		return nil, fmt.Errorf("unexpected payload type %q", env.PayloadType)
+	}
+	payload, err := base64.StdEncoding.DecodeString(env.Payload)
+	if err != nil {
+		return nil, fmt.Errorf("decode payload: %w", err)
+	}
+	valid := false
+	for _, s := range env.Signatures {
+		sig, err := base64.StdEncoding.DecodeString(s.Sig)
+		if err == nil && ed25519.Verify(pub, pae(env.PayloadType, payload), sig) {
+			valid = true
+			break
+		}
+	}
+	if !valid {
+		return nil, ErrBadSignature
+	}
+
+	var st Statement
+	if err := json.Unmarshal(payload, &st); err != nil {
+		return nil, fmt.Errorf("parse statement: %w", err)
+	}
+	return &st, nil
+}
+
+// pae is the DSSE pre-authentication encoding that is actually signed.
+func pae(payloadType string, payload []byte) []byte {
+	return []byte(fmt.Sprintf("DSSEv1 %d %s %d %s", len(payloadType), payloadType, len(payload), payload))
+}
+
+// KeyID identifies a public key: the hex SHA-256 of its PKIX encoding.
+func KeyID(pub ed25519.PublicKey) string {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(der)
+	return hex.EncodeToString(sum[:])
+}
+
+// LoadOrCreateKey reads the PEM (PKCS #8) Ed25519 private key at path. If
+// there is none, a new key is generated and saved there, with its public
+// key next to it in path+".pub" for verifiers.
+func LoadOrCreateKey(path string) (ed25519.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err == nil {
+		block, _ := pem.Decode(data)
+		if block == nil {
+			return nil, fmt.Errorf("%s: no PEM block", path)
+		}
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		edKey, ok := key.(ed25519.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("%s: not an Ed25519 key", path)
+		}
+		return edKey, nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	pub, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("generate key: %w", err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	pubDER, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(path+".pub", pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER}), 0644); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// LoadPublicKey reads a PEM (PKIX) Ed25519 public key.
+func LoadPublicKey(path string) (ed25519.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s: no PEM block", path)
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	pub, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("%s: not an Ed25519 key", path)
+	}
+	return pub, nil
+}
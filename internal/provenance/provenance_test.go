@@ -0,0 +1,86 @@
+package provenance
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/anthropic/gap-map/internal/report"
+	"github.com/anthropic/gap-map/internal/store"
+)
+
+func TestBuildSignVerify(t *testing.T) {
+	proj := t.TempDir()
+	if err := os.WriteFile(filepath.Join(proj, "a.go"), []byte("package a\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	base := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	r := &report.ProjectReport{
+		ProjectPath: proj,
+		Scorer:      "weighted",
+		RawAIPct:    75,
+		TotalLines:  4,
+		AILines:     3,
+		Files: []report.FileReport{
+			{FilePath: "a.go", WorkType: "core_logic", RawAIPct: 75, TotalLines: 4, AILines: 3, AuthorshipLevel: "mostly_ai"},
+			{FilePath: "gone.go", TotalLines: 1},
+		},
+	}
+	activity := map[string]store.FileActivity{
+		"a.go": {FirstAt: base, LastAt: base.Add(time.Hour), Tools: []string{"Write"}},
+	}
+
+	st := Build(r, activity, base.Add(2*time.Hour).In(time.FixedZone("CET", 3600)))
+	if len(st.Subject) != 1 || st.Subject[0].Name != "a.go" {
+		t.Fatalf("subjects = %+v, want only a.go", st.Subject)
+	}
+	// sha256 of "package a\n".
+	if got := st.Subject[0].Digest["sha256"]; got != "7b39baa38a2ec2b8d111bbbd8e448e80226477ab40105d9d2123d4dc18067438" {
+		t.Errorf("digest = %q", got)
+	}
+	f := st.Predicate.Files[0]
+	if f.AIPct != 75 || f.HumanPct != 25 || f.TimeRange == nil || f.TimeRange.Start != "2026-03-01T09:00:00Z" {
+		t.Errorf("file = %+v", f)
+	}
+	if tr := st.Predicate.TimeRange; tr == nil || tr.End != "2026-03-01T10:00:00Z" || tr.EndLocal != "2026-03-01T11:00:00+01:00" {
+		t.Errorf("time range = %+v", tr)
+	}
+
+	keyPath := filepath.Join(t.TempDir(), "keys", "provenance.key")
+	key, err := LoadOrCreateKey(keyPath)
+	if err != nil {
+		t.Fatalf("LoadOrCreateKey: %v", err)
+	}
+	again, err := LoadOrCreateKey(keyPath)
+	if err != nil || !again.Equal(key) {
+		t.Fatalf("reloaded key differs (%v)", err)
+	}
+	pub, err := LoadPublicKey(keyPath + ".pub")
+	if err != nil {
+		t.Fatalf("LoadPublicKey: %v", err)
+	}
+
+	env, err := Sign(st, key)
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	if env.Signatures[0].KeyID != KeyID(pub) {
+		t.Errorf("keyid = %q, want %q", env.Signatures[0].KeyID, KeyID(pub))
+	}
+	got, err := Verify(env, pub)
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if got.Predicate.Files[0].SHA256 != f.SHA256 {
+		t.Errorf("verified statement = %+v", got)
+	}
+
+	// Any change to the payload breaks the signature.
+	tampered := *env
+	tampered.Payload = env.Payload[:len(env.Payload)-4] + "AAAA"
+	if _, err := Verify(&tampered, pub); !errors.Is(err, ErrBadSignature) {
+		t.Errorf("Verify(tampered) = %v, want ErrBadSignature", err)
+	}
+}
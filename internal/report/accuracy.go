@@ -0,0 +1,147 @@
+package report
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/anthropic/gap-map/internal/metrics"
+	"github.com/anthropic/gap-map/internal/store"
+)
+
+// AccuracySummary compares lines users labeled with gapmap label against
+// the attribution computed for them, with AI as the positive class: how
+// far the report's numbers can be trusted.
+type AccuracySummary struct {
+	LabeledLines int            `json:"labeled_lines"`
+	Agreed       int            `json:"agreed"`
+	TruePos      int            `json:"true_positives"`  // computed AI, labeled AI
+	FalsePos     int            `json:"false_positives"` // computed AI, labeled human
+	FalseNeg     int            `json:"false_negatives"` // computed human, labeled AI
+	AgreementPct float64        `json:"agreement_pct"`
+	Precision    float64        `json:"precision"`             // share of computed AI lines labeled AI
+	Recall       float64        `json:"recall"`                // share of AI-labeled lines computed AI
+	StaleLines   int            `json:"stale_lines,omitempty"` // labeled lines past the end of their file
+	Files        []FileAccuracy `json:"files"`
+}
+
+// FileAccuracy is the accuracy of one labeled file.
+type FileAccuracy struct {
+	FilePath     string `json:"file_path"`
+	LabeledLines int    `json:"labeled_lines"`
+	Agreed       int    `json:"agreed"`
+	TruePos      int    `json:"true_positives"`
+	FalsePos     int    `json:"false_positives"`
+	FalseNeg     int    `json:"false_negatives"`
+}
+
+// ApplyAccuracy sets the accuracy section of a report generated from s
+// (GenerateProjectFromStore and friends). Each labeled line in the working
+// tree is attributed as in ApplyCoverage; lines unchanged since tracking
+// began, and lines of files the report does not include, count as human.
+// Where labels overlap, the newest wins. Sets nothing when the project has
+// no labels.
+func ApplyAccuracy(ctx context.Context, s *store.Store, r *ProjectReport, opts Options) error {
+	labels, err := s.QueryLineLabels(r.ProjectPath)
+	if err != nil {
+		return fmt.Errorf("query line labels: %w", err)
+	}
+	if len(labels) == 0 {
+		return nil
+	}
+
+	// Labeled lines by absolute file path, newest label last.
+	byFile := make(map[string]map[int]string)
+	for _, l := range labels {
+		lines, ok := byFile[l.FilePath]
+		if !ok {
+			lines = make(map[int]string)
+			byFile[l.FilePath] = lines
+		}
+		for n := l.StartLine; n <= l.EndLine; n++ {
+			lines[n] = l.Label
+		}
+	}
+
+	reportPaths := make(map[string]string, len(r.Files))
+	for _, fr := range r.Files {
+		reportPaths[resolveFilePath(r.ProjectPath, fr.FilePath)] = fr.FilePath
+	}
+
+	var claudeContentByFile map[string][]string
+	summary := &AccuracySummary{}
+	for absPath, labeled := range byFile {
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("apply accuracy: %w", err)
+		}
+		content := readFileContent(absPath)
+		lineCount := strings.Count(content, "\n")
+		if content != "" && !strings.HasSuffix(content, "\n") {
+			lineCount++
+		}
+
+		aiLines := make(map[int]bool)
+		if filePath, ok := reportPaths[absPath]; ok {
+			if claudeContentByFile == nil {
+				sessionEvents, err := s.QueryWriteEditSessionEvents()
+				if err != nil {
+					return fmt.Errorf("query session events: %w", err)
+				}
+				claudeContentByFile = buildClaudeContentMap(s, sessionEvents)
+			}
+			numbers, lines, _, base := getChangedLines(ctx, s, r.ProjectPath, filePath, opts)
+			numbers, lines, _ = opts.Guard.CapLines(numbers, lines)
+			ai := metrics.ClassifyLines(lines, findClaudeContent(filePath, claudeContentByFile), base, opts.matchOptions(r.ProjectPath))
+			for i, n := range numbers {
+				if ai[i] {
+					aiLines[n] = true
+				}
+			}
+		}
+
+		fa := FileAccuracy{FilePath: absPath}
+		if rel, err := filepath.Rel(r.ProjectPath, absPath); err == nil && !strings.HasPrefix(rel, "..") {
+			fa.FilePath = rel
+		}
+		for n, label := range labeled {
+			if n > lineCount {
+				summary.StaleLines++
+				continue
+			}
+			computedAI := aiLines[n]
+			labeledAI := label == store.LabelAI
+			fa.LabeledLines++
+			switch {
+			case computedAI == labeledAI:
+				fa.Agreed++
+				if labeledAI {
+					fa.TruePos++
+				}
+			case computedAI:
+				fa.FalsePos++
+			default:
+				fa.FalseNeg++
+			}
+		}
+		if fa.LabeledLines == 0 {
+			continue
+		}
+		summary.Files = append(summary.Files, fa)
+		summary.LabeledLines += fa.LabeledLines
+		summary.Agreed += fa.Agreed
+		summary.TruePos += fa.TruePos
+		summary.FalsePos += fa.FalsePos
+		summary.FalseNeg += fa.FalseNeg
+	}
+
+	sort.Slice(summary.Files, func(i, j int) bool {
+		return summary.Files[i].FilePath < summary.Files[j].FilePath
+	})
+	summary.AgreementPct = pct(summary.Agreed, summary.LabeledLines)
+	summary.Precision = pct(summary.TruePos, summary.TruePos+summary.FalsePos)
+	summary.Recall = pct(summary.TruePos, summary.TruePos+summary.FalseNeg)
+	r.Accuracy = summary
+	return nil
+}
@@ -0,0 +1,66 @@
+package report
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/anthropic/gap-map/internal/metrics"
+	"github.com/anthropic/gap-map/internal/store"
+)
+
+func TestApplyAccuracy(t *testing.T) {
+	s, projDir, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	// Claude wrote Add; a human wrote Sub.
+	content := "package calc\n\nfunc Add(a, b int) int {\n\treturn a + b\n}\n\nfunc Sub(a, b int) int {\n\treturn a - b\n}\n"
+	writeFile(t, projDir, "calc.go", content)
+	absPath := filepath.Join(projDir, "calc.go")
+	insertSessionEvent(t, s, "s1", absPath,
+		makeWriteRawJSON(absPath, "func Add(a, b int) int {\n\treturn a + b\n}\n"), baseTime)
+	insertAttribution(t, s, "calc.go", projDir, "mostly_ai", "core_logic", baseTime, 9)
+
+	report, err := GenerateProject(t.Context(), s, metrics.DefaultScorer(), Filter{}, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// No labels, no section.
+	if err := ApplyAccuracy(context.Background(), s, report, Options{}); err != nil {
+		t.Fatalf("ApplyAccuracy: %v", err)
+	}
+	if report.Accuracy != nil {
+		t.Fatalf("Accuracy = %+v without labels, want nil", report.Accuracy)
+	}
+
+	for _, l := range []store.LineLabel{
+		{StartLine: 1, EndLine: 5, Label: store.LabelAI},    // lines 1-2 are computed human
+		{StartLine: 7, EndLine: 9, Label: store.LabelHuman}, // agrees
+		{StartLine: 9, EndLine: 9, Label: store.LabelAI},    // newest label wins
+		{StartLine: 20, EndLine: 20, Label: store.LabelHuman},
+	} {
+		l.ProjectPath, l.FilePath = projDir, absPath
+		if _, err := s.InsertLineLabel(l); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := ApplyAccuracy(context.Background(), s, report, Options{}); err != nil {
+		t.Fatalf("ApplyAccuracy: %v", err)
+	}
+
+	a := report.Accuracy
+	if a == nil || len(a.Files) != 1 || a.Files[0].FilePath != "calc.go" {
+		t.Fatalf("Accuracy = %+v, want calc.go", a)
+	}
+	if a.LabeledLines != 8 || a.Agreed != 5 || a.TruePos != 3 || a.FalsePos != 0 || a.FalseNeg != 3 || a.StaleLines != 1 {
+		t.Errorf("Accuracy = %+v, want 8 labeled, 5 agreed, TP 3, FP 0, FN 3, 1 stale", a)
+	}
+	if !almostEqual(a.Precision, 100, 0.1) || !almostEqual(a.Recall, 50, 0.1) {
+		t.Errorf("Precision = %.1f, Recall = %.1f, want 100 and 50", a.Precision, a.Recall)
+	}
+	if out := FormatProjectReport(report, Style{}); !strings.Contains(out, "Attribution Accuracy") {
+		t.Errorf("FormatProjectReport output missing accuracy section:\n%s", out)
+	}
+}
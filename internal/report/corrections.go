@@ -0,0 +1,24 @@
+package report
+
+import (
+	"time"
+
+	"github.com/anthropic/gap-map/internal/store"
+	"github.com/anthropic/gap-map/internal/survival"
+)
+
+// countCorrections sets fr's correction counts from the AI attributions
+// among attrs, the file's, that a human changed within window. Counts stay
+// zero when they cannot be read.
+func countCorrections(s *store.Store, fr *FileReport, attrs []store.AttributionWithWorkType, window time.Duration) {
+	corrected, err := survival.Corrections(s, attrs, window)
+	if err != nil {
+		return
+	}
+	for _, c := range corrected {
+		fr.CorrectionEvents++
+		if c {
+			fr.CorrectedEvents++
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package report
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+
+	"github.com/anthropic/gap-map/internal/coverage"
+	"github.com/anthropic/gap-map/internal/metrics"
+	"github.com/anthropic/gap-map/internal/store"
+)
+
+// CoverageSummary relates a project's changed lines to test coverage: is
+// AI-written code actually exercised by tests? Only lines the coverage
+// profile instruments are counted; comments and blank lines are neither
+// covered nor uncovered.
+type CoverageSummary struct {
+	Profile          string  `json:"profile"`
+	Files            int     `json:"files"` // report files found in the profile
+	CoveredLines     int     `json:"covered_lines"`
+	CoveredAILines   int     `json:"covered_ai_lines"`
+	UncoveredLines   int     `json:"uncovered_lines"`
+	UncoveredAILines int     `json:"uncovered_ai_lines"`
+	CoveredAIPct     float64 `json:"covered_ai_pct"`    // AI share of covered lines
+	UncoveredAIPct   float64 `json:"uncovered_ai_pct"`  // AI share of uncovered lines
+	AICoveredPct     float64 `json:"ai_covered_pct"`    // share of AI lines that are covered
+	HumanCoveredPct  float64 `json:"human_covered_pct"` // share of human lines that are covered
+}
+
+// FileCoverage is the coverage of one file's changed lines.
+type FileCoverage struct {
+	CoveredLines     int `json:"covered_lines"`
+	CoveredAILines   int `json:"covered_ai_lines"`
+	UncoveredLines   int `json:"uncovered_lines"`
+	UncoveredAILines int `json:"uncovered_ai_lines"`
+}
+
+// ApplyCoverage sets the coverage section of a report generated from s
+// (GenerateProjectFromStore and friends): each file's lines changed since
+// tracking began are attributed individually and split by whether the
+// profile marks them covered. Files the profile does not include get no
+// coverage and are left out of the summary.
+func ApplyCoverage(ctx context.Context, s *store.Store, r *ProjectReport, profile *coverage.Profile, opts Options) error {
+	sessionEvents, err := s.QueryWriteEditSessionEvents()
+	if err != nil {
+		return fmt.Errorf("query session events: %w", err)
+	}
+	claudeContentByFile := buildClaudeContentMap(s, sessionEvents)
+	match := opts.matchOptions(r.ProjectPath)
+
+	// Each worker writes only its own file, as in GenerateProjectFromStoreContext.
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < min(opts.workers(), len(r.Files)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				r.Files[i].Coverage = fileCoverage(ctx, s, opts, match, r.ProjectPath, r.Files[i].FilePath, claudeContentByFile, profile)
+			}
+		}()
+	}
+feed:
+	for i := range r.Files {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("apply coverage: %w", err)
+	}
+
+	summary := &CoverageSummary{Profile: profile.Path}
+	for _, fr := range r.Files {
+		if fr.Coverage == nil {
+			continue
+		}
+		summary.Files++
+		summary.CoveredLines += fr.Coverage.CoveredLines
+		summary.CoveredAILines += fr.Coverage.CoveredAILines
+		summary.UncoveredLines += fr.Coverage.UncoveredLines
+		summary.UncoveredAILines += fr.Coverage.UncoveredAILines
+	}
+	summary.CoveredAIPct = pct(summary.CoveredAILines, summary.CoveredLines)
+	summary.UncoveredAIPct = pct(summary.UncoveredAILines, summary.UncoveredLines)
+	summary.AICoveredPct = pct(summary.CoveredAILines, summary.CoveredAILines+summary.UncoveredAILines)
+	coveredHuman := summary.CoveredLines - summary.CoveredAILines
+	uncoveredHuman := summary.UncoveredLines - summary.UncoveredAILines
+	summary.HumanCoveredPct = pct(coveredHuman, coveredHuman+uncoveredHuman)
+	r.Coverage = summary
+	return nil
+}
+
+// fileCoverage attributes a file's changed lines and looks each up in the
+// profile. Returns nil if the profile does not include the file.
+func fileCoverage(ctx context.Context, s *store.Store, opts Options, match metrics.MatchOptions, projectPath, filePath string, claudeContentByFile map[string][]string, profile *coverage.Profile) *FileCoverage {
+	relPath := filePath
+	if rel, err := filepath.Rel(projectPath, resolveFilePath(projectPath, filePath)); err == nil {
+		relPath = rel
+	}
+	covered := profile.Lines(relPath)
+	if covered == nil {
+		return nil
+	}
+
+	numbers, lines, _, base := getChangedLines(ctx, s, projectPath, filePath, opts)
+	numbers, lines, _ = opts.Guard.CapLines(numbers, lines)
+	ai := metrics.ClassifyLines(lines, findClaudeContent(filePath, claudeContentByFile), base, match)
+
+	fc := &FileCoverage{}
+	for i, n := range numbers {
+		isCovered, instrumented := covered[n]
+		switch {
+		case !instrumented:
+		case isCovered:
+			fc.CoveredLines++
+			if ai[i] {
+				fc.CoveredAILines++
+			}
+		default:
+			fc.UncoveredLines++
+			if ai[i] {
+				fc.UncoveredAILines++
+			}
+		}
+	}
+	return fc
+}
+
+// pct returns n as a percentage of total, or 0 if total is 0.
+func pct(n, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(n) / float64(total) * 100.0
+}
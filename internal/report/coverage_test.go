@@ -0,0 +1,84 @@
+package report
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/anthropic/gap-map/internal/coverage"
+	"github.com/anthropic/gap-map/internal/metrics"
+)
+
+func TestApplyCoverage(t *testing.T) {
+	s, projDir, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	// Claude wrote Add, which the tests cover; a human wrote Sub, which
+	// they do not.
+	content := "package calc\n\nfunc Add(a, b int) int {\n\treturn a + b\n}\n\nfunc Sub(a, b int) int {\n\treturn a - b\n}\n"
+	writeFile(t, projDir, "calc.go", content)
+	absPath := filepath.Join(projDir, "calc.go")
+	insertSessionEvent(t, s, "s1", absPath,
+		makeWriteRawJSON(absPath, "func Add(a, b int) int {\n\treturn a + b\n}\n"), baseTime)
+	insertAttribution(t, s, "calc.go", projDir, "mostly_ai", "core_logic", baseTime, 9)
+
+	report, err := GenerateProject(t.Context(), s, metrics.DefaultScorer(), Filter{}, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	profile, err := coverage.Parse(strings.NewReader("mode: set\n" +
+		"example.com/calc/calc.go:3.24,5.2 1 1\n" +
+		"example.com/calc/calc.go:7.24,9.2 1 0\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ApplyCoverage(context.Background(), s, report, profile, Options{}); err != nil {
+		t.Fatalf("ApplyCoverage: %v", err)
+	}
+
+	c := report.Coverage
+	if c == nil || c.Files != 1 {
+		t.Fatalf("Coverage = %+v, want 1 file", c)
+	}
+	if c.CoveredLines != 3 || c.CoveredAILines != 3 || c.UncoveredLines != 3 || c.UncoveredAILines != 0 {
+		t.Errorf("Coverage = %+v, want 3/3 covered AI and 3/0 uncovered", c)
+	}
+	if !almostEqual(c.AICoveredPct, 100, 0.1) || !almostEqual(c.HumanCoveredPct, 0, 0.1) {
+		t.Errorf("AICoveredPct = %.1f, HumanCoveredPct = %.1f, want 100 and 0", c.AICoveredPct, c.HumanCoveredPct)
+	}
+	if report.Files[0].Coverage == nil {
+		t.Error("file coverage not set")
+	}
+	if out := FormatProjectReport(report, Style{}); !strings.Contains(out, "Test Coverage") {
+		t.Errorf("FormatProjectReport output missing coverage section:\n%s", out)
+	}
+}
+
+func TestParseDiffAdditionsNumbered(t *testing.T) {
+	diff := `diff --git a/f.go b/f.go
+--- a/f.go
++++ b/f.go
+@@ -1,4 +1,5 @@
+ package f
+-var a = 1
++var a = 2
++var b = 3
+
+ func F() {}
+@@ -20 +21,2 @@ func G() {
++	x()
++	y()
+`
+	numbers, lines := parseDiffAdditionsNumbered(diff)
+	wantNumbers := []int{2, 3, 21, 22}
+	wantLines := []string{"var a = 2", "var b = 3", "\tx()", "\ty()"}
+	if len(numbers) != len(wantNumbers) {
+		t.Fatalf("numbers = %v, want %v", numbers, wantNumbers)
+	}
+	for i := range wantNumbers {
+		if numbers[i] != wantNumbers[i] || lines[i] != wantLines[i] {
+			t.Errorf("addition %d = %d %q, want %d %q", i, numbers[i], lines[i], wantNumbers[i], wantLines[i])
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package report
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/anthropic/gap-map/internal/store"
+)
+
+// Explanation is one user prompt behind a file's AI edits: what the human
+// asked for when the AI wrote those lines.
+type Explanation struct {
+	PromptID string    `json:"prompt_id,omitempty"`
+	Prompt   string    `json:"prompt"` // redacted and truncated; "" if not recorded
+	Models   []string  `json:"models,omitempty"`
+	Edits    int       `json:"edits"` // AI Write/Edit calls on the file
+	Lines    int       `json:"lines"` // lines those calls changed
+	At       time.Time `json:"at"`    // first edit
+	AtLocal  string    `json:"at_local"`
+}
+
+// Explain sets fr.Explanations from the session events behind the file's
+// AI attributions, one per prompt in the order the edits were made. Edits
+// whose prompt was not recorded share an entry without one. loc is the
+// zone of AtLocal.
+func Explain(s *store.Store, fr *FileReport, loc *time.Location) error {
+	attrs, err := s.QueryAttributionsByFileWithWorkType(fr.FilePath)
+	if err != nil {
+		return fmt.Errorf("query attributions for file %q: %w", fr.FilePath, err)
+	}
+
+	byPrompt := make(map[string]*Explanation)
+	models := make(map[string]map[string]bool)
+	seen := make(map[int64]bool)
+	for _, attr := range attrs {
+		if !isAIAuthorship(attr.AuthorshipLevel) || attr.SessionEventID == nil || seen[*attr.SessionEventID] {
+			continue
+		}
+		seen[*attr.SessionEventID] = true
+		se, err := s.QuerySessionEventByID(*attr.SessionEventID)
+		if err != nil {
+			continue
+		}
+
+		key := se.PromptID
+		if key == "" {
+			key = se.Prompt
+		}
+		ex, ok := byPrompt[key]
+		if !ok {
+			ex = &Explanation{PromptID: se.PromptID, Prompt: se.Prompt, At: se.Timestamp}
+			byPrompt[key] = ex
+			models[key] = make(map[string]bool)
+		}
+		ex.Edits++
+		ex.Lines += se.LinesChanged
+		if se.Timestamp.Before(ex.At) {
+			ex.At = se.Timestamp
+		}
+		if ex.Prompt == "" {
+			ex.Prompt = se.Prompt
+		}
+		if se.Model != "" && !models[key][se.Model] {
+			models[key][se.Model] = true
+			ex.Models = append(ex.Models, se.Model)
+		}
+	}
+
+	fr.Explanations = make([]Explanation, 0, len(byPrompt))
+	for _, ex := range byPrompt {
+		sort.Strings(ex.Models)
+		ex.AtLocal = ex.At.In(loc).Format(time.RFC3339)
+		fr.Explanations = append(fr.Explanations, *ex)
+	}
+	sort.Slice(fr.Explanations, func(i, j int) bool {
+		return fr.Explanations[i].At.Before(fr.Explanations[j].At)
+	})
+	return nil
+}
+
+// FormatExplanations formats the prompts behind a file's AI edits, with
+// times shown in loc.
+func FormatExplanations(exs []Explanation, loc *time.Location, st Style) string {
+	ansi := st.palette()
+	var b strings.Builder
+
+	b.WriteString("\n" + ansi.bold + "Why (prompts behind the AI edits)" + ansi.reset + "\n")
+	b.WriteString(strings.Repeat("-", 60) + "\n")
+	if len(exs) == 0 {
+		b.WriteString("No AI edits linked to a session event.\n")
+		return b.String()
+	}
+	for _, ex := range exs {
+		prompt := ex.Prompt
+		if prompt == "" {
+			prompt = "(prompt not recorded)"
+		}
+		detail := fmt.Sprintf("%d edits, %d lines", ex.Edits, ex.Lines)
+		if ex.Edits == 1 {
+			detail = fmt.Sprintf("1 edit, %d lines", ex.Lines)
+		}
+		if len(ex.Models) > 0 {
+			detail += ", " + strings.Join(ex.Models, ", ")
+		}
+		b.WriteString(fmt.Sprintf("%s  %s\n", ex.At.In(loc).Format("2006-01-02 15:04"), detail))
+		b.WriteString(fmt.Sprintf("  %q\n", prompt))
+	}
+	return b.String()
+}
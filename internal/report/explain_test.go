@@ -0,0 +1,77 @@
+package report
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anthropic/gap-map/internal/store"
+)
+
+func TestExplain(t *testing.T) {
+	s, projDir, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	events := []struct {
+		ctx   store.SessionEventContext
+		lines int
+		at    time.Time
+	}{
+		{store.SessionEventContext{Model: "claude-opus-4-1", PromptID: "u1", Prompt: "add retries"}, 10, baseTime.Add(time.Minute)},
+		{store.SessionEventContext{Model: "claude-opus-4-1", PromptID: "u1", Prompt: "add retries"}, 4, baseTime},
+		{store.SessionEventContext{PromptID: "u2", Prompt: "now log each retry"}, 3, baseTime.Add(time.Hour)},
+		{store.SessionEventContext{}, 2, baseTime.Add(-time.Hour)},
+	}
+	filePath := projDir + "/client.go"
+	for i, e := range events {
+		raw := fmt.Sprintf(`{"uuid":"e%d"}`, i)
+		if err := s.InsertSessionEventWithContext("sess1", "tool_use", "Edit", filePath, "", e.at, raw, e.lines, e.ctx); err != nil {
+			t.Fatal(err)
+		}
+		seID := int64(i + 1)
+		id, err := s.InsertAttribution(store.AttributionRecord{
+			FilePath: filePath, ProjectPath: projDir, SessionEventID: &seID,
+			AuthorshipLevel: "mostly_ai", Timestamp: e.at,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := s.UpdateAttributionWorkType(id, "core_logic"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Human attributions are not explained.
+	insertAttribution(t, s, filePath, projDir, "mostly_human", "core_logic", baseTime, 5)
+
+	fr := &FileReport{FilePath: filePath}
+	if err := Explain(s, fr, time.UTC); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Explanation{
+		{Prompt: "", Edits: 1, Lines: 2, At: baseTime.Add(-time.Hour)},
+		{PromptID: "u1", Prompt: "add retries", Models: []string{"claude-opus-4-1"}, Edits: 2, Lines: 14, At: baseTime},
+		{PromptID: "u2", Prompt: "now log each retry", Edits: 1, Lines: 3, At: baseTime.Add(time.Hour)},
+	}
+	if len(fr.Explanations) != len(want) {
+		t.Fatalf("Explanations = %+v, want %d entries", fr.Explanations, len(want))
+	}
+	for i, w := range want {
+		got := fr.Explanations[i]
+		if got.PromptID != w.PromptID || got.Prompt != w.Prompt || got.Edits != w.Edits || got.Lines != w.Lines ||
+			!got.At.Equal(w.At) || strings.Join(got.Models, ",") != strings.Join(w.Models, ",") {
+			t.Errorf("Explanations[%d] = %+v, want %+v", i, got, w)
+		}
+		if got.AtLocal != w.At.Format(time.RFC3339) {
+			t.Errorf("Explanations[%d].AtLocal = %q", i, got.AtLocal)
+		}
+	}
+
+	out := FormatExplanations(fr.Explanations, time.UTC, Style{})
+	for _, s := range []string{"(prompt not recorded)", `"add retries"`, "2 edits, 14 lines, claude-opus-4-1", "2026-02-09 13:00"} {
+		if !strings.Contains(out, s) {
+			t.Errorf("FormatExplanations output missing %q:\n%s", s, out)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package report
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+
+	"github.com/anthropic/gap-map/internal/store"
+	"github.com/anthropic/gap-map/internal/worktype"
+)
+
+// Orders of the files in a filtered report.
+const (
+	SortAIPct  = "ai_pct" // meaningful AI% descending (the default)
+	SortLines  = "lines"  // changed lines descending
+	SortEvents = "events" // attributed events descending
+)
+
+// Filter narrows a project report. WorkType and PathGlob select the files
+// the report covers, totals included; MinAIPct, Sort and Top only shape the
+// Files list. The zero value keeps every file, by AI% descending.
+type Filter struct {
+	WorkType string  // only files of this work type
+	PathGlob string  // only files matching this glob, relative to the project root (see MatchGlob)
+	MinAIPct float64 // list only files with at least this meaningful AI%
+	Sort     string  // SortAIPct, SortLines or SortEvents
+	Top      int     // list only the first Top files; 0 lists all
+
+	// Project is the path of the project reported; empty means the one
+	// DiscoverProjectPath finds.
+	Project string
+
+	// Window restricts the report to the attributions and AI edits made in
+	// it, with changed lines diffed from the commit nearest before its
+	// start.
+	Window Window
+}
+
+// Validate checks the filter's values.
+func (f Filter) Validate() error {
+	switch f.Sort {
+	case "", SortAIPct, SortLines, SortEvents:
+	default:
+		return fmt.Errorf("unknown sort %q (want %s, %s or %s)", f.Sort, SortAIPct, SortLines, SortEvents)
+	}
+	if f.WorkType != "" {
+		if _, ok := worktype.WorkTypeTier[worktype.WorkType(f.WorkType)]; !ok {
+			return fmt.Errorf("unknown work type %q", f.WorkType)
+		}
+	}
+	if f.Top < 0 {
+		return fmt.Errorf("top must not be negative")
+	}
+	if f.MinAIPct < 0 || f.MinAIPct > 100 {
+		return fmt.Errorf("min AI%% must be between 0 and 100")
+	}
+	return f.Window.Validate()
+}
+
+// projectPath returns the project the report covers.
+func (f Filter) projectPath(s *store.Store) (string, error) {
+	if f.Project != "" {
+		return f.Project, nil
+	}
+	return DiscoverProjectPath(s)
+}
+
+// matchesPath reports whether a file is selected by PathGlob.
+func (f Filter) matchesPath(projectPath, filePath string) bool {
+	return f.PathGlob == "" || MatchGlob(f.PathGlob, projectRelPath(projectPath, filePath))
+}
+
+// selects reports whether an attributed file is selected by WorkType and
+// PathGlob.
+func (f Filter) selects(projectPath string, fr *FileReport) bool {
+	return (f.WorkType == "" || fr.WorkType == f.WorkType) && f.matchesPath(projectPath, fr.FilePath)
+}
+
+// shape sorts the report's files and trims them to MinAIPct and Top.
+func (f Filter) shape(r *ProjectReport) {
+	if f.MinAIPct > 0 {
+		kept := r.Files[:0]
+		for _, fr := range r.Files {
+			if fr.MeaningfulAIPct >= f.MinAIPct {
+				kept = append(kept, fr)
+			}
+		}
+		r.Files = kept
+	}
+
+	key := func(fr FileReport) float64 { return fr.MeaningfulAIPct }
+	switch f.Sort {
+	case SortLines:
+		key = func(fr FileReport) float64 { return float64(fr.TotalLines) }
+	case SortEvents:
+		key = func(fr FileReport) float64 { return float64(fr.TotalEvents) }
+	}
+	sort.SliceStable(r.Files, func(i, j int) bool {
+		a, b := r.Files[i], r.Files[j]
+		if key(a) != key(b) {
+			return key(a) > key(b)
+		}
+		return a.FilePath < b.FilePath
+	})
+
+	if f.Top > 0 && len(r.Files) > f.Top {
+		r.Files = r.Files[:f.Top]
+	}
+}
+
+// projectRelPath returns filePath relative to the project root,
+// slash-separated.
+func projectRelPath(projectPath, filePath string) string {
+	rel := filePath
+	if filepath.IsAbs(filePath) {
+		if r, err := filepath.Rel(projectPath, filePath); err == nil {
+			rel = r
+		}
+	}
+	return filepath.ToSlash(rel)
+}
@@ -0,0 +1,129 @@
+package report
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/anthropic/gap-map/internal/metrics"
+)
+
+func TestFilterShape(t *testing.T) {
+	files := func() []FileReport {
+		return []FileReport{
+			{FilePath: "a.go", MeaningfulAIPct: 90, TotalLines: 5, TotalEvents: 1},
+			{FilePath: "b.go", MeaningfulAIPct: 10, TotalLines: 50, TotalEvents: 2},
+			{FilePath: "c.go", MeaningfulAIPct: 50, TotalLines: 20, TotalEvents: 9},
+		}
+	}
+	cases := []struct {
+		name string
+		f    Filter
+		want []string
+	}{
+		{"default", Filter{}, []string{"a.go", "c.go", "b.go"}},
+		{"lines", Filter{Sort: SortLines}, []string{"b.go", "c.go", "a.go"}},
+		{"events top", Filter{Sort: SortEvents, Top: 2}, []string{"c.go", "b.go"}},
+		{"min ai", Filter{MinAIPct: 50}, []string{"a.go", "c.go"}},
+	}
+	for _, c := range cases {
+		r := &ProjectReport{Files: files()}
+		c.f.shape(r)
+		var got []string
+		for _, fr := range r.Files {
+			got = append(got, fr.FilePath)
+		}
+		if len(got) != len(c.want) {
+			t.Errorf("%s: files = %v, want %v", c.name, got, c.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != c.want[i] {
+				t.Errorf("%s: files = %v, want %v", c.name, got, c.want)
+				break
+			}
+		}
+	}
+}
+
+func TestFilterValidate(t *testing.T) {
+	for _, f := range []Filter{{Sort: "size"}, {WorkType: "poetry"}, {Top: -1}, {MinAIPct: 101}} {
+		if err := f.Validate(); err == nil {
+			t.Errorf("Validate(%+v) = nil, want error", f)
+		}
+	}
+	if err := (Filter{Sort: SortLines, WorkType: "test_scaffolding", Top: 5, MinAIPct: 20}).Validate(); err != nil {
+		t.Errorf("Validate: %v", err)
+	}
+}
+
+func TestGenerateProjectFilter(t *testing.T) {
+	s, projDir, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	writeFile(t, projDir, "cmd/main.go", "package main\n\nfunc main() {}\n")
+	writeFile(t, projDir, "cmd/main_test.go", "package main\n\nfunc TestMain() {}\n")
+	writeFile(t, projDir, "lib/lib.go", "package lib\n\nvar X = 1\nvar Y = 2\n")
+	insertAttribution(t, s, "cmd/main.go", projDir, "mostly_ai", "core_logic", baseTime, 1)
+	insertAttribution(t, s, "cmd/main_test.go", projDir, "mostly_human", "test_scaffolding", baseTime, 1)
+	insertAttribution(t, s, "lib/lib.go", projDir, "mostly_human", "core_logic", baseTime, 1)
+	insertSessionEvent(t, s, "s1", filepath.Join(projDir, "cmd/main.go"),
+		makeWriteRawJSON(filepath.Join(projDir, "cmd/main.go"), "package main\n\nfunc main() {}\n"), baseTime)
+
+	// Selection narrows the totals too.
+	r, err := GenerateProject(t.Context(), s, metrics.DefaultScorer(), Filter{WorkType: "core_logic", PathGlob: "cmd/**"}, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.TotalFiles != 1 || len(r.Files) != 1 || r.Files[0].FilePath != "cmd/main.go" {
+		t.Fatalf("Files = %+v, want only cmd/main.go", r.Files)
+	}
+	if r.RawAIPct != 100 {
+		t.Errorf("RawAIPct = %.1f, want 100", r.RawAIPct)
+	}
+
+	// Shaping keeps the totals of every file.
+	r, err = GenerateProject(t.Context(), s, metrics.DefaultScorer(), Filter{Sort: SortLines, Top: 1}, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.TotalFiles != 3 || len(r.Files) != 1 || r.Files[0].FilePath != "lib/lib.go" {
+		t.Errorf("TotalFiles = %d, Files = %+v, want 3 and lib/lib.go", r.TotalFiles, r.Files)
+	}
+
+	if _, err := GenerateProject(t.Context(), s, metrics.DefaultScorer(), Filter{Sort: "size"}, Options{}); err == nil {
+		t.Error("expected an error for an unknown sort")
+	}
+}
+
+func TestGenerateProjectFilter_Project(t *testing.T) {
+	s, projDir, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	// The other project sorts first, so it is the one discovered.
+	other := filepath.Join(filepath.Dir(projDir), "aaa")
+	if err := os.MkdirAll(other, 0755); err != nil {
+		t.Fatal(err)
+	}
+	gitInit(t, other)
+	writeFile(t, other, "other.go", "package other\n")
+	writeFile(t, projDir, "main.go", "package main\n")
+	insertAttribution(t, s, "other.go", other, "mostly_human", "core_logic", baseTime, 1)
+	insertAttribution(t, s, "main.go", projDir, "mostly_human", "core_logic", baseTime, 1)
+
+	r, err := GenerateProject(t.Context(), s, metrics.DefaultScorer(), Filter{}, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.ProjectPath != other {
+		t.Fatalf("ProjectPath = %q, want the discovered %q", r.ProjectPath, other)
+	}
+
+	r, err = GenerateProject(t.Context(), s, metrics.DefaultScorer(), Filter{Project: projDir}, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.ProjectPath != projDir || len(r.Files) != 1 || r.Files[0].FilePath != "main.go" {
+		t.Errorf("ProjectPath = %q, Files = %+v, want %q and main.go", r.ProjectPath, r.Files, projDir)
+	}
+}
@@ -4,34 +4,79 @@ import (
 	"encoding/json"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/anthropic/gap-map/internal/ipc"
+	"github.com/anthropic/gap-map/internal/metrics"
+	"github.com/anthropic/gap-map/internal/numfmt"
 )
 
-// ANSI escape codes for terminal formatting.
-const (
-	bold  = "\033[1m"
-	reset = "\033[0m"
-)
+// Style is how formatted reports are written. The zero value writes plain
+// text with numbers in the C locale, so they stay parseable; JSON output is
+// unaffected.
+type Style struct {
+	Color   bool          // ANSI bold and color, for a terminal
+	Numbers numfmt.Locale // the locale numbers are written in
+}
+
+// palette holds the ANSI escape codes a Style writes with, all empty
+// without color.
+type palette struct {
+	bold, green, yellow, red, reset string
+}
+
+func (st Style) palette() palette {
+	if !st.Color {
+		return palette{}
+	}
+	return palette{bold: "\033[1m", green: "\033[32m", yellow: "\033[33m", red: "\033[31m", reset: "\033[0m"}
+}
 
 // FormatProjectReport formats a ProjectReport as a terminal-friendly string.
-func FormatProjectReport(r *ProjectReport) string {
+func FormatProjectReport(r *ProjectReport, st Style) string {
+	ansi := st.palette()
 	var b strings.Builder
 
 	// Header.
-	b.WriteString(bold + "Gap Map - Attribution Report" + reset + "\n")
+	b.WriteString(ansi.bold + "Gap Map - Attribution Report" + ansi.reset + "\n")
 	b.WriteString(strings.Repeat("=", 40) + "\n\n")
 
 	// Headline metric.
 	b.WriteString(fmt.Sprintf("Project: %s\n", r.ProjectPath))
-	b.WriteString(fmt.Sprintf("Meaningful AI: %s%.1f%%%s\n",
-		bold, r.MeaningfulAIPct, reset))
-	b.WriteString(fmt.Sprintf("Raw AI:        %.1f%%\n", r.RawAIPct))
-	b.WriteString(fmt.Sprintf("Total files:   %d\n", r.TotalFiles))
-	b.WriteString(fmt.Sprintf("Total lines:   %d (%d AI)\n\n", r.TotalLines, r.AILines))
+	if r.Source == SourceGit {
+		b.WriteString("Source:  git commit metadata (no daemon data)\n")
+	}
+	if r.Window != nil {
+		b.WriteString(fmt.Sprintf("Window:  %s\n", r.Window))
+	}
+	b.WriteString(fmt.Sprintf("Meaningful AI: %s%s%s\n",
+		ansi.bold, st.percent(r.MeaningfulAIPct), ansi.reset))
+	b.WriteString(fmt.Sprintf("Raw AI:        %s\n", st.percent(r.RawAIPct)))
+	if r.ComplexityLines > 0 {
+		b.WriteString(fmt.Sprintf("Complexity AI: %s\n", st.percent(r.ComplexityAIPct)))
+	}
+	if r.Scorer != "" && r.Scorer != metrics.ScorerWeighted {
+		b.WriteString(fmt.Sprintf("Scorer:        %s\n", r.Scorer))
+	}
+	b.WriteString(fmt.Sprintf("Total files:   %s\n", st.num(r.TotalFiles)))
+	b.WriteString(fmt.Sprintf("Total lines:   %s (%s AI)\n", st.num(r.TotalLines), st.num(r.AILines)))
+	if r.RevisedLines > 0 {
+		b.WriteString(fmt.Sprintf("Revised lines: %s AI lines edited by a human\n", st.num(r.RevisedLines)))
+	}
+	if r.CorrectionEvents > 0 {
+		b.WriteString(fmt.Sprintf("Corrected:     %s of AI edits changed by a human within %s days (%s of %s)\n",
+			st.percent(r.CorrectionRate), st.num(r.CorrectionWindowDays), st.num(r.CorrectedEvents), st.num(r.CorrectionEvents)))
+	}
+	if r.HumanLines > 0 {
+		b.WriteString(fmt.Sprintf("Human lines:   %s seen in snapshots (%s unaccounted for)\n", st.num(r.HumanLines), st.num(r.TotalLines-r.AILines-r.HumanLines)))
+	}
+	if r.DeletedLines > 0 {
+		b.WriteString(fmt.Sprintf("Deleted lines: %s (%s AI)\n", st.num(r.DeletedLines), st.num(r.AIDeletedLines)))
+	}
+	b.WriteString("\n")
 
 	// Spectrum breakdown table (3 levels).
-	b.WriteString(bold + "Authorship Spectrum" + reset + "\n")
+	b.WriteString(ansi.bold + "Authorship Spectrum" + ansi.reset + "\n")
 	b.WriteString(strings.Repeat("-", 35) + "\n")
 	b.WriteString(fmt.Sprintf("%-20s %12s\n", "Level", "Files"))
 	b.WriteString(strings.Repeat("-", 35) + "\n")
@@ -47,38 +92,99 @@ func FormatProjectReport(r *ProjectReport) string {
 	}
 	for _, level := range spectrumLevels {
 		count := r.ByAuthorship[level]
-		pct := 0.0
+		share := 0.0
 		if totalFiles > 0 {
-			pct = float64(count) / float64(totalFiles) * 100.0
+			share = float64(count) / float64(totalFiles) * 100.0
 		}
-		b.WriteString(fmt.Sprintf("%-20s %4d (%4.1f%%)\n", level, count, pct))
+		b.WriteString(fmt.Sprintf("%-20s %4s (%5s)\n", level, st.num(count), st.percent(share)))
 	}
 	b.WriteString("\n")
 
 	// Work-type distribution table.
-	b.WriteString(bold + "Work Type Distribution" + reset + "\n")
+	b.WriteString(ansi.bold + "Work Type Distribution" + ansi.reset + "\n")
 	b.WriteString(strings.Repeat("-", 70) + "\n")
 	b.WriteString(fmt.Sprintf("%-18s %-8s %5s %8s %6s %6s\n", "Work Type", "Tier", "Files", "Lines", "AI%", "Weight"))
 	b.WriteString(strings.Repeat("-", 70) + "\n")
 
 	// Sort work types for stable output.
-	wtOrder := []string{"architecture", "core_logic", "bug_fix", "edge_case", "boilerplate", "test_scaffolding"}
+	wtOrder := []string{"architecture", "core_logic", "bug_fix", "edge_case", "database_migration", "boilerplate", "test_scaffolding", "documentation"}
 	for _, wt := range wtOrder {
 		summary, ok := r.ByWorkType[wt]
 		if !ok {
 			continue
 		}
-		b.WriteString(fmt.Sprintf("%-18s %-8s %5d %8d %5.1f%% %6.1f\n",
-			wt, summary.Tier, summary.Files,
-			summary.TotalLines,
-			summary.AIPct,
-			summary.Weight))
+		b.WriteString(fmt.Sprintf("%-18s %-8s %5s %8s %6s %6s\n",
+			wt, summary.Tier, st.num(summary.Files),
+			st.num(summary.TotalLines),
+			st.percent(summary.AIPct),
+			st.dec(summary.Weight, 1)))
 	}
 	b.WriteString("\n")
 
+	// Per-package breakdown for monorepos.
+	if len(r.ByPackage) > 0 {
+		b.WriteString(ansi.bold + "Packages" + ansi.reset + "\n")
+		b.WriteString(strings.Repeat("-", 50) + "\n")
+		b.WriteString(fmt.Sprintf("%-24s %5s %8s %6s\n", "Package", "Files", "Lines", "AI%"))
+		b.WriteString(strings.Repeat("-", 50) + "\n")
+		for _, name := range SortedPackages(r.ByPackage) {
+			summary := r.ByPackage[name]
+			b.WriteString(fmt.Sprintf("%-24s %5s %8s %6s\n", name, st.num(summary.Files), st.num(summary.TotalLines), st.percent(summary.AIPct)))
+		}
+		b.WriteString("\n")
+	}
+
+	// AI work by model, when any session recorded one.
+	if len(r.ByModel) > 0 && (len(r.ByModel) > 1 || r.ByModel[UnknownModel].AIEvents == 0) {
+		b.WriteString(ansi.bold + "Models" + ansi.reset + "\n")
+		b.WriteString(strings.Repeat("-", 60) + "\n")
+		b.WriteString(fmt.Sprintf("%-32s %5s %9s %8s\n", "Model", "Files", "AI Events", "Lines"))
+		b.WriteString(strings.Repeat("-", 60) + "\n")
+		for _, m := range SortedModels(r.ByModel) {
+			summary := r.ByModel[m]
+			b.WriteString(fmt.Sprintf("%-32s %5s %9s %8s\n", m, st.num(summary.Files), st.num(summary.AIEvents), st.num(summary.LinesWritten)))
+		}
+		b.WriteString("\n")
+	}
+
+	// Changed lines split by test coverage.
+	if c := r.Coverage; c != nil {
+		b.WriteString(ansi.bold + "Test Coverage" + ansi.reset + fmt.Sprintf(" (%s, %s files)\n", c.Profile, st.num(c.Files)))
+		b.WriteString(strings.Repeat("-", 35) + "\n")
+		b.WriteString(fmt.Sprintf("%-12s %8s %6s %6s\n", "Lines", "Total", "AI", "AI%"))
+		b.WriteString(strings.Repeat("-", 35) + "\n")
+		b.WriteString(fmt.Sprintf("%-12s %8s %6s %6s\n", "covered", st.num(c.CoveredLines), st.num(c.CoveredAILines), st.percent(c.CoveredAIPct)))
+		b.WriteString(fmt.Sprintf("%-12s %8s %6s %6s\n", "uncovered", st.num(c.UncoveredLines), st.num(c.UncoveredAILines), st.percent(c.UncoveredAIPct)))
+		b.WriteString(fmt.Sprintf("AI lines covered:    %s\n", st.percent(c.AICoveredPct)))
+		b.WriteString(fmt.Sprintf("Human lines covered: %s\n", st.percent(c.HumanCoveredPct)))
+		b.WriteString("\n")
+	}
+
+	// Computed attribution checked against labeled lines.
+	if a := r.Accuracy; a != nil {
+		b.WriteString(ansi.bold + "Attribution Accuracy" + ansi.reset + fmt.Sprintf(" (%s labeled lines, AI is positive)\n", st.num(a.LabeledLines)))
+		b.WriteString(strings.Repeat("-", 60) + "\n")
+		b.WriteString(fmt.Sprintf("%-35s %7s %6s %4s %4s %4s\n", "File", "Labeled", "Agree", "TP", "FP", "FN"))
+		b.WriteString(strings.Repeat("-", 60) + "\n")
+		for _, f := range a.Files {
+			name := f.FilePath
+			if len(name) > 34 {
+				name = "..." + name[len(name)-31:]
+			}
+			b.WriteString(fmt.Sprintf("%-35s %7s %6s %4s %4s %4s\n", name, st.num(f.LabeledLines), st.num(f.Agreed), st.num(f.TruePos), st.num(f.FalsePos), st.num(f.FalseNeg)))
+		}
+		b.WriteString(fmt.Sprintf("Agreement: %s\n", st.percent(a.AgreementPct)))
+		b.WriteString(fmt.Sprintf("Precision: %s\n", st.percent(a.Precision)))
+		b.WriteString(fmt.Sprintf("Recall:    %s\n", st.percent(a.Recall)))
+		if a.StaleLines > 0 {
+			b.WriteString(fmt.Sprintf("%s labeled lines are past the end of their file; relabel them\n", st.num(a.StaleLines)))
+		}
+		b.WriteString("\n")
+	}
+
 	// Top files sorted by AI%.
 	if len(r.Files) > 0 {
-		b.WriteString(bold + "Files by AI %" + reset + "\n")
+		b.WriteString(ansi.bold + "Files by AI %" + ansi.reset + "\n")
 		b.WriteString(strings.Repeat("-", 80) + "\n")
 		b.WriteString(fmt.Sprintf("%-35s %-16s %6s %7s %8s\n", "File", "Work Type", "AI%", "Lines", "Level"))
 		b.WriteString(strings.Repeat("-", 80) + "\n")
@@ -92,36 +198,82 @@ func FormatProjectReport(r *ProjectReport) string {
 			if len(name) > 34 {
 				name = "..." + name[len(name)-31:]
 			}
-			b.WriteString(fmt.Sprintf("%-35s %-16s %5.1f%% %7d %8s\n",
+			b.WriteString(fmt.Sprintf("%-35s %-16s %6s %7s %8s\n",
 				name, f.WorkType,
-				f.MeaningfulAIPct,
-				f.TotalLines, f.AuthorshipLevel))
+				st.percent(f.MeaningfulAIPct),
+				st.num(f.TotalLines), f.AuthorshipLevel))
 		}
 		if len(r.Files) > 20 {
-			b.WriteString(fmt.Sprintf("... and %d more files\n", len(r.Files)-20))
+			b.WriteString(fmt.Sprintf("... and %s more files\n", st.num(len(r.Files)-20)))
 		}
 	}
 
+	// Files the size and binary guards kept out or capped.
+	if len(r.Excluded) > 0 {
+		b.WriteString("\n" + ansi.bold + "Excluded Files" + ansi.reset + "\n")
+		b.WriteString(strings.Repeat("-", 60) + "\n")
+		for _, ex := range r.Excluded {
+			b.WriteString(fmt.Sprintf("%-35s %s\n", ex.FilePath, ex.Describe()))
+		}
+	}
+
+	// Attributed files that have since been deleted.
+	if len(r.Removed) > 0 {
+		b.WriteString("\n" + ansi.bold + "Removed Files" + ansi.reset + "\n")
+		b.WriteString(strings.Repeat("-", 70) + "\n")
+		b.WriteString(fmt.Sprintf("%-35s %8s %9s  %s\n", "File", "AI Lines", "AI Events", "Last Attributed"))
+		b.WriteString(strings.Repeat("-", 70) + "\n")
+		for _, rm := range r.Removed {
+			name := rm.FilePath
+			if len(name) > 34 {
+				name = "..." + name[len(name)-31:]
+			}
+			b.WriteString(fmt.Sprintf("%-35s %8s %9s  %s\n", name, st.num(rm.AILines), st.num(rm.AIEvents), rm.LastAttributed.Format("2006-01-02")))
+		}
+	}
+
+	// Files ranked for review, when ApplyReview ran.
+	if r.Review != nil {
+		b.WriteString(formatReview(r.Review, st))
+	}
+
 	return b.String()
 }
 
 // FormatFileReport formats a single FileReport as a terminal-friendly string.
-func FormatFileReport(r *FileReport) string {
+func FormatFileReport(r *FileReport, st Style) string {
+	ansi := st.palette()
 	var b strings.Builder
 
-	b.WriteString(bold + "Gap Map - File Report" + reset + "\n")
+	b.WriteString(ansi.bold + "Gap Map - File Report" + ansi.reset + "\n")
 	b.WriteString(strings.Repeat("=", 40) + "\n\n")
 
 	b.WriteString(fmt.Sprintf("File:      %s\n", r.FilePath))
 	b.WriteString(fmt.Sprintf("Work Type: %s\n", r.WorkType))
-	b.WriteString(fmt.Sprintf("AI %%:      %s%.1f%%%s\n",
-		bold, r.MeaningfulAIPct, reset))
-	b.WriteString(fmt.Sprintf("Raw AI %%:  %.1f%%\n", r.RawAIPct))
-	b.WriteString(fmt.Sprintf("Lines:     %d total, %d AI\n", r.TotalLines, r.AILines))
+	if r.Window != nil {
+		b.WriteString(fmt.Sprintf("Window:    %s\n", r.Window))
+	}
+	b.WriteString(fmt.Sprintf("AI %%:      %s%s%s\n",
+		ansi.bold, st.percent(r.MeaningfulAIPct), ansi.reset))
+	b.WriteString(fmt.Sprintf("Raw AI %%:  %s\n", st.percent(r.RawAIPct)))
+	if r.ComplexityLines > 0 {
+		b.WriteString(fmt.Sprintf("Complex %%: %s (weighted by code complexity)\n", st.percent(r.ComplexityAIPct)))
+	}
+	b.WriteString(fmt.Sprintf("Lines:     %s total, %s AI", st.num(r.TotalLines), st.num(r.AILines)))
+	if r.RevisedLines > 0 {
+		b.WriteString(fmt.Sprintf(" (%s revised)", st.num(r.RevisedLines)))
+	}
+	if r.HumanLines > 0 {
+		b.WriteString(fmt.Sprintf(", %s human seen in snapshots", st.num(r.HumanLines)))
+	}
+	b.WriteString("\n")
+	if r.DeletedLines > 0 {
+		b.WriteString(fmt.Sprintf("Deleted:   %s total, %s AI\n", st.num(r.DeletedLines), st.num(r.AIDeletedLines)))
+	}
 	b.WriteString(fmt.Sprintf("Level:     %s\n", r.AuthorshipLevel))
-	b.WriteString(fmt.Sprintf("Events:    %d total, %d AI\n\n", r.TotalEvents, r.AIEventCount))
+	b.WriteString(fmt.Sprintf("Events:    %s total, %s AI\n\n", st.num(r.TotalEvents), st.num(r.AIEventCount)))
 
-	b.WriteString(bold + "Authorship Breakdown" + reset + "\n")
+	b.WriteString(ansi.bold + "Authorship Breakdown" + ansi.reset + "\n")
 	b.WriteString(strings.Repeat("-", 40) + "\n")
 	b.WriteString(fmt.Sprintf("%-20s %6s\n", "Level", "Count"))
 	b.WriteString(strings.Repeat("-", 40) + "\n")
@@ -134,7 +286,7 @@ func FormatFileReport(r *FileReport) string {
 	for _, level := range levels {
 		count := r.AuthorshipCounts[level]
 		if count > 0 {
-			b.WriteString(fmt.Sprintf("%-20s %6d\n", level, count))
+			b.WriteString(fmt.Sprintf("%-20s %6s\n", level, st.num(count)))
 		}
 	}
 
@@ -142,10 +294,11 @@ func FormatFileReport(r *FileReport) string {
 }
 
 // FormatStatus formats daemon StatusData as a terminal-friendly table.
-func FormatStatus(status *ipc.StatusData) string {
+func FormatStatus(status *ipc.StatusData, st Style) string {
+	ansi := st.palette()
 	var b strings.Builder
 
-	b.WriteString(bold + "Gap Map - Daemon Status" + reset + "\n")
+	b.WriteString(ansi.bold + "Gap Map - Daemon Status" + ansi.reset + "\n")
 	b.WriteString(strings.Repeat("=", 40) + "\n\n")
 
 	b.WriteString(fmt.Sprintf("%-20s %s\n", "Uptime:", status.Uptime))
@@ -153,19 +306,121 @@ func FormatStatus(status *ipc.StatusData) string {
 	b.WriteString(fmt.Sprintf("%-20s %d\n", "File Events:", status.FileEventsCount))
 	b.WriteString(fmt.Sprintf("%-20s %d\n", "Session Events:", status.SessionEventsCount))
 	b.WriteString(fmt.Sprintf("%-20s %d\n", "Git Commits:", status.GitCommitsCount))
+	b.WriteString(fmt.Sprintf("%-20s %d\n", "Attribution Backlog:", status.AttributionBacklog))
+	if status.Crashes > 0 {
+		b.WriteString(fmt.Sprintf("%-20s %d, last %s (%s)\n", "Crashes:", status.Crashes, ago(status.LastCrashAt), status.LastCrash))
+	}
+	b.WriteString(fmt.Sprintf("%-20s %s\n", "Last Maintenance:", ago(status.LastMaintenanceAt)))
 
-	if len(status.WatchedPaths) > 0 {
-		b.WriteString(fmt.Sprintf("\n%sWatched Paths:%s\n", bold, reset))
+	switch {
+	case len(status.WatchPathStats) > 0:
+		b.WriteString(fmt.Sprintf("\n%sWatched Paths:%s %d directories\n", ansi.bold, ansi.reset, status.WatchedDirs))
+		if status.PolledDirs > 0 {
+			b.WriteString(fmt.Sprintf("  %d directories polled every %s\n", status.PolledDirs, status.PollInterval))
+		}
+		switch {
+		case status.WatchLimitReached && status.PolledDirs > 0:
+			b.WriteString("  warning: file watch limit reached; directories past it are polled\n")
+		case status.WatchLimitReached:
+			b.WriteString(fmt.Sprintf("  warning: file watch limit reached; changes in %d directories are missed (set watch_mode to auto)\n", status.UnwatchedDirs))
+		case status.UnwatchedDirs > 0:
+			b.WriteString(fmt.Sprintf("  %d directories could not be watched; see \"gapmap logs\"\n", status.UnwatchedDirs))
+		}
+		if status.EventOverflows > 0 {
+			b.WriteString(fmt.Sprintf("  event queue overflowed %d time(s); some file events were dropped\n", status.EventOverflows))
+		}
+		for _, wp := range status.WatchPathStats {
+			disabled := ""
+			if wp.Disabled {
+				disabled = " (disabled)"
+			}
+			b.WriteString(fmt.Sprintf("  %s%s\n    %d events, last %s\n", wp.Path, disabled, wp.Events, ago(wp.LastEventAt)))
+		}
+	case len(status.WatchedPaths) > 0:
+		b.WriteString(fmt.Sprintf("\n%sWatched Paths:%s\n", ansi.bold, ansi.reset))
 		for _, p := range status.WatchedPaths {
 			b.WriteString(fmt.Sprintf("  %s\n", p))
 		}
-	} else {
+	default:
 		b.WriteString(fmt.Sprintf("%-20s %s\n", "Watched Paths:", "(none)"))
 	}
 
+	if status.ProjectTrust == "allowlist" || len(status.EnabledProjects) > 0 || len(status.DisabledProjects) > 0 {
+		if status.ProjectTrust == "allowlist" {
+			b.WriteString(fmt.Sprintf("\n%sProjects:%s only enabled projects are recorded\n", ansi.bold, ansi.reset))
+		} else {
+			b.WriteString(fmt.Sprintf("\n%sProjects:%s all but disabled projects are recorded\n", ansi.bold, ansi.reset))
+		}
+		for _, p := range status.EnabledProjects {
+			b.WriteString(fmt.Sprintf("  enabled   %s\n", p))
+		}
+		for _, p := range status.DisabledProjects {
+			b.WriteString(fmt.Sprintf("  disabled  %s\n", p))
+		}
+	}
+
+	if len(status.SessionRoots) > 0 {
+		b.WriteString(fmt.Sprintf("\n%sSession Directories:%s\n", ansi.bold, ansi.reset))
+		for _, r := range status.SessionRoots {
+			if r.Exists {
+				b.WriteString(fmt.Sprintf("  %s  %d tailed\n", r.Path, r.Sessions))
+			} else {
+				b.WriteString(fmt.Sprintf("  %s  (missing)\n", r.Path))
+			}
+		}
+	}
+
+	if len(status.Sessions) > 0 || status.IdleSessions > 0 {
+		b.WriteString(fmt.Sprintf("\n%sSessions:%s %d active, %d idle\n", ansi.bold, ansi.reset, len(status.Sessions), status.IdleSessions))
+		for _, s := range status.Sessions {
+			b.WriteString(fmt.Sprintf("  %s\n    %d lines parsed, %s behind, last line %s\n",
+				s.SessionID, s.LinesParsed, humanBytes(s.LagBytes), ago(s.LastLineAt)))
+		}
+	}
+
+	if len(status.GitRepos) > 0 {
+		b.WriteString(fmt.Sprintf("\n%sGit Sync:%s\n", ansi.bold, ansi.reset))
+		for _, g := range status.GitRepos {
+			b.WriteString(fmt.Sprintf("  %s\n    last sync %s\n", g.Path, ago(g.LastSyncAt)))
+			if g.LastError != "" {
+				b.WriteString(fmt.Sprintf("    error: %s\n", g.LastError))
+			}
+		}
+	}
+
+	if len(status.UntrackedRepos) > 0 {
+		b.WriteString(fmt.Sprintf("\n%sUntracked Repositories:%s AI edits outside the watch paths; run \"gapmap orphans --track\"\n", ansi.bold, ansi.reset))
+		for _, r := range status.UntrackedRepos {
+			b.WriteString(fmt.Sprintf("  %s  %d edits\n", r.Path, r.Edits))
+		}
+	}
+
 	return b.String()
 }
 
+// ago formats an RFC 3339 timestamp as the time elapsed since it, e.g.
+// "5m ago", or "never" when it is empty.
+func ago(ts string) string {
+	if ts == "" {
+		return "never"
+	}
+	t, err := time.Parse(time.RFC3339, ts)
+	if err != nil {
+		return ts
+	}
+	d := time.Since(t)
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return fmt.Sprintf("%dm ago", int(d.Minutes()))
+	case d < 48*time.Hour:
+		return fmt.Sprintf("%dh ago", int(d.Hours()))
+	default:
+		return fmt.Sprintf("%dd ago", int(d.Hours()/24))
+	}
+}
+
 // FormatJSON marshals any value as indented JSON.
 func FormatJSON(v interface{}) string {
 	data, err := json.MarshalIndent(v, "", "  ")
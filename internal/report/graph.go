@@ -0,0 +1,290 @@
+package report
+
+import (
+	"fmt"
+	"math"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/anthropic/gap-map/internal/store"
+)
+
+// Graph levels: what each node of a collaboration graph stands for.
+const (
+	GraphDirectories = "dir"
+	GraphFiles       = "file"
+)
+
+// Graph is a collaboration map of a project: its directories or files,
+// sized by lines and colored by AI%, with edges from the AI sessions that
+// wrote in them.
+type Graph struct {
+	ProjectPath     string         `json:"project_path"`
+	Level           string         `json:"level"`
+	Nodes           []GraphNode    `json:"nodes"`    // most lines first
+	Sessions        []GraphSession `json:"sessions"` // most AI lines first
+	Edges           []GraphEdge    `json:"edges"`
+	OmittedSessions int            `json:"omitted_sessions,omitempty"` // sessions past GraphOptions.MaxSessions
+}
+
+// GraphNode is a directory or file of a Graph.
+type GraphNode struct {
+	ID         string  `json:"id"`
+	Path       string  `json:"path"` // relative to the project; "." for its root
+	Files      int     `json:"files"`
+	TotalLines int     `json:"total_lines"`
+	AILines    int     `json:"ai_lines"`
+	AIPct      float64 `json:"ai_pct"`
+}
+
+// GraphSession is an AI session that wrote lines in a Graph's nodes.
+type GraphSession struct {
+	ID        string    `json:"id"`
+	SessionID string    `json:"session_id"`
+	AILines   int       `json:"ai_lines"`
+	Start     time.Time `json:"start"` // its first attributed edit
+}
+
+// GraphEdge links a session to a node it wrote AILines lines in.
+type GraphEdge struct {
+	Session string `json:"session"` // GraphSession.ID
+	Node    string `json:"node"`    // GraphNode.ID
+	AILines int    `json:"ai_lines"`
+}
+
+// GraphOptions shapes a Graph.
+type GraphOptions struct {
+	Level       string // GraphDirectories (default) or GraphFiles
+	Depth       int    // leading directory components kept at the directory level; 0 keeps all
+	MaxSessions int    // sessions shown, most AI lines first; 0 shows all
+}
+
+// BuildGraph builds the collaboration graph of the files of pr, with the
+// sessions behind their AI attributions read from s.
+func BuildGraph(s *store.Store, pr *ProjectReport, opts GraphOptions) (*Graph, error) {
+	switch opts.Level {
+	case "":
+		opts.Level = GraphDirectories
+	case GraphDirectories, GraphFiles:
+	default:
+		return nil, fmt.Errorf("invalid graph level %q: use %s or %s", opts.Level, GraphDirectories, GraphFiles)
+	}
+
+	g := &Graph{ProjectPath: pr.ProjectPath, Level: opts.Level}
+	nodeOf := make(map[string]*GraphNode) // attributed file path -> node
+	byPath := make(map[string]*GraphNode)
+	for _, fr := range pr.Files {
+		key := graphPath(pr.ProjectPath, fr.FilePath, opts)
+		n := byPath[key]
+		if n == nil {
+			n = &GraphNode{Path: key}
+			byPath[key] = n
+		}
+		n.Files++
+		n.TotalLines += fr.TotalLines
+		n.AILines += fr.AILines
+		nodeOf[fr.FilePath] = n
+	}
+
+	attrs, err := s.QueryAttributionsWithWorkType(pr.ProjectPath)
+	if err != nil {
+		return nil, fmt.Errorf("query attributions: %w", err)
+	}
+	events, err := s.QueryWriteEditSessionEvents()
+	if err != nil {
+		return nil, fmt.Errorf("query session events: %w", err)
+	}
+	sessionOf := make(map[int64]string, len(events))
+	for _, se := range events {
+		sessionOf[se.ID] = se.SessionID
+	}
+
+	type edgeKey struct {
+		session string
+		node    *GraphNode
+	}
+	edges := make(map[edgeKey]int)
+	sessions := make(map[string]*GraphSession)
+	for _, a := range attrs {
+		n := nodeOf[a.FilePath]
+		if n == nil || a.SessionEventID == nil || !isAIAuthorship(a.AuthorshipLevel) ||
+			a.Kind == store.AttributionDeletion || a.LinesChanged <= 0 {
+			continue
+		}
+		id, ok := sessionOf[*a.SessionEventID]
+		if !ok {
+			se, err := s.QuerySessionEventByID(*a.SessionEventID)
+			if err != nil {
+				continue
+			}
+			id = se.SessionID
+			sessionOf[*a.SessionEventID] = id
+		}
+		gs := sessions[id]
+		if gs == nil {
+			gs = &GraphSession{SessionID: id, Start: a.Timestamp}
+			sessions[id] = gs
+		}
+		gs.AILines += a.LinesChanged
+		if a.Timestamp.Before(gs.Start) {
+			gs.Start = a.Timestamp
+		}
+		edges[edgeKey{id, n}] += a.LinesChanged
+	}
+
+	for _, n := range byPath {
+		if n.TotalLines > 0 {
+			n.AIPct = float64(n.AILines) / float64(n.TotalLines) * 100
+		}
+		g.Nodes = append(g.Nodes, *n)
+	}
+	sort.Slice(g.Nodes, func(i, j int) bool {
+		if g.Nodes[i].TotalLines != g.Nodes[j].TotalLines {
+			return g.Nodes[i].TotalLines > g.Nodes[j].TotalLines
+		}
+		return g.Nodes[i].Path < g.Nodes[j].Path
+	})
+	nodeIndex := make(map[string]int, len(g.Nodes))
+	for i := range g.Nodes {
+		g.Nodes[i].ID = fmt.Sprintf("n%d", i)
+		nodeIndex[g.Nodes[i].Path] = i
+	}
+
+	for _, gs := range sessions {
+		g.Sessions = append(g.Sessions, *gs)
+	}
+	sort.Slice(g.Sessions, func(i, j int) bool {
+		if g.Sessions[i].AILines != g.Sessions[j].AILines {
+			return g.Sessions[i].AILines > g.Sessions[j].AILines
+		}
+		return g.Sessions[i].SessionID < g.Sessions[j].SessionID
+	})
+	if opts.MaxSessions > 0 && len(g.Sessions) > opts.MaxSessions {
+		g.OmittedSessions = len(g.Sessions) - opts.MaxSessions
+		g.Sessions = g.Sessions[:opts.MaxSessions]
+	}
+	sessionIndex := make(map[string]int, len(g.Sessions))
+	for i := range g.Sessions {
+		g.Sessions[i].ID = fmt.Sprintf("s%d", i)
+		sessionIndex[g.Sessions[i].SessionID] = i
+	}
+
+	type indexed struct{ session, node, lines int }
+	var kept []indexed
+	for k, lines := range edges {
+		if si, ok := sessionIndex[k.session]; ok {
+			kept = append(kept, indexed{si, nodeIndex[k.node.Path], lines})
+		}
+	}
+	sort.Slice(kept, func(i, j int) bool {
+		if kept[i].session != kept[j].session {
+			return kept[i].session < kept[j].session
+		}
+		return kept[i].node < kept[j].node
+	})
+	for _, e := range kept {
+		g.Edges = append(g.Edges, GraphEdge{Session: g.Sessions[e.session].ID, Node: g.Nodes[e.node].ID, AILines: e.lines})
+	}
+	return g, nil
+}
+
+// graphPath returns the node path of an attributed file: the file relative
+// to the project, or its directory cut to opts.Depth components.
+func graphPath(projectPath, filePath string, opts GraphOptions) string {
+	if rel, err := filepath.Rel(projectPath, resolveFilePath(projectPath, filePath)); err == nil && !strings.HasPrefix(rel, "..") {
+		filePath = rel
+	}
+	filePath = filepath.ToSlash(filePath)
+	if opts.Level == GraphFiles {
+		return filePath
+	}
+	dir := filepath.ToSlash(filepath.Dir(filePath))
+	if parts := strings.Split(dir, "/"); opts.Depth > 0 && len(parts) > opts.Depth {
+		dir = strings.Join(parts[:opts.Depth], "/")
+	}
+	return dir
+}
+
+// graphColors fill nodes from mostly human (blue) to mostly AI (red), one
+// color per 20 points of AI%.
+var graphColors = []string{"#2c7bb6", "#abd9e9", "#ffffbf", "#fdae61", "#d7191c"}
+
+// graphColor returns the index into graphColors of an AI%.
+func graphColor(aiPct float64) int {
+	return min(int(aiPct/20), len(graphColors)-1)
+}
+
+// graphSessionLabel returns how a session is labeled: its ID, shortened,
+// and the day it started in loc.
+func graphSessionLabel(gs GraphSession, loc *time.Location) string {
+	id := gs.SessionID
+	if len(id) > 8 {
+		id = id[:8]
+	}
+	return fmt.Sprintf("session %s, %s", id, gs.Start.In(loc).Format("2006-01-02"))
+}
+
+// FormatGraphMermaid formats g as a Mermaid flowchart, with session start
+// days in loc. Mermaid cannot size nodes, so each node's label carries its
+// lines.
+func FormatGraphMermaid(g *Graph, loc *time.Location) string {
+	quote := func(s string) string { return strings.ReplaceAll(s, `"`, "#quot;") }
+
+	var b strings.Builder
+	b.WriteString("flowchart LR\n")
+	for _, n := range g.Nodes {
+		b.WriteString(fmt.Sprintf("  %s[\"%s<br/>%d lines, %.0f%% AI\"]:::ai%d\n",
+			n.ID, quote(n.Path), n.TotalLines, n.AIPct, graphColor(n.AIPct)))
+	}
+	for _, gs := range g.Sessions {
+		b.WriteString(fmt.Sprintf("  %s([\"%s<br/>%d AI lines\"]):::session\n",
+			gs.ID, quote(graphSessionLabel(gs, loc)), gs.AILines))
+	}
+	for _, e := range g.Edges {
+		b.WriteString(fmt.Sprintf("  %s -->|%d| %s\n", e.Session, e.AILines, e.Node))
+	}
+	for i, c := range graphColors {
+		b.WriteString(fmt.Sprintf("  classDef ai%d fill:%s,stroke:#333,color:#000\n", i, c))
+	}
+	b.WriteString("  classDef session fill:#eeeeee,stroke:#999,color:#000\n")
+	return b.String()
+}
+
+// FormatGraphDOT formats g as a Graphviz digraph, with session start days
+// in loc. Node widths grow with the square root of their lines and edge
+// widths with the lines written along them.
+func FormatGraphDOT(g *Graph, loc *time.Location) string {
+	quote := func(s string) string {
+		return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s) + `"`
+	}
+	maxLines, maxEdge := 1, 1
+	for _, n := range g.Nodes {
+		maxLines = max(maxLines, n.TotalLines)
+	}
+	for _, e := range g.Edges {
+		maxEdge = max(maxEdge, e.AILines)
+	}
+
+	var b strings.Builder
+	b.WriteString("digraph gapmap {\n")
+	b.WriteString("  rankdir=LR;\n")
+	b.WriteString("  node [style=filled, fontname=\"Helvetica\"];\n")
+	for _, n := range g.Nodes {
+		width := 0.75 + 2.25*math.Sqrt(float64(n.TotalLines)/float64(maxLines))
+		b.WriteString(fmt.Sprintf("  %s [label=%s, shape=box, fillcolor=%s, width=%.2f];\n",
+			n.ID, quote(fmt.Sprintf("%s\n%d lines, %.0f%% AI", n.Path, n.TotalLines, n.AIPct)),
+			quote(graphColors[graphColor(n.AIPct)]), width))
+	}
+	for _, gs := range g.Sessions {
+		b.WriteString(fmt.Sprintf("  %s [label=%s, shape=ellipse, fillcolor=\"#eeeeee\"];\n",
+			gs.ID, quote(fmt.Sprintf("%s\n%d AI lines", graphSessionLabel(gs, loc), gs.AILines))))
+	}
+	for _, e := range g.Edges {
+		b.WriteString(fmt.Sprintf("  %s -> %s [label=\"%d\", penwidth=%.1f];\n",
+			e.Session, e.Node, e.AILines, 1+4*float64(e.AILines)/float64(maxEdge)))
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
@@ -0,0 +1,93 @@
+package report
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anthropic/gap-map/internal/store"
+)
+
+func TestBuildGraph(t *testing.T) {
+	s, projDir, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	// Two sessions: s1 wrote in internal/api twice and in cmd once; s2 in
+	// cmd only.
+	edits := []struct {
+		session, file string
+		lines         int
+	}{
+		{"session-one-1234", "internal/api/h.go", 30},
+		{"session-one-1234", "internal/api/routes.go", 10},
+		{"session-one-1234", "cmd/main.go", 5},
+		{"session-two-5678", "cmd/main.go", 8},
+	}
+	for i, e := range edits {
+		path := projDir + "/" + e.file
+		at := baseTime.Add(time.Duration(i) * time.Minute)
+		if err := s.InsertSessionEvent(e.session, "tool_use", "Edit", path, "", at, fmt.Sprintf(`{"uuid":"e%d"}`, i), e.lines); err != nil {
+			t.Fatal(err)
+		}
+		seID := int64(i + 1)
+		id, err := s.InsertAttribution(store.AttributionRecord{
+			FilePath: path, ProjectPath: projDir, SessionEventID: &seID,
+			AuthorshipLevel: "mostly_ai", Timestamp: at, LinesChanged: e.lines,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := s.UpdateAttributionWorkType(id, "core_logic"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Human work has no session edge.
+	insertAttribution(t, s, projDir+"/cmd/main.go", projDir, "mostly_human", "core_logic", baseTime, 20)
+
+	pr := &ProjectReport{ProjectPath: projDir, Files: []FileReport{
+		{FilePath: projDir + "/internal/api/h.go", TotalLines: 30, AILines: 30},
+		{FilePath: projDir + "/internal/api/routes.go", TotalLines: 10, AILines: 10},
+		{FilePath: projDir + "/cmd/main.go", TotalLines: 33, AILines: 13},
+	}}
+
+	g, err := BuildGraph(s, pr, GraphOptions{Depth: 1})
+	if err != nil {
+		t.Fatalf("BuildGraph: %v", err)
+	}
+	if len(g.Nodes) != 2 || g.Nodes[0].Path != "internal" || g.Nodes[0].TotalLines != 40 || g.Nodes[0].AIPct != 100 || g.Nodes[1].Path != "cmd" {
+		t.Fatalf("Nodes = %+v", g.Nodes)
+	}
+	if len(g.Sessions) != 2 || g.Sessions[0].SessionID != "session-one-1234" || g.Sessions[0].AILines != 45 || !g.Sessions[0].Start.Equal(baseTime) {
+		t.Fatalf("Sessions = %+v", g.Sessions)
+	}
+	want := []GraphEdge{{"s0", "n0", 40}, {"s0", "n1", 5}, {"s1", "n1", 8}}
+	if fmt.Sprint(g.Edges) != fmt.Sprint(want) {
+		t.Errorf("Edges = %+v, want %+v", g.Edges, want)
+	}
+
+	mermaid := FormatGraphMermaid(g, time.UTC)
+	for _, check := range []string{"flowchart LR", `n0["internal<br/>40 lines, 100% AI"]:::ai4`, "s0 -->|40| n0", "session session-, 2026"} {
+		if !strings.Contains(mermaid, check) {
+			t.Errorf("FormatGraphMermaid output missing %q:\n%s", check, mermaid)
+		}
+	}
+	dot := FormatGraphDOT(g, time.UTC)
+	for _, check := range []string{"digraph gapmap {", `n1 [label="cmd\n33 lines, 39% AI"`, `s1 -> n1 [label="8"`} {
+		if !strings.Contains(dot, check) {
+			t.Errorf("FormatGraphDOT output missing %q:\n%s", check, dot)
+		}
+	}
+
+	// File level, with only the busiest session kept.
+	g, err = BuildGraph(s, pr, GraphOptions{Level: GraphFiles, MaxSessions: 1})
+	if err != nil {
+		t.Fatalf("BuildGraph files: %v", err)
+	}
+	if len(g.Nodes) != 3 || g.Nodes[0].Path != "cmd/main.go" || len(g.Sessions) != 1 || g.OmittedSessions != 1 || len(g.Edges) != 3 {
+		t.Errorf("file graph = %+v", g)
+	}
+	if _, err := BuildGraph(s, pr, GraphOptions{Level: "package"}); err == nil {
+		t.Error("BuildGraph accepted an invalid level")
+	}
+}
@@ -0,0 +1,42 @@
+package report
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/anthropic/gap-map/internal/metrics"
+)
+
+// Exclusion records a file that Options.Guard kept out of a report, or whose
+// changed lines it capped, so reports can list them.
+type Exclusion struct {
+	FilePath string `json:"file_path"`
+	Reason   string `json:"reason"`          // metrics.GuardBinary, GuardTooLarge or GuardCapped
+	Lines    int    `json:"lines,omitempty"` // lines in the file; 0 for binary files
+}
+
+func newExclusion(filePath, reason, content string) *Exclusion {
+	ex := &Exclusion{FilePath: filePath, Reason: reason}
+	if reason != metrics.GuardBinary {
+		ex.Lines = metrics.CountFileLines(content)
+	}
+	return ex
+}
+
+// Describe says why the file was excluded or capped.
+func (e Exclusion) Describe() string {
+	switch e.Reason {
+	case metrics.GuardBinary:
+		return "binary"
+	case metrics.GuardTooLarge:
+		return fmt.Sprintf("%d lines, over the size limit", e.Lines)
+	case metrics.GuardCapped:
+		return fmt.Sprintf("%d lines, capped", e.Lines)
+	}
+	return e.Reason
+}
+
+// sortExclusions orders exclusions by path.
+func sortExclusions(excluded []Exclusion) {
+	sort.Slice(excluded, func(i, j int) bool { return excluded[i].FilePath < excluded[j].FilePath })
+}
@@ -0,0 +1,243 @@
+package report
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/anthropic/gap-map/internal/store"
+)
+
+// Heatmap buckets of the per-file timeline.
+const (
+	BucketDay   = "day"
+	BucketWeek  = "week"
+	BucketMonth = "month"
+)
+
+// Heatmap shows when AI-authored lines were written: by weekday and hour
+// (a punch card) and per file over time.
+type Heatmap struct {
+	ProjectPath string  `json:"project_path"`
+	Timezone    string  `json:"timezone"`
+	Window      *Window `json:"window,omitempty"`
+	AILines     int     `json:"ai_lines"`
+
+	// PunchCard counts AI lines by weekday (0 = Sunday) and hour of day.
+	PunchCard [7][24]int `json:"punch_card"`
+
+	// Bucket is the period of each timeline column, starting at Periods.
+	Bucket  string         `json:"bucket"`
+	Periods []string       `json:"periods"` // start date of each column, YYYY-MM-DD
+	Files   []FileActivity `json:"files"`   // most active first
+}
+
+// FileActivity is one file's AI lines per heatmap period.
+type FileActivity struct {
+	FilePath string `json:"file_path"`
+	AILines  int    `json:"ai_lines"`
+	Periods  []int  `json:"periods"`
+}
+
+// GenerateHeatmap buckets the AI-attributed lines of the project, made in
+// w, by local weekday and hour in loc and per file by day, week or month,
+// whichever keeps the timeline readable. topFiles limits the files listed
+// (0 lists all).
+func GenerateHeatmap(s *store.Store, loc *time.Location, w Window, topFiles int) (*Heatmap, error) {
+	if err := w.Validate(); err != nil {
+		return nil, err
+	}
+	projectPath, err := DiscoverProjectPath(s)
+	if err != nil {
+		return nil, err
+	}
+	attrs, err := s.QueryAttributionsWithWorkType(projectPath)
+	if err != nil {
+		return nil, fmt.Errorf("query attributions: %w", err)
+	}
+
+	h := &Heatmap{ProjectPath: projectPath, Timezone: loc.String()}
+	if !w.IsZero() {
+		h.Window = &w
+	}
+	var ai []store.AttributionWithWorkType
+	var first, last time.Time
+	for _, a := range w.attributions(attrs) {
+		if !isAIAuthorship(a.AuthorshipLevel) || a.LinesChanged <= 0 {
+			continue
+		}
+		t := a.Timestamp.In(loc)
+		h.PunchCard[t.Weekday()][t.Hour()] += a.LinesChanged
+		h.AILines += a.LinesChanged
+		if first.IsZero() || t.Before(first) {
+			first = t
+		}
+		if t.After(last) {
+			last = t
+		}
+		ai = append(ai, a)
+	}
+	if len(ai) == 0 {
+		return h, nil
+	}
+
+	h.Bucket = heatmapBucket(last.Sub(first))
+	start := periodStart(first, h.Bucket)
+	for p := start; !p.After(last); p = nextPeriod(p, h.Bucket) {
+		h.Periods = append(h.Periods, p.Format("2006-01-02"))
+	}
+	byFile := make(map[string]*FileActivity)
+	for _, a := range ai {
+		fa := byFile[a.FilePath]
+		if fa == nil {
+			fa = &FileActivity{FilePath: a.FilePath, Periods: make([]int, len(h.Periods))}
+			byFile[a.FilePath] = fa
+		}
+		i := periodIndex(start, periodStart(a.Timestamp.In(loc), h.Bucket), h.Bucket)
+		fa.Periods[i] += a.LinesChanged
+		fa.AILines += a.LinesChanged
+	}
+	for _, fa := range byFile {
+		h.Files = append(h.Files, *fa)
+	}
+	sort.Slice(h.Files, func(i, j int) bool {
+		if h.Files[i].AILines != h.Files[j].AILines {
+			return h.Files[i].AILines > h.Files[j].AILines
+		}
+		return h.Files[i].FilePath < h.Files[j].FilePath
+	})
+	if topFiles > 0 && len(h.Files) > topFiles {
+		h.Files = h.Files[:topFiles]
+	}
+	return h, nil
+}
+
+// heatmapBucket picks the timeline period for activity spanning span:
+// days up to two months, weeks up to two years, months beyond.
+func heatmapBucket(span time.Duration) string {
+	switch {
+	case span <= 60*24*time.Hour:
+		return BucketDay
+	case span <= 2*365*24*time.Hour:
+		return BucketWeek
+	default:
+		return BucketMonth
+	}
+}
+
+// periodStart returns the start of the period of bucket holding t: its
+// day, its week (from Monday) or its month, in t's location.
+func periodStart(t time.Time, bucket string) time.Time {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	switch bucket {
+	case BucketWeek:
+		return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+	case BucketMonth:
+		return day.AddDate(0, 0, 1-day.Day())
+	}
+	return day
+}
+
+// nextPeriod returns the start of the period after the one starting at p.
+func nextPeriod(p time.Time, bucket string) time.Time {
+	switch bucket {
+	case BucketWeek:
+		return p.AddDate(0, 0, 7)
+	case BucketMonth:
+		return p.AddDate(0, 1, 0)
+	}
+	return p.AddDate(0, 0, 1)
+}
+
+// periodIndex returns the index of the period starting at p in a timeline
+// starting at start.
+func periodIndex(start, p time.Time, bucket string) int {
+	i := 0
+	for q := start; q.Before(p); q = nextPeriod(q, bucket) {
+		i++
+	}
+	return i
+}
+
+// heatShades are the cells of an ASCII heatmap, from none to the most.
+const heatShades = " .:-=+*#%@"
+
+// shade returns the cell for n out of a maximum of peak.
+func shade(n, peak int) byte {
+	if n <= 0 || peak <= 0 {
+		return heatShades[0]
+	}
+	i := 1 + (n*(len(heatShades)-2)+peak-1)/peak
+	return heatShades[min(i, len(heatShades)-1)]
+}
+
+// FormatHeatmap renders h as an ASCII punch card of AI lines by weekday
+// and hour, followed by a timeline row per file.
+func FormatHeatmap(h *Heatmap, st Style) string {
+	ansi := st.palette()
+	var b strings.Builder
+	b.WriteString(ansi.bold + "Gap Map - AI Activity" + ansi.reset + "\n")
+	b.WriteString(strings.Repeat("=", 40) + "\n\n")
+	b.WriteString(fmt.Sprintf("Project:  %s\n", h.ProjectPath))
+	if h.Window != nil {
+		b.WriteString(fmt.Sprintf("Window:   %s\n", h.Window))
+	}
+	b.WriteString(fmt.Sprintf("AI lines: %d (times in %s)\n\n", h.AILines, h.Timezone))
+	if h.AILines == 0 {
+		b.WriteString("No AI-authored lines recorded.\n")
+		return b.String()
+	}
+
+	b.WriteString(ansi.bold + "By weekday and hour" + ansi.reset + "\n")
+	peak := 0
+	for _, day := range h.PunchCard {
+		for _, n := range day {
+			peak = max(peak, n)
+		}
+	}
+	b.WriteString("     ")
+	for hour := 0; hour < 24; hour += 3 {
+		b.WriteString(fmt.Sprintf("%-6d", hour))
+	}
+	b.WriteString("\n")
+	// Monday first, as most work weeks start.
+	for _, wd := range []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday, time.Saturday, time.Sunday} {
+		b.WriteString(wd.String()[:3] + "  ")
+		total := 0
+		for _, n := range h.PunchCard[wd] {
+			c := shade(n, peak)
+			b.WriteByte(c)
+			b.WriteByte(c)
+			total += n
+		}
+		b.WriteString(fmt.Sprintf("  %d\n", total))
+	}
+	b.WriteString(fmt.Sprintf("     scale: %q = none ... %q = %d lines\n", heatShades[0], heatShades[len(heatShades)-1], peak))
+
+	if len(h.Files) == 0 {
+		return b.String()
+	}
+	b.WriteString(fmt.Sprintf("\n%sBy file, per %s from %s%s\n", ansi.bold, h.Bucket, h.Periods[0], ansi.reset))
+	peak = 0
+	width := 0
+	for _, fa := range h.Files {
+		for _, n := range fa.Periods {
+			peak = max(peak, n)
+		}
+		width = max(width, len(fa.FilePath))
+	}
+	width = min(width, 40)
+	for _, fa := range h.Files {
+		name := fa.FilePath
+		if len(name) > width {
+			name = "..." + name[len(name)-width+3:]
+		}
+		b.WriteString(fmt.Sprintf("%-*s |", width, name))
+		for _, n := range fa.Periods {
+			b.WriteByte(shade(n, peak))
+		}
+		b.WriteString(fmt.Sprintf("| %d\n", fa.AILines))
+	}
+	return b.String()
+}
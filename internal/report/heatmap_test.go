@@ -0,0 +1,83 @@
+package report
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGenerateHeatmap(t *testing.T) {
+	s, projDir, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	// baseTime is a Monday, 12:00 UTC.
+	insertAttribution(t, s, "a.go", projDir, "mostly_ai", "core_logic", baseTime, 10)
+	insertAttribution(t, s, "a.go", projDir, "mostly_ai", "core_logic", baseTime.Add(48*time.Hour+3*time.Hour), 5)
+	insertAttribution(t, s, "b.go", projDir, "fully_ai", "core_logic", baseTime.Add(time.Hour), 2)
+	insertAttribution(t, s, "c.go", projDir, "mostly_human", "core_logic", baseTime, 50)
+
+	loc := time.FixedZone("UTC+2", 2*3600)
+	h, err := GenerateHeatmap(s, loc, Window{}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.AILines != 17 {
+		t.Errorf("AILines = %d, want 17 (human lines left out)", h.AILines)
+	}
+	if got := h.PunchCard[time.Monday][14]; got != 10 {
+		t.Errorf("Monday 14:00 = %d, want 10 (times in the display zone)", got)
+	}
+	if got := h.PunchCard[time.Wednesday][17]; got != 5 {
+		t.Errorf("Wednesday 17:00 = %d, want 5", got)
+	}
+	if h.Bucket != BucketDay || len(h.Periods) != 3 || h.Periods[0] != "2026-02-09" {
+		t.Errorf("timeline = %s %v, want 3 days from 2026-02-09", h.Bucket, h.Periods)
+	}
+	if len(h.Files) != 2 || h.Files[0].FilePath != "a.go" || h.Files[0].AILines != 15 {
+		t.Fatalf("Files = %+v, want a.go first with 15 lines", h.Files)
+	}
+	if p := h.Files[0].Periods; p[0] != 10 || p[1] != 0 || p[2] != 5 {
+		t.Errorf("a.go periods = %v, want [10 0 5]", p)
+	}
+
+	top, err := GenerateHeatmap(s, loc, Window{Since: baseTime.Add(24 * time.Hour)}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if top.AILines != 5 || len(top.Files) != 1 {
+		t.Errorf("windowed heatmap = %d lines, %d files; want 5 and 1", top.AILines, len(top.Files))
+	}
+
+	out := FormatHeatmap(h, Style{})
+	for _, want := range []string{"Mon  ", "Sun  ", "a.go", "per day from 2026-02-09"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("FormatHeatmap output lacks %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestHeatmapBuckets(t *testing.T) {
+	if b := heatmapBucket(90 * 24 * time.Hour); b != BucketWeek {
+		t.Errorf("90 days bucket = %s, want week", b)
+	}
+	if b := heatmapBucket(3 * 365 * 24 * time.Hour); b != BucketMonth {
+		t.Errorf("3 years bucket = %s, want month", b)
+	}
+	// Weeks start on Monday.
+	sunday := time.Date(2026, 3, 8, 23, 0, 0, 0, time.UTC)
+	if got := periodStart(sunday, BucketWeek); !got.Equal(time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("week of %v starts %v, want 2026-03-02", sunday, got)
+	}
+	if got := periodStart(sunday, BucketMonth); got.Day() != 1 || got.Month() != time.March {
+		t.Errorf("month of %v starts %v", sunday, got)
+	}
+	if c := shade(0, 10); c != ' ' {
+		t.Errorf("shade(0) = %q, want blank", c)
+	}
+	if c := shade(10, 10); c != '@' {
+		t.Errorf("shade(peak) = %q, want @", c)
+	}
+	if c := shade(1, 1000); c == ' ' {
+		t.Error("shade of a small nonzero count is blank")
+	}
+}
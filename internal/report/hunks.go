@@ -0,0 +1,114 @@
+package report
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/anthropic/gap-map/internal/metrics"
+	"github.com/anthropic/gap-map/internal/store"
+)
+
+// Hunk is a run of consecutive lines a branch added to a file, with how
+// many of them AI wrote.
+type Hunk struct {
+	FilePath  string `json:"file_path"` // as in the report's FileReport
+	RepoPath  string `json:"repo_path"` // relative to the repository root, slash-separated
+	WorkType  string `json:"work_type"`
+	StartLine int    `json:"start_line"` // in the branch's version of the file
+	EndLine   int    `json:"end_line"`
+	Lines     int    `json:"lines"`
+	AILines   int    `json:"ai_lines"`
+}
+
+// AIPct returns the percentage of the hunk's lines AI wrote.
+func (h Hunk) AIPct() float64 {
+	if h.Lines == 0 {
+		return 0
+	}
+	return float64(h.AILines) / float64(h.Lines) * 100
+}
+
+// BranchHunks returns the hunks that the files of r, a report from
+// GenerateProjectForBranch, gained on branch since its merge-base with
+// baseBranch, with each line classified as the report classifies it, and
+// the branch head commit the line numbers refer to. Unlike the report it
+// reads committed changes only, so the line numbers match the branch as
+// pushed.
+func BranchHunks(s *store.Store, r *ProjectReport, branch, baseBranch string, opts Options) (string, []Hunk, error) {
+	projectPath := r.ProjectPath
+	top, err := opts.gitOutput(projectPath, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return "", nil, fmt.Errorf("not a git repository: %w", err)
+	}
+	top = strings.TrimSpace(top)
+
+	branchRef := resolveRef(projectPath, branch, opts)
+	head, err := opts.gitOutput(projectPath, "rev-parse", "--verify", branchRef+"^{commit}")
+	if err != nil {
+		return "", nil, fmt.Errorf("resolve %s: %w", branch, err)
+	}
+	head = strings.TrimSpace(head)
+	mergeBase := gitMergeBaseCommit(projectPath, resolveRef(projectPath, baseBranch, opts), branchRef, opts)
+	if mergeBase == "" {
+		return "", nil, fmt.Errorf("cannot compute merge-base for %s and %s", baseBranch, branch)
+	}
+
+	sessionEvents, err := s.QueryWriteEditSessionEvents()
+	if err != nil {
+		return "", nil, fmt.Errorf("query session events: %w", err)
+	}
+	claudeContentByFile := buildClaudeContentMap(s, sessionEvents)
+	match := opts.matchOptions(projectPath)
+
+	var hunks []Hunk
+	for _, fr := range r.Files {
+		absPath := resolveFilePath(projectPath, fr.FilePath)
+		repoPath, err := filepath.Rel(top, absPath)
+		if err != nil {
+			continue
+		}
+		diff, err := opts.gitOutput(top, "diff", opts.diffAlgorithmFlag(), "-U0", mergeBase, head, "--", repoPath)
+		if err != nil || diff == "" {
+			continue
+		}
+		numbers, added := parseDiffAdditionsNumbered(diff)
+		if match.ExcludeComments {
+			content := gitShowFile(context.Background(), projectPath, fr.FilePath, head, opts)
+			numbers, added = metrics.DropCommentLines(fr.FilePath, content, numbers, added)
+		}
+		numbers, added, _ = opts.Guard.CapLines(numbers, added)
+		baseContent := gitShowFile(context.Background(), projectPath, fr.FilePath, mergeBase, opts)
+		ai := metrics.ClassifyLines(added, findClaudeContent(fr.FilePath, claudeContentByFile), baseContent, match)
+
+		var h *Hunk
+		prev := 0
+		for i, n := range numbers {
+			contiguous := h != nil && n == prev+1
+			prev = n
+			if strings.TrimSpace(added[i]) == "" {
+				// Blank lines never count, but do not split a hunk.
+				if !contiguous {
+					h = nil
+				}
+				continue
+			}
+			if !contiguous {
+				hunks = append(hunks, Hunk{
+					FilePath:  fr.FilePath,
+					RepoPath:  filepath.ToSlash(repoPath),
+					WorkType:  fr.WorkType,
+					StartLine: n,
+				})
+				h = &hunks[len(hunks)-1]
+			}
+			h.EndLine = n
+			h.Lines++
+			if ai[i] {
+				h.AILines++
+			}
+		}
+	}
+	return head, hunks, nil
+}
@@ -0,0 +1,51 @@
+package report
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestBranchHunks(t *testing.T) {
+	s, projDir, cleanup := setupBranchTestStore(t)
+	defer cleanup()
+
+	gitCommitOnBranch(t, projDir, "handler.go", "package handler\n\nfunc A() {}\n", "add handler")
+
+	// Claude adds B; a human then adds a package comment by hand.
+	gitCheckoutCreate(t, projDir, "feature-x")
+	aiContent := "package handler\n\nfunc A() {}\n\nfunc B() int {\n\treturn 1\n}\n"
+	gitCommitOnBranch(t, projDir, "handler.go", aiContent, "claude edit")
+	gitCommitOnBranch(t, projDir, "handler.go", "// Package handler handles.\n"+aiContent, "human edit")
+
+	insertSessionEvent(t, s, "s1", filepath.Join(projDir, "handler.go"),
+		makeWriteRawJSON(filepath.Join(projDir, "handler.go"), aiContent), baseTime)
+	insertAttributionOnBranch(t, s, "handler.go", projDir, "mostly_ai", "core_logic", "feature-x", baseTime, 4)
+
+	r, err := GenerateProjectForBranch(s, "feature-x", "main", Options{})
+	if err != nil {
+		t.Fatalf("GenerateProjectForBranch: %v", err)
+	}
+	commit, hunks, err := BranchHunks(s, r, "feature-x", "main", Options{})
+	if err != nil {
+		t.Fatalf("BranchHunks: %v", err)
+	}
+	if want := gitRevParseReport(t, projDir, "feature-x"); commit != want {
+		t.Errorf("commit = %s, want %s", commit, want)
+	}
+	if len(hunks) != 2 {
+		t.Fatalf("hunks = %+v, want 2", hunks)
+	}
+
+	// The blank line before B is neither counted nor part of a hunk.
+	for i, want := range []Hunk{
+		{FilePath: "handler.go", RepoPath: "handler.go", WorkType: "core_logic", StartLine: 1, EndLine: 1, Lines: 1, AILines: 0},
+		{FilePath: "handler.go", RepoPath: "handler.go", WorkType: "core_logic", StartLine: 6, EndLine: 8, Lines: 3, AILines: 3},
+	} {
+		if hunks[i] != want {
+			t.Errorf("hunk %d = %+v, want %+v", i, hunks[i], want)
+		}
+	}
+	if pct := hunks[1].AIPct(); pct != 100 {
+		t.Errorf("AIPct = %.1f, want 100", pct)
+	}
+}
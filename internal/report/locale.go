@@ -0,0 +1,17 @@
+package report
+
+import "github.com/anthropic/gap-map/internal/numfmt"
+
+// numbers is the locale st writes numbers in: Numbers, or numfmt.C when it
+// is unset.
+func (st Style) numbers() numfmt.Locale {
+	if st.Numbers.Decimal == "" {
+		return numfmt.C
+	}
+	return st.Numbers
+}
+
+// num, dec and percent format numbers in the locale of st.
+func (st Style) num(n int) string               { return st.numbers().Int(n) }
+func (st Style) dec(f float64, prec int) string { return st.numbers().Float(f, prec) }
+func (st Style) percent(f float64) string       { return st.numbers().Pct(f) }
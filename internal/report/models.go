@@ -0,0 +1,65 @@
+package report
+
+import (
+	"sort"
+
+	"github.com/anthropic/gap-map/internal/store"
+)
+
+// UnknownModel is the ByModel key for AI events whose session does not
+// record a model, such as those stored before models were recorded.
+const UnknownModel = "unknown"
+
+// ModelSummary is the AI work of one model in a project report.
+type ModelSummary struct {
+	Files        int `json:"files"`         // files with an AI event by the model
+	AIEvents     int `json:"ai_events"`     // AI-authored attributions of its events
+	LinesWritten int `json:"lines_written"` // lines its Write/Edit calls changed
+}
+
+// addModels adds the AI attributions of one reported file to report.ByModel,
+// keyed by the model of the session event each was correlated with.
+func addModels(report *ProjectReport, attrs []store.AttributionWithWorkType, events map[int64]store.StoredSessionEvent) {
+	seen := make(map[string]bool)
+	for _, attr := range attrs {
+		if !isAIAuthorship(attr.AuthorshipLevel) || attr.SessionEventID == nil {
+			continue
+		}
+		se, ok := events[*attr.SessionEventID]
+		if !ok {
+			continue
+		}
+		model := se.Model
+		if model == "" {
+			model = UnknownModel
+		}
+		if report.ByModel == nil {
+			report.ByModel = make(map[string]ModelSummary)
+		}
+		summary := report.ByModel[model]
+		if !seen[model] {
+			seen[model] = true
+			summary.Files++
+		}
+		summary.AIEvents++
+		summary.LinesWritten += se.LinesChanged
+		report.ByModel[model] = summary
+	}
+}
+
+// SortedModels returns the models of byModel by AI events descending, then
+// by name.
+func SortedModels(byModel map[string]ModelSummary) []string {
+	models := make([]string, 0, len(byModel))
+	for m := range byModel {
+		models = append(models, m)
+	}
+	sort.Slice(models, func(i, j int) bool {
+		a, b := byModel[models[i]], byModel[models[j]]
+		if a.AIEvents != b.AIEvents {
+			return a.AIEvents > b.AIEvents
+		}
+		return models[i] < models[j]
+	})
+	return models
+}
@@ -0,0 +1,60 @@
+package report
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/anthropic/gap-map/internal/store"
+)
+
+func TestAddModels(t *testing.T) {
+	id := func(v int64) *int64 { return &v }
+	events := map[int64]store.StoredSessionEvent{
+		1: {ID: 1, Model: "claude-opus-4-1", LinesChanged: 10},
+		2: {ID: 2, Model: "claude-opus-4-1", LinesChanged: 5},
+		3: {ID: 3, Model: "claude-sonnet-4-5", LinesChanged: 7},
+		4: {ID: 4, LinesChanged: 2},
+	}
+
+	r := &ProjectReport{}
+	addModels(r, []store.AttributionWithWorkType{
+		{AttributionRecord: store.AttributionRecord{SessionEventID: id(1), AuthorshipLevel: "mostly_ai"}},
+		{AttributionRecord: store.AttributionRecord{SessionEventID: id(2), AuthorshipLevel: "mostly_ai"}},
+		{AttributionRecord: store.AttributionRecord{SessionEventID: id(3), AuthorshipLevel: "mostly_human"}},
+		{AttributionRecord: store.AttributionRecord{AuthorshipLevel: "mostly_ai"}},
+	}, events)
+	addModels(r, []store.AttributionWithWorkType{
+		{AttributionRecord: store.AttributionRecord{SessionEventID: id(3), AuthorshipLevel: "fully_ai"}},
+		{AttributionRecord: store.AttributionRecord{SessionEventID: id(4), AuthorshipLevel: "mostly_ai"}},
+	}, events)
+
+	want := map[string]ModelSummary{
+		"claude-opus-4-1":   {Files: 1, AIEvents: 2, LinesWritten: 15},
+		"claude-sonnet-4-5": {Files: 1, AIEvents: 1, LinesWritten: 7},
+		UnknownModel:        {Files: 1, AIEvents: 1, LinesWritten: 2},
+	}
+	if len(r.ByModel) != len(want) {
+		t.Fatalf("ByModel = %+v, want %+v", r.ByModel, want)
+	}
+	for m, w := range want {
+		if got := r.ByModel[m]; got != w {
+			t.Errorf("ByModel[%s] = %+v, want %+v", m, got, w)
+		}
+	}
+
+	models := SortedModels(r.ByModel)
+	if strings.Join(models, ",") != "claude-opus-4-1,claude-sonnet-4-5,unknown" {
+		t.Errorf("SortedModels = %v", models)
+	}
+
+	out := FormatProjectReport(r, Style{})
+	if !strings.Contains(out, "Models") || !strings.Contains(out, "claude-sonnet-4-5") {
+		t.Errorf("FormatProjectReport lacks the model table:\n%s", out)
+	}
+
+	// Nothing to compare when no session recorded a model.
+	r.ByModel = map[string]ModelSummary{UnknownModel: {Files: 1, AIEvents: 3}}
+	if out := FormatProjectReport(r, Style{}); strings.Contains(out, "Models") {
+		t.Errorf("FormatProjectReport shows a model table with only unknown models:\n%s", out)
+	}
+}
@@ -0,0 +1,184 @@
+package report
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/anthropic/gap-map/internal/gitint"
+	"github.com/anthropic/gap-map/internal/metrics"
+	"github.com/anthropic/gap-map/internal/store"
+)
+
+// SourceNotes marks a ProjectReport read from git notes written by
+// `gapmap annotate`.
+const SourceNotes = "notes"
+
+// NoteVersion is the version of the Note format written to git notes.
+// Readers reject notes of a newer version.
+const NoteVersion = 1
+
+// Note is the attribution of a commit as stored in git notes under
+// gitint.NotesRef, so that it travels with the repository to machines
+// without the local database.
+type Note struct {
+	Version     int        `json:"version"`
+	Scorer      string     `json:"scorer"`
+	GeneratedAt time.Time  `json:"generated_at"`
+	Files       []NoteFile `json:"files"`
+}
+
+// NoteFile is one file's attribution in a Note.
+type NoteFile struct {
+	Path            string  `json:"path"` // relative to the repository root
+	WorkType        string  `json:"work_type"`
+	AuthorshipLevel string  `json:"authorship_level"`
+	MeaningfulAIPct float64 `json:"meaningful_ai_pct"`
+	TotalLines      int     `json:"total_lines"`
+	AILines         int     `json:"ai_lines"`
+	DeletedLines    int     `json:"deleted_lines,omitempty"`
+	AIDeletedLines  int     `json:"ai_deleted_lines,omitempty"`
+	TotalEvents     int     `json:"total_events"`
+	AIEventCount    int     `json:"ai_event_count"`
+	// AILinesAt lists the AI-written line numbers of the file as it was
+	// annotated, as ranges such as "3-9,14". Only set by annotate --lines.
+	AILinesAt string `json:"ai_lines_at,omitempty"`
+}
+
+// BuildNote converts pr into a Note with paths relative to repoRoot, the
+// top of the repository the note is written to. Files outside repoRoot are
+// left out. aiLines, if non-nil, maps report file paths to their AI-written
+// line numbers (see AILineNumbers).
+func BuildNote(pr *ProjectReport, repoRoot string, aiLines map[string][]int, now time.Time) *Note {
+	note := &Note{Version: NoteVersion, Scorer: pr.Scorer, GeneratedAt: now.UTC()}
+	for _, fr := range pr.Files {
+		rel, err := filepath.Rel(repoRoot, resolveFilePath(pr.ProjectPath, fr.FilePath))
+		if err != nil || strings.HasPrefix(rel, "..") {
+			continue
+		}
+		note.Files = append(note.Files, NoteFile{
+			Path:            filepath.ToSlash(rel),
+			WorkType:        fr.WorkType,
+			AuthorshipLevel: fr.AuthorshipLevel,
+			MeaningfulAIPct: fr.MeaningfulAIPct,
+			TotalLines:      fr.TotalLines,
+			AILines:         fr.AILines,
+			DeletedLines:    fr.DeletedLines,
+			AIDeletedLines:  fr.AIDeletedLines,
+			TotalEvents:     fr.TotalEvents,
+			AIEventCount:    fr.AIEventCount,
+			AILinesAt:       formatLineRanges(aiLines[fr.FilePath]),
+		})
+	}
+	sort.Slice(note.Files, func(i, j int) bool { return note.Files[i].Path < note.Files[j].Path })
+	return note
+}
+
+// AILineNumbers returns the AI-written line numbers of each of filePaths in
+// the working tree of projectPath, classified as the project report
+// classifies them. Files with no AI lines are left out.
+func AILineNumbers(ctx context.Context, s *store.Store, projectPath string, filePaths []string, opts Options) (map[string][]int, error) {
+	sessionEvents, err := s.QueryWriteEditSessionEvents()
+	if err != nil {
+		return nil, fmt.Errorf("query session events: %w", err)
+	}
+	claudeContentByFile, _ := buildClaudeContentMaps(s, sessionEvents)
+
+	match := opts.matchOptions(projectPath)
+	lines := make(map[string][]int)
+	for _, filePath := range filePaths {
+		numbers, added, _, baseContent := getChangedLines(ctx, s, projectPath, filePath, opts)
+		if match.ExcludeComments {
+			content := readFileContent(resolveFilePath(projectPath, filePath))
+			numbers, added = metrics.DropCommentLines(filePath, content, numbers, added)
+		}
+		numbers, added, _ = opts.Guard.CapLines(numbers, added)
+		ai := metrics.ClassifyLines(added, findClaudeContent(filePath, claudeContentByFile), baseContent, match)
+		for i, isAI := range ai {
+			if isAI {
+				lines[filePath] = append(lines[filePath], numbers[i])
+			}
+		}
+	}
+	return lines, nil
+}
+
+// GenerateProjectFromNotes produces a project report from the note on the
+// nearest commit reachable from rev that has one, for machines where the
+// daemon never ran. It returns the annotated commit with the report.
+func GenerateProjectFromNotes(repoPath, rev string, scorer metrics.Scorer, opts Options) (*ProjectReport, string, error) {
+	top, err := opts.gitOutput(repoPath, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, "", fmt.Errorf("not a git repository: %w", err)
+	}
+	top = strings.TrimSpace(top)
+
+	commit, data, err := gitint.LatestNote(top, rev)
+	if err != nil {
+		return nil, "", err
+	}
+	if commit == "" {
+		return nil, "", fmt.Errorf("no commit reachable from %s has a gap-map note (fetch them with `git fetch origin %s:%s`)", rev, gitint.NotesRef, gitint.NotesRef)
+	}
+	var note Note
+	if err := json.Unmarshal(data, &note); err != nil {
+		return nil, "", fmt.Errorf("parse note on %s: %w", commit, err)
+	}
+	if note.Version > NoteVersion {
+		return nil, "", fmt.Errorf("note on %s has version %d; this gapmap reads up to %d", commit, note.Version, NoteVersion)
+	}
+
+	report := &ProjectReport{
+		ProjectPath:  top,
+		Source:       SourceNotes,
+		Scorer:       scorer.Name(),
+		ByAuthorship: make(map[string]int),
+		ByWorkType:   make(map[string]WorkTypeSummary),
+	}
+	for _, nf := range note.Files {
+		rawPct := 0.0
+		if nf.TotalLines > 0 {
+			rawPct = float64(nf.AILines) / float64(nf.TotalLines) * 100.0
+		}
+		addFileToReport(report, FileReport{
+			FilePath:         filepath.FromSlash(nf.Path),
+			WorkType:         nf.WorkType,
+			MeaningfulAIPct:  nf.MeaningfulAIPct,
+			RawAIPct:         rawPct,
+			TotalLines:       nf.TotalLines,
+			AILines:          nf.AILines,
+			DeletedLines:     nf.DeletedLines,
+			AIDeletedLines:   nf.AIDeletedLines,
+			AuthorshipLevel:  nf.AuthorshipLevel,
+			TotalEvents:      nf.TotalEvents,
+			AIEventCount:     nf.AIEventCount,
+			AuthorshipCounts: map[string]int{nf.AuthorshipLevel: nf.TotalEvents},
+		}, scorer)
+	}
+	finishProjectReport(report, scorer, opts.Packages)
+	return report, commit, nil
+}
+
+// formatLineRanges formats sorted line numbers as comma-separated ranges,
+// e.g. [1 2 3 5] as "1-3,5".
+func formatLineRanges(lines []int) string {
+	var parts []string
+	for i := 0; i < len(lines); {
+		j := i
+		for j+1 < len(lines) && lines[j+1] == lines[j]+1 {
+			j++
+		}
+		if i == j {
+			parts = append(parts, strconv.Itoa(lines[i]))
+		} else {
+			parts = append(parts, strconv.Itoa(lines[i])+"-"+strconv.Itoa(lines[j]))
+		}
+		i = j + 1
+	}
+	return strings.Join(parts, ",")
+}
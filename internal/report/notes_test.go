@@ -0,0 +1,92 @@
+package report
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/anthropic/gap-map/internal/gitint"
+	"github.com/anthropic/gap-map/internal/metrics"
+)
+
+func TestNotesRoundTrip(t *testing.T) {
+	s, projDir, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	// main.go: lines 3-5 written by Claude, the package clause by a human.
+	aiContent := "func main() {\n\tprintln(\"hi\")\n}\n"
+	writeFile(t, projDir, "main.go", "package main\n\n"+aiContent)
+
+	insertSessionEvent(t, s, "s1", filepath.Join(projDir, "main.go"),
+		makeWriteRawJSON(filepath.Join(projDir, "main.go"), aiContent), baseTime)
+	insertAttribution(t, s, "main.go", projDir, "mostly_ai", "core_logic", baseTime, 3)
+
+	pr, err := GenerateProject(t.Context(), s, metrics.DefaultScorer(), Filter{}, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines, err := AILineNumbers(context.Background(), s, pr.ProjectPath, []string{"main.go"}, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	note := BuildNote(pr, projDir, lines, baseTime)
+	if len(note.Files) != 1 || note.Files[0].Path != "main.go" || note.Files[0].AILinesAt != "3-5" {
+		t.Fatalf("note files = %+v", note.Files)
+	}
+
+	data, err := json.Marshal(note)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := gitint.WriteNote(projDir, "HEAD", data); err != nil {
+		t.Fatal(err)
+	}
+	// A later commit still reads the note on its ancestor.
+	writeFile(t, projDir, "README", "hello\n")
+	gitAdd(t, projDir, []string{"README"}, "add readme")
+
+	got, commit, err := GenerateProjectFromNotes(projDir, "HEAD", metrics.DefaultScorer(), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if commit == "" || got.Source != SourceNotes {
+		t.Errorf("commit = %q, Source = %q", commit, got.Source)
+	}
+	if got.TotalFiles != pr.TotalFiles || got.TotalLines != pr.TotalLines || got.AILines != pr.AILines {
+		t.Errorf("from notes: files=%d lines=%d ai=%d, want files=%d lines=%d ai=%d",
+			got.TotalFiles, got.TotalLines, got.AILines, pr.TotalFiles, pr.TotalLines, pr.AILines)
+	}
+	if !almostEqual(got.MeaningfulAIPct, pr.MeaningfulAIPct, 0.01) {
+		t.Errorf("MeaningfulAIPct = %.2f, want %.2f", got.MeaningfulAIPct, pr.MeaningfulAIPct)
+	}
+	if got.ByAuthorship["mostly_ai"] != 1 {
+		t.Errorf("ByAuthorship = %v", got.ByAuthorship)
+	}
+}
+
+func TestGenerateProjectFromNotes_NoNotes(t *testing.T) {
+	dir := t.TempDir()
+	gitInit(t, dir)
+	_, _, err := GenerateProjectFromNotes(dir, "HEAD", metrics.DefaultScorer(), Options{})
+	if err == nil || !strings.Contains(err.Error(), "no commit reachable") {
+		t.Errorf("err = %v, want no-note error", err)
+	}
+}
+
+func TestFormatLineRanges(t *testing.T) {
+	tests := []struct {
+		lines []int
+		want  string
+	}{
+		{nil, ""},
+		{[]int{7}, "7"},
+		{[]int{1, 2, 3, 5, 9, 10}, "1-3,5,9-10"},
+	}
+	for _, tt := range tests {
+		if got := formatLineRanges(tt.lines); got != tt.want {
+			t.Errorf("formatLineRanges(%v) = %q, want %q", tt.lines, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package report
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/anthropic/gap-map/internal/store"
+	"github.com/anthropic/gap-map/internal/watcher"
+)
+
+// OrphanRoot is a repository, or a directory outside any repository, that
+// AI wrote files in without it being under a watch path. Its session
+// events never correlate with a file event, so its AI work is missing
+// from every report.
+type OrphanRoot struct {
+	Root       string    `json:"root"`
+	Repo       bool      `json:"repo"` // Root is a git repository
+	Edits      int       `json:"edits"`
+	Files      int       `json:"files"`
+	Sessions   int       `json:"sessions"`
+	LastEditAt time.Time `json:"last_edit_at"`
+}
+
+// Orphans groups the AI Write and Edit session events of files outside
+// watchPaths by repository, most edited first.
+func Orphans(s *store.Store, watchPaths []string) ([]OrphanRoot, error) {
+	events, err := s.QueryWriteEditSessionEvents()
+	if err != nil {
+		return nil, fmt.Errorf("query session events: %w", err)
+	}
+	byRoot := make(map[string]*OrphanRoot)
+	files := make(map[string]map[string]bool)
+	sessions := make(map[string]map[string]bool)
+	roots := make(map[string]string) // file -> root, to stat each file once
+	for _, se := range events {
+		if se.FilePath == "" {
+			continue
+		}
+		root, ok := roots[se.FilePath]
+		if !ok {
+			var repo bool
+			root, repo = watcher.UntrackedRoot(watchPaths, se.FilePath)
+			roots[se.FilePath] = root
+			if root != "" && byRoot[root] == nil {
+				byRoot[root] = &OrphanRoot{Root: root, Repo: repo}
+				files[root] = make(map[string]bool)
+				sessions[root] = make(map[string]bool)
+			}
+		}
+		if root == "" {
+			continue
+		}
+		o := byRoot[root]
+		o.Edits++
+		files[root][se.FilePath] = true
+		sessions[root][se.SessionID] = true
+		if se.Timestamp.After(o.LastEditAt) {
+			o.LastEditAt = se.Timestamp
+		}
+	}
+
+	out := make([]OrphanRoot, 0, len(byRoot))
+	for root, o := range byRoot {
+		o.Files = len(files[root])
+		o.Sessions = len(sessions[root])
+		out = append(out, *o)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Edits != out[j].Edits {
+			return out[i].Edits > out[j].Edits
+		}
+		return out[i].Root < out[j].Root
+	})
+	return out, nil
+}
+
+// FormatOrphans formats orphan roots as a table, with times in loc.
+func FormatOrphans(orphans []OrphanRoot, loc *time.Location, st Style) string {
+	ansi := st.palette()
+	if len(orphans) == 0 {
+		return "Every AI edit is under a watch path.\n"
+	}
+	var b strings.Builder
+	b.WriteString(ansi.bold + "AI Edits Outside Watch Paths" + ansi.reset + "\n")
+	b.WriteString(strings.Repeat("=", 40) + "\n\n")
+	b.WriteString(fmt.Sprintf("%6s %6s %8s  %-16s  %s\n", "Edits", "Files", "Sessions", "Last Edit", "Location"))
+	for _, o := range orphans {
+		kind := ""
+		if !o.Repo {
+			kind = " (not a repository)"
+		}
+		b.WriteString(fmt.Sprintf("%6s %6s %8s  %-16s  %s%s\n",
+			st.num(o.Edits), st.num(o.Files), st.num(o.Sessions), o.LastEditAt.In(loc).Format("2006-01-02 15:04"), o.Root, kind))
+	}
+	return b.String()
+}
@@ -0,0 +1,50 @@
+package report
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOrphans(t *testing.T) {
+	s, projDir, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	// A sibling repository and a scratch directory next to the project.
+	parent := filepath.Dir(projDir)
+	sibling := filepath.Join(parent, "web")
+	scratch := filepath.Join(parent, "scratch")
+	for _, d := range []string{filepath.Join(sibling, ".git"), scratch} {
+		if err := os.MkdirAll(d, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	insertSessionEvent(t, s, "s1", filepath.Join(projDir, "main.go"), "{}", baseTime)
+	insertSessionEvent(t, s, "s1", filepath.Join(sibling, "app.ts"), "{}", baseTime.Add(time.Minute))
+	insertSessionEvent(t, s, "s2", filepath.Join(sibling, "app.ts"), "{}", baseTime.Add(2*time.Minute))
+	insertSessionEvent(t, s, "s2", filepath.Join(sibling, "lib", "util.ts"), "{}", baseTime.Add(3*time.Minute))
+	insertSessionEvent(t, s, "s2", filepath.Join(scratch, "notes.md"), "{}", baseTime.Add(4*time.Minute))
+
+	orphans, err := Orphans(s, []string{projDir})
+	if err != nil {
+		t.Fatalf("Orphans: %v", err)
+	}
+	if len(orphans) != 2 {
+		t.Fatalf("got %d orphan roots, want 2: %+v", len(orphans), orphans)
+	}
+	web := orphans[0]
+	if web.Root != sibling || !web.Repo || web.Edits != 3 || web.Files != 2 || web.Sessions != 2 || !web.LastEditAt.Equal(baseTime.Add(3*time.Minute)) {
+		t.Errorf("sibling repo = %+v", web)
+	}
+	if o := orphans[1]; o.Root != scratch || o.Repo || o.Edits != 1 {
+		t.Errorf("scratch dir = %+v", o)
+	}
+
+	out := FormatOrphans(orphans, time.UTC, Style{})
+	if !strings.Contains(out, sibling) || !strings.Contains(out, scratch+" (not a repository)") {
+		t.Errorf("FormatOrphans output missing roots:\n%s", out)
+	}
+}
@@ -0,0 +1,128 @@
+package report
+
+import (
+	"path"
+	"sort"
+	"strings"
+)
+
+// OtherPackage is the package of files that match no package glob.
+const OtherPackage = "(other)"
+
+// PackageSummary holds per-package aggregate data for the report.
+type PackageSummary struct {
+	Files      int     `json:"files"`
+	TotalLines int     `json:"total_lines"`
+	AILines    int     `json:"ai_lines"`
+	AIPct      float64 `json:"ai_pct"`
+}
+
+// assignPackages sets each file's package and the per-package totals of r
+// when packages, as in Options.Packages, is configured.
+func assignPackages(r *ProjectReport, packages map[string]string) {
+	if len(packages) == 0 {
+		return
+	}
+	r.ByPackage = make(map[string]PackageSummary)
+	for i := range r.Files {
+		addFilePackage(r, &r.Files[i], packages)
+	}
+	finishPackages(r)
+}
+
+// addFilePackage sets f's package and adds it to the per-package totals
+// of r. It does nothing unless packages is configured.
+func addFilePackage(r *ProjectReport, f *FileReport, packages map[string]string) {
+	if len(packages) == 0 {
+		return
+	}
+	if r.ByPackage == nil {
+		r.ByPackage = make(map[string]PackageSummary)
+	}
+	f.Package = packageOf(r.ProjectPath, f.FilePath, packages)
+	summary := r.ByPackage[f.Package]
+	summary.Files++
+	summary.TotalLines += f.TotalLines
+	summary.AILines += f.AILines
+	r.ByPackage[f.Package] = summary
+}
+
+// finishPackages computes the AI% of each package of r.
+func finishPackages(r *ProjectReport) {
+	for name, summary := range r.ByPackage {
+		if summary.TotalLines > 0 {
+			summary.AIPct = float64(summary.AILines) / float64(summary.TotalLines) * 100.0
+		}
+		r.ByPackage[name] = summary
+	}
+}
+
+// packageOf returns the package filePath belongs to: the name of the most
+// specific (longest) glob in packages that matches it, or OtherPackage.
+func packageOf(projectPath, filePath string, packages map[string]string) string {
+	rel := projectRelPath(projectPath, filePath)
+
+	best, bestGlob := OtherPackage, ""
+	for glob, name := range packages {
+		if !MatchGlob(glob, rel) {
+			continue
+		}
+		if len(glob) > len(bestGlob) || (len(glob) == len(bestGlob) && glob < bestGlob) {
+			best, bestGlob = name, glob
+		}
+	}
+	return best
+}
+
+// MatchGlob reports whether a slash-separated path matches pattern. A "**"
+// segment matches any number of segments; other segments follow path.Match.
+// A pattern without wildcards also matches everything under that directory,
+// so "services/api" is the same as "services/api/**".
+func MatchGlob(pattern, name string) bool {
+	pattern = strings.Trim(pattern, "/")
+	if !strings.ContainsAny(pattern, "*?[") {
+		return name == pattern || strings.HasPrefix(name, pattern+"/")
+	}
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
+
+// SortedPackages returns the package names of a breakdown, most changed
+// lines first, then by name, with OtherPackage last.
+func SortedPackages(m map[string]PackageSummary) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		a, b := names[i], names[j]
+		if (a == OtherPackage) != (b == OtherPackage) {
+			return b == OtherPackage
+		}
+		if m[a].TotalLines != m[b].TotalLines {
+			return m[a].TotalLines > m[b].TotalLines
+		}
+		return a < b
+	})
+	return names
+}
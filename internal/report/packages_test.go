@@ -0,0 +1,59 @@
+package report
+
+import "testing"
+
+func TestMatchGlob(t *testing.T) {
+	cases := []struct {
+		pattern, name string
+		want          bool
+	}{
+		{"services/api/**", "services/api/main.go", true},
+		{"services/api/**", "services/api/internal/db/db.go", true},
+		{"services/api/**", "services/apigw/main.go", false},
+		{"services/*/cmd/**", "services/web/cmd/main.go", true},
+		{"**/*_test.go", "libs/ui/button_test.go", true},
+		{"**/*_test.go", "libs/ui/button.go", false},
+		{"libs/ui", "libs/ui/button.go", true},
+		{"libs/ui", "libs/uikit/x.go", false},
+		{"*.md", "docs/readme.md", false},
+	}
+	for _, c := range cases {
+		if got := MatchGlob(c.pattern, c.name); got != c.want {
+			t.Errorf("MatchGlob(%q, %q) = %v, want %v", c.pattern, c.name, got, c.want)
+		}
+	}
+}
+
+func TestAssignPackages(t *testing.T) {
+	packages := map[string]string{
+		"services/**":     "services",
+		"services/api/**": "api",
+	}
+
+	r := &ProjectReport{
+		ProjectPath: "/repo",
+		Files: []FileReport{
+			{FilePath: "/repo/services/api/main.go", TotalLines: 10, AILines: 10},
+			{FilePath: "services/web/app.go", TotalLines: 10, AILines: 0},
+			{FilePath: "README.md", TotalLines: 5},
+		},
+	}
+	assignPackages(r, packages)
+
+	// The longer glob wins for files both match.
+	if r.Files[0].Package != "api" || r.Files[1].Package != "services" || r.Files[2].Package != OtherPackage {
+		t.Errorf("packages = %q, %q, %q", r.Files[0].Package, r.Files[1].Package, r.Files[2].Package)
+	}
+	if api := r.ByPackage["api"]; api.Files != 1 || api.AIPct != 100 {
+		t.Errorf("api summary = %+v", api)
+	}
+	if names := SortedPackages(r.ByPackage); len(names) != 3 || names[2] != OtherPackage {
+		t.Errorf("SortedPackages = %v, want %s last", names, OtherPackage)
+	}
+
+	r.ByPackage = nil
+	assignPackages(r, nil)
+	if r.ByPackage != nil {
+		t.Error("no breakdown expected without configured packages")
+	}
+}
@@ -0,0 +1,44 @@
+package report
+
+import (
+	"os"
+	"time"
+
+	"github.com/anthropic/gap-map/internal/store"
+)
+
+// RemovedFile is a file with attributions that no longer exists, so its
+// work would otherwise vanish from reports without a trace.
+type RemovedFile struct {
+	FilePath       string    `json:"file_path"`
+	AILines        int       `json:"ai_lines"` // lines AI added, as last attributed
+	Attributions   int       `json:"attributions"`
+	AIEvents       int       `json:"ai_events"`
+	LastAttributed time.Time `json:"last_attributed"`
+}
+
+// removedFile returns the RemovedFile for a tracked file missing from the
+// working tree, or nil when it exists or the report ends at a commit,
+// where the file's content comes from git instead.
+func removedFile(sp span, projectPath, filePath string, attrs []store.AttributionWithWorkType) *RemovedFile {
+	if sp.end != "" {
+		return nil
+	}
+	if _, err := os.Stat(resolveFilePath(projectPath, filePath)); err == nil || !os.IsNotExist(err) {
+		return nil
+	}
+	rm := &RemovedFile{FilePath: filePath, Attributions: len(attrs)}
+	for _, attr := range attrs {
+		if attr.Timestamp.After(rm.LastAttributed) {
+			rm.LastAttributed = attr.Timestamp
+		}
+		if !isAIAuthorship(attr.AuthorshipLevel) {
+			continue
+		}
+		rm.AIEvents++
+		if attr.Kind != store.AttributionDeletion {
+			rm.AILines += attr.LinesChanged
+		}
+	}
+	return rm
+}
@@ -0,0 +1,19 @@
+package report
+
+import (
+	"github.com/anthropic/gap-map/internal/config"
+	"github.com/anthropic/gap-map/internal/worktype"
+)
+
+// newWorkTypeClassifier returns a work-type classifier that also applies
+// the work_types of the .gapmap.toml of the repository at projectPath.
+// File paths are absolute or relative to projectPath.
+func newWorkTypeClassifier(override worktype.OverrideReader, projectPath string) *worktype.Classifier {
+	c := worktype.NewClassifier(override)
+	if repo, err := config.FindRepoSettings(projectPath); err == nil && repo != nil {
+		c.SetPathRules(func(filePath string) (worktype.WorkType, bool) {
+			return repo.WorkType(resolveFilePath(projectPath, filePath))
+		})
+	}
+	return c
+}
@@ -5,31 +5,60 @@ package report
 
 import (
 	"bufio"
+	"context"
+	"errors"
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
+	"runtime"
 	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/anthropic/gap-map/internal/config"
+	"github.com/anthropic/gap-map/internal/gitexec"
+	"github.com/anthropic/gap-map/internal/gitint"
 	"github.com/anthropic/gap-map/internal/metrics"
 	"github.com/anthropic/gap-map/internal/sessionparser"
 	"github.com/anthropic/gap-map/internal/store"
+	"github.com/anthropic/gap-map/internal/survival"
 	"github.com/anthropic/gap-map/internal/worktype"
 )
 
 // ProjectReport holds the full project attribution report data.
 type ProjectReport struct {
 	ProjectPath    string                    `json:"project_path"`
+	Source         string                    `json:"source,omitempty"` // "git" when derived from commit metadata only, "notes" when read from git notes
+	Scorer         string                    `json:"scorer"`
 	MeaningfulAIPct float64                  `json:"meaningful_ai_pct"`
 	RawAIPct       float64                   `json:"raw_ai_pct"`
 	TotalFiles     int                       `json:"total_files"`
 	TotalLines     int                       `json:"total_lines"`
 	AILines        int                       `json:"ai_lines"`
+	DeletedLines   int                       `json:"deleted_lines"`
+	AIDeletedLines int                       `json:"ai_deleted_lines"`
+	HumanLines     int                       `json:"human_lines,omitempty"` // lines seen in human edit snapshots
+	RevisedLines   int                       `json:"revised_lines,omitempty"` // AI lines a human has since revised
+	CorrectionEvents int                     `json:"correction_events,omitempty"` // AI events whose correction is known
+	CorrectedEvents int                      `json:"corrected_events,omitempty"`  // of them, those a human changed soon after
+	CorrectionRate float64                   `json:"correction_rate"`             // % of CorrectionEvents corrected; see survival.Corrections
+	CorrectionWindowDays int                 `json:"correction_window_days,omitempty"` // how soon after an AI edit a change counts as a correction
+	ComplexityAIPct float64                  `json:"complexity_ai_pct"` // AI% with lines weighted by the complexity of their code
+	ComplexityLines float64                  `json:"complexity_lines,omitempty"`
+	AIComplexity   float64                   `json:"ai_complexity_lines,omitempty"`
 	ByAuthorship   map[string]int            `json:"by_authorship"`
 	ByWorkType     map[string]WorkTypeSummary `json:"by_work_type"`
+	ByPackage      map[string]PackageSummary `json:"by_package,omitempty"` // when Options.Packages is configured
+	ByModel        map[string]ModelSummary   `json:"by_model,omitempty"`   // AI work by the model that did it
 	Files          []FileReport              `json:"files"`
+	Coverage       *CoverageSummary          `json:"coverage,omitempty"` // set by ApplyCoverage
+	Accuracy       *AccuracySummary          `json:"accuracy,omitempty"` // set by ApplyAccuracy
+	Excluded       []Exclusion               `json:"excluded,omitempty"` // files kept out or capped by Guard
+	Removed        []RemovedFile             `json:"removed,omitempty"`  // attributed files since deleted
+	Review         []ReviewItem              `json:"review,omitempty"`   // set by ApplyReview
+	Window         *Window                   `json:"window,omitempty"`   // when the report covers a time window
 }
 
 // WorkTypeSummary holds per-work-type aggregate data for the report.
@@ -55,26 +84,59 @@ type FileReport struct {
 	AIEventCount     int            `json:"ai_event_count"`
 	TotalLines       int            `json:"total_lines"`
 	AILines          int            `json:"ai_lines"`
+	DeletedLines     int            `json:"deleted_lines"`                 // lines removed since tracking began
+	AIDeletedLines   int            `json:"ai_deleted_lines"`              // removed lines matching an AI edit's old_string
+	HumanLines       int            `json:"human_lines,omitempty"`        // changed lines seen in human edit snapshots
+	RevisedLines     int            `json:"revised_lines,omitempty"`      // AI lines kept AI though a human since revised them
+	CorrectionEvents int            `json:"correction_events,omitempty"`  // AI events whose correction is known
+	CorrectedEvents  int            `json:"corrected_events,omitempty"`   // of them, those a human changed within the correction window
+	ComplexityAIPct  float64        `json:"complexity_ai_pct"`             // AI% with lines weighted by complexity
+	ComplexityLines  float64        `json:"complexity_lines,omitempty"`    // complexity-weighted changed lines
+	AIComplexity     float64        `json:"ai_complexity_lines,omitempty"` // complexity-weighted AI lines
 	AuthorshipLevel  string         `json:"authorship_level"`
+	Package          string         `json:"package,omitempty"`  // when Options.Packages is configured
+	Coverage         *FileCoverage  `json:"coverage,omitempty"` // set by ApplyCoverage
+	Explanations     []Explanation  `json:"explanations,omitempty"` // set by Explain
+	Window           *Window        `json:"window,omitempty"`       // when the report covers a time window
 }
 
-// GenerateProject reads the store at dbPath and produces a full project report.
-func GenerateProject(dbPath string) (*ProjectReport, error) {
-	s, err := store.New(dbPath)
+// GenerateProject produces a project report from an open store for the
+// files f selects, with the file list shaped by f. For each tracked file,
+// it gets the git diff additions (lines changed since tracking began) and
+// compares them against Claude's session event content, so attribution is
+// based on changes, not full file content. Lines are matched and files
+// attributed as opts says. Files outside f.PathGlob are not attributed at
+// all, so a narrow glob also makes the report cheaper. Files are
+// attributed concurrently by a bounded pool of workers (each file costs
+// several git subprocesses); the report is the same regardless of
+// completion order.
+func GenerateProject(ctx context.Context, s *store.Store, scorer metrics.Scorer, f Filter, opts Options) (*ProjectReport, error) {
+	if err := f.Validate(); err != nil {
+		return nil, err
+	}
+	report, err := generateProject(ctx, s, scorer, f, opts, func(report *ProjectReport, fr FileReport) error {
+		addFileToReport(report, fr, scorer)
+		return nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("open store: %w", err)
+		return nil, err
 	}
-	defer s.Close()
 
-	return GenerateProjectFromStore(s)
+	finishProjectReport(report, scorer, opts.Packages)
+	f.shape(report)
+
+	return report, nil
 }
 
-// GenerateProjectFromStore produces a full project report from an open store.
-// For each tracked file, it gets the git diff additions (lines changed since
-// tracking began) and compares against Claude's session event content.
-// This ensures attribution is based on changes, not full file content.
-func GenerateProjectFromStore(s *store.Store) (*ProjectReport, error) {
-	projectPath, err := discoverProjectPath(s)
+// generateProject attributes the files f selects and passes each file's
+// report to add, with the report being built, in path order as soon as it
+// and every file before it are done. Only a few files per worker are
+// attributed ahead of the next one add waits for, so memory stays bounded
+// however many files there are. The returned report has the project's
+// models and exclusions; add accumulates the rest. An error from add
+// stops the report.
+func generateProject(ctx context.Context, s *store.Store, scorer metrics.Scorer, f Filter, opts Options, add func(*ProjectReport, FileReport) error) (*ProjectReport, error) {
+	projectPath, err := f.projectPath(s)
 	if err != nil {
 		return nil, err
 	}
@@ -84,135 +146,402 @@ func GenerateProjectFromStore(s *store.Store) (*ProjectReport, error) {
 	if err != nil {
 		return nil, fmt.Errorf("query session events: %w", err)
 	}
+	sessionEvents = f.Window.sessionEvents(sessionEvents)
 
 	// Extract content from each session event and group by file path.
-	claudeContentByFile := buildClaudeContentMap(s, sessionEvents)
+	claudeContentByFile, claudeDeletedByFile := buildClaudeContentMaps(s, sessionEvents)
+	eventsByID := make(map[int64]store.StoredSessionEvent, len(sessionEvents))
+	for _, se := range sessionEvents {
+		eventsByID[se.ID] = se
+	}
 
 	// Get all tracked files from attributions (so we know which files to report on).
 	attrs, err := s.QueryAttributionsWithWorkType(projectPath)
 	if err != nil {
 		return nil, fmt.Errorf("query attributions: %w", err)
 	}
+	attrs = f.Window.attributions(attrs)
+	sp := f.Window.resolve(ctx, projectPath, opts)
 
-	// Group attributions by file to get work type and event counts.
+	// Group attributions by file to get work type and event counts. Files
+	// git leaves out of the working tree (ignored, or outside a sparse
+	// checkout) are not attributed.
+	paths, _ := gitint.NewPathFilter(projectPath, config.RepoIgnore(projectPath))
 	fileAttrs := make(map[string][]store.AttributionWithWorkType)
+	var filePaths []string
 	for _, attr := range attrs {
+		if !f.matchesPath(projectPath, attr.FilePath) {
+			continue
+		}
+		if paths.Excluded(resolveFilePath(projectPath, attr.FilePath)) {
+			continue
+		}
+		if _, ok := fileAttrs[attr.FilePath]; !ok {
+			filePaths = append(filePaths, attr.FilePath)
+		}
 		fileAttrs[attr.FilePath] = append(fileAttrs[attr.FilePath], attr)
 	}
+	sort.Strings(filePaths)
 
 	report := &ProjectReport{
 		ProjectPath:  projectPath,
+		Scorer:       scorer.Name(),
 		ByAuthorship: make(map[string]int),
 		ByWorkType:   make(map[string]WorkTypeSummary),
+
+		CorrectionWindowDays: int(opts.correctionWindow() / (24 * time.Hour)),
+	}
+	if !f.Window.IsZero() {
+		report.Window = &f.Window
+	}
+
+	wtClassifier := newWorkTypeClassifier(s, projectPath)
+	match := opts.matchOptions(projectPath)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		i  int
+		fr *FileReport
+		ex *Exclusion
+		rm *RemovedFile
+	}
+	workers := min(opts.workers(), len(filePaths))
+	jobs := make(chan int)
+	done := make(chan result)
+	ahead := make(chan struct{}, max(reportWindow*workers, 1))
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				filePath := filePaths[i]
+				if rm := removedFile(sp, projectPath, filePath, fileAttrs[filePath]); rm != nil {
+					done <- result{i: i, rm: rm}
+					continue
+				}
+				fr, ex := attributeFile(ctx, s, wtClassifier, opts, match, sp, projectPath, filePath, fileAttrs[filePath], claudeContentByFile, claudeDeletedByFile)
+				done <- result{i: i, fr: fr, ex: ex}
+			}
+		}()
+	}
+	go func() {
+		defer close(jobs)
+		for i := range filePaths {
+			select {
+			case ahead <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			select {
+			case jobs <- i:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	// Results arrive in completion order; hand them to add in file order.
+	var addErr error
+	pending := make(map[int]result)
+	next := 0
+	for r := range done {
+		pending[r.i] = r
+		for {
+			r, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			next++
+			<-ahead
+			if addErr != nil {
+				continue
+			}
+			if r.fr != nil && f.selects(projectPath, r.fr) {
+				if addErr = add(report, *r.fr); addErr != nil {
+					cancel()
+					continue
+				}
+				addModels(report, fileAttrs[r.fr.FilePath], eventsByID)
+			}
+			if r.ex != nil {
+				report.Excluded = append(report.Excluded, *r.ex)
+			}
+			if r.rm != nil {
+				report.Removed = append(report.Removed, *r.rm)
+			}
+		}
 	}
 
-	wtClassifier := worktype.NewClassifier(s)
+	if addErr != nil {
+		return nil, addErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("generate report: %w", err)
+	}
+	return report, nil
+}
 
-	for filePath, fileAttrList := range fileAttrs {
-		// Verify the file still exists on disk.
-		absPath := resolveFilePath(projectPath, filePath)
-		if _, err := os.Stat(absPath); err != nil {
-			continue
-		}
+// reportWindow is how many files per worker generateProject attributes
+// ahead of the next file it reports.
+const reportWindow = 4
+
+// Options are the settings reports attribute lines with, passed along
+// like the scorer. The CLI sets them from config; the zero value matches
+// lines exactly and attributes one file per CPU at a time.
+type Options struct {
+	// LineMatch returns the line matching options for a project; nil
+	// means exact matching everywhere.
+	LineMatch func(projectPath string) metrics.MatchOptions
+	// Workers bounds how many files are attributed at once; 0 means
+	// runtime.NumCPU().
+	Workers int
+	// Packages maps path globs, relative to the project root, to package
+	// names so monorepo reports break down by package; when empty,
+	// reports have no per-package breakdown.
+	Packages map[string]string
+	// Guard keeps large generated and binary files out of reports, or
+	// caps the lines they contribute; the zero value excludes binary
+	// files only.
+	Guard metrics.FileGuard
+	// DiffAlgorithm is the git diff algorithm reports read changes with,
+	// one of the metrics.Diff* names; "" or an unknown name means
+	// metrics.DiffMyers. It should match the one session events were
+	// diffed with.
+	DiffAlgorithm string
+	// CorrectionWindow is how soon after an AI edit a human change to its
+	// lines counts as a correction; 0 means
+	// survival.DefaultCorrectionWindow.
+	CorrectionWindow time.Duration
+	// Git runs the git commands reports need; nil means an executor with
+	// gitexec's default limits, shared by every report in the process.
+	Git *gitexec.Executor
+}
 
-		// Find Claude's content for this file (using suffix matching for paths).
-		claudeContents := findClaudeContent(filePath, claudeContentByFile)
+// defaultGit is the executor of reports whose options set none.
+var defaultGit = gitexec.New(gitexec.DefaultMaxProcesses, gitexec.DefaultCacheBytes)
 
-		// Get the changed lines (git diff additions) instead of full file.
-		changedContent, baseContent := getChangedLinesWithBase(s, projectPath, filePath)
+// correctionWindow returns the window corrections are counted within.
+func (o Options) correctionWindow() time.Duration {
+	if o.CorrectionWindow > 0 {
+		return o.CorrectionWindow
+	}
+	return survival.DefaultCorrectionWindow
+}
 
-		// Compute line-level attribution against the changes.
-		la := metrics.ComputeLineAttribution(changedContent, claudeContents, baseContent)
+// git returns the executor to run git commands through.
+func (o Options) git() *gitexec.Executor {
+	if o.Git != nil {
+		return o.Git
+	}
+	return defaultGit
+}
 
-		// Skip files with no changed lines (e.g. fully reverted).
-		if la.TotalLines == 0 {
-			continue
-		}
+// matchOptions returns the line matching options for projectPath.
+func (o Options) matchOptions(projectPath string) metrics.MatchOptions {
+	if o.LineMatch == nil {
+		return metrics.MatchOptions{}
+	}
+	return o.LineMatch(projectPath)
+}
 
-		// Determine work type from attributions or content.
-		wt := fileAttrList[0].WorkType
-		if wt == "" {
-			wt = string(wtClassifier.ClassifyFile(filePath, "", ""))
-		}
+// workers returns how many files to attribute at once.
+func (o Options) workers() int {
+	if o.Workers > 0 {
+		return o.Workers
+	}
+	return runtime.NumCPU()
+}
 
-		// Compute AI%.
-		aiPct := 0.0
-		if la.TotalLines > 0 {
-			aiPct = float64(la.AILines) / float64(la.TotalLines) * 100.0
-		}
+// countedLines returns a file's changed lines and deleted content without
+// comment-only lines when opts excludes comments, and unchanged otherwise.
+// content is the file the changed lines are numbered in.
+func countedLines(opts metrics.MatchOptions, filePath, content string, numbers []int, added []string, deletedContent string) ([]int, []string, string) {
+	if !opts.ExcludeComments {
+		return numbers, added, deletedContent
+	}
+	numbers, added = metrics.DropCommentLines(filePath, content, numbers, added)
+	return numbers, added, metrics.StripCommentLines(filePath, deletedContent)
+}
 
-		// Compute 3-level authorship from line ratio.
-		level := "mostly_human"
-		switch {
-		case aiPct > 70:
-			level = "mostly_ai"
-		case aiPct >= 30:
-			level = "mixed"
-		}
+// attributeFile computes the line-level attribution for one tracked file
+// over sp. Returns a nil report for files that no longer exist, have no
+// changed lines or are excluded by opts.Guard; the exclusion says why a guard
+// excluded or capped the file.
+func attributeFile(ctx context.Context, s *store.Store, wtClassifier *worktype.Classifier, opts Options, match metrics.MatchOptions, sp span, projectPath, filePath string, fileAttrList []store.AttributionWithWorkType, claudeContentByFile, claudeDeletedByFile map[string][]string) (*FileReport, *Exclusion) {
+	// Verify the file still exists on disk (or at the window's end).
+	absPath := resolveFilePath(projectPath, filePath)
+	if _, err := os.Stat(absPath); err != nil && sp.end == "" {
+		return nil, nil
+	}
+	content := sp.content(ctx, projectPath, filePath, opts)
+	if reason := opts.Guard.Check(content); reason != "" {
+		return nil, newExclusion(filePath, reason, content)
+	}
 
-		fr := FileReport{
-			FilePath:        filePath,
-			WorkType:        wt,
-			MeaningfulAIPct: aiPct,
-			RawAIPct:        aiPct,
-			TotalLines:      la.TotalLines,
-			AILines:         la.AILines,
-			AuthorshipLevel: level,
-			TotalEvents:     len(fileAttrList),
-			AuthorshipCounts: map[string]int{level: len(fileAttrList)},
-		}
+	// Find Claude's content for this file (using suffix matching for paths).
+	claudeContents := findClaudeContent(filePath, claudeContentByFile)
 
-		// Count AI events from attributions.
-		for _, attr := range fileAttrList {
-			if isAIAuthorship(attr.AuthorshipLevel) {
-				fr.AIEventCount++
-			}
+	// Get the changed lines (git diff additions) instead of full file.
+	numbers, added, deletedContent, baseContent := sp.changedLines(ctx, s, projectPath, filePath, opts)
+	numbers, added, deletedContent = countedLines(match, filePath, content, numbers, added, deletedContent)
+	var exclusion *Exclusion
+	numbers, added, capped := opts.Guard.CapLines(numbers, added)
+	if capped {
+		exclusion = newExclusion(filePath, metrics.GuardCapped, content)
+	}
+
+	// Compute line-level attribution against the changes, and weight it by
+	// the complexity of the code the changed lines sit in.
+	ai, revised := metrics.ClassifyLinesRevised(added, claudeContents, baseContent, match)
+	la := metrics.CountLines(added, ai)
+	cx := metrics.WeighLines(numbers, added, ai, metrics.LineComplexity(filePath, content))
+	del := metrics.ComputeLineAttributionWithOptions(deletedContent, findClaudeContent(filePath, claudeDeletedByFile), "", match)
+
+	// Skip files with no changed lines (e.g. fully reverted).
+	if la.TotalLines == 0 && del.TotalLines == 0 {
+		return nil, nil
+	}
+
+	// Determine work type from attributions or content. A .gapmap.toml
+	// work type applies to attributions stored before it was added.
+	wt := fileAttrList[0].WorkType
+	if _, repoRule := wtClassifier.PathRule(filePath); wt == "" || repoRule {
+		wt = string(wtClassifier.ClassifyFile(filePath, "", ""))
+	}
+
+	// Compute AI%.
+	aiPct := 0.0
+	if la.TotalLines > 0 {
+		aiPct = float64(la.AILines) / float64(la.TotalLines) * 100.0
+	}
+
+	// Compute 3-level authorship from line ratio.
+	level := "mostly_human"
+	switch {
+	case aiPct > 70:
+		level = "mostly_ai"
+	case aiPct >= 30:
+		level = "mixed"
+	}
+
+	fr := &FileReport{
+		FilePath:         filePath,
+		WorkType:         wt,
+		MeaningfulAIPct:  aiPct,
+		RawAIPct:         aiPct,
+		TotalLines:       la.TotalLines,
+		AILines:          la.AILines,
+		DeletedLines:     del.TotalLines,
+		AIDeletedLines:   del.AILines,
+		RevisedLines:     metrics.CountRevised(revised),
+		ComplexityAIPct:  cx.AIPct(),
+		ComplexityLines:  cx.Total,
+		AIComplexity:     cx.AI,
+		AuthorshipLevel:  level,
+		TotalEvents:      len(fileAttrList),
+		AuthorshipCounts: map[string]int{level: len(fileAttrList)},
+	}
+	fr.HumanLines = snapshotHumanLines(s, absPath, added, ai)
+	countCorrections(s, fr, fileAttrList, opts.correctionWindow())
+
+	// Count AI events from attributions.
+	for _, attr := range fileAttrList {
+		if isAIAuthorship(attr.AuthorshipLevel) {
+			fr.AIEventCount++
 		}
+	}
+
+	return fr, exclusion
+}
 
-		report.Files = append(report.Files, fr)
-		report.TotalFiles++
-		report.TotalLines += la.TotalLines
-		report.AILines += la.AILines
-		report.ByAuthorship[level]++
+// addFileToReport adds a file's lines to the project totals and its
+// authorship level and work-type aggregates.
+func addFileToReport(report *ProjectReport, fr FileReport, scorer metrics.Scorer) {
+	report.Files = append(report.Files, fr)
+	addFileTotals(report, fr, scorer)
+}
 
-		// Aggregate by work type.
-		wtKey := wt
-		wtWeight, ok := worktype.WorkTypeWeights[worktype.WorkType(wtKey)]
-		if !ok {
-			wtWeight = worktype.WorkTypeWeights[worktype.CoreLogic]
+// addFileTotals is addFileToReport without keeping the file.
+func addFileTotals(report *ProjectReport, fr FileReport, scorer metrics.Scorer) {
+	report.TotalFiles++
+	report.TotalLines += fr.TotalLines
+	report.AILines += fr.AILines
+	report.HumanLines += fr.HumanLines
+	report.RevisedLines += fr.RevisedLines
+	report.CorrectionEvents += fr.CorrectionEvents
+	report.CorrectedEvents += fr.CorrectedEvents
+	report.DeletedLines += fr.DeletedLines
+	report.AIDeletedLines += fr.AIDeletedLines
+	report.ComplexityLines += fr.ComplexityLines
+	report.AIComplexity += fr.AIComplexity
+	report.ByAuthorship[fr.AuthorshipLevel]++
+
+	// Aggregate by work type.
+	summary := report.ByWorkType[fr.WorkType]
+	summary.Files++
+	summary.AILines += fr.AILines
+	summary.TotalLines += fr.TotalLines
+	summary.AIEvents += fr.AIEventCount
+	summary.TotalEvents += fr.TotalEvents
+	summary.Weight = scorer.Weight(fr.WorkType)
+	summary.Tier = string(worktype.WorkTypeTier[worktype.WorkType(fr.WorkType)])
+	report.ByWorkType[fr.WorkType] = summary
+}
+
+// finishProjectReport computes project-level and per-work-type percentages
+// once all files are added, assigns them to packages, and sorts files by
+// AI% descending.
+func finishProjectReport(report *ProjectReport, scorer metrics.Scorer, packages map[string]string) {
+	scores := make([]metrics.FileScore, 0, len(report.Files))
+	for _, fr := range report.Files {
+		scores = append(scores, fileScore(fr))
+	}
+	finishTotals(report, scorer, scores)
+
+	assignPackages(report, packages)
+
+	// Sort files by AI% descending, then by path for a stable order.
+	sort.Slice(report.Files, func(i, j int) bool {
+		a, b := report.Files[i], report.Files[j]
+		if a.MeaningfulAIPct != b.MeaningfulAIPct {
+			return a.MeaningfulAIPct > b.MeaningfulAIPct
 		}
-		summary := report.ByWorkType[wtKey]
-		summary.Files++
-		summary.AILines += la.AILines
-		summary.TotalLines += la.TotalLines
-		summary.AIEvents += fr.AIEventCount
-		summary.TotalEvents += fr.TotalEvents
-		summary.Weight = wtWeight
-		tier := worktype.WorkTypeTier[worktype.WorkType(wtKey)]
-		summary.Tier = string(tier)
-		report.ByWorkType[wtKey] = summary
+		return a.FilePath < b.FilePath
+	})
+}
+
+// fileScore returns what the scorer needs of a file.
+func fileScore(fr FileReport) metrics.FileScore {
+	return metrics.FileScore{
+		WorkType:   fr.WorkType,
+		AILines:    fr.AILines,
+		TotalLines: fr.TotalLines,
 	}
+}
 
+// finishTotals computes the project-level and per-work-type percentages
+// from the totals added and the scores of every file.
+func finishTotals(report *ProjectReport, scorer metrics.Scorer, scores []metrics.FileScore) {
 	// Compute project-level AI%.
 	if report.TotalLines > 0 {
 		report.RawAIPct = float64(report.AILines) / float64(report.TotalLines) * 100.0
 	}
-
-	// Meaningful AI% uses work-type weights.
-	var totalWeightedAI, totalWeightedAll float64
-	for _, fr := range report.Files {
-		wt := worktype.WorkType(fr.WorkType)
-		weight, ok := worktype.WorkTypeWeights[wt]
-		if !ok {
-			weight = worktype.WorkTypeWeights[worktype.CoreLogic]
-		}
-		totalWeightedAI += float64(fr.AILines) * weight
-		totalWeightedAll += float64(fr.TotalLines) * weight
-	}
-	if totalWeightedAll > 0 {
-		report.MeaningfulAIPct = totalWeightedAI / totalWeightedAll * 100.0
+	report.ComplexityAIPct = metrics.ComplexityAttribution{Total: report.ComplexityLines, AI: report.AIComplexity}.AIPct()
+	if report.CorrectionEvents > 0 {
+		report.CorrectionRate = float64(report.CorrectedEvents) / float64(report.CorrectionEvents) * 100.0
 	}
 
+	// Meaningful AI% is delegated to the scorer.
+	report.MeaningfulAIPct = scorer.Score(scores)
+
 	// Compute per-work-type AI%.
 	for key, summary := range report.ByWorkType {
 		if summary.TotalLines > 0 {
@@ -220,13 +549,6 @@ func GenerateProjectFromStore(s *store.Store) (*ProjectReport, error) {
 		}
 		report.ByWorkType[key] = summary
 	}
-
-	// Sort files by AI% descending.
-	sort.Slice(report.Files, func(i, j int) bool {
-		return report.Files[i].MeaningfulAIPct > report.Files[j].MeaningfulAIPct
-	})
-
-	return report, nil
 }
 
 // GenerateFile reads the store at dbPath and produces a report for a single file.
@@ -240,15 +562,31 @@ func GenerateFile(dbPath string, filePath string) (*FileReport, error) {
 	return GenerateFileFromStore(s, filePath)
 }
 
-// GenerateFileFromStore produces a single-file report from an open store.
+// GenerateFileFromStore produces a single-file report from an open store
+// using the default Options.
 func GenerateFileFromStore(s *store.Store, filePath string) (*FileReport, error) {
+	return GenerateFileWindow(s, filePath, Window{}, Options{})
+}
+
+// GenerateFileWindow is GenerateFileFromStore restricted to the
+// attributions and AI edits made in w, with lines matched as opts says.
+func GenerateFileWindow(s *store.Store, filePath string, w Window, opts Options) (*FileReport, error) {
+	if err := w.Validate(); err != nil {
+		return nil, err
+	}
+	if resolved, err := s.ResolvePath(filePath); err == nil {
+		filePath = resolved
+	}
 	attrs, err := s.QueryAttributionsByFileWithWorkType(filePath)
 	if err != nil {
 		return nil, fmt.Errorf("query attributions for file %q: %w", filePath, err)
 	}
 
 	if len(attrs) == 0 {
-		return nil, fmt.Errorf("no attribution data found for file %q", filePath)
+		return nil, fmt.Errorf("%w for file %q", ErrNoData, filePath)
+	}
+	if attrs = w.attributions(attrs); len(attrs) == 0 {
+		return nil, fmt.Errorf("%w for file %q from %s", ErrNoData, filePath, w)
 	}
 
 	// Discover project path for resolving file.
@@ -256,24 +594,43 @@ func GenerateFileFromStore(s *store.Store, filePath string) (*FileReport, error)
 
 	// Verify the file exists.
 	absPath := resolveFilePath(projectPath, filePath)
-	if _, err := os.Stat(absPath); err != nil {
+	if paths, _ := gitint.NewPathFilter(projectPath, config.RepoIgnore(projectPath)); paths.Sparse(absPath) {
+		return nil, fmt.Errorf("file %q is outside the sparse checkout", filePath)
+	} else if paths.IgnoredByRules(absPath) {
+		return nil, fmt.Errorf("file %q is ignored by %s", filePath, config.RepoSettingsFile)
+	} else if paths.Excluded(absPath) {
+		return nil, fmt.Errorf("file %q is ignored by git", filePath)
+	}
+	sp := w.resolve(context.Background(), projectPath, opts)
+	if _, err := os.Stat(absPath); err != nil && sp.end == "" {
 		return nil, fmt.Errorf("read file %q: %w", absPath, err)
 	}
+	content := sp.content(context.Background(), projectPath, filePath, opts)
+	if reason := opts.Guard.Check(content); reason != "" {
+		return nil, fmt.Errorf("file %q is excluded from attribution (%s)", filePath, reason)
+	}
 
 	// Get Claude's session event content for this file.
 	sessionEvents, err := s.QueryWriteEditSessionEvents()
 	if err != nil {
 		return nil, fmt.Errorf("query session events: %w", err)
 	}
+	sessionEvents = w.sessionEvents(sessionEvents)
 
-	claudeContentByFile := buildClaudeContentMap(s, sessionEvents)
+	claudeContentByFile, claudeDeletedByFile := buildClaudeContentMaps(s, sessionEvents)
 	claudeContents := findClaudeContent(filePath, claudeContentByFile)
 
 	// Get the changed lines (git diff additions) instead of full file.
-	changedContent, baseContent := getChangedLinesWithBase(s, projectPath, filePath)
+	numbers, added, deletedContent, baseContent := sp.changedLines(context.Background(), s, projectPath, filePath, opts)
+	match := opts.matchOptions(projectPath)
+	numbers, added, deletedContent = countedLines(match, filePath, content, numbers, added, deletedContent)
+	numbers, added, _ = opts.Guard.CapLines(numbers, added)
 
 	// Compute line-level attribution against the changes.
-	la := metrics.ComputeLineAttribution(changedContent, claudeContents, baseContent)
+	ai, revised := metrics.ClassifyLinesRevised(added, claudeContents, baseContent, match)
+	la := metrics.CountLines(added, ai)
+	cx := metrics.WeighLines(numbers, added, ai, metrics.LineComplexity(filePath, content))
+	del := metrics.ComputeLineAttributionWithOptions(deletedContent, findClaudeContent(filePath, claudeDeletedByFile), "", match)
 
 	aiPct := 0.0
 	if la.TotalLines > 0 {
@@ -289,8 +646,8 @@ func GenerateFileFromStore(s *store.Store, filePath string) (*FileReport, error)
 	}
 
 	wt := attrs[0].WorkType
-	if wt == "" {
-		wtClassifier := worktype.NewClassifier(s)
+	wtClassifier := newWorkTypeClassifier(s, projectPath)
+	if _, repoRule := wtClassifier.PathRule(filePath); wt == "" || repoRule {
 		wt = string(wtClassifier.ClassifyFile(filePath, "", ""))
 	}
 
@@ -301,10 +658,21 @@ func GenerateFileFromStore(s *store.Store, filePath string) (*FileReport, error)
 		RawAIPct:        aiPct,
 		TotalLines:      la.TotalLines,
 		AILines:         la.AILines,
+		DeletedLines:    del.TotalLines,
+		AIDeletedLines:  del.AILines,
+		RevisedLines:    metrics.CountRevised(revised),
+		ComplexityAIPct: cx.AIPct(),
+		ComplexityLines: cx.Total,
+		AIComplexity:    cx.AI,
 		AuthorshipLevel: level,
 		TotalEvents:     len(attrs),
 		AuthorshipCounts: map[string]int{level: len(attrs)},
 	}
+	fr.HumanLines = snapshotHumanLines(s, absPath, added, ai)
+	countCorrections(s, fr, attrs, opts.correctionWindow())
+	if !w.IsZero() {
+		fr.Window = &w
+	}
 
 	for _, attr := range attrs {
 		if isAIAuthorship(attr.AuthorshipLevel) {
@@ -315,122 +683,205 @@ func GenerateFileFromStore(s *store.Store, filePath string) (*FileReport, error)
 	return fr, nil
 }
 
-// getChangedLinesWithBase returns the changed lines for a file and the base file
-// content (before tracking started). The base content is used to subtract
-// pre-existing patterns from AI attribution.
-// If git diff is unavailable or the file was created during tracking, it falls
-// back to reading the full file content with empty base.
-func getChangedLinesWithBase(s *store.Store, projectPath, filePath string) (changed string, base string) {
-	absPath := resolveFilePath(projectPath, filePath)
+// getChangedLines returns the lines added to a file since tracking began,
+// with their line numbers in the current file, the lines removed from it,
+// and the base file content (before tracking started). The base content is
+// used to subtract pre-existing patterns from AI attribution. If git diff is
+// unavailable or the file was created during tracking, every line of the
+// file counts as added, with empty base and no deletions.
+func getChangedLines(ctx context.Context, s *store.Store, projectPath, filePath string, opts Options) (numbers []int, added []string, deleted, base string) {
+	baseCommit := trackingBaseCommit(ctx, s, projectPath, filePath, opts)
+	if baseCommit == "" {
+		content := readFileContent(resolveFilePath(projectPath, filePath))
+		if content == "" {
+			return nil, nil, "", ""
+		}
+		for i, line := range strings.Split(strings.TrimSuffix(content, "\n"), "\n") {
+			numbers = append(numbers, i+1)
+			added = append(added, line)
+		}
+		return numbers, added, "", ""
+	}
+
+	// Get git diff additions and removals between the base commit and the
+	// current working tree. If both are empty, the file is unchanged from
+	// the base commit (e.g. all changes were reverted).
+	diff := gitDiff(ctx, projectPath, filePath, baseCommit, opts)
+	numbers, added = parseDiffAdditionsNumbered(diff)
+	deleted = parseDiffDeletions(diff)
+	if len(added) == 0 && deleted == "" {
+		return nil, nil, "", ""
+	}
+
+	// Get the base file content at the base commit.
+	return numbers, added, deleted, gitShowFile(ctx, projectPath, filePath, baseCommit, opts)
+}
+
+// trackingBaseCommit returns the commit holding the file as it was before
+// tracking began: the parent of the commit the earliest attributed change
+// landed in, which follows that commit through rebases and amends, or else
+// the latest commit that touched filePath before its earliest attribution.
+// Returns empty string if there is none.
+func trackingBaseCommit(ctx context.Context, s *store.Store, projectPath, filePath string, opts Options) string {
+	if hash, err := s.QueryCommitForFile(filePath); err == nil && hash != "" {
+		if parent := gitParentCommit(ctx, projectPath, hash, opts); parent != "" {
+			return parent
+		}
+	}
 
-	// Find the earliest attribution timestamp for this file.
 	ts, err := s.QueryEarliestAttributionTimestamp(filePath)
 	if err != nil || ts == "" {
-		return readFileContent(absPath), ""
+		return ""
 	}
-
-	// Parse the timestamp to find a base commit before tracking started.
 	attrTime, err := time.Parse(time.RFC3339Nano, ts)
 	if err != nil {
-		return readFileContent(absPath), ""
-	}
-
-	// Find the latest commit before the earliest attribution.
-	baseCommit := findBaseCommit(projectPath, filePath, attrTime)
-	if baseCommit == "" {
-		return readFileContent(absPath), ""
+		return ""
 	}
+	return findBaseCommit(ctx, projectPath, filePath, attrTime, opts)
+}
 
-	// Get git diff additions between the base commit and current working tree.
-	// If additions is empty, the file is unchanged from the base commit
-	// (e.g. all changes were reverted), so there are zero changed lines.
-	additions := gitDiffAdditions(projectPath, filePath, baseCommit)
-	if additions == "" {
-		return "", ""
+// gitParentCommit returns the first parent of commit, or empty string if
+// it has none or is not in the repository.
+func gitParentCommit(ctx context.Context, projectPath, commit string, opts Options) string {
+	out, err := opts.git().Output(ctx, projectPath, "rev-parse", "--verify", "--quiet", commit+"^")
+	if err != nil {
+		return ""
 	}
-
-	// Get the base file content at the base commit.
-	baseContent := gitShowFile(projectPath, filePath, baseCommit)
-
-	return additions, baseContent
+	return strings.TrimSpace(string(out))
 }
 
-// gitShowFile returns the content of a file at a specific commit.
-func gitShowFile(projectPath, filePath, commit string) string {
+// gitShowFile returns the content of a file at a specific commit, or
+// empty string if it does not exist there.
+func gitShowFile(ctx context.Context, projectPath, filePath, commit string, opts Options) string {
 	absPath := resolveFilePath(projectPath, filePath)
 	relPath, err := filepath.Rel(projectPath, absPath)
 	if err != nil {
 		relPath = filePath
 	}
 
-	cmd := exec.Command("git", "show", commit+":"+relPath)
-	cmd.Dir = projectPath
-	out, err := cmd.Output()
+	content, err := opts.git().Show(ctx, projectPath, commit, relPath)
 	if err != nil {
 		return ""
 	}
-	return string(out)
+	return content
 }
 
 // findBaseCommit finds the latest git commit hash that modified the file
 // before the given timestamp. Returns empty string if not found.
-func findBaseCommit(projectPath, filePath string, before time.Time) string {
+func findBaseCommit(ctx context.Context, projectPath, filePath string, before time.Time, opts Options) string {
 	absPath := resolveFilePath(projectPath, filePath)
 	relPath, err := filepath.Rel(projectPath, absPath)
 	if err != nil {
 		relPath = filePath
 	}
 
-	cmd := exec.Command("git", "log",
+	out, err := opts.git().Output(ctx, projectPath, "log",
 		"--before="+before.UTC().Format(time.RFC3339),
 		"--format=%H",
 		"-1",
 		"--", relPath,
 	)
-	cmd.Dir = projectPath
-	out, err := cmd.Output()
 	if err != nil {
 		return ""
 	}
 	return strings.TrimSpace(string(out))
 }
 
-// gitDiffAdditions runs git diff between a base commit and the current working
-// tree, returning only the added lines (without the "+" prefix).
-func gitDiffAdditions(projectPath, filePath, baseCommit string) string {
+// gitDiff returns the unified diff of a file between a base commit and the
+// current working tree, or empty string on error.
+func gitDiff(ctx context.Context, projectPath, filePath, baseCommit string, opts Options) string {
 	absPath := resolveFilePath(projectPath, filePath)
 	relPath, err := filepath.Rel(projectPath, absPath)
 	if err != nil {
 		relPath = filePath
 	}
 
-	cmd := exec.Command("git", "diff", baseCommit, "--", relPath)
-	cmd.Dir = projectPath
-	out, err := cmd.Output()
+	out, err := opts.git().Output(ctx, projectPath, "diff", opts.diffAlgorithmFlag(), baseCommit, "--", relPath)
 	if err != nil {
 		return ""
 	}
+	return string(out)
+}
 
-	return parseDiffAdditions(string(out))
+// diffAlgorithmFlag returns the git diff flag for o.DiffAlgorithm, so the
+// diffs reports read from git match those of Write events.
+func (o Options) diffAlgorithmFlag() string {
+	if o.DiffAlgorithm == "" || metrics.ValidDiffAlgorithm(o.DiffAlgorithm) != nil {
+		return "--diff-algorithm=" + metrics.DiffMyers
+	}
+	return "--diff-algorithm=" + o.DiffAlgorithm
 }
 
 // parseDiffAdditions extracts added lines from unified diff output.
 // It returns only the content of lines starting with "+" (excluding the
 // "+++" file header) joined by newlines.
 func parseDiffAdditions(diff string) string {
-	var additions []string
+	return parseDiffLines(diff, "+", "+++")
+}
+
+// parseDiffDeletions extracts removed lines from unified diff output,
+// excluding the "---" file header.
+func parseDiffDeletions(diff string) string {
+	return parseDiffLines(diff, "-", "---")
+}
+
+// parseDiffLines returns the content of diff lines starting with prefix but
+// not header, joined by newlines.
+func parseDiffLines(diff, prefix, header string) string {
+	var lines []string
 	scanner := bufio.NewScanner(strings.NewReader(diff))
 	for scanner.Scan() {
 		line := scanner.Text()
-		if strings.HasPrefix(line, "+") && !strings.HasPrefix(line, "+++") {
-			// Strip the leading "+" to get the actual line content.
-			additions = append(additions, line[1:])
+		if strings.HasPrefix(line, prefix) && !strings.HasPrefix(line, header) {
+			// Strip the leading marker to get the actual line content.
+			lines = append(lines, line[1:])
 		}
 	}
-	if len(additions) == 0 {
+	if len(lines) == 0 {
 		return ""
 	}
-	return strings.Join(additions, "\n") + "\n"
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// parseDiffAdditionsNumbered returns the added lines of a unified diff with
+// their line numbers in the new file, counted from each hunk header
+// ("@@ -a,b +c,d @@") over context and added lines.
+func parseDiffAdditionsNumbered(diff string) (numbers []int, lines []string) {
+	next := 0 // new-file line number of the next context or added line
+	inHunk := false
+	scanner := bufio.NewScanner(strings.NewReader(diff))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "@@") {
+			// "@@ -a,b +c,d @@ context": take c.
+			fields := strings.Fields(line)
+			inHunk = false
+			if len(fields) >= 3 && strings.HasPrefix(fields[2], "+") {
+				start, _, _ := strings.Cut(fields[2][1:], ",")
+				if n, err := strconv.Atoi(start); err == nil {
+					next, inHunk = n, true
+				}
+			}
+			continue
+		}
+		if strings.HasPrefix(line, "diff ") {
+			inHunk = false
+		}
+		if !inHunk {
+			continue // file headers
+		}
+		switch {
+		case strings.HasPrefix(line, "+"):
+			numbers = append(numbers, next)
+			lines = append(lines, line[1:])
+			next++
+		case line == "" || strings.HasPrefix(line, " "):
+			// Some tools strip the space from blank context lines.
+			next++
+		}
+	}
+	return numbers, lines
 }
 
 // readFileContent reads a file and returns its content as a string.
@@ -443,21 +894,35 @@ func readFileContent(path string) string {
 	return string(data)
 }
 
-// buildClaudeContentMap extracts content from session event raw JSON and groups
-// it by file path.
+// buildClaudeContentMap reads the content session events wrote and groups it
+// by file path.
 func buildClaudeContentMap(s *store.Store, events []store.StoredSessionEvent) map[string][]string {
-	result := make(map[string][]string)
+	added, _ := buildClaudeContentMaps(s, events)
+	return added
+}
+
+// buildClaudeContentMaps is buildClaudeContentMap that also returns the lines
+// Claude's edits removed, grouped by file path.
+func buildClaudeContentMaps(s *store.Store, events []store.StoredSessionEvent) (added, deleted map[string][]string) {
+	added = make(map[string][]string)
+	deleted = make(map[string][]string)
+	contents, err := s.QueryWriteEditSessionContent(sessionparser.ExtractContent)
+	if err != nil {
+		return added, deleted
+	}
 	for _, se := range events {
-		rawJSON, err := s.QuerySessionEventRawJSON(se.ID)
-		if err != nil {
+		content, ok := contents[se.ID]
+		if !ok {
 			continue
 		}
-		content := sessionparser.ExtractDiffContent(rawJSON)
-		if content != "" {
-			result[se.FilePath] = append(result[se.FilePath], content)
+		if content.Added != "" {
+			added[se.FilePath] = append(added[se.FilePath], content.Added)
+		}
+		if content.Deleted != "" {
+			deleted[se.FilePath] = append(deleted[se.FilePath], content.Deleted)
 		}
 	}
-	return result
+	return added, deleted
 }
 
 // findClaudeContent finds all Claude-authored content for a file, using suffix
@@ -501,14 +966,18 @@ func resolveFilePath(projectPath, filePath string) string {
 // isAIAuthorship returns true if the authorship level indicates AI involvement.
 func isAIAuthorship(level string) bool {
 	switch level {
-	case "mostly_ai", "fully_ai", "ai_first_human_revised":
+	case "mostly_ai", "fully_ai", "ai_first_human_revised", "ai_suggested_human_written":
 		return true
 	}
 	return false
 }
 
-// discoverProjectPath finds the project path from the attributions table.
-func discoverProjectPath(s *store.Store) (string, error) {
+// ErrNoData is wrapped by the errors of reports that find no attribution
+// data to report on.
+var ErrNoData = errors.New("no attribution data found")
+
+// DiscoverProjectPath finds the project path from the attributions table.
+func DiscoverProjectPath(s *store.Store) (string, error) {
 	rows, err := s.DB().Query("SELECT DISTINCT project_path FROM attributions ORDER BY project_path LIMIT 1")
 	if err != nil {
 		return "", fmt.Errorf("discover project path: %w", err)
@@ -516,7 +985,7 @@ func discoverProjectPath(s *store.Store) (string, error) {
 	defer rows.Close()
 
 	if !rows.Next() {
-		return "", fmt.Errorf("no attribution data found in database")
+		return "", fmt.Errorf("%w in database", ErrNoData)
 	}
 
 	var path string
@@ -1,13 +1,15 @@
 package report
 
 import (
+	"context"
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"sort"
 	"strings"
+	"time"
 
+	"github.com/anthropic/gap-map/internal/config"
 	"github.com/anthropic/gap-map/internal/gitint"
 	"github.com/anthropic/gap-map/internal/metrics"
 	"github.com/anthropic/gap-map/internal/store"
@@ -15,24 +17,45 @@ import (
 
 // GenerateProjectForBranch produces a project report scoped to a specific branch,
 // using the merge-base diff between baseBranch and branch to determine changed lines.
-func GenerateProjectForBranch(s *store.Store, branch, baseBranch string) (*ProjectReport, error) {
+// Lines are matched as opts says.
+func GenerateProjectForBranch(s *store.Store, branch, baseBranch string, opts Options) (*ProjectReport, error) {
 	// Discover project path from any attributions in the DB.
-	projectPath, err := discoverProjectPath(s)
+	projectPath, err := DiscoverProjectPath(s)
 	if err != nil {
-		return nil, fmt.Errorf("no attribution data found: %w", err)
+		return nil, err
 	}
+	return GenerateProjectForBranchIn(s, projectPath, branch, baseBranch, opts)
+}
 
+// GenerateProjectForBranchIn is GenerateProjectForBranch for the project at
+// projectPath, for stores that hold more than one.
+func GenerateProjectForBranchIn(s *store.Store, projectPath, branch, baseBranch string, opts Options) (*ProjectReport, error) {
 	// Verify the branch has at least one attribution (to reject nonexistent branches).
 	branchAttrs, err := s.QueryAttributionsByBranch(projectPath, branch)
 	if err != nil {
 		return nil, fmt.Errorf("query attributions for branch %q: %w", branch, err)
 	}
 	if len(branchAttrs) == 0 {
+		if branches, err := s.QueryBranchAttributions(projectPath); err == nil {
+			for _, b := range branches {
+				if b.Branch == branch && b.Archived != "" {
+					return nil, fmt.Errorf("branch %q was %s; its attributions are archived", branch, b.Archived)
+				}
+			}
+		}
 		return nil, fmt.Errorf("no attribution data for branch %q", branch)
 	}
+	return generateBranchReport(s, projectPath, branch, baseBranch, opts)
+}
 
+// generateBranchReport produces the report of the lines changed between the
+// merge-base of baseBranch and branch, whether or not any attribution was
+// recorded on branch itself.
+func generateBranchReport(s *store.Store, projectPath, branch, baseBranch string, opts Options) (*ProjectReport, error) {
 	// Compute merge-base between baseBranch and branch.
-	mergeBase := gitMergeBaseCommit(projectPath, baseBranch, branch)
+	// CI checkouts may only have remote-tracking refs for either branch.
+	branchRef := resolveRef(projectPath, branch, opts)
+	mergeBase := gitMergeBaseCommit(projectPath, resolveRef(projectPath, baseBranch, opts), branchRef, opts)
 	if mergeBase == "" {
 		return nil, fmt.Errorf("cannot compute merge-base for %s and %s", baseBranch, branch)
 	}
@@ -47,6 +70,7 @@ func GenerateProjectForBranch(s *store.Store, branch, baseBranch string) (*Proje
 		return nil, fmt.Errorf("query session events: %w", err)
 	}
 	claudeContentByFile := buildClaudeContentMap(s, sessionEvents)
+	match := opts.matchOptions(projectPath)
 
 	// Get ALL attributions for the project (any branch) so stacked branch
 	// reports include files attributed on ancestor branches.
@@ -58,27 +82,39 @@ func GenerateProjectForBranch(s *store.Store, branch, baseBranch string) (*Proje
 	for _, attr := range allAttrs {
 		fileAttrs[attr.FilePath] = append(fileAttrs[attr.FilePath], attr)
 	}
+	// Files outside a sparse checkout are read from the branch instead of
+	// the working tree; ignored files are left out.
+	paths, _ := gitint.NewPathFilter(projectPath, config.RepoIgnore(projectPath))
+	wtClassifier := newWorkTypeClassifier(s, projectPath)
 
 	report := &ProjectReport{
 		ProjectPath:  projectPath,
 		ByAuthorship: make(map[string]int),
 		ByWorkType:   make(map[string]WorkTypeSummary),
+
+		CorrectionWindowDays: int(opts.correctionWindow() / (24 * time.Hour)),
 	}
 
 	for filePath, fileAttrList := range fileAttrs {
+		absPath := resolveFilePath(projectPath, filePath)
+		sparse := paths.Sparse(absPath)
+		if !sparse && paths.Excluded(absPath) {
+			continue
+		}
+		inWorktree := onBranch && !sparse
+
 		// Get diff additions for this file between merge-base and branch.
 		var additions string
 		var baseContent string
 
-		if onBranch {
+		if inWorktree {
 			// Working tree diff (includes uncommitted changes).
-			additions = gitDiffAdditionsForBranch(projectPath, filePath, mergeBase, "")
+			additions = gitDiffAdditionsForBranch(projectPath, filePath, mergeBase, "", opts)
 			// If no tracked diff, check for untracked new files.
 			if additions == "" {
-				baseFileContent := gitShowFile(projectPath, filePath, mergeBase)
+				baseFileContent := gitShowFile(context.Background(), projectPath, filePath, mergeBase, opts)
 				if baseFileContent == "" {
 					// File doesn't exist at merge-base — it may be an untracked new file.
-					absPath := resolveFilePath(projectPath, filePath)
 					if _, statErr := os.Stat(absPath); statErr == nil {
 						additions = readFileContent(absPath)
 					}
@@ -86,28 +122,49 @@ func GenerateProjectForBranch(s *store.Store, branch, baseBranch string) (*Proje
 			}
 		} else {
 			// Committed-only diff (not on the branch).
-			additions = gitDiffAdditionsForBranch(projectPath, filePath, mergeBase, branch)
+			additions = gitDiffAdditionsForBranch(projectPath, filePath, mergeBase, branchRef, opts)
 		}
 
 		if additions == "" {
 			continue
 		}
 
+		// Apply the large and binary file guards to the file on the branch.
+		var content string
+		if inWorktree {
+			content = readFileContent(absPath)
+		} else {
+			content = gitShowFile(context.Background(), projectPath, filePath, branchRef, opts)
+		}
+		if reason := opts.Guard.Check(content); reason != "" {
+			report.Excluded = append(report.Excluded, *newExclusion(filePath, reason, content))
+			continue
+		}
+		if match.ExcludeComments {
+			additions = metrics.StripCommentLines(filePath, additions)
+		}
+		if _, lines, capped := opts.Guard.CapLines(nil, strings.Split(additions, "\n")); capped {
+			additions = strings.Join(lines, "\n")
+			report.Excluded = append(report.Excluded, *newExclusion(filePath, metrics.GuardCapped, content))
+		}
+
 		// Get base content at merge-base for pre-existing pattern subtraction.
-		baseContent = gitShowFile(projectPath, filePath, mergeBase)
+		baseContent = gitShowFile(context.Background(), projectPath, filePath, mergeBase, opts)
 
 		// Find Claude's content for this file.
 		claudeContents := findClaudeContent(filePath, claudeContentByFile)
 
 		// Compute line-level attribution.
-		la := metrics.ComputeLineAttribution(additions, claudeContents, baseContent)
+		la := metrics.ComputeLineAttributionWithOptions(additions, claudeContents, baseContent, match)
 		if la.TotalLines == 0 {
 			continue
 		}
 
 		// Determine work type.
 		wt := fileAttrList[0].WorkType
-		if wt == "" {
+		if _, ok := wtClassifier.PathRule(filePath); ok {
+			wt = string(wtClassifier.ClassifyFile(filePath, "", ""))
+		} else if wt == "" {
 			wt = "core_logic"
 		}
 
@@ -135,6 +192,7 @@ func GenerateProjectForBranch(s *store.Store, branch, baseBranch string) (*Proje
 			TotalEvents:      len(fileAttrList),
 			AuthorshipCounts: map[string]int{level: len(fileAttrList)},
 		}
+		countCorrections(s, &fr, fileAttrList, opts.correctionWindow())
 
 		for _, attr := range fileAttrList {
 			if isAIAuthorship(attr.AuthorshipLevel) {
@@ -146,14 +204,23 @@ func GenerateProjectForBranch(s *store.Store, branch, baseBranch string) (*Proje
 		report.TotalFiles++
 		report.TotalLines += la.TotalLines
 		report.AILines += la.AILines
+		report.CorrectionEvents += fr.CorrectionEvents
+		report.CorrectedEvents += fr.CorrectedEvents
 		report.ByAuthorship[level]++
 	}
 
-	// Compute project-level AI%.
+	// Compute project-level AI%. Branch reports are unweighted.
+	report.Scorer = metrics.ScorerRaw
 	if report.TotalLines > 0 {
 		report.RawAIPct = float64(report.AILines) / float64(report.TotalLines) * 100.0
 		report.MeaningfulAIPct = report.RawAIPct
 	}
+	if report.CorrectionEvents > 0 {
+		report.CorrectionRate = float64(report.CorrectedEvents) / float64(report.CorrectionEvents) * 100.0
+	}
+
+	assignPackages(report, opts.Packages)
+	sortExclusions(report.Excluded)
 
 	// Sort files by AI% descending.
 	sort.Slice(report.Files, func(i, j int) bool {
@@ -164,10 +231,8 @@ func GenerateProjectForBranch(s *store.Store, branch, baseBranch string) (*Proje
 }
 
 // gitMergeBaseCommit returns the merge-base commit hash between two refs.
-func gitMergeBaseCommit(repoPath, ref1, ref2 string) string {
-	cmd := exec.Command("git", "merge-base", ref1, ref2)
-	cmd.Dir = repoPath
-	out, err := cmd.Output()
+func gitMergeBaseCommit(repoPath, ref1, ref2 string, opts Options) string {
+	out, err := opts.git().Output(context.Background(), repoPath, "merge-base", ref1, ref2)
 	if err != nil {
 		return ""
 	}
@@ -176,7 +241,7 @@ func gitMergeBaseCommit(repoPath, ref1, ref2 string) string {
 
 // gitDiffAdditionsForBranch returns the added lines for a specific file between
 // mergeBase and target. If target is empty, compares against the working tree.
-func gitDiffAdditionsForBranch(projectPath, filePath, mergeBase, target string) string {
+func gitDiffAdditionsForBranch(projectPath, filePath, mergeBase, target string, opts Options) string {
 	absPath := resolveFilePath(projectPath, filePath)
 	relPath, err := filepath.Rel(projectPath, absPath)
 	if err != nil {
@@ -185,14 +250,12 @@ func gitDiffAdditionsForBranch(projectPath, filePath, mergeBase, target string)
 
 	var args []string
 	if target == "" {
-		args = []string{"diff", mergeBase, "--", relPath}
+		args = []string{"diff", opts.diffAlgorithmFlag(), mergeBase, "--", relPath}
 	} else {
-		args = []string{"diff", mergeBase, target, "--", relPath}
+		args = []string{"diff", opts.diffAlgorithmFlag(), mergeBase, target, "--", relPath}
 	}
 
-	cmd := exec.Command("git", args...)
-	cmd.Dir = projectPath
-	out, err := cmd.Output()
+	out, err := opts.git().Output(context.Background(), projectPath, args...)
 	if err != nil {
 		return ""
 	}
@@ -151,7 +151,7 @@ func TestBranchReport_RebaseSquash(t *testing.T) {
 	// Squash all 3 into 1.
 	gitSquashCommits(t, projDir, 3, "squashed: add all files")
 
-	report, err := GenerateProjectForBranch(s, "feature-x", "main")
+	report, err := GenerateProjectForBranch(s, "feature-x", "main", Options{})
 	if err != nil {
 		t.Fatalf("GenerateProjectForBranch: %v", err)
 	}
@@ -180,7 +180,7 @@ func TestBranchReport_RebaseDropAICommit(t *testing.T) {
 	// Drop the AI commit.
 	gitDropCommit(t, projDir, aiHash)
 
-	report, err := GenerateProjectForBranch(s, "feature-x", "main")
+	report, err := GenerateProjectForBranch(s, "feature-x", "main", Options{})
 	if err != nil {
 		t.Fatalf("GenerateProjectForBranch: %v", err)
 	}
@@ -211,7 +211,7 @@ func TestBranchReport_RebaseDropHumanCommit(t *testing.T) {
 	// Drop the human commit.
 	gitDropCommit(t, projDir, humanHash)
 
-	report, err := GenerateProjectForBranch(s, "feature-x", "main")
+	report, err := GenerateProjectForBranch(s, "feature-x", "main", Options{})
 	if err != nil {
 		t.Fatalf("GenerateProjectForBranch: %v", err)
 	}
@@ -245,7 +245,7 @@ func TestBranchReport_RebaseFixupModifiesAICode(t *testing.T) {
 	// Squash the two commits (simulates fixup).
 	gitSquashCommits(t, projDir, 2, "fixuped: file.go")
 
-	report, err := GenerateProjectForBranch(s, "feature-x", "main")
+	report, err := GenerateProjectForBranch(s, "feature-x", "main", Options{})
 	if err != nil {
 		t.Fatalf("GenerateProjectForBranch: %v", err)
 	}
@@ -289,7 +289,7 @@ func TestBranchReport_RebaseReorder(t *testing.T) {
 		gitCherryPick(t, projDir, hashes[idx])
 	}
 
-	report, err := GenerateProjectForBranch(s, "feature-x", "main")
+	report, err := GenerateProjectForBranch(s, "feature-x", "main", Options{})
 	if err != nil {
 		t.Fatalf("GenerateProjectForBranch: %v", err)
 	}
@@ -315,7 +315,7 @@ func TestBranchReport_RebaseReword(t *testing.T) {
 		t.Fatalf("git commit --amend failed: %v\n%s", err, out)
 	}
 
-	report, err := GenerateProjectForBranch(s, "feature-x", "main")
+	report, err := GenerateProjectForBranch(s, "feature-x", "main", Options{})
 	if err != nil {
 		t.Fatalf("GenerateProjectForBranch: %v", err)
 	}
@@ -355,7 +355,7 @@ func TestBranchReport_RebaseFixupPartialOverwrite(t *testing.T) {
 	// Squash into one commit.
 	gitSquashCommits(t, projDir, 2, "fixuped partial")
 
-	report, err := GenerateProjectForBranch(s, "feature-x", "main")
+	report, err := GenerateProjectForBranch(s, "feature-x", "main", Options{})
 	if err != nil {
 		t.Fatalf("GenerateProjectForBranch: %v", err)
 	}
@@ -389,7 +389,7 @@ func TestBranchReport_RebaseOntoUpdatedMain(t *testing.T) {
 	gitCheckoutExisting(t, projDir, "feature-x")
 	gitRebaseOnto(t, projDir, "main")
 
-	report, err := GenerateProjectForBranch(s, "feature-x", "main")
+	report, err := GenerateProjectForBranch(s, "feature-x", "main", Options{})
 	if err != nil {
 		t.Fatalf("GenerateProjectForBranch: %v", err)
 	}
@@ -425,7 +425,7 @@ func TestStack_DiffAgainstParentBranch(t *testing.T) {
 	insertAttributionOnBranch(t, s, "c.go", projDir, "mostly_ai", "core_logic", "branch-c", baseTime.Add(2*time.Second), 2)
 
 	// Report for branch-b against branch-a: should only show b.go.
-	report, err := GenerateProjectForBranch(s, "branch-b", "branch-a")
+	report, err := GenerateProjectForBranch(s, "branch-b", "branch-a", Options{})
 	if err != nil {
 		t.Fatalf("GenerateProjectForBranch: %v", err)
 	}
@@ -456,7 +456,7 @@ func TestStack_SharedFileAcrossStack(t *testing.T) {
 	insertAttributionOnBranch(t, s, "shared.go", projDir, "mostly_ai", "core_logic", "branch-b", baseTime.Add(time.Second), 1)
 
 	// branch-b vs branch-a: should only show FuncC addition, not FuncB.
-	report, err := GenerateProjectForBranch(s, "branch-b", "branch-a")
+	report, err := GenerateProjectForBranch(s, "branch-b", "branch-a", Options{})
 	if err != nil {
 		t.Fatalf("GenerateProjectForBranch: %v", err)
 	}
@@ -484,10 +484,10 @@ func TestStack_FullStackVsMain(t *testing.T) {
 	insertAttributionOnBranch(t, s, "c.go", projDir, "mostly_ai", "core_logic", "branch-c", baseTime.Add(2*time.Second), 2)
 
 	// Per-branch reports.
-	ra, errA := GenerateProjectForBranch(s, "branch-a", "main")
-	rb, errB := GenerateProjectForBranch(s, "branch-b", "branch-a")
-	rc, errC := GenerateProjectForBranch(s, "branch-c", "branch-b")
-	rFull, errFull := GenerateProjectForBranch(s, "branch-c", "main")
+	ra, errA := GenerateProjectForBranch(s, "branch-a", "main", Options{})
+	rb, errB := GenerateProjectForBranch(s, "branch-b", "branch-a", Options{})
+	rc, errC := GenerateProjectForBranch(s, "branch-c", "branch-b", Options{})
+	rFull, errFull := GenerateProjectForBranch(s, "branch-c", "main", Options{})
 
 	for _, err := range []error{errA, errB, errC, errFull} {
 		if err != nil {
@@ -532,7 +532,7 @@ func TestStack_Restack_ModifyBottomBranch(t *testing.T) {
 	}
 	gitCherryPick(t, projDir, bCommit)
 
-	report, err := GenerateProjectForBranch(s, "branch-b", "branch-a")
+	report, err := GenerateProjectForBranch(s, "branch-b", "branch-a", Options{})
 	if err != nil {
 		t.Fatalf("GenerateProjectForBranch: %v", err)
 	}
@@ -572,7 +572,7 @@ func TestStack_Restack_ConflictResolution(t *testing.T) {
 	resolvedContent := "line1\nline2-resolved-by-human\nline3\n"
 	gitCherryPickResolve(t, projDir, bCommit, "shared.go", resolvedContent)
 
-	report, err := GenerateProjectForBranch(s, "branch-b", "branch-a")
+	report, err := GenerateProjectForBranch(s, "branch-b", "branch-a", Options{})
 	if err != nil {
 		t.Fatalf("GenerateProjectForBranch: %v", err)
 	}
@@ -605,7 +605,7 @@ func TestStack_BottomMerges_RetargetToMain(t *testing.T) {
 
 	// Now branch-b's report against main should only show its delta (b.go).
 	gitCheckoutExisting(t, projDir, "branch-b")
-	report, err := GenerateProjectForBranch(s, "branch-b", "main")
+	report, err := GenerateProjectForBranch(s, "branch-b", "main", Options{})
 	if err != nil {
 		t.Fatalf("GenerateProjectForBranch: %v", err)
 	}
@@ -645,7 +645,7 @@ func TestStack_Split_SingleBranchToTwo(t *testing.T) {
 	}
 
 	// Report for part1: only file1.go.
-	r1, err := GenerateProjectForBranch(s, "feature-part1", "main")
+	r1, err := GenerateProjectForBranch(s, "feature-part1", "main", Options{})
 	if err != nil {
 		t.Fatalf("GenerateProjectForBranch (part1): %v", err)
 	}
@@ -654,7 +654,7 @@ func TestStack_Split_SingleBranchToTwo(t *testing.T) {
 	}
 
 	// Report for part2 against part1: only file2.go.
-	r2, err := GenerateProjectForBranch(s, "feature-part2", "feature-part1")
+	r2, err := GenerateProjectForBranch(s, "feature-part2", "feature-part1", Options{})
 	if err != nil {
 		t.Fatalf("GenerateProjectForBranch (part2): %v", err)
 	}
@@ -686,9 +686,9 @@ func TestStack_SameFileEditedAtEveryLevel(t *testing.T) {
 	insertAttributionOnBranch(t, s, "shared.go", projDir, "mostly_ai", "core_logic", "branch-c", baseTime.Add(2*time.Second), 1)
 
 	// Each level should only report its own addition.
-	ra, errA := GenerateProjectForBranch(s, "branch-a", "main")
-	rb, errB := GenerateProjectForBranch(s, "branch-b", "branch-a")
-	rc, errC := GenerateProjectForBranch(s, "branch-c", "branch-b")
+	ra, errA := GenerateProjectForBranch(s, "branch-a", "main", Options{})
+	rb, errB := GenerateProjectForBranch(s, "branch-b", "branch-a", Options{})
+	rc, errC := GenerateProjectForBranch(s, "branch-c", "branch-b", Options{})
 
 	for _, err := range []error{errA, errB, errC} {
 		if err != nil {
@@ -153,7 +153,7 @@ func TestBranchReport_BasicAttribution(t *testing.T) {
 		makeWriteRawJSON(filepath.Join(projDir, "handler.go"), editedContent), baseTime)
 	insertAttributionOnBranch(t, s, "handler.go", projDir, "mostly_ai", "core_logic", "feature-x", baseTime, 3)
 
-	report, err := GenerateProjectForBranch(s, "feature-x", "main")
+	report, err := GenerateProjectForBranch(s, "feature-x", "main", Options{})
 	if err != nil {
 		t.Fatalf("GenerateProjectForBranch: %v", err)
 	}
@@ -185,7 +185,7 @@ func TestBranchReport_NewFileOnBranch(t *testing.T) {
 		makeWriteRawJSON(filepath.Join(projDir, "new.go"), newContent), baseTime)
 	insertAttributionOnBranch(t, s, "new.go", projDir, "mostly_ai", "core_logic", "feature-x", baseTime, 4)
 
-	report, err := GenerateProjectForBranch(s, "feature-x", "main")
+	report, err := GenerateProjectForBranch(s, "feature-x", "main", Options{})
 	if err != nil {
 		t.Fatalf("GenerateProjectForBranch: %v", err)
 	}
@@ -218,7 +218,7 @@ func TestBranchReport_IgnoresOtherBranches(t *testing.T) {
 	insertAttributionOnBranch(t, s, "file-y.go", projDir, "mostly_ai", "core_logic", "feature-y", baseTime.Add(time.Second), 2)
 
 	// Report for feature-x should only show file-x.go.
-	report, err := GenerateProjectForBranch(s, "feature-x", "main")
+	report, err := GenerateProjectForBranch(s, "feature-x", "main", Options{})
 	if err != nil {
 		t.Fatalf("GenerateProjectForBranch: %v", err)
 	}
@@ -253,7 +253,7 @@ func TestBranchReport_SameFileMultipleBranches(t *testing.T) {
 	insertAttributionOnBranch(t, s, "shared.go", projDir, "mostly_ai", "core_logic", "feature-y", baseTime.Add(time.Second), 1)
 
 	// Report for feature-x should show FeatureX changes only.
-	reportX, err := GenerateProjectForBranch(s, "feature-x", "main")
+	reportX, err := GenerateProjectForBranch(s, "feature-x", "main", Options{})
 	if err != nil {
 		t.Fatalf("GenerateProjectForBranch (feature-x): %v", err)
 	}
@@ -262,7 +262,7 @@ func TestBranchReport_SameFileMultipleBranches(t *testing.T) {
 	}
 
 	// Report for feature-y should show FeatureY changes only.
-	reportY, err := GenerateProjectForBranch(s, "feature-y", "main")
+	reportY, err := GenerateProjectForBranch(s, "feature-y", "main", Options{})
 	if err != nil {
 		t.Fatalf("GenerateProjectForBranch (feature-y): %v", err)
 	}
@@ -287,7 +287,7 @@ func TestBranchReport_RevertedOnBranch(t *testing.T) {
 		"package handler\n\nfunc Handle() {\n\treturn nil\n}\n", "revert")
 	insertAttributionOnBranch(t, s, "handler.go", projDir, "mostly_ai", "core_logic", "feature-x", baseTime, 1)
 
-	report, err := GenerateProjectForBranch(s, "feature-x", "main")
+	report, err := GenerateProjectForBranch(s, "feature-x", "main", Options{})
 	if err != nil {
 		t.Fatalf("GenerateProjectForBranch: %v", err)
 	}
@@ -314,7 +314,7 @@ func TestBranchReport_UncommittedChanges(t *testing.T) {
 	writeFile(t, projDir, "b.go", "package b\n\nvar B = 2\n")
 	insertAttributionOnBranch(t, s, "b.go", projDir, "mostly_ai", "core_logic", "feature-x", baseTime.Add(time.Second), 2)
 
-	report, err := GenerateProjectForBranch(s, "feature-x", "main")
+	report, err := GenerateProjectForBranch(s, "feature-x", "main", Options{})
 	if err != nil {
 		t.Fatalf("GenerateProjectForBranch: %v", err)
 	}
@@ -330,7 +330,7 @@ func TestBranchReport_EmptyBranch(t *testing.T) {
 	defer cleanup()
 	_ = projDir
 
-	_, err := GenerateProjectForBranch(s, "nonexistent-branch", "main")
+	_, err := GenerateProjectForBranch(s, "nonexistent-branch", "main", Options{})
 	if err == nil {
 		t.Fatal("expected error for empty/nonexistent branch, got nil")
 	}
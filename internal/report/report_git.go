@@ -0,0 +1,177 @@
+package report
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/anthropic/gap-map/internal/gitint"
+	"github.com/anthropic/gap-map/internal/metrics"
+)
+
+// SourceGit marks a ProjectReport derived from commit metadata only.
+const SourceGit = "git"
+
+// gitFileStats accumulates per-file line counts across commits.
+type gitFileStats struct {
+	totalLines    int
+	aiLines       int
+	partialLines  int // added by AI-Assisted: partial commits, half of which count as AI
+	commits       int
+	aiCommits     int
+	commitMessage string // most recent commit message touching the file
+}
+
+// GenerateProjectFromGit produces a project report from git metadata alone,
+// for CI runners where the daemon never ran and there is no local database.
+//
+// Every non-merge commit between the merge-base of baseBranch and HEAD is
+// classified with gitint.DetectAISignal (AI-Assisted trailers, Claude
+// co-author tags, generated-with footers). Lines added by an AI commit count
+// as AI lines; half of each file's lines from partial commits, rounded up,
+// do too. Accuracy is
+// coarser than daemon attribution because a commit is all-or-nothing.
+func GenerateProjectFromGit(repoPath, baseBranch string, scorer metrics.Scorer, opts Options) (*ProjectReport, error) {
+	top, err := opts.gitOutput(repoPath, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, fmt.Errorf("not a git repository: %w", err)
+	}
+	top = strings.TrimSpace(top)
+
+	mergeBase := gitMergeBaseCommit(top, resolveRef(top, baseBranch, opts), "HEAD", opts)
+	if mergeBase == "" {
+		return nil, fmt.Errorf("no merge-base between %q and HEAD (fetch the base branch, e.g. fetch-depth: 0)", baseBranch)
+	}
+
+	// Commits are separated by \x1e; hash and message by \x1f.
+	history, err := opts.gitOutput(top, "log", "--no-merges", "--format=%H%x1f%B%x1e", mergeBase+"..HEAD")
+	if err != nil {
+		return nil, fmt.Errorf("git log: %w", err)
+	}
+
+	stats := make(map[string]*gitFileStats)
+	for _, entry := range strings.Split(history, "\x1e") {
+		hash, message, ok := strings.Cut(strings.TrimSpace(entry), "\x1f")
+		if !ok {
+			continue
+		}
+		signal := gitint.DetectAISignal(message)
+
+		numstat, err := opts.gitOutput(top, "show", "--numstat", "--no-renames", "--format=", hash)
+		if err != nil {
+			return nil, fmt.Errorf("git show %s: %w", hash, err)
+		}
+		for path, added := range parseNumstat(numstat) {
+			fs := stats[path]
+			if fs == nil {
+				// git log lists newest first, so the first message seen is the latest.
+				fs = &gitFileStats{commitMessage: message}
+				stats[path] = fs
+			}
+			fs.commits++
+			fs.totalLines += added
+			switch signal {
+			case gitint.AISignalFull:
+				fs.aiLines += added
+				fs.aiCommits++
+			case gitint.AISignalPartial:
+				fs.partialLines += added
+				fs.aiCommits++
+			}
+		}
+	}
+
+	report := &ProjectReport{
+		ProjectPath:  top,
+		Source:       SourceGit,
+		Scorer:       scorer.Name(),
+		ByAuthorship: make(map[string]int),
+		ByWorkType:   make(map[string]WorkTypeSummary),
+	}
+
+	wtClassifier := newWorkTypeClassifier(nil, top)
+	for path, fs := range stats {
+		// Skip files deleted by the end of the branch and commits that only
+		// removed lines.
+		if _, err := os.Stat(filepath.Join(top, path)); err != nil || fs.totalLines == 0 {
+			continue
+		}
+
+		// Halve partial lines once per file, rounding up, so one-line
+		// partial commits are not each rounded down to nothing.
+		aiLines := fs.aiLines + (fs.partialLines+1)/2
+		aiPct := float64(aiLines) / float64(fs.totalLines) * 100.0
+		level := "mostly_human"
+		switch {
+		case aiPct > 70:
+			level = "mostly_ai"
+		case aiPct >= 30:
+			level = "mixed"
+		}
+
+		addFileToReport(report, FileReport{
+			FilePath:         path,
+			WorkType:         string(wtClassifier.ClassifyFile(path, "", fs.commitMessage)),
+			MeaningfulAIPct:  aiPct,
+			RawAIPct:         aiPct,
+			TotalLines:       fs.totalLines,
+			AILines:          aiLines,
+			AuthorshipLevel:  level,
+			TotalEvents:      fs.commits,
+			AIEventCount:     fs.aiCommits,
+			AuthorshipCounts: map[string]int{level: fs.commits},
+		}, scorer)
+	}
+
+	if report.TotalFiles == 0 {
+		return nil, fmt.Errorf("no changed files between %s and HEAD", baseBranch)
+	}
+
+	finishProjectReport(report, scorer, opts.Packages)
+	return report, nil
+}
+
+// parseNumstat parses `git show --numstat` output into added lines per file.
+// Binary files (reported as "-") are skipped.
+func parseNumstat(out string) map[string]int {
+	added := make(map[string]int)
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.SplitN(line, "\t", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		n, err := strconv.Atoi(fields[0])
+		if err != nil {
+			continue
+		}
+		added[fields[2]] += n
+	}
+	return added
+}
+
+// resolveRef returns ref if it names a commit in repoPath, else its
+// origin/ remote-tracking ref if that exists (CI checkouts often only have
+// the remote ref), else ref unchanged.
+func resolveRef(repoPath, ref string, opts Options) string {
+	if _, err := opts.gitOutput(repoPath, "rev-parse", "--verify", "--quiet", ref+"^{commit}"); err == nil {
+		return ref
+	}
+	if remote := "origin/" + ref; !strings.HasPrefix(ref, "origin/") {
+		if _, err := opts.gitOutput(repoPath, "rev-parse", "--verify", "--quiet", remote+"^{commit}"); err == nil {
+			return remote
+		}
+	}
+	return ref
+}
+
+// gitOutput runs git with args in dir and returns stdout.
+func (o Options) gitOutput(dir string, args ...string) (string, error) {
+	out, err := o.git().Output(context.Background(), dir, args...)
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
@@ -0,0 +1,108 @@
+package report
+
+import (
+	"testing"
+
+	"github.com/anthropic/gap-map/internal/metrics"
+)
+
+// TestGenerateProjectFromGit verifies that a report can be built from
+// commit metadata alone, with no database.
+func TestGenerateProjectFromGit(t *testing.T) {
+	dir := t.TempDir()
+	gitInitMain(t, dir)
+	gitCheckoutCreate(t, dir, "feature")
+
+	gitCommitOnBranch(t, dir, "ai.go", "package x\n\nfunc A() {}\nfunc B() {}\n",
+		"feat: add A and B\n\nCo-Authored-By: Claude <noreply@anthropic.com>")
+	gitCommitOnBranch(t, dir, "human.go", "package x\n\nfunc H() {}\n", "feat: add H")
+	gitCommitOnBranch(t, dir, "half.go", "package x\n\nfunc P() {}\nfunc Q() {}\n",
+		"feat: add P and Q\n\nAI-Assisted: partial")
+
+	r, err := GenerateProjectFromGit(dir, "main", metrics.DefaultScorer(), Options{})
+	if err != nil {
+		t.Fatalf("GenerateProjectFromGit: %v", err)
+	}
+	if r.Source != SourceGit {
+		t.Errorf("Source = %q, want %q", r.Source, SourceGit)
+	}
+	if r.TotalFiles != 3 {
+		t.Fatalf("TotalFiles = %d, want 3", r.TotalFiles)
+	}
+
+	byPath := make(map[string]FileReport)
+	for _, f := range r.Files {
+		byPath[f.FilePath] = f
+	}
+	if f := byPath["ai.go"]; f.AILines != 4 || f.AuthorshipLevel != "mostly_ai" {
+		t.Errorf("ai.go: AILines=%d level=%s, want 4 mostly_ai", f.AILines, f.AuthorshipLevel)
+	}
+	if f := byPath["human.go"]; f.AILines != 0 || f.AuthorshipLevel != "mostly_human" {
+		t.Errorf("human.go: AILines=%d level=%s, want 0 mostly_human", f.AILines, f.AuthorshipLevel)
+	}
+	if f := byPath["half.go"]; f.AILines != 2 || f.AuthorshipLevel != "mixed" {
+		t.Errorf("half.go: AILines=%d level=%s, want 2 mixed", f.AILines, f.AuthorshipLevel)
+	}
+	if r.AILines != 6 || r.TotalLines != 11 {
+		t.Errorf("AILines/TotalLines = %d/%d, want 6/11", r.AILines, r.TotalLines)
+	}
+}
+
+// TestGenerateProjectFromGit_PartialLines verifies that partial commits
+// are halved per file, not per commit, so one-line partial commits count.
+func TestGenerateProjectFromGit_PartialLines(t *testing.T) {
+	dir := t.TempDir()
+	gitInitMain(t, dir)
+	gitCheckoutCreate(t, dir, "feature")
+
+	content := ""
+	for _, line := range []string{"package x\n", "func A() {}\n", "func B() {}\n"} {
+		content += line
+		gitCommitOnBranch(t, dir, "small.go", content, "feat: one line\n\nAI-Assisted: partial")
+	}
+
+	r, err := GenerateProjectFromGit(dir, "main", metrics.DefaultScorer(), Options{})
+	if err != nil {
+		t.Fatalf("GenerateProjectFromGit: %v", err)
+	}
+	if len(r.Files) != 1 {
+		t.Fatalf("Files = %d, want 1", len(r.Files))
+	}
+	// 3 partial lines halve to 1.5, rounded up.
+	if f := r.Files[0]; f.AILines != 2 || f.TotalLines != 3 {
+		t.Errorf("small.go: AILines/TotalLines = %d/%d, want 2/3", f.AILines, f.TotalLines)
+	}
+}
+
+// TestGenerateProjectFromGit_NoChanges verifies a clear error when the
+// branch has no commits beyond its base.
+func TestGenerateProjectFromGit_NoChanges(t *testing.T) {
+	dir := t.TempDir()
+	gitInitMain(t, dir)
+
+	if _, err := GenerateProjectFromGit(dir, "main", metrics.DefaultScorer(), Options{}); err == nil {
+		t.Error("expected error for branch with no changes")
+	}
+}
+
+// TestResolveRef_RemoteFallback verifies that a branch only present as an
+// origin/ remote-tracking ref (as on CI checkouts) still resolves.
+func TestResolveRef_RemoteFallback(t *testing.T) {
+	dir := t.TempDir()
+	gitInitMain(t, dir)
+	gitCheckoutCreate(t, dir, "develop")
+	gitCommitOnBranch(t, dir, "a.go", "package a\n", "add a")
+	if _, err := (Options{}).gitOutput(dir, "update-ref", "refs/remotes/origin/base", "main"); err != nil {
+		t.Fatalf("git update-ref: %v", err)
+	}
+
+	if got := resolveRef(dir, "develop", Options{}); got != "develop" {
+		t.Errorf("resolveRef(develop) = %q, want develop", got)
+	}
+	if got := resolveRef(dir, "base", Options{}); got != "origin/base" {
+		t.Errorf("resolveRef(base) = %q, want origin/base", got)
+	}
+	if got := resolveRef(dir, "missing", Options{}); got != "missing" {
+		t.Errorf("resolveRef(missing) = %q, want missing", got)
+	}
+}
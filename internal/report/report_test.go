@@ -1,15 +1,21 @@
 package report
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"math"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/anthropic/gap-map/internal/ipc"
+	"github.com/anthropic/gap-map/internal/metrics"
+	"github.com/anthropic/gap-map/internal/numfmt"
 	"github.com/anthropic/gap-map/internal/store"
 )
 
@@ -17,7 +23,7 @@ var baseTime = time.Date(2026, 2, 9, 12, 0, 0, 0, time.UTC)
 
 // setupTestStore creates a temporary SQLite store, a git-initialized project
 // directory, and returns the store, project dir, and cleanup function.
-func setupTestStore(t *testing.T) (*store.Store, string, func()) {
+func setupTestStore(t testing.TB) (*store.Store, string, func()) {
 	t.Helper()
 
 	dir, err := os.MkdirTemp("", "report-test-*")
@@ -52,7 +58,7 @@ func setupTestStore(t *testing.T) (*store.Store, string, func()) {
 }
 
 // gitInit initializes a git repo and makes an initial empty commit.
-func gitInit(t *testing.T, dir string) {
+func gitInit(t testing.TB, dir string) {
 	t.Helper()
 	cmds := [][]string{
 		{"git", "init"},
@@ -70,13 +76,13 @@ func gitInit(t *testing.T, dir string) {
 }
 
 // gitAdd stages and commits files in the project dir with a specific date.
-func gitAdd(t *testing.T, dir string, files []string, message string) {
+func gitAdd(t testing.TB, dir string, files []string, message string) {
 	t.Helper()
 	gitAddAt(t, dir, files, message, commitTime)
 }
 
 // gitAddAt stages and commits files with a specific date.
-func gitAddAt(t *testing.T, dir string, files []string, message string, when time.Time) {
+func gitAddAt(t testing.TB, dir string, files []string, message string, when time.Time) {
 	t.Helper()
 	args := append([]string{"add"}, files...)
 	cmd := exec.Command("git", args...)
@@ -100,7 +106,7 @@ func gitAddAt(t *testing.T, dir string, files []string, message string, when tim
 var commitTime = baseTime.Add(-24 * time.Hour)
 
 // writeFile writes content to a file in the project directory.
-func writeFile(t *testing.T, projDir, name, content string) {
+func writeFile(t testing.TB, projDir, name, content string) {
 	t.Helper()
 	path := filepath.Join(projDir, name)
 	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
@@ -126,7 +132,7 @@ func mustJSON(v interface{}) string {
 }
 
 // insertAttribution inserts a test attribution with work type into the store.
-func insertAttribution(t *testing.T, s *store.Store, filePath, projectPath, level, workType string, ts time.Time, linesChanged int) {
+func insertAttribution(t testing.TB, s *store.Store, filePath, projectPath, level, workType string, ts time.Time, linesChanged int) {
 	t.Helper()
 
 	if err := s.InsertFileEvent(projectPath, filePath, "write", ts); err != nil {
@@ -155,7 +161,7 @@ func insertAttribution(t *testing.T, s *store.Store, filePath, projectPath, leve
 }
 
 // insertSessionEvent inserts a session event with raw JSON into the store.
-func insertSessionEvent(t *testing.T, s *store.Store, sessionID, filePath, rawJSON string, ts time.Time) {
+func insertSessionEvent(t testing.TB, s *store.Store, sessionID, filePath, rawJSON string, ts time.Time) {
 	t.Helper()
 	if err := s.InsertSessionEvent(sessionID, "tool_use", "Write", filePath, "", ts, rawJSON, 0); err != nil {
 		t.Fatalf("insert session event: %v", err)
@@ -191,7 +197,7 @@ func TestGenerateProjectFromStore_NewFiles(t *testing.T) {
 	insertAttribution(t, s, "main.go", projDir, "mostly_ai", "core_logic", baseTime, 7)
 	insertAttribution(t, s, "go.mod", projDir, "mostly_human", "boilerplate", baseTime.Add(time.Second), 0)
 
-	report, err := GenerateProjectFromStore(s)
+	report, err := GenerateProject(t.Context(), s, metrics.DefaultScorer(), Filter{}, Options{})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -226,6 +232,34 @@ func TestGenerateProjectFromStore_NewFiles(t *testing.T) {
 	}
 }
 
+func TestGenerateProjectFromStore_GitIgnored(t *testing.T) {
+	s, projDir, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	// Generated code and scratch files git ignores were attributed before
+	// they were ignored; reports leave them out.
+	writeFile(t, projDir, ".gitignore", "gen/\n")
+	writeFile(t, projDir, ".git/info/exclude", "scratch.go\n")
+	writeFile(t, projDir, "main.go", "package main\n\nfunc main() {}\n")
+	writeFile(t, projDir, "gen/out.go", "package gen\n\nvar X = 1\n")
+	writeFile(t, projDir, "scratch.go", "package main\n\nvar y = 2\n")
+	for _, f := range []string{"main.go", "gen/out.go", "scratch.go"} {
+		insertAttribution(t, s, f, projDir, "mostly_human", "core_logic", baseTime, 2)
+	}
+
+	report, err := GenerateProject(t.Context(), s, metrics.DefaultScorer(), Filter{}, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.TotalFiles != 1 || report.Files[0].FilePath != "main.go" {
+		t.Errorf("Files = %+v, want only main.go", report.Files)
+	}
+
+	if _, err := GenerateFileFromStore(s, "gen/out.go"); err == nil || !strings.Contains(err.Error(), "ignored by git") {
+		t.Errorf("GenerateFileFromStore(gen/out.go) error = %v, want ignored by git", err)
+	}
+}
+
 func TestGenerateProjectFromStore_DiffBasedAttribution(t *testing.T) {
 	s, projDir, cleanup := setupTestStore(t)
 	defer cleanup()
@@ -248,7 +282,7 @@ func TestGenerateProjectFromStore_DiffBasedAttribution(t *testing.T) {
 	// Attribution timestamp is AFTER the initial commit so git finds the base.
 	insertAttribution(t, s, "handler.go", projDir, "mostly_ai", "core_logic", baseTime, 1)
 
-	report, err := GenerateProjectFromStore(s)
+	report, err := GenerateProject(t.Context(), s, metrics.DefaultScorer(), Filter{}, Options{})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -274,6 +308,78 @@ func TestGenerateProjectFromStore_DiffBasedAttribution(t *testing.T) {
 	}
 }
 
+func TestGenerateProjectFromStore_ComplexityWeighted(t *testing.T) {
+	s, projDir, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	// Claude wrote the branchy Clamp; a human wrote the trivial Add.
+	clamp := "func Clamp(x int) int {\n\tif x < 0 {\n\t\treturn 0\n\t}\n\tif x > 9 {\n\t\treturn 9\n\t}\n\treturn x\n}\n"
+	content := "package calc\n\nfunc Add(a, b int) int {\n\treturn a + b\n}\n\n" + clamp
+	writeFile(t, projDir, "calc.go", content)
+	absPath := filepath.Join(projDir, "calc.go")
+	insertSessionEvent(t, s, "s1", absPath, makeWriteRawJSON(absPath, clamp), baseTime)
+	insertAttribution(t, s, "calc.go", projDir, "mostly_ai", "core_logic", baseTime, 13)
+
+	report, err := GenerateProject(t.Context(), s, metrics.DefaultScorer(), Filter{}, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Lines in Clamp weigh 3 (two ifs); the rest weigh 1. Clamp's last
+	// "}" is human because Add's "}" consumed one of Claude's.
+	// AI: 1 + 8*3 = 25 of 4 + 9*3 = 31.
+	if !almostEqual(report.ComplexityAIPct, 25.0/31.0*100, 0.1) {
+		t.Errorf("ComplexityAIPct = %.1f, want %.1f", report.ComplexityAIPct, 25.0/31.0*100)
+	}
+	if report.ComplexityAIPct <= report.RawAIPct {
+		t.Errorf("ComplexityAIPct = %.1f, want above RawAIPct %.1f", report.ComplexityAIPct, report.RawAIPct)
+	}
+	if fr := report.Files[0]; fr.ComplexityAIPct != report.ComplexityAIPct {
+		t.Errorf("file ComplexityAIPct = %.1f, want %.1f", fr.ComplexityAIPct, report.ComplexityAIPct)
+	}
+}
+
+func TestGenerateProjectFromStore_FileGuards(t *testing.T) {
+	s, projDir, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	writeFile(t, projDir, "small.go", "package a\n\nvar x = 1\n")
+	writeFile(t, projDir, "bundle.js", "a()\nb()\nc()\nd()\n")
+	writeFile(t, projDir, "logo.png", "\x89PNG\x00\x00data\n")
+	for _, f := range []string{"small.go", "bundle.js", "logo.png"} {
+		insertAttribution(t, s, f, projDir, "mostly_human", "core_logic", baseTime, 1)
+	}
+
+	opts := Options{Guard: metrics.FileGuard{MaxLines: 3}}
+	report, err := GenerateProject(t.Context(), s, metrics.DefaultScorer(), Filter{}, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Files) != 1 || report.Files[0].FilePath != "small.go" {
+		t.Fatalf("Files = %+v, want only small.go", report.Files)
+	}
+	want := []Exclusion{
+		{FilePath: "bundle.js", Reason: metrics.GuardTooLarge, Lines: 4},
+		{FilePath: "logo.png", Reason: metrics.GuardBinary},
+	}
+	if !reflect.DeepEqual(report.Excluded, want) {
+		t.Errorf("Excluded = %+v, want %+v", report.Excluded, want)
+	}
+
+	// Capping attributes the large file on its first lines instead.
+	opts.Guard.Cap = true
+	report, err = GenerateProject(t.Context(), s, metrics.DefaultScorer(), Filter{}, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.TotalLines != 2+3 {
+		t.Errorf("TotalLines = %d, want 5", report.TotalLines)
+	}
+	if len(report.Excluded) != 2 || report.Excluded[0].Reason != metrics.GuardCapped {
+		t.Errorf("Excluded = %+v, want bundle.js capped", report.Excluded)
+	}
+}
+
 func TestGenerateProjectFromStore_RevertedFileShowsNoChanges(t *testing.T) {
 	s, projDir, cleanup := setupTestStore(t)
 	defer cleanup()
@@ -295,7 +401,7 @@ func TestGenerateProjectFromStore_RevertedFileShowsNoChanges(t *testing.T) {
 	// Insert attribution (timestamp after the commit so base commit is found).
 	insertAttribution(t, s, "handler.go", projDir, "mostly_ai", "core_logic", baseTime, 1)
 
-	report, err := GenerateProjectFromStore(s)
+	report, err := GenerateProject(t.Context(), s, metrics.DefaultScorer(), Filter{}, Options{})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -312,6 +418,43 @@ func TestGenerateProjectFromStore_RevertedFileShowsNoChanges(t *testing.T) {
 	}
 }
 
+func TestGenerateProjectFromStore_Deletions(t *testing.T) {
+	s, projDir, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	original := "package legacy\n\nfunc Keep() {}\n\nfunc OldA() {}\n\nfunc OldB() {}\n\nfunc Manual() {}\n"
+	writeFile(t, projDir, "legacy.go", original)
+	gitAdd(t, projDir, []string{"legacy.go"}, "add legacy.go")
+
+	// Claude's Edit removes OldA and OldB; a human then removes Manual.
+	absPath := filepath.Join(projDir, "legacy.go")
+	edit := fmt.Sprintf(
+		`{"type":"assistant","message":{"content":[{"type":"tool_use","name":"Edit","input":{"file_path":%q,"old_string":%s,"new_string":""}}]}}`,
+		absPath, mustJSON("func OldA() {}\n\nfunc OldB() {}\n"))
+	insertSessionEvent(t, s, "s1", absPath, edit, baseTime)
+	writeFile(t, projDir, "legacy.go", "package legacy\n\nfunc Keep() {}\n")
+
+	insertAttribution(t, s, "legacy.go", projDir, "mostly_ai", "core_logic", baseTime, 1)
+
+	report, err := GenerateProject(t.Context(), s, metrics.DefaultScorer(), Filter{}, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Files) != 1 {
+		t.Fatalf("Files = %d, want 1 (deletion-only file is still reported)", len(report.Files))
+	}
+	fr := report.Files[0]
+	if fr.DeletedLines != 3 || fr.AIDeletedLines != 2 {
+		t.Errorf("DeletedLines = %d, AIDeletedLines = %d, want 3 and 2", fr.DeletedLines, fr.AIDeletedLines)
+	}
+	if report.DeletedLines != 3 || report.AIDeletedLines != 2 {
+		t.Errorf("project DeletedLines = %d, AIDeletedLines = %d, want 3 and 2", report.DeletedLines, report.AIDeletedLines)
+	}
+	if fr.TotalLines != 0 {
+		t.Errorf("TotalLines = %d, want 0 (nothing added)", fr.TotalLines)
+	}
+}
+
 func TestGenerateProjectFromStore_FilesSortedByAIPct(t *testing.T) {
 	s, projDir, cleanup := setupTestStore(t)
 	defer cleanup()
@@ -329,7 +472,7 @@ func TestGenerateProjectFromStore_FilesSortedByAIPct(t *testing.T) {
 	insertAttribution(t, s, "a.go", projDir, "mostly_human", "core_logic", baseTime, 0)
 	insertAttribution(t, s, "b.go", projDir, "mostly_ai", "core_logic", baseTime.Add(time.Second), 3)
 
-	report, err := GenerateProjectFromStore(s)
+	report, err := GenerateProject(t.Context(), s, metrics.DefaultScorer(), Filter{}, Options{})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -347,11 +490,50 @@ func TestGenerateProjectFromStore_FilesSortedByAIPct(t *testing.T) {
 	}
 }
 
+func TestGenerateProjectFromStore_DeterministicOrder(t *testing.T) {
+	s, projDir, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	// Equal AI% everywhere, so only the path tie-break orders the files.
+	for i := 0; i < 12; i++ {
+		name := fmt.Sprintf("f%02d.go", i)
+		writeFile(t, projDir, name, "package p\n")
+		insertAttribution(t, s, name, projDir, "mostly_human", "core_logic", baseTime.Add(time.Duration(11-i)*time.Second), 1)
+	}
+
+	report, err := GenerateProject(t.Context(), s, metrics.DefaultScorer(), Filter{}, Options{Workers: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Files) != 12 {
+		t.Fatalf("Files = %d, want 12", len(report.Files))
+	}
+	for i, fr := range report.Files {
+		if want := fmt.Sprintf("f%02d.go", i); fr.FilePath != want {
+			t.Errorf("Files[%d] = %s, want %s", i, fr.FilePath, want)
+		}
+	}
+}
+
+func TestGenerateProjectFromStoreContext_Cancelled(t *testing.T) {
+	s, projDir, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	writeFile(t, projDir, "main.go", "package main\n")
+	insertAttribution(t, s, "main.go", projDir, "mostly_ai", "core_logic", baseTime, 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := GenerateProject(ctx, s, metrics.DefaultScorer(), Filter{}, Options{}); err == nil {
+		t.Fatal("expected error from cancelled context")
+	}
+}
+
 func TestGenerateProjectFromStore_NoData(t *testing.T) {
 	s, _, cleanup := setupTestStore(t)
 	defer cleanup()
 
-	_, err := GenerateProjectFromStore(s)
+	_, err := GenerateProject(t.Context(), s, metrics.DefaultScorer(), Filter{}, Options{})
 	if err == nil {
 		t.Fatal("expected error for empty database")
 	}
@@ -541,6 +723,63 @@ func TestHumanBytes(t *testing.T) {
 	}
 }
 
+func TestFormatStatus_Health(t *testing.T) {
+	recent := time.Now().Add(-5 * time.Minute).UTC().Format(time.RFC3339)
+	status := &ipc.StatusData{
+		Uptime:       "1h0m0s",
+		WatchedPaths: []string{"/work/api"},
+		HealthData: ipc.HealthData{
+			WatchPathStats:     []ipc.WatchPathStatus{{Path: "/work/api", Events: 12, LastEventAt: recent}, {Path: "/work/client", Disabled: true}},
+			WatchedDirs:        140,
+			UnwatchedDirs:      3,
+			EventOverflows:     1,
+			WatchLimitReached:  true,
+			PolledDirs:         3,
+			PollInterval:       "5s",
+			SessionRoots:       []ipc.SessionRootStatus{{Path: "/home/u/.claude/projects", Exists: true, Sessions: 4}, {Path: "/home/u/.claude-work/projects"}},
+			Sessions:           []ipc.SessionStatus{{SessionID: "sess-1", LagBytes: 2048, LinesParsed: 40, LastLineAt: recent}},
+			IdleSessions:       3,
+			GitRepos:           []ipc.GitRepoStatus{{Path: "/work/api", LastError: "exit status 128"}},
+			AttributionBacklog: 7,
+			Crashes:            2,
+			LastCrashAt:        recent,
+			LastCrash:          "exit status 2",
+			ProjectTrust:       "allowlist",
+			EnabledProjects:    []string{"/work/api"},
+			DisabledProjects:   []string{"/work/client"},
+			UntrackedRepos:     []ipc.UntrackedRepoStatus{{Path: "/work/web", Edits: 9}},
+		},
+	}
+
+	out := FormatStatus(status, Style{})
+	for _, want := range []string{
+		"Attribution Backlog: 7",
+		"12 events, last 5m ago",
+		"140 directories",
+		"3 directories polled every 5s",
+		"warning: file watch limit reached",
+		"overflowed 1 time(s)",
+		"/home/u/.claude/projects  4 tailed",
+		"/home/u/.claude-work/projects  (missing)",
+		"1 active, 3 idle",
+		"40 lines parsed, 2.0 KB behind",
+		"last sync never",
+		"error: exit status 128",
+		"Crashes:             2, last 5m ago (exit status 2)",
+		"/work/client (disabled)",
+		"only enabled projects are recorded",
+		"enabled   /work/api",
+		"disabled  /work/client",
+		"Last Maintenance:    never",
+		"gapmap orphans --track",
+		"/work/web  9 edits",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("FormatStatus output missing %q:\n%s", want, out)
+		}
+	}
+}
+
 func TestFormatProjectReport_ContainsKey(t *testing.T) {
 	report := &ProjectReport{
 		ProjectPath:     "/proj",
@@ -559,7 +798,7 @@ func TestFormatProjectReport_ContainsKey(t *testing.T) {
 		},
 	}
 
-	output := FormatProjectReport(report)
+	output := FormatProjectReport(report, Style{})
 
 	checks := []string{
 		"Attribution Report",
@@ -577,6 +816,28 @@ func TestFormatProjectReport_ContainsKey(t *testing.T) {
 	}
 }
 
+func TestFormatProjectReport_NumberLocale(t *testing.T) {
+	de, err := numfmt.Parse("de_DE.UTF-8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	output := FormatProjectReport(&ProjectReport{
+		ProjectPath:     "/proj",
+		MeaningfulAIPct: 42.5,
+		TotalFiles:      1,
+		TotalLines:      12345,
+		AILines:         2500,
+		ByWorkType: map[string]WorkTypeSummary{
+			"core_logic": {Files: 1, AIPct: 20.25, Tier: "high", Weight: 3.0, TotalLines: 12345},
+		},
+	}, Style{Numbers: de})
+	for _, check := range []string{"42,5%", "12.345 (2.500 AI)", "core_logic         high         1   12.345  20,2%    3,0"} {
+		if !containsStr(output, check) {
+			t.Errorf("German report missing %q:\n%s", check, output)
+		}
+	}
+}
+
 func TestFormatFileReport_ContainsKey(t *testing.T) {
 	fr := &FileReport{
 		FilePath:         "handler.go",
@@ -591,7 +852,7 @@ func TestFormatFileReport_ContainsKey(t *testing.T) {
 		AuthorshipCounts: map[string]int{"mostly_ai": 1, "mixed": 1, "mostly_human": 1},
 	}
 
-	output := FormatFileReport(fr)
+	output := FormatFileReport(fr, Style{})
 
 	checks := []string{
 		"File Report",
@@ -619,3 +880,176 @@ func searchStr(s, substr string) bool {
 	}
 	return false
 }
+
+// BenchmarkGenerateProject measures report generation over a
+// project of committed files that were each edited after tracking began.
+func BenchmarkGenerateProject(b *testing.B) {
+	s, projDir, cleanup := setupTestStore(b)
+	defer cleanup()
+
+	const numFiles = 64
+	var files []string
+	for i := 0; i < numFiles; i++ {
+		name := fmt.Sprintf("pkg/file%03d.go", i)
+		writeFile(b, projDir, name, "package pkg\n\nfunc f() {}\n")
+		files = append(files, name)
+	}
+	gitAdd(b, projDir, files, "base")
+
+	for i, name := range files {
+		content := fmt.Sprintf("package pkg\n\nfunc f() {}\n\nfunc g%d() int {\n\treturn %d\n}\n", i, i)
+		writeFile(b, projDir, name, content)
+		ts := baseTime.Add(time.Duration(i) * time.Second)
+		if i%2 == 0 {
+			insertSessionEvent(b, s, "s1", filepath.Join(projDir, name), makeWriteRawJSON(filepath.Join(projDir, name), content), ts)
+		}
+		insertAttribution(b, s, name, projDir, "mostly_ai", "core_logic", ts, 3)
+	}
+
+	b.ResetTimer()
+	for b.Loop() {
+		if _, err := GenerateProject(b.Context(), s, metrics.DefaultScorer(), Filter{}, Options{}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkGenerateProject_Events measures report generation
+// over 64 committed files as the store grows to 10k, 100k and 1M AI
+// edits and attributions; the largest is skipped with -short.
+func BenchmarkGenerateProject_Events(b *testing.B) {
+	const numFiles = 64
+	for _, n := range []int{10_000, 100_000, 1_000_000} {
+		b.Run(fmt.Sprintf("events=%d", n), func(b *testing.B) {
+			if testing.Short() && n > 100_000 {
+				b.Skip("large dataset skipped with -short")
+			}
+			s, projDir, cleanup := setupTestStore(b)
+			defer cleanup()
+
+			var files []string
+			for i := 0; i < numFiles; i++ {
+				name := fmt.Sprintf("pkg/file%03d.go", i)
+				writeFile(b, projDir, name, "package pkg\n\nfunc f() {}\n")
+				files = append(files, name)
+			}
+			gitAdd(b, projDir, files, "base")
+			for i, name := range files {
+				writeFile(b, projDir, name, fmt.Sprintf("package pkg\n\nfunc f() {}\n\nfunc g%d() int {\n\treturn %d\n}\n", i, i))
+			}
+
+			const batchSize = 10_000
+			events := make([]store.NewSessionEvent, 0, batchSize)
+			attrs := make([]store.AttributionRecord, 0, batchSize)
+			for i := 0; i < n; i++ {
+				name := files[i%numFiles]
+				absPath := filepath.Join(projDir, name)
+				ts := baseTime.Add(time.Duration(i) * time.Second)
+				content := fmt.Sprintf("func g%d() int {\n\treturn %d\n}\n", i%numFiles, i%numFiles)
+				events = append(events, store.NewSessionEvent{
+					SessionID: fmt.Sprintf("s%d", i/1000),
+					EventType: "tool_use",
+					ToolName:  "Write",
+					FilePath:  absPath,
+					Timestamp: ts,
+					RawJSON:   fmt.Sprintf(`{"uuid":"u%d","type":"assistant","message":{"content":[{"type":"tool_use","name":"Write","input":{"file_path":%q,"content":%s}}]}}`, i, absPath, mustJSON(content)),
+				})
+				attrs = append(attrs, store.AttributionRecord{
+					FilePath: name, ProjectPath: projDir, AuthorshipLevel: "mostly_ai",
+					Confidence: 0.95, FirstAuthor: "ai", Timestamp: ts, LinesChanged: 3,
+				})
+				if len(events) == batchSize || i == n-1 {
+					if err := s.InsertSessionEvents(events); err != nil {
+						b.Fatal(err)
+					}
+					if _, err := s.RecordAttributions(attrs, "core_logic"); err != nil {
+						b.Fatal(err)
+					}
+					events, attrs = events[:0], attrs[:0]
+				}
+			}
+
+			for b.Loop() {
+				if _, err := GenerateProject(b.Context(), s, metrics.DefaultScorer(), Filter{}, Options{}); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestGenerateProject_RevisedLines(t *testing.T) {
+	s, projDir, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	// Claude wrote both lines; a human then renamed one argument.
+	aiWrote := "\ttotal := computeTotal(items, taxRate, discount)\n\treturn total\n"
+	writeFile(t, projDir, "calc.go", "package calc\n\nfunc f() int {\n\ttotal := computeTotal(items, taxRate, coupon)\n\treturn total\n}\n")
+	absPath := filepath.Join(projDir, "calc.go")
+	insertSessionEvent(t, s, "s1", absPath, makeWriteRawJSON(absPath, aiWrote), baseTime)
+	insertAttribution(t, s, "calc.go", projDir, "mostly_ai", "core_logic", baseTime, 2)
+
+	report, err := GenerateProject(t.Context(), s, metrics.DefaultScorer(), Filter{}, Options{
+		LineMatch: func(string) metrics.MatchOptions { return metrics.MatchOptions{Revision: 0.8} },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Files) != 1 {
+		t.Fatalf("Files = %+v, want calc.go", report.Files)
+	}
+	fr := report.Files[0]
+	if fr.AILines != 2 || fr.RevisedLines != 1 {
+		t.Errorf("AI lines = %d, revised = %d; want 2 AI, 1 revised", fr.AILines, fr.RevisedLines)
+	}
+	if report.RevisedLines != 1 {
+		t.Errorf("report RevisedLines = %d, want 1", report.RevisedLines)
+	}
+	if out := FormatFileReport(&fr, Style{}); !strings.Contains(out, "(1 revised)") {
+		t.Errorf("FormatFileReport output missing revised lines:\n%s", out)
+	}
+}
+
+func TestGenerateProject_RemovedFiles(t *testing.T) {
+	s, projDir, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	writeFile(t, projDir, "kept.go", "package kept\n\nfunc Kept() {}\n")
+	absPath := filepath.Join(projDir, "kept.go")
+	insertSessionEvent(t, s, "s1", absPath, makeWriteRawJSON(absPath, "func Kept() {}\n"), baseTime)
+	insertAttribution(t, s, "kept.go", projDir, "mostly_ai", "core_logic", baseTime, 1)
+
+	// Claude wrote gone.go, which was then deleted.
+	insertAttribution(t, s, "gone.go", projDir, "mostly_ai", "core_logic", baseTime, 12)
+	insertAttribution(t, s, "gone.go", projDir, "mostly_human", "core_logic", baseTime.Add(time.Hour), 3)
+
+	report, err := GenerateProject(t.Context(), s, metrics.DefaultScorer(), Filter{}, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Files) != 1 || report.Files[0].FilePath != "kept.go" {
+		t.Errorf("Files = %+v, want kept.go", report.Files)
+	}
+	if len(report.Removed) != 1 {
+		t.Fatalf("Removed = %+v, want gone.go", report.Removed)
+	}
+	rm := report.Removed[0]
+	if rm.FilePath != "gone.go" || rm.AILines != 12 || rm.AIEvents != 1 || rm.Attributions != 2 || !rm.LastAttributed.Equal(baseTime.Add(time.Hour)) {
+		t.Errorf("removed = %+v, want gone.go with 12 AI lines from 1 of 2 attributions", rm)
+	}
+	if out := FormatProjectReport(report, Style{}); !strings.Contains(out, "Removed Files") || !strings.Contains(out, "gone.go") {
+		t.Errorf("FormatProjectReport output missing removed files:\n%s", out)
+	}
+}
+
+func TestOptions_DiffAlgorithmFlag(t *testing.T) {
+	for alg, want := range map[string]string{
+		"":          "--diff-algorithm=myers",
+		"histogram": "--diff-algorithm=histogram",
+		"minimal":   "--diff-algorithm=myers", // unknown names are not passed to git
+	} {
+		if got := (Options{DiffAlgorithm: alg}).diffAlgorithmFlag(); got != want {
+			t.Errorf("diffAlgorithmFlag(%q) = %q, want %q", alg, got, want)
+		}
+	}
+}
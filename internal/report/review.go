@@ -0,0 +1,91 @@
+package report
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/anthropic/gap-map/internal/worktype"
+)
+
+// ReviewItem is a file on the review checklist, with the signals that put
+// it there.
+type ReviewItem struct {
+	FilePath        string   `json:"file_path"`
+	WorkType        string   `json:"work_type"`
+	MeaningfulAIPct float64  `json:"meaningful_ai_pct"`
+	SurvivalRate    *float64 `json:"survival_rate,omitempty"`  // % of the file's AI edits still in git blame
+	AICoveredPct    *float64 `json:"ai_covered_pct,omitempty"` // % of its AI lines the cover profile covers
+	Risk            float64  `json:"risk"`                     // 0-100, highest reviewed first
+	Reasons         []string `json:"reasons"`
+}
+
+// ApplyReview sets r.Review to the files with AI lines ranked by review
+// risk. survivalRates maps file paths to the survival rate of their AI
+// edits and may be nil; coverage comes from ApplyCoverage, if it ran.
+//
+// Risk scales with the file's AI share: up to 60 points for the weight of
+// its work type (architecture and core logic count most), 20 for AI edits
+// since rewritten and 20 for AI lines no test covers. Signals that are not
+// available add nothing.
+func ApplyReview(r *ProjectReport, survivalRates map[string]float64) {
+	r.Review = make([]ReviewItem, 0)
+	for _, fr := range r.Files {
+		if fr.AILines == 0 {
+			continue
+		}
+		item := ReviewItem{FilePath: fr.FilePath, WorkType: fr.WorkType, MeaningfulAIPct: fr.MeaningfulAIPct}
+		ai := fr.MeaningfulAIPct / 100.0
+		weight := worktype.WorkTypeWeights[worktype.WorkType(fr.WorkType)] / worktype.WorkTypeWeights[worktype.CoreLogic]
+		if weight == 0 {
+			weight = 1
+		}
+		score := 60 * weight
+		item.Reasons = append(item.Reasons, fmt.Sprintf("%.0f%% AI", fr.MeaningfulAIPct), fr.WorkType)
+
+		if rate, ok := survivalRates[fr.FilePath]; ok {
+			item.SurvivalRate = &rate
+			score += 20 * (100 - rate) / 100
+			if rate < 100 {
+				item.Reasons = append(item.Reasons, fmt.Sprintf("%.0f%% of AI edits rewritten", 100-rate))
+			}
+		}
+		if c := fr.Coverage; c != nil && c.CoveredAILines+c.UncoveredAILines > 0 {
+			covered := float64(c.CoveredAILines) / float64(c.CoveredAILines+c.UncoveredAILines) * 100
+			item.AICoveredPct = &covered
+			score += 20 * (100 - covered) / 100
+			if c.UncoveredAILines > 0 {
+				item.Reasons = append(item.Reasons, fmt.Sprintf("%d AI lines untested", c.UncoveredAILines))
+			}
+		}
+
+		item.Risk = ai * score
+		r.Review = append(r.Review, item)
+	}
+
+	sort.Slice(r.Review, func(i, j int) bool {
+		a, b := r.Review[i], r.Review[j]
+		if a.Risk != b.Risk {
+			return a.Risk > b.Risk
+		}
+		return a.FilePath < b.FilePath
+	})
+}
+
+// formatReview formats a review checklist, highest risk first.
+func formatReview(items []ReviewItem, st Style) string {
+	ansi := st.palette()
+	var b strings.Builder
+
+	b.WriteString("\n" + ansi.bold + "Review Checklist (highest risk first)" + ansi.reset + "\n")
+	b.WriteString(strings.Repeat("-", 60) + "\n")
+	if len(items) == 0 {
+		b.WriteString("No AI-written lines to review.\n")
+		return b.String()
+	}
+	for i, item := range items {
+		b.WriteString(fmt.Sprintf("%2d. [ ] %-40s risk %3.0f\n", i+1, item.FilePath, item.Risk))
+		b.WriteString(fmt.Sprintf("        %s\n", strings.Join(item.Reasons, ", ")))
+	}
+	return b.String()
+}
@@ -0,0 +1,54 @@
+package report
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestApplyReview(t *testing.T) {
+	r := &ProjectReport{Files: []FileReport{
+		{FilePath: "core.go", WorkType: "core_logic", MeaningfulAIPct: 90, AILines: 9, TotalLines: 10},
+		{FilePath: "gen.go", WorkType: "boilerplate", MeaningfulAIPct: 100, AILines: 20, TotalLines: 20},
+		{FilePath: "churn.go", WorkType: "core_logic", MeaningfulAIPct: 90, AILines: 9, TotalLines: 10,
+			Coverage: &FileCoverage{CoveredAILines: 3, UncoveredAILines: 6}},
+		{FilePath: "human.go", WorkType: "core_logic", TotalLines: 10},
+	}}
+	ApplyReview(r, map[string]float64{"churn.go": 50, "core.go": 100})
+
+	var order []string
+	for _, item := range r.Review {
+		order = append(order, item.FilePath)
+	}
+	// Rewritten, untested core logic first; boilerplate last despite being
+	// all AI; files without AI lines are not listed.
+	if strings.Join(order, ",") != "churn.go,core.go,gen.go" {
+		t.Fatalf("review order = %v", order)
+	}
+
+	churn := r.Review[0]
+	// 0.9 * (60 + 20*0.5 + 20*(2/3))
+	if !almostEqual(churn.Risk, 75, 0.01) {
+		t.Errorf("churn.go risk = %.2f, want 75", churn.Risk)
+	}
+	if churn.SurvivalRate == nil || *churn.SurvivalRate != 50 || churn.AICoveredPct == nil {
+		t.Errorf("churn.go signals = %+v", churn)
+	}
+	if got := strings.Join(churn.Reasons, ", "); got != "90% AI, core_logic, 50% of AI edits rewritten, 6 AI lines untested" {
+		t.Errorf("churn.go reasons = %q", got)
+	}
+	if gen := r.Review[2]; !almostEqual(gen.Risk, 20, 0.01) || gen.SurvivalRate != nil {
+		t.Errorf("gen.go = %+v, want risk 20 and no survival", gen)
+	}
+
+	out := FormatProjectReport(r, Style{})
+	if !strings.Contains(out, "Review Checklist") || !strings.Contains(out, "[ ] churn.go") {
+		t.Errorf("FormatProjectReport lacks the review checklist:\n%s", out)
+	}
+
+	// Requested but nothing to review.
+	empty := &ProjectReport{Files: []FileReport{{FilePath: "human.go", TotalLines: 3}}}
+	ApplyReview(empty, nil)
+	if out := FormatProjectReport(empty, Style{}); !strings.Contains(out, "No AI-written lines to review") {
+		t.Errorf("FormatProjectReport without AI lines:\n%s", out)
+	}
+}
@@ -0,0 +1,50 @@
+package report
+
+import (
+	"strings"
+
+	"github.com/anthropic/gap-map/internal/store"
+)
+
+// snapshotHumanLines counts the changed lines not classified as AI that a
+// human edit snapshot of the file (human_snapshots) saw added: human lines
+// known from what was recorded rather than by subtraction. Returns 0 when
+// the file has no snapshots.
+func snapshotHumanLines(s *store.Store, absPath string, added []string, ai []bool) int {
+	snapshots, err := s.QueryFileSnapshots(absPath)
+	if err != nil || len(snapshots) == 0 {
+		return 0
+	}
+
+	seen := make(map[string]bool)
+	for _, sn := range snapshots {
+		for _, line := range snapshotAddedLines(sn) {
+			if line = strings.TrimSpace(line); line != "" {
+				seen[line] = true
+			}
+		}
+	}
+
+	n := 0
+	for i, line := range added {
+		if !ai[i] && seen[strings.TrimSpace(line)] {
+			n++
+		}
+	}
+	return n
+}
+
+// snapshotAddedLines returns the lines a snapshot shows as added: the
+// additions of a diff, or every line of a content snapshot.
+func snapshotAddedLines(sn store.FileSnapshot) []string {
+	if sn.Kind == store.SnapshotContent {
+		return strings.Split(sn.Content, "\n")
+	}
+	var lines []string
+	for _, line := range strings.Split(sn.Content, "\n") {
+		if strings.HasPrefix(line, "+") && !strings.HasPrefix(line, "+++") {
+			lines = append(lines, line[1:])
+		}
+	}
+	return lines
+}
@@ -0,0 +1,56 @@
+package report
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anthropic/gap-map/internal/metrics"
+	"github.com/anthropic/gap-map/internal/store"
+)
+
+func TestSnapshotHumanLines(t *testing.T) {
+	s, projDir, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	// Claude wrote Add; a human wrote Sub, and a snapshot saw it.
+	content := "package calc\n\nfunc Add(a, b int) int {\n\treturn a + b\n}\n\nfunc Sub(a, b int) int {\n\treturn a - b\n}\n"
+	writeFile(t, projDir, "calc.go", content)
+	absPath := filepath.Join(projDir, "calc.go")
+	insertSessionEvent(t, s, "s1", absPath,
+		makeWriteRawJSON(absPath, "func Add(a, b int) int {\n\treturn a + b\n}\n"), baseTime)
+	insertAttribution(t, s, "calc.go", projDir, "mostly_ai", "core_logic", baseTime, 3)
+
+	id, err := s.InsertAttribution(store.AttributionRecord{
+		FilePath: "calc.go", ProjectPath: projDir, AuthorshipLevel: "mostly_human",
+		Confidence: 1, FirstAuthor: "human", Timestamp: baseTime.Add(time.Minute), LinesChanged: 3,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	diff := "--- a/calc.go\n+++ b/calc.go\n@@ -5,0 +6,4 @@\n+\n+func Sub(a, b int) int {\n+\treturn a - b\n+}\n"
+	if _, err := s.InsertFileSnapshot(store.FileSnapshot{
+		AttributionID: id, FilePath: absPath, Kind: store.SnapshotDiff, Content: diff, Timestamp: baseTime.Add(time.Minute),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := GenerateProject(t.Context(), s, metrics.DefaultScorer(), Filter{}, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Files) != 1 {
+		t.Fatalf("Files = %+v, want calc.go", report.Files)
+	}
+	fr := report.Files[0]
+	if fr.TotalLines != 7 || fr.AILines != 3 || fr.HumanLines != 3 {
+		t.Errorf("lines = %d total, %d AI, %d human; want 7, 3, 3", fr.TotalLines, fr.AILines, fr.HumanLines)
+	}
+	if report.HumanLines != 3 {
+		t.Errorf("report HumanLines = %d, want 3", report.HumanLines)
+	}
+	if out := FormatProjectReport(report, Style{}); !strings.Contains(out, "Human lines:   3 seen in snapshots (1 unaccounted for)") {
+		t.Errorf("FormatProjectReport output missing human lines:\n%s", out)
+	}
+}
@@ -0,0 +1,91 @@
+package report
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/anthropic/gap-map/internal/gitint"
+	"github.com/anthropic/gap-map/internal/store"
+)
+
+// StackReport is the report of a stack of branches: one report per layer,
+// each against the layer below it, and a rollup of the whole stack against
+// its base.
+type StackReport struct {
+	Base   string         `json:"base"`
+	Source string         `json:"source"` // gitint.StackGraphite or gitint.StackMergeBase
+	Layers []StackLayer   `json:"layers"` // bottom layer first
+	Rollup *ProjectReport `json:"rollup"` // the top layer against Base
+}
+
+// StackLayer is the report of one branch of a stack against its parent.
+type StackLayer struct {
+	Branch string         `json:"branch"`
+	Parent string         `json:"parent"`
+	Report *ProjectReport `json:"report"`
+}
+
+// Top returns the branch at the top of the stack.
+func (sr *StackReport) Top() string {
+	return sr.Layers[len(sr.Layers)-1].Branch
+}
+
+// GenerateStack produces the report of stack. Layers without attributions
+// of their own still get a report, since their lines count against the AI
+// share; at least one layer must have attributions.
+func GenerateStack(s *store.Store, stack gitint.Stack, opts Options) (*StackReport, error) {
+	projectPath, err := DiscoverProjectPath(s)
+	if err != nil {
+		return nil, err
+	}
+	var attributed bool
+	for _, b := range stack.Branches {
+		attrs, err := s.QueryAttributionsByBranch(projectPath, b)
+		if err != nil {
+			return nil, fmt.Errorf("query attributions for branch %q: %w", b, err)
+		}
+		attributed = attributed || len(attrs) > 0
+	}
+	if !attributed {
+		return nil, fmt.Errorf("no attribution data for any branch of the stack %s", strings.Join(stack.Branches, ", "))
+	}
+
+	sr := &StackReport{Base: stack.Base, Source: stack.Source}
+	for i, b := range stack.Branches {
+		pr, err := generateBranchReport(s, projectPath, b, stack.Parent(i), opts)
+		if err != nil {
+			return nil, fmt.Errorf("layer %s: %w", b, err)
+		}
+		sr.Layers = append(sr.Layers, StackLayer{Branch: b, Parent: stack.Parent(i), Report: pr})
+	}
+	sr.Rollup, err = generateBranchReport(s, projectPath, sr.Top(), stack.Base, opts)
+	if err != nil {
+		return nil, fmt.Errorf("full stack: %w", err)
+	}
+	return sr, nil
+}
+
+// FormatStack formats sr as text: a summary of the layers, then each
+// layer's report and the full-stack report.
+func FormatStack(sr *StackReport, st Style) string {
+	ansi := st.palette()
+	var b strings.Builder
+	b.WriteString(ansi.bold + "Stack" + ansi.reset + "\n")
+	b.WriteString(strings.Repeat("=", 40) + "\n\n")
+	b.WriteString(fmt.Sprintf("%d layers on %s, from %s\n\n", len(sr.Layers), sr.Base, sr.Source))
+	b.WriteString(fmt.Sprintf("  %-3s %-30s %6s %8s %6s\n", "#", "Branch", "Files", "Lines", "AI%"))
+	for i, l := range sr.Layers {
+		b.WriteString(fmt.Sprintf("  %-3d %-30s %6s %8s %5.1f%%\n",
+			i+1, l.Branch, st.num(l.Report.TotalFiles), st.num(l.Report.TotalLines), l.Report.MeaningfulAIPct))
+	}
+	b.WriteString(fmt.Sprintf("  %-3s %-30s %6s %8s %5.1f%%\n",
+		"", "full stack", st.num(sr.Rollup.TotalFiles), st.num(sr.Rollup.TotalLines), sr.Rollup.MeaningfulAIPct))
+
+	for i, l := range sr.Layers {
+		b.WriteString(fmt.Sprintf("\n"+ansi.bold+"Layer %d: %s (on %s)"+ansi.reset+"\n\n", i+1, l.Branch, l.Parent))
+		b.WriteString(FormatProjectReport(l.Report, st))
+	}
+	b.WriteString(fmt.Sprintf("\n"+ansi.bold+"Full stack: %s (on %s)"+ansi.reset+"\n\n", sr.Top(), sr.Base))
+	b.WriteString(FormatProjectReport(sr.Rollup, st))
+	return b.String()
+}
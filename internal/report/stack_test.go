@@ -0,0 +1,68 @@
+package report
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anthropic/gap-map/internal/gitint"
+)
+
+func TestGenerateStack(t *testing.T) {
+	s, projDir, cleanup := setupBranchTestStore(t)
+	defer cleanup()
+
+	// main → branch-a → branch-b → branch-c, with b.go attributed before
+	// branch-b was recorded, so nothing is attributed on branch-b.
+	gitCheckoutCreate(t, projDir, "branch-a")
+	gitCommitOnBranch(t, projDir, "a.go", "package a\n\nvar A = 1\n", "add a")
+	insertAttributionOnBranch(t, s, "a.go", projDir, "mostly_ai", "core_logic", "branch-a", baseTime, 2)
+
+	gitCheckoutCreate(t, projDir, "branch-b")
+	gitCommitOnBranch(t, projDir, "b.go", "package b\n\nvar B = 1\n", "add b")
+	insertAttributionOnBranch(t, s, "b.go", projDir, "mostly_human", "core_logic", "", baseTime, 2)
+
+	gitCheckoutCreate(t, projDir, "branch-c")
+	gitCommitOnBranch(t, projDir, "c.go", "package c\n\nvar C = 1\n", "add c")
+	insertAttributionOnBranch(t, s, "c.go", projDir, "mostly_ai", "core_logic", "branch-c", baseTime.Add(time.Second), 2)
+
+	stack, err := gitint.DiscoverStack(projDir, "branch-c", "main")
+	if err != nil {
+		t.Fatalf("DiscoverStack: %v", err)
+	}
+	sr, err := GenerateStack(s, stack, Options{})
+	if err != nil {
+		t.Fatalf("GenerateStack: %v", err)
+	}
+	if len(sr.Layers) != 3 {
+		t.Fatalf("got %d layers, want 3", len(sr.Layers))
+	}
+	for i, want := range []struct{ branch, parent, file string }{
+		{"branch-a", "main", "a.go"},
+		{"branch-b", "branch-a", "b.go"},
+		{"branch-c", "branch-b", "c.go"},
+	} {
+		l := sr.Layers[i]
+		if l.Branch != want.branch || l.Parent != want.parent {
+			t.Errorf("layer %d = %s on %s, want %s on %s", i, l.Branch, l.Parent, want.branch, want.parent)
+		}
+		if l.Report.TotalFiles != 1 || l.Report.Files[0].FilePath != want.file {
+			t.Errorf("layer %s files = %+v, want only %s", l.Branch, l.Report.Files, want.file)
+		}
+	}
+	if sr.Rollup.TotalFiles != 3 {
+		t.Errorf("rollup TotalFiles = %d, want 3", sr.Rollup.TotalFiles)
+	}
+
+	out := FormatStack(sr, Style{})
+	for _, want := range []string{"3 layers on main", "Layer 2: branch-b (on branch-a)", "Full stack: branch-c (on main)"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("FormatStack output missing %q:\n%s", want, out)
+		}
+	}
+
+	// A stack with no attributions at all is rejected.
+	if _, err := GenerateStack(s, gitint.Stack{Base: "branch-a", Branches: []string{"branch-b"}}, Options{}); err == nil {
+		t.Error("GenerateStack of an unattributed stack: want error")
+	}
+}
@@ -0,0 +1,80 @@
+package report
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/anthropic/gap-map/internal/metrics"
+	"github.com/anthropic/gap-map/internal/store"
+)
+
+// StreamProject is GenerateProjectFiltered for projects too large to hold
+// in memory: each selected file's report is passed to fn, in path order, as
+// soon as it is ready, and is not kept. Files below f.MinAIPct are counted
+// in the totals but not passed to fn. f.Sort and f.Top need the whole file
+// list and are rejected. The returned report has the project totals and
+// breakdowns but no Files. An error from fn stops the report and is
+// returned.
+func StreamProject(ctx context.Context, s *store.Store, scorer metrics.Scorer, f Filter, opts Options, fn func(FileReport) error) (*ProjectReport, error) {
+	if err := f.Validate(); err != nil {
+		return nil, err
+	}
+	if f.Sort != "" || f.Top > 0 {
+		return nil, fmt.Errorf("a streamed report lists files in path order; sort and top need the whole list")
+	}
+
+	var scores []metrics.FileScore
+	report, err := generateProject(ctx, s, scorer, f, opts, func(report *ProjectReport, fr FileReport) error {
+		addFileTotals(report, fr, scorer)
+		addFilePackage(report, &fr, opts.Packages)
+		scores = append(scores, fileScore(fr))
+		if fr.MeaningfulAIPct < f.MinAIPct {
+			return nil
+		}
+		return fn(fr)
+	})
+	if err != nil {
+		return nil, err
+	}
+	finishTotals(report, scorer, scores)
+	finishPackages(report)
+	return report, nil
+}
+
+// NDJSON record types: the "type" member of each line NDJSONWriter writes.
+const (
+	NDJSONFile    = "file"
+	NDJSONSummary = "summary"
+)
+
+// NDJSONWriter writes a streamed project report as newline-delimited JSON:
+// one {"type":"file",...} object per file, with FileReport's members, then
+// a {"type":"summary",...} object with ProjectReport's members but no
+// files.
+type NDJSONWriter struct {
+	enc *json.Encoder
+}
+
+// NewNDJSONWriter returns an NDJSONWriter writing to w.
+func NewNDJSONWriter(w io.Writer) *NDJSONWriter {
+	return &NDJSONWriter{enc: json.NewEncoder(w)}
+}
+
+// File writes one file's line.
+func (n *NDJSONWriter) File(fr FileReport) error {
+	return n.enc.Encode(struct {
+		Type string `json:"type"`
+		FileReport
+	}{NDJSONFile, fr})
+}
+
+// Summary writes the project summary line.
+func (n *NDJSONWriter) Summary(r *ProjectReport) error {
+	return n.enc.Encode(struct {
+		Type string `json:"type"`
+		*ProjectReport
+		Files []FileReport `json:"files,omitempty"` // shadows ProjectReport.Files
+	}{Type: NDJSONSummary, ProjectReport: r})
+}
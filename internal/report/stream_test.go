@@ -0,0 +1,151 @@
+package report
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/anthropic/gap-map/internal/metrics"
+)
+
+// setupStreamProject commits numFiles files, has the AI rewrite every
+// other one and records an attribution for each.
+func setupStreamProject(t *testing.T, numFiles int) (*ProjectReport, func(Filter, func(FileReport) error) (*ProjectReport, error)) {
+	t.Helper()
+	s, projDir, cleanup := setupTestStore(t)
+	t.Cleanup(cleanup)
+
+	var files []string
+	for i := 0; i < numFiles; i++ {
+		name := fmt.Sprintf("pkg/file%02d.go", i)
+		writeFile(t, projDir, name, "package pkg\n\nfunc f() {}\n")
+		files = append(files, name)
+	}
+	gitAdd(t, projDir, files, "base")
+	for i, name := range files {
+		content := fmt.Sprintf("package pkg\n\nfunc f() {}\n\nfunc g%d() int {\n\treturn %d\n}\n", i, i)
+		writeFile(t, projDir, name, content)
+		ts := baseTime.Add(time.Duration(i) * time.Second)
+		level := "mostly_human"
+		if i%2 == 0 {
+			insertSessionEvent(t, s, "s1", filepath.Join(projDir, name), makeWriteRawJSON(filepath.Join(projDir, name), content), ts)
+			level = "mostly_ai"
+		}
+		insertAttribution(t, s, name, projDir, level, "core_logic", ts, 3)
+	}
+
+	// Several workers, so files finish out of order.
+	opts := Options{Workers: 3}
+	full, err := GenerateProject(t.Context(), s, metrics.DefaultScorer(), Filter{}, opts)
+	if err != nil {
+		t.Fatalf("GenerateProjectFromStoreWithScorer: %v", err)
+	}
+	stream := func(f Filter, fn func(FileReport) error) (*ProjectReport, error) {
+		return StreamProject(context.Background(), s, metrics.DefaultScorer(), f, opts, fn)
+	}
+	return full, stream
+}
+
+func TestStreamProject_MatchesFullReport(t *testing.T) {
+	full, stream := setupStreamProject(t, 20)
+
+	var got []FileReport
+	summary, err := stream(Filter{}, func(fr FileReport) error {
+		got = append(got, fr)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("StreamProject: %v", err)
+	}
+
+	if len(summary.Files) != 0 {
+		t.Errorf("summary holds %d files, want none", len(summary.Files))
+	}
+	if summary.TotalFiles != full.TotalFiles || summary.TotalLines != full.TotalLines || summary.AILines != full.AILines {
+		t.Errorf("summary totals = %d files, %d lines, %d AI; want %d, %d, %d",
+			summary.TotalFiles, summary.TotalLines, summary.AILines, full.TotalFiles, full.TotalLines, full.AILines)
+	}
+	if summary.MeaningfulAIPct != full.MeaningfulAIPct || summary.RawAIPct != full.RawAIPct {
+		t.Errorf("summary AI%% = %.2f/%.2f, want %.2f/%.2f",
+			summary.MeaningfulAIPct, summary.RawAIPct, full.MeaningfulAIPct, full.RawAIPct)
+	}
+
+	// Files arrive in path order, and are the full report's files.
+	if !sort.SliceIsSorted(got, func(i, j int) bool { return got[i].FilePath < got[j].FilePath }) {
+		t.Error("streamed files are not in path order")
+	}
+	want := make(map[string]FileReport)
+	for _, fr := range full.Files {
+		want[fr.FilePath] = fr
+	}
+	if len(got) != len(want) {
+		t.Fatalf("streamed %d files, want %d", len(got), len(want))
+	}
+	for _, fr := range got {
+		w, ok := want[fr.FilePath]
+		if !ok || w.AILines != fr.AILines || w.TotalLines != fr.TotalLines {
+			t.Errorf("streamed %s = %d/%d lines, want %+v", fr.FilePath, fr.AILines, fr.TotalLines, w)
+		}
+	}
+}
+
+func TestStreamProject_StopsOnError(t *testing.T) {
+	_, stream := setupStreamProject(t, 6)
+
+	stop := errors.New("reader went away")
+	calls := 0
+	_, err := stream(Filter{}, func(FileReport) error {
+		calls++
+		return stop
+	})
+	if !errors.Is(err, stop) {
+		t.Errorf("StreamProject = %v, want the callback's error", err)
+	}
+	if calls != 1 {
+		t.Errorf("callback called %d times after failing, want 1", calls)
+	}
+
+	if _, err := stream(Filter{Top: 3}, func(FileReport) error { return nil }); err == nil {
+		t.Error("StreamProject with Top: want error")
+	}
+}
+
+func TestNDJSONWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewNDJSONWriter(&buf)
+	if err := w.File(FileReport{FilePath: "a.go", AILines: 3}); err != nil {
+		t.Fatal(err)
+	}
+	r := &ProjectReport{ProjectPath: "/p", TotalFiles: 1, Files: []FileReport{{FilePath: "a.go"}}}
+	if err := w.Summary(r); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want 2:\n%s", len(lines), buf.String())
+	}
+	var file map[string]interface{}
+	if err := json.Unmarshal(lines[0], &file); err != nil {
+		t.Fatal(err)
+	}
+	if file["type"] != NDJSONFile || file["file_path"] != "a.go" || file["ai_lines"] != 3.0 {
+		t.Errorf("file line = %s", lines[0])
+	}
+	var summary map[string]interface{}
+	if err := json.Unmarshal(lines[1], &summary); err != nil {
+		t.Fatal(err)
+	}
+	if summary["type"] != NDJSONSummary || summary["project_path"] != "/p" {
+		t.Errorf("summary line = %s", lines[1])
+	}
+	if _, ok := summary["files"]; ok {
+		t.Errorf("summary line lists files: %s", lines[1])
+	}
+}
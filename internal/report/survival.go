@@ -0,0 +1,302 @@
+package report
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/anthropic/gap-map/internal/store"
+	"github.com/anthropic/gap-map/internal/survival"
+)
+
+// Survival breakdown rows in display order; categories not listed are
+// omitted.
+var (
+	survivalLevels    = []string{"mostly_ai", "mixed", "mostly_human"}
+	survivalWorkTypes = []string{"architecture", "core_logic", "bug_fix", "edge_case", "database_migration", "boilerplate", "test_scaffolding", "documentation"}
+)
+
+// survivalFileAges and survivalSizes return the file age and change size
+// rows of sr in display order.
+func survivalFileAges() []string {
+	return []string{store.FileAgeNew, store.FileAgeExisting, survival.UnknownBucket}
+}
+
+func survivalSizes() []string {
+	return append(append([]string(nil), survival.SizeBuckets...), survival.UnknownBucket)
+}
+
+// maxSurvivalDirectories is how many directories the survival report
+// lists; JSON output has them all.
+const maxSurvivalDirectories = 10
+
+// survivalDirectories returns the directories of sr with the most tracked
+// edits, most first, or nil when all the edits are in one directory.
+func survivalDirectories(sr *survival.SurvivalReport) []string {
+	if len(sr.ByDirectory) < 2 {
+		return nil
+	}
+	dirs := make([]string, 0, len(sr.ByDirectory))
+	for d := range sr.ByDirectory {
+		dirs = append(dirs, d)
+	}
+	sort.Slice(dirs, func(i, j int) bool {
+		a, b := sr.ByDirectory[dirs[i]], sr.ByDirectory[dirs[j]]
+		if a.Tracked != b.Tracked {
+			return a.Tracked > b.Tracked
+		}
+		return dirs[i] < dirs[j]
+	})
+	if len(dirs) > maxSurvivalDirectories {
+		dirs = dirs[:maxSurvivalDirectories]
+	}
+	return dirs
+}
+
+// survivalModels returns the models of sr in name order, or nil when there
+// are fewer than two to compare.
+func survivalModels(sr *survival.SurvivalReport) []string {
+	if len(sr.ByModel) < 2 {
+		return nil
+	}
+	models := make([]string, 0, len(sr.ByModel))
+	for m := range sr.ByModel {
+		models = append(models, m)
+	}
+	sort.Strings(models)
+	return models
+}
+
+// FormatSurvivalReport formats a SurvivalReport as a terminal-friendly
+// string, with survival rates colored when st has color.
+func FormatSurvivalReport(sr *survival.SurvivalReport, st Style) string {
+	ansi := st.palette()
+	var b strings.Builder
+
+	colorRate := func(rate float64) string {
+		switch {
+		case rate >= 80:
+			return ansi.green
+		case rate >= 50:
+			return ansi.yellow
+		default:
+			return ansi.red
+		}
+	}
+
+	b.WriteString(ansi.bold + "Gap Map - Code Survival Report" + ansi.reset + "\n")
+	b.WriteString(strings.Repeat("=", 40) + "\n\n")
+
+	b.WriteString(fmt.Sprintf("Tracked AI lines: %d\n", sr.TotalTracked))
+	b.WriteString(fmt.Sprintf("Survived:         %d\n", sr.SurvivedCount))
+	b.WriteString(fmt.Sprintf("Survival rate:    %s%s%.1f%%%s\n",
+		ansi.bold, colorRate(sr.SurvivalRate), sr.SurvivalRate, ansi.reset))
+	if sr.CorrectionTracked > 0 {
+		b.WriteString(fmt.Sprintf("Correction rate:  %.1f%% (%d of %d AI edits changed by a human within %d days)\n",
+			sr.CorrectionRate, sr.CorrectedCount, sr.CorrectionTracked, sr.CorrectionWindowDays))
+	}
+	if !sr.CheckedAt.IsZero() {
+		b.WriteString(fmt.Sprintf("Checked:          %s\n", ago(sr.CheckedAt.Format(time.RFC3339))))
+	}
+	b.WriteString("\n")
+
+	// By authorship level.
+	if len(sr.ByAuthorship) > 0 {
+		b.WriteString(ansi.bold + "By Authorship Level" + ansi.reset + "\n")
+		b.WriteString(strings.Repeat("-", 50) + "\n")
+		b.WriteString(fmt.Sprintf("%-28s %8s %8s %7s\n", "Level", "Tracked", "Survived", "Rate"))
+		b.WriteString(strings.Repeat("-", 50) + "\n")
+
+		for _, level := range survivalLevels {
+			bd, ok := sr.ByAuthorship[level]
+			if !ok {
+				continue
+			}
+			b.WriteString(fmt.Sprintf("%-28s %8d %8d %s%6.1f%%%s\n",
+				level, bd.Tracked, bd.Survived, colorRate(bd.Rate), bd.Rate, ansi.reset))
+		}
+		b.WriteString("\n")
+	}
+
+	// By work type.
+	if len(sr.ByWorkType) > 0 {
+		b.WriteString(ansi.bold + "By Work Type" + ansi.reset + "\n")
+		b.WriteString(strings.Repeat("-", 50) + "\n")
+		b.WriteString(fmt.Sprintf("%-18s %8s %8s %7s\n", "Work Type", "Tracked", "Survived", "Rate"))
+		b.WriteString(strings.Repeat("-", 50) + "\n")
+
+		for _, wt := range survivalWorkTypes {
+			bd, ok := sr.ByWorkType[wt]
+			if !ok {
+				continue
+			}
+			b.WriteString(fmt.Sprintf("%-18s %8d %8d %s%6.1f%%%s\n",
+				wt, bd.Tracked, bd.Survived, colorRate(bd.Rate), bd.Rate, ansi.reset))
+		}
+	}
+
+	// By model, once there is more than one to compare.
+	if models := survivalModels(sr); models != nil {
+		b.WriteString("\n" + ansi.bold + "By Model" + ansi.reset + "\n")
+		b.WriteString(strings.Repeat("-", 50) + "\n")
+		b.WriteString(fmt.Sprintf("%-28s %8s %8s %7s\n", "Model", "Tracked", "Survived", "Rate"))
+		b.WriteString(strings.Repeat("-", 50) + "\n")
+
+		for _, m := range models {
+			bd := sr.ByModel[m]
+			b.WriteString(fmt.Sprintf("%-28s %8d %8d %s%6.1f%%%s\n",
+				m, bd.Tracked, bd.Survived, colorRate(bd.Rate), bd.Rate, ansi.reset))
+		}
+	}
+
+	// By file age, change size and directory.
+	breakdown := func(title, column string, keys []string, rows map[string]survival.SurvivalBreakdown) {
+		var present []string
+		for _, k := range keys {
+			if _, ok := rows[k]; ok {
+				present = append(present, k)
+			}
+		}
+		if len(present) == 0 {
+			return
+		}
+		b.WriteString("\n" + ansi.bold + title + ansi.reset + "\n")
+		b.WriteString(strings.Repeat("-", 50) + "\n")
+		b.WriteString(fmt.Sprintf("%-28s %8s %8s %7s\n", column, "Tracked", "Survived", "Rate"))
+		b.WriteString(strings.Repeat("-", 50) + "\n")
+		for _, k := range present {
+			bd := rows[k]
+			b.WriteString(fmt.Sprintf("%-28s %8d %8d %s%6.1f%%%s\n",
+				k, bd.Tracked, bd.Survived, colorRate(bd.Rate), bd.Rate, ansi.reset))
+		}
+	}
+	breakdown("By File Age", "File", survivalFileAges(), sr.ByFileAge)
+	breakdown("By Change Size", "Lines Changed", survivalSizes(), sr.BySize)
+	breakdown("By Directory", "Directory", survivalDirectories(sr), sr.ByDirectory)
+
+	// Tokens spent against the lines that survived.
+	if c := sr.Cost; c != nil {
+		b.WriteString("\n" + ansi.bold + "Cost" + ansi.reset + "\n")
+		b.WriteString(strings.Repeat("-", 50) + "\n")
+		b.WriteString(fmt.Sprintf("Sessions:           %d\n", c.Sessions))
+		b.WriteString(fmt.Sprintf("Tokens:             %d (input %d, output %d, cache write %d, cache read %d)\n",
+			c.TotalTokens, c.InputTokens, c.OutputTokens, c.CacheCreationTokens, c.CacheReadTokens))
+		b.WriteString(fmt.Sprintf("Surviving lines:    %d\n", sr.SurvivedLines))
+		if sr.SurvivedLines > 0 {
+			b.WriteString(fmt.Sprintf("Tokens per line:    %.0f\n", c.TokensPerSurvivingLine))
+		}
+		if c.USD > 0 {
+			b.WriteString(fmt.Sprintf("Cost:               $%.2f", c.USD))
+			if sr.SurvivedLines > 0 {
+				b.WriteString(fmt.Sprintf(" ($%.4f per surviving line)", c.USDPerSurvivingLine))
+			}
+			b.WriteString("\n")
+		}
+		if len(c.UnpricedModels) > 0 {
+			b.WriteString(fmt.Sprintf("Unpriced models:    %s (set token_prices to include them in the cost)\n",
+				strings.Join(c.UnpricedModels, ", ")))
+		}
+	}
+
+	return b.String()
+}
+
+// FormatSurvivalMarkdown formats a SurvivalReport as Markdown, with the
+// same sections as FormatSurvivalReport as tables, for pasting into PRs
+// and wikis.
+func FormatSurvivalMarkdown(sr *survival.SurvivalReport) string {
+	var b strings.Builder
+
+	b.WriteString("## Gap Map - Code Survival Report\n\n")
+	b.WriteString(fmt.Sprintf("**%.1f%%** of tracked AI lines survived (%d of %d).\n",
+		sr.SurvivalRate, sr.SurvivedCount, sr.TotalTracked))
+	if sr.CorrectionTracked > 0 {
+		b.WriteString(fmt.Sprintf("\n**%.1f%%** of AI edits were changed by a human within %d days (%d of %d).\n",
+			sr.CorrectionRate, sr.CorrectionWindowDays, sr.CorrectedCount, sr.CorrectionTracked))
+	}
+
+	table := func(title, column string, keys []string, rows map[string]survival.SurvivalBreakdown) {
+		var present []string
+		for _, k := range keys {
+			if _, ok := rows[k]; ok {
+				present = append(present, k)
+			}
+		}
+		if len(present) == 0 {
+			return
+		}
+		b.WriteString("\n### " + title + "\n\n")
+		b.WriteString(fmt.Sprintf("| %s | Tracked | Survived | Rate |\n", column))
+		b.WriteString("|---|---:|---:|---:|\n")
+		for _, k := range present {
+			bd := rows[k]
+			b.WriteString(fmt.Sprintf("| %s | %d | %d | %.1f%% |\n", k, bd.Tracked, bd.Survived, bd.Rate))
+		}
+	}
+	table("By Authorship Level", "Level", survivalLevels, sr.ByAuthorship)
+	table("By Work Type", "Work Type", survivalWorkTypes, sr.ByWorkType)
+	table("By Model", "Model", survivalModels(sr), sr.ByModel)
+	table("By File Age", "File", survivalFileAges(), sr.ByFileAge)
+	table("By Change Size", "Lines Changed", survivalSizes(), sr.BySize)
+	table("By Directory", "Directory", survivalDirectories(sr), sr.ByDirectory)
+
+	if c := sr.Cost; c != nil {
+		b.WriteString("\n### Cost\n\n")
+		b.WriteString("| | |\n|---|---:|\n")
+		b.WriteString(fmt.Sprintf("| Sessions | %d |\n", c.Sessions))
+		b.WriteString(fmt.Sprintf("| Tokens | %d |\n", c.TotalTokens))
+		b.WriteString(fmt.Sprintf("| Surviving lines | %d |\n", sr.SurvivedLines))
+		if sr.SurvivedLines > 0 {
+			b.WriteString(fmt.Sprintf("| Tokens per line | %.0f |\n", c.TokensPerSurvivingLine))
+		}
+		if c.USD > 0 {
+			b.WriteString(fmt.Sprintf("| Cost | $%.2f |\n", c.USD))
+			if sr.SurvivedLines > 0 {
+				b.WriteString(fmt.Sprintf("| Cost per line | $%.4f |\n", c.USDPerSurvivingLine))
+			}
+		}
+		if len(c.UnpricedModels) > 0 {
+			b.WriteString(fmt.Sprintf("\nUnpriced models: %s\n", strings.Join(c.UnpricedModels, ", ")))
+		}
+	}
+
+	return b.String()
+}
+
+// FormatSurvivalHistory formats the survival of a project at each recorded
+// check as a table, oldest first, with times shown in loc.
+func FormatSurvivalHistory(points []store.SurvivalPoint, loc *time.Location, st Style) string {
+	ansi := st.palette()
+	if len(points) == 0 {
+		return "No survival checks recorded yet. The daemon records one every survival_interval.\n"
+	}
+	var b strings.Builder
+	b.WriteString(ansi.bold + "Gap Map - Code Survival History" + ansi.reset + "\n")
+	b.WriteString(strings.Repeat("-", 58) + "\n")
+	b.WriteString(fmt.Sprintf("%-20s %8s %8s %10s %7s\n", "Checked", "Tracked", "Survived", "Lines", "Rate"))
+	b.WriteString(strings.Repeat("-", 58) + "\n")
+	for _, p := range points {
+		b.WriteString(fmt.Sprintf("%-20s %8d %8d %10d %6.1f%%\n",
+			p.CheckedAt.In(loc).Format("2006-01-02 15:04"), p.Tracked, p.Survived, p.SurvivedLines, p.Rate))
+	}
+	return b.String()
+}
+
+// FormatSurvivalHistoryMarkdown formats the survival of a project at each
+// recorded check as a Markdown table, oldest first, with times shown in loc.
+func FormatSurvivalHistoryMarkdown(points []store.SurvivalPoint, loc *time.Location) string {
+	var b strings.Builder
+	b.WriteString("## Gap Map - Code Survival History\n\n")
+	if len(points) == 0 {
+		b.WriteString("No survival checks recorded yet.\n")
+		return b.String()
+	}
+	b.WriteString("| Checked | Tracked | Survived | Surviving lines | Rate |\n")
+	b.WriteString("|---|---:|---:|---:|---:|\n")
+	for _, p := range points {
+		b.WriteString(fmt.Sprintf("| %s | %d | %d | %d | %.1f%% |\n",
+			p.CheckedAt.In(loc).Format("2006-01-02 15:04"), p.Tracked, p.Survived, p.SurvivedLines, p.Rate))
+	}
+	return b.String()
+}
@@ -0,0 +1,132 @@
+package report
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/anthropic/gap-map/internal/survival"
+)
+
+func TestFormatSurvivalReport_BasicOutput(t *testing.T) {
+	sr := &survival.SurvivalReport{
+		TotalTracked:  100,
+		SurvivedCount: 85,
+		SurvivalRate:  85.0,
+		ByAuthorship: map[string]survival.SurvivalBreakdown{
+			"mostly_ai": {Tracked: 60, Survived: 50, Rate: 83.3},
+			"mixed":     {Tracked: 40, Survived: 35, Rate: 87.5},
+		},
+		ByWorkType: map[string]survival.SurvivalBreakdown{
+			"core_logic":  {Tracked: 70, Survived: 60, Rate: 85.7},
+			"boilerplate": {Tracked: 30, Survived: 25, Rate: 83.3},
+		},
+		ByModel: map[string]survival.SurvivalBreakdown{
+			"claude-opus-4-1":   {Tracked: 50, Survived: 45, Rate: 90.0},
+			"claude-sonnet-4-5": {Tracked: 50, Survived: 40, Rate: 80.0},
+		},
+		ByFileAge: map[string]survival.SurvivalBreakdown{
+			"new":      {Tracked: 30, Survived: 29, Rate: 96.7},
+			"existing": {Tracked: 70, Survived: 56, Rate: 80.0},
+		},
+		BySize: map[string]survival.SurvivalBreakdown{
+			"6-50": {Tracked: 100, Survived: 85, Rate: 85.0},
+		},
+		ByDirectory: map[string]survival.SurvivalBreakdown{
+			"internal/api": {Tracked: 80, Survived: 70, Rate: 87.5},
+			".":            {Tracked: 20, Survived: 15, Rate: 75.0},
+		},
+	}
+
+	output := FormatSurvivalReport(sr, Style{})
+
+	checks := []string{
+		"Code Survival Report",
+		"Tracked AI lines: 100",
+		"Survived:         85",
+		"85.0%",
+		"mostly_ai",
+		"mixed",
+		"core_logic",
+		"boilerplate",
+		"By Model",
+		"claude-opus-4-1",
+		"claude-sonnet-4-5",
+		"By File Age",
+		"new",
+		"By Change Size",
+		"6-50",
+		"By Directory",
+		"internal/api",
+	}
+
+	for _, check := range checks {
+		if !strings.Contains(output, check) {
+			t.Errorf("FormatSurvivalReport output missing %q", check)
+		}
+	}
+}
+
+func TestFormatSurvivalReport_Cost(t *testing.T) {
+	sr := &survival.SurvivalReport{
+		TotalTracked:  10,
+		SurvivedCount: 8,
+		SurvivalRate:  80.0,
+		SurvivedLines: 200,
+		Cost: &survival.CostReport{
+			Sessions:               2,
+			OutputTokens:           50000,
+			CacheReadTokens:        150000,
+			TotalTokens:            200000,
+			TokensPerSurvivingLine: 1000,
+			USD:                    3.00,
+			USDPerSurvivingLine:    0.015,
+			UnpricedModels:         []string{"claude-haiku-4-5"},
+		},
+	}
+
+	output := FormatSurvivalReport(sr, Style{})
+	for _, check := range []string{"Cost", "Tokens:             200000", "Tokens per line:    1000", "$3.00 ($0.0150 per surviving line)", "Unpriced models:    claude-haiku-4-5"} {
+		if !strings.Contains(output, check) {
+			t.Errorf("FormatSurvivalReport output missing %q\n\nFull output:\n%s", check, output)
+		}
+	}
+
+	sr.Cost = nil
+	if output := FormatSurvivalReport(sr, Style{}); strings.Contains(output, "Tokens:") {
+		t.Errorf("FormatSurvivalReport shows a cost section without usage:\n%s", output)
+	}
+}
+
+func TestFormatSurvivalMarkdown(t *testing.T) {
+	sr := &survival.SurvivalReport{
+		TotalTracked:  10,
+		SurvivedCount: 8,
+		SurvivalRate:  80.0,
+		SurvivedLines: 120,
+		ByAuthorship: map[string]survival.SurvivalBreakdown{
+			"mostly_ai": {Tracked: 10, Survived: 8, Rate: 80.0},
+		},
+		ByModel: map[string]survival.SurvivalBreakdown{
+			"claude-opus-4-1": {Tracked: 10, Survived: 8, Rate: 80.0},
+		},
+		Cost: &survival.CostReport{Sessions: 1, TotalTokens: 12000, TokensPerSurvivingLine: 100},
+	}
+
+	output := FormatSurvivalMarkdown(sr)
+	for _, check := range []string{
+		"## Gap Map - Code Survival Report",
+		"**80.0%** of tracked AI lines survived (8 of 10)",
+		"| Level | Tracked | Survived | Rate |",
+		"| mostly_ai | 10 | 8 | 80.0% |",
+		"| Tokens per line | 100 |",
+	} {
+		if !strings.Contains(output, check) {
+			t.Errorf("FormatSurvivalMarkdown output missing %q\n\nFull output:\n%s", check, output)
+		}
+	}
+	for _, absent := range []string{"\033[", "By Work Type", "By Model"} {
+		if strings.Contains(output, absent) {
+			t.Errorf("FormatSurvivalMarkdown output contains %q\n\nFull output:\n%s", absent, output)
+		}
+	}
+}
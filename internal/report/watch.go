@@ -0,0 +1,63 @@
+package report
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/anthropic/gap-map/internal/ipc"
+)
+
+// MatchEvent reports whether an attribution notification's file matches a
+// watch filter. A relative pattern is matched against the file's path
+// relative to its project and an absolute one against the full path, both
+// with MatchGlob. An empty pattern matches everything.
+func MatchEvent(pattern string, ev ipc.AttributionEvent) bool {
+	if pattern == "" {
+		return true
+	}
+	if filepath.IsAbs(pattern) {
+		return MatchGlob(filepath.ToSlash(pattern), strings.TrimPrefix(filepath.ToSlash(ev.FilePath), "/"))
+	}
+	return MatchGlob(filepath.ToSlash(pattern), filepath.ToSlash(eventPath(ev)))
+}
+
+// FormatAttributionEvent formats one attribution notification as a single
+// line: local time, authorship, lines, work type and file. AI authorship is
+// shown in red, mixed in yellow and human in green.
+func FormatAttributionEvent(ev ipc.AttributionEvent, loc *time.Location, st Style) string {
+	ansi := st.palette()
+	when := ev.Timestamp
+	if ts, err := time.Parse(time.RFC3339, ev.Timestamp); err == nil {
+		when = ts.In(loc).Format("15:04:05")
+	}
+
+	color := ansi.green
+	switch {
+	case isAIAuthorship(ev.AuthorshipLevel):
+		color = ansi.red
+	case ev.AuthorshipLevel == "mixed":
+		color = ansi.yellow
+	}
+
+	workType := ev.WorkType
+	if workType == "" {
+		workType = "-"
+	}
+	return fmt.Sprintf("%s  %s%-26s%s %+5d  %-12s %s",
+		when, color, ev.AuthorshipLevel, ansi.reset, ev.LinesChanged, workType, eventPath(ev))
+}
+
+// eventPath returns an event's file relative to its project, or the full
+// path when it is outside the project.
+func eventPath(ev ipc.AttributionEvent) string {
+	if ev.ProjectPath == "" {
+		return ev.FilePath
+	}
+	rel, err := filepath.Rel(ev.ProjectPath, ev.FilePath)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return ev.FilePath
+	}
+	return rel
+}
@@ -0,0 +1,63 @@
+package report
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anthropic/gap-map/internal/ipc"
+)
+
+func TestMatchEvent(t *testing.T) {
+	ev := ipc.AttributionEvent{FilePath: "/work/api/internal/auth/token.go", ProjectPath: "/work/api"}
+	tests := []struct {
+		pattern string
+		want    bool
+	}{
+		{"", true},
+		{"internal/**/*.go", true},
+		{"internal/auth", true},
+		{"*.go", false},
+		{"**/*.go", true},
+		{"cmd/**", false},
+		{"/work/api/**/*.go", true},
+		{"/work/web/**", false},
+	}
+	for _, tt := range tests {
+		if got := MatchEvent(tt.pattern, ev); got != tt.want {
+			t.Errorf("MatchEvent(%q) = %v, want %v", tt.pattern, got, tt.want)
+		}
+	}
+}
+
+func TestFormatAttributionEvent(t *testing.T) {
+	loc := time.FixedZone("EST", -5*3600)
+	ev := ipc.AttributionEvent{
+		FilePath:        "/work/api/handler.go",
+		ProjectPath:     "/work/api",
+		AuthorshipLevel: "mostly_ai",
+		WorkType:        "core_logic",
+		LinesChanged:    12,
+		Timestamp:       "2026-02-09T12:00:05Z",
+	}
+	got := FormatAttributionEvent(ev, loc, Style{})
+	for _, want := range []string{"07:00:05", "mostly_ai", "+12", "core_logic", "handler.go"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("line %q missing %q", got, want)
+		}
+	}
+	if strings.Contains(got, "/work/api") {
+		t.Errorf("line %q should show the path relative to the project", got)
+	}
+	if strings.Contains(got, "\033[") {
+		t.Errorf("plain line %q has ANSI escapes", got)
+	}
+	if got := FormatAttributionEvent(ev, loc, Style{Color: true}); !strings.Contains(got, "\033[31mmostly_ai") {
+		t.Errorf("colored line %q does not show AI authorship in red", got)
+	}
+
+	ev.FilePath = "/elsewhere/x.go"
+	if got := FormatAttributionEvent(ev, loc, Style{}); !strings.Contains(got, "/elsewhere/x.go") {
+		t.Errorf("file outside the project = %q, want the full path", got)
+	}
+}
@@ -0,0 +1,192 @@
+package report
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/anthropic/gap-map/internal/store"
+)
+
+// Window restricts a report to the attributions and AI edits made between
+// Since and Until. A zero bound leaves that side open; the zero Window
+// covers everything since tracking began.
+type Window struct {
+	Since time.Time `json:"since,omitzero"`
+	Until time.Time `json:"until,omitzero"`
+}
+
+// IsZero reports whether w covers everything.
+func (w Window) IsZero() bool {
+	return w.Since.IsZero() && w.Until.IsZero()
+}
+
+// Validate checks that Until does not come before Since.
+func (w Window) Validate() error {
+	if !w.Since.IsZero() && !w.Until.IsZero() && w.Until.Before(w.Since) {
+		return fmt.Errorf("until (%s) is before since (%s)", w.Until.Format(time.RFC3339), w.Since.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// Contains reports whether t falls in w. Since is inclusive, Until
+// exclusive.
+func (w Window) Contains(t time.Time) bool {
+	return (w.Since.IsZero() || !t.Before(w.Since)) && (w.Until.IsZero() || t.Before(w.Until))
+}
+
+// String describes w, e.g. "2026-03-01 09:00 to now".
+func (w Window) String() string {
+	const layout = "2006-01-02 15:04"
+	since, until := "start of tracking", "now"
+	if !w.Since.IsZero() {
+		since = w.Since.Format(layout)
+	}
+	if !w.Until.IsZero() {
+		until = w.Until.Format(layout)
+	}
+	return since + " to " + until
+}
+
+// sessionEvents returns the events of events made in w.
+func (w Window) sessionEvents(events []store.StoredSessionEvent) []store.StoredSessionEvent {
+	if w.IsZero() {
+		return events
+	}
+	var in []store.StoredSessionEvent
+	for _, se := range events {
+		if w.Contains(se.Timestamp) {
+			in = append(in, se)
+		}
+	}
+	return in
+}
+
+// attributions returns the attributions of attrs made in w.
+func (w Window) attributions(attrs []store.AttributionWithWorkType) []store.AttributionWithWorkType {
+	if w.IsZero() {
+		return attrs
+	}
+	var in []store.AttributionWithWorkType
+	for _, a := range attrs {
+		if w.Contains(a.Timestamp) {
+			in = append(in, a)
+		}
+	}
+	return in
+}
+
+// span is a Window resolved against a repository: the commits the
+// changed lines of its files are diffed between.
+type span struct {
+	base string // commit nearest before Since; "" diffs from each file's tracking base
+	end  string // commit nearest before Until; "" diffs to the working tree
+}
+
+// resolve finds the commits on HEAD nearest before w's bounds in the
+// repository at projectPath. A bound with no commit before it resolves to
+// "", as does an open one.
+func (w Window) resolve(ctx context.Context, projectPath string, opts Options) span {
+	var sp span
+	if !w.Since.IsZero() {
+		sp.base = commitBefore(ctx, projectPath, w.Since, opts)
+	}
+	if !w.Until.IsZero() {
+		sp.end = commitBefore(ctx, projectPath, w.Until, opts)
+	}
+	return sp
+}
+
+// commitBefore returns the latest commit on HEAD committed at or before t,
+// or "" if there is none.
+func commitBefore(ctx context.Context, projectPath string, t time.Time, opts Options) string {
+	out, err := opts.git().Output(ctx, projectPath, "rev-list", "-1", "--before="+t.UTC().Format(time.RFC3339), "HEAD")
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// content returns the file as it is at the end of sp: in the working tree,
+// or at sp.end.
+func (sp span) content(ctx context.Context, projectPath, filePath string, opts Options) string {
+	if sp.end == "" {
+		return readFileContent(resolveFilePath(projectPath, filePath))
+	}
+	return gitShowFile(ctx, projectPath, filePath, sp.end, opts)
+}
+
+// changedLines is getChangedLines over sp: the lines added to and removed
+// from a file between sp.base (or its tracking base) and sp.end (or the
+// working tree).
+func (sp span) changedLines(ctx context.Context, s *store.Store, projectPath, filePath string, opts Options) (numbers []int, added []string, deleted, base string) {
+	if sp.base == "" && sp.end == "" {
+		return getChangedLines(ctx, s, projectPath, filePath, opts)
+	}
+	baseCommit := sp.base
+	if baseCommit == "" {
+		baseCommit = trackingBaseCommit(ctx, s, projectPath, filePath, opts)
+	}
+	if baseCommit == "" {
+		// The file has no history before the window: all of it is new.
+		content := sp.content(ctx, projectPath, filePath, opts)
+		if content == "" {
+			return nil, nil, "", ""
+		}
+		for i, line := range strings.Split(strings.TrimSuffix(content, "\n"), "\n") {
+			numbers = append(numbers, i+1)
+			added = append(added, line)
+		}
+		return numbers, added, "", ""
+	}
+
+	var diff string
+	if sp.end == "" {
+		diff = gitDiff(ctx, projectPath, filePath, baseCommit, opts)
+	} else {
+		diff = gitDiffCommits(ctx, projectPath, filePath, baseCommit, sp.end, opts)
+	}
+	numbers, added = parseDiffAdditionsNumbered(diff)
+	deleted = parseDiffDeletions(diff)
+	if len(added) == 0 && deleted == "" {
+		return nil, nil, "", ""
+	}
+	return numbers, added, deleted, gitShowFile(ctx, projectPath, filePath, baseCommit, opts)
+}
+
+// gitDiffCommits returns the unified diff of a file between two commits,
+// or empty string on error.
+func gitDiffCommits(ctx context.Context, projectPath, filePath, from, to string, opts Options) string {
+	out, err := opts.git().Output(ctx, projectPath, "diff", opts.diffAlgorithmFlag(), from, to, "--", projectRelPath(projectPath, resolveFilePath(projectPath, filePath)))
+	if err != nil {
+		return ""
+	}
+	return string(out)
+}
+
+// ParseWindowBound parses a --since or --until value: an RFC 3339 time, a
+// date ("2026-03-01", midnight in loc), or a duration before now ("36h",
+// "14d", "2w").
+func ParseWindowBound(v string, now time.Time, loc *time.Location) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", v, loc); err == nil {
+		return t, nil
+	}
+	if n := len(v); n > 1 && (v[n-1] == 'd' || v[n-1] == 'w') {
+		var count int
+		if _, err := fmt.Sscanf(v[:n-1], "%d", &count); err == nil && count >= 0 && fmt.Sprint(count) == v[:n-1] {
+			days := count
+			if v[n-1] == 'w' {
+				days *= 7
+			}
+			return now.AddDate(0, 0, -days), nil
+		}
+	}
+	if d, err := time.ParseDuration(v); err == nil && d >= 0 {
+		return now.Add(-d), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q (want RFC 3339, YYYY-MM-DD or a duration like 14d)", v)
+}
@@ -0,0 +1,101 @@
+package report
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/anthropic/gap-map/internal/metrics"
+)
+
+func TestGenerateProjectWindow(t *testing.T) {
+	s, projDir, cleanup := setupTestStore(t)
+	defer cleanup()
+	path := filepath.Join(projDir, "handler.go")
+
+	// Before tracking, then a human commit, then an uncommitted AI edit.
+	writeFile(t, projDir, "handler.go", "package handler\n\nfunc Handle() {\n\treturn nil\n}\n")
+	gitAdd(t, projDir, []string{"handler.go"}, "add handler.go")
+	writeFile(t, projDir, "handler.go", "package handler\n\n// Handle handles.\nfunc Handle() {\n\treturn nil\n}\n")
+	gitAddAt(t, projDir, []string{"handler.go"}, "document Handle", baseTime.Add(time.Hour))
+	insertAttribution(t, s, "handler.go", projDir, "mostly_human", "core_logic", baseTime.Add(30*time.Minute), 1)
+	writeFile(t, projDir, "handler.go", "package handler\n\n// Handle handles.\nfunc Handle() {\n\treturn ok\n}\n")
+	insertSessionEvent(t, s, "s1", path, makeWriteRawJSON(path, "\treturn ok"), baseTime.Add(3*time.Hour))
+	insertAttribution(t, s, "handler.go", projDir, "mostly_ai", "core_logic", baseTime.Add(3*time.Hour), 1)
+
+	tests := []struct {
+		name          string
+		window        Window
+		total, ai     int
+		events        int
+		wantNilWindow bool
+	}{
+		{"everything", Window{}, 2, 1, 2, true},
+		{"since", Window{Since: baseTime.Add(2 * time.Hour)}, 1, 1, 1, false},
+		{"until", Window{Until: baseTime.Add(2 * time.Hour)}, 1, 0, 1, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := GenerateProject(t.Context(), s, metrics.DefaultScorer(), Filter{Window: tt.window}, Options{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(r.Files) != 1 {
+				t.Fatalf("Files = %+v, want handler.go", r.Files)
+			}
+			fr := r.Files[0]
+			if fr.TotalLines != tt.total || fr.AILines != tt.ai || fr.TotalEvents != tt.events {
+				t.Errorf("lines = %d (%d AI), events = %d; want %d (%d AI), %d", fr.TotalLines, fr.AILines, fr.TotalEvents, tt.total, tt.ai, tt.events)
+			}
+			if (r.Window == nil) != tt.wantNilWindow {
+				t.Errorf("Window = %v", r.Window)
+			}
+
+			file, err := GenerateFileWindow(s, "handler.go", tt.window, Options{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if file.TotalLines != tt.total || file.AILines != tt.ai {
+				t.Errorf("file report lines = %d (%d AI), want %d (%d AI)", file.TotalLines, file.AILines, tt.total, tt.ai)
+			}
+		})
+	}
+
+	if _, err := GenerateFileWindow(s, "handler.go", Window{Since: baseTime.Add(5 * time.Hour)}, Options{}); err == nil {
+		t.Error("expected an error for a window without attributions")
+	}
+	bad := Window{Since: baseTime, Until: baseTime.Add(-time.Hour)}
+	if _, err := GenerateProject(t.Context(), s, metrics.DefaultScorer(), Filter{Window: bad}, Options{}); err == nil {
+		t.Error("expected an error for until before since")
+	}
+}
+
+func TestParseWindowBound(t *testing.T) {
+	now := time.Date(2026, 3, 15, 12, 0, 0, 0, time.UTC)
+	loc := time.FixedZone("UTC+2", 2*3600)
+	tests := []struct {
+		in   string
+		want time.Time
+	}{
+		{"2026-03-01T09:00:00Z", time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)},
+		{"2026-03-01", time.Date(2026, 3, 1, 0, 0, 0, 0, loc)},
+		{"14d", now.AddDate(0, 0, -14)},
+		{"2w", now.AddDate(0, 0, -14)},
+		{"36h", now.Add(-36 * time.Hour)},
+	}
+	for _, tt := range tests {
+		got, err := ParseWindowBound(tt.in, now, loc)
+		if err != nil {
+			t.Errorf("ParseWindowBound(%q): %v", tt.in, err)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("ParseWindowBound(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+	for _, bad := range []string{"", "yesterday", "-3d", "1.5d", "-2h"} {
+		if _, err := ParseWindowBound(bad, now, loc); err == nil {
+			t.Errorf("ParseWindowBound(%q) accepted an invalid value", bad)
+		}
+	}
+}
@@ -54,6 +54,7 @@ func watchForNewSessions(ctx context.Context, baseDir string, found chan<- Sessi
 					Path:      event.Name,
 					SessionID: sessionIDFromPath(event.Name),
 					Provider:  "claude-code",
+					Root:      baseDir,
 				}
 
 				select {
@@ -0,0 +1,335 @@
+package sessionparser
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+// PluginProtocol is the version of the session provider plugin protocol,
+// sent with discover and tail requests.
+const PluginProtocol = 1
+
+// pluginCallTimeout bounds one request to a plugin. A plugin that does not
+// answer in time is killed and restarted on the next request.
+const pluginCallTimeout = 10 * time.Second
+
+// GenericProvider implements SessionProvider with an external command, so
+// AI tools gap-map does not know can feed it events without a fork.
+//
+// The command is started once and kept running. gap-map writes one JSON
+// request per line to its stdin and reads one JSON response per line from
+// its stdout; anything it writes to stderr goes to the daemon's stderr.
+// Requests:
+//
+//	{"method":"discover","protocol":1}
+//	{"method":"tail","protocol":1}
+//	{"method":"parse","line":"<one line of a session file>"}
+//
+// discover and tail answer {"sessions":[{"path":"/abs/file","session_id":"id"}]}:
+// discover the session files to read now, tail those that appeared since
+// (returning known ones again is harmless). gap-map tails every session
+// file itself and sends each new line to parse, which answers
+// {"event":null} for lines without an AI edit, or
+//
+//	{"event":{"tool":"Write","file_path":"/abs/file","content":"...","timestamp":"2026-01-02T15:04:05Z"}}
+//
+// with "tool" one of Write (content is the whole new file), Edit
+// (old_string and new_string), Read or another tool name, and optionally
+// the "model" that made the call, the tool's "client_version" and the
+// user "prompt" it answers. Any request may be answered {"error":"..."}.
+type GenericProvider struct {
+	name         string
+	command      []string
+	pollInterval time.Duration
+
+	// parser extracts events from the Claude Code style lines plugin events
+	// are normalized to, so the rest of gap-map reads them unchanged.
+	parser *ClaudeCodeParser
+
+	mu     sync.Mutex // serializes requests
+	proc   *exec.Cmd
+	stdin  io.WriteCloser
+	stdout *bufio.Reader
+	seen   map[string]bool // session paths already reported
+}
+
+// NewGenericProvider returns a provider named name that runs command (the
+// program and its arguments) and asks it for new sessions every
+// pollInterval (default 5s). Write events are diffed with diffAlgorithm, as
+// by NewClaudeCodeParser.
+func NewGenericProvider(name string, command []string, pollInterval time.Duration, diffAlgorithm string) *GenericProvider {
+	if pollInterval <= 0 {
+		pollInterval = 5 * time.Second
+	}
+	return &GenericProvider{
+		name:         name,
+		command:      command,
+		pollInterval: pollInterval,
+		parser:       NewClaudeCodeParser("", 0, diffAlgorithm),
+		seen:         make(map[string]bool),
+	}
+}
+
+// pluginRequest is one request to a plugin.
+type pluginRequest struct {
+	Method   string `json:"method"`
+	Protocol int    `json:"protocol,omitempty"`
+	Line     string `json:"line,omitempty"`
+}
+
+// pluginResponse is a plugin's answer to any request.
+type pluginResponse struct {
+	Error    string          `json:"error,omitempty"`
+	Sessions []pluginSession `json:"sessions,omitempty"`
+	Event    *PluginEvent    `json:"event,omitempty"`
+}
+
+type pluginSession struct {
+	Path      string `json:"path"`
+	SessionID string `json:"session_id,omitempty"` // derived from the path when empty
+}
+
+// PluginEvent is an AI tool call as a plugin reports it.
+type PluginEvent struct {
+	Tool         string `json:"tool"` // "Write", "Edit", "Read", ...
+	FilePath     string `json:"file_path"`
+	Content      string `json:"content,omitempty"`       // Write: the whole new file
+	OldString    string `json:"old_string,omitempty"`    // Edit: the text replaced
+	NewString    string `json:"new_string,omitempty"`    // Edit: its replacement
+	Timestamp    string `json:"timestamp,omitempty"`     // RFC 3339; parse time when empty
+	LinesChanged int    `json:"lines_changed,omitempty"` // computed from the content when 0
+
+	Model         string `json:"model,omitempty"`          // model that made the call
+	ClientVersion string `json:"client_version,omitempty"` // version of the AI tool
+	Prompt        string `json:"prompt,omitempty"`         // what the user asked for; redacted before storing
+}
+
+// Name returns the configured provider name.
+func (p *GenericProvider) Name() string { return p.name }
+
+// Discover asks the plugin for the session files to read now.
+func (p *GenericProvider) Discover(ctx context.Context) ([]SessionFile, error) {
+	return p.sessions(pluginRequest{Method: "discover", Protocol: PluginProtocol})
+}
+
+// WatchForNew asks the plugin for new session files every poll interval
+// and sends those not reported before; failed requests are logged and
+// retried on the next tick. It blocks until ctx is cancelled.
+func (p *GenericProvider) WatchForNew(ctx context.Context, found chan<- SessionFile) error {
+	ticker := time.NewTicker(p.pollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+		files, err := p.sessions(pluginRequest{Method: "tail", Protocol: PluginProtocol})
+		if err != nil {
+			slog.Warn("sessionparser: tail failed", "provider", p.name, "err", err)
+			continue
+		}
+		for _, sf := range files {
+			select {
+			case found <- sf:
+			case <-ctx.Done():
+				return nil
+			}
+		}
+	}
+}
+
+// ParseLine sends line to the plugin and returns the event it reports, or
+// nil if the line holds none.
+func (p *GenericProvider) ParseLine(line []byte) (*SessionEvent, error) {
+	line = trimLine(line)
+	if len(line) == 0 {
+		return nil, nil
+	}
+	resp, err := p.call(pluginRequest{Method: "parse", Line: string(line)})
+	if err != nil {
+		return nil, err
+	}
+	if resp.Event == nil || resp.Event.Tool == "" {
+		return nil, nil
+	}
+
+	raw, err := resp.Event.normalize()
+	if err != nil {
+		return nil, fmt.Errorf("session provider %s: %w", p.name, err)
+	}
+	event, err := p.parser.ParseLine(raw)
+	if err != nil || event == nil {
+		return event, err
+	}
+	if resp.Event.LinesChanged > 0 {
+		event.LinesChanged = resp.Event.LinesChanged
+	}
+	if resp.Event.Prompt != "" {
+		event.Prompt = RedactPrompt(resp.Event.Prompt)
+	}
+	return event, nil
+}
+
+// Close stops the plugin.
+func (p *GenericProvider) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.stop()
+	return nil
+}
+
+// sessions sends a discover or tail request and returns the sessions not
+// reported before.
+func (p *GenericProvider) sessions(req pluginRequest) ([]SessionFile, error) {
+	resp, err := p.call(req)
+	if err != nil {
+		return nil, err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var files []SessionFile
+	for _, s := range resp.Sessions {
+		if s.Path == "" || p.seen[s.Path] {
+			continue
+		}
+		p.seen[s.Path] = true
+		id := s.SessionID
+		if id == "" {
+			id = sessionIDFromPath(s.Path)
+		}
+		files = append(files, SessionFile{Path: s.Path, SessionID: id, Provider: p.name})
+	}
+	return files, nil
+}
+
+// call sends one request and reads its response, starting the plugin if
+// it is not running.
+func (p *GenericProvider) call(req pluginRequest) (*pluginResponse, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.proc == nil {
+		if err := p.start(); err != nil {
+			return nil, fmt.Errorf("session provider %s: start: %w", p.name, err)
+		}
+	}
+
+	data, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.stdin.Write(append(data, '\n')); err != nil {
+		p.stop()
+		return nil, fmt.Errorf("session provider %s: %s: %w", p.name, req.Method, err)
+	}
+
+	type result struct {
+		line []byte
+		err  error
+	}
+	done := make(chan result, 1)
+	stdout := p.stdout
+	go func() {
+		line, err := stdout.ReadBytes('\n')
+		done <- result{line, err}
+	}()
+
+	var res result
+	select {
+	case res = <-done:
+	case <-time.After(pluginCallTimeout):
+		p.stop()
+		return nil, fmt.Errorf("session provider %s: %s: no response within %s", p.name, req.Method, pluginCallTimeout)
+	}
+	if res.err != nil {
+		p.stop()
+		if errors.Is(res.err, io.EOF) {
+			res.err = errors.New("plugin exited")
+		}
+		return nil, fmt.Errorf("session provider %s: %s: %w", p.name, req.Method, res.err)
+	}
+
+	var resp pluginResponse
+	if err := json.Unmarshal(res.line, &resp); err != nil {
+		return nil, fmt.Errorf("session provider %s: %s: bad response: %w", p.name, req.Method, err)
+	}
+	if resp.Error != "" {
+		return nil, fmt.Errorf("session provider %s: %s: %s", p.name, req.Method, resp.Error)
+	}
+	return &resp, nil
+}
+
+// start runs the plugin. Callers hold p.mu.
+func (p *GenericProvider) start() error {
+	if len(p.command) == 0 {
+		return errors.New("no command")
+	}
+	cmd := exec.Command(p.command[0], p.command[1:]...)
+	cmd.Stderr = os.Stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	p.proc, p.stdin, p.stdout = cmd, stdin, bufio.NewReader(stdout)
+	return nil
+}
+
+// stop kills the plugin; the next request starts it again. Callers hold
+// p.mu.
+func (p *GenericProvider) stop() {
+	if p.proc == nil {
+		return
+	}
+	_ = p.stdin.Close()
+	_ = p.proc.Process.Kill()
+	_ = p.proc.Wait()
+	p.proc, p.stdin, p.stdout = nil, nil, nil
+}
+
+// normalize returns e as a Claude Code tool_use line, the form session
+// events are stored in and read back from.
+func (e *PluginEvent) normalize() ([]byte, error) {
+	var input any
+	switch e.Tool {
+	case "Write":
+		input = writeInput{FilePath: e.FilePath, Content: e.Content}
+	case "Edit":
+		input = editInput{FilePath: e.FilePath, OldString: e.OldString, NewString: e.NewString}
+	default:
+		input = readInput{FilePath: e.FilePath}
+	}
+	inputJSON, err := json.Marshal(input)
+	if err != nil {
+		return nil, err
+	}
+	message, err := json.Marshal(messageWrapper{Model: e.Model, Content: []contentBlock{{Type: "tool_use", Name: e.Tool, Input: inputJSON}}})
+	if err != nil {
+		return nil, err
+	}
+
+	env := jsonlEnvelope{Type: "assistant", Message: message, Version: e.ClientVersion}
+	if e.Timestamp != "" {
+		if _, err := time.Parse(time.RFC3339Nano, e.Timestamp); err != nil {
+			return nil, fmt.Errorf("timestamp: %w", err)
+		}
+		env.Timestamp, _ = json.Marshal(e.Timestamp)
+	}
+	return json.Marshal(env)
+}
@@ -0,0 +1,114 @@
+package sessionparser
+
+import (
+	"path/filepath"
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// TailNotifier wakes tailers when the files they tail change. One notifier
+// serves any number of tailers with a single fsnotify watcher on the
+// directories their files are in, so a rotated or recreated file keeps
+// waking its tailer and hundreds of idle tails cost no polling. A tailer
+// whose directory cannot be watched (e.g. the system's watch limit was
+// reached) falls back to polling.
+type TailNotifier struct {
+	w *fsnotify.Watcher
+
+	mu   sync.Mutex
+	subs map[string]map[chan struct{}]struct{} // wake channels by file path
+	dirs map[string]int                        // watched directories: files subscribed in each
+	done chan struct{}
+}
+
+// NewTailNotifier starts a notifier. Close it when no tailer uses it any
+// more.
+func NewTailNotifier() (*TailNotifier, error) {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	n := &TailNotifier{
+		w:    w,
+		subs: make(map[string]map[chan struct{}]struct{}),
+		dirs: make(map[string]int),
+		done: make(chan struct{}),
+	}
+	go n.run()
+	return n, nil
+}
+
+// subscribe returns a channel that receives a value after path is written,
+// created, renamed or removed, and a function that ends the subscription.
+// watched reports whether notifications will come; if not, the caller must
+// poll.
+func (n *TailNotifier) subscribe(path string) (wake <-chan struct{}, watched bool, cancel func()) {
+	path = filepath.Clean(path)
+	dir := filepath.Dir(path)
+	ch := make(chan struct{}, 1)
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.dirs[dir] == 0 {
+		if err := n.w.Add(dir); err != nil {
+			return ch, false, func() {}
+		}
+	}
+	n.dirs[dir]++
+	if n.subs[path] == nil {
+		n.subs[path] = make(map[chan struct{}]struct{})
+	}
+	n.subs[path][ch] = struct{}{}
+
+	return ch, true, func() {
+		n.mu.Lock()
+		defer n.mu.Unlock()
+		if _, ok := n.subs[path][ch]; !ok {
+			return
+		}
+		delete(n.subs[path], ch)
+		if len(n.subs[path]) == 0 {
+			delete(n.subs, path)
+		}
+		if n.dirs[dir]--; n.dirs[dir] == 0 {
+			delete(n.dirs, dir)
+			_ = n.w.Remove(dir)
+		}
+	}
+}
+
+func (n *TailNotifier) run() {
+	defer close(n.done)
+	for {
+		select {
+		case event, ok := <-n.w.Events:
+			if !ok {
+				return
+			}
+			if event.Op == fsnotify.Chmod {
+				continue
+			}
+			n.mu.Lock()
+			for ch := range n.subs[filepath.Clean(event.Name)] {
+				select {
+				case ch <- struct{}{}:
+				default: // a wakeup is already pending
+				}
+			}
+			n.mu.Unlock()
+		case _, ok := <-n.w.Errors:
+			if !ok {
+				return
+			}
+		}
+	}
+}
+
+// Close stops the notifier. Tailers still using it fall back to polling
+// at their idle interval.
+func (n *TailNotifier) Close() error {
+	err := n.w.Close()
+	<-n.done
+	return err
+}
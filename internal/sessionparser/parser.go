@@ -12,6 +12,8 @@ import (
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/anthropic/gap-map/internal/metrics"
 )
 
 // ClaudeCodeParser implements SessionProvider for Claude Code JSONL session files.
@@ -23,16 +25,32 @@ type ClaudeCodeParser struct {
 	// maxAge limits initial discovery to sessions modified within this duration.
 	maxAge time.Duration
 
+	// diffAlgorithm diffs Write events against the previous content.
+	diffAlgorithm string
+
 	// lastContent tracks the most recent full content written to each file path,
 	// enabling accurate line-diff computation for Write tool events.
 	mu          sync.Mutex
 	lastContent map[string]string
+
+	// msgTimes remembers recent message timestamps by uuid so a line
+	// without its own timestamp can take its parent message's time.
+	msgTimes *messageTimes
+
+	// prompts remembers which user prompt recent messages descend from, so
+	// a tool call can be linked to what the user asked for.
+	prompts *messagePrompts
+
+	// now is the time of lines that carry none; time.Now unless set.
+	now func() time.Time
 }
 
 // NewClaudeCodeParser creates a parser that discovers sessions under sessionDir.
 // If sessionDir is empty, it defaults to ~/.claude/projects/.
 // maxAge controls how far back to look during Discover (default: 24h).
-func NewClaudeCodeParser(sessionDir string, maxAge time.Duration) *ClaudeCodeParser {
+// diffAlgorithm is the metrics.Diff algorithm Write events are diffed with
+// (default: myers).
+func NewClaudeCodeParser(sessionDir string, maxAge time.Duration, diffAlgorithm string) *ClaudeCodeParser {
 	if sessionDir == "" {
 		home, err := os.UserHomeDir()
 		if err != nil {
@@ -44,15 +62,30 @@ func NewClaudeCodeParser(sessionDir string, maxAge time.Duration) *ClaudeCodePar
 		maxAge = 24 * time.Hour
 	}
 	return &ClaudeCodeParser{
-		sessionDir:  sessionDir,
-		maxAge:      maxAge,
-		lastContent: make(map[string]string),
+		sessionDir:    sessionDir,
+		maxAge:        maxAge,
+		diffAlgorithm: diffAlgorithm,
+		lastContent:   make(map[string]string),
+		msgTimes:      newMessageTimes(maxTrackedMessages),
+		prompts:       newMessagePrompts(maxTrackedMessages),
+		now:           time.Now,
 	}
 }
 
+// SetClock sets the time given to lines that carry none, so replaying a
+// session gives the same events every time.
+func (p *ClaudeCodeParser) SetClock(now func() time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.now = now
+}
+
 // Name returns "claude-code".
 func (p *ClaudeCodeParser) Name() string { return "claude-code" }
 
+// Root returns the directory the parser discovers sessions under.
+func (p *ClaudeCodeParser) Root() string { return p.sessionDir }
+
 // Discover scans sessionDir recursively for *.jsonl files modified within maxAge.
 func (p *ClaudeCodeParser) Discover(ctx context.Context) ([]SessionFile, error) {
 	cutoff := time.Now().Add(-p.maxAge)
@@ -92,6 +125,7 @@ func (p *ClaudeCodeParser) Discover(ctx context.Context) ([]SessionFile, error)
 			Path:      path,
 			SessionID: sessionID,
 			Provider:  "claude-code",
+			Root:      p.sessionDir,
 		})
 
 		return nil
@@ -126,8 +160,19 @@ func (p *ClaudeCodeParser) ParseLine(line []byte) (*SessionEvent, error) {
 		return nil, nil
 	}
 
-	// Fast path: skip lines that clearly aren't tool_use.
+	// Fast path: skip lines that clearly aren't tool_use, remembering
+	// their time and prompt in case a later tool_use line descends from
+	// them.
 	if !containsToolUse(line) {
+		if strings.Contains(string(line), `"uuid"`) {
+			var envelope jsonlEnvelope
+			if err := json.Unmarshal(line, &envelope); err == nil {
+				p.mu.Lock()
+				p.rememberTime(envelope.messageHeader)
+				p.rememberPrompt(&envelope)
+				p.mu.Unlock()
+			}
+		}
 		return nil, nil
 	}
 
@@ -141,23 +186,129 @@ func (p *ClaudeCodeParser) ParseLine(line []byte) (*SessionEvent, error) {
 		// skip malformed line silently
 		return nil, nil
 	}
+	p.rememberTime(envelope.messageHeader)
+	p.rememberPrompt(&envelope)
 
 	return p.extractToolUse(&envelope, string(line))
 }
 
+// eventTime returns when the line's event happened: the envelope timestamp,
+// else a timestamp on the message itself, else the parent message's time.
+// Parse time is used only when the line carries no usable time at all.
+func (p *ClaudeCodeParser) eventTime(env *jsonlEnvelope) time.Time {
+	if ts, ok := parseTimestamp(env.Timestamp); ok {
+		return ts
+	}
+	if len(env.Message) > 0 {
+		var msg struct {
+			Timestamp json.RawMessage `json:"timestamp"`
+		}
+		if err := json.Unmarshal(env.Message, &msg); err == nil {
+			if ts, ok := parseTimestamp(msg.Timestamp); ok {
+				return ts
+			}
+		}
+	}
+	if ts, ok := p.msgTimes.get(env.ParentUUID); ok {
+		return ts
+	}
+	return p.now()
+}
+
+// rememberTime records a message's timestamp under its uuid.
+func (p *ClaudeCodeParser) rememberTime(h messageHeader) {
+	if h.UUID == "" {
+		return
+	}
+	if ts, ok := parseTimestamp(h.Timestamp); ok {
+		p.msgTimes.put(h.UUID, ts)
+	}
+}
+
+// parseTimestamp accepts an RFC 3339 string or a Unix time in seconds or
+// milliseconds.
+func parseTimestamp(raw json.RawMessage) (time.Time, bool) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return time.Time{}, false
+	}
+
+	var str string
+	if err := json.Unmarshal(raw, &str); err == nil {
+		ts, err := time.Parse(time.RFC3339Nano, str)
+		if err != nil {
+			return time.Time{}, false
+		}
+		return ts, true
+	}
+
+	var num float64
+	if err := json.Unmarshal(raw, &num); err == nil && num > 0 {
+		if num > 1e12 {
+			return time.UnixMilli(int64(num)), true
+		}
+		return time.Unix(int64(num), 0), true
+	}
+	return time.Time{}, false
+}
+
+// maxTrackedMessages bounds how many message timestamps are remembered
+// for parent lookups across all tailed sessions.
+const maxTrackedMessages = 4096
+
+// messageTimes is a bounded uuid -> timestamp map that evicts the oldest
+// entry first. Callers hold ClaudeCodeParser.mu.
+type messageTimes struct {
+	limit int
+	times map[string]time.Time
+	order []string
+}
+
+func newMessageTimes(limit int) *messageTimes {
+	return &messageTimes{limit: limit, times: make(map[string]time.Time)}
+}
+
+func (m *messageTimes) put(uuid string, ts time.Time) {
+	if _, ok := m.times[uuid]; !ok {
+		if len(m.order) >= m.limit {
+			delete(m.times, m.order[0])
+			m.order = m.order[1:]
+		}
+		m.order = append(m.order, uuid)
+	}
+	m.times[uuid] = ts
+}
+
+func (m *messageTimes) get(uuid string) (time.Time, bool) {
+	if uuid == "" {
+		return time.Time{}, false
+	}
+	ts, ok := m.times[uuid]
+	return ts, ok
+}
+
 // --- internal types for JSON parsing ---
 
 // jsonlEnvelope is the top-level structure of a Claude Code JSONL line.
 // We use a flexible structure to handle format variations.
 type jsonlEnvelope struct {
+	messageHeader
 	Type    string          `json:"type"`
 	Message json.RawMessage `json:"message,omitempty"`
+	Version string          `json:"version,omitempty"` // Claude Code version
 
 	// Some formats put content directly at top level.
 	Content json.RawMessage `json:"content,omitempty"`
 }
 
+// messageHeader holds the envelope fields that place a line in time.
+type messageHeader struct {
+	UUID       string          `json:"uuid,omitempty"`
+	ParentUUID string          `json:"parentUuid,omitempty"`
+	Timestamp  json.RawMessage `json:"timestamp,omitempty"`
+}
+
 type messageWrapper struct {
+	Model   string         `json:"model,omitempty"`
 	Content []contentBlock `json:"content"`
 }
 
@@ -191,11 +342,13 @@ type bashInput struct {
 func (p *ClaudeCodeParser) extractToolUse(env *jsonlEnvelope, rawJSON string) (*SessionEvent, error) {
 	// Try message.content first (standard assistant message format).
 	var blocks []contentBlock
+	var model string
 
 	if len(env.Message) > 0 {
 		var msg messageWrapper
 		if err := json.Unmarshal(env.Message, &msg); err == nil {
 			blocks = msg.Content
+			model = msg.Model
 		}
 	}
 
@@ -214,10 +367,15 @@ func (p *ClaudeCodeParser) extractToolUse(env *jsonlEnvelope, rawJSON string) (*
 		}
 
 		event := &SessionEvent{
-			EventType: "tool_use",
-			ToolName:  block.Name,
-			Timestamp: time.Now(),
-			RawJSON:   rawJSON,
+			EventType:     "tool_use",
+			ToolName:      block.Name,
+			Timestamp:     p.eventTime(env),
+			RawJSON:       rawJSON,
+			Model:         model,
+			ClientVersion: env.Version,
+		}
+		if ref, ok := p.prompts.get(env.UUID); ok {
+			event.PromptID, event.Prompt = ref.id, ref.text
 		}
 
 		switch block.Name {
@@ -243,8 +401,8 @@ func (p *ClaudeCodeParser) extractToolUse(env *jsonlEnvelope, rawJSON string) (*
 				}
 			}
 			if hasCached {
-				event.LinesChanged = diffLineCount(prev, inp.Content)
-				event.DiffContent = writeDiffContent(prev, inp.Content)
+				event.LinesChanged = diffLineCount(prev, inp.Content, p.diffAlgorithm)
+				event.DiffContent = writeDiffContent(prev, inp.Content, p.diffAlgorithm)
 			} else {
 				event.LinesChanged = countLines(inp.Content)
 				event.DiffContent = inp.Content
@@ -262,6 +420,9 @@ func (p *ClaudeCodeParser) extractToolUse(env *jsonlEnvelope, rawJSON string) (*
 			event.ContentHash = hashContent(newOnly)
 			event.LinesChanged = countLines(newOnly)
 			event.DiffContent = newOnly
+			if inp.OldString != "" {
+				event.DeletedContent = editOnlyNewLines(inp.NewString, inp.OldString)
+			}
 
 		case "Read":
 			var inp readInput
@@ -404,6 +565,49 @@ func ExtractDiffContent(rawJSON string) string {
 	return ""
 }
 
+// ExtractContent returns both ExtractDiffContent and ExtractDeletedContent
+// of a raw JSONL line, for session events stored without their content.
+func ExtractContent(rawJSON string) (added, deleted string) {
+	return ExtractDiffContent(rawJSON), ExtractDeletedContent(rawJSON)
+}
+
+// ExtractDeletedContent parses a raw JSONL line and returns the lines an
+// Edit removed: lines of old_string that do not survive into new_string.
+// Write events carry no prior content and return "".
+func ExtractDeletedContent(rawJSON string) string {
+	line := trimLine([]byte(rawJSON))
+	if len(line) == 0 || !containsToolUse(line) {
+		return ""
+	}
+
+	var envelope jsonlEnvelope
+	if err := json.Unmarshal(line, &envelope); err != nil {
+		return ""
+	}
+
+	var blocks []contentBlock
+	if len(envelope.Message) > 0 {
+		var msg messageWrapper
+		if err := json.Unmarshal(envelope.Message, &msg); err == nil {
+			blocks = msg.Content
+		}
+	}
+	if len(blocks) == 0 && len(envelope.Content) > 0 {
+		_ = json.Unmarshal(envelope.Content, &blocks)
+	}
+
+	for _, block := range blocks {
+		if block.Type != "tool_use" || block.Name != "Edit" {
+			continue
+		}
+		var inp editInput
+		if err := json.Unmarshal(block.Input, &inp); err == nil && inp.OldString != "" {
+			return editOnlyNewLines(inp.NewString, inp.OldString)
+		}
+	}
+	return ""
+}
+
 // trimLine removes leading/trailing whitespace and BOM.
 func trimLine(line []byte) []byte {
 	// Strip UTF-8 BOM if present.
@@ -423,35 +627,16 @@ func trimLine(line []byte) []byte {
 }
 
 // diffLineCount computes the number of lines that actually differ between
-// old and new content. Lines present in both (even at different positions)
-// are not counted. This gives an accurate change count for Write tool events
-// that rewrite the entire file.
-func diffLineCount(old, new string) int {
+// old and new content, diffed with algorithm. A line inserted
+// or removed counts once rather than shifting every line below it. This
+// gives an accurate change count for Write tool events that rewrite the
+// entire file.
+func diffLineCount(old, new, algorithm string) int {
 	if old == new {
 		return 0
 	}
 
-	oldLines := strings.Split(old, "\n")
-	newLines := strings.Split(new, "\n")
-
-	// Count lines that differ by position, bounded by the shorter slice.
-	changed := 0
-	minLen := len(oldLines)
-	if len(newLines) < minLen {
-		minLen = len(newLines)
-	}
-	for i := 0; i < minLen; i++ {
-		if oldLines[i] != newLines[i] {
-			changed++
-		}
-	}
-
-	// Lines added or removed beyond the shared range.
-	if len(newLines) > len(oldLines) {
-		changed += len(newLines) - len(oldLines)
-	} else {
-		changed += len(oldLines) - len(newLines)
-	}
+	changed := metrics.ChangedLines(metrics.Diff(strings.Split(old, "\n"), strings.Split(new, "\n"), algorithm))
 
 	// If strings differ but all lines match (e.g. trailing newline difference),
 	// report at least 1.
@@ -461,31 +646,16 @@ func diffLineCount(old, new string) int {
 	return changed
 }
 
-// writeDiffContent returns only the lines from new that differ from old,
-// for use as DiffContent on Write events with a known previous version.
-func writeDiffContent(old, new string) string {
+// writeDiffContent returns only the lines from new that the diff against
+// old adds with algorithm, for use as DiffContent on Write events with a
+// known previous version.
+func writeDiffContent(old, new, algorithm string) string {
 	if old == new {
 		return ""
 	}
 
-	oldLines := strings.Split(old, "\n")
 	newLines := strings.Split(new, "\n")
-
-	var result []string
-	minLen := len(oldLines)
-	if len(newLines) < minLen {
-		minLen = len(newLines)
-	}
-	for i := 0; i < minLen; i++ {
-		if oldLines[i] != newLines[i] {
-			result = append(result, newLines[i])
-		}
-	}
-	// Appended lines.
-	for i := minLen; i < len(newLines); i++ {
-		result = append(result, newLines[i])
-	}
-
+	result := metrics.AddedLines(newLines, metrics.Diff(strings.Split(old, "\n"), newLines, algorithm))
 	if len(result) == 0 {
 		return ""
 	}
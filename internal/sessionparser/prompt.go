@@ -0,0 +1,134 @@
+package sessionparser
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+)
+
+// MaxPromptLen bounds the prompt text kept with a session event, in runes.
+const MaxPromptLen = 200
+
+// promptRef is the user prompt a message descends from.
+type promptRef struct {
+	id   string // uuid of the user message
+	text string // redacted and truncated
+}
+
+// messagePrompts is a bounded uuid -> prompt map that evicts the oldest
+// entry first, so a tool call can be traced back through the assistant and
+// tool result messages before it to the prompt that asked for it. Callers
+// hold ClaudeCodeParser.mu.
+type messagePrompts struct {
+	limit   int
+	prompts map[string]promptRef
+	order   []string
+}
+
+func newMessagePrompts(limit int) *messagePrompts {
+	return &messagePrompts{limit: limit, prompts: make(map[string]promptRef)}
+}
+
+func (m *messagePrompts) put(uuid string, ref promptRef) {
+	if _, ok := m.prompts[uuid]; !ok {
+		if len(m.order) >= m.limit {
+			delete(m.prompts, m.order[0])
+			m.order = m.order[1:]
+		}
+		m.order = append(m.order, uuid)
+	}
+	m.prompts[uuid] = ref
+}
+
+func (m *messagePrompts) get(uuid string) (promptRef, bool) {
+	if uuid == "" {
+		return promptRef{}, false
+	}
+	ref, ok := m.prompts[uuid]
+	return ref, ok
+}
+
+// rememberPrompt records which prompt the message on env descends from: its
+// own text if it is a prompt the user typed, else its parent's prompt.
+func (p *ClaudeCodeParser) rememberPrompt(env *jsonlEnvelope) {
+	if env.UUID == "" {
+		return
+	}
+	if text, ok := userPromptText(env); ok {
+		p.prompts.put(env.UUID, promptRef{id: env.UUID, text: RedactPrompt(text)})
+		return
+	}
+	if ref, ok := p.prompts.get(env.ParentUUID); ok {
+		p.prompts.put(env.UUID, ref)
+	}
+}
+
+// userPromptText returns the text of a user message the human typed.
+// Messages carrying tool results are written by the client, not the user,
+// and are not prompts.
+func userPromptText(env *jsonlEnvelope) (string, bool) {
+	if env.Type != "user" || len(env.Message) == 0 {
+		return "", false
+	}
+	var msg struct {
+		Content json.RawMessage `json:"content"`
+	}
+	if err := json.Unmarshal(env.Message, &msg); err != nil || len(msg.Content) == 0 {
+		return "", false
+	}
+
+	var text string
+	if err := json.Unmarshal(msg.Content, &text); err == nil {
+		return text, strings.TrimSpace(text) != ""
+	}
+
+	var blocks []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal(msg.Content, &blocks); err != nil {
+		return "", false
+	}
+	var parts []string
+	for _, b := range blocks {
+		switch b.Type {
+		case "tool_result":
+			return "", false
+		case "text":
+			parts = append(parts, b.Text)
+		}
+	}
+	text = strings.Join(parts, "\n")
+	return text, strings.TrimSpace(text) != ""
+}
+
+// secretPatterns match credentials that must not be stored with a prompt.
+var secretPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`\b(?:sk|pk|rk)-[A-Za-z0-9_-]{16,}`),                           // API keys (Anthropic, OpenAI, Stripe)
+	regexp.MustCompile(`\b(?:ghp|gho|ghu|ghs|ghr|github_pat)_[A-Za-z0-9_]{20,}`),      // GitHub tokens
+	regexp.MustCompile(`\bxox[abpr]-[A-Za-z0-9-]{10,}`),                               // Slack tokens
+	regexp.MustCompile(`\b(?:AKIA|ASIA)[A-Z0-9]{16}\b`),                               // AWS access key ids
+	regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]+`), // JWTs
+	regexp.MustCompile(`(?i)\bbearer\s+[A-Za-z0-9._~+/=-]{16,}`),
+	regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?(?:-----END [A-Z ]*PRIVATE KEY-----|$)`),
+}
+
+// assignmentPattern matches values given to secret-sounding names, such as
+// password=hunter2 or "api_key": "abc".
+var assignmentPattern = regexp.MustCompile(`(?i)\b((?:password|passwd|secret|token|api[_-]?key|access[_-]?key|private[_-]?key)["']?\s*[:=]\s*)["']?[^\s"',;]+["']?`)
+
+// RedactPrompt makes prompt text fit to store: credentials are replaced by
+// [REDACTED], whitespace is collapsed, and the text is cut to MaxPromptLen
+// runes.
+func RedactPrompt(text string) string {
+	for _, re := range secretPatterns {
+		text = re.ReplaceAllString(text, "[REDACTED]")
+	}
+	text = assignmentPattern.ReplaceAllString(text, "${1}[REDACTED]")
+	text = strings.Join(strings.Fields(text), " ")
+
+	if r := []rune(text); len(r) > MaxPromptLen {
+		text = strings.TrimSpace(string(r[:MaxPromptLen-1])) + "…"
+	}
+	return text
+}
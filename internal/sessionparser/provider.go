@@ -28,22 +28,58 @@ type SessionProvider interface {
 	ParseLine(line []byte) (*SessionEvent, error)
 }
 
+// UsageParser is implemented by providers whose session files record the
+// tokens each API message billed. The daemon passes every line to
+// ParseUsage as well as to ParseLine.
+type UsageParser interface {
+	// ParseUsage returns the token usage a line records, or nil if it
+	// records none.
+	ParseUsage(line []byte) (*TokenUsage, error)
+}
+
+// TokenUsage is the tokens billed for one API message of a session.
+type TokenUsage struct {
+	MessageID           string // API message id; a message's lines repeat its usage
+	Model               string
+	InputTokens         int64
+	OutputTokens        int64
+	CacheCreationTokens int64 // input written to the prompt cache
+	CacheReadTokens     int64 // input read from the prompt cache
+	Timestamp           time.Time
+}
+
 // SessionFile represents a discovered AI tool session file.
 type SessionFile struct {
 	Path      string // Absolute path to the session file.
 	SessionID string // Unique session identifier (derived from filename/path).
 	Provider  string // Provider name (e.g. "claude-code").
+	Root      string // Session directory it was found under, for providers reading several; else empty.
+}
+
+// RootedProvider is implemented by providers that read the sessions under
+// one directory. The daemon runs one per configured session directory and
+// tells their files apart by SessionFile.Root.
+type RootedProvider interface {
+	// Root returns the directory the provider reads sessions from.
+	Root() string
 }
 
 // SessionEvent represents a parsed tool_use event from a session file.
 type SessionEvent struct {
-	SessionID    string    // Session that produced this event.
-	EventType    string    // "tool_use"
-	ToolName     string    // "Write", "Read", "Bash", etc.
-	FilePath     string    // File path affected (for Write/Read events).
-	ContentHash  string    // SHA-256 hash of written content (for Write events).
-	Timestamp    time.Time // When the event occurred (or was parsed).
-	RawJSON      string    // Original JSON line for debugging/reprocessing.
-	LinesChanged int       // Number of lines written/edited (0 for non-Write/Edit tools).
-	DiffContent  string    // Written/edited content for work type classification.
+	SessionID      string    // Session that produced this event.
+	EventType      string    // "tool_use"
+	ToolName       string    // "Write", "Read", "Bash", etc.
+	FilePath       string    // File path affected (for Write/Read events).
+	ContentHash    string    // SHA-256 hash of written content (for Write events).
+	Timestamp      time.Time // When the event occurred (or was parsed).
+	RawJSON        string    // Original JSON line for debugging/reprocessing.
+	LinesChanged   int       // Number of lines written/edited (0 for non-Write/Edit tools).
+	DiffContent    string    // Written/edited content for work type classification.
+	DeletedContent string    // Lines an Edit removed (empty for Write).
+
+	Model         string // Model that made the call (e.g. "claude-opus-4-1-20250805"), if recorded.
+	ClientVersion string // Version of the AI client that wrote the session, if recorded.
+
+	PromptID string // uuid of the user prompt that led to the call, if known.
+	Prompt   string // That prompt's text, redacted and truncated (see RedactPrompt).
 }
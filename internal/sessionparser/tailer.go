@@ -2,53 +2,208 @@ package sessionparser
 
 import (
 	"bufio"
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
+	"strconv"
+	"sync/atomic"
 	"time"
 )
 
+// fingerprintBytes is how much of the start of a file identifies it. Claude
+// Code session files open with a line carrying the session UUID and a
+// timestamp, so the head differs between any two sessions.
+const fingerprintBytes = 4096
+
+// Checkpoint is a persisted read position. The fingerprint ties the offset
+// to the file it was taken from, so a rewritten or rotated file at the same
+// path is read from the beginning instead of from a stale offset.
+type Checkpoint struct {
+	Offset      int64  `json:"offset"`
+	Fingerprint string `json:"fingerprint,omitempty"`
+}
+
+// ParseCheckpoint decodes a checkpoint written by Checkpoint.String. A bare
+// integer (the format used before fingerprints) is read as an offset with no
+// fingerprint, which is trusted as long as the file is not shorter.
+func ParseCheckpoint(s string) Checkpoint {
+	var cp Checkpoint
+	if s == "" {
+		return cp
+	}
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		cp.Offset = n
+		return cp
+	}
+	if err := json.Unmarshal([]byte(s), &cp); err != nil {
+		return Checkpoint{}
+	}
+	return cp
+}
+
+// String encodes the checkpoint for storage.
+func (c Checkpoint) String() string {
+	data, _ := json.Marshal(c)
+	return string(data)
+}
+
 // Tailer watches a single file for new lines appended at the end.
-// It uses polling (check file size periodically) rather than fsnotify
-// for individual files, which is more reliable for files being appended
-// to by other processes.
+// It polls the file, every interval while lines keep arriving and backing
+// off while it is quiet. With a TailNotifier (see SetNotifier) it also
+// wakes as soon as the file is written, and polling only catches what
+// notifications miss.
+//
+// The tailer detects three ways a session file can change under it:
+// truncation (the file is shorter than the offset), rewrite (the head of
+// the file no longer matches the fingerprint), and rotation (the path now
+// names a different inode). In each case it starts over from the beginning
+// of the current file; on rotation, complete lines left in the old file
+// are read first.
 type Tailer struct {
-	path     string
-	offset   int64
-	interval time.Duration
+	path        string
+	offset      int64
+	fingerprint string // from the checkpoint; verified on open
+	head        []byte // first min(offset, fingerprintBytes) bytes read
+	interval    time.Duration
+	notifier    *TailNotifier
+	published   atomic.Int64 // copy of offset for Offset while Tail runs
 }
 
+// A quiet file is polled less and less often, the wait doubling from the
+// tailer's interval up to maxQuietPoll, or up to maxNotifiedPoll when a
+// TailNotifier wakes the tailer on writes.
+const (
+	maxQuietPoll    = 2 * time.Second
+	maxNotifiedPoll = 30 * time.Second
+)
+
 // NewTailer creates a tailer that starts reading from the given offset.
 // If offset is 0 and the file exists, it starts from the beginning.
 // interval controls poll frequency (default: 500ms).
 func NewTailer(path string, offset int64, interval time.Duration) *Tailer {
+	return NewTailerFromCheckpoint(path, Checkpoint{Offset: offset}, interval)
+}
+
+// NewTailerFromCheckpoint creates a tailer that resumes from a checkpoint.
+// The offset is discarded if the file's fingerprint no longer matches.
+func NewTailerFromCheckpoint(path string, cp Checkpoint, interval time.Duration) *Tailer {
 	if interval == 0 {
 		interval = 500 * time.Millisecond
 	}
-	return &Tailer{
-		path:     path,
-		offset:   offset,
-		interval: interval,
+	t := &Tailer{
+		path:        path,
+		offset:      cp.Offset,
+		fingerprint: cp.Fingerprint,
+		interval:    interval,
 	}
+	t.published.Store(cp.Offset)
+	return t
+}
+
+// SetNotifier has the tailer wake when n reports its file changed, rather
+// than only when it polls. Call it before Tail.
+func (t *Tailer) SetNotifier(n *TailNotifier) {
+	t.notifier = n
+}
+
+// pollWaits returns the channel that wakes the tailer on writes, nil if
+// there is none, the longest wait between polls and a function releasing
+// the subscription.
+func (t *Tailer) pollWaits() (wake <-chan struct{}, maxWait time.Duration, cancel func()) {
+	maxWait = max(maxQuietPoll, t.interval)
+	if t.notifier == nil {
+		return nil, maxWait, func() {}
+	}
+	wake, watched, cancel := t.notifier.subscribe(t.path)
+	if watched {
+		maxWait = max(maxNotifiedPoll, t.interval)
+	}
+	return wake, maxWait, cancel
 }
 
 // Tail opens the file, seeks to the stored offset, and sends new lines
 // on the lines channel as they are appended. It blocks until ctx is
 // cancelled, at which point it returns the final offset for persistence.
 //
-// If the file does not exist yet, Tail waits for it to appear.
-// If the file is truncated (offset > file size), it resets to the beginning.
+// If the file does not exist yet, Tail waits for it to appear. Only
+// complete (newline-terminated) lines are sent; a trailing partial line is
+// held until the rest of it is written.
 func (t *Tailer) Tail(ctx context.Context, lines chan<- []byte) (finalOffset int64, err error) {
+	return t.tail(ctx, func(data []byte, _ func() Checkpoint) bool {
+		select {
+		case lines <- data:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	})
+}
+
+// Line is a complete line sent by TailLines, with the checkpoint that
+// resumes reading right after it.
+type Line struct {
+	Data       []byte
+	Checkpoint Checkpoint
+}
+
+// TailLines is Tail, but sends each line with its checkpoint, so a
+// consumer can persist the position of the lines it has processed rather
+// than of those merely read, which may still be waiting in the channel.
+func (t *Tailer) TailLines(ctx context.Context, lines chan<- Line) (finalOffset int64, err error) {
+	return t.tail(ctx, func(data []byte, checkpoint func() Checkpoint) bool {
+		select {
+		case lines <- Line{Data: data, Checkpoint: checkpoint()}:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	})
+}
+
+// sendFunc delivers a line read by tail. checkpoint returns the position
+// after the line. It reports false when ctx was cancelled first.
+type sendFunc func(data []byte, checkpoint func() Checkpoint) bool
+
+// tail implements Tail and TailLines, delivering lines through send.
+func (t *Tailer) tail(ctx context.Context, send sendFunc) (finalOffset int64, err error) {
+	wake, maxWait, cancel := t.pollWaits()
+	defer cancel()
+	timer := time.NewTimer(maxWait)
+	defer timer.Stop()
+	wait := t.interval
+
+	// sleep waits for a notification, the next poll or ctx. active
+	// resets the wait to the interval; otherwise it doubles up to maxWait.
+	sleep := func(active bool) bool {
+		if active {
+			wait = t.interval
+		} else {
+			wait = min(2*wait, maxWait)
+		}
+		timer.Reset(wait)
+		select {
+		case <-ctx.Done():
+			return false
+		case <-wake:
+			timer.Stop()
+			wait = t.interval
+		case <-timer.C:
+		}
+		return true
+	}
+
 	// Wait for the file to exist.
 	for {
 		if _, err := os.Stat(t.path); err == nil {
 			break
 		}
-		select {
-		case <-ctx.Done():
+		if !sleep(false) {
 			return t.offset, nil
-		case <-time.After(t.interval):
 		}
 	}
 
@@ -56,16 +211,18 @@ func (t *Tailer) Tail(ctx context.Context, lines chan<- []byte) (finalOffset int
 	if err != nil {
 		return t.offset, fmt.Errorf("open %s: %w", t.path, err)
 	}
-	defer f.Close()
+	defer func() { f.Close() }()
 
-	// Check for truncation.
 	info, err := f.Stat()
 	if err != nil {
 		return t.offset, fmt.Errorf("stat %s: %w", t.path, err)
 	}
-	if info.Size() < t.offset {
-		// file truncated, reset offset
+	if !t.validOffset(f, info.Size()) {
 		t.offset = 0
+		t.published.Store(0)
+	}
+	if t.head, err = readHead(f, t.offset); err != nil {
+		return t.offset, fmt.Errorf("read %s: %w", t.path, err)
 	}
 
 	// Seek to the stored offset.
@@ -74,74 +231,166 @@ func (t *Tailer) Tail(ctx context.Context, lines chan<- []byte) (finalOffset int
 	}
 
 	reader := bufio.NewReader(f)
-	ticker := time.NewTicker(t.interval)
-	defer ticker.Stop()
+	var partial []byte
+	lastSize := info.Size()
+	active := true
 
 	for {
-		// Try to read all available lines.
-		for {
-			lineBytes, err := reader.ReadBytes('\n')
+		var done bool
+		read := t.offset + int64(len(partial))
+		if partial, done, err = t.readLines(reader, partial, send); err != nil || done {
+			return t.offset, err
+		}
+		active = active || t.offset+int64(len(partial)) != read
+
+		// Wait for more data.
+		if !sleep(active) {
+			return t.offset, nil
+		}
+		active = false
+
+		// Re-stat to detect rotation, truncation, or rewrite.
+		cur, err := os.Stat(t.path)
+		if err != nil {
+			// File removed -- keep the handle until something reappears.
+			continue
+		}
+
+		reset := false
+		switch {
+		case !os.SameFile(info, cur):
+			nf, err := os.Open(t.path)
 			if err != nil {
-				if err == io.EOF {
-					// Partial line (no newline yet) -- put bytes back for next read.
-					// We only process complete lines.
-					break
-				}
-				return t.offset, fmt.Errorf("read %s: %w", t.path, err)
+				continue
 			}
-
-			// Remove trailing newline.
-			if len(lineBytes) > 0 && lineBytes[len(lineBytes)-1] == '\n' {
-				lineBytes = lineBytes[:len(lineBytes)-1]
+			// Rotated: finish the old file, then switch to the new one.
+			if _, done, err := t.readLines(reader, partial, send); err != nil || done {
+				nf.Close()
+				return t.offset, err
 			}
-			if len(lineBytes) > 0 && lineBytes[len(lineBytes)-1] == '\r' {
-				lineBytes = lineBytes[:len(lineBytes)-1]
+			f.Close()
+			f = nf
+			if info, err = f.Stat(); err != nil {
+				return t.offset, fmt.Errorf("stat %s: %w", t.path, err)
 			}
+			reset = true
+		case cur.Size() < t.offset+int64(len(partial)):
+			reset = true
+		case cur.Size() != lastSize && !t.headUnchanged(f):
+			reset = true
+		}
+		lastSize = cur.Size()
 
-			t.offset += int64(len(lineBytes)) + 1 // +1 for the newline
+		if reset {
+			active = true
+			t.offset = 0
+			t.published.Store(0)
+			t.head = nil
+			partial = nil
+			if _, err := f.Seek(0, io.SeekStart); err != nil {
+				return t.offset, fmt.Errorf("seek %s to 0: %w", t.path, err)
+			}
+			reader.Reset(f)
+		}
+	}
+}
 
-			if len(lineBytes) == 0 {
-				continue
+// readLines sends every complete line available from reader. partial holds
+// bytes of a line whose newline has not been written yet; the updated
+// remainder is returned. done reports that send gave up as ctx was
+// cancelled.
+func (t *Tailer) readLines(reader *bufio.Reader, partial []byte, send sendFunc) ([]byte, bool, error) {
+	for {
+		chunk, err := reader.ReadBytes('\n')
+		if err != nil {
+			if err == io.EOF {
+				return append(partial, chunk...), false, nil
 			}
+			return partial, false, fmt.Errorf("read %s: %w", t.path, err)
+		}
+
+		raw := append(partial, chunk...)
+		partial = nil
 
+		if lineBytes := bytes.TrimRight(raw, "\r\n"); len(lineBytes) > 0 {
 			// Make a copy so the caller owns the bytes.
 			lineCopy := make([]byte, len(lineBytes))
 			copy(lineCopy, lineBytes)
 
-			select {
-			case lines <- lineCopy:
-			case <-ctx.Done():
-				return t.offset, nil
+			checkpoint := func() Checkpoint {
+				head := t.head
+				if room := fingerprintBytes - len(head); room > 0 {
+					head = append(head[:len(head):len(head)], raw[:min(room, len(raw))]...)
+				}
+				return Checkpoint{Offset: t.offset + int64(len(raw)), Fingerprint: hashHead(head)}
+			}
+			if !send(lineCopy, checkpoint) {
+				// Not delivered: leave the offset before this line so it
+				// is read again on resume.
+				return nil, true, nil
 			}
 		}
 
-		// Wait for more data.
-		select {
-		case <-ctx.Done():
-			return t.offset, nil
-		case <-ticker.C:
-			// Re-stat to detect truncation or growth.
-			info, err := os.Stat(t.path)
-			if err != nil {
-				// File removed -- wait for it to reappear.
-				continue
-			}
-			if info.Size() < t.offset {
-				t.offset = 0
-				f.Close()
-				f, err = os.Open(t.path)
-				if err != nil {
-					return t.offset, fmt.Errorf("reopen %s: %w", t.path, err)
-				}
-				reader = bufio.NewReader(f)
-			}
+		t.offset += int64(len(raw))
+		t.published.Store(t.offset)
+		if room := fingerprintBytes - len(t.head); room > 0 {
+			t.head = append(t.head, raw[:min(room, len(raw))]...)
 		}
 	}
 }
 
-// Offset returns the current read offset.
+// validOffset reports whether the stored offset still points into the same
+// content: the file is at least that long and, when a fingerprint is known,
+// its head is unchanged.
+func (t *Tailer) validOffset(f *os.File, size int64) bool {
+	if size < t.offset {
+		return false
+	}
+	if t.fingerprint == "" || t.offset == 0 {
+		return true
+	}
+	head, err := readHead(f, t.offset)
+	return err == nil && hashHead(head) == t.fingerprint
+}
+
+// headUnchanged reports whether the start of the file still matches the
+// bytes already read from it.
+func (t *Tailer) headUnchanged(f *os.File) bool {
+	head, err := readHead(f, int64(len(t.head)))
+	return err == nil && bytes.Equal(head, t.head)
+}
+
+// readHead returns the first min(limit, fingerprintBytes) bytes of f.
+// Only bytes before the read offset are used, since those have already been
+// consumed and do not change while the file is appended to.
+func readHead(f *os.File, limit int64) ([]byte, error) {
+	buf := make([]byte, min(limit, fingerprintBytes))
+	if _, err := f.ReadAt(buf, 0); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+// hashHead returns the fingerprint of a file head.
+func hashHead(head []byte) string {
+	sum := sha256.Sum256(head)
+	return hex.EncodeToString(sum[:8])
+}
+
+// Offset returns the current read offset. It is safe to call while Tail
+// runs, e.g. to report how far behind the end of the file the tailer is.
 func (t *Tailer) Offset() int64 {
-	return t.offset
+	return t.published.Load()
+}
+
+// Checkpoint returns the current read position and the fingerprint of the
+// file it belongs to.
+func (t *Tailer) Checkpoint() Checkpoint {
+	cp := Checkpoint{Offset: t.offset}
+	if t.offset > 0 && len(t.head) > 0 {
+		cp.Fingerprint = hashHead(t.head)
+	}
+	return cp
 }
 
 // Path returns the file path being tailed.
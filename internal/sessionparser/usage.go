@@ -0,0 +1,66 @@
+package sessionparser
+
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+)
+
+// usageLine is the part of a Claude Code assistant line that records what
+// the API call behind it billed:
+//
+//	{"type":"assistant","uuid":"...","message":{"id":"msg_...","model":"...","usage":{"input_tokens":4,"output_tokens":120,...}}}
+type usageLine struct {
+	messageHeader
+	Type    string `json:"type"`
+	Message struct {
+		ID    string `json:"id"`
+		Model string `json:"model"`
+		Usage *struct {
+			InputTokens              int64 `json:"input_tokens"`
+			OutputTokens             int64 `json:"output_tokens"`
+			CacheCreationInputTokens int64 `json:"cache_creation_input_tokens"`
+			CacheReadInputTokens     int64 `json:"cache_read_input_tokens"`
+		} `json:"usage"`
+	} `json:"message"`
+}
+
+// ParseUsage returns the token usage recorded on an assistant line, or nil
+// for lines without usage. Claude Code writes each content block of a
+// message on its own line, repeating the message's usage; MessageID lets
+// the store count it once.
+func (p *ClaudeCodeParser) ParseUsage(line []byte) (*TokenUsage, error) {
+	line = trimLine(line)
+	if !bytes.Contains(line, []byte(`"usage"`)) {
+		return nil, nil
+	}
+	var ul usageLine
+	if err := json.Unmarshal(line, &ul); err != nil || ul.Type != "assistant" || ul.Message.Usage == nil {
+		return nil, nil
+	}
+	u := ul.Message.Usage
+	if u.InputTokens+u.OutputTokens+u.CacheCreationInputTokens+u.CacheReadInputTokens == 0 {
+		// Synthetic messages the client writes itself bill nothing.
+		return nil, nil
+	}
+	id := ul.Message.ID
+	if id == "" {
+		id = ul.UUID
+	}
+	if id == "" {
+		return nil, nil
+	}
+	ts, ok := parseTimestamp(ul.Timestamp)
+	if !ok {
+		ts = time.Now()
+	}
+	return &TokenUsage{
+		MessageID:           id,
+		Model:               ul.Message.Model,
+		InputTokens:         u.InputTokens,
+		OutputTokens:        u.OutputTokens,
+		CacheCreationTokens: u.CacheCreationInputTokens,
+		CacheReadTokens:     u.CacheReadInputTokens,
+		Timestamp:           ts,
+	}, nil
+}
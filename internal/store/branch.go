@@ -8,6 +8,9 @@ import (
 
 // InsertFileEventWithBranch records a file system event with branch tracking.
 func (s *Store) InsertFileEventWithBranch(projectPath, filePath, eventType string, timestamp time.Time, branch string) error {
+	if err := s.resolvePaths(&projectPath, &filePath); err != nil {
+		return err
+	}
 	_, err := s.db.Exec(
 		`INSERT INTO file_events (project_path, file_path, event_type, timestamp, branch)
 		 VALUES (?, ?, ?, ?, ?)`,
@@ -18,25 +21,29 @@ func (s *Store) InsertFileEventWithBranch(projectPath, filePath, eventType strin
 
 // InsertSessionEventWithBranch records an AI tool session event with branch tracking.
 func (s *Store) InsertSessionEventWithBranch(sessionID, eventType, toolName, filePath, contentHash string, timestamp time.Time, rawJSON string, linesChanged int, branch string) error {
+	if err := s.resolvePaths(&filePath); err != nil {
+		return err
+	}
 	_, err := s.db.Exec(
-		`INSERT INTO session_events (session_id, event_type, tool_name, file_path, content_hash, timestamp, raw_json, lines_changed, branch)
-		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		`INSERT OR IGNORE INTO session_events (session_id, event_type, tool_name, file_path, content_hash, timestamp, raw_json, lines_changed, branch, dedupe_key)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
 		sessionID, eventType, toolName, filePath, contentHash,
-		timestamp.UTC().Format(time.RFC3339Nano), rawJSON, linesChanged, branch,
+		timestamp.UTC().Format(time.RFC3339Nano), s.seal(rawJSON), linesChanged, branch, sessionEventKey(rawJSON),
 	)
 	return err
 }
 
 // QueryAttributionsByBranch returns all attributions for a project on a specific branch,
-// with work type information, ordered by timestamp ascending.
+// with work type information, ordered by timestamp ascending. Attributions
+// archived with their branch are left out.
 func (s *Store) QueryAttributionsByBranch(projectPath, branch string) ([]AttributionWithWorkType, error) {
 	rows, err := s.db.Query(
 		`SELECT id, file_path, project_path, file_event_id, session_event_id,
 		        authorship_level, confidence, uncertain, first_author,
 		        correlation_window_ms, timestamp, COALESCE(work_type, ''), lines_changed, branch
 		 FROM attributions
-		 WHERE project_path = ? AND branch = ?
-		 ORDER BY timestamp ASC`,
+		 WHERE project_path = ? AND branch = ? AND kind = 'addition' AND archived = ''
+		 ORDER BY timestamp ASC, id ASC`,
 		projectPath, branch,
 	)
 	if err != nil {
@@ -71,3 +78,78 @@ func scanAttributionsWithWorkTypeAndBranch(rows *sql.Rows) ([]AttributionWithWor
 	}
 	return records, rows.Err()
 }
+
+// Reasons an attribution is archived with its branch.
+const (
+	ArchivedMerged  = "merged"  // the branch was merged into the default branch
+	ArchivedDeleted = "deleted" // the branch no longer exists
+)
+
+// BranchAttributions summarizes the attributions of one branch.
+type BranchAttributions struct {
+	Branch       string
+	Archived     string // ArchivedMerged, ArchivedDeleted or '' for a live branch
+	Attributions int
+	Latest       time.Time // timestamp of the newest attribution
+}
+
+// QueryBranchAttributions returns the branches a project's attributions
+// were recorded on, by name, and whether they are archived.
+func (s *Store) QueryBranchAttributions(projectPath string) ([]BranchAttributions, error) {
+	rows, err := s.db.Query(
+		`SELECT branch, archived, COUNT(*), MAX(timestamp)
+		 FROM attributions
+		 WHERE project_path = ? AND branch != ''
+		 GROUP BY branch, archived
+		 ORDER BY branch, archived`,
+		projectPath,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var branches []BranchAttributions
+	for rows.Next() {
+		var b BranchAttributions
+		var ts string
+		if err := rows.Scan(&b.Branch, &b.Archived, &b.Attributions, &ts); err != nil {
+			return nil, err
+		}
+		t, err := time.Parse(time.RFC3339Nano, ts)
+		if err != nil {
+			return nil, fmt.Errorf("parse attribution timestamp %q: %w", ts, err)
+		}
+		b.Latest = t
+		branches = append(branches, b)
+	}
+	return branches, rows.Err()
+}
+
+// ArchiveBranch marks the live attributions of a project's branch archived
+// for reason, ArchivedMerged or ArchivedDeleted, and returns how many it
+// marked.
+func (s *Store) ArchiveBranch(projectPath, branch, reason string) (int64, error) {
+	if reason != ArchivedMerged && reason != ArchivedDeleted {
+		return 0, fmt.Errorf("unknown archive reason %q", reason)
+	}
+	res, err := s.db.Exec(
+		`UPDATE attributions SET archived = ? WHERE project_path = ? AND branch = ? AND archived = ''`,
+		reason, projectPath, branch,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// DeleteArchivedAttributions deletes a project's archived attributions,
+// with their labels, snapshots and survival history, and returns how many
+// it deleted.
+func (s *Store) DeleteArchivedAttributions(projectPath string) (int64, error) {
+	res, err := s.db.Exec(`DELETE FROM attributions WHERE project_path = ? AND archived != ''`, projectPath)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
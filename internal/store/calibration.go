@@ -0,0 +1,110 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Labels a user gives an attribution: who actually made the change.
+const (
+	LabelAI    = "ai"
+	LabelHuman = "human"
+)
+
+// MatchManual is the match type of an attribution the user asserted (e.g.
+// from an editor plugin) rather than one correlated from events.
+const MatchManual = "manual"
+
+// CalibrationSample is an attribution as gapmap calibrate shows it, with the
+// label the user gave it, if any.
+type CalibrationSample struct {
+	AttributionRecord
+	WorkType   string
+	CommitHash string
+	Label      string // LabelAI, LabelHuman or "" when unlabeled
+	LabeledAt  time.Time
+}
+
+const calibrationColumns = `a.id, a.file_path, a.project_path, a.file_event_id, a.session_event_id,
+	a.authorship_level, a.confidence, a.uncertain, a.first_author,
+	a.correlation_window_ms, a.timestamp, a.lines_changed, a.match_type,
+	a.work_type, a.commit_hash, COALESCE(l.label, ''), COALESCE(l.labeled_at, '')`
+
+// SampleAttributions returns up to n random unlabeled attributions of
+// added code made since since, leaving out manual ones: those are labels
+// already.
+func (s *Store) SampleAttributions(since time.Time, n int) ([]CalibrationSample, error) {
+	rows, err := s.db.Query(
+		`SELECT `+calibrationColumns+`
+		 FROM attributions a LEFT JOIN attribution_labels l ON l.attribution_id = a.id
+		 WHERE a.kind = 'addition' AND a.match_type != ? AND a.timestamp >= ? AND l.attribution_id IS NULL
+		 ORDER BY RANDOM()
+		 LIMIT ?`,
+		MatchManual, since.UTC().Format(time.RFC3339Nano), n,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanCalibrationSamples(rows)
+}
+
+// QueryLabeledAttributions returns every labeled attribution, oldest label
+// first.
+func (s *Store) QueryLabeledAttributions() ([]CalibrationSample, error) {
+	rows, err := s.db.Query(
+		`SELECT ` + calibrationColumns + `
+		 FROM attributions a JOIN attribution_labels l ON l.attribution_id = a.id
+		 ORDER BY l.labeled_at ASC, a.id ASC`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanCalibrationSamples(rows)
+}
+
+// LabelAttribution records who the user says made an attribution's change,
+// replacing an earlier label.
+func (s *Store) LabelAttribution(id int64, label string) error {
+	if label != LabelAI && label != LabelHuman {
+		return fmt.Errorf("unknown label %q (want %s or %s)", label, LabelAI, LabelHuman)
+	}
+	_, err := s.db.Exec(
+		`INSERT INTO attribution_labels (attribution_id, label, labeled_at) VALUES (?, ?, ?)
+		 ON CONFLICT(attribution_id) DO UPDATE SET label = excluded.label, labeled_at = excluded.labeled_at`,
+		id, label, time.Now().UTC().Format(time.RFC3339Nano),
+	)
+	return err
+}
+
+func scanCalibrationSamples(rows *sql.Rows) ([]CalibrationSample, error) {
+	var samples []CalibrationSample
+	for rows.Next() {
+		var c CalibrationSample
+		var ts, labeledAt string
+		var uncertain int
+		if err := rows.Scan(
+			&c.ID, &c.FilePath, &c.ProjectPath,
+			&c.FileEventID, &c.SessionEventID,
+			&c.AuthorshipLevel, &c.Confidence, &uncertain,
+			&c.FirstAuthor, &c.CorrelationWindowMs, &ts,
+			&c.LinesChanged, &c.MatchType,
+			&c.WorkType, &c.CommitHash, &c.Label, &labeledAt,
+		); err != nil {
+			return nil, err
+		}
+		t, err := time.Parse(time.RFC3339Nano, ts)
+		if err != nil {
+			return nil, fmt.Errorf("parse attribution timestamp %q: %w", ts, err)
+		}
+		c.Timestamp = t
+		c.Uncertain = uncertain != 0
+		if labeledAt != "" {
+			c.LabeledAt, _ = time.Parse(time.RFC3339Nano, labeledAt)
+		}
+		samples = append(samples, c)
+	}
+	return samples, rows.Err()
+}
@@ -0,0 +1,85 @@
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLabelAttributions(t *testing.T) {
+	s := newTestStore(t)
+	now := time.Now()
+
+	insert := func(path, matchType string, ts time.Time) int64 {
+		t.Helper()
+		id, err := s.InsertAttribution(AttributionRecord{
+			FilePath: path, ProjectPath: "/p", AuthorshipLevel: "mostly_ai",
+			Confidence: 0.95, FirstAuthor: "ai", Timestamp: ts, LinesChanged: 3, MatchType: matchType,
+		})
+		if err != nil {
+			t.Fatalf("InsertAttribution: %v", err)
+		}
+		return id
+	}
+	recent := insert("/p/a.go", "exact_file", now.Add(-time.Hour))
+	insert("/p/b.go", MatchManual, now.Add(-time.Hour))
+	insert("/p/old.go", "exact_file", now.AddDate(0, 0, -60))
+
+	// Manual and old attributions are not sampled.
+	samples, err := s.SampleAttributions(now.AddDate(0, 0, -30), 10)
+	if err != nil {
+		t.Fatalf("SampleAttributions: %v", err)
+	}
+	if len(samples) != 1 || samples[0].ID != recent || samples[0].MatchType != "exact_file" {
+		t.Fatalf("samples = %+v, want only the recent correlated attribution", samples)
+	}
+
+	if err := s.LabelAttribution(recent, "maybe"); err == nil {
+		t.Error("LabelAttribution accepted an unknown label")
+	}
+	if err := s.LabelAttribution(recent, LabelAI); err != nil {
+		t.Fatalf("LabelAttribution: %v", err)
+	}
+	if err := s.LabelAttribution(recent, LabelHuman); err != nil {
+		t.Fatalf("relabel: %v", err)
+	}
+
+	// Labeled attributions are not offered again.
+	if samples, _ := s.SampleAttributions(now.AddDate(0, 0, -30), 10); len(samples) != 0 {
+		t.Errorf("labeled attribution sampled again: %+v", samples)
+	}
+	labeled, err := s.QueryLabeledAttributions()
+	if err != nil {
+		t.Fatalf("QueryLabeledAttributions: %v", err)
+	}
+	if len(labeled) != 1 || labeled[0].Label != LabelHuman || labeled[0].LabeledAt.IsZero() {
+		t.Fatalf("labeled = %+v, want one relabeled human", labeled)
+	}
+}
+
+func TestLineLabels(t *testing.T) {
+	s := newTestStore(t)
+
+	if _, err := s.InsertLineLabel(LineLabel{ProjectPath: "/p", FilePath: "/p/a.go", StartLine: 1, EndLine: 3, Label: "maybe"}); err == nil {
+		t.Error("InsertLineLabel accepted an unknown label")
+	}
+	if _, err := s.InsertLineLabel(LineLabel{ProjectPath: "/p", FilePath: "/p/a.go", StartLine: 5, EndLine: 2, Label: LabelAI}); err == nil {
+		t.Error("InsertLineLabel accepted an inverted range")
+	}
+	for _, l := range []LineLabel{
+		{ProjectPath: "/p", FilePath: "/p/a.go", StartLine: 1, EndLine: 10, Label: LabelAI},
+		{ProjectPath: "/p", FilePath: "/p/a.go", StartLine: 4, EndLine: 4, Label: LabelHuman},
+		{ProjectPath: "/q", FilePath: "/q/b.go", StartLine: 1, EndLine: 1, Label: LabelHuman},
+	} {
+		if _, err := s.InsertLineLabel(l); err != nil {
+			t.Fatalf("InsertLineLabel: %v", err)
+		}
+	}
+
+	labels, err := s.QueryLineLabels("/p")
+	if err != nil {
+		t.Fatalf("QueryLineLabels: %v", err)
+	}
+	if len(labels) != 2 || labels[0].Label != LabelAI || labels[1].Label != LabelHuman || labels[1].StartLine != 4 {
+		t.Errorf("labels = %+v, want the project's two labels oldest first", labels)
+	}
+}
@@ -0,0 +1,80 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// ClipboardSnapshot is a hashed clipboard block captured shortly after AI
+// session activity.
+type ClipboardSnapshot struct {
+	ID          int64
+	Timestamp   time.Time
+	ContentHash string
+	LineHashes  []string
+	LineCount   int
+}
+
+// InsertClipboardSnapshot records a hashed clipboard block and returns its row ID.
+func (s *Store) InsertClipboardSnapshot(snap ClipboardSnapshot) (int64, error) {
+	hashes, err := json.Marshal(snap.LineHashes)
+	if err != nil {
+		return 0, err
+	}
+	result, err := s.db.Exec(
+		`INSERT INTO clipboard_snapshots (timestamp, content_hash, line_hashes, line_count)
+		 VALUES (?, ?, ?, ?)`,
+		snap.Timestamp.UTC().Format(time.RFC3339Nano), snap.ContentHash, string(hashes), snap.LineCount,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+// QueryUnmatchedClipboardSnapshots returns clipboard snapshots captured in
+// [start, end] that have not yet been matched to a file event, newest first.
+func (s *Store) QueryUnmatchedClipboardSnapshots(start, end time.Time) ([]ClipboardSnapshot, error) {
+	rows, err := s.db.Query(
+		`SELECT id, timestamp, content_hash, line_hashes, line_count
+		 FROM clipboard_snapshots
+		 WHERE matched_file_event_id IS NULL AND timestamp >= ? AND timestamp <= ?
+		 ORDER BY timestamp DESC`,
+		start.UTC().Format(time.RFC3339Nano),
+		end.UTC().Format(time.RFC3339Nano),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var snaps []ClipboardSnapshot
+	for rows.Next() {
+		var snap ClipboardSnapshot
+		var ts, hashes string
+		if err := rows.Scan(&snap.ID, &ts, &snap.ContentHash, &hashes, &snap.LineCount); err != nil {
+			return nil, err
+		}
+		t, err := time.Parse(time.RFC3339Nano, ts)
+		if err != nil {
+			return nil, fmt.Errorf("parse clipboard timestamp %q: %w", ts, err)
+		}
+		snap.Timestamp = t
+		if err := json.Unmarshal([]byte(hashes), &snap.LineHashes); err != nil {
+			return nil, fmt.Errorf("parse clipboard line hashes: %w", err)
+		}
+		snaps = append(snaps, snap)
+	}
+	return snaps, rows.Err()
+}
+
+// MarkClipboardSnapshotMatched links a clipboard snapshot to the file event
+// it was attributed to, so it is not matched again.
+func (s *Store) MarkClipboardSnapshotMatched(snapshotID, fileEventID int64) error {
+	_, err := s.db.Exec(
+		`UPDATE clipboard_snapshots SET matched_file_event_id = ? WHERE id = ?`,
+		fileEventID, snapshotID,
+	)
+	return err
+}
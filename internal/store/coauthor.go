@@ -0,0 +1,74 @@
+package store
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Co-author verification statuses of a commit with an AI Co-Authored-By
+// trailer; see SetCoauthorVerification.
+const (
+	CoauthorCorroborated = "corroborated" // AI edits touched the commit's files before it
+	CoauthorUnverified   = "unverified"   // no session activity backs the trailer
+)
+
+// CoauthorCommit is a synced commit with a Co-Authored-By trailer, and what
+// checking the trailer against session data found.
+type CoauthorCommit struct {
+	Hash          string    `json:"hash"`
+	Author        string    `json:"author"`
+	Message       string    `json:"-"`
+	CoauthorName  string    `json:"coauthor"`
+	Timestamp     time.Time `json:"timestamp"`
+	Files         []string  `json:"files"` // changed files, relative to the repository root
+	Status        string    `json:"status,omitempty"`
+	Confidence    float64   `json:"confidence,omitempty"`
+	SessionEvents int       `json:"session_events"`
+}
+
+// QueryCoauthorCommits returns the synced commits made at or after since
+// that carry a Co-Authored-By trailer, newest first, with the files each
+// changed.
+func (s *Store) QueryCoauthorCommits(since time.Time) ([]CoauthorCommit, error) {
+	rows, err := s.db.Query(
+		`SELECT c.hash, c.author, c.message, c.coauthor_name, c.timestamp,
+		        c.coauthor_status, c.coauthor_confidence, c.coauthor_session_events,
+		        COALESCE((SELECT GROUP_CONCAT(d.file_path, char(31)) FROM git_diffs d WHERE d.commit_id = c.id), '')
+		 FROM git_commits c
+		 WHERE c.has_coauthor_tag = 1 AND c.timestamp >= ?
+		 ORDER BY c.timestamp DESC, c.id DESC`,
+		since.UTC().Format(time.RFC3339),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("query co-authored commits: %w", err)
+	}
+	defer rows.Close()
+
+	var commits []CoauthorCommit
+	for rows.Next() {
+		var c CoauthorCommit
+		var ts, files string
+		if err := rows.Scan(&c.Hash, &c.Author, &c.Message, &c.CoauthorName, &ts,
+			&c.Status, &c.Confidence, &c.SessionEvents, &files); err != nil {
+			return nil, err
+		}
+		c.Timestamp, _ = time.Parse(time.RFC3339, ts)
+		if files != "" {
+			c.Files = strings.Split(files, "\x1f")
+		}
+		commits = append(commits, c)
+	}
+	return commits, rows.Err()
+}
+
+// SetCoauthorVerification records the outcome of checking a commit's AI
+// Co-Authored-By trailer against session data: its status, the
+// confidence the trailer earns, and how many AI edits back it.
+func (s *Store) SetCoauthorVerification(hash, status string, confidence float64, sessionEvents int) error {
+	_, err := s.db.Exec(
+		`UPDATE git_commits SET coauthor_status = ?, coauthor_confidence = ?, coauthor_session_events = ? WHERE hash = ?`,
+		status, confidence, sessionEvents, hash,
+	)
+	return err
+}
@@ -0,0 +1,213 @@
+package store
+
+import (
+	"bytes"
+	"compress/flate"
+	"database/sql"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// SessionContent is what a Write or Edit tool call changed, as the session
+// parser computed it when the event was ingested.
+type SessionContent struct {
+	Added   string // lines the call wrote
+	Deleted string // lines an Edit removed; "" for a Write
+}
+
+// ContentExtractor extracts the lines a session event's tool call wrote
+// and removed from its raw JSONL line, for events stored without them.
+type ContentExtractor func(rawJSON string) (added, deleted string)
+
+// compressedPrefix marks a content value compressed with DEFLATE. Content
+// shorter than compressMinBytes, or that does not shrink, is stored as is.
+const (
+	compressedPrefix = "z:v1:"
+	compressMinBytes = 512
+)
+
+func compressContent(v string) string {
+	if len(v) < compressMinBytes {
+		return v
+	}
+	var buf bytes.Buffer
+	w, _ := flate.NewWriter(&buf, flate.DefaultCompression)
+	_, _ = w.Write([]byte(v))
+	_ = w.Close()
+	packed := compressedPrefix + base64.StdEncoding.EncodeToString(buf.Bytes())
+	if len(packed) >= len(v) {
+		return v
+	}
+	return packed
+}
+
+func decompressContent(v string) (string, error) {
+	if !strings.HasPrefix(v, compressedPrefix) {
+		return v, nil
+	}
+	raw, err := base64.StdEncoding.DecodeString(v[len(compressedPrefix):])
+	if err != nil {
+		return "", fmt.Errorf("decode compressed content: %w", err)
+	}
+	plain, err := io.ReadAll(flate.NewReader(bytes.NewReader(raw)))
+	if err != nil {
+		return "", fmt.Errorf("decompress content: %w", err)
+	}
+	return string(plain), nil
+}
+
+// packContent compresses and then seals a content column value.
+func (s *Store) packContent(v string) string {
+	return s.seal(compressContent(v))
+}
+
+// unpackContent reverses packContent.
+func (s *Store) unpackContent(v string) (string, error) {
+	v, err := s.unseal(v)
+	if err != nil {
+		return "", err
+	}
+	return decompressContent(v)
+}
+
+// contentArgs returns the diff_content and deleted_content values to
+// store for c: NULL when the content was not extracted.
+func (s *Store) contentArgs(c *SessionContent) (added, deleted interface{}) {
+	if c == nil {
+		return nil, nil
+	}
+	return s.packContent(c.Added), s.packContent(c.Deleted)
+}
+
+// contentColumns selects a session event's stored content, and its raw
+// JSON only when there is none to extract it from.
+const contentColumns = `diff_content, deleted_content, CASE WHEN diff_content IS NULL THEN raw_json ELSE '' END`
+
+// QuerySessionEventContent returns the lines session event id wrote and
+// removed. Events stored without them have them extracted from their raw
+// JSON with extract.
+func (s *Store) QuerySessionEventContent(id int64, extract ContentExtractor) (SessionContent, error) {
+	var added, deleted sql.NullString
+	var rawJSON string
+	err := s.db.QueryRow(
+		`SELECT `+contentColumns+` FROM session_events WHERE id = ?`, id,
+	).Scan(&added, &deleted, &rawJSON)
+	if err != nil {
+		return SessionContent{}, err
+	}
+	return s.readContent(id, added, deleted, rawJSON, extract)
+}
+
+// QueryWriteEditSessionContent returns the content of every Write and Edit
+// session event, keyed by event ID, in one query. Reports read it instead
+// of calling QuerySessionEventContent per event.
+func (s *Store) QueryWriteEditSessionContent(extract ContentExtractor) (map[int64]SessionContent, error) {
+	rows, err := s.db.Query(
+		`SELECT id, ` + contentColumns + `
+		 FROM session_events
+		 WHERE tool_name IN ('Write', 'Edit')`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("query session content: %w", err)
+	}
+	defer rows.Close()
+
+	contents := make(map[int64]SessionContent)
+	for rows.Next() {
+		var id int64
+		var added, deleted sql.NullString
+		var rawJSON string
+		if err := rows.Scan(&id, &added, &deleted, &rawJSON); err != nil {
+			return nil, err
+		}
+		c, err := s.readContent(id, added, deleted, rawJSON, extract)
+		if err != nil {
+			return nil, err
+		}
+		contents[id] = c
+	}
+	return contents, rows.Err()
+}
+
+// readContent unpacks the content columns of session event id, or
+// extracts its content from rawJSON when none is stored.
+func (s *Store) readContent(id int64, added, deleted sql.NullString, rawJSON string, extract ContentExtractor) (SessionContent, error) {
+	var c SessionContent
+	if !added.Valid {
+		raw, err := s.unseal(rawJSON)
+		if err != nil {
+			return SessionContent{}, fmt.Errorf("session event %d raw json: %w", id, err)
+		}
+		c.Added, c.Deleted = extract(raw)
+		return c, nil
+	}
+
+	var err error
+	if c.Added, err = s.unpackContent(added.String); err != nil {
+		return SessionContent{}, fmt.Errorf("session event %d diff content: %w", id, err)
+	}
+	if c.Deleted, err = s.unpackContent(deleted.String); err != nil {
+		return SessionContent{}, fmt.Errorf("session event %d deleted content: %w", id, err)
+	}
+	return c, nil
+}
+
+// BackfillSessionContent stores the content of session events recorded
+// without it, extracting it from their raw JSON with extract, in batches
+// of batchSize. It returns how many events were filled.
+func (s *Store) BackfillSessionContent(extract ContentExtractor, batchSize int) (int64, error) {
+	var filled int64
+	for {
+		n, err := s.backfillContentBatch(extract, batchSize)
+		filled += n
+		if err != nil || n < int64(batchSize) {
+			return filled, err
+		}
+	}
+}
+
+func (s *Store) backfillContentBatch(extract ContentExtractor, limit int) (int64, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	type pending struct {
+		id      int64
+		rawJSON string
+	}
+	rows, err := tx.Query(`SELECT id, raw_json FROM session_events WHERE diff_content IS NULL ORDER BY id LIMIT ?`, limit)
+	if err != nil {
+		return 0, fmt.Errorf("query session events without content: %w", err)
+	}
+	var batch []pending
+	for rows.Next() {
+		var p pending
+		if err := rows.Scan(&p.id, &p.rawJSON); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		batch = append(batch, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, p := range batch {
+		raw, err := s.unseal(p.rawJSON)
+		if err != nil {
+			return 0, fmt.Errorf("session event %d: %w", p.id, err)
+		}
+		var c SessionContent
+		c.Added, c.Deleted = extract(raw)
+		added, deleted := s.contentArgs(&c)
+		if _, err := tx.Exec(`UPDATE session_events SET diff_content = ?, deleted_content = ? WHERE id = ?`, added, deleted, p.id); err != nil {
+			return 0, fmt.Errorf("store content of session event %d: %w", p.id, err)
+		}
+	}
+	return int64(len(batch)), tx.Commit()
+}
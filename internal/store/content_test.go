@@ -0,0 +1,140 @@
+package store
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCompressContent(t *testing.T) {
+	short := "func main() {}\n"
+	if got := compressContent(short); got != short {
+		t.Errorf("short content compressed to %q", got)
+	}
+
+	long := strings.Repeat("if err != nil {\n\treturn err\n}\n", 100)
+	packed := compressContent(long)
+	if !strings.HasPrefix(packed, compressedPrefix) || len(packed) >= len(long) {
+		t.Fatalf("long content not compressed: %d bytes, prefix %q", len(packed), packed[:min(len(packed), 8)])
+	}
+	if got, err := decompressContent(packed); err != nil || got != long {
+		t.Errorf("round trip = %d bytes, %v", len(got), err)
+	}
+}
+
+func TestQuerySessionEventContent(t *testing.T) {
+	s := newTestStore(t)
+	now := time.Now()
+
+	long := strings.Repeat("x := compute(y)\n", 100)
+	stored := SessionContent{Added: long, Deleted: "old := 1\n"}
+	if err := s.InsertSessionEventWithContext("sess", "tool_use", "Edit", "a.go", "h", now,
+		`{"uuid":"u-1"}`, 100, SessionEventContext{Content: &stored}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.InsertSessionEvent("sess", "tool_use", "Write", "b.go", "h", now, `{"uuid":"u-2"}`, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	var extracted []string
+	extract := func(rawJSON string) (string, string) {
+		extracted = append(extracted, rawJSON)
+		return "from raw\n", ""
+	}
+
+	var raw string
+	if err := s.db.QueryRow(`SELECT diff_content FROM session_events WHERE id = 1`).Scan(&raw); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(raw, compressedPrefix) {
+		t.Errorf("large diff content stored uncompressed")
+	}
+
+	got, err := s.QuerySessionEventContent(1, extract)
+	if err != nil || got != stored {
+		t.Errorf("stored content = %+v, %v", got, err)
+	}
+	if len(extracted) != 0 {
+		t.Errorf("extractor called for an event with stored content")
+	}
+
+	// An event stored without content falls back to its raw JSON.
+	got, err = s.QuerySessionEventContent(2, extract)
+	if err != nil || got.Added != "from raw\n" {
+		t.Errorf("fallback content = %+v, %v", got, err)
+	}
+	if len(extracted) != 1 || extracted[0] != `{"uuid":"u-2"}` {
+		t.Errorf("extractor calls = %q", extracted)
+	}
+}
+
+func TestBackfillSessionContent(t *testing.T) {
+	s := newTestStore(t)
+	now := time.Now()
+	for _, line := range []string{`{"uuid":"u-1"}`, `{"uuid":"u-2"}`, `{"uuid":"u-3"}`} {
+		if err := s.InsertSessionEvent("sess", "tool_use", "Edit", "a.go", "h", now, line, 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.InsertSessionEventWithContext("sess", "tool_use", "Edit", "a.go", "h", now, `{"uuid":"u-4"}`, 1,
+		SessionEventContext{Content: &SessionContent{Added: "kept\n"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	calls := 0
+	extract := func(rawJSON string) (string, string) {
+		calls++
+		return rawJSON, "gone\n"
+	}
+	n, err := s.BackfillSessionContent(extract, 2)
+	if err != nil || n != 3 {
+		t.Fatalf("BackfillSessionContent = %d, %v; want 3", n, err)
+	}
+	if n, err := s.BackfillSessionContent(extract, 2); err != nil || n != 0 {
+		t.Errorf("second backfill = %d, %v; want 0", n, err)
+	}
+
+	calls = 0
+	got, err := s.QuerySessionEventContent(2, extract)
+	if err != nil || got.Added != `{"uuid":"u-2"}` || got.Deleted != "gone\n" {
+		t.Errorf("backfilled content = %+v, %v", got, err)
+	}
+	if got, err := s.QuerySessionEventContent(4, extract); err != nil || got.Added != "kept\n" {
+		t.Errorf("stored content = %+v, %v", got, err)
+	}
+	if calls != 0 {
+		t.Errorf("extractor called %d times after backfill", calls)
+	}
+}
+
+func TestEncryptContent_DiffContent(t *testing.T) {
+	key := bytes.Repeat([]byte{1}, KeySize)
+	s := newTestStore(t)
+	content := SessionContent{Added: "func launchCodes() {}\n"}
+	if err := s.InsertSessionEventWithContext("sess", "tool_use", "Write", "a.go", "h", time.Now(), `{"uuid":"u-1"}`, 1,
+		SessionEventContext{Content: &content}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.InsertSessionEvent("sess", "tool_use", "Write", "b.go", "h", time.Now(), `{"uuid":"u-2"}`, 1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.EncryptContent(key); err != nil {
+		t.Fatalf("EncryptContent: %v", err)
+	}
+
+	var raw string
+	if err := s.db.QueryRow(`SELECT diff_content FROM session_events WHERE id = 1`).Scan(&raw); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(raw, encryptedPrefix) {
+		t.Errorf("diff content not encrypted: %q", raw)
+	}
+	var unset int
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM session_events WHERE diff_content IS NULL`).Scan(&unset); err != nil || unset != 1 {
+		t.Errorf("events without content after encryption = %d, %v; want 1", unset, err)
+	}
+	if got, err := s.QuerySessionEventContent(1, nil); err != nil || got != content {
+		t.Errorf("content after encryption = %+v, %v", got, err)
+	}
+}
@@ -0,0 +1,323 @@
+package store
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// KeySource returns the key of an encrypted database.
+type KeySource func() ([]byte, error)
+
+// Option configures how New opens a database.
+type Option func(*Store)
+
+// WithKeySource has New read an encrypted database with the key from ks,
+// which it calls only when the database is encrypted. Without it,
+// encrypted databases are unreadable.
+func WithKeySource(ks KeySource) Option {
+	return func(s *Store) { s.keySource = ks }
+}
+
+// ErrNoKey is returned (wrapped) when an encrypted database is opened
+// without its key.
+var ErrNoKey = errors.New("database is encrypted and its key is unavailable")
+
+// KeySize is the length of an encryption key: AES-256.
+const KeySize = 32
+
+// encryptedPrefix marks a column value sealed with the database key, so
+// plaintext written before encryption was turned on still reads.
+const encryptedPrefix = "enc:v1:"
+
+// keyIDState is the daemon_state key holding the fingerprint of the
+// database key. Its presence marks the database as encrypted.
+const keyIDState = "encryption_key_id"
+
+// EncryptionResult is what EncryptContent or DecryptContent changed.
+type EncryptionResult struct {
+	Rows int64 // session events and file snapshots rewritten
+}
+
+// keyID returns a fingerprint of key that is safe to store beside the
+// data it protects.
+func keyID(key []byte) string {
+	sum := sha256.Sum256(append([]byte("gap-map key id\x00"), key...))
+	return hex.EncodeToString(sum[:8])
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("encryption key is %d bytes, want %d", len(key), KeySize)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// loadKey readies the store to read and write an encrypted database. A
+// plaintext database needs no key and is left as it is.
+func (s *Store) loadKey() error {
+	id, err := s.GetDaemonState(keyIDState)
+	if err != nil {
+		return fmt.Errorf("read encryption state: %w", err)
+	}
+	if id == "" {
+		return nil
+	}
+	if s.keySource == nil {
+		return ErrNoKey
+	}
+	key, err := s.keySource()
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrNoKey, err)
+	}
+	if keyID(key) != id {
+		return fmt.Errorf("%w: the available key (id %s) is not the one the database was encrypted with (id %s)", ErrNoKey, keyID(key), id)
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return err
+	}
+	s.aead = aead
+	return nil
+}
+
+// Encrypted reports whether the store seals session content.
+func (s *Store) Encrypted() bool {
+	return s.aead != nil
+}
+
+// seal encrypts v with the database key, if the database has one.
+func (s *Store) seal(v string) string {
+	if s.aead == nil || v == "" {
+		return v
+	}
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		panic(fmt.Sprintf("read random nonce: %v", err))
+	}
+	sealed := s.aead.Seal(nonce, nonce, []byte(v), nil)
+	return encryptedPrefix + base64.StdEncoding.EncodeToString(sealed)
+}
+
+// unseal decrypts a value written by seal. Values without the encrypted
+// prefix are plaintext and returned as they are.
+func (s *Store) unseal(v string) (string, error) {
+	if !strings.HasPrefix(v, encryptedPrefix) {
+		return v, nil
+	}
+	if s.aead == nil {
+		return "", ErrNoKey
+	}
+	sealed, err := base64.StdEncoding.DecodeString(v[len(encryptedPrefix):])
+	if err != nil {
+		return "", fmt.Errorf("decode encrypted value: %w", err)
+	}
+	n := s.aead.NonceSize()
+	if len(sealed) < n {
+		return "", errors.New("decode encrypted value: too short")
+	}
+	plain, err := s.aead.Open(nil, sealed[:n], sealed[n:], nil)
+	if err != nil {
+		return "", fmt.Errorf("decrypt value: %w", err)
+	}
+	return string(plain), nil
+}
+
+// EncryptContent encrypts the session content of the database (each
+// event's raw JSONL line, prompt text and diff content, and human file
+// snapshots) with key and marks the database encrypted, so later writes
+// are encrypted too and later opens need key. Rows already encrypted are
+// skipped, so an interrupted run can be repeated. The database is
+// vacuumed afterwards so no plaintext is left in free pages or the WAL;
+// backups taken earlier still hold plaintext.
+func (s *Store) EncryptContent(key []byte) (EncryptionResult, error) {
+	id, err := s.GetDaemonState(keyIDState)
+	if err != nil {
+		return EncryptionResult{}, fmt.Errorf("read encryption state: %w", err)
+	}
+	if id != "" && id != keyID(key) {
+		return EncryptionResult{}, fmt.Errorf("database is already encrypted with another key (id %s)", id)
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return EncryptionResult{}, err
+	}
+
+	prev := s.aead
+	s.aead = aead
+	res, err := s.rewriteContent(func(v string) (string, error) {
+		if strings.HasPrefix(v, encryptedPrefix) {
+			return v, nil
+		}
+		return s.seal(v), nil
+	}, func(tx *sql.Tx) error {
+		_, err := tx.Exec(
+			`INSERT INTO daemon_state (key, value, updated_at) VALUES (?, ?, ?)
+			 ON CONFLICT(key) DO UPDATE SET value = excluded.value, updated_at = excluded.updated_at`,
+			keyIDState, keyID(key), time.Now().UTC().Format(time.RFC3339),
+		)
+		return err
+	})
+	if err != nil {
+		s.aead = prev
+		return res, err
+	}
+	return res, s.compact()
+}
+
+// DecryptContent reverses EncryptContent: it writes the session content
+// back as plaintext and clears the encryption mark. The store must have
+// been opened with the database key.
+func (s *Store) DecryptContent() (EncryptionResult, error) {
+	if s.aead == nil {
+		return EncryptionResult{}, errors.New("database is not encrypted")
+	}
+	res, err := s.rewriteContent(s.unseal, func(tx *sql.Tx) error {
+		_, err := tx.Exec(`DELETE FROM daemon_state WHERE key = ?`, keyIDState)
+		return err
+	})
+	if err != nil {
+		return res, err
+	}
+	s.aead = nil
+	return res, s.compact()
+}
+
+// rewriteContent applies fn to the raw_json, prompt and stored content of
+// every session event and the content of every file snapshot and runs finish, all in one
+// transaction.
+func (s *Store) rewriteContent(fn func(string) (string, error), finish func(tx *sql.Tx) error) (EncryptionResult, error) {
+	var res EncryptionResult
+	tx, err := s.db.Begin()
+	if err != nil {
+		return res, err
+	}
+	defer tx.Rollback()
+
+	type content struct {
+		id              int64
+		rawJSON, prompt string
+		added, deleted  sql.NullString
+	}
+	rows, err := tx.Query(
+		`SELECT id, raw_json, prompt, diff_content, deleted_content FROM session_events
+		 WHERE raw_json != '' OR prompt != '' OR diff_content != '' OR deleted_content != ''
+		 ORDER BY id`)
+	if err != nil {
+		return res, fmt.Errorf("query session content: %w", err)
+	}
+	var all []content
+	for rows.Next() {
+		var c content
+		if err := rows.Scan(&c.id, &c.rawJSON, &c.prompt, &c.added, &c.deleted); err != nil {
+			rows.Close()
+			return res, err
+		}
+		all = append(all, c)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return res, err
+	}
+
+	for _, c := range all {
+		rawJSON, err := fn(c.rawJSON)
+		if err != nil {
+			return res, fmt.Errorf("session event %d: %w", c.id, err)
+		}
+		prompt, err := fn(c.prompt)
+		if err != nil {
+			return res, fmt.Errorf("session event %d: %w", c.id, err)
+		}
+		added, err := rewriteNull(fn, c.added)
+		if err != nil {
+			return res, fmt.Errorf("session event %d: %w", c.id, err)
+		}
+		deleted, err := rewriteNull(fn, c.deleted)
+		if err != nil {
+			return res, fmt.Errorf("session event %d: %w", c.id, err)
+		}
+		if rawJSON == c.rawJSON && prompt == c.prompt && added == c.added && deleted == c.deleted {
+			continue
+		}
+		if _, err := tx.Exec(`UPDATE session_events SET raw_json = ?, prompt = ?, diff_content = ?, deleted_content = ? WHERE id = ?`,
+			rawJSON, prompt, added, deleted, c.id); err != nil {
+			return res, fmt.Errorf("rewrite session event %d: %w", c.id, err)
+		}
+		res.Rows++
+	}
+
+	type snapshot struct {
+		id      int64
+		content string
+	}
+	rows, err = tx.Query(`SELECT id, content FROM file_snapshots ORDER BY id`)
+	if err != nil {
+		return res, fmt.Errorf("query file snapshots: %w", err)
+	}
+	var snapshots []snapshot
+	for rows.Next() {
+		var sn snapshot
+		if err := rows.Scan(&sn.id, &sn.content); err != nil {
+			rows.Close()
+			return res, err
+		}
+		snapshots = append(snapshots, sn)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return res, err
+	}
+
+	for _, sn := range snapshots {
+		content, err := fn(sn.content)
+		if err != nil {
+			return res, fmt.Errorf("file snapshot %d: %w", sn.id, err)
+		}
+		if content == sn.content {
+			continue
+		}
+		if _, err := tx.Exec(`UPDATE file_snapshots SET content = ? WHERE id = ?`, content, sn.id); err != nil {
+			return res, fmt.Errorf("rewrite file snapshot %d: %w", sn.id, err)
+		}
+		res.Rows++
+	}
+
+	if err := finish(tx); err != nil {
+		return res, fmt.Errorf("update encryption state: %w", err)
+	}
+	return res, tx.Commit()
+}
+
+// rewriteNull applies fn to v, leaving NULL as it is.
+func rewriteNull(fn func(string) (string, error), v sql.NullString) (sql.NullString, error) {
+	if !v.Valid {
+		return v, nil
+	}
+	out, err := fn(v.String)
+	return sql.NullString{String: out, Valid: true}, err
+}
+
+// compact rewrites the database file and truncates the WAL, dropping the
+// old copies of rewritten rows.
+func (s *Store) compact() error {
+	if _, err := s.db.Exec(`VACUUM`); err != nil {
+		return fmt.Errorf("vacuum: %w", err)
+	}
+	if _, err := s.db.Exec(`PRAGMA wal_checkpoint(TRUNCATE)`); err != nil {
+		return fmt.Errorf("checkpoint: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package store
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEncryptContent(t *testing.T) {
+	key := bytes.Repeat([]byte{1}, KeySize)
+	withKey := WithKeySource(func() ([]byte, error) { return key, nil })
+
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	s, err := New(dbPath, withKey)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	now := time.Now()
+	line := `{"uuid":"u-1","timestamp":"2026-01-15T10:00:00Z","secret":"func launchCodes()"}`
+	if err := s.InsertSessionEventWithContext("sess", "tool_use", "Write", "a.go", "h", now, line, 5,
+		SessionEventContext{Prompt: "write launchCodes"}); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+	if s.Encrypted() {
+		t.Fatal("new database is encrypted")
+	}
+
+	res, err := s.EncryptContent(key)
+	if err != nil {
+		t.Fatalf("EncryptContent: %v", err)
+	}
+	if res.Rows != 1 {
+		t.Errorf("encrypted %d rows, want 1", res.Rows)
+	}
+	// Written after encryption: sealed on insert, and still deduplicated.
+	line2 := `{"uuid":"u-2","timestamp":"2026-01-15T10:01:00Z","secret":"func launchCodes2()"}`
+	for i := 0; i < 2; i++ {
+		if err := s.InsertSessionEvent("sess", "tool_use", "Edit", "a.go", "h", now, line2, 1); err != nil {
+			t.Fatalf("insert: %v", err)
+		}
+	}
+	s.Close()
+
+	data, err := os.ReadFile(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(data, []byte("launchCodes")) {
+		t.Error("database file holds plaintext content after encryption")
+	}
+
+	// Reopened with the key, reads are transparent.
+	s, err = New(dbPath, withKey)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	events, err := s.QueryWriteEditSessionEvents()
+	if err != nil {
+		t.Fatalf("QueryWriteEditSessionEvents: %v", err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("got %d events, want 2", len(events))
+	}
+	if events[0].Prompt != "write launchCodes" {
+		t.Errorf("prompt = %q", events[0].Prompt)
+	}
+	for i, want := range []string{line, line2} {
+		if raw, err := s.QuerySessionEventRawJSON(events[i].ID); err != nil || raw != want {
+			t.Errorf("raw json of event %d = %q, %v", i, raw, err)
+		}
+	}
+
+	// Encrypting again is a no-op; another key is refused.
+	if res, err := s.EncryptContent(key); err != nil || res.Rows != 0 {
+		t.Errorf("EncryptContent again = %+v, %v", res, err)
+	}
+	if _, err := s.EncryptContent(bytes.Repeat([]byte{2}, KeySize)); err == nil {
+		t.Error("EncryptContent with another key: want error")
+	}
+	s.Close()
+
+	// Without the right key the database does not open.
+	wrongKey := WithKeySource(func() ([]byte, error) { return bytes.Repeat([]byte{2}, KeySize), nil })
+	if _, err := New(dbPath, wrongKey); !errors.Is(err, ErrNoKey) {
+		t.Errorf("New with the wrong key = %v, want ErrNoKey", err)
+	}
+	if _, err := New(dbPath); !errors.Is(err, ErrNoKey) {
+		t.Errorf("New without a key source = %v, want ErrNoKey", err)
+	}
+
+	// Decrypting restores plaintext and drops the key requirement.
+	s, err = New(dbPath, withKey)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	if res, err := s.DecryptContent(); err != nil || res.Rows != 2 {
+		t.Fatalf("DecryptContent = %+v, %v", res, err)
+	}
+	s.Close()
+	s, err = New(dbPath)
+	if err != nil {
+		t.Fatalf("open decrypted database: %v", err)
+	}
+	defer s.Close()
+	var raw string
+	if err := s.db.QueryRow(`SELECT raw_json FROM session_events ORDER BY id LIMIT 1`).Scan(&raw); err != nil {
+		t.Fatal(err)
+	}
+	if strings.HasPrefix(raw, encryptedPrefix) || raw != line {
+		t.Errorf("stored raw json after decrypt = %q", raw)
+	}
+}
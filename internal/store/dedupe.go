@@ -0,0 +1,138 @@
+package store
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+)
+
+// sessionEventKey returns the dedupe key for a session event's raw JSONL
+// line: "uuid:<uuid>" when the line carries a message uuid, otherwise
+// "sha256:<hash of the line>" when it carries a timestamp. A line with
+// neither cannot be told apart from a genuine repeat of the same action, so
+// it gets no key and is never deduplicated.
+func sessionEventKey(rawJSON string) string {
+	if rawJSON == "" {
+		return ""
+	}
+	var envelope struct {
+		UUID      string          `json:"uuid"`
+		Timestamp json.RawMessage `json:"timestamp"`
+	}
+	if err := json.Unmarshal([]byte(rawJSON), &envelope); err != nil {
+		return ""
+	}
+	if envelope.UUID != "" {
+		return "uuid:" + envelope.UUID
+	}
+	if len(envelope.Timestamp) == 0 {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(rawJSON))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// DedupeResult summarizes a DedupeSessionEvents run.
+type DedupeResult struct {
+	Scanned    int // session events that had no dedupe key
+	Backfilled int // events given a key and kept
+	Duplicates int // events removed as duplicates of an earlier one
+}
+
+// DedupeSessionEvents cleans up session events ingested before dedupe keys
+// existed. Each keyless event is given its key; an event whose key is
+// already taken within its session is a duplicate and is deleted, with any
+// attributions pointing at it moved to the event that is kept. With dryRun,
+// the counts are computed and nothing is changed.
+func (s *Store) DedupeSessionEvents(dryRun bool) (DedupeResult, error) {
+	var res DedupeResult
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return res, err
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	// Keys already taken, by session.
+	kept := make(map[string]int64)
+	rows, err := tx.Query(`SELECT id, session_id, dedupe_key FROM session_events WHERE dedupe_key != ''`)
+	if err != nil {
+		return res, fmt.Errorf("query keyed session events: %w", err)
+	}
+	for rows.Next() {
+		var id int64
+		var sessionID, key string
+		if err := rows.Scan(&id, &sessionID, &key); err != nil {
+			rows.Close()
+			return res, err
+		}
+		kept[sessionID+"\x00"+key] = id
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return res, err
+	}
+
+	type pending struct {
+		id        int64
+		sessionID string
+		rawJSON   string
+	}
+	var unkeyed []pending
+	rows, err = tx.Query(`SELECT id, session_id, raw_json FROM session_events WHERE dedupe_key = '' AND raw_json != '' ORDER BY id`)
+	if err != nil {
+		return res, fmt.Errorf("query unkeyed session events: %w", err)
+	}
+	for rows.Next() {
+		var p pending
+		if err := rows.Scan(&p.id, &p.sessionID, &p.rawJSON); err != nil {
+			rows.Close()
+			return res, err
+		}
+		unkeyed = append(unkeyed, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return res, err
+	}
+
+	for _, p := range unkeyed {
+		res.Scanned++
+		rawJSON, err := s.unseal(p.rawJSON)
+		if err != nil {
+			return res, fmt.Errorf("session event %d: %w", p.id, err)
+		}
+		key := sessionEventKey(rawJSON)
+		if key == "" {
+			continue
+		}
+		if keepID, dup := kept[p.sessionID+"\x00"+key]; dup {
+			res.Duplicates++
+			if dryRun {
+				continue
+			}
+			if _, err := tx.Exec(`UPDATE attributions SET session_event_id = ? WHERE session_event_id = ?`, keepID, p.id); err != nil {
+				return res, fmt.Errorf("repoint attributions for session event %d: %w", p.id, err)
+			}
+			if _, err := tx.Exec(`DELETE FROM session_events WHERE id = ?`, p.id); err != nil {
+				return res, fmt.Errorf("delete session event %d: %w", p.id, err)
+			}
+			continue
+		}
+
+		kept[p.sessionID+"\x00"+key] = p.id
+		res.Backfilled++
+		if dryRun {
+			continue
+		}
+		if _, err := tx.Exec(`UPDATE session_events SET dedupe_key = ? WHERE id = ?`, key, p.id); err != nil {
+			return res, fmt.Errorf("set dedupe key for session event %d: %w", p.id, err)
+		}
+	}
+
+	if dryRun {
+		return res, nil
+	}
+	return res, tx.Commit()
+}
@@ -0,0 +1,133 @@
+package store
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newTestStore(t testing.TB) *Store {
+	t.Helper()
+	s, err := New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func TestInsertSessionEvent_Idempotent(t *testing.T) {
+	s := newTestStore(t)
+	now := time.Now()
+	line := `{"type":"assistant","uuid":"u-1","timestamp":"2026-01-15T10:00:00Z"}`
+
+	for i := 0; i < 3; i++ {
+		if err := s.InsertSessionEvent("sess", "tool_use", "Write", "a.go", "h", now, line, 5); err != nil {
+			t.Fatalf("InsertSessionEvent: %v", err)
+		}
+	}
+	// The same line in another session is a different event.
+	if err := s.InsertSessionEvent("other", "tool_use", "Write", "a.go", "h", now, line, 5); err != nil {
+		t.Fatalf("InsertSessionEvent: %v", err)
+	}
+	// Lines without uuid or timestamp are never deduplicated.
+	for i := 0; i < 2; i++ {
+		if err := s.InsertSessionEvent("sess", "tool_use", "Bash", "ls", "", now, "{}", 0); err != nil {
+			t.Fatalf("InsertSessionEvent: %v", err)
+		}
+	}
+
+	n, err := s.SessionEventsCount()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 4 {
+		t.Errorf("SessionEventsCount = %d, want 4", n)
+	}
+}
+
+func TestInsertSessionEvents_Batch(t *testing.T) {
+	s := newTestStore(t)
+	now := time.Now()
+	line := `{"type":"assistant","uuid":"u-1","timestamp":"2026-01-15T10:00:00Z"}`
+	events := []NewSessionEvent{
+		{SessionID: "sess", EventType: "tool_use", ToolName: "Write", FilePath: "a.go", Timestamp: now, RawJSON: line, LinesChanged: 5,
+			Context: SessionEventContext{Model: "m", Content: &SessionContent{Added: "x\n"}}},
+		{SessionID: "sess", EventType: "tool_use", ToolName: "Write", FilePath: "a.go", Timestamp: now, RawJSON: line, LinesChanged: 5},
+		{SessionID: "sess", EventType: "tool_use", ToolName: "Bash", FilePath: "ls", Timestamp: now, RawJSON: "{}"},
+	}
+	if err := s.InsertSessionEvents(events); err != nil {
+		t.Fatalf("InsertSessionEvents: %v", err)
+	}
+	if n, err := s.SessionEventsCount(); err != nil || n != 2 {
+		t.Errorf("SessionEventsCount = %d, %v; want 2 (a repeated line is stored once)", n, err)
+	}
+	if got, err := s.QuerySessionEventContent(1, nil); err != nil || got.Added != "x\n" {
+		t.Errorf("content of the first event = %+v, %v", got, err)
+	}
+}
+
+func TestDedupeSessionEvents(t *testing.T) {
+	s := newTestStore(t)
+	now := time.Now().UTC().Format(time.RFC3339Nano)
+
+	// Rows ingested before dedupe keys existed: two copies of one line.
+	line := `{"type":"assistant","timestamp":"2026-01-15T10:00:00Z","message":{}}`
+	var ids []int64
+	for i := 0; i < 2; i++ {
+		res, err := s.DB().Exec(
+			`INSERT INTO session_events (session_id, event_type, tool_name, file_path, timestamp, raw_json)
+			 VALUES ('sess', 'tool_use', 'Write', 'a.go', ?, ?)`, now, line)
+		if err != nil {
+			t.Fatal(err)
+		}
+		id, _ := res.LastInsertId()
+		ids = append(ids, id)
+	}
+	dupID := ids[1]
+	attrID, err := s.InsertAttribution(AttributionRecord{
+		FilePath: "a.go", ProjectPath: "/p", SessionEventID: &dupID,
+		AuthorshipLevel: "mostly_ai", Timestamp: time.Now(),
+	})
+	if err != nil {
+		t.Fatalf("InsertAttribution: %v", err)
+	}
+
+	dry, err := s.DedupeSessionEvents(true)
+	if err != nil {
+		t.Fatalf("DedupeSessionEvents(dry run): %v", err)
+	}
+	if dry.Duplicates != 1 || dry.Backfilled != 1 {
+		t.Errorf("dry run = %+v, want 1 duplicate, 1 backfilled", dry)
+	}
+	if n, _ := s.SessionEventsCount(); n != 2 {
+		t.Fatalf("dry run changed the database: %d events", n)
+	}
+
+	res, err := s.DedupeSessionEvents(false)
+	if err != nil {
+		t.Fatalf("DedupeSessionEvents: %v", err)
+	}
+	if res.Duplicates != 1 {
+		t.Errorf("Duplicates = %d, want 1", res.Duplicates)
+	}
+	if n, _ := s.SessionEventsCount(); n != 1 {
+		t.Errorf("SessionEventsCount = %d, want 1", n)
+	}
+
+	var sessionEventID int64
+	if err := s.DB().QueryRow(`SELECT session_event_id FROM attributions WHERE id = ?`, attrID).Scan(&sessionEventID); err != nil {
+		t.Fatal(err)
+	}
+	if sessionEventID != ids[0] {
+		t.Errorf("attribution points at session event %d, want kept event %d", sessionEventID, ids[0])
+	}
+
+	// The backfilled key now blocks re-ingestion of the same line.
+	if err := s.InsertSessionEvent("sess", "tool_use", "Write", "a.go", "", time.Now(), line, 0); err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := s.SessionEventsCount(); n != 1 {
+		t.Errorf("SessionEventsCount after re-ingest = %d, want 1", n)
+	}
+}
@@ -0,0 +1,54 @@
+package store
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// File event origins.
+const (
+	OriginUser = ""    // an edit, by a person or an AI tool
+	OriginGit  = "git" // a file rewritten by a git checkout, rebase or merge
+)
+
+// MarkGitOperationEvents handles the file events in projects recorded at or
+// after since, once a git operation that started at since is detected:
+// they are tagged OriginGit, or deleted when drop is set. Attributions
+// already made for them are removed, since they classified git's rewrite
+// as an edit. It returns the number of file events changed.
+func (s *Store) MarkGitOperationEvents(projects []string, since time.Time, drop bool) (int64, error) {
+	if len(projects) == 0 {
+		return 0, nil
+	}
+
+	args := []interface{}{since.UTC().Format(time.RFC3339Nano)}
+	for _, p := range projects {
+		args = append(args, p)
+	}
+	match := `timestamp >= ? AND origin = '' AND project_path IN (?` + strings.Repeat(", ?", len(projects)-1) + `)`
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	if _, err := tx.Exec(`DELETE FROM attributions WHERE file_event_id IN (SELECT id FROM file_events WHERE `+match+`)`, args...); err != nil {
+		return 0, fmt.Errorf("delete attributions: %w", err)
+	}
+
+	query := `UPDATE file_events SET origin = '` + OriginGit + `' WHERE ` + match
+	if drop {
+		query = `DELETE FROM file_events WHERE ` + match
+	}
+	res, err := tx.Exec(query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("mark file events: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return n, tx.Commit()
+}
@@ -0,0 +1,50 @@
+package store
+
+import (
+	"fmt"
+	"time"
+)
+
+// FileEventStats summarizes the file events recorded for one project.
+type FileEventStats struct {
+	Events      int64
+	LastEventAt time.Time
+}
+
+// QueryFileEventStatsByProject returns the number of file events and the
+// time of the latest one for each project path.
+func (s *Store) QueryFileEventStatsByProject() (map[string]FileEventStats, error) {
+	rows, err := s.db.Query(
+		`SELECT project_path, COUNT(*), MAX(timestamp)
+		 FROM file_events
+		 GROUP BY project_path`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stats := make(map[string]FileEventStats)
+	for rows.Next() {
+		var project, last string
+		var st FileEventStats
+		if err := rows.Scan(&project, &st.Events, &last); err != nil {
+			return nil, err
+		}
+		if st.LastEventAt, err = time.Parse(time.RFC3339Nano, last); err != nil {
+			return nil, fmt.Errorf("parse file_event timestamp %q: %w", last, err)
+		}
+		stats[project] = st
+	}
+	return stats, rows.Err()
+}
+
+// UnprocessedFileEventsCount returns the number of file events that have
+// no attribution yet: the attribution processor's backlog.
+func (s *Store) UnprocessedFileEventsCount() (int64, error) {
+	var count int64
+	err := s.db.QueryRow(
+		`SELECT COUNT(*) FROM file_events WHERE processed_at = '' AND origin = ''`,
+	).Scan(&count)
+	return count, err
+}
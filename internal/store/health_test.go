@@ -0,0 +1,53 @@
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFileEventStatsAndBacklog(t *testing.T) {
+	s := newTestStore(t)
+	base := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+
+	for i, project := range []string{"/work/api", "/work/api", "/work/web"} {
+		if err := s.InsertFileEvent(project, project+"/main.go", "write", base.Add(time.Duration(i)*time.Minute)); err != nil {
+			t.Fatalf("InsertFileEvent: %v", err)
+		}
+	}
+
+	stats, err := s.QueryFileEventStatsByProject()
+	if err != nil {
+		t.Fatalf("QueryFileEventStatsByProject: %v", err)
+	}
+	if api := stats["/work/api"]; api.Events != 2 || !api.LastEventAt.Equal(base.Add(time.Minute)) {
+		t.Errorf("/work/api stats = %+v", api)
+	}
+	if web := stats["/work/web"]; web.Events != 1 {
+		t.Errorf("/work/web stats = %+v", web)
+	}
+
+	backlog, err := s.UnprocessedFileEventsCount()
+	if err != nil {
+		t.Fatalf("UnprocessedFileEventsCount: %v", err)
+	}
+	if backlog != 3 {
+		t.Fatalf("backlog = %d, want 3", backlog)
+	}
+
+	events, err := s.QueryUnprocessedFileEvents(1)
+	if err != nil || len(events) != 1 {
+		t.Fatalf("QueryUnprocessedFileEvents: %v (%d events)", err, len(events))
+	}
+	if _, err := s.InsertAttribution(AttributionRecord{
+		FilePath:        events[0].FilePath,
+		ProjectPath:     events[0].ProjectPath,
+		FileEventID:     &events[0].ID,
+		AuthorshipLevel: "mostly_human",
+		Timestamp:       events[0].Timestamp,
+	}); err != nil {
+		t.Fatalf("InsertAttribution: %v", err)
+	}
+	if backlog, _ := s.UnprocessedFileEventsCount(); backlog != 2 {
+		t.Errorf("backlog after attributing one event = %d, want 2", backlog)
+	}
+}
@@ -0,0 +1,164 @@
+package store
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// seedLargeStore fills s with events and attributions spread over files
+// files, n of each, for query plan checks and benchmarks.
+func seedLargeStore(tb testing.TB, s *Store, n, files int) {
+	tb.Helper()
+	tx, err := s.db.Begin()
+	if err != nil {
+		tb.Fatal(err)
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	base := time.Date(2026, 2, 9, 12, 0, 0, 0, time.UTC)
+	tools := []string{"Write", "Edit", "Read", "Bash"}
+	for i := 0; i < n; i++ {
+		file := fmt.Sprintf("/work/api/pkg%d/file%d.go", i%files%10, i%files)
+		ts := base.Add(time.Duration(i) * time.Second).Format(time.RFC3339Nano)
+		content := fmt.Sprintf("func f%d() {}\n", i)
+		if _, err := tx.Exec(
+			`INSERT INTO session_events (session_id, event_type, tool_name, file_path, content_hash, timestamp, raw_json, lines_changed, diff_content, deleted_content)
+			 VALUES (?, 'tool_use', ?, ?, '', ?, '{}', 1, ?, '')`,
+			fmt.Sprintf("s%d", i/100), tools[i%len(tools)], file, ts, content,
+		); err != nil {
+			tb.Fatal(err)
+		}
+		res, err := tx.Exec(
+			`INSERT INTO file_events (project_path, file_path, event_type, timestamp) VALUES ('/work/api', ?, 'write', ?)`,
+			file, ts,
+		)
+		if err != nil {
+			tb.Fatal(err)
+		}
+		if i%4 == 3 {
+			continue // leave some file events unattributed
+		}
+		feID, _ := res.LastInsertId()
+		if _, err := tx.Exec(`UPDATE file_events SET processed_at = ? WHERE id = ?`, ts, feID); err != nil {
+			tb.Fatal(err)
+		}
+		if _, err := tx.Exec(
+			`INSERT INTO attributions (file_path, project_path, file_event_id, authorship_level, confidence, timestamp, created_at, lines_changed)
+			 VALUES (?, '/work/api', ?, 'mostly_ai', 0.9, ?, ?, 1)`,
+			file, feID, ts, ts,
+		); err != nil {
+			tb.Fatal(err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		tb.Fatal(err)
+	}
+	if _, err := s.db.Exec(`ANALYZE`); err != nil {
+		tb.Fatal(err)
+	}
+}
+
+// TestQueryPlansUseIndexes checks that the filters of the hot per-file and
+// per-project queries are answered from an index rather than a table scan.
+func TestQueryPlansUseIndexes(t *testing.T) {
+	s := newTestStore(t)
+	seedLargeStore(t, s, 2000, 50)
+
+	ts := "2026-02-09T12:00:00Z"
+	queries := []struct {
+		name  string
+		query string
+		args  []interface{}
+	}{
+		{"session events of a file", `SELECT id FROM session_events WHERE file_path = ? AND tool_name IN ('Write', 'Edit') AND timestamp >= ? AND timestamp <= ?`, []interface{}{"f", ts, ts}},
+		{"session events near a time", `SELECT id FROM session_events WHERE tool_name IN ('Write', 'Edit') AND timestamp >= ? AND timestamp <= ?`, []interface{}{ts, ts}},
+		{"file events of a file", `SELECT id FROM file_events WHERE file_path = ? AND timestamp >= ? AND timestamp <= ? AND origin = ''`, []interface{}{"f", ts, ts}},
+		{"file events of a project", `SELECT id FROM file_events WHERE project_path = ? AND timestamp >= ? AND origin = ''`, []interface{}{"p", ts}},
+		{"unprocessed file events", `SELECT id FROM file_events WHERE processed_at = '' AND origin = '' ORDER BY priority DESC, timestamp DESC LIMIT 100`, nil},
+		{"attributions of a file", `SELECT id FROM attributions WHERE file_path = ? AND kind = 'addition' ORDER BY timestamp ASC`, []interface{}{"f"}},
+		{"attributions of a project", `SELECT id FROM attributions WHERE project_path = ? AND kind = 'addition' ORDER BY timestamp ASC`, []interface{}{"p"}},
+		{"attribution of a file event", `SELECT id FROM attributions WHERE file_event_id = ?`, []interface{}{1}},
+		{"attribution of a session event", `SELECT id FROM attributions WHERE session_event_id = ?`, []interface{}{1}},
+	}
+	for _, q := range queries {
+		rows, err := s.db.Query(`EXPLAIN QUERY PLAN `+q.query, q.args...)
+		if err != nil {
+			t.Fatalf("%s: %v", q.name, err)
+		}
+		for rows.Next() {
+			var id, parent, unused int
+			var detail string
+			if err := rows.Scan(&id, &parent, &unused, &detail); err != nil {
+				t.Fatal(err)
+			}
+			if strings.HasPrefix(detail, "SCAN ") && !strings.Contains(detail, " USING ") {
+				t.Errorf("%s: plan scans the table: %s", q.name, detail)
+			}
+		}
+		rows.Close()
+	}
+}
+
+// BenchmarkQueries measures the queries report generation and the
+// attribution processor run most, over 20k events of each kind.
+func BenchmarkQueries(b *testing.B) {
+	s := newTestStore(b)
+	seedLargeStore(b, s, 20000, 500)
+	file := "/work/api/pkg7/file7.go"
+	start := time.Date(2026, 2, 9, 12, 0, 0, 0, time.UTC)
+	end := start.Add(6 * time.Hour)
+
+	b.Run("SessionEventsInWindow", func(b *testing.B) {
+		for b.Loop() {
+			if _, err := s.QuerySessionEventsInWindow(file, start, end); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("FileEventsInWindow", func(b *testing.B) {
+		for b.Loop() {
+			if _, err := s.QueryFileEventsInWindow(file, start, end); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("AttributionsByFile", func(b *testing.B) {
+		for b.Loop() {
+			if _, err := s.QueryAttributionsByFile(file); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("UnprocessedFileEvents", func(b *testing.B) {
+		for b.Loop() {
+			if _, err := s.QueryUnprocessedFileEvents(100); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("WriteEditContentPerEvent", func(b *testing.B) {
+		for b.Loop() {
+			events, err := s.QueryWriteEditSessionEvents()
+			if err != nil {
+				b.Fatal(err)
+			}
+			for _, se := range events {
+				if _, err := s.QuerySessionEventContent(se.ID, nil); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	})
+	b.Run("WriteEditContentJoined", func(b *testing.B) {
+		for b.Loop() {
+			if _, err := s.QueryWriteEditSessionEvents(); err != nil {
+				b.Fatal(err)
+			}
+			if _, err := s.QueryWriteEditSessionContent(nil); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
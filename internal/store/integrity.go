@@ -0,0 +1,200 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"modernc.org/sqlite"
+)
+
+// ErrCorrupt is returned (wrapped) for a database SQLite cannot read.
+var ErrCorrupt = errors.New("database is corrupt")
+
+// SQLite result codes for a damaged or foreign file.
+const (
+	sqliteCorrupt = 11 // SQLITE_CORRUPT
+	sqliteNotADB  = 26 // SQLITE_NOTADB
+)
+
+// backupTimeFormat stamps backup files; it sorts in time order.
+const backupTimeFormat = "20060102T150405"
+
+// isCorruption reports whether err is SQLite reporting a damaged file.
+func isCorruption(err error) bool {
+	var se *sqlite.Error
+	if errors.As(err, &se) {
+		code := se.Code() & 0xff
+		return code == sqliteCorrupt || code == sqliteNotADB
+	}
+	return false
+}
+
+// CheckIntegrity runs PRAGMA integrity_check on the database at dbPath and
+// returns an error wrapping ErrCorrupt with SQLite's findings if it fails.
+// A missing database is fine: it will be created.
+func CheckIntegrity(dbPath string) error {
+	if _, err := os.Stat(dbPath); errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	db, err := sql.Open("sqlite", "file:"+dbPath)
+	if err != nil {
+		return fmt.Errorf("open database: %w", err)
+	}
+	defer db.Close()
+
+	rows, err := db.Query(`PRAGMA integrity_check`)
+	if err != nil {
+		if isCorruption(err) {
+			return fmt.Errorf("%w: %v", ErrCorrupt, err)
+		}
+		return fmt.Errorf("integrity check: %w", err)
+	}
+	defer rows.Close()
+
+	var problems []string
+	for rows.Next() {
+		var msg string
+		if err := rows.Scan(&msg); err != nil {
+			return fmt.Errorf("integrity check: %w", err)
+		}
+		if msg != "ok" {
+			problems = append(problems, msg)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		if isCorruption(err) {
+			return fmt.Errorf("%w: %v", ErrCorrupt, err)
+		}
+		return fmt.Errorf("integrity check: %w", err)
+	}
+	if len(problems) > 0 {
+		if len(problems) > 3 {
+			problems = append(problems[:3], fmt.Sprintf("and %d more", len(problems)-3))
+		}
+		return fmt.Errorf("%w: %s", ErrCorrupt, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// Backups returns the backups of the database at dbPath, newest first:
+// the <db>.v<version>.<timestamp>.bak files written before migrations and
+// by BackupIfStale.
+func Backups(dbPath string) ([]string, error) {
+	matches, err := filepath.Glob(dbPath + ".v*.bak")
+	if err != nil {
+		return nil, err
+	}
+	stamp := func(path string) string {
+		name := strings.TrimSuffix(strings.TrimPrefix(path, dbPath+".v"), ".bak")
+		_, ts, _ := strings.Cut(name, ".")
+		return ts
+	}
+	sort.Slice(matches, func(i, j int) bool { return stamp(matches[i]) > stamp(matches[j]) })
+	return matches, nil
+}
+
+// RecoverCorrupt checks the database at dbPath and, if it is corrupt,
+// moves it aside as <db>.corrupt.<timestamp> and puts the newest backup
+// that passes the check in its place. It returns the backup used, or ""
+// if the database was healthy. With no usable backup the database is left
+// alone and the corruption error is returned.
+func RecoverCorrupt(dbPath string) (string, error) {
+	checkErr := CheckIntegrity(dbPath)
+	if checkErr == nil || !errors.Is(checkErr, ErrCorrupt) {
+		return "", checkErr
+	}
+
+	backups, err := Backups(dbPath)
+	if err != nil {
+		return "", err
+	}
+	for _, backup := range backups {
+		if CheckIntegrity(backup) != nil {
+			continue
+		}
+		if _, err := Quarantine(dbPath); err != nil {
+			return "", fmt.Errorf("move corrupt database aside: %w", err)
+		}
+		if err := copyFile(backup, dbPath); err != nil {
+			return "", fmt.Errorf("restore %s: %w", backup, err)
+		}
+		return backup, nil
+	}
+	return "", fmt.Errorf("%w; no usable backup, run \"gapmap repair\" to salvage it", checkErr)
+}
+
+// Quarantine moves the database at dbPath, with its WAL and shared memory
+// files, aside to <db>.corrupt.<timestamp> and returns that path.
+func Quarantine(dbPath string) (string, error) {
+	aside := dbPath + ".corrupt." + time.Now().UTC().Format(backupTimeFormat)
+	if err := os.Rename(dbPath, aside); err != nil {
+		return "", err
+	}
+	for _, suffix := range []string{"-wal", "-shm"} {
+		if err := os.Rename(dbPath+suffix, aside+suffix); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return aside, err
+		}
+	}
+	return aside, nil
+}
+
+// BackupIfStale writes a backup of the database, as a migration does,
+// unless one is younger than maxAge, and then removes all but the keep
+// newest backups (keep <= 0 keeps them all). It returns the new backup's
+// path, or "" if none was needed.
+func (s *Store) BackupIfStale(maxAge time.Duration, keep int) (string, error) {
+	backups, err := Backups(s.path)
+	if err != nil {
+		return "", err
+	}
+	if len(backups) > 0 {
+		if info, err := os.Stat(backups[0]); err == nil && time.Since(info.ModTime()) < maxAge {
+			return "", nil
+		}
+	}
+
+	version, err := currentVersion(s.db)
+	if err != nil {
+		return "", fmt.Errorf("read schema version: %w", err)
+	}
+	m := &Migrator{db: s.db, path: s.path}
+	path, err := m.Backup(version)
+	if err != nil {
+		return "", err
+	}
+
+	if keep > 0 {
+		backups, err = Backups(s.path)
+		if err != nil {
+			return path, err
+		}
+		for _, old := range backups[min(keep, len(backups)):] {
+			_ = os.Remove(old)
+		}
+	}
+	return path, nil
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
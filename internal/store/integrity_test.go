@@ -0,0 +1,154 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// seedStore writes n file events to a new database at path and closes it.
+func seedStore(t *testing.T, path string, n int) {
+	t.Helper()
+	s, err := New(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < n; i++ {
+		file := filepath.Join("/proj", strings.Repeat("dir/", 10), "file", string(rune('a'+i%26))+".go")
+		if err := s.InsertFileEvent("/proj", file, "write", time.Now()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// corruptPage overwrites page n (1-based, 4 KB pages) of the file at path.
+func corruptPage(t *testing.T, path string, n int) {
+	t.Helper()
+	f, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.WriteAt([]byte(strings.Repeat("\xde\xad\xbe\xef", 1024)), int64(n-1)*4096); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCheckIntegrityAndRecover(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gapmap.db")
+	if err := CheckIntegrity(path); err != nil {
+		t.Fatalf("missing database: %v", err)
+	}
+	seedStore(t, path, 10)
+	if err := CheckIntegrity(path); err != nil {
+		t.Fatalf("healthy database: %v", err)
+	}
+
+	s, err := New(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	backup, err := s.BackupIfStale(time.Hour, 2)
+	if err != nil || backup == "" {
+		t.Fatalf("BackupIfStale = %q, %v; want a backup", backup, err)
+	}
+	if again, err := s.BackupIfStale(time.Hour, 2); err != nil || again != "" {
+		t.Errorf("second BackupIfStale = %q, %v; want none within maxAge", again, err)
+	}
+	s.Close()
+
+	corruptPage(t, path, 1)
+	if err := CheckIntegrity(path); !errors.Is(err, ErrCorrupt) {
+		t.Fatalf("CheckIntegrity = %v, want ErrCorrupt", err)
+	}
+	if _, err := New(path); !errors.Is(err, ErrCorrupt) {
+		t.Errorf("New = %v, want ErrCorrupt", err)
+	}
+
+	restored, err := RecoverCorrupt(path)
+	if err != nil || restored != backup {
+		t.Fatalf("RecoverCorrupt = %q, %v; want %q", restored, err, backup)
+	}
+	s, err = New(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if n, _ := s.FileEventsCount(); n != 10 {
+		t.Errorf("restored %d file events, want 10", n)
+	}
+	if aside, _ := filepath.Glob(path + ".corrupt.*"); len(aside) != 1 {
+		t.Errorf("corrupt database not kept aside: %v", aside)
+	}
+}
+
+func TestRecoverCorruptWithoutBackup(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gapmap.db")
+	seedStore(t, path, 1)
+	corruptPage(t, path, 1)
+	if _, err := RecoverCorrupt(path); !errors.Is(err, ErrCorrupt) || !strings.Contains(err.Error(), "gapmap repair") {
+		t.Errorf("RecoverCorrupt = %v, want ErrCorrupt pointing at gapmap repair", err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("database moved without a backup to replace it: %v", err)
+	}
+}
+
+func TestRepair(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "gapmap.db")
+	seedStore(t, src, 2000)
+	healthy, err := os.ReadFile(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Damage pages from the middle of the file until one holds file_events
+	// rows (others hold index entries, which Repair does not need).
+	pages := len(healthy) / 4096
+	for p := pages / 3; p < pages; p++ {
+		if err := os.WriteFile(src, healthy, 0644); err != nil {
+			t.Fatal(err)
+		}
+		corruptPage(t, src, p)
+		dst := filepath.Join(dir, fmt.Sprintf("repaired-%d.db", p))
+		res, err := Repair(context.Background(), src, dst)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var events TableRepair
+		for _, tr := range res.Tables {
+			if tr.Table == "file_events" {
+				events = tr
+			}
+		}
+		if events.Lost == 0 {
+			continue
+		}
+
+		if events.Copied == 0 || events.Copied+events.Lost != 2000 || res.Lost() != events.Lost {
+			t.Errorf("page %d: repair = %+v, want the other rows of 2000 copied", p, res.Tables)
+		}
+		if err := CheckIntegrity(dst); err != nil {
+			t.Errorf("repaired database: %v", err)
+		}
+		s, err := New(dst)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer s.Close()
+		if n, _ := s.FileEventsCount(); n != events.Copied {
+			t.Errorf("repaired database has %d file events, want %d", n, events.Copied)
+		}
+		return
+	}
+	t.Fatal("no damaged page lost file_events rows")
+}
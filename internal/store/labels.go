@@ -0,0 +1,73 @@
+package store
+
+import (
+	"fmt"
+	"time"
+)
+
+// LineLabel is a range of lines in a file that the user says AI or a human
+// wrote: ground truth to check computed attribution against.
+type LineLabel struct {
+	ID          int64
+	ProjectPath string
+	FilePath    string // absolute
+	StartLine   int
+	EndLine     int    // inclusive
+	Label       string // LabelAI or LabelHuman
+	CreatedAt   time.Time
+}
+
+// InsertLineLabel stores a line label. Labels do not replace earlier ones;
+// where ranges overlap, the newest label wins.
+func (s *Store) InsertLineLabel(l LineLabel) (int64, error) {
+	if l.Label != LabelAI && l.Label != LabelHuman {
+		return 0, fmt.Errorf("unknown label %q (want %s or %s)", l.Label, LabelAI, LabelHuman)
+	}
+	if l.StartLine < 1 || l.EndLine < l.StartLine {
+		return 0, fmt.Errorf("invalid line range %d-%d", l.StartLine, l.EndLine)
+	}
+	if l.CreatedAt.IsZero() {
+		l.CreatedAt = time.Now()
+	}
+	res, err := s.db.Exec(
+		`INSERT INTO line_labels (project_path, file_path, start_line, end_line, label, created_at)
+		 VALUES (?, ?, ?, ?, ?, ?)`,
+		l.ProjectPath, l.FilePath, l.StartLine, l.EndLine, l.Label,
+		l.CreatedAt.UTC().Format(time.RFC3339Nano),
+	)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// QueryLineLabels returns the line labels of a project, oldest first, so
+// applying them in order leaves the newest label on each line.
+func (s *Store) QueryLineLabels(projectPath string) ([]LineLabel, error) {
+	rows, err := s.db.Query(
+		`SELECT id, project_path, file_path, start_line, end_line, label, created_at
+		 FROM line_labels WHERE project_path = ?
+		 ORDER BY id ASC`,
+		projectPath,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var labels []LineLabel
+	for rows.Next() {
+		var l LineLabel
+		var created string
+		if err := rows.Scan(&l.ID, &l.ProjectPath, &l.FilePath, &l.StartLine, &l.EndLine, &l.Label, &created); err != nil {
+			return nil, err
+		}
+		t, err := time.Parse(time.RFC3339Nano, created)
+		if err != nil {
+			return nil, fmt.Errorf("parse label time %q: %w", created, err)
+		}
+		l.CreatedAt = t
+		labels = append(labels, l)
+	}
+	return labels, rows.Err()
+}
@@ -0,0 +1,126 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// lastMaintenanceState is the daemon_state key holding when Maintain last
+// finished, RFC 3339.
+const lastMaintenanceState = "last_maintenance_at"
+
+// MaintenanceResult is what one Maintain run did.
+type MaintenanceResult struct {
+	BlameFilesPruned int   // files whose blame lines were removed
+	PagesFreed       int64 // free pages returned to the file system
+	Took             time.Duration
+}
+
+// Maintain prunes the blame lines of files exists reports gone, refreshes
+// the query planner's statistics (ANALYZE, PRAGMA optimize) and returns
+// free pages to the file system with an incremental vacuum. The first run
+// on a database created without incremental auto-vacuum turns it on,
+// which takes one full VACUUM. The finish time is recorded for
+// LastMaintenance.
+func (s *Store) Maintain(ctx context.Context, exists func(filePath string) bool) (MaintenanceResult, error) {
+	start := time.Now()
+	var res MaintenanceResult
+
+	pruned, err := s.pruneBlameLines(ctx, exists)
+	if err != nil {
+		return res, fmt.Errorf("prune blame lines: %w", err)
+	}
+	res.BlameFilesPruned = pruned
+
+	// PRAGMA auto_vacuum only takes effect through a VACUUM on the same
+	// connection, so everything below runs on one.
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return res, err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `ANALYZE`); err != nil {
+		return res, fmt.Errorf("analyze: %w", err)
+	}
+	if _, err := conn.ExecContext(ctx, `PRAGMA optimize`); err != nil {
+		return res, fmt.Errorf("optimize: %w", err)
+	}
+
+	var mode int
+	if err := conn.QueryRowContext(ctx, `PRAGMA auto_vacuum`).Scan(&mode); err != nil {
+		return res, fmt.Errorf("read auto_vacuum: %w", err)
+	}
+	var before int64
+	if err := conn.QueryRowContext(ctx, `PRAGMA freelist_count`).Scan(&before); err != nil {
+		return res, fmt.Errorf("read freelist_count: %w", err)
+	}
+	if mode != 2 { // not INCREMENTAL
+		if _, err := conn.ExecContext(ctx, `PRAGMA auto_vacuum = INCREMENTAL`); err != nil {
+			return res, fmt.Errorf("enable incremental vacuum: %w", err)
+		}
+		if _, err := conn.ExecContext(ctx, `VACUUM`); err != nil {
+			return res, fmt.Errorf("vacuum: %w", err)
+		}
+	} else if _, err := conn.ExecContext(ctx, `PRAGMA incremental_vacuum`); err != nil {
+		return res, fmt.Errorf("incremental vacuum: %w", err)
+	}
+	var after int64
+	if err := conn.QueryRowContext(ctx, `PRAGMA freelist_count`).Scan(&after); err != nil {
+		return res, fmt.Errorf("read freelist_count: %w", err)
+	}
+	res.PagesFreed = max(before-after, 0)
+
+	res.Took = time.Since(start)
+	if err := s.SetDaemonState(lastMaintenanceState, time.Now().UTC().Format(time.RFC3339)); err != nil {
+		return res, fmt.Errorf("record maintenance: %w", err)
+	}
+	return res, nil
+}
+
+// LastMaintenance returns when Maintain last finished, or the zero time
+// if it never has.
+func (s *Store) LastMaintenance() (time.Time, error) {
+	v, err := s.GetDaemonState(lastMaintenanceState)
+	if err != nil || v == "" {
+		return time.Time{}, err
+	}
+	return time.Parse(time.RFC3339, v)
+}
+
+// pruneBlameLines deletes the blame lines of every file exists reports
+// gone and returns how many files it pruned.
+func (s *Store) pruneBlameLines(ctx context.Context, exists func(filePath string) bool) (int, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT DISTINCT file_path FROM git_blame_lines`)
+	if err != nil {
+		return 0, err
+	}
+	var gone []string
+	for rows.Next() {
+		var path string
+		if err := rows.Scan(&path); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		if !exists(path) {
+			gone = append(gone, path)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback() //nolint:errcheck
+	for _, path := range gone {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM git_blame_lines WHERE file_path = ?`, path); err != nil {
+			return 0, err
+		}
+	}
+	return len(gone), tx.Commit()
+}
@@ -0,0 +1,46 @@
+package store
+
+import (
+	"context"
+	"testing"
+)
+
+func TestMaintain(t *testing.T) {
+	s := newTestStore(t)
+
+	if last, err := s.LastMaintenance(); err != nil || !last.IsZero() {
+		t.Fatalf("LastMaintenance before any run = %v, %v; want zero", last, err)
+	}
+	for _, file := range []string{"kept.go", "deleted.go"} {
+		if err := s.InsertBlameLines(file, []BlameLine{{LineNumber: 1, CommitHash: "abc", Author: "dev"}}); err != nil {
+			t.Fatalf("InsertBlameLines: %v", err)
+		}
+	}
+
+	res, err := s.Maintain(context.Background(), func(path string) bool { return path == "kept.go" })
+	if err != nil {
+		t.Fatalf("Maintain: %v", err)
+	}
+	if res.BlameFilesPruned != 1 {
+		t.Errorf("BlameFilesPruned = %d, want 1", res.BlameFilesPruned)
+	}
+	if lines, _ := s.QueryBlameLinesByFile("deleted.go"); len(lines) != 0 {
+		t.Errorf("deleted.go still has %d blame lines", len(lines))
+	}
+	if lines, _ := s.QueryBlameLinesByFile("kept.go"); len(lines) != 1 {
+		t.Errorf("kept.go has %d blame lines, want 1", len(lines))
+	}
+
+	var mode int
+	if err := s.db.QueryRow(`PRAGMA auto_vacuum`).Scan(&mode); err != nil || mode != 2 {
+		t.Errorf("auto_vacuum = %d, %v; want 2 (incremental)", mode, err)
+	}
+	if last, err := s.LastMaintenance(); err != nil || last.IsZero() {
+		t.Errorf("LastMaintenance = %v, %v; want the run's time", last, err)
+	}
+
+	// Later runs vacuum incrementally.
+	if _, err := s.Maintain(context.Background(), func(string) bool { return true }); err != nil {
+		t.Fatalf("second Maintain: %v", err)
+	}
+}
@@ -3,14 +3,198 @@ package store
 import (
 	"database/sql"
 	"fmt"
+	"sort"
 	"strconv"
 	"time"
 )
 
-// runMigrations applies all pending schema migrations.
-// It reads the current version from daemon_state and applies each
-// subsequent migration up to schemaVersion.
-func runMigrations(db *sql.DB) error {
+// LatestVersion returns the schema version this build migrates to.
+func LatestVersion() int {
+	return schemaVersion
+}
+
+// MigrationStatus describes one known migration and whether it has been
+// applied to a database.
+type MigrationStatus struct {
+	Version   int
+	Applied   bool
+	AppliedAt time.Time // zero if not applied or applied before tracking began
+}
+
+// MigrationStep is a single migration that Migrate would run.
+type MigrationStep struct {
+	Version int
+	Down    bool // true when reverting Version, false when applying it
+	SQL     string
+}
+
+// Migrator inspects and changes the schema of a database explicitly,
+// without the automatic upgrade that New performs.
+type Migrator struct {
+	db   *sql.DB
+	path string
+}
+
+// OpenMigrator opens the database at dbPath for schema inspection.
+// Pending migrations are not applied.
+func OpenMigrator(dbPath string) (*Migrator, error) {
+	db, err := open(dbPath)
+	if err != nil {
+		return nil, corruptionHint(dbPath, err)
+	}
+	if err := ensureMigrationTables(db); err != nil {
+		_ = db.Close()
+		return nil, corruptionHint(dbPath, err)
+	}
+	return &Migrator{db: db, path: dbPath}, nil
+}
+
+// Close closes the underlying database connection.
+func (m *Migrator) Close() error {
+	return m.db.Close()
+}
+
+// Version returns the highest applied schema version (0 for a new database).
+func (m *Migrator) Version() (int, error) {
+	return currentVersion(m.db)
+}
+
+// Status returns every migration known to this build, in version order,
+// with its applied state.
+func (m *Migrator) Status() ([]MigrationStatus, error) {
+	rows, err := m.db.Query(`SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int]time.Time)
+	for rows.Next() {
+		var v int
+		var at string
+		if err := rows.Scan(&v, &at); err != nil {
+			return nil, err
+		}
+		t, _ := time.Parse(time.RFC3339, at)
+		applied[v] = t
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	versions := make([]int, 0, len(migrations))
+	for v := range migrations {
+		versions = append(versions, v)
+	}
+	sort.Ints(versions)
+
+	statuses := make([]MigrationStatus, 0, len(versions))
+	for _, v := range versions {
+		at, ok := applied[v]
+		statuses = append(statuses, MigrationStatus{Version: v, Applied: ok, AppliedAt: at})
+	}
+	return statuses, nil
+}
+
+// Plan returns the steps needed to move the schema to target, in the order
+// they would run. An empty plan means the schema is already at target.
+func (m *Migrator) Plan(target int) ([]MigrationStep, error) {
+	if target < 0 || target > schemaVersion {
+		return nil, fmt.Errorf("target version %d out of range (0-%d)", target, schemaVersion)
+	}
+	current, err := currentVersion(m.db)
+	if err != nil {
+		return nil, fmt.Errorf("read schema version: %w", err)
+	}
+	if current > schemaVersion {
+		return nil, fmt.Errorf("database schema version %d is newer than this build (%d)", current, schemaVersion)
+	}
+
+	var steps []MigrationStep
+	for v := current + 1; v <= target; v++ {
+		sql, ok := migrations[v]
+		if !ok {
+			return nil, fmt.Errorf("missing migration for version %d", v)
+		}
+		steps = append(steps, MigrationStep{Version: v, SQL: sql})
+	}
+	for v := current; v > target; v-- {
+		sql, ok := downMigrations[v]
+		if !ok {
+			return nil, fmt.Errorf("missing down migration for version %d", v)
+		}
+		steps = append(steps, MigrationStep{Version: v, Down: true, SQL: sql})
+	}
+	return steps, nil
+}
+
+// Migrate moves the schema to target, applying or reverting migrations one
+// transaction at a time. If any step will run against an existing database,
+// a backup is written next to it first; its path is returned ("" if no
+// backup was needed).
+func (m *Migrator) Migrate(target int) (string, error) {
+	steps, err := m.Plan(target)
+	if err != nil {
+		return "", err
+	}
+	if len(steps) == 0 {
+		return "", nil
+	}
+
+	current, err := currentVersion(m.db)
+	if err != nil {
+		return "", fmt.Errorf("read schema version: %w", err)
+	}
+	var backup string
+	if current > 0 {
+		backup, err = m.Backup(current)
+		if err != nil {
+			return "", fmt.Errorf("backup before migration: %w", err)
+		}
+	}
+
+	for _, step := range steps {
+		if err := applyStep(m.db, step); err != nil {
+			if backup != "" {
+				return backup, fmt.Errorf("%w (backup at %s)", err, backup)
+			}
+			return "", err
+		}
+	}
+	return backup, nil
+}
+
+// Backup writes a consistent copy of the database to
+// <db>.v<version>.<timestamp>.bak and returns its path.
+func (m *Migrator) Backup(version int) (string, error) {
+	path := fmt.Sprintf("%s.v%d.%s.bak", m.path, version, time.Now().UTC().Format(backupTimeFormat))
+	if _, err := m.db.Exec(`VACUUM INTO ?`, path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// runMigrations applies all pending schema migrations, backing up an
+// existing database first.
+func runMigrations(db *sql.DB, dbPath string) error {
+	if err := ensureMigrationTables(db); err != nil {
+		return err
+	}
+	m := &Migrator{db: db, path: dbPath}
+	current, err := m.Version()
+	if err != nil {
+		return fmt.Errorf("read schema version: %w", err)
+	}
+	if current >= schemaVersion {
+		return nil
+	}
+	_, err = m.Migrate(schemaVersion)
+	return err
+}
+
+// ensureMigrationTables creates daemon_state and schema_migrations, and
+// backfills schema_migrations for databases migrated before it existed.
+func ensureMigrationTables(db *sql.DB) error {
 	// Ensure daemon_state table exists so we can read the schema version.
 	// This is idempotent because of IF NOT EXISTS.
 	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS daemon_state (
@@ -22,56 +206,97 @@ func runMigrations(db *sql.DB) error {
 		return fmt.Errorf("create daemon_state: %w", err)
 	}
 
-	current, err := currentVersion(db)
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    INTEGER PRIMARY KEY,
+		applied_at TEXT NOT NULL
+	)`)
 	if err != nil {
-		return fmt.Errorf("read schema version: %w", err)
+		return fmt.Errorf("create schema_migrations: %w", err)
 	}
 
-	for v := current + 1; v <= schemaVersion; v++ {
-		sql, ok := migrations[v]
-		if !ok {
-			return fmt.Errorf("missing migration for version %d", v)
-		}
+	var tracked int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM schema_migrations`).Scan(&tracked); err != nil {
+		return fmt.Errorf("read schema_migrations: %w", err)
+	}
+	if tracked > 0 {
+		return nil
+	}
 
-		tx, err := db.Begin()
-		if err != nil {
-			return fmt.Errorf("begin migration %d: %w", v, err)
+	// Databases from before schema_migrations only recorded the latest
+	// version in daemon_state. Individual apply times are unknown.
+	var val string
+	err = db.QueryRow(`SELECT value FROM daemon_state WHERE key = 'schema_version'`).Scan(&val)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("read schema version: %w", err)
+	}
+	legacy, err := strconv.Atoi(val)
+	if err != nil {
+		return fmt.Errorf("parse schema version %q: %w", val, err)
+	}
+	for v := 1; v <= legacy; v++ {
+		if _, err := db.Exec(`INSERT INTO schema_migrations (version, applied_at) VALUES (?, '')`, v); err != nil {
+			return fmt.Errorf("backfill schema_migrations: %w", err)
 		}
+	}
+	return nil
+}
 
-		if _, err := tx.Exec(sql); err != nil {
-			_ = tx.Rollback()
-			return fmt.Errorf("migration %d: %w", v, err)
-		}
+// applyStep runs one migration step and records the resulting version in a
+// single transaction.
+func applyStep(db *sql.DB, step MigrationStep) error {
+	verb := "migration"
+	if step.Down {
+		verb = "down migration"
+	}
 
-		now := time.Now().UTC().Format(time.RFC3339)
-		_, err = tx.Exec(
-			`INSERT INTO daemon_state (key, value, updated_at) VALUES ('schema_version', ?, ?)
-			 ON CONFLICT(key) DO UPDATE SET value = excluded.value, updated_at = excluded.updated_at`,
-			strconv.Itoa(v), now,
-		)
-		if err != nil {
-			_ = tx.Rollback()
-			return fmt.Errorf("update schema version to %d: %w", v, err)
-		}
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("begin %s %d: %w", verb, step.Version, err)
+	}
 
-		if err := tx.Commit(); err != nil {
-			return fmt.Errorf("commit migration %d: %w", v, err)
-		}
+	if _, err := tx.Exec(step.SQL); err != nil {
+		_ = tx.Rollback()
+		return fmt.Errorf("%s %d: %w", verb, step.Version, err)
 	}
 
+	now := time.Now().UTC().Format(time.RFC3339)
+	newVersion := step.Version
+	if step.Down {
+		_, err = tx.Exec(`DELETE FROM schema_migrations WHERE version = ?`, step.Version)
+		newVersion = step.Version - 1
+	} else {
+		_, err = tx.Exec(`INSERT OR REPLACE INTO schema_migrations (version, applied_at) VALUES (?, ?)`, step.Version, now)
+	}
+	if err != nil {
+		_ = tx.Rollback()
+		return fmt.Errorf("record %s %d: %w", verb, step.Version, err)
+	}
+
+	// daemon_state.schema_version is kept in sync for older builds.
+	_, err = tx.Exec(
+		`INSERT INTO daemon_state (key, value, updated_at) VALUES ('schema_version', ?, ?)
+		 ON CONFLICT(key) DO UPDATE SET value = excluded.value, updated_at = excluded.updated_at`,
+		strconv.Itoa(newVersion), now,
+	)
+	if err != nil {
+		_ = tx.Rollback()
+		return fmt.Errorf("update schema version to %d: %w", newVersion, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit %s %d: %w", verb, step.Version, err)
+	}
 	return nil
 }
 
-// currentVersion reads the schema version from daemon_state.
-// Returns 0 if no version is recorded yet.
+// currentVersion returns the highest applied migration version.
 func currentVersion(db *sql.DB) (int, error) {
-	var val string
-	err := db.QueryRow(`SELECT value FROM daemon_state WHERE key = 'schema_version'`).Scan(&val)
-	if err == sql.ErrNoRows {
-		return 0, nil
-	}
-	if err != nil {
+	var v sql.NullInt64
+	if err := db.QueryRow(`SELECT MAX(version) FROM schema_migrations`).Scan(&v); err != nil {
 		return 0, err
 	}
-	return strconv.Atoi(val)
+	return int(v.Int64), nil
 }
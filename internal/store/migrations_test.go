@@ -0,0 +1,244 @@
+package store
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestMigrator_StatusAfterNew verifies that every migration is recorded in
+// schema_migrations once New has run.
+func TestMigrator_StatusAfterNew(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	s, err := New(dbPath)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	s.Close()
+
+	m, err := OpenMigrator(dbPath)
+	if err != nil {
+		t.Fatalf("OpenMigrator: %v", err)
+	}
+	defer m.Close()
+
+	statuses, err := m.Status()
+	if err != nil {
+		t.Fatalf("Status: %v", err)
+	}
+	if len(statuses) != LatestVersion() {
+		t.Fatalf("got %d statuses, want %d", len(statuses), LatestVersion())
+	}
+	for _, st := range statuses {
+		if !st.Applied {
+			t.Errorf("version %d not applied", st.Version)
+		}
+		if st.AppliedAt.IsZero() {
+			t.Errorf("version %d has no applied_at", st.Version)
+		}
+	}
+}
+
+// TestMigrator_DownAndUp verifies that every migration can be reverted and
+// re-applied, and that a backup is written before touching an existing DB.
+func TestMigrator_DownAndUp(t *testing.T) {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "test.db")
+	s, err := New(dbPath)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if err := s.InsertFileEvent("/p", "/p/a.go", "write", time.Now()); err != nil {
+		t.Fatalf("InsertFileEvent: %v", err)
+	}
+	s.Close()
+
+	m, err := OpenMigrator(dbPath)
+	if err != nil {
+		t.Fatalf("OpenMigrator: %v", err)
+	}
+	defer m.Close()
+
+	backup, err := m.Migrate(0)
+	if err != nil {
+		t.Fatalf("Migrate(0): %v", err)
+	}
+	if backup == "" {
+		t.Fatal("expected a backup path")
+	}
+	if _, err := os.Stat(backup); err != nil {
+		t.Fatalf("backup missing: %v", err)
+	}
+	if v, _ := m.Version(); v != 0 {
+		t.Fatalf("version after down = %d, want 0", v)
+	}
+
+	second, err := m.Migrate(LatestVersion())
+	if err != nil {
+		t.Fatalf("Migrate(latest): %v", err)
+	}
+	if second != "" {
+		t.Errorf("unexpected backup %q when migrating an empty schema", second)
+	}
+	if v, _ := m.Version(); v != LatestVersion() {
+		t.Fatalf("version after up = %d, want %d", v, LatestVersion())
+	}
+
+	// The backup still has the original data.
+	old, err := New(backup)
+	if err != nil {
+		t.Fatalf("open backup: %v", err)
+	}
+	defer old.Close()
+	events, err := old.QueryUnprocessedFileEvents(10)
+	if err != nil {
+		t.Fatalf("QueryUnprocessedFileEvents: %v", err)
+	}
+	if len(events) != 1 {
+		t.Errorf("backup has %d file events, want 1", len(events))
+	}
+}
+
+// TestMigrator_Plan verifies dry-run planning in both directions.
+func TestMigrator_Plan(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	s, err := New(dbPath)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	s.Close()
+
+	m, err := OpenMigrator(dbPath)
+	if err != nil {
+		t.Fatalf("OpenMigrator: %v", err)
+	}
+	defer m.Close()
+
+	steps, err := m.Plan(LatestVersion() - 2)
+	if err != nil {
+		t.Fatalf("Plan: %v", err)
+	}
+	if len(steps) != 2 || !steps[0].Down || steps[0].Version != LatestVersion() {
+		t.Fatalf("unexpected down plan: %+v", steps)
+	}
+
+	steps, err = m.Plan(LatestVersion())
+	if err != nil {
+		t.Fatalf("Plan: %v", err)
+	}
+	if len(steps) != 0 {
+		t.Errorf("expected empty plan at latest, got %d steps", len(steps))
+	}
+
+	if _, err := m.Plan(LatestVersion() + 1); err == nil {
+		t.Error("expected error for out-of-range target")
+	}
+}
+
+// TestMigrations_BackfillLegacyVersion verifies that a database versioned
+// only through daemon_state gets schema_migrations rows on open.
+func TestMigrations_BackfillLegacyVersion(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	s, err := New(dbPath)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if _, err := s.db.Exec(`DROP TABLE schema_migrations`); err != nil {
+		t.Fatalf("drop schema_migrations: %v", err)
+	}
+	s.Close()
+
+	s, err = New(dbPath)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer s.Close()
+
+	v, err := currentVersion(s.db)
+	if err != nil {
+		t.Fatalf("currentVersion: %v", err)
+	}
+	if v != LatestVersion() {
+		t.Errorf("version = %d, want %d", v, LatestVersion())
+	}
+}
+
+// TestMigrations_MarkAttributedFileEventsProcessed verifies that migration
+// 26 marks the file events attributed before it as processed.
+func TestMigrations_MarkAttributedFileEventsProcessed(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	s, err := New(dbPath)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	now := time.Now()
+	for _, path := range []string{"/p/a.go", "/p/b.go"} {
+		if err := s.InsertFileEvent("/p", path, "write", now); err != nil {
+			t.Fatalf("InsertFileEvent: %v", err)
+		}
+	}
+	s.Close()
+
+	m, err := OpenMigrator(dbPath)
+	if err != nil {
+		t.Fatalf("OpenMigrator: %v", err)
+	}
+	if _, err := m.Migrate(25); err != nil {
+		t.Fatalf("Migrate(25): %v", err)
+	}
+	if _, err := m.db.Exec(
+		`INSERT INTO attributions (file_path, project_path, file_event_id, authorship_level, confidence, timestamp, created_at)
+		 SELECT file_path, project_path, id, 'mostly_ai', 0.9, timestamp, timestamp FROM file_events WHERE file_path = '/p/a.go'`,
+	); err != nil {
+		t.Fatalf("insert attribution: %v", err)
+	}
+	m.Close()
+
+	s, err = New(dbPath)
+	if err != nil {
+		t.Fatalf("New (migrate up): %v", err)
+	}
+	defer s.Close()
+	events, err := s.QueryUnprocessedFileEvents(10)
+	if err != nil {
+		t.Fatalf("QueryUnprocessedFileEvents: %v", err)
+	}
+	if len(events) != 1 || events[0].FilePath != "/p/b.go" {
+		t.Errorf("unprocessed events = %+v, want only /p/b.go", events)
+	}
+}
+
+// TestOpenReadOnly verifies that OpenReadOnly neither creates nor changes a
+// database.
+func TestOpenReadOnly(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	if _, err := OpenReadOnly(dbPath); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("OpenReadOnly of a missing database = %v, want os.ErrNotExist", err)
+	}
+	if _, err := os.Stat(dbPath); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("OpenReadOnly created the database: %v", err)
+	}
+
+	s, err := New(dbPath)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if err := s.SetDaemonState("k", "v"); err != nil {
+		t.Fatalf("SetDaemonState: %v", err)
+	}
+	s.Close()
+
+	ro, err := OpenReadOnly(dbPath)
+	if err != nil {
+		t.Fatalf("OpenReadOnly: %v", err)
+	}
+	defer ro.Close()
+	if v, err := ro.GetDaemonState("k"); err != nil || v != "v" {
+		t.Errorf("GetDaemonState = %q, %v, want v", v, err)
+	}
+	if err := ro.SetDaemonState("k", "w"); err == nil {
+		t.Error("SetDaemonState on a read-only store: want error")
+	}
+}
@@ -0,0 +1,225 @@
+package store
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ProjectAlias maps a path a project was recorded under to the path its
+// data now lives under.
+type ProjectAlias struct {
+	Alias       string
+	ProjectPath string
+	CreatedAt   time.Time
+}
+
+// ProjectSummary is a project path and how much data is recorded under it.
+type ProjectSummary struct {
+	ProjectPath  string
+	FileEvents   int
+	Attributions int
+	LastEvent    time.Time // zero without file events
+}
+
+// CanonicalPath returns path with symlinks resolved, so one checkout
+// reached through a symlinked home, /var vs /private/var or a bind mount
+// is recorded under one path. A path that does not exist (a deleted file)
+// has its longest existing parent resolved. Relative paths are returned
+// cleaned but otherwise unchanged.
+func CanonicalPath(path string) string {
+	if path == "" || !filepath.IsAbs(path) {
+		return path
+	}
+	path = filepath.Clean(path)
+	rest := ""
+	for dir := path; ; dir = filepath.Dir(dir) {
+		if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+			return filepath.Join(resolved, rest)
+		} else if !os.IsNotExist(err) {
+			return path
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return path
+		}
+		rest = filepath.Join(filepath.Base(dir), rest)
+	}
+}
+
+// ResolvePath returns the path path is recorded under: the alias target
+// of the longest project alias containing it, else its canonical path
+// (with aliases applied to that in turn).
+func (s *Store) ResolvePath(path string) (string, error) {
+	if path == "" || !filepath.IsAbs(path) {
+		return path, nil
+	}
+	aliases, err := s.QueryProjectAliases()
+	if err != nil {
+		return "", err
+	}
+	path = filepath.Clean(path)
+	if mapped, ok := applyAlias(aliases, path); ok {
+		return mapped, nil
+	}
+	canonical := CanonicalPath(path)
+	if mapped, ok := applyAlias(aliases, canonical); ok {
+		return mapped, nil
+	}
+	return canonical, nil
+}
+
+// resolvePaths resolves each of paths with ResolvePath.
+func (s *Store) resolvePaths(paths ...*string) error {
+	for _, p := range paths {
+		resolved, err := s.ResolvePath(*p)
+		if err != nil {
+			return fmt.Errorf("resolve %s: %w", *p, err)
+		}
+		*p = resolved
+	}
+	return nil
+}
+
+// applyAlias rewrites path with the longest alias containing it.
+func applyAlias(aliases []ProjectAlias, path string) (string, bool) {
+	best := -1
+	for i, a := range aliases {
+		if within(a.Alias, path) && (best < 0 || len(a.Alias) > len(aliases[best].Alias)) {
+			best = i
+		}
+	}
+	if best < 0 {
+		return path, false
+	}
+	return aliases[best].ProjectPath + path[len(aliases[best].Alias):], true
+}
+
+// within reports whether path is dir or below it.
+func within(dir, path string) bool {
+	return path == dir || strings.HasPrefix(path, dir+string(filepath.Separator))
+}
+
+// QueryProjectAliases returns all project aliases, ordered by alias.
+func (s *Store) QueryProjectAliases() ([]ProjectAlias, error) {
+	rows, err := s.db.Query(`SELECT alias, project_path, created_at FROM project_aliases ORDER BY alias`)
+	if err != nil {
+		return nil, fmt.Errorf("query project aliases: %w", err)
+	}
+	defer rows.Close()
+
+	var aliases []ProjectAlias
+	for rows.Next() {
+		var a ProjectAlias
+		var created string
+		if err := rows.Scan(&a.Alias, &a.ProjectPath, &created); err != nil {
+			return nil, fmt.Errorf("scan project alias: %w", err)
+		}
+		a.CreatedAt, _ = time.Parse(time.RFC3339, created)
+		aliases = append(aliases, a)
+	}
+	return aliases, rows.Err()
+}
+
+// QueryProjects returns every project path with file events or
+// attributions, ordered by path.
+func (s *Store) QueryProjects() ([]ProjectSummary, error) {
+	rows, err := s.db.Query(
+		`SELECT project_path, SUM(events), SUM(attrs), MAX(last_event) FROM (
+		   SELECT project_path, COUNT(*) AS events, 0 AS attrs, MAX(timestamp) AS last_event
+		   FROM file_events GROUP BY project_path
+		   UNION ALL
+		   SELECT project_path, 0, COUNT(*), '' FROM attributions GROUP BY project_path
+		 )
+		 GROUP BY project_path
+		 ORDER BY project_path`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("query projects: %w", err)
+	}
+	defer rows.Close()
+
+	var projects []ProjectSummary
+	for rows.Next() {
+		var p ProjectSummary
+		var last string
+		if err := rows.Scan(&p.ProjectPath, &p.FileEvents, &p.Attributions, &last); err != nil {
+			return nil, fmt.Errorf("scan project: %w", err)
+		}
+		p.LastEvent, _ = time.Parse(time.RFC3339Nano, last)
+		projects = append(projects, p)
+	}
+	return projects, rows.Err()
+}
+
+// projectPathColumns lists the columns holding absolute project or file
+// paths that MergeProjects rewrites.
+var projectPathColumns = []struct{ table, column string }{
+	{"file_events", "project_path"},
+	{"file_events", "file_path"},
+	{"session_events", "file_path"},
+	{"attributions", "project_path"},
+	{"attributions", "file_path"},
+	{"code_survival", "project_path"},
+	{"code_survival", "file_path"},
+	{"line_labels", "project_path"},
+	{"line_labels", "file_path"},
+	{"file_snapshots", "file_path"},
+	{"typing_bursts", "file_path"},
+}
+
+// MergeProjects moves the data recorded under the path from, and the
+// files below it, to the path into, and records from as an alias of into
+// so later events recorded under from land there too. Aliases that
+// pointed at from are pointed at into. It returns the number of rows
+// changed.
+func (s *Store) MergeProjects(from, into string) (int64, error) {
+	from, into = filepath.Clean(from), filepath.Clean(into)
+	if from == into {
+		return 0, fmt.Errorf("cannot merge %s into itself", from)
+	}
+	if within(from, into) {
+		return 0, fmt.Errorf("cannot merge %s into %s, which is below it", from, into)
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("begin merge: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	var total int64
+	for _, c := range projectPathColumns {
+		res, err := tx.Exec(
+			fmt.Sprintf(`UPDATE %[1]s SET %[2]s = ? || substr(%[2]s, ?)
+			 WHERE %[2]s = ? OR substr(%[2]s, 1, ?) = ?`, c.table, c.column),
+			into, len(from)+1, from, len(from)+1, from+string(filepath.Separator),
+		)
+		if err != nil {
+			return 0, fmt.Errorf("merge %s.%s: %w", c.table, c.column, err)
+		}
+		n, _ := res.RowsAffected()
+		total += n
+	}
+
+	if _, err := tx.Exec(`UPDATE project_aliases SET project_path = ? WHERE project_path = ?`, into, from); err != nil {
+		return 0, fmt.Errorf("repoint aliases: %w", err)
+	}
+	if _, err := tx.Exec(
+		`INSERT INTO project_aliases (alias, project_path, created_at) VALUES (?, ?, ?)
+		 ON CONFLICT(alias) DO UPDATE SET project_path = excluded.project_path, created_at = excluded.created_at`,
+		from, into, time.Now().UTC().Format(time.RFC3339),
+	); err != nil {
+		return 0, fmt.Errorf("record alias: %w", err)
+	}
+	if _, err := tx.Exec(`DELETE FROM project_aliases WHERE alias = ?`, into); err != nil {
+		return 0, fmt.Errorf("drop alias of %s: %w", into, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("commit merge: %w", err)
+	}
+	return total, nil
+}
@@ -0,0 +1,108 @@
+package store
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestInsertResolvesSymlinks(t *testing.T) {
+	s := newTestStore(t)
+	target, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(t.TempDir(), "link")
+	if err := os.Symlink(target, link); err != nil {
+		t.Skipf("symlinks unsupported: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(target, "main.go"), []byte("package main\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	ts := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	// main.go exists; gone.go does not, but its directory resolves.
+	for _, name := range []string{"main.go", "gone.go"} {
+		if err := s.InsertWatchedFileEvent(link, filepath.Join(link, name), "write", ts, PriorityNormal, OriginUser); err != nil {
+			t.Fatal(err)
+		}
+	}
+	events, err := s.QueryUnprocessedFileEvents(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, fe := range events {
+		if fe.ProjectPath != target || filepath.Dir(fe.FilePath) != target {
+			t.Errorf("event stored as %s in %s, want under %s", fe.FilePath, fe.ProjectPath, target)
+		}
+	}
+}
+
+func TestMergeProjects(t *testing.T) {
+	s := newTestStore(t)
+	ts := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	if err := s.InsertFileEvent("/old/app", "/old/app/main.go", "write", ts); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.InsertFileEvent("/new/app", "/new/app/util.go", "write", ts); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.InsertSessionEvent("s1", "tool_use", "Write", "/old/app/main.go", "h", ts, `{"uuid":"1"}`, 1); err != nil {
+		t.Fatal(err)
+	}
+	// A sibling sharing the prefix must not move.
+	if err := s.InsertFileEvent("/old/application", "/old/application/x.go", "write", ts); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.MergeProjects("/old/app", "/old/app/sub"); err == nil {
+		t.Error("merging a project into a path below it should fail")
+	}
+	n, err := s.MergeProjects("/old/app", "/new/app")
+	if err != nil {
+		t.Fatalf("MergeProjects: %v", err)
+	}
+	if n != 3 {
+		t.Errorf("MergeProjects changed %d rows, want 3", n)
+	}
+
+	projects, err := s.QueryProjects()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, p := range projects {
+		got = append(got, p.ProjectPath)
+	}
+	if len(got) != 2 || got[0] != "/new/app" || got[1] != "/old/application" {
+		t.Errorf("projects = %v, want [/new/app /old/application]", got)
+	}
+	events, err := s.QueryWriteEditSessionEvents()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].FilePath != "/new/app/main.go" {
+		t.Errorf("session events = %+v, want /new/app/main.go", events)
+	}
+
+	// Events still arriving under the old path land under the new one.
+	if err := s.InsertFileEvent("/old/app", "/old/app/late.go", "write", ts); err != nil {
+		t.Fatal(err)
+	}
+	fes, err := s.QueryFileEventsByProject("/new/app", time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fes) != 3 {
+		t.Errorf("got %d events under /new/app, want 3", len(fes))
+	}
+
+	// Merging further repoints the alias.
+	if _, err := s.MergeProjects("/new/app", "/final/app"); err != nil {
+		t.Fatal(err)
+	}
+	if p, _ := s.ResolvePath("/old/app/main.go"); p != "/final/app/main.go" {
+		t.Errorf("ResolvePath(/old/app/main.go) = %s, want /final/app/main.go", p)
+	}
+}
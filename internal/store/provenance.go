@@ -0,0 +1,56 @@
+package store
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// FileActivity summarizes the attributed work on one file, for provenance
+// documents.
+type FileActivity struct {
+	FirstAt time.Time
+	LastAt  time.Time
+	Tools   []string // AI tool calls (e.g. Write, Edit) correlated with its changes, sorted
+}
+
+// QueryFileActivity returns, for each file with attributions in projectPath,
+// the time range of its attributions and the tools of the session events
+// they were correlated with.
+func (s *Store) QueryFileActivity(projectPath string) (map[string]FileActivity, error) {
+	rows, err := s.db.Query(
+		`SELECT a.file_path, MIN(a.timestamp), MAX(a.timestamp),
+		        COALESCE(GROUP_CONCAT(DISTINCT NULLIF(se.tool_name, '')), '')
+		 FROM attributions a
+		 LEFT JOIN session_events se ON se.id = a.session_event_id
+		 WHERE a.project_path = ?
+		 GROUP BY a.file_path`,
+		projectPath,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("query file activity: %w", err)
+	}
+	defer rows.Close()
+
+	activity := make(map[string]FileActivity)
+	for rows.Next() {
+		var filePath, first, last, tools string
+		if err := rows.Scan(&filePath, &first, &last, &tools); err != nil {
+			return nil, err
+		}
+		var fa FileActivity
+		if fa.FirstAt, err = time.Parse(time.RFC3339Nano, first); err != nil {
+			return nil, fmt.Errorf("parse attribution timestamp %q: %w", first, err)
+		}
+		if fa.LastAt, err = time.Parse(time.RFC3339Nano, last); err != nil {
+			return nil, fmt.Errorf("parse attribution timestamp %q: %w", last, err)
+		}
+		if tools != "" {
+			fa.Tools = strings.Split(tools, ",")
+			sort.Strings(fa.Tools)
+		}
+		activity[filePath] = fa
+	}
+	return activity, rows.Err()
+}
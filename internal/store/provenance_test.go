@@ -0,0 +1,54 @@
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+func TestQueryFileActivity(t *testing.T) {
+	s := newTestStore(t)
+	base := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+
+	var editID int64
+	for i, tool := range []string{"Write", "Edit"} {
+		if err := s.InsertSessionEvent("s1", "tool_use", tool, "/work/api/a.go", "", base, `{"tool":"`+tool+`"}`, 1); err != nil {
+			t.Fatalf("InsertSessionEvent: %v", err)
+		}
+		if err := s.db.QueryRow(`SELECT MAX(id) FROM session_events`).Scan(&editID); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.InsertAttribution(AttributionRecord{
+			FilePath:        "/work/api/a.go",
+			ProjectPath:     "/work/api",
+			SessionEventID:  &editID,
+			AuthorshipLevel: "mostly_ai",
+			Timestamp:       base.Add(time.Duration(i) * time.Hour),
+		}); err != nil {
+			t.Fatalf("InsertAttribution: %v", err)
+		}
+	}
+	// A human change with no session event.
+	if _, err := s.InsertAttribution(AttributionRecord{
+		FilePath:        "/work/api/b.go",
+		ProjectPath:     "/work/api",
+		AuthorshipLevel: "mostly_human",
+		Timestamp:       base,
+	}); err != nil {
+		t.Fatalf("InsertAttribution: %v", err)
+	}
+
+	activity, err := s.QueryFileActivity("/work/api")
+	if err != nil {
+		t.Fatalf("QueryFileActivity: %v", err)
+	}
+	a := activity["/work/api/a.go"]
+	if !a.FirstAt.Equal(base) || !a.LastAt.Equal(base.Add(time.Hour)) {
+		t.Errorf("a.go range = %v..%v", a.FirstAt, a.LastAt)
+	}
+	if len(a.Tools) != 2 || a.Tools[0] != "Edit" || a.Tools[1] != "Write" {
+		t.Errorf("a.go tools = %v, want [Edit Write]", a.Tools)
+	}
+	if b, ok := activity["/work/api/b.go"]; !ok || len(b.Tools) != 0 {
+		t.Errorf("b.go activity = %+v (found %v), want no tools", b, ok)
+	}
+}
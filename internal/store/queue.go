@@ -0,0 +1,68 @@
+package store
+
+import "time"
+
+// File event priorities. The attribution processor takes higher priorities
+// first.
+const (
+	PriorityNormal = 0
+	PriorityLow    = -1 // bulk changes such as a branch switch
+)
+
+// InsertWatchedFileEvent records a file system event with its attribution
+// priority and origin (OriginUser, or OriginGit for a change made by a git
+// checkout, rebase or merge).
+func (s *Store) InsertWatchedFileEvent(projectPath, filePath, eventType string, timestamp time.Time, priority int, origin string) error {
+	if err := s.resolvePaths(&projectPath, &filePath); err != nil {
+		return err
+	}
+	_, err := s.db.Exec(
+		`INSERT INTO file_events (project_path, file_path, event_type, timestamp, priority, origin)
+		 VALUES (?, ?, ?, ?, ?, ?)`,
+		projectPath, filePath, eventType, timestamp.UTC().Format(time.RFC3339Nano), priority, origin,
+	)
+	return err
+}
+
+// watchedEvents selects the file events the watcher records: those made
+// on this machine, not forwarded by an agent.
+const watchedEvents = `host = '' AND origin IN ('` + OriginUser + `', '` + OriginGit + `')`
+
+// DeferFileEventsSince lowers the unprocessed file events the watcher
+// recorded in projectPath at or after since to PriorityLow, for events
+// already stored when a bulk change is detected. It returns the number of
+// events changed.
+func (s *Store) DeferFileEventsSince(projectPath string, since time.Time) (int64, error) {
+	if err := s.resolvePaths(&projectPath); err != nil {
+		return 0, err
+	}
+	res, err := s.db.Exec(
+		`UPDATE file_events SET priority = ?
+		 WHERE project_path = ? AND timestamp >= ? AND priority > ?
+		   AND processed_at = '' AND `+watchedEvents,
+		PriorityLow, projectPath, since.UTC().Format(time.RFC3339Nano), PriorityLow,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// DeleteUnprocessedFileEventsSince removes the unprocessed file events the
+// watcher recorded in projectPath at or after since, for bulk changes that
+// are configured to be skipped. It returns the number of events removed.
+func (s *Store) DeleteUnprocessedFileEventsSince(projectPath string, since time.Time) (int64, error) {
+	if err := s.resolvePaths(&projectPath); err != nil {
+		return 0, err
+	}
+	res, err := s.db.Exec(
+		`DELETE FROM file_events
+		 WHERE project_path = ? AND timestamp >= ?
+		   AND processed_at = '' AND `+watchedEvents,
+		projectPath, since.UTC().Format(time.RFC3339Nano),
+	)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
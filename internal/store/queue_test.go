@@ -0,0 +1,191 @@
+package store
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestQueryUnprocessedFileEvents_PriorityThenNewest(t *testing.T) {
+	s := newTestStore(t)
+	base := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+
+	insert := func(path string, offset time.Duration, priority int) {
+		t.Helper()
+		if err := s.InsertWatchedFileEvent("/p", path, "write", base.Add(offset), priority, OriginUser); err != nil {
+			t.Fatalf("InsertWatchedFileEvent: %v", err)
+		}
+	}
+	insert("/p/old.go", 0, PriorityNormal)
+	insert("/p/bulk.go", 2*time.Minute, PriorityLow)
+	insert("/p/new.go", time.Minute, PriorityNormal)
+
+	events, err := s.QueryUnprocessedFileEvents(10)
+	if err != nil {
+		t.Fatalf("QueryUnprocessedFileEvents: %v", err)
+	}
+	var got []string
+	for _, fe := range events {
+		got = append(got, fe.FilePath)
+	}
+	want := []string{"/p/new.go", "/p/old.go", "/p/bulk.go"}
+	if len(got) != len(want) {
+		t.Fatalf("order = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("order = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestDeferAndDeleteFileEventsSince(t *testing.T) {
+	s := newTestStore(t)
+	base := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+
+	for i, path := range []string{"/p/a.go", "/p/b.go", "/p/c.go"} {
+		if err := s.InsertWatchedFileEvent("/p", path, "write", base.Add(time.Duration(i)*time.Second), PriorityNormal, OriginUser); err != nil {
+			t.Fatalf("InsertWatchedFileEvent: %v", err)
+		}
+	}
+	// Events of another project, or forwarded by an agent, are not part of
+	// the bulk change.
+	if err := s.InsertWatchedFileEvent("/other", "/other/d.go", "write", base.Add(2*time.Second), PriorityNormal, OriginUser); err != nil {
+		t.Fatalf("InsertWatchedFileEvent: %v", err)
+	}
+	if err := s.InsertRemoteFileEvent("devbox", "/p", "/p/e.go", "write", base.Add(2*time.Second)); err != nil {
+		t.Fatalf("InsertRemoteFileEvent: %v", err)
+	}
+
+	n, err := s.DeferFileEventsSince("/p", base.Add(time.Second))
+	if err != nil || n != 2 {
+		t.Fatalf("DeferFileEventsSince = %d, %v; want 2", n, err)
+	}
+	rows, err := s.db.Query(`SELECT file_path FROM file_events WHERE priority = ? ORDER BY file_path`, PriorityLow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var deferred []string
+	for rows.Next() {
+		var path string
+		if err := rows.Scan(&path); err != nil {
+			t.Fatal(err)
+		}
+		deferred = append(deferred, path)
+	}
+	rows.Close()
+	if want := []string{"/p/b.go", "/p/c.go"}; !slices.Equal(deferred, want) {
+		t.Errorf("deferred events = %v, want %v", deferred, want)
+	}
+
+	n, err = s.DeleteUnprocessedFileEventsSince("/p", base.Add(2*time.Second))
+	if err != nil || n != 1 {
+		t.Fatalf("DeleteUnprocessedFileEventsSince = %d, %v; want 1", n, err)
+	}
+	if count, _ := s.FileEventsCount(); count != 4 {
+		t.Errorf("FileEventsCount = %d, want 4", count)
+	}
+}
+
+func TestMarkGitOperationEvents(t *testing.T) {
+	s := newTestStore(t)
+	base := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+
+	for i, path := range []string{"/p/edit.go", "/p/a.go", "/p/b.go"} {
+		if err := s.InsertFileEvent("/p", path, "write", base.Add(time.Duration(i)*time.Second)); err != nil {
+			t.Fatalf("InsertFileEvent: %v", err)
+		}
+	}
+	if err := s.InsertFileEvent("/other", "/other/c.go", "write", base.Add(2*time.Second)); err != nil {
+		t.Fatalf("InsertFileEvent: %v", err)
+	}
+
+	// One checkout event was attributed before the checkout was detected.
+	events, _ := s.QueryUnprocessedFileEvents(10)
+	for _, fe := range events {
+		if fe.FilePath == "/p/a.go" {
+			if _, err := s.InsertAttribution(AttributionRecord{FilePath: fe.FilePath, ProjectPath: "/p", FileEventID: &fe.ID, AuthorshipLevel: "mostly_human", Timestamp: fe.Timestamp}); err != nil {
+				t.Fatalf("InsertAttribution: %v", err)
+			}
+		}
+	}
+
+	n, err := s.MarkGitOperationEvents([]string{"/p"}, base.Add(time.Second), false)
+	if err != nil || n != 2 {
+		t.Fatalf("MarkGitOperationEvents = %d, %v; want 2", n, err)
+	}
+	if attrs, _ := s.QueryAttributionsByFile("/p/a.go"); len(attrs) != 0 {
+		t.Errorf("attribution for a checkout event kept: %+v", attrs)
+	}
+
+	events, _ = s.QueryUnprocessedFileEvents(10)
+	if len(events) != 2 {
+		t.Fatalf("unprocessed events = %+v, want /other/c.go and /p/edit.go", events)
+	}
+	if count, _ := s.FileEventsCount(); count != 4 {
+		t.Errorf("FileEventsCount = %d, want 4 (tagged events are kept)", count)
+	}
+
+	if _, err := s.MarkGitOperationEvents([]string{"/other"}, base, true); err != nil {
+		t.Fatalf("MarkGitOperationEvents(drop): %v", err)
+	}
+	if count, _ := s.FileEventsCount(); count != 3 {
+		t.Errorf("FileEventsCount after drop = %d, want 3", count)
+	}
+}
+
+func TestRecordAttributions_ClaimsFileEventOnce(t *testing.T) {
+	s := newTestStore(t)
+	now := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	if err := s.InsertFileEvent("/p", "/p/a.go", "write", now); err != nil {
+		t.Fatal(err)
+	}
+	events, _ := s.QueryUnprocessedFileEvents(10)
+	if len(events) != 1 {
+		t.Fatalf("unprocessed events = %+v, want 1", events)
+	}
+	fe := events[0]
+
+	addition := AttributionRecord{FilePath: fe.FilePath, ProjectPath: "/p", FileEventID: &fe.ID, AuthorshipLevel: "mostly_ai", Timestamp: now, LinesChanged: 3}
+	deletion := addition
+	deletion.Kind = AttributionDeletion
+	ids, err := s.RecordAttributions([]AttributionRecord{addition, deletion}, "core_logic")
+	if err != nil || len(ids) != 2 {
+		t.Fatalf("RecordAttributions = %v, %v; want 2 IDs", ids, err)
+	}
+	if events, _ := s.QueryUnprocessedFileEvents(10); len(events) != 0 {
+		t.Errorf("file event still unprocessed: %+v", events)
+	}
+	if backlog, _ := s.UnprocessedFileEventsCount(); backlog != 0 {
+		t.Errorf("backlog = %d, want 0", backlog)
+	}
+	attrs, _ := s.QueryAttributionsByFileWithWorkType(fe.FilePath)
+	if len(attrs) != 1 || attrs[0].WorkType != "core_logic" {
+		t.Errorf("attributions with work type = %+v, want the addition as core_logic", attrs)
+	}
+
+	// Reprocessing the event, as after a crash before the processor moved
+	// on, records nothing.
+	ids, err = s.RecordAttributions([]AttributionRecord{addition}, "core_logic")
+	if err != nil || len(ids) != 0 {
+		t.Errorf("second RecordAttributions = %v, %v; want no IDs", ids, err)
+	}
+	var n int
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM attributions`).Scan(&n); err != nil || n != 2 {
+		t.Errorf("attributions = %d, %v; want 2", n, err)
+	}
+
+	// A failed insert rolls the claim back with it.
+	if err := s.InsertFileEvent("/p", "/p/b.go", "write", now); err != nil {
+		t.Fatal(err)
+	}
+	events, _ = s.QueryUnprocessedFileEvents(10)
+	missing := int64(1 << 40)
+	bad := AttributionRecord{FilePath: "/p/b.go", ProjectPath: "/p", FileEventID: &events[0].ID, SessionEventID: &missing, AuthorshipLevel: "mostly_ai", Timestamp: now}
+	if _, err := s.RecordAttributions([]AttributionRecord{bad}, "core_logic"); err == nil {
+		t.Fatal("RecordAttributions with a missing session event: want error")
+	}
+	if events, _ := s.QueryUnprocessedFileEvents(10); len(events) != 1 {
+		t.Errorf("unprocessed events after a failed record = %+v, want /p/b.go", events)
+	}
+}
@@ -0,0 +1,125 @@
+package store
+
+import (
+	"fmt"
+	"time"
+)
+
+// RemoteSessionEvent is a session event with its raw JSON, as a gapmap
+// agent forwards it to another machine.
+type RemoteSessionEvent struct {
+	StoredSessionEvent
+	RawJSON string
+}
+
+// QueryFileEventsAfter returns up to limit file events with an ID above
+// afterID, oldest first. Only events recorded on this machine from user
+// edits are returned: git-origin events are re-derived by the receiving
+// daemon, and events forwarded from elsewhere are not forwarded again.
+func (s *Store) QueryFileEventsAfter(afterID int64, limit int) ([]FileEvent, error) {
+	rows, err := s.db.Query(
+		`SELECT id, project_path, file_path, event_type, timestamp
+		 FROM file_events
+		 WHERE id > ? AND origin = ? AND host = ''
+		 ORDER BY id ASC
+		 LIMIT ?`,
+		afterID, OriginUser, limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("query file events after %d: %w", afterID, err)
+	}
+	defer rows.Close()
+
+	var events []FileEvent
+	for rows.Next() {
+		var fe FileEvent
+		var ts string
+		if err := rows.Scan(&fe.ID, &fe.ProjectPath, &fe.FilePath, &fe.EventType, &ts); err != nil {
+			return nil, fmt.Errorf("scan file event: %w", err)
+		}
+		if fe.Timestamp, err = time.Parse(time.RFC3339Nano, ts); err != nil {
+			return nil, fmt.Errorf("parse file_event timestamp %q: %w", ts, err)
+		}
+		events = append(events, fe)
+	}
+	return events, rows.Err()
+}
+
+// QuerySessionEventsAfter returns up to limit session events with an ID
+// above afterID, oldest first, with their raw JSON and prompt unsealed.
+// Events forwarded from another host are skipped.
+func (s *Store) QuerySessionEventsAfter(afterID int64, limit int) ([]RemoteSessionEvent, error) {
+	rows, err := s.db.Query(
+		`SELECT id, session_id, event_type, tool_name, file_path, content_hash, timestamp, lines_changed, model, client_version, prompt_id, prompt, raw_json
+		 FROM session_events
+		 WHERE id > ? AND host = ''
+		 ORDER BY id ASC
+		 LIMIT ?`,
+		afterID, limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("query session events after %d: %w", afterID, err)
+	}
+	defer rows.Close()
+
+	var events []RemoteSessionEvent
+	for rows.Next() {
+		var se RemoteSessionEvent
+		var ts string
+		if err := rows.Scan(&se.ID, &se.SessionID, &se.EventType, &se.ToolName, &se.FilePath, &se.ContentHash, &ts,
+			&se.LinesChanged, &se.Model, &se.ClientVersion, &se.PromptID, &se.Prompt, &se.RawJSON); err != nil {
+			return nil, fmt.Errorf("scan session event: %w", err)
+		}
+		if se.Timestamp, err = time.Parse(time.RFC3339Nano, ts); err != nil {
+			return nil, fmt.Errorf("parse session_event timestamp %q: %w", ts, err)
+		}
+		if se.Prompt, err = s.unseal(se.Prompt); err != nil {
+			return nil, fmt.Errorf("session event %d prompt: %w", se.ID, err)
+		}
+		if se.RawJSON, err = s.unseal(se.RawJSON); err != nil {
+			return nil, fmt.Errorf("session event %d raw json: %w", se.ID, err)
+		}
+		events = append(events, se)
+	}
+	return events, rows.Err()
+}
+
+// InsertRemoteFileEvent records a file event a gapmap agent forwarded from
+// host. It is queued like a user edit made on this machine.
+func (s *Store) InsertRemoteFileEvent(host, projectPath, filePath, eventType string, timestamp time.Time) error {
+	if err := s.resolvePaths(&projectPath, &filePath); err != nil {
+		return err
+	}
+	return insertRemoteFileEvent(s.db, host, projectPath, filePath, eventType, timestamp)
+}
+
+// InsertRemoteFileEventWithState is InsertRemoteFileEvent setting the
+// daemon state key to value in the same transaction, so a cursor kept
+// there never disagrees with the events stored.
+func (s *Store) InsertRemoteFileEventWithState(host, projectPath, filePath, eventType string, timestamp time.Time, key, value string) error {
+	if err := s.resolvePaths(&projectPath, &filePath); err != nil {
+		return err
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	if err := insertRemoteFileEvent(tx, host, projectPath, filePath, eventType, timestamp); err != nil {
+		return err
+	}
+	if err := setDaemonState(tx, key, value); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func insertRemoteFileEvent(db execer, host, projectPath, filePath, eventType string, timestamp time.Time) error {
+	_, err := db.Exec(
+		`INSERT INTO file_events (project_path, file_path, event_type, timestamp, priority, origin, host)
+		 VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		projectPath, filePath, eventType, timestamp.UTC().Format(time.RFC3339Nano), PriorityNormal, OriginUser, host,
+	)
+	return err
+}
@@ -0,0 +1,269 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// repairBatch is how many rows Repair reads at a time. A batch that fails
+// is retried row by row, so one damaged page loses only its own rows.
+const repairBatch = 500
+
+// TableRepair is what Repair salvaged from one table.
+type TableRepair struct {
+	Table  string
+	Copied int64
+	Lost   int64  // rows that could not be read
+	Err    string // why the table could not be read at all, if so
+}
+
+// RepairResult summarizes a Repair.
+type RepairResult struct {
+	Tables []TableRepair // in table name order
+}
+
+// Copied returns the number of rows salvaged.
+func (r RepairResult) Copied() int64 {
+	var n int64
+	for _, t := range r.Tables {
+		n += t.Copied
+	}
+	return n
+}
+
+// Lost returns the number of rows that could not be read.
+func (r RepairResult) Lost() int64 {
+	var n int64
+	for _, t := range r.Tables {
+		n += t.Lost
+	}
+	return n
+}
+
+// Repair salvages the readable rows of the (possibly corrupt) database at
+// src into a fresh database at dst, which must not exist. Every table of
+// the current schema is copied by rowid in batches, keeping row ids so
+// references between tables hold; columns src lacks take their defaults.
+// Unreadable rows and tables are skipped and counted.
+func Repair(ctx context.Context, src, dst string) (RepairResult, error) {
+	var result RepairResult
+
+	if _, err := os.Stat(dst); err == nil {
+		return result, fmt.Errorf("%s already exists", dst)
+	}
+	out, err := New(dst)
+	if err != nil {
+		return result, fmt.Errorf("create %s: %w", dst, err)
+	}
+	defer out.Close()
+
+	in, err := sql.Open("sqlite", "file:"+src)
+	if err != nil {
+		return result, fmt.Errorf("open %s: %w", src, err)
+	}
+	defer in.Close()
+
+	// Rows reference each other by id; copy them in any order.
+	conn, err := out.db.Conn(ctx)
+	if err != nil {
+		return result, err
+	}
+	defer conn.Close()
+	if _, err := conn.ExecContext(ctx, `PRAGMA foreign_keys = OFF`); err != nil {
+		return result, err
+	}
+
+	tables, err := tableColumns(ctx, conn)
+	if err != nil {
+		return result, fmt.Errorf("read schema: %w", err)
+	}
+	names := make([]string, 0, len(tables))
+	for name := range tables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+		tr := TableRepair{Table: name}
+		if err := repairTable(ctx, in, conn, name, tables[name], &tr); err != nil {
+			tr.Err = err.Error()
+		}
+		result.Tables = append(result.Tables, tr)
+	}
+	return result, nil
+}
+
+// repairTable copies the readable rows of one table.
+func repairTable(ctx context.Context, in *sql.DB, out *sql.Conn, table string, columns []string, tr *TableRepair) error {
+	srcCols, err := sourceColumns(ctx, in, table)
+	if err != nil {
+		return err
+	}
+	var cols []string
+	for _, c := range columns {
+		if srcCols[c] {
+			cols = append(cols, quoteIdent(c))
+		}
+	}
+	if len(cols) == 0 {
+		return nil
+	}
+
+	var maxRowID sql.NullInt64
+	if err := in.QueryRowContext(ctx, `SELECT MAX(rowid) FROM `+quoteIdent(table)).Scan(&maxRowID); err != nil {
+		return fmt.Errorf("find last row: %w", err)
+	}
+	if !maxRowID.Valid {
+		return nil
+	}
+
+	colList := strings.Join(cols, ", ")
+	selectSQL := fmt.Sprintf(`SELECT rowid, %s FROM %s WHERE rowid BETWEEN ? AND ? ORDER BY rowid`, colList, quoteIdent(table))
+	insertSQL := fmt.Sprintf(`INSERT OR IGNORE INTO %s (%s) VALUES (%s)`, quoteIdent(table), colList, strings.TrimSuffix(strings.Repeat("?, ", len(cols)), ", "))
+
+	for lo := int64(1); lo <= maxRowID.Int64; lo += repairBatch {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		hi := lo + repairBatch - 1
+		rows, err := readRows(ctx, in, selectSQL, len(cols), lo, hi)
+		if err != nil {
+			// Retry the batch row by row to save what is readable.
+			rows = nil
+			for id := lo; id <= hi; id++ {
+				row, err := readRows(ctx, in, selectSQL, len(cols), id, id)
+				if err != nil {
+					tr.Lost++
+					continue
+				}
+				rows = append(rows, row...)
+			}
+		}
+		if err := insertRows(ctx, out, insertSQL, rows); err != nil {
+			return err
+		}
+		tr.Copied += int64(len(rows))
+	}
+	return nil
+}
+
+// readRows reads the rows with rowids lo..hi, without the rowid.
+func readRows(ctx context.Context, in *sql.DB, query string, ncols int, lo, hi int64) ([][]any, error) {
+	rows, err := in.QueryContext(ctx, query, lo, hi)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out [][]any
+	for rows.Next() {
+		vals := make([]any, ncols+1)
+		ptrs := make([]any, len(vals))
+		for i := range vals {
+			ptrs[i] = &vals[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return nil, err
+		}
+		out = append(out, vals[1:])
+	}
+	return out, rows.Err()
+}
+
+func insertRows(ctx context.Context, out *sql.Conn, query string, rows [][]any) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	tx, err := out.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	stmt, err := tx.PrepareContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for _, row := range rows {
+		if _, err := stmt.ExecContext(ctx, row...); err != nil {
+			return fmt.Errorf("insert: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// tableColumns returns the columns of each table of a fresh database.
+func tableColumns(ctx context.Context, conn *sql.Conn) (map[string][]string, error) {
+	rows, err := conn.QueryContext(ctx, `SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%'`)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	tables := make(map[string][]string, len(names))
+	for _, name := range names {
+		cols, err := pragmaColumns(ctx, conn.QueryContext, name)
+		if err != nil {
+			return nil, err
+		}
+		tables[name] = cols
+	}
+	return tables, nil
+}
+
+// sourceColumns returns the set of columns a table has in the database
+// being repaired.
+func sourceColumns(ctx context.Context, in *sql.DB, table string) (map[string]bool, error) {
+	cols, err := pragmaColumns(ctx, in.QueryContext, table)
+	if err != nil {
+		return nil, err
+	}
+	if len(cols) == 0 {
+		return nil, fmt.Errorf("table %s is missing", table)
+	}
+	set := make(map[string]bool, len(cols))
+	for _, c := range cols {
+		set[c] = true
+	}
+	return set, nil
+}
+
+func pragmaColumns(ctx context.Context, query func(context.Context, string, ...any) (*sql.Rows, error), table string) ([]string, error) {
+	rows, err := query(ctx, `SELECT name FROM pragma_table_info(?)`, table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var cols []string
+	for rows.Next() {
+		var c string
+		if err := rows.Scan(&c); err != nil {
+			return nil, err
+		}
+		cols = append(cols, c)
+	}
+	return cols, rows.Err()
+}
+
+func quoteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
@@ -0,0 +1,124 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// StoredCommit is a synced git commit, as used to follow history rewrites.
+type StoredCommit struct {
+	Hash      string
+	PatchID   string
+	Timestamp time.Time
+}
+
+// SetCommitPatchID records the patch id of a synced commit: a hash of its
+// changes that is the same after the commit is rebased or amended without
+// changing its content.
+func (s *Store) SetCommitPatchID(hash, patchID string) error {
+	_, err := s.db.Exec(`UPDATE git_commits SET patch_id = ? WHERE hash = ?`, patchID, hash)
+	return err
+}
+
+// LinkAttributionsToCommit records hash as the commit of the attributions
+// in projectPath that were made at or before committed, are not linked to a
+// commit yet, and are for a file the commit changed (per its stored diffs,
+// with paths relative to projectPath). Commits must be linked oldest first.
+// It returns the number of attributions linked.
+func (s *Store) LinkAttributionsToCommit(projectPath, hash string, committed time.Time) (int64, error) {
+	res, err := s.db.Exec(
+		`UPDATE attributions SET commit_hash = ?
+		 WHERE commit_hash = '' AND project_path = ? AND timestamp <= ?
+		   AND EXISTS (
+		     SELECT 1 FROM git_diffs d JOIN git_commits c ON c.id = d.commit_id
+		     WHERE c.hash = ?
+		       AND (attributions.file_path = d.file_path OR attributions.file_path = ? || '/' || d.file_path))`,
+		hash, projectPath, committed.UTC().Format(time.RFC3339Nano), hash, projectPath,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// QueryLiveCommitsSince returns the synced commits authored at or after
+// since that have not been rewritten, newest first.
+func (s *Store) QueryLiveCommitsSince(since time.Time) ([]StoredCommit, error) {
+	rows, err := s.db.Query(
+		`SELECT hash, patch_id, timestamp FROM git_commits
+		 WHERE rewritten_to = '' AND timestamp >= ?
+		 ORDER BY timestamp DESC, id DESC`,
+		since.UTC().Format(time.RFC3339),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var commits []StoredCommit
+	for rows.Next() {
+		var c StoredCommit
+		var ts string
+		if err := rows.Scan(&c.Hash, &c.PatchID, &ts); err != nil {
+			return nil, err
+		}
+		c.Timestamp, _ = time.Parse(time.RFC3339, ts)
+		commits = append(commits, c)
+	}
+	return commits, rows.Err()
+}
+
+// QueryCommitForFile returns the commit of the earliest attribution of
+// filePath that is linked to one, or "" if none is.
+func (s *Store) QueryCommitForFile(filePath string) (string, error) {
+	var hash string
+	err := s.db.QueryRow(
+		`SELECT commit_hash FROM attributions
+		 WHERE file_path = ? AND commit_hash != ''
+		 ORDER BY timestamp ASC LIMIT 1`,
+		filePath,
+	).Scan(&hash)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return hash, err
+}
+
+// RemapCommits moves everything that refers to a rewritten commit to the
+// commit that replaced it, for each old -> new hash in rewrites:
+// attributions, blame lines, survival records and work type overrides. The
+// old commits are marked as rewritten. It returns the number of
+// attributions moved.
+func (s *Store) RemapCommits(rewrites map[string]string) (int64, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	var moved int64
+	for oldHash, newHash := range rewrites {
+		res, err := tx.Exec(`UPDATE attributions SET commit_hash = ? WHERE commit_hash = ?`, newHash, oldHash)
+		if err != nil {
+			return 0, fmt.Errorf("remap attributions: %w", err)
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return 0, err
+		}
+		moved += n
+
+		for _, q := range []string{
+			`UPDATE git_blame_lines SET commit_hash = ? WHERE commit_hash = ?`,
+			`UPDATE code_survival SET blame_commit_hash = ? WHERE blame_commit_hash = ?`,
+			`UPDATE OR IGNORE work_type_overrides SET commit_hash = ? WHERE commit_hash = ?`,
+			`UPDATE git_commits SET rewritten_to = ? WHERE hash = ?`,
+		} {
+			if _, err := tx.Exec(q, newHash, oldHash); err != nil {
+				return 0, fmt.Errorf("remap %s to %s: %w", oldHash, newHash, err)
+			}
+		}
+	}
+	return moved, tx.Commit()
+}
@@ -0,0 +1,68 @@
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLinkAndRemapCommits(t *testing.T) {
+	s := newTestStore(t)
+	now := time.Now()
+
+	commitID, err := s.InsertGitCommit("old", "Dev <dev@example.com>", "add a", now, false, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.InsertGitDiff(commitID, "a.go", "", "add", 3, 0); err != nil {
+		t.Fatal(err)
+	}
+	for _, attr := range []AttributionRecord{
+		{FilePath: "/proj/a.go", ProjectPath: "/proj", AuthorshipLevel: "mostly_ai", Timestamp: now.Add(-time.Minute)},
+		{FilePath: "/proj/b.go", ProjectPath: "/proj", AuthorshipLevel: "mostly_ai", Timestamp: now.Add(-time.Minute)},
+		{FilePath: "/proj/a.go", ProjectPath: "/proj", AuthorshipLevel: "mostly_ai", Timestamp: now.Add(time.Minute)},
+	} {
+		if _, err := s.InsertAttribution(attr); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Only the attribution of a file in the commit, made before it, is linked.
+	n, err := s.LinkAttributionsToCommit("/proj", "old", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Errorf("linked %d attributions, want 1", n)
+	}
+
+	if err := s.InsertBlameLines("a.go", []BlameLine{{LineNumber: 1, CommitHash: "old", Author: "Dev"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.InsertWorkTypeOverride("a.go", "old", "test_scaffolding"); err != nil {
+		t.Fatal(err)
+	}
+
+	moved, err := s.RemapCommits(map[string]string{"old": "new"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if moved != 1 {
+		t.Errorf("moved %d attributions, want 1", moved)
+	}
+	if hash, _ := s.QueryCommitForFile("/proj/a.go"); hash != "new" {
+		t.Errorf("attribution commit = %q, want new", hash)
+	}
+	if lines, _ := s.QueryBlameLinesByFile("a.go"); len(lines) != 1 || lines[0].CommitHash != "new" {
+		t.Errorf("blame lines = %+v, want commit new", lines)
+	}
+	if wt, ok, _ := s.QueryWorkTypeOverride("a.go", "new"); !ok || wt != "test_scaffolding" {
+		t.Errorf("work type override = %q, %v; want it moved to new", wt, ok)
+	}
+	live, err := s.QueryLiveCommitsSince(now.Add(-time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(live) != 0 {
+		t.Errorf("live commits = %+v, want the rewritten commit excluded", live)
+	}
+}
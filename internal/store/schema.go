@@ -1,7 +1,8 @@
 package store
 
-// schemaVersion is the current schema version. Increment when adding migrations.
-const schemaVersion = 6
+// schemaVersion is the current schema version. Increment when adding migrations,
+// and add the matching entry to downMigrations.
+const schemaVersion = 29
 
 // migrations maps version numbers to SQL statements that bring the schema
 // from (version-1) to (version). Version 1 is the initial schema.
@@ -157,5 +158,456 @@ ALTER TABLE attributions ADD COLUMN branch TEXT NOT NULL DEFAULT '';
 CREATE INDEX IF NOT EXISTS idx_file_events_branch ON file_events(branch);
 CREATE INDEX IF NOT EXISTS idx_session_events_branch ON session_events(branch);
 CREATE INDEX IF NOT EXISTS idx_attributions_branch ON attributions(branch);
+`,
+
+	7: `
+-- Clipboard snapshots copied shortly after AI session activity. Only
+-- per-line hashes are stored, never the clipboard text itself.
+CREATE TABLE IF NOT EXISTS clipboard_snapshots (
+	id                    INTEGER PRIMARY KEY AUTOINCREMENT,
+	timestamp             TEXT    NOT NULL,
+	content_hash          TEXT    NOT NULL,
+	line_hashes           TEXT    NOT NULL DEFAULT '[]',
+	line_count            INTEGER NOT NULL DEFAULT 0,
+	matched_file_event_id INTEGER REFERENCES file_events(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_clipboard_snapshots_timestamp ON clipboard_snapshots(timestamp);
+`,
+
+	8: `
+-- Dedupe key for idempotent session event ingestion: the JSONL line's uuid,
+-- or a hash of the line. Rows from before this migration have an empty key
+-- until ` + "`gapmap dedupe`" + ` backfills them.
+ALTER TABLE session_events ADD COLUMN dedupe_key TEXT NOT NULL DEFAULT '';
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_session_events_dedupe
+	ON session_events(session_id, dedupe_key) WHERE dedupe_key != '';
+`,
+
+	9: `
+-- Attribution kind: 'addition' for lines written, 'deletion' for lines an
+-- AI edit removed. Line-count queries read additions only.
+ALTER TABLE attributions ADD COLUMN kind TEXT NOT NULL DEFAULT 'addition';
+
+CREATE INDEX IF NOT EXISTS idx_attributions_kind ON attributions(kind);
+`,
+
+	10: `
+-- Typing bursts reported by editor plugins: a span of time in which a human
+-- typed into a file. Used by correlation as evidence of human authorship.
+CREATE TABLE IF NOT EXISTS typing_bursts (
+	id          INTEGER PRIMARY KEY AUTOINCREMENT,
+	file_path   TEXT    NOT NULL,
+	started_at  TEXT    NOT NULL,
+	ended_at    TEXT    NOT NULL,
+	chars_typed INTEGER NOT NULL DEFAULT 0,
+	source      TEXT    NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS idx_typing_bursts_file_ended ON typing_bursts(file_path, ended_at);
+`,
+
+	11: `
+-- File event priority for the attribution processor: 0 for normal events,
+-- -1 for bulk changes (e.g. a branch switch) attributed after all others.
+ALTER TABLE file_events ADD COLUMN priority INTEGER NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS idx_file_events_priority ON file_events(priority, timestamp);
+`,
+
+	12: `
+-- File event origin: '' for edits, 'git' for files rewritten by a git
+-- checkout, rebase or merge. Git events are kept but never attributed.
+ALTER TABLE file_events ADD COLUMN origin TEXT NOT NULL DEFAULT '';
+`,
+
+	13: `
+-- Following commits through amend and rebase: the commit an attribution's
+-- change was committed in, each commit's patch id (a hash of its changes
+-- that survives a rewrite) and the commit a rewritten one became.
+ALTER TABLE attributions ADD COLUMN commit_hash TEXT NOT NULL DEFAULT '';
+ALTER TABLE git_commits ADD COLUMN patch_id TEXT NOT NULL DEFAULT '';
+ALTER TABLE git_commits ADD COLUMN rewritten_to TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_attributions_commit ON attributions(commit_hash);
+CREATE INDEX IF NOT EXISTS idx_git_commits_patch_id ON git_commits(patch_id);
+`,
+
+	14: `
+-- The model that made a session event (e.g. claude-opus-4-1-20250805) and
+-- the version of the AI client, as the session file records them; '' when
+-- it does not.
+ALTER TABLE session_events ADD COLUMN model TEXT NOT NULL DEFAULT '';
+ALTER TABLE session_events ADD COLUMN client_version TEXT NOT NULL DEFAULT '';
+`,
+
+	15: `
+-- The user prompt a session event answers: its message uuid and its text,
+-- redacted and truncated, so reports can say what the AI was asked to do.
+ALTER TABLE session_events ADD COLUMN prompt_id TEXT NOT NULL DEFAULT '';
+ALTER TABLE session_events ADD COLUMN prompt TEXT NOT NULL DEFAULT '';
+`,
+
+	16: `
+-- Tokens billed for each API message of an AI session, so reports can
+-- relate what a project cost to the code that survived. Keyed by message
+-- id because a session file repeats a message's usage on every line of it.
+CREATE TABLE IF NOT EXISTS token_usage (
+	message_id            TEXT    PRIMARY KEY,
+	session_id            TEXT    NOT NULL,
+	model                 TEXT    NOT NULL DEFAULT '',
+	input_tokens          INTEGER NOT NULL DEFAULT 0,
+	output_tokens         INTEGER NOT NULL DEFAULT 0,
+	cache_creation_tokens INTEGER NOT NULL DEFAULT 0,
+	cache_read_tokens     INTEGER NOT NULL DEFAULT 0,
+	timestamp             TEXT    NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_token_usage_session ON token_usage(session_id);
+`,
+
+	17: `
+-- How each attribution was correlated (exact_file, fuzzy_file, clipboard,
+-- typing, none or manual; '' before it was recorded), and the authorship
+-- users confirmed for sampled attributions with gapmap calibrate.
+ALTER TABLE attributions ADD COLUMN match_type TEXT NOT NULL DEFAULT '';
+
+CREATE TABLE IF NOT EXISTS attribution_labels (
+	attribution_id INTEGER PRIMARY KEY REFERENCES attributions(id) ON DELETE CASCADE,
+	label          TEXT    NOT NULL,
+	labeled_at     TEXT    NOT NULL
+);
+`,
+
+	18: `
+-- Line ranges users labeled AI- or human-written with gapmap label, the
+-- ground truth analyze --accuracy checks computed attribution against.
+CREATE TABLE IF NOT EXISTS line_labels (
+	id           INTEGER PRIMARY KEY AUTOINCREMENT,
+	project_path TEXT    NOT NULL,
+	file_path    TEXT    NOT NULL,
+	start_line   INTEGER NOT NULL,
+	end_line     INTEGER NOT NULL,
+	label        TEXT    NOT NULL,
+	created_at   TEXT    NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_line_labels_project ON line_labels(project_path);
+`,
+
+	19: `
+-- What a human changed: the diff against git HEAD (kind 'diff') or, for
+-- files outside git, the content (kind 'content') of a file when an event
+-- on it was attributed to a human. Only stored with human_snapshots on.
+CREATE TABLE IF NOT EXISTS file_snapshots (
+	id             INTEGER PRIMARY KEY AUTOINCREMENT,
+	attribution_id INTEGER NOT NULL REFERENCES attributions(id) ON DELETE CASCADE,
+	file_path      TEXT    NOT NULL,
+	kind           TEXT    NOT NULL,
+	content        TEXT    NOT NULL,
+	timestamp      TEXT    NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_file_snapshots_file ON file_snapshots(file_path);
+`,
+
+	20: `
+-- Survival history: the daemon's survival job records a snapshot of whether
+-- each tracked AI attribution survives, with what it was classified as.
+-- Rebuilt so rows go with their attribution.
+CREATE TABLE code_survival_new (
+	id                INTEGER PRIMARY KEY AUTOINCREMENT,
+	file_path         TEXT    NOT NULL,
+	project_path      TEXT    NOT NULL,
+	attribution_id    INTEGER NOT NULL REFERENCES attributions(id) ON DELETE CASCADE,
+	survived          INTEGER NOT NULL DEFAULT 0,
+	checked_at        TEXT    NOT NULL,
+	blame_commit_hash TEXT    NOT NULL DEFAULT '',
+	authorship_level  TEXT    NOT NULL DEFAULT '',
+	work_type         TEXT    NOT NULL DEFAULT '',
+	model             TEXT    NOT NULL DEFAULT '',
+	lines_changed     INTEGER NOT NULL DEFAULT 0
+);
+
+INSERT INTO code_survival_new (id, file_path, project_path, attribution_id, survived, checked_at, blame_commit_hash)
+SELECT id, file_path, project_path, attribution_id, survived, checked_at, blame_commit_hash
+FROM code_survival WHERE attribution_id IN (SELECT id FROM attributions);
+
+DROP TABLE code_survival;
+ALTER TABLE code_survival_new RENAME TO code_survival;
+
+CREATE INDEX IF NOT EXISTS idx_code_survival_project ON code_survival(project_path, checked_at);
+CREATE INDEX IF NOT EXISTS idx_code_survival_file ON code_survival(file_path);
+CREATE INDEX IF NOT EXISTS idx_code_survival_attribution ON code_survival(attribution_id);
+`,
+
+	21: `
+-- Why an attribution's branch is gone: 'merged' into the default branch or
+-- 'deleted'; '' while the branch is live. Branch reports skip archived
+-- attributions and gapmap prune --merged-branches deletes them.
+ALTER TABLE attributions ADD COLUMN archived TEXT NOT NULL DEFAULT '';
+`,
+
+	22: `
+-- The host a file or session event happened on, for events a gapmap agent
+-- forwarded from a remote machine; '' for this one.
+ALTER TABLE file_events ADD COLUMN host TEXT NOT NULL DEFAULT '';
+ALTER TABLE session_events ADD COLUMN host TEXT NOT NULL DEFAULT '';
+`,
+
+	23: `
+-- Paths a project was recorded under before gapmap projects merge moved
+-- its data to project_path. New events under an alias are stored under
+-- its project_path.
+CREATE TABLE IF NOT EXISTS project_aliases (
+	alias        TEXT PRIMARY KEY,
+	project_path TEXT NOT NULL,
+	created_at   TEXT NOT NULL
+);
+`,
+
+	24: `
+-- The lines a Write or Edit call wrote and the lines an Edit removed, as
+-- the session parser computed them, so reports need not re-parse raw_json.
+-- Sealed like raw_json and compressed when large. NULL for events stored
+-- before this migration until the daemon backfills them.
+ALTER TABLE session_events ADD COLUMN diff_content TEXT;
+ALTER TABLE session_events ADD COLUMN deleted_content TEXT;
+`,
+
+	25: `
+-- Composite indexes for the per-file and per-project queries of reports
+-- and the attribution processor, which scanned whole tables on large
+-- databases. The single-column file and project indexes they replace are
+-- prefixes of the new ones.
+CREATE INDEX IF NOT EXISTS idx_session_events_file_ts ON session_events(file_path, timestamp);
+CREATE INDEX IF NOT EXISTS idx_session_events_tool_ts ON session_events(tool_name, timestamp);
+CREATE INDEX IF NOT EXISTS idx_file_events_file_ts ON file_events(file_path, timestamp);
+CREATE INDEX IF NOT EXISTS idx_file_events_project_ts ON file_events(project_path, timestamp);
+DROP INDEX IF EXISTS idx_file_events_project;
+CREATE INDEX IF NOT EXISTS idx_attributions_file_kind_ts ON attributions(file_path, kind, timestamp);
+CREATE INDEX IF NOT EXISTS idx_attributions_project_kind_ts ON attributions(project_path, kind, timestamp);
+DROP INDEX IF EXISTS idx_attributions_file;
+DROP INDEX IF EXISTS idx_attributions_project;
+
+-- Attributions are looked up by the events they came from: the processor
+-- finds unattributed file events and dedupe repoints session events.
+CREATE INDEX IF NOT EXISTS idx_attributions_file_event ON attributions(file_event_id);
+CREATE INDEX IF NOT EXISTS idx_attributions_session_event ON attributions(session_event_id);
+`,
+
+	26: `
+-- When the attribution processor committed a file event's attributions;
+-- '' until then. The processor claims an event by setting it in the same
+-- transaction as the attributions, so a crash can neither leave an event
+-- half-attributed nor have it attributed twice. Events attributed before
+-- this migration are marked from their attributions.
+ALTER TABLE file_events ADD COLUMN processed_at TEXT NOT NULL DEFAULT '';
+UPDATE file_events SET processed_at = (
+	SELECT MIN(a.created_at) FROM attributions a WHERE a.file_event_id = file_events.id
+) WHERE id IN (SELECT file_event_id FROM attributions WHERE file_event_id IS NOT NULL);
+CREATE INDEX IF NOT EXISTS idx_file_events_unprocessed ON file_events(priority, timestamp)
+	WHERE processed_at = '' AND origin = '';
+`,
+
+	27: `
+-- Whether session data backs a commit's AI Co-Authored-By trailer:
+-- 'corroborated' when AI edits touched its files shortly before it,
+-- 'unverified' when none did, '' until checked. coauthor_confidence is
+-- the confidence the trailer earns from that check.
+ALTER TABLE git_commits ADD COLUMN coauthor_status TEXT NOT NULL DEFAULT '';
+ALTER TABLE git_commits ADD COLUMN coauthor_confidence REAL NOT NULL DEFAULT 0;
+ALTER TABLE git_commits ADD COLUMN coauthor_session_events INTEGER NOT NULL DEFAULT 0;
+`,
+
+	28: `
+-- Whether a survival record's file was created while it was watched
+-- ('new') or existed before ('existing'); '' in snapshots recorded before
+-- this migration.
+ALTER TABLE code_survival ADD COLUMN file_age TEXT NOT NULL DEFAULT '';
+`,
+
+	29: `
+-- Whether a human changed the lines of a survival record's attribution
+-- soon after the AI wrote them: 1 or 0, NULL when unknown, as in
+-- snapshots recorded before this migration.
+ALTER TABLE code_survival ADD COLUMN corrected INTEGER;
+`,
+}
+
+// downMigrations maps version numbers to SQL statements that revert the
+// schema from (version) to (version-1). Used by `gapmap migrate down`.
+// Indexes on a column must be dropped before the column itself.
+var downMigrations = map[int]string{
+	1: `
+DROP TABLE IF EXISTS git_blame_lines;
+DROP TABLE IF EXISTS git_diffs;
+DROP TABLE IF EXISTS git_commits;
+DROP TABLE IF EXISTS session_events;
+DROP TABLE IF EXISTS file_events;
+`,
+
+	2: `
+DROP TABLE IF EXISTS attributions;
+`,
+
+	3: `
+DROP TABLE IF EXISTS work_type_overrides;
+ALTER TABLE attributions DROP COLUMN work_type;
+`,
+
+	4: `
+DROP TABLE IF EXISTS code_survival;
+`,
+
+	5: `
+ALTER TABLE attributions DROP COLUMN lines_changed;
+ALTER TABLE session_events DROP COLUMN lines_changed;
+`,
+
+	6: `
+DROP INDEX IF EXISTS idx_attributions_branch;
+DROP INDEX IF EXISTS idx_session_events_branch;
+DROP INDEX IF EXISTS idx_file_events_branch;
+
+ALTER TABLE attributions DROP COLUMN branch;
+ALTER TABLE session_events DROP COLUMN branch;
+ALTER TABLE file_events DROP COLUMN branch;
+`,
+
+	7: `
+DROP TABLE IF EXISTS clipboard_snapshots;
+`,
+
+	8: `
+DROP INDEX IF EXISTS idx_session_events_dedupe;
+ALTER TABLE session_events DROP COLUMN dedupe_key;
+`,
+
+	9: `
+DROP INDEX IF EXISTS idx_attributions_kind;
+ALTER TABLE attributions DROP COLUMN kind;
+`,
+
+	10: `
+DROP TABLE IF EXISTS typing_bursts;
+`,
+
+	11: `
+DROP INDEX IF EXISTS idx_file_events_priority;
+ALTER TABLE file_events DROP COLUMN priority;
+`,
+
+	12: `
+ALTER TABLE file_events DROP COLUMN origin;
+`,
+
+	13: `
+DROP INDEX IF EXISTS idx_git_commits_patch_id;
+DROP INDEX IF EXISTS idx_attributions_commit;
+
+ALTER TABLE git_commits DROP COLUMN rewritten_to;
+ALTER TABLE git_commits DROP COLUMN patch_id;
+ALTER TABLE attributions DROP COLUMN commit_hash;
+`,
+
+	14: `
+ALTER TABLE session_events DROP COLUMN client_version;
+ALTER TABLE session_events DROP COLUMN model;
+`,
+
+	15: `
+ALTER TABLE session_events DROP COLUMN prompt;
+ALTER TABLE session_events DROP COLUMN prompt_id;
+`,
+
+	16: `
+DROP TABLE IF EXISTS token_usage;
+`,
+
+	17: `
+DROP TABLE IF EXISTS attribution_labels;
+ALTER TABLE attributions DROP COLUMN match_type;
+`,
+
+	18: `
+DROP INDEX IF EXISTS idx_line_labels_project;
+DROP TABLE IF EXISTS line_labels;
+`,
+
+	19: `
+DROP INDEX IF EXISTS idx_file_snapshots_file;
+DROP TABLE IF EXISTS file_snapshots;
+`,
+
+	20: `
+CREATE TABLE code_survival_old (
+	id                INTEGER PRIMARY KEY AUTOINCREMENT,
+	file_path         TEXT    NOT NULL,
+	project_path      TEXT    NOT NULL,
+	attribution_id    INTEGER NOT NULL REFERENCES attributions(id),
+	survived          INTEGER NOT NULL DEFAULT 0,
+	checked_at        TEXT    NOT NULL,
+	blame_commit_hash TEXT    NOT NULL DEFAULT ''
+);
+
+INSERT INTO code_survival_old (id, file_path, project_path, attribution_id, survived, checked_at, blame_commit_hash)
+SELECT id, file_path, project_path, attribution_id, survived, checked_at, blame_commit_hash FROM code_survival;
+
+DROP TABLE code_survival;
+ALTER TABLE code_survival_old RENAME TO code_survival;
+
+CREATE INDEX IF NOT EXISTS idx_code_survival_project ON code_survival(project_path);
+CREATE INDEX IF NOT EXISTS idx_code_survival_file ON code_survival(file_path);
+CREATE INDEX IF NOT EXISTS idx_code_survival_attribution ON code_survival(attribution_id);
+`,
+
+	21: `
+ALTER TABLE attributions DROP COLUMN archived;
+`,
+
+	22: `
+ALTER TABLE session_events DROP COLUMN host;
+ALTER TABLE file_events DROP COLUMN host;
+`,
+
+	23: `
+DROP TABLE IF EXISTS project_aliases;
+`,
+
+	24: `
+ALTER TABLE session_events DROP COLUMN deleted_content;
+ALTER TABLE session_events DROP COLUMN diff_content;
+`,
+
+	25: `
+DROP INDEX IF EXISTS idx_attributions_session_event;
+DROP INDEX IF EXISTS idx_attributions_file_event;
+CREATE INDEX IF NOT EXISTS idx_attributions_project ON attributions(project_path);
+CREATE INDEX IF NOT EXISTS idx_attributions_file ON attributions(file_path);
+DROP INDEX IF EXISTS idx_attributions_project_kind_ts;
+DROP INDEX IF EXISTS idx_attributions_file_kind_ts;
+CREATE INDEX IF NOT EXISTS idx_file_events_project ON file_events(project_path);
+DROP INDEX IF EXISTS idx_file_events_project_ts;
+DROP INDEX IF EXISTS idx_file_events_file_ts;
+DROP INDEX IF EXISTS idx_session_events_tool_ts;
+DROP INDEX IF EXISTS idx_session_events_file_ts;
+`,
+
+	26: `
+DROP INDEX IF EXISTS idx_file_events_unprocessed;
+ALTER TABLE file_events DROP COLUMN processed_at;
+`,
+	27: `
+ALTER TABLE git_commits DROP COLUMN coauthor_session_events;
+ALTER TABLE git_commits DROP COLUMN coauthor_confidence;
+ALTER TABLE git_commits DROP COLUMN coauthor_status;
+`,
+	28: `
+ALTER TABLE code_survival DROP COLUMN file_age;
+`,
+	29: `
+ALTER TABLE code_survival DROP COLUMN corrected;
 `,
 }
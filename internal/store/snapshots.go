@@ -0,0 +1,76 @@
+package store
+
+import (
+	"fmt"
+	"time"
+)
+
+// Kinds of file snapshot.
+const (
+	SnapshotDiff    = "diff"    // unified diff of the file against git HEAD
+	SnapshotContent = "content" // the whole file, for files outside git
+)
+
+// FileSnapshot is what a file looked like, relative to git HEAD, when a
+// human edit to it was attributed.
+type FileSnapshot struct {
+	ID            int64
+	AttributionID int64
+	FilePath      string
+	Kind          string // SnapshotDiff or SnapshotContent
+	Content       string
+	Timestamp     time.Time
+}
+
+// InsertFileSnapshot stores a snapshot of a human-attributed file change.
+// The content is encrypted when the database is.
+func (s *Store) InsertFileSnapshot(fs FileSnapshot) (int64, error) {
+	if fs.Kind != SnapshotDiff && fs.Kind != SnapshotContent {
+		return 0, fmt.Errorf("unknown snapshot kind %q", fs.Kind)
+	}
+	res, err := s.db.Exec(
+		`INSERT INTO file_snapshots (attribution_id, file_path, kind, content, timestamp)
+		 VALUES (?, ?, ?, ?, ?)`,
+		fs.AttributionID, fs.FilePath, fs.Kind, s.seal(fs.Content),
+		fs.Timestamp.UTC().Format(time.RFC3339Nano),
+	)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// QueryFileSnapshots returns the snapshots of a file, oldest first.
+func (s *Store) QueryFileSnapshots(filePath string) ([]FileSnapshot, error) {
+	rows, err := s.db.Query(
+		`SELECT id, attribution_id, file_path, kind, content, timestamp
+		 FROM file_snapshots WHERE file_path = ?
+		 ORDER BY timestamp ASC, id ASC`,
+		filePath,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var snapshots []FileSnapshot
+	for rows.Next() {
+		var fs FileSnapshot
+		var ts string
+		if err := rows.Scan(&fs.ID, &fs.AttributionID, &fs.FilePath, &fs.Kind, &fs.Content, &ts); err != nil {
+			return nil, err
+		}
+		content, err := s.unseal(fs.Content)
+		if err != nil {
+			return nil, fmt.Errorf("snapshot %d: %w", fs.ID, err)
+		}
+		fs.Content = content
+		t, err := time.Parse(time.RFC3339Nano, ts)
+		if err != nil {
+			return nil, fmt.Errorf("parse snapshot timestamp %q: %w", ts, err)
+		}
+		fs.Timestamp = t
+		snapshots = append(snapshots, fs)
+	}
+	return snapshots, rows.Err()
+}
@@ -0,0 +1,68 @@
+package store
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileSnapshots(t *testing.T) {
+	key := bytes.Repeat([]byte{2}, KeySize)
+	withKey := WithKeySource(func() ([]byte, error) { return key, nil })
+
+	s, err := New(filepath.Join(t.TempDir(), "test.db"), withKey)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	defer s.Close()
+
+	now := time.Now()
+	id, err := s.InsertAttribution(AttributionRecord{
+		FilePath: "/p/a.go", ProjectPath: "/p", AuthorshipLevel: "mostly_human",
+		Confidence: 1, FirstAuthor: "human", Timestamp: now, LinesChanged: 1,
+	})
+	if err != nil {
+		t.Fatalf("InsertAttribution: %v", err)
+	}
+
+	if _, err := s.InsertFileSnapshot(FileSnapshot{AttributionID: id, FilePath: "/p/a.go", Kind: "zip", Timestamp: now}); err == nil {
+		t.Error("InsertFileSnapshot accepted an unknown kind")
+	}
+	diff := "--- a/a.go\n+++ b/a.go\n@@ -1 +1 @@\n-x\n+y\n"
+	if _, err := s.InsertFileSnapshot(FileSnapshot{AttributionID: id, FilePath: "/p/a.go", Kind: SnapshotDiff, Content: diff, Timestamp: now}); err != nil {
+		t.Fatalf("InsertFileSnapshot: %v", err)
+	}
+
+	// Snapshots are code, so they are encrypted with the session content.
+	res, err := s.EncryptContent(key)
+	if err != nil {
+		t.Fatalf("EncryptContent: %v", err)
+	}
+	if res.Rows != 1 {
+		t.Errorf("encrypted %d rows, want 1", res.Rows)
+	}
+	var stored string
+	if err := s.DB().QueryRow(`SELECT content FROM file_snapshots`).Scan(&stored); err != nil {
+		t.Fatal(err)
+	}
+	if stored == diff {
+		t.Error("snapshot content stored as plaintext after encryption")
+	}
+
+	snapshots, err := s.QueryFileSnapshots("/p/a.go")
+	if err != nil {
+		t.Fatalf("QueryFileSnapshots: %v", err)
+	}
+	if len(snapshots) != 1 || snapshots[0].Content != diff || snapshots[0].Kind != SnapshotDiff || snapshots[0].AttributionID != id {
+		t.Fatalf("snapshots = %+v, want the stored diff", snapshots)
+	}
+
+	// Removing the attribution removes its snapshot.
+	if _, err := s.DB().Exec(`DELETE FROM attributions WHERE id = ?`, id); err != nil {
+		t.Fatal(err)
+	}
+	if snapshots, _ := s.QueryFileSnapshots("/p/a.go"); len(snapshots) != 0 {
+		t.Errorf("snapshots left after their attribution was deleted: %+v", snapshots)
+	}
+}
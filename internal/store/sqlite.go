@@ -1,8 +1,10 @@
 package store
 
 import (
+	"crypto/cipher"
 	"database/sql"
 	"fmt"
+	"os"
 	"time"
 
 	_ "modernc.org/sqlite" // Pure Go SQLite driver.
@@ -10,12 +12,69 @@ import (
 
 // Store wraps a SQLite database connection for the daemon.
 type Store struct {
-	db *sql.DB
+	db        *sql.DB
+	path      string
+	aead      cipher.AEAD // seals session content; nil for a plaintext database
+	keySource KeySource   // supplies the key of an encrypted database
 }
 
 // New opens (or creates) the SQLite database at dbPath with WAL mode
-// and a 5-second busy timeout, then runs any pending migrations.
-func New(dbPath string) (*Store, error) {
+// and a 5-second busy timeout, then runs any pending migrations. A
+// database SQLite cannot read yields an error wrapping ErrCorrupt, and
+// an encrypted one whose key no WithKeySource option supplies one wrapping
+// ErrNoKey.
+func New(dbPath string, opts ...Option) (*Store, error) {
+	db, err := open(dbPath)
+	if err != nil {
+		return nil, corruptionHint(dbPath, err)
+	}
+
+	if err := runMigrations(db, dbPath); err != nil {
+		_ = db.Close()
+		return nil, corruptionHint(dbPath, fmt.Errorf("run migrations: %w", err))
+	}
+
+	s := &Store{db: db, path: dbPath}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if err := s.loadKey(); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// OpenReadOnly opens the existing database at dbPath for reading only, for
+// commands that must not change it: it is neither created nor migrated,
+// and writes fail. A missing database yields an error wrapping
+// os.ErrNotExist. Encrypted session content stays sealed.
+func OpenReadOnly(dbPath string) (*Store, error) {
+	if _, err := os.Stat(dbPath); err != nil {
+		return nil, err
+	}
+	db, err := sql.Open("sqlite", fmt.Sprintf("file:%s?mode=ro&_pragma=busy_timeout(5000)", dbPath))
+	if err != nil {
+		return nil, fmt.Errorf("open database: %w", err)
+	}
+	if err := db.Ping(); err != nil {
+		_ = db.Close()
+		return nil, corruptionHint(dbPath, fmt.Errorf("open database: %w", err))
+	}
+	return &Store{db: db, path: dbPath}, nil
+}
+
+// corruptionHint turns SQLite's corruption errors into ErrCorrupt with a
+// pointer to "gapmap repair"; other errors are returned as they are.
+func corruptionHint(dbPath string, err error) error {
+	if !isCorruption(err) {
+		return err
+	}
+	return fmt.Errorf("%w: %s (%v); run \"gapmap repair\" to salvage it", ErrCorrupt, dbPath, err)
+}
+
+// open opens the SQLite database at dbPath in WAL mode with foreign keys on.
+func open(dbPath string) (*sql.DB, error) {
 	dsn := fmt.Sprintf("file:%s?_pragma=journal_mode(wal)&_pragma=busy_timeout(5000)&_pragma=foreign_keys(on)", dbPath)
 
 	db, err := sql.Open("sqlite", dsn)
@@ -34,12 +93,7 @@ func New(dbPath string) (*Store, error) {
 		return nil, fmt.Errorf("expected WAL journal mode, got %q", journalMode)
 	}
 
-	if err := runMigrations(db); err != nil {
-		_ = db.Close()
-		return nil, fmt.Errorf("run migrations: %w", err)
-	}
-
-	return &Store{db: db}, nil
+	return db, nil
 }
 
 // Close closes the underlying database connection.
@@ -77,8 +131,13 @@ func (s *Store) GitCommitsCount() (int64, error) {
 	return count, err
 }
 
-// InsertFileEvent records a file system event in the store.
+// InsertFileEvent records a file system event in the store. Paths are
+// stored as ResolvePath returns them, as are those of every other event
+// and attribution insert.
 func (s *Store) InsertFileEvent(projectPath, filePath, eventType string, timestamp time.Time) error {
+	if err := s.resolvePaths(&projectPath, &filePath); err != nil {
+		return err
+	}
 	_, err := s.db.Exec(
 		`INSERT INTO file_events (project_path, file_path, event_type, timestamp)
 		 VALUES (?, ?, ?, ?)`,
@@ -88,14 +147,99 @@ func (s *Store) InsertFileEvent(projectPath, filePath, eventType string, timesta
 }
 
 // InsertSessionEvent records an AI tool session event in the store.
+// Re-ingesting the same JSONL line for a session is a no-op.
 func (s *Store) InsertSessionEvent(sessionID, eventType, toolName, filePath, contentHash string, timestamp time.Time, rawJSON string, linesChanged int) error {
-	_, err := s.db.Exec(
-		`INSERT INTO session_events (session_id, event_type, tool_name, file_path, content_hash, timestamp, raw_json, lines_changed)
-		 VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
-		sessionID, eventType, toolName, filePath, contentHash,
-		timestamp.UTC().Format(time.RFC3339Nano), rawJSON, linesChanged,
-	)
-	return err
+	return s.InsertSessionEventWithContext(sessionID, eventType, toolName, filePath, contentHash, timestamp, rawJSON, linesChanged, SessionEventContext{})
+}
+
+// SessionEventContext is what a session records around a tool call: the
+// model that made it, the AI client's version and the user prompt it
+// answers. Empty fields were not recorded.
+type SessionEventContext struct {
+	Model         string
+	ClientVersion string
+	PromptID      string // uuid of the prompt message
+	Prompt        string // redacted and truncated prompt text
+	Host          string // remote host a gapmap agent forwarded it from; '' for this one
+
+	// Content is what the call changed; nil when it was not extracted, in
+	// which case readers extract it from the raw JSON.
+	Content *SessionContent
+}
+
+// InsertSessionEventWithContext is InsertSessionEvent recording the
+// event's context.
+func (s *Store) InsertSessionEventWithContext(sessionID, eventType, toolName, filePath, contentHash string, timestamp time.Time, rawJSON string, linesChanged int, ec SessionEventContext) error {
+	return s.InsertSessionEvents([]NewSessionEvent{{
+		SessionID: sessionID, EventType: eventType, ToolName: toolName,
+		FilePath: filePath, ContentHash: contentHash, Timestamp: timestamp,
+		RawJSON: rawJSON, LinesChanged: linesChanged, Context: ec,
+	}})
+}
+
+// NewSessionEvent is a session event to store with InsertSessionEvents.
+type NewSessionEvent struct {
+	SessionID    string
+	EventType    string
+	ToolName     string
+	FilePath     string
+	ContentHash  string
+	Timestamp    time.Time
+	RawJSON      string
+	LinesChanged int
+	Context      SessionEventContext
+}
+
+// InsertSessionEvents records session events in one transaction, for a
+// burst of session lines. Events already stored are skipped, as by
+// InsertSessionEvent.
+func (s *Store) InsertSessionEvents(events []NewSessionEvent) error {
+	return s.insertSessionEvents(events, nil)
+}
+
+// InsertSessionEventsWithState is InsertSessionEvents setting the daemon
+// state key to value in the same transaction, so a cursor kept there
+// never disagrees with the events stored.
+func (s *Store) InsertSessionEventsWithState(events []NewSessionEvent, key, value string) error {
+	return s.insertSessionEvents(events, func(tx *sql.Tx) error {
+		return setDaemonState(tx, key, value)
+	})
+}
+
+// insertSessionEvents inserts events and runs finish, when not nil, in
+// one transaction.
+func (s *Store) insertSessionEvents(events []NewSessionEvent, finish func(tx *sql.Tx) error) error {
+	for i := range events {
+		if err := s.resolvePaths(&events[i].FilePath); err != nil {
+			return err
+		}
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	for _, e := range events {
+		ec := e.Context
+		added, deleted := s.contentArgs(ec.Content)
+		if _, err := tx.Exec(
+			`INSERT OR IGNORE INTO session_events (session_id, event_type, tool_name, file_path, content_hash, timestamp, raw_json, lines_changed, dedupe_key, model, client_version, prompt_id, prompt, host, diff_content, deleted_content)
+			 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			e.SessionID, e.EventType, e.ToolName, e.FilePath, e.ContentHash,
+			e.Timestamp.UTC().Format(time.RFC3339Nano), s.seal(e.RawJSON), e.LinesChanged, sessionEventKey(e.RawJSON),
+			ec.Model, ec.ClientVersion, ec.PromptID, s.seal(ec.Prompt), ec.Host, added, deleted,
+		); err != nil {
+			return err
+		}
+	}
+	if finish != nil {
+		if err := finish(tx); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
 }
 
 // GetDaemonState reads a value from the daemon_state key-value table.
@@ -111,8 +255,17 @@ func (s *Store) GetDaemonState(key string) (string, error) {
 
 // SetDaemonState upserts a value in the daemon_state key-value table.
 func (s *Store) SetDaemonState(key, value string) error {
+	return setDaemonState(s.db, key, value)
+}
+
+// execer is a *sql.DB or a *sql.Tx.
+type execer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+}
+
+func setDaemonState(db execer, key, value string) error {
 	now := time.Now().UTC().Format(time.RFC3339)
-	_, err := s.db.Exec(
+	_, err := db.Exec(
 		`INSERT INTO daemon_state (key, value, updated_at) VALUES (?, ?, ?)
 		 ON CONFLICT(key) DO UPDATE SET value = excluded.value, updated_at = excluded.updated_at`,
 		key, value, now,
@@ -225,15 +378,19 @@ type FileEvent struct {
 
 // StoredSessionEvent represents a session event row from the store.
 type StoredSessionEvent struct {
-	ID           int64
-	SessionID    string
-	EventType    string
-	ToolName     string
-	FilePath     string
-	ContentHash  string
-	Timestamp    time.Time
-	LinesChanged int
-	Branch       string
+	ID            int64
+	SessionID     string
+	EventType     string
+	ToolName      string
+	FilePath      string
+	ContentHash   string
+	Timestamp     time.Time
+	LinesChanged  int
+	Branch        string
+	Model         string // e.g. "claude-opus-4-1-20250805"; "" if not recorded
+	ClientVersion string // version of the AI client; "" if not recorded
+	PromptID      string // uuid of the user prompt the call answers; "" if unknown
+	Prompt        string // that prompt, redacted and truncated
 }
 
 // AttributionRecord represents a row in the attributions table.
@@ -251,20 +408,30 @@ type AttributionRecord struct {
 	Timestamp           time.Time
 	LinesChanged        int
 	Branch              string
+	Kind                string // AttributionAddition (default) or AttributionDeletion
+	MatchType           string // how it was correlated, e.g. "exact_file"; MatchManual when asserted
 }
 
+// Attribution kinds. A deletion attribution's LinesChanged counts lines
+// removed rather than written.
+const (
+	AttributionAddition = "addition"
+	AttributionDeletion = "deletion"
+)
+
 // ---------------------------------------------------------------------------
 // Query methods for correlation engine
 // ---------------------------------------------------------------------------
 
 // QueryFileEventsInWindow returns file events for a given file path within
-// a time window [start, end], ordered by timestamp ascending.
+// a time window [start, end], ordered by timestamp ascending. Events made
+// by git operations are excluded here and in the other correlation queries.
 func (s *Store) QueryFileEventsInWindow(filePath string, start, end time.Time) ([]FileEvent, error) {
 	rows, err := s.db.Query(
 		`SELECT id, project_path, file_path, event_type, timestamp
 		 FROM file_events
-		 WHERE file_path = ? AND timestamp >= ? AND timestamp <= ?
-		 ORDER BY timestamp ASC`,
+		 WHERE file_path = ? AND timestamp >= ? AND timestamp <= ? AND origin = ''
+		 ORDER BY timestamp ASC, id ASC`,
 		filePath,
 		start.UTC().Format(time.RFC3339Nano),
 		end.UTC().Format(time.RFC3339Nano),
@@ -283,8 +450,8 @@ func (s *Store) QueryFileEventsByProject(projectPath string, since time.Time) ([
 	rows, err := s.db.Query(
 		`SELECT id, project_path, file_path, event_type, timestamp
 		 FROM file_events
-		 WHERE project_path = ? AND timestamp >= ?
-		 ORDER BY timestamp ASC`,
+		 WHERE project_path = ? AND timestamp >= ? AND origin = ''
+		 ORDER BY timestamp ASC, id ASC`,
 		projectPath,
 		since.UTC().Format(time.RFC3339Nano),
 	)
@@ -301,10 +468,10 @@ func (s *Store) QueryFileEventsByProject(projectPath string, since time.Time) ([
 // ordered by timestamp ascending.
 func (s *Store) QuerySessionEventsInWindow(filePath string, start, end time.Time) ([]StoredSessionEvent, error) {
 	rows, err := s.db.Query(
-		`SELECT id, session_id, event_type, tool_name, file_path, content_hash, timestamp, lines_changed
+		`SELECT id, session_id, event_type, tool_name, file_path, content_hash, timestamp, lines_changed, model, client_version, prompt_id, prompt
 		 FROM session_events
 		 WHERE file_path = ? AND tool_name IN ('Write', 'Edit') AND timestamp >= ? AND timestamp <= ?
-		 ORDER BY timestamp ASC`,
+		 ORDER BY timestamp ASC, id ASC`,
 		filePath,
 		start.UTC().Format(time.RFC3339Nano),
 		end.UTC().Format(time.RFC3339Nano),
@@ -314,7 +481,7 @@ func (s *Store) QuerySessionEventsInWindow(filePath string, start, end time.Time
 	}
 	defer rows.Close()
 
-	return scanSessionEvents(rows)
+	return s.scanSessionEvents(rows)
 }
 
 // QuerySessionEventsNearTimestamp returns Write/Edit session events within windowMs
@@ -327,10 +494,10 @@ func (s *Store) QuerySessionEventsNearTimestamp(timestamp time.Time, windowMs in
 	end := timestamp.Add(windowDur)
 
 	rows, err := s.db.Query(
-		`SELECT id, session_id, event_type, tool_name, file_path, content_hash, timestamp, lines_changed
+		`SELECT id, session_id, event_type, tool_name, file_path, content_hash, timestamp, lines_changed, model, client_version, prompt_id, prompt
 		 FROM session_events
 		 WHERE tool_name IN ('Write', 'Edit') AND timestamp >= ? AND timestamp <= ?
-		 ORDER BY timestamp ASC`,
+		 ORDER BY timestamp ASC, id ASC`,
 		start.UTC().Format(time.RFC3339Nano),
 		end.UTC().Format(time.RFC3339Nano),
 	)
@@ -339,7 +506,7 @@ func (s *Store) QuerySessionEventsNearTimestamp(timestamp time.Time, windowMs in
 	}
 	defer rows.Close()
 
-	return scanSessionEvents(rows)
+	return s.scanSessionEvents(rows)
 }
 
 // QueryAnySessionEventsNearTimestamp returns ALL session events (any tool_name)
@@ -351,10 +518,10 @@ func (s *Store) QueryAnySessionEventsNearTimestamp(timestamp time.Time, windowMs
 	end := timestamp.Add(windowDur)
 
 	rows, err := s.db.Query(
-		`SELECT id, session_id, event_type, tool_name, file_path, content_hash, timestamp, lines_changed
+		`SELECT id, session_id, event_type, tool_name, file_path, content_hash, timestamp, lines_changed, model, client_version, prompt_id, prompt
 		 FROM session_events
 		 WHERE timestamp >= ? AND timestamp <= ?
-		 ORDER BY timestamp ASC`,
+		 ORDER BY timestamp ASC, id ASC`,
 		start.UTC().Format(time.RFC3339Nano),
 		end.UTC().Format(time.RFC3339Nano),
 	)
@@ -363,7 +530,7 @@ func (s *Store) QueryAnySessionEventsNearTimestamp(timestamp time.Time, windowMs
 	}
 	defer rows.Close()
 
-	return scanSessionEvents(rows)
+	return s.scanSessionEvents(rows)
 }
 
 // ---------------------------------------------------------------------------
@@ -371,29 +538,104 @@ func (s *Store) QueryAnySessionEventsNearTimestamp(timestamp time.Time, windowMs
 // ---------------------------------------------------------------------------
 
 // InsertAttribution persists an attribution record and returns its row ID.
+// The file event it was made from, if any, is marked processed.
 func (s *Store) InsertAttribution(attr AttributionRecord) (int64, error) {
-	uncertain := 0
-	if attr.Uncertain {
-		uncertain = 1
-	}
-	result, err := s.db.Exec(
-		`INSERT INTO attributions
-		 (file_path, project_path, file_event_id, session_event_id, authorship_level,
-		  confidence, uncertain, first_author, correlation_window_ms, timestamp, created_at, lines_changed, branch)
-		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
-		attr.FilePath, attr.ProjectPath,
-		attr.FileEventID, attr.SessionEventID,
-		attr.AuthorshipLevel, attr.Confidence, uncertain,
-		attr.FirstAuthor, attr.CorrelationWindowMs,
-		attr.Timestamp.UTC().Format(time.RFC3339Nano),
-		time.Now().UTC().Format(time.RFC3339Nano),
-		attr.LinesChanged,
-		attr.Branch,
-	)
+	ids, err := s.recordAttributions([]AttributionRecord{attr}, "", false)
 	if err != nil {
 		return 0, err
 	}
-	return result.LastInsertId()
+	return ids[0], nil
+}
+
+// RecordAttributions persists the attributions computed for a file event,
+// with work type workType, and marks the file events they were made from
+// processed, in one transaction: a crash leaves all of them or none. If a
+// file event was already processed, by an earlier run that committed just
+// before crashing, nothing is recorded and no IDs are returned. Otherwise
+// it returns the records' row IDs.
+func (s *Store) RecordAttributions(records []AttributionRecord, workType string) ([]int64, error) {
+	return s.recordAttributions(records, workType, true)
+}
+
+// recordAttributions inserts records and marks their file events
+// processed. With claim, it records nothing if one of them already was.
+func (s *Store) recordAttributions(records []AttributionRecord, workType string, claim bool) ([]int64, error) {
+	for i := range records {
+		if err := s.resolvePaths(&records[i].ProjectPath, &records[i].FilePath); err != nil {
+			return nil, err
+		}
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	now := time.Now().UTC().Format(time.RFC3339Nano)
+	claimed := make(map[int64]bool)
+	for _, attr := range records {
+		if attr.FileEventID == nil || claimed[*attr.FileEventID] {
+			continue
+		}
+		res, err := tx.Exec(
+			`UPDATE file_events SET processed_at = ? WHERE id = ? AND processed_at = ''`,
+			now, *attr.FileEventID,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("mark file event %d processed: %w", *attr.FileEventID, err)
+		}
+		if n, err := res.RowsAffected(); err != nil {
+			return nil, err
+		} else if n == 0 && claim {
+			var exists bool
+			if err := tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM file_events WHERE id = ?)`, *attr.FileEventID).Scan(&exists); err != nil {
+				return nil, err
+			}
+			if exists {
+				return nil, nil // already attributed
+			}
+		}
+		claimed[*attr.FileEventID] = true
+	}
+
+	ids := make([]int64, 0, len(records))
+	for _, attr := range records {
+		uncertain := 0
+		if attr.Uncertain {
+			uncertain = 1
+		}
+		kind := attr.Kind
+		if kind == "" {
+			kind = AttributionAddition
+		}
+		result, err := tx.Exec(
+			`INSERT INTO attributions
+			 (file_path, project_path, file_event_id, session_event_id, authorship_level,
+			  confidence, uncertain, first_author, correlation_window_ms, timestamp, created_at, lines_changed, branch, kind, match_type, work_type)
+			 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			attr.FilePath, attr.ProjectPath,
+			attr.FileEventID, attr.SessionEventID,
+			attr.AuthorshipLevel, attr.Confidence, uncertain,
+			attr.FirstAuthor, attr.CorrelationWindowMs,
+			attr.Timestamp.UTC().Format(time.RFC3339Nano),
+			now,
+			attr.LinesChanged,
+			attr.Branch,
+			kind,
+			attr.MatchType,
+			workType,
+		)
+		if err != nil {
+			return nil, err
+		}
+		id, err := result.LastInsertId()
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, tx.Commit()
 }
 
 // QueryAttributionsByFile returns all attributions for a file, ordered by
@@ -404,8 +646,8 @@ func (s *Store) QueryAttributionsByFile(filePath string) ([]AttributionRecord, e
 		        authorship_level, confidence, uncertain, first_author,
 		        correlation_window_ms, timestamp, lines_changed
 		 FROM attributions
-		 WHERE file_path = ?
-		 ORDER BY timestamp ASC`,
+		 WHERE file_path = ? AND kind = 'addition'
+		 ORDER BY timestamp ASC, id ASC`,
 		filePath,
 	)
 	if err != nil {
@@ -424,8 +666,8 @@ func (s *Store) QueryLatestAttributionByFile(filePath string) (*AttributionRecor
 		        authorship_level, confidence, uncertain, first_author,
 		        correlation_window_ms, timestamp, lines_changed
 		 FROM attributions
-		 WHERE file_path = ?
-		 ORDER BY timestamp DESC
+		 WHERE file_path = ? AND kind = 'addition'
+		 ORDER BY timestamp DESC, id DESC
 		 LIMIT 1`,
 		filePath,
 	)
@@ -452,8 +694,8 @@ func (s *Store) QueryAttributionsByProject(projectPath string) ([]AttributionRec
 		        authorship_level, confidence, uncertain, first_author,
 		        correlation_window_ms, timestamp, lines_changed
 		 FROM attributions
-		 WHERE project_path = ?
-		 ORDER BY timestamp ASC`,
+		 WHERE project_path = ? AND kind = 'addition'
+		 ORDER BY timestamp ASC, id ASC`,
 		projectPath,
 	)
 	if err != nil {
@@ -468,21 +710,20 @@ func (s *Store) QueryAttributionsByProject(projectPath string) ([]AttributionRec
 // Unprocessed file events query (for attribution pipeline)
 // ---------------------------------------------------------------------------
 
-// QueryUnprocessedFileEvents returns file events that have no matching
-// attribution record. Uses a LEFT JOIN against attributions on file_event_id
-// to find unprocessed events. Ordered by timestamp ascending so oldest events
-// are processed first. Limits to batchSize rows per call to bound processing
-// time. If batchSize <= 0, defaults to 100.
+// QueryUnprocessedFileEvents returns file events the attribution processor
+// has not yet recorded attributions for. Higher-priority events come first and, within
+// a priority, the newest, so a burst of bulk events never delays fresh ones.
+// Limits to batchSize rows per call to bound processing time. If
+// batchSize <= 0, defaults to 100.
 func (s *Store) QueryUnprocessedFileEvents(batchSize int) ([]FileEvent, error) {
 	if batchSize <= 0 {
 		batchSize = 100
 	}
 	rows, err := s.db.Query(
-		`SELECT fe.id, fe.project_path, fe.file_path, fe.event_type, fe.timestamp
-		 FROM file_events fe
-		 LEFT JOIN attributions a ON a.file_event_id = fe.id
-		 WHERE a.id IS NULL
-		 ORDER BY fe.timestamp ASC
+		`SELECT id, project_path, file_path, event_type, timestamp
+		 FROM file_events
+		 WHERE processed_at = '' AND origin = ''
+		 ORDER BY priority DESC, timestamp DESC
 		 LIMIT ?`,
 		batchSize,
 	)
@@ -554,8 +795,8 @@ func (s *Store) QueryAttributionsWithWorkType(projectPath string) ([]Attribution
 		        authorship_level, confidence, uncertain, first_author,
 		        correlation_window_ms, timestamp, work_type, lines_changed
 		 FROM attributions
-		 WHERE project_path = ? AND work_type != ''
-		 ORDER BY timestamp ASC`,
+		 WHERE project_path = ? AND kind = 'addition' AND work_type != ''
+		 ORDER BY timestamp ASC, id ASC`,
 		projectPath,
 	)
 	if err != nil {
@@ -574,8 +815,8 @@ func (s *Store) QueryAttributionsByFileWithWorkType(filePath string) ([]Attribut
 		        authorship_level, confidence, uncertain, first_author,
 		        correlation_window_ms, timestamp, work_type, lines_changed
 		 FROM attributions
-		 WHERE file_path = ? AND work_type != ''
-		 ORDER BY timestamp ASC`,
+		 WHERE file_path = ? AND kind = 'addition' AND work_type != ''
+		 ORDER BY timestamp ASC, id ASC`,
 		filePath,
 	)
 	if err != nil {
@@ -608,12 +849,12 @@ func scanFileEvents(rows *sql.Rows) ([]FileEvent, error) {
 	return events, rows.Err()
 }
 
-func scanSessionEvents(rows *sql.Rows) ([]StoredSessionEvent, error) {
+func (s *Store) scanSessionEvents(rows *sql.Rows) ([]StoredSessionEvent, error) {
 	var events []StoredSessionEvent
 	for rows.Next() {
 		var se StoredSessionEvent
 		var ts string
-		if err := rows.Scan(&se.ID, &se.SessionID, &se.EventType, &se.ToolName, &se.FilePath, &se.ContentHash, &ts, &se.LinesChanged); err != nil {
+		if err := rows.Scan(&se.ID, &se.SessionID, &se.EventType, &se.ToolName, &se.FilePath, &se.ContentHash, &ts, &se.LinesChanged, &se.Model, &se.ClientVersion, &se.PromptID, &se.Prompt); err != nil {
 			return nil, err
 		}
 		t, err := time.Parse(time.RFC3339Nano, ts)
@@ -621,6 +862,9 @@ func scanSessionEvents(rows *sql.Rows) ([]StoredSessionEvent, error) {
 			return nil, fmt.Errorf("parse session_event timestamp %q: %w", ts, err)
 		}
 		se.Timestamp = t
+		if se.Prompt, err = s.unseal(se.Prompt); err != nil {
+			return nil, fmt.Errorf("session event %d prompt: %w", se.ID, err)
+		}
 		events = append(events, se)
 	}
 	return events, rows.Err()
@@ -686,11 +930,11 @@ func (s *Store) QuerySessionEventByID(id int64) (*StoredSessionEvent, error) {
 	var se StoredSessionEvent
 	var ts string
 	err := s.db.QueryRow(
-		`SELECT id, session_id, event_type, tool_name, file_path, content_hash, timestamp, lines_changed
+		`SELECT id, session_id, event_type, tool_name, file_path, content_hash, timestamp, lines_changed, model, client_version, prompt_id, prompt
 		 FROM session_events
 		 WHERE id = ?`,
 		id,
-	).Scan(&se.ID, &se.SessionID, &se.EventType, &se.ToolName, &se.FilePath, &se.ContentHash, &ts, &se.LinesChanged)
+	).Scan(&se.ID, &se.SessionID, &se.EventType, &se.ToolName, &se.FilePath, &se.ContentHash, &ts, &se.LinesChanged, &se.Model, &se.ClientVersion, &se.PromptID, &se.Prompt)
 	if err != nil {
 		return nil, err
 	}
@@ -699,30 +943,35 @@ func (s *Store) QuerySessionEventByID(id int64) (*StoredSessionEvent, error) {
 		return nil, fmt.Errorf("parse session_event timestamp %q: %w", ts, err)
 	}
 	se.Timestamp = t
+	if se.Prompt, err = s.unseal(se.Prompt); err != nil {
+		return nil, fmt.Errorf("session event %d prompt: %w", se.ID, err)
+	}
 	return &se, nil
 }
 
 // QuerySessionEventRawJSON returns the raw_json column for a session event by ID.
 func (s *Store) QuerySessionEventRawJSON(id int64) (string, error) {
 	var rawJSON string
-	err := s.db.QueryRow(`SELECT raw_json FROM session_events WHERE id = ?`, id).Scan(&rawJSON)
-	return rawJSON, err
+	if err := s.db.QueryRow(`SELECT raw_json FROM session_events WHERE id = ?`, id).Scan(&rawJSON); err != nil {
+		return "", err
+	}
+	return s.unseal(rawJSON)
 }
 
 // QueryWriteEditSessionEvents returns all Write/Edit session events with their
 // file_path and raw_json. Used for line-level attribution against current files.
 func (s *Store) QueryWriteEditSessionEvents() ([]StoredSessionEvent, error) {
 	rows, err := s.db.Query(
-		`SELECT id, session_id, event_type, tool_name, file_path, content_hash, timestamp, lines_changed
+		`SELECT id, session_id, event_type, tool_name, file_path, content_hash, timestamp, lines_changed, model, client_version, prompt_id, prompt
 		 FROM session_events
 		 WHERE tool_name IN ('Write', 'Edit')
-		 ORDER BY timestamp ASC`,
+		 ORDER BY timestamp ASC, id ASC`,
 	)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	return scanSessionEvents(rows)
+	return s.scanSessionEvents(rows)
 }
 
 // QueryEarliestAttributionTimestamp returns the earliest attribution timestamp
@@ -730,7 +979,7 @@ func (s *Store) QueryWriteEditSessionEvents() ([]StoredSessionEvent, error) {
 func (s *Store) QueryEarliestAttributionTimestamp(filePath string) (string, error) {
 	var ts string
 	err := s.db.QueryRow(
-		`SELECT MIN(timestamp) FROM attributions WHERE file_path = ?`,
+		`SELECT MIN(timestamp) FROM attributions WHERE file_path = ? AND kind = 'addition'`,
 		filePath,
 	).Scan(&ts)
 	if err != nil {
@@ -739,59 +988,6 @@ func (s *Store) QueryEarliestAttributionTimestamp(filePath string) (string, erro
 	return ts, nil
 }
 
-// InsertSurvivalRecord persists a code survival check result.
-func (s *Store) InsertSurvivalRecord(filePath, projectPath string, attributionID int64, survived bool, blameCommitHash string) error {
-	surv := 0
-	if survived {
-		surv = 1
-	}
-	_, err := s.db.Exec(
-		`INSERT INTO code_survival (file_path, project_path, attribution_id, survived, checked_at, blame_commit_hash)
-		 VALUES (?, ?, ?, ?, ?, ?)`,
-		filePath, projectPath, attributionID, surv,
-		time.Now().UTC().Format(time.RFC3339), blameCommitHash,
-	)
-	return err
-}
-
-// SurvivalRecord represents a row in the code_survival table.
-type SurvivalRecord struct {
-	ID              int64
-	FilePath        string
-	ProjectPath     string
-	AttributionID   int64
-	Survived        bool
-	CheckedAt       string
-	BlameCommitHash string
-}
-
-// QuerySurvivalByProject returns all survival records for a project.
-func (s *Store) QuerySurvivalByProject(projectPath string) ([]SurvivalRecord, error) {
-	rows, err := s.db.Query(
-		`SELECT id, file_path, project_path, attribution_id, survived, checked_at, blame_commit_hash
-		 FROM code_survival
-		 WHERE project_path = ?
-		 ORDER BY id ASC`,
-		projectPath,
-	)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var records []SurvivalRecord
-	for rows.Next() {
-		var r SurvivalRecord
-		var surv int
-		if err := rows.Scan(&r.ID, &r.FilePath, &r.ProjectPath, &r.AttributionID, &surv, &r.CheckedAt, &r.BlameCommitHash); err != nil {
-			return nil, err
-		}
-		r.Survived = surv != 0
-		records = append(records, r)
-	}
-	return records, rows.Err()
-}
-
 func scanAttributionsWithWorkType(rows *sql.Rows) ([]AttributionWithWorkType, error) {
 	var records []AttributionWithWorkType
 	for rows.Next() {
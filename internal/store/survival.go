@@ -0,0 +1,221 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// SurvivalRecord represents a row in the code_survival table: whether an AI
+// attribution survived in git blame when a survival snapshot was taken.
+type SurvivalRecord struct {
+	ID              int64
+	FilePath        string
+	ProjectPath     string
+	AttributionID   int64
+	Survived        bool
+	CheckedAt       time.Time
+	BlameCommitHash string // commit blame gives the surviving content, if any
+	AuthorshipLevel string
+	WorkType        string
+	Model           string
+	LinesChanged    int
+	FileAge         string // FileAgeNew, FileAgeExisting, or '' if not recorded
+	Corrected       *bool  // a human changed the lines soon after; nil if unknown
+}
+
+// Values of SurvivalRecord.FileAge.
+const (
+	FileAgeNew      = "new"      // the file was created while it was watched
+	FileAgeExisting = "existing" // the file existed before
+)
+
+// SurvivalPoint is the survival of a project at one snapshot.
+type SurvivalPoint struct {
+	CheckedAt     time.Time `json:"checked_at"`
+	Tracked       int       `json:"tracked"`
+	Survived      int       `json:"survived"`
+	TrackedLines  int       `json:"tracked_lines"`
+	SurvivedLines int       `json:"survived_lines"`
+	Rate          float64   `json:"rate"`
+}
+
+// InsertSurvivalSnapshot records the survival of a project's attributions
+// checked at checkedAt, in one transaction. An empty snapshot records
+// nothing.
+func (s *Store) InsertSurvivalSnapshot(projectPath string, checkedAt time.Time, records []SurvivalRecord) error {
+	if len(records) == 0 {
+		return nil
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	stmt, err := tx.Prepare(
+		`INSERT INTO code_survival (file_path, project_path, attribution_id, survived, checked_at,
+		 blame_commit_hash, authorship_level, work_type, model, lines_changed, file_age, corrected)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+	)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	ts := checkedAt.UTC().Format(time.RFC3339)
+	for _, r := range records {
+		surv := 0
+		if r.Survived {
+			surv = 1
+		}
+		var corrected interface{}
+		if r.Corrected != nil {
+			corrected = *r.Corrected
+		}
+		if _, err := stmt.Exec(
+			r.FilePath, projectPath, r.AttributionID, surv, ts,
+			r.BlameCommitHash, r.AuthorshipLevel, r.WorkType, r.Model, r.LinesChanged, r.FileAge, corrected,
+		); err != nil {
+			return fmt.Errorf("insert survival of attribution %d: %w", r.AttributionID, err)
+		}
+	}
+	return tx.Commit()
+}
+
+// QuerySurvivalByProject returns all survival records for a project.
+func (s *Store) QuerySurvivalByProject(projectPath string) ([]SurvivalRecord, error) {
+	return s.querySurvival(`WHERE project_path = ? ORDER BY id ASC`, projectPath)
+}
+
+// QueryLatestSurvival returns the records of a project's most recent
+// survival snapshot, or none if no snapshot was taken.
+func (s *Store) QueryLatestSurvival(projectPath string) ([]SurvivalRecord, error) {
+	return s.querySurvival(
+		`WHERE project_path = ? AND checked_at =
+		 (SELECT MAX(checked_at) FROM code_survival WHERE project_path = ?)
+		 ORDER BY id ASC`,
+		projectPath, projectPath,
+	)
+}
+
+// QuerySurvivalHistory returns the survival of a project at each of its
+// snapshots, oldest first.
+func (s *Store) QuerySurvivalHistory(projectPath string) ([]SurvivalPoint, error) {
+	rows, err := s.db.Query(
+		`SELECT checked_at, COUNT(*), SUM(survived), SUM(lines_changed), SUM(survived * lines_changed)
+		 FROM code_survival WHERE project_path = ?
+		 GROUP BY checked_at ORDER BY checked_at ASC`,
+		projectPath,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var points []SurvivalPoint
+	for rows.Next() {
+		var p SurvivalPoint
+		var ts string
+		if err := rows.Scan(&ts, &p.Tracked, &p.Survived, &p.TrackedLines, &p.SurvivedLines); err != nil {
+			return nil, err
+		}
+		t, err := time.Parse(time.RFC3339, ts)
+		if err != nil {
+			return nil, fmt.Errorf("parse survival timestamp %q: %w", ts, err)
+		}
+		p.CheckedAt = t
+		if p.Tracked > 0 {
+			p.Rate = float64(p.Survived) / float64(p.Tracked) * 100.0
+		}
+		points = append(points, p)
+	}
+	return points, rows.Err()
+}
+
+// querySurvival returns the code_survival rows selected by clause.
+func (s *Store) querySurvival(clause string, args ...interface{}) ([]SurvivalRecord, error) {
+	rows, err := s.db.Query(
+		`SELECT id, file_path, project_path, attribution_id, survived, checked_at, blame_commit_hash,
+		 authorship_level, work_type, model, lines_changed, file_age, corrected
+		 FROM code_survival `+clause,
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []SurvivalRecord
+	for rows.Next() {
+		var r SurvivalRecord
+		var surv int
+		var ts string
+		var corrected sql.NullBool
+		if err := rows.Scan(
+			&r.ID, &r.FilePath, &r.ProjectPath, &r.AttributionID, &surv, &ts, &r.BlameCommitHash,
+			&r.AuthorshipLevel, &r.WorkType, &r.Model, &r.LinesChanged, &r.FileAge, &corrected,
+		); err != nil {
+			return nil, err
+		}
+		t, err := time.Parse(time.RFC3339, ts)
+		if err != nil {
+			return nil, fmt.Errorf("parse survival timestamp %q: %w", ts, err)
+		}
+		r.Survived = surv != 0
+		r.CheckedAt = t
+		if corrected.Valid {
+			r.Corrected = &corrected.Bool
+		}
+		records = append(records, r)
+	}
+	return records, rows.Err()
+}
+
+// QueryCreatedFiles returns the files of a project whose first recorded
+// file event is a create: files that did not exist before they were
+// watched.
+func (s *Store) QueryCreatedFiles(projectPath string) (map[string]bool, error) {
+	// SQLite takes event_type from the row MIN(id) picks.
+	rows, err := s.db.Query(
+		`SELECT file_path, event_type, MIN(id) FROM file_events
+		 WHERE project_path = ? GROUP BY file_path`,
+		projectPath,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	created := make(map[string]bool)
+	for rows.Next() {
+		var path, eventType string
+		var id int64
+		if err := rows.Scan(&path, &eventType, &id); err != nil {
+			return nil, err
+		}
+		if eventType == "create" {
+			created[path] = true
+		}
+	}
+	return created, rows.Err()
+}
+
+// QueryAttributedProjects returns the projects that have attributions.
+func (s *Store) QueryAttributedProjects() ([]string, error) {
+	rows, err := s.db.Query(`SELECT DISTINCT project_path FROM attributions ORDER BY project_path`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var projects []string
+	for rows.Next() {
+		var p string
+		if err := rows.Scan(&p); err != nil {
+			return nil, err
+		}
+		projects = append(projects, p)
+	}
+	return projects, rows.Err()
+}
@@ -0,0 +1,83 @@
+package store
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSurvivalSnapshots(t *testing.T) {
+	s, err := New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	defer s.Close()
+
+	t0 := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	var ids []int64
+	for i, path := range []string{"/p/a.go", "/p/b.go"} {
+		id, err := s.InsertAttribution(AttributionRecord{
+			FilePath: path, ProjectPath: "/p", AuthorshipLevel: "mostly_ai",
+			Confidence: 1, FirstAuthor: "ai", Timestamp: t0, LinesChanged: 10 * (i + 1),
+		})
+		if err != nil {
+			t.Fatalf("InsertAttribution: %v", err)
+		}
+		ids = append(ids, id)
+	}
+
+	snapshot := func(at time.Time, survivedB bool) {
+		t.Helper()
+		err := s.InsertSurvivalSnapshot("/p", at, []SurvivalRecord{
+			{FilePath: "/p/a.go", AttributionID: ids[0], Survived: true, BlameCommitHash: "abc", AuthorshipLevel: "mostly_ai", WorkType: "core_logic", Model: "m", LinesChanged: 10},
+			{FilePath: "/p/b.go", AttributionID: ids[1], Survived: survivedB, AuthorshipLevel: "mostly_ai", WorkType: "boilerplate", Model: "m", LinesChanged: 20},
+		})
+		if err != nil {
+			t.Fatalf("InsertSurvivalSnapshot: %v", err)
+		}
+	}
+	snapshot(t0, true)
+	snapshot(t0.Add(time.Hour), false)
+
+	latest, err := s.QueryLatestSurvival("/p")
+	if err != nil {
+		t.Fatalf("QueryLatestSurvival: %v", err)
+	}
+	if len(latest) != 2 || !latest[0].CheckedAt.Equal(t0.Add(time.Hour)) || latest[1].Survived || latest[1].WorkType != "boilerplate" {
+		t.Errorf("QueryLatestSurvival = %+v, want the second snapshot", latest)
+	}
+
+	history, err := s.QuerySurvivalHistory("/p")
+	if err != nil {
+		t.Fatalf("QuerySurvivalHistory: %v", err)
+	}
+	want := []SurvivalPoint{
+		{CheckedAt: t0, Tracked: 2, Survived: 2, TrackedLines: 30, SurvivedLines: 30, Rate: 100},
+		{CheckedAt: t0.Add(time.Hour), Tracked: 2, Survived: 1, TrackedLines: 30, SurvivedLines: 10, Rate: 50},
+	}
+	if len(history) != len(want) {
+		t.Fatalf("QuerySurvivalHistory = %+v, want %+v", history, want)
+	}
+	for i := range want {
+		if !history[i].CheckedAt.Equal(want[i].CheckedAt) || history[i].Tracked != want[i].Tracked ||
+			history[i].Survived != want[i].Survived || history[i].SurvivedLines != want[i].SurvivedLines || history[i].Rate != want[i].Rate {
+			t.Errorf("history[%d] = %+v, want %+v", i, history[i], want[i])
+		}
+	}
+
+	// History goes with its attribution.
+	if _, err := s.DB().Exec(`DELETE FROM attributions WHERE id = ?`, ids[1]); err != nil {
+		t.Fatalf("delete attribution: %v", err)
+	}
+	records, err := s.QuerySurvivalByProject("/p")
+	if err != nil {
+		t.Fatalf("QuerySurvivalByProject: %v", err)
+	}
+	if len(records) != 2 {
+		t.Errorf("QuerySurvivalByProject after delete = %d records, want 2", len(records))
+	}
+
+	if latest, err := s.QueryLatestSurvival("/other"); err != nil || len(latest) != 0 {
+		t.Errorf("QueryLatestSurvival(/other) = %v, %v, want none", latest, err)
+	}
+}
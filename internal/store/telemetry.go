@@ -0,0 +1,75 @@
+package store
+
+import (
+	"time"
+)
+
+// QueryToolUsageSince returns the number of session events per tool name
+// recorded at or after since. Only tool names are returned -- never paths
+// or content -- so the result is safe for aggregate reporting.
+func (s *Store) QueryToolUsageSince(since time.Time) (map[string]int, error) {
+	rows, err := s.db.Query(
+		`SELECT tool_name, COUNT(*)
+		 FROM session_events
+		 WHERE timestamp >= ? AND tool_name != ''
+		 GROUP BY tool_name`,
+		since.UTC().Format(time.RFC3339Nano),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	usage := make(map[string]int)
+	for rows.Next() {
+		var name string
+		var count int
+		if err := rows.Scan(&name, &count); err != nil {
+			return nil, err
+		}
+		usage[name] = count
+	}
+	return usage, rows.Err()
+}
+
+// QueryAttributionLineTotals returns the total lines changed and the lines
+// changed by AI-authored attributions recorded at or after since.
+// Attributions without a line count contribute one line each.
+func (s *Store) QueryAttributionLineTotals(since time.Time) (aiLines, totalLines int, err error) {
+	err = s.db.QueryRow(
+		`SELECT
+		   COALESCE(SUM(CASE WHEN authorship_level IN ('mostly_ai', 'fully_ai', 'ai_first_human_revised', 'ai_suggested_human_written')
+		                     THEN MAX(lines_changed, 1) ELSE 0 END), 0),
+		   COALESCE(SUM(MAX(lines_changed, 1)), 0)
+		 FROM attributions
+		 WHERE timestamp >= ? AND kind = 'addition'`,
+		since.UTC().Format(time.RFC3339Nano),
+	).Scan(&aiLines, &totalLines)
+	return aiLines, totalLines, err
+}
+
+// QueryContributionTotals returns the lines added by attributions recorded
+// in [start, end), split by what became of them: AI-authored lines whose
+// latest survival check found them still in place, human-authored lines,
+// and AI-authored lines a later survival check found rewritten. AI lines
+// never checked for survival are in neither AI total. Attributions without
+// a line count contribute one line each.
+func (s *Store) QueryContributionTotals(start, end time.Time) (survivingAI, human, correctedAI int, err error) {
+	err = s.db.QueryRow(
+		`SELECT
+		   COALESCE(SUM(CASE WHEN ai AND survived = 1 THEN n ELSE 0 END), 0),
+		   COALESCE(SUM(CASE WHEN NOT ai THEN n ELSE 0 END), 0),
+		   COALESCE(SUM(CASE WHEN ai AND survived = 0 THEN n ELSE 0 END), 0)
+		 FROM (
+		   SELECT MAX(a.lines_changed, 1) AS n,
+		          a.authorship_level IN ('mostly_ai', 'fully_ai', 'ai_first_human_revised', 'ai_suggested_human_written') AS ai,
+		          (SELECT cs.survived FROM code_survival cs
+		           WHERE cs.attribution_id = a.id
+		           ORDER BY cs.checked_at DESC, cs.id DESC LIMIT 1) AS survived
+		   FROM attributions a
+		   WHERE a.kind = 'addition' AND a.timestamp >= ? AND a.timestamp < ?
+		 )`,
+		start.UTC().Format(time.RFC3339Nano), end.UTC().Format(time.RFC3339Nano),
+	).Scan(&survivingAI, &human, &correctedAI)
+	return survivingAI, human, correctedAI, err
+}
@@ -0,0 +1,69 @@
+package store
+
+import (
+	"fmt"
+	"time"
+)
+
+// TypingBurst is a span of human typing in one file, reported by an editor
+// plugin.
+type TypingBurst struct {
+	ID         int64
+	FilePath   string
+	StartedAt  time.Time
+	EndedAt    time.Time
+	CharsTyped int
+	Source     string // reporting editor, e.g. "neovim"
+}
+
+// InsertTypingBurst records a typing burst and returns its row ID.
+func (s *Store) InsertTypingBurst(b TypingBurst) (int64, error) {
+	result, err := s.db.Exec(
+		`INSERT INTO typing_bursts (file_path, started_at, ended_at, chars_typed, source)
+		 VALUES (?, ?, ?, ?, ?)`,
+		b.FilePath,
+		b.StartedAt.UTC().Format(time.RFC3339Nano),
+		b.EndedAt.UTC().Format(time.RFC3339Nano),
+		b.CharsTyped,
+		b.Source,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+// QueryTypingBurstsInWindow returns the typing bursts on filePath that
+// overlap [start, end], ordered by end time.
+func (s *Store) QueryTypingBurstsInWindow(filePath string, start, end time.Time) ([]TypingBurst, error) {
+	rows, err := s.db.Query(
+		`SELECT id, file_path, started_at, ended_at, chars_typed, source
+		 FROM typing_bursts
+		 WHERE file_path = ? AND ended_at >= ? AND started_at <= ?
+		 ORDER BY ended_at ASC`,
+		filePath,
+		start.UTC().Format(time.RFC3339Nano),
+		end.UTC().Format(time.RFC3339Nano),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var bursts []TypingBurst
+	for rows.Next() {
+		var b TypingBurst
+		var started, ended string
+		if err := rows.Scan(&b.ID, &b.FilePath, &started, &ended, &b.CharsTyped, &b.Source); err != nil {
+			return nil, err
+		}
+		if b.StartedAt, err = time.Parse(time.RFC3339Nano, started); err != nil {
+			return nil, fmt.Errorf("parse typing burst start %q: %w", started, err)
+		}
+		if b.EndedAt, err = time.Parse(time.RFC3339Nano, ended); err != nil {
+			return nil, fmt.Errorf("parse typing burst end %q: %w", ended, err)
+		}
+		bursts = append(bursts, b)
+	}
+	return bursts, rows.Err()
+}
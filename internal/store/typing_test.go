@@ -0,0 +1,42 @@
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+func TestQueryTypingBurstsInWindow_Overlap(t *testing.T) {
+	s := newTestStore(t)
+	base := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+
+	for _, b := range []TypingBurst{
+		{FilePath: "/p/a.go", StartedAt: base, EndedAt: base.Add(10 * time.Second), CharsTyped: 50, Source: "neovim"},
+		{FilePath: "/p/a.go", StartedAt: base.Add(time.Minute), EndedAt: base.Add(70 * time.Second), CharsTyped: 5},
+		{FilePath: "/p/b.go", StartedAt: base, EndedAt: base.Add(10 * time.Second), CharsTyped: 9},
+	} {
+		if _, err := s.InsertTypingBurst(b); err != nil {
+			t.Fatalf("InsertTypingBurst: %v", err)
+		}
+	}
+
+	// A window inside the first burst overlaps it, even though the burst
+	// started before the window.
+	got, err := s.QueryTypingBurstsInWindow("/p/a.go", base.Add(5*time.Second), base.Add(8*time.Second))
+	if err != nil {
+		t.Fatalf("QueryTypingBurstsInWindow: %v", err)
+	}
+	if len(got) != 1 || got[0].CharsTyped != 50 || got[0].Source != "neovim" {
+		t.Fatalf("got %+v, want the first a.go burst", got)
+	}
+	if !got[0].EndedAt.Equal(base.Add(10 * time.Second)) {
+		t.Errorf("EndedAt = %v", got[0].EndedAt)
+	}
+
+	got, err = s.QueryTypingBurstsInWindow("/p/a.go", base.Add(20*time.Second), base.Add(30*time.Second))
+	if err != nil {
+		t.Fatalf("QueryTypingBurstsInWindow: %v", err)
+	}
+	if len(got) != 0 {
+		t.Errorf("got %d bursts between the two, want 0", len(got))
+	}
+}
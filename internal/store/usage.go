@@ -0,0 +1,85 @@
+package store
+
+import "time"
+
+// TokenUsage is the tokens billed for one API message of an AI session.
+type TokenUsage struct {
+	MessageID           string
+	SessionID           string
+	Model               string
+	InputTokens         int64
+	OutputTokens        int64
+	CacheCreationTokens int64
+	CacheReadTokens     int64
+	Timestamp           time.Time
+}
+
+// ModelTokenUsage is the token usage of one model summed over sessions.
+type ModelTokenUsage struct {
+	Model               string
+	Sessions            int
+	Messages            int
+	InputTokens         int64
+	OutputTokens        int64
+	CacheCreationTokens int64
+	CacheReadTokens     int64
+}
+
+// InsertTokenUsage records a message's token usage. A message already
+// recorded is left unchanged, so re-reading a session file does not count
+// its usage twice.
+func (s *Store) InsertTokenUsage(u TokenUsage) error {
+	_, err := s.db.Exec(
+		`INSERT OR IGNORE INTO token_usage
+		 (message_id, session_id, model, input_tokens, output_tokens, cache_creation_tokens, cache_read_tokens, timestamp)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		u.MessageID, u.SessionID, u.Model, u.InputTokens, u.OutputTokens,
+		u.CacheCreationTokens, u.CacheReadTokens, u.Timestamp.UTC().Format(time.RFC3339Nano),
+	)
+	return err
+}
+
+// QueryProjectTokenUsage returns the token usage, by model, of the sessions
+// that wrote attributed code in projectPath, and how many such sessions
+// recorded any usage.
+func (s *Store) QueryProjectTokenUsage(projectPath string) ([]ModelTokenUsage, int, error) {
+	const projectSessions = `SELECT DISTINCT se.session_id
+		FROM attributions a JOIN session_events se ON se.id = a.session_event_id
+		WHERE a.project_path = ?`
+
+	rows, err := s.db.Query(
+		`SELECT model, COUNT(DISTINCT session_id), COUNT(*),
+		        SUM(input_tokens), SUM(output_tokens), SUM(cache_creation_tokens), SUM(cache_read_tokens)
+		 FROM token_usage
+		 WHERE session_id IN (`+projectSessions+`)
+		 GROUP BY model
+		 ORDER BY model`,
+		projectPath,
+	)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var usage []ModelTokenUsage
+	for rows.Next() {
+		var u ModelTokenUsage
+		if err := rows.Scan(&u.Model, &u.Sessions, &u.Messages,
+			&u.InputTokens, &u.OutputTokens, &u.CacheCreationTokens, &u.CacheReadTokens); err != nil {
+			return nil, 0, err
+		}
+		usage = append(usage, u)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	var sessions int
+	if err := s.db.QueryRow(
+		`SELECT COUNT(DISTINCT session_id) FROM token_usage WHERE session_id IN (`+projectSessions+`)`,
+		projectPath,
+	).Scan(&sessions); err != nil {
+		return nil, 0, err
+	}
+	return usage, sessions, nil
+}
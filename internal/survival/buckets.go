@@ -0,0 +1,51 @@
+package survival
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/anthropic/gap-map/internal/store"
+)
+
+// SizeBuckets are the keys of SurvivalReport.BySize, smallest changes
+// first.
+var SizeBuckets = []string{"1-5", "6-50", "51+"}
+
+// UnknownBucket is the ByFileAge or BySize key of records that lack the
+// data, such as snapshots recorded before file ages were.
+const UnknownBucket = "unknown"
+
+// SizeBucket returns the BySize key of an attribution that changed lines
+// lines.
+func SizeBucket(lines int) string {
+	switch {
+	case lines <= 0:
+		return UnknownBucket
+	case lines <= 5:
+		return SizeBuckets[0]
+	case lines <= 50:
+		return SizeBuckets[1]
+	default:
+		return SizeBuckets[2]
+	}
+}
+
+// fileAge returns the ByFileAge key of a record.
+func fileAge(r store.SurvivalRecord) string {
+	if r.FileAge == "" {
+		return UnknownBucket
+	}
+	return r.FileAge
+}
+
+// directory returns the ByDirectory key of a record: the directory of its
+// file relative to the project, "." for the project root.
+func directory(r store.SurvivalRecord) string {
+	path := r.FilePath
+	if filepath.IsAbs(path) && r.ProjectPath != "" {
+		if rel, err := filepath.Rel(r.ProjectPath, path); err == nil && !strings.HasPrefix(rel, "..") {
+			path = rel
+		}
+	}
+	return filepath.ToSlash(filepath.Dir(path))
+}
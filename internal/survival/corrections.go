@@ -0,0 +1,135 @@
+package survival
+
+import (
+	"strings"
+	"time"
+
+	"github.com/anthropic/gap-map/internal/sessionparser"
+	"github.com/anthropic/gap-map/internal/store"
+)
+
+// DefaultCorrectionWindow is how soon after an AI edit a human change to
+// its lines counts as a correction, a proxy for the quality of the AI's
+// code, unless correction_window_days says otherwise.
+const DefaultCorrectionWindow = 14 * 24 * time.Hour
+
+// Corrections returns whether a human corrected each AI attribution among
+// attrs, changing the lines it wrote within window of it. Attributions
+// whose written lines are unknown are left out.
+//
+// An AI attribution is linked to the human attributions of its file that
+// follow it within window. One of them corrects it when its diff snapshot
+// removes a line the AI wrote or, for one without a diff snapshot, when
+// what the AI wrote is gone from the file's git blame.
+func Corrections(s *store.Store, attrs []store.AttributionWithWorkType, window time.Duration) (map[int64]bool, error) {
+	byFile := make(map[string][]store.AttributionWithWorkType)
+	for _, attr := range attrs {
+		byFile[attr.FilePath] = append(byFile[attr.FilePath], attr)
+	}
+
+	corrected := make(map[int64]bool)
+	for filePath, fileAttrs := range byFile {
+		var ai, human []store.AttributionWithWorkType
+		for _, attr := range fileAttrs {
+			switch {
+			case !aiAuthorshipLevels[attr.AuthorshipLevel]:
+				human = append(human, attr)
+			case attr.SessionEventID != nil:
+				ai = append(ai, attr)
+			}
+		}
+		if len(ai) == 0 {
+			continue
+		}
+
+		// Lines each human attribution's diff snapshots removed.
+		removed := make(map[int64]map[string]bool)
+		if len(human) > 0 {
+			snapshots, err := s.QueryFileSnapshots(filePath)
+			if err != nil {
+				return nil, err
+			}
+			for _, sn := range snapshots {
+				if sn.Kind != store.SnapshotDiff {
+					continue
+				}
+				lines := removed[sn.AttributionID]
+				if lines == nil {
+					lines = make(map[string]bool)
+					removed[sn.AttributionID] = lines
+				}
+				for _, line := range strings.Split(sn.Content, "\n") {
+					if strings.HasPrefix(line, "-") && !strings.HasPrefix(line, "---") {
+						if line = strings.TrimSpace(line[1:]); line != "" {
+							lines[line] = true
+						}
+					}
+				}
+			}
+		}
+
+		var blame map[string]bool // content hashes in current blame, read once needed
+		survives := func(sessionEventID int64) bool {
+			if blame == nil {
+				blame = make(map[string]bool)
+				lines, _ := s.QueryBlameLinesByFile(filePath)
+				for _, bl := range lines {
+					if bl.ContentHash != "" {
+						blame[bl.ContentHash] = true
+					}
+				}
+			}
+			se, err := s.QuerySessionEventByID(sessionEventID)
+			// Without blame or a content hash there is nothing to compare.
+			return len(blame) == 0 || err != nil || se.ContentHash == "" || blame[se.ContentHash]
+		}
+
+		for _, attr := range ai {
+			content, err := s.QuerySessionEventContent(*attr.SessionEventID, sessionparser.ExtractContent)
+			if err != nil {
+				continue
+			}
+			written := make(map[string]bool)
+			for _, line := range strings.Split(content.Added, "\n") {
+				if line = strings.TrimSpace(line); line != "" {
+					written[line] = true
+				}
+			}
+			if len(written) == 0 {
+				continue
+			}
+
+			corrected[attr.ID] = false
+			for _, h := range human {
+				if !h.Timestamp.After(attr.Timestamp) || h.Timestamp.Sub(attr.Timestamp) > window {
+					continue
+				}
+				if lines, ok := removed[h.ID]; ok {
+					if overlaps(lines, written) {
+						corrected[attr.ID] = true
+						break
+					}
+					continue
+				}
+				if !survives(*attr.SessionEventID) {
+					corrected[attr.ID] = true
+					break
+				}
+			}
+		}
+	}
+	return corrected, nil
+}
+
+// overlaps reports whether a and b share a line.
+func overlaps(a, b map[string]bool) bool {
+	if len(a) > len(b) {
+		a, b = b, a
+	}
+	for line := range a {
+		if b[line] {
+			return true
+		}
+	}
+	return false
+}
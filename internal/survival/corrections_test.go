@@ -0,0 +1,124 @@
+package survival
+
+import (
+	"testing"
+	"time"
+
+	"github.com/anthropic/gap-map/internal/store"
+)
+
+func TestCorrections(t *testing.T) {
+	s, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	day := 24 * time.Hour
+	var seID int64
+	// aiEdit records an AI edit of file writing added, with content hash
+	// hash, and returns its attribution.
+	aiEdit := func(file, hash, added string) int64 {
+		t.Helper()
+		ec := store.SessionEventContext{Content: &store.SessionContent{Added: added}}
+		if err := s.InsertSessionEventWithContext("sess1", "tool_result", "Edit", file, hash, baseTime, "{}", 1, ec); err != nil {
+			t.Fatal(err)
+		}
+		seID++
+		id := seID
+		attrID, err := s.InsertAttribution(store.AttributionRecord{
+			FilePath: file, ProjectPath: "/proj", SessionEventID: &id,
+			AuthorshipLevel: "mostly_ai", Timestamp: baseTime, LinesChanged: 1,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := s.UpdateAttributionWorkType(attrID, "core_logic"); err != nil {
+			t.Fatal(err)
+		}
+		return attrID
+	}
+	// humanEdit records a human edit of file after the AI edits, with a
+	// diff snapshot when diff is not empty.
+	humanEdit := func(file string, after time.Duration, diff string) {
+		t.Helper()
+		id, err := s.InsertAttribution(store.AttributionRecord{
+			FilePath: file, ProjectPath: "/proj", AuthorshipLevel: "mostly_human",
+			Timestamp: baseTime.Add(after), LinesChanged: 1,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := s.UpdateAttributionWorkType(id, "core_logic"); err != nil {
+			t.Fatal(err)
+		}
+		if diff == "" {
+			return
+		}
+		if _, err := s.InsertFileSnapshot(store.FileSnapshot{
+			AttributionID: id, FilePath: file, Kind: store.SnapshotDiff, Content: diff, Timestamp: baseTime.Add(after),
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// a.go: a human removes one of the first edit's lines two days
+	// later, and the second edit's line a month later.
+	first := aiEdit("a.go", "h1", "x := 1\ny := 2\n")
+	second := aiEdit("a.go", "h2", "z := 3\n")
+	humanEdit("a.go", 2*day, "--- a/a.go\n+++ b/a.go\n-y := 2\n+y := 20\n")
+	humanEdit("a.go", 30*day, "--- a/a.go\n+++ b/a.go\n-z := 3\n")
+
+	// b.go and c.go: a human edit without a snapshot the next day; only
+	// b.go's AI content is gone from blame.
+	gone := aiEdit("b.go", "h3", "w := 4\n")
+	kept := aiEdit("c.go", "h4", "v := 5\n")
+	humanEdit("b.go", day, "")
+	humanEdit("c.go", day, "")
+	if err := s.InsertBlameLines("b.go", []store.BlameLine{{LineNumber: 1, CommitHash: "c1", ContentHash: "other"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.InsertBlameLines("c.go", []store.BlameLine{{LineNumber: 1, CommitHash: "c1", ContentHash: "h4"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	// d.go: an AI edit whose written lines are unknown.
+	unknown := aiEdit("d.go", "h5", "")
+
+	attrs, err := s.QueryAttributionsWithWorkType("/proj")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := Corrections(s, attrs, 14*day)
+	if err != nil {
+		t.Fatalf("Corrections: %v", err)
+	}
+	want := map[int64]bool{first: true, second: false, gone: true, kept: false}
+	for id, corrected := range want {
+		if c, ok := got[id]; !ok || c != corrected {
+			t.Errorf("attribution %d: corrected = %v (known %v), want %v", id, c, ok, corrected)
+		}
+	}
+	if _, ok := got[unknown]; ok {
+		t.Errorf("attribution %d without content: correction should be unknown", unknown)
+	}
+
+	// A 60-day window reaches the edit of the second edit's line.
+	if got, _ := Corrections(s, attrs, 60*day); !got[second] {
+		t.Error("60-day window: second edit should be corrected")
+	}
+}
+
+func TestSummarizeCorrections(t *testing.T) {
+	yes, no := true, false
+	sr := Summarize([]store.SurvivalRecord{
+		{AuthorshipLevel: "mostly_ai", Corrected: &yes},
+		{AuthorshipLevel: "mostly_ai", Corrected: &no},
+		{AuthorshipLevel: "mostly_ai", Corrected: &no},
+		{AuthorshipLevel: "mostly_ai", Corrected: &no},
+		{AuthorshipLevel: "mostly_ai"}, // recorded before corrections were
+	}, DefaultCorrectionWindow)
+	if sr.CorrectionTracked != 4 || sr.CorrectedCount != 1 || sr.CorrectionRate != 25 {
+		t.Errorf("corrections = %d of %d (%.1f%%), want 1 of 4 (25%%)", sr.CorrectedCount, sr.CorrectionTracked, sr.CorrectionRate)
+	}
+	if sr.CorrectionWindowDays != 14 {
+		t.Errorf("CorrectionWindowDays = %d, want 14", sr.CorrectionWindowDays)
+	}
+}
@@ -0,0 +1,108 @@
+package survival
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/anthropic/gap-map/internal/config"
+	"github.com/anthropic/gap-map/internal/store"
+)
+
+// CostReport relates the tokens spent on a project to the AI-written
+// lines that survived.
+type CostReport struct {
+	Sessions               int                  `json:"sessions"` // sessions that recorded usage
+	InputTokens            int64                `json:"input_tokens"`
+	OutputTokens           int64                `json:"output_tokens"`
+	CacheCreationTokens    int64                `json:"cache_creation_tokens"`
+	CacheReadTokens        int64                `json:"cache_read_tokens"`
+	TotalTokens            int64                `json:"total_tokens"`
+	SurvivedLines          int                  `json:"survived_lines"`
+	TokensPerSurvivingLine float64              `json:"tokens_per_surviving_line"`
+	USD                    float64              `json:"usd,omitempty"` // for models with a configured price
+	USDPerSurvivingLine    float64              `json:"usd_per_surviving_line,omitempty"`
+	UnpricedModels         []string             `json:"unpriced_models,omitempty"` // models with usage but no price
+	ByModel                map[string]ModelCost `json:"by_model"`
+}
+
+// ModelCost is the token usage and cost of one model in a CostReport.
+type ModelCost struct {
+	Sessions    int     `json:"sessions"`
+	TotalTokens int64   `json:"total_tokens"`
+	USD         float64 `json:"usd,omitempty"`
+}
+
+// AnalyzeCost totals the token usage of the sessions that wrote attributed
+// code in projectPath and divides it by the AI lines of sr that survived.
+// prices is keyed by model name or model name prefix (e.g.
+// "claude-opus-4-1" prices "claude-opus-4-1-20250805"); models without a
+// price count towards tokens but not USD. It returns nil if no session
+// recorded usage.
+func AnalyzeCost(s *store.Store, projectPath string, sr *SurvivalReport, prices map[string]config.TokenPrice) (*CostReport, error) {
+	usage, sessions, err := s.QueryProjectTokenUsage(projectPath)
+	if err != nil {
+		return nil, fmt.Errorf("query token usage: %w", err)
+	}
+	if len(usage) == 0 {
+		return nil, nil
+	}
+
+	report := &CostReport{
+		Sessions:      sessions,
+		SurvivedLines: sr.SurvivedLines,
+		ByModel:       make(map[string]ModelCost),
+	}
+	for _, u := range usage {
+		model := u.Model
+		if model == "" {
+			model = UnknownModel
+		}
+		total := u.InputTokens + u.OutputTokens + u.CacheCreationTokens + u.CacheReadTokens
+		report.InputTokens += u.InputTokens
+		report.OutputTokens += u.OutputTokens
+		report.CacheCreationTokens += u.CacheCreationTokens
+		report.CacheReadTokens += u.CacheReadTokens
+		report.TotalTokens += total
+
+		mc := ModelCost{Sessions: u.Sessions, TotalTokens: total}
+		if price, ok := priceFor(prices, u.Model); ok {
+			mc.USD = (float64(u.InputTokens)*price.Input +
+				float64(u.OutputTokens)*price.Output +
+				float64(u.CacheCreationTokens)*price.CacheWrite +
+				float64(u.CacheReadTokens)*price.CacheRead) / 1e6
+			report.USD += mc.USD
+		} else {
+			report.UnpricedModels = append(report.UnpricedModels, model)
+		}
+		report.ByModel[model] = mc
+	}
+	sort.Strings(report.UnpricedModels)
+
+	if report.SurvivedLines > 0 {
+		report.TokensPerSurvivingLine = float64(report.TotalTokens) / float64(report.SurvivedLines)
+		report.USDPerSurvivingLine = report.USD / float64(report.SurvivedLines)
+	}
+	return report, nil
+}
+
+// priceFor returns the price of model: an exact match, else the longest
+// key that prefixes it.
+func priceFor(prices map[string]config.TokenPrice, model string) (config.TokenPrice, bool) {
+	if model == "" {
+		return config.TokenPrice{}, false
+	}
+	if p, ok := prices[model]; ok {
+		return p, true
+	}
+	best := ""
+	for key := range prices {
+		if strings.HasPrefix(model, key) && len(key) > len(best) {
+			best = key
+		}
+	}
+	if best == "" {
+		return config.TokenPrice{}, false
+	}
+	return prices[best], true
+}
@@ -0,0 +1,66 @@
+package survival
+
+import (
+	"testing"
+	"time"
+
+	"github.com/anthropic/gap-map/internal/config"
+	"github.com/anthropic/gap-map/internal/store"
+)
+
+func TestAnalyzeCost(t *testing.T) {
+	s, cleanup := setupTestStore(t)
+	defer cleanup()
+	insertTestData(t, s)
+
+	usage := []store.TokenUsage{
+		{MessageID: "msg_1", SessionID: "sess1", Model: "claude-opus-4-1-20250805", InputTokens: 1000, OutputTokens: 2000, CacheReadTokens: 10000},
+		{MessageID: "msg_1", SessionID: "sess1", Model: "claude-opus-4-1-20250805", InputTokens: 1000, OutputTokens: 2000, CacheReadTokens: 10000}, // repeated line
+		{MessageID: "msg_2", SessionID: "sess1", Model: "claude-haiku-4-5", OutputTokens: 1000},
+		{MessageID: "msg_3", SessionID: "other", Model: "claude-opus-4-1-20250805", OutputTokens: 99999}, // wrote no attributed code
+	}
+	for _, u := range usage {
+		u.Timestamp = baseTime.Add(time.Minute)
+		if err := s.InsertTokenUsage(u); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	sr, err := Analyze(s, "/proj", DefaultCorrectionWindow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prices := map[string]config.TokenPrice{
+		"claude-opus-4":   {Input: 10, Output: 50, CacheRead: 1},
+		"claude-opus-4-1": {Input: 15, Output: 75, CacheRead: 1.5},
+	}
+	cr, err := AnalyzeCost(s, "/proj", sr, prices)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cr == nil {
+		t.Fatal("AnalyzeCost = nil, want a report")
+	}
+
+	if cr.Sessions != 1 || cr.TotalTokens != 14000 || cr.SurvivedLines != 14 {
+		t.Errorf("Sessions, TotalTokens, SurvivedLines = %d, %d, %d, want 1, 14000, 14", cr.Sessions, cr.TotalTokens, cr.SurvivedLines)
+	}
+	if !almostEqual(cr.TokensPerSurvivingLine, 1000, 0.001) {
+		t.Errorf("TokensPerSurvivingLine = %.2f, want 1000", cr.TokensPerSurvivingLine)
+	}
+	// The longest prefix prices opus: (1000*15 + 2000*75 + 10000*1.5) / 1e6.
+	if !almostEqual(cr.USD, 0.18, 1e-9) || !almostEqual(cr.USDPerSurvivingLine, 0.18/14, 1e-9) {
+		t.Errorf("USD, USDPerSurvivingLine = %v, %v", cr.USD, cr.USDPerSurvivingLine)
+	}
+	if len(cr.UnpricedModels) != 1 || cr.UnpricedModels[0] != "claude-haiku-4-5" {
+		t.Errorf("UnpricedModels = %v", cr.UnpricedModels)
+	}
+	if mc := cr.ByModel["claude-haiku-4-5"]; mc.TotalTokens != 1000 || mc.USD != 0 {
+		t.Errorf("ByModel[haiku] = %+v", mc)
+	}
+
+	// No usage recorded for the project.
+	if cr, err := AnalyzeCost(s, "/elsewhere", sr, prices); err != nil || cr != nil {
+		t.Errorf("AnalyzeCost without usage = %+v, %v, want nil", cr, err)
+	}
+}
@@ -5,7 +5,11 @@ package survival
 
 import (
 	"fmt"
+	"path/filepath"
+	"time"
 
+	"github.com/anthropic/gap-map/internal/config"
+	"github.com/anthropic/gap-map/internal/gitint"
 	"github.com/anthropic/gap-map/internal/store"
 )
 
@@ -14,24 +18,47 @@ type SurvivalReport struct {
 	TotalTracked  int                          `json:"total_tracked"`
 	SurvivedCount int                          `json:"survived_count"`
 	SurvivalRate  float64                      `json:"survival_rate"`
+	TrackedLines  int                          `json:"tracked_lines"`  // lines the tracked AI edits changed
+	SurvivedLines int                          `json:"survived_lines"` // lines of the edits that survived
 	ByAuthorship  map[string]SurvivalBreakdown `json:"by_authorship"`
 	ByWorkType    map[string]SurvivalBreakdown `json:"by_work_type"`
+	ByModel       map[string]SurvivalBreakdown `json:"by_model"`          // by the model that wrote the code
+	ByFile        map[string]SurvivalBreakdown `json:"by_file,omitempty"` // by attributed file path
+	ByFileAge     map[string]SurvivalBreakdown `json:"by_file_age"`       // new or existing file; see FileAge
+	BySize        map[string]SurvivalBreakdown `json:"by_size"`           // by lines changed; see SizeBucket
+	ByDirectory   map[string]SurvivalBreakdown `json:"by_directory"`      // by directory relative to the project
+	Cost          *CostReport                  `json:"cost,omitempty"`    // set from AnalyzeCost
+	CheckedAt     time.Time                    `json:"checked_at"`        // when blame was compared
+
+	// CorrectionRate is the share of the AI edits whose correction is
+	// known (CorrectionTracked) that a human changed within
+	// CorrectionWindowDays of the AI writing them; see Corrections.
+	CorrectionTracked    int     `json:"correction_tracked"`
+	CorrectedCount       int     `json:"corrected_count"`
+	CorrectionRate       float64 `json:"correction_rate"`
+	CorrectionWindowDays int     `json:"correction_window_days"`
 }
 
 // SurvivalBreakdown holds survival statistics for a single category
-// (authorship level or work type).
+// (authorship level, work type, model, file, file age, change size or
+// directory).
 type SurvivalBreakdown struct {
 	Tracked  int     `json:"tracked"`
 	Survived int     `json:"survived"`
 	Rate     float64 `json:"rate"`
 }
 
+// UnknownModel is the model key for code from session events that do not
+// record their model, such as those stored before models were recorded.
+const UnknownModel = "unknown"
+
 // aiAuthorshipLevels defines which authorship levels are considered AI-authored.
 // Includes both new 3-level names and legacy 5-level names for backward compat.
 var aiAuthorshipLevels = map[string]bool{
-	"mostly_ai":              true,
-	"fully_ai":               true,
-	"ai_first_human_revised": true,
+	"mostly_ai":                  true,
+	"fully_ai":                   true,
+	"ai_first_human_revised":     true,
+	"ai_suggested_human_written": true,
 }
 
 // Analyze performs code survival analysis for a project. For each file with
@@ -39,42 +66,92 @@ var aiAuthorshipLevels = map[string]bool{
 // git blame data by comparing content hashes. Lines where the content_hash
 // matches the original session event's content_hash are counted as "survived".
 //
-// Files without blame data are skipped (not counted as "not survived").
-func Analyze(s *store.Store, projectPath string) (*SurvivalReport, error) {
-	// Query all attributions with AI authorship for this project.
-	allAttrs, err := s.QueryAttributionsWithWorkType(projectPath)
+// Files without blame data are skipped (not counted as "not survived"), and
+// so are files git ignores. Files outside a sparse checkout are analyzed:
+// their blame comes from HEAD, not the working tree. Corrections are
+// counted within window of each AI edit.
+func Analyze(s *store.Store, projectPath string, window time.Duration) (*SurvivalReport, error) {
+	checks, err := Check(s, projectPath, window)
 	if err != nil {
-		return nil, fmt.Errorf("query attributions: %w", err)
+		return nil, err
 	}
+	report := Summarize(checks, window)
+	report.CheckedAt = time.Now().UTC()
+	return report, nil
+}
 
-	report := &SurvivalReport{
-		ByAuthorship: make(map[string]SurvivalBreakdown),
-		ByWorkType:   make(map[string]SurvivalBreakdown),
+// Record checks the survival of a project's attributions and stores the
+// results as a snapshot taken at now, for Latest and History to read.
+func Record(s *store.Store, projectPath string, now time.Time, window time.Duration) (*SurvivalReport, error) {
+	checks, err := Check(s, projectPath, window)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.InsertSurvivalSnapshot(projectPath, now, checks); err != nil {
+		return nil, fmt.Errorf("record survival snapshot: %w", err)
 	}
+	report := Summarize(checks, window)
+	report.CheckedAt = now.UTC()
+	return report, nil
+}
 
-	// Group AI attributions by file.
-	type fileAttr struct {
-		attrs []store.AttributionWithWorkType
+// Latest returns the survival report of a project's most recent recorded
+// snapshot, or nil if none was recorded. window is the correction window
+// the snapshot was recorded with.
+func Latest(s *store.Store, projectPath string, window time.Duration) (*SurvivalReport, error) {
+	records, err := s.QueryLatestSurvival(projectPath)
+	if err != nil {
+		return nil, fmt.Errorf("query survival snapshot: %w", err)
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+	report := Summarize(records, window)
+	report.CheckedAt = records[0].CheckedAt
+	return report, nil
+}
+
+// Check returns whether each tracked AI attribution of a project survives
+// in the current git blame data, as Analyze counts them. Its results are
+// what the daemon's survival job records with store.InsertSurvivalSnapshot.
+// An attribution counts as corrected when a human changed its lines within
+// window of it.
+func Check(s *store.Store, projectPath string, window time.Duration) ([]store.SurvivalRecord, error) {
+	// Query all attributions with AI authorship for this project.
+	allAttrs, err := s.QueryAttributionsWithWorkType(projectPath)
+	if err != nil {
+		return nil, fmt.Errorf("query attributions: %w", err)
 	}
-	byFile := make(map[string]*fileAttr)
 
+	// Group AI attributions by file.
+	byFile := make(map[string][]store.AttributionWithWorkType)
 	for _, attr := range allAttrs {
 		if !aiAuthorshipLevels[attr.AuthorshipLevel] {
 			continue
 		}
-		fa, ok := byFile[attr.FilePath]
-		if !ok {
-			fa = &fileAttr{}
-			byFile[attr.FilePath] = fa
-		}
-		fa.attrs = append(fa.attrs, attr)
+		byFile[attr.FilePath] = append(byFile[attr.FilePath], attr)
 	}
 
 	if len(byFile) == 0 {
-		return report, nil
+		return nil, nil
 	}
 
-	for filePath, fa := range byFile {
+	created, err := s.QueryCreatedFiles(projectPath)
+	if err != nil {
+		return nil, fmt.Errorf("query created files: %w", err)
+	}
+
+	var checks []store.SurvivalRecord
+	paths, _ := gitint.NewPathFilter(projectPath, config.RepoIgnore(projectPath))
+	for filePath, attrs := range byFile {
+		absPath := filePath
+		if !filepath.IsAbs(absPath) {
+			absPath = filepath.Join(projectPath, filePath)
+		}
+		if paths.Excluded(absPath) && !paths.Sparse(absPath) {
+			continue
+		}
+
 		// Get current blame lines for this file.
 		blameLines, err := s.QueryBlameLinesByFile(filePath)
 		if err != nil {
@@ -85,19 +162,28 @@ func Analyze(s *store.Store, projectPath string) (*SurvivalReport, error) {
 		if len(blameLines) == 0 {
 			continue
 		}
+		fileAge := store.FileAgeExisting
+		if created[filePath] {
+			fileAge = store.FileAgeNew
+		}
+		corrections, err := Corrections(s, byFile[filePath], window)
+		if err != nil {
+			return nil, fmt.Errorf("find corrections in %q: %w", filePath, err)
+		}
 
-		// Build a set of content hashes present in current blame.
-		blameHashes := make(map[string]bool)
+		// Map the content hashes present in current blame to the commit
+		// that last touched them.
+		blameHashes := make(map[string]string)
 		for _, bl := range blameLines {
 			if bl.ContentHash != "" {
-				blameHashes[bl.ContentHash] = true
+				blameHashes[bl.ContentHash] = bl.CommitHash
 			}
 		}
 
 		// Check each AI attribution's associated session event content_hash.
-		for _, attr := range fa.attrs {
+		for _, attr := range attrs {
 			// Get the session event content_hash if we have a session event ID.
-			var contentHash string
+			var contentHash, model string
 			if attr.SessionEventID != nil {
 				se, err := s.QuerySessionEventByID(*attr.SessionEventID)
 				if err != nil {
@@ -105,6 +191,7 @@ func Analyze(s *store.Store, projectPath string) (*SurvivalReport, error) {
 					continue
 				}
 				contentHash = se.ContentHash
+				model = se.Model
 			}
 
 			if contentHash == "" {
@@ -112,51 +199,98 @@ func Analyze(s *store.Store, projectPath string) (*SurvivalReport, error) {
 				continue
 			}
 
-			survived := blameHashes[contentHash]
-
-			report.TotalTracked++
-			if survived {
-				report.SurvivedCount++
+			commit, survived := blameHashes[contentHash]
+			workType := attr.WorkType
+			if workType == "" {
+				workType = "core_logic"
 			}
-
-			// Aggregate by authorship level.
-			bd := report.ByAuthorship[attr.AuthorshipLevel]
-			bd.Tracked++
-			if survived {
-				bd.Survived++
+			if model == "" {
+				model = UnknownModel
 			}
-			report.ByAuthorship[attr.AuthorshipLevel] = bd
-
-			// Aggregate by work type.
-			wt := attr.WorkType
-			if wt == "" {
-				wt = "core_logic"
+			var corrected *bool
+			if c, ok := corrections[attr.ID]; ok {
+				corrected = &c
 			}
-			wtBd := report.ByWorkType[wt]
-			wtBd.Tracked++
-			if survived {
-				wtBd.Survived++
+			checks = append(checks, store.SurvivalRecord{
+				FilePath:        filePath,
+				ProjectPath:     projectPath,
+				AttributionID:   attr.ID,
+				Survived:        survived,
+				BlameCommitHash: commit,
+				AuthorshipLevel: attr.AuthorshipLevel,
+				WorkType:        workType,
+				Model:           model,
+				LinesChanged:    attr.LinesChanged,
+				FileAge:         fileAge,
+				Corrected:       corrected,
+			})
+		}
+	}
+	return checks, nil
+}
+
+// Summarize aggregates per-attribution survival results, from Check or a
+// recorded snapshot, into a SurvivalReport of corrections within window.
+func Summarize(checks []store.SurvivalRecord, window time.Duration) *SurvivalReport {
+	report := &SurvivalReport{
+		ByAuthorship: make(map[string]SurvivalBreakdown),
+		ByWorkType:   make(map[string]SurvivalBreakdown),
+		ByModel:      make(map[string]SurvivalBreakdown),
+		ByFile:       make(map[string]SurvivalBreakdown),
+		ByFileAge:    make(map[string]SurvivalBreakdown),
+		BySize:       make(map[string]SurvivalBreakdown),
+		ByDirectory:  make(map[string]SurvivalBreakdown),
+
+		CorrectionWindowDays: int(window / (24 * time.Hour)),
+	}
+
+	add := func(m map[string]SurvivalBreakdown, key string, survived bool) {
+		bd := m[key]
+		bd.Tracked++
+		if survived {
+			bd.Survived++
+		}
+		m[key] = bd
+	}
+	for _, c := range checks {
+		report.TotalTracked++
+		report.TrackedLines += c.LinesChanged
+		if c.Survived {
+			report.SurvivedCount++
+			report.SurvivedLines += c.LinesChanged
+		}
+		if c.Corrected != nil {
+			report.CorrectionTracked++
+			if *c.Corrected {
+				report.CorrectedCount++
 			}
-			report.ByWorkType[wt] = wtBd
 		}
+		add(report.ByAuthorship, c.AuthorshipLevel, c.Survived)
+		add(report.ByWorkType, c.WorkType, c.Survived)
+		add(report.ByModel, c.Model, c.Survived)
+		add(report.ByFile, c.FilePath, c.Survived)
+		add(report.ByFileAge, fileAge(c), c.Survived)
+		add(report.BySize, SizeBucket(c.LinesChanged), c.Survived)
+		add(report.ByDirectory, directory(c), c.Survived)
 	}
 
 	// Compute rates.
 	if report.TotalTracked > 0 {
 		report.SurvivalRate = float64(report.SurvivedCount) / float64(report.TotalTracked) * 100.0
 	}
-	for key, bd := range report.ByAuthorship {
-		if bd.Tracked > 0 {
-			bd.Rate = float64(bd.Survived) / float64(bd.Tracked) * 100.0
-		}
-		report.ByAuthorship[key] = bd
+	if report.CorrectionTracked > 0 {
+		report.CorrectionRate = float64(report.CorrectedCount) / float64(report.CorrectionTracked) * 100.0
 	}
-	for key, bd := range report.ByWorkType {
-		if bd.Tracked > 0 {
-			bd.Rate = float64(bd.Survived) / float64(bd.Tracked) * 100.0
+	for _, m := range []map[string]SurvivalBreakdown{
+		report.ByAuthorship, report.ByWorkType, report.ByModel, report.ByFile,
+		report.ByFileAge, report.BySize, report.ByDirectory,
+	} {
+		for key, bd := range m {
+			if bd.Tracked > 0 {
+				bd.Rate = float64(bd.Survived) / float64(bd.Tracked) * 100.0
+			}
+			m[key] = bd
 		}
-		report.ByWorkType[key] = bd
 	}
-
-	return report, nil
+	return report
 }
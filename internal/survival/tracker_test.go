@@ -45,10 +45,10 @@ func insertTestData(t *testing.T, s *store.Store) {
 	t.Helper()
 
 	// Insert session events (AI tool writes) with content hashes.
-	if err := s.InsertSessionEvent("sess1", "tool_result", "Write", "main.go", "hash_a", baseTime, "{}", 0); err != nil {
+	if err := s.InsertSessionEventWithContext("sess1", "tool_result", "Write", "main.go", "hash_a", baseTime, "{}", 0, store.SessionEventContext{Model: "claude-opus-4-1", ClientVersion: "1.0.80"}); err != nil {
 		t.Fatal(err)
 	}
-	if err := s.InsertSessionEvent("sess1", "tool_result", "Write", "main.go", "hash_b", baseTime.Add(time.Second), "{}", 0); err != nil {
+	if err := s.InsertSessionEventWithContext("sess1", "tool_result", "Write", "main.go", "hash_b", baseTime.Add(time.Second), "{}", 0, store.SessionEventContext{Model: "claude-sonnet-4-5", ClientVersion: "1.0.80"}); err != nil {
 		t.Fatal(err)
 	}
 	if err := s.InsertSessionEvent("sess1", "tool_result", "Write", "util.go", "hash_c", baseTime.Add(2*time.Second), "{}", 0); err != nil {
@@ -62,7 +62,7 @@ func insertTestData(t *testing.T, s *store.Store) {
 	if err := s.InsertFileEvent("/proj", "main.go", "write", baseTime.Add(time.Second)); err != nil {
 		t.Fatal(err)
 	}
-	if err := s.InsertFileEvent("/proj", "util.go", "write", baseTime.Add(2*time.Second)); err != nil {
+	if err := s.InsertFileEvent("/proj", "util.go", "create", baseTime.Add(2*time.Second)); err != nil {
 		t.Fatal(err)
 	}
 
@@ -79,6 +79,7 @@ func insertTestData(t *testing.T, s *store.Store) {
 		AuthorshipLevel: "fully_ai",
 		Confidence:      0.95,
 		Timestamp:       baseTime,
+		LinesChanged:    10,
 	}
 	id1, err := s.InsertAttribution(attr1)
 	if err != nil {
@@ -95,6 +96,7 @@ func insertTestData(t *testing.T, s *store.Store) {
 		AuthorshipLevel: "fully_ai",
 		Confidence:      0.95,
 		Timestamp:       baseTime.Add(time.Second),
+		LinesChanged:    6,
 	}
 	id2, err := s.InsertAttribution(attr2)
 	if err != nil {
@@ -111,6 +113,7 @@ func insertTestData(t *testing.T, s *store.Store) {
 		AuthorshipLevel: "ai_first_human_revised",
 		Confidence:      0.8,
 		Timestamp:       baseTime.Add(2 * time.Second),
+		LinesChanged:    4,
 	}
 	id3, err := s.InsertAttribution(attr3)
 	if err != nil {
@@ -165,7 +168,7 @@ func TestAnalyze_BasicSurvival(t *testing.T) {
 
 	insertTestData(t, s)
 
-	sr, err := Analyze(s, "/proj")
+	sr, err := Analyze(s, "/proj", DefaultCorrectionWindow)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -186,6 +189,11 @@ func TestAnalyze_BasicSurvival(t *testing.T) {
 		t.Errorf("SurvivalRate = %.1f, want %.1f", sr.SurvivalRate, expectedRate)
 	}
 
+	// Lines: main.go's 10 and util.go's 4 survived, main.go's 6 did not.
+	if sr.TrackedLines != 20 || sr.SurvivedLines != 14 {
+		t.Errorf("TrackedLines, SurvivedLines = %d, %d, want 20, 14", sr.TrackedLines, sr.SurvivedLines)
+	}
+
 	// By authorship: fully_ai tracked 2, survived 1 (hash_a).
 	fullyAI := sr.ByAuthorship["fully_ai"]
 	if fullyAI.Tracked != 2 {
@@ -221,6 +229,79 @@ func TestAnalyze_BasicSurvival(t *testing.T) {
 	if bp.Survived != 1 {
 		t.Errorf("ByWorkType[boilerplate].Survived = %d, want 1", bp.Survived)
 	}
+
+	// By model: opus wrote hash_a (survived), sonnet hash_b (gone); the
+	// event for hash_c records no model.
+	wantModels := map[string]SurvivalBreakdown{
+		"claude-opus-4-1":   {Tracked: 1, Survived: 1, Rate: 100},
+		"claude-sonnet-4-5": {Tracked: 1, Survived: 0, Rate: 0},
+		UnknownModel:        {Tracked: 1, Survived: 1, Rate: 100},
+	}
+	if len(sr.ByModel) != len(wantModels) {
+		t.Errorf("ByModel = %v, want %v", sr.ByModel, wantModels)
+	}
+	for model, want := range wantModels {
+		if got := sr.ByModel[model]; got != want {
+			t.Errorf("ByModel[%s] = %+v, want %+v", model, got, want)
+		}
+	}
+
+	// By file: main.go kept one of its two AI edits.
+	if got := sr.ByFile["main.go"]; got != (SurvivalBreakdown{Tracked: 2, Survived: 1, Rate: 50}) {
+		t.Errorf("ByFile[main.go] = %+v", got)
+	}
+	if got := sr.ByFile["util.go"]; got != (SurvivalBreakdown{Tracked: 1, Survived: 1, Rate: 100}) {
+		t.Errorf("ByFile[util.go] = %+v", got)
+	}
+
+	// util.go was created while watched; main.go already existed.
+	if got := sr.ByFileAge[store.FileAgeNew]; got != (SurvivalBreakdown{Tracked: 1, Survived: 1, Rate: 100}) {
+		t.Errorf("ByFileAge[new] = %+v", got)
+	}
+	if got := sr.ByFileAge[store.FileAgeExisting]; got != (SurvivalBreakdown{Tracked: 2, Survived: 1, Rate: 50}) {
+		t.Errorf("ByFileAge[existing] = %+v", got)
+	}
+
+	// The 10- and 6-line edits of main.go, and the 4-line one of util.go.
+	if got := sr.BySize["6-50"]; got != (SurvivalBreakdown{Tracked: 2, Survived: 1, Rate: 50}) {
+		t.Errorf("BySize[6-50] = %+v", got)
+	}
+	if got := sr.BySize["1-5"]; got != (SurvivalBreakdown{Tracked: 1, Survived: 1, Rate: 100}) {
+		t.Errorf("BySize[1-5] = %+v", got)
+	}
+	if got := sr.ByDirectory["."]; got.Tracked != 3 || len(sr.ByDirectory) != 1 {
+		t.Errorf("ByDirectory = %+v", sr.ByDirectory)
+	}
+}
+
+func TestSummarize_Buckets(t *testing.T) {
+	sr := Summarize([]store.SurvivalRecord{
+		{FilePath: "/proj/internal/api/h.go", ProjectPath: "/proj", Survived: true, LinesChanged: 80, FileAge: store.FileAgeNew},
+		{FilePath: "internal/api/routes.go", ProjectPath: "/proj", LinesChanged: 51},
+		{FilePath: "README.md", ProjectPath: "/proj", Survived: true},
+	}, DefaultCorrectionWindow)
+
+	if got := sr.ByDirectory["internal/api"]; got != (SurvivalBreakdown{Tracked: 2, Survived: 1, Rate: 50}) {
+		t.Errorf("ByDirectory[internal/api] = %+v", got)
+	}
+	if got := sr.ByDirectory["."]; got.Tracked != 1 {
+		t.Errorf("ByDirectory[.] = %+v", got)
+	}
+	if got := sr.BySize["51+"]; got.Tracked != 2 {
+		t.Errorf("BySize[51+] = %+v", got)
+	}
+	// Snapshots from before file ages and line counts were recorded.
+	if got := sr.BySize[UnknownBucket]; got.Tracked != 1 {
+		t.Errorf("BySize[unknown] = %+v", got)
+	}
+	if got := sr.ByFileAge[UnknownBucket]; got.Tracked != 2 {
+		t.Errorf("ByFileAge[unknown] = %+v", got)
+	}
+	for lines, want := range map[int]string{1: "1-5", 5: "1-5", 6: "6-50", 50: "6-50", 51: "51+"} {
+		if got := SizeBucket(lines); got != want {
+			t.Errorf("SizeBucket(%d) = %s, want %s", lines, got, want)
+		}
+	}
 }
 
 func TestAnalyze_NoBlameData(t *testing.T) {
@@ -252,7 +333,7 @@ func TestAnalyze_NoBlameData(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	sr, err := Analyze(s, "/proj")
+	sr, err := Analyze(s, "/proj", DefaultCorrectionWindow)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -267,7 +348,7 @@ func TestAnalyze_EmptyProject(t *testing.T) {
 	s, cleanup := setupTestStore(t)
 	defer cleanup()
 
-	sr, err := Analyze(s, "/proj")
+	sr, err := Analyze(s, "/proj", DefaultCorrectionWindow)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -279,3 +360,44 @@ func TestAnalyze_EmptyProject(t *testing.T) {
 		t.Errorf("SurvivalRate = %f, want 0", sr.SurvivalRate)
 	}
 }
+
+func TestRecordAndLatest(t *testing.T) {
+	s, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	if sr, err := Latest(s, "/proj", DefaultCorrectionWindow); err != nil || sr != nil {
+		t.Fatalf("Latest before any record = %v, %v, want nil", sr, err)
+	}
+
+	insertTestData(t, s)
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	recorded, err := Record(s, "/proj", now, DefaultCorrectionWindow)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sr, err := Latest(s, "/proj", DefaultCorrectionWindow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sr == nil {
+		t.Fatal("Latest = nil after Record")
+	}
+	if !sr.CheckedAt.Equal(now) {
+		t.Errorf("CheckedAt = %v, want %v", sr.CheckedAt, now)
+	}
+
+	// The recorded snapshot reads back as the analysis it was taken from.
+	live, err := Analyze(s, "/proj", DefaultCorrectionWindow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, got := range []*SurvivalReport{recorded, sr} {
+		if got.TotalTracked != live.TotalTracked || got.SurvivedCount != live.SurvivedCount ||
+			got.SurvivedLines != live.SurvivedLines || got.ByModel["claude-opus-4-1"] != live.ByModel["claude-opus-4-1"] ||
+			got.ByWorkType["boilerplate"] != live.ByWorkType["boilerplate"] ||
+			got.ByFileAge[store.FileAgeNew] != live.ByFileAge[store.FileAgeNew] {
+			t.Errorf("recorded survival = %+v, want %+v", got, live)
+		}
+	}
+}
@@ -0,0 +1,229 @@
+package telemetry
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+// LeaderboardSource is what collecting leaderboard entries needs beyond
+// Source.
+type LeaderboardSource interface {
+	Source
+	QueryContributionTotals(start, end time.Time) (survivingAI, human, correctedAI int, err error)
+}
+
+// LeaderboardEntry is one developer's contribution in one ISO week, as
+// shared with a team's aggregation endpoint. Only developers who opted in
+// produce entries, and OptIn records that they did: ranking ignores
+// entries without it. An anonymized entry has no name and an ID that
+// changes every week, derived from a secret that never leaves the install,
+// so its weeks cannot be linked to each other or to the install.
+type LeaderboardEntry struct {
+	Week             string `json:"week"` // e.g. "2026-W42"
+	DeveloperID      string `json:"developer_id"`
+	Name             string `json:"name,omitempty"`
+	OptIn            bool   `json:"opt_in"`
+	Anonymized       bool   `json:"anonymized,omitempty"`
+	SurvivingAILines int    `json:"surviving_ai_lines"` // AI lines still in place at the latest survival check
+	HumanLines       int    `json:"human_lines"`
+	AICorrections    int    `json:"ai_corrections"` // AI lines since rewritten
+}
+
+// LeaderboardOptions are a developer's leaderboard privacy settings.
+type LeaderboardOptions struct {
+	Name      string // display name; entries are anonymized without one
+	Anonymize bool
+	Location  *time.Location // where weeks start; time.Local if nil
+}
+
+// anonymous reports whether entries are anonymized: when asked to, or
+// when there is no name to show.
+func (o LeaderboardOptions) anonymous() bool {
+	return o.Anonymize || o.Name == ""
+}
+
+// Leaderboard ranking keys.
+const (
+	RankSurviving   = "surviving"
+	RankHuman       = "human"
+	RankCorrections = "corrections"
+)
+
+// WeekStart returns the Monday 00:00 in loc starting t's ISO week.
+func WeekStart(t time.Time, loc *time.Location) time.Time {
+	if loc == nil {
+		loc = time.Local
+	}
+	t = t.In(loc)
+	offset := (int(t.Weekday()) + 6) % 7 // days since Monday
+	return time.Date(t.Year(), t.Month(), t.Day()-offset, 0, 0, 0, 0, loc)
+}
+
+// WeekLabel returns the ISO week containing t, e.g. "2026-W42".
+func WeekLabel(t time.Time) string {
+	year, week := t.ISOWeek()
+	return fmt.Sprintf("%d-W%02d", year, week)
+}
+
+// ParseWeek returns the start of ISO week label (e.g. "2026-W42") in loc.
+func ParseWeek(label string, loc *time.Location) (time.Time, error) {
+	var year, week int
+	if _, err := fmt.Sscanf(label, "%d-W%d", &year, &week); err != nil || week < 1 || week > 53 {
+		return time.Time{}, fmt.Errorf("invalid week %q (want e.g. 2026-W42)", label)
+	}
+	if loc == nil {
+		loc = time.Local
+	}
+	// January 4th is always in week 1.
+	start := WeekStart(time.Date(year, time.January, 4, 12, 0, 0, 0, loc), loc).AddDate(0, 0, 7*(week-1))
+	if WeekLabel(start) != fmt.Sprintf("%d-W%02d", year, week) {
+		return time.Time{}, fmt.Errorf("invalid week %q: %d has no week %d", label, year, week)
+	}
+	return start, nil
+}
+
+// CollectLeaderboard returns this install's entries for the week before
+// the one containing now and for that week so far. The previous week is
+// resent because survival checks keep moving lines between its surviving
+// and corrected totals after it ends.
+func CollectLeaderboard(src LeaderboardSource, opts LeaderboardOptions, now time.Time) ([]LeaderboardEntry, error) {
+	id, err := installID(src)
+	if err != nil {
+		return nil, fmt.Errorf("install id: %w", err)
+	}
+	current := WeekStart(now, opts.Location)
+	var entries []LeaderboardEntry
+	for _, start := range []time.Time{current.AddDate(0, 0, -7), current} {
+		e, err := collectWeek(src, id, opts, start)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// CollectLeaderboardWeek returns this install's entry for the ISO week
+// starting at start.
+func CollectLeaderboardWeek(src LeaderboardSource, opts LeaderboardOptions, start time.Time) (LeaderboardEntry, error) {
+	id, err := installID(src)
+	if err != nil {
+		return LeaderboardEntry{}, fmt.Errorf("install id: %w", err)
+	}
+	return collectWeek(src, id, opts, start)
+}
+
+func collectWeek(src LeaderboardSource, id string, opts LeaderboardOptions, start time.Time) (LeaderboardEntry, error) {
+	surviving, human, corrected, err := src.QueryContributionTotals(start, start.AddDate(0, 0, 7))
+	if err != nil {
+		return LeaderboardEntry{}, fmt.Errorf("query contribution totals: %w", err)
+	}
+	e := LeaderboardEntry{
+		Week:             WeekLabel(start),
+		DeveloperID:      id,
+		Name:             opts.Name,
+		OptIn:            true,
+		SurvivingAILines: surviving,
+		HumanLines:       human,
+		AICorrections:    corrected,
+	}
+	if opts.anonymous() {
+		salt, err := randomState(src, anonSaltKey)
+		if err != nil {
+			return LeaderboardEntry{}, fmt.Errorf("anonymous id salt: %w", err)
+		}
+		mac := hmac.New(sha256.New, []byte(salt))
+		mac.Write([]byte(e.Week))
+		e.DeveloperID = "anon-" + hex.EncodeToString(mac.Sum(nil)[:6])
+		e.Name = ""
+		e.Anonymized = true
+	}
+	return e, nil
+}
+
+// ReadLeaderboard reads the leaderboard entries of a JSONL stream of
+// snapshots, as an aggregation endpoint collects them or the reporter
+// appends them to telemetry.jsonl. When a developer's week was sent more
+// than once, the entry of the latest snapshot wins. Entries that do not
+// record an opt-in are dropped.
+func ReadLeaderboard(r io.Reader) ([]LeaderboardEntry, error) {
+	type key struct{ week, id string }
+	type seen struct {
+		at    time.Time
+		entry LeaderboardEntry
+	}
+	latest := make(map[key]seen)
+	var order []key
+
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for line := 1; sc.Scan(); line++ {
+		if len(sc.Bytes()) == 0 {
+			continue
+		}
+		var snap Snapshot
+		if err := json.Unmarshal(sc.Bytes(), &snap); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		for _, e := range snap.Leaderboard {
+			if !e.OptIn || e.Week == "" || e.DeveloperID == "" {
+				continue
+			}
+			k := key{e.Week, e.DeveloperID}
+			prev, ok := latest[k]
+			if !ok {
+				order = append(order, k)
+			} else if snap.Timestamp.Before(prev.at) {
+				continue
+			}
+			latest[k] = seen{at: snap.Timestamp, entry: e}
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+
+	entries := make([]LeaderboardEntry, 0, len(order))
+	for _, k := range order {
+		entries = append(entries, latest[k].entry)
+	}
+	return entries, nil
+}
+
+// RankLeaderboard returns the opted-in entries of week, best first by the
+// total named by by (RankSurviving, RankHuman or RankCorrections). Ties
+// are broken by developer ID.
+func RankLeaderboard(entries []LeaderboardEntry, week, by string) ([]LeaderboardEntry, error) {
+	var value func(LeaderboardEntry) int
+	switch by {
+	case RankSurviving, "":
+		value = func(e LeaderboardEntry) int { return e.SurvivingAILines }
+	case RankHuman:
+		value = func(e LeaderboardEntry) int { return e.HumanLines }
+	case RankCorrections:
+		value = func(e LeaderboardEntry) int { return e.AICorrections }
+	default:
+		return nil, fmt.Errorf("unknown ranking %q (want %q, %q or %q)", by, RankSurviving, RankHuman, RankCorrections)
+	}
+
+	var ranked []LeaderboardEntry
+	for _, e := range entries {
+		if e.OptIn && e.Week == week {
+			ranked = append(ranked, e)
+		}
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		if vi, vj := value(ranked[i]), value(ranked[j]); vi != vj {
+			return vi > vj
+		}
+		return ranked[i].DeveloperID < ranked[j].DeveloperID
+	})
+	return ranked, nil
+}
@@ -0,0 +1,204 @@
+package telemetry
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anthropic/gap-map/internal/store"
+)
+
+func TestCollectLeaderboard(t *testing.T) {
+	s := setupStore(t)
+	now := time.Date(2026, 10, 14, 15, 0, 0, 0, time.UTC) // Wednesday of 2026-W42
+	thisWeek := now.Add(-24 * time.Hour)
+	lastWeek := now.Add(-7 * 24 * time.Hour)
+	older := now.Add(-21 * 24 * time.Hour)
+
+	var ids []int64
+	for _, rec := range []store.AttributionRecord{
+		{AuthorshipLevel: "mostly_ai", Timestamp: thisWeek, LinesChanged: 30},    // survives
+		{AuthorshipLevel: "fully_ai", Timestamp: thisWeek, LinesChanged: 10},     // rewritten
+		{AuthorshipLevel: "mostly_ai", Timestamp: thisWeek, LinesChanged: 7},     // never checked
+		{AuthorshipLevel: "mostly_human", Timestamp: thisWeek, LinesChanged: 5},  // human
+		{AuthorshipLevel: "mostly_human", Timestamp: lastWeek, LinesChanged: 12}, // last week
+		{AuthorshipLevel: "mostly_ai", Timestamp: older, LinesChanged: 100},      // out of range
+	} {
+		rec.FilePath, rec.ProjectPath = "/p/a.go", "/p"
+		id, err := s.InsertAttribution(rec)
+		if err != nil {
+			t.Fatalf("InsertAttribution: %v", err)
+		}
+		ids = append(ids, id)
+	}
+	// The first attribution was rewritten, then restored: the latest
+	// check counts.
+	if err := s.InsertSurvivalSnapshot("/p", now.Add(-2*time.Hour), []store.SurvivalRecord{
+		{FilePath: "/p/a.go", AttributionID: ids[0], Survived: false},
+		{FilePath: "/p/a.go", AttributionID: ids[1], Survived: true},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.InsertSurvivalSnapshot("/p", now.Add(-time.Hour), []store.SurvivalRecord{
+		{FilePath: "/p/a.go", AttributionID: ids[0], Survived: true},
+		{FilePath: "/p/a.go", AttributionID: ids[1], Survived: false},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := CollectLeaderboard(s, LeaderboardOptions{Name: "Ada", Location: time.UTC}, now)
+	if err != nil {
+		t.Fatalf("CollectLeaderboard: %v", err)
+	}
+	if len(entries) != 2 || entries[0].Week != "2026-W41" || entries[1].Week != "2026-W42" {
+		t.Fatalf("entries = %+v, want 2026-W41 and 2026-W42", entries)
+	}
+	cur := entries[1]
+	if cur.SurvivingAILines != 30 || cur.HumanLines != 5 || cur.AICorrections != 10 {
+		t.Errorf("this week = %+v, want 30 surviving, 5 human, 10 corrections", cur)
+	}
+	if prev := entries[0]; prev.HumanLines != 12 || prev.SurvivingAILines != 0 {
+		t.Errorf("last week = %+v, want 12 human lines", prev)
+	}
+	if cur.Name != "Ada" || !cur.OptIn || cur.Anonymized {
+		t.Errorf("named entry = %+v", cur)
+	}
+	if entries[0].DeveloperID != cur.DeveloperID {
+		t.Errorf("named entries have different IDs: %q, %q", entries[0].DeveloperID, cur.DeveloperID)
+	}
+
+	// Anonymized entries drop the name and cannot be linked across weeks.
+	anon, err := CollectLeaderboard(s, LeaderboardOptions{Name: "Ada", Anonymize: true, Location: time.UTC}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range anon {
+		if e.Name != "" || !e.Anonymized || !strings.HasPrefix(e.DeveloperID, "anon-") || e.DeveloperID == cur.DeveloperID {
+			t.Errorf("anonymized entry = %+v", e)
+		}
+	}
+	if anon[0].DeveloperID == anon[1].DeveloperID {
+		t.Errorf("anonymized ID %q is the same in both weeks", anon[0].DeveloperID)
+	}
+}
+
+func TestReporterAnonymizedWeeks(t *testing.T) {
+	s := setupStore(t)
+	path := filepath.Join(t.TempDir(), "telemetry.jsonl")
+	r := NewReporter(s, path, "", time.Hour)
+	r.SetLeaderboard(s, LeaderboardOptions{Name: "Ada", Anonymize: true, Location: time.UTC})
+	r.tick()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := installID(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), id) {
+		t.Errorf("emitted JSON holds the install ID:\n%s", data)
+	}
+
+	var weeks []LeaderboardEntry
+	sc := bufio.NewScanner(strings.NewReader(string(data)))
+	for sc.Scan() {
+		var snap Snapshot
+		if err := json.Unmarshal(sc.Bytes(), &snap); err != nil {
+			t.Fatal(err)
+		}
+		if snap.InstallID != "" {
+			t.Errorf("snapshot has install ID %q", snap.InstallID)
+		}
+		if len(snap.Leaderboard) > 1 {
+			t.Errorf("one snapshot links %d anonymized weeks", len(snap.Leaderboard))
+		}
+		weeks = append(weeks, snap.Leaderboard...)
+	}
+	if len(weeks) != 2 {
+		t.Fatalf("got %d leaderboard entries, want 2", len(weeks))
+	}
+	a, b := weeks[0], weeks[1]
+	if a.DeveloperID == b.DeveloperID {
+		t.Errorf("both weeks have ID %q", a.DeveloperID)
+	}
+	// Nothing sent derives one week's ID from the other's.
+	for _, e := range weeks {
+		sum := sha256.Sum256([]byte(id + "/" + e.Week))
+		if e.DeveloperID == "anon-"+hex.EncodeToString(sum[:6]) {
+			t.Errorf("week %s ID is derived from the install ID", e.Week)
+		}
+	}
+}
+
+func TestReadLeaderboard(t *testing.T) {
+	input := strings.Join([]string{
+		`{"install_id":"a","timestamp":"2026-10-14T00:00:00Z","leaderboard":[{"week":"2026-W42","developer_id":"a","name":"Ada","opt_in":true,"surviving_ai_lines":10}]}`,
+		`{"install_id":"b","timestamp":"2026-10-14T00:00:00Z","leaderboard":[{"week":"2026-W42","developer_id":"b","opt_in":true,"surviving_ai_lines":40,"human_lines":1}]}`,
+		`{"install_id":"c","timestamp":"2026-10-14T00:00:00Z","leaderboard":[{"week":"2026-W42","developer_id":"c","surviving_ai_lines":99}]}`,
+		``,
+		`{"install_id":"a","timestamp":"2026-10-15T00:00:00Z","leaderboard":[{"week":"2026-W42","developer_id":"a","name":"Ada","opt_in":true,"surviving_ai_lines":50,"human_lines":3}]}`,
+		`{"install_id":"a","timestamp":"2026-10-13T00:00:00Z","leaderboard":[{"week":"2026-W42","developer_id":"a","name":"Ada","opt_in":true,"surviving_ai_lines":1}]}`,
+		`{"install_id":"d","timestamp":"2026-10-14T00:00:00Z","tool_mix":{}}`,
+	}, "\n")
+	entries, err := ReadLeaderboard(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ReadLeaderboard: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("entries = %+v, want a and b (c did not opt in)", entries)
+	}
+
+	ranked, err := RankLeaderboard(entries, "2026-W42", RankSurviving)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ranked) != 2 || ranked[0].DeveloperID != "a" || ranked[0].SurvivingAILines != 50 {
+		t.Errorf("ranked by surviving = %+v, want the latest entry of a first", ranked)
+	}
+	if ranked, _ := RankLeaderboard(entries, "2026-W42", RankHuman); ranked[0].DeveloperID != "a" {
+		t.Errorf("ranked by human = %+v", ranked)
+	}
+	if ranked, _ := RankLeaderboard(entries, "2026-W41", RankSurviving); len(ranked) != 0 {
+		t.Errorf("other week = %+v, want none", ranked)
+	}
+	if _, err := RankLeaderboard(entries, "2026-W42", "commits"); err == nil {
+		t.Error("unknown ranking accepted")
+	}
+
+	if _, err := ReadLeaderboard(strings.NewReader("not json\n")); err == nil {
+		t.Error("malformed snapshot accepted")
+	}
+}
+
+func TestParseWeek(t *testing.T) {
+	tests := []struct {
+		label string
+		want  string
+	}{
+		{"2026-W42", "2026-10-12"},
+		{"2026-W01", "2025-12-29"},
+		{"2026-W53", "2026-12-28"},
+	}
+	for _, tt := range tests {
+		got, err := ParseWeek(tt.label, time.UTC)
+		if err != nil || got.Format("2006-01-02") != tt.want {
+			t.Errorf("ParseWeek(%q) = %v, %v; want %s", tt.label, got, err, tt.want)
+		}
+		if WeekLabel(got) != tt.label {
+			t.Errorf("WeekLabel(ParseWeek(%q)) = %q", tt.label, WeekLabel(got))
+		}
+	}
+	for _, bad := range []string{"2025-W53", "2026-W00", "W42", "2026-42"} {
+		if _, err := ParseWeek(bad, time.UTC); err == nil {
+			t.Errorf("ParseWeek(%q) accepted", bad)
+		}
+	}
+}
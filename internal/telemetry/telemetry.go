@@ -0,0 +1,258 @@
+// Package telemetry aggregates coarse, anonymized usage metrics for users
+// who opt in. Snapshots contain only percentages, rates, and tool-name
+// counts -- never file paths, project names, or code -- and are written to
+// a local JSONL file or posted to a user-configured endpoint.
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"time"
+)
+
+// Source is the minimal store interface telemetry needs.
+type Source interface {
+	QueryToolUsageSince(since time.Time) (map[string]int, error)
+	QueryAttributionLineTotals(since time.Time) (aiLines, totalLines int, err error)
+	GetDaemonState(key string) (string, error)
+	SetDaemonState(key, value string) error
+}
+
+// Snapshot is a single aggregated telemetry record.
+type Snapshot struct {
+	InstallID   string         `json:"install_id,omitempty"` // left out with anonymized leaderboard entries
+	Timestamp   time.Time      `json:"timestamp"`
+	WindowHours float64        `json:"window_hours"`
+	AIPct       float64        `json:"ai_pct"`
+	LinesPerDay float64        `json:"lines_per_day"`
+	ToolMix     map[string]int `json:"tool_mix"`
+
+	// Leaderboard holds this developer's weekly entries, only when they
+	// opted in to the team leaderboard. Anonymized entries are sent one per
+	// snapshot, in snapshots of their own.
+	Leaderboard []LeaderboardEntry `json:"leaderboard,omitempty"`
+}
+
+// knownTools are reported by name; anything else (e.g. MCP tools whose
+// names may reveal internal server names) is folded into "other".
+var knownTools = map[string]bool{
+	"Write": true, "Edit": true, "MultiEdit": true, "Read": true,
+	"Bash": true, "Grep": true, "Glob": true, "Task": true,
+	"WebFetch": true, "WebSearch": true, "TodoWrite": true, "NotebookEdit": true,
+}
+
+const (
+	installIDKey = "telemetry_install_id"
+	lastSentKey  = "telemetry_last_sent"
+	anonSaltKey  = "leaderboard_anon_salt" // never sent
+)
+
+// Collect aggregates metrics over the window ending at now.
+func Collect(src Source, window time.Duration, now time.Time) (*Snapshot, error) {
+	id, err := installID(src)
+	if err != nil {
+		return nil, fmt.Errorf("install id: %w", err)
+	}
+	return collect(src, id, window, now)
+}
+
+// Preview is Collect without writing to src: the install ID is left empty
+// when none has been generated yet, rather than generated and persisted.
+func Preview(src Source, window time.Duration, now time.Time) (*Snapshot, error) {
+	id, err := src.GetDaemonState(installIDKey)
+	if err != nil {
+		return nil, fmt.Errorf("install id: %w", err)
+	}
+	return collect(src, id, window, now)
+}
+
+func collect(src Source, id string, window time.Duration, now time.Time) (*Snapshot, error) {
+	since := now.Add(-window)
+
+	usage, err := src.QueryToolUsageSince(since)
+	if err != nil {
+		return nil, fmt.Errorf("query tool usage: %w", err)
+	}
+	aiLines, totalLines, err := src.QueryAttributionLineTotals(since)
+	if err != nil {
+		return nil, fmt.Errorf("query line totals: %w", err)
+	}
+
+	snap := &Snapshot{
+		InstallID:   id,
+		Timestamp:   now.UTC(),
+		WindowHours: window.Hours(),
+		ToolMix:     make(map[string]int),
+	}
+	for name, count := range usage {
+		if !knownTools[name] {
+			name = "other"
+		}
+		snap.ToolMix[name] += count
+	}
+	if totalLines > 0 {
+		snap.AIPct = float64(aiLines) / float64(totalLines) * 100.0
+	}
+	if days := window.Hours() / 24; days > 0 {
+		snap.LinesPerDay = float64(totalLines) / days
+	}
+	return snap, nil
+}
+
+// LastSent returns when the last snapshot was emitted, or the zero time.
+func LastSent(src Source) (time.Time, error) {
+	val, err := src.GetDaemonState(lastSentKey)
+	if err != nil || val == "" {
+		return time.Time{}, err
+	}
+	return time.Parse(time.RFC3339, val)
+}
+
+// installID returns a random per-install identifier, generating and
+// persisting one on first use. It is not derived from any user data.
+func installID(src Source) (string, error) {
+	return randomState(src, installIDKey)
+}
+
+// randomState returns the random hex value stored under key, generating
+// and persisting one on first use.
+func randomState(src Source, key string) (string, error) {
+	v, err := src.GetDaemonState(key)
+	if err != nil {
+		return "", err
+	}
+	if v != "" {
+		return v, nil
+	}
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	v = hex.EncodeToString(buf)
+	return v, src.SetDaemonState(key, v)
+}
+
+// Reporter periodically collects and emits snapshots.
+type Reporter struct {
+	src      Source
+	path     string
+	endpoint string
+	interval time.Duration
+	client   *http.Client
+
+	board     LeaderboardSource // nil unless the developer opted in
+	boardOpts LeaderboardOptions
+}
+
+// NewReporter creates a Reporter. If endpoint is empty, snapshots are
+// appended to the JSONL file at path. interval defaults to 24h.
+func NewReporter(src Source, path, endpoint string, interval time.Duration) *Reporter {
+	if interval <= 0 {
+		interval = 24 * time.Hour
+	}
+	return &Reporter{
+		src:      src,
+		path:     path,
+		endpoint: endpoint,
+		interval: interval,
+		client:   &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// SetLeaderboard adds the developer's weekly leaderboard entries, collected
+// from src with opts, to every snapshot. Call it only for developers who
+// opted in, before Run.
+func (r *Reporter) SetLeaderboard(src LeaderboardSource, opts LeaderboardOptions) {
+	r.board = src
+	r.boardOpts = opts
+}
+
+// Run emits a snapshot every interval until ctx is cancelled. If the last
+// snapshot is older than one interval (or none was ever sent), one is
+// emitted immediately.
+func (r *Reporter) Run(ctx context.Context) {
+	if last, err := LastSent(r.src); err == nil && time.Since(last) >= r.interval {
+		r.tick()
+	}
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.tick()
+		}
+	}
+}
+
+func (r *Reporter) tick() {
+	snap, err := Collect(r.src, r.interval, time.Now())
+	if err != nil {
+		slog.Warn("telemetry: collect failed", "err", err)
+		return
+	}
+	snaps := []*Snapshot{snap}
+	if r.board != nil {
+		entries, err := CollectLeaderboard(r.board, r.boardOpts, snap.Timestamp)
+		if err != nil {
+			slog.Warn("telemetry: collect leaderboard failed", "err", err)
+			return
+		}
+		if r.boardOpts.anonymous() {
+			// Nothing sent may tie the weeks together: no install ID, and
+			// no record holding more than one of them.
+			snap.InstallID = ""
+			for _, e := range entries {
+				snaps = append(snaps, &Snapshot{Timestamp: snap.Timestamp, Leaderboard: []LeaderboardEntry{e}})
+			}
+		} else {
+			snap.Leaderboard = entries
+		}
+	}
+	for _, snap := range snaps {
+		if err := r.Emit(snap); err != nil {
+			slog.Warn("telemetry: emit failed", "err", err)
+			return
+		}
+	}
+	_ = r.src.SetDaemonState(lastSentKey, snap.Timestamp.Format(time.RFC3339))
+}
+
+// Emit writes a snapshot to the endpoint or local file.
+func (r *Reporter) Emit(snap *Snapshot) error {
+	data, err := json.Marshal(snap)
+	if err != nil {
+		return err
+	}
+
+	if r.endpoint != "" {
+		resp, err := r.client.Post(r.endpoint, "application/json", bytes.NewReader(data))
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode >= 300 {
+			body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+			return fmt.Errorf("endpoint returned status %d: %s", resp.StatusCode, body)
+		}
+		return nil
+	}
+
+	f, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(append(data, '\n'))
+	return err
+}
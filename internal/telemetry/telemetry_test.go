@@ -0,0 +1,118 @@
+package telemetry
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anthropic/gap-map/internal/store"
+)
+
+func setupStore(t *testing.T) *store.Store {
+	t.Helper()
+	s, err := store.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("store.New: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func TestCollect_AggregatesWithoutPaths(t *testing.T) {
+	s := setupStore(t)
+	now := time.Now().UTC()
+
+	for _, tool := range []string{"Write", "Write", "Edit", "mcp__secret__tool"} {
+		if err := s.InsertSessionEvent("sess", "tool_use", tool, "/secret/project/main.go", "", now.Add(-time.Hour), "{}", 1); err != nil {
+			t.Fatalf("InsertSessionEvent: %v", err)
+		}
+	}
+	for _, rec := range []store.AttributionRecord{
+		{FilePath: "/secret/project/a.go", ProjectPath: "/secret/project", AuthorshipLevel: "mostly_ai", Timestamp: now.Add(-time.Hour), LinesChanged: 30},
+		{FilePath: "/secret/project/b.go", ProjectPath: "/secret/project", AuthorshipLevel: "mostly_human", Timestamp: now.Add(-time.Hour), LinesChanged: 10},
+	} {
+		if _, err := s.InsertAttribution(rec); err != nil {
+			t.Fatalf("InsertAttribution: %v", err)
+		}
+	}
+
+	snap, err := Collect(s, 24*time.Hour, now)
+	if err != nil {
+		t.Fatalf("Collect: %v", err)
+	}
+	if snap.AIPct != 75 {
+		t.Errorf("AIPct = %.1f, want 75", snap.AIPct)
+	}
+	if snap.LinesPerDay != 40 {
+		t.Errorf("LinesPerDay = %.1f, want 40", snap.LinesPerDay)
+	}
+	if snap.ToolMix["Write"] != 2 || snap.ToolMix["Edit"] != 1 || snap.ToolMix["other"] != 1 {
+		t.Errorf("ToolMix = %v", snap.ToolMix)
+	}
+
+	data, _ := json.Marshal(snap)
+	if strings.Contains(string(data), "secret") {
+		t.Errorf("snapshot leaks identifying data: %s", data)
+	}
+
+	// Install ID is stable across collections.
+	again, _ := Collect(s, 24*time.Hour, now)
+	if snap.InstallID == "" || again.InstallID != snap.InstallID {
+		t.Errorf("install id not stable: %q vs %q", snap.InstallID, again.InstallID)
+	}
+}
+
+func TestPreview_PersistsNothing(t *testing.T) {
+	s := setupStore(t)
+	now := time.Now().UTC()
+
+	snap, err := Preview(s, 24*time.Hour, now)
+	if err != nil {
+		t.Fatalf("Preview: %v", err)
+	}
+	if snap.InstallID != "" {
+		t.Errorf("InstallID = %q before any collection, want empty", snap.InstallID)
+	}
+	if id, _ := s.GetDaemonState(installIDKey); id != "" {
+		t.Errorf("Preview persisted install id %q", id)
+	}
+
+	// Once generated, the preview shows the real install ID.
+	collected, err := Collect(s, 24*time.Hour, now)
+	if err != nil {
+		t.Fatalf("Collect: %v", err)
+	}
+	if snap, _ = Preview(s, 24*time.Hour, now); snap.InstallID != collected.InstallID {
+		t.Errorf("InstallID = %q, want %q", snap.InstallID, collected.InstallID)
+	}
+}
+
+func TestEmit_AppendsJSONL(t *testing.T) {
+	s := setupStore(t)
+	path := filepath.Join(t.TempDir(), "telemetry.jsonl")
+	r := NewReporter(s, path, "", time.Hour)
+
+	for i := 0; i < 2; i++ {
+		if err := r.Emit(&Snapshot{InstallID: "x", ToolMix: map[string]int{}}); err != nil {
+			t.Fatalf("Emit: %v", err)
+		}
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	lines := 0
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		lines++
+	}
+	if lines != 2 {
+		t.Errorf("expected 2 lines, got %d", lines)
+	}
+}
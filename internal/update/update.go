@@ -0,0 +1,245 @@
+// Package update replaces the gapmap binary with one from a GitHub
+// release, once it matches the release's checksums and their signature.
+package update
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/anthropic/gap-map/internal/github"
+)
+
+// Repository releases are fetched from, and the assets besides the
+// binaries each release carries.
+const (
+	Owner          = "anthropic"
+	Repo           = "gap-map"
+	ChecksumsAsset = "checksums.txt"     // "<sha256>  <asset>" per line, as sha256sum writes
+	SignatureAsset = "checksums.txt.sig" // ed25519 signature of ChecksumsAsset, raw or base64
+)
+
+// SigningKey is the base64 ed25519 public key release checksums are
+// signed with. Release builds set it with
+// -ldflags "-X github.com/anthropic/gap-map/internal/update.SigningKey=...".
+// Without one, Download checks binaries against the checksums only, which
+// proves no origin; gapmap self-update then refuses to install unless told
+// to skip the signature.
+var SigningKey string
+
+// AssetName returns the name of the release asset holding the binary for
+// goos and goarch, e.g. "gapmap_linux_amd64".
+func AssetName(goos, goarch string) string {
+	name := "gapmap_" + goos + "_" + goarch
+	if goos == "windows" {
+		name += ".exe"
+	}
+	return name
+}
+
+// Compare compares two versions such as "v1.4.0" or "1.5.0-rc.1" and
+// returns -1, 0 or 1 as a is older than, the same as or newer than b. A
+// pre-release is older than its release. ok is false when either is not
+// a version, as for a development build.
+func Compare(a, b string) (result int, ok bool) {
+	va, okA := parseVersion(a)
+	vb, okB := parseVersion(b)
+	if !okA || !okB {
+		return 0, false
+	}
+	for i := range va.core {
+		if va.core[i] != vb.core[i] {
+			if va.core[i] < vb.core[i] {
+				return -1, true
+			}
+			return 1, true
+		}
+	}
+	switch {
+	case va.pre == vb.pre:
+		return 0, true
+	case va.pre == "":
+		return 1, true
+	case vb.pre == "":
+		return -1, true
+	}
+	return comparePre(va.pre, vb.pre), true
+}
+
+// comparePre orders two pre-releases as semver does: by their
+// dot-separated identifiers in turn, numeric ones numerically and before
+// alphanumeric ones, and with fewer identifiers first when all others
+// are equal, so "rc.2" < "rc.10" < "rc.10.1".
+func comparePre(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		if c := compareIdent(as[i], bs[i]); c != 0 {
+			return c
+		}
+	}
+	return cmp.Compare(len(as), len(bs))
+}
+
+func compareIdent(a, b string) int {
+	na, errA := strconv.ParseUint(a, 10, 64)
+	nb, errB := strconv.ParseUint(b, 10, 64)
+	switch {
+	case errA == nil && errB == nil:
+		return cmp.Compare(na, nb)
+	case errA == nil:
+		return -1
+	case errB == nil:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+type version struct {
+	core [3]int
+	pre  string
+}
+
+func parseVersion(s string) (version, bool) {
+	var v version
+	s = strings.TrimPrefix(strings.TrimSpace(s), "v")
+	if i := strings.IndexByte(s, '+'); i >= 0 {
+		s = s[:i] // build metadata does not order
+	}
+	if i := strings.IndexByte(s, '-'); i >= 0 {
+		s, v.pre = s[:i], s[i+1:]
+	}
+	parts := strings.Split(s, ".")
+	if len(parts) > 3 {
+		return v, false
+	}
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return v, false
+		}
+		v.core[i] = n
+	}
+	return v, true
+}
+
+// Download fetches the binary for goos and goarch from rel and returns it
+// once Verify accepts it against the release's checksums and, with a key,
+// their signature, downloading the assets through client.
+func Download(client *github.Client, rel github.Release, goos, goarch, key string) ([]byte, error) {
+	name := AssetName(goos, goarch)
+	binAsset, ok := rel.Asset(name)
+	if !ok {
+		return nil, fmt.Errorf("release %s has no binary for %s/%s (%s)", rel.Tag, goos, goarch, name)
+	}
+	sumsAsset, ok := rel.Asset(ChecksumsAsset)
+	if !ok {
+		return nil, fmt.Errorf("release %s has no %s to verify the binary against", rel.Tag, ChecksumsAsset)
+	}
+
+	var sums, sig, binary bytes.Buffer
+	if err := client.DownloadAsset(sumsAsset, &sums); err != nil {
+		return nil, err
+	}
+	if key != "" {
+		sigAsset, ok := rel.Asset(SignatureAsset)
+		if !ok {
+			return nil, fmt.Errorf("release %s has no %s", rel.Tag, SignatureAsset)
+		}
+		if err := client.DownloadAsset(sigAsset, &sig); err != nil {
+			return nil, err
+		}
+	}
+	if err := client.DownloadAsset(binAsset, &binary); err != nil {
+		return nil, err
+	}
+	if err := Verify(binary.Bytes(), name, sums.Bytes(), sig.Bytes(), key); err != nil {
+		return nil, err
+	}
+	return binary.Bytes(), nil
+}
+
+// Verify checks that binary has the SHA-256 checksums lists for name and,
+// when key is set, that sig is key's signature of checksums.
+func Verify(binary []byte, name string, checksums, sig []byte, key string) error {
+	if key != "" {
+		pub, err := base64.StdEncoding.DecodeString(key)
+		if err != nil || len(pub) != ed25519.PublicKeySize {
+			return fmt.Errorf("invalid signing key")
+		}
+		raw := sig
+		if len(raw) != ed25519.SignatureSize {
+			if raw, err = base64.StdEncoding.DecodeString(strings.TrimSpace(string(sig))); err != nil {
+				return fmt.Errorf("decode %s: %w", SignatureAsset, err)
+			}
+		}
+		if !ed25519.Verify(pub, checksums, raw) {
+			return fmt.Errorf("%s: signature does not match the signing key", SignatureAsset)
+		}
+	}
+
+	want := ""
+	sc := bufio.NewScanner(bytes.NewReader(checksums))
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == name {
+			want = strings.ToLower(fields[0])
+			break
+		}
+	}
+	if want == "" {
+		return fmt.Errorf("%s lists no checksum for %s", ChecksumsAsset, name)
+	}
+	sum := sha256.Sum256(binary)
+	if got := hex.EncodeToString(sum[:]); got != want {
+		return fmt.Errorf("%s: checksum %s does not match %s", name, got, want)
+	}
+	return nil
+}
+
+// Replace replaces the file at exe, following symlinks, with binary. The
+// binary is written next to it and renamed over it, so the file is either
+// the old binary or the new one, never half written; a running process
+// keeps the old one.
+func Replace(exe string, binary []byte) error {
+	path, err := filepath.EvalSymlinks(exe)
+	if err != nil {
+		return fmt.Errorf("resolve %s: %w", exe, err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".update-*")
+	if err != nil {
+		return fmt.Errorf("write next to %s: %w", path, err)
+	}
+	defer os.Remove(tmp.Name()) // fails harmlessly once renamed
+	if _, err := tmp.Write(binary); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write new binary: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write new binary: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("write new binary: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), info.Mode().Perm()|0o111); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("replace %s: %w", path, err)
+	}
+	return nil
+}
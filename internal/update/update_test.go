@@ -0,0 +1,104 @@
+package update
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+		ok   bool
+	}{
+		{"v1.2.0", "v1.2.0", 0, true},
+		{"v1.2.0", "v1.10.0", -1, true},
+		{"1.3", "v1.2.9", 1, true},
+		{"v2.0.0-rc.1", "v2.0.0", -1, true},
+		{"v2.0.0", "v2.0.0-rc.1", 1, true},
+		{"v2.0.0-rc.1", "v2.0.0-rc.2", -1, true},
+		{"v2.0.0-rc.2", "v2.0.0-rc.10", -1, true},
+		{"v2.0.0-rc.10", "v2.0.0-rc.10.1", -1, true},
+		{"v2.0.0-alpha", "v2.0.0-alpha.1", -1, true},
+		{"v2.0.0-1", "v2.0.0-alpha", -1, true},
+		{"v2.0.0-beta", "v2.0.0-alpha.9", 1, true},
+		{"v1.0.0+build.5", "v1.0.0", 0, true},
+		{"(devel)", "v1.0.0", 0, false},
+		{"", "v1.0.0", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := Compare(tt.a, tt.b)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("Compare(%q, %q) = %d, %v; want %d, %v", tt.a, tt.b, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestVerify(t *testing.T) {
+	binary := []byte("new gapmap")
+	sum := sha256.Sum256(binary)
+	name := AssetName("linux", "amd64")
+	sums := []byte(fmt.Sprintf("%s  %s\n%s  gapmap_darwin_arm64\n", hex.EncodeToString(sum[:]), name, hex.EncodeToString(make([]byte, 32))))
+
+	if err := Verify(binary, name, sums, nil, ""); err != nil {
+		t.Errorf("Verify: %v", err)
+	}
+	if err := Verify([]byte("tampered"), name, sums, nil, ""); err == nil {
+		t.Error("Verify of a tampered binary: want error")
+	}
+	if err := Verify(binary, "gapmap_windows_amd64.exe", sums, nil, ""); err == nil {
+		t.Error("Verify of an unlisted asset: want error")
+	}
+
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := base64.StdEncoding.EncodeToString(pub)
+	sig := ed25519.Sign(priv, sums)
+	if err := Verify(binary, name, sums, sig, key); err != nil {
+		t.Errorf("Verify with a raw signature: %v", err)
+	}
+	if err := Verify(binary, name, sums, []byte(base64.StdEncoding.EncodeToString(sig)+"\n"), key); err != nil {
+		t.Errorf("Verify with a base64 signature: %v", err)
+	}
+	forged := append([]byte{}, sums...)
+	forged[0] ^= 1
+	if err := Verify(binary, name, forged, sig, key); err == nil {
+		t.Error("Verify of checksums not matching their signature: want error")
+	}
+}
+
+func TestReplace(t *testing.T) {
+	dir := t.TempDir()
+	exe := filepath.Join(dir, "gapmap")
+	if err := os.WriteFile(exe, []byte("old"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(dir, "link")
+	if err := os.Symlink(exe, link); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Replace(link, []byte("new")); err != nil {
+		t.Fatalf("Replace: %v", err)
+	}
+	if data, _ := os.ReadFile(exe); string(data) != "new" {
+		t.Errorf("binary = %q, want new", data)
+	}
+	if info, err := os.Lstat(link); err != nil || info.Mode()&os.ModeSymlink == 0 {
+		t.Error("symlink was replaced instead of its target")
+	}
+	if info, _ := os.Stat(exe); info.Mode().Perm() != 0o755 {
+		t.Errorf("mode = %v, want 0755", info.Mode().Perm())
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 2 {
+		t.Errorf("directory has %d entries, want no temporary file left", len(entries))
+	}
+}
@@ -0,0 +1,89 @@
+package watcher
+
+import (
+	"sync"
+	"time"
+)
+
+// bulkDetector recognizes bulk changes: more than threshold distinct files
+// changing within window, as a branch switch or a repo-wide format does.
+// It is safe for concurrent use.
+type bulkDetector struct {
+	threshold int
+	window    time.Duration
+
+	mu      sync.Mutex
+	recent  []Event        // events within window of the latest, oldest first
+	counts  map[string]int // occurrences of each path in recent
+	inBurst bool
+}
+
+func newBulkDetector(threshold int, window time.Duration) *bulkDetector {
+	return &bulkDetector{
+		threshold: threshold,
+		window:    window,
+		counts:    make(map[string]int),
+	}
+}
+
+// observe records e and reports whether it belongs to a bulk change. When e
+// is the event that starts one, started is set and since is the time of the
+// oldest event in the window: events from then on that were already let
+// through belong to the bulk change too.
+func (b *bulkDetector) observe(e Event) (bulk, started bool, since time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.recent = append(b.recent, e)
+	b.counts[e.Path]++
+
+	cutoff := e.Timestamp.Add(-b.window)
+	for len(b.recent) > 0 && b.recent[0].Timestamp.Before(cutoff) {
+		old := b.recent[0]
+		b.recent = b.recent[1:]
+		if b.counts[old.Path]--; b.counts[old.Path] <= 0 {
+			delete(b.counts, old.Path)
+		}
+	}
+
+	if len(b.counts) <= b.threshold {
+		b.inBurst = false
+		return false, false, time.Time{}
+	}
+	if b.inBurst {
+		return true, false, time.Time{}
+	}
+	b.inBurst = true
+	return true, true, b.recent[0].Timestamp
+}
+
+// bulkDetectors keeps a bulkDetector for each project, so a bulk change in
+// one project does not mark the edits of another. It is safe for
+// concurrent use.
+type bulkDetectors struct {
+	threshold int
+	window    time.Duration
+
+	mu        sync.Mutex
+	byProject map[string]*bulkDetector
+}
+
+func newBulkDetectors(threshold int, window time.Duration) *bulkDetectors {
+	return &bulkDetectors{
+		threshold: threshold,
+		window:    window,
+		byProject: make(map[string]*bulkDetector),
+	}
+}
+
+// project returns the detector for project, creating it on first use.
+func (d *bulkDetectors) project(project string) *bulkDetector {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	b, ok := d.byProject[project]
+	if !ok {
+		b = newBulkDetector(d.threshold, d.window)
+		d.byProject[project] = b
+	}
+	return b
+}
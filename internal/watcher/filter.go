@@ -6,9 +6,10 @@ import (
 )
 
 // defaultIgnorePatterns are always ignored regardless of user configuration.
+// Dependency and build directories are excluded by config.WatchExclude
+// instead, so a project can opt back in to them.
 var defaultIgnorePatterns = []string{
 	".git",
-	"node_modules",
 	".idea",
 	".vscode",
 	"__pycache__",
@@ -18,9 +19,6 @@ var defaultIgnorePatterns = []string{
 	"*.tmp",
 	"*.tmp.*",
 	".DS_Store",
-	"build",
-	"dist",
-	"target",
 }
 
 // Filter checks file paths against a set of ignore patterns.
@@ -0,0 +1,157 @@
+package watcher
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+const (
+	// gitOpLookback is how far before a HEAD change a git operation is
+	// assumed to have started when no index.lock was seen.
+	gitOpLookback = 5 * time.Second
+	// gitOpLockSlack is how long after index.lock is released a HEAD
+	// change still belongs to the same operation: checkout writes HEAD
+	// just after the index.
+	gitOpLockSlack = time.Second
+	// gitOpGrace is how long after a HEAD change file events still belong
+	// to the operation. Git rewrites files before HEAD, so this only
+	// covers events racing the HEAD change; edits after it are the user's.
+	gitOpGrace = 500 * time.Millisecond
+)
+
+// gitRepoState tracks one repository's .git directory.
+type gitRepoState struct {
+	projects  []string // watch roots inside the repository
+	lockStart time.Time
+	lockEnd   time.Time
+	lockHeld  bool
+	opStart   time.Time // start of the latest detected operation
+	opUntil   time.Time // file events until then belong to it
+}
+
+// gitOpDetector recognizes git operations that rewrite the working tree
+// (checkout, rebase, merge, reset) from changes to HEAD and ORIG_HEAD in
+// the .git directories of the watched repositories. Git holds index.lock
+// while it updates the working tree, so the operation is taken to span
+// from when the lock was taken until shortly after HEAD changes. It is
+// safe for concurrent use.
+type gitOpDetector struct {
+	mu    sync.Mutex
+	repos map[string]*gitRepoState // by .git directory
+}
+
+// newGitOpDetector finds the repository containing each watch root.
+func newGitOpDetector(watchPaths []string) *gitOpDetector {
+	g := &gitOpDetector{repos: make(map[string]*gitRepoState)}
+	for _, root := range watchPaths {
+		absRoot, err := filepath.Abs(root)
+		if err != nil {
+			continue
+		}
+		gitDir := findGitDir(absRoot)
+		if gitDir == "" {
+			continue
+		}
+		repo, ok := g.repos[gitDir]
+		if !ok {
+			repo = &gitRepoState{}
+			g.repos[gitDir] = repo
+		}
+		repo.projects = append(repo.projects, absRoot)
+	}
+	return g
+}
+
+// findGitDir returns the .git directory of the repository containing dir,
+// or "" if there is none. Worktrees and submodules, whose .git is a file,
+// are not followed.
+func findGitDir(dir string) string {
+	for {
+		gitDir := filepath.Join(dir, ".git")
+		if info, err := os.Stat(gitDir); err == nil && info.IsDir() {
+			return gitDir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// gitDirs returns the .git directories to watch.
+func (g *gitOpDetector) gitDirs() []string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	dirs := make([]string, 0, len(g.repos))
+	for dir := range g.repos {
+		dirs = append(dirs, dir)
+	}
+	return dirs
+}
+
+// observe handles an fsnotify op on path at time now. It reports whether
+// path is in a watched .git directory; when HEAD or ORIG_HEAD was written,
+// operation is set with the projects affected and the start of the
+// operation, so events already stored since then can be marked.
+func (g *gitOpDetector) observe(path string, op fsnotify.Op, now time.Time) (isGit, operation bool, projects []string, since time.Time) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	repo, ok := g.repos[filepath.Dir(path)]
+	if !ok {
+		return false, false, nil, time.Time{}
+	}
+
+	switch filepath.Base(path) {
+	case "index.lock":
+		switch {
+		case op.Has(fsnotify.Create):
+			repo.lockHeld = true
+			repo.lockStart = now
+		case op.Has(fsnotify.Remove) || op.Has(fsnotify.Rename):
+			repo.lockHeld = false
+			repo.lockEnd = now
+		}
+		return true, false, nil, time.Time{}
+	case "HEAD", "ORIG_HEAD":
+		// Git writes HEAD.lock and renames it over HEAD.
+		if !op.Has(fsnotify.Create) && !op.Has(fsnotify.Write) {
+			return true, false, nil, time.Time{}
+		}
+	default:
+		return true, false, nil, time.Time{}
+	}
+
+	since = now.Add(-gitOpLookback)
+	if !repo.lockStart.IsZero() && (repo.lockHeld || now.Sub(repo.lockEnd) <= gitOpLockSlack) {
+		since = repo.lockStart
+	}
+	// A rebase changes HEAD once per commit; keep the earliest start while
+	// the operation is ongoing.
+	if now.Before(repo.opUntil) && repo.opStart.Before(since) {
+		since = repo.opStart
+	}
+	repo.opStart = since
+	repo.opUntil = now.Add(gitOpGrace)
+	return true, true, repo.projects, since
+}
+
+// during reports whether a file event in project at ts belongs to a git
+// operation that is in progress or just finished.
+func (g *gitOpDetector) during(project string, ts time.Time) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for _, repo := range g.repos {
+		for _, p := range repo.projects {
+			if p == project && !ts.Before(repo.opStart) && !ts.After(repo.opUntil) {
+				return true
+			}
+		}
+	}
+	return false
+}
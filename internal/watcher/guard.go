@@ -0,0 +1,47 @@
+package watcher
+
+import (
+	"log/slog"
+	"os"
+	"sync"
+
+	"github.com/anthropic/gap-map/internal/metrics"
+)
+
+// fileGuard drops events for files the large and binary file guards keep
+// out of attribution, logging each such file once. It is safe for
+// concurrent use.
+type fileGuard struct {
+	guard metrics.FileGuard
+
+	mu     sync.Mutex
+	logged map[string]bool
+}
+
+func newFileGuard(guard metrics.FileGuard) *fileGuard {
+	return &fileGuard{guard: guard, logged: make(map[string]bool)}
+}
+
+// excludes reports whether an event is for a file the guard excludes.
+// Deletes and renames are never excluded, since the file may be gone.
+func (g *fileGuard) excludes(e Event) bool {
+	if e.Type != "create" && e.Type != "modify" {
+		return false
+	}
+	data, err := os.ReadFile(e.Path)
+	if err != nil {
+		return false
+	}
+	reason := g.guard.Check(string(data))
+	if reason == "" {
+		return false
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if !g.logged[e.Path] {
+		g.logged[e.Path] = true
+		slog.Info("watcher: not tracking file", "file", e.Path, "reason", reason)
+	}
+	return true
+}
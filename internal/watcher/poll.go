@@ -0,0 +1,138 @@
+package watcher
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// isWatchLimit reports whether err from adding a watch means the system's
+// limit was reached: inotify watches (ENOSPC) or, where every watch is a
+// file descriptor, open files (EMFILE).
+func isWatchLimit(err error) bool {
+	return errors.Is(err, syscall.ENOSPC) || errors.Is(err, syscall.EMFILE)
+}
+
+// entryState is what the poller remembers about a directory entry.
+type entryState struct {
+	dir     bool
+	size    int64
+	modTime time.Time
+}
+
+// poller finds changes in directories that are not watched by comparing
+// their entries with the previous scan. Each directory is scanned on its
+// own, not recursively; new subdirectories are reported so the caller can
+// watch or poll them. It is safe for concurrent use.
+type poller struct {
+	mu   sync.Mutex
+	dirs map[string]map[string]entryState // directory -> entry name -> state
+}
+
+func newPoller() *poller {
+	return &poller{dirs: make(map[string]map[string]entryState)}
+}
+
+// add starts polling dir. Its current entries are the baseline, so they do
+// not show up as created.
+func (p *poller) add(dir string) {
+	entries, err := readEntries(dir)
+	if err != nil {
+		return
+	}
+	p.mu.Lock()
+	p.dirs[dir] = entries
+	p.mu.Unlock()
+}
+
+// len returns the number of directories polled.
+func (p *poller) len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.dirs)
+}
+
+// scan compares every polled directory with its previous scan and returns
+// the file events found, in path order, and the subdirectories created
+// since. A directory that no longer exists is dropped, with a delete for
+// each of its files.
+func (p *poller) scan(now time.Time) (events []Event, newDirs []string) {
+	p.mu.Lock()
+	dirs := make([]string, 0, len(p.dirs))
+	for dir := range p.dirs {
+		dirs = append(dirs, dir)
+	}
+	p.mu.Unlock()
+	sort.Strings(dirs)
+
+	for _, dir := range dirs {
+		cur, err := readEntries(dir)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+
+		p.mu.Lock()
+		prev, ok := p.dirs[dir]
+		if cur == nil {
+			delete(p.dirs, dir)
+		} else if ok {
+			p.dirs[dir] = cur
+		}
+		p.mu.Unlock()
+		if !ok {
+			continue
+		}
+
+		for name, st := range cur {
+			path := filepath.Join(dir, name)
+			old, existed := prev[name]
+			switch {
+			case st.dir:
+				if !existed || !old.dir {
+					newDirs = append(newDirs, path)
+				}
+			case !existed || old.dir:
+				events = append(events, Event{Path: path, Type: "create", Timestamp: now})
+			case st.size != old.size || !st.modTime.Equal(old.modTime):
+				events = append(events, Event{Path: path, Type: "modify", Timestamp: now})
+			}
+		}
+		for name, old := range prev {
+			if _, ok := cur[name]; !ok && !old.dir {
+				events = append(events, Event{Path: filepath.Join(dir, name), Type: "delete", Timestamp: now})
+			}
+		}
+	}
+
+	sort.Slice(events, func(i, j int) bool { return events[i].Path < events[j].Path })
+	sort.Strings(newDirs)
+	return events, newDirs
+}
+
+// readEntries returns the state of each entry of dir.
+func readEntries(dir string) (map[string]entryState, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	states := make(map[string]entryState, len(entries))
+	for _, e := range entries {
+		if e.IsDir() {
+			states[e.Name()] = entryState{dir: true}
+			continue
+		}
+		if !e.Type().IsRegular() {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		states[e.Name()] = entryState{size: info.Size(), modTime: info.ModTime()}
+	}
+	return states, nil
+}
@@ -0,0 +1,32 @@
+package watcher
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// UntrackedRoot returns where a file outside every watch path lives: the
+// git repository containing it, with repo true, or else its directory.
+// It returns "" for a file under a watch path.
+func UntrackedRoot(watchPaths []string, path string) (root string, repo bool) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return "", false
+	}
+	for _, wp := range watchPaths {
+		if abs, err := filepath.Abs(wp); err == nil && within(abs, path) {
+			return "", false
+		}
+	}
+	dir := filepath.Dir(path)
+	for d := dir; ; {
+		if _, err := os.Stat(filepath.Join(d, ".git")); err == nil {
+			return d, true
+		}
+		parent := filepath.Dir(d)
+		if parent == d {
+			return dir, false
+		}
+		d = parent
+	}
+}
@@ -2,14 +2,19 @@ package watcher
 
 import (
 	"context"
-	"log"
+	"errors"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/fsnotify/fsnotify"
 
 	"github.com/anthropic/gap-map/internal/config"
+	"github.com/anthropic/gap-map/internal/gitint"
 	"github.com/anthropic/gap-map/internal/store"
 )
 
@@ -21,14 +26,49 @@ type Watcher struct {
 	fsw       *fsnotify.Watcher
 	filter    *Filter
 	debouncer *Debouncer
+	bulk      *bulkDetectors // nil when bulk detection is disabled
+	gitOps    *gitOpDetector // nil when git_operation_events is "off"
+	guard     *fileGuard
+	poll      *poller                       // directories scanned instead of watched
+	gitPaths  map[string]*gitint.PathFilter // by watch root; nil outside git. Event loop only.
+	trust     atomic.Pointer[config.Trust]
+
+	mu           sync.Mutex // guards fsw for Stats
+	failed       atomic.Int64
+	overflows    atomic.Int64
+	limitReached atomic.Bool
+	pollInterval atomic.Int64
+}
+
+// Stats describes what the watcher is watching, for "gapmap status".
+type Stats struct {
+	Dirs      int   // directories being watched
+	Failed    int64 // directories that could not be watched, e.g. past the system's watch limit
+	Overflows int64 // times the kernel's event queue overflowed and dropped events
+
+	// LimitReached is set once a directory could not be watched because
+	// of the system's watch limit. Polled directories are scanned every
+	// PollInterval instead (see config.WatchMode).
+	LimitReached bool
+	Polled       int
+	PollInterval time.Duration
 }
 
 // New creates a Watcher wired to the given store and config.
 func New(s *store.Store, cfg *config.Config) *Watcher {
-	return &Watcher{
+	w := &Watcher{
 		store: s,
 		cfg:   cfg,
+		poll:  newPoller(),
 	}
+	w.trust.Store(cfg.Trust())
+	return w
+}
+
+// SetTrust replaces the project trust settings deciding which projects'
+// events are recorded.
+func (w *Watcher) SetTrust(t *config.Trust) {
+	w.trust.Store(t)
 }
 
 // Start begins watching all configured paths recursively.
@@ -38,22 +78,54 @@ func (w *Watcher) Start(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
+	w.mu.Lock()
 	w.fsw = fsw
+	w.mu.Unlock()
 
 	// Build filter from config + defaults.
-	w.filter = NewFilter(w.cfg.IgnorePatterns)
+	w.filter = NewFilter(append(append([]string(nil), w.cfg.WatchExclude...), w.cfg.IgnorePatterns...))
+	w.guard = newFileGuard(w.cfg.FileGuard())
 
-	// Build debouncer that writes events to the store.
-	w.debouncer = NewDebouncer(100*time.Millisecond, func(e Event) {
-		if err := w.store.InsertFileEvent(w.projectPath(e.Path), e.Path, e.Type, e.Timestamp); err != nil {
-			log.Printf("watcher: store insert: %v", err)
+	if w.cfg.BulkEventThreshold > 0 {
+		window, err := time.ParseDuration(w.cfg.BulkEventWindow)
+		if err != nil || window <= 0 {
+			window = 2 * time.Second
 		}
-	})
+		w.bulk = newBulkDetectors(w.cfg.BulkEventThreshold, window)
+	}
+
+	pollInterval, err := time.ParseDuration(w.cfg.WatchPollInterval)
+	if err != nil || pollInterval <= 0 {
+		pollInterval = 5 * time.Second
+	}
+	w.pollInterval.Store(int64(pollInterval))
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	// Build debouncer that writes events to the store.
+	w.debouncer = NewDebouncer(100*time.Millisecond, w.record)
+
+	// Read what git leaves out of each watch root's working tree.
+	w.gitPaths = make(map[string]*gitint.PathFilter, len(w.cfg.WatchPaths))
+	for _, root := range w.cfg.WatchPaths {
+		w.loadGitPaths(root)
+	}
 
 	// Add all configured watch paths (recursively).
 	for _, root := range w.cfg.WatchPaths {
 		if err := w.addRecursive(root); err != nil {
-			log.Printf("watcher: walk %s: %v", root, err)
+			slog.Warn("watcher: walk failed", "root", root, "err", err)
+		}
+	}
+
+	// Watch the .git directories themselves (not their contents) to tell
+	// git's working tree rewrites from edits.
+	if w.cfg.GitOperationEvents != config.GitOperationEventsOff {
+		w.gitOps = newGitOpDetector(w.cfg.WatchPaths)
+		for _, dir := range w.gitOps.gitDirs() {
+			if err := fsw.Add(dir); err != nil {
+				slog.Warn("watcher: watch failed", "dir", dir, "err", err)
+			}
 		}
 	}
 
@@ -71,15 +143,43 @@ func (w *Watcher) Start(ctx context.Context) error {
 			}
 			w.handleEvent(ev)
 
+		case now := <-ticker.C:
+			w.scanPolled(now)
+
 		case err, ok := <-fsw.Errors:
 			if !ok {
 				return nil
 			}
-			log.Printf("watcher: fsnotify error: %v", err)
+			if errors.Is(err, fsnotify.ErrEventOverflow) {
+				w.overflows.Add(1)
+				slog.Warn("watcher: event queue overflowed, events were dropped")
+				continue
+			}
+			slog.Error("watcher: fsnotify error", "err", err)
 		}
 	}
 }
 
+// Stats returns the number of directories watched and the watches and
+// events lost so far.
+func (w *Watcher) Stats() Stats {
+	st := Stats{
+		Failed:       w.failed.Load(),
+		Overflows:    w.overflows.Load(),
+		LimitReached: w.limitReached.Load(),
+		PollInterval: time.Duration(w.pollInterval.Load()),
+	}
+	w.mu.Lock()
+	if w.fsw != nil {
+		st.Dirs = len(w.fsw.WatchList())
+	}
+	w.mu.Unlock()
+	if w.poll != nil {
+		st.Polled = w.poll.len()
+	}
+	return st
+}
+
 // Stop drains the debouncer (emitting pending events) and closes fsnotify.
 func (w *Watcher) Stop() {
 	if w.debouncer != nil {
@@ -90,10 +190,84 @@ func (w *Watcher) Stop() {
 	}
 }
 
+// record writes a debounced event to the store. Events written by a git
+// operation are tagged with the git origin, or dropped when
+// git_operation_events is "drop". Events in a bulk change are stored at low
+// priority, or dropped when bulk_events is "skip"; the events of the change
+// stored in the same project before it was recognized are updated to match. Files excluded by
+// the large and binary file guards, or in projects gap-map is not enabled
+// for, are not recorded.
+func (w *Watcher) record(e Event) {
+	if !w.trust.Load().Allows(e.Path) {
+		return
+	}
+	if w.guard != nil && w.guard.excludes(e) {
+		return
+	}
+	project := w.projectPath(e.Path)
+	origin := store.OriginUser
+	if w.gitOps != nil && w.gitOps.during(project, e.Timestamp) {
+		if w.cfg.GitOperationEvents == config.GitOperationEventsDrop {
+			return
+		}
+		origin = store.OriginGit
+	}
+
+	priority := store.PriorityNormal
+	if w.bulk != nil {
+		bulk, started, since := w.bulk.project(project).observe(e)
+		skip := w.cfg.BulkEvents == config.BulkEventsSkip
+		if started {
+			slog.Info("watcher: bulk change detected", "project", project, "threshold", w.bulk.threshold, "window", w.bulk.window)
+			var err error
+			if skip {
+				_, err = w.store.DeleteUnprocessedFileEventsSince(project, since)
+			} else {
+				_, err = w.store.DeferFileEventsSince(project, since)
+			}
+			if err != nil {
+				slog.Error("watcher: bulk change failed", "err", err)
+			}
+		}
+		if bulk {
+			if skip {
+				return
+			}
+			priority = store.PriorityLow
+		}
+	}
+	if err := w.store.InsertWatchedFileEvent(project, e.Path, e.Type, e.Timestamp, priority, origin); err != nil {
+		slog.Error("watcher: store insert failed", "err", err)
+	}
+}
+
 // handleEvent processes a single fsnotify event.
 func (w *Watcher) handleEvent(ev fsnotify.Event) {
+	// Changes in a .git directory only signal git operations.
+	if w.gitOps != nil {
+		isGit, operation, projects, since := w.gitOps.observe(ev.Name, ev.Op, time.Now())
+		if operation {
+			drop := w.cfg.GitOperationEvents == config.GitOperationEventsDrop
+			if _, err := w.store.MarkGitOperationEvents(projects, since, drop); err != nil {
+				slog.Error("watcher: git operation failed", "err", err)
+			}
+		}
+		if isGit {
+			return
+		}
+	}
+
+	// An ignore file changed: re-read the rules before filtering.
+	if base := filepath.Base(ev.Name); base == ".gitignore" || base == config.RepoSettingsFile {
+		for _, root := range w.cfg.WatchPaths {
+			if within(root, ev.Name) {
+				w.loadGitPaths(root)
+			}
+		}
+	}
+
 	// Skip if path matches an ignore pattern.
-	if w.filter.ShouldIgnore(ev.Name) {
+	if w.ignored(ev.Name) {
 		return
 	}
 
@@ -117,8 +291,26 @@ func (w *Watcher) handleEvent(ev fsnotify.Event) {
 	})
 }
 
-// addRecursive walks root and adds every directory that is not ignored.
+// scanPolled feeds the changes in polled directories to the debouncer and
+// adds the directories created in them.
+func (w *Watcher) scanPolled(now time.Time) {
+	events, dirs := w.poll.scan(now)
+	for _, dir := range dirs {
+		_ = w.addRecursive(dir)
+	}
+	for _, e := range events {
+		if !w.ignored(e.Path) {
+			w.debouncer.Feed(e)
+		}
+	}
+}
+
+// addRecursive walks root and adds every directory that is not ignored:
+// to the poller in poll mode, otherwise to fsnotify. Directories that
+// cannot be watched are counted in Stats, and polled in auto mode when
+// the watch limit is the reason; the first failure is logged.
 func (w *Watcher) addRecursive(root string) error {
+	mode := w.cfg.WatchMode
 	return filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
 		if err != nil {
 			return nil // skip inaccessible entries
@@ -126,18 +318,74 @@ func (w *Watcher) addRecursive(root string) error {
 		if !d.IsDir() {
 			return nil
 		}
-		if w.filter.ShouldIgnore(path) {
+		if w.ignored(path) {
 			return filepath.SkipDir
 		}
-		_ = w.fsw.Add(path)
+		if mode == config.WatchModePoll {
+			w.poll.add(path)
+			return nil
+		}
+		if err = w.fsw.Add(path); err == nil {
+			return nil
+		}
+		limit := isWatchLimit(err)
+		if limit {
+			w.limitReached.Store(true)
+		}
+		poll := limit && mode != config.WatchModeNotify
+		if poll {
+			w.poll.add(path)
+		}
+		if w.failed.Add(1) == 1 {
+			switch {
+			case poll:
+				slog.Warn("watcher: file watch limit reached, polling the directories that cannot be watched; add patterns to watch_exclude or raise fs.inotify.max_user_watches", "dir", path)
+			case limit:
+				slog.Warn("watcher: file watch limit reached, changes in unwatched directories are missed; add patterns to watch_exclude, raise fs.inotify.max_user_watches or set watch_mode to auto", "dir", path)
+			default:
+				slog.Warn("watcher: watch failed", "dir", path, "err", err)
+			}
+		}
 		return nil
 	})
 }
 
+// ignored reports whether path matches an ignore pattern, or git leaves it
+// out of the working tree (see gitint.PathFilter). Paths under a watch root
+// are matched relative to it, so a pattern like "build" does not exclude a
+// project that lives in a directory named build.
+func (w *Watcher) ignored(path string) bool {
+	for _, root := range w.cfg.WatchPaths {
+		if within(root, path) {
+			rel, _ := filepath.Rel(root, path)
+			return w.filter.ShouldIgnore(rel) || w.gitPaths[root].Excluded(path)
+		}
+	}
+	return w.filter.ShouldIgnore(path)
+}
+
+// loadGitPaths reads the ignore rules and sparse checkout of the
+// repository containing root; roots outside git get none.
+func (w *Watcher) loadGitPaths(root string) {
+	w.gitPaths[root], _ = gitint.NewPathFilter(root, config.RepoIgnore(root))
+}
+
+// within reports whether path is root or below it.
+func within(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
 // projectPath returns the configured watch root that contains path, or the
 // path itself if no watch root matches.
 func (w *Watcher) projectPath(path string) string {
-	for _, root := range w.cfg.WatchPaths {
+	return ProjectRoot(w.cfg.WatchPaths, path)
+}
+
+// ProjectRoot returns the watch root in watchPaths that contains path, or
+// the path itself if no watch root matches.
+func ProjectRoot(watchPaths []string, path string) string {
+	for _, root := range watchPaths {
 		absRoot, err1 := filepath.Abs(root)
 		absPath, err2 := filepath.Abs(path)
 		if err1 == nil && err2 == nil {
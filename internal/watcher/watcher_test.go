@@ -1,9 +1,19 @@
 package watcher
 
 import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
 	"sync"
 	"testing"
 	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/anthropic/gap-map/internal/config"
+	"github.com/anthropic/gap-map/internal/metrics"
+	"github.com/anthropic/gap-map/internal/store"
 )
 
 // ---------------------------------------------------------------------------
@@ -11,7 +21,7 @@ import (
 // ---------------------------------------------------------------------------
 
 func TestFilterDefaultPatterns(t *testing.T) {
-	f := NewFilter(nil)
+	f := NewFilter(config.DefaultWatchExclude)
 
 	cases := []struct {
 		path string
@@ -56,7 +66,7 @@ func TestFilterAllowsNormalFiles(t *testing.T) {
 }
 
 func TestFilterNestedIgnore(t *testing.T) {
-	f := NewFilter(nil)
+	f := NewFilter(config.DefaultWatchExclude)
 
 	cases := []struct {
 		path string
@@ -90,7 +100,10 @@ func TestFilterCustomPatterns(t *testing.T) {
 		{"data/tmp/file", true},
 		// Default patterns still work.
 		{".git/config", true},
-		{"node_modules/x.js", true},
+		{".idea/workspace.xml", true},
+		// Watch exclusions are configured, not built in.
+		{"node_modules/x.js", false},
+		{"build/out.js", false},
 	}
 
 	for _, tc := range cases {
@@ -112,6 +125,169 @@ func TestFilterDuplicatePatterns(t *testing.T) {
 	}
 }
 
+func TestWatcherExcludesDirectories(t *testing.T) {
+	// The project lives under a directory named "build", which must not
+	// exclude it.
+	root := filepath.Join(t.TempDir(), "build", "proj")
+	for _, dir := range []string{"src/pkg", "node_modules/left-pad", "dist", "vendor/lib"} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s, err := store.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("store.New: %v", err)
+	}
+	defer s.Close()
+
+	cfg := config.Default()
+	cfg.WatchPaths = []string{root}
+	cfg.GitOperationEvents = config.GitOperationEventsOff
+	w := New(s, cfg)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- w.Start(ctx) }()
+	defer func() {
+		cancel()
+		<-done
+		w.Stop()
+	}()
+
+	// root, src and src/pkg.
+	deadline := time.Now().Add(5 * time.Second)
+	for w.Stats().Dirs != 3 {
+		if time.Now().After(deadline) {
+			t.Fatalf("watched dirs = %d, want 3", w.Stats().Dirs)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !w.ignored(filepath.Join(root, "node_modules", "left-pad", "index.js")) {
+		t.Error("node_modules not ignored")
+	}
+	if w.ignored(filepath.Join(root, "src", "main.go")) {
+		t.Error("src/main.go ignored")
+	}
+
+	cfg.WatchExclude = nil
+	w2 := New(s, cfg)
+	w2.filter = NewFilter(nil)
+	if w2.ignored(filepath.Join(root, "dist", "app.js")) {
+		t.Error("dist ignored with watch_exclude empty")
+	}
+}
+
+func TestWatcherRespectsGitIgnore(t *testing.T) {
+	root := t.TempDir()
+	for _, dir := range []string{"src", "out/js", "scratch"} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if out, err := exec.Command("git", "init", "-q", root).CombinedOutput(); err != nil {
+		t.Fatalf("git init: %v\n%s", err, out)
+	}
+	for name, content := range map[string]string{
+		".gitignore":        "out/\n*.log\n",
+		".git/info/exclude": "scratch/\n",
+	} {
+		if err := os.WriteFile(filepath.Join(root, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s, err := store.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("store.New: %v", err)
+	}
+	defer s.Close()
+
+	cfg := config.Default()
+	cfg.WatchPaths = []string{root}
+	cfg.GitOperationEvents = config.GitOperationEventsOff
+	w := New(s, cfg)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- w.Start(ctx) }()
+	defer func() {
+		cancel()
+		<-done
+		w.Stop()
+	}()
+
+	// root and src: out and scratch are ignored by git.
+	deadline := time.Now().Add(5 * time.Second)
+	for w.Stats().Dirs != 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("watched dirs = %d, want 2", w.Stats().Dirs)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	for path, want := range map[string]bool{
+		"src/main.go":      false,
+		"src/debug.log":    true,
+		"out/js/app.js":    true,
+		"scratch/notes.go": true,
+	} {
+		if got := w.ignored(filepath.Join(root, path)); got != want {
+			t.Errorf("ignored(%s) = %v, want %v", path, got, want)
+		}
+	}
+}
+
+func TestWatcherPollMode(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "src"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	s, err := store.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("store.New: %v", err)
+	}
+	defer s.Close()
+
+	cfg := config.Default()
+	cfg.WatchPaths = []string{root}
+	cfg.GitOperationEvents = config.GitOperationEventsOff
+	cfg.WatchMode = config.WatchModePoll
+	cfg.WatchPollInterval = "20ms"
+	w := New(s, cfg)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- w.Start(ctx) }()
+	defer func() {
+		cancel()
+		<-done
+		w.Stop()
+	}()
+
+	waitFor := func(what string, cond func() bool) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for !cond() {
+			if time.Now().After(deadline) {
+				t.Fatalf("timed out waiting for %s", what)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	waitFor("root and src to be polled", func() bool { return w.Stats().Polled == 2 })
+	if st := w.Stats(); st.Dirs != 0 || st.PollInterval != 20*time.Millisecond {
+		t.Errorf("stats = %+v, want no watches and a 20ms poll", st)
+	}
+
+	// A file in a new directory is found on the scans after it appears.
+	if err := os.MkdirAll(filepath.Join(root, "src", "pkg"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	waitFor("src/pkg to be polled", func() bool { return w.Stats().Polled == 3 })
+	if err := os.WriteFile(filepath.Join(root, "src", "pkg", "a.go"), []byte("package pkg\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	waitFor("the file event", func() bool {
+		stats, err := s.QueryFileEventStatsByProject()
+		return err == nil && stats[root].Events > 0
+	})
+}
+
 // ---------------------------------------------------------------------------
 // Debouncer tests
 // ---------------------------------------------------------------------------
@@ -281,3 +457,231 @@ func TestDebouncerFeedAfterStop(t *testing.T) {
 		t.Errorf("expected 0 emissions after stop, got %d", emitted)
 	}
 }
+
+// ---------------------------------------------------------------------------
+// Bulk detector tests
+// ---------------------------------------------------------------------------
+
+func TestBulkDetector(t *testing.T) {
+	b := newBulkDetector(3, time.Second)
+	base := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	at := func(ms int) time.Time { return base.Add(time.Duration(ms) * time.Millisecond) }
+
+	// Repeated saves of one file are not a bulk change.
+	for i := 0; i < 5; i++ {
+		if bulk, _, _ := b.observe(Event{Path: "/a.go", Timestamp: at(i * 100)}); bulk {
+			t.Fatalf("save %d of one file flagged as bulk", i)
+		}
+	}
+
+	// The fourth distinct file within the window starts a bulk change
+	// reaching back to the oldest event still in the window.
+	b.observe(Event{Path: "/b.go", Timestamp: at(500)})
+	b.observe(Event{Path: "/c.go", Timestamp: at(600)})
+	bulk, started, since := b.observe(Event{Path: "/d.go", Timestamp: at(700)})
+	if !bulk || !started || !since.Equal(at(0)) {
+		t.Fatalf("observe(/d.go) = %v, %v, %v; want bulk change started at %v", bulk, started, since, at(0))
+	}
+	if bulk, started, _ := b.observe(Event{Path: "/e.go", Timestamp: at(800)}); !bulk || started {
+		t.Errorf("observe(/e.go) = %v, %v; want bulk, already started", bulk, started)
+	}
+
+	// Once the window has passed, single edits are normal again.
+	if bulk, _, _ := b.observe(Event{Path: "/f.go", Timestamp: at(5000)}); bulk {
+		t.Error("edit after the burst flagged as bulk")
+	}
+}
+
+func TestBulkDetectors_PerProject(t *testing.T) {
+	d := newBulkDetectors(2, time.Second)
+	base := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+
+	// Two files in each of two projects stay under the threshold of each.
+	for i, path := range []string{"/p/a.go", "/q/a.go", "/p/b.go", "/q/b.go"} {
+		project := path[:2]
+		if bulk, _, _ := d.project(project).observe(Event{Path: path, Timestamp: base.Add(time.Duration(i) * time.Millisecond)}); bulk {
+			t.Fatalf("observe(%s) flagged as bulk", path)
+		}
+	}
+	if bulk, started, _ := d.project("/p").observe(Event{Path: "/p/c.go", Timestamp: base.Add(5 * time.Millisecond)}); !bulk || !started {
+		t.Errorf("third file in /p = %v, %v; want bulk change started", bulk, started)
+	}
+	if d.project("/p") != d.project("/p") {
+		t.Error("project returned a new detector for a known project")
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Git operation detector tests
+// ---------------------------------------------------------------------------
+
+func TestGitOpDetector(t *testing.T) {
+	repo := t.TempDir()
+	project := filepath.Join(repo, "service")
+	if err := os.MkdirAll(filepath.Join(repo, ".git"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(project, 0755); err != nil {
+		t.Fatal(err)
+	}
+	gitDir := filepath.Join(repo, ".git")
+
+	g := newGitOpDetector([]string{project})
+	if dirs := g.gitDirs(); len(dirs) != 1 || dirs[0] != gitDir {
+		t.Fatalf("gitDirs = %v, want [%s]", dirs, gitDir)
+	}
+
+	base := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	at := func(ms int) time.Time { return base.Add(time.Duration(ms) * time.Millisecond) }
+
+	if isGit, _, _, _ := g.observe(filepath.Join(project, "main.go"), fsnotify.Write, at(0)); isGit {
+		t.Error("project file reported as a git change")
+	}
+
+	// Checkout: take the index lock, rewrite files, release, write HEAD.
+	if isGit, op, _, _ := g.observe(filepath.Join(gitDir, "index.lock"), fsnotify.Create, at(1000)); !isGit || op {
+		t.Errorf("index.lock = %v, %v; want git change, no operation", isGit, op)
+	}
+	g.observe(filepath.Join(gitDir, "index.lock"), fsnotify.Write, at(2500))
+	g.observe(filepath.Join(gitDir, "index.lock"), fsnotify.Rename, at(3000))
+	_, op, projects, since := g.observe(filepath.Join(gitDir, "HEAD"), fsnotify.Create, at(3100))
+	if !op || len(projects) != 1 || projects[0] != project || !since.Equal(at(1000)) {
+		t.Fatalf("HEAD = %v, %v, %v; want operation in %s since %v", op, projects, since, project, at(1000))
+	}
+
+	if !g.during(project, at(2000)) || !g.during(project, at(3300)) {
+		t.Error("events during and just after the checkout not attributed to it")
+	}
+	if g.during(project, at(500)) || g.during(project, at(4000)) {
+		t.Error("events before or well after the checkout attributed to it")
+	}
+
+	// A HEAD change with no index lock looks back a fixed interval.
+	_, _, _, since = g.observe(filepath.Join(gitDir, "ORIG_HEAD"), fsnotify.Write, at(60000))
+	if !since.Equal(at(60000).Add(-gitOpLookback)) {
+		t.Errorf("ORIG_HEAD since = %v, want %v", since, at(60000).Add(-gitOpLookback))
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Poller tests
+// ---------------------------------------------------------------------------
+
+func TestPoller(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("keep.go", "package a\n")
+	write("edit.go", "package a\n")
+	write("gone.go", "package a\n")
+
+	p := newPoller()
+	p.add(dir)
+	if events, dirs := p.scan(time.Now()); len(events) != 0 || len(dirs) != 0 {
+		t.Fatalf("first scan = %v, %v; want no changes", events, dirs)
+	}
+
+	write("edit.go", "package a\n\nfunc f() {}\n")
+	write("new.go", "package a\n")
+	if err := os.Remove(filepath.Join(dir, "gone.go")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(dir, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	events, dirs := p.scan(time.Now())
+	var got []string
+	for _, e := range events {
+		got = append(got, e.Type+" "+filepath.Base(e.Path))
+	}
+	want := []string{"modify edit.go", "delete gone.go", "create new.go"}
+	if len(got) != len(want) {
+		t.Fatalf("events = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("event %d = %q, want %q", i, got[i], want[i])
+		}
+	}
+	if len(dirs) != 1 || dirs[0] != filepath.Join(dir, "sub") {
+		t.Errorf("new dirs = %v, want [sub]", dirs)
+	}
+
+	// A directory that disappears stops being polled.
+	if err := os.RemoveAll(dir); err != nil {
+		t.Fatal(err)
+	}
+	events, _ = p.scan(time.Now())
+	if len(events) != 3 || p.len() != 0 {
+		t.Errorf("after removal: %d events, %d dirs polled; want 3 deletes and none", len(events), p.len())
+	}
+}
+
+// ---------------------------------------------------------------------------
+// File guard tests
+// ---------------------------------------------------------------------------
+
+func TestFileGuard(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"main.go":   "package main\n",
+		"app.js":    "a()\nb()\nc()\n",
+		"image.png": "\x89PNG\x00\x00",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	g := newFileGuard(metrics.FileGuard{MaxLines: 2})
+	cases := []struct {
+		name, typ string
+		want      bool
+	}{
+		{"main.go", "modify", false},
+		{"app.js", "modify", true},
+		{"image.png", "create", true},
+		{"image.png", "delete", false},
+		{"missing.go", "modify", false},
+	}
+	for _, c := range cases {
+		if got := g.excludes(Event{Path: filepath.Join(dir, c.name), Type: c.typ}); got != c.want {
+			t.Errorf("excludes(%s %s) = %v, want %v", c.typ, c.name, got, c.want)
+		}
+	}
+
+	// A capped large file is still watched.
+	g = newFileGuard(metrics.FileGuard{MaxLines: 2, Cap: true})
+	if g.excludes(Event{Path: filepath.Join(dir, "app.js"), Type: "modify"}) {
+		t.Error("capped large file excluded")
+	}
+}
+
+func TestUntrackedRoot(t *testing.T) {
+	dir := t.TempDir()
+	watched := filepath.Join(dir, "api")
+	sibling := filepath.Join(dir, "web")
+	for _, d := range []string{filepath.Join(watched, "pkg"), filepath.Join(sibling, ".git"), filepath.Join(sibling, "src"), filepath.Join(dir, "scratch")} {
+		if err := os.MkdirAll(d, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	watchPaths := []string{watched}
+
+	if root, repo := UntrackedRoot(watchPaths, filepath.Join(watched, "pkg", "a.go")); root != "" || repo {
+		t.Errorf("watched file: got %q, %v; want untracked root \"\"", root, repo)
+	}
+	if root, repo := UntrackedRoot(watchPaths, filepath.Join(sibling, "src", "deleted.ts")); root != sibling || !repo {
+		t.Errorf("sibling repo file: got %q, %v; want %q, true", root, repo, sibling)
+	}
+	scratch := filepath.Join(dir, "scratch")
+	if root, repo := UntrackedRoot(watchPaths, filepath.Join(scratch, "notes.md")); root != scratch || repo {
+		t.Errorf("scratch file: got %q, %v; want %q, false", root, repo, scratch)
+	}
+}
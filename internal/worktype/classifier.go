@@ -15,13 +15,19 @@ type OverrideReader interface {
 }
 
 // Classifier applies heuristic pattern rules to classify each file change
-// into one of six work types. Overrides are checked first; pattern rules
-// are applied in descending priority order; the default is CoreLogic.
+// into one of eight work types. Overrides are checked first, then path
+// rules; pattern rules are applied in descending priority order; the
+// default is CoreLogic.
 type Classifier struct {
-	rules    []PatternRule
-	override OverrideReader
+	rules     []PatternRule
+	override  OverrideReader
+	pathRules PathRules
 }
 
+// PathRules returns the work type a repository assigns to a file path, if
+// any, as its .gapmap.toml work_types do.
+type PathRules func(filePath string) (WorkType, bool)
+
 // NewClassifier creates a Classifier with default rules and an optional
 // override reader. Pass nil for override if no override checking is needed.
 func NewClassifier(override OverrideReader) *Classifier {
@@ -36,19 +42,36 @@ func NewClassifier(override OverrideReader) *Classifier {
 	}
 }
 
+// SetPathRules makes c check rules after overrides and before its own
+// rules. Pass nil to remove them.
+func (c *Classifier) SetPathRules(rules PathRules) {
+	c.pathRules = rules
+}
+
+// PathRule returns the work type the path rules give filePath, if any.
+func (c *Classifier) PathRule(filePath string) (WorkType, bool) {
+	if c.pathRules == nil {
+		return "", false
+	}
+	return c.pathRules(filePath)
+}
+
 // ClassifyFile determines the work type of a file change based on its path,
 // diff content, and commit message.
 //
 // Evaluation order:
-//  1. Check override store (specific file/commit override wins immediately).
+//  1. Check override store (specific file/commit override wins immediately),
+//     then the path rules.
 //  2. Check file path against test path segments.
-//  3. Check file path against architecture path segments.
-//  4. Evaluate pattern rules in descending priority order.
+//  3. Check file path against migration, then documentation path segments.
+//  4. Check file path against architecture path segments.
+//  5. Evaluate pattern rules in descending priority order.
 //     - Architecture rules match keywords in diffContent.
 //     - Bug fix rules match keywords in commitMessage (not diffContent).
 //     - Edge case rules use keyword threshold (>= 3 occurrences).
+//     - Database migration rules need a file glob and a keyword to match.
 //     - All other rules match file path globs or keywords in diffContent.
-//  5. Default: CoreLogic.
+//  6. Default: CoreLogic.
 func (c *Classifier) ClassifyFile(filePath string, diffContent string, commitMessage string) WorkType {
 	// Step 1: Check overrides.
 	if c.override != nil {
@@ -56,6 +79,9 @@ func (c *Classifier) ClassifyFile(filePath string, diffContent string, commitMes
 			return WorkType(wt)
 		}
 	}
+	if wt, ok := c.PathRule(filePath); ok {
+		return wt
+	}
 
 	// Step 2: Test path segments (checked before rules for fast detection).
 	lowerPath := strings.ToLower(filePath)
@@ -65,14 +91,28 @@ func (c *Classifier) ClassifyFile(filePath string, diffContent string, commitMes
 		}
 	}
 
-	// Step 3: Architecture path segments.
+	// Step 3: Migration and documentation path segments. They win over
+	// architecture ones, e.g. for db/schema/migrations/.
+	rootedPath := "/" + strings.TrimPrefix(lowerPath, "/")
+	for _, seg := range migrationPathSegments {
+		if strings.Contains(rootedPath, seg) {
+			return DatabaseMigration
+		}
+	}
+	for _, seg := range documentationPathSegments {
+		if strings.Contains(rootedPath, seg) {
+			return Documentation
+		}
+	}
+
+	// Step 4: Architecture path segments.
 	for _, seg := range architecturePathSegments {
 		if strings.Contains(lowerPath, seg) {
 			return Architecture
 		}
 	}
 
-	// Step 4: Evaluate pattern rules in descending priority order.
+	// Step 5: Evaluate pattern rules in descending priority order.
 	baseName := path.Base(filePath)
 	lowerDiff := strings.ToLower(diffContent)
 	lowerCommit := strings.ToLower(commitMessage)
@@ -83,7 +123,7 @@ func (c *Classifier) ClassifyFile(filePath string, diffContent string, commitMes
 		}
 	}
 
-	// Step 5: Default fallback.
+	// Step 6: Default fallback.
 	return CoreLogic
 }
 
@@ -114,6 +154,10 @@ func (c *Classifier) matchRule(rule PatternRule, baseName, lowerPath, lowerDiff,
 		// Architecture matches keywords in diff content.
 		return c.matchAnyKeyword(rule.Keywords, lowerDiff)
 
+	case DatabaseMigration:
+		// A SQL file is a migration only when it changes the schema.
+		return c.matchGlob(rule.FileGlobs, baseName) && c.matchAnyKeyword(rule.Keywords, lowerDiff)
+
 	default:
 		// File glob match (test scaffolding, boilerplate).
 		if c.matchGlob(rule.FileGlobs, baseName) {
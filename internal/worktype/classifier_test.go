@@ -216,6 +216,35 @@ func TestClassifyFile_OverrideWins(t *testing.T) {
 	}
 }
 
+func TestClassifyFile_PathRules(t *testing.T) {
+	override := &mockOverrideReader{
+		overrides: map[string]string{
+			"e2e/pinned_test.go|": string(BugFix),
+		},
+	}
+	c := NewClassifier(override)
+	c.SetPathRules(func(filePath string) (WorkType, bool) {
+		if strings.HasPrefix(filePath, "e2e/") {
+			return Boilerplate, true
+		}
+		return "", false
+	})
+
+	// Path rules win over the built-in rules, not over overrides.
+	if wt := c.ClassifyFile("e2e/login_test.go", "", ""); wt != Boilerplate {
+		t.Errorf("ClassifyFile with a path rule = %q, want %q", wt, Boilerplate)
+	}
+	if wt := c.ClassifyFile("e2e/pinned_test.go", "", ""); wt != BugFix {
+		t.Errorf("ClassifyFile with an override = %q, want %q", wt, BugFix)
+	}
+	if wt := c.ClassifyFile("src/login_test.go", "", ""); wt != TestScaffolding {
+		t.Errorf("ClassifyFile without a path rule = %q, want %q", wt, TestScaffolding)
+	}
+	if _, ok := c.PathRule("src/main.go"); ok {
+		t.Error("PathRule matched a path without a rule")
+	}
+}
+
 func TestClassifyFileWithCommit_CommitSpecificOverride(t *testing.T) {
 	override := &mockOverrideReader{
 		overrides: map[string]string{
@@ -248,19 +277,72 @@ func TestClassifyFile_PriorityOrdering(t *testing.T) {
 	}
 }
 
+func TestClassifyFile_Documentation(t *testing.T) {
+	c := NewClassifier(nil)
+
+	tests := []struct {
+		path string
+		diff string
+	}{
+		{"README.md", ""},
+		{"docs/guide/setup.md", ""},
+		{"docs/conf.py", "html_theme = 'alabaster'"},
+		{"internal/api/CHANGES.rst", ""},
+		// Code samples in prose stay documentation.
+		{"guide.mdx", "if err != nil {\n\treturn err\n}\nif err != nil {}\nfallback"},
+		{"site/docs/models/user.adoc", "type User struct"},
+	}
+
+	for _, tt := range tests {
+		wt := c.ClassifyFile(tt.path, tt.diff, "")
+		if wt != Documentation {
+			t.Errorf("ClassifyFile(%q) = %q, want %q", tt.path, wt, Documentation)
+		}
+	}
+}
+
+func TestClassifyFile_DatabaseMigration(t *testing.T) {
+	c := NewClassifier(nil)
+
+	tests := []struct {
+		path string
+		diff string
+		want WorkType
+	}{
+		{"migrations/0001_init.sql", "CREATE TABLE users (id int);", DatabaseMigration},
+		{"db/migrations/0002_users.go", "func up(tx *sql.Tx) error {", DatabaseMigration},
+		{"db/migrate/20240101_add_email.rb", "add_column :users, :email", DatabaseMigration},
+		{"db/schema/migrations/0003.sql", "", DatabaseMigration},
+		{"sql/0004.sql", "ALTER TABLE users ADD COLUMN email text;", DatabaseMigration},
+		{"sql/indexes.sql", "create unique index users_email on users (email);", DatabaseMigration},
+		// SQL without DDL is queries.
+		{"queries/users.sql", "SELECT id, email FROM users WHERE id = $1;", CoreLogic},
+		// Only a migrations/ directory counts, not packages named migration.
+		{"internal/migration/runner.go", "func Run() {}", CoreLogic},
+	}
+
+	for _, tt := range tests {
+		wt := c.ClassifyFile(tt.path, tt.diff, "")
+		if wt != tt.want {
+			t.Errorf("ClassifyFile(%q) = %q, want %q", tt.path, wt, tt.want)
+		}
+	}
+}
+
 // ---------------------------------------------------------------------------
 // Work type constants and weights
 // ---------------------------------------------------------------------------
 
 func TestAllWorkTypes_Complete(t *testing.T) {
 	all := AllWorkTypes()
-	if len(all) != 6 {
-		t.Fatalf("AllWorkTypes() has %d entries, want 6", len(all))
+	if len(all) != 8 {
+		t.Fatalf("AllWorkTypes() has %d entries, want 8", len(all))
 	}
 
 	expected := map[WorkType]bool{
 		Architecture: true, CoreLogic: true, Boilerplate: true,
 		BugFix: true, EdgeCase: true, TestScaffolding: true,
+		Documentation: true, DatabaseMigration: true,
 	}
 	for _, wt := range all {
 		if !expected[wt] {
@@ -293,6 +375,22 @@ func TestWorkTypeWeights_ThreeTiers(t *testing.T) {
 	if WorkTypeWeights[TestScaffolding] != 1.0 {
 		t.Errorf("TestScaffolding weight = %f, want 1.0", WorkTypeWeights[TestScaffolding])
 	}
+
+	// Migrations sit below the medium tier, documentation below low.
+	if WorkTypeWeights[DatabaseMigration] != 1.5 {
+		t.Errorf("DatabaseMigration weight = %f, want 1.5", WorkTypeWeights[DatabaseMigration])
+	}
+	if WorkTypeWeights[Documentation] != 0.5 {
+		t.Errorf("Documentation weight = %f, want 0.5", WorkTypeWeights[Documentation])
+	}
+	for _, wt := range AllWorkTypes() {
+		if _, ok := WorkTypeWeights[wt]; !ok {
+			t.Errorf("no weight for %q", wt)
+		}
+		if _, ok := WorkTypeTier[wt]; !ok {
+			t.Errorf("no tier for %q", wt)
+		}
+	}
 }
 
 func TestWorkTypeTier_Mappings(t *testing.T) {
@@ -308,11 +406,17 @@ func TestWorkTypeTier_Mappings(t *testing.T) {
 	if WorkTypeTier[Boilerplate] != TierLow {
 		t.Errorf("Boilerplate tier = %q, want %q", WorkTypeTier[Boilerplate], TierLow)
 	}
+	if WorkTypeTier[DatabaseMigration] != TierMedium {
+		t.Errorf("DatabaseMigration tier = %q, want %q", WorkTypeTier[DatabaseMigration], TierMedium)
+	}
+	if WorkTypeTier[Documentation] != TierMinimal {
+		t.Errorf("Documentation tier = %q, want %q", WorkTypeTier[Documentation], TierMinimal)
+	}
 }
 
 func TestDefaultRules_Count(t *testing.T) {
 	rules := DefaultRules()
-	if len(rules) != 5 {
-		t.Errorf("DefaultRules() has %d entries, want 5", len(rules))
+	if len(rules) != 7 {
+		t.Errorf("DefaultRules() has %d entries, want 7", len(rules))
 	}
 }
@@ -1,7 +1,8 @@
 // Package worktype provides a heuristic work-type classifier that labels each
-// file change as one of six categories: architecture, core_logic, boilerplate,
-// bug_fix, edge_case, or test_scaffolding. Classification uses file path
-// patterns and code content keywords rather than LLM inference.
+// file change as one of eight categories: architecture, core_logic, boilerplate,
+// bug_fix, edge_case, test_scaffolding, documentation, or database_migration.
+// Classification uses file path patterns and code content keywords rather
+// than LLM inference.
 package worktype
 
 // WorkType represents the category of work a file change falls into.
@@ -31,6 +32,14 @@ const (
 	// TestScaffolding represents test files identified by naming conventions
 	// and path patterns.
 	TestScaffolding WorkType = "test_scaffolding"
+
+	// Documentation represents prose: Markdown and other markup files, and
+	// anything under a docs/ directory.
+	Documentation WorkType = "documentation"
+
+	// DatabaseMigration represents schema changes: files under a
+	// migrations/ directory, and SQL files containing DDL statements.
+	DatabaseMigration WorkType = "database_migration"
 )
 
 // AllWorkTypes returns the complete set of work type constants.
@@ -42,6 +51,8 @@ func AllWorkTypes() []WorkType {
 		BugFix,
 		EdgeCase,
 		TestScaffolding,
+		Documentation,
+		DatabaseMigration,
 	}
 }
 
@@ -54,38 +65,48 @@ const (
 	// AI percentage: architecture and core logic.
 	TierHigh WeightTier = "high"
 
-	// TierMedium represents work types with moderate impact: bug fixes and
-	// edge case handling.
+	// TierMedium represents work types with moderate impact: bug fixes,
+	// edge case handling and database migrations.
 	TierMedium WeightTier = "medium"
 
-	// TierLow represents work types with the lowest impact: boilerplate
-	// and test scaffolding.
+	// TierLow represents work types with low impact: boilerplate and test
+	// scaffolding.
 	TierLow WeightTier = "low"
+
+	// TierMinimal represents work types that barely affect meaningful AI
+	// percentage: documentation.
+	TierMinimal WeightTier = "minimal"
 )
 
 // WorkTypeWeights maps each work type to its numeric weight for the meaningful
 // AI percentage calculation.
 //
-//	High tier   (architecture, core_logic):     3.0
-//	Medium tier (bug_fix, edge_case):           2.0
-//	Low tier    (boilerplate, test_scaffolding): 1.0
+//	High tier    (architecture, core_logic):     3.0
+//	Medium tier  (bug_fix, edge_case):           2.0
+//	             (database_migration):           1.5
+//	Low tier     (boilerplate, test_scaffolding): 1.0
+//	Minimal tier (documentation):                0.5
 var WorkTypeWeights = map[WorkType]float64{
-	Architecture:    3.0,
-	CoreLogic:       3.0,
-	BugFix:          2.0,
-	EdgeCase:        2.0,
-	Boilerplate:     1.0,
-	TestScaffolding: 1.0,
+	Architecture:      3.0,
+	CoreLogic:         3.0,
+	BugFix:            2.0,
+	EdgeCase:          2.0,
+	DatabaseMigration: 1.5,
+	Boilerplate:       1.0,
+	TestScaffolding:   1.0,
+	Documentation:     0.5,
 }
 
 // WorkTypeTier maps each work type to its weight tier.
 var WorkTypeTier = map[WorkType]WeightTier{
-	Architecture:    TierHigh,
-	CoreLogic:       TierHigh,
-	BugFix:          TierMedium,
-	EdgeCase:        TierMedium,
-	Boilerplate:     TierLow,
-	TestScaffolding: TierLow,
+	Architecture:      TierHigh,
+	CoreLogic:         TierHigh,
+	BugFix:            TierMedium,
+	EdgeCase:          TierMedium,
+	DatabaseMigration: TierMedium,
+	Boilerplate:       TierLow,
+	TestScaffolding:   TierLow,
+	Documentation:     TierMinimal,
 }
 
 // PatternRule defines a heuristic rule for classifying file changes.
@@ -107,7 +128,9 @@ type PatternRule struct {
 //	30 - Edge case (error handling keywords)
 //	35 - Bug fix (commit message keywords, secondary signal)
 //	40 - Architecture (interface/struct/type definitions)
-//	50 - Core logic (default fallback, not a pattern rule)
+//	45 - Documentation (Markdown and other markup files)
+//	50 - Database migration (SQL files with DDL keywords)
+//	   - Core logic (default fallback, not a pattern rule)
 func DefaultRules() []PatternRule {
 	return []PatternRule{
 		{
@@ -201,6 +224,42 @@ func DefaultRules() []PatternRule {
 			},
 			Priority: 40,
 		},
+		{
+			// Above the keyword rules, so code samples in prose do not
+			// make it edge case or architecture.
+			WorkType: Documentation,
+			FileGlobs: []string{
+				"*.md",
+				"*.mdx",
+				"*.rst",
+				"*.adoc",
+			},
+			Keywords: nil,
+			Priority: 45,
+		},
+		{
+			// Both a glob and a keyword must match: SQL without DDL is
+			// queries, classified as usual.
+			WorkType: DatabaseMigration,
+			FileGlobs: []string{
+				"*.sql",
+			},
+			Keywords: []string{
+				"create table",
+				"alter table",
+				"drop table",
+				"rename table",
+				"create index",
+				"create unique index",
+				"drop index",
+				"add column",
+				"drop column",
+				"create type",
+				"create view",
+				"create schema",
+			},
+			Priority: 50,
+		},
 	}
 }
 
@@ -212,6 +271,19 @@ var testPathSegments = []string{
 	"/testing/",
 }
 
+// documentationPathSegments are directory path segments that indicate
+// documentation files.
+var documentationPathSegments = []string{
+	"/docs/",
+}
+
+// migrationPathSegments are directory path segments that indicate
+// database migration files.
+var migrationPathSegments = []string{
+	"/migrations/",
+	"/db/migrate/",
+}
+
 // architecturePathSegments are directory path segments that indicate
 // architecture/type definition files.
 var architecturePathSegments = []string{
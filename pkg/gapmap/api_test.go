@@ -0,0 +1,123 @@
+package gapmap
+
+import (
+	"bytes"
+	"flag"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+var updateAPI = flag.Bool("update", false, "rewrite testdata/api.txt with the current API")
+
+// TestAPI guards the package's semver promise: the exported API must match
+// testdata/api.txt. Additions are fine within a major version; run
+// "go test ./pkg/gapmap -update" to record them. Any other change needs a
+// new major version.
+func TestAPI(t *testing.T) {
+	got := exportedAPI(t)
+	golden := filepath.Join("testdata", "api.txt")
+	if *updateAPI {
+		if err := os.WriteFile(golden, []byte(got), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	data, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	have := make(map[string]bool)
+	for _, line := range strings.Split(got, "\n") {
+		have[line] = true
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if !have[line] {
+			t.Errorf("exported API no longer has:\n%s", line)
+		}
+	}
+	if got != string(data) {
+		t.Log("exported API gained declarations; run go test -update to record them")
+	}
+}
+
+// exportedAPI lists the package's exported declarations, one per line and
+// sorted, with struct fields listed individually.
+func exportedAPI(t *testing.T) string {
+	t.Helper()
+	fset := token.NewFileSet()
+	paths, err := filepath.Glob("*.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var files []*ast.File
+	for _, path := range paths {
+		if strings.HasSuffix(path, "_test.go") {
+			continue
+		}
+		f, err := parser.ParseFile(fset, path, nil, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, f)
+	}
+
+	src := func(node interface{}) string {
+		var b bytes.Buffer
+		if err := printer.Fprint(&b, fset, node); err != nil {
+			t.Fatal(err)
+		}
+		return strings.Join(strings.Fields(b.String()), " ")
+	}
+
+	var lines []string
+	for _, f := range files {
+		for _, decl := range f.Decls {
+			switch d := decl.(type) {
+			case *ast.FuncDecl:
+				if !d.Name.IsExported() {
+					continue
+				}
+				d.Body = nil
+				lines = append(lines, src(d))
+			case *ast.GenDecl:
+				for _, spec := range d.Specs {
+					switch s := spec.(type) {
+					case *ast.TypeSpec:
+						if !s.Name.IsExported() {
+							continue
+						}
+						st, ok := s.Type.(*ast.StructType)
+						if !ok {
+							lines = append(lines, "type "+src(s))
+							continue
+						}
+						lines = append(lines, "type "+s.Name.Name+" struct")
+						for _, field := range st.Fields.List {
+							for _, name := range field.Names {
+								if name.IsExported() {
+									lines = append(lines, s.Name.Name+"."+name.Name+" "+src(field.Type))
+								}
+							}
+						}
+					case *ast.ValueSpec:
+						for _, name := range s.Names {
+							if name.IsExported() {
+								lines = append(lines, d.Tok.String()+" "+name.Name)
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+	sort.Strings(lines)
+	return strings.Join(lines, "\n") + "\n"
+}
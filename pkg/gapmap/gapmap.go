@@ -0,0 +1,282 @@
+// Package gapmap lets other Go programs query a gap-map attribution
+// database: project and file reports of how much code AI wrote, and code
+// survival.
+//
+// The API of this package follows semantic versioning: it only gains
+// fields, methods and functions within a major version, and the types are
+// its own, not the internal ones, so internal changes do not break callers.
+// api_test.go pins the exported API.
+//
+//	p, err := gapmap.OpenProject(filepath.Join(home, ".gapmap", "gapmap.db"), nil)
+//	if err != nil { ... }
+//	defer p.Close()
+//	r, err := p.ProjectReport(ctx)
+//	fmt.Printf("%.1f%% meaningful AI\n", r.MeaningfulAIPct)
+package gapmap
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/anthropic/gap-map/internal/config"
+	"github.com/anthropic/gap-map/internal/keychain"
+	"github.com/anthropic/gap-map/internal/metrics"
+	"github.com/anthropic/gap-map/internal/report"
+	"github.com/anthropic/gap-map/internal/store"
+	"github.com/anthropic/gap-map/internal/survival"
+)
+
+// APIVersion is the version of this package's API.
+const APIVersion = "1.3.0"
+
+// Options configure how a Project computes reports. The zero value uses
+// the defaults of the gapmap CLI.
+type Options struct {
+	// Scorer weights work types in the meaningful AI percentage:
+	// "weighted" (default), "raw" or "custom".
+	Scorer string
+	// ScorerWeights override the default weight of work types for the
+	// "custom" scorer; a weight of 0 excludes a work type.
+	ScorerWeights map[string]float64
+
+	// LineMatch selects how changed lines are matched against AI output:
+	// "exact" (default) or "normalized". SimilarityThreshold and
+	// RevisionThreshold, when above 0, also attribute lines that share
+	// enough tokens with an AI line or are small edits of one, and
+	// ExcludeComments leaves comment-only lines out. A repository's
+	// .gapmap.toml overrides them, as in the CLI. Added in 1.2.0.
+	LineMatch           string
+	SimilarityThreshold float64
+	RevisionThreshold   float64
+	ExcludeComments     bool
+
+	// DiffAlgorithm is the git diff algorithm changes are read with:
+	// "myers" (default), "patience" or "histogram". It should match the
+	// daemon's diff_algorithm. Added in 1.3.0.
+	DiffAlgorithm string
+
+	// MaxFileLines leaves files with more lines, such as generated
+	// bundles, out of reports, or with CapLargeFiles attributes only their
+	// first MaxFileLines changed lines; 0 means the CLI default of 20000
+	// and a negative value no limit. IncludeBinaryFiles attributes binary
+	// files, which are left out by default. Added in 1.3.0.
+	MaxFileLines       int
+	CapLargeFiles      bool
+	IncludeBinaryFiles bool
+}
+
+// Project is an open attribution database. It is safe for concurrent use.
+type Project struct {
+	store  *store.Store
+	scorer metrics.Scorer
+	opts   report.Options
+}
+
+// OpenProject opens the attribution database at dbPath, as written by the
+// gapmap daemon (by default ~/.gapmap/gapmap.db). Older databases are
+// migrated to the current schema. A database encrypted with "gapmap
+// encrypt-db" is read with the key from $GAPMAP_DB_KEY or the OS keychain,
+// as in the CLI. opts may be nil.
+func OpenProject(dbPath string, opts *Options) (*Project, error) {
+	if opts == nil {
+		opts = &Options{}
+	}
+	scorer, err := metrics.NewScorer(opts.Scorer, opts.ScorerWeights)
+	if err != nil {
+		return nil, fmt.Errorf("scorer: %w", err)
+	}
+	if _, err := metrics.NewMatchOptions(opts.LineMatch, opts.SimilarityThreshold); err != nil {
+		return nil, fmt.Errorf("line match: %w", err)
+	}
+	if opts.RevisionThreshold < 0 || opts.RevisionThreshold > 1 {
+		return nil, fmt.Errorf("revision threshold %v must be between 0 and 1", opts.RevisionThreshold)
+	}
+	if err := metrics.ValidDiffAlgorithm(opts.DiffAlgorithm); err != nil {
+		return nil, err
+	}
+	match := &config.Config{
+		LineMatch:           opts.LineMatch,
+		SimilarityThreshold: opts.SimilarityThreshold,
+		RevisionThreshold:   opts.RevisionThreshold,
+		ExcludeComments:     opts.ExcludeComments,
+	}
+	guard := metrics.FileGuard{MaxLines: opts.MaxFileLines, Cap: opts.CapLargeFiles, AllowBinary: opts.IncludeBinaryFiles}
+	switch {
+	case guard.MaxLines == 0:
+		guard.MaxLines = config.Default().MaxFileLines
+	case guard.MaxLines < 0:
+		guard.MaxLines = 0
+	}
+	s, err := store.New(dbPath, store.WithKeySource(keychain.Key))
+	if err != nil {
+		return nil, fmt.Errorf("open store: %w", err)
+	}
+	return &Project{store: s, scorer: scorer, opts: report.Options{LineMatch: match.MatchOptions, Guard: guard, DiffAlgorithm: opts.DiffAlgorithm}}, nil
+}
+
+// Close closes the database.
+func (p *Project) Close() error {
+	return p.store.Close()
+}
+
+// ProjectReport is the attribution of every tracked file of the project.
+type ProjectReport struct {
+	ProjectPath     string
+	Scorer          string
+	MeaningfulAIPct float64 // AI% with lines weighted by work type
+	RawAIPct        float64 // AI% of changed lines
+	TotalFiles      int
+	TotalLines      int // lines changed since tracking began
+	AILines         int
+	DeletedLines    int
+	AIDeletedLines  int
+	ByWorkType      map[string]WorkTypeSummary
+	Files           []FileReport // by AI% descending
+	// ByModel is the AI work of each model ("unknown" for sessions that
+	// do not record one). Added in 1.1.0.
+	ByModel map[string]ModelSummary
+}
+
+// ModelSummary is the AI work of one model.
+type ModelSummary struct {
+	Files        int // files with an AI event by the model
+	AIEvents     int
+	LinesWritten int // lines its Write and Edit calls changed
+}
+
+// WorkTypeSummary is the attribution of the files of one work type.
+type WorkTypeSummary struct {
+	Files      int
+	TotalLines int
+	AILines    int
+	AIPct      float64
+	Tier       string  // "high", "medium", "low" or "minimal"
+	Weight     float64 // weight in the meaningful AI percentage
+}
+
+// FileReport is the attribution of one file.
+type FileReport struct {
+	Path            string // as tracked, absolute or relative to ProjectPath
+	WorkType        string
+	AuthorshipLevel string // "mostly_ai", "mixed" or "mostly_human"
+	MeaningfulAIPct float64
+	RawAIPct        float64
+	TotalLines      int
+	AILines         int
+	DeletedLines    int
+	AIDeletedLines  int
+}
+
+// SurvivalReport is how much AI-written code is still in the files.
+type SurvivalReport struct {
+	Tracked      int
+	Survived     int
+	Rate         float64 // percentage of tracked lines that survived
+	ByAuthorship map[string]SurvivalBreakdown
+	ByWorkType   map[string]SurvivalBreakdown
+	ByModel      map[string]SurvivalBreakdown // added in 1.1.0
+}
+
+// SurvivalBreakdown is the survival of one authorship level or work type.
+type SurvivalBreakdown struct {
+	Tracked  int
+	Survived int
+	Rate     float64
+}
+
+// ProjectReport attributes every tracked file of the project.
+func (p *Project) ProjectReport(ctx context.Context) (*ProjectReport, error) {
+	r, err := report.GenerateProject(ctx, p.store, p.scorer, report.Filter{}, p.opts)
+	if err != nil {
+		return nil, err
+	}
+	pr := &ProjectReport{
+		ProjectPath:     r.ProjectPath,
+		Scorer:          r.Scorer,
+		MeaningfulAIPct: r.MeaningfulAIPct,
+		RawAIPct:        r.RawAIPct,
+		TotalFiles:      r.TotalFiles,
+		TotalLines:      r.TotalLines,
+		AILines:         r.AILines,
+		DeletedLines:    r.DeletedLines,
+		AIDeletedLines:  r.AIDeletedLines,
+		ByWorkType:      make(map[string]WorkTypeSummary, len(r.ByWorkType)),
+		Files:           make([]FileReport, 0, len(r.Files)),
+		ByModel:         make(map[string]ModelSummary, len(r.ByModel)),
+	}
+	for wt, s := range r.ByWorkType {
+		pr.ByWorkType[wt] = WorkTypeSummary{
+			Files:      s.Files,
+			TotalLines: s.TotalLines,
+			AILines:    s.AILines,
+			AIPct:      s.AIPct,
+			Tier:       s.Tier,
+			Weight:     s.Weight,
+		}
+	}
+	for i := range r.Files {
+		pr.Files = append(pr.Files, fileReport(&r.Files[i]))
+	}
+	for m, s := range r.ByModel {
+		pr.ByModel[m] = ModelSummary{Files: s.Files, AIEvents: s.AIEvents, LinesWritten: s.LinesWritten}
+	}
+	return pr, nil
+}
+
+// FileReport attributes one tracked file, given as it is tracked.
+func (p *Project) FileReport(ctx context.Context, filePath string) (*FileReport, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r, err := report.GenerateFileWindow(p.store, filePath, report.Window{}, p.opts)
+	if err != nil {
+		return nil, err
+	}
+	fr := fileReport(r)
+	return &fr, nil
+}
+
+// Survival reports how much AI-written code is still in the project.
+func (p *Project) Survival(ctx context.Context) (*SurvivalReport, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	projectPath, err := report.DiscoverProjectPath(p.store)
+	if err != nil {
+		return nil, err
+	}
+	r, err := survival.Analyze(p.store, projectPath, survival.DefaultCorrectionWindow)
+	if err != nil {
+		return nil, err
+	}
+	return &SurvivalReport{
+		Tracked:      r.TotalTracked,
+		Survived:     r.SurvivedCount,
+		Rate:         r.SurvivalRate,
+		ByAuthorship: survivalBreakdowns(r.ByAuthorship),
+		ByWorkType:   survivalBreakdowns(r.ByWorkType),
+		ByModel:      survivalBreakdowns(r.ByModel),
+	}, nil
+}
+
+func fileReport(r *report.FileReport) FileReport {
+	return FileReport{
+		Path:            r.FilePath,
+		WorkType:        r.WorkType,
+		AuthorshipLevel: r.AuthorshipLevel,
+		MeaningfulAIPct: r.MeaningfulAIPct,
+		RawAIPct:        r.RawAIPct,
+		TotalLines:      r.TotalLines,
+		AILines:         r.AILines,
+		DeletedLines:    r.DeletedLines,
+		AIDeletedLines:  r.AIDeletedLines,
+	}
+}
+
+func survivalBreakdowns(m map[string]survival.SurvivalBreakdown) map[string]SurvivalBreakdown {
+	out := make(map[string]SurvivalBreakdown, len(m))
+	for k, b := range m {
+		out[k] = SurvivalBreakdown{Tracked: b.Tracked, Survived: b.Survived, Rate: b.Rate}
+	}
+	return out
+}
@@ -0,0 +1,129 @@
+package gapmap
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/anthropic/gap-map/internal/keychain"
+	"github.com/anthropic/gap-map/internal/store"
+)
+
+func TestProject(t *testing.T) {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "gapmap.db")
+	projDir := filepath.Join(dir, "proj")
+	if err := os.MkdirAll(projDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(projDir, "main.go"), []byte("package main\n\nfunc main() {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := store.New(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := s.InsertAttribution(store.AttributionRecord{
+		FilePath:        "main.go",
+		ProjectPath:     projDir,
+		AuthorshipLevel: "mostly_human",
+		Timestamp:       time.Now(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.UpdateAttributionWorkType(id, "core_logic"); err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+
+	if _, err := OpenProject(dbPath, &Options{Scorer: "bogus"}); err == nil {
+		t.Error("OpenProject with an unknown scorer succeeded")
+	}
+	p, err := OpenProject(dbPath, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	ctx := context.Background()
+
+	pr, err := p.ProjectReport(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Outside git, every line of the file counts as changed.
+	if pr.ProjectPath != projDir || pr.TotalLines != 2 || pr.AILines != 0 || len(pr.Files) != 1 {
+		t.Errorf("ProjectReport = %+v, want main.go with 2 human lines", pr)
+	}
+	if pr.Scorer != "weighted" {
+		t.Errorf("Scorer = %q, want weighted", pr.Scorer)
+	}
+
+	fr, err := p.FileReport(ctx, "main.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fr.Path != "main.go" || fr.TotalLines != 2 || fr.AuthorshipLevel != "mostly_human" {
+		t.Errorf("FileReport = %+v", fr)
+	}
+
+	sr, err := p.Survival(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sr.Tracked != 0 {
+		t.Errorf("Survival = %+v, want nothing tracked", sr)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := p.FileReport(cancelled, "main.go"); err == nil {
+		t.Error("FileReport with a cancelled context succeeded")
+	}
+}
+
+func TestOpenProjectEncrypted(t *testing.T) {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "gapmap.db")
+	file := filepath.Join(dir, "main.go")
+	if err := os.WriteFile(file, []byte("package main\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	key := bytes.Repeat([]byte{3}, store.KeySize)
+	s, err := store.New(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.InsertSessionEvent("sess", "tool_use", "Write", file, "h", time.Now(), `{"uuid":"u-1"}`, 1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.InsertAttribution(store.AttributionRecord{
+		FilePath: "main.go", ProjectPath: dir, AuthorshipLevel: "mostly_ai", Timestamp: time.Now(),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.EncryptContent(key); err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+
+	t.Setenv(keychain.EnvKey, base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{4}, store.KeySize)))
+	if _, err := OpenProject(dbPath, nil); err == nil {
+		t.Error("OpenProject with the wrong key succeeded")
+	}
+
+	t.Setenv(keychain.EnvKey, base64.StdEncoding.EncodeToString(key))
+	p, err := OpenProject(dbPath, nil)
+	if err != nil {
+		t.Fatalf("OpenProject of an encrypted database: %v", err)
+	}
+	defer p.Close()
+	if _, err := p.ProjectReport(context.Background()); err != nil {
+		t.Errorf("ProjectReport: %v", err)
+	}
+}